@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/admission"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/trust"
+)
+
+// admissionTopic 邀请的广播主题：本节点签发的邀请发给把本节点当作受信节点的邻居
+const admissionTopic = "/daan/admission/1.0.0"
+
+// admissionAllowList 始终允许连入的节点：引导节点和上次保存的邻居
+func admissionAllowList(bootstrapPeers []string, neighbors []*neighbor.Neighbor) []string {
+	var ids []string
+	for _, addr := range bootstrapPeers {
+		if i := strings.LastIndex(addr, "/p2p/"); i >= 0 {
+			if id := strings.TrimSpace(addr[i+len("/p2p/"):]); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, n := range neighbors {
+		if n != nil && n.NodeID != "" {
+			ids = append(ids, n.NodeID)
+		}
+	}
+	return ids
+}
+
+// wireAdmission 以信任网和声誉管理器作为关系证明的来源：
+// 受信节点是本节点直接背书的节点，声誉只认本节点记录过的分值。
+func wireAdmission(m *admission.Manager, self string, endorsements *trust.EndorsementManager, reputations *reputation.Manager) {
+	if endorsements != nil {
+		m.SetTrustedFunc(func(nodeID string) bool {
+			now := time.Now()
+			for _, e := range endorsements.GetEndorsementsBy(self) {
+				if e.Subject == nodeID && e.Valid(now) {
+					return true
+				}
+			}
+			return false
+		})
+		m.SetEndorsersFunc(func(subject string) []string {
+			now := time.Now()
+			var endorsers []string
+			for _, e := range endorsements.GetEndorsementsFor(subject) {
+				if e.Valid(now) {
+					endorsers = append(endorsers, e.Endorser)
+				}
+			}
+			return endorsers
+		})
+	}
+	if reputations != nil {
+		m.SetReputationFunc(func(nodeID string) (float64, bool) {
+			score, ok := reputations.Get(nodeID)
+			if !ok {
+				return 0, false
+			}
+			return score.Score, true
+		})
+	}
+}
+
+// startAdmissionGossip 接收其他节点广播的邀请，只保存受信节点签发的
+func startAdmissionGossip(m *admission.Manager, transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var inv admission.Invitation
+		return json.Unmarshal(data, &inv) == nil && inv.Inviter != "" && inv.Invitee != ""
+	}
+	return transport.Subscribe(admissionTopic, validate, func(data []byte, from string) {
+		var inv admission.Invitation
+		if json.Unmarshal(data, &inv) == nil && inv.Inviter == from {
+			m.AddInvitation(&inv)
+		}
+	})
+}
+
+// registerAdmissionAPI 把准入 API 接到准入管理器；transport 非空时广播本节点签发的邀请
+func registerAdmissionAPI(s *httpapi.Server, m *admission.Manager, transport gossipTransport) {
+	s.AdmissionStatusFunc = func() map[string]interface{} {
+		return toMap(m.Status())
+	}
+	s.AdmissionInviteFunc = func(req *httpapi.AdmissionInviteRequest) (map[string]interface{}, error) {
+		inv, err := m.Invite(req.PeerID, req.Note, time.Duration(req.TTL)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			if data, err := json.Marshal(inv); err == nil {
+				if err := transport.Publish(admissionTopic, data); err != nil {
+					fmt.Printf("⚠️  邀请广播失败: %v\n", err)
+				}
+			}
+		}
+		return toMap(inv), nil
+	}
+	s.AdmissionRevokeFunc = m.RevokeInvitation
+	s.AdmissionInvitationsFunc = func(peerID string) []map[string]interface{} {
+		var result []map[string]interface{}
+		for _, inv := range m.Invitations(peerID) {
+			result = append(result, toMap(inv))
+		}
+		return result
+	}
+	s.AdmissionImportFunc = func(record []byte) (map[string]interface{}, error) {
+		var inv admission.Invitation
+		if err := json.Unmarshal(record, &inv); err != nil {
+			return nil, err
+		}
+		added, err := m.AddInvitation(&inv)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(added), nil
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// API Key 列表与角色策略文件（相对数据目录）
+const (
+	apiKeysFile    = "auth/api_keys.json"
+	rbacPolicyFile = "rbac.json"
+)
+
+// openAPIKeys 打开数据目录中的 API Key 列表和角色策略
+func openAPIKeys(dataDir string) (*auth.KeyStore, *auth.Policy, error) {
+	policy, err := auth.LoadPolicy(filepath.Join(dataDir, rbacPolicyFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载角色策略失败: %w", err)
+	}
+	keys, err := auth.NewKeyStore(filepath.Join(dataDir, apiKeysFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载 API Key 失败: %w", err)
+	}
+	return keys, policy, nil
+}
+
+// createAPIKey 为策略中已定义的角色签发 API Key
+func createAPIKey(dataDir, role, name string) (*auth.APIKey, string, error) {
+	keys, policy, err := openAPIKeys(dataDir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !policy.HasRole(auth.Role(role)) {
+		return nil, "", fmt.Errorf("%w: %q（可用角色: %s）", auth.ErrUnknownRole, role, strings.Join(policy.RoleNames(), ", "))
+	}
+	return keys.Create(auth.Role(role), name)
+}
+
+// apiKeyAccount API 计量的计费账户：API Key 按 Key ID 计量，名称为 Key 名称；其他令牌返回空，按令牌 ID 计量
+func apiKeyAccount(keys *auth.KeyStore) httpapi.MeterAccountFunc {
+	return func(token string) (string, string) {
+		key, ok := keys.Authenticate(token)
+		if !ok {
+			return "", ""
+		}
+		return key.ID, key.Name
+	}
+}
+
+func cmdTokenCreate(args []string) {
+	fs := flag.NewFlagSet("token create", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	role := fs.String("role", "", "角色: read-only, operator, arbitrator, admin 或 rbac.json 中定义的角色")
+	name := fs.String("name", "", "Key 名称（可选，便于识别用途）")
+	fs.Parse(args)
+
+	if *role == "" {
+		fmt.Fprintln(os.Stderr, "请使用 -role 指定角色")
+		os.Exit(1)
+	}
+	key, secret, err := createAPIKey(*dataDir, *role, *name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建 API Key 失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("======== API Key ========")
+	fmt.Printf("ID:   %s\n", key.ID)
+	fmt.Printf("角色: %s\n", key.Role)
+	fmt.Printf("密钥: %s\n", secret)
+	fmt.Println("=========================")
+	fmt.Println("⚠️  密钥只显示这一次，节点无需重启即可使用")
+}
+
+func cmdTokenRevoke(args []string) {
+	fs := flag.NewFlagSet("token revoke", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	// Key ID 可以写在选项之前或之后
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if id == "" {
+		id = fs.Arg(0)
+	}
+	if id == "" {
+		fmt.Fprintln(os.Stderr, "用法: agentnetwork token revoke <id> [-data ./data]")
+		os.Exit(1)
+	}
+
+	keys, _, err := openAPIKeys(*dataDir)
+	if err == nil {
+		err = keys.Revoke(id)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "撤销 API Key 失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已撤销 %s\n", id)
+}
+
+func cmdTokenList(args []string) {
+	fs := flag.NewFlagSet("token list", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	fs.Parse(args)
+
+	keys, _, err := openAPIKeys(*dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	list := keys.List()
+	if len(list) == 0 {
+		fmt.Println("暂无 API Key，使用 agentnetwork token create -role=<角色> 创建")
+		return
+	}
+	fmt.Printf("%-14s %-12s %-20s %-20s %s\n", "ID", "角色", "名称", "创建时间", "状态")
+	for _, key := range list {
+		status := "有效"
+		if key.RevokedAt != nil {
+			status = "已撤销 " + key.RevokedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-14s %-12s %-20s %-20s %s\n", key.ID, key.Role, key.Name, key.CreatedAt.Local().Format("2006-01-02 15:04"), status)
+	}
+}
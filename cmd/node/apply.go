@@ -0,0 +1,234 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/daemon"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/provision"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"go.yaml.in/yaml/v2"
+)
+
+// featureReadyPath 特性就绪 API 路径
+const featureReadyPath = "/api/v1/node/features/ready"
+
+func cmdApply() {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := fs.String("f", "", "节点清单文件（YAML）")
+	dataDir := fs.String("data", "./data", "数据目录")
+	httpAddr := fs.String("http", "", "运行中节点的 HTTP 服务地址（默认取清单中的 ports.http）")
+	dryRun := fs.Bool("dry-run", false, "只显示变更，不应用")
+	fs.Usage = printApplyUsage
+	fs.Parse(os.Args[2:])
+
+	if *file == "" {
+		printApplyUsage()
+		os.Exit(1)
+	}
+
+	manifest, err := loadManifest(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "清单无效: %v\n", err)
+		os.Exit(1)
+	}
+	current, err := provision.LoadApplied(*dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取已应用的清单失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	changes := provision.Diff(current, manifest)
+	if len(changes) == 0 {
+		fmt.Println("✅ 节点已与清单一致，无需变更")
+		return
+	}
+
+	fmt.Printf("📋 %d 项变更:\n", len(changes))
+	for _, c := range changes {
+		note := "（重启后生效）"
+		if c.Live {
+			note = ""
+		}
+		fmt.Printf("   %s%s\n", c, note)
+	}
+	if *dryRun {
+		return
+	}
+
+	// 特性开关在运行中的节点上直接生效；节点未运行时在下次启动时应用
+	d := daemon.New(&daemon.Config{DataDir: *dataDir})
+	_, running := d.IsRunning()
+	if running {
+		addr := *httpAddr
+		if addr == "" {
+			addr = provisionedHTTPAddr(manifest)
+		}
+		token := loadOrGenerateToken(*dataDir)
+		sign := nodeRequestSigner(filepath.Join(*dataDir, "keys", "node.key"))
+		for _, c := range changes {
+			if !c.Live {
+				continue
+			}
+			name := strings.TrimPrefix(c.Path, "features.")
+			payload := map[string]interface{}{"name": name, "ready": c.To == "true"}
+			if _, err := nodeAPIRequest(addr, token, http.MethodPost, featureReadyPath, payload, sign); err != nil {
+				fmt.Fprintf(os.Stderr, "应用 %s 失败: %v\n", c.Path, err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	if err := provision.SaveApplied(*dataDir, manifest); err != nil {
+		fmt.Fprintf(os.Stderr, "保存清单失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✅ 清单已应用")
+
+	if running && provision.NeedsRestart(changes) {
+		fmt.Printf("⚠️  部分变更需重启节点后生效: agentnetwork stop -data %s && agentnetwork start -data %s\n", *dataDir, *dataDir)
+	}
+}
+
+func printApplyUsage() {
+	fmt.Print(`用法: agentnetwork apply -f <清单文件> [选项]
+
+按 YAML 清单声明节点的角色、端口、引导节点、特性开关、配额、Webhook 和定时任务。
+与上次应用的清单比较后只应用变化的部分，重复应用同一清单不会产生变更。
+启动参数类的配置在节点下次启动时生效（命令行显式指定的参数优先）。
+
+选项:
+  -f        节点清单文件（YAML）
+  -data     数据目录 (默认: ./data)
+  -http     运行中节点的 HTTP 服务地址 (默认: 清单中的 ports.http)
+  -dry-run  只显示变更，不应用
+
+示例:
+  agentnetwork apply -f node.yaml -dry-run
+  agentnetwork apply -f node.yaml -data /var/lib/agentnetwork
+`)
+}
+
+// loadManifest 读取并校验 YAML 清单，拒绝未知字段
+func loadManifest(path string) (*provision.Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest provision.Manifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, err
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// provisionedHTTPAddr 清单中声明的 HTTP 服务地址
+func provisionedHTTPAddr(m *provision.Manifest) string {
+	if m != nil && m.Ports.HTTP != 0 {
+		return fmt.Sprintf(":%d", m.Ports.HTTP)
+	}
+	return ":18345"
+}
+
+// loadProvisionedFlags 以已应用清单中的启动配置作为 run/start 参数默认值
+func loadProvisionedFlags(fs *flag.FlagSet, cf *commonFlags) {
+	m, err := applyProvisionedFlags(fs, cf.dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载节点清单失败: %v\n", err)
+		os.Exit(1)
+	}
+	cf.provisioned = m
+}
+
+// applyProvisionedFlags 把已应用清单中的启动配置作为参数默认值
+// 命令行显式指定的参数优先。未应用过清单时返回 nil。
+func applyProvisionedFlags(fs *flag.FlagSet, dataDir string) (*provision.Manifest, error) {
+	m, err := provision.LoadApplied(dataDir)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	values := map[string]string{}
+	if m.Role != "" {
+		values["role"] = m.Role
+	}
+	if m.Namespace != "" {
+		values["namespace"] = m.Namespace
+	}
+	if m.Ports.P2P != 0 {
+		values["listen"] = fmt.Sprintf("/ip4/0.0.0.0/tcp/%d,/ip4/0.0.0.0/udp/%d/quic-v1", m.Ports.P2P, m.Ports.P2P)
+	}
+	if m.Ports.HTTP != 0 {
+		values["http"] = fmt.Sprintf(":%d", m.Ports.HTTP)
+	}
+	if m.Ports.Admin != 0 {
+		values["admin"] = fmt.Sprintf(":%d", m.Ports.Admin)
+	}
+	if m.Ports.GRPC != 0 {
+		values["grpc"] = fmt.Sprintf(":%d", m.Ports.GRPC)
+	}
+	if len(m.Bootstrap) > 0 {
+		values["bootstrap"] = strings.Join(m.Bootstrap, ",")
+	}
+	if m.Quotas.OutboundRate != nil {
+		values["outbound-rate"] = strconv.FormatInt(*m.Quotas.OutboundRate, 10)
+	}
+	if m.Quotas.PublicInbox != nil {
+		values["public-inbox"] = strconv.FormatBool(*m.Quotas.PublicInbox)
+	}
+	for _, job := range m.Jobs {
+		if job.Type != provision.JobBackup {
+			continue
+		}
+		values["backup-interval"] = job.Interval
+		for key, value := range job.Params {
+			values["backup-"+key] = value
+		}
+	}
+
+	for name, value := range values {
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("清单中的 %s 无效: %w", name, err)
+		}
+	}
+	return m, nil
+}
+
+// provisionedWebhooks 按清单创建声誉事件 Webhook，清单未声明 Webhook 时返回 nil
+// 密钥从清单引用的环境变量读取；推送同时用节点的 Webhook 签名密钥签名。
+func provisionedWebhooks(nodeID string, m *provision.Manifest, keyring *reputation.WebhookKeyring) (*reputation.WebhookManager, error) {
+	if m == nil || (m.Webhooks.CallbackSecretEnv == "" && len(m.Webhooks.Targets) == 0) {
+		return nil, nil
+	}
+
+	config := reputation.DefaultWebhookConfig(nodeID)
+	config.Keyring = keyring
+	if env := m.Webhooks.CallbackSecretEnv; env != "" {
+		config.CallbackSecret = os.Getenv(env)
+		if config.CallbackSecret == "" {
+			return nil, fmt.Errorf("环境变量 %s 未设置", env)
+		}
+	}
+	for _, t := range m.Webhooks.Targets {
+		secret := os.Getenv(t.SecretEnv)
+		if t.SecretEnv != "" && secret == "" {
+			return nil, fmt.Errorf("环境变量 %s 未设置", t.SecretEnv)
+		}
+		config.Endpoints = append(config.Endpoints, reputation.WebhookEndpoint{URL: t.URL, Secret: secret})
+	}
+	return reputation.NewWebhookManager(config)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/audit"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// auditTopic 执行记录、审计结论和执行记录请求的广播主题
+const auditTopic = "/daan/audit/1.0.0"
+
+// auditCollateralPurpose 偏离共识时被罚没的抵押用途
+const auditCollateralPurpose = "supernode_auditor"
+
+// auditFetchTimeout 向执行方请求执行记录的等待时间
+const auditFetchTimeout = 10 * time.Second
+
+// auditMessage 审计广播消息，每条只携带一项
+type auditMessage struct {
+	Transcript *audit.Transcript `json:"transcript,omitempty"`
+	Audit      *audit.Audit      `json:"audit,omitempty"`
+	Request    string            `json:"request,omitempty"` // 请求执行记录的任务ID
+}
+
+// taskTranscriptInput 执行记录的输入：任务类型、描述和模板参数
+func taskTranscriptInput(t *task.Task) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        t.Type,
+		"description": t.Description,
+		"payload":     t.Payload,
+	})
+	return data
+}
+
+// wireAudit 偏离共识的审计者扣减声誉，并罚没其审计抵押
+func wireAudit(m *audit.Manager, reputations *reputation.Manager, collaterals *collateral.CollateralManager) {
+	if reputations != nil {
+		m.SetReputationFunc(func(nodeID string, delta float64, reason string) error {
+			_, err := reputations.Adjust(nodeID, delta, reason)
+			return err
+		})
+	}
+	if collaterals != nil {
+		m.SetSlashFunc(func(nodeID string, ratio float64, reason string, evidence []string) (float64, error) {
+			event, err := collaterals.SlashByNodePurpose(nodeID, auditCollateralPurpose, reason, evidence, ratio)
+			if err != nil {
+				return 0, err
+			}
+			return event.Amount, nil
+		})
+	}
+}
+
+// auditNetwork 经 GossipSub 交换执行记录和审计结论；未接入广播时只在本地记录
+type auditNetwork struct {
+	m    *audit.Manager
+	self string
+
+	mu        sync.Mutex
+	transport gossipTransport
+	waiting   map[string][]chan *audit.Transcript // taskID -> 等待执行记录的请求
+}
+
+func newAuditNetwork(m *audit.Manager, self string) *auditNetwork {
+	return &auditNetwork{m: m, self: self, waiting: make(map[string][]chan *audit.Transcript)}
+}
+
+// start 订阅审计主题，并把向执行方请求执行记录接到审计管理器
+func (an *auditNetwork) start(transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var msg auditMessage
+		return json.Unmarshal(data, &msg) == nil && (msg.Transcript != nil || msg.Audit != nil || msg.Request != "")
+	}
+	if err := transport.Subscribe(auditTopic, validate, an.handle); err != nil {
+		return err
+	}
+	an.mu.Lock()
+	an.transport = transport
+	an.mu.Unlock()
+	an.m.SetFetchFunc(an.fetch)
+	return nil
+}
+
+// handle 只接受执行方本人的执行记录和审计者本人的结论；请求只由执行方应答
+func (an *auditNetwork) handle(data []byte, from string) {
+	var msg auditMessage
+	if json.Unmarshal(data, &msg) != nil {
+		return
+	}
+	switch {
+	case msg.Transcript != nil && msg.Transcript.Executor == from:
+		if an.m.AddTranscript(msg.Transcript) == nil {
+			an.deliver(msg.Transcript)
+		}
+	case msg.Audit != nil && msg.Audit.Auditor == from:
+		an.m.SubmitAudit(msg.Audit)
+	case msg.Request != "":
+		if t, err := an.m.Transcript(msg.Request); err == nil && t.Executor == an.self {
+			an.publish(auditMessage{Transcript: t})
+		}
+	}
+}
+
+// fetch 广播执行记录请求，等待执行方应答
+func (an *auditNetwork) fetch(taskID string) (*audit.Transcript, error) {
+	ch := make(chan *audit.Transcript, 1)
+	an.mu.Lock()
+	an.waiting[taskID] = append(an.waiting[taskID], ch)
+	an.mu.Unlock()
+	defer an.cancel(taskID, ch)
+
+	if err := an.publish(auditMessage{Request: taskID}); err != nil {
+		return nil, err
+	}
+	select {
+	case t := <-ch:
+		return t, nil
+	case <-time.After(auditFetchTimeout):
+		return nil, fmt.Errorf("executor did not answer within %s", auditFetchTimeout)
+	}
+}
+
+func (an *auditNetwork) deliver(t *audit.Transcript) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	for _, ch := range an.waiting[t.TaskID] {
+		select {
+		case ch <- t:
+		default:
+		}
+	}
+	delete(an.waiting, t.TaskID)
+}
+
+func (an *auditNetwork) cancel(taskID string, ch chan *audit.Transcript) {
+	an.mu.Lock()
+	defer an.mu.Unlock()
+	list := an.waiting[taskID]
+	for i, c := range list {
+		if c == ch {
+			an.waiting[taskID] = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(an.waiting[taskID]) == 0 {
+		delete(an.waiting, taskID)
+	}
+}
+
+func (an *auditNetwork) publish(msg auditMessage) error {
+	an.mu.Lock()
+	transport := an.transport
+	an.mu.Unlock()
+	if transport == nil {
+		return errors.New("audit gossip not available")
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return transport.Publish(auditTopic, data)
+}
+
+// recordTask 执行方交付后保存执行记录并广播，供超级节点复核
+func (an *auditNetwork) recordTask(t *task.Task, result string) {
+	transcript, err := an.m.RecordTranscript(t.ID, taskTranscriptInput(t), []byte(result))
+	if err != nil {
+		fmt.Printf("⚠️  保存执行记录失败: %v\n", err)
+		return
+	}
+	an.publish(auditMessage{Transcript: transcript})
+}
+
+// share 广播本节点的审计结论，未接入广播时结论只保存在本地
+func (an *auditNetwork) share(a *audit.Audit) {
+	if err := an.publish(auditMessage{Audit: a}); err != nil {
+		fmt.Printf("⚠️  审计结论广播失败: %v\n", err)
+	}
+}
+
+// auditError 把审计错误映射为 API 的 404/403
+func auditError(err error) error {
+	switch {
+	case errors.Is(err, audit.ErrTranscriptNotFound), errors.Is(err, audit.ErrNoConsensus):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, audit.ErrNotAuditor), errors.Is(err, audit.ErrSelfAudit):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
+
+// registerAuditAPI 把审计 API 和超级节点审计提交接到审计管理器
+func registerAuditAPI(s *httpapi.Server, an *auditNetwork) {
+	m := an.m
+	s.AuditTranscriptFunc = func(taskID string) (map[string]interface{}, error) {
+		t, err := m.FetchTranscript(taskID)
+		if err != nil {
+			return nil, auditError(err)
+		}
+		return toMap(t), nil
+	}
+	s.AuditRunFunc = func(req *httpapi.AuditRunRequest) (map[string]interface{}, error) {
+		a, err := m.Audit(req.TaskID, req.ResultHash)
+		if err != nil {
+			return nil, auditError(err)
+		}
+		an.share(a)
+		return toMap(a), nil
+	}
+	s.AuditConsensusFunc = func(taskID string) (map[string]interface{}, error) {
+		audits := m.Audits(taskID)
+		consensus, err := m.Consensus(taskID)
+		if err != nil && len(audits) == 0 {
+			return nil, auditError(err)
+		}
+		return map[string]interface{}{
+			"task_id":   taskID,
+			"consensus": consensus,
+			"audits":    audits,
+			"pass_rate": m.PassRate(taskID),
+		}, nil
+	}
+	s.AuditDeviationsFunc = func(auditor string, limit int) []map[string]interface{} {
+		var result []map[string]interface{}
+		for _, d := range m.Deviations(auditor, limit) {
+			result = append(result, toMap(d))
+		}
+		return result
+	}
+	s.AuditPenaltyConfigFunc = func() map[string]httpapi.PenaltyConfig {
+		config := make(map[string]httpapi.PenaltyConfig)
+		for severity, rule := range m.PenaltyRules() {
+			config[severity] = httpapi.PenaltyConfig{Severity: rule.Severity, RepPenalty: rule.RepPenalty, SlashRatio: rule.SlashRatio}
+		}
+		return config
+	}
+	s.AuditSetPenaltyFunc = func(config httpapi.PenaltyConfig) error {
+		return m.SetPenaltyRule(audit.PenaltyRule{Severity: config.Severity, RepPenalty: config.RepPenalty, SlashRatio: config.SlashRatio})
+	}
+	s.AuditManualPenaltyFunc = func(nodeID, severity, reason string) (map[string]interface{}, error) {
+		p, err := m.ManualPenalty(nodeID, severity, reason)
+		if err != nil {
+			return nil, err
+		}
+		result := toMap(p)
+		result["penalty_applied"] = p.Error == ""
+		return result, nil
+	}
+	s.SuperNodeAuditSubmit = func(target string, passed bool, details string) (string, error) {
+		a, err := m.RecordAudit(target, passed, details)
+		if err != nil {
+			return "", err
+		}
+		an.share(a)
+		return a.ID, nil
+	}
+	s.SuperNodeAuditResult = func(target string) (float64, error) {
+		return m.PassRate(target), nil
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/backup"
+)
+
+// 备份凭据与口令只从环境变量读取，避免出现在进程参数中
+const (
+	envBackupAccessKey  = "DAAN_BACKUP_ACCESS_KEY"
+	envBackupSecretKey  = "DAAN_BACKUP_SECRET_KEY"
+	envBackupPassphrase = "DAAN_BACKUP_PASSPHRASE"
+)
+
+// backupFlags 远端备份参数（backup 命令与 run/start 的定时备份共用）
+type backupFlags struct {
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	pathStyle bool
+	interval  time.Duration
+	keep      int
+	maxAge    time.Duration
+}
+
+// registerBackupFlags 注册备份参数，prefix 为参数名前缀（run/start 中为 "backup-"）
+func registerBackupFlags(fs *flag.FlagSet, prefix string) *backupFlags {
+	defaults := backup.DefaultConfig("")
+	bf := &backupFlags{}
+	fs.StringVar(&bf.endpoint, prefix+"endpoint", "https://s3.amazonaws.com", "S3 兼容存储地址（MinIO、GCS 互操作地址等）")
+	fs.StringVar(&bf.region, prefix+"region", "us-east-1", "存储区域（GCS 使用 auto）")
+	fs.StringVar(&bf.bucket, prefix+"bucket", "", "存储桶")
+	fs.StringVar(&bf.prefix, prefix+"prefix", defaults.Prefix, "对象前缀")
+	fs.BoolVar(&bf.pathStyle, prefix+"path-style", false, "使用路径风格地址（MinIO 需要）")
+	fs.DurationVar(&bf.interval, prefix+"interval", 0, "定时备份间隔，0 表示不定时（仅 run/start）")
+	fs.IntVar(&bf.keep, prefix+"keep", defaults.KeepLast, "至少保留最近的快照数")
+	fs.DurationVar(&bf.maxAge, prefix+"max-age", defaults.MaxAge, "超过此时长的快照会被清理，0 表示只按数量保留")
+	return bf
+}
+
+// newBackupManager 根据参数和环境变量创建备份管理器
+func newBackupManager(dataDir string, bf *backupFlags) (*backup.Manager, error) {
+	if bf.bucket == "" {
+		return nil, fmt.Errorf("未指定存储桶")
+	}
+	accessKey := firstEnv(envBackupAccessKey, "AWS_ACCESS_KEY_ID")
+	secretKey := firstEnv(envBackupSecretKey, "AWS_SECRET_ACCESS_KEY")
+	client, err := backup.NewS3Client(&backup.S3Config{
+		Endpoint:  bf.endpoint,
+		Region:    bf.region,
+		Bucket:    bf.bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PathStyle: bf.pathStyle,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config := backup.DefaultConfig(dataDir)
+	config.Prefix = bf.prefix
+	config.Passphrase = os.Getenv(envBackupPassphrase)
+	config.Interval = bf.interval
+	config.KeepLast = bf.keep
+	config.MaxAge = bf.maxAge
+	return backup.NewManager(config, client)
+}
+
+// startScheduledBackup 运行节点时按间隔定时备份，未配置时返回 nil
+func startScheduledBackup(dataDir string, bf *backupFlags) *backup.Manager {
+	if bf == nil || bf.interval <= 0 || bf.bucket == "" {
+		return nil
+	}
+	m, err := newBackupManager(dataDir, bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "定时备份未启用: %v\n", err)
+		return nil
+	}
+	m.Start()
+	fmt.Printf("定时备份已启用: 每 %s 推送到 %s/%s\n", bf.interval, bf.bucket, bf.prefix)
+	return m
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func cmdBackup() {
+	if len(os.Args) < 3 {
+		printBackupUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	fs := flag.NewFlagSet("backup "+subCmd, flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	bf := registerBackupFlags(fs, "")
+	key := fs.String("object", "", "快照对象键（verify/restore，默认最新快照）")
+	target := fs.String("target", "", "恢复目标目录（restore，默认数据目录）")
+	force := fs.Bool("force", false, "目标目录非空时仍然恢复（覆盖同名文件）")
+	jsonOutput := fs.Bool("json", false, "以 JSON 输出")
+	fs.Usage = printBackupUsage
+	fs.Parse(os.Args[3:])
+
+	m, err := newBackupManager(*dataDir, bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "备份配置错误: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var result interface{}
+	switch subCmd {
+	case "push":
+		var snap *backup.Snapshot
+		snap, err = m.RunOnce(ctx)
+		if err == nil {
+			result = snap
+			if !*jsonOutput {
+				fmt.Printf("✅ 已推送快照 %s (%d 字节, sha256 %s)\n", snap.Key, snap.Size, snap.SHA256)
+			}
+		}
+	case "list":
+		var snapshots []*backup.Snapshot
+		snapshots, err = m.List(ctx)
+		result = snapshots
+		if err == nil && !*jsonOutput {
+			for _, s := range snapshots {
+				fmt.Printf("%s  %s  %d 字节\n", s.CreatedAt.Format(time.RFC3339), s.Key, s.Size)
+			}
+			fmt.Printf("共 %d 个快照\n", len(snapshots))
+		}
+	case "verify", "restore":
+		objectKey := *key
+		if objectKey == "" {
+			var latest *backup.Snapshot
+			if latest, err = m.Latest(ctx); err != nil {
+				break
+			}
+			objectKey = latest.Key
+		}
+		var snap *backup.Snapshot
+		if subCmd == "verify" {
+			snap, err = m.Verify(ctx, objectKey)
+			if err == nil && !*jsonOutput {
+				fmt.Printf("✅ 快照 %s 完整性校验通过\n", snap.Key)
+			}
+		} else {
+			dir := *target
+			if dir == "" {
+				dir = *dataDir
+			}
+			snap, err = m.Restore(ctx, objectKey, dir, *force)
+			if err == nil && !*jsonOutput {
+				fmt.Printf("✅ 已将快照 %s 恢复到 %s\n", snap.Key, dir)
+			}
+		}
+		result = snap
+	case "prune":
+		var deleted []string
+		deleted, err = m.Prune(ctx)
+		result = map[string]interface{}{"deleted": deleted}
+		if err == nil && !*jsonOutput {
+			fmt.Printf("已清理 %d 个过期快照\n", len(deleted))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printBackupUsage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 备份操作失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	}
+}
+
+func printBackupUsage() {
+	fmt.Print(`用法: agentnetwork backup <子命令> [选项]
+
+将数据目录加密后备份到 S3 兼容存储（AWS S3、MinIO、GCS 互操作模式）。
+快照使用口令派生的 AES-256-GCM 加密，远端对象附带 SHA-256 用于完整性校验。
+
+子命令:
+  push      立即推送一个快照，并按保留策略清理
+  list      列出远端快照
+  verify    下载快照并校验完整性（默认最新快照）
+  restore   校验后恢复快照到数据目录（默认最新快照）
+  prune     按保留策略清理远端快照
+
+选项:
+  -data        数据目录 (默认: ./data)
+  -endpoint    存储地址 (默认: https://s3.amazonaws.com)
+  -region      存储区域 (默认: us-east-1)
+  -bucket      存储桶（必填）
+  -prefix      对象前缀 (默认: daan-backups)
+  -path-style  使用路径风格地址（MinIO 需要）
+  -keep        至少保留最近的快照数 (默认: 7)
+  -max-age     超过此时长的快照会被清理 (默认: 720h)
+  -object      快照对象键（verify/restore）
+  -target      恢复目标目录（restore，默认数据目录）
+  -force       目标目录非空时仍然恢复
+  -json        以 JSON 输出
+
+环境变量:
+  DAAN_BACKUP_ACCESS_KEY   访问密钥（或 AWS_ACCESS_KEY_ID）
+  DAAN_BACKUP_SECRET_KEY   私有密钥（或 AWS_SECRET_ACCESS_KEY）
+  DAAN_BACKUP_PASSPHRASE   加密口令（必填，丢失后快照无法恢复）
+
+定时备份:
+  在 run/start 中使用 -backup-bucket 与 -backup-interval 等带 backup- 前缀的同名参数。
+
+示例:
+  agentnetwork backup push -bucket daan -endpoint http://127.0.0.1:9000 -path-style
+  agentnetwork backup verify -bucket daan
+  agentnetwork backup restore -bucket daan -target ./data-restored
+  agentnetwork start -backup-bucket daan -backup-interval 6h -backup-keep 14
+`)
+}
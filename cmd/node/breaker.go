@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/breaker"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+)
+
+// maxBreakerDirectives 启动时从留言板读取的熔断指令上限
+const maxBreakerDirectives = 200
+
+// publishBreakerDirective 将熔断指令发布到留言板的熔断话题，返回留言ID
+func publishBreakerDirective(bb *bulletin.BulletinBoard, d *breaker.Directive) (string, error) {
+	if bb == nil {
+		return "", errors.New("bulletin board not available")
+	}
+	content, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	msg, err := bb.PublishMessageWithOptions(string(content), breaker.BulletinTopic, []string{d.Class, string(d.Action)}, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return msg.MessageID, nil
+}
+
+// applyBreakerDirective 执行留言板上的熔断指令（指令自带超级节点签名，与留言作者无关）
+func applyBreakerDirective(breakers *breaker.Manager, msg *bulletin.Message) error {
+	var d breaker.Directive
+	if err := json.Unmarshal([]byte(msg.Content), &d); err != nil {
+		return breaker.ErrInvalidDirective
+	}
+	return breakers.Apply(&d)
+}
+
+// syncBreakerDirectives 执行留言板上已有的熔断指令，返回接受的次数
+func syncBreakerDirectives(breakers *breaker.Manager, bb *bulletin.BulletinBoard) int {
+	messages, err := bb.QueryByTopic(breaker.BulletinTopic, maxBreakerDirectives, 0)
+	if err != nil {
+		return 0
+	}
+	// 顺序无关：每个类别只保留签发时间最新的指令
+	applied := 0
+	for _, msg := range messages {
+		if applyBreakerDirective(breakers, msg) == nil {
+			applied++
+		}
+	}
+	return applied
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// bulletinMessage 将留言转换为 API 响应，附带发布时提取的链接预览
+func bulletinMessage(msg *bulletin.Message) *httpapi.BulletinMessage {
+	m := &httpapi.BulletinMessage{
+		ID:        msg.MessageID,
+		Author:    msg.Author,
+		Topic:     msg.Topic,
+		Content:   msg.Content,
+		Timestamp: msg.Timestamp.Unix(),
+		TTL:       int64(time.Until(msg.ExpiresAt).Seconds()),
+		Encrypted: msg.Encrypted,
+	}
+	for _, p := range msg.Previews {
+		m.Previews = append(m.Previews, &httpapi.BulletinPreview{
+			Ref:         p.Ref,
+			Kind:        p.Kind,
+			Title:       p.Title,
+			MIME:        p.MIME,
+			Size:        p.Size,
+			ContentHash: p.ContentHash,
+			Truncated:   p.Truncated,
+			Error:       p.Error,
+		})
+	}
+	return m
+}
+
+// bulletinMessages 批量转换留言
+func bulletinMessages(list []*bulletin.Message) []*httpapi.BulletinMessage {
+	result := make([]*httpapi.BulletinMessage, 0, len(list))
+	for _, msg := range list {
+		result = append(result, bulletinMessage(msg))
+	}
+	return result
+}
+
+// subscriptionFilter 将 API 过滤器转换为留言板订阅过滤器
+func subscriptionFilter(f *httpapi.BulletinFilter) *bulletin.SubscriptionFilter {
+	if f == nil {
+		return nil
+	}
+	return &bulletin.SubscriptionFilter{
+		Keywords:      f.Keywords,
+		Authors:       f.Authors,
+		MinReputation: f.MinReputation,
+		Languages:     f.Languages,
+	}
+}
+
+// bulletinSubscriptions 订阅列表，按话题排序
+func bulletinSubscriptions(subs []*bulletin.Subscription) []*httpapi.BulletinSubscription {
+	result := make([]*httpapi.BulletinSubscription, 0, len(subs))
+	for _, sub := range subs {
+		s := &httpapi.BulletinSubscription{
+			Topic:        sub.Topic,
+			SubscribedAt: sub.SubscribedAt.Unix(),
+			MessageCount: sub.MessageCount,
+		}
+		if f := sub.Filter; f != nil {
+			s.Filter = &httpapi.BulletinFilter{
+				Keywords:      f.Keywords,
+				Authors:       f.Authors,
+				MinReputation: f.MinReputation,
+				Languages:     f.Languages,
+			}
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Topic < result[j].Topic })
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+)
+
+// disputeBundlePath 监管链导出包的 API 路径前缀，后接争议ID
+const disputeBundlePath = "/api/v1/dispute/bundle/"
+
+// exportDisputeBundle 导出本节点保存的争议的监管链包，由本节点签名；关联托管的记录作为回执附上
+func exportDisputeBundle(m *dispute.DisputeManager, escrows *escrow.EscrowManager, self string) func(disputeID string) (map[string]interface{}, error) {
+	return func(disputeID string) (map[string]interface{}, error) {
+		d, err := m.GetDispute(disputeID)
+		if err != nil {
+			return nil, disputeAPIError(err)
+		}
+		var attachments []dispute.BundleAttachment
+		if d.EscrowID != "" && escrows != nil {
+			if e, err := escrows.GetEscrow(d.EscrowID); err == nil {
+				a, err := dispute.NewBundleAttachment(dispute.AttachmentReceipt, e.ID, e)
+				if err != nil {
+					return nil, err
+				}
+				attachments = append(attachments, a)
+			}
+		}
+		bundle, err := m.ExportBundle(disputeID, self, attachments)
+		if err != nil {
+			return nil, disputeAPIError(err)
+		}
+		return toMap(bundle), nil
+	}
+}
+
+// verifyDisputeBundle 离线校验导出包，签名按导出节点ID中的公钥验证，
+// 包内附带的公钥不能代替导出节点
+func verifyDisputeBundle(b *dispute.CustodyBundle) (*dispute.BundleReport, error) {
+	return dispute.VerifyBundle(b, func(_, data, signature []byte) (bool, error) {
+		return verifyPeerSignature(b.Exporter, data, signature)
+	})
+}
+
+// cmdDisputeBundle 导出或离线校验争议监管链包
+func cmdDisputeBundle() {
+	if len(os.Args) < 3 {
+		printDisputeBundleUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	fs := flag.NewFlagSet("dispute-bundle "+subCmd, flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
+	output := fs.String("o", "", "导出包文件，默认 <争议ID>.bundle.json（export 时有效）")
+	jsonOutput := fs.Bool("json", false, "JSON格式输出校验结果（verify 时有效）")
+	fs.Parse(os.Args[3:])
+	if fs.NArg() != 1 {
+		printDisputeBundleUsage()
+		os.Exit(1)
+	}
+
+	switch subCmd {
+	case "export":
+		disputeID := fs.Arg(0)
+		data, err := nodeAPIRequest(*httpAddr, loadOrGenerateToken(*dataDir), http.MethodGet, disputeBundlePath+url.PathEscape(disputeID), nil, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+			os.Exit(1)
+		}
+		path := *output
+		if path == "" {
+			path = disputeID + ".bundle.json"
+		}
+		raw, _ := json.MarshalIndent(data, "", "  ")
+		if err := os.WriteFile(path, raw, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "写入导出包失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ 争议 %s 的监管链包已导出到 %s\n", disputeID, path)
+	case "verify":
+		raw, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取导出包失败: %v\n", err)
+			os.Exit(1)
+		}
+		var b dispute.CustodyBundle
+		if err := json.Unmarshal(raw, &b); err != nil {
+			fmt.Fprintf(os.Stderr, "解析导出包失败: %v\n", err)
+			os.Exit(1)
+		}
+		report, err := verifyDisputeBundle(&b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 校验失败: %v\n", err)
+			os.Exit(1)
+		}
+		if *jsonOutput {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		printBundleReport(report)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printDisputeBundleUsage()
+		os.Exit(1)
+	}
+}
+
+func printBundleReport(report *dispute.BundleReport) {
+	fmt.Println("✅ 监管链包校验通过")
+	fmt.Printf("   争议: %s\n", report.DisputeID)
+	fmt.Printf("   导出节点: %s\n", report.Exporter)
+	fmt.Printf("   摘要: %s\n", report.Digest)
+	fmt.Printf("   证据: %d，仲裁投票: %d\n", report.Evidence, report.Votes)
+	if report.TranscriptHead != "" {
+		fmt.Printf("   调解记录: %d 条，链头 %s\n", report.Messages, report.TranscriptHead)
+	}
+	kinds := make([]string, 0, len(report.Attachments))
+	for kind := range report.Attachments {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("   附件 %s: %d\n", kind, report.Attachments[kind])
+	}
+}
+
+func printDisputeBundleUsage() {
+	fmt.Print(`用法: agentnetwork dispute-bundle <子命令> [选项] <参数>
+
+子命令:
+  export <争议ID>   从运行中的节点导出本节点签名的争议监管链包
+  verify <文件>     离线校验导出包（摘要、导出节点签名、附件哈希、调解记录哈希链）
+
+选项:
+  -data     数据目录，用于读取访问令牌 (默认: ./data)
+  -http     HTTP服务地址 (默认: :18345)
+  -o        导出包文件 (默认: <争议ID>.bundle.json)
+  -json     JSON格式输出校验结果
+
+示例:
+  agentnetwork dispute-bundle export -o case.json dispute_...
+  agentnetwork dispute-bundle verify case.json
+`)
+}
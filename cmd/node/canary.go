@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/voting"
+)
+
+// requestCounter HTTP API 的累计请求数和服务端出错数（5xx），供金丝雀发布对比出错率
+type requestCounter struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+}
+
+// observe 返回计数的请求观测函数，next 非空时继续交给 next
+func (c *requestCounter) observe(next httpapi.RequestObserver) httpapi.RequestObserver {
+	return func(method, route string, status int, elapsed time.Duration) {
+		c.requests.Add(1)
+		if status >= http.StatusInternalServerError {
+			c.errors.Add(1)
+		}
+		if next != nil {
+			next(method, route, status, elapsed)
+		}
+	}
+}
+
+// canaryMetrics 金丝雀发布使用的本节点累计计数：请求和出错数取自 API，任务数和争议数取自各自的管理器。
+// 在投票管理器持有锁时调用，只读取计数，不回调投票管理器。
+func canaryMetrics(requests *requestCounter, tasks, disputes func() int) voting.MetricsFunc {
+	return func() voting.CanaryMetrics {
+		return voting.CanaryMetrics{
+			Requests: requests.requests.Load(),
+			Errors:   requests.errors.Load(),
+			Tasks:    int64(tasks()),
+			Disputes: int64(disputes()),
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// collateralService 把抵押管理器适配为 HTTP API 的抵押服务
+// 存入和提取只作用于本节点自己的账户；罚没可以针对任意节点，但必须附带证据。
+type collateralService struct {
+	m    *collateral.CollateralManager
+	self string
+}
+
+func (s collateralService) ListCollaterals(nodeID, status string) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, a := range s.m.ListAccounts(nodeID) {
+		if status == "" || a.Status() == status {
+			result = append(result, accountMap(a))
+		}
+	}
+	return result
+}
+
+func (s collateralService) GetCollateral(nodeID, purpose string) (map[string]interface{}, error) {
+	a, err := s.m.GetAccount(nodeID, purpose)
+	if err != nil {
+		return nil, collateralAPIError(err)
+	}
+	return accountMap(a), nil
+}
+
+func (s collateralService) Deposit(purpose string, amount float64) (map[string]interface{}, error) {
+	a, err := s.m.Deposit(s.self, purpose, amount)
+	if err != nil {
+		return nil, collateralAPIError(err)
+	}
+	return accountMap(a), nil
+}
+
+func (s collateralService) Withdraw(purpose string, amount float64) (map[string]interface{}, error) {
+	a, err := s.m.Withdraw(s.self, purpose, amount)
+	if err != nil {
+		return nil, collateralAPIError(err)
+	}
+	return accountMap(a), nil
+}
+
+func (s collateralService) Slash(req httpapi.CollateralSlashRequest) (map[string]interface{}, error) {
+	event, err := s.m.SlashAccount(req.NodeID, req.Purpose, req.Reason, []string{req.Evidence}, req.Ratio)
+	if err != nil {
+		return nil, collateralAPIError(err)
+	}
+	result := toMap(event)
+	result["slashed_amount"] = event.Amount
+	result["remaining"] = event.Remaining
+	return result, nil
+}
+
+func (s collateralService) SlashHistory(nodeID, purpose string, limit int) []map[string]interface{} {
+	events := s.m.QuerySlashHistory(nodeID, purpose, limit)
+	result := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		result = append(result, toMap(e))
+	}
+	return result
+}
+
+// accountMap 抵押账户附带可提取金额和状态
+func accountMap(a *collateral.Account) map[string]interface{} {
+	m := toMap(a)
+	m["available"] = a.Available()
+	m["status"] = a.Status()
+	return m
+}
+
+// collateralAPIError 把抵押错误映射为 HTTP API 的错误类别（404/403）
+func collateralAPIError(err error) error {
+	switch {
+	case errors.Is(err, collateral.ErrCollateralNotFound):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, collateral.ErrUnauthorized):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
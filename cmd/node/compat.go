@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+)
+
+// defaultLegacyProtocolsUntil 旧版本协议的默认弃用截止日期
+const defaultLegacyProtocolsUntil = "2027-04-16"
+
+// mailKeyCompatFamily 邮件密钥交换在协议版本回退表中的协议族名称
+const mailKeyCompatFamily = "mailbox_key"
+
+// maxLegacyPeersListed 协议兼容状态中每个旧版本列出的最近节点数
+const maxLegacyPeersListed = 50
+
+// parseLegacySunset 解析 -legacy-protocols-until：日期（UTC 零点）或 RFC3339 时间，空表示不提供旧版本协议
+func parseLegacySunset(value string) (sunset time.Time, enabled bool, err error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if sunset, err = time.Parse("2006-01-02", value); err == nil {
+		return sunset, true, nil
+	}
+	if sunset, err = time.Parse(time.RFC3339, value); err == nil {
+		return sunset, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC3339)", value)
+}
+
+// newProtocolCompat 创建协议版本回退表：邮件密钥交换和留言广播的 v1 版本在 sunset 前以兼容模式提供。
+// 不提供旧版本时仍登记当前版本，流量统计照常。
+func newProtocolCompat(ns string, sunset time.Time, legacy bool) *compat.Table {
+	mailKey := compat.Family{
+		Name:    mailKeyCompatFamily,
+		Current: namespace.Protocol(ns, mailbox.KeyExchangeProtocol),
+	}
+	gossip := bulletin.GossipFamily(sunset)
+	if legacy {
+		mailKey.Legacy = []compat.Legacy{{ID: namespace.Protocol(ns, mailbox.KeyExchangeProtocolV1), Sunset: sunset}}
+	} else {
+		gossip.Legacy = nil
+	}
+
+	table := compat.NewTable()
+	table.Register(mailKey)
+	table.Register(gossip)
+	return table
+}
+
+// protocolCompatStatus 各协议族按版本的使用情况和最近使用旧版本的节点，供 /api/v1/network/protocol-compat 查询
+func protocolCompatStatus(table *compat.Table) map[string]interface{} {
+	families := table.Status()
+	legacyPeers := make(map[string][]string)
+	for _, f := range families {
+		for _, v := range f.Versions {
+			if v.Legacy {
+				legacyPeers[v.ID] = table.Peers(f.Name, v.ID, maxLegacyPeersListed)
+			}
+		}
+	}
+	return map[string]interface{}{
+		"families":     families,
+		"legacy_peers": legacyPeers,
+	}
+}
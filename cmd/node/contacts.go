@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+)
+
+// apiContact 把通讯录联系人转换为 HTTP API 类型
+func apiContact(c *mailbox.Contact) *httpapi.MailboxContact {
+	if c == nil {
+		return nil
+	}
+	return &httpapi.MailboxContact{
+		NodeID:     c.NodeID,
+		Label:      c.Label,
+		Notes:      c.Notes,
+		Trust:      string(c.Trust),
+		Encryption: string(c.Encryption),
+		CreatedAt:  c.CreatedAt.Unix(),
+		UpdatedAt:  c.UpdatedAt.Unix(),
+	}
+}
+
+// apiRecipientCheck 把发信检查结果转换为 HTTP API 类型，联系人有加密偏好时带上 Encrypt
+func apiRecipientCheck(check *mailbox.RecipientCheck) *httpapi.MailboxRecipientCheck {
+	result := &httpapi.MailboxRecipientCheck{
+		NodeID:      check.NodeID,
+		Contact:     apiContact(check.Contact),
+		Reputation:  check.Reputation,
+		Quarantined: check.Quarantined,
+		Warnings:    make([]httpapi.MailboxRecipientWarning, 0, len(check.Warnings)),
+	}
+	if check.Contact != nil && check.Contact.Encryption != mailbox.EncryptionDefault {
+		encrypt := check.Contact.Encrypt(false)
+		result.Encrypt = &encrypt
+	}
+	for _, w := range check.Warnings {
+		result.Warnings = append(result.Warnings, httpapi.MailboxRecipientWarning{Code: w.Code, Message: w.Message})
+	}
+	return result
+}
+
+// contactAPIError 把通讯录错误映射为 HTTP API 的错误类别（404/400）
+func contactAPIError(err error) error {
+	if errors.Is(err, mailbox.ErrContactNotFound) {
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	}
+	return err
+}
+
+// mailboxContact 把 HTTP API 的联系人转换为通讯录联系人，时间戳由邮箱维护
+func mailboxContact(c *httpapi.MailboxContact) mailbox.Contact {
+	return mailbox.Contact{
+		NodeID:     c.NodeID,
+		Label:      c.Label,
+		Notes:      c.Notes,
+		Trust:      mailbox.TrustLevel(c.Trust),
+		Encryption: mailbox.EncryptionPreference(c.Encryption),
+	}
+}
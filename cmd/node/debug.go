@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// profileEndpoints 支持的 profile 类型及其管理后台路径
+var profileEndpoints = map[string]string{
+	"cpu":       "/debug/pprof/profile",
+	"heap":      "/debug/pprof/heap",
+	"goroutine": "/debug/pprof/goroutine",
+	"block":     "/debug/pprof/block",
+	"mutex":     "/debug/pprof/mutex",
+	"allocs":    "/debug/pprof/allocs",
+	"trace":     "/debug/pprof/trace",
+}
+
+func cmdDebug() {
+	if len(os.Args) < 3 {
+		printDebugUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	switch subCmd {
+	case "profile":
+		fs := flag.NewFlagSet("debug profile", flag.ExitOnError)
+		dataDir := fs.String("data", "./data", "数据目录")
+		adminAddr := fs.String("admin", ":18080", "管理后台地址")
+		token := fs.String("token", "", "管理后台访问令牌（默认读取数据目录中的令牌）")
+		profileType := fs.String("type", "cpu", "profile 类型: cpu, heap, goroutine, block, mutex, allocs, trace")
+		duration := fs.Duration("d", 30*time.Second, "采样时长（仅 cpu 和 trace）")
+		output := fs.String("o", "", "输出文件（默认: <类型>-<时间>.pprof）")
+		fs.Parse(os.Args[3:])
+
+		if *token == "" {
+			t, err := readAdminToken(*dataDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "读取管理令牌失败: %v\n", err)
+				os.Exit(1)
+			}
+			*token = t
+		}
+		path, err := fetchProfile(*adminAddr, *token, *profileType, *duration, *output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取 profile 失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("profile 已保存: %s\n", path)
+		if *profileType == "trace" {
+			fmt.Printf("查看: go tool trace %s\n", path)
+		} else {
+			fmt.Printf("查看: go tool pprof %s\n", path)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printDebugUsage()
+		os.Exit(1)
+	}
+}
+
+func printDebugUsage() {
+	fmt.Print(`用法: agentnetwork debug <子命令> [选项]
+
+子命令:
+  profile   从运行中的节点抓取运行时 profile
+
+选项:
+  -data     数据目录，用于读取访问令牌 (默认: ./data)
+  -token    管理后台访问令牌，节点以 -admin-token 启动时使用 (默认读取数据目录)
+  -admin    管理后台地址 (默认: :18080)
+  -type     profile 类型: cpu, heap, goroutine, block, mutex, allocs, trace (默认: cpu)
+  -d        采样时长，仅 cpu 和 trace 有效 (默认: 30s)
+  -o        输出文件
+
+示例:
+  agentnetwork debug profile -d 30s
+  agentnetwork debug profile -type heap -o heap.pprof
+  agentnetwork debug profile -type trace -d 5s
+`)
+}
+
+// readAdminToken 读取节点启动时加载或生成的管理令牌，文件不存在时报错而不生成新令牌：
+// 新令牌只会写入数据目录，运行中的节点并不认可
+func readAdminToken(dataDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, "admin_token"))
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("管理令牌为空")
+	}
+	return token, nil
+}
+
+// fetchProfile 通过管理后台下载 profile 并保存到文件，返回文件路径
+func fetchProfile(adminAddr, token, profileType string, duration time.Duration, output string) (string, error) {
+	endpoint, ok := profileEndpoints[profileType]
+	if !ok {
+		return "", fmt.Errorf("不支持的 profile 类型: %s", profileType)
+	}
+
+	url := profileURL(adminAddr, endpoint, profileType, duration)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: duration + 30*time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("HTTP状态码: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if output == "" {
+		ext := "pprof"
+		if profileType == "trace" {
+			ext = "out"
+		}
+		output = fmt.Sprintf("%s-%s.%s", profileType, time.Now().Format("20060102-150405"), ext)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// profileURL 构造 profile 下载地址
+func profileURL(adminAddr, endpoint, profileType string, duration time.Duration) string {
+	host := adminAddr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	url := "http://" + host + endpoint
+	if profileType == "cpu" || profileType == "trace" {
+		seconds := int(duration.Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		url += fmt.Sprintf("?seconds=%d", seconds)
+	}
+	return url
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/webadmin"
+)
+
+// runDemo 以演示模式运行管理后台：不启动 P2P 节点，所有数据由固定种子生成，
+// 供前端开发和 e2e 测试使用。
+func runDemo(cf *commonFlags, seed int64) {
+	demoConfig := webadmin.DefaultDemoConfig()
+	demoConfig.Seed = seed
+
+	adminToken := cf.adminToken
+	if adminToken == "" {
+		adminToken = "demo"
+	}
+
+	nodeInfo := webadmin.NewDemoNodeInfoProvider(demoConfig, version)
+	nodeInfo.SetPorts(0, extractPort(cf.httpAddr), extractPort(cf.grpcAddr), extractPort(cf.adminAddr))
+
+	adminServer := webadmin.New(&webadmin.Config{
+		ListenAddr: cf.adminAddr,
+		AdminToken: adminToken,
+		EnableCORS: true, // 前端开发服务器通常在其他端口
+	}, nodeInfo)
+	ops := webadmin.NewDemoOperationsProvider(demoConfig)
+	adminServer.SetOperationsProvider(ops)
+	adminServer.SetExtendedOperationsProvider(ops)
+
+	if err := adminServer.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "启动管理后台失败: %v\n", err)
+		os.Exit(1)
+	}
+	nodeInfo.Start(adminServer)
+
+	fmt.Printf("演示模式已启动（seed=%d，不连接 P2P 网络）\n", seed)
+	fmt.Printf("  管理后台: %s\n", adminServer.GetAdminURL())
+	fmt.Printf("  访问令牌: %s\n", adminToken)
+	fmt.Println("\n按 Ctrl+C 停止...")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+
+	nodeInfo.Stop()
+	adminServer.Stop()
+}
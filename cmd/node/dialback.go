@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/host"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/identity"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/node"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reachability"
+)
+
+// reachabilityDialBack 可达性回拨测试
+// 用临时身份的独立节点新建连接拨号对方登记的直连地址，不复用本节点已有的连接
+// （已有连接可能是对方主动发起的，不能说明对方可被拨通），也不经过中继。
+func reachabilityDialBack(n *node.Node) reachability.DialBackFunc {
+	return func(ctx context.Context, subject string) ([]string, time.Duration, error) {
+		id, err := peer.Decode(subject)
+		if err != nil {
+			return nil, 0, err
+		}
+		var addrs []multiaddr.Multiaddr
+		var tried []string
+		for _, addr := range n.Host().Host().Peerstore().Addrs(id) {
+			if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+				continue
+			}
+			addrs = append(addrs, addr)
+			tried = append(tried, addr.String())
+		}
+		if len(addrs) == 0 {
+			return nil, 0, errors.New("no direct addresses known for peer")
+		}
+
+		probeID, err := identity.NewIdentity()
+		if err != nil {
+			return tried, 0, err
+		}
+		probe, err := host.New(&host.Config{
+			Identity:    probeID,
+			ListenAddrs: []string{"/ip4/127.0.0.1/tcp/0"},
+			Role:        host.RoleNormal,
+		})
+		if err != nil {
+			return tried, 0, err
+		}
+		defer probe.Stop()
+
+		start := time.Now()
+		if err := probe.Connect(ctx, peer.AddrInfo{ID: id, Addrs: addrs}); err != nil {
+			return tried, 0, err
+		}
+		latency := time.Since(start)
+
+		var connected []string
+		for _, conn := range probe.Host().Network().ConnsToPeer(id) {
+			connected = append(connected, conn.RemoteMultiaddr().String())
+		}
+		return connected, latency, nil
+	}
+}
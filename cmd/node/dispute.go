@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/snapshot"
+)
+
+// disputeStatuses 列出争议时依次遍历的状态
+var disputeStatuses = []dispute.DisputeStatus{
+	dispute.DisputePending, dispute.DisputeInReview, dispute.DisputeArbitration,
+	dispute.DisputeResolved, dispute.DisputeDismissed, dispute.DisputeExpired,
+}
+
+// disputeService 把争议管理器适配为 HTTP API 的争议预审服务
+// 本节点作为申诉方立案和举证；关联托管时校验双方都是托管参与方，锁定中的托管随之转入争议。
+// 立案和托管转入争议通过 snapshots 作为一次跨存储写入，联合查询不会看到只完成一半的状态。
+type disputeService struct {
+	m         *dispute.DisputeManager
+	escrows   *escrow.EscrowManager
+	snapshots *snapshot.Reader // 为 nil 时不协调
+	self      string
+}
+
+func (s disputeService) ListDisputes(status string) []map[string]interface{} {
+	statuses := disputeStatuses
+	if status != "" {
+		statuses = []dispute.DisputeStatus{dispute.DisputeStatus(status)}
+	}
+	var list []*dispute.Dispute
+	for _, st := range statuses {
+		list = append(list, s.m.GetDisputesByStatus(st)...)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, d := range list {
+		result = append(result, toMap(d))
+	}
+	return result
+}
+
+func (s disputeService) GetDispute(disputeID string) (map[string]interface{}, error) {
+	d, err := s.m.GetDispute(disputeID)
+	if err != nil {
+		return nil, disputeAPIError(err)
+	}
+	return toMap(d), nil
+}
+
+func (s disputeService) FileDispute(req httpapi.DisputeFileRequest) (map[string]interface{}, error) {
+	disputeType := dispute.DisputeType(req.Type)
+	if req.EscrowID == "" {
+		d, err := s.m.CreateDispute(req.TaskID, s.self, req.Defendant, disputeType, req.Description, req.Amount)
+		if err != nil {
+			return nil, disputeAPIError(err)
+		}
+		return toMap(d), nil
+	}
+
+	if s.escrows == nil {
+		return nil, errors.New("escrow not available")
+	}
+	e, err := s.escrows.GetEscrow(req.EscrowID)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	for _, party := range []string{s.self, req.Defendant} {
+		if !escrowParticipant(e, party) {
+			return nil, fmt.Errorf("%w: %s is not a participant of escrow %s", httpapi.ErrUnauthorized, party, e.ID)
+		}
+	}
+	taskID := req.TaskID
+	if taskID == "" {
+		taskID = e.TaskID
+	}
+	var d *dispute.Dispute
+	err = s.update(func() error {
+		var err error
+		d, err = s.m.CreateEscrowDispute(e.ID, taskID, s.self, req.Defendant, disputeType, req.Description, req.Amount)
+		if err != nil {
+			return disputeAPIError(err)
+		}
+		if e.Status == escrow.EscrowLocked {
+			if err := s.escrows.Dispute(e.ID, s.self, req.Description); err != nil {
+				return escrowAPIError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toMap(d), nil
+}
+
+func (s disputeService) update(fn func() error) error {
+	if s.snapshots == nil {
+		return fn()
+	}
+	return s.snapshots.Update(fn)
+}
+
+func (s disputeService) AttachEvidence(disputeID, evidenceType, description string, data []byte) (map[string]interface{}, error) {
+	evidence, err := s.m.AttachEvidence(disputeID, s.self, evidenceType, description, data)
+	if err != nil {
+		return nil, disputeAPIError(err)
+	}
+	return toMap(evidence), nil
+}
+
+func (s disputeService) VerifyEvidence(disputeID, evidenceID, verifierID string) error {
+	if verifierID == "" {
+		verifierID = s.self
+	}
+	return disputeAPIError(s.m.VerifyEvidence(disputeID, evidenceID, verifierID))
+}
+
+func (s disputeService) Suggestion(disputeID string) (map[string]interface{}, error) {
+	suggestion, err := s.m.Suggest(disputeID)
+	if err != nil {
+		return nil, disputeAPIError(err)
+	}
+	return toMap(suggestion), nil
+}
+
+func (s disputeService) ApplySuggestion(disputeID, approverID string) (map[string]interface{}, error) {
+	if approverID == "" {
+		approverID = s.self
+	}
+	resolution, err := s.m.ApplySuggestion(disputeID, approverID)
+	if err != nil {
+		return nil, disputeAPIError(err)
+	}
+	return map[string]interface{}{
+		"applied":    true,
+		"dispute_id": disputeID,
+		"resolution": toMap(resolution),
+	}, nil
+}
+
+// proposeEscrowResolution 争议裁决后为关联的托管提出结算方案，仲裁者对方案签名后放款
+func proposeEscrowResolution(escrows *escrow.EscrowManager, self string) func(d *dispute.Dispute) {
+	return func(d *dispute.Dispute) {
+		if d.EscrowID == "" || d.Resolution == nil || d.Resolution.Winner == "" {
+			return
+		}
+		if _, err := escrows.ProposeResolution(d.EscrowID, d.Resolution.Winner, d.Resolution.AmountToWinner, self); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  争议 %s 的裁决未能提交给托管 %s: %v\n", d.ID, d.EscrowID, err)
+		}
+	}
+}
+
+func escrowParticipant(e *escrow.Escrow, nodeID string) bool {
+	for _, p := range e.Participants {
+		if p == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// disputeAPIError 把争议错误映射为 HTTP API 的错误类别（404/403）
+func disputeAPIError(err error) error {
+	switch {
+	case errors.Is(err, dispute.ErrDisputeNotFound):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, dispute.ErrUnauthorized), errors.Is(err, dispute.ErrNotChannelMember):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
+
+// disputeChannel 以本节点为成员使用调解频道：本节点保存的争议直接读写，
+// 其他节点（申诉方）保存的争议经 dispute.ChannelProtocol 交给该节点处理
+type disputeChannel struct {
+	m    *dispute.DisputeManager
+	self string
+	dial func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) // 打开到保存争议节点的流
+}
+
+func (c disputeChannel) post(disputeID, holder, content string) (map[string]interface{}, error) {
+	if c.local(holder) {
+		entry, err := c.m.PostMessage(disputeID, c.self, content)
+		if err != nil {
+			return nil, disputeAPIError(err)
+		}
+		return toMap(entry), nil
+	}
+	resp, err := c.request(holder, &dispute.ChannelRequest{DisputeID: disputeID, Content: content})
+	if err != nil {
+		return nil, err
+	}
+	return toMap(resp.Entry), nil
+}
+
+func (c disputeChannel) transcript(disputeID, holder string) (map[string]interface{}, error) {
+	var messages []dispute.TranscriptMessage
+	if c.local(holder) {
+		var err error
+		if messages, err = c.m.ReadTranscript(disputeID, c.self); err != nil {
+			return nil, disputeAPIError(err)
+		}
+	} else {
+		resp, err := c.request(holder, &dispute.ChannelRequest{DisputeID: disputeID})
+		if err != nil {
+			return nil, err
+		}
+		messages = resp.Messages
+	}
+	if messages == nil {
+		messages = []dispute.TranscriptMessage{}
+	}
+	return map[string]interface{}{
+		"dispute_id": disputeID,
+		"messages":   messages,
+		"total":      len(messages),
+	}, nil
+}
+
+func (c disputeChannel) local(holder string) bool {
+	return holder == "" || holder == c.self
+}
+
+func (c disputeChannel) request(holder string, req *dispute.ChannelRequest) (*dispute.ChannelResponse, error) {
+	if c.dial == nil {
+		return nil, fmt.Errorf("dispute %s is not held by this node", req.DisputeID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	s, err := c.dial(ctx, holder)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	resp, err := dispute.RequestChannel(s, req)
+	if err != nil {
+		return nil, disputeAPIError(err)
+	}
+	return resp, nil
+}
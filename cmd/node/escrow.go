@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// escrowStatuses 列出托管时依次遍历的状态
+var escrowStatuses = []escrow.EscrowStatus{
+	escrow.EscrowPending, escrow.EscrowLocked, escrow.EscrowDisputed,
+	escrow.EscrowReleased, escrow.EscrowRefunded, escrow.EscrowForfeited,
+}
+
+// escrowService 把托管管理器适配为 HTTP API 的托管服务
+// 存入押金和仲裁签名未给出签名时，以本节点身份对相应载荷签名。
+type escrowService struct {
+	m    *escrow.EscrowManager
+	self string
+	sign func(data []byte) (string, error)
+}
+
+func (s escrowService) ListEscrows(status string) []map[string]interface{} {
+	statuses := escrowStatuses
+	if status != "" {
+		statuses = []escrow.EscrowStatus{escrow.EscrowStatus(status)}
+	}
+	var list []*escrow.Escrow
+	for _, st := range statuses {
+		list = append(list, s.m.GetEscrowsByStatus(st)...)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, e := range list {
+		result = append(result, toMap(e))
+	}
+	return result
+}
+
+func (s escrowService) GetEscrow(escrowID string) (map[string]interface{}, error) {
+	e, err := s.m.GetEscrow(escrowID)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return toMap(e), nil
+}
+
+func (s escrowService) CreateEscrow(taskID string, deposits map[string]float64) (map[string]interface{}, error) {
+	e, err := s.m.CreateEscrow(taskID, deposits)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return toMap(e), nil
+}
+
+func (s escrowService) Deposit(escrowID, nodeID string, amount float64, signature string) (map[string]interface{}, error) {
+	if nodeID == "" {
+		nodeID = s.self
+	}
+	if signature == "" {
+		if nodeID != s.self {
+			return nil, errors.New("signature is required when depositing for another node")
+		}
+		sig, err := s.sign(escrow.DepositPayload(escrowID, nodeID, amount))
+		if err != nil {
+			return nil, err
+		}
+		signature = sig
+	}
+	if err := s.m.Deposit(escrowID, nodeID, amount, signature); err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return s.GetEscrow(escrowID)
+}
+
+// SetArbitrators 本节点是托管参与方且未给出本节点签名时，以本节点身份对更换签名
+func (s escrowService) SetArbitrators(escrowID string, arbitrators []string, threshold int, signatures map[string]string) (map[string]interface{}, error) {
+	current, err := s.m.GetEscrow(escrowID)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	if _, signed := signatures[s.self]; !signed && slices.Contains(current.Participants, s.self) {
+		sig, err := s.sign(escrow.ArbitratorsPayload(escrowID, arbitrators, threshold))
+		if err != nil {
+			return nil, err
+		}
+		merged := map[string]string{s.self: sig}
+		for id, sig := range signatures {
+			merged[id] = sig
+		}
+		signatures = merged
+	}
+	e, err := s.m.SetArbitrators(escrowID, arbitrators, threshold, signatures)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return toMap(e), nil
+}
+
+func (s escrowService) Dispute(escrowID, reason string) (map[string]interface{}, error) {
+	if err := s.m.Dispute(escrowID, s.self, reason); err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return s.GetEscrow(escrowID)
+}
+
+func (s escrowService) ProposeResolution(escrowID, winner string, amount float64) (map[string]interface{}, error) {
+	res, err := s.m.ProposeResolution(escrowID, winner, amount, s.self)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	result := toMap(res)
+	result["escrow_id"] = escrowID
+	result["payload"] = string(escrow.ResolutionPayload(escrowID, res.Winner, res.Amount))
+	return result, nil
+}
+
+func (s escrowService) SubmitArbitratorSignature(escrowID, arbitratorID, signature string) (map[string]interface{}, error) {
+	if signature == "" {
+		e, err := s.m.GetEscrow(escrowID)
+		if err != nil {
+			return nil, escrowAPIError(err)
+		}
+		if e.Resolution == nil {
+			return nil, escrow.ErrNoResolution
+		}
+		if arbitratorID != "" && arbitratorID != s.self {
+			return nil, errors.New("signature is required when submitting for another arbitrator")
+		}
+		arbitratorID = s.self
+		if signature, err = s.sign(escrow.ResolutionPayload(escrowID, e.Resolution.Winner, e.Resolution.Amount)); err != nil {
+			return nil, err
+		}
+	}
+	if arbitratorID == "" {
+		return nil, errors.New("arbitrator_id is required with a signature")
+	}
+
+	resolved, err := s.m.SubmitArbitratorSignature(escrowID, arbitratorID, signature)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	result, err := s.SignatureCount(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	result["submitted"] = true
+	result["resolved"] = resolved
+	return result, nil
+}
+
+func (s escrowService) SignatureCount(escrowID string) (map[string]interface{}, error) {
+	count, required, err := s.m.GetArbitratorSignatureCount(escrowID)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	signers, _ := s.m.GetArbitratorSigners(escrowID)
+	if signers == nil {
+		signers = []string{}
+	}
+	return map[string]interface{}{
+		"escrow_id":     escrowID,
+		"current_count": count,
+		"required":      required,
+		"signers":       signers,
+	}, nil
+}
+
+func (s escrowService) Resolve(escrowID, winner string, amount float64, signatures map[string]string) (map[string]interface{}, error) {
+	if err := s.m.ResolveDispute(escrowID, winner, amount, signatures); err != nil {
+		return nil, escrowAPIError(err)
+	}
+	e, err := s.m.GetEscrow(escrowID)
+	if err != nil {
+		return nil, escrowAPIError(err)
+	}
+	return map[string]interface{}{
+		"escrow_id": escrowID,
+		"resolved":  true,
+		"winner":    e.ReleasedTo,
+		"amount":    e.ReleasedAmount,
+	}, nil
+}
+
+// escrowAPIError 把托管错误映射为 HTTP API 的错误类别（404/403）
+func escrowAPIError(err error) error {
+	switch {
+	case errors.Is(err, escrow.ErrEscrowNotFound):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, escrow.ErrUnauthorized), errors.Is(err, escrow.ErrNotArbitrator), errors.Is(err, escrow.ErrInvalidSignature):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/feature"
+)
+
+// featureStatusMap 把特性激活状态转换为 HTTP API 响应
+func featureStatusMap(status *feature.Status) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":        status.Name,
+		"state":       status.State,
+		"threshold":   status.Threshold,
+		"ready":       status.Ready,
+		"supernodes":  status.Supernodes,
+		"ratio":       status.Ratio,
+		"local_ready": status.LocalReady,
+	}
+	if status.Description != "" {
+		result["description"] = status.Description
+	}
+	if !status.ActivatedAt.IsZero() {
+		result["activated_at"] = status.ActivatedAt.Format(time.RFC3339)
+	}
+	if len(status.ReadyNodes) > 0 {
+		result["ready_nodes"] = status.ReadyNodes
+	}
+	return result
+}
+
+// activeFeatures 已在全网激活的特性名称，写入节点清单
+func activeFeatures(statuses []*feature.Status) []string {
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if status.State == feature.StateActive {
+			names = append(names, status.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/genesis"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// genesisGraphPath 邀请树 API 路径
+const genesisGraphPath = "/api/v1/genesis/invite/graph"
+
+// genesisJoinTopic 加入记录的广播主题：各节点按邀请函和受邀方签名在本地重放，邀请树和准入在全网一致
+const genesisJoinTopic = "/daan/genesis/join/1.0.0"
+
+// genesisError 把创世错误映射为 API 的 404/403
+func genesisError(err error) error {
+	switch {
+	case errors.Is(err, genesis.ErrGenesisNotFound), errors.Is(err, genesis.ErrNodeNotFound):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, genesis.ErrInviterNotTrusted):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
+
+// startGenesisGossip 接收其他节点登记的加入，重新校验邀请函和受邀方签名后记入本地邀请树；
+// 邀请人尚未在本地登记或邀请函已使用的记录被丢弃
+func startGenesisGossip(gm *genesis.GenesisManager, transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var req genesis.JoinRequest
+		return json.Unmarshal(data, &req) == nil && req.Invitation != nil && req.PeerID != "" && req.Signature != ""
+	}
+	return transport.Subscribe(genesisJoinTopic, validate, func(data []byte, from string) {
+		var req genesis.JoinRequest
+		if json.Unmarshal(data, &req) == nil {
+			gm.ProcessJoinRequest(&req)
+		}
+	})
+}
+
+// registerGenesisAPI 把创世与邀请 API 接到创世管理器：
+// 邀请码是签名后编码的邀请函，绑定受邀节点公钥和 libp2p 节点ID，过期或使用一次后失效。
+// transport 非空时广播本节点登记的加入。
+func registerGenesisAPI(s *httpapi.Server, gm *genesis.GenesisManager, transport gossipTransport) {
+	s.GenesisInfoFunc = func() map[string]interface{} {
+		info := map[string]interface{}{}
+		if g := gm.GetGenesis(); g != nil {
+			info = toMap(g)
+			info["joined_nodes"] = len(gm.GetJoinedNodes())
+		}
+		info["initialized"] = gm.GetGenesis() != nil
+		info["node_id"] = gm.GetNodeID()
+		info["public_key"] = gm.GetPublicKeyHex()
+		return info
+	}
+	s.GenesisCreateInviteFunc = func(forPubkey, peerID string) (string, error) {
+		inv, err := gm.CreateInvitation(forPubkey, peerID)
+		if err != nil {
+			return "", genesisError(err)
+		}
+		return genesis.EncodeInvitation(inv)
+	}
+	s.GenesisVerifyInviteFunc = func(code string) (bool, string, error) {
+		inv, err := genesis.DecodeInvitation(code)
+		if err != nil {
+			return false, "", err
+		}
+		if err := gm.VerifyInvitation(inv); err != nil {
+			return false, inv.InviterNodeID, err
+		}
+		return true, inv.InviterNodeID, nil
+	}
+	s.GenesisJoinFunc = func(req *httpapi.GenesisJoinRequest) (string, []string, error) {
+		join, err := genesis.NewJoinRequest(req.Invitation, req.Pubkey, req.PeerID, req.Signature)
+		if err != nil {
+			return "", nil, err
+		}
+		resp, err := gm.ProcessJoinRequest(join)
+		if err != nil {
+			return "", nil, genesisError(err)
+		}
+		if transport != nil {
+			if data, err := json.Marshal(join); err == nil {
+				if err := transport.Publish(genesisJoinTopic, data); err != nil {
+					fmt.Printf("⚠️  加入记录广播失败: %v\n", err)
+				}
+			}
+		}
+		neighbors := make([]string, 0, len(resp.Neighbors))
+		for _, n := range resp.Neighbors {
+			neighbors = append(neighbors, n.NodeID)
+		}
+		return resp.NodeID, neighbors, nil
+	}
+	s.GenesisGraphFunc = func(root string) (map[string]interface{}, error) {
+		graph, err := gm.InvitationGraph(root)
+		if err != nil {
+			return nil, genesisError(err)
+		}
+		return toMap(graph), nil
+	}
+}
+
+func cmdGenesis() {
+	if len(os.Args) < 3 {
+		printGenesisUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	fs := flag.NewFlagSet("genesis "+subCmd, flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
+	network := fs.String("network", "DAAN", "网络名称（init）")
+	networkVersion := fs.String("network-version", version, "网络版本（init）")
+	pubkey := fs.String("pubkey", "", "受邀节点的公钥，由对方执行 genesis key 得到（invite）")
+	peerID := fs.String("peer", "", "受邀节点的 libp2p 节点ID，由对方执行 genesis key 得到（invite）")
+	code := fs.String("code", "", "邀请码（join）")
+	root := fs.String("root", "", "只显示该节点邀请的子树（graph）")
+	jsonOutput := fs.Bool("json", false, "JSON格式输出（graph）")
+	fs.Parse(os.Args[3:])
+
+	openManager := func() *genesis.GenesisManager {
+		gm, err := genesis.NewGenesisManager(filepath.Join(*dataDir, "genesis"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "打开创世数据失败: %v\n", err)
+			os.Exit(1)
+		}
+		return gm
+	}
+
+	switch subCmd {
+	case "init":
+		g, err := openManager().InitGenesis(*network, *networkVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "初始化创世信息失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("======== 创世信息 ========")
+		fmt.Printf("网络:       %s %s\n", g.NetworkName, g.NetworkVersion)
+		fmt.Printf("创世节点ID: %s\n", g.GenesisNodeID)
+		fmt.Printf("创世公钥:   %s\n", g.GenesisKey)
+		fmt.Printf("邀请有效期: %d 小时\n", g.InvitationValidHours)
+		fmt.Println("==========================")
+		fmt.Println("节点运行中时需重启后生效")
+	case "key":
+		gm := openManager()
+		pub, err := gm.EnsureKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "生成密钥失败: %v\n", err)
+			os.Exit(1)
+		}
+		peer, err := nodeKeyPeerID(filepath.Join(*dataDir, "keys", "node.key"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取节点密钥失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("节点ID: %s\n", gm.GetNodeID())
+		fmt.Printf("公钥:   %s\n", pub)
+		fmt.Printf("libp2p: %s\n", peer)
+		fmt.Println("把公钥和 libp2p 节点ID交给邀请人签发邀请码")
+	case "invite":
+		if *pubkey == "" || *peerID == "" {
+			fmt.Fprintln(os.Stderr, "错误: 需要 -pubkey 和 -peer")
+			os.Exit(1)
+		}
+		data, err := nodeAPIRequest(*httpAddr, loadOrGenerateToken(*dataDir), http.MethodPost, "/api/v1/genesis/invite/create",
+			httpapi.GenesisInviteRequest{ForPubkey: *pubkey, PeerID: *peerID}, nodeRequestSigner(filepath.Join(*dataDir, "keys", "node.key")))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "签发邀请失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(data["invitation_id"])
+	case "join":
+		if *code == "" {
+			fmt.Fprintln(os.Stderr, "错误: 需要 -code")
+			os.Exit(1)
+		}
+		req, err := genesisJoinRequest(openManager(), *code, filepath.Join(*dataDir, "keys", "node.key"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "签名加入请求失败: %v\n", err)
+			os.Exit(1)
+		}
+		out, _ := json.MarshalIndent(req, "", "  ")
+		fmt.Println(string(out))
+		fmt.Fprintln(os.Stderr, "把以上请求交给已加入的节点，由其提交到 POST /api/v1/genesis/join")
+	case "graph":
+		path := genesisGraphPath
+		if *root != "" {
+			path += "?root=" + url.QueryEscape(*root)
+		}
+		data, err := nodeAPIRequest(*httpAddr, loadOrGenerateToken(*dataDir), http.MethodGet, path, nil, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "获取邀请树失败: %v\n", err)
+			os.Exit(1)
+		}
+		if *jsonOutput {
+			out, _ := json.MarshalIndent(data, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		raw, _ := json.Marshal(data)
+		var graph genesis.InvitationGraph
+		if err := json.Unmarshal(raw, &graph); err != nil {
+			fmt.Fprintf(os.Stderr, "解析邀请树失败: %v\n", err)
+			os.Exit(1)
+		}
+		printInvitationGraph(&graph)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printGenesisUsage()
+		os.Exit(1)
+	}
+}
+
+// genesisJoinRequest 受邀方用创世私钥为邀请码签名，生成提交给登记节点的加入请求
+func genesisJoinRequest(gm *genesis.GenesisManager, code, keyPath string) (*httpapi.GenesisJoinRequest, error) {
+	peer, err := nodeKeyPeerID(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := gm.SignJoin(code, peer)
+	if err != nil {
+		return nil, err
+	}
+	return &httpapi.GenesisJoinRequest{
+		Invitation: code,
+		Pubkey:     gm.GetPublicKeyHex(),
+		PeerID:     peer,
+		Signature:  signature,
+	}, nil
+}
+
+// printInvitationGraph 按邀请关系缩进输出邀请树
+func printInvitationGraph(graph *genesis.InvitationGraph) {
+	children := make(map[string][]genesis.GraphNode)
+	for _, n := range graph.Nodes {
+		if n.Depth > 0 {
+			children[n.InviterID] = append(children[n.InviterID], n)
+		}
+	}
+	var show func(n genesis.GraphNode)
+	show = func(n genesis.GraphNode) {
+		fmt.Printf("%s%s  声誉 %d，直接邀请 %d，子树 %d\n", strings.Repeat("  ", n.Depth), n.NodeID, n.Reputation, n.Invited, n.Descendants)
+		for _, c := range children[n.NodeID] {
+			show(c)
+		}
+	}
+	for _, n := range graph.Nodes {
+		if n.Depth == 0 {
+			show(n)
+		}
+	}
+	fmt.Printf("节点 %d，最大深度 %d；邀请函: 待使用 %d，已使用 %d，已过期 %d\n",
+		len(graph.Nodes), graph.MaxDepth, graph.Pending, graph.Used, graph.Expired)
+}
+
+func printGenesisUsage() {
+	fmt.Print(`用法: agentnetwork genesis <子命令> [选项]
+
+创世信息与邀请制加入：邀请码由已加入且声誉足够的节点签发，绑定受邀节点公钥和 libp2p 节点ID，
+过期或使用一次后失效。受邀方用创世私钥签名加入请求，加入记录广播到全网，构成邀请树（谁邀请了谁），
+可用于女巫分析；开启入站准入时，已加入的节点可以连入。
+
+子命令:
+  init     初始化创世信息，本节点成为创世节点（只能执行一次）
+  key      显示本节点的创世公钥和 libp2p 节点ID，没有密钥时生成
+  invite   为受邀节点签发邀请码（需要 admin 权限）
+  join     受邀方签名加入请求，交给已加入的节点提交
+  graph    查看邀请树
+
+选项:
+  -data             数据目录 (默认: ./data)
+  -http             HTTP服务地址 (默认: :18345)
+  -network          网络名称（init，默认: DAAN）
+  -network-version  网络版本（init，默认: 程序版本）
+  -pubkey           受邀节点公钥（invite）
+  -peer             受邀节点 libp2p 节点ID（invite）
+  -code             邀请码（join）
+  -root             只显示该节点邀请的子树（graph）
+  -json             JSON格式输出（graph）
+
+示例:
+  agentnetwork genesis init -network DAAN
+  agentnetwork genesis key -data ./newnode
+  agentnetwork genesis invite -pubkey 03a1... -peer 12D3KooW...
+  agentnetwork genesis join -data ./newnode -code eyJpZCI6...
+  agentnetwork genesis graph -root 5f2c...
+`)
+}
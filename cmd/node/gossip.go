@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/network"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/quota"
+	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// bulletinGossip 把 GossipSub 广播器适配为留言板的广播通道
+// 回调中的 from 是 GossipSub 验签过的原始发布节点，而非消息中自行声明的发送方。
+type bulletinGossip struct {
+	b     *network.Broadcaster
+	admit func(topic, from string) bool // 按主题和原始发布节点的准入检查（节点间配额），nil 表示不检查
+}
+
+// gossipUsage 计入节点间配额的广播主题：留言（含 v1 主题）和新发布的任务报价，其余主题不计
+func gossipUsage(topic string) (quota.Usage, bool) {
+	if strings.HasPrefix(topic, bulletin.LegacyGossipTopicPrefix) {
+		return quota.UsageBulletin, true
+	}
+	if topic == taskOfferTopic {
+		return quota.UsageTaskOffer, true
+	}
+	return "", false
+}
+
+// newBulletinGossip 创建自适应调参的广播器，主题按网络命名空间隔离
+// 节点评分按声誉、无效留言和配额状态计算，低分节点被移出 mesh。
+func newBulletinGossip(h host.Host, ns string, reputation func(peerID string) float64, quotaState func(peerID string) (throttled, banned bool)) (*bulletinGossip, error) {
+	scorer := network.NewGossipScorer(nil)
+	scorer.SetReputationFunc(reputation)
+	scorer.SetQuotaFunc(quotaState)
+	b, err := network.NewScoredBroadcaster(h, nil, scorer)
+	if err != nil {
+		return nil, err
+	}
+	b.SetNamespace(ns)
+	return &bulletinGossip{b: b}, nil
+}
+
+func (g *bulletinGossip) Publish(topic string, data []byte) error {
+	return g.b.Broadcast(topic, data)
+}
+
+func (g *bulletinGossip) Subscribe(topic string, validate func(data []byte) bool, handler func(data []byte, from string)) error {
+	return g.b.SubscribeWithValidator(topic,
+		func(msg *network.BroadcastMessage) bool {
+			if g.admit != nil && !g.admit(topic, msg.From) {
+				return false
+			}
+			return validate(msg.Payload)
+		},
+		func(msg *network.BroadcastMessage) { handler(msg.Payload, msg.From) })
+}
+
+func (g *bulletinGossip) Unsubscribe(topic string) error {
+	return g.b.Unsubscribe(topic)
+}
+
+func (g *bulletinGossip) Stop() {
+	g.b.Stop()
+}
+
+// heartbeatTransport 节点心跳共用留言的广播器，心跳不计入节点间的留言配额
+func (g *bulletinGossip) heartbeatTransport() *bulletinGossip {
+	return &bulletinGossip{b: g.b}
+}
+
+// tuningStatus 自适应调参状态，供 /api/v1/network/gossip-tuning 查询
+func (g *bulletinGossip) tuningStatus() map[string]interface{} {
+	status := g.b.GossipTuning()
+	if status == nil {
+		return nil
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// gossipScores 节点评分，供 /api/v1/network/gossip-scores 查询
+func (g *bulletinGossip) gossipScores(peerID string) map[string]interface{} {
+	scorer := g.b.GossipScorer()
+	if scorer == nil {
+		return nil
+	}
+	if peerID != "" {
+		score, connected := scorer.Score(peerID)
+		result := toMap(score)
+		result["connected"] = connected
+		return result
+	}
+	return map[string]interface{}{
+		"thresholds": scorer.Config().Thresholds,
+		"peers":      scorer.Scores(),
+	}
+}
+
+// signWithNodeKey 用节点私钥签名（留言、托管载荷），签名以 base64 存放
+func signWithNodeKey(priv libp2pcrypto.PrivKey) func(data []byte) (string, error) {
+	return func(data []byte) (string, error) {
+		sig, err := priv.Sign(data)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(sig), nil
+	}
+}
+
+// verifyNodeSignature 按签名者节点ID提取公钥验签（base64 签名）
+func verifyNodeSignature(signer string, data []byte, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	ok, err := verifyPeerSignature(signer, data, sig)
+	return err == nil && ok
+}
+
+// verifyPeerSignature 按签名者节点ID提取公钥验签（原始签名，用于邮件回执、治理消息）
+func verifyPeerSignature(signer string, data, signature []byte) (bool, error) {
+	peerID, err := peer.Decode(signer)
+	if err != nil {
+		return false, err
+	}
+	pubKey, err := peerID.ExtractPublicKey()
+	if err != nil {
+		return false, err
+	}
+	return pubKey.Verify(data, signature)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/voting"
+)
+
+// governanceTopic 提案、投票、委托、金丝雀参与声明和报告的广播主题
+const governanceTopic = "/daan/governance/1.0.0"
+
+// governanceSyncInterval 投票权重（信誉、抵押）的刷新周期
+const governanceSyncInterval = time.Minute
+
+// governanceMessage 治理广播消息，每条只携带其中一项
+type governanceMessage struct {
+	Proposal   *voting.Proposal     `json:"proposal,omitempty"`
+	Vote       *voting.Vote         `json:"vote,omitempty"`
+	Delegation *voting.Delegation   `json:"delegation,omitempty"`
+	Report     *voting.CanaryReport `json:"report,omitempty"`
+	OptIn      *voting.CanaryOptIn  `json:"opt_in,omitempty"`
+}
+
+// gossipTransport GossipSub 上的广播通道，治理消息和邀请都经它传播
+type gossipTransport interface {
+	Publish(topic string, data []byte) error
+	Subscribe(topic string, validate func(data []byte) bool, handler func(data []byte, from string)) error
+}
+
+// votingTrust 投票权重的来源：参与投票的节点及其信誉、抵押
+type votingTrust struct {
+	nodes      func() []string
+	reputation func(nodeID string) float64
+	stake      func(nodeID string) float64
+}
+
+// clampTrust 投票管理器的信誉和抵押分取值 [0, 100]，声誉管理器的分值更大时按上限计
+func clampTrust(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
+}
+
+// syncVotingNodes 把本节点和已知节点登记为投票节点，并刷新各自的信誉和抵押
+func syncVotingNodes(vm *voting.VotingManager, self string, trust votingTrust) {
+	ids := []string{self}
+	if trust.nodes != nil {
+		ids = append(ids, trust.nodes()...)
+	}
+	for _, id := range ids {
+		var rep, stake float64
+		if trust.reputation != nil {
+			rep = clampTrust(trust.reputation(id))
+		}
+		if trust.stake != nil {
+			stake = clampTrust(trust.stake(id))
+		}
+		if err := vm.UpdateNodeTrust(id, rep, stake); err != nil {
+			vm.RegisterNode(id, rep, stake)
+		}
+	}
+}
+
+// governanceEffects 已通过提案作用的子系统
+type governanceEffects struct {
+	neighbors  *neighbor.NeighborManager
+	disconnect func(nodeID string)
+}
+
+// registerGovernanceExecutors 注册剔除、晋升和撤销超级节点提案的执行器：
+// 被剔除的节点移出邻居表并断开连接，晋升和撤销改变邻居表中的节点类型。
+func registerGovernanceExecutors(vm *voting.VotingManager, fx governanceEffects) {
+	vm.RegisterExecutor(voting.VoteKick, func(p *voting.Proposal) error {
+		if fx.neighbors != nil {
+			err := fx.neighbors.RemoveNeighbor(p.TargetNodeID, "kicked by vote "+p.ID)
+			if err != nil && !errors.Is(err, neighbor.ErrNeighborNotFound) {
+				return err
+			}
+		}
+		if fx.disconnect != nil {
+			go fx.disconnect(p.TargetNodeID)
+		}
+		return nil
+	})
+	if fx.neighbors == nil {
+		return
+	}
+	vm.RegisterExecutor(voting.VotePromote, func(p *voting.Proposal) error {
+		return fx.neighbors.SetNeighborType(p.TargetNodeID, neighbor.TypeSuper)
+	})
+	vm.RegisterExecutor(voting.VoteDemote, func(p *voting.Proposal) error {
+		nb, err := fx.neighbors.GetNeighbor(p.TargetNodeID)
+		if err != nil {
+			return err
+		}
+		if nb.Type != neighbor.TypeSuper {
+			return fmt.Errorf("%s is not a supernode", p.TargetNodeID)
+		}
+		return fx.neighbors.SetNeighborType(p.TargetNodeID, neighbor.TypeNormal)
+	})
+}
+
+// votingParameters 可由参数提案修改的投票规则，键为 voting.<name>，取值 (0, 1]
+func votingParameters(cfg *voting.VotingConfig) voting.ParameterApplier {
+	params := map[string]*float64{
+		"voting.pass_threshold":          &cfg.PassThreshold,
+		"voting.supermajority_threshold": &cfg.SupermajorityThreshold,
+		"voting.quorum_threshold":        &cfg.QuorumThreshold,
+		"voting.canary_tolerance":        &cfg.CanaryTolerance,
+	}
+	return func(key, value string) (string, error) {
+		p, ok := params[key]
+		if !ok {
+			return "", fmt.Errorf("unknown parameter %q", key)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v <= 0 || v > 1 {
+			return "", fmt.Errorf("invalid value %q for %s: must be in (0, 1]", value, key)
+		}
+		old := strconv.FormatFloat(*p, 'g', -1, 64)
+		*p = v
+		return old, nil
+	}
+}
+
+// publishGovernance 广播一条治理消息，失败时只打印警告
+func publishGovernance(transport gossipTransport, msg governanceMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if err := transport.Publish(governanceTopic, data); err != nil {
+		fmt.Printf("⚠️  治理消息广播失败: %v\n", err)
+	}
+}
+
+// startGovernance 在广播通道上交换提案、投票、委托和金丝雀报告，并周期同步投票权重，返回停止函数。
+// 委托和金丝雀参与声明没有回调，由发起的一方调用 publishGovernance 广播；
+// 本节点的参与声明随每次同步重新广播，让之后加入的节点也能得知。
+func startGovernance(vm *voting.VotingManager, self string, trust votingTrust, transport gossipTransport) (context.CancelFunc, error) {
+	publish := func(msg governanceMessage) { publishGovernance(transport, msg) }
+	validate := func(data []byte) bool {
+		var msg governanceMessage
+		return json.Unmarshal(data, &msg) == nil &&
+			(msg.Proposal != nil || msg.Vote != nil || msg.Delegation != nil || msg.Report != nil || msg.OptIn != nil)
+	}
+	err := transport.Subscribe(governanceTopic, validate, func(data []byte, from string) {
+		var msg governanceMessage
+		if json.Unmarshal(data, &msg) != nil {
+			return
+		}
+		switch {
+		case msg.Proposal != nil:
+			vm.ReceiveProposal(msg.Proposal)
+		case msg.Vote != nil:
+			vm.ReceiveVote(msg.Vote)
+		case msg.Delegation != nil:
+			vm.ReceiveDelegation(msg.Delegation)
+		case msg.Report != nil:
+			vm.ReceiveCanaryReport(msg.Report)
+		case msg.OptIn != nil:
+			vm.ReceiveCanaryOptIn(msg.OptIn)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 回调只在本节点发起提案、投票和生成报告时触发，收到的消息不再转发
+	vm.SetOnProposalCreated(func(p *voting.Proposal) { publish(governanceMessage{Proposal: p}) })
+	vm.SetOnVoteCast(func(v *voting.Vote) { publish(governanceMessage{Vote: v}) })
+	vm.SetOnCanaryReport(func(r *voting.CanaryReport) { publish(governanceMessage{Report: r}) })
+
+	refresh := func() {
+		syncVotingNodes(vm, self, trust)
+		if o := vm.CanaryOptInOf(self); o != nil {
+			publish(governanceMessage{OptIn: o})
+		}
+	}
+	refresh()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(governanceSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+	return cancel, nil
+}
+
+// createGovernanceProposal 按 /api/v1/voting/proposal/create 的请求发起提案：
+// proposal 类型且指定 key 时为参数提案（canary_trial 大于 0 时先在金丝雀节点试运行），
+// 其余类型针对 target 节点。
+func createGovernanceProposal(vm *voting.VotingManager, req *httpapi.ProposalRequest) (*voting.Proposal, error) {
+	reason := req.Title
+	if req.Description != "" {
+		reason += ": " + req.Description
+	}
+	voteType := voting.VoteType(req.Type)
+	switch voteType {
+	case "", voting.VoteProposal:
+		if req.Key != "" {
+			if req.CanaryTrial > 0 {
+				return vm.CreateCanaryParameterProposal(req.Key, req.Value, reason, time.Duration(req.CanaryTrial)*time.Second)
+			}
+			return vm.CreateParameterProposal(req.Key, req.Value, reason)
+		}
+		if req.Target == "" {
+			return nil, errors.New("target or key required")
+		}
+		return vm.CreateProposal(voting.VoteProposal, req.Target, reason)
+	case voting.VoteKick, voting.VoteRestore, voting.VotePromote, voting.VoteDemote:
+		if req.Target == "" {
+			return nil, errors.New("target required")
+		}
+		return vm.CreateProposal(voteType, req.Target, reason)
+	default:
+		return nil, fmt.Errorf("unknown proposal type %q", req.Type)
+	}
+}
+
+// strikeProposals 留言板警告达到阈值时发起的治理提案：作者的留言屡次被隐藏时提议剔除，
+// 版主的隐藏屡次被推翻时发起针对该版主的普通提案，由投票决定如何处理。
+func strikeProposals(vm *voting.VotingManager) bulletin.StrikeProposalFunc {
+	return func(role bulletin.StrikeRole, nodeID, reason string) (string, error) {
+		voteType := voting.VoteKick
+		if role == bulletin.StrikeModerator {
+			voteType = voting.VoteProposal
+		}
+		p, err := vm.CreateProposal(voteType, nodeID, reason)
+		if err != nil {
+			return "", err
+		}
+		return p.ID, nil
+	}
+}
+
+// listGovernanceProposals 按状态列出提案，供 /api/v1/voting/proposal/list 查询
+func listGovernanceProposals(vm *voting.VotingManager, status string) []map[string]interface{} {
+	var proposals []map[string]interface{}
+	for _, p := range vm.ListProposals(voting.ProposalStatus(status), 0, 0) {
+		proposals = append(proposals, toMap(p))
+	}
+	return proposals
+}
+
+// votingMetrics 投票模块统计
+func votingMetrics(vm *voting.VotingManager) []stats.Metric {
+	s := vm.GetStats()
+	return []stats.Metric{
+		stats.Gauge("pending_proposals", float64(s.PendingProposals), "进行中的提案数"),
+		stats.Counter("passed_proposals", float64(s.PassedProposals), "已通过的提案数"),
+		stats.Counter("rejected_proposals", float64(s.RejectedProposals), "被否决的提案数"),
+		stats.Gauge("active_nodes", float64(s.ActiveNodes), "有投票权的节点数"),
+		stats.Gauge("removed_nodes", float64(s.RemovedNodes), "已被剔除的节点数"),
+	}
+}
+
+// registerVotingAPI 把投票 API 接到投票管理器；本节点的委托、撤销和金丝雀参与声明经 transport 广播
+func registerVotingAPI(s *httpapi.Server, vm *voting.VotingManager, self string, transport gossipTransport) {
+	s.VotingCreateFunc = func(req *httpapi.ProposalRequest) (string, error) {
+		p, err := createGovernanceProposal(vm, req)
+		if err != nil {
+			return "", err
+		}
+		return p.ID, nil
+	}
+	s.VotingListFunc = func(status string) []map[string]interface{} {
+		return listGovernanceProposals(vm, status)
+	}
+	s.VotingGetFunc = func(id string) (map[string]interface{}, error) {
+		p, err := vm.GetProposal(id)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(p), nil
+	}
+	s.VotingVoteFunc = func(id, choice string) error {
+		_, err := vm.CastVote(id, voting.VoteChoice(choice), "")
+		return err
+	}
+	s.VotingFinalizeFunc = func(id string) (string, error) {
+		p, err := vm.FinalizeProposal(id)
+		if err != nil {
+			return "", err
+		}
+		return string(p.Status), nil
+	}
+	s.VotingTallyFunc = func(id string) (map[string]interface{}, error) {
+		result, err := vm.TallyProposal(id)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(result), nil
+	}
+	s.VotingDelegateFunc = func(delegate, scope string) (map[string]interface{}, error) {
+		d, err := vm.Delegate(delegate, voting.VoteType(scope))
+		if err != nil {
+			return nil, err
+		}
+		publishGovernance(transport, governanceMessage{Delegation: d})
+		return toMap(d), nil
+	}
+	s.VotingRevokeDelegationFunc = func(scope string) error {
+		d, err := vm.RevokeDelegation(voting.VoteType(scope))
+		if err != nil {
+			return err
+		}
+		publishGovernance(transport, governanceMessage{Delegation: d})
+		return nil
+	}
+	s.VotingDelegationsFunc = func(nodeID string) []map[string]interface{} {
+		var result []map[string]interface{}
+		for _, d := range vm.ListDelegations(nodeID) {
+			result = append(result, toMap(d))
+		}
+		return result
+	}
+	s.VotingCanaryFunc = func(id string) (map[string]interface{}, error) {
+		c, err := vm.GetCanary(id)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(c), nil
+	}
+	s.VotingCanaryOptInFunc = func(optIn bool) ([]string, error) {
+		o, err := vm.OptInCanary(optIn)
+		if err != nil {
+			return nil, err
+		}
+		publishGovernance(transport, governanceMessage{OptIn: o})
+		return vm.CanaryNodes(), nil
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/feature"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/heartbeat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/maintenance"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// parseCapabilities 解析 -capabilities：逗号分隔的能力标签，去重后排序
+func parseCapabilities(value string) []string {
+	seen := make(map[string]bool)
+	var caps []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.TrimSpace(c)
+		if c != "" && !seen[c] {
+			seen[c] = true
+			caps = append(caps, c)
+		}
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// heartbeatSources 心跳包内容的来源和收到心跳后需要更新的子系统
+type heartbeatSources struct {
+	capabilities []string
+	protocols    func() []string // 本节点已注册的协议，用于计算协议哈希
+	features     *feature.Manager
+	maintenance  *maintenance.Manager
+	neighbors    *neighbor.NeighborManager
+	tasks        *task.TaskManager
+}
+
+// newHeartbeatManager 创建心跳管理器：心跳通告协议哈希、能力、已就绪特性和维护状态；
+// 收到的心跳刷新邻居在线状态，并作为特性激活的就绪通告和对端维护状态；
+// 心跳中通告的能力登记到任务的能力注册表，作为接单匹配和审计评审人的候选。
+// 心跳存活度计入邻居信任分。adaptive-heartbeat 激活后网络稳定时心跳间隔逐步放宽。
+func newHeartbeatManager(cfg *heartbeat.ManagerConfig, src heartbeatSources) (*heartbeat.HeartbeatManager, error) {
+	hb, err := heartbeat.NewHeartbeatManager(cfg)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := append([]string(nil), src.capabilities...)
+	hb.SetCapabilitiesFunc(func() []string { return capabilities })
+	if src.protocols != nil {
+		hb.SetProtocolHashFunc(func() string { return heartbeat.ProtocolHash(src.protocols()) })
+	}
+	if src.features != nil {
+		hb.SetFeaturesFunc(src.features.ReadyFeatures)
+		// 旧版本节点按固定间隔判断离线，全网激活后才放宽心跳间隔
+		hb.SetBackoffFunc(func() bool { return src.features.IsActive(feature.FeatureAdaptiveHeartbeat) })
+	}
+	if src.maintenance != nil {
+		hb.SetStatusFunc(func() heartbeat.Status {
+			if src.maintenance.IsEnabled() {
+				return heartbeat.StatusMaintenance
+			}
+			return heartbeat.StatusIdle
+		})
+	}
+
+	hb.SetOnBeat(func(b *heartbeat.Beat) {
+		if src.features != nil {
+			src.features.ObserveSignal(b.NodeID, b.Features)
+		}
+		if src.maintenance != nil {
+			src.maintenance.RecordPeerStatus(&maintenance.Status{
+				NodeID:  b.NodeID,
+				Enabled: b.Status == heartbeat.StatusMaintenance,
+			})
+		}
+		if src.neighbors != nil {
+			src.neighbors.RecordHeartbeat(b.NodeID)
+		}
+		if src.tasks != nil {
+			src.tasks.RegisterCapability(&task.AgentCapability{
+				AgentID:      b.NodeID,
+				Capabilities: append([]string(nil), b.Capabilities...),
+			})
+		}
+	})
+	if src.neighbors != nil {
+		src.neighbors.SetLivenessFunc(hb.Liveness)
+	}
+	return hb, nil
+}
+
+// startHeartbeat 在广播通道上运行心跳管理器，返回停止函数
+func startHeartbeat(hb *heartbeat.HeartbeatManager, transport heartbeat.Transport) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		if err := hb.Run(ctx, transport); err != nil {
+			fmt.Printf("⚠️  心跳不可用: %v\n", err)
+		}
+	}()
+	return cancel
+}
+
+// heartbeatStatus 本节点心跳状态或单个节点的存活情况，供 /api/v1/heartbeat/status 查询
+func heartbeatStatus(hb *heartbeat.HeartbeatManager, nodeID string) (map[string]interface{}, error) {
+	if nodeID == "" {
+		return toMap(hb.Status()), nil
+	}
+	p, ok := hb.Peer(nodeID)
+	if !ok {
+		return nil, fmt.Errorf("no heartbeat received from %s", nodeID)
+	}
+	return toMap(p), nil
+}
+
+// relayRole 中继节点在心跳中通告的角色（与 host.RoleRelay 相同）
+const relayRole = "relay"
+
+// heartbeatRelays 心跳在线的中继节点，按存活度从高到低排列，不含 exclude 中的节点
+func heartbeatRelays(hb *heartbeat.HeartbeatManager, exclude ...string) []string {
+	skip := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		skip[id] = true
+	}
+	var relays []*heartbeat.Peer
+	for _, p := range hb.Peers() {
+		if p.Online && p.Role == relayRole && !skip[p.NodeID] {
+			relays = append(relays, p)
+		}
+	}
+	sort.SliceStable(relays, func(i, j int) bool { return relays[i].Liveness > relays[j].Liveness })
+	ids := make([]string, len(relays))
+	for i, p := range relays {
+		ids[i] = p.NodeID
+	}
+	return ids
+}
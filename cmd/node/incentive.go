@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/trust"
+)
+
+// sourceRelation 声誉传播来源与本节点的关系：被隔离（安全黑名单）优先，其次是邻居，再次是本节点直接背书的节点
+func sourceRelation(quarantined, neighbor func(nodeID string) bool, endorsements *trust.EndorsementManager) func(nodeID string) incentive.SourceRelation {
+	return func(nodeID string) incentive.SourceRelation {
+		switch {
+		case quarantined(nodeID):
+			return incentive.RelationQuarantined
+		case neighbor(nodeID):
+			return incentive.RelationNeighbor
+		case endorsements.TrustDistance(nodeID) == 1:
+			return incentive.RelationEndorsed
+		}
+		return incentive.RelationUnknown
+	}
+}
+
+// toleranceLevels 本节点对来源的剩余和最大耐受值，尚无记录时按当前声誉和关系预估
+func toleranceLevels(im *incentive.IncentiveManager, nodeID string) (int, int) {
+	if record := im.GetToleranceRecord(nodeID); record != nil {
+		return int(record.RemainingTolerance), int(record.MaxTolerance)
+	}
+	tolerance := int(im.PreviewTolerance(nodeID).Tolerance)
+	return tolerance, tolerance
+}
+
+// toleranceInputs 初始耐受值的计算依据：已有记录时为记录使用的依据，手动设置的记录没有依据
+func toleranceInputs(im *incentive.IncentiveManager, nodeID string) map[string]interface{} {
+	if record := im.GetToleranceRecord(nodeID); record != nil {
+		if record.Inputs == nil {
+			return nil
+		}
+		return toMap(record.Inputs)
+	}
+	return toMap(im.PreviewTolerance(nodeID))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/ledger"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+)
+
+// ledgerVerifyPath 账本校验 API 路径
+const ledgerVerifyPath = "/api/v1/ledger/verify"
+
+// recordReputationChange 把声誉变化以本节点签名写入事件账本
+func recordReputationChange(events *ledger.Ledger, self string) func(nodeID string, delta, value float64, reason string) {
+	return func(nodeID string, delta, value float64, reason string) {
+		data := ledger.ReputationChangeData{NodeID: nodeID, Delta: delta, NewValue: value, Source: reason}
+		if _, err := events.AppendEvent(ledger.EventReputationChange, nodeID, data, self); err != nil {
+			fmt.Printf("⚠️  声誉变化写入事件账本失败: %v\n", err)
+		}
+	}
+}
+
+// ledgerUsage 事件账本按类型的存储占用和压缩情况
+func ledgerUsage(events *ledger.Ledger) map[string]interface{} {
+	var bytes int64
+	usage := events.StorageUsage()
+	for _, u := range usage {
+		bytes += u.Bytes
+	}
+	return map[string]interface{}{
+		"types":         usage,
+		"bytes":         bytes,
+		"pruned_ranges": len(events.GetPrunedRanges()),
+	}
+}
+
+// ledgerSources 账本校验的数据来源，为 nil 的一项跳过
+type ledgerSources struct {
+	events      *ledger.Ledger
+	tokens      *token.Ledger
+	reputations *reputation.Manager
+}
+
+// ledgerVerifyReport 事件账本和代币流水的合并校验结果
+type ledgerVerifyReport struct {
+	OK         bool                 `json:"ok"`
+	VerifiedAt time.Time            `json:"verified_at"`
+	Events     *ledger.VerifyReport `json:"events,omitempty"`
+	Tokens     *token.VerifyReport  `json:"tokens,omitempty"`
+}
+
+// verifyLedgers 重放事件账本并把重放出的声誉与声誉存储比对，再重放代币流水与账户表比对
+func verifyLedgers(src ledgerSources) *ledgerVerifyReport {
+	report := &ledgerVerifyReport{OK: true, VerifiedAt: time.Now()}
+	if src.events != nil {
+		// 本节点写入的事件都带签名，未签名即被改写过
+		opts := ledger.VerifyOptions{RequireSignatures: true}
+		if src.reputations != nil {
+			opts.Reputation = func(nodeID string) (float64, bool) {
+				s, ok := src.reputations.Get(nodeID)
+				if !ok {
+					return 0, false
+				}
+				return s.Score, true
+			}
+		}
+		report.Events = src.events.Verify(opts)
+		report.OK = report.OK && report.Events.OK()
+	}
+	if src.tokens != nil {
+		report.Tokens = src.tokens.Verify()
+		report.OK = report.OK && report.Tokens.OK()
+	}
+	return report
+}
+
+// loadLedgerSources 从数据目录读取账本和声誉存储，目录不存在的一项跳过
+func loadLedgerSources(dataDir string, verify func(signer string, data []byte, signature string) bool) (ledgerSources, error) {
+	var src ledgerSources
+	exists := func(name string) bool {
+		info, err := os.Stat(filepath.Join(dataDir, name))
+		return err == nil && info.IsDir()
+	}
+	if exists("ledger") {
+		events, err := ledger.NewLedger(filepath.Join(dataDir, "ledger"))
+		if err != nil {
+			return src, fmt.Errorf("事件账本: %w", err)
+		}
+		events.SetVerifyFunc(verify)
+		src.events = events
+	}
+	if exists("token") {
+		config := token.DefaultConfig()
+		config.DataDir = filepath.Join(dataDir, "token")
+		config.VerifyFunc = verify
+		tokens, err := token.NewLedger(config)
+		if err != nil {
+			return src, fmt.Errorf("代币账本: %w", err)
+		}
+		src.tokens = tokens
+	}
+	if exists("reputation") {
+		config := reputation.DefaultManagerConfig()
+		config.DataDir = filepath.Join(dataDir, "reputation")
+		reputations, err := reputation.NewManager(config)
+		if err != nil {
+			return src, fmt.Errorf("声誉存储: %w", err)
+		}
+		src.reputations = reputations
+	}
+	return src, nil
+}
+
+func cmdVerifyLedger() {
+	fs := flag.NewFlagSet("verify-ledger", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
+	offline := fs.Bool("offline", false, "直接读取数据目录校验，不连接运行中的节点（节点已停止时使用）")
+	jsonOutput := fs.Bool("json", false, "JSON格式输出")
+	limit := fs.Int("limit", 50, "每个账本最多列出的问题数，0 表示全部")
+	fs.Parse(os.Args[2:])
+
+	var report *ledgerVerifyReport
+	if *offline {
+		src, err := loadLedgerSources(*dataDir, verifyNodeSignature)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取账本失败: %v\n", err)
+			os.Exit(1)
+		}
+		report = verifyLedgers(src)
+	} else {
+		data, err := nodeAPIRequest(*httpAddr, loadOrGenerateToken(*dataDir), http.MethodGet, ledgerVerifyPath, nil, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "账本校验失败: %v\n（节点未运行时可使用 -offline）\n", err)
+			os.Exit(1)
+		}
+		raw, _ := json.Marshal(data)
+		report = &ledgerVerifyReport{}
+		if err := json.Unmarshal(raw, report); err != nil {
+			fmt.Fprintf(os.Stderr, "解析校验结果失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printLedgerReport(report, *limit)
+	}
+	if !report.OK {
+		os.Exit(1)
+	}
+}
+
+func printLedgerReport(report *ledgerVerifyReport, limit int) {
+	fmt.Println("======== 账本校验 ========")
+	if e := report.Events; e != nil {
+		fmt.Printf("事件账本: %d 条事件 (seq %d-%d)，已签名 %d，未签名 %d，已压缩区间 %d\n",
+			e.Events, e.FirstSeq, e.LastSeq, e.Signed, e.Unsigned, e.PrunedRanges)
+		if !e.SignaturesChecked {
+			fmt.Println("  ⚠️  未验证签名")
+		}
+		fmt.Printf("  声誉比对: %d 个节点\n", e.NodesChecked)
+		for i, issue := range e.Issues {
+			if limit > 0 && i >= limit {
+				fmt.Printf("  ... 另有 %d 个问题\n", len(e.Issues)-limit)
+				break
+			}
+			fmt.Printf("  ❌ seq %d %s %s: %s\n", issue.Sequence, issue.Kind, issue.NodeID, issue.Detail)
+		}
+		if e.Truncated {
+			fmt.Printf("  问题过多，只列出前 %d 个\n", ledger.DefaultMaxIssues)
+		}
+	} else {
+		fmt.Println("事件账本: 无")
+	}
+	if t := report.Tokens; t != nil {
+		fmt.Printf("代币流水: %d 条流水，%d 个账户，签名转账 %d，流通总量 %.2f\n",
+			t.Entries, t.Accounts, t.SignedTransfers, t.Supply)
+		if !t.SignaturesChecked {
+			fmt.Println("  ⚠️  未验证签名")
+		}
+		for i, issue := range t.Issues {
+			if limit > 0 && i >= limit {
+				fmt.Printf("  ... 另有 %d 个问题\n", len(t.Issues)-limit)
+				break
+			}
+			fmt.Printf("  ❌ %s %s %s: %s\n", issue.EntryID, issue.Kind, issue.NodeID, issue.Detail)
+		}
+	} else {
+		fmt.Println("代币流水: 无")
+	}
+	if report.OK {
+		fmt.Println("结果: ✅ 账本与当前状态一致")
+	} else {
+		fmt.Println("结果: ❌ 发现不一致")
+	}
+	fmt.Println("==========================")
+}
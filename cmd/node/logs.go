@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/logging"
+)
+
+// newNodeLogger 节点的结构化事件日志，写入 <data>/logs，条目由本节点签名；
+// replicas 为查询使用的只读副本数，0 表示查询直接读写入端
+func newNodeLogger(dataDir, nodeID string, replicas int, sign func(data []byte) (string, error)) (*logging.Logger, error) {
+	config := logging.DefaultLogConfig(nodeID)
+	config.DataDir = filepath.Join(dataDir, "logs")
+	config.ReadReplicas = replicas
+	config.SignFunc = sign
+	return logging.NewLogger(config)
+}
+
+// registerLogAPI 日志 API：提交的条目以 info 级别记录，查询和副本状态来自日志的只读副本
+func registerLogAPI(s *httpapi.Server, l *logging.Logger) {
+	s.LogSubmitFunc = func(eventType string, details map[string]interface{}) (map[string]interface{}, error) {
+		entry, err := l.Info(logging.EventType(eventType), details)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(entry), nil
+	}
+	s.LogQueryFunc = func(nodeID, eventType string, limit int) []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, entry := range l.Query(&logging.QueryFilter{NodeID: nodeID, EventType: logging.EventType(eventType), Limit: limit}) {
+			result = append(result, toMap(entry))
+		}
+		return result
+	}
+	s.LogReplicasFunc = func() []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, stats := range l.ReplicaStats() {
+			result = append(result, toMap(stats))
+		}
+		return result
+	}
+}
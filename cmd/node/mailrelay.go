@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bandwidth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/node"
+)
+
+const (
+	mailDeliverTimeout  = 10 * time.Second // 单次投递或交给中继的超时
+	mailRelayCandidates = 3                // 收件人不在线时最多尝试的中继数
+	mailRelayBatch      = 50               // 中继每次推送的邮件数
+	mailRelaySweep      = time.Minute      // 中继检查已连接收件人的间隔
+)
+
+// mailRelay 邮件的节点间投递：收件人已连接时经 DeliverProtocol 直接投递，否则把加密邮件
+// 交给心跳在线的中继暂存。中继按邮箱路由表估计的期望耗时（实测延迟 / 成功率）依次尝试，
+// 直连实测过慢且有更快的中继时先交给中继。以中继模式运行时，收件人重新连接后推送暂存的
+// 邮件并把送达回执转交给原发件人。
+type mailRelay struct {
+	n       *node.Node
+	mb      *mailbox.Mailbox
+	deliver protocol.ID
+	relay   protocol.ID
+	relays  func(exclude ...string) []string
+	banned  func(peerID string) bool
+	routes  *mailbox.RouteTable
+	gate    *bandwidth.FairScheduler
+
+	mu       sync.Mutex
+	inflight map[string]bool // 正在推送的收件人
+}
+
+// startMailRelay 注册投递协议（中继模式下还有暂存协议），设置邮箱的投递和中继函数，返回停止函数。
+// relays 返回可用的中继节点，为 nil 时不经中继转发；发出的邮件帧经 gate 按节点公平调度出站带宽。
+func startMailRelay(n *node.Node, ns string, mb *mailbox.Mailbox, relays func(exclude ...string) []string,
+	banned func(peerID string) bool, gate *bandwidth.FairScheduler) context.CancelFunc {
+	r := &mailRelay{
+		n:        n,
+		mb:       mb,
+		deliver:  protocol.ID(namespace.Protocol(ns, mailbox.DeliverProtocol)),
+		relay:    protocol.ID(namespace.Protocol(ns, mailbox.RelayProtocol)),
+		relays:   relays,
+		banned:   banned,
+		routes:   mb.Routes(),
+		gate:     gate,
+		inflight: make(map[string]bool),
+	}
+	h := n.Host().Host()
+
+	// 没有本地投递记录的路径按 libp2p 测得的往返时延估计
+	r.routes.SetLatencyFunc(func(id string) (time.Duration, bool) {
+		pid, err := peer.Decode(id)
+		if err != nil {
+			return 0, false
+		}
+		rtt := h.Peerstore().LatencyEWMA(pid)
+		return rtt, rtt > 0
+	})
+
+	mb.SetDeliverFunc(r.deliverMessage)
+	if relays != nil {
+		mb.SetRelayFunc(r.relayMessage)
+	}
+	h.SetStreamHandler(r.deliver, func(s network.Stream) {
+		r.serve(s, mb.HandleEnvelope)
+	})
+	if mb.IsRelay() {
+		h.SetStreamHandler(r.relay, func(s network.Stream) {
+			r.serve(s, func(env *mailbox.Envelope, from string) *mailbox.DeliveryResult {
+				result := mb.HandleRelayEnvelope(env, from)
+				// 收件人恰好在线时立即推送
+				accepted := make(map[string]bool, len(result.Accepted))
+				for _, id := range result.Accepted {
+					accepted[id] = true
+				}
+				for _, msg := range env.Messages {
+					if msg != nil && accepted[msg.ID] {
+						go r.push(msg.Receiver)
+					}
+				}
+				return result
+			})
+		})
+	}
+
+	// 节点重新连接：重试发给它的待发邮件，中继推送暂存的邮件和回执
+	notifee := &network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			id := conn.RemotePeer().String()
+			if banned(id) {
+				return
+			}
+			go func() {
+				mb.RetryPending(id)
+				if mb.IsRelay() {
+					r.push(id)
+				}
+			}()
+		},
+	}
+	h.Network().Notify(notifee)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if mb.IsRelay() {
+		go r.sweep(ctx)
+	}
+	return func() {
+		cancel()
+		h.Network().StopNotify(notifee)
+		h.RemoveStreamHandler(r.deliver)
+		h.RemoveStreamHandler(r.relay)
+	}
+}
+
+// serve 处理入站的投递或暂存请求，被封禁的节点直接断开
+func (r *mailRelay) serve(s network.Stream, handle func(env *mailbox.Envelope, from string) *mailbox.DeliveryResult) {
+	remote := s.Conn().RemotePeer().String()
+	if r.banned(remote) {
+		s.Reset()
+		return
+	}
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(mailDeliverTimeout))
+	mailbox.ServeEnvelope(s, func(env *mailbox.Envelope) *mailbox.DeliveryResult {
+		return handle(env, remote)
+	})
+}
+
+// exchange 向 peerID 发送一帧并读取应答
+func (r *mailRelay) exchange(peerID string, proto protocol.ID, env *mailbox.Envelope) (*mailbox.DeliveryResult, error) {
+	pid, err := peer.Decode(peerID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mailDeliverTimeout)
+	defer cancel()
+	s, err := r.n.Host().Host().NewStream(ctx, pid, proto)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	s.SetDeadline(time.Now().Add(mailDeliverTimeout))
+	return mailbox.SendEnvelope(r.gate.GateStream(ctx, peerID, s), env)
+}
+
+// connected 是否与节点保持连接
+func (r *mailRelay) connected(peerID string) bool {
+	pid, err := peer.Decode(peerID)
+	return err == nil && r.n.Host().Host().Network().Connectedness(pid) == network.Connected
+}
+
+// deliverMessage 直接投递给已连接的收件人，收件人签发回执才算送达。加密邮件的直连被判定为
+// 慢路径时返回 mailbox.ErrSlowPath，邮箱先交给中继。在邮箱锁内调用，不能回调邮箱。
+func (r *mailRelay) deliverMessage(receiver string, msg *mailbox.Message) error {
+	var relays []string
+	if msg.Encrypted && r.relays != nil {
+		relays = r.relayCandidates(receiver)
+	}
+	if r.routes.DirectSlow(msg.ID, receiver, relays) {
+		return mailbox.ErrSlowPath
+	}
+	if !r.connected(receiver) {
+		return fmt.Errorf("receiver %s is not connected", receiver)
+	}
+	start := time.Now()
+	err := r.deliverDirect(receiver, msg)
+	r.routes.Attempt(msg.ID, receiver, receiver, mailbox.PathDirect, time.Since(start), err)
+	return err
+}
+
+func (r *mailRelay) deliverDirect(receiver string, msg *mailbox.Message) error {
+	result, err := r.exchange(receiver, r.deliver, &mailbox.Envelope{Messages: []*mailbox.Message{msg}})
+	if err != nil {
+		return err
+	}
+	for _, receipt := range result.Receipts {
+		if receipt.MessageID == msg.ID {
+			return nil
+		}
+	}
+	if reason, ok := result.Rejected[msg.ID]; ok {
+		return fmt.Errorf("rejected by %s: %s", receiver, reason)
+	}
+	return fmt.Errorf("no receipt from %s", receiver)
+}
+
+// relayCandidates 心跳在线且未被封禁的中继（按心跳活跃度排序）
+func (r *mailRelay) relayCandidates(receiver string) []string {
+	var ids []string
+	for _, id := range r.relays(r.n.Host().ID().String(), receiver) {
+		if !r.banned(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// relayMessage 按期望耗时依次尝试在线的中继，返回接收暂存的中继节点。在邮箱锁内调用，不能回调邮箱。
+func (r *mailRelay) relayMessage(msg *mailbox.Message) (string, error) {
+	candidates := r.routes.Rank(r.relayCandidates(msg.Receiver), mailbox.PathRelay)
+	if len(candidates) > mailRelayCandidates {
+		candidates = candidates[:mailRelayCandidates]
+	}
+	var errs []string
+	for _, c := range candidates {
+		start := time.Now()
+		err := r.handoff(c.Via, msg)
+		r.routes.Attempt(msg.ID, msg.Receiver, c.Via, mailbox.PathRelay, time.Since(start), err)
+		if err == nil {
+			return c.Via, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", c.Via, err))
+	}
+	if len(errs) == 0 {
+		return "", errors.New("no relay available")
+	}
+	return "", fmt.Errorf("no relay accepted the message (%s)", strings.Join(errs, "; "))
+}
+
+// handoff 把邮件交给中继暂存，中继确认接收才算成功
+func (r *mailRelay) handoff(relay string, msg *mailbox.Message) error {
+	result, err := r.exchange(relay, r.relay, &mailbox.Envelope{Messages: []*mailbox.Message{msg}})
+	if err != nil {
+		return err
+	}
+	for _, accepted := range result.Accepted {
+		if accepted == msg.ID {
+			return nil
+		}
+	}
+	if reason, ok := result.Rejected[msg.ID]; ok {
+		return errors.New(reason)
+	}
+	return errors.New("not accepted")
+}
+
+// push 把暂存的邮件和待转交的回执推送给已连接的节点，直到没有可推送的内容或对方不再确认
+func (r *mailRelay) push(peerID string) {
+	r.mu.Lock()
+	if r.inflight[peerID] {
+		r.mu.Unlock()
+		return
+	}
+	r.inflight[peerID] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.inflight, peerID)
+		r.mu.Unlock()
+	}()
+
+	for r.connected(peerID) && !r.banned(peerID) {
+		env := r.mb.RelayBatch(peerID, mailRelayBatch)
+		if env == nil {
+			return
+		}
+		result, err := r.exchange(peerID, r.deliver, env)
+		if err != nil {
+			return
+		}
+		for _, sender := range r.mb.CompleteRelay(peerID, env, result) {
+			if r.connected(sender) {
+				go r.push(sender)
+			}
+		}
+		// 对方既没有确认也没有拒收，下次连接或定期检查时再推送
+		if len(env.Receipts) == 0 && len(result.Receipts) == 0 && len(result.Rejected) == 0 {
+			return
+		}
+	}
+}
+
+// sweep 定期向已连接的收件人推送（补上连接时推送失败或之后才暂存的邮件）
+func (r *mailRelay) sweep(ctx context.Context) {
+	ticker := time.NewTicker(mailRelaySweep)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, id := range r.mb.RelayReceivers() {
+				if r.connected(id) {
+					r.push(id)
+				}
+			}
+		}
+	}
+}
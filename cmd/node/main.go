@@ -2,29 +2,75 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/accusation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/admission"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/api/server"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/audit"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bandwidth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/breaker"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/clockskew"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/config"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/daemon"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/ledger"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/logging"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/feature"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/genesis"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/heartbeat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/maintenance"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/metrics"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/dialer"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/host"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/identity"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/node"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/peercache"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/provision"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/quota"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reachability"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/recovery"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/retention"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/startup"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/supernode"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/transfer"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/trust"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/voting"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/webadmin"
+	libp2pcrypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 )
 
 var (
@@ -62,6 +108,30 @@ func main() {
 		cmdKeygen()
 	case "health":
 		cmdHealth()
+	case "debug":
+		cmdDebug()
+	case "maintenance":
+		cmdMaintenance()
+	case "verify-ledger":
+		cmdVerifyLedger()
+	case "dispute-bundle":
+		cmdDisputeBundle()
+	case "tray":
+		cmdTray()
+	case "genesis":
+		cmdGenesis()
+	case "update":
+		cmdUpdate()
+	case "backup":
+		cmdBackup()
+	case "smoke":
+		cmdSmoke()
+	case "migrate":
+		cmdMigrate()
+	case "apply":
+		cmdApply()
+	case "netgen":
+		cmdNetgen()
 	case "version", "-v", "--version":
 		cmdVersion()
 	case "help", "-h", "--help":
@@ -95,10 +165,22 @@ DAAN P2P Node v%s
   logs        查看节点日志
   run         前台运行节点（调试用）
   
-  token       管理访问令牌
+  token       管理访问令牌与 API Key
   config      管理配置文件
   keygen      生成密钥对
   health      健康检查
+  debug       诊断工具（profile 抓取）
+  maintenance 维护模式（on/off/status）
+  tray        本地控制接口，供桌面托盘程序使用（status/start/stop/notifications）
+  update      使用已下载的发布归档更新程序（校验签名与校验和）
+  backup      加密备份到 S3 兼容存储（push/list/verify/restore/prune）
+  smoke       对运行中的节点做端到端冒烟测试（部署验收）
+  verify-ledger 重放事件账本和代币流水，校验签名、哈希链并与当前状态比对
+  dispute-bundle 导出或离线校验争议监管链包（export/verify）
+  genesis     创世信息与邀请码（init/key/invite/join/graph）
+  migrate     升级本地数据格式（启动时自动执行，-dry-run 预览）
+  apply       按 YAML 清单声明式配置节点（-dry-run 预览变更）
+  netgen      生成 N 个节点的本地测试网（密钥、清单、docker-compose 或 systemd 单元）
   
   version     显示版本信息
   help        显示帮助信息
@@ -112,6 +194,7 @@ DAAN P2P Node v%s
   agentnetwork logs -n 100                     # 查看最后100行日志
   agentnetwork logs -f                         # 实时查看日志
   agentnetwork run                             # 前台运行（调试）
+  agentnetwork run -demo -admin :18080         # 演示模式（模拟数据，无需 P2P 网络）
   
   agentnetwork token show                      # 显示当前令牌
   agentnetwork token refresh                   # 刷新令牌
@@ -119,6 +202,19 @@ DAAN P2P Node v%s
   agentnetwork config show                     # 显示配置
   agentnetwork keygen                          # 生成新密钥
   agentnetwork health                          # 检查节点健康
+  agentnetwork debug profile -d 30s            # 抓取30秒 CPU profile
+  agentnetwork maintenance on -d 2h            # 进入维护模式2小时
+  agentnetwork tray notifications              # 输出新邮件、任务等通知
+  agentnetwork update -archive ./agentnetwork-0.2.0-linux-amd64.tar.gz  # 校验并更新
+  agentnetwork backup push -bucket daan        # 推送加密快照（口令见 DAAN_BACKUP_PASSPHRASE）
+  agentnetwork backup restore -bucket daan     # 从最新快照恢复
+  agentnetwork smoke                           # 验收本机运行中的节点
+  agentnetwork verify-ledger -offline          # 节点停止时校验本地账本
+  agentnetwork dispute-bundle verify case.json # 离线校验争议监管链包
+  agentnetwork genesis graph                   # 查看邀请树（谁邀请了谁）
+  agentnetwork migrate -dry-run                # 预览升级后需要的数据迁移
+  agentnetwork apply -f node.yaml              # 按清单配置节点
+  agentnetwork netgen -n 5                     # 生成 5 个节点的本地测试网
 
 运行 'agentnetwork <命令> -h' 查看命令的详细选项
 `, getASCIILogo(), version)
@@ -150,6 +246,36 @@ type commonFlags struct {
 	httpAddr       string
 	adminAddr      string
 	adminToken     string
+	blockProfile   int
+	mutexProfile   int
+	signResponses  bool
+	strictAPI      bool
+	outboundRate   int64
+	peerLimits     quota.PeerLimits
+	storageKey     string
+	ttlGrace       time.Duration
+	publicInbox    bool
+	store          string
+	namespace      string
+	readyMinPeers  int
+	bootstrapAfter time.Duration
+	sendDelay      time.Duration
+	routeAuth      string
+	signedRequests bool
+	inboundGating  bool
+	admissionMinRep float64
+	startTimeout   time.Duration
+	metricsAddr    string
+	controlSocket  string
+	tokenFunds     bool
+	reportMailTo   string
+	legacyUntil    string
+	capabilities   string
+	heartbeatMin   time.Duration
+	heartbeatMax   time.Duration
+	logReplicas    int
+	backup         *backupFlags
+	provisioned    *provision.Manifest // 已应用的节点清单（apply 命令写入）
 }
 
 func parseCommonFlags(fs *flag.FlagSet) *commonFlags {
@@ -163,6 +289,38 @@ func parseCommonFlags(fs *flag.FlagSet) *commonFlags {
 	fs.StringVar(&cf.httpAddr, "http", ":18345", "HTTP服务地址")
 	fs.StringVar(&cf.adminAddr, "admin", ":18080", "管理后台地址")
 	fs.StringVar(&cf.adminToken, "admin-token", "", "管理后台访问令牌（可选，默认自动生成）")
+	fs.IntVar(&cf.blockProfile, "block-profile-rate", webadmin.DefaultConfig().BlockProfileRate, "阻塞 profile 采样率（纳秒），0 表示不采集")
+	fs.IntVar(&cf.mutexProfile, "mutex-profile-fraction", webadmin.DefaultConfig().MutexProfileFraction, "锁竞争 profile 采样比例（1/n），0 表示不采集")
+	fs.BoolVar(&cf.signResponses, "sign-responses", false, "对所有 HTTP API 响应签名（默认仅在请求带 X-Sign-Response: 1 时签名）")
+	fs.BoolVar(&cf.strictAPI, "strict-api", false, "HTTP API 拒绝请求体中的未知字段（返回 422）")
+	fs.Int64Var(&cf.outboundRate, "outbound-rate", bandwidth.DefaultConfig().Rate, "出站带宽预算（字节/秒），按节点公平分配，0 表示不限速")
+	peerLimits := quota.DefaultPeerLimits()
+	fs.IntVar(&cf.peerLimits.MailPerHour, "peer-mail-per-hour", peerLimits.MailPerHour, "握手时通告的每个节点每小时最多发来的邮件数，0 表示不限")
+	fs.IntVar(&cf.peerLimits.BulletinPerHour, "peer-bulletin-per-hour", peerLimits.BulletinPerHour, "握手时通告的每个节点每小时最多发布的留言数，0 表示不限")
+	fs.IntVar(&cf.peerLimits.TaskOffersPerDay, "peer-task-offers-per-day", peerLimits.TaskOffersPerDay, "握手时通告的每个节点每天最多发来的任务报价数，0 表示不限")
+	fs.StringVar(&cf.storageKey, "storage-key", "", "本地存储加密密钥文件（默认由节点私钥派生）")
+	fs.DurationVar(&cf.ttlGrace, "ttl-grace", clockskew.DefaultConfig().Grace, "邮件和留言过期判断容忍的节点间时钟偏差")
+	fs.BoolVar(&cf.publicInbox, "public-inbox", false, "陌生发件人的邮件进入限额的公开收件箱，回复后成为已知发件人")
+	fs.StringVar(&cf.store, "store", "log", "邮箱、留言板和激励记录的存储后端: log（增量写入，首次启动导入旧的 JSON 文件）或 json（每次保存整个文件）")
+	fs.StringVar(&cf.namespace, "namespace", "", "网络命名空间，共用引导节点的多个逻辑网络互相隔离（空为默认网络）")
+	fs.IntVar(&cf.readyMinPeers, "ready-min-peers", 1, "/readyz 要求的最少连接节点数（网络中第一个引导节点设为 0）")
+	fs.DurationVar(&cf.bootstrapAfter, "bootstrap-fallback", 15*time.Second, "先重连缓存的节点，等待该时长后连接数仍不足 -ready-min-peers 才连接引导节点（0 表示启动即连接）")
+	fs.DurationVar(&cf.sendDelay, "send-delay", 0, "邮件默认两阶段发送：先暂存该时长，期间可通过 API 撤回（0 表示立即发送）")
+	fs.StringVar(&cf.routeAuth, "route-auth", "", "按路由要求节点签名: 路径=token|signature|both（逗号分隔，recommended 为指责和投票推荐策略）")
+	fs.BoolVar(&cf.signedRequests, "signed-requests", false, "所有变更类 HTTP 请求要求 Token 加节点签名（-route-auth 中配置为 token 的路由豁免）")
+	fs.DurationVar(&cf.startTimeout, "start-timeout", startup.DefaultConfig().DefaultTimeout, "单个子系统的启动超时，超时或失败的子系统不影响互不依赖的其他子系统")
+	fs.StringVar(&cf.metricsAddr, "metrics-addr", "", "Prometheus 指标监听地址（如 :9090，空表示不启用）")
+	fs.StringVar(&cf.controlSocket, "control-socket", "", "桌面托盘使用的本地控制套接字（默认: <数据目录>/control.sock，off 表示不启用）")
+	fs.BoolVar(&cf.tokenFunds, "token-funds", false, "押金托管和抵押在 $DAAN 代币账本上锁定（需要已铸造的余额，默认只在各自管理器内记账）")
+	fs.StringVar(&cf.legacyUntil, "legacy-protocols-until", defaultLegacyProtocolsUntil, "在该日期（YYYY-MM-DD 或 RFC3339）前以兼容模式提供旧版本的邮件密钥交换和留言广播协议（空表示不提供）")
+	fs.BoolVar(&cf.inboundGating, "inbound-gating", false, "入站连接需要关系证明：本节点或受信节点的邀请、受信节点的背书，或声誉不低于 -admission-min-reputation")
+	fs.Float64Var(&cf.admissionMinRep, "admission-min-reputation", admission.DefaultMinReputation, "入站准入的声誉下限，只认本节点记录过的声誉（0 表示不按声誉准入）")
+	fs.StringVar(&cf.capabilities, "capabilities", "", "心跳中通告的本节点能力（逗号分隔，如 relay,storage,gpu）")
+	fs.DurationVar(&cf.heartbeatMin, "heartbeat-min-interval", heartbeat.DefaultMinInterval, "心跳和邻居心跳检测的最短间隔，出现故障后收紧到该值")
+	fs.DurationVar(&cf.heartbeatMax, "heartbeat-max-interval", heartbeat.DefaultMaxInterval, "心跳和邻居心跳检测的最长间隔，网络稳定时逐步放宽到该值（心跳需 adaptive-heartbeat 特性激活）")
+	fs.StringVar(&cf.reportMailTo, "report-mail-to", "", "每周活动报告的摘要发送到: self（本节点收件箱）或运营者的节点 ID（空表示只保存，可在管理后台下载）")
+	fs.IntVar(&cf.logReplicas, "log-replicas", 1, "节点日志查询使用的只读副本数（0 表示查询直接读写入端）")
+	cf.backup = registerBackupFlags(fs, "backup-")
 	return cf
 }
 
@@ -172,6 +330,7 @@ func cmdStart() {
 	fs := flag.NewFlagSet("start", flag.ExitOnError)
 	cf := parseCommonFlags(fs)
 	fs.Parse(os.Args[2:])
+	loadProvisionedFlags(fs, cf)
 
 	// 创建守护进程管理器
 	d := daemon.New(&daemon.Config{
@@ -292,7 +451,15 @@ func cmdLogs() {
 func cmdRun() {
 	fs := flag.NewFlagSet("run", flag.ExitOnError)
 	cf := parseCommonFlags(fs)
+	demo := fs.Bool("demo", false, "演示模式：只启动管理后台，使用确定性的模拟数据（前端开发用）")
+	demoSeed := fs.Int64("demo-seed", 42, "演示数据随机种子")
 	fs.Parse(os.Args[2:])
+	loadProvisionedFlags(fs, cf)
+
+	if *demo {
+		runDemo(cf, *demoSeed)
+		return
+	}
 
 	d := daemon.New(&daemon.Config{
 		DataDir: cf.dataDir,
@@ -324,6 +491,16 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		keyPath = cf.dataDir + "/keys/node.key"
 	}
 
+	if err := namespace.Validate(cf.namespace); err != nil {
+		fmt.Fprintf(os.Stderr, "网络命名空间无效: %v\n", err)
+		os.Exit(1)
+	}
+	legacySunset, legacyProtocols, err := parseLegacySunset(cf.legacyUntil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-legacy-protocols-until 无效: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 解析监听地址
 	var addrs []string
 	if cf.listenAddrs != "" {
@@ -347,14 +524,35 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		nodeRole = host.RoleNormal
 	}
 
+	// 拨号策略：优先重连上次保存的邻居和最近可用的缓存节点
+	persistedNeighbors := loadPersistedNeighbors(cf.dataDir)
+	dialPolicy := dialer.NewPolicy(dialer.DefaultConfig())
+	peerCacheConfig := peercache.DefaultConfig()
+	peerCacheConfig.Path = filepath.Join(cf.dataDir, "peer_cache.json")
+	peerCache, err := peercache.New(peerCacheConfig)
+	if err != nil {
+		// 缓存损坏时丢弃，本次从引导节点重新发现
+		fmt.Fprintf(os.Stderr, "⚠️  丢弃损坏的节点缓存: %v\n", err)
+		os.Remove(peerCacheConfig.Path)
+		peerCache, _ = peercache.New(peerCacheConfig)
+	}
+
 	// 创建节点配置
 	cfg := &node.Config{
 		KeyPath:        keyPath,
 		ListenAddrs:    addrs,
 		BootstrapPeers: peers,
 		Role:           nodeRole,
+		DialPolicy:     dialPolicy,
+		PersistedPeers: neighborPeerAddrs(persistedNeighbors),
+		PeerCache:      peerCache,
+		MinPeers:       cf.readyMinPeers,
+
+		BootstrapFallback: cf.bootstrapAfter,
 		EnableRelay:    true,
 		EnableDHT:      true,
+		Namespace:      cf.namespace,
+		GateInbound:    cf.inboundGating,
 	}
 
 	// 创建节点
@@ -365,18 +563,199 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		os.Exit(1)
 	}
 
-	// 启动节点
+	// 本地存储静态加密（邮箱、留言板）
+	nodeSecret, err := n.Identity().PrivKey.Raw()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取节点私钥失败: %v\n", err)
+		os.Exit(1)
+	}
+	storageKeys, err := openStorageKeyring(cf.dataDir, cf.storageKey, nodeSecret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开存储密钥环失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 启动阶段完成情况，供就绪探针使用
+	readiness := newStartupTracker("migrations", "mailbox", "bulletin")
+
+	// 升级本地数据格式（在各存储加载之前，失败时不修改数据）
+	if report, err := runMigrations(cf.dataDir, storageKeys, false); err != nil {
+		fmt.Fprintf(os.Stderr, "数据迁移失败: %v\n", err)
+		fmt.Fprintln(os.Stderr, "数据未修改，可用 'agentnetwork migrate -dry-run' 查看详情")
+		os.Exit(1)
+	} else if report.Migrated() {
+		fmt.Println("已升级本地数据格式:")
+		printMigrationReport(report, false)
+	}
+	readiness.finish("migrations", nil)
+
+	// 邮箱、留言板和激励记录的存储后端（在格式迁移之后打开，旧 JSON 文件由各模块首次加载时导入）
+	nodeStore, err := openNodeStore(cf.dataDir, cf.store, storageKeys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// 子系统按依赖并行启动：P2P 主机与邮箱、留言板的磁盘加载互不依赖，
+	// 其余子系统在 P2P 主机就绪后启动。单个子系统失败或超时只影响依赖它的子系统。
+	boot := startup.New(context.Background(), &startup.Config{DefaultTimeout: cf.startTimeout})
+	nodeID := n.Identity().PeerID.String()
+	var mb *mailbox.Mailbox
+	var bb *bulletin.BulletinBoard
+
 	fmt.Println("正在启动节点...")
-	if err := n.Start(); err != nil {
+	boot.Go("p2p", func(ctx context.Context) error {
+		return n.Start()
+	})
+
+	// 协议版本回退表：弃用窗口内同时提供 v1 协议，与未升级的节点互通
+	protocolCompat := newProtocolCompat(cf.namespace, legacySunset, legacyProtocols)
+
+	// 邮件端到端加密：用收件人节点公钥加密，节点ID中取不到公钥时通过密钥交换握手取得
+	var mailKeys *mailbox.E2E
+	mailKeys, err = mailbox.NewE2E(&mailbox.E2EConfig{
+		NodeID:     nodeID,
+		PrivateKey: ed25519.PrivateKey(nodeSecret),
+		ResolveKey: func(id string) (ed25519.PublicKey, error) {
+			peerID, err := peer.Decode(id)
+			if err != nil {
+				return nil, err
+			}
+			pubKey, err := peerID.ExtractPublicKey()
+			if err != nil {
+				return nil, err
+			}
+			raw, err := pubKey.Raw()
+			if err != nil {
+				return nil, err
+			}
+			return ed25519.PublicKey(raw), nil
+		},
+		VerifyNodeID: func(id string, pub ed25519.PublicKey) bool {
+			pubKey, err := libp2pcrypto.UnmarshalEd25519PublicKey(pub)
+			if err != nil {
+				return false
+			}
+			peerID, err := peer.IDFromPublicKey(pubKey)
+			return err == nil && peerID.String() == id
+		},
+		Handshake: func(id string) (*mailbox.KeyAnnouncement, error) {
+			peerID, err := peer.Decode(id)
+			if err != nil {
+				return nil, err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			// 按新到旧的顺序提供各版本，对方未升级时协商为 v1
+			var protocols []protocol.ID
+			for _, p := range protocolCompat.Protocols(mailKeyCompatFamily) {
+				protocols = append(protocols, protocol.ID(p))
+			}
+			s, err := n.Host().Host().NewStream(ctx, peerID, protocols...)
+			if err != nil {
+				return nil, err
+			}
+			defer s.Close()
+			s.SetDeadline(time.Now().Add(10 * time.Second))
+			negotiated := string(s.Protocol())
+			protocolCompat.Accept(mailKeyCompatFamily, negotiated, id, compat.Outbound)
+			if protocolCompat.IsLegacy(mailKeyCompatFamily, negotiated) {
+				return mailKeys.ExchangeKeysV1(s)
+			}
+			return mailKeys.ExchangeKeys(s)
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  节点密钥不支持邮件端到端加密: %v\n", err)
+	}
+
+	// 初始化邮箱（与本节点其他子系统的关联在全部启动后设置）
+	boot.Go("mailbox", func(ctx context.Context) error {
+		mailboxConfig := mailbox.DefaultConfig(nodeID)
+		mailboxConfig.DataDir = filepath.Join(cf.dataDir, "mailbox")
+		mailboxConfig.ExpiryGrace = cf.ttlGrace
+		mailboxConfig.PublicInbox = cf.publicInbox
+		mailboxConfig.Store = nodeStore
+		// 中继节点为不在线的收件人暂存加密邮件
+		mailboxConfig.Relay = nodeRole == host.RoleRelay
+		m, err := mailbox.NewMailbox(mailboxConfig)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err() // 加载超时，不再接入
+		}
+		readiness.finish("mailbox", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建邮箱失败: %v\n", err)
+			return err
+		}
+		m.SetStorageKeyring(storageKeys)
+		if mailKeys != nil {
+			m.SetEncryptFunc(mailKeys.Encrypt)
+			m.SetDecryptFunc(mailKeys.Decrypt)
+		}
+		// 邮件和送达回执用节点私钥签名，按节点ID验签
+		m.SetSignFunc(n.Identity().PrivKey.Sign)
+		m.SetVerifyFunc(verifyPeerSignature)
+		m.Start()
+		mb = m
+		return nil
+	})
+
+	// 初始化留言板
+	bulletinConfig := bulletin.DefaultBulletinConfig(nodeID)
+	bulletinConfig.DataDir = filepath.Join(cf.dataDir, "bulletin")
+	bulletinConfig.StorageKeyring = storageKeys
+	bulletinConfig.Store = nodeStore
+	bulletinConfig.ExpiryGrace = cf.ttlGrace
+	// 留言用节点私钥签名，广播收到的留言按作者节点ID验签
+	bulletinConfig.SignFunc = signWithNodeKey(n.Identity().PrivKey)
+	bulletinConfig.VerifyFunc = verifyNodeSignature
+	// 发布时为正文链接和附件提取预览（拒绝抓取内网地址）
+	bulletinConfig.PreviewFunc = bulletin.NewPreviewer(nil).Preview
+	// 定向加密留言：节点ID即 Ed25519 公钥，内容密钥用接收方公钥包裹
+	bulletinConfig.WrapKeyFunc = func(recipient string, key []byte) ([]byte, error) {
+		peerID, err := peer.Decode(recipient)
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := peerID.ExtractPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := pubKey.Raw()
+		if err != nil {
+			return nil, err
+		}
+		return crypto.SealToEd25519(ed25519.PublicKey(raw), key)
+	}
+	bulletinConfig.UnwrapKeyFunc = func(wrapped []byte) ([]byte, error) {
+		raw, err := n.Identity().PrivKey.Raw()
+		if err != nil {
+			return nil, err
+		}
+		return crypto.OpenWithEd25519(ed25519.PrivateKey(raw), wrapped)
+	}
+	boot.Go("bulletin", func(ctx context.Context) error {
+		board, err := bulletin.NewBulletinBoard(bulletinConfig)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		readiness.finish("bulletin", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建留言板失败: %v\n", err)
+			return err
+		}
+		board.Start()
+		bb = board
+		return nil
+	})
+
+	if err := boot.Wait("p2p"); err != nil {
 		fmt.Fprintf(os.Stderr, "启动节点失败: %v\n", err)
 		os.Exit(1)
 	}
 
-	// 启动 gRPC 服务
+	// gRPC 服务在 HTTP API 配置完成后启动（消息流复用 HTTP API 的事件和发送逻辑）
 	grpcServer := server.NewServer(n, cf.grpcAddr)
-	if err := grpcServer.Start(); err != nil {
-		fmt.Fprintf(os.Stderr, "启动 gRPC 服务失败: %v\n", err)
-	}
 
 	// 加载或生成 API Token（在创建 HTTP Server 之前）
 	adminToken := cf.adminToken
@@ -385,20 +764,1645 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		adminToken = loadOrGenerateToken(cf.dataDir)
 	}
 
-	// 启动 HTTP API 服务
-	httpConfig := httpapi.DefaultConfig(n.Host().ID().String())
-	httpConfig.ListenAddr = cf.httpAddr
-	httpConfig.APIToken = adminToken // 使用统一的 Token
-	httpServer, err := httpapi.NewServer(httpConfig)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "创建 HTTP 服务失败: %v\n", err)
-	} else {
-		if err := httpServer.Start(); err != nil {
-			fmt.Fprintf(os.Stderr, "启动 HTTP 服务失败: %v\n", err)
-		} else {
-			fmt.Printf("HTTP API 服务已启动: %s\n", cf.httpAddr)
+	// 维护模式
+	maintConfig := maintenance.DefaultConfig(n.Host().ID().String())
+	maintConfig.DataDir = filepath.Join(cf.dataDir, "maintenance")
+	maintManager, err := maintenance.NewManager(maintConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建维护模式管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 协议特性激活（超级节点就绪比例达到阈值后激活）
+	featureConfig := feature.DefaultConfig(n.Host().ID().String())
+	featureConfig.DataDir = filepath.Join(cf.dataDir, "features")
+	features, err := feature.NewManager(featureConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建特性激活管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	features.OnActivated = func(status *feature.Status) {
+		fmt.Printf("协议特性已激活: %s (%d/%d 超级节点就绪)\n", status.Name, status.Ready, status.Supernodes)
+	}
+	if cf.provisioned != nil {
+		for name, ready := range cf.provisioned.Features {
+			if err := features.SetReady(name, ready); err != nil {
+				fmt.Fprintf(os.Stderr, "警告: 清单中的特性 %s 无效: %v\n", name, err)
+			}
+		}
+	}
+
+	// 清单声明的声誉 Webhook（外部评分回调与变化事件推送）
+	// 推送签名密钥独立于节点身份，接收方从公开地址获取公钥验签
+	webhookKeys, err := reputation.LoadOrCreateWebhookKeyring(filepath.Join(cf.dataDir, "keys", "webhook_signing.json"), 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: Webhook 签名密钥不可用: %v\n", err)
+	}
+	webhooks, err := provisionedWebhooks(n.Host().ID().String(), cf.provisioned, webhookKeys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "警告: 声誉 Webhook 未启用: %v\n", err)
+	}
+
+	// 信任网背书（使用节点私钥签名，按背书人节点ID提取公钥验签）
+	endorseConfig := trust.DefaultEndorsementConfig(n.Host().ID().String())
+	endorseConfig.DataDir = filepath.Join(cf.dataDir, "trust")
+	endorseConfig.SignFunc = n.Identity().PrivKey.Sign
+	endorseConfig.VerifyFunc = func(endorserID string, data, signature []byte) (bool, error) {
+		peerID, err := peer.Decode(endorserID)
+		if err != nil {
+			return false, err
+		}
+		pubKey, err := peerID.ExtractPublicKey()
+		if err != nil {
+			return false, err
+		}
+		return pubKey.Verify(data, signature)
+	}
+	endorsements, err := trust.NewEndorsementManager(endorseConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建背书管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 外部身份证明（GitHub gist / Moltbook / DNS TXT），与背书共用签名和验签
+	proofConfig := trust.DefaultIdentityProofConfig(n.Host().ID().String())
+	proofConfig.DataDir = endorseConfig.DataDir
+	proofConfig.SignFunc = endorseConfig.SignFunc
+	proofConfig.VerifyFunc = endorseConfig.VerifyFunc
+	identityProofs, err := trust.NewIdentityProofManager(proofConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建身份证明管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 邻居协助的可达性证明：回拨测试结果用节点私钥签名，证明人权重在邻居管理器就绪后设置
+	reach := reachability.New(n.Host().ID().String(), nil)
+	reach.SetSigner(endorseConfig.SignFunc, endorseConfig.VerifyFunc)
+	reach.SetDialBackFunc(reachabilityDialBack(n))
+
+	// 私钥社交恢复：份额用受托节点公钥包裹，发回的份额由受托节点签名
+	recoveryConfig := recovery.DefaultConfig(n.Host().ID().String())
+	recoveryConfig.DataDir = filepath.Join(cf.dataDir, "recovery")
+	recoveryConfig.WrapFunc = bulletinConfig.WrapKeyFunc
+	recoveryConfig.UnwrapFunc = bulletinConfig.UnwrapKeyFunc
+	recoveryConfig.SignFunc = endorseConfig.SignFunc
+	recoveryConfig.VerifyFunc = endorseConfig.VerifyFunc
+	recoveries, err := recovery.NewManager(recoveryConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建社交恢复管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 全网熔断：超级节点签发的指令经留言板广播，超级节点判断在邻居管理器就绪后设置
+	breakerConfig := breaker.DefaultConfig(n.Host().ID().String())
+	breakerConfig.DataDir = filepath.Join(cf.dataDir, "breaker")
+	breakerConfig.SignFunc = endorseConfig.SignFunc
+	breakerConfig.VerifyFunc = endorseConfig.VerifyFunc
+	breakers, err := breaker.NewManager(breakerConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建熔断管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	breakers.OnDirective = func(d *breaker.Directive) {
+		fmt.Printf("熔断指令: %s %s (签发者 %s, 到期 %s): %s\n", d.Class, d.Action, d.Issuer, d.ExpiresAt.Format(time.RFC3339), d.Reason)
+	}
+
+	// 模块统计注册表，各管理器就绪后注册
+	statsRegistry := stats.NewRegistry()
+	statsRegistry.Register("breaker", breakers)
+	statsRegistry.Register("compat", protocolCompat)
+
+	// 节点间文件传输
+	transferConfig := transfer.DefaultTransferConfig()
+	transferConfig.DataDir = filepath.Join(cf.dataDir, "transfer")
+	transferServiceConfig := transfer.DefaultServiceConfig()
+	transferServiceConfig.IncomingDir = filepath.Join(cf.dataDir, "transfer", "incoming")
+	// API 只能发送发件目录内的文件
+	transferServiceConfig.OutboxDir = filepath.Join(cf.dataDir, "transfer", "outbox")
+	if err := os.MkdirAll(transferServiceConfig.OutboxDir, 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "创建发件目录失败: %v\n", err)
+	}
+	transfers := transfer.NewService(transfer.NewTransferManager(transferConfig), n.Host().ID().String(), transferServiceConfig)
+	// 只自动接受受信联系人发来的文件，其余等待本地通过 API 接受
+	transfers.SetAcceptFunc(func(peerID string) bool {
+		if boot.Wait("mailbox") != nil {
+			return false
+		}
+		c, err := mb.GetContact(peerID)
+		return err == nil && c.Trust == mailbox.TrustTrusted
+	})
+	transferProtocol := protocol.ID(namespace.Protocol(cf.namespace, transfer.ProtocolID))
+	transfers.SetStreamOpener(func(ctx context.Context, peerID string) (io.ReadWriteCloser, error) {
+		pid, err := peer.Decode(peerID)
+		if err != nil {
+			return nil, err
+		}
+		return n.Host().Host().NewStream(ctx, pid, transferProtocol)
+	})
+	n.Host().Host().SetStreamHandler(transferProtocol, func(s network.Stream) {
+		transfers.HandleStream(s, s.Conn().RemotePeer().String())
+	})
+	if mailKeys != nil {
+		for _, id := range protocolCompat.Protocols(mailKeyCompatFamily) {
+			id := id
+			legacy := protocolCompat.IsLegacy(mailKeyCompatFamily, id)
+			n.Host().Host().SetStreamHandler(protocol.ID(id), func(s network.Stream) {
+				defer s.Close()
+				// 过了弃用截止时间的旧版本不再处理
+				if !protocolCompat.Accept(mailKeyCompatFamily, id, s.Conn().RemotePeer().String(), compat.Inbound) {
+					s.Reset()
+					return
+				}
+				s.SetDeadline(time.Now().Add(10 * time.Second))
+				if legacy {
+					mailKeys.HandleKeyExchangeV1(s)
+				} else {
+					mailKeys.HandleKeyExchange(s)
+				}
+			})
+		}
+	}
+
+	// 节点间用量配额：连接建立后交换各自的上限，入站用量超限先丢弃再断开
+	peerQuotaConfig := quota.DefaultPeerQuotaConfig()
+	peerQuotaConfig.Limits = cf.peerLimits
+	peerQuotas := quota.NewPeerQuotas(peerQuotaConfig)
+	peerQuotaProtocol := protocol.ID(namespace.Protocol(cf.namespace, quota.PeerQuotaProtocol))
+	peerQuotas.SetDisconnectFunc(func(peerID string) {
+		if pid, err := peer.Decode(peerID); err == nil {
+			n.Host().Host().Network().ClosePeer(pid)
+		}
+	})
+	n.Host().Host().SetStreamHandler(peerQuotaProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(10 * time.Second))
+		peerQuotas.HandleHandshake(s, s.Conn().RemotePeer().String())
+	})
+	n.Host().Host().Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(nw network.Network, conn network.Conn) {
+			pid := conn.RemotePeer()
+			if peerQuotas.Banned(pid.String()) {
+				go nw.ClosePeer(pid)
+				return
+			}
+			// 续传因断线或重启中断的发往该节点的文件
+			go transfers.ResumeInterrupted(pid.String())
+			// 由发起连接的一方握手，避免重复交换
+			if conn.Stat().Direction != network.DirOutbound {
+				return
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				s, err := n.Host().Host().NewStream(ctx, pid, peerQuotaProtocol)
+				if err != nil {
+					return
+				}
+				defer s.Close()
+				s.SetDeadline(time.Now().Add(10 * time.Second))
+				peerQuotas.Handshake(s, pid.String())
+			}()
+		},
+	})
+
+	// 出站带宽按节点公平调度（权重在邻居管理器初始化后接入）：文件传输按分片申请额度，
+	// 邮件、任务进度、调解频道和声誉同步等流协议的写入经 GateStream 申请
+	outboundConfig := bandwidth.DefaultConfig()
+	outboundConfig.Rate = cf.outboundRate
+	outbound := bandwidth.NewFairScheduler(outboundConfig)
+	outbound.Start()
+	transfers.SetOutboundGate(outbound.Acquire)
+	statsRegistry.Register("bandwidth", stats.ProviderFunc(func() []stats.Metric {
+		return bandwidthMetrics(outbound.Stats())
+	}))
+	statsRegistry.Register("p2p", stats.ProviderFunc(func() []stats.Metric {
+		return []stats.Metric{stats.Gauge("connected_peers", float64(n.Host().ConnectedPeers()), "已连接的节点数")}
+	}))
+
+	// 合规保全（邮箱会话、争议、账本区间）
+	retentionConfig := retention.DefaultConfig()
+	retentionConfig.DataDir = filepath.Join(cf.dataDir, "retention")
+	holds, err := retention.NewManager(retentionConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建合规保全管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 任务模板（邮箱和留言板并行加载，共享和轮换密钥时再引用）
+	templates := task.NewTemplateStore(n.Host().ID().String(), filepath.Join(cf.dataDir, "tasks"))
+	taskManager := newTaskManager(cf.dataDir)
+	statsRegistry.Register("task", taskManager)
+
+	// 声誉管理器：本节点记录的各节点声誉，带历史、上下限、阻尼和长期不活跃衰减
+	reputationConfig := reputation.DefaultManagerConfig()
+	reputationConfig.DataDir = filepath.Join(cf.dataDir, "reputation")
+	reputationConfig.Damping = reputation.DefaultDampingConfig()
+	reputationManager, err := reputation.NewManager(reputationConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建声誉管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	reputationManager.Start()
+	statsRegistry.Register("reputation", reputationManager)
+	// 外部身份证明首次通过验证时计入起始声誉，隔离时长的缩短见 quarantineScale
+	identityProofs.OnProofVerified = grantStarterReputation(identityProofs, reputationManager)
+	// 邻居间按版本增量推送声誉表，对端的表只作为邻居视图，不写入本地声誉
+	reputationViews := reputation.NewDeltaSync(reputation.DefaultDeltaConfig())
+	statsRegistry.Register("reputation_sync", reputationViews)
+	reputationSyncProtocol := protocol.ID(namespace.Protocol(cf.namespace, reputation.DeltaSyncProtocol))
+	n.Host().Host().SetStreamHandler(reputationSyncProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(30 * time.Second))
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		remote := s.Conn().RemotePeer().String()
+		reputationViews.HandlePush(outbound.GateStream(ctx, remote, s), remote)
+	})
+	// 邻居查询其他节点的声誉时，用本地声誉表签发证明
+	reputationAttestProtocol := protocol.ID(namespace.Protocol(cf.namespace, reputation.AttestationProtocol))
+	n.Host().Host().SetStreamHandler(reputationAttestProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(30 * time.Second))
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		reputation.ServeAttestation(outbound.GateStream(ctx, s.Conn().RemotePeer().String(), s), attestReputation(reputationManager, nodeID, n.Identity().PrivKey.Sign))
+	})
+	n.Host().Host().Network().Notify(&network.NotifyBundle{
+		DisconnectedF: func(nw network.Network, conn network.Conn) {
+			if nw.Connectedness(conn.RemotePeer()) != network.Connected {
+				reputationViews.ForgetPeer(conn.RemotePeer().String())
+			}
+		},
+	})
+	// 事件账本：声誉变化以本节点签名写入哈希链，verify-ledger 重放后与声誉存储比对
+	eventLedger, err := ledger.NewLedger(filepath.Join(cf.dataDir, "ledger"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开事件账本失败: %v\n", err)
+		os.Exit(1)
+	}
+	eventLedger.SetSignFunc(signWithNodeKey(n.Identity().PrivKey))
+	eventLedger.SetVerifyFunc(verifyNodeSignature)
+	// 按类型保留期定期压缩，保全范围内的事件不删除
+	eventLedger.SetHoldFunc(holds.LedgerRangeHeld)
+	eventLedger.StartCompaction(time.Hour)
+	recordReputation := recordReputationChange(eventLedger, nodeID)
+	// 节点日志：运营者和本地智能体经日志 API 提交的事件，查询交给只读副本
+	nodeLogger, err := newNodeLogger(cf.dataDir, nodeID, cf.logReplicas, signWithNodeKey(n.Identity().PrivKey))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开节点日志失败: %v\n", err)
+		os.Exit(1)
+	}
+	nodeLogger.Start()
+	nodeLogger.Info(logging.EventSystemStart, map[string]interface{}{"version": version})
+	// 审计评审人按本节点记录的声誉排序，已验证同一外部账号的节点视为同一运营者
+	taskManager.SetReviewerReputationFunc(reputationManager.GetReputation)
+	taskManager.SetOperatorFunc(proofOperators(identityProofs))
+	// 同等匹配的执行方按公共可达性分数排序
+	taskManager.SetReachabilityFunc(reach.ScoreValue)
+
+	// 入站连接准入：开启 -inbound-gating 后，连入的节点需要邀请、受信节点背书或足够的声誉
+	admissionConfig := admission.DefaultConfig(nodeID)
+	admissionConfig.DataDir = filepath.Join(cf.dataDir, "admission")
+	admissionConfig.Enabled = cf.inboundGating
+	admissionConfig.MinReputation = cf.admissionMinRep
+	admissionConfig.Allow = admissionAllowList(peers, persistedNeighbors)
+	admissionConfig.SignFunc = endorseConfig.SignFunc
+	admissionConfig.VerifyFunc = endorseConfig.VerifyFunc
+	admissions, err := admission.New(admissionConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建准入管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	wireAdmission(admissions, nodeID, endorsements, reputationManager)
+	n.Host().SetAdmitFunc(admissions.Admit)
+	statsRegistry.Register("admission", admissions)
+
+	// 创世与邀请制加入：签名邀请码单次有效，加入记录构成邀请树；
+	// 凭邀请码加入的节点按邀请函绑定的 libp2p 节点ID通过入站准入
+	genesisManager, err := genesis.NewGenesisManager(filepath.Join(cf.dataDir, "genesis"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开创世数据失败: %v\n", err)
+		os.Exit(1)
+	}
+	admissions.SetMemberFunc(genesisManager.IsPeerJoined)
+
+	// $DAAN 代币账本：激励奖励铸造、签名转账和任务预付报酬，转账和铸造按节点公钥验签。
+	// 本节点铸造的奖励和支付的任务报酬以本节点签名记账，连同签名转账一起广播给其他节点
+	tokenConfig := token.DefaultConfig()
+	tokenConfig.DataDir = filepath.Join(cf.dataDir, "token")
+	tokenConfig.VerifyFunc = verifyNodeSignature
+	tokenConfig.NodeID = nodeID
+	tokenConfig.SignFunc = signWithNodeKey(n.Identity().PrivKey)
+	tokenLedger, err := token.NewLedger(tokenConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建代币账本失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 激励：任务结算后为执行者记录奖励，奖励计入声誉并确认后铸造代币
+	incentiveConfig := incentive.DefaultIncentiveConfig(nodeID)
+	incentiveConfig.DataDir = filepath.Join(cf.dataDir, "incentive")
+	incentiveConfig.Store = nodeStore
+	incentiveConfig.Reputation = reputationManager
+	incentiveConfig.ToleranceMultiplierFunc = endorsements.ToleranceMultiplier
+	incentiveManager, err := incentive.NewIncentiveManager(incentiveConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建激励系统失败: %v\n", err)
+		os.Exit(1)
+	}
+	incentiveManager.OnRewardCreated = tokenLedger.RewardHook()
+	incentiveManager.Start()
+
+	// 存储证明：委托方按原始文件登记存储任务的承诺，定期随机抽取分块挑战执行方；
+	// 证明失败或超时未响应时按比例扣减该任务的奖励，并对存储方发起数据损坏指责
+	accusationConfig := accusation.DefaultAccusationConfig(nodeID)
+	accusationConfig.DataDir = filepath.Join(cf.dataDir, "accusation")
+	accusationConfig.Store = nodeStore
+	accusationConfig.SignFunc = signWithNodeKey(n.Identity().PrivKey)
+	accusationConfig.Reputation = reputationManager
+	accusations, err := accusation.NewAccusationManager(accusationConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建指责系统失败: %v\n", err)
+		os.Exit(1)
+	}
+	accusations.Start()
+	storageProofConfig := incentive.DefaultStorageProofConfig(nodeID)
+	storageProofConfig.DataDir = filepath.Join(cf.dataDir, "incentive")
+	storageProofConfig.AccuseFunc = func(accused, reason, evidence string) error {
+		_, err := accusations.CreateAccusation(accused, accusation.TypeDataCorruption, reason, evidence)
+		return err
+	}
+	storageProofs, err := incentive.NewStorageProofManager(incentiveManager, storageProofConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建存储证明失败: %v\n", err)
+		os.Exit(1)
+	}
+	storageProofs.OnChallengeFailed = func(c *incentive.StorageChallenge) {
+		fmt.Printf("⚠️  存储任务 %s 的挑战失败: %s (%s)\n", c.TaskID, c.NodeID, c.FailReason)
+	}
+	// 本节点作为存储方时，只响应委托方对自己持有的数据的挑战
+	storageHolds := loadStorageHoldings(cf.dataDir)
+	storageProofProtocol := protocol.ID(namespace.Protocol(cf.namespace, incentive.StorageProofProtocol))
+	n.Host().Host().SetStreamHandler(storageProofProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(storageChallengeTimeout))
+		ctx, cancel := context.WithTimeout(context.Background(), storageChallengeTimeout)
+		defer cancel()
+		remote := s.Conn().RemotePeer().String()
+		incentive.ServeStorageProof(outbound.GateStream(ctx, remote, s), func(c *incentive.StorageChallenge) (*incentive.StorageProof, error) {
+			return storageHolds.prove(remote, c)
+		})
+	})
+	storageChallenge := challengeStorage(storageProofs, func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) {
+		pid, err := peer.Decode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		s, err := n.Host().Host().NewStream(ctx, pid, storageProofProtocol)
+		if err != nil {
+			return nil, err
+		}
+		s.SetDeadline(time.Now().Add(storageChallengeTimeout))
+		return outbound.GateStream(ctx, nodeID, s), nil
+	})
+	stopStorageChallenges := startStorageChallenges(storageProofs, storageChallenge, storageTaskActive(taskManager), storageChallengeInterval)
+
+	// 任务结算时支付预付报酬并记录奖励，取消或过期时退回预付报酬；状态变化广播给其他节点
+	payments := taskPayments{tasks: taskManager, tokens: tokenLedger, incentive: incentiveManager}
+	taskNet := newTaskNetwork(taskManager, nodeID, reputationManager.GetReputation)
+	// 其他节点新发布的任务进入邀约队列，按单位报酬、委托方声誉、截止时间和本节点能力排序
+	offerConfig := task.DefaultOfferQueueConfig()
+	offerConfig.Capabilities = parseCapabilities(cf.capabilities)
+	offerConfig.ReputationFunc = reputationManager.GetReputation
+	if taskNet.offers, err = task.NewOfferQueue(offerConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "创建任务邀约队列失败: %v\n", err)
+		os.Exit(1)
+	}
+	taskManager.SetSettlementHandler(func(r *task.SettlementResult) {
+		payments.settled(r)
+		taskNet.announce(r.TaskID)
+	})
+	taskManager.SetCancelHandler(func(t *task.Task) {
+		payments.refund(t.ID)
+		taskNet.announce(t.ID)
+	})
+	// 评审人超时补选后把新的分配广播给评审人
+	taskManager.SetReviewHandler(taskNet.reviewers)
+	// 执行方经 P2P 流向委托方推送进度，执行者身份取自连接的对端节点
+	taskProgressProtocol := protocol.ID(namespace.Protocol(cf.namespace, task.ProgressProtocolID))
+	n.Host().Host().SetStreamHandler(taskProgressProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetReadDeadline(time.Now().Add(time.Minute))
+		if err := taskManager.HandleProgressStream(s, s.Conn().RemotePeer().String()); err != nil {
+			fmt.Printf("⚠️  %s 推送的任务进度无效: %v\n", s.Conn().RemotePeer(), err)
+		}
+	})
+	taskManager.SetProgressSendFunc(progressSender(nodeID, func(ctx context.Context, requester string) (io.WriteCloser, error) {
+		pid, err := peer.Decode(requester)
+		if err != nil {
+			return nil, err
+		}
+		s, err := n.Host().Host().NewStream(ctx, pid, taskProgressProtocol)
+		if err != nil {
+			return nil, err
+		}
+		return outbound.GateStream(ctx, requester, s), nil
+	}))
+
+	// 押金托管：存入、释放、退款和仲裁签名都按节点公钥验签，超时未结清的托管定期退款。
+	// 指定 -token-funds 时押金在代币账本上锁定，否则只在托管内记账。
+	escrowConfig := escrow.DefaultEscrowConfig()
+	escrowConfig.DataDir = filepath.Join(cf.dataDir, "escrow")
+	escrowConfig.VerifyFunc = verifyNodeSignature
+	escrowManager := escrow.NewEscrowManager(escrowConfig)
+	if cf.tokenFunds {
+		escrowManager.SetFunds(tokenLedger)
+	}
+	escrowManager.Start()
+	wireSubcontracts(taskManager, escrowManager, reputationManager)
+
+	// 抵押账户：按节点和用途记账，超级节点候选押金从中锁定，审计偏差凭证据罚没。
+	// 与押金托管一样由 -token-funds 决定是否锁定代币。
+	collateralManager := collateral.NewCollateralManager()
+	if cf.tokenFunds {
+		collateralManager.SetFunds(tokenLedger)
+	}
+	if err := collateralManager.SetDataDir(filepath.Join(cf.dataDir, "collateral")); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  加载抵押账户失败: %v\n", err)
+	}
+
+	// 超级节点候选与选举：候选抵押在审计抵押用途下实际锁定，与审计偏离的罚没是同一个账户
+	superConfig := supernode.DefaultConfig(nodeID)
+	superConfig.DataDir = filepath.Join(cf.dataDir, "supernode")
+	superNodes, err := supernode.NewSuperNodeManager(superConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建超级节点管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	superNodes.SetStakeLocker(supernode.NewCollateralStake(collateralManager, auditCollateralPurpose))
+	superNodes.Start()
+
+	// 审计：执行方交付时保存签名的执行记录，超级节点复核后广播结论，
+	// 与共识不一致的审计者扣减声誉并罚没审计抵押
+	auditConfig := audit.DefaultConfig(nodeID)
+	auditConfig.DataDir = filepath.Join(cf.dataDir, "task_audit")
+	auditConfig.SignFunc = endorseConfig.SignFunc
+	auditConfig.VerifyFunc = endorseConfig.VerifyFunc
+	audits, err := audit.New(auditConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建审计管理器失败: %v\n", err)
+		os.Exit(1)
+	}
+	wireAudit(audits, reputationManager, collateralManager)
+	auditNet := newAuditNetwork(audits, nodeID)
+	statsRegistry.Register("audit", audits)
+
+	// 争议预审：证据原件保存在争议目录下，裁决后为关联的托管提出结算方案
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = filepath.Join(cf.dataDir, "dispute")
+	// 仲裁开始时建立调解频道，频道密钥与定向加密留言一样用成员的节点公钥包裹
+	disputeConfig.WrapKeyFunc = bulletinConfig.WrapKeyFunc
+	disputeConfig.UnwrapKeyFunc = bulletinConfig.UnwrapKeyFunc
+	// 监管链导出包由本节点私钥签名，审阅方凭导出节点ID验签
+	disputeConfig.BundleSignFunc = endorseConfig.SignFunc
+	if raw, err := n.Identity().PrivKey.GetPublic().Raw(); err == nil {
+		disputeConfig.BundlePublicKey = raw
+		disputeConfig.BundleAlgorithm = strings.ToLower(n.Identity().PrivKey.Type().String())
+	}
+	disputeManager := dispute.NewDisputeManager(disputeConfig)
+	disputeManager.SetRetentionHoldFunc(func(disputeID string) bool {
+		return holds.IsHeld(retention.KindDispute, disputeID)
+	})
+	disputeManager.SetResolutionHandler(proposeEscrowResolution(escrowManager, nodeID))
+	// 被诉方和仲裁员经 P2P 流在申诉方节点保存的调解频道中发言和读取记录，身份取自连接的对端节点
+	disputeChannelProtocol := protocol.ID(namespace.Protocol(cf.namespace, dispute.ChannelProtocol))
+	n.Host().Host().SetStreamHandler(disputeChannelProtocol, func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(30 * time.Second))
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		remote := s.Conn().RemotePeer().String()
+		disputeManager.HandleChannelRequest(outbound.GateStream(ctx, remote, s), remote)
+	})
+	mediation := disputeChannel{m: disputeManager, self: nodeID, dial: func(ctx context.Context, holder string) (io.ReadWriteCloser, error) {
+		pid, err := peer.Decode(holder)
+		if err != nil {
+			return nil, err
+		}
+		s, err := n.Host().Host().NewStream(ctx, pid, disputeChannelProtocol)
+		if err != nil {
+			return nil, err
+		}
+		return outbound.GateStream(ctx, holder, s), nil
+	}}
+
+	// 争议、托管和抵押的联合查询在同一快照上读取，跨存储写入经由 snapshots 协调
+	snapshots := newSnapshotReader(disputeManager, escrowManager, collateralManager)
+
+	// Prometheus 指标导出（-metrics-addr 启用），HTTP/gRPC 耗时在服务创建时接入
+	var exporter *metrics.Exporter
+	if cf.metricsAddr != "" {
+		exporter, err = metrics.New(metrics.DefaultConfig(statsRegistry))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建指标导出失败: %v\n", err)
+			os.Exit(1)
+		}
+		grpcServer.SetLatencyObserver(exporter.ObserveGRPC)
+	}
+
+	// 启动 HTTP API 服务
+	httpConfig := httpapi.DefaultConfig(n.Host().ID().String())
+	httpConfig.ListenAddr = cf.httpAddr
+	httpConfig.APIToken = adminToken // 使用统一的 Token
+	if compat, err := httpapi.LoadCompatConfig(filepath.Join(cf.dataDir, "api_compat.json")); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  加载 API 兼容配置失败: %v\n", err)
+	} else {
+		httpConfig.Compat = compat
+	}
+	// 按角色限权的 API Key（token create / token revoke 修改后无需重启），策略无效时只接受访问令牌
+	apiKeys, rbacPolicy, err := openAPIKeys(cf.dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v，API Key 未启用\n", err)
+	} else {
+		httpConfig.Roles = auth.NewAuthorizer(apiKeys, rbacPolicy, rbacPolicy.HTTPRoutes)
+	}
+	// API 使用统计与弃用提示
+	if deprecations, err := httpapi.LoadDeprecationConfig(filepath.Join(cf.dataDir, "api_deprecation.json")); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  加载 API 弃用配置失败: %v\n", err)
+	} else {
+		httpConfig.Deprecations = deprecations
+	}
+	apiUsage, err := httpapi.OpenAPIUsage(filepath.Join(cf.dataDir, "api_usage.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  加载 API 使用统计失败，重新开始统计: %v\n", err)
+	}
+	httpConfig.APIUsage = apiUsage
+	// 按令牌计量 API 用量，按月导出计费记录；API Key 按 Key ID 计量
+	tokenMeter, err := httpapi.OpenTokenMeter(filepath.Join(cf.dataDir, "api_metering.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  加载 API 计量记录失败，重新开始计量: %v\n", err)
+	}
+	httpConfig.TokenMeter = tokenMeter
+	if apiKeys != nil {
+		httpConfig.MeterAccount = apiKeyAccount(apiKeys)
+	}
+	// 请求数和出错数同时供金丝雀发布对比
+	apiRequests := &requestCounter{}
+	var observeHTTP httpapi.RequestObserver
+	if exporter != nil {
+		observeHTTP = exporter.ObserveHTTP
+	}
+	httpConfig.RequestObserver = apiRequests.observe(observeHTTP)
+	// 响应签名：节点ID即公钥，其他 agent 可凭节点ID验签并把响应作为证据
+	httpConfig.ResponseSignFunc = n.Identity().PrivKey.Sign
+	httpConfig.ResponseVerifyFunc = endorseConfig.VerifyFunc
+	if raw, err := n.Identity().PrivKey.GetPublic().Raw(); err == nil {
+		httpConfig.SigningPublicKey = raw
+		httpConfig.SigningAlgorithm = strings.ToLower(n.Identity().PrivKey.Type().String())
+	}
+	httpConfig.SignResponses = cf.signResponses
+	// 对端回调（如消息接收）必须用自己的节点私钥签名，按声称的节点ID验签
+	httpConfig.CallbackVerifyFunc = endorseConfig.VerifyFunc
+	httpConfig.RejectUnknownFields = cf.strictAPI
+	httpConfig.Namespace = cf.namespace
+	// 按路由的身份要求：签名者须为本节点或与本节点完成过身份握手（identify）的节点
+	routePolicies, err := httpapi.ParseRoutePolicies(cf.routeAuth)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无效的 -route-auth: %v\n", err)
+		os.Exit(1)
+	}
+	httpConfig.RoutePolicies = routePolicies
+	httpConfig.SignedRequests = cf.signedRequests
+	// 签名身份须在本节点的成员登记中：自身、创世加入记录，邻居表和投票登记就绪后加入
+	members := &memberRegistry{}
+	members.add(func(id string) bool { return id == nodeID })
+	members.add(genesisManager.IsPeerJoined)
+	httpConfig.IdentityRegisteredFunc = members.registered
+	// 变更类 API 调用审计日志（哈希链，节点私钥逐条签名）；链断裂时原日志留作证据并开始新链
+	mutationAuditConfig := httpapi.DefaultMutationAuditConfig(filepath.Join(cf.dataDir, "audit", "api_mutations.jsonl"))
+	mutationAuditConfig.SignFunc = n.Identity().PrivKey.Sign
+	mutationAuditConfig.VerifyFunc = n.Identity().PrivKey.GetPublic().Verify
+	mutationAudit, err := httpapi.OpenMutationAuditLog(mutationAuditConfig)
+	if errors.Is(err, httpapi.ErrAuditChainBroken) {
+		fmt.Fprintf(os.Stderr, "⚠️  API 变更审计日志校验失败，可能已被篡改: %v\n", err)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  打开 API 变更审计日志失败: %v\n", err)
+	}
+	httpConfig.MutationAudit = mutationAudit
+	httpServer, err := httpapi.NewServer(httpConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建 HTTP 服务失败: %v\n", err)
+	} else {
+		httpServer.LivenessChecksFunc = func() []httpapi.ProbeCheck {
+			return []httpapi.ProbeCheck{readiness.livenessCheck()}
+		}
+		httpServer.ReadinessChecksFunc = func() []httpapi.ProbeCheck {
+			checks := readiness.stageChecks()
+			kad := n.Host().DHT()
+			routing := 0
+			if kad != nil {
+				routing = kad.RoutingTable().Size()
+			}
+			checks = append(checks, dhtCheck(kad != nil, routing, cf.readyMinPeers))
+			return append(checks, peersCheck(n.Host().ConnectedPeers(), cf.readyMinPeers))
+		}
+		httpServer.Reputation = reputationService{m: reputationManager, views: reputationViews}
+		httpServer.Escrow = escrowService{m: escrowManager, self: nodeID, sign: signWithNodeKey(n.Identity().PrivKey)}
+		httpServer.Dispute = disputeService{m: disputeManager, escrows: escrowManager, snapshots: snapshots, self: nodeID}
+		httpServer.DisputeChannelPostFunc = mediation.post
+		httpServer.DisputeChannelTranscriptFunc = mediation.transcript
+		httpServer.DisputeBundleFunc = exportDisputeBundle(disputeManager, escrowManager, nodeID)
+		httpServer.Collateral = collateralService{m: collateralManager, self: nodeID}
+		httpServer.IncentiveToleranceFunc = func(nodeID string) (int, int) {
+			return toleranceLevels(incentiveManager, nodeID)
+		}
+		httpServer.IncentiveToleranceInputsFunc = func(nodeID string) map[string]interface{} {
+			return toleranceInputs(incentiveManager, nodeID)
+		}
+		registerSuperNodeAPI(httpServer, superNodes, nodeID, reputationManager.GetReputation)
+		registerLogAPI(httpServer, nodeLogger)
+		httpServer.Token = tokenService{l: tokenLedger, tasks: taskManager, self: nodeID, sign: signWithNodeKey(n.Identity().PrivKey)}
+		httpServer.LedgerVerifyFunc = func() map[string]interface{} {
+			return toMap(verifyLedgers(ledgerSources{events: eventLedger, tokens: tokenLedger, reputations: reputationManager}))
+		}
+		httpServer.LedgerUsageFunc = func() map[string]interface{} {
+			return ledgerUsage(eventLedger)
+		}
+		httpServer.SnapshotQuery = snapshotQuery{reader: snapshots, disputes: disputeManager, escrows: escrowManager, collaterals: collateralManager, self: nodeID}
+		httpServer.InMaintenanceFunc = maintManager.IsEnabled
+		httpServer.MaintenanceStatusFunc = func() map[string]interface{} {
+			return maintenanceStatusMap(maintManager.GetStatus())
+		}
+		httpServer.MaintenanceSetFunc = func(enabled bool, reason string, duration time.Duration) (map[string]interface{}, error) {
+			var err error
+			if enabled {
+				_, err = maintManager.Enable(reason, duration)
+			} else {
+				_, err = maintManager.Disable()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return maintenanceStatusMap(maintManager.GetStatus()), nil
+		}
+		httpServer.BreakerAllowFunc = breakers.Allow
+		httpServer.BreakerStatusFunc = func() interface{} {
+			return map[string]interface{}{"classes": breakers.Status()}
+		}
+		httpServer.BreakerIssueFunc = func(req *httpapi.BreakerDirectiveRequest) (interface{}, error) {
+			d, err := breakers.Issue(req.Class, breaker.Action(req.Action), req.Rate, req.Reason, time.Duration(req.Duration)*time.Second)
+			if err != nil {
+				return nil, err
+			}
+			result := map[string]interface{}{"directive": d}
+			if messageID, err := publishBreakerDirective(bb, d); err != nil {
+				result["publish_error"] = err.Error()
+			} else {
+				result["message_id"] = messageID
+			}
+			return result, nil
+		}
+		httpServer.BreakerOverrideFunc = func(req *httpapi.BreakerOverrideRequest) (interface{}, error) {
+			return breakers.SetOverride(req.Class, breaker.Action(req.Action), req.Rate, req.Reason, time.Duration(req.Duration)*time.Second)
+		}
+		httpServer.BreakerClearOverrideFunc = breakers.ClearOverride
+		httpServer.FeatureListFunc = func() []map[string]interface{} {
+			statuses := features.ListStatus()
+			result := make([]map[string]interface{}, 0, len(statuses))
+			for _, status := range statuses {
+				result = append(result, featureStatusMap(status))
+			}
+			return result
+		}
+		httpServer.FeatureStatusFunc = func(name string) (map[string]interface{}, error) {
+			status, err := features.GetStatus(name)
+			if err != nil {
+				return nil, err
+			}
+			return featureStatusMap(status), nil
+		}
+		httpServer.FeatureReadyFunc = func(name string, ready bool) (map[string]interface{}, error) {
+			if err := features.SetReady(name, ready); err != nil {
+				return nil, err
+			}
+			status, err := features.GetStatus(name)
+			if err != nil {
+				return nil, err
+			}
+			return featureStatusMap(status), nil
+		}
+		httpServer.ManifestFunc = func() *httpapi.Manifest {
+			protocols := make([]string, 0)
+			for _, id := range n.Host().Host().Mux().Protocols() {
+				protocols = append(protocols, string(id))
+			}
+			sort.Strings(protocols)
+			endpoints := map[string]string{"http": cf.httpAddr, "grpc": cf.grpcAddr}
+			for i, addr := range n.Host().Addrs() {
+				endpoints[fmt.Sprintf("p2p.%d", i)] = fmt.Sprintf("%s/p2p/%s", addr, n.Host().ID())
+			}
+			if cf.metricsAddr != "" {
+				endpoints["metrics"] = cf.metricsAddr
+			}
+			return &httpapi.Manifest{
+				Version:   version,
+				Features:  activeFeatures(features.ListStatus()),
+				Protocols: protocols,
+				KeyAlgorithms: map[string]string{
+					"identity":   httpConfig.SigningAlgorithm,
+					"encryption": "x25519-aes-256-gcm",
+				},
+				Limits: map[string]int64{
+					"outbound_rate": cf.outboundRate,
+				},
+				Endpoints: endpoints,
+			}
+		}
+		if webhooks != nil {
+			httpServer.ReputationWebhookFunc = func(header http.Header, body []byte) (map[string]interface{}, error) {
+				adj, err := webhooks.HandleCallback(header, body)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"node_id": adj.NodeID, "delta": adj.Delta}, nil
+			}
+		}
+		httpServer.TrustEndorseFunc = func(req *httpapi.EndorseRequest) (map[string]interface{}, error) {
+			e, err := endorsements.Endorse(req.Subject, req.SubjectKey, req.Statement, time.Duration(req.TTL)*time.Second)
+			if err != nil {
+				return nil, err
+			}
+			return endorsementMap(e), nil
+		}
+		httpServer.TrustEndorsementsFunc = func(nodeID string) []map[string]interface{} {
+			return endorsementMaps(endorsements.GetEndorsementsFor(nodeID))
+		}
+		httpServer.TrustDistanceFunc = endorsements.TrustDistance
+		httpServer.TrustProofCreateFunc = func(req *httpapi.IdentityProofRequest) (map[string]interface{}, error) {
+			p, err := identityProofs.CreateProof(trust.ProofKind(req.Kind), req.Handle, req.Location)
+			if err != nil {
+				return nil, err
+			}
+			return proofMap(p), nil
+		}
+		httpServer.TrustProofVerifyFunc = func(proofID string) (map[string]interface{}, error) {
+			p, err := identityProofs.Verify(context.Background(), proofID)
+			if err != nil {
+				return nil, err
+			}
+			return proofMap(p), nil
+		}
+		httpServer.TrustProofImportFunc = func(record []byte) (map[string]interface{}, error) {
+			var p trust.IdentityProof
+			if err := json.Unmarshal(record, &p); err != nil {
+				return nil, err
+			}
+			imported, err := identityProofs.ImportProof(context.Background(), &p)
+			if err != nil {
+				return nil, err
+			}
+			return proofMap(imported), nil
+		}
+		httpServer.TrustProofsFunc = func(nodeID string) map[string]interface{} {
+			proofs := make([]map[string]interface{}, 0)
+			for _, p := range identityProofs.GetProofsFor(nodeID) {
+				proofs = append(proofs, proofMap(p))
+			}
+			return map[string]interface{}{
+				"node_id":               nodeID,
+				"proofs":                proofs,
+				"starter_reputation":    identityProofs.StarterReputation(nodeID),
+				"quarantine_multiplier": identityProofs.QuarantineMultiplier(nodeID),
+			}
+		}
+		httpServer.ReachabilityFunc = func(nodeID string) map[string]interface{} {
+			result := toMap(reach.Score(nodeID))
+			result["attestations"] = reach.Attestations(nodeID)
+			return result
+		}
+		httpServer.ReachabilityTestFunc = func(nodeID string) (map[string]interface{}, error) {
+			a, err := reach.Test(context.Background(), nodeID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(a), nil
+		}
+		httpServer.ReachabilityImportFunc = func(record []byte) (map[string]interface{}, error) {
+			var a reachability.Attestation
+			if err := json.Unmarshal(record, &a); err != nil {
+				return nil, err
+			}
+			if err := reach.Add(&a); err != nil {
+				return nil, err
+			}
+			return toMap(reach.Score(a.Subject)), nil
+		}
+		// 按能力发现：宣告的技能保存到数据目录，重启后重新宣告
+		if disc := n.Discovery(); disc != nil {
+			saveSkills := func() {
+				var skills []string
+				for _, a := range disc.Announcements() {
+					skills = append(skills, a.Skill)
+				}
+				if err := saveAnnouncedSkills(cf.dataDir, skills); err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  保存宣告的技能失败: %v\n", err)
+				}
+			}
+			for _, skill := range loadAnnouncedSkills(cf.dataDir) {
+				if _, err := disc.Announce(skill); err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  重新宣告技能 %s 失败: %v\n", skill, err)
+				}
+			}
+			httpServer.DiscoveryAnnounceFunc = func(skill string) (map[string]interface{}, error) {
+				a, err := disc.Announce(skill)
+				if err != nil {
+					return nil, err
+				}
+				saveSkills()
+				return toMap(a), nil
+			}
+			httpServer.DiscoveryWithdrawFunc = func(skill string) error {
+				if err := disc.Withdraw(skill); err != nil {
+					return err
+				}
+				saveSkills()
+				return nil
+			}
+			httpServer.DiscoveryAnnouncementsFunc = func() []map[string]interface{} {
+				announcements := disc.Announcements()
+				result := make([]map[string]interface{}, 0, len(announcements))
+				for _, a := range announcements {
+					result = append(result, toMap(a))
+				}
+				return result
+			}
+			httpServer.DiscoveryFindFunc = func(skill string, limit int) ([]map[string]interface{}, error) {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				providers, err := disc.FindProviders(ctx, skill, limit)
+				if err != nil {
+					return nil, err
+				}
+				result := make([]map[string]interface{}, 0, len(providers))
+				for _, p := range providers {
+					addrs := make([]string, 0, len(p.Addrs))
+					for _, addr := range p.Addrs {
+						addrs = append(addrs, addr.String())
+					}
+					result = append(result, map[string]interface{}{
+						"node_id": p.ID.String(),
+						"addrs":   addrs,
+					})
+				}
+				return result, nil
+			}
+		}
+		if webhookKeys != nil {
+			httpServer.WebhookKeysFunc = func() []map[string]interface{} {
+				keys := webhookKeys.PublicKeys()
+				result := make([]map[string]interface{}, 0, len(keys))
+				for _, k := range keys {
+					result = append(result, toMap(k))
+				}
+				return result
+			}
+			httpServer.WebhookKeyRotateFunc = func() (map[string]interface{}, error) {
+				key, err := webhookKeys.Rotate()
+				if err != nil {
+					return nil, err
+				}
+				return toMap(key), nil
+			}
+		}
+		httpServer.StatsFunc = func() map[string]interface{} {
+			return toMap(statsRegistry.Snapshot())
+		}
+		httpServer.StatsModuleFunc = func(module string) (map[string]interface{}, error) {
+			ms, err := statsRegistry.Collect(module)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(ms), nil
+		}
+		httpServer.RecoveryEscrowFunc = func(guardians []string, threshold int) (map[string]interface{}, error) {
+			key, err := os.ReadFile(keyPath)
+			if err != nil {
+				return nil, err
+			}
+			escrow, envelopes, err := recoveries.CreateEscrow(key, guardians, threshold)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"escrow": escrow, "envelopes": envelopes}, nil
+		}
+		httpServer.RecoveryHoldFunc = func(record []byte) (map[string]interface{}, error) {
+			var held struct {
+				Escrow   *recovery.Escrow        `json:"escrow"`
+				Envelope *recovery.ShareEnvelope `json:"envelope"`
+			}
+			if err := json.Unmarshal(record, &held); err != nil {
+				return nil, err
+			}
+			if held.Escrow == nil || held.Envelope == nil {
+				return nil, errors.New("escrow and envelope are required")
+			}
+			if err := recoveries.HoldShare(held.Escrow, held.Envelope); err != nil {
+				return nil, err
+			}
+			return toMap(held.Escrow), nil
+		}
+		httpServer.RecoveryEscrowGetFunc = func(escrowID string) (map[string]interface{}, error) {
+			escrow, err := recoveries.GetEscrow(escrowID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(escrow), nil
+		}
+		httpServer.RecoveryRequestFunc = func(escrowID, requester, reason string) (map[string]interface{}, error) {
+			req, err := recoveries.SubmitRequest(escrowID, requester, reason)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(req), nil
+		}
+		httpServer.RecoveryRequestsFunc = func() []map[string]interface{} {
+			list := recoveries.Requests()
+			result := make([]map[string]interface{}, 0, len(list))
+			for _, req := range list {
+				result = append(result, toMap(req))
+			}
+			return result
+		}
+		httpServer.RecoveryDecideFunc = func(requestID string, approve bool, note string) (map[string]interface{}, error) {
+			if !approve {
+				if err := recoveries.Reject(requestID, note); err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"request_id": requestID, "status": recovery.RequestRejected}, nil
+			}
+			share, err := recoveries.Approve(requestID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"request_id": requestID, "status": recovery.RequestApproved, "share": share}, nil
+		}
+		httpServer.RecoveryCeremonyFunc = func(record []byte) (map[string]interface{}, error) {
+			var escrow recovery.Escrow
+			if err := json.Unmarshal(record, &escrow); err != nil {
+				return nil, err
+			}
+			c, err := recoveries.StartCeremony(&escrow)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(c), nil
+		}
+		httpServer.RecoveryShareFunc = func(ceremonyID string, record []byte) (map[string]interface{}, error) {
+			var share recovery.ReleasedShare
+			if err := json.Unmarshal(record, &share); err != nil {
+				return nil, err
+			}
+			c, err := recoveries.SubmitShare(ceremonyID, &share)
+			if err != nil {
+				return nil, err
+			}
+			result := toMap(c)
+			if c.Status == recovery.CeremonyCompleted {
+				// 还原的私钥写入单独文件，由运营者确认后用 -key 启动
+				key, err := recoveries.RecoveredKey(ceremonyID)
+				if err != nil {
+					return nil, err
+				}
+				keyFile := filepath.Join(cf.dataDir, "keys", "recovered.key")
+				if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+					return nil, err
+				}
+				if err := os.WriteFile(keyFile, key, 0600); err != nil {
+					return nil, err
+				}
+				result["key_file"] = keyFile
+			}
+			return result, nil
+		}
+		httpServer.RecoveryCeremonyGetFunc = func(ceremonyID string) (map[string]interface{}, error) {
+			c, err := recoveries.GetCeremony(ceremonyID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(c), nil
+		}
+		httpServer.RecoveryLogFunc = func(escrowID string) []map[string]interface{} {
+			entries := recoveries.Log(escrowID)
+			result := make([]map[string]interface{}, 0, len(entries))
+			for _, e := range entries {
+				result = append(result, toMap(e))
+			}
+			return result
+		}
+		httpServer.TaskTemplateListFunc = func() []map[string]interface{} {
+			list := templates.List()
+			result := make([]map[string]interface{}, 0, len(list))
+			for _, tpl := range list {
+				result = append(result, toMap(tpl))
+			}
+			return result
+		}
+		httpServer.TaskTemplateSaveFunc = func(m map[string]interface{}) (map[string]interface{}, error) {
+			tpl, err := templateFromMap(m)
+			if err != nil {
+				return nil, err
+			}
+			saved, err := templates.Save(tpl)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(saved), nil
+		}
+		httpServer.TaskTemplateShareFunc = func(templateID string) (string, error) {
+			return shareTemplate(templates, bb, templateID)
+		}
+		httpServer.TaskTemplateDeleteFunc = templates.Delete
+		httpServer.TransferSendFunc = func(peerID, path string) (map[string]interface{}, error) {
+			p, err := transfers.Send(peerID, path)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(p), nil
+		}
+		httpServer.TransferStatusFunc = func(transferID string) (map[string]interface{}, error) {
+			p, err := transfers.Progress(transferID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(p), nil
+		}
+		httpServer.ClockSkewFunc = func() map[string]interface{} {
+			result := map[string]interface{}{}
+			if mb != nil {
+				result["mailbox"] = mb.SkewStats()
+			}
+			if bb != nil {
+				result["bulletin"] = bb.SkewStats()
+			}
+			return result
+		}
+		httpServer.ProtocolCompatFunc = func() map[string]interface{} {
+			return protocolCompatStatus(protocolCompat)
+		}
+		httpServer.OutboundFairnessFunc = func() map[string]interface{} {
+			return toMap(outbound.Stats())
+		}
+		httpServer.PeerQuotaFunc = func(peerID string) (map[string]interface{}, error) {
+			if peerID == "" {
+				return map[string]interface{}{
+					"limits": peerQuotas.Limits(),
+					"peers":  peerQuotas.States(),
+				}, nil
+			}
+			state, ok := peerQuotas.State(peerID)
+			if !ok {
+				return nil, fmt.Errorf("no quota state for peer %s", peerID)
+			}
+			return toMap(state), nil
+		}
+		httpServer.StorageKeysFunc = func() map[string]interface{} {
+			return map[string]interface{}{
+				"active_key_id": storageKeys.ActiveKeyID(),
+				"keys":          storageKeys.Keys(),
+			}
+		}
+		httpServer.StorageRotateKeyFunc = func() (map[string]interface{}, error) {
+			keyID, err := storageKeys.Rotate()
+			if err != nil {
+				return nil, err
+			}
+			resealed := []string{}
+			if mb != nil {
+				if err := mb.Reseal(); err != nil {
+					return nil, fmt.Errorf("重写邮箱数据失败: %w", err)
+				}
+				resealed = append(resealed, "mailbox")
+			}
+			if bb != nil {
+				if err := bb.Reseal(); err != nil {
+					return nil, fmt.Errorf("重写留言板数据失败: %w", err)
+				}
+				resealed = append(resealed, "bulletin")
+			}
+			return map[string]interface{}{"active_key_id": keyID, "resealed": resealed}, nil
+		}
+		httpServer.TransferPauseFunc = transfers.Pause
+		httpServer.TransferResumeFunc = transfers.Resume
+		httpServer.TransferAcceptFunc = transfers.Accept
+		httpServer.TransferRejectFunc = transfers.Reject
+		httpServer.TransferListFunc = func(status string) []map[string]interface{} {
+			var result []map[string]interface{}
+			for _, p := range transfers.List(transfer.TransferStatus(status)) {
+				result = append(result, toMap(p))
+			}
+			return result
+		}
+		httpServer.RetentionHoldFunc = func(actor string, req *httpapi.RetentionHoldRequest) (map[string]interface{}, error) {
+			var hold *retention.Hold
+			var err error
+			if retention.Kind(req.Kind) == retention.KindLedgerRange {
+				hold, err = holds.PlaceLedgerHold(req.RangeStart, req.RangeEnd, req.Reason, actor)
+			} else {
+				hold, err = holds.PlaceHold(retention.Kind(req.Kind), req.Target, req.Reason, actor)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return toMap(hold), nil
+		}
+		httpServer.RetentionReleaseFunc = func(holdID, actor, reason string) (map[string]interface{}, error) {
+			hold, err := holds.Release(holdID, actor, reason)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(hold), nil
+		}
+		httpServer.RetentionListFunc = func(includeReleased bool) []map[string]interface{} {
+			var result []map[string]interface{}
+			for _, h := range holds.ListHolds(includeReleased) {
+				result = append(result, toMap(h))
+			}
+			return result
+		}
+		httpServer.RetentionAuditFunc = func() ([]map[string]interface{}, error) {
+			if err := holds.VerifyAudit(); err != nil {
+				return nil, err
+			}
+			var result []map[string]interface{}
+			for _, e := range holds.AuditLog() {
+				result = append(result, toMap(e))
+			}
+			return result, nil
+		}
+		// 邮箱与留言板基础接口（smoke 命令据此验收）
+		httpServer.GetPeersFunc = func() []*httpapi.PeerInfo {
+			var peers []*httpapi.PeerInfo
+			for _, id := range n.Host().Peers() {
+				peers = append(peers, &httpapi.PeerInfo{NodeID: id.String(), Status: "connected"})
+			}
+			return peers
+		}
+		httpServer.MailboxSendFunc = func(to, subject, content string, encrypted bool) (string, error) {
+			if mb == nil {
+				return "", fmt.Errorf("mailbox not available")
+			}
+			msg, err := mb.SendMessage(to, subject, []byte(content), encrypted)
+			if err != nil {
+				return "", err
+			}
+			return msg.ID, nil
+		}
+		httpServer.MailboxStageFunc = func(req *httpapi.MailboxSendRequest) (*httpapi.MailboxSendResult, error) {
+			if mb == nil {
+				return nil, fmt.Errorf("mailbox not available")
+			}
+			delay := time.Duration(req.DelaySeconds) * time.Second
+			if delay == 0 {
+				delay = cf.sendDelay
+			}
+			if delay == 0 {
+				msg, err := mb.SendMessage(req.To, req.Subject, []byte(req.Content), req.Encrypted)
+				if err != nil {
+					return nil, err
+				}
+				return &httpapi.MailboxSendResult{MessageID: msg.ID, Status: "sent"}, nil
+			}
+			msg, err := mb.StageMessage(req.To, req.Subject, []byte(req.Content), req.Encrypted, delay)
+			if err != nil {
+				return nil, err
+			}
+			return &httpapi.MailboxSendResult{MessageID: msg.ID, Status: "staged", DeliverAt: msg.DeliverAt.Unix()}, nil
+		}
+		httpServer.MailboxCancelFunc = func(messageID string) (*httpapi.MailboxMessage, error) {
+			if mb == nil {
+				return nil, fmt.Errorf("mailbox not available")
+			}
+			msg, err := mb.CancelStaged(messageID)
+			if err != nil {
+				return nil, err
+			}
+			return &httpapi.MailboxMessage{ID: msg.ID, From: msg.Sender, To: msg.Receiver, Subject: msg.Subject, Timestamp: msg.Timestamp.Unix()}, nil
+		}
+		httpServer.MailboxStagedFunc = func() []*httpapi.MailboxMessage {
+			if mb == nil {
+				return nil
+			}
+			var messages []*httpapi.MailboxMessage
+			for _, msg := range mb.ListStaged() {
+				messages = append(messages, &httpapi.MailboxMessage{
+					ID:        msg.ID,
+					From:      msg.Sender,
+					To:        msg.Receiver,
+					Subject:   msg.Subject,
+					Timestamp: msg.Timestamp.Unix(),
+					DeliverAt: msg.DeliverAt.Unix(),
+				})
+			}
+			return messages
+		}
+		httpServer.MailboxReadFunc = func(messageID string) (*httpapi.MailboxMessage, error) {
+			if mb == nil {
+				return nil, fmt.Errorf("mailbox not available")
+			}
+			msg, err := mb.GetMessage(messageID)
+			if err != nil {
+				return nil, err
+			}
+			// 加密邮件读取时自动解密；已发出的加密邮件只有收件人能解密，不返回内容
+			content, err := mb.GetMessageContent(messageID)
+			if err != nil && !errors.Is(err, mailbox.ErrEncryptedForRecipient) {
+				return nil, err
+			}
+			return &httpapi.MailboxMessage{
+				ID:        msg.ID,
+				From:      msg.Sender,
+				To:        msg.Receiver,
+				Subject:   msg.Subject,
+				Content:   string(content),
+				Encrypted: msg.Encrypted,
+				Timestamp: msg.Timestamp.Unix(),
+				Read:      msg.Status == mailbox.StatusRead,
+				Status:    string(msg.Status),
+			}, nil
+		}
+		httpServer.MailboxPublicFunc = func(limit, offset int) ([]*httpapi.MailboxMessage, int) {
+			if mb == nil {
+				return nil, 0
+			}
+			var messages []*httpapi.MailboxMessage
+			for _, sum := range mb.ListPublic(limit, offset) {
+				messages = append(messages, &httpapi.MailboxMessage{
+					ID:        sum.ID,
+					From:      sum.Sender,
+					To:        n.Host().ID().String(),
+					Subject:   sum.Subject,
+					Timestamp: sum.Timestamp.Unix(),
+					Read:      sum.Status == mailbox.StatusRead,
+				})
+			}
+			return messages, mb.GetPublicCount()
+		}
+		httpServer.MailboxKnownFunc = func() []string {
+			if mb == nil {
+				return nil
+			}
+			return mb.ListKnownSenders()
+		}
+		httpServer.MailboxSetKnownFunc = func(id string, known bool) error {
+			if mb == nil {
+				return fmt.Errorf("mailbox not available")
+			}
+			if known {
+				mb.AddKnownSender(id)
+			} else {
+				mb.RemoveKnownSender(id)
+			}
+			return nil
+		}
+		httpServer.MailboxContactsFunc = func(query string, limit int) []*httpapi.MailboxContact {
+			if mb == nil {
+				return nil
+			}
+			var contacts []*httpapi.MailboxContact
+			for _, c := range mb.SearchContacts(query, limit) {
+				contacts = append(contacts, apiContact(c))
+			}
+			return contacts
+		}
+		httpServer.MailboxSaveContactFunc = func(c *httpapi.MailboxContact) (*httpapi.MailboxContact, error) {
+			if mb == nil {
+				return nil, fmt.Errorf("mailbox not available")
+			}
+			saved, err := mb.SaveContact(mailboxContact(c))
+			if err != nil {
+				return nil, err
+			}
+			return apiContact(saved), nil
+		}
+		httpServer.MailboxDeleteContactFunc = func(nodeID string) error {
+			if mb == nil {
+				return fmt.Errorf("mailbox not available")
+			}
+			return contactAPIError(mb.DeleteContact(nodeID))
+		}
+		httpServer.MailboxCheckFunc = func(to string) *httpapi.MailboxRecipientCheck {
+			if mb == nil {
+				return &httpapi.MailboxRecipientCheck{NodeID: to, Warnings: []httpapi.MailboxRecipientWarning{}}
+			}
+			return apiRecipientCheck(mb.CheckRecipient(to))
+		}
+		httpServer.BulletinPublishFunc = func(topic, content string, ttl int64) (string, error) {
+			if bb == nil {
+				return "", fmt.Errorf("bulletin board not available")
+			}
+			msg, err := bb.PublishMessage(content, topic)
+			if err != nil {
+				return "", err
+			}
+			if ttl > 0 {
+				bb.SetExpiry(msg.MessageID, time.Now().Add(time.Duration(ttl)*time.Second))
+			}
+			return msg.MessageID, nil
+		}
+		httpServer.BulletinGetFunc = func(messageID string) (*httpapi.BulletinMessage, error) {
+			if bb == nil {
+				return nil, fmt.Errorf("bulletin board not available")
+			}
+			msg, err := bb.QueryMessage(messageID)
+			if err != nil {
+				return nil, err
+			}
+			return bulletinMessage(msg), nil
+		}
+		httpServer.BulletinByTopicFunc = func(topic string, limit int) []*httpapi.BulletinMessage {
+			if bb == nil {
+				return nil
+			}
+			msgs, _ := bb.QueryByTopic(topic, limit, 0)
+			return bulletinMessages(msgs)
+		}
+		httpServer.BulletinByAuthorFunc = func(author string, limit int) []*httpapi.BulletinMessage {
+			if bb == nil {
+				return nil
+			}
+			msgs, _ := bb.QueryByAuthor(author, limit, 0)
+			return bulletinMessages(msgs)
+		}
+		httpServer.BulletinSearchFunc = func(keyword string, limit int) []*httpapi.BulletinMessage {
+			if bb == nil {
+				return nil
+			}
+			return bulletinMessages(bb.SearchMessages(keyword, limit))
+		}
+		httpServer.BulletinPublishEncryptedFunc = func(topic, content string, recipients []string) (string, error) {
+			if bb == nil {
+				return "", fmt.Errorf("bulletin board not available")
+			}
+			msg, err := bb.PublishEncrypted(content, topic, recipients, nil)
+			if err != nil {
+				return "", err
+			}
+			return msg.MessageID, nil
+		}
+		httpServer.BulletinDecryptFunc = func(messageID string) (string, error) {
+			if bb == nil {
+				return "", fmt.Errorf("bulletin board not available")
+			}
+			return bb.DecryptMessage(messageID)
+		}
+		// 订阅过滤在本节点匹配，不匹配的留言不推送到事件流
+		httpServer.BulletinSubscribeFunc = func(topic string, filter *httpapi.BulletinFilter) error {
+			if bb == nil {
+				return fmt.Errorf("bulletin board not available")
+			}
+			return bb.SubscribeTopicWithFilter(topic, subscriptionFilter(filter), nil)
+		}
+		httpServer.BulletinUnsubscribe = func(topic string) error {
+			if bb == nil {
+				return fmt.Errorf("bulletin board not available")
+			}
+			return bb.UnsubscribeTopic(topic)
+		}
+		httpServer.BulletinFilterFunc = func(topic string, filter *httpapi.BulletinFilter) error {
+			if bb == nil {
+				return fmt.Errorf("bulletin board not available")
+			}
+			return bb.SetSubscriptionFilter(topic, subscriptionFilter(filter))
+		}
+		httpServer.BulletinSubscriptionsFunc = func() []*httpapi.BulletinSubscription {
+			if bb == nil {
+				return nil
+			}
+			return bulletinSubscriptions(bb.GetSubscriptions())
+		}
+		// 版主治理：操作者为验签通过的请求身份，未签名的本地请求为本节点
+		moderate := func(fn func() (*bulletin.Moderation, error)) (map[string]interface{}, error) {
+			if bb == nil {
+				return nil, fmt.Errorf("bulletin board not available")
+			}
+			mod, err := fn()
+			if err != nil {
+				return nil, err
+			}
+			return toMap(mod), nil
+		}
+		httpServer.BulletinHideFunc = func(actor, messageID, reason string) (map[string]interface{}, error) {
+			return moderate(func() (*bulletin.Moderation, error) {
+				return bb.HideMessage(messageID, actor, reason)
+			})
+		}
+		httpServer.BulletinAppealFunc = func(actor, messageID, statement string) (map[string]interface{}, error) {
+			return moderate(func() (*bulletin.Moderation, error) {
+				return bb.AppealHide(messageID, actor, statement)
+			})
+		}
+		httpServer.BulletinResolveAppealFunc = func(actor, messageID string, overturn bool, note string) (map[string]interface{}, error) {
+			return moderate(func() (*bulletin.Moderation, error) {
+				return bb.ResolveAppeal(messageID, actor, overturn, note)
+			})
+		}
+		httpServer.BulletinAppealsFunc = func() []map[string]interface{} {
+			var result []map[string]interface{}
+			if bb != nil {
+				for _, mod := range bb.PendingAppeals() {
+					result = append(result, toMap(mod))
+				}
+			}
+			return result
+		}
+		httpServer.BulletinStrikesFunc = func(nodeID string) []map[string]interface{} {
+			var result []map[string]interface{}
+			if bb != nil {
+				for _, rec := range bb.GetStrikes(nodeID) {
+					result = append(result, toMap(rec))
+				}
+			}
+			return result
+		}
+		// publishTask 发布本节点委托的任务并向任务网络广播
+		publishTask := func(t *task.Task) error {
+			if err := taskManager.PublishTask(t, 0); err != nil {
+				return err
+			}
+			// 指定 -token-funds 时报酬在发布时从本节点的代币余额预付，余额不足则撤回任务
+			if cf.tokenFunds && t.Reward > 0 {
+				if _, err := tokenLedger.HoldTaskPayment(t.ID, nodeID, t.Reward); err != nil {
+					taskManager.CancelTask(t.ID, nodeID)
+					return err
+				}
+			}
+			taskNet.offer(t.ID)
+			return nil
+		}
+		httpServer.CreateTaskFunc = func(req *httpapi.TaskRequest) (string, error) {
+			t := taskFromRequest(nodeID, req)
+			if err := publishTask(t); err != nil {
+				return "", err
+			}
+			return t.ID, nil
+		}
+		httpServer.AcceptTaskFunc = func(req *httpapi.TaskAcceptRequest) (map[string]interface{}, error) {
+			claim := &task.TaskClaim{TaskID: req.TaskID, ClaimerID: nodeID, ClaimTime: time.Now().Unix()}
+			for _, f := range req.PayloadFormats {
+				claim.PayloadFormats = append(claim.PayloadFormats, task.PayloadFormat(f))
+			}
+			if err := taskManager.ClaimTask(claim, 0); err != nil {
+				return nil, err
+			}
+			taskNet.claim(claim)
+			t, err := taskManager.GetTask(req.TaskID)
+			if err != nil {
+				return nil, err
+			}
+			return taskMap(t), nil
+		}
+		registerTaskOfferAPI(httpServer, taskNet.offers)
+		registerSubcontractAPI(httpServer, taskManager, nodeID, taskNet.offer, taskNet.announce)
+		registerStorageProofAPI(httpServer, storageProofs, storageHolds, taskManager, nodeID, storageChallenge)
+		httpServer.TaskStatusFunc = func(taskID string) (map[string]interface{}, error) {
+			t, err := taskManager.GetTask(taskID)
+			if err != nil {
+				return nil, err
+			}
+			return taskMap(t), nil
+		}
+		httpServer.TaskSubmitFunc = func(req *httpapi.TaskSubmitRequest) (map[string]interface{}, error) {
+			t, delivery, err := submitTaskResult(taskManager, nodeID, req, endorseConfig.SignFunc)
+			if err != nil {
+				return nil, err
+			}
+			taskNet.deliver(delivery)
+			if req.Result != "" {
+				auditNet.recordTask(t, req.Result)
+			}
+			return taskMap(t), nil
+		}
+		httpServer.TaskConfirmFunc = func(taskID string) (map[string]interface{}, error) {
+			t, err := confirmTaskDelivery(taskManager, nodeID, taskID, endorseConfig.SignFunc)
+			if err != nil {
+				return nil, err
+			}
+			taskNet.announce(taskID)
+			return taskMap(t), nil
+		}
+		httpServer.TaskSettleFunc = func(taskID string) (map[string]interface{}, error) {
+			result, err := settleTask(taskManager, nodeID, taskID)
+			if err != nil {
+				return nil, err
+			}
+			t, err := taskManager.GetTask(taskID)
+			if err != nil {
+				return nil, err
+			}
+			m := taskMap(t)
+			m["settlement"] = toMap(result)
+			return m, nil
+		}
+		httpServer.TaskListFunc = func(status string, limit int) ([]map[string]interface{}, error) {
+			filter, err := task.ParseStatus(status)
+			if err != nil {
+				return nil, err
+			}
+			var result []map[string]interface{}
+			for _, t := range taskManager.ListTasks(filter, limit) {
+				result = append(result, taskMap(t))
+			}
+			return result, nil
+		}
+		httpServer.TaskReviewersFunc = func(taskID string) (map[string]interface{}, error) {
+			a, err := taskManager.GetReviewAssignment(taskID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(a), nil
+		}
+		httpServer.TaskReviewersAssignFunc = func(taskID string, count int) (map[string]interface{}, error) {
+			t, err := taskManager.GetTask(taskID)
+			if err != nil {
+				return nil, err
+			}
+			if t.RequesterID != nodeID {
+				return nil, fmt.Errorf("only the requester can assign reviewers")
+			}
+			a, err := taskManager.AssignReviewers(taskID, count)
+			if err != nil {
+				return nil, err
+			}
+			taskNet.reviewers(taskID)
+			return toMap(a), nil
+		}
+		httpServer.TaskAuditFunc = func(taskID string, approved bool, note string) (map[string]interface{}, error) {
+			record, err := taskManager.RecordAudit(taskID, nodeID, approved, note)
+			if err != nil {
+				return nil, err
+			}
+			taskNet.audit(&taskAudit{TaskID: taskID, Approved: approved, Note: note})
+			return toMap(record), nil
+		}
+		httpServer.TaskVerificationFunc = func(taskID string) (map[string]interface{}, error) {
+			record, err := taskManager.GetVerification(taskID)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(record), nil
+		}
+		httpServer.TaskProgressFunc = func(taskID string) (map[string]interface{}, error) {
+			return taskProgressMap(taskManager, taskID)
+		}
+		httpServer.TaskProgressReportFunc = func(req *httpapi.TaskProgressRequest) (map[string]interface{}, error) {
+			return reportTaskProgress(taskManager, nodeID, req)
+		}
+		httpServer.TaskReviewRespondFunc = func(taskID string, accept bool) (map[string]interface{}, error) {
+			a, err := taskNet.respondReview(taskID, accept)
+			if err != nil {
+				return nil, err
+			}
+			return toMap(a), nil
+		}
+		httpServer.TaskFromTemplateFunc = func(req *httpapi.TaskFromTemplateRequest) (map[string]interface{}, error) {
+			t, err := templates.Instantiate(req.TemplateID, nodeID, req.Params, req.Reward)
+			if err != nil {
+				return nil, err
+			}
+			if err := publishTask(t); err != nil {
+				return nil, err
+			}
+			return taskMap(t), nil
+		}
+		boot.Go("http", func(ctx context.Context) error {
+			// 邮箱和留言板在其他阶段中加载，等它们结束（成功或失败）后再接受请求，
+			// 接口读到的 mb、bb 要么是加载好的实例，要么是 nil（对应接口返回不可用）
+			boot.Wait("mailbox", "bulletin")
+			if err := httpServer.Start(); err != nil {
+				fmt.Fprintf(os.Stderr, "启动 HTTP 服务失败: %v\n", err)
+				return err
+			}
+			fmt.Printf("HTTP API 服务已启动: %s\n", cf.httpAddr)
+			return nil
+		}, "p2p")
+	}
+
+	// 启动 gRPC 服务，消息流与 HTTP 事件流同源，使用相同的 API Token
+	if httpServer != nil {
+		grpcServer.EnableMessageStream(&server.MessageStreamConfig{
+			Events:          httpServer.Events(),
+			Authorize:       httpServer.ValidateAPIToken,
+			SendMail:        httpServer.MailboxSendFunc,
+			PublishBulletin: httpServer.BulletinPublishFunc,
+		})
+	}
+	boot.Go("grpc", func(ctx context.Context) error {
+		if err := grpcServer.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "启动 gRPC 服务失败: %v\n", err)
+			return err
 		}
-	}
+		return nil
+	}, "p2p")
 
 	// 启动管理后台服务
 	var adminServer *webadmin.Server
@@ -417,20 +2421,40 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 	})
 
 	adminConfig := &webadmin.Config{
-		ListenAddr: cf.adminAddr,
-		AdminToken: adminToken,
+		ListenAddr:           cf.adminAddr,
+		AdminToken:           adminToken,
+		BlockProfileRate:     cf.blockProfile,
+		MutexProfileFraction: cf.mutexProfile,
+	}
+	// 由节点私钥派生 WebSocket 重连令牌密钥，重启后客户端仍可凭原令牌恢复订阅
+	if sig, err := n.Identity().PrivKey.Sign([]byte("daan-webadmin-ws-reconnect")); err == nil {
+		key := sha256.Sum256(sig)
+		adminConfig.ReconnectKey = key[:]
 	}
 
 	adminServer = webadmin.New(adminConfig, nodeInfoProvider)
-	if err := adminServer.Start(); err != nil {
-		fmt.Fprintf(os.Stderr, "启动管理后台失败: %v\n", err)
-	} else {
-		fmt.Printf("管理后台已启动: %s\n", adminServer.GetAdminURL())
+	// 报告和收到的任务进度推送给管理后台 /ws/tasks 的订阅者
+	taskManager.SetProgressListener(func(update *task.ProgressUpdate) {
+		adminServer.PublishTaskProgress(update)
+	})
+	if apiKeys != nil {
+		adminServer.SetAuthorizer(auth.NewAuthorizer(apiKeys, rbacPolicy, rbacPolicy.AdminRoutes))
 	}
+	boot.Go("admin", func(ctx context.Context) error {
+		if err := adminServer.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "启动管理后台失败: %v\n", err)
+			return err
+		}
+		fmt.Printf("管理后台已启动: %s\n", adminServer.GetAdminURL())
+		return nil
+	}, "p2p")
 
 	// 初始化邻居管理器
 	neighborConfig := neighbor.DefaultConfig()
+	neighborConfig.MinPingInterval = cf.heartbeatMin
+	neighborConfig.MaxPingInterval = cf.heartbeatMax
 	neighborManager := neighbor.NewNeighborManager(neighborConfig)
+	members.add(neighborManager.IsNeighbor)
 	neighborManager.SetPingFunc(func(nodeID string) error {
 		peerID, err := peer.Decode(nodeID)
 		if err != nil {
@@ -441,32 +2465,461 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		_, err = n.Host().FindPeer(ctx, peerID)
 		return err
 	})
-	neighborManager.Start()
+	neighborManager.ImportNeighbors(persistedNeighbors)
+	// 邻居表的声誉以声誉管理器为准：变化时立即同步，维护周期内再整体刷新
+	neighborManager.SetReputationFunc(func(nodeID string) (int64, error) {
+		return int64(reputationManager.GetReputation(nodeID)), nil
+	})
+	reputationManager.SetOnChange(func(nodeID string, delta, value float64, reason string) {
+		recordReputation(nodeID, delta, value, reason)
+		incentiveManager.OnReputationChanged(nodeID)
+		if neighborManager.IsNeighbor(nodeID) {
+			neighborManager.UpdateNeighborReputation(nodeID, int64(value))
+		}
+	})
+	neighborManager.SetMaintenanceFunc(maintManager.IsPeerInMaintenance)
+	neighborReputation := func(peerID string) (float64, bool) {
+		nb, err := neighborManager.GetNeighbor(peerID)
+		if err != nil {
+			return 0, false
+		}
+		return float64(nb.Reputation), true
+	}
+	dialPolicy.SetReputationFunc(func(peerID string) float64 {
+		rep, _ := neighborReputation(peerID)
+		return rep
+	})
+	if peerCache != nil {
+		peerCache.SetReputationFunc(neighborReputation)
+	}
+	boot.Go("neighbor", func(ctx context.Context) error {
+		neighborManager.Start()
+		return nil
+	}, "p2p")
+	statsRegistry.Register("neighbor", neighborManager)
+	// 节点交换：与已连接节点交换签名的邻居列表，引导节点不可用时仍能发现新节点
+	var stopPeerExchange context.CancelFunc
+	boot.Go("pex", func(ctx context.Context) error {
+		_, stopPeerExchange = startPeerExchange(n, cf.namespace, neighborManager, dialPolicy, reputationManager.GetReputation, peerQuotas.Banned)
+		return nil
+	}, "p2p")
+	var metricsServer *http.Server
+	if exporter != nil {
+		exporter.SetReputationFunc(func() []float64 {
+			return reputationSamples(neighborManager.GetAllNeighbors())
+		})
+		metricsServer = startMetricsServer(cf.metricsAddr, exporter.Handler())
+		fmt.Printf("Prometheus 指标已启用: http://%s/metrics\n", cf.metricsAddr)
+	}
+	// 邻居的可达性证明按完整权重计入，其他节点转发来的证明降权，防止陌生节点刷分
+	reach.SetWeightFunc(func(attester string) float64 {
+		if neighborManager.IsNeighbor(attester) {
+			return 1
+		}
+		return 0.25
+	})
+	stopReachability := startReachabilityTests(reach, neighborManager)
+	// 超级节点履职记分：用 libp2p ping 探测其他超级节点的在线状态和往返时延
+	stopSuperNodeProbes := startSuperNodeProbes(superNodes, nodeID, func(id string) (time.Duration, error) {
+		pid, err := peer.Decode(id)
+		if err != nil {
+			return 0, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		res, ok := <-ping.Ping(ctx, n.Host().Host(), pid)
+		if !ok {
+			return 0, ctx.Err()
+		}
+		return res.RTT, res.Error
+	})
+	stopReputationSync := startReputationSync(reputationViews, reputationManager, neighborManager, func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) {
+		pid, err := peer.Decode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		s, err := n.Host().Host().NewStream(ctx, pid, reputationSyncProtocol)
+		if err != nil {
+			return nil, err
+		}
+		s.SetDeadline(time.Now().Add(30 * time.Second))
+		return outbound.GateStream(ctx, nodeID, s), nil
+	})
+	// 其他节点的声誉向信任度最高的邻居查询签名证明并缓存，只采信邻居签发的证明
+	reputationCache := reputation.NewCache(reputation.DefaultCacheConfig(), fetchAttestation(func() []string {
+		ids := []string{}
+		for _, nb := range neighborManager.GetBestNeighbors(3) {
+			ids = append(ids, nb.NodeID)
+		}
+		return ids
+	}, func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) {
+		pid, err := peer.Decode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		s, err := n.Host().Host().NewStream(ctx, pid, reputationAttestProtocol)
+		if err != nil {
+			return nil, err
+		}
+		s.SetDeadline(time.Now().Add(30 * time.Second))
+		return outbound.GateStream(ctx, nodeID, s), nil
+	}))
+	reputationCache.SetVerifier(verifyAttestation(neighborManager.IsNeighbor, verifyPeerSignature))
+	if httpServer != nil {
+		httpServer.ReputationLookupFunc = reputationLookup(reputationCache, reputationService{m: reputationManager, views: reputationViews})
+	}
+	isSupernode := func(id string) bool {
+		if id == nodeID {
+			return nodeRole == host.RoleRelay
+		}
+		nb, err := neighborManager.GetNeighbor(id)
+		return err == nil && nb.Type == neighbor.TypeSuper
+	}
+	// 熔断指令经留言板全网传播，签发者按选举产生的超级节点集合判断，不要求是本节点的直接邻居
+	breakers.SetSupernodeFunc(superNodes.IsSuperNode)
+	audits.SetAuditorFunc(isSupernode)
+	taskManager.SetSupernodeCheckFunc(isSupernode)
+	features.SetSupernodesFunc(func() []string {
+		supernodes := neighborManager.GetNeighborsByType(neighbor.TypeSuper)
+		ids := make([]string, 0, len(supernodes))
+		for _, nb := range supernodes {
+			ids = append(ids, nb.NodeID)
+		}
+		return ids
+	})
+	if webhooks != nil {
+		// 外部评分回调调整邻居声誉，并向推送目标发出变化事件
+		webhooks.SetApplyAdjustmentFunc(func(adj *reputation.ExternalAdjustment) error {
+			if _, err := reputationManager.Adjust(adj.NodeID, adj.Delta, "webhook:"+adj.Source); err != nil {
+				return err
+			}
+			webhooks.EmitChange(adj.NodeID, adj.Source, adj.Delta, adj.EvidenceRefs)
+			httpServer.PublishEvent(httpapi.EventReputation, map[string]interface{}{
+				"node_id": adj.NodeID,
+				"source":  adj.Source,
+				"delta":   adj.Delta,
+			})
+			return nil
+		})
+	}
+	outbound.SetPeerRelationFunc(func(peerID string) bandwidth.PeerRelation {
+		nb, err := neighborManager.GetNeighbor(peerID)
+		if err != nil {
+			return bandwidth.PeerRelation{}
+		}
+		return bandwidth.PeerRelation{
+			Reputation: float64(nb.Reputation),
+			Neighbor:   true,
+			Supernode:  nb.Type == neighbor.TypeSuper,
+		}
+	})
 
-	// 初始化邮箱
-	nodeID := n.Host().ID().String()
-	mailboxConfig := mailbox.DefaultConfig(nodeID)
-	mailboxConfig.DataDir = filepath.Join(cf.dataDir, "mailbox")
-	mb, err := mailbox.NewMailbox(mailboxConfig)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "创建邮箱失败: %v\n", err)
-	} else {
-		mb.Start()
+	// 等待全部子系统启动完成，输出各阶段耗时
+	boot.Wait()
+	fmt.Print(boot.Report())
+
+	if mb != nil {
+		statsRegistry.Register("mailbox", mb)
+		// 新邮件推送到 HTTP 事件流（只推送摘要，正文仍需读取收件箱）
+		mb.SetOnMessageReceived(func(msg *mailbox.Message) {
+			httpServer.PublishEvent(httpapi.EventMail, map[string]interface{}{
+				"id":        msg.ID,
+				"sender":    msg.Sender,
+				"subject":   msg.Subject,
+				"encrypted": msg.Encrypted,
+				"timestamp": msg.Timestamp.Unix(),
+			})
+		})
+		// 维护期间缓存入站邮件通知，退出后补发
+		mb.SetHoldInbound(maintManager.IsEnabled())
+		maintManager.OnEnabled = func(*maintenance.Status) { mb.SetHoldInbound(true) }
+		maintManager.OnDisabled = func(*maintenance.Status) { mb.SetHoldInbound(false) }
+		// 保全中的会话不参与过期清理
+		mb.SetRetentionHoldFunc(func(threadID string) bool {
+			return holds.IsHeld(retention.KindMailboxThread, threadID)
+		})
+		mb.SetAdmitFunc(func(*mailbox.Message) error {
+			return breakers.Allow(breaker.ClassMail)
+		})
+		// 节点间投递的邮件按投递的对端计入每小时邮件配额
+		mb.SetPeerAdmitFunc(func(peerID string) error {
+			return peerQuotas.Allow(peerID, quota.UsageMail)
+		})
+		// 发信检查：收件人声誉过低或因超出配额被封禁时提醒
+		mb.SetReputationFunc(reputationManager.GetReputation)
+		mb.SetQuarantineFunc(peerQuotas.Banned)
+	}
+	var gossip *bulletinGossip
+	if bb != nil {
+		statsRegistry.Register("bulletin", bb)
+		bb.OnMessagePublished = func(msg *bulletin.Message) {
+			httpServer.PublishEvent(httpapi.EventBulletin, bulletinMessage(msg))
+		}
+		bb.OnMessageReceived = bb.OnMessagePublished
+		// 导入其他节点共享的任务模板
+		syncSharedTemplates(templates, bb)
+		bb.SubscribeTopic(task.TemplateBulletinTopic, func(msg *bulletin.Message) {
+			importSharedTemplate(templates, msg)
+		})
+		// 熔断指令：执行已有的并订阅新的，熔断话题本身不受留言熔断限制
+		syncBreakerDirectives(breakers, bb)
+		bb.SubscribeTopic(breaker.BulletinTopic, func(msg *bulletin.Message) {
+			applyBreakerDirective(breakers, msg)
+		})
+		bb.SetAdmitFunc(func(msg *bulletin.Message) error {
+			if msg.Topic == breaker.BulletinTopic {
+				return nil
+			}
+			return breakers.Allow(breaker.ClassBulletin)
+		})
+		// 发布的留言经 GossipSub 传播到订阅了同一话题的节点
+		// 节点评分：声誉低、留言被拒或超出配额的节点分数降低，被移出 GossipSub mesh
+		quotaState := func(peerID string) (bool, bool) {
+			state, ok := peerQuotas.State(peerID)
+			if !ok {
+				return false, false
+			}
+			return state.Throttled, !state.BannedUntil.IsZero()
+		}
+		if g, err := newBulletinGossip(n.Host().Host(), cf.namespace, reputationManager.GetReputation, quotaState); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  留言广播不可用: %v\n", err)
+		} else {
+			gossip = g
+			// 留言和任务报价按验签过的原始发布节点计入节点间配额（本节点发布的不经校验）
+			gossip.admit = func(topic, from string) bool {
+				usage, ok := gossipUsage(topic)
+				return !ok || peerQuotas.Allow(from, usage) == nil
+			}
+			bb.SetCompat(protocolCompat)
+			bb.SetGossip(gossip)
+			if httpServer != nil {
+				httpServer.GossipTuningFunc = gossip.tuningStatus
+				httpServer.GossipScoresFunc = gossip.gossipScores
+			}
+		}
 	}
 
-	// 初始化留言板
-	bulletinConfig := bulletin.DefaultBulletinConfig(nodeID)
-	bulletinConfig.DataDir = filepath.Join(cf.dataDir, "bulletin")
-	bb, err := bulletin.NewBulletinBoard(bulletinConfig)
+	// 节点心跳：经 GossipSub 广播签名的心跳，跟踪已知节点的存活情况并计入邻居信任分
+	var stopHeartbeat context.CancelFunc
+	var mailRelays func(exclude ...string) []string
+	if gossip != nil {
+		hbConfig := heartbeat.DefaultManagerConfig(nodeID)
+		hbConfig.Role = string(nodeRole)
+		hbConfig.MinInterval = cf.heartbeatMin
+		hbConfig.MaxInterval = cf.heartbeatMax
+		hbConfig.SignFunc = signWithNodeKey(n.Identity().PrivKey)
+		hbConfig.VerifyFunc = verifyNodeSignature
+		hb, err := newHeartbeatManager(hbConfig, heartbeatSources{
+			capabilities: parseCapabilities(cf.capabilities),
+			protocols: func() []string {
+				var ids []string
+				for _, id := range n.Host().Host().Mux().Protocols() {
+					ids = append(ids, string(id))
+				}
+				return ids
+			},
+			features:    features,
+			maintenance: maintManager,
+			neighbors:   neighborManager,
+			tasks:       taskManager,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  节点心跳不可用: %v\n", err)
+		} else {
+			stopHeartbeat = startHeartbeat(hb, gossip.heartbeatTransport())
+			statsRegistry.Register("heartbeat", hb)
+			// 中继候选按公共可达性分数排序，分数过低的不参与
+			mailRelays = rankedRelays(reach, func(exclude ...string) []string { return heartbeatRelays(hb, exclude...) })
+			if httpServer != nil {
+				httpServer.HeartbeatStatusFunc = func(id string) (map[string]interface{}, error) {
+					return heartbeatStatus(hb, id)
+				}
+			}
+		}
+	}
+
+	// 背书经 GossipSub 传播，各节点的信任网据此计算背书距离
+	if gossip != nil {
+		if err := startEndorsementGossip(endorsements, nodeID, gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  背书广播不可用: %v\n", err)
+		}
+	}
+
+	// 本节点签发的可达性证明经 GossipSub 传播，各节点验签后计入公共可达性分数
+	if gossip != nil {
+		if err := startReachabilityGossip(reach, nodeID, gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  可达性证明广播不可用: %v\n", err)
+		}
+	}
+
+	// 邀请经 GossipSub 发给受信本节点的邻居，收到受信节点的邀请后保存
+	var invitationTransport gossipTransport
+	if gossip != nil {
+		if err := startAdmissionGossip(admissions, gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  邀请广播不可用: %v\n", err)
+		} else {
+			invitationTransport = gossip
+		}
+	}
+	if httpServer != nil {
+		registerAdmissionAPI(httpServer, admissions, invitationTransport)
+	}
+
+	// 加入记录经 GossipSub 传播，各节点的邀请树和入站准入随之更新
+	var joinTransport gossipTransport
+	if gossip != nil {
+		if err := startGenesisGossip(genesisManager, gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  加入记录广播不可用: %v\n", err)
+		} else {
+			joinTransport = gossip
+		}
+	}
+	if httpServer != nil {
+		registerGenesisAPI(httpServer, genesisManager, joinTransport)
+	}
+
+	// 签名转账、铸造和任务报酬支付经 GossipSub 传播，各节点的代币账本据此入账
+	if gossip != nil {
+		if err := startTokenGossip(tokenLedger, gossip, members.registered); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  代币流水广播不可用: %v\n", err)
+		}
+	}
+
+	// 任务报价、副本、接单和交付经 GossipSub 在委托方和执行方之间交换
+	if gossip != nil {
+		if err := taskNet.start(gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  任务广播不可用: %v\n", err)
+		}
+	}
+
+	// 执行记录和审计结论经 GossipSub 在执行方与超级节点之间交换
+	if gossip != nil {
+		if err := auditNet.start(gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  审计广播不可用: %v\n", err)
+		}
+	}
+	if httpServer != nil {
+		registerAuditAPI(httpServer, auditNet)
+	}
+
+	// 治理投票：提案和投票经 GossipSub 传播，权重取自声誉和抵押，
+	// 通过的剔除、晋升、撤销超级节点和参数提案由本节点执行
+	var stopGovernance context.CancelFunc
+	votingConfig := voting.DefaultConfig(nodeID)
+	votingConfig.DataDir = filepath.Join(cf.dataDir, "voting")
+	votingManager, err := voting.NewVotingManager(votingConfig)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "创建留言板失败: %v\n", err)
-	} else {
-		bb.Start()
+		fmt.Fprintf(os.Stderr, "⚠️  治理投票不可用: %v\n", err)
+	} else if gossip != nil {
+		members.add(func(id string) bool {
+			return votingManager.GetNodeStatus(id) == voting.StatusActive
+		})
+		votingManager.SetSignFunc(n.Identity().PrivKey.Sign)
+		votingManager.SetVerifyFunc(verifyPeerSignature)
+		votingManager.SetGetReputationFunc(func(id string) float64 {
+			return clampTrust(reputationManager.GetReputation(id))
+		})
+		votingManager.SetParameterApplier(votingParameters(votingConfig))
+		// 金丝雀发布：试运行期间的出错数和争议数与对照组比较，对比结果发布到留言板的治理变更话题
+		votingManager.SetMetricsFunc(canaryMetrics(apiRequests,
+			func() int { return taskManager.GetStatistics().TotalTasks },
+			func() int { return disputeManager.GetStatistics().TotalDisputes }))
+		changelog := voting.NewChangelogPublisher(eventLedger, func(content, topic string, tags []string) error {
+			if boot.Wait("bulletin") != nil || bb == nil {
+				return errors.New("bulletin board not available")
+			}
+			_, err := bb.PublishMessageWithOptions(content, topic, tags, nil, "")
+			return err
+		}, nodeID)
+		votingManager.SetOnCanaryEvaluated(changelog.HandleCanary)
+		// 提案执行后的变更写入账本并在治理变更话题发布摘要
+		votingManager.SetOnChangeApplied(changelog.Handle)
+		// 留言板警告达到阈值时经治理投票处理
+		if bb != nil {
+			bb.SetProposalFunc(strikeProposals(votingManager))
+		}
+		// 履职记分板连续不达标的超级节点自动提议降级
+		watchSuperNodeDemotions(superNodes, votingManager)
+		registerGovernanceExecutors(votingManager, governanceEffects{
+			neighbors: neighborManager,
+			disconnect: func(id string) {
+				if pid, err := peer.Decode(id); err == nil {
+					n.Host().Host().Network().ClosePeer(pid)
+				}
+			},
+		})
+		// 被剔除的节点重新连入时直接断开，恢复提案通过后才接受
+		n.Host().Host().Network().Notify(&network.NotifyBundle{
+			ConnectedF: func(nw network.Network, conn network.Conn) {
+				pid := conn.RemotePeer()
+				if votingManager.GetNodeStatus(pid.String()) == voting.StatusRemoved {
+					go nw.ClosePeer(pid)
+				}
+			},
+		})
+		trust := votingTrust{
+			nodes: func() []string {
+				var ids []string
+				for _, nb := range neighborManager.GetAllNeighbors() {
+					ids = append(ids, nb.NodeID)
+				}
+				return ids
+			},
+			reputation: reputationManager.GetReputation,
+			stake:      collateralManager.GetActiveCollateral,
+		}
+		if stopGovernance, err = startGovernance(votingManager, nodeID, trust, gossip); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  治理投票不可用: %v\n", err)
+		} else {
+			votingManager.Start()
+			statsRegistry.Register("voting", stats.ProviderFunc(func() []stats.Metric { return votingMetrics(votingManager) }))
+			if httpServer != nil {
+				registerVotingAPI(httpServer, votingManager, nodeID, gossip)
+			}
+		}
+	}
+
+	// 邮件投递：收件人在线时直接投递，不在线时交给心跳在线的中继暂存
+	var stopMailRelay context.CancelFunc
+	if mb != nil {
+		stopMailRelay = startMailRelay(n, cf.namespace, mb, mailRelays, peerQuotas.Banned, outbound)
+		if httpServer != nil {
+			httpServer.MailboxRoutesFunc = func(receiver, messageID string, limit int) map[string]interface{} {
+				return map[string]interface{}{
+					"decisions": mb.Routes().Decisions(receiver, messageID, limit),
+					"paths":     mb.Routes().PathStats(),
+				}
+			}
+		}
+		if httpServer != nil && mb.IsRelay() {
+			httpServer.MailboxPendingFunc = func(receiver string) map[string]interface{} {
+				return toMap(mb.RelayStatus(receiver))
+			}
+		}
 	}
 
+	// 任务截止时间扫描，过期和逾期推送到事件流
+	taskManager.SetDeadlineHandler(func(ev task.DeadlineEvent) {
+		if ev.Kind == task.DeadlineExpired {
+			payments.refund(ev.TaskID)
+		}
+		taskNet.announce(ev.TaskID)
+		httpServer.PublishEvent(httpapi.EventTask, map[string]interface{}{"action": string(ev.Kind), "task_id": ev.TaskID, "deadline": ev.Deadline})
+	})
+	taskManager.StartScheduler()
+
 	// 设置 OperationsProvider
 	opsProvider := webadmin.NewRealOperationsProvider(nodeID)
+	opsProvider.GetSecurityManager().SetQuarantineScaleFunc(quarantineScale(endorsements, identityProofs))
+	// 声誉传播的耐受值按来源关系调整：被隔离的来源拒收，邻居和本节点背书的节点放宽
+	incentiveManager.SetRelationFunc(sourceRelation(opsProvider.IsBlacklisted, neighborManager.IsNeighbor, endorsements))
 	opsProvider.SetNeighborManager(neighborManager)
+	opsProvider.SetReputationManager(reputationManager)
+	opsProvider.SetEscrowManager(escrowManager, signWithNodeKey(n.Identity().PrivKey))
+	opsProvider.SetDisputeManager(disputeManager)
+	opsProvider.SetCollateralManager(collateralManager)
+	opsProvider.SetTokenLedger(tokenLedger, signWithNodeKey(n.Identity().PrivKey))
+	opsProvider.SetTaskManager(taskManager)
 	if mb != nil {
 		opsProvider.SetMailbox(mb)
 	}
@@ -483,6 +2936,24 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		return n.Host().FindPeer(ctx, id)
 	})
 	adminServer.SetOperationsProvider(opsProvider)
+	adminServer.SetTaskOperationsProvider(opsProvider)
+	adminServer.SetReputationOperationsProvider(opsProvider)
+	adminServer.SetEscrowOperationsProvider(opsProvider)
+	adminServer.SetDisputeOperationsProvider(opsProvider)
+	adminServer.SetCollateralOperationsProvider(opsProvider)
+	adminServer.SetTokenOperationsProvider(opsProvider)
+	adminServer.SetContactOperationsProvider(opsProvider)
+
+	// 每周活动报告，可在管理后台下载
+	reportGen, stopReports, err := startActivityReports(cf.dataDir, reportSources{
+		tasks: taskManager, tokens: tokenLedger, reputation: reputationManager, disputes: disputeManager, self: nodeID,
+	}, mb, cf.reportMailTo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  活动报告不可用: %v\n", err)
+	} else {
+		opsProvider.SetReportGenerator(reportGen)
+		adminServer.SetReportOperationsProvider(opsProvider)
+	}
 
 	// 获取节点监听地址
 	listenAddrs := make([]string, 0)
@@ -518,6 +2989,7 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 				status.PeerCount = n.Host().ConnectedPeers()
 				status.Uptime = time.Since(startTime).Round(time.Second).String()
 				d.WriteStatus(status)
+				readiness.beat()
 
 				// 轮转日志
 				d.RotateLogs()
@@ -525,10 +2997,32 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 		}
 	}()
 
+	// 定时远端备份
+	backupManager := startScheduledBackup(cf.dataDir, cf.backup)
+
 	// 等待停止信号
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
+	// 桌面托盘的本地控制接口，与 HTTP API 隔离且不需要管理令牌
+	var trayEvents *httpapi.EventHub
+	if httpServer != nil {
+		trayEvents = httpServer.Events()
+	}
+	controlServer := startControlServer(controlSocketPath(cf.dataDir, cf.controlSocket), func() map[string]interface{} {
+		return map[string]interface{}{
+			"node_id":     nodeID,
+			"version":     version,
+			"pid":         os.Getpid(),
+			"start_time":  startTime,
+			"uptime":      time.Since(startTime).Round(time.Second).String(),
+			"peers":       n.Host().ConnectedPeers(),
+			"http_addr":   cf.httpAddr,
+			"admin_addr":  cf.adminAddr,
+			"maintenance": maintManager.IsEnabled(),
+		}
+	}, notifyStop(sigCh), trayEvents)
+
 	if !daemon.IsDaemonProcess() {
 		fmt.Println("\n按 Ctrl+C 停止节点...")
 	}
@@ -537,6 +3031,18 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 
 	fmt.Println("\n正在停止节点...")
 
+	if backupManager != nil {
+		backupManager.Stop()
+	}
+	if stopReports != nil {
+		stopReports()
+	}
+	taskManager.StopScheduler()
+
+	if controlServer != nil {
+		controlServer.Stop()
+	}
+
 	// 清理
 	d.Cleanup()
 
@@ -547,16 +3053,64 @@ func runNode(cf *commonFlags, d *daemon.Daemon) {
 	if httpServer != nil {
 		httpServer.Stop()
 	}
+	if mutationAudit != nil {
+		mutationAudit.Close()
+	}
+	if err := apiUsage.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "保存 API 使用统计失败: %v\n", err)
+	}
+	if err := tokenMeter.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "保存 API 计量记录失败: %v\n", err)
+	}
 	grpcServer.Stop()
+	if metricsServer != nil {
+		stopMetricsServer(metricsServer)
+	}
 	
+	if stopPeerExchange != nil {
+		stopPeerExchange()
+	}
+	if stopHeartbeat != nil {
+		stopHeartbeat()
+	}
+	stopReachability()
+	stopSuperNodeProbes()
+	stopReputationSync()
+	stopStorageChallenges()
+	if stopMailRelay != nil {
+		stopMailRelay()
+	}
+	if stopGovernance != nil {
+		stopGovernance()
+		votingManager.Stop()
+	}
 	// 停止邻居、邮箱、留言板服务
+	if err := savePersistedNeighbors(cf.dataDir, neighborManager.ExportNeighbors()); err != nil {
+		fmt.Fprintf(os.Stderr, "保存邻居列表失败: %v\n", err)
+	}
 	neighborManager.Stop()
+	reputationManager.Stop()
+	eventLedger.StopCompaction()
+	nodeLogger.Info(logging.EventSystemStop, nil)
+	nodeLogger.Stop()
+	incentiveManager.Stop()
+	accusations.Stop()
+	escrowManager.Stop()
+	superNodes.Stop()
 	if mb != nil {
 		mb.Stop()
 	}
 	if bb != nil {
 		bb.Stop()
 	}
+	if gossip != nil {
+		gossip.Stop()
+	}
+	if nodeStore != nil {
+		nodeStore.Close()
+	}
+	transfers.Close()
+	outbound.Stop()
 	
 	n.Stop()
 
@@ -596,6 +3150,15 @@ func cmdToken() {
 		fmt.Println("========================")
 		fmt.Println("⚠️  提示: 如果节点正在运行，请重启以应用新令牌")
 
+	case "create":
+		cmdTokenCreate(os.Args[3:])
+
+	case "revoke":
+		cmdTokenRevoke(os.Args[3:])
+
+	case "list":
+		cmdTokenList(os.Args[3:])
+
 	default:
 		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
 		printTokenUsage()
@@ -607,15 +3170,22 @@ func printTokenUsage() {
 	fmt.Print(`用法: agentnetwork token <子命令> [选项]
 
 子命令:
-  show      显示当前访问令牌
+  show      显示当前访问令牌（拥有全部权限）
   refresh   刷新（重新生成）访问令牌
+  create    签发按角色限权的 API Key
+  revoke    撤销 API Key
+  list      列出 API Key
 
 选项:
   -data     数据目录 (默认: ./data)
+  -role     角色: read-only, operator, arbitrator, admin（create 时必填）
+  -name     Key 名称（create 时可选）
 
 示例:
   agentnetwork token show
   agentnetwork token refresh -data ./mydata
+  agentnetwork token create --role=operator -name ci-bot
+  agentnetwork token revoke key-1a2b3c4d
 `)
 }
 
@@ -747,12 +3317,34 @@ func cmdKeygen() {
 	fmt.Println("⚠️  警告: 请妥善保管私钥文件!")
 }
 
+// loadNodeSecret 读取节点私钥（不存在时不生成），用于离线命令派生存储密钥
+func loadNodeSecret(keyPath string) ([]byte, error) {
+	if _, err := os.Stat(keyPath); err != nil {
+		return nil, err
+	}
+	id, err := identity.LoadOrCreate(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	return id.PrivKey.Raw()
+}
+
+// nodeKeyPeerID 读取或生成节点密钥，返回节点 ID
+func nodeKeyPeerID(keyPath string) (string, error) {
+	id, err := identity.LoadOrCreate(keyPath)
+	if err != nil {
+		return "", err
+	}
+	return id.PeerID.String(), nil
+}
+
 func cmdHealth() {
 	fs := flag.NewFlagSet("health", flag.ExitOnError)
 	dataDir := fs.String("data", "./data", "数据目录")
 	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
 	timeout := fs.Int("timeout", 5, "超时时间（秒）")
 	jsonOutput := fs.Bool("json", false, "JSON格式输出")
+	requireReady := fs.Bool("ready", false, "同时要求节点就绪（/readyz）")
 	fs.Parse(os.Args[2:])
 
 	// 首先检查守护进程状态
@@ -766,6 +3358,7 @@ func cmdHealth() {
 		Healthy     bool     `json:"healthy"`
 		Process     bool     `json:"process"`
 		HTTPService bool     `json:"http_service"`
+		Ready       *bool    `json:"ready,omitempty"`
 		PID         int      `json:"pid,omitempty"`
 		NodeID      string   `json:"node_id,omitempty"`
 		Uptime      string   `json:"uptime,omitempty"`
@@ -785,10 +3378,10 @@ func cmdHealth() {
 		healthResult.Errors = append(healthResult.Errors, "节点进程未运行")
 	}
 
-	// 检查 HTTP 服务
+	// 检查 HTTP 服务（存活探针）
+	client := &httpClient{timeout: time.Duration(*timeout) * time.Second}
 	if status.Running {
-		httpURL := fmt.Sprintf("http://localhost%s/v1/health", *httpAddr)
-		client := &httpClient{timeout: time.Duration(*timeout) * time.Second}
+		httpURL := fmt.Sprintf("http://localhost%s/livez", *httpAddr)
 		if err := client.checkHealth(httpURL); err != nil {
 			healthResult.Errors = append(healthResult.Errors, fmt.Sprintf("HTTP服务检查失败: %v", err))
 		} else {
@@ -798,6 +3391,17 @@ func cmdHealth() {
 
 	healthResult.Healthy = healthResult.Process && healthResult.HTTPService
 
+	// 就绪检查（迁移、存储加载、DHT 引导、最少连接数）
+	if *requireReady && healthResult.HTTPService {
+		ready := true
+		if err := client.checkHealth(fmt.Sprintf("http://localhost%s/readyz", *httpAddr)); err != nil {
+			ready = false
+			healthResult.Errors = append(healthResult.Errors, fmt.Sprintf("节点未就绪: %v", err))
+		}
+		healthResult.Ready = &ready
+		healthResult.Healthy = healthResult.Healthy && ready
+	}
+
 	if *jsonOutput {
 		data, _ := json.MarshalIndent(healthResult, "", "  ")
 		fmt.Println(string(data))
@@ -817,6 +3421,9 @@ func cmdHealth() {
 
 	fmt.Printf("进程状态: %s\n", boolToStatus(healthResult.Process))
 	fmt.Printf("HTTP服务: %s\n", boolToStatus(healthResult.HTTPService))
+	if healthResult.Ready != nil {
+		fmt.Printf("就绪状态: %s\n", boolToStatus(*healthResult.Ready))
+	}
 
 	if healthResult.NodeID != "" {
 		fmt.Printf("节点ID: %s\n", healthResult.NodeID)
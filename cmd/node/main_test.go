@@ -1,9 +1,57 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/admission"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/audit"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bandwidth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/breaker"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/control"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/feature"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/genesis"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/heartbeat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/ledger"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/maintenance"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/migrate"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/pex"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/provision"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reachability"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/release"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/report"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/snapshot"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/supernode"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/trust"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/voting"
 )
 
 func TestExtractPort(t *testing.T) {
@@ -111,3 +159,2920 @@ func TestGetASCIILogo(t *testing.T) {
 		t.Error("Logo seems too short")
 	}
 }
+
+func TestProfileURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		adminAddr   string
+		profileType string
+		duration    time.Duration
+		expected    string
+	}{
+		{"cpu with port only", ":18080", "cpu", 30 * time.Second, "http://localhost:18080/debug/pprof/profile?seconds=30"},
+		{"trace sub-second", "127.0.0.1:9000", "trace", 200 * time.Millisecond, "http://127.0.0.1:9000/debug/pprof/trace?seconds=1"},
+		{"heap ignores duration", ":18080", "heap", 30 * time.Second, "http://localhost:18080/debug/pprof/heap"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := profileURL(tt.adminAddr, profileEndpoints[tt.profileType], tt.profileType, tt.duration)
+			if result != tt.expected {
+				t.Errorf("profileURL() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadAdminToken(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// 令牌文件不存在时报错，且不生成新令牌
+	if _, err := readAdminToken(tmpDir); err == nil {
+		t.Error("expected error for missing token")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "admin_token")); !os.IsNotExist(err) {
+		t.Errorf("expected no token file to be created, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "admin_token"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := readAdminToken(tmpDir); err != nil || token != "secret" {
+		t.Errorf("expected secret, got %q, %v", token, err)
+	}
+}
+
+func TestPersistedNeighbors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if got := loadPersistedNeighbors(tmpDir); got != nil {
+		t.Errorf("expected nil for missing file, got %v", got)
+	}
+
+	neighbors := []*neighbor.Neighbor{
+		{NodeID: "peerA", Addresses: []string{"/ip4/1.2.3.4/tcp/4001", "/ip4/1.2.3.4/udp/4001/quic-v1/p2p/peerA"}},
+		{NodeID: "peerB"},
+	}
+	if err := savePersistedNeighbors(tmpDir, neighbors); err != nil {
+		t.Fatalf("savePersistedNeighbors failed: %v", err)
+	}
+
+	loaded := loadPersistedNeighbors(tmpDir)
+	if len(loaded) != 2 {
+		t.Fatalf("expected 2 neighbors, got %d", len(loaded))
+	}
+
+	addrs := neighborPeerAddrs(loaded)
+	expected := []string{
+		"/ip4/1.2.3.4/tcp/4001/p2p/peerA",
+		"/ip4/1.2.3.4/udp/4001/quic-v1/p2p/peerA",
+	}
+	if len(addrs) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, addrs)
+	}
+	for i := range expected {
+		if addrs[i] != expected[i] {
+			t.Errorf("addr %d: expected %s, got %s", i, expected[i], addrs[i])
+		}
+	}
+}
+
+func TestPexNeighborCandidate(t *testing.T) {
+	c := &pex.Candidate{ID: "peerC", Addrs: []string{"/ip4/1.2.3.5/tcp/4001"}, Reputation: 42.7, Score: 0.8}
+	nb := pexNeighborCandidate(c)
+	if nb.NodeID != "peerC" || nb.Reputation != 42 || nb.TrustScore != 0.8 || nb.PingStatus != neighbor.StatusUnknown {
+		t.Errorf("unexpected neighbor %+v", nb)
+	}
+	if addrs := neighborPeerAddrs([]*neighbor.Neighbor{nb}); len(addrs) != 1 || addrs[0] != "/ip4/1.2.3.5/tcp/4001/p2p/peerC" {
+		t.Errorf("unexpected addrs %v", addrs)
+	}
+}
+
+func TestAnnouncedSkills(t *testing.T) {
+	tmpDir := t.TempDir()
+	if got := loadAnnouncedSkills(tmpDir); got != nil {
+		t.Errorf("expected nil for missing file, got %v", got)
+	}
+	if err := saveAnnouncedSkills(tmpDir, []string{"ocr", "translation"}); err != nil {
+		t.Fatalf("saveAnnouncedSkills failed: %v", err)
+	}
+	if got := loadAnnouncedSkills(tmpDir); len(got) != 2 || got[0] != "ocr" || got[1] != "translation" {
+		t.Errorf("unexpected skills %v", got)
+	}
+	saveAnnouncedSkills(tmpDir, nil)
+	if got := loadAnnouncedSkills(tmpDir); got == nil || len(got) != 0 {
+		t.Errorf("expected empty list after withdrawing everything, got %v", got)
+	}
+}
+
+func TestMaintenanceRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != maintenancePath || r.Header.Get("X-API-Token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"success":false,"error":"invalid or missing API token","code":401}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"data":{"enabled":true,"reason":"upgrade"},"code":200}`))
+	}))
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().String()
+	status, err := maintenanceRequest(addr, "tok", http.MethodGet, nil, nil)
+	if err != nil {
+		t.Fatalf("maintenanceRequest failed: %v", err)
+	}
+	if status["enabled"] != true || status["reason"] != "upgrade" {
+		t.Errorf("unexpected status: %v", status)
+	}
+
+	if _, err := maintenanceRequest(addr, "bad", http.MethodGet, nil, nil); err == nil {
+		t.Error("expected error for invalid token")
+	}
+}
+
+func TestNodeAPIRequestSigned(t *testing.T) {
+	var signedBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Signature") != "sig:"+string(body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"success":false,"error":"unsigned","code":401}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"data":{"enabled":false},"code":200}`))
+	}))
+	defer srv.Close()
+
+	sign := func(req *http.Request, body []byte) error {
+		signedBody = string(body)
+		req.Header.Set("X-Signature", "sig:"+string(body))
+		return nil
+	}
+	addr := srv.Listener.Addr().String()
+	if _, err := maintenanceRequest(addr, "tok", http.MethodPost, map[string]interface{}{"enabled": false}, sign); err != nil {
+		t.Fatalf("signed request failed: %v", err)
+	}
+	if signedBody != `{"enabled":false}` {
+		t.Errorf("signer should see the exact request body, got %q", signedBody)
+	}
+	if _, err := maintenanceRequest(addr, "tok", http.MethodPost, map[string]interface{}{"enabled": false}, nil); err == nil {
+		t.Error("expected unsigned request to be rejected")
+	}
+}
+
+func TestMaintenanceStatusMap(t *testing.T) {
+	off := maintenanceStatusMap(&maintenance.Status{NodeID: "n1"})
+	if off["enabled"] != false || off["reason"] != nil {
+		t.Errorf("unexpected map for disabled status: %v", off)
+	}
+
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	on := maintenanceStatusMap(&maintenance.Status{
+		NodeID:  "n1",
+		Enabled: true,
+		Reason:  "upgrade",
+		Since:   since,
+		Until:   since.Add(time.Hour),
+	})
+	if on["until"] != "2026-01-02T04:04:05Z" || on["reason"] != "upgrade" {
+		t.Errorf("unexpected map for enabled status: %v", on)
+	}
+}
+
+func TestEndorsementMaps(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	list := endorsementMaps([]*trust.Endorsement{{
+		ID:         "e1",
+		Endorser:   "A",
+		Subject:    "B",
+		SubjectKey: "key-B",
+		Statement:  "met in person",
+		CreatedAt:  created,
+		ExpiresAt:  created.Add(24 * time.Hour),
+		Signature:  []byte("sig"),
+	}})
+	if len(list) != 1 {
+		t.Fatalf("expected 1 endorsement, got %d", len(list))
+	}
+	if list[0]["subject"] != "B" || list[0]["expires_at"] != "2026-01-03T03:04:05Z" {
+		t.Errorf("unexpected endorsement map: %v", list[0])
+	}
+	if _, ok := list[0]["signature"]; ok {
+		t.Error("signature should not be exposed")
+	}
+}
+
+func TestShareTaskTemplate(t *testing.T) {
+	owner := task.NewTemplateStore("node-a", "")
+	tpl, err := templateFromMap(map[string]interface{}{
+		"name":          "Paper search",
+		"type":          "search",
+		"title_pattern": "Search: {{query}}",
+		"verification":  "manual",
+		"params":        []interface{}{map[string]interface{}{"name": "query", "type": "string", "required": true}},
+	})
+	if err != nil {
+		t.Fatalf("templateFromMap failed: %v", err)
+	}
+	saved, err := owner.Save(tpl)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, err := shareTemplate(owner, nil, saved.ID); err == nil {
+		t.Error("expected error without bulletin board")
+	}
+
+	cfg := bulletin.DefaultBulletinConfig("node-a")
+	cfg.DataDir = t.TempDir()
+	bb, err := bulletin.NewBulletinBoard(cfg)
+	if err != nil {
+		t.Fatalf("NewBulletinBoard failed: %v", err)
+	}
+	if _, err := shareTemplate(owner, bb, saved.ID); err != nil {
+		t.Fatalf("shareTemplate failed: %v", err)
+	}
+
+	receiver := task.NewTemplateStore("node-b", "")
+	if got := syncSharedTemplates(receiver, bb); got != 1 {
+		t.Fatalf("expected 1 imported template, got %d", got)
+	}
+	draft, err := receiver.Instantiate(saved.ID, "node-b", map[string]interface{}{"query": "LLM"}, 0)
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	if m := toMap(draft); m["title"] != "Search: LLM" || m["template_id"] != saved.ID {
+		t.Errorf("unexpected draft task: %v", m)
+	}
+}
+
+func TestBreakerDirectiveBulletin(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(nil)
+	newBreaker := func(nodeID string) *breaker.Manager {
+		cfg := breaker.DefaultConfig(nodeID)
+		cfg.DataDir = t.TempDir()
+		cfg.SignFunc = func(data []byte) ([]byte, error) { return ed25519.Sign(priv, data), nil }
+		cfg.VerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+			return signerID == "super-a" && ed25519.Verify(priv.Public().(ed25519.PublicKey), data, signature), nil
+		}
+		m, err := breaker.NewManager(cfg)
+		if err != nil {
+			t.Fatalf("NewManager failed: %v", err)
+		}
+		m.SetSupernodeFunc(func(id string) bool { return id == "super-a" })
+		return m
+	}
+
+	issuer := newBreaker("super-a")
+	d, err := issuer.Issue(breaker.ClassAccusation, breaker.ActionPause, 0, "spam storm", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	if _, err := publishBreakerDirective(nil, d); err == nil {
+		t.Error("expected error without bulletin board")
+	}
+
+	cfg := bulletin.DefaultBulletinConfig("super-a")
+	cfg.DataDir = t.TempDir()
+	bb, err := bulletin.NewBulletinBoard(cfg)
+	if err != nil {
+		t.Fatalf("NewBulletinBoard failed: %v", err)
+	}
+	if _, err := publishBreakerDirective(bb, d); err != nil {
+		t.Fatalf("publishBreakerDirective failed: %v", err)
+	}
+	bb.PublishMessage("not a directive", breaker.BulletinTopic)
+
+	receiver := newBreaker("node-b")
+	if got := syncBreakerDirectives(receiver, bb); got != 1 {
+		t.Fatalf("expected 1 applied directive, got %d", got)
+	}
+	if err := receiver.Allow(breaker.ClassAccusation); err == nil {
+		t.Error("accusations should be paused on the receiver")
+	}
+}
+
+func TestActiveFeatures(t *testing.T) {
+	names := activeFeatures([]*feature.Status{
+		{Name: "escrow", State: feature.StateActive},
+		{Name: "gossip-v2", State: feature.StateSignaling},
+		{Name: "quota", State: feature.StateActive},
+	})
+	if len(names) != 2 || names[0] != "escrow" || names[1] != "quota" {
+		t.Errorf("activeFeatures = %v", names)
+	}
+}
+
+func TestMetricsHelpers(t *testing.T) {
+	got := map[string]float64{}
+	for _, m := range bandwidthMetrics(&bandwidth.Stats{
+		Rate:        1024,
+		TotalBytes:  4096,
+		ActivePeers: 2,
+		Peers:       []*bandwidth.PeerShare{{QueuedBytes: 100}, {QueuedBytes: 50}},
+	}) {
+		got[m.Name] = m.Value
+	}
+	if got["outbound_bytes"] != 4096 || got["active_peers"] != 2 || got["queued_bytes"] != 150 {
+		t.Errorf("带宽指标错误: %v", got)
+	}
+
+	samples := reputationSamples([]*neighbor.Neighbor{{Reputation: 10}, {Reputation: -5}})
+	if len(samples) != 2 || samples[0] != 10 || samples[1] != -5 {
+		t.Errorf("声誉样本错误: %v", samples)
+	}
+
+	srv := startMetricsServer("127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	stopMetricsServer(srv)
+}
+
+func TestEscrowService(t *testing.T) {
+	config := escrow.DefaultEscrowConfig()
+	config.DataDir = t.TempDir()
+	config.VerifyFunc = func(signer string, data []byte, signature string) bool {
+		return signature == signer+"|"+string(data)
+	}
+	sign := func(signer string) func([]byte) (string, error) {
+		return func(data []byte) (string, error) { return signer + "|" + string(data), nil }
+	}
+	var svc httpapi.EscrowService = escrowService{m: escrow.NewEscrowManager(config), self: "self", sign: sign("self")}
+
+	if _, err := svc.GetEscrow("missing"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	created, err := svc.CreateEscrow("task1", map[string]float64{"self": 10, "peer": 5})
+	if err != nil {
+		t.Fatalf("CreateEscrow failed: %v", err)
+	}
+	id := created["id"].(string)
+
+	// 本节点存入时自动签名，替其他节点存入必须带签名
+	if _, err := svc.Deposit(id, "", 10, ""); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	if _, err := svc.Deposit(id, "peer", 5, "bad"); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for a bad signature, got %v", err)
+	}
+	peerSig, _ := sign("peer")(escrow.DepositPayload(id, "peer", 5))
+	if _, err := svc.Deposit(id, "peer", 5, peerSig); err != nil {
+		t.Fatalf("peer Deposit failed: %v", err)
+	}
+
+	if _, err := svc.SetArbitrators(id, []string{"a1", "a2", "a3"}, 2, nil); !errors.Is(err, escrow.ErrArbitratorConsent) {
+		t.Fatalf("expected ErrArbitratorConsent without the peer's signature, got %v", err)
+	}
+	arbSig, _ := sign("peer")(escrow.ArbitratorsPayload(id, []string{"a1", "a2", "a3"}, 2))
+	if _, err := svc.SetArbitrators(id, []string{"a1", "a2", "a3"}, 2, map[string]string{"peer": arbSig}); err != nil {
+		t.Fatalf("SetArbitrators with both participants' signatures failed: %v", err)
+	}
+	if _, err := svc.Dispute(id, "late delivery"); err != nil {
+		t.Fatalf("Dispute failed: %v", err)
+	}
+	proposal, err := svc.ProposeResolution(id, "self", 0)
+	if err != nil || proposal["amount"] != 15.0 {
+		t.Fatalf("ProposeResolution = %v, %v", proposal, err)
+	}
+
+	for i, arb := range []string{"a1", "a2"} {
+		sig, _ := sign(arb)([]byte(proposal["payload"].(string)))
+		result, err := svc.SubmitArbitratorSignature(id, arb, sig)
+		if err != nil {
+			t.Fatalf("SubmitArbitratorSignature(%s) failed: %v", arb, err)
+		}
+		if result["resolved"] != (i == 1) {
+			t.Errorf("%s: resolved = %v", arb, result["resolved"])
+		}
+	}
+	detail, _ := svc.GetEscrow(id)
+	if detail["status"] != "released" || detail["released_to"] != "self" {
+		t.Errorf("expected release to self, got %v", detail)
+	}
+	if list := svc.ListEscrows("released"); len(list) != 1 {
+		t.Errorf("expected 1 released escrow, got %d", len(list))
+	}
+}
+
+func TestDisputeService(t *testing.T) {
+	escrowConfig := escrow.DefaultEscrowConfig()
+	escrowConfig.DataDir = t.TempDir()
+	escrows := escrow.NewEscrowManager(escrowConfig)
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = t.TempDir()
+	disputes := dispute.NewDisputeManager(disputeConfig)
+	disputes.SetResolutionHandler(proposeEscrowResolution(escrows, "self"))
+	var svc httpapi.DisputeService = disputeService{m: disputes, escrows: escrows, self: "self"}
+
+	e, _ := escrows.CreateEscrow("task1", map[string]float64{"self": 10, "peer": 5})
+	escrows.Deposit(e.ID, "self", 10, "sig")
+	escrows.Deposit(e.ID, "peer", 5, "sig")
+
+	fileReq := httpapi.DisputeFileRequest{EscrowID: e.ID, Defendant: "stranger", Type: "non_delivery", Description: "never delivered"}
+	if _, err := svc.FileDispute(fileReq); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for a non-participant, got %v", err)
+	}
+	fileReq.Defendant = "peer"
+	filed, err := svc.FileDispute(fileReq)
+	if err != nil {
+		t.Fatalf("FileDispute failed: %v", err)
+	}
+	id := filed["id"].(string)
+	if filed["task_id"] != "task1" || filed["stage"] != "filed" || e.Status != escrow.EscrowDisputed {
+		t.Errorf("filed = %v, escrow status %s", filed, e.Status)
+	}
+
+	if _, err := svc.GetDispute("missing"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	evidence, err := svc.AttachEvidence(id, "text", "chat log", []byte("no delivery after deadline"))
+	if err != nil {
+		t.Fatalf("AttachEvidence failed: %v", err)
+	}
+	if err := svc.VerifyEvidence(id, evidence["id"].(string), ""); err != nil {
+		t.Fatalf("VerifyEvidence failed: %v", err)
+	}
+	suggestion, err := svc.Suggestion(id)
+	if err != nil || suggestion["can_auto_execute"] != true {
+		t.Fatalf("Suggestion = %v, %v", suggestion, err)
+	}
+	if _, err := svc.ApplySuggestion(id, ""); err != nil {
+		t.Fatalf("ApplySuggestion failed: %v", err)
+	}
+
+	// 裁决成为托管的待签名结算方案（金额 0 表示全部可用余额）
+	if e.Resolution == nil || e.Resolution.Winner != "self" || e.Resolution.Amount != 15 {
+		t.Errorf("escrow resolution = %+v", e.Resolution)
+	}
+	if list := svc.ListDisputes("resolved"); len(list) != 1 {
+		t.Errorf("expected 1 resolved dispute, got %d", len(list))
+	}
+}
+
+func TestDisputeChannel(t *testing.T) {
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = t.TempDir()
+	disputeConfig.WrapKeyFunc = func(member string, key []byte) ([]byte, error) { return key, nil }
+	disputeConfig.UnwrapKeyFunc = func(wrapped []byte) ([]byte, error) { return wrapped, nil }
+	disputes := dispute.NewDisputeManager(disputeConfig)
+	d, err := disputes.CreateDispute("task1", "self", "peer", dispute.DisputeQualityIssue, "unusable", 10)
+	if err != nil {
+		t.Fatalf("CreateDispute: %v", err)
+	}
+	disputes.SubmitEvidence(d.ID, "self", "text", "evidence", "hash")
+	disputes.StartReview(d.ID)
+	if err := disputes.StartArbitration(d.ID, []string{"arb1", "arb2", "arb3"}); err != nil {
+		t.Fatalf("StartArbitration: %v", err)
+	}
+
+	// 被诉方节点经流访问申诉方节点保存的频道，对端身份由连接决定
+	remote := func(self string) disputeChannel {
+		return disputeChannel{self: self, dial: func(ctx context.Context, holder string) (io.ReadWriteCloser, error) {
+			if holder != "self" {
+				return nil, errors.New("unknown holder")
+			}
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+				disputes.HandleChannelRequest(server, self)
+			}()
+			return client, nil
+		}}
+	}
+	holder := disputeChannel{m: disputes, self: "self"}
+	if _, err := holder.post(d.ID, "", "section 3 is missing"); err != nil {
+		t.Fatalf("local post: %v", err)
+	}
+	if _, err := remote("peer").post(d.ID, "self", "out of scope"); err != nil {
+		t.Fatalf("remote post: %v", err)
+	}
+	transcript, err := remote("arb1").transcript(d.ID, "self")
+	if err != nil {
+		t.Fatalf("remote transcript: %v", err)
+	}
+	messages := transcript["messages"].([]dispute.TranscriptMessage)
+	if len(messages) != 2 || messages[1].SenderID != "peer" || messages[1].Content != "out of scope" {
+		t.Errorf("transcript = %+v", messages)
+	}
+	if _, err := remote("stranger").transcript(d.ID, "self"); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for a non-member, got %v", err)
+	}
+	if _, err := holder.transcript("missing", ""); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestExportDisputeBundle(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = t.TempDir()
+	disputeConfig.BundleSignFunc = func(data []byte) ([]byte, error) { return ed25519.Sign(priv, data), nil }
+	disputeConfig.BundlePublicKey = pub
+	disputeConfig.BundleAlgorithm = "ed25519"
+	disputes := dispute.NewDisputeManager(disputeConfig)
+	escrows := escrow.NewEscrowManager(escrow.DefaultEscrowConfig())
+	e, err := escrows.CreateEscrow("task1", map[string]float64{"self": 10, "peer": 10})
+	if err != nil {
+		t.Fatalf("CreateEscrow: %v", err)
+	}
+	d, err := disputes.CreateEscrowDispute(e.ID, "task1", "self", "peer", dispute.DisputeQualityIssue, "unusable", 10)
+	if err != nil {
+		t.Fatalf("CreateEscrowDispute: %v", err)
+	}
+
+	export := exportDisputeBundle(disputes, escrows, "self")
+	if _, err := export("missing"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	data, err := export(d.ID)
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	// 经 API 的 JSON 往返后仍能离线校验
+	raw, _ := json.Marshal(data)
+	var b dispute.CustodyBundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		t.Fatalf("decode bundle: %v", err)
+	}
+	report, err := dispute.VerifyBundle(&b, nil)
+	if err != nil {
+		t.Fatalf("VerifyBundle: %v", err)
+	}
+	if report.Exporter != "self" || report.Attachments[dispute.AttachmentReceipt] != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestCollateralService(t *testing.T) {
+	m := collateral.NewCollateralManager()
+	var svc httpapi.CollateralService = collateralService{m: m, self: "self"}
+
+	if _, err := svc.Deposit("supernode_auditor", 100); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	m.Lock("self", "supernode_auditor", 60, "supernode candidacy")
+	if _, err := svc.Withdraw("supernode_auditor", 50); !errors.Is(err, collateral.ErrCollateralLocked) {
+		t.Errorf("expected ErrCollateralLocked, got %v", err)
+	}
+	account, err := svc.GetCollateral("self", "supernode_auditor")
+	if err != nil || account["available"] != 40.0 || account["status"] != collateral.CollateralStatusLocked {
+		t.Fatalf("GetCollateral = %v, %v", account, err)
+	}
+	if _, err := svc.GetCollateral("peer", "supernode_auditor"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	slashed, err := svc.Slash(httpapi.CollateralSlashRequest{NodeID: "self", Purpose: "supernode_auditor", Ratio: 0.25, Reason: "audit deviation", Evidence: "violation_event:abc"})
+	if err != nil || slashed["slashed_amount"] != 25.0 || slashed["remaining"] != 75.0 {
+		t.Fatalf("Slash = %v, %v", slashed, err)
+	}
+	if history := svc.SlashHistory("self", "", 0); len(history) != 1 || history[0]["id"] != slashed["id"] {
+		t.Errorf("SlashHistory = %v", history)
+	}
+	if list := svc.ListCollaterals("", collateral.CollateralStatusLocked); len(list) != 1 {
+		t.Errorf("expected 1 locked account, got %d", len(list))
+	}
+}
+
+func TestTokenService(t *testing.T) {
+	config := token.DefaultConfig()
+	config.DataDir = ""
+	config.VerifyFunc = func(signer string, data []byte, sig string) bool { return sig == "sig:"+signer }
+	l, err := token.NewLedger(config)
+	if err != nil {
+		t.Fatalf("NewLedger failed: %v", err)
+	}
+	l.MintReward("self", "r1", 50)
+	l.MintReward("peer", "r2", 20)
+	sign := func(data []byte) (string, error) { return "sig:self", nil }
+	tasks := task.NewTaskManager(&task.TaskManagerConfig{DataDir: t.TempDir(), MaxTasksPerHour: 10})
+	for _, tk := range []*task.Task{
+		{ID: "task1", Type: task.TaskTypeSearch, Title: "own", RequesterID: "self", Reward: 30},
+		{ID: "task3", Type: task.TaskTypeSearch, Title: "other", RequesterID: "peer", Reward: 10},
+	} {
+		if err := tasks.PublishTask(tk, 0); err != nil {
+			t.Fatalf("PublishTask: %v", err)
+		}
+	}
+	var svc httpapi.TokenService = tokenService{l: l, tasks: tasks, self: "self", sign: sign}
+
+	if _, err := svc.Transfer(httpapi.TokenTransferRequest{To: "peer", Amount: 10, Memo: "hire"}); err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	if _, err := svc.Transfer(httpapi.TokenTransferRequest{From: "peer", To: "self", Amount: 5, Nonce: 1, Signature: "sig:self"}); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	if balance := svc.Balance(""); balance["balance"] != 40.0 || balance["available"] != 40.0 {
+		t.Errorf("Balance = %v", balance)
+	}
+
+	if _, err := svc.GetTaskPayment("task1"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := svc.HoldTaskPayment("missing", 30); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("hold for unknown task error = %v", err)
+	}
+	if _, err := svc.HoldTaskPayment("task3", 5); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("hold for another requester's task error = %v", err)
+	}
+	if _, err := svc.HoldTaskPayment("task1", 30); err != nil {
+		t.Fatalf("HoldTaskPayment failed: %v", err)
+	}
+	l.HoldTaskPayment("task2", "peer", 10)
+	if _, err := svc.RefundTaskPayment("task2"); !errors.Is(err, httpapi.ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for another payer, got %v", err)
+	}
+	paid, err := svc.ReleaseTaskPayment("task1", map[string]float64{"peer": 25})
+	if err != nil || paid["status"] != string(token.PaymentReleased) || paid["refunded"] != 5.0 {
+		t.Fatalf("ReleaseTaskPayment = %v, %v", paid, err)
+	}
+	if history := svc.History("peer", 0); len(history) < 2 || history[0]["amount"] != 25.0 {
+		t.Errorf("History = %v", history)
+	}
+}
+
+func TestTaskPayments(t *testing.T) {
+	newLedger := func(self string) *token.Ledger {
+		config := token.DefaultConfig()
+		config.DataDir = ""
+		config.NodeID = self
+		config.SignFunc = func(data []byte) (string, error) { return "sig:" + self, nil }
+		config.VerifyFunc = func(signer string, data []byte, sig string) bool { return sig == "sig:"+signer }
+		l, err := token.NewLedger(config)
+		if err != nil {
+			t.Fatalf("NewLedger: %v", err)
+		}
+		return l
+	}
+	requester, worker := newLedger("self"), newLedger("worker")
+
+	// 两个节点的账本经广播同步签名流水，铸造只接受已登记节点的
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	registered := func(id string) bool { return id == "self" || id == "worker" }
+	if err := startTokenGossip(requester, busTransport{bus: bus, node: "self"}, registered); err != nil {
+		t.Fatalf("startTokenGossip: %v", err)
+	}
+	if err := startTokenGossip(worker, busTransport{bus: bus, node: "worker"}, registered); err != nil {
+		t.Fatalf("startTokenGossip: %v", err)
+	}
+	requester.MintReward("self", "seed", 50)
+
+	tasks := task.NewTaskManager(&task.TaskManagerConfig{DataDir: t.TempDir(), MaxTasksPerHour: 10})
+	incentiveConfig := incentive.DefaultIncentiveConfig("self")
+	incentiveConfig.DataDir = t.TempDir()
+	rewards, err := incentive.NewIncentiveManager(incentiveConfig)
+	if err != nil {
+		t.Fatalf("NewIncentiveManager: %v", err)
+	}
+	rewards.OnRewardCreated = requester.RewardHook()
+	payments := taskPayments{tasks: tasks, tokens: requester, incentive: rewards}
+	tasks.SetSettlementHandler(payments.settled)
+	tasks.SetCancelHandler(func(tk *task.Task) { payments.refund(tk.ID) })
+
+	done := &task.Task{ID: "paid", Type: task.TaskTypeCompute, Title: "paid", RequesterID: "self", Reward: 20}
+	cancelled := &task.Task{ID: "dropped", Type: task.TaskTypeSearch, Title: "dropped", RequesterID: "self", Reward: 10}
+	for _, tk := range []*task.Task{done, cancelled} {
+		if err := tasks.PublishTask(tk, 0); err != nil {
+			t.Fatalf("PublishTask: %v", err)
+		}
+		if _, err := requester.HoldTaskPayment(tk.ID, "self", tk.Reward); err != nil {
+			t.Fatalf("HoldTaskPayment: %v", err)
+		}
+	}
+	tasks.AssignTask(&task.TaskAssignment{TaskID: done.ID, AssignedTo: "worker"})
+	tasks.StartExecution(done.ID, "worker")
+	tasks.SubmitDelivery(done.ID, "worker", "hash", "sig")
+	tasks.ConfirmDelivery(done.ID, "self", "sig")
+	if _, err := tasks.SettleTask(done.ID); err != nil {
+		t.Fatalf("SettleTask: %v", err)
+	}
+	if err := tasks.CancelTask(cancelled.ID, "self"); err != nil {
+		t.Fatalf("CancelTask: %v", err)
+	}
+
+	if p, _ := requester.GetTaskPayment(done.ID); p.Status != token.PaymentReleased || p.Payouts["worker"] != 20 {
+		t.Errorf("settled payment = %+v", p)
+	}
+	if p, _ := requester.GetTaskPayment(cancelled.ID); p.Status != token.PaymentRefunded {
+		t.Errorf("cancelled payment = %+v", p)
+	}
+	reward, err := rewards.GetRewardByTask(done.ID)
+	if err != nil || reward.NodeID != "worker" || reward.TaskType != incentive.TaskTypeCompute {
+		t.Fatalf("reward = %+v, %v", reward, err)
+	}
+	// 执行方节点收到报酬支付和奖励铸造
+	minted := reward.FinalScore
+	if a := worker.GetAccount("worker"); a.Balance != 20+minted || a.Minted != minted {
+		t.Errorf("worker account on worker node = %+v, want 20 paid + %v minted", a, minted)
+	}
+}
+
+func TestTaskNetwork(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	nodes := make(map[string]*taskNetwork)
+	for _, id := range []string{"self", "worker", "other"} {
+		nodes[id] = newTaskNetwork(newTaskManager(t.TempDir()), id, nil)
+		if err := nodes[id].start(busTransport{bus: bus, node: id}); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	}
+	requester, worker, other := nodes["self"], nodes["worker"], nodes["other"]
+	for _, tn := range []*taskNetwork{worker, other} {
+		tn.offers, _ = task.NewOfferQueue(nil)
+	}
+	status := func(tn *taskNetwork, taskID string) task.TaskStatus {
+		t.Helper()
+		tk, err := tn.tm.GetTask(taskID)
+		if err != nil {
+			t.Fatalf("GetTask on %s: %v", tn.self, err)
+		}
+		return tk.Status
+	}
+
+	offer := taskFromRequest("self", &httpapi.TaskRequest{Type: "search", Description: "find papers", Reward: 5})
+	if err := requester.tm.PublishTask(offer, 0); err != nil {
+		t.Fatalf("PublishTask: %v", err)
+	}
+	requester.offer(offer.ID)
+	if status(worker, offer.ID) != task.StatusPublished {
+		t.Fatal("the offer should reach other nodes")
+	}
+	s := &httpapi.Server{}
+	registerTaskOfferAPI(s, worker.offers)
+	if offers := s.TaskOffersFunc(10); len(offers) != 1 || offers[0]["rank"] != float64(1) {
+		t.Fatalf("offers = %v", offers)
+	}
+	if score, err := s.TaskOfferScoreFunc(offer.ID); err != nil || score["total"] == nil {
+		t.Errorf("offer score = %v, %v", score, err)
+	}
+	policy, err := s.TaskOfferPolicySetFunc(&httpapi.TaskOfferPolicyRequest{BudgetWeight: 1, ReferenceBudgetPerUnit: 10, ReferenceReputation: 100, DeadlineHorizonSeconds: 3600, MinLeadTimeSeconds: 60})
+	if err != nil || policy["deadline_horizon_seconds"] != int64(3600) || s.TaskOfferPolicyFunc()["budget_weight"] != float64(1) {
+		t.Errorf("policy = %v, %v", policy, err)
+	}
+	if _, err := s.TaskOfferPolicySetFunc(&httpapi.TaskOfferPolicyRequest{ReferenceBudgetPerUnit: 10, ReferenceReputation: 100, DeadlineHorizonSeconds: 3600}); err == nil {
+		t.Error("a policy without weights must be rejected")
+	}
+
+	// 接单方先在本地记下，再交给委托方节点；晚到的接单被委托方拒绝
+	claim := &task.TaskClaim{TaskID: offer.ID, ClaimerID: "worker"}
+	if err := worker.tm.ClaimTask(claim, 0); err != nil {
+		t.Fatalf("ClaimTask: %v", err)
+	}
+	worker.claim(claim)
+	other.claim(&task.TaskClaim{TaskID: offer.ID, ClaimerID: "other"})
+	for _, tn := range nodes {
+		if tk, _ := tn.tm.GetTask(offer.ID); tk.ExecutorID != "worker" || tk.Status != task.StatusAccepted {
+			t.Errorf("%s: task should be accepted by worker, got %+v", tn.self, tk)
+		}
+	}
+	// 接单后任务不再待接，移出各节点的邀约队列
+	if worker.offers.Len() != 0 || other.offers.Len() != 0 {
+		t.Errorf("offers left after claim: worker %d, other %d", worker.offers.Len(), other.offers.Len())
+	}
+
+	_, delivery, err := submitTaskResult(worker.tm, "worker", &httpapi.TaskSubmitRequest{TaskID: offer.ID, Result: "done"}, nil)
+	if err != nil {
+		t.Fatalf("submitTaskResult: %v", err)
+	}
+	worker.deliver(delivery)
+	if status(requester, offer.ID) != task.StatusDelivered {
+		t.Fatal("the delivery should reach the requester")
+	}
+
+	// 伪造的副本只能由委托方本人广播
+	forged, _ := worker.tm.GetTask(offer.ID)
+	fake := *forged
+	fake.Status, fake.Revision = task.StatusSettled, forged.Revision+1
+	worker.publish(taskTopic, taskMessage{Task: &fake})
+	if status(other, offer.ID) != task.StatusDelivered {
+		t.Error("a copy published by the executor must be ignored")
+	}
+
+	if _, err := confirmTaskDelivery(requester.tm, "self", offer.ID, nil); err != nil {
+		t.Fatalf("confirmTaskDelivery: %v", err)
+	}
+	requester.announce(offer.ID)
+	if _, err := requester.tm.SettleTask(offer.ID); err != nil {
+		t.Fatalf("SettleTask: %v", err)
+	}
+	requester.announce(offer.ID)
+	for _, tn := range nodes {
+		if got := status(tn, offer.ID); got != task.StatusSettled {
+			t.Errorf("%s: status = %s, want settled", tn.self, got)
+		}
+	}
+}
+
+func TestSubcontractAPI(t *testing.T) {
+	tasks := task.NewTaskManager(&task.TaskManagerConfig{DataDir: t.TempDir(), MaxTasksPerHour: 10})
+	if err := tasks.ReceiveTask(&task.Task{ID: "parent", Type: task.TaskTypeCompute, Title: "train", RequesterID: "origin", ExecutorID: "self", Reward: 10, Status: task.StatusAccepted, Revision: 1}); err != nil {
+		t.Fatalf("ReceiveTask: %v", err)
+	}
+	escrowConfig := escrow.DefaultEscrowConfig()
+	escrowConfig.DataDir = t.TempDir()
+	escrows := escrow.NewEscrowManager(escrowConfig)
+	parentEscrow, _ := escrows.CreateEscrow("parent", map[string]float64{"origin": 10})
+	if err := escrows.Deposit(parentEscrow.ID, "origin", 10, "sig"); err != nil {
+		t.Fatal(err)
+	}
+	reputations, err := reputation.NewManager(reputation.DefaultManagerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wireSubcontracts(tasks, escrows, reputations)
+
+	var offered, announced []string
+	s := &httpapi.Server{}
+	registerSubcontractAPI(s, tasks, "self", func(id string) { offered = append(offered, id) }, func(id string) { announced = append(announced, id) })
+
+	sub, err := s.TaskSubcontractFunc(&httpapi.TaskSubcontractRequest{ParentTaskID: "parent", Title: "preprocess", Reward: 5, Executor: "worker"})
+	if err != nil {
+		t.Fatalf("subcontract failed: %v", err)
+	}
+	subID, _ := sub["id"].(string)
+	if sub["requester_id"] != "self" || sub["target_executor_id"] != "worker" || sub["parent_task_id"] != "parent" {
+		t.Errorf("unexpected subtask %v", sub)
+	}
+	// 子任务向任务网络发布，预算从上级托管划拨
+	if len(offered) != 1 || offered[0] != subID {
+		t.Errorf("expected subtask to be offered, got %v", offered)
+	}
+	if child, err := escrows.GetEscrowByTask(subID); err != nil || child.ParentID != parentEscrow.ID || child.TotalAmount != 5 {
+		t.Errorf("expected nested escrow, got %+v, %v", child, err)
+	}
+	if _, err := s.TaskSubcontractFunc(&httpapi.TaskSubcontractRequest{ParentTaskID: "parent", Title: "too much", Reward: 4}); !errors.Is(err, task.ErrSubcontractBudget) {
+		t.Errorf("expected ErrSubcontractBudget, got %v", err)
+	}
+
+	chain, err := s.TaskSubcontractChainFunc(subID)
+	if err != nil || len(chain) != 2 || chain[0]["id"] != "parent" {
+		t.Errorf("unexpected chain %v, %v", chain, err)
+	}
+
+	// 承包方接单后失败：处罚扣减链上各级的声誉，争议状态的子任务副本被广播
+	claim := &task.TaskClaim{TaskID: subID, ClaimerID: "worker", ClaimTime: time.Now().Unix()}
+	if err := tasks.ClaimTask(claim, 0); err != nil {
+		t.Fatalf("ClaimTask: %v", err)
+	}
+	before := reputations.GetReputation("worker")
+	penalties, err := s.TaskSubcontractFailFunc(subID, "missed deadline")
+	if err != nil || len(penalties) != 2 || penalties[1]["node_id"] != "self" {
+		t.Fatalf("unexpected penalties %v, %v", penalties, err)
+	}
+	if reputations.GetReputation("worker") >= before {
+		t.Error("expected worker reputation to drop")
+	}
+	if len(announced) != 1 || announced[0] != subID {
+		t.Errorf("expected disputed subtask to be announced, got %v", announced)
+	}
+}
+
+func TestStorageProofChallenge(t *testing.T) {
+	data := []byte(strings.Repeat("stored chunk ", 20000)) // 多个分块
+	// 委托方 self 和存储方 storer 各自保存任务副本
+	newTasks := func() *task.TaskManager {
+		tasks := task.NewTaskManager(&task.TaskManagerConfig{DataDir: t.TempDir(), MaxTasksPerHour: 10})
+		if err := tasks.ReceiveTask(&task.Task{ID: "store1", Type: task.TaskTypeStorage, Title: "keep file", RequesterID: "self", ExecutorID: "storer", Status: task.StatusAccepted, Revision: 1}); err != nil {
+			t.Fatalf("ReceiveTask: %v", err)
+		}
+		return tasks
+	}
+	requesterTasks, storerTasks := newTasks(), newTasks()
+
+	// 委托方和存储方的文件都位于各自的 <data>/storage 内
+	holdingsDir, requesterDir := t.TempDir(), t.TempDir()
+	holdings, requesterHoldings := loadStorageHoldings(holdingsDir), loadStorageHoldings(requesterDir)
+	path := filepath.Join(holdingsDir, "storage", "data.bin")
+	for _, p := range []string{path, filepath.Join(requesterDir, "storage", "data.bin")} {
+		if err := os.WriteFile(p, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	storerAPI := &httpapi.Server{}
+	registerStorageProofAPI(storerAPI, nil, holdings, storerTasks, "storer", nil)
+
+	// 存储目录外的文件（包括经符号链接和 .. 指向的）不能登记
+	secret := filepath.Join(holdingsDir, "node.key")
+	os.WriteFile(secret, []byte("private key"), 0600)
+	os.Symlink(secret, filepath.Join(holdingsDir, "storage", "link.bin"))
+	for _, p := range []string{secret, "../node.key", "link.bin", "sub/../../node.key", "."} {
+		if _, err := storerAPI.TaskStorageHoldFunc(&httpapi.TaskStorageRequest{TaskID: "store1", Path: p}); !errors.Is(err, errOutsideStorage) {
+			t.Errorf("hold %q: expected errOutsideStorage, got %v", p, err)
+		}
+	}
+
+	held, err := storerAPI.TaskStorageHoldFunc(&httpapi.TaskStorageRequest{TaskID: "store1", Path: "data.bin"})
+	if err != nil {
+		t.Fatalf("hold failed: %v", err)
+	}
+	if reloaded := loadStorageHoldings(holdingsDir); reloaded.entries["store1"] == nil || reloaded.entries["store1"].Requester != "self" {
+		t.Errorf("expected holding to persist, got %+v", reloaded.entries)
+	}
+
+	m, err := incentive.NewStorageProofManager(nil, incentive.DefaultStorageProofConfig("self"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accused []string
+	m.OnChallengeFailed = func(c *incentive.StorageChallenge) { accused = append(accused, c.NodeID) }
+	challenger := "self"
+	challenge := challengeStorage(m, func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) {
+		if nodeID != "storer" {
+			return nil, fmt.Errorf("unexpected peer %s", nodeID)
+		}
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			incentive.ServeStorageProof(server, func(c *incentive.StorageChallenge) (*incentive.StorageProof, error) {
+				return holdings.prove(challenger, c)
+			})
+		}()
+		return client, nil
+	})
+	requesterAPI := &httpapi.Server{}
+	registerStorageProofAPI(requesterAPI, m, requesterHoldings, requesterTasks, "self", challenge)
+
+	// 委托方按原始文件登记承诺，与存储方持有文件的 Merkle 根一致
+	if _, err := requesterAPI.TaskStorageCommitFunc(&httpapi.TaskStorageRequest{TaskID: "store1", Path: secret}); !errors.Is(err, errOutsideStorage) {
+		t.Errorf("commit outside storage: expected errOutsideStorage, got %v", err)
+	}
+	commitment, err := requesterAPI.TaskStorageCommitFunc(&httpapi.TaskStorageRequest{TaskID: "store1", Path: "data.bin"})
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if commitment["merkle_root"] != held["merkle_root"] || commitment["node_id"] != "storer" {
+		t.Errorf("commitment %v does not match holding %v", commitment, held)
+	}
+	if _, err := storerAPI.TaskStorageCommitFunc(&httpapi.TaskStorageRequest{TaskID: "store1", Path: "data.bin"}); err == nil {
+		t.Error("expected only the requester to register a commitment")
+	}
+
+	result, err := requesterAPI.TaskStorageChallengeFunc("store1")
+	if err != nil || result["status"] != string(incentive.ChallengePassed) {
+		t.Fatalf("expected passed challenge, got %v, %v", result, err)
+	}
+
+	// 存储方不响应其他节点的挑战，挑战保持待响应，超时后判定失败
+	challenger = "intruder"
+	if _, err := challenge("store1"); err == nil || !strings.Contains(err.Error(), "not from the task requester") {
+		t.Errorf("expected storer to refuse, got %v", err)
+	}
+	challenger = "self"
+
+	// 存储方的数据被篡改后证明无法通过
+	if err := os.WriteFile(path, []byte(strings.Repeat("lost chunk!! ", 20000)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := challenge("store1"); err != nil || result.Status != incentive.ChallengeFailed {
+		t.Errorf("expected failed challenge, got %+v, %v", result, err)
+	}
+	if len(accused) != 1 || accused[0] != "storer" {
+		t.Errorf("expected storer to be accused once, got %v", accused)
+	}
+
+	history, err := requesterAPI.TaskStorageChallengesFunc("store1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenges := history["challenges"].([]map[string]interface{}); len(challenges) != 3 || challenges[0]["status"] != "passed" || challenges[1]["status"] != "pending" {
+		t.Errorf("unexpected challenge history %v", challenges)
+	}
+	if active := storageTaskActive(requesterTasks); !active("store1") || active("missing") {
+		t.Error("expected only the accepted storage task to be active")
+	}
+}
+
+func TestTaskProgress(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	nodes := make(map[string]*taskNetwork)
+	for _, id := range []string{"self", "worker"} {
+		nodes[id] = newTaskNetwork(newTaskManager(t.TempDir()), id, nil)
+		if err := nodes[id].start(busTransport{bus: bus, node: id}); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	}
+	requester, worker := nodes["self"], nodes["worker"]
+	offer := taskFromRequest("self", &httpapi.TaskRequest{Type: "train", Description: "fine-tune", Reward: 5})
+	if err := requester.tm.PublishTask(offer, 0); err != nil {
+		t.Fatalf("PublishTask: %v", err)
+	}
+	requester.offer(offer.ID)
+	claim := &task.TaskClaim{TaskID: offer.ID, ClaimerID: "worker"}
+	if err := worker.tm.ClaimTask(claim, 0); err != nil {
+		t.Fatalf("ClaimTask: %v", err)
+	}
+	worker.claim(claim)
+
+	// 进度经流推送给委托方节点，执行者身份取自连接
+	var wg sync.WaitGroup
+	reachable := true
+	worker.tm.SetProgressSendFunc(progressSender("worker", func(ctx context.Context, nodeID string) (io.WriteCloser, error) {
+		if !reachable || nodeID != "self" {
+			return nil, errors.New("unreachable")
+		}
+		client, server := net.Pipe()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requester.tm.HandleProgressStream(server, "worker")
+		}()
+		return client, nil
+	}))
+
+	report, err := reportTaskProgress(worker.tm, "worker", &httpapi.TaskProgressRequest{TaskID: offer.ID, Percent: 40, Checkpoint: "sha256:7f3a", Message: "training"})
+	wg.Wait()
+	if err != nil || report["delivered"] != true {
+		t.Fatalf("reportTaskProgress = %v, %v", report, err)
+	}
+	progress, err := taskProgressMap(requester.tm, offer.ID)
+	if err != nil {
+		t.Fatalf("taskProgressMap: %v", err)
+	}
+	executors := progress["executors"].([]*task.TaskProgress)
+	if len(executors) != 1 || executors[0].ExecutorID != "worker" || executors[0].Percent != 40 || len(executors[0].Checkpoints) != 1 {
+		t.Errorf("requester progress = %+v", executors)
+	}
+
+	// 委托方不可达时进度仍在本地记录，返回送达错误
+	reachable = false
+	report, err = reportTaskProgress(worker.tm, "worker", &httpapi.TaskProgressRequest{TaskID: offer.ID, Percent: 60})
+	if err != nil || report["delivered"] != false || report["delivery_error"] == nil {
+		t.Errorf("undelivered report = %v, %v", report, err)
+	}
+	if _, err := reportTaskProgress(worker.tm, "worker", &httpapi.TaskProgressRequest{TaskID: offer.ID, Percent: 10}); err == nil {
+		t.Error("percent must not go back")
+	}
+}
+
+func TestTaskNetworkReviewers(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	nodes := make(map[string]*taskNetwork)
+	for _, id := range []string{"self", "worker", "r1", "r2"} {
+		nodes[id] = newTaskNetwork(newTaskManager(t.TempDir()), id, nil)
+		if err := nodes[id].start(busTransport{bus: bus, node: id}); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	}
+	requester := nodes["self"]
+	for _, id := range []string{"worker", "r1", "r2"} {
+		requester.tm.RegisterCapability(&task.AgentCapability{AgentID: id})
+	}
+
+	tk := taskFromRequest("self", &httpapi.TaskRequest{Type: "search", Description: "review me", Reward: 5})
+	if err := requester.tm.PublishTask(tk, 0); err != nil {
+		t.Fatalf("PublishTask: %v", err)
+	}
+	requester.offer(tk.ID)
+	claim := &task.TaskClaim{TaskID: tk.ID, ClaimerID: "worker"}
+	nodes["worker"].tm.ClaimTask(claim, 0)
+	nodes["worker"].claim(claim)
+
+	a, err := requester.tm.AssignReviewers(tk.ID, 1)
+	if err != nil {
+		t.Fatalf("AssignReviewers: %v", err)
+	}
+	requester.reviewers(tk.ID)
+	first := nodes[a.Reviewers[0].NodeID]
+	second := nodes["r1"]
+	if first == second {
+		second = nodes["r2"]
+	}
+	if _, err := second.respondReview(tk.ID, true); err == nil {
+		t.Error("an unassigned node should not respond")
+	}
+
+	// 评审人在自己的节点上拒绝，委托方节点记录并补选，新的分配广播给补选的评审人
+	if _, err := first.respondReview(tk.ID, false); err != nil {
+		t.Fatalf("respondReview on %s: %v", first.self, err)
+	}
+	if _, err := second.respondReview(tk.ID, true); err != nil {
+		t.Fatalf("respondReview on %s: %v", second.self, err)
+	}
+	a, _ = requester.tm.GetReviewAssignment(tk.ID)
+	if len(a.Reviewers) != 2 || a.Reviewers[0].Status != task.ReviewerDeclined || a.Reviewers[1].Status != task.ReviewerAccepted {
+		t.Fatalf("unexpected assignment on the requester: %+v", a.Reviewers)
+	}
+	if copied, _ := second.tm.GetReviewAssignment(tk.ID); len(copied.Reviewers) != 2 || copied.Reviewers[1].Status != task.ReviewerAccepted {
+		t.Errorf("the reviewer copy should follow the requester: %+v", copied.Reviewers)
+	}
+}
+
+func TestTaskNetworkVerification(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	nodes := make(map[string]*taskNetwork)
+	isSupernode := func(id string) bool { return id == "super" }
+	for _, id := range []string{"self", "w1", "w2", "w3", "super"} {
+		tm := newTaskManager(t.TempDir())
+		tm.SetSupernodeCheckFunc(isSupernode)
+		nodes[id] = newTaskNetwork(tm, id, nil)
+		if err := nodes[id].start(busTransport{bus: bus, node: id}); err != nil {
+			t.Fatalf("start: %v", err)
+		}
+	}
+	requester := nodes["self"]
+	publish := func(req *httpapi.TaskRequest) *task.Task {
+		t.Helper()
+		tk := taskFromRequest("self", req)
+		if err := requester.tm.PublishTask(tk, 0); err != nil {
+			t.Fatalf("PublishTask: %v", err)
+		}
+		requester.offer(tk.ID)
+		return tk
+	}
+	claim := func(id, taskID string) {
+		t.Helper()
+		c := &task.TaskClaim{TaskID: taskID, ClaimerID: id}
+		if err := nodes[id].tm.ClaimTask(c, 0); err != nil {
+			t.Fatalf("ClaimTask on %s: %v", id, err)
+		}
+		nodes[id].claim(c)
+	}
+	deliver := func(id, taskID, result string) {
+		t.Helper()
+		tn := nodes[id]
+		_, delivery, err := submitTaskResult(tn.tm, id, &httpapi.TaskSubmitRequest{TaskID: taskID, Result: result}, nil)
+		if err != nil {
+			t.Fatalf("submitTaskResult on %s: %v", id, err)
+		}
+		tn.deliver(delivery)
+	}
+
+	// 冗余执行：主执行者之后接单的节点成为冗余执行者，2/3 结果一致即通过验收
+	redundant := publish(&httpapi.TaskRequest{Type: "compute", Description: "sum", Reward: 9,
+		Verification: "redundant", RedundancyRequired: 2, RedundancyTotal: 3})
+	for _, id := range []string{"w1", "w2", "w3"} {
+		claim(id, redundant.ID)
+	}
+	deliver("w2", redundant.ID, "41")
+	deliver("w3", redundant.ID, "42")
+	deliver("w1", redundant.ID, "42")
+	result, err := settleTask(requester.tm, "self", redundant.ID)
+	if err != nil {
+		t.Fatalf("settleTask: %v", err)
+	}
+	if len(result.RewardShares) != 2 || result.RewardShares["w1"] != 4.5 || result.RewardShares["w3"] != 4.5 {
+		t.Errorf("reward shares = %v", result.RewardShares)
+	}
+
+	// 超级节点审计：审计结论交给委托方节点
+	audited := publish(&httpapi.TaskRequest{Type: "search", Description: "check", Reward: 3, Verification: "supernode_audit"})
+	claim("w1", audited.ID)
+	deliver("w1", audited.ID, "found")
+	if _, err := nodes["super"].tm.RecordAudit(audited.ID, "super", true, "looks right"); err != nil {
+		t.Fatalf("RecordAudit: %v", err)
+	}
+	nodes["super"].audit(&taskAudit{TaskID: audited.ID, Approved: true, Note: "looks right"})
+	if record, _ := requester.tm.GetVerification(audited.ID); !record.Passed || record.Auditor != "super" {
+		t.Fatalf("audit should reach the requester: %+v", record)
+	}
+	if _, err := settleTask(nodes["w1"].tm, "w1", audited.ID); err == nil {
+		t.Error("only the requester may settle")
+	}
+	if _, err := settleTask(requester.tm, "self", audited.ID); err != nil {
+		t.Errorf("settleTask: %v", err)
+	}
+}
+
+func TestSnapshotQuery(t *testing.T) {
+	escrowConfig := escrow.DefaultEscrowConfig()
+	escrowConfig.DataDir = t.TempDir()
+	escrows := escrow.NewEscrowManager(escrowConfig)
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = t.TempDir()
+	disputes := dispute.NewDisputeManager(disputeConfig)
+	collaterals := collateral.NewCollateralManager()
+	reader := newSnapshotReader(disputes, escrows, collaterals)
+	disputeSvc := disputeService{m: disputes, escrows: escrows, snapshots: reader, self: "self"}
+	var svc httpapi.SnapshotQueryService = snapshotQuery{reader: reader, disputes: disputes, escrows: escrows, collaterals: collaterals, self: "self"}
+
+	e, _ := escrows.CreateEscrow("task1", map[string]float64{"self": 10, "peer": 5})
+	escrows.Deposit(e.ID, "self", 10, "sig")
+	escrows.Deposit(e.ID, "peer", 5, "sig")
+	collaterals.Deposit("peer", "supernode_auditor", 50)
+	filed, err := disputeSvc.FileDispute(httpapi.DisputeFileRequest{EscrowID: e.ID, Defendant: "peer", Type: "non_delivery", Description: "never delivered"})
+	if err != nil {
+		t.Fatalf("FileDispute failed: %v", err)
+	}
+
+	if _, err := svc.DisputeCase("missing"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	c, err := svc.DisputeCase(filed["id"].(string))
+	if err != nil {
+		t.Fatalf("DisputeCase failed: %v", err)
+	}
+	if c["escrow"].(map[string]interface{})["status"] != string(escrow.EscrowDisputed) {
+		t.Errorf("case escrow = %v", c["escrow"])
+	}
+	if defendant := c["collateral"].(map[string]interface{})["defendant"].([]map[string]interface{}); len(defendant) != 1 {
+		t.Errorf("defendant collateral = %v", defendant)
+	}
+	snap := c["snapshot"].(*snapshot.Snapshot)
+	if snap.Timestamp.IsZero() || snap.Versions["dispute"] != disputes.Version() || snap.Versions["escrow"] != escrows.Version() {
+		t.Errorf("snapshot = %+v", snap)
+	}
+
+	exposure, err := svc.NodeExposure("")
+	if err != nil {
+		t.Fatalf("NodeExposure failed: %v", err)
+	}
+	if exposure["node_id"] != "self" || exposure["open_disputes"] != 1 || exposure["escrow_locked"] != 10.0 {
+		t.Errorf("exposure = %v", exposure)
+	}
+}
+
+func TestReputationService(t *testing.T) {
+	m, err := reputation.NewManager(reputation.DefaultManagerConfig())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	var svc httpapi.ReputationService = reputationService{m: m}
+
+	if value, err := svc.AdjustReputation("node-a", 5, ""); err != nil || value != 15 {
+		t.Fatalf("AdjustReputation = %v, %v", value, err)
+	}
+	svc.AdjustReputation("node-b", 20, "task")
+
+	ranking := svc.ReputationRanking(10)
+	if len(ranking) != 2 || ranking[0]["node_id"] != "node-b" || ranking[0]["rank"] != 1 {
+		t.Errorf("排行错误: %v", ranking)
+	}
+	history := svc.ReputationHistory("node-a", 10)
+	if len(history) != 1 || history[0]["reason"] != "api" || history[0]["reputation"] != 15.0 {
+		t.Errorf("历史错误: %v", history)
+	}
+	if detail := svc.ReputationDetail("node-a"); detail["raw"] != 15.0 || detail["damped"] != 15.0 || detail["damping"] != false {
+		t.Errorf("原始分数与生效分数错误: %v", detail)
+	}
+	if svc.ReputationDetail("unknown") != nil {
+		t.Error("未记录的节点不应返回详情")
+	}
+	if scores := reputationScores(m); len(scores) != 2 || scores["node-b"] != 30 {
+		t.Errorf("本地声誉表错误: %v", scores)
+	}
+
+	// 邻居推送的声誉表作为邻居视图返回，本地未记录的节点同样返回
+	views := reputation.NewDeltaSync(nil)
+	neighborSync := reputation.NewDeltaSync(nil)
+	neighborSync.Update(map[string]float64{"unknown": 42})
+	err = neighborSync.Push("self", func() (io.ReadWriteCloser, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			views.HandlePush(server, "node-b")
+		}()
+		return client, nil
+	})
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	svc = reputationService{m: m, views: views}
+	detail := svc.ReputationDetail("unknown")
+	if v, _ := detail["neighbor_views"].(map[string]float64); v["node-b"] != 42 || detail["raw"] != nil {
+		t.Errorf("邻居视图错误: %v", detail)
+	}
+}
+
+func TestReputationLookup(t *testing.T) {
+	local, err := reputation.NewManager(reputation.DefaultManagerConfig())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	local.Adjust("node-a", 5, "task")
+	remote, err := reputation.NewManager(reputation.DefaultManagerConfig())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	remote.Adjust("node-a", 10, "task")
+
+	sign := func(data []byte) ([]byte, error) { return append([]byte("sig:"), data...), nil }
+	verify := func(signer string, data, signature []byte) (bool, error) {
+		return string(signature) == "sig:"+string(data), nil
+	}
+	var asked []string
+	open := func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error) {
+		asked = append(asked, nodeID)
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			reputation.ServeAttestation(server, attestReputation(remote, nodeID, sign))
+		}()
+		return client, nil
+	}
+	trusted := func(nodeID string) bool { return nodeID == "nb" }
+
+	// 被查询节点本身不作为证明人，证明与本地记录合并返回
+	cache := reputation.NewCache(reputation.DefaultCacheConfig(), fetchAttestation(func() []string { return []string{"node-a", "nb"} }, open))
+	cache.SetVerifier(verifyAttestation(trusted, verify))
+	result, err := reputationLookup(cache, reputationService{m: local})("node-a")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if len(asked) != 1 || asked[0] != "nb" {
+		t.Errorf("应只向邻居查询: %v", asked)
+	}
+	if result["score"] != 20.0 || result["attester"] != "nb" || result["reputation"] != 15.0 || result["raw"] != 15.0 {
+		t.Errorf("合并结果错误: %v", result)
+	}
+
+	// 非邻居签发的证明不被采信，返回本地记录并附原因
+	cache = reputation.NewCache(reputation.DefaultCacheConfig(), fetchAttestation(func() []string { return []string{"stranger"} }, open))
+	cache.SetVerifier(verifyAttestation(trusted, verify))
+	result, err = reputationLookup(cache, reputationService{m: local})("node-a")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if result["last_error"] == nil || result["score"] != nil || result["reputation"] != 15.0 {
+		t.Errorf("未取到证明时应返回本地记录: %v", result)
+	}
+}
+
+func TestVerifyReleaseArchive(t *testing.T) {
+	dir := t.TempDir()
+	target := release.Target{GOOS: "linux", GOARCH: "amd64"}
+
+	binPath := filepath.Join(dir, "bin")
+	os.WriteFile(binPath, []byte("new binary"), 0755)
+	archive := filepath.Join(dir, target.ArchiveName("0.2.0"))
+	if err := release.WriteArchive(archive, binPath, target); err != nil {
+		t.Fatalf("WriteArchive failed: %v", err)
+	}
+	sum, _ := release.FileChecksum(archive)
+	checksums := release.Checksums{filepath.Base(archive): sum}.Marshal()
+	sumsPath := filepath.Join(dir, release.ChecksumsFile)
+	os.WriteFile(sumsPath, checksums, 0644)
+
+	pubHex, seedHex, _ := release.GenerateKey()
+	priv, _ := release.ParsePrivateKey(seedHex)
+	sigPath := filepath.Join(dir, release.SignatureFile)
+	os.WriteFile(sigPath, release.Sign(priv, checksums), 0644)
+
+	binary, err := verifyReleaseArchive(archive, sumsPath, sigPath, pubHex, false)
+	if err != nil {
+		t.Fatalf("verifyReleaseArchive failed: %v", err)
+	}
+	if string(binary) != "new binary" {
+		t.Errorf("unexpected binary content: %q", binary)
+	}
+
+	otherPub, _, _ := release.GenerateKey()
+	if _, err := verifyReleaseArchive(archive, sumsPath, sigPath, otherPub, false); err != release.ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	// 没有公钥时默认拒绝，-insecure 只校验 SHA-256
+	if _, err := verifyReleaseArchive(archive, sumsPath, sigPath, "", false); err != errReleaseKeyMissing {
+		t.Errorf("expected errReleaseKeyMissing, got %v", err)
+	}
+	if _, err := verifyReleaseArchive(archive, sumsPath, sigPath, "", true); err != nil {
+		t.Errorf("insecure update should accept a matching checksum: %v", err)
+	}
+
+	os.WriteFile(archive, []byte("tampered"), 0644)
+	if _, err := verifyReleaseArchive(archive, sumsPath, sigPath, pubHex, false); err == nil {
+		t.Error("expected checksum mismatch for tampered archive")
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "agentnetwork")
+	os.WriteFile(exe, []byte("old"), 0755)
+
+	if err := replaceExecutable(exe, []byte("new")); err != nil {
+		t.Fatalf("replaceExecutable failed: %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Errorf("expected new binary, got %q", data)
+	}
+	if data, _ := os.ReadFile(exe + ".old"); string(data) != "old" {
+		t.Errorf("expected old binary backup, got %q", data)
+	}
+}
+
+func TestOpenStorageKeyring(t *testing.T) {
+	dataDir := t.TempDir()
+	nodeSecret := []byte("node-private-key")
+
+	kr, err := openStorageKeyring(dataDir, "", nodeSecret)
+	if err != nil {
+		t.Fatalf("openStorageKeyring failed: %v", err)
+	}
+	sealed, _ := kr.Seal([]byte("mail"))
+
+	keyFile := filepath.Join(dataDir, "storage.key")
+	if _, err := openStorageKeyring(dataDir, keyFile, nodeSecret); err == nil {
+		t.Error("expected error for missing storage key file")
+	}
+	os.WriteFile(keyFile, []byte("short\n"), 0600)
+	if _, err := openStorageKeyring(dataDir, keyFile, nodeSecret); err == nil {
+		t.Error("expected error for short storage key")
+	}
+
+	// 改用存储密钥后，节点私钥创建的密钥环自动迁移
+	os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef\n"), 0600)
+	migrated, err := openStorageKeyring(dataDir, keyFile, nodeSecret)
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if got, err := migrated.Open(sealed); err != nil || string(got) != "mail" {
+		t.Errorf("expected data readable after migration, got %q %v", got, err)
+	}
+	if _, err := openStorageKeyring(dataDir, keyFile, []byte("other-node")); err != nil {
+		t.Errorf("expected keyring to be sealed with storage key: %v", err)
+	}
+}
+
+type fakeSmokePeer struct {
+	id         string
+	connectErr error
+	inbox      chan *mailbox.Message
+}
+
+func (p *fakeSmokePeer) ID() string                                        { return p.id }
+func (p *fakeSmokePeer) Connect(ctx context.Context, addrs []string) error { return p.connectErr }
+func (p *fakeSmokePeer) Close() error                                      { return nil }
+
+func (p *fakeSmokePeer) ListenMail(namespace string) (<-chan *mailbox.Message, error) {
+	return p.inbox, nil
+}
+
+// newSmokeTestNode 模拟节点 API：按 ID 保存邮件和留言，对等列表固定包含 smoke-peer；
+// 发出的邮件交给 deliver，deliver 为 nil 时邮件不送达
+func newSmokeTestNode(t *testing.T, deliver func(msg *mailbox.Message), taskStatus string) *httptest.Server {
+	stored := make(map[string]map[string]interface{})
+	reply := func(w http.ResponseWriter, data interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data, "code": 200})
+	}
+	store := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := fmt.Sprintf("msg_%d", len(stored))
+		body["author"] = "node-1"
+		if to, ok := body["to"].(string); ok {
+			body["status"] = "pending"
+			if deliver != nil {
+				content, _ := body["content"].(string)
+				deliver(&mailbox.Message{ID: id, Sender: "node-1", Receiver: to, Content: []byte(content)})
+				body["status"] = "delivered"
+			}
+		}
+		stored[id] = body
+		reply(w, map[string]interface{}{"message_id": id})
+	}
+	get := func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if msg, ok := stored[id]; ok {
+			reply(w, msg)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "message not found", "code": 404})
+	}
+	echoTask := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		reply(w, map[string]interface{}{"task_id": body["task_id"]})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]interface{}{"node_id": "node-1"})
+	})
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "invalid or missing API token", "code": 401})
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v1/node/peers":
+			reply(w, map[string]interface{}{"peers": []map[string]string{{"node_id": "smoke-peer"}}})
+		case r.URL.Path == "/api/v1/mailbox/send", r.URL.Path == "/api/v1/bulletin/publish":
+			store(w, r)
+		case strings.HasPrefix(r.URL.Path, "/api/v1/mailbox/read/"), strings.HasPrefix(r.URL.Path, "/api/v1/bulletin/message/"):
+			get(w, r)
+		case r.URL.Path == "/api/v1/task/status":
+			reply(w, map[string]interface{}{"task_id": r.URL.Query().Get("task_id"), "status": taskStatus})
+		case strings.HasPrefix(r.URL.Path, "/api/v1/task/"):
+			echoTask(w, r)
+		default:
+			reply(w, map[string]interface{}{})
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSmokeSuite(t *testing.T) {
+	inbox := make(chan *mailbox.Message, 4)
+	server := newSmokeTestNode(t, func(msg *mailbox.Message) { inbox <- msg }, "verified")
+	api := &smokeAPI{baseURL: server.URL, token: "secret", client: server.Client()}
+	addrs := []string{"/ip4/127.0.0.1/tcp/4001/p2p/node-1"}
+
+	suite := newSmokeSuite(api, &fakeSmokePeer{id: "smoke-peer", inbox: inbox}, addrs)
+	results := runSmokeSteps(context.Background(), suite.steps(), 2*time.Second)
+	if !smokePassed(results) {
+		t.Fatalf("expected all steps to pass, got %+v", results)
+	}
+	if len(results) != 6 || results[5].Step != "task" {
+		t.Errorf("unexpected steps: %+v", results)
+	}
+
+	// 邮件未送达临时节点、任务未通过验收时相应步骤失败
+	stuck := newSmokeTestNode(t, nil, "delivered")
+	stuckAPI := &smokeAPI{baseURL: stuck.URL, token: "secret", client: stuck.Client()}
+	results = runSmokeSteps(context.Background(), newSmokeSuite(stuckAPI, &fakeSmokePeer{id: "smoke-peer", inbox: make(chan *mailbox.Message)}, addrs).steps(), 300*time.Millisecond)
+	failed := make(map[string]string)
+	for _, r := range results {
+		failed[r.Step] = r.Status
+	}
+	if failed["mailbox"] != smokeFail || failed["task"] != smokeFail || failed["bulletin"] != smokePass {
+		t.Errorf("expected mailbox and task to fail, got %+v", results)
+	}
+
+	// 连接失败时邮箱步骤跳过，其余步骤照常执行
+	suite = newSmokeSuite(api, &fakeSmokePeer{id: "smoke-peer", connectErr: fmt.Errorf("dial refused")}, addrs)
+	results = runSmokeSteps(context.Background(), suite.steps(), 2*time.Second)
+	status := make(map[string]string)
+	for _, r := range results {
+		status[r.Step] = r.Status
+	}
+	if status["connect"] != smokeFail || status["mailbox"] != smokeSkip || status["bulletin"] != smokePass {
+		t.Errorf("unexpected statuses: %v", status)
+	}
+
+	// 令牌错误时 API 步骤失败，依赖它的步骤全部跳过
+	bad := &smokeAPI{baseURL: server.URL, token: "wrong", client: server.Client()}
+	results = runSmokeSteps(context.Background(), newSmokeSuite(bad, &fakeSmokePeer{id: "smoke-peer"}, addrs).steps(), 2*time.Second)
+	if results[0].Status != smokePass || results[1].Status != smokeFail || !strings.Contains(results[1].Detail, "401") {
+		t.Errorf("expected api step to fail with 401, got %+v", results[:2])
+	}
+	for _, r := range results[2:] {
+		if r.Status != smokeSkip {
+			t.Errorf("step %s = %s, want skip", r.Step, r.Status)
+		}
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := openStorageKeyring(dir, "", []byte("test-node-secret-0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 升级前写入的加密邮箱数据和明文任务模板
+	sealed, _ := keys.Seal([]byte(`{"inbox":{}}`))
+	os.MkdirAll(filepath.Join(dir, "mailbox"), 0755)
+	os.WriteFile(filepath.Join(dir, "mailbox", "mailbox.json"), sealed, 0644)
+	os.MkdirAll(filepath.Join(dir, "tasks"), 0755)
+	os.WriteFile(filepath.Join(dir, "tasks", "templates.json"), []byte(`{}`), 0644)
+
+	report, err := runMigrations(dir, keys, false)
+	if err != nil {
+		t.Fatalf("runMigrations() error = %v", err)
+	}
+	if len(report.Steps) != len(schemaStores(keys)) || report.Migrated() {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	for _, step := range report.Steps {
+		if step.Status != migrate.StatusStamped {
+			t.Errorf("%s status = %s, want stamped", step.Store, step.Status)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, migrate.ManifestFile)); err != nil {
+		t.Errorf("schema manifest should be written: %v", err)
+	}
+
+	// 加密存储可在迁移时解密，缺少密钥时报错
+	codec := &keyringCodec{keys: keys}
+	if plain, err := codec.Decode(sealed); err != nil || string(plain) != `{"inbox":{}}` {
+		t.Errorf("Decode() = %q, %v", plain, err)
+	}
+	if _, err := (&keyringCodec{}).Decode(sealed); err == nil {
+		t.Error("sealed data without keyring should fail to decode")
+	}
+	if out, _ := codec.Encode([]byte(`{}`)); !crypto.IsSealed(out) {
+		t.Error("Encode() should seal with the active key")
+	}
+}
+
+func TestStartupTracker(t *testing.T) {
+	startup := newStartupTracker("migrations", "mailbox")
+	now := time.Now()
+	startup.now = func() time.Time { return now }
+
+	checks := startup.stageChecks()
+	if len(checks) != 2 || checks[0].OK || checks[0].Detail != "loading" {
+		t.Fatalf("stages should be pending before finish: %+v", checks)
+	}
+
+	startup.finish("migrations", nil)
+	startup.finish("mailbox", fmt.Errorf("disk full"))
+	checks = startup.stageChecks()
+	if !checks[0].OK || checks[1].OK || checks[1].Detail != "disk full" {
+		t.Errorf("unexpected stage checks: %+v", checks)
+	}
+
+	startup.beat()
+	if check := startup.livenessCheck(); !check.OK {
+		t.Errorf("fresh heartbeat should be live: %+v", check)
+	}
+	now = now.Add(livenessStaleAfter + time.Second)
+	if check := startup.livenessCheck(); check.OK || check.Detail == "" {
+		t.Errorf("stale heartbeat should fail liveness: %+v", check)
+	}
+}
+
+func TestReadinessNetworkChecks(t *testing.T) {
+	if check := peersCheck(0, 1); check.OK || check.Detail != "0/1 connected" {
+		t.Errorf("unexpected peers check: %+v", check)
+	}
+	if check := peersCheck(0, 0); !check.OK {
+		t.Errorf("zero minimum should always be ready: %+v", check)
+	}
+	if check := dhtCheck(true, 0, 1); check.OK {
+		t.Errorf("empty routing table should not be ready: %+v", check)
+	}
+	if check := dhtCheck(true, 0, 0); !check.OK {
+		t.Errorf("first bootstrap node should be ready with an empty routing table: %+v", check)
+	}
+	if check := dhtCheck(false, 0, 0); check.OK {
+		t.Errorf("disabled dht should not be ready: %+v", check)
+	}
+}
+
+func TestTaskBook(t *testing.T) {
+	tm := newTaskManager(t.TempDir())
+	created := taskFromRequest("requester", &httpapi.TaskRequest{
+		Type:           "search",
+		Description:    "find papers",
+		Target:         "executor",
+		PayloadFormats: []string{"application/json"},
+	})
+	if created.Title != "find papers" || created.PublishMode != task.ModeDirect {
+		t.Fatalf("unexpected task from request: %+v", created)
+	}
+	if err := tm.PublishTask(created, 0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	if err := tm.ClaimTask(&task.TaskClaim{TaskID: created.ID, ClaimerID: "executor"}, 0); err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+	if m := taskMap(created); m["stage"] != "accepted" {
+		t.Errorf("stage = %v, want accepted", m["stage"])
+	}
+
+	signed := false
+	sign := func(data []byte) ([]byte, error) {
+		signed = true
+		return []byte("sig"), nil
+	}
+	submitted, delivery, err := submitTaskResult(tm, "executor", &httpapi.TaskSubmitRequest{TaskID: created.ID, Result: "done"}, sign)
+	if err != nil {
+		t.Fatalf("submitTaskResult failed: %v", err)
+	}
+	if submitted.DeliverableHash != task.HashResult([]byte("done")) || !signed {
+		t.Errorf("delivery should carry the signed result hash: %+v", submitted)
+	}
+	if delivery.Hash != submitted.DeliverableHash || delivery.Signature != hex.EncodeToString([]byte("sig")) {
+		t.Errorf("unexpected delivery: %+v", delivery)
+	}
+	if m := taskMap(submitted); m["stage"] != "submitted" {
+		t.Errorf("stage = %v, want submitted", m["stage"])
+	}
+}
+
+func TestNodeStore(t *testing.T) {
+	dataDir := t.TempDir()
+	if store, err := openNodeStore(dataDir, storeJSON, nil); store != nil || err != nil {
+		t.Fatalf("json driver should not open a backend: %v, %v", store, err)
+	}
+	if _, err := openNodeStore(dataDir, "nope", nil); err == nil {
+		t.Error("unknown driver should fail")
+	}
+
+	keys, err := crypto.OpenStorageKeyring(filepath.Join(dataDir, "keys.json"), []byte("node-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openNodeStore(dataDir, "log", keys)
+	if err != nil {
+		t.Fatalf("openNodeStore failed: %v", err)
+	}
+	store.Put("mailbox/inbox", "m1", []byte(`{"subject":"hello"}`))
+	store.Close()
+
+	raw, err := os.ReadFile(filepath.Join(dataDir, "store", "node.db"))
+	if err != nil || strings.Contains(string(raw), "hello") {
+		t.Errorf("store records should be sealed with the storage keyring (err %v)", err)
+	}
+	store, err = openNodeStore(dataDir, "log", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if v, _ := store.Get("mailbox/inbox", "m1"); string(v) != `{"subject":"hello"}` {
+		t.Errorf("reopened record = %s", v)
+	}
+}
+
+func TestGenerateTestnet(t *testing.T) {
+	outDir := t.TempDir()
+	var keyPaths []string
+	fakeKey := func(keyPath string) (string, error) {
+		keyPaths = append(keyPaths, keyPath)
+		return fmt.Sprintf("12D3KooWPeer%d", len(keyPaths)-1), nil
+	}
+
+	cfg := provision.DefaultTestnetConfig()
+	cfg.Nodes = 3
+	nodes, err := generateTestnet(cfg, outDir, fakeKey)
+	if err != nil {
+		t.Fatalf("generateTestnet failed: %v", err)
+	}
+	if len(nodes) != 3 || keyPaths[1] != filepath.Join(outDir, "relay", "keys", "node.key") {
+		t.Fatalf("unexpected nodes %d / key paths %v", len(nodes), keyPaths)
+	}
+
+	applied, err := provision.LoadApplied(filepath.Join(outDir, "node1"))
+	if err != nil || applied == nil {
+		t.Fatalf("LoadApplied failed: %v", err)
+	}
+	if applied.Role != "normal" || len(applied.Bootstrap) != 2 || !strings.HasSuffix(applied.Bootstrap[0], "/p2p/12D3KooWPeer0") {
+		t.Errorf("unexpected applied manifest %+v", applied)
+	}
+	if _, err := loadManifest(filepath.Join(outDir, "relay", "node.yaml")); err != nil {
+		t.Errorf("node.yaml should be a valid manifest: %v", err)
+	}
+	if compose, err := os.ReadFile(filepath.Join(outDir, "docker-compose.yaml")); err != nil || !strings.Contains(string(compose), "  node1:\n") {
+		t.Errorf("compose file not generated: %v", err)
+	}
+
+	cfg.Format = provision.FormatSystemd
+	if _, err := generateTestnet(cfg, outDir, fakeKey); err != nil {
+		t.Fatalf("generateTestnet (systemd) failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "systemd", "agentnetwork-bootstrap.service")); err != nil {
+		t.Errorf("systemd unit not generated: %v", err)
+	}
+
+	cfg.Nodes = 1
+	if _, err := generateTestnet(cfg, t.TempDir(), fakeKey); err == nil {
+		t.Error("a single node testnet should be rejected")
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := createAPIKey(dir, "superuser", ""); !errors.Is(err, auth.ErrUnknownRole) {
+		t.Errorf("unknown role error = %v", err)
+	}
+
+	// rbac.json 中定义的角色也可以签发
+	os.WriteFile(filepath.Join(dir, rbacPolicyFile), []byte(`{"roles": {"auditor": ["read"]}}`), 0600)
+	key, secret, err := createAPIKey(dir, "auditor", "audit-bot")
+	if err != nil {
+		t.Fatalf("createAPIKey() error = %v", err)
+	}
+
+	keys, policy, err := openAPIKeys(dir)
+	if err != nil {
+		t.Fatalf("openAPIKeys() error = %v", err)
+	}
+	a := auth.NewAuthorizer(keys, policy, policy.HTTPRoutes)
+	if role, allowed := a.Authorize(secret, http.MethodGet, "/api/v1/node/info"); role != "auditor" || !allowed {
+		t.Errorf("Authorize(GET) = %q, %v", role, allowed)
+	}
+	if _, allowed := a.Authorize(secret, http.MethodPost, "/api/v1/message/send"); allowed {
+		t.Error("auditor should not send messages")
+	}
+	// API 计量按 Key ID 计费
+	account := apiKeyAccount(keys)
+	if id, name := account(secret); id != key.ID || name != "audit-bot" {
+		t.Errorf("meter account = %q, %q", id, name)
+	}
+	if id, _ := account("not-a-key"); id != "" {
+		t.Errorf("unknown token should fall back to token ID, got %q", id)
+	}
+	if err := keys.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if role, _ := a.Authorize(secret, http.MethodGet, "/api/v1/node/info"); role != "" {
+		t.Errorf("revoked key role = %q", role)
+	}
+}
+
+func TestReportSources(t *testing.T) {
+	tm := newTaskManager(t.TempDir())
+	done := taskFromRequest("requester", &httpapi.TaskRequest{Type: "search", Description: "find papers", Target: "self"})
+	if err := tm.PublishTask(done, 0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	tm.ClaimTask(&task.TaskClaim{TaskID: done.ID, ClaimerID: "self"}, 0)
+	sign := func(data []byte) ([]byte, error) { return []byte("sig"), nil }
+	if _, _, err := submitTaskResult(tm, "self", &httpapi.TaskSubmitRequest{TaskID: done.ID, Result: "done"}, sign); err != nil {
+		t.Fatalf("submitTaskResult failed: %v", err)
+	}
+	if err := tm.ConfirmDelivery(done.ID, "requester", "sig"); err != nil {
+		t.Fatalf("ConfirmDelivery failed: %v", err)
+	}
+	open := taskFromRequest("requester", &httpapi.TaskRequest{Type: "search", Description: "still open", Target: "self"})
+	tm.PublishTask(open, 0)
+
+	config := token.DefaultConfig()
+	config.DataDir = ""
+	l, err := token.NewLedger(config)
+	if err != nil {
+		t.Fatalf("NewLedger failed: %v", err)
+	}
+	l.MintReward("self", "r1", 50)
+	if _, err := l.SignAndTransfer("self", "peer", 10, "hire", func([]byte) (string, error) { return "sig", nil }); err != nil {
+		t.Fatalf("SignAndTransfer failed: %v", err)
+	}
+
+	rep, _ := reputation.NewManager(reputation.DefaultManagerConfig())
+	rep.Adjust("self", 5, "task")
+
+	disputeConfig := dispute.DefaultDisputeConfig()
+	disputeConfig.DataDir = t.TempDir()
+	disputes := dispute.NewDisputeManager(disputeConfig)
+	disputes.CreateDispute("task9", "peer", "self", dispute.DisputeNonDelivery, "late", 3)
+
+	in := reportSources{tasks: tm, tokens: l, reputation: rep, disputes: disputes, self: "self"}.input()
+	if len(in.Tasks) != 1 || in.Tasks[0].ID != done.ID || in.Tasks[0].Time.IsZero() {
+		t.Errorf("Tasks = %+v", in.Tasks)
+	}
+	if len(in.Tokens) != 2 || in.TokenBalance != l.GetAccount("self").Balance {
+		t.Fatalf("Tokens = %+v, balance %v", in.Tokens, in.TokenBalance)
+	}
+	for _, e := range in.Tokens {
+		if e.Type == "transfer" && (e.Amount != -10 || e.Counterparty != "peer") {
+			t.Errorf("transfer = %+v", e)
+		}
+	}
+	if len(in.Reputation) != 1 || in.CurrentReputation != rep.GetReputation("self") {
+		t.Errorf("Reputation = %+v", in.Reputation)
+	}
+	if len(in.Disputes) != 1 || in.Disputes[0].Role != report.DisputeDefendant {
+		t.Errorf("Disputes = %+v", in.Disputes)
+	}
+
+	now := time.Now()
+	r := report.Build("self", now.Add(-time.Hour), now.Add(time.Hour), in)
+	if r.Tasks.Completed != 1 || r.Tokens.Sent != 10 || r.Disputes.Defended != 1 {
+		t.Errorf("report = %+v", r)
+	}
+
+	mbConfig := mailbox.DefaultConfig("self")
+	mbConfig.DataDir = t.TempDir()
+	mb, err := mailbox.NewMailbox(mbConfig)
+	if err != nil {
+		t.Fatalf("NewMailbox failed: %v", err)
+	}
+	if err := reportMailer(mb, reportMailSelf)(r); err != nil {
+		t.Fatalf("reportMailer failed: %v", err)
+	}
+	if mb.GetInboxCount() != 1 {
+		t.Errorf("inbox count = %d", mb.GetInboxCount())
+	}
+}
+
+func TestContactAdapters(t *testing.T) {
+	config := mailbox.DefaultConfig("self")
+	config.DataDir = t.TempDir()
+	mb, err := mailbox.NewMailbox(config)
+	if err != nil {
+		t.Fatalf("NewMailbox failed: %v", err)
+	}
+	mb.SetReputationFunc(func(string) float64 { return 1 })
+
+	saved, err := mb.SaveContact(mailboxContact(&httpapi.MailboxContact{NodeID: "peer", Label: "Alice", Trust: "low", Encryption: "never"}))
+	if err != nil {
+		t.Fatalf("SaveContact failed: %v", err)
+	}
+	c := apiContact(saved)
+	if c.Trust != "low" || c.Encryption != "never" || c.CreatedAt == 0 {
+		t.Errorf("contact = %+v", c)
+	}
+
+	check := apiRecipientCheck(mb.CheckRecipient("peer"))
+	if check.Encrypt == nil || *check.Encrypt || len(check.Warnings) != 2 || check.Contact == nil {
+		t.Errorf("check = %+v", check)
+	}
+	check = apiRecipientCheck(mb.CheckRecipient("stranger"))
+	if check.Encrypt != nil || check.Contact != nil || len(check.Warnings) != 1 {
+		t.Errorf("stranger check = %+v", check)
+	}
+
+	if err := contactAPIError(mb.DeleteContact("missing")); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("DeleteContact(missing) = %v, want ErrNotFound", err)
+	}
+	if err := contactAPIError(mb.DeleteContact("peer")); err != nil {
+		t.Errorf("DeleteContact failed: %v", err)
+	}
+}
+
+func TestProtocolCompat(t *testing.T) {
+	sunset, enabled, err := parseLegacySunset(defaultLegacyProtocolsUntil)
+	if err != nil || !enabled || sunset.Year() != 2027 {
+		t.Fatalf("default sunset = %v, %v, %v", sunset, enabled, err)
+	}
+	if _, enabled, err := parseLegacySunset(""); err != nil || enabled {
+		t.Errorf("empty value should disable legacy protocols, got %v, %v", enabled, err)
+	}
+	if _, _, err := parseLegacySunset("next year"); err == nil {
+		t.Error("invalid date accepted")
+	}
+
+	table := newProtocolCompat("acme", time.Now().Add(time.Hour), true)
+	want := []string{"/ns/acme" + mailbox.KeyExchangeProtocol, "/ns/acme" + mailbox.KeyExchangeProtocolV1}
+	if got := table.Protocols(mailKeyCompatFamily); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("mailbox key protocols = %v, want %v", got, want)
+	}
+	// 留言广播主题由广播器加命名空间前缀，回退表中不带
+	if got := table.Protocols(bulletin.GossipCompatFamily); len(got) != 2 || got[1] != bulletin.LegacyGossipTopicPrefix {
+		t.Errorf("bulletin gossip prefixes = %v", got)
+	}
+	table.Accept(mailKeyCompatFamily, want[1], "12D3KooWOld", compat.Inbound)
+	status := protocolCompatStatus(table)
+	if peers := status["legacy_peers"].(map[string][]string)[want[1]]; len(peers) != 1 || peers[0] != "12D3KooWOld" {
+		t.Errorf("legacy peers = %v", status["legacy_peers"])
+	}
+
+	table = newProtocolCompat("", time.Time{}, false)
+	if got := table.Protocols(mailKeyCompatFamily); len(got) != 1 || got[0] != mailbox.KeyExchangeProtocol {
+		t.Errorf("protocols without compatibility mode = %v", got)
+	}
+	if got := table.Protocols(bulletin.GossipCompatFamily); len(got) != 1 {
+		t.Errorf("gossip prefixes without compatibility mode = %v", got)
+	}
+}
+
+func TestParseCapabilities(t *testing.T) {
+	got := parseCapabilities(" storage,relay,,storage , gpu")
+	if strings.Join(got, ",") != "gpu,relay,storage" {
+		t.Errorf("parseCapabilities = %v", got)
+	}
+	if got := parseCapabilities(""); len(got) != 0 {
+		t.Errorf("empty capabilities = %v", got)
+	}
+}
+
+func TestHeartbeatWiring(t *testing.T) {
+	keys := make(map[string]ed25519.PrivateKey)
+	config := func(nodeID string) *heartbeat.ManagerConfig {
+		_, priv, _ := ed25519.GenerateKey(nil)
+		keys[nodeID] = priv
+		cfg := heartbeat.DefaultManagerConfig(nodeID)
+		cfg.SignFunc = func(data []byte) (string, error) {
+			return fmt.Sprintf("%x", ed25519.Sign(priv, data)), nil
+		}
+		cfg.VerifyFunc = func(signer string, data []byte, signature string) bool {
+			priv, ok := keys[signer]
+			return ok && fmt.Sprintf("%x", ed25519.Sign(priv, data)) == signature
+		}
+		return cfg
+	}
+
+	aliceFeatureConfig := feature.DefaultConfig("alice")
+	aliceFeatureConfig.DataDir = t.TempDir()
+	aliceFeatures, _ := feature.NewManager(aliceFeatureConfig)
+	aliceFeatures.SetReady(feature.FeatureMailboxV2, true)
+	aliceMaint, _ := maintenance.NewManager(&maintenance.Config{NodeID: "alice", DataDir: t.TempDir()})
+	aliceMaint.Enable("upgrade", time.Hour)
+	alice, err := newHeartbeatManager(config("alice"), heartbeatSources{
+		capabilities: []string{"relay"},
+		protocols:    func() []string { return []string{"/daan/mailbox/key/2.0.0"} },
+		features:     aliceFeatures,
+		maintenance:  aliceMaint,
+	})
+	if err != nil {
+		t.Fatalf("newHeartbeatManager: %v", err)
+	}
+
+	bobFeatureConfig := feature.DefaultConfig("bob")
+	bobFeatureConfig.DataDir = t.TempDir()
+	bobFeatures, _ := feature.NewManager(bobFeatureConfig)
+	bobFeatures.SetSupernodesFunc(func() []string { return []string{"alice"} })
+	bobMaint, _ := maintenance.NewManager(&maintenance.Config{NodeID: "bob", DataDir: t.TempDir(), PeerTTL: time.Hour})
+	neighbors := neighbor.NewNeighborManager(nil)
+	neighbors.AddNeighbor(&neighbor.Neighbor{NodeID: "alice", Reputation: 50})
+	bob, err := newHeartbeatManager(config("bob"), heartbeatSources{
+		features:    bobFeatures,
+		maintenance: bobMaint,
+		neighbors:   neighbors,
+	})
+	if err != nil {
+		t.Fatalf("newHeartbeatManager: %v", err)
+	}
+
+	beat, err := alice.NextBeat()
+	if err != nil {
+		t.Fatalf("NextBeat: %v", err)
+	}
+	if beat.Status != heartbeat.StatusMaintenance || len(beat.Features) != 1 || beat.ProtocolHash == "" {
+		t.Errorf("beat = %+v", beat)
+	}
+	if beat.IntervalMs != 0 || alice.Status().Backoff {
+		t.Error("heartbeat interval should not back off before adaptive-heartbeat is active")
+	}
+	data, _ := json.Marshal(beat)
+	if err := bob.Handle(data, "alice"); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	if !bobMaint.IsPeerInMaintenance("alice") {
+		t.Error("maintenance status from heartbeat not recorded")
+	}
+	if status, _ := bobFeatures.GetStatus(feature.FeatureMailboxV2); status.Ready != 1 {
+		t.Errorf("feature signal from heartbeat not observed: %+v", status)
+	}
+	if nb, _ := neighbors.GetNeighbor("alice"); nb.PingStatus != neighbor.StatusOnline {
+		t.Errorf("neighbor status = %s, want online", nb.PingStatus)
+	}
+
+	status, err := heartbeatStatus(bob, "alice")
+	if err != nil || status["liveness"] != float64(1) || status["online"] != true {
+		t.Errorf("peer status = %v, %v", status, err)
+	}
+	if _, err := heartbeatStatus(bob, "carol"); err == nil {
+		t.Error("unknown node should return an error")
+	}
+	if status, _ := heartbeatStatus(bob, ""); status["node_id"] != "bob" {
+		t.Errorf("local status = %v", status)
+	}
+}
+
+func TestHeartbeatRelays(t *testing.T) {
+	config := func(nodeID, role string) *heartbeat.ManagerConfig {
+		cfg := heartbeat.DefaultManagerConfig(nodeID)
+		cfg.Role = role
+		cfg.SignFunc = func(data []byte) (string, error) { return nodeID, nil }
+		cfg.VerifyFunc = func(signer string, data []byte, signature string) bool { return signer == signature }
+		return cfg
+	}
+	local, _ := newHeartbeatManager(config("local", "normal"), heartbeatSources{})
+	for _, p := range []struct{ id, role string }{{"relay-a", "relay"}, {"relay-b", "relay"}, {"peer-c", "normal"}} {
+		hb, _ := newHeartbeatManager(config(p.id, p.role), heartbeatSources{})
+		beat, _ := hb.NextBeat()
+		data, _ := json.Marshal(beat)
+		if err := local.Handle(data, p.id); err != nil {
+			t.Fatalf("Handle %s: %v", p.id, err)
+		}
+	}
+
+	if relays := heartbeatRelays(local); len(relays) != 2 {
+		t.Errorf("relays = %v, want relay-a and relay-b", relays)
+	}
+	if relays := heartbeatRelays(local, "relay-a"); len(relays) != 1 || relays[0] != "relay-b" {
+		t.Errorf("relays excluding relay-a = %v", relays)
+	}
+}
+
+// governanceBus 进程内的广播：消息按主题同步投递给其他节点的订阅者
+type governanceBus struct {
+	mu       sync.Mutex
+	handlers map[string]func(data []byte, from string) // topic + " " + node -> handler
+}
+
+type busTransport struct {
+	bus  *governanceBus
+	node string
+}
+
+func (t busTransport) Publish(topic string, data []byte) error {
+	t.bus.mu.Lock()
+	var handlers []func([]byte, string)
+	for key, h := range t.bus.handlers {
+		if tp, node, _ := strings.Cut(key, " "); tp == topic && node != t.node {
+			handlers = append(handlers, h)
+		}
+	}
+	t.bus.mu.Unlock()
+	for _, h := range handlers {
+		h(data, t.node)
+	}
+	return nil
+}
+
+func (t busTransport) Subscribe(topic string, validate func(data []byte) bool, handler func(data []byte, from string)) error {
+	t.bus.mu.Lock()
+	defer t.bus.mu.Unlock()
+	t.bus.handlers[topic+" "+t.node] = func(data []byte, from string) {
+		if validate(data) {
+			handler(data, from)
+		}
+	}
+	return nil
+}
+
+func TestGovernanceWiring(t *testing.T) {
+	keys := make(map[string]ed25519.PrivateKey)
+	for _, id := range []string{"alice", "bob"} {
+		_, keys[id], _ = ed25519.GenerateKey(nil)
+	}
+	verify := func(signer string, data, signature []byte) (bool, error) {
+		priv, ok := keys[signer]
+		return ok && ed25519.Verify(priv.Public().(ed25519.PublicKey), data, signature), nil
+	}
+	trust := votingTrust{
+		nodes:      func() []string { return []string{"alice", "bob", "mallory"} },
+		reputation: func(string) float64 { return 500 },
+	}
+
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	managers := make(map[string]*voting.VotingManager)
+	neighbors := make(map[string]*neighbor.NeighborManager)
+	for _, id := range []string{"alice", "bob"} {
+		cfg := voting.DefaultConfig(id)
+		cfg.DataDir = t.TempDir()
+		cfg.BufferPeriod = 0
+		cfg.PowerFormula = voting.PowerEqual
+		cfg.QuorumThreshold = 0.6
+		vm, err := voting.NewVotingManager(cfg)
+		if err != nil {
+			t.Fatalf("NewVotingManager: %v", err)
+		}
+		priv := keys[id]
+		vm.SetSignFunc(func(data []byte) ([]byte, error) { return ed25519.Sign(priv, data), nil })
+		vm.SetVerifyFunc(verify)
+		vm.SetParameterApplier(votingParameters(cfg))
+		nm := neighbor.NewNeighborManager(nil)
+		nm.AddNeighbor(&neighbor.Neighbor{NodeID: "mallory", Reputation: 50})
+		registerGovernanceExecutors(vm, governanceEffects{neighbors: nm})
+		stop, err := startGovernance(vm, id, trust, busTransport{bus: bus, node: id})
+		if err != nil {
+			t.Fatalf("startGovernance: %v", err)
+		}
+		defer stop()
+		managers[id], neighbors[id] = vm, nm
+	}
+	if nt, _ := managers["bob"].GetNodeTrust("mallory"); nt == nil || nt.Reputation != 100 {
+		t.Fatalf("mallory trust = %+v, want reputation clamped to 100", nt)
+	}
+
+	alice, bob := managers["alice"], managers["bob"]
+	kick, err := createGovernanceProposal(alice, &httpapi.ProposalRequest{Title: "spam", Description: "flooding the bulletin", Type: "kick", Target: "mallory"})
+	if err != nil {
+		t.Fatalf("createGovernanceProposal: %v", err)
+	}
+	if kick.Reason != "spam: flooding the bulletin" {
+		t.Errorf("reason = %q", kick.Reason)
+	}
+	param, err := createGovernanceProposal(alice, &httpapi.ProposalRequest{Title: "lower quorum", Key: "voting.quorum_threshold", Value: "0.5"})
+	if err != nil || param.Parameter == nil {
+		t.Fatalf("parameter proposal = %+v, %v", param, err)
+	}
+	for _, req := range []*httpapi.ProposalRequest{
+		{Title: "no target", Type: "kick"},
+		{Title: "bad type", Type: "elect", Target: "bob"},
+	} {
+		if _, err := createGovernanceProposal(alice, req); err == nil {
+			t.Errorf("createGovernanceProposal(%+v) should fail", req)
+		}
+	}
+
+	// 提案经广播到达 bob，两个节点都投赞成票后各自执行
+	deadline := time.Now().Add(2 * time.Second)
+	for len(bob.GetActiveProposals()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, p := range []*voting.Proposal{kick, param} {
+		if _, err := bob.CastVote(p.ID, voting.ChoiceYes, ""); err != nil {
+			t.Fatalf("bob CastVote: %v", err)
+		}
+		if _, err := alice.CastVote(p.ID, voting.ChoiceYes, ""); err != nil {
+			t.Fatalf("alice CastVote: %v", err)
+		}
+	}
+	for id, vm := range managers {
+		for len(vm.GetActiveProposals()) > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		p, _ := vm.GetProposal(kick.ID)
+		if p.Status != voting.ProposalPassed || p.Execution == nil || p.Execution.Error != "" {
+			t.Errorf("%s: kick = %s, execution = %+v", id, p.Status, p.Execution)
+		}
+		if neighbors[id].IsNeighbor("mallory") || vm.GetNodeStatus("mallory") != voting.StatusRemoved {
+			t.Errorf("%s: mallory not removed", id)
+		}
+		if p, _ := vm.GetProposal(param.ID); p.Status != voting.ProposalPassed || p.Parameter.OldValue != "0.6" {
+			t.Errorf("%s: parameter proposal = %s, %+v", id, p.Status, p.Parameter)
+		}
+	}
+	if list := listGovernanceProposals(alice, "passed"); len(list) != 2 {
+		t.Errorf("passed proposals = %d", len(list))
+	}
+
+	// 金丝雀参与声明经广播到达其他节点
+	optIn, err := alice.OptInCanary(true)
+	if err != nil {
+		t.Fatalf("OptInCanary: %v", err)
+	}
+	publishGovernance(busTransport{bus: bus, node: "alice"}, governanceMessage{OptIn: optIn})
+	if got := bob.CanaryNodes(); len(got) != 1 || got[0] != "alice" {
+		t.Errorf("bob canary nodes = %v", got)
+	}
+}
+
+func TestStrikeProposals(t *testing.T) {
+	cfg := voting.DefaultConfig("alice")
+	cfg.DataDir = t.TempDir()
+	vm, err := voting.NewVotingManager(cfg)
+	if err != nil {
+		t.Fatalf("NewVotingManager: %v", err)
+	}
+	vm.SetGetReputationFunc(func(string) float64 { return 100 })
+	propose := strikeProposals(vm)
+	for role, want := range map[bulletin.StrikeRole]voting.VoteType{
+		bulletin.StrikeAuthor:    voting.VoteKick,
+		bulletin.StrikeModerator: voting.VoteProposal,
+	} {
+		id, err := propose(role, "mallory", "strikes")
+		if err != nil {
+			t.Fatalf("%s proposal: %v", role, err)
+		}
+		p, err := vm.GetProposal(id)
+		if err != nil || p.Type != want || p.TargetNodeID != "mallory" {
+			t.Errorf("%s proposal = %+v, %v; want %s against mallory", role, p, err, want)
+		}
+	}
+}
+
+func TestAdmissionWiring(t *testing.T) {
+	allow := admissionAllowList(
+		[]string{"/ip4/1.2.3.4/tcp/4001/p2p/12D3KooWBoot", "/ip4/1.2.3.4/tcp/4002"},
+		[]*neighbor.Neighbor{{NodeID: "12D3KooWNeighbor"}, nil},
+	)
+	if len(allow) != 2 || allow[0] != "12D3KooWBoot" || allow[1] != "12D3KooWNeighbor" {
+		t.Errorf("allow list = %v", allow)
+	}
+
+	sign := func(nodeID string) func([]byte) ([]byte, error) {
+		return func(data []byte) ([]byte, error) { return []byte(nodeID), nil }
+	}
+	verify := func(nodeID string, data, signature []byte) (bool, error) { return string(signature) == nodeID, nil }
+	newAdmission := func(nodeID string) *admission.Manager {
+		cfg := admission.DefaultConfig(nodeID)
+		cfg.DataDir = t.TempDir()
+		cfg.Enabled = true
+		cfg.SignFunc, cfg.VerifyFunc = sign(nodeID), verify
+		m, err := admission.New(cfg)
+		if err != nil {
+			t.Fatalf("admission.New: %v", err)
+		}
+		return m
+	}
+
+	endorseConfig := trust.DefaultEndorsementConfig("self")
+	endorseConfig.DataDir = t.TempDir()
+	endorseConfig.SignFunc = sign("self")
+	endorsements, _ := trust.NewEndorsementManager(endorseConfig)
+	if _, err := endorsements.Endorse("friend", "key", "met in person", 0); err != nil {
+		t.Fatalf("Endorse: %v", err)
+	}
+	endorsements.AddEndorsement(&trust.Endorsement{Endorser: "friend", Subject: "newcomer", SubjectKey: "k", ExpiresAt: time.Now().Add(time.Hour)})
+	endorsements.AddEndorsement(&trust.Endorsement{Endorser: "stranger", Subject: "sybil", SubjectKey: "k", ExpiresAt: time.Now().Add(time.Hour)})
+	reputations, _ := reputation.NewManager(reputation.DefaultManagerConfig())
+	reputations.Set("veteran", 300, "test")
+
+	self := newAdmission("self")
+	wireAdmission(self, "self", endorsements, reputations)
+	for peerID, want := range map[string]admission.Reason{
+		"friend":   admission.ReasonEndorsement,
+		"newcomer": admission.ReasonEndorsement,
+		"sybil":    admission.ReasonDenied,
+		"veteran":  admission.ReasonReputation,
+		"unknown":  admission.ReasonDenied,
+	} {
+		if d := self.Evaluate(peerID); d.Reason != want {
+			t.Errorf("Evaluate(%s) = %+v, want %s", peerID, d, want)
+		}
+	}
+
+	// friend 签发的邀请经广播到达本节点；stranger 不受信，其邀请被忽略
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	if err := startAdmissionGossip(self, busTransport{bus: bus, node: "self"}); err != nil {
+		t.Fatalf("startAdmissionGossip: %v", err)
+	}
+	for _, inviter := range []string{"friend", "stranger"} {
+		s := &httpapi.Server{}
+		registerAdmissionAPI(s, newAdmission(inviter), busTransport{bus: bus, node: inviter})
+		if _, err := s.AdmissionInviteFunc(&httpapi.AdmissionInviteRequest{PeerID: "guest-of-" + inviter}); err != nil {
+			t.Fatalf("invite from %s: %v", inviter, err)
+		}
+	}
+	if !self.Admit("guest-of-friend") || self.Admit("guest-of-stranger") {
+		t.Errorf("invitations = %+v", self.Invitations(""))
+	}
+	status := toMap(self.Status())
+	if status["admitted"] != float64(1) || status["denied"] != float64(1) {
+		t.Errorf("status = %v", status)
+	}
+}
+
+func TestIncentiveTolerance(t *testing.T) {
+	endorseConfig := trust.DefaultEndorsementConfig("self")
+	endorseConfig.DataDir = t.TempDir()
+	endorseConfig.SignFunc = func(data []byte) ([]byte, error) { return []byte("self"), nil }
+	endorsements, _ := trust.NewEndorsementManager(endorseConfig)
+	if _, err := endorsements.Endorse("friend", "key", "met in person", 0); err != nil {
+		t.Fatalf("Endorse: %v", err)
+	}
+	members := map[string]bool{"neighbor": true, "bad": true}
+	relation := sourceRelation(
+		func(nodeID string) bool { return nodeID == "bad" },
+		func(nodeID string) bool { return members[nodeID] },
+		endorsements,
+	)
+	for nodeID, want := range map[string]incentive.SourceRelation{
+		"bad":      incentive.RelationQuarantined,
+		"neighbor": incentive.RelationNeighbor,
+		"friend":   incentive.RelationEndorsed,
+		"stranger": incentive.RelationUnknown,
+	} {
+		if got := relation(nodeID); got != want {
+			t.Errorf("relation(%s) = %s, want %s", nodeID, got, want)
+		}
+	}
+
+	config := incentive.DefaultIncentiveConfig("self")
+	config.DataDir = t.TempDir()
+	config.DefaultTolerance = 10
+	im, err := incentive.NewIncentiveManager(config)
+	if err != nil {
+		t.Fatalf("NewIncentiveManager: %v", err)
+	}
+	im.SetRelationFunc(relation)
+
+	// 尚无记录时按关系预估，首次传播后以记录为准
+	if remaining, max := toleranceLevels(im, "neighbor"); remaining != max || max == 0 {
+		t.Errorf("preview levels = %d/%d", remaining, max)
+	}
+	if inputs := toleranceInputs(im, "neighbor"); inputs["relation"] != string(incentive.RelationNeighbor) {
+		t.Errorf("preview inputs = %v", inputs)
+	}
+	if err := im.ReceivePropagation("neighbor", 1, 1, "reward-1"); err != nil {
+		t.Fatalf("ReceivePropagation: %v", err)
+	}
+	if remaining, max := toleranceLevels(im, "neighbor"); remaining >= max {
+		t.Errorf("levels after propagation = %d/%d", remaining, max)
+	}
+	if err := im.ReceivePropagation("bad", 1, 1, "reward-2"); err != incentive.ErrToleranceExceeded {
+		t.Errorf("quarantined source: %v", err)
+	}
+}
+
+func TestEndorsementGossip(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	newNode := func(nodeID string) *trust.EndorsementManager {
+		cfg := trust.DefaultEndorsementConfig(nodeID)
+		cfg.DataDir = t.TempDir()
+		cfg.SignFunc = func(data []byte) ([]byte, error) { return []byte(nodeID), nil }
+		cfg.VerifyFunc = func(endorser string, data, signature []byte) (bool, error) { return string(signature) == endorser, nil }
+		em, err := trust.NewEndorsementManager(cfg)
+		if err != nil {
+			t.Fatalf("NewEndorsementManager: %v", err)
+		}
+		if err := startEndorsementGossip(em, nodeID, busTransport{bus: bus, node: nodeID}); err != nil {
+			t.Fatalf("startEndorsementGossip: %v", err)
+		}
+		return em
+	}
+	a, b, c := newNode("a"), newNode("b"), newNode("c")
+
+	// a 背书 b、b 背书 c，广播后 a 算得到 c 的背书距离为 2
+	if _, err := a.Endorse("b", "key-b", "", 0); err != nil {
+		t.Fatalf("Endorse: %v", err)
+	}
+	if _, err := b.Endorse("c", "key-c", "", 0); err != nil {
+		t.Fatalf("Endorse: %v", err)
+	}
+	if d := a.TrustDistance("c"); d != 2 {
+		t.Errorf("distance a->c = %d, want 2", d)
+	}
+	if m := a.ToleranceMultiplier("c"); m != 1.5 {
+		t.Errorf("tolerance multiplier = %v", m)
+	}
+	if len(c.GetEndorsementsFor("b")) != 1 {
+		t.Errorf("c should receive a's endorsement of b")
+	}
+
+	// 冒充背书人的广播被忽略
+	forged, _ := json.Marshal(&trust.Endorsement{Endorser: "a", Subject: "mallory", SubjectKey: "k", Signature: []byte("a"), ExpiresAt: time.Now().Add(time.Hour)})
+	busTransport{bus: bus, node: "mallory"}.Publish(endorsementTopic, forged)
+	if len(b.GetEndorsementsFor("mallory")) != 0 {
+		t.Errorf("forged endorsement accepted")
+	}
+
+	// 隔离缩放取背书距离和外部身份证明之积，没有证明时只看背书
+	proofConfig := trust.DefaultIdentityProofConfig("a")
+	proofConfig.DataDir = t.TempDir()
+	proofs, err := trust.NewIdentityProofManager(proofConfig)
+	if err != nil {
+		t.Fatalf("NewIdentityProofManager: %v", err)
+	}
+	if got, want := quarantineScale(a, proofs)("b"), a.QuarantineMultiplier("b"); got != want || got >= 1 {
+		t.Errorf("quarantine scale = %v, want %v", got, want)
+	}
+}
+
+func TestGrantStarterReputation(t *testing.T) {
+	page := ""
+	config := trust.DefaultIdentityProofConfig("a")
+	config.DataDir = t.TempDir()
+	config.SignFunc = func(data []byte) ([]byte, error) { return []byte("a"), nil }
+	config.FetchFunc = func(ctx context.Context, rawURL string) (string, error) { return page, nil }
+	proofs, err := trust.NewIdentityProofManager(config)
+	if err != nil {
+		t.Fatalf("NewIdentityProofManager: %v", err)
+	}
+	reputations, _ := reputation.NewManager(reputation.DefaultManagerConfig())
+	proofs.OnProofVerified = grantStarterReputation(proofs, reputations)
+	before := reputations.GetReputation("a")
+
+	gist, err := proofs.CreateProof(trust.ProofGitHubGist, "alice", "https://gist.github.com/alice/1")
+	if err != nil {
+		t.Fatalf("CreateProof: %v", err)
+	}
+	page = gist.Token()
+	if _, err := proofs.Verify(context.Background(), gist.ID); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got, want := reputations.GetReputation("a"), before+config.StarterReputation; got != want {
+		t.Errorf("reputation after proof = %v, want %v", got, want)
+	}
+
+	// 撤下后重新验证不重复计入
+	page = ""
+	proofs.ReverifyAll(context.Background())
+	page = gist.Token()
+	proofs.Verify(context.Background(), gist.ID)
+	if got, want := reputations.GetReputation("a"), before+config.StarterReputation; got != want {
+		t.Errorf("reputation after re-verify = %v, want %v", got, want)
+	}
+}
+
+func TestReachabilityGossip(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	newNode := func(nodeID string) *reachability.Service {
+		reach := reachability.New(nodeID, nil)
+		reach.SetSigner(func(data []byte) ([]byte, error) { return []byte(nodeID), nil },
+			func(signer string, data, signature []byte) (bool, error) { return string(signature) == signer, nil })
+		if err := startReachabilityGossip(reach, nodeID, busTransport{bus: bus, node: nodeID}); err != nil {
+			t.Fatalf("startReachabilityGossip: %v", err)
+		}
+		return reach
+	}
+	a, b := newNode("a"), newNode("b")
+	a.SetDialBackFunc(func(ctx context.Context, subject string) ([]string, time.Duration, error) {
+		if subject == "natted" {
+			return nil, 0, errors.New("dial timeout")
+		}
+		return []string{"/ip4/203.0.113.7/tcp/4001"}, 40 * time.Millisecond, nil
+	})
+
+	// a 回拨测试后签发的证明广播给 b
+	for _, subject := range []string{"relay", "natted"} {
+		if _, err := a.Test(context.Background(), subject); err != nil {
+			t.Fatalf("Test %s: %v", subject, err)
+		}
+	}
+	if s := b.Score("relay"); s.Attesters != 1 || s.Reachable != 1 {
+		t.Errorf("b should receive a's attestation: %+v", s)
+	}
+
+	// 冒充证明人的广播被忽略
+	forged, _ := json.Marshal(&reachability.Attestation{Subject: "mallory", Attester: "a", Reachable: true, IssuedAt: time.Now(), Signature: []byte("a")})
+	busTransport{bus: bus, node: "mallory"}.Publish(reachabilityTopic, forged)
+	if s := b.Score("mallory"); s.Attesters != 0 {
+		t.Errorf("forged attestation accepted: %+v", s)
+	}
+
+	// 中继候选按可达性分数重排
+	relays := rankedRelays(b, func(exclude ...string) []string { return []string{"natted", "unknown", "relay"} })
+	if got := relays(); strings.Join(got, ",") != "relay,unknown,natted" {
+		t.Errorf("ranked relays = %v", got)
+	}
+}
+
+func TestAuditWiring(t *testing.T) {
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	newNode := func(nodeID string, subscribe bool) (*auditNetwork, *httpapi.Server) {
+		cfg := audit.DefaultConfig(nodeID)
+		cfg.DataDir = t.TempDir()
+		cfg.SignFunc = func(data []byte) ([]byte, error) { return []byte(nodeID), nil }
+		cfg.VerifyFunc = func(signer string, data, signature []byte) (bool, error) { return string(signature) == signer, nil }
+		m, err := audit.New(cfg)
+		if err != nil {
+			t.Fatalf("audit.New: %v", err)
+		}
+		m.SetAuditorFunc(func(id string) bool { return strings.HasPrefix(id, "sn") })
+		an := newAuditNetwork(m, nodeID)
+		if subscribe {
+			if err := an.start(busTransport{bus: bus, node: nodeID}); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+		}
+		s := &httpapi.Server{}
+		registerAuditAPI(s, an)
+		return an, s
+	}
+
+	executor, _ := newNode("executor", true)
+	sn1, api1 := newNode("sn1", true)
+	_, api2 := newNode("sn2", true)
+	reputations, _ := reputation.NewManager(reputation.DefaultManagerConfig())
+	collaterals := collateral.NewCollateralManager()
+	collaterals.Deposit("sn3", auditCollateralPurpose, 100)
+	wireAudit(sn1.m, reputations, collaterals)
+
+	// 执行方交付时广播执行记录；sn3 之后才加入，需向执行方请求
+	executor.recordTask(&task.Task{ID: "t1", Type: task.TaskTypeCompute, Description: "2+2"}, "4")
+	sn3, api3 := newNode("sn3", false)
+	if _, err := api3.AuditTranscriptFunc("t1"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("transcript without gossip error = %v", err)
+	}
+	sn3.start(busTransport{bus: bus, node: "sn3"})
+	if tr, err := api3.AuditTranscriptFunc("t1"); err != nil || tr["executor"] != "executor" {
+		t.Fatalf("fetched transcript = %v, %v", tr, err)
+	}
+
+	hash := audit.HashResult([]byte("4"))
+	for _, api := range []*httpapi.Server{api1, api2} {
+		if a, err := api.AuditRunFunc(&httpapi.AuditRunRequest{TaskID: "t1", ResultHash: hash}); err != nil || a["passed"] != true {
+			t.Fatalf("AuditRunFunc = %v, %v", a, err)
+		}
+	}
+	if _, err := api3.SuperNodeAuditSubmit("t1", false, "output looks wrong"); err != nil {
+		t.Fatalf("SuperNodeAuditSubmit: %v", err)
+	}
+
+	// 每个节点都看到全部三份结论并形成相同的共识
+	result, err := api1.AuditConsensusFunc("t1")
+	if err != nil || result["consensus"].(*audit.Consensus).Passed != true {
+		t.Fatalf("consensus = %v, %v", result, err)
+	}
+	if rate, _ := api3.SuperNodeAuditResult("t1"); rate < 0.66 || rate > 0.67 {
+		t.Errorf("pass rate = %v", rate)
+	}
+	deviations := api1.AuditDeviationsFunc("", 0)
+	if len(deviations) != 1 || deviations[0]["auditor"] != "sn3" || deviations[0]["severity"] != audit.SeverityMinor {
+		t.Fatalf("deviations = %v", deviations)
+	}
+	if score, _ := reputations.Get("sn3"); score == nil || score.Score != reputation.ReputationInitial-5 {
+		t.Errorf("sn3 reputation = %+v", score)
+	}
+	if account, _ := collaterals.GetAccount("sn3", auditCollateralPurpose); account == nil || account.Balance != 90 {
+		t.Errorf("sn3 collateral = %+v", account)
+	}
+	if _, err := api1.AuditConsensusFunc("t2"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("consensus for unknown task error = %v", err)
+	}
+}
+
+func TestControlWiring(t *testing.T) {
+	dir := t.TempDir()
+	if got := controlSocketPath(dir, ""); got != filepath.Join(dir, control.SocketName) {
+		t.Errorf("default socket = %s", got)
+	}
+	if got := controlSocketPath(dir, controlSocketOff); got != "" {
+		t.Errorf("disabled socket = %q", got)
+	}
+	if startControlServer("", nil, nil, nil) != nil {
+		t.Error("control server started while disabled")
+	}
+
+	// 托盘请求停止时，主流程像收到 SIGTERM 一样退出
+	sigCh := make(chan os.Signal, 1)
+	server := startControlServer(controlSocketPath(dir, ""), func() map[string]interface{} {
+		return map[string]interface{}{"node_id": "self"}
+	}, notifyStop(sigCh), httpapi.NewEventHub(8))
+	if server == nil {
+		t.Fatal("control server not started")
+	}
+	defer server.Stop()
+
+	client := control.NewClient(server.SocketPath())
+	if status, err := client.Status(); err != nil || status["node_id"] != "self" {
+		t.Fatalf("Status() = %v, %v", status, err)
+	}
+	client.Stop()
+	client.Stop() // 信号尚未处理时重复请求不阻塞
+	select {
+	case <-sigCh:
+	case <-time.After(time.Second):
+		t.Fatal("stop request did not reach the signal channel")
+	}
+}
+
+func TestVerifyLedgers(t *testing.T) {
+	dir := t.TempDir()
+	sign := func(data []byte) (string, error) { return "sig:" + string(data), nil }
+	verify := func(signer string, data []byte, signature string) bool { return signature == "sig:"+string(data) }
+
+	events, _ := ledger.NewLedger(filepath.Join(dir, "ledger"))
+	events.SetSignFunc(sign)
+	events.SetVerifyFunc(verify)
+	repConfig := reputation.DefaultManagerConfig()
+	repConfig.DataDir = filepath.Join(dir, "reputation")
+	reputations, _ := reputation.NewManager(repConfig)
+	reputations.SetOnChange(recordReputationChange(events, "self"))
+	tokenConfig := token.DefaultConfig()
+	tokenConfig.DataDir = filepath.Join(dir, "token")
+	tokenConfig.VerifyFunc = verify
+	tokens, _ := token.NewLedger(tokenConfig)
+
+	reputations.Adjust("peer-a", 10, "task")
+	reputations.Adjust("peer-a", -3, "late")
+	reputations.Adjust("peer-b", -20, "spam")
+	tokens.MintReward("peer-a", "r1", 10)
+
+	src := ledgerSources{events: events, tokens: tokens, reputations: reputations}
+	report := verifyLedgers(src)
+	if !report.OK || report.Events.Events != 3 || report.Events.NodesChecked != 2 || report.Tokens.Entries != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// 绕过事件账本改写的声誉被发现
+	reputations.SetOnChange(nil)
+	reputations.Set("peer-b", 99, "edited")
+	report = verifyLedgers(src)
+	if report.OK || len(report.Events.Issues) != 1 || report.Events.Issues[0].NodeID != "peer-b" {
+		t.Errorf("events issues = %+v", report.Events.Issues)
+	}
+
+	// 离线读取数据目录得到相同结果
+	if err := reputations.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadLedgerSources(dir, verify)
+	if err != nil || loaded.events == nil || loaded.tokens == nil || loaded.reputations == nil {
+		t.Fatalf("loadLedgerSources() = %+v, %v", loaded, err)
+	}
+	offline := verifyLedgers(loaded)
+	if offline.OK || len(offline.Events.Issues) != 1 || !offline.Tokens.OK() {
+		t.Errorf("offline report = %+v", offline)
+	}
+	if empty, err := loadLedgerSources(t.TempDir(), verify); err != nil || !verifyLedgers(empty).OK {
+		t.Errorf("empty data dir = %+v, %v", empty, err)
+	}
+}
+
+func TestSuperNodeAPI(t *testing.T) {
+	collaterals := collateral.NewCollateralManager()
+	collaterals.Deposit("self", auditCollateralPurpose, 100)
+	cfg := supernode.DefaultConfig("self")
+	cfg.DataDir = ""
+	sm, _ := supernode.NewSuperNodeManager(cfg)
+	sm.SetStakeLocker(supernode.NewCollateralStake(collaterals, auditCollateralPurpose))
+	s := &httpapi.Server{}
+	registerSuperNodeAPI(s, sm, "self", func(string) float64 { return 80 })
+
+	// 申请候选时实际锁定抵押，抵押不足的申请被拒绝
+	if err := s.SuperNodeApplyFunc(500); err == nil {
+		t.Error("stake beyond the collateral balance should be rejected")
+	}
+	if err := s.SuperNodeApplyFunc(40); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if a, _ := collaterals.GetAccount("self", auditCollateralPurpose); a.Locked != 40 {
+		t.Errorf("locked = %v, want 40", a.Locked)
+	}
+	if c := s.SuperNodeCandidatesFunc(); len(c) != 1 || c[0]["stake"] != 40.0 {
+		t.Errorf("candidates = %v", c)
+	}
+
+	if err := s.SuperNodeVoteFunc("self"); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+	electionID, err := s.SuperNodeStartElection()
+	if err != nil {
+		t.Fatalf("start election: %v", err)
+	}
+	if _, err := s.SuperNodeFinalizeFunc("elec_other"); err == nil {
+		t.Error("finalizing an unknown election should fail")
+	}
+	elected, err := s.SuperNodeFinalizeFunc(electionID)
+	if err != nil || len(elected) != 1 || elected[0] != "self" || len(s.SuperNodeListFunc()) != 1 {
+		t.Errorf("elected = %v, %v", elected, err)
+	}
+
+	// 被移除后释放抵押
+	sm.RemoveSuperNode("self", "test")
+	if a, _ := collaterals.GetAccount("self", auditCollateralPurpose); a.Locked != 0 {
+		t.Errorf("locked after removal = %v", a.Locked)
+	}
+}
+
+func TestSuperNodeDuty(t *testing.T) {
+	cfg := supernode.DefaultConfig("self")
+	cfg.DataDir = ""
+	cfg.Scoreboard.DemotionEpochs = 1
+	sm, _ := supernode.NewSuperNodeManager(cfg)
+	for _, id := range []string{"self", "peer"} {
+		if err := sm.ApplyCandidate(id, 80, 40); err != nil {
+			t.Fatalf("apply %s: %v", id, err)
+		}
+		sm.VoteForCandidate("self", id, 80)
+	}
+	sm.StartElection()
+	if _, err := sm.FinalizeElection(); err != nil || !sm.IsSuperNode("peer") {
+		t.Fatalf("election: %v", err)
+	}
+	s := &httpapi.Server{}
+	registerSuperNodeAPI(s, sm, "self", func(string) float64 { return 80 })
+
+	// 探测跳过本节点，离线的超级节点计入记分板
+	var probed []string
+	probeSuperNodes(sm, "self", func(id string) (time.Duration, error) {
+		probed = append(probed, id)
+		return 0, errors.New("timeout")
+	})
+	if len(probed) != 1 || probed[0] != "peer" {
+		t.Errorf("probed = %v", probed)
+	}
+	board, err := s.SuperNodeScoreboardFunc("peer")
+	if err != nil || len(board) != 1 {
+		t.Fatalf("scoreboard = %v, %v", board, err)
+	}
+	if current, _ := board[0]["current"].(map[string]interface{}); current["uptime_checks"] != 1.0 || current["uptime_ok"] != 0.0 {
+		t.Errorf("current = %v", board[0]["current"])
+	}
+	if _, err := s.SuperNodeScoreboardFunc("nobody"); err == nil {
+		t.Error("unknown node should not have a duty score")
+	}
+
+	// 不达标的超级节点经治理投票提议降级，提案结束后按结果移除
+	votingConfig := voting.DefaultConfig("self")
+	votingConfig.DataDir = t.TempDir()
+	votingConfig.BufferPeriod = 0
+	vm, err := voting.NewVotingManager(votingConfig)
+	if err != nil {
+		t.Fatalf("NewVotingManager: %v", err)
+	}
+	vm.SetGetReputationFunc(func(string) float64 { return 100 })
+	vm.RegisterNode("self", 100, 0)
+	watchSuperNodeDemotions(sm, vm)
+	if proposed := sm.CloseEpoch(); len(proposed) != 1 || proposed[0] != "peer" {
+		t.Fatalf("proposed = %v", proposed)
+	}
+	score, _ := sm.GetDutyScore("peer")
+	p, err := vm.GetProposal(score.DemotionProposalID)
+	if err != nil || p.Type != voting.VoteDemote || p.TargetNodeID != "peer" {
+		t.Fatalf("demotion proposal = %+v, %v", p, err)
+	}
+	if _, err := vm.CastVote(p.ID, voting.ChoiceYes, ""); err != nil {
+		t.Fatalf("CastVote: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for sm.IsSuperNode("peer") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sm.IsSuperNode("peer") {
+		t.Error("peer should be removed once the demotion passes")
+	}
+}
+
+func TestLogAPI(t *testing.T) {
+	l, err := newNodeLogger(t.TempDir(), "self", 1, func(data []byte) (string, error) { return "sig", nil })
+	if err != nil {
+		t.Fatalf("newNodeLogger: %v", err)
+	}
+	defer l.Stop()
+	s := &httpapi.Server{}
+	registerLogAPI(s, l)
+
+	entry, err := s.LogSubmitFunc("task_complete", map[string]interface{}{"task_id": "t1"})
+	if err != nil || entry["node_id"] != "self" || entry["signature"] != "sig" {
+		t.Fatalf("submit = %v, %v", entry, err)
+	}
+	s.LogSubmitFunc("task_create", nil)
+
+	// 查询由只读副本执行，提交后立即可见
+	if logs := s.LogQueryFunc("", "task_complete", 10); len(logs) != 1 || logs[0]["log_id"] != entry["log_id"] {
+		t.Errorf("query = %v", logs)
+	}
+	replicas := s.LogReplicasFunc()
+	if len(replicas) != 1 || replicas[0]["queries"] != 1.0 {
+		t.Errorf("replicas = %v", replicas)
+	}
+}
+
+func TestLedgerUsage(t *testing.T) {
+	events, _ := ledger.NewLedger("")
+	events.AppendEvent(ledger.EventReputationChange, "peer-a", ledger.ReputationChangeData{NodeID: "peer-a", Delta: 1, NewValue: 51}, "self")
+	events.AppendEvent(ledger.EventReputationChange, "peer-b", ledger.ReputationChangeData{NodeID: "peer-b", Delta: 1, NewValue: 51}, "self")
+
+	usage := ledgerUsage(events)
+	types, _ := usage["types"].([]ledger.TypeUsage)
+	if len(types) != 1 || types[0].Events != 2 || types[0].Retention != "forever" || usage["bytes"] != types[0].Bytes {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestGenesisAPI(t *testing.T) {
+	gm, _ := genesis.NewGenesisManager(t.TempDir())
+	bus := &governanceBus{handlers: make(map[string]func([]byte, string))}
+	s := &httpapi.Server{}
+	registerGenesisAPI(s, gm, busTransport{bus: bus, node: "genesis"})
+
+	if info := s.GenesisInfoFunc(); info["initialized"] != false {
+		t.Errorf("info before init = %v", info)
+	}
+	if _, err := s.GenesisCreateInviteFunc("03ab", "12D3KooWInvitee"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("invite before init error = %v", err)
+	}
+	g, _ := gm.InitGenesis("TestNetwork", "1.0.0")
+
+	// 其他节点加载同一创世信息，经广播重放加入记录
+	replica, _ := genesis.NewGenesisManager(t.TempDir())
+	genesisJSON, _ := json.Marshal(g)
+	if err := replica.LoadGenesis(genesisJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := startGenesisGossip(replica, busTransport{bus: bus, node: "replica"}); err != nil {
+		t.Fatal(err)
+	}
+	admissionConfig := admission.DefaultConfig("replica")
+	admissionConfig.DataDir = ""
+	admissionConfig.Enabled = true
+	gate, _ := admission.New(admissionConfig)
+	gate.SetMemberFunc(replica.IsPeerJoined)
+
+	invitee, _ := genesis.NewGenesisManager(t.TempDir())
+	pubkey, err := invitee.EnsureKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := s.GenesisCreateInviteFunc(pubkey, "12D3KooWInvitee")
+	if err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+	if valid, inviter, err := s.GenesisVerifyInviteFunc(code); !valid || inviter != g.GenesisNodeID || err != nil {
+		t.Errorf("verify = %v, %s, %v", valid, inviter, err)
+	}
+	// 没有受邀方签名的请求被拒绝，邀请码仍可使用
+	if _, _, err := s.GenesisJoinFunc(&httpapi.GenesisJoinRequest{Invitation: code, Pubkey: pubkey, PeerID: "12D3KooWInvitee", Signature: "00"}); !errors.Is(err, genesis.ErrInvalidJoinSignature) {
+		t.Errorf("join without proof error = %v", err)
+	}
+	signature, err := invitee.SignJoin(code, "12D3KooWInvitee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeID, _, err := s.GenesisJoinFunc(&httpapi.GenesisJoinRequest{Invitation: code, Pubkey: pubkey, PeerID: "12D3KooWInvitee", Signature: signature})
+	if err != nil || nodeID != invitee.GetNodeID() {
+		t.Fatalf("join = %s, %v", nodeID, err)
+	}
+	if !replica.IsNodeJoined(nodeID) || !replica.IsPeerJoined("12D3KooWInvitee") {
+		t.Error("join should propagate to the replica")
+	}
+	if d := gate.Evaluate("12D3KooWInvitee"); !d.Allowed || d.Reason != admission.ReasonMember {
+		t.Errorf("admission of joined peer = %+v", d)
+	}
+	// 邀请码只能使用一次
+	if valid, _, err := s.GenesisVerifyInviteFunc(code); valid || !errors.Is(err, genesis.ErrInvitationUsed) {
+		t.Errorf("verify used invite = %v, %v", valid, err)
+	}
+
+	graph, err := s.GenesisGraphFunc("")
+	if err != nil || graph["max_depth"] != float64(1) || len(graph["nodes"].([]interface{})) != 2 {
+		t.Errorf("graph = %v, %v", graph, err)
+	}
+	if _, err := s.GenesisGraphFunc("missing"); !errors.Is(err, httpapi.ErrNotFound) {
+		t.Errorf("graph of unknown root error = %v", err)
+	}
+}
+
+func TestMemberRegistry(t *testing.T) {
+	r := &memberRegistry{}
+	if r.registered("peer-a") {
+		t.Error("empty registry should not register anyone")
+	}
+	r.add(func(id string) bool { return id == "peer-a" })
+	r.add(func(id string) bool { return id == "peer-b" })
+	if !r.registered("peer-a") || !r.registered("peer-b") {
+		t.Error("nodes in any source should be registered")
+	}
+	if r.registered("peer-c") {
+		t.Error("unknown node should not be registered")
+	}
+}
+
+func TestCanaryMetrics(t *testing.T) {
+	var observed int
+	requests := &requestCounter{}
+	observe := requests.observe(func(string, string, int, time.Duration) { observed++ })
+	for _, status := range []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError, http.StatusBadGateway} {
+		observe(http.MethodGet, "/api/v1/node/info", status, time.Millisecond)
+	}
+	if observed != 4 {
+		t.Errorf("next observer called %d times", observed)
+	}
+
+	metrics := canaryMetrics(requests, func() int { return 7 }, func() int { return 1 })()
+	want := voting.CanaryMetrics{Requests: 4, Errors: 2, Tasks: 7, Disputes: 1}
+	if metrics != want {
+		t.Errorf("metrics = %+v, want %+v", metrics, want)
+	}
+}
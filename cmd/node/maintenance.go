@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/maintenance"
+)
+
+// maintenancePath 维护模式 API 路径
+const maintenancePath = "/api/v1/node/maintenance"
+
+func cmdMaintenance() {
+	if len(os.Args) < 3 {
+		printMaintenanceUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	fs := flag.NewFlagSet("maintenance "+subCmd, flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
+	reason := fs.String("reason", "", "维护原因（on 时有效）")
+	duration := fs.Duration("d", time.Hour, "维护时长，不能超过节点的上限（on 时有效）")
+	fs.Parse(os.Args[3:])
+
+	token := loadOrGenerateToken(*dataDir)
+	sign := nodeRequestSigner(filepath.Join(*dataDir, "keys", "node.key"))
+
+	var (
+		status map[string]interface{}
+		err    error
+	)
+	switch subCmd {
+	case "on":
+		status, err = maintenanceRequest(*httpAddr, token, http.MethodPost, map[string]interface{}{
+			"enabled":  true,
+			"reason":   *reason,
+			"duration": int64(duration.Seconds()),
+		}, sign)
+	case "off":
+		status, err = maintenanceRequest(*httpAddr, token, http.MethodPost, map[string]interface{}{
+			"enabled": false,
+		}, sign)
+	case "status":
+		status, err = maintenanceRequest(*httpAddr, token, http.MethodGet, nil, nil)
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printMaintenanceUsage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "维护模式操作失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if enabled, _ := status["enabled"].(bool); enabled {
+		fmt.Println("🔧 维护模式: 已开启")
+		if r, _ := status["reason"].(string); r != "" {
+			fmt.Printf("   原因: %s\n", r)
+		}
+		if until, _ := status["until"].(string); until != "" {
+			fmt.Printf("   截止: %s\n", until)
+		}
+	} else {
+		fmt.Println("✅ 维护模式: 已关闭")
+	}
+}
+
+func printMaintenanceUsage() {
+	fmt.Print(`用法: agentnetwork maintenance <子命令> [选项]
+
+子命令:
+  on        进入维护模式（保持连接、缓存邮件，拒绝新任务并暂停选举）
+  off       退出维护模式
+  status    查看维护状态
+
+选项:
+  -data     数据目录，用于读取访问令牌 (默认: ./data)
+  -http     HTTP服务地址 (默认: :18345)
+  -reason   维护原因
+  -d        维护时长，如 30m、2h，不超过节点上限 24h (默认: 1h)
+
+示例:
+  agentnetwork maintenance on -reason "升级硬盘" -d 2h
+  agentnetwork maintenance status
+  agentnetwork maintenance off
+`)
+}
+
+// maintenanceRequest 调用节点的维护模式 API，返回响应中的 data 字段
+func maintenanceRequest(httpAddr, token, method string, payload interface{}, sign apiRequestSigner) (map[string]interface{}, error) {
+	return nodeAPIRequest(httpAddr, token, method, maintenancePath, payload, sign)
+}
+
+// apiRequestSigner 为请求添加节点签名头
+type apiRequestSigner func(req *http.Request, body []byte) error
+
+// nodeRequestSigner 用本机节点密钥为请求签名（节点以 -signed-requests 启动时变更类请求需要），
+// 密钥文件不存在时返回 nil，请求只带 Token
+func nodeRequestSigner(keyPath string) apiRequestSigner {
+	secret, err := loadNodeSecret(keyPath)
+	if err != nil || len(secret) != ed25519.PrivateKeySize {
+		return nil
+	}
+	nodeID, err := nodeKeyPeerID(keyPath)
+	if err != nil {
+		return nil
+	}
+	key := ed25519.PrivateKey(secret)
+	return func(req *http.Request, body []byte) error {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		return httpapi.SignCallbackRequest(req, nodeID, body, hex.EncodeToString(nonce), func(data []byte) ([]byte, error) {
+			return ed25519.Sign(key, data), nil
+		})
+	}
+}
+
+// nodeAPIRequest 调用本机运行中节点的 HTTP API，返回响应中的 data 字段；sign 为 nil 时不签名
+func nodeAPIRequest(httpAddr, token, method, path string, payload interface{}, sign apiRequestSigner) (map[string]interface{}, error) {
+	host := httpAddr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+
+	var data []byte
+	var body io.Reader
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, "http://"+host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Token", token)
+	if sign != nil {
+		if err := sign(req, data); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool                   `json:"success"`
+		Data    map[string]interface{} `json:"data"`
+		Error   string                 `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("HTTP状态码: %d", resp.StatusCode)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return result.Data, nil
+}
+
+// maintenanceStatusMap 将维护状态转换为 API 响应
+func maintenanceStatusMap(status *maintenance.Status) map[string]interface{} {
+	result := map[string]interface{}{
+		"node_id": status.NodeID,
+		"enabled": status.Enabled,
+	}
+	if status.Enabled {
+		result["reason"] = status.Reason
+		result["since"] = status.Since.Format(time.RFC3339)
+		if !status.Until.IsZero() {
+			result["until"] = status.Until.Format(time.RFC3339)
+		}
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package main
+
+import "sync"
+
+// memberRegistry 汇总本节点认可的成员登记：邻居表、治理投票登记和创世加入记录。
+// 各来源在对应子系统就绪后加入，任一来源登记过即视为已登记。
+type memberRegistry struct {
+	mu      sync.RWMutex
+	sources []func(nodeID string) bool
+}
+
+// add 加入一个登记来源
+func (r *memberRegistry) add(registered func(nodeID string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, registered)
+}
+
+// registered 节点是否在任一来源中登记过
+func (r *memberRegistry) registered(nodeID string) bool {
+	r.mu.RLock()
+	sources := r.sources
+	r.mu.RUnlock()
+	for _, fn := range sources {
+		if fn(nodeID) {
+			return true
+		}
+	}
+	return false
+}
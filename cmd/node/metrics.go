@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bandwidth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// bandwidthMetrics 出站带宽调度器的统计指标
+func bandwidthMetrics(s *bandwidth.Stats) []stats.Metric {
+	var queued int64
+	for _, p := range s.Peers {
+		queued += p.QueuedBytes
+	}
+	return []stats.Metric{
+		stats.Counter("outbound_bytes", float64(s.TotalBytes), "已放行的出站字节数"),
+		stats.Gauge("outbound_rate", float64(s.Rate), "出站带宽预算（字节/秒）"),
+		stats.Gauge("active_peers", float64(s.ActivePeers), "有排队请求的节点数"),
+		stats.Gauge("queued_bytes", float64(queued), "排队等待放行的字节数"),
+	}
+}
+
+// reputationSamples 邻居声誉分，用于声誉分布直方图
+func reputationSamples(neighbors []*neighbor.Neighbor) []float64 {
+	samples := make([]float64, 0, len(neighbors))
+	for _, nb := range neighbors {
+		samples = append(samples, float64(nb.Reputation))
+	}
+	return samples
+}
+
+// startMetricsServer 在独立地址上提供 /metrics，与需要 Token 的 HTTP API 分开
+func startMetricsServer(addr string, handler http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", handler)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "⚠️  指标服务异常退出: %v\n", err)
+		}
+	}()
+	return srv
+}
+
+// stopMetricsServer 关闭指标服务
+func stopMetricsServer(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+}
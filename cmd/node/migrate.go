@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/daemon"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/migrate"
+)
+
+// schemaStores 数据目录中需要版本管理的存储
+// 只新增字段的改动不需要迁移；字段改名、类型或结构变化时在对应存储末尾追加迁移。
+func schemaStores(keys *crypto.StorageKeyring) []*migrate.Store {
+	sealed := &keyringCodec{keys: keys}
+	return []*migrate.Store{
+		{Name: "mailbox", Path: filepath.Join("mailbox", "mailbox.json"), Codec: sealed},
+		{Name: "bulletin", Path: filepath.Join("bulletin", "bulletin.json"), Codec: sealed},
+		{Name: "maintenance", Path: filepath.Join("maintenance", "maintenance.json")},
+		{Name: "endorsements", Path: filepath.Join("trust", "endorsements.json")},
+		{Name: "identity_proofs", Path: filepath.Join("trust", "identity_proofs.json")},
+		{Name: "transfers", Path: filepath.Join("transfer", "transfers.json")},
+		{Name: "retention_holds", Path: filepath.Join("retention", "holds.json")},
+		{Name: "task_templates", Path: filepath.Join("tasks", "templates.json")},
+		{Name: "tasks", Path: filepath.Join("tasks", "tasks.json")},
+	}
+}
+
+// keyringCodec 静态加密存储的编解码：迁移前解密，迁移后用当前密钥加密
+type keyringCodec struct {
+	keys *crypto.StorageKeyring
+}
+
+func (c *keyringCodec) Decode(data []byte) ([]byte, error) {
+	if !crypto.IsSealed(data) {
+		return data, nil
+	}
+	if c.keys == nil {
+		return nil, fmt.Errorf("数据已加密，需要节点密钥或存储密钥")
+	}
+	return c.keys.Open(data)
+}
+
+func (c *keyringCodec) Encode(data []byte) ([]byte, error) {
+	if c.keys == nil {
+		return data, nil
+	}
+	return c.keys.Seal(data)
+}
+
+// runMigrations 对数据目录执行迁移
+func runMigrations(dataDir string, keys *crypto.StorageKeyring, dryRun bool) (*migrate.Report, error) {
+	m, err := migrate.NewMigrator(&migrate.Config{DataDir: dataDir, DryRun: dryRun}, schemaStores(keys)...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Run()
+}
+
+// printMigrationReport 打印迁移结果，只有需要迁移时才逐项列出
+func printMigrationReport(report *migrate.Report, verbose bool) {
+	for _, step := range report.Steps {
+		if !verbose && step.Status != migrate.StatusMigrated && step.Status != migrate.StatusPending {
+			continue
+		}
+		fmt.Printf("  %-16s v%d -> v%d  %s\n", step.Store, step.From, step.To, step.Status)
+		for _, applied := range step.Applied {
+			fmt.Printf("      %s\n", applied)
+		}
+	}
+	if report.BackupDir != "" {
+		fmt.Printf("  迁移前数据已备份到 %s\n", report.BackupDir)
+	}
+}
+
+func cmdMigrate() {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录")
+	keyPath := fs.String("key", "", "密钥文件路径（默认: <数据目录>/keys/node.key），用于解密加密存储")
+	storageKey := fs.String("storage-key", "", "本地存储加密密钥文件（与 run/start 的 -storage-key 一致）")
+	dryRun := fs.Bool("dry-run", false, "只检查并试运行迁移，不写入任何文件")
+	jsonOut := fs.Bool("json", false, "以 JSON 输出结果")
+	fs.Parse(os.Args[2:])
+
+	if !*dryRun {
+		d := daemon.New(&daemon.Config{DataDir: *dataDir})
+		if pid, running := d.IsRunning(); running {
+			fmt.Fprintf(os.Stderr, "节点正在运行 (PID: %d)，请先停止节点再迁移，或使用 -dry-run 预览\n", pid)
+			os.Exit(1)
+		}
+	}
+
+	if *keyPath == "" {
+		*keyPath = filepath.Join(*dataDir, "keys", "node.key")
+	}
+	var keys *crypto.StorageKeyring
+	if secret, err := loadNodeSecret(*keyPath); err == nil {
+		if keys, err = openStorageKeyring(*dataDir, *storageKey, secret); err != nil {
+			fmt.Fprintf(os.Stderr, "打开存储密钥环失败: %v\n", err)
+			os.Exit(1)
+		}
+	} else if !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "读取节点密钥失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := runMigrations(*dataDir, keys, *dryRun)
+	if *jsonOut && report != nil {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "迁移失败（数据未修改）: %v\n", err)
+		os.Exit(1)
+	}
+	if *jsonOut {
+		return
+	}
+
+	switch {
+	case *dryRun && report.Migrated():
+		fmt.Println("以下存储需要迁移（dry-run，未写入）:")
+	case report.Migrated():
+		fmt.Println("✅ 迁移完成:")
+	default:
+		fmt.Println("✅ 数据已是最新版本")
+	}
+	printMigrationReport(report, true)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/pex"
+)
+
+// neighborsFile 持久化邻居列表的文件名
+const neighborsFile = "neighbors.json"
+
+// loadPersistedNeighbors 读取上次运行保存的邻居列表，文件不存在或损坏时返回空
+func loadPersistedNeighbors(dataDir string) []*neighbor.Neighbor {
+	data, err := os.ReadFile(filepath.Join(dataDir, neighborsFile))
+	if err != nil {
+		return nil
+	}
+	var neighbors []*neighbor.Neighbor
+	if err := json.Unmarshal(data, &neighbors); err != nil {
+		return nil
+	}
+	return neighbors
+}
+
+// savePersistedNeighbors 保存邻居列表，供下次启动时优先重连
+func savePersistedNeighbors(dataDir string, neighbors []*neighbor.Neighbor) error {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(neighbors, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dataDir, neighborsFile), data, 0600)
+}
+
+// neighborPeerAddrs 将邻居地址转换为带 /p2p/ID 后缀的完整 multiaddr
+func neighborPeerAddrs(neighbors []*neighbor.Neighbor) []string {
+	var addrs []string
+	for _, n := range neighbors {
+		if n == nil || n.NodeID == "" {
+			continue
+		}
+		suffix := "/p2p/" + n.NodeID
+		for _, addr := range n.Addresses {
+			if addr == "" {
+				continue
+			}
+			if !strings.HasSuffix(addr, suffix) {
+				addr += suffix
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// pexNeighborCandidate 把节点交换学到的节点转换为候选邻居，评分作为信任分决定提升顺序
+func pexNeighborCandidate(c *pex.Candidate) *neighbor.Neighbor {
+	return &neighbor.Neighbor{
+		NodeID:     c.ID,
+		Type:       neighbor.TypeNormal,
+		Reputation: int64(c.Reputation),
+		TrustScore: c.Score,
+		Addresses:  append([]string(nil), c.Addrs...),
+		PingStatus: neighbor.StatusUnknown,
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/provision"
+	"go.yaml.in/yaml/v2"
+)
+
+func cmdNetgen() {
+	fs := flag.NewFlagSet("netgen", flag.ExitOnError)
+	cfg := provision.DefaultTestnetConfig()
+	fs.IntVar(&cfg.Nodes, "n", cfg.Nodes, "节点数（含 1 个引导节点和 1 个中继节点）")
+	outDir := fs.String("out", "./testnet", "输出目录")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "编排格式: compose 或 systemd")
+	fs.StringVar(&cfg.Image, "image", cfg.Image, "compose 使用的镜像")
+	fs.StringVar(&cfg.Binary, "binary", cfg.Binary, "systemd 单元启动的可执行文件")
+	fs.StringVar(&cfg.Subnet, "subnet", cfg.Subnet, "compose 网络子网")
+	fs.StringVar(&cfg.Namespace, "namespace", "", "网络命名空间（默认: 主网络）")
+	fs.Usage = printNetgenUsage
+	fs.Parse(os.Args[2:])
+
+	nodes, err := generateTestnet(cfg, *outDir, nodeKeyPeerID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成测试网失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ 已在 %s 生成 %d 个节点的测试网\n", *outDir, len(nodes))
+	for _, n := range nodes {
+		fmt.Printf("   %-10s %-10s http=127.0.0.1:%d  %s\n", n.Name, n.Role, n.HostPorts.HTTP, n.PeerID)
+	}
+	if cfg.Format == provision.FormatCompose {
+		fmt.Printf("\n启动: docker build -t %s . && docker compose -f %s up -d\n", cfg.Image, filepath.Join(*outDir, "docker-compose.yaml"))
+	} else {
+		fmt.Printf("\n安装: sudo cp %s/*.service /etc/systemd/system/ && sudo systemctl daemon-reload\n", filepath.Join(*outDir, "systemd"))
+		fmt.Printf("启动: sudo systemctl start 'agentnetwork-*.service'\n")
+	}
+}
+
+func printNetgenUsage() {
+	fmt.Print(`用法: agentnetwork netgen [选项]
+
+生成 N 个节点的本地测试网：每个节点的密钥、清单和数据目录，以及 docker-compose.yaml
+（或 systemd 单元）。第一个节点是引导节点，第二个是中继节点，其余节点以二者为引导节点。
+重复运行时沿用已有的节点密钥，只重新生成配置。
+
+输出目录结构:
+  <out>/<节点名>/keys/node.key                 节点密钥
+  <out>/<节点名>/node.yaml                     节点清单（可修改后用 apply -f 重新应用）
+  <out>/<节点名>/provision/applied.json        已应用的清单（启动时作为参数默认值）
+  <out>/docker-compose.yaml                    -format compose
+  <out>/systemd/agentnetwork-<节点名>.service  -format systemd
+
+选项:
+  -n          节点数 (默认: 5，至少 2)
+  -out        输出目录 (默认: ./testnet)
+  -format     编排格式: compose 或 systemd (默认: compose)
+  -image      compose 使用的镜像 (默认: agentnetwork:dev)
+  -binary     systemd 单元启动的可执行文件 (默认: /usr/local/bin/agentnetwork)
+  -subnet     compose 网络子网 (默认: 172.30.0.0/24)
+  -namespace  网络命名空间 (默认: 主网络)
+
+示例:
+  agentnetwork netgen -n 5
+  cd testnet && docker compose up -d
+  agentnetwork netgen -n 3 -format systemd -out /srv/testnet
+`)
+}
+
+// generateTestnet 生成测试网的密钥、清单和编排文件
+// newKey 读取或生成 keyPath 处的节点密钥，返回节点 ID。
+func generateTestnet(cfg *provision.TestnetConfig, outDir string, newKey func(keyPath string) (string, error)) ([]*provision.TestnetNode, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	peerIDs := make([]string, cfg.Nodes)
+	for i := range peerIDs {
+		keyPath := filepath.Join(root, provision.TestnetNodeName(i), "keys", "node.key")
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+			return nil, err
+		}
+		if peerIDs[i], err = newKey(keyPath); err != nil {
+			return nil, fmt.Errorf("生成 %s 的密钥失败: %w", provision.TestnetNodeName(i), err)
+		}
+	}
+	nodes, err := provision.PlanTestnet(cfg, peerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nodes {
+		dataDir := filepath.Join(root, n.Name)
+		manifest := n.Manifest(cfg.Namespace)
+		if err := provision.SaveApplied(dataDir, manifest); err != nil {
+			return nil, err
+		}
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dataDir, "node.yaml"), data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Format == provision.FormatCompose {
+		return nodes, os.WriteFile(filepath.Join(root, "docker-compose.yaml"), provision.ComposeFile(cfg, nodes), 0644)
+	}
+	unitDir := filepath.Join(root, "systemd")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if err := os.WriteFile(filepath.Join(unitDir, provision.SystemdUnitName(n)), provision.SystemdUnit(cfg, n, root), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
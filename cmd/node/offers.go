@@ -0,0 +1,60 @@
+package main
+
+import (
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// registerTaskOfferAPI 任务邀约队列 API：队列由任务网络收到的其他节点新发布的任务填充，
+// 按当前策略排序并给出评分明细；调整策略后队列立即按新策略重新评分
+func registerTaskOfferAPI(s *httpapi.Server, q *task.OfferQueue) {
+	s.TaskOffersFunc = func(limit int) []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, offer := range q.List(limit) {
+			result = append(result, toMap(offer))
+		}
+		return result
+	}
+	s.TaskOfferScoreFunc = func(taskID string) (map[string]interface{}, error) {
+		score, err := q.Explain(taskID)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(score), nil
+	}
+	s.TaskOfferPolicyFunc = func() map[string]interface{} {
+		return offerPolicyMap(q.GetPolicy())
+	}
+	s.TaskOfferPolicySetFunc = func(req *httpapi.TaskOfferPolicyRequest) (map[string]interface{}, error) {
+		policy := &task.PriorityPolicy{
+			BudgetWeight:           req.BudgetWeight,
+			ReputationWeight:       req.ReputationWeight,
+			DeadlineWeight:         req.DeadlineWeight,
+			CapabilityWeight:       req.CapabilityWeight,
+			ReferenceBudgetPerUnit: req.ReferenceBudgetPerUnit,
+			ReferenceReputation:    req.ReferenceReputation,
+			DeadlineHorizon:        time.Duration(req.DeadlineHorizonSeconds) * time.Second,
+			MinLeadTime:            time.Duration(req.MinLeadTimeSeconds) * time.Second,
+		}
+		if err := q.SetPolicy(policy); err != nil {
+			return nil, err
+		}
+		return offerPolicyMap(q.GetPolicy()), nil
+	}
+}
+
+// offerPolicyMap 策略的 API 表示，时长以秒为单位，与 TaskOfferPolicyRequest 一致
+func offerPolicyMap(p *task.PriorityPolicy) map[string]interface{} {
+	return map[string]interface{}{
+		"budget_weight":             p.BudgetWeight,
+		"reputation_weight":         p.ReputationWeight,
+		"deadline_weight":           p.DeadlineWeight,
+		"capability_weight":         p.CapabilityWeight,
+		"reference_budget_per_unit": p.ReferenceBudgetPerUnit,
+		"reference_reputation":      p.ReferenceReputation,
+		"deadline_horizon_seconds":  int64(p.DeadlineHorizon / time.Second),
+		"min_lead_time_seconds":     int64(p.MinLeadTime / time.Second),
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/multiformats/go-multiaddr"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/dialer"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/node"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/pex"
+)
+
+// pexTransport 基于 libp2p 主机的节点交换传输，拨号候选同样受出站拨号策略约束
+type pexTransport struct {
+	n        *node.Node
+	protocol protocol.ID
+	policy   *dialer.Policy
+	banned   func(peerID string) bool
+}
+
+func (t *pexTransport) ConnectedPeers() []string {
+	peers := t.n.Host().Host().Network().Peers()
+	ids := make([]string, 0, len(peers))
+	for _, id := range peers {
+		if !t.banned(id.String()) {
+			ids = append(ids, id.String())
+		}
+	}
+	return ids
+}
+
+func (t *pexTransport) OpenStream(ctx context.Context, peerID string) (io.ReadWriteCloser, error) {
+	pid, err := peer.Decode(peerID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := t.n.Host().Host().NewStream(ctx, pid, t.protocol)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.SetDeadline(deadline)
+	}
+	return s, nil
+}
+
+func (t *pexTransport) Connect(ctx context.Context, c *pex.Candidate) error {
+	if t.banned(c.ID) {
+		return fmt.Errorf("peer %s is banned", c.ID)
+	}
+	info, err := pexAddrInfo(c)
+	if err != nil {
+		return err
+	}
+	if ok, reason := t.policy.Allow(c.ID); !ok {
+		return fmt.Errorf("dial to %s suppressed: %s", c.ID, reason)
+	}
+	if err := t.n.Host().Host().Connect(ctx, info); err != nil {
+		t.policy.RecordFailure(c.ID)
+		return err
+	}
+	t.policy.RecordSuccess(c.ID)
+	return nil
+}
+
+// pexAddrInfo 解析候选节点的ID和地址，忽略无效地址
+func pexAddrInfo(c *pex.Candidate) (peer.AddrInfo, error) {
+	pid, err := peer.Decode(c.ID)
+	if err != nil {
+		return peer.AddrInfo{}, err
+	}
+	info := peer.AddrInfo{ID: pid}
+	for _, addr := range c.Addrs {
+		if ma, err := multiaddr.NewMultiaddr(addr); err == nil {
+			info.Addrs = append(info.Addrs, ma)
+		}
+	}
+	if len(info.Addrs) == 0 {
+		return peer.AddrInfo{}, fmt.Errorf("no valid addresses for %s", c.ID)
+	}
+	return info, nil
+}
+
+// pexLocalPeers 本节点通告的邻居列表：在线邻居在前，其后为其他已连接节点，
+// 地址取自 peerstore，时延为 libp2p 测得的往返时延均值
+func pexLocalPeers(n *node.Node, nm *neighbor.NeighborManager, banned func(peerID string) bool) func() []pex.PeerRecord {
+	return func() []pex.PeerRecord {
+		h := n.Host().Host()
+		var ids []peer.ID
+		seen := make(map[peer.ID]bool)
+		for _, nb := range nm.GetOnlineNeighbors() {
+			if pid, err := peer.Decode(nb.NodeID); err == nil && !seen[pid] {
+				seen[pid] = true
+				ids = append(ids, pid)
+			}
+		}
+		for _, pid := range h.Network().Peers() {
+			if !seen[pid] {
+				seen[pid] = true
+				ids = append(ids, pid)
+			}
+		}
+
+		records := make([]pex.PeerRecord, 0, len(ids))
+		for _, pid := range ids {
+			if banned(pid.String()) {
+				continue
+			}
+			addrs := h.Peerstore().Addrs(pid)
+			if len(addrs) == 0 {
+				continue
+			}
+			record := pex.PeerRecord{ID: pid.String(), LatencyMs: h.Peerstore().LatencyEWMA(pid).Milliseconds()}
+			for _, addr := range addrs {
+				record.Addrs = append(record.Addrs, addr.String())
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+// startPeerExchange 注册节点交换协议并定期与已连接节点交换签名的邻居列表，
+// 学到的节点按时延和声誉评分后作为候选邻居，连接数不足时直接拨号，不依赖引导节点
+func startPeerExchange(n *node.Node, ns string, nm *neighbor.NeighborManager, policy *dialer.Policy,
+	reputationOf func(nodeID string) float64, banned func(peerID string) bool) (*pex.Exchange, context.CancelFunc) {
+	pexProtocol := protocol.ID(namespace.Protocol(ns, pex.ProtocolID))
+	ex := pex.New(n.Host().ID().String(), pex.DefaultConfig(), signWithNodeKey(n.Identity().PrivKey), verifyNodeSignature)
+	ex.SetLocalPeersFunc(pexLocalPeers(n, nm, banned))
+	ex.SetReputationFunc(reputationOf)
+	ex.SetOnCandidates(func(from string, candidates []*pex.Candidate) {
+		for _, c := range candidates {
+			if !banned(c.ID) {
+				nm.AddCandidate(pexNeighborCandidate(c))
+			}
+		}
+	})
+
+	n.Host().Host().SetStreamHandler(pexProtocol, func(s network.Stream) {
+		defer s.Close()
+		remote := s.Conn().RemotePeer().String()
+		if banned(remote) {
+			s.Reset()
+			return
+		}
+		s.SetDeadline(time.Now().Add(10 * time.Second))
+		if err := ex.HandleStream(s, remote); err != nil {
+			fmt.Printf("⚠️  处理 %s 的邻居列表失败: %v\n", remote, err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go ex.Run(ctx, &pexTransport{n: n, protocol: pexProtocol, policy: policy, banned: banned})
+	return ex, cancel
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reachability"
+)
+
+// reachabilityTopic 可达性证明的广播主题：本节点回拨测试后签发的证明发给全网，其他节点验签后计入汇总
+const reachabilityTopic = "/daan/reachability/1.0.0"
+
+// reachabilityTestInterval 对在线邻居做回拨测试的间隔
+const reachabilityTestInterval = 30 * time.Minute
+
+// relayMinReachability 低于此可达性分数的中继不参与选择（没有证明的中继按 0.5 计）
+const relayMinReachability = 0.2
+
+// startReachabilityGossip 接收其他节点广播的可达性证明，只保存证明人本人发布的；本节点签发的证明随后广播
+func startReachabilityGossip(reach *reachability.Service, self string, transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var a reachability.Attestation
+		return json.Unmarshal(data, &a) == nil && a.Subject != "" && a.Attester != ""
+	}
+	err := transport.Subscribe(reachabilityTopic, validate, func(data []byte, from string) {
+		var a reachability.Attestation
+		if json.Unmarshal(data, &a) != nil || a.Attester != from || from == self {
+			return
+		}
+		// 过期的证明是正常的传播延迟，不提示
+		if err := reach.Add(&a); err != nil && !errors.Is(err, reachability.ErrStaleAttestation) {
+			fmt.Printf("⚠️  收到 %s 的可达性证明无效: %v\n", from, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	reach.SetOnAttested(func(a *reachability.Attestation) {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return
+		}
+		if err := transport.Publish(reachabilityTopic, data); err != nil {
+			fmt.Printf("⚠️  可达性证明广播失败: %v\n", err)
+		}
+	})
+	return nil
+}
+
+// startReachabilityTests 定期对在线邻居做回拨测试并清理过期证明，返回停止函数
+func startReachabilityTests(reach *reachability.Service, neighbors *neighbor.NeighborManager) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go runReachabilityTests(ctx, reach, neighbors, reachabilityTestInterval)
+	return cancel
+}
+
+func runReachabilityTests(ctx context.Context, reach *reachability.Service, neighbors *neighbor.NeighborManager, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, nb := range neighbors.GetOnlineNeighbors() {
+			if ctx.Err() != nil {
+				return
+			}
+			reach.Test(ctx, nb.NodeID)
+		}
+		reach.Prune()
+	}
+}
+
+// rankedRelays 按公共可达性分数重排中继候选，剔除分数过低的
+func rankedRelays(reach *reachability.Service, relays func(exclude ...string) []string) func(exclude ...string) []string {
+	return func(exclude ...string) []string {
+		return reach.RankRelays(relays(exclude...), relayMinReachability)
+	}
+}
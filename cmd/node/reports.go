@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/report"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+)
+
+// reportMailSelf -report-mail-to 取该值时报告投递到本节点自己的收件箱
+const reportMailSelf = "self"
+
+// reportSources 活动报告的数据来源
+type reportSources struct {
+	tasks      *task.TaskManager
+	tokens     *token.Ledger
+	reputation *reputation.Manager
+	disputes   *dispute.DisputeManager
+	self       string
+}
+
+// unixTime 秒级时间戳转为 UTC 时间，0 为零值
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// input 收集与本节点有关的全部记录，按周期过滤由 report.Build 完成
+func (s reportSources) input() *report.Input {
+	in := &report.Input{}
+
+	for _, t := range s.tasks.ListTasks("", 0) {
+		v, _ := s.tasks.GetVerification(t.ID)
+		if v != nil && v.Auditor == s.self && v.Decided {
+			in.Audits = append(in.Audits, report.AuditRecord{TaskID: t.ID, Passed: v.Passed, Reason: v.Reason, Time: unixTime(v.DecidedAt)})
+		}
+		if t.ExecutorID != s.self {
+			continue
+		}
+		switch t.Status {
+		case task.StatusVerified, task.StatusSettled, task.StatusCompleted:
+		default:
+			continue
+		}
+		// 完成时间依次取验收判定、委托方确认、交付时间，都没有时取创建时间
+		done := t.CreatedAt
+		if v != nil && v.DecidedAt > 0 {
+			done = v.DecidedAt
+		} else if p, err := s.tasks.GetDeliveryProof(t.ID); err == nil {
+			if p.ReceiveTime > 0 {
+				done = p.ReceiveTime
+			} else if p.DeliveryTime > 0 {
+				done = p.DeliveryTime
+			}
+		}
+		in.Tasks = append(in.Tasks, report.TaskRecord{ID: t.ID, Title: t.Title, Status: string(t.Status), Reward: t.Reward, Time: unixTime(done)})
+	}
+
+	if s.tokens != nil {
+		in.TokenBalance = s.tokens.GetAccount(s.self).Balance
+		for _, e := range s.tokens.History(s.self, 0) {
+			rec := report.TokenRecord{Type: string(e.Type), Amount: e.Amount, Reference: e.Reference, Time: e.Timestamp}
+			switch {
+			case e.Type == token.EntryTransfer && e.From == s.self:
+				rec.Amount = -e.Amount
+				rec.Counterparty = e.To
+			case e.Type == token.EntryTransfer:
+				rec.Counterparty = e.From
+			case e.Type == token.EntrySlash:
+				rec.Amount = -e.Amount
+			}
+			in.Tokens = append(in.Tokens, rec)
+		}
+	}
+
+	if s.reputation != nil {
+		in.CurrentReputation = s.reputation.GetReputation(s.self)
+		for _, h := range s.reputation.History(s.self, 0) {
+			in.Reputation = append(in.Reputation, report.ReputationPoint{Time: h.Timestamp, Value: h.Value, Delta: h.Delta, Reason: h.Reason})
+		}
+	}
+
+	if s.disputes != nil {
+		addDispute := func(d *dispute.Dispute, role string) {
+			in.Disputes = append(in.Disputes, report.DisputeRecord{
+				ID: d.ID, TaskID: d.TaskID, Role: role, Status: string(d.Status), Amount: d.Amount,
+				CreatedAt: unixTime(d.CreatedAt), ResolvedAt: unixTime(d.ResolvedAt),
+			})
+		}
+		for _, d := range s.disputes.GetDisputesByNode(s.self) {
+			if d.ComplainantID == s.self {
+				addDispute(d, report.DisputeComplainant)
+			} else {
+				addDispute(d, report.DisputeDefendant)
+			}
+		}
+		// 仲裁员不在按节点的索引中，逐个状态查找
+		for _, status := range []dispute.DisputeStatus{dispute.DisputeArbitration, dispute.DisputeResolved, dispute.DisputeDismissed, dispute.DisputeExpired} {
+			for _, d := range s.disputes.GetDisputesByStatus(status) {
+				for _, a := range d.Arbitrators {
+					if a == s.self && d.ComplainantID != s.self && d.DefendantID != s.self {
+						addDispute(d, report.DisputeArbitrator)
+						break
+					}
+				}
+			}
+		}
+	}
+	return in
+}
+
+// reportMailer 把定期报告的文本摘要发到运营者邮箱：self 为本节点收件箱，否则为目标节点 ID
+func reportMailer(mb *mailbox.Mailbox, to string) report.DeliverFunc {
+	return func(r *report.Report) error {
+		subject := fmt.Sprintf("Activity report %s - %s", r.PeriodStart.Format("2006-01-02"), r.PeriodEnd.Format("2006-01-02"))
+		body := []byte(report.RenderText(r) + "\nDownload the full report (HTML/PDF/JSON) from the admin console: " + r.ID + "\n")
+		if to == reportMailSelf {
+			_, err := mb.DeliverToSelf(subject, body)
+			return err
+		}
+		_, err := mb.SendMessage(to, subject, body, true)
+		return err
+	}
+}
+
+// startActivityReports 打开数据目录中的报告存储，每小时检查并补上一周的周报
+// mailTo 非空时周报的摘要发到邮箱；邮箱未启动时只保存报告。
+func startActivityReports(dataDir string, src reportSources, mb *mailbox.Mailbox, mailTo string) (*report.Generator, context.CancelFunc, error) {
+	store, err := report.NewStore(filepath.Join(dataDir, "reports"))
+	if err != nil {
+		return nil, nil, err
+	}
+	gen := report.NewGenerator(src.self, store, src.input)
+	if mailTo != "" {
+		if mb != nil {
+			gen.SetDeliverFunc(reportMailer(mb, mailTo))
+		} else {
+			fmt.Fprintln(os.Stderr, "⚠️  邮箱不可用，活动报告不会发送到邮箱")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go gen.Run(ctx, time.Hour)
+	return gen, cancel, nil
+}
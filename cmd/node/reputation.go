@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+)
+
+// reputationSyncInterval 向在线邻居推送声誉表的间隔
+const reputationSyncInterval = time.Minute
+
+// reputationService 把声誉管理器适配为 HTTP API 的声誉服务
+type reputationService struct {
+	m     *reputation.Manager
+	views *reputation.DeltaSync // 邻居推送的声誉表，为 nil 时不返回邻居视图
+}
+
+func (s reputationService) GetReputation(nodeID string) float64 {
+	return s.m.GetReputation(nodeID)
+}
+
+func (s reputationService) AdjustReputation(nodeID string, delta float64, reason string) (float64, error) {
+	if reason == "" {
+		reason = "api"
+	}
+	return s.m.Adjust(nodeID, delta, reason)
+}
+
+func (s reputationService) ReputationRanking(limit int) []map[string]interface{} {
+	scores := s.m.Ranking(limit)
+	result := make([]map[string]interface{}, 0, len(scores))
+	for _, score := range scores {
+		result = append(result, map[string]interface{}{
+			"rank":        score.Rank,
+			"node_id":     score.NodeID,
+			"reputation":  score.Score,
+			"raw":         score.Raw,
+			"highest":     score.Highest,
+			"lowest":      score.Lowest,
+			"last_active": score.LastActive.Format(time.RFC3339),
+		})
+	}
+	return result
+}
+
+func (s reputationService) ReputationHistory(nodeID string, limit int) []map[string]interface{} {
+	entries := s.m.History(nodeID, limit)
+	result := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, map[string]interface{}{
+			"timestamp":  e.Timestamp.Format(time.RFC3339),
+			"delta":      e.Delta,
+			"reputation": e.Value,
+			"reason":     e.Reason,
+		})
+	}
+	return result
+}
+
+func (s reputationService) ReputationDetail(nodeID string) map[string]interface{} {
+	result := map[string]interface{}{}
+	if d, ok := s.m.Detail(nodeID); ok {
+		result["raw"] = d.Raw
+		result["damped"] = d.Damped
+		result["damping"] = d.Damping
+		result["window_change"] = d.WindowChange
+		result["updated_at"] = d.UpdatedAt.Format(time.RFC3339)
+		if !d.WindowStart.IsZero() {
+			result["window_start"] = d.WindowStart.Format(time.RFC3339)
+		}
+	}
+	if s.views != nil {
+		if views := s.views.Views(nodeID); len(views) > 0 {
+			result["neighbor_views"] = views
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// attestReputation 用本地声誉表为 nodeID 签发声誉证明，供邻居查询
+func attestReputation(m *reputation.Manager, self string, sign func(data []byte) ([]byte, error)) func(nodeID string) (*reputation.Attestation, error) {
+	return func(nodeID string) (*reputation.Attestation, error) {
+		a := &reputation.Attestation{NodeID: nodeID, Score: m.GetReputation(nodeID), Attester: self, IssuedAt: time.Now()}
+		sig, err := sign(a.SignData())
+		if err != nil {
+			return nil, err
+		}
+		a.Signature = sig
+		return a, nil
+	}
+}
+
+// fetchAttestation 依次向 attesters 列出的邻居查询声誉证明，跳过被查询节点本身，返回第一份取回的证明
+func fetchAttestation(attesters func() []string, open func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error)) reputation.AttestationFetcher {
+	return func(ctx context.Context, nodeID string) (*reputation.Attestation, error) {
+		lastErr := errors.New("no neighbor available to attest reputation")
+		for _, attester := range attesters() {
+			if attester == nodeID {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			rw, err := open(ctx, attester)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			a, err := reputation.RequestAttestation(rw, nodeID)
+			rw.Close()
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return a, nil
+		}
+		return nil, lastErr
+	}
+}
+
+// verifyAttestation 只采信邻居签发的证明，签名按证明人节点ID中的公钥验证
+func verifyAttestation(trusted func(nodeID string) bool, verify func(signer string, data, signature []byte) (bool, error)) reputation.AttestationVerifier {
+	return func(a *reputation.Attestation) error {
+		if !trusted(a.Attester) {
+			return reputation.ErrInvalidAttestation
+		}
+		if ok, err := verify(a.Attester, a.SignData(), a.Signature); err != nil || !ok {
+			return reputation.ErrInvalidAttestation
+		}
+		return nil
+	}
+}
+
+// reputationLookup 其他节点的声誉：邻居签发的证明（经缓存）与本地记录、邻居视图合并返回，
+// 取不到证明时只返回本地部分并以 last_error 说明原因
+func reputationLookup(cache *reputation.Cache, local reputationService) func(nodeID string) (map[string]interface{}, error) {
+	return func(nodeID string) (map[string]interface{}, error) {
+		result := map[string]interface{}{
+			"node_id":    nodeID,
+			"reputation": local.GetReputation(nodeID),
+		}
+		// 同步查询的超时由缓存的 FetchTimeout 控制
+		if score, err := cache.Get(context.Background(), nodeID); err != nil {
+			result["last_error"] = err.Error()
+		} else {
+			for k, v := range toMap(score) {
+				result[k] = v
+			}
+		}
+		for k, v := range local.ReputationDetail(nodeID) {
+			result[k] = v
+		}
+		return result, nil
+	}
+}
+
+// reputationScores 本地声誉表：节点ID -> 生效分数
+func reputationScores(m *reputation.Manager) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, s := range m.Ranking(0) {
+		scores[s.NodeID] = s.Score
+	}
+	return scores
+}
+
+// startReputationSync 定期把本地声誉表以增量推送给在线邻居，返回停止函数
+func startReputationSync(views *reputation.DeltaSync, m *reputation.Manager, neighbors *neighbor.NeighborManager, open func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go runReputationSync(ctx, views, m, neighbors, open, reputationSyncInterval)
+	return cancel
+}
+
+func runReputationSync(ctx context.Context, views *reputation.DeltaSync, m *reputation.Manager, neighbors *neighbor.NeighborManager, open func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		views.Update(reputationScores(m))
+		for _, nb := range neighbors.GetOnlineNeighbors() {
+			if ctx.Err() != nil {
+				return
+			}
+			views.Push(nb.NodeID, func() (io.ReadWriteCloser, error) {
+				return open(ctx, nb.NodeID)
+			})
+		}
+	}
+}
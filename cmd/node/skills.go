@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// skillsFile 持久化本节点宣告的技能的文件名
+const skillsFile = "discovery_skills.json"
+
+// loadAnnouncedSkills 读取上次运行宣告的技能，启动后重新宣告；文件不存在或损坏时返回空
+func loadAnnouncedSkills(dataDir string) []string {
+	data, err := os.ReadFile(filepath.Join(dataDir, skillsFile))
+	if err != nil {
+		return nil
+	}
+	var skills []string
+	if err := json.Unmarshal(data, &skills); err != nil {
+		return nil
+	}
+	return skills
+}
+
+// saveAnnouncedSkills 保存当前宣告的技能
+func saveAnnouncedSkills(dataDir string, skills []string) error {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return err
+	}
+	if skills == nil {
+		skills = []string{}
+	}
+	data, err := json.MarshalIndent(skills, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dataDir, skillsFile), data, 0600)
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/daemon"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+)
+
+// 冒烟测试：部署完成后一条命令验收运行中的节点
+// 以临时身份作为第二个节点接入，依次验证 API、P2P 连接、邮箱、留言板和任务流程，
+// 逐步报告通过/失败。前置步骤失败时，依赖它的步骤标记为跳过。
+
+// 步骤结果
+const (
+	smokePass = "pass"
+	smokeFail = "fail"
+	smokeSkip = "skip"
+)
+
+// smokePeer 冒烟测试使用的进程内临时节点
+type smokePeer interface {
+	ID() string
+	Connect(ctx context.Context, addrs []string) error
+	// ListenMail 在命名空间内的邮件投递协议上收信，收到的邮件签发送达回执后送入返回的通道
+	ListenMail(namespace string) (<-chan *mailbox.Message, error)
+	Close() error
+}
+
+// smokeStep 冒烟测试步骤
+type smokeStep struct {
+	name     string
+	requires []string // 前置步骤，任一未通过则跳过
+	run      func(ctx context.Context) (string, error)
+}
+
+// smokeResult 步骤结果
+type smokeResult struct {
+	Step     string `json:"step"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// runSmokeSteps 依次执行步骤，每步单独计时和超时
+func runSmokeSteps(ctx context.Context, steps []smokeStep, timeout time.Duration) []smokeResult {
+	passed := make(map[string]bool)
+	results := make([]smokeResult, 0, len(steps))
+	for _, step := range steps {
+		result := smokeResult{Step: step.name, Status: smokeSkip, Duration: "0s"}
+		for _, dep := range step.requires {
+			if !passed[dep] {
+				result.Detail = "前置步骤未通过: " + dep
+				break
+			}
+		}
+		if result.Detail == "" {
+			stepCtx, cancel := context.WithTimeout(ctx, timeout)
+			start := time.Now()
+			detail, err := step.run(stepCtx)
+			cancel()
+			result.Duration = time.Since(start).Round(time.Millisecond).String()
+			if err != nil {
+				result.Status = smokeFail
+				result.Detail = err.Error()
+			} else {
+				result.Status = smokePass
+				result.Detail = detail
+				passed[step.name] = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// smokePassed 所有步骤是否都通过
+func smokePassed(results []smokeResult) bool {
+	for _, r := range results {
+		if r.Status != smokePass {
+			return false
+		}
+	}
+	return true
+}
+
+// smokeAPI 节点 HTTP API 客户端
+type smokeAPI struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// call 调用 API 并把响应中的 data 解析到 out
+func (a *smokeAPI) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("X-API-Token", a.token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return fmt.Errorf("%s %s: HTTP状态码 %d，响应无法解析", method, path, resp.StatusCode)
+	}
+	if !envelope.Success {
+		return fmt.Errorf("%s %s: HTTP状态码 %d %s", method, path, resp.StatusCode, envelope.Error)
+	}
+	if out != nil && len(envelope.Data) > 0 {
+		return json.Unmarshal(envelope.Data, out)
+	}
+	return nil
+}
+
+// smokeSuite 一次冒烟测试的上下文
+type smokeSuite struct {
+	api       *smokeAPI
+	peer      smokePeer
+	nodeAddrs []string // 被测节点的 P2P 地址（含 /p2p/ 节点ID）
+	nonce     string   // 本次测试写入数据的唯一标记
+	nodeID    string
+	namespace string // 被测节点的网络命名空间，临时节点按它注册邮件投递协议
+}
+
+func newSmokeSuite(api *smokeAPI, peer smokePeer, nodeAddrs []string) *smokeSuite {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	return &smokeSuite{api: api, peer: peer, nodeAddrs: nodeAddrs, nonce: hex.EncodeToString(buf)}
+}
+
+// steps 返回完整的测试步骤
+func (s *smokeSuite) steps() []smokeStep {
+	return []smokeStep{
+		{name: "identity", run: s.checkIdentity},
+		{name: "api", run: s.checkAPI},
+		{name: "connect", requires: []string{"identity", "api"}, run: s.checkConnect},
+		{name: "mailbox", requires: []string{"connect"}, run: s.checkMailbox},
+		{name: "bulletin", requires: []string{"api"}, run: s.checkBulletin},
+		{name: "task", requires: []string{"api"}, run: s.checkTask},
+	}
+}
+
+func (s *smokeSuite) checkIdentity(ctx context.Context) (string, error) {
+	if s.peer == nil || s.peer.ID() == "" {
+		return "", fmt.Errorf("临时身份未创建")
+	}
+	return "临时节点 " + s.peer.ID(), nil
+}
+
+func (s *smokeSuite) checkAPI(ctx context.Context) (string, error) {
+	var health struct {
+		NodeID string `json:"node_id"`
+	}
+	if err := s.api.call(ctx, http.MethodGet, "/health", nil, &health); err != nil {
+		return "", err
+	}
+	if health.NodeID == "" {
+		return "", fmt.Errorf("健康检查未返回节点ID")
+	}
+	s.nodeID = health.NodeID
+	// 健康检查免认证，再访问一个需要 Token 的端点确认令牌有效
+	var info struct {
+		Namespace string `json:"namespace"`
+	}
+	if err := s.api.call(ctx, http.MethodGet, "/api/v1/node/info", nil, &info); err != nil {
+		return "", err
+	}
+	s.namespace = info.Namespace
+	return "节点 " + health.NodeID, nil
+}
+
+// checkConnect 临时节点拨号被测节点，并确认对方的对等节点列表中出现了自己
+func (s *smokeSuite) checkConnect(ctx context.Context) (string, error) {
+	if len(s.nodeAddrs) == 0 {
+		return "", fmt.Errorf("未知节点 P2P 地址，请用 -peer 指定")
+	}
+	if err := s.peer.Connect(ctx, s.nodeAddrs); err != nil {
+		return "", fmt.Errorf("连接节点失败: %w", err)
+	}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var peers struct {
+			Peers []struct {
+				NodeID string `json:"node_id"`
+			} `json:"peers"`
+		}
+		if err := s.api.call(ctx, http.MethodGet, "/api/v1/node/peers", nil, &peers); err != nil {
+			return "", err
+		}
+		for _, p := range peers.Peers {
+			if p.NodeID == s.peer.ID() {
+				return fmt.Sprintf("已连接，节点共有 %d 个对等节点", len(peers.Peers)), nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("节点对等列表中未出现临时节点")
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkMailbox 节点给临时节点发信：临时节点须经投递协议收到同一封邮件，
+// 节点发件箱中的邮件须凭临时节点签发的回执标记为已送达
+func (s *smokeSuite) checkMailbox(ctx context.Context) (string, error) {
+	inbox, err := s.peer.ListenMail(s.namespace)
+	if err != nil {
+		return "", fmt.Errorf("临时节点无法收信: %w", err)
+	}
+
+	content := "smoke " + s.nonce
+	var sent struct {
+		MessageID string `json:"message_id"`
+	}
+	// 临时节点是陌生节点，跳过收件人警告和撤回窗口，立即投递
+	err = s.api.call(ctx, http.MethodPost, "/api/v1/mailbox/send", map[string]interface{}{
+		"to":        s.peer.ID(),
+		"subject":   "smoke test",
+		"content":   content,
+		"immediate": true,
+		"confirmed": true,
+	}, &sent)
+	if err != nil {
+		return "", err
+	}
+
+	for received := false; !received; {
+		select {
+		case msg := <-inbox:
+			if msg.ID != sent.MessageID {
+				continue
+			}
+			if msg.Sender != s.nodeID || string(msg.Content) != content {
+				return "", fmt.Errorf("临时节点收到的邮件与发送的不一致")
+			}
+			received = true
+		case <-ctx.Done():
+			return "", fmt.Errorf("临时节点未收到邮件 %s", sent.MessageID)
+		}
+	}
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var msg struct {
+			Status string `json:"status"`
+		}
+		if err := s.api.call(ctx, http.MethodGet, "/api/v1/mailbox/read/"+url.PathEscape(sent.MessageID), nil, &msg); err != nil {
+			return "", err
+		}
+		if msg.Status == "delivered" || msg.Status == "read" {
+			return "消息 " + sent.MessageID + " 已送达", nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("邮件已收到，但节点未确认送达（状态 %s）", msg.Status)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkBulletin 发布留言并按 ID 取回核对
+func (s *smokeSuite) checkBulletin(ctx context.Context) (string, error) {
+	content := "smoke " + s.nonce
+	var published struct {
+		MessageID string `json:"message_id"`
+	}
+	err := s.api.call(ctx, http.MethodPost, "/api/v1/bulletin/publish", map[string]interface{}{
+		"topic":   "smoke",
+		"content": content,
+		"ttl":     300,
+	}, &published)
+	if err != nil {
+		return "", err
+	}
+
+	var msg struct {
+		Author  string `json:"author"`
+		Content string `json:"content"`
+	}
+	if err := s.api.call(ctx, http.MethodGet, "/api/v1/bulletin/message/"+url.PathEscape(published.MessageID), nil, &msg); err != nil {
+		return "", err
+	}
+	if msg.Content != content || (s.nodeID != "" && msg.Author != s.nodeID) {
+		return "", fmt.Errorf("取回的留言与发布的不一致")
+	}
+	return "留言 " + published.MessageID, nil
+}
+
+// checkTask 走一遍最小任务流程：发布（哈希验收）、接受、提交，提交的结果与期望摘要一致，任务须自动通过验收
+func (s *smokeSuite) checkTask(ctx context.Context) (string, error) {
+	result := "smoke " + s.nonce
+	sum := sha256.Sum256([]byte(result))
+	var created struct {
+		TaskID string `json:"task_id"`
+	}
+	err := s.api.call(ctx, http.MethodPost, "/api/v1/task/create", map[string]interface{}{
+		"task_id":       "smoke-" + s.nonce,
+		"type":          "smoke",
+		"description":   "smoke test micro task",
+		"verification":  "hash",
+		"expected_hash": hex.EncodeToString(sum[:]),
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	if created.TaskID == "" {
+		return "", fmt.Errorf("创建任务未返回任务ID")
+	}
+
+	if err := s.api.call(ctx, http.MethodPost, "/api/v1/task/accept", map[string]interface{}{
+		"task_id": created.TaskID,
+	}, nil); err != nil {
+		return "", err
+	}
+	if err := s.api.call(ctx, http.MethodPost, "/api/v1/task/submit", map[string]interface{}{
+		"task_id": created.TaskID,
+		"result":  result,
+	}, nil); err != nil {
+		return "", err
+	}
+
+	var status struct {
+		TaskID        string `json:"task_id"`
+		Status        string `json:"status"`
+		FailureReason string `json:"failure_reason"`
+	}
+	if err := s.api.call(ctx, http.MethodGet, "/api/v1/task/status?task_id="+url.QueryEscape(created.TaskID), nil, &status); err != nil {
+		return "", err
+	}
+	if status.TaskID != created.TaskID {
+		return "", fmt.Errorf("任务状态查询返回了其他任务")
+	}
+	if status.Status != "verified" {
+		if status.FailureReason != "" {
+			return "", fmt.Errorf("任务状态为 %s，期望 verified: %s", status.Status, status.FailureReason)
+		}
+		return "", fmt.Errorf("任务状态为 %s，期望 verified", status.Status)
+	}
+	return fmt.Sprintf("任务 %s (%s)", created.TaskID, status.Status), nil
+}
+
+func cmdSmoke() {
+	fs := flag.NewFlagSet("smoke", flag.ExitOnError)
+	dataDir := fs.String("data", "./data", "数据目录（读取访问令牌和节点地址）")
+	httpAddr := fs.String("http", ":18345", "HTTP服务地址")
+	peerAddrs := fs.String("peer", "", "节点 P2P 地址（逗号分隔，默认读取节点状态）")
+	timeout := fs.Duration("timeout", 10*time.Second, "每个步骤的超时时间")
+	jsonOutput := fs.Bool("json", false, "JSON格式输出")
+	fs.Parse(os.Args[2:])
+
+	var addrs []string
+	if *peerAddrs != "" {
+		addrs = strings.Split(*peerAddrs, ",")
+	} else {
+		addrs = daemon.New(&daemon.Config{DataDir: *dataDir}).Status().ListenAddrs
+	}
+
+	host := *httpAddr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	api := &smokeAPI{
+		baseURL: "http://" + host,
+		token:   loadOrGenerateToken(*dataDir),
+		client:  &http.Client{Timeout: *timeout},
+	}
+
+	peer, err := newSmokePeer()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建临时节点失败: %v\n", err)
+		os.Exit(1)
+	}
+	results := runSmokeSteps(context.Background(), newSmokeSuite(api, peer, addrs).steps(), *timeout)
+	peer.Close()
+	ok := smokePassed(results)
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(map[string]interface{}{"passed": ok, "steps": results}, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		icons := map[string]string{smokePass: "✅", smokeFail: "❌", smokeSkip: "⏭️ "}
+		for _, r := range results {
+			fmt.Printf("%s %-9s %-7s %s\n", icons[r.Status], r.Step, r.Duration, r.Detail)
+		}
+		if ok {
+			fmt.Println("\n冒烟测试通过")
+		} else {
+			fmt.Println("\n冒烟测试未通过")
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
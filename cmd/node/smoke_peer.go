@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/multiformats/go-multiaddr"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/host"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/identity"
+)
+
+// libp2pSmokePeer 以临时身份运行的进程内 libp2p 节点（不落盘、不加入 DHT）
+type libp2pSmokePeer struct {
+	host     *host.Host
+	identity *identity.Identity
+}
+
+func newSmokePeer() (smokePeer, error) {
+	id, err := identity.NewIdentity()
+	if err != nil {
+		return nil, err
+	}
+	h, err := host.New(&host.Config{
+		Identity:    id,
+		ListenAddrs: []string{"/ip4/127.0.0.1/tcp/0"},
+		Role:        host.RoleNormal,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &libp2pSmokePeer{host: h, identity: id}, nil
+}
+
+func (p *libp2pSmokePeer) ID() string {
+	return p.host.ID().String()
+}
+
+// Connect 拨号被测节点，地址需包含 /p2p/ 节点ID
+func (p *libp2pSmokePeer) Connect(ctx context.Context, addrs []string) error {
+	mas := make([]multiaddr.Multiaddr, 0, len(addrs))
+	for _, addr := range addrs {
+		ma, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return fmt.Errorf("解析地址失败 %s: %w", addr, err)
+		}
+		mas = append(mas, ma)
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(mas...)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := p.host.Connect(ctx, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListenMail 用内存邮箱处理投递协议：收下邮件、以临时身份签发回执，再把邮件送入通道
+func (p *libp2pSmokePeer) ListenMail(ns string) (<-chan *mailbox.Message, error) {
+	config := mailbox.DefaultConfig(p.ID())
+	config.DataDir = ""
+	mb, err := mailbox.NewMailbox(config)
+	if err != nil {
+		return nil, err
+	}
+	mb.SetSignFunc(p.identity.PrivKey.Sign)
+	mb.SetVerifyFunc(verifyPeerSignature)
+
+	inbox := make(chan *mailbox.Message, 16)
+	p.host.Host().SetStreamHandler(protocol.ID(namespace.Protocol(ns, mailbox.DeliverProtocol)), func(s network.Stream) {
+		defer s.Close()
+		s.SetDeadline(time.Now().Add(mailDeliverTimeout))
+		mailbox.ServeEnvelope(s, func(env *mailbox.Envelope) *mailbox.DeliveryResult {
+			result := mb.HandleEnvelope(env, s.Conn().RemotePeer().String())
+			for _, msg := range env.Messages {
+				if msg == nil {
+					continue
+				}
+				select {
+				case inbox <- msg:
+				default:
+				}
+			}
+			return result
+		})
+	})
+	return inbox, nil
+}
+
+func (p *libp2pSmokePeer) Close() error {
+	return p.host.Stop()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/snapshot"
+)
+
+// newSnapshotReader 登记参与联合查询的争议、托管和抵押存储
+func newSnapshotReader(disputes *dispute.DisputeManager, escrows *escrow.EscrowManager, collaterals *collateral.CollateralManager) *snapshot.Reader {
+	r := snapshot.NewReader()
+	r.AddSource("dispute", disputes)
+	r.AddSource("escrow", escrows)
+	r.AddSource("collateral", collaterals)
+	return r
+}
+
+// snapshotQuery 把争议、托管和抵押的联合查询适配为 HTTP API 的快照查询服务
+type snapshotQuery struct {
+	reader      *snapshot.Reader
+	disputes    *dispute.DisputeManager
+	escrows     *escrow.EscrowManager
+	collaterals *collateral.CollateralManager
+	self        string
+}
+
+func (q snapshotQuery) DisputeCase(disputeID string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	snap, err := q.reader.Read(func() error {
+		d, err := q.disputes.GetDispute(disputeID)
+		if err != nil {
+			return disputeAPIError(err)
+		}
+		result = map[string]interface{}{
+			"dispute": toMap(d),
+			"collateral": map[string]interface{}{
+				"complainant": q.accounts(d.ComplainantID),
+				"defendant":   q.accounts(d.DefendantID),
+			},
+		}
+		if d.EscrowID != "" {
+			e, err := q.escrows.GetEscrow(d.EscrowID)
+			if err != nil {
+				return escrowAPIError(err)
+			}
+			result["escrow"] = toMap(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result["snapshot"] = snap
+	return result, nil
+}
+
+func (q snapshotQuery) NodeExposure(nodeID string) (map[string]interface{}, error) {
+	if nodeID == "" {
+		nodeID = q.self
+	}
+	var result map[string]interface{}
+	snap, err := q.reader.Read(func() error {
+		disputes := q.disputes.GetDisputesByNode(nodeID)
+		open := 0
+		disputeMaps := make([]map[string]interface{}, 0, len(disputes))
+		for _, d := range disputes {
+			switch d.Status {
+			case dispute.DisputeResolved, dispute.DisputeDismissed, dispute.DisputeExpired:
+			default:
+				open++
+			}
+			disputeMaps = append(disputeMaps, toMap(d))
+		}
+
+		escrows := q.escrows.GetEscrowsByNode(nodeID)
+		escrowMaps := make([]map[string]interface{}, 0, len(escrows))
+		for _, e := range escrows {
+			escrowMaps = append(escrowMaps, toMap(e))
+		}
+
+		accounts := q.collaterals.ListAccounts(nodeID)
+		accountMaps := make([]map[string]interface{}, 0, len(accounts))
+		var balance, locked float64
+		for _, a := range accounts {
+			balance += a.Balance
+			locked += a.Locked
+			accountMaps = append(accountMaps, accountMap(a))
+		}
+
+		result = map[string]interface{}{
+			"node_id":            nodeID,
+			"disputes":           disputeMaps,
+			"open_disputes":      open,
+			"escrows":            escrowMaps,
+			"escrow_locked":      q.escrows.GetLockedAmount(nodeID),
+			"collaterals":        accountMaps,
+			"collateral_balance": balance,
+			"collateral_locked":  locked,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result["snapshot"] = snap
+	return result, nil
+}
+
+func (q snapshotQuery) accounts(nodeID string) []map[string]interface{} {
+	accounts := q.collaterals.ListAccounts(nodeID)
+	result := make([]map[string]interface{}, 0, len(accounts))
+	for _, a := range accounts {
+		result = append(result, accountMap(a))
+	}
+	return result
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+)
+
+// openStorageKeyring 打开本地存储的静态加密密钥环
+// 未指定存储密钥文件时主密钥由节点私钥派生；指定后改用存储密钥，
+// 并允许用节点私钥解锁此前创建的密钥环，完成一次性迁移。
+func openStorageKeyring(dataDir, storageKeyPath string, nodeSecret []byte) (*crypto.StorageKeyring, error) {
+	path := filepath.Join(dataDir, "keys", "storage_keys.json")
+	nodeMaster := crypto.DeriveStorageKey(nodeSecret, "node")
+	if storageKeyPath == "" {
+		return crypto.OpenStorageKeyring(path, nodeMaster)
+	}
+
+	secret, err := os.ReadFile(storageKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取存储密钥失败: %w", err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) < 16 {
+		return nil, fmt.Errorf("存储密钥过短: 至少 16 字节")
+	}
+	return crypto.OpenStorageKeyring(path, crypto.DeriveStorageKey(secret, "storage"), nodeMaster)
+}
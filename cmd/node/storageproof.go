@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+const (
+	storageChallengeInterval = 10 * time.Minute // 委托方挑战存储方的间隔，长于挑战超时，上一轮未响应的挑战在下一轮判定失败
+	storageChallengeTimeout  = 30 * time.Second // 单次挑战的网络超时
+)
+
+// errOutsideStorage 登记的文件不在存储目录内
+var errOutsideStorage = errors.New("path is outside the storage directory")
+
+// storageHolding 存储方为任务持有的数据文件
+type storageHolding struct {
+	TaskID     string `json:"task_id"`
+	Requester  string `json:"requester"` // 只响应委托方的挑战，避免向其他节点泄露数据分块
+	Path       string `json:"path"`
+	MerkleRoot string `json:"merkle_root"`
+	ChunkCount int    `json:"chunk_count"`
+}
+
+// storageHoldings 本节点作为存储方登记的持有文件，保存在 <data>/storage_holdings.json。
+// 存储任务的文件只能位于 <data>/storage 内，挑战响应会把文件分块发给委托方。
+type storageHoldings struct {
+	mu      sync.Mutex
+	path    string
+	dir     string
+	entries map[string]*storageHolding
+}
+
+// loadStorageHoldings 读取已登记的持有文件，文件不存在或损坏时从空表开始
+func loadStorageHoldings(dataDir string) *storageHoldings {
+	h := &storageHoldings{
+		path:    filepath.Join(dataDir, "storage_holdings.json"),
+		dir:     filepath.Join(dataDir, "storage"),
+		entries: make(map[string]*storageHolding),
+	}
+	os.MkdirAll(h.dir, 0700)
+	if data, err := os.ReadFile(h.path); err == nil {
+		json.Unmarshal(data, &h.entries)
+	}
+	return h
+}
+
+// resolve 把文件路径限制在存储目录内：相对路径相对于存储目录，解析符号链接后仍须位于其下
+func (h *storageHoldings) resolve(path string) (string, error) {
+	root, err := filepath.Abs(h.dir)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", errOutsideStorage, path)
+	}
+	return resolved, nil
+}
+
+// hold 以本节点为执行方登记任务的数据文件，返回文件的 Merkle 根，委托方据此核对承诺
+func (h *storageHoldings) hold(t *task.Task, self, path string) (*storageHolding, error) {
+	if t.Type != task.TaskTypeStorage {
+		return nil, errors.New("not a storage task")
+	}
+	if t.ExecutorID != self {
+		return nil, task.ErrNotAssignedToMe
+	}
+	path, err := h.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := fileMerkleTree(path)
+	if err != nil {
+		return nil, err
+	}
+	holding := &storageHolding{TaskID: t.ID, Requester: t.RequesterID, Path: path, MerkleRoot: tree.Root(), ChunkCount: tree.ChunkCount()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries[t.ID] = holding
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(h.path, data, 0600); err != nil {
+		return nil, err
+	}
+	return holding, nil
+}
+
+// prove 响应 from 发来的挑战：读取持有的文件并为抽取的分块生成 Merkle 证明
+func (h *storageHoldings) prove(from string, challenge *incentive.StorageChallenge) (*incentive.StorageProof, error) {
+	h.mu.Lock()
+	holding, ok := h.entries[challenge.TaskID]
+	h.mu.Unlock()
+	if !ok {
+		return nil, errors.New("no data held for this task")
+	}
+	if from != holding.Requester || challenge.VerifierID != from {
+		return nil, errors.New("challenge is not from the task requester")
+	}
+	// 登记后文件可能被替换为指向存储目录外的符号链接，每次读取前重新检查
+	path, err := h.resolve(holding.Path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return incentive.BuildStorageProof(challenge, data)
+}
+
+// fileMerkleTree 按 StorageChunkSize 切分文件并构建 Merkle 树
+func fileMerkleTree(path string) (*incentive.MerkleTree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return incentive.BuildMerkleTree(incentive.SplitChunks(data, incentive.StorageChunkSize))
+}
+
+// challengeStorage 委托方对任务的存储方发起一次挑战并判定证明。存储方无法连接或拒绝响应时
+// 挑战保持待响应，超时后由 ExpireChallenges 判定失败。
+func challengeStorage(m *incentive.StorageProofManager, open func(ctx context.Context, nodeID string) (io.ReadWriteCloser, error)) func(taskID string) (*incentive.StorageChallenge, error) {
+	return func(taskID string) (*incentive.StorageChallenge, error) {
+		challenge, err := m.IssueChallenge(taskID)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), storageChallengeTimeout)
+		defer cancel()
+		rw, err := open(ctx, challenge.NodeID)
+		if err != nil {
+			return challenge, err
+		}
+		defer rw.Close()
+		proof, err := incentive.RequestStorageProof(rw, challenge)
+		if err != nil {
+			return challenge, err
+		}
+		// 证明只能用于本次挑战
+		proof.ChallengeID = challenge.ChallengeID
+		if _, err := m.SubmitProof(proof); err != nil {
+			return challenge, err
+		}
+		return m.GetChallenge(challenge.ChallengeID)
+	}
+}
+
+// startStorageChallenges 定期挑战 active 返回 true 的任务的存储方，并把超时未响应的挑战判定为失败，返回停止函数
+func startStorageChallenges(m *incentive.StorageProofManager, challenge func(taskID string) (*incentive.StorageChallenge, error), active func(taskID string) bool, interval time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			m.ExpireChallenges()
+			for _, commitment := range m.Commitments() {
+				if ctx.Err() != nil {
+					return
+				}
+				if !active(commitment.TaskID) {
+					continue
+				}
+				if _, err := challenge(commitment.TaskID); err != nil {
+					fmt.Printf("⚠️  存储任务 %s 的挑战未完成: %v\n", commitment.TaskID, err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// storageTaskActive 任务取消或过期后不再挑战存储方
+func storageTaskActive(tasks *task.TaskManager) func(taskID string) bool {
+	return func(taskID string) bool {
+		t, err := tasks.GetTask(taskID)
+		return err == nil && t.Status != task.StatusCancelled && t.Status != task.StatusExpired
+	}
+}
+
+// registerStorageProofAPI 存储证明 API：存储方登记持有的文件，委托方按原始文件登记承诺、
+// 立即发起挑战和查询挑战记录。两者的文件都须位于存储目录内。
+func registerStorageProofAPI(s *httpapi.Server, m *incentive.StorageProofManager, holdings *storageHoldings, tasks *task.TaskManager, self string, challenge func(taskID string) (*incentive.StorageChallenge, error)) {
+	s.TaskStorageHoldFunc = func(req *httpapi.TaskStorageRequest) (map[string]interface{}, error) {
+		t, err := tasks.GetTask(req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		holding, err := holdings.hold(t, self, req.Path)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(holding), nil
+	}
+	s.TaskStorageCommitFunc = func(req *httpapi.TaskStorageRequest) (map[string]interface{}, error) {
+		t, err := tasks.GetTask(req.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if t.Type != task.TaskTypeStorage {
+			return nil, errors.New("not a storage task")
+		}
+		if t.RequesterID != self {
+			return nil, errors.New("task was not published by this node")
+		}
+		if t.ExecutorID == "" {
+			return nil, errors.New("storage task has no executor yet")
+		}
+		path, err := holdings.resolve(req.Path)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := fileMerkleTree(path)
+		if err != nil {
+			return nil, err
+		}
+		commitment, err := m.RegisterCommitment(t.ID, t.ExecutorID, tree.Root(), tree.ChunkCount())
+		if err != nil {
+			return nil, err
+		}
+		return toMap(commitment), nil
+	}
+	s.TaskStorageChallengeFunc = func(taskID string) (map[string]interface{}, error) {
+		result, err := challenge(taskID)
+		if err != nil {
+			return nil, err
+		}
+		return toMap(result), nil
+	}
+	s.TaskStorageChallengesFunc = func(taskID string) (map[string]interface{}, error) {
+		commitment, err := m.GetCommitment(taskID)
+		if err != nil {
+			return nil, err
+		}
+		challenges := m.GetChallengesByTask(taskID)
+		sort.Slice(challenges, func(i, j int) bool {
+			return challenges[i].IssuedAt.Before(challenges[j].IssuedAt)
+		})
+		list := []map[string]interface{}{}
+		for _, c := range challenges {
+			list = append(list, toMap(c))
+		}
+		return map[string]interface{}{"commitment": toMap(commitment), "challenges": list}, nil
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
+)
+
+// storeJSON 沿用各模块各自的 JSON 文件，不使用存储后端
+const storeJSON = "json"
+
+// openNodeStore 打开邮箱、留言板和激励记录共用的存储后端（<data>/store/node.db），记录用存储密钥环加密
+// 驱动为 json 时返回 nil；各模块首次使用后端时自动导入并保留旧的 JSON 文件。
+func openNodeStore(dataDir, driver string, keys *crypto.StorageKeyring) (storage.Backend, error) {
+	if driver == storeJSON {
+		return nil, nil
+	}
+	opts := &storage.Options{}
+	if keys != nil {
+		opts.Sealer = keys
+	}
+	store, err := storage.Open(driver, filepath.Join(dataDir, "store", "node.db"), opts)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储后端 %s 失败: %w", driver, err)
+	}
+	return store, nil
+}
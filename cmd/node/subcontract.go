@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// wireSubcontracts 转包子任务的预算从上级任务在本节点的托管中划拨（上级任务没有托管时不建下级托管），
+// 转包失败时链上各级的处罚扣减其声誉
+func wireSubcontracts(tm *task.TaskManager, escrows *escrow.EscrowManager, reputations *reputation.Manager) {
+	tm.SetSubcontractEscrowFunc(func(parentTaskID, subtaskID, payerID string, budget float64) error {
+		parent, err := escrows.GetEscrowByTask(parentTaskID)
+		if errors.Is(err, escrow.ErrEscrowNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = escrows.CreateChildEscrow(parent.ID, subtaskID, payerID, budget, nil)
+		return err
+	})
+	tm.SetPenaltyFunc(func(nodeID string, amount float64, reason string) {
+		if _, err := reputations.Adjust(nodeID, -amount, reason); err != nil {
+			fmt.Printf("⚠️  转包处罚 %s 失败: %v\n", nodeID, err)
+		}
+	})
+}
+
+// registerSubcontractAPI 任务转包 API：本节点作为上级任务的执行方转包，子任务经 offer 向任务网络发布；
+// 报告子任务失败后经 announce 广播进入争议状态的子任务副本
+func registerSubcontractAPI(s *httpapi.Server, tm *task.TaskManager, self string, offer, announce func(taskID string)) {
+	s.TaskSubcontractFunc = func(req *httpapi.TaskSubcontractRequest) (map[string]interface{}, error) {
+		sub := &task.Task{
+			Title:       req.Title,
+			Description: req.Description,
+			Reward:      req.Reward,
+			Deadline:    req.Deadline,
+		}
+		if req.Executor != "" {
+			sub.PublishMode = task.ModeDirect
+			sub.TargetExecutorID = req.Executor
+		}
+		if err := tm.SubcontractTask(req.ParentTaskID, self, sub); err != nil {
+			return nil, err
+		}
+		offer(sub.ID)
+		t, err := tm.GetTask(sub.ID)
+		if err != nil {
+			return nil, err
+		}
+		return taskMap(t), nil
+	}
+	s.TaskSubcontractFailFunc = func(subtaskID, reason string) ([]map[string]interface{}, error) {
+		penalties, err := tm.ReportSubcontractFailure(subtaskID, self, reason)
+		if err != nil {
+			return nil, err
+		}
+		announce(subtaskID)
+		result := []map[string]interface{}{}
+		for _, p := range penalties {
+			result = append(result, toMap(p))
+		}
+		return result, nil
+	}
+	s.TaskSubcontractChainFunc = func(taskID string) ([]map[string]interface{}, error) {
+		chain, err := tm.GetSubcontractChain(taskID)
+		if err != nil {
+			return nil, err
+		}
+		result := []map[string]interface{}{}
+		for _, t := range chain {
+			result = append(result, taskMap(t))
+		}
+		return result, nil
+	}
+}
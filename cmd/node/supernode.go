@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/supernode"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/voting"
+)
+
+// superNodeProbeInterval 超级节点在线探测间隔
+const superNodeProbeInterval = 5 * time.Minute
+
+// registerSuperNodeAPI 超级节点候选与选举 API：本节点以当前声誉和申报的抵押申请候选，
+// 抵押由 SetStakeLocker 设置的锁定器在审计抵押用途下实际锁定，撤回、被移除或任期结束时释放。
+// 投票权重为投票节点的声誉。
+func registerSuperNodeAPI(s *httpapi.Server, sm *supernode.SuperNodeManager, self string, reputation func(nodeID string) float64) {
+	s.SuperNodeListFunc = func() []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, sn := range sm.GetActiveSuperNodes() {
+			result = append(result, toMap(sn))
+		}
+		return result
+	}
+	s.SuperNodeCandidatesFunc = func() []map[string]interface{} {
+		result := []map[string]interface{}{}
+		for _, c := range sm.GetCandidates() {
+			result = append(result, map[string]interface{}{
+				"node_id":    c.NodeID,
+				"reputation": c.Reputation,
+				"stake":      c.Stake,
+				"applied_at": c.AppliedAt.Format(time.RFC3339),
+				"votes":      c.Votes,
+			})
+		}
+		return result
+	}
+	s.SuperNodeApplyFunc = func(stake int64) error {
+		return sm.ApplyCandidate(self, reputation(self), float64(stake))
+	}
+	s.SuperNodeWithdrawFunc = func() error {
+		return sm.WithdrawCandidate(self)
+	}
+	s.SuperNodeVoteFunc = func(candidate string) error {
+		return sm.VoteForCandidate(self, candidate, reputation(self))
+	}
+	s.SuperNodeStartElection = func() (string, error) {
+		e, err := sm.StartElection()
+		if err != nil {
+			return "", err
+		}
+		return e.ID, nil
+	}
+	s.SuperNodeFinalizeFunc = func(electionID string) ([]string, error) {
+		if current := sm.GetCurrentElection(); current == nil || current.ID != electionID {
+			return nil, fmt.Errorf("election %s is not open", electionID)
+		}
+		e, err := sm.FinalizeElection()
+		if err != nil {
+			return nil, err
+		}
+		return e.Winners, nil
+	}
+	s.SuperNodeScoreboardFunc = func(nodeID string) ([]map[string]interface{}, error) {
+		if nodeID != "" {
+			score, err := sm.GetDutyScore(nodeID)
+			if err != nil {
+				return nil, err
+			}
+			return []map[string]interface{}{toMap(score)}, nil
+		}
+		result := []map[string]interface{}{}
+		for _, score := range sm.GetScoreboard() {
+			result = append(result, toMap(score))
+		}
+		return result, nil
+	}
+}
+
+// probeSuperNodes 探测一轮其他活跃超级节点是否在线，在线时同时记录响应延迟
+func probeSuperNodes(sm *supernode.SuperNodeManager, self string, probe func(nodeID string) (time.Duration, error)) {
+	for _, sn := range sm.GetActiveSuperNodes() {
+		if sn.NodeID == self {
+			continue
+		}
+		latency, err := probe(sn.NodeID)
+		sm.RecordUptimeProbe(sn.NodeID, err == nil)
+		if err == nil {
+			sm.RecordResponseLatency(sn.NodeID, latency)
+		}
+	}
+}
+
+// startSuperNodeProbes 定期探测超级节点，结果计入履职记分板；评估周期由超级节点管理器按时结束
+func startSuperNodeProbes(sm *supernode.SuperNodeManager, self string, probe func(nodeID string) (time.Duration, error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(superNodeProbeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				probeSuperNodes(sm, self, probe)
+			}
+		}
+	}()
+	return cancel
+}
+
+// watchSuperNodeDemotions 记分板连续不达标的超级节点经治理投票提议降级。降级提案结束后，
+// 本节点发起的提案按结果交给记分板处理，其他节点发起且通过的提案直接移除该超级节点
+func watchSuperNodeDemotions(sm *supernode.SuperNodeManager, vm *voting.VotingManager) {
+	sm.SetDemotionProposer(func(nodeID, reason string) (string, error) {
+		p, err := vm.CreateProposal(voting.VoteDemote, nodeID, reason)
+		if err != nil {
+			return "", err
+		}
+		return p.ID, nil
+	})
+	vm.SetOnProposalFinalized(func(p *voting.Proposal) {
+		if p.Type != voting.VoteDemote {
+			return
+		}
+		passed := p.Status == voting.ProposalPassed
+		if score, err := sm.GetDutyScore(p.TargetNodeID); err == nil && score.DemotionProposalID == p.ID {
+			if err := sm.ResolveDemotion(p.TargetNodeID, passed); err != nil {
+				fmt.Printf("⚠️  处理超级节点 %s 的降级提案失败: %v\n", p.TargetNodeID, err)
+			}
+			return
+		}
+		if passed && sm.IsSuperNode(p.TargetNodeID) {
+			if err := sm.RemoveSuperNode(p.TargetNodeID, "demoted by governance proposal "+p.ID); err != nil {
+				fmt.Printf("⚠️  移除被降级的超级节点 %s 失败: %v\n", p.TargetNodeID, err)
+			}
+		}
+	})
+}
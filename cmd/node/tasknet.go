@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// 任务的广播主题：新发布的任务走报价主题，计入节点间的任务报价配额；
+// 之后的任务副本、接单和交付走任务主题，只更新已知的任务。
+const (
+	taskOfferTopic = "/daan/task/offer/1.0.0"
+	taskTopic      = "/daan/task/1.0.0"
+)
+
+// taskMessage 任务广播消息，每条只携带其中一项
+type taskMessage struct {
+	Task     *task.Task      `json:"task,omitempty"`     // 委托方节点的任务副本
+	Claim    *task.TaskClaim `json:"claim,omitempty"`    // 执行方的接单请求
+	Delivery *taskDelivery   `json:"delivery,omitempty"` // 执行方的交付
+	Audit    *taskAudit      `json:"audit,omitempty"`    // 超级节点的审计结论
+
+	Reviewers *task.ReviewAssignment `json:"reviewers,omitempty"` // 委托方节点的评审人分配副本
+	Review    *taskReview            `json:"review,omitempty"`    // 评审人对分配的响应
+}
+
+// taskDelivery 执行方交给委托方节点的交付摘要和签名
+type taskDelivery struct {
+	TaskID    string `json:"task_id"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// taskAudit 超级节点交给委托方节点的审计结论
+type taskAudit struct {
+	TaskID   string `json:"task_id"`
+	Approved bool   `json:"approved"`
+	Note     string `json:"note,omitempty"`
+}
+
+// taskReview 评审人交给委托方节点的接受或拒绝
+type taskReview struct {
+	TaskID string `json:"task_id"`
+	Accept bool   `json:"accept"`
+}
+
+// taskNetwork 经 GossipSub 交换任务：本节点发布的任务由本节点推进状态并广播副本，
+// 其他节点发布的任务只保存副本，接单、交付和审计结论广播给委托方节点处理。未接入广播时只在本地记录。
+// 设置了邀约队列时，其他节点新发布的任务同时进入队列，任务不再待接或本节点接单后移出。
+type taskNetwork struct {
+	tm         *task.TaskManager
+	self       string
+	reputation func(nodeID string) float64
+	transport  gossipTransport
+	offers     *task.OfferQueue
+}
+
+func newTaskNetwork(tm *task.TaskManager, self string, reputation func(nodeID string) float64) *taskNetwork {
+	return &taskNetwork{tm: tm, self: self, reputation: reputation}
+}
+
+// start 订阅任务主题；只接受 GossipSub 验证过的原始发布节点本人的副本、接单、交付、审计结论和评审响应
+func (tn *taskNetwork) start(transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var msg taskMessage
+		return json.Unmarshal(data, &msg) == nil &&
+			(msg.Task != nil || msg.Claim != nil || msg.Delivery != nil || msg.Audit != nil || msg.Reviewers != nil || msg.Review != nil)
+	}
+	tn.transport = transport
+	if err := transport.Subscribe(taskOfferTopic, validate, tn.handleOffer); err != nil {
+		return err
+	}
+	return transport.Subscribe(taskTopic, validate, tn.handle)
+}
+
+// handleOffer 保存其他节点新发布的任务，已知的任务只经任务主题更新
+func (tn *taskNetwork) handleOffer(data []byte, from string) {
+	var msg taskMessage
+	if json.Unmarshal(data, &msg) != nil || msg.Task == nil || !tn.fromRequester(msg.Task, from) {
+		return
+	}
+	if _, err := tn.tm.GetTask(msg.Task.ID); err == nil {
+		return
+	}
+	// 队列保存独立的副本，本地记录之后的状态变化不影响队列
+	offer := *msg.Task
+	if tn.tm.ReceiveTask(msg.Task) != nil || tn.offers == nil || offer.Status != task.StatusPublished {
+		return
+	}
+	// 队列已满且邀约排在最后、或已过期时不入队，任务副本照常保存
+	tn.offers.Add(&offer)
+}
+
+func (tn *taskNetwork) handle(data []byte, from string) {
+	var msg taskMessage
+	if json.Unmarshal(data, &msg) != nil {
+		return
+	}
+	switch {
+	case msg.Task != nil && tn.fromRequester(msg.Task, from):
+		if _, err := tn.tm.GetTask(msg.Task.ID); err == nil {
+			tn.tm.ReceiveTask(msg.Task)
+			if msg.Task.Status != task.StatusPublished {
+				tn.dropOffer(msg.Task.ID)
+			}
+		}
+	case msg.Claim != nil && msg.Claim.ClaimerID == from && tn.owns(msg.Claim.TaskID):
+		var rep float64
+		if tn.reputation != nil {
+			rep = tn.reputation(from)
+		}
+		if err := tn.tm.ClaimTask(msg.Claim, rep); err != nil {
+			fmt.Printf("⚠️  %s 接单 %s 失败: %v\n", from, msg.Claim.TaskID, err)
+		}
+		// 接单失败也广播副本，纠正接单方本地先行记下的状态
+		tn.announce(msg.Claim.TaskID)
+	case msg.Delivery != nil && tn.owns(msg.Delivery.TaskID):
+		d := msg.Delivery
+		if err := tn.tm.SubmitResult(d.TaskID, from, d.Hash, d.Signature); err != nil {
+			fmt.Printf("⚠️  %s 交付 %s 失败: %v\n", from, d.TaskID, err)
+			return
+		}
+		tn.announce(d.TaskID)
+	case msg.Audit != nil && tn.owns(msg.Audit.TaskID):
+		a := msg.Audit
+		if _, err := tn.tm.RecordAudit(a.TaskID, from, a.Approved, a.Note); err != nil {
+			fmt.Printf("⚠️  %s 审计 %s 失败: %v\n", from, a.TaskID, err)
+			return
+		}
+		tn.announce(a.TaskID)
+	case msg.Reviewers != nil && !tn.owns(msg.Reviewers.TaskID):
+		// 分配副本只能由委托方本人广播，由 ReceiveReviewAssignment 按本地任务副本核对
+		if err := tn.tm.ReceiveReviewAssignment(from, msg.Reviewers); err != nil && !errors.Is(err, task.ErrTaskNotFound) {
+			fmt.Printf("⚠️  %s 的评审人分配 %s 无效: %v\n", from, msg.Reviewers.TaskID, err)
+		}
+	case msg.Review != nil && tn.owns(msg.Review.TaskID):
+		r := msg.Review
+		if _, err := tn.tm.RespondReview(r.TaskID, from, r.Accept); err != nil {
+			fmt.Printf("⚠️  %s 响应评审 %s 失败: %v\n", from, r.TaskID, err)
+		}
+		// 响应失败也广播分配，纠正评审人本地的副本
+		tn.reviewers(r.TaskID)
+	}
+}
+
+// fromRequester 副本只能由委托方本人广播，本节点发布的任务以本地记录为准
+func (tn *taskNetwork) fromRequester(t *task.Task, from string) bool {
+	return t.RequesterID == from && from != tn.self
+}
+
+// owns 任务是否由本节点发布
+func (tn *taskNetwork) owns(taskID string) bool {
+	t, err := tn.tm.GetTask(taskID)
+	return err == nil && t.RequesterID == tn.self
+}
+
+// offer 广播本节点新发布的任务
+func (tn *taskNetwork) offer(taskID string) {
+	if snapshot, err := tn.tm.Snapshot(taskID); err == nil {
+		tn.publish(taskOfferTopic, taskMessage{Task: snapshot})
+	}
+}
+
+// announce 本节点发布的任务状态变化后广播新副本，其他节点的任务不处理
+func (tn *taskNetwork) announce(taskID string) {
+	if !tn.owns(taskID) {
+		return
+	}
+	if snapshot, err := tn.tm.Snapshot(taskID); err == nil {
+		tn.publish(taskTopic, taskMessage{Task: snapshot})
+	}
+}
+
+// claim 接单后，其他节点发布的任务把接单请求交给委托方节点，本节点发布的任务广播新副本
+func (tn *taskNetwork) claim(c *task.TaskClaim) {
+	tn.dropOffer(c.TaskID)
+	if tn.owns(c.TaskID) {
+		tn.announce(c.TaskID)
+		return
+	}
+	tn.publish(taskTopic, taskMessage{Claim: c})
+}
+
+// deliver 提交结果后，其他节点发布的任务把交付交给委托方节点，本节点发布的任务广播新副本
+func (tn *taskNetwork) deliver(d *taskDelivery) {
+	if tn.owns(d.TaskID) {
+		tn.announce(d.TaskID)
+		return
+	}
+	tn.publish(taskTopic, taskMessage{Delivery: d})
+}
+
+// audit 本节点作为超级节点审计其他节点发布的任务后，把结论交给委托方节点
+func (tn *taskNetwork) audit(a *taskAudit) {
+	if !tn.owns(a.TaskID) {
+		tn.publish(taskTopic, taskMessage{Audit: a})
+	}
+}
+
+// reviewers 本节点发布的任务分配或补选评审人后，把分配广播给其他节点上的评审人
+func (tn *taskNetwork) reviewers(taskID string) {
+	if !tn.owns(taskID) {
+		return
+	}
+	if a, err := tn.tm.GetReviewAssignment(taskID); err == nil {
+		tn.publish(taskTopic, taskMessage{Reviewers: a})
+	}
+}
+
+// respondReview 评审人接受或拒绝分配：本节点发布的任务直接记录并广播新的分配；
+// 其他节点发布的任务先按收到的分配副本检查，再把响应交给委托方节点记录和补选，
+// 返回的是本地副本，委托方广播新的分配后更新
+func (tn *taskNetwork) respondReview(taskID string, accept bool) (*task.ReviewAssignment, error) {
+	if tn.owns(taskID) {
+		a, err := tn.tm.RespondReview(taskID, tn.self, accept)
+		if err != nil {
+			return nil, err
+		}
+		tn.reviewers(taskID)
+		return a, nil
+	}
+	if err := tn.tm.PendingReview(taskID, tn.self); err != nil {
+		return nil, err
+	}
+	tn.publish(taskTopic, taskMessage{Review: &taskReview{TaskID: taskID, Accept: accept}})
+	return tn.tm.GetReviewAssignment(taskID)
+}
+
+// dropOffer 任务已被接单、取消或过期后移出邀约队列
+func (tn *taskNetwork) dropOffer(taskID string) {
+	if tn.offers != nil {
+		tn.offers.Remove(taskID)
+	}
+}
+
+func (tn *taskNetwork) publish(topic string, msg taskMessage) {
+	if tn.transport == nil {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if err := tn.transport.Publish(topic, data); err != nil {
+		fmt.Printf("⚠️  任务广播失败: %v\n", err)
+	}
+}
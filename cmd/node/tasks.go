@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// newTaskManager 本节点的任务簿，与任务模板共用 <data>/tasks 目录
+func newTaskManager(dataDir string) *task.TaskManager {
+	config := task.DefaultConfig()
+	config.DataDir = filepath.Join(dataDir, "tasks")
+	// 任务簿只接受本节点运营者通过本地接口发布的任务，声誉门槛和发布限频对其没有意义
+	config.MinRepToPublish = 0
+	config.MaxTasksPerHour = 60
+	return task.NewTaskManager(config)
+}
+
+// taskFromRequest 将创建任务请求转换为以本节点为委托方的任务
+func taskFromRequest(requesterID string, req *httpapi.TaskRequest) *task.Task {
+	t := &task.Task{
+		ID:                 req.TaskID,
+		Type:               task.TaskType(req.Type),
+		Title:              req.Title,
+		Description:        req.Description,
+		RequesterID:        requesterID,
+		Reward:             req.Reward,
+		Deadline:           req.Deadline,
+		Payload:            req.Payload,
+		RequesterSig:       req.Signature,
+		Verification:       task.VerificationMethod(req.Verification),
+		ExpectedHash:       req.ExpectedHash,
+		RedundancyRequired: req.RedundancyRequired,
+		RedundancyTotal:    req.RedundancyTotal,
+	}
+	if t.Title == "" {
+		t.Title = req.Description
+	}
+	if t.Title == "" {
+		t.Title = req.Type
+	}
+	if req.Target != "" {
+		t.PublishMode = task.ModeDirect
+		t.TargetExecutorID = req.Target
+	}
+	for _, f := range req.PayloadFormats {
+		t.PayloadFormats = append(t.PayloadFormats, task.PayloadFormat(f))
+	}
+	return t
+}
+
+// taskMap 任务的 API 表示，附带对外展示的生命周期阶段
+func taskMap(t *task.Task) map[string]interface{} {
+	m := toMap(t)
+	m["stage"] = string(task.StageOf(t.Status))
+	m["overdue"] = t.OverdueAt > 0
+	return m
+}
+
+// submitTaskResult 以本节点为执行方提交结果，交付物哈希缺省按结果内容计算并签名
+// 返回的交付摘要和签名供转交给其他节点上的委托方。
+func submitTaskResult(tm *task.TaskManager, executorID string, req *httpapi.TaskSubmitRequest, sign func([]byte) ([]byte, error)) (*task.Task, *taskDelivery, error) {
+	hash := req.ResultHash
+	if hash == "" {
+		hash = task.HashResult([]byte(req.Result))
+	}
+	var signature string
+	if sign != nil {
+		sig, err := sign([]byte(hash))
+		if err != nil {
+			return nil, nil, err
+		}
+		signature = hex.EncodeToString(sig)
+	}
+	if err := tm.SubmitResult(req.TaskID, executorID, hash, signature); err != nil {
+		return nil, nil, err
+	}
+	t, err := tm.GetTask(req.TaskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, &taskDelivery{TaskID: req.TaskID, Hash: hash, Signature: signature}, nil
+}
+
+// confirmTaskDelivery 以本节点为委托方验收交付，对交付物摘要签名
+func confirmTaskDelivery(tm *task.TaskManager, requesterID, taskID string, sign func([]byte) ([]byte, error)) (*task.Task, error) {
+	t, err := tm.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	var signature string
+	if sign != nil {
+		sig, err := sign([]byte(t.DeliverableHash))
+		if err != nil {
+			return nil, err
+		}
+		signature = hex.EncodeToString(sig)
+	}
+	if err := tm.ConfirmDelivery(taskID, requesterID, signature); err != nil {
+		return nil, err
+	}
+	return tm.GetTask(taskID)
+}
+
+// settleTask 结算本节点发布的任务；其他节点发布的任务只有副本，由委托方节点结算
+func settleTask(tm *task.TaskManager, requesterID, taskID string) (*task.SettlementResult, error) {
+	t, err := tm.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if t.RequesterID != requesterID {
+		return nil, errors.New("not the task requester")
+	}
+	return tm.SettleTask(taskID)
+}
+
+// reportTaskProgress 以本节点为执行方报告进度；已记录但未送达委托方时照常返回，附带送达错误
+func reportTaskProgress(tm *task.TaskManager, executorID string, req *httpapi.TaskProgressRequest) (map[string]interface{}, error) {
+	update, err := tm.ReportProgress(&task.ProgressUpdate{
+		TaskID:     req.TaskID,
+		ExecutorID: executorID,
+		Percent:    req.Percent,
+		Checkpoint: req.Checkpoint,
+		Log:        req.Log,
+		Message:    req.Message,
+	})
+	if update == nil {
+		return nil, err
+	}
+	result := toMap(update)
+	result["delivered"] = err == nil
+	if err != nil {
+		result["delivery_error"] = err.Error()
+	}
+	return result, nil
+}
+
+// taskProgressMap 任务各执行者的进度，主执行者在前
+func taskProgressMap(tm *task.TaskManager, taskID string) (map[string]interface{}, error) {
+	progress, err := tm.GetProgress(taskID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"task_id":   taskID,
+		"executors": progress,
+	}, nil
+}
+
+// progressSender 经 task.ProgressProtocolID 流把进度推送给委托方节点，本节点发布的任务不推送
+func progressSender(self string, open func(ctx context.Context, nodeID string) (io.WriteCloser, error)) task.ProgressSendFunc {
+	return func(requesterID string, update *task.ProgressUpdate) error {
+		if requesterID == self {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		w, err := open(ctx, requesterID)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		return task.WriteProgress(w, update)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// maxSharedTemplates 启动时从留言板导入的共享模板上限
+const maxSharedTemplates = 500
+
+// toMap 将结构体经 JSON 转换为 API 响应
+func toMap(v interface{}) map[string]interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// templateFromMap 将 API 请求体解析为任务模板
+func templateFromMap(m map[string]interface{}) (*task.TaskTemplate, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var tpl task.TaskTemplate
+	if err := json.Unmarshal(data, &tpl); err != nil {
+		return nil, task.ErrInvalidTemplate
+	}
+	return &tpl, nil
+}
+
+// shareTemplate 将模板发布到留言板的模板话题，返回留言ID
+func shareTemplate(store *task.TemplateStore, bb *bulletin.BulletinBoard, templateID string) (string, error) {
+	if bb == nil {
+		return "", errors.New("bulletin board not available")
+	}
+	tpl, err := store.Get(templateID)
+	if err != nil {
+		return "", err
+	}
+	content, err := task.MarshalShared(tpl)
+	if err != nil {
+		return "", err
+	}
+	msg, err := bb.PublishMessageWithOptions(content, task.TemplateBulletinTopic, []string{string(tpl.Type)}, nil, "")
+	if err != nil {
+		return "", err
+	}
+	return msg.MessageID, nil
+}
+
+// importSharedTemplate 导入留言板上其他节点共享的模板
+func importSharedTemplate(store *task.TemplateStore, msg *bulletin.Message) error {
+	tpl, err := task.ParseShared(msg.Content, msg.Author)
+	if err != nil {
+		return err
+	}
+	return store.Import(tpl)
+}
+
+// syncSharedTemplates 导入留言板上已有的共享模板，返回成功导入的数量
+func syncSharedTemplates(store *task.TemplateStore, bb *bulletin.BulletinBoard) int {
+	messages, err := bb.QueryByTopic(task.TemplateBulletinTopic, maxSharedTemplates, 0)
+	if err != nil {
+		return 0
+	}
+	imported := 0
+	for _, msg := range messages {
+		if importSharedTemplate(store, msg) == nil {
+			imported++
+		}
+	}
+	return imported
+}
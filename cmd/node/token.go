@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+)
+
+// tokenTopic 签名转账、签名铸造和任务报酬支付的广播主题
+const tokenTopic = "/daan/token/1.0.0"
+
+// tokenService 把代币账本适配为 HTTP API 的代币服务
+// 省略 from 的转账由本节点签名；替其他节点提交的转账必须带该节点的签名和序号。
+// 任务预付报酬总是从本节点锁定，只能为本节点发布且未结束的任务预付。
+type tokenService struct {
+	l     *token.Ledger
+	tasks *task.TaskManager
+	self  string
+	sign  func(data []byte) (string, error)
+}
+
+func (s tokenService) Balance(nodeID string) map[string]interface{} {
+	if nodeID == "" {
+		nodeID = s.self
+	}
+	a := s.l.GetAccount(nodeID)
+	m := toMap(a)
+	m["available"] = a.Available()
+	return m
+}
+
+func (s tokenService) Transfer(req httpapi.TokenTransferRequest) (map[string]interface{}, error) {
+	var (
+		e   *token.Entry
+		err error
+	)
+	if req.From == "" || req.From == s.self {
+		if req.Signature != "" {
+			e, err = s.l.SignedTransfer(s.self, req.To, req.Amount, req.Nonce, req.Memo, req.Signature)
+		} else {
+			e, err = s.l.SignAndTransfer(s.self, req.To, req.Amount, req.Memo, s.sign)
+		}
+	} else {
+		e, err = s.l.SignedTransfer(req.From, req.To, req.Amount, req.Nonce, req.Memo, req.Signature)
+	}
+	if err != nil {
+		return nil, tokenAPIError(err)
+	}
+	return toMap(e), nil
+}
+
+func (s tokenService) History(nodeID string, limit int) []map[string]interface{} {
+	if nodeID == "" {
+		nodeID = s.self
+	}
+	entries := s.l.History(nodeID, limit)
+	result := make([]map[string]interface{}, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, toMap(e))
+	}
+	return result
+}
+
+func (s tokenService) GetTaskPayment(taskID string) (map[string]interface{}, error) {
+	p, err := s.l.GetTaskPayment(taskID)
+	if err != nil {
+		return nil, tokenAPIError(err)
+	}
+	return toMap(p), nil
+}
+
+func (s tokenService) HoldTaskPayment(taskID string, amount float64) (map[string]interface{}, error) {
+	t, err := s.tasks.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	}
+	if t.RequesterID != s.self {
+		return nil, fmt.Errorf("%w: task requested by %s", httpapi.ErrUnauthorized, t.RequesterID)
+	}
+	if taskFinished(t) {
+		return nil, fmt.Errorf("task %s is %s", taskID, t.Status)
+	}
+	p, err := s.l.HoldTaskPayment(taskID, s.self, amount)
+	if err != nil {
+		return nil, tokenAPIError(err)
+	}
+	return toMap(p), nil
+}
+
+func (s tokenService) ReleaseTaskPayment(taskID string, payouts map[string]float64) (map[string]interface{}, error) {
+	if err := s.checkPayer(taskID); err != nil {
+		return nil, err
+	}
+	p, err := s.l.ReleaseTaskPayment(taskID, payouts)
+	if err != nil {
+		return nil, tokenAPIError(err)
+	}
+	return toMap(p), nil
+}
+
+func (s tokenService) RefundTaskPayment(taskID string) (map[string]interface{}, error) {
+	if err := s.checkPayer(taskID); err != nil {
+		return nil, err
+	}
+	p, err := s.l.RefundTaskPayment(taskID)
+	if err != nil {
+		return nil, tokenAPIError(err)
+	}
+	return toMap(p), nil
+}
+
+// checkPayer 只有锁定预付报酬的本节点可以支付或退回
+func (s tokenService) checkPayer(taskID string) error {
+	p, err := s.l.GetTaskPayment(taskID)
+	if err != nil {
+		return tokenAPIError(err)
+	}
+	if p.PayerID != s.self {
+		return fmt.Errorf("%w: task payment held by %s", httpapi.ErrUnauthorized, p.PayerID)
+	}
+	return nil
+}
+
+// tokenAPIError 把代币账本错误映射为 HTTP API 的错误类别（404/403）
+func tokenAPIError(err error) error {
+	switch {
+	case errors.Is(err, token.ErrPaymentNotFound):
+		return fmt.Errorf("%w: %v", httpapi.ErrNotFound, err)
+	case errors.Is(err, token.ErrInvalidSignature):
+		return fmt.Errorf("%w: %v", httpapi.ErrUnauthorized, err)
+	}
+	return err
+}
+
+// taskFinished 任务是否已结算、取消或过期，结束的任务不再预付报酬
+func taskFinished(t *task.Task) bool {
+	switch t.Status {
+	case task.StatusSettled, task.StatusCompleted, task.StatusCancelled, task.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// taskPayments 把任务生命周期接到代币账本和激励系统：结算时按份额支付预付报酬，
+// 并为执行者记录任务奖励（奖励确认后铸造代币）；取消或过期时退回预付报酬。
+type taskPayments struct {
+	tasks     *task.TaskManager
+	tokens    *token.Ledger
+	incentive *incentive.IncentiveManager
+}
+
+// settled 作为任务结算回调使用
+func (p taskPayments) settled(r *task.SettlementResult) {
+	if payment, err := p.tokens.GetTaskPayment(r.TaskID); err == nil && payment.Status == token.PaymentHeld {
+		if _, err := p.tokens.ReleaseTaskPayment(r.TaskID, taskPayouts(payment, r.RewardShares)); err != nil {
+			fmt.Printf("⚠️  任务 %s 支付预付报酬失败: %v\n", r.TaskID, err)
+		}
+	}
+	if p.incentive == nil {
+		return
+	}
+	taskType := incentive.TaskTypeGeneral
+	if t, err := p.tasks.GetTask(r.TaskID); err == nil {
+		taskType = incentiveTaskType(t.Type)
+	}
+	for executorID, share := range r.RewardShares {
+		// 委托方自己执行的任务不产生奖励
+		if executorID == r.RequesterID {
+			continue
+		}
+		rewardTask := r.TaskID
+		if len(r.RewardShares) > 1 {
+			rewardTask += "/" + executorID
+		}
+		_, err := p.incentive.AwardTaskCompletion(executorID, rewardTask, taskType, math.Max(share, 1), "settled task "+r.TaskID)
+		if err != nil && !errors.Is(err, incentive.ErrDuplicateReward) {
+			fmt.Printf("⚠️  任务 %s 记录奖励失败: %v\n", r.TaskID, err)
+		}
+	}
+}
+
+// refund 作为任务取消回调和过期事件的处理使用，没有预付报酬时不做任何事
+func (p taskPayments) refund(taskID string) {
+	payment, err := p.tokens.GetTaskPayment(taskID)
+	if err != nil || payment.Status != token.PaymentHeld {
+		return
+	}
+	if _, err := p.tokens.RefundTaskPayment(taskID); err != nil {
+		fmt.Printf("⚠️  任务 %s 退回预付报酬失败: %v\n", taskID, err)
+	}
+}
+
+// taskPayouts 按结算份额分配预付报酬：委托方自己的份额不支付，总额超过预付额时按比例缩减
+func taskPayouts(payment *token.TaskPayment, shares map[string]float64) map[string]float64 {
+	payouts := make(map[string]float64)
+	var total float64
+	for id, share := range shares {
+		if id == payment.PayerID || share <= 0 {
+			continue
+		}
+		payouts[id] = share
+		total += share
+	}
+	if total > payment.Amount {
+		for id := range payouts {
+			payouts[id] *= payment.Amount / total
+		}
+	}
+	return payouts
+}
+
+// incentiveTaskType 任务类型对应的奖励权重类别
+func incentiveTaskType(t task.TaskType) incentive.TaskType {
+	switch t {
+	case task.TaskTypeStorage:
+		return incentive.TaskTypeStorage
+	case task.TaskTypeCompute:
+		return incentive.TaskTypeCompute
+	}
+	return incentive.TaskTypeGeneral
+}
+
+// startTokenGossip 广播本节点记入的签名流水，并记入其他节点广播的流水。
+// 铸造流水只接受已登记节点签名的，转账由账本按转出方签名和序号校验。
+func startTokenGossip(l *token.Ledger, transport gossipTransport, registered func(nodeID string) bool) error {
+	validate := func(data []byte) bool {
+		var e token.Entry
+		return json.Unmarshal(data, &e) == nil && e.Signature != ""
+	}
+	err := transport.Subscribe(tokenTopic, validate, func(data []byte, from string) {
+		var e token.Entry
+		if json.Unmarshal(data, &e) != nil {
+			return
+		}
+		if e.Type == token.EntryMint && !registered(e.Minter) {
+			return
+		}
+		if err := l.ReceiveEntry(&e); err != nil {
+			fmt.Printf("⚠️  代币流水 %s 入账失败: %v\n", e.ID, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	l.SetOnSigned(func(e *token.Entry) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		if err := transport.Publish(tokenTopic, data); err != nil {
+			fmt.Printf("⚠️  代币流水广播失败: %v\n", err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/control"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// controlSocketOff -control-socket 取该值时不启用本地控制接口
+const controlSocketOff = "off"
+
+// trayStartTimeout tray start 拉起节点后等待控制套接字可用的时间
+const trayStartTimeout = 30 * time.Second
+
+// trayStopTimeout tray stop 等待节点退出的时间
+const trayStopTimeout = 30 * time.Second
+
+// controlSocketPath 解析控制套接字路径，空为数据目录下的默认路径，off 返回空
+func controlSocketPath(dataDir, socket string) string {
+	switch socket {
+	case controlSocketOff:
+		return ""
+	case "":
+		return filepath.Join(dataDir, control.SocketName)
+	}
+	return socket
+}
+
+// startControlServer 启动供桌面托盘使用的本地控制接口，失败只告警
+func startControlServer(socketPath string, status func() map[string]interface{}, stop func(), events *httpapi.EventHub) *control.Server {
+	if socketPath == "" {
+		return nil
+	}
+	server, err := control.NewServer(&control.Config{SocketPath: socketPath})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  本地控制接口不可用: %v\n", err)
+		return nil
+	}
+	server.StatusFunc = status
+	server.StopFunc = stop
+	server.Events = events
+	if err := server.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  本地控制接口不可用: %v\n", err)
+		return nil
+	}
+	fmt.Printf("🖥️  本地控制接口: %s\n", socketPath)
+	return server
+}
+
+// notifyStop 让等待停止信号的主流程开始退出，已有信号待处理时不阻塞
+func notifyStop(sigCh chan os.Signal) func() {
+	return func() {
+		select {
+		case sigCh <- syscall.SIGTERM:
+		default:
+		}
+	}
+}
+
+func cmdTray() {
+	if len(os.Args) < 3 {
+		printTrayUsage()
+		return
+	}
+
+	subCmd := os.Args[2]
+	fs := flag.NewFlagSet("tray "+subCmd, flag.ExitOnError)
+	var (
+		dataDir, socket string
+		jsonOutput      *bool
+		types           *string
+		lastID          *uint64
+	)
+	switch subCmd {
+	case "start":
+		// 其余参数原样传给 start 命令
+		cf := parseCommonFlags(fs)
+		fs.Parse(os.Args[3:])
+		dataDir, socket = cf.dataDir, cf.controlSocket
+	case "status", "stop", "notifications":
+		fs.StringVar(&dataDir, "data", "./data", "数据目录")
+		fs.StringVar(&socket, "control-socket", "", "控制套接字路径（默认: <数据目录>/control.sock）")
+		jsonOutput = fs.Bool("json", false, "JSON格式输出")
+		types = fs.String("types", "", "只接收这些类型的通知（逗号分隔: mail,bulletin,task,reputation）")
+		lastID = fs.Uint64("last-id", 0, "从该事件 ID 之后开始接收（补发缓冲中的事件）")
+		fs.Parse(os.Args[3:])
+	default:
+		fmt.Fprintf(os.Stderr, "未知子命令: %s\n", subCmd)
+		printTrayUsage()
+		os.Exit(1)
+	}
+
+	socketPath := controlSocketPath(dataDir, socket)
+	if socketPath == "" {
+		fmt.Fprintln(os.Stderr, "错误: 本地控制接口已关闭 (-control-socket off)")
+		os.Exit(1)
+	}
+	client := control.NewClient(socketPath)
+
+	var err error
+	switch subCmd {
+	case "status":
+		var status map[string]interface{}
+		status, err = client.Status()
+		if err == nil {
+			printTrayStatus(status, *jsonOutput)
+		}
+	case "start":
+		var status map[string]interface{}
+		status, err = client.Start(launchDaemon, trayStartTimeout)
+		if err == nil {
+			printTrayStatus(status, false)
+		}
+	case "stop":
+		if err = client.Stop(); err == nil {
+			err = client.WaitStopped(trayStopTimeout)
+		}
+		if err == nil {
+			fmt.Println("节点已停止")
+		}
+	case "notifications":
+		err = trayNotifications(client, *types, *lastID, *jsonOutput)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// launchDaemon 以 start 命令在后台拉起节点，tray start 之后的参数原样传递
+func launchDaemon() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, append([]string{"start"}, os.Args[3:]...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func printTrayStatus(status map[string]interface{}, jsonOutput bool) {
+	if jsonOutput {
+		data, _ := json.MarshalIndent(status, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	keys := make([]string, 0, len(status))
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%-16s %v\n", k+":", status[k])
+	}
+}
+
+// trayNotifications 持续输出通知，断线后从最后收到的事件继续，Ctrl+C 退出
+func trayNotifications(client *control.Client, types string, lastID uint64, jsonOutput bool) error {
+	var typeList []string
+	if types != "" {
+		for _, t := range strings.Split(types, ",") {
+			typeList = append(typeList, strings.TrimSpace(t))
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	show := func(ev httpapi.Event) {
+		if ev.ID > lastID {
+			lastID = ev.ID
+		}
+		if jsonOutput {
+			data, _ := json.Marshal(ev)
+			fmt.Println(string(data))
+			return
+		}
+		data, _ := json.Marshal(ev.Data)
+		fmt.Printf("[%s] %-10s %s\n", ev.Time.Local().Format("15:04:05"), ev.Type, data)
+	}
+	for ctx.Err() == nil {
+		if err := client.Notifications(ctx, typeList, lastID, show); err != nil {
+			return err
+		}
+		// 节点退出或订阅积压被断开
+		if ctx.Err() == nil {
+			time.Sleep(time.Second)
+		}
+	}
+	return nil
+}
+
+func printTrayUsage() {
+	fmt.Print(`用法: agentnetwork tray <子命令> [选项]
+
+通过本地控制套接字管理节点，供桌面托盘程序调用；不经过 HTTP API，也不需要管理令牌。
+
+子命令:
+  status          查看节点状态（未运行时退出码为 1）
+  start           节点未运行时在后台启动（选项与 start 命令相同）
+  stop            请求节点优雅退出并等待其停止
+  notifications   持续输出新邮件、留言、任务和声誉变化通知
+
+选项:
+  -data            数据目录 (默认: ./data)
+  -control-socket  控制套接字路径 (默认: <数据目录>/control.sock)
+  -json            JSON格式输出（status、notifications）
+  -types           通知类型过滤，如 mail,task（notifications）
+  -last-id         从该事件 ID 之后开始接收（notifications）
+
+示例:
+  agentnetwork tray status -json
+  agentnetwork tray start -data ./data -http :18345
+  agentnetwork tray notifications -types mail,task
+  agentnetwork tray stop
+`)
+}
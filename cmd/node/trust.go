@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/trust"
+)
+
+// endorsementTopic 背书的广播主题：本节点签发的背书发给全网，其他节点验签后计入各自的信任网
+const endorsementTopic = "/daan/trust/endorsement/1.0.0"
+
+// startEndorsementGossip 接收其他节点广播的背书，只保存背书人本人发布的；本节点签发的背书随后广播。
+// 撤销只在本地生效，不经广播传播。
+func startEndorsementGossip(em *trust.EndorsementManager, self string, transport gossipTransport) error {
+	validate := func(data []byte) bool {
+		var e trust.Endorsement
+		return json.Unmarshal(data, &e) == nil && e.Endorser != "" && e.Subject != ""
+	}
+	err := transport.Subscribe(endorsementTopic, validate, func(data []byte, from string) {
+		var e trust.Endorsement
+		if json.Unmarshal(data, &e) != nil || e.Endorser != from || from == self {
+			return
+		}
+		if err := em.AddEndorsement(&e); err != nil {
+			fmt.Printf("⚠️  收到 %s 的背书无效: %v\n", from, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	em.OnEndorsementAdded = func(e *trust.Endorsement) {
+		if e.Endorser != self {
+			return
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		if err := transport.Publish(endorsementTopic, data); err != nil {
+			fmt.Printf("⚠️  背书广播失败: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// reasonIdentityProof 外部身份证明计入起始声誉的历史原因
+const reasonIdentityProof = "identity_proof"
+
+// grantStarterReputation 外部身份证明首次通过验证时，把尚未计入的起始声誉加到声誉管理器
+func grantStarterReputation(proofs *trust.IdentityProofManager, reputations *reputation.Manager) func(p *trust.IdentityProof) {
+	return func(p *trust.IdentityProof) {
+		if delta := proofs.ClaimStarterReputation(p.NodeID); delta > 0 {
+			if _, err := reputations.Adjust(p.NodeID, delta, reasonIdentityProof); err != nil {
+				fmt.Printf("⚠️  计入 %s 的起始声誉失败: %v\n", p.NodeID, err)
+			}
+		}
+	}
+}
+
+// quarantineScale 自动隔离时长的缩放：背书距离和已验证的外部身份各自缩短，取两者之积
+func quarantineScale(endorsements *trust.EndorsementManager, proofs *trust.IdentityProofManager) func(nodeID string) float64 {
+	return func(nodeID string) float64 {
+		return endorsements.QuarantineMultiplier(nodeID) * proofs.QuarantineMultiplier(nodeID)
+	}
+}
+
+// endorsementMap 将背书转换为 API 响应
+func endorsementMap(e *trust.Endorsement) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          e.ID,
+		"endorser":    e.Endorser,
+		"subject":     e.Subject,
+		"subject_key": e.SubjectKey,
+		"statement":   e.Statement,
+		"created_at":  e.CreatedAt.Format(time.RFC3339),
+		"expires_at":  e.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// endorsementMaps 批量转换背书
+func endorsementMaps(list []*trust.Endorsement) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, e := range list {
+		result = append(result, endorsementMap(e))
+	}
+	return result
+}
+
+// proofMap 将外部身份证明转换为 API 响应，附带需要发布的令牌
+func proofMap(p *trust.IdentityProof) map[string]interface{} {
+	m := map[string]interface{}{
+		"id":         p.ID,
+		"node_id":    p.NodeID,
+		"kind":       p.Kind,
+		"handle":     p.Handle,
+		"location":   p.Location,
+		"status":     p.Status,
+		"created_at": p.CreatedAt.Format(time.RFC3339),
+		"signature":  p.Signature,
+		"token":      p.Token(),
+	}
+	if p.Kind == trust.ProofDNSTXT {
+		m["dns_name"] = p.DNSName()
+	}
+	if !p.VerifiedAt.IsZero() {
+		m["verified_at"] = p.VerifiedAt.Format(time.RFC3339)
+	}
+	if p.LastError != "" {
+		m["last_error"] = p.LastError
+	}
+	return m
+}
+
+// proofOperators 以节点已验证的外部身份（kind:handle）作为运营者标识，供评审人分配排除同一运营者
+func proofOperators(pm *trust.IdentityProofManager) task.OperatorFunc {
+	return func(nodeID string) []string {
+		var ops []string
+		for _, p := range pm.GetProofsFor(nodeID) {
+			if p.Status == trust.ProofVerified {
+				ops = append(ops, string(p.Kind)+":"+strings.ToLower(p.Handle))
+			}
+		}
+		return ops
+	}
+}
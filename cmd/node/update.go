@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/release"
+)
+
+// releasePubKey 发布签名公钥（hex），由 cmd/release 构建时通过 -ldflags 注入
+var releasePubKey = ""
+
+// errReleaseKeyMissing 没有发布公钥时拒绝更新：仅凭未签名的 SHA256SUMS 无法确认归档来源
+var errReleaseKeyMissing = errors.New("未配置发布公钥，无法校验签名（确认归档来源可信时可使用 -insecure 只校验 SHA-256）")
+
+func cmdUpdate() {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	archive := fs.String("archive", "", "已下载的发布归档（必填）")
+	checksums := fs.String("checksums", "", "校验和文件 (默认: 归档同目录下的 SHA256SUMS)")
+	sigPath := fs.String("sig", "", "校验和签名文件 (默认: 校验和文件同目录下的 SHA256SUMS.sig)")
+	pubKey := fs.String("pubkey", releasePubKey, "发布签名公钥（hex）")
+	dryRun := fs.Bool("dry-run", false, "仅校验，不替换当前程序")
+	insecure := fs.Bool("insecure", false, "没有发布公钥时只校验 SHA-256（不校验签名）")
+	fs.Usage = printUpdateUsage
+	fs.Parse(os.Args[2:])
+
+	if *archive == "" {
+		printUpdateUsage()
+		os.Exit(1)
+	}
+	if *checksums == "" {
+		*checksums = filepath.Join(filepath.Dir(*archive), release.ChecksumsFile)
+	}
+	if *sigPath == "" {
+		*sigPath = filepath.Join(filepath.Dir(*checksums), release.SignatureFile)
+	}
+
+	binary, err := verifyReleaseArchive(*archive, *checksums, *sigPath, *pubKey, *insecure)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 校验失败: %v\n", err)
+		os.Exit(1)
+	}
+	if *pubKey == "" {
+		fmt.Println("⚠️  未配置发布公钥，仅校验了 SHA-256，未校验签名")
+	} else {
+		fmt.Println("✅ 签名与校验和均有效")
+	}
+
+	if *dryRun {
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法定位当前程序: %v\n", err)
+		os.Exit(1)
+	}
+	if err := replaceExecutable(exe, binary); err != nil {
+		fmt.Fprintf(os.Stderr, "替换程序失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("已更新: %s\n", exe)
+	fmt.Println("请执行 'agentnetwork restart' 使新版本生效")
+}
+
+func printUpdateUsage() {
+	fmt.Print(`用法: agentnetwork update -archive <归档> [选项]
+
+使用 cmd/release 生成的发布归档更新本程序。先校验 SHA256SUMS 的签名，
+再校验归档的 SHA-256，全部通过后才替换当前可执行文件（旧版本保留为 .old）。
+
+选项:
+  -archive    已下载的发布归档（.tar.gz 或 .zip）
+  -checksums  校验和文件 (默认: 归档同目录下的 SHA256SUMS)
+  -sig        签名文件 (默认: SHA256SUMS.sig)
+  -pubkey     发布签名公钥 (默认: 构建时嵌入的公钥)
+  -insecure   没有发布公钥时只校验 SHA-256（默认拒绝更新）
+  -dry-run    仅校验，不替换
+
+示例:
+  agentnetwork update -archive ./agentnetwork-0.2.0-linux-amd64.tar.gz
+  agentnetwork update -archive ./agentnetwork-0.2.0-linux-amd64.tar.gz -dry-run
+`)
+}
+
+// verifyReleaseArchive 校验签名和校验和，返回归档中的可执行文件内容
+// pubKeyHex 为空时返回 errReleaseKeyMissing，insecure 为 true 时才跳过签名校验
+func verifyReleaseArchive(archivePath, checksumsPath, sigPath, pubKeyHex string, insecure bool) ([]byte, error) {
+	if pubKeyHex == "" && !insecure {
+		return nil, errReleaseKeyMissing
+	}
+	data, err := os.ReadFile(checksumsPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取校验和文件: %w", err)
+	}
+
+	if pubKeyHex != "" {
+		pub, err := release.ParsePublicKey(pubKeyHex)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := os.ReadFile(sigPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取签名文件: %w", err)
+		}
+		if err := release.VerifySignature(pub, data, sig); err != nil {
+			return nil, err
+		}
+	}
+
+	sums, err := release.ParseChecksums(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := sums.VerifyFile(archivePath); err != nil {
+		return nil, err
+	}
+
+	binary, err := release.ExtractBinary(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(binary) == 0 {
+		return nil, errors.New("归档中的程序为空")
+	}
+	return binary, nil
+}
+
+// replaceExecutable 原子替换可执行文件，旧版本保留为 <exe>.old
+func replaceExecutable(exe string, binary []byte) error {
+	newPath := exe + ".new"
+	if err := os.WriteFile(newPath, binary, 0755); err != nil {
+		return err
+	}
+
+	oldPath := exe + ".old"
+	os.Remove(oldPath)
+	if err := os.Rename(exe, oldPath); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	if err := os.Rename(newPath, exe); err != nil {
+		os.Rename(oldPath, exe)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// release 交叉编译各平台节点程序并生成带签名的发布归档
+//
+// 用法:
+//
+//	go run ./cmd/release -version 0.2.0 -key release.key
+//	go run ./cmd/release -genkey
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/release"
+)
+
+func main() {
+	version := flag.String("version", "", "发布版本号（必填）")
+	outDir := flag.String("out", "build/release", "输出目录")
+	targetList := flag.String("targets", "", "目标平台，如 linux/amd64,darwin/arm64（默认: 全部）")
+	keyPath := flag.String("key", "", "签名私钥文件（hex 编码的 Ed25519 种子），为空则不签名")
+	mainPath := flag.String("main", "./cmd/node", "节点程序入口")
+	genKey := flag.Bool("genkey", false, "生成新的发布签名密钥对")
+	flag.Parse()
+
+	if *genKey {
+		pub, seed, err := release.GenerateKey()
+		if err != nil {
+			fatalf("生成密钥失败: %v", err)
+		}
+		fmt.Printf("公钥: %s\n私钥: %s\n", pub, seed)
+		fmt.Println("请将私钥保存到文件并通过 -key 指定，公钥会嵌入构建产物供 update 校验")
+		return
+	}
+
+	if *version == "" {
+		fatalf("必须指定 -version")
+	}
+
+	targets, err := release.ParseTargets(*targetList)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	var pubHex string
+	var signKey ed25519.PrivateKey
+	if *keyPath != "" {
+		data, err := os.ReadFile(*keyPath)
+		if err != nil {
+			fatalf("读取签名密钥失败: %v", err)
+		}
+		priv, err := release.ParsePrivateKey(string(data))
+		if err != nil {
+			fatalf("签名密钥无效: %v", err)
+		}
+		signKey = priv
+		pubHex = hex.EncodeToString(priv.Public().(ed25519.PublicKey))
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		fatalf("创建输出目录失败: %v", err)
+	}
+	workDir, err := os.MkdirTemp("", "agentnetwork-release-")
+	if err != nil {
+		fatalf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	buildTime := time.Now().UTC().Format(time.RFC3339)
+	ldflags := release.LDFlags(*version, buildTime, pubHex)
+	sums := make(release.Checksums)
+
+	for _, t := range targets {
+		fmt.Printf("构建 %s ...\n", t)
+		binary := filepath.Join(workDir, t.String(), t.BinaryName())
+		cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", binary, *mainPath)
+		cmd.Env = append(os.Environ(), "GOOS="+t.GOOS, "GOARCH="+t.GOARCH, "CGO_ENABLED=0")
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fatalf("构建 %s 失败: %v", t, err)
+		}
+
+		archive := filepath.Join(*outDir, t.ArchiveName(*version))
+		if err := release.WriteArchive(archive, binary, t); err != nil {
+			fatalf("打包 %s 失败: %v", t, err)
+		}
+		sum, err := release.FileChecksum(archive)
+		if err != nil {
+			fatalf("计算校验和失败: %v", err)
+		}
+		sums[filepath.Base(archive)] = sum
+		fmt.Printf("  ✓ %s\n", filepath.Base(archive))
+	}
+
+	checksums := sums.Marshal()
+	if err := os.WriteFile(filepath.Join(*outDir, release.ChecksumsFile), checksums, 0644); err != nil {
+		fatalf("写入校验和失败: %v", err)
+	}
+	if signKey != nil {
+		sig := release.Sign(signKey, checksums)
+		if err := os.WriteFile(filepath.Join(*outDir, release.SignatureFile), sig, 0644); err != nil {
+			fatalf("写入签名失败: %v", err)
+		}
+		fmt.Printf("已签名，公钥: %s\n", pubHex)
+	} else {
+		fmt.Println("⚠️  未指定 -key，发布包未签名")
+	}
+
+	fmt.Printf("完成: %s (版本 %s, 构建时间 %s)\n", *outDir, *version, buildTime)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -13,6 +13,10 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 // 错误定义
@@ -107,9 +111,11 @@ type AccusationConfig struct {
 	// 获取邻居函数
 	GetNeighborsFunc func(nodeID string) []string
 	
-	// 获取/更新声誉函数
-	GetReputationFunc    func(nodeID string) float64
-	UpdateReputationFunc func(nodeID string, delta float64) error
+	// 声誉存储（通常为 reputation.Manager），为 nil 时不检查指责者声誉、不扣减声誉
+	Reputation reputation.Store
+	
+	// 存储后端，非空时替代 accusation.json，首次加载时导入旧文件
+	Store storage.Backend
 }
 
 // DefaultAccusationConfig 返回默认配置
@@ -149,6 +155,7 @@ type AccusationManager struct {
 	analyses     map[string][]*AccusationAnalysis        // AccusationID -> []Analysis
 	tolerances   map[string]*ToleranceRecord             // AccuserNodeID -> Tolerance
 	lastDecayTime time.Time                              // 上次自然衰减时间
+	dirty        *storage.Tracker                        // 使用存储后端时，上次保存后变化的记录
 	running      bool
 	stopCh       chan struct{}
 	
@@ -183,6 +190,7 @@ func NewAccusationManager(config *AccusationConfig) (*AccusationManager, error)
 		analyses:      make(map[string][]*AccusationAnalysis),
 		tolerances:    make(map[string]*ToleranceRecord),
 		lastDecayTime: time.Now(),
+		dirty:         storage.NewTracker(),
 		stopCh:        make(chan struct{}),
 	}
 	
@@ -248,12 +256,12 @@ func (am *AccusationManager) mainLoop() {
 
 // applyNaturalDecay 应用自然衰减
 func (am *AccusationManager) applyNaturalDecay() {
-	if am.config.UpdateReputationFunc == nil {
+	if am.config.Reputation == nil {
 		return
 	}
 	
 	// 对本节点应用自然衰减
-	err := am.config.UpdateReputationFunc(am.config.NodeID, -am.config.NaturalDecayAmount)
+	err := am.config.Reputation.UpdateReputation(am.config.NodeID, -am.config.NaturalDecayAmount)
 	if err == nil && am.OnNaturalDecay != nil {
 		am.OnNaturalDecay(am.config.NodeID, am.config.NaturalDecayAmount)
 	}
@@ -274,6 +282,7 @@ func (am *AccusationManager) cleanup() {
 			acc.Status = StatusArchived
 			delete(am.accusations, id)
 			delete(am.analyses, id)
+			am.markLocked(id)
 		}
 	}
 }
@@ -290,8 +299,7 @@ func (am *AccusationManager) checkAndResetTolerances() {
 			record.RemainingTolerance = record.MaxTolerance
 			record.LastResetTime = now
 			record.NextResetTime = now.Add(am.config.ToleranceResetPeriod)
-			
-			_ = accuserID // 避免未使用警告
+			am.dirty.Mark("tolerances", accuserID)
 		}
 	}
 }
@@ -307,8 +315,8 @@ func (am *AccusationManager) CreateAccusation(accused string, accusationType Acc
 	
 	// 检查指责者声誉
 	var accuserRep float64 = 50.0
-	if am.config.GetReputationFunc != nil {
-		accuserRep = am.config.GetReputationFunc(am.config.NodeID)
+	if am.config.Reputation != nil {
+		accuserRep = am.config.Reputation.GetReputation(am.config.NodeID)
 		if accuserRep < am.config.MinAccuserReputation {
 			return nil, ErrLowReputation
 		}
@@ -359,11 +367,12 @@ func (am *AccusationManager) CreateAccusation(accused string, accusationType Acc
 	am.mu.Lock()
 	am.accusations[accusationID] = acc
 	am.analyses[accusationID] = make([]*AccusationAnalysis, 0)
+	am.markLocked(accusationID)
 	am.mu.Unlock()
 	
 	// 扣除指责者声誉（代价）
-	if am.config.UpdateReputationFunc != nil {
-		am.config.UpdateReputationFunc(am.config.NodeID, -accuserCost)
+	if am.config.Reputation != nil {
+		am.config.Reputation.UpdateReputation(am.config.NodeID, -accuserCost)
 	}
 	
 	// 保存
@@ -442,6 +451,7 @@ func (am *AccusationManager) PropagateAccusation(accusationID string) ([]string,
 	am.mu.Lock()
 	acc.PropagatedTo = append(acc.PropagatedTo, propagatedTo...)
 	acc.Status = StatusDelivered
+	am.markLocked(accusationID)
 	am.mu.Unlock()
 	
 	am.save()
@@ -514,6 +524,8 @@ func (am *AccusationManager) ReceiveAccusation(acc *Accusation, fromNode string)
 	// 存储
 	am.accusations[acc.AccusationID] = acc
 	am.analyses[acc.AccusationID] = make([]*AccusationAnalysis, 0)
+	am.markLocked(acc.AccusationID)
+	am.dirty.Mark("tolerances", acc.Accuser)
 	
 	am.mu.Unlock()
 	
@@ -565,12 +577,13 @@ func (am *AccusationManager) AnalyzeAccusation(accusationID string, accepted boo
 	} else {
 		acc.Status = StatusRejected
 	}
+	am.markLocked(accusationID)
 	
 	am.mu.Unlock()
 	
 	// 如果接受，应用惩罚
-	if accepted && am.config.UpdateReputationFunc != nil {
-		am.config.UpdateReputationFunc(acc.Accused, -decayedPenalty)
+	if accepted && am.config.Reputation != nil {
+		am.config.Reputation.UpdateReputation(acc.Accused, -decayedPenalty)
 	}
 	
 	// 保存
@@ -695,6 +708,7 @@ func (am *AccusationManager) SetTolerance(accuserID string, tolerance float64) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 	
+	am.dirty.Mark("tolerances", accuserID)
 	now := time.Now()
 	if record, ok := am.tolerances[accuserID]; ok {
 		record.MaxTolerance = tolerance
@@ -729,6 +743,7 @@ func (am *AccusationManager) ResetTolerance(accuserID string) error {
 	record.RemainingTolerance = record.MaxTolerance
 	record.LastResetTime = now
 	record.NextResetTime = now.Add(am.config.ToleranceResetPeriod)
+	am.dirty.Mark("tolerances", accuserID)
 	
 	return nil
 }
@@ -767,6 +782,7 @@ func (am *AccusationManager) ContinuePropagation(accusationID string) ([]string,
 	
 	am.mu.Lock()
 	acc.PropagatedTo = append(acc.PropagatedTo, propagatedTo...)
+	am.markLocked(accusationID)
 	am.mu.Unlock()
 	
 	am.save()
@@ -820,6 +836,20 @@ func (am *AccusationManager) GetStats() *AccusationStats {
 	return stats
 }
 
+// Stats 实现 stats.Provider
+func (am *AccusationManager) Stats() []stats.Metric {
+	s := am.GetStats()
+	return []stats.Metric{
+		stats.Counter("accusations_total", float64(s.TotalAccusations), "累计指责数"),
+		stats.Gauge("pending_accusations", float64(s.PendingAccusations), "待验证的指责数"),
+		stats.Gauge("verified_accusations", float64(s.VerifiedAccusations), "已成立的指责数"),
+		stats.Gauge("rejected_accusations", float64(s.RejectedAccusations), "被驳回的指责数"),
+		stats.Counter("penalty_applied_total", s.TotalPenaltyApplied, "累计施加的惩罚"),
+		stats.Counter("accuser_cost_total", s.TotalAccuserCost, "指责方累计付出的成本"),
+		stats.Gauge("active_tolerances", float64(s.ActiveTolerances), "容忍记录数"),
+	}
+}
+
 // SetDecayFactor 设置衰减因子
 func (am *AccusationManager) SetDecayFactor(factor float64) {
 	am.mu.Lock()
@@ -851,17 +881,22 @@ type persistState struct {
 
 // save 保存数据
 func (am *AccusationManager) save() error {
+	if am.config.Store != nil {
+		am.mu.RLock()
+		snap, err := storage.EncodeState(storePrefix, am.stateLocked(), am.dirty)
+		am.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		_, err = snap.Write(am.config.Store)
+		return err
+	}
 	if am.config.DataDir == "" {
 		return nil
 	}
 	
 	am.mu.RLock()
-	state := &persistState{
-		Accusations:   am.accusations,
-		Analyses:      am.analyses,
-		Tolerances:    am.tolerances,
-		LastDecayTime: am.lastDecayTime,
-	}
+	state := am.stateLocked()
 	am.mu.RUnlock()
 	
 	data, err := json.MarshalIndent(state, "", "  ")
@@ -873,8 +908,56 @@ func (am *AccusationManager) save() error {
 	return os.WriteFile(filePath, data, 0644)
 }
 
-// load 加载数据
+// stateLocked 当前持久化状态
+func (am *AccusationManager) stateLocked() *persistState {
+	return &persistState{
+		Accusations:   am.accusations,
+		Analyses:      am.analyses,
+		Tolerances:    am.tolerances,
+		LastDecayTime: am.lastDecayTime,
+	}
+}
+
+// markLocked 记下变化的指责，指责和分析按同一个ID保存
+func (am *AccusationManager) markLocked(accusationID string) {
+	am.dirty.Mark("accusations", accusationID)
+	am.dirty.Mark("analyses", accusationID)
+}
+
+// storePrefix 指责数据在存储后端中的 bucket 前缀
+const storePrefix = "accusation"
+
+// load 加载数据：使用存储后端时，后端为空则导入旧的 accusation.json
 func (am *AccusationManager) load() error {
+	store := am.config.Store
+	if store == nil {
+		return am.loadFile()
+	}
+	
+	var state persistState
+	found, err := storage.LoadState(store, storePrefix, &state)
+	if err != nil {
+		return err
+	}
+	if found {
+		am.applyState(&state)
+		return nil
+	}
+	if am.config.DataDir == "" {
+		return nil
+	}
+	if err := am.loadFile(); err != nil {
+		return err
+	}
+	am.dirty.MarkAll()
+	if err := am.save(); err != nil {
+		return err
+	}
+	return storage.RetireLegacy(filepath.Join(am.config.DataDir, "accusation.json"))
+}
+
+// loadFile 从 accusation.json 加载
+func (am *AccusationManager) loadFile() error {
 	if am.config.DataDir == "" {
 		return nil
 	}
@@ -893,6 +976,12 @@ func (am *AccusationManager) load() error {
 		return err
 	}
 	
+	am.applyState(&state)
+	return nil
+}
+
+// applyState 用加载的状态替换内存数据
+func (am *AccusationManager) applyState(state *persistState) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
 	
@@ -908,8 +997,6 @@ func (am *AccusationManager) load() error {
 	if !state.LastDecayTime.IsZero() {
 		am.lastDecayTime = state.LastDecayTime
 	}
-	
-	return nil
 }
 
 // Clear 清空所有数据
@@ -920,4 +1007,5 @@ func (am *AccusationManager) Clear() {
 	am.accusations = make(map[string]*Accusation)
 	am.analyses = make(map[string][]*AccusationAnalysis)
 	am.tolerances = make(map[string]*ToleranceRecord)
+	am.dirty.MarkAll()
 }
@@ -6,6 +6,9 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 // 测试工具函数
@@ -70,9 +73,9 @@ func TestDefaultAccusationConfig(t *testing.T) {
 func TestCreateAccusation(t *testing.T) {
 	config := DefaultAccusationConfig("accuser1")
 	config.DataDir = tempDir(t)
-	config.GetReputationFunc = func(nodeID string) float64 {
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 		return 50.0
-	}
+	}}
 	
 	am, err := NewAccusationManager(config)
 	if err != nil {
@@ -96,9 +99,9 @@ func TestCreateAccusation(t *testing.T) {
 	t.Run("low reputation", func(t *testing.T) {
 		config2 := DefaultAccusationConfig("accuser2")
 		config2.DataDir = tempDir(t)
-		config2.GetReputationFunc = func(nodeID string) float64 {
+		config2.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 			return 10.0 // 低于最低要求
-		}
+		}}
 		
 		am2, _ := NewAccusationManager(config2)
 		_, err := am2.CreateAccusation("accused1", TypeTaskCheating, "test reason", "")
@@ -214,13 +217,13 @@ func TestAnalyzeAccusation(t *testing.T) {
 		delta  float64
 	}
 	
-	config.UpdateReputationFunc = func(nodeID string, delta float64) error {
+	config.Reputation = reputation.StoreFuncs{Update: func(nodeID string, delta float64) error {
 		reputationUpdates = append(reputationUpdates, struct {
 			nodeID string
 			delta  float64
 		}{nodeID, delta})
 		return nil
-	}
+	}}
 	
 	am, _ := NewAccusationManager(config)
 	
@@ -369,9 +372,9 @@ func TestResetTolerance(t *testing.T) {
 func TestPropagate(t *testing.T) {
 	config := DefaultAccusationConfig("node1")
 	config.DataDir = tempDir(t)
-	config.GetReputationFunc = func(nodeID string) float64 {
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 		return 50.0
-	}
+	}}
 	config.GetNeighborsFunc = func(nodeID string) []string {
 		return []string{"neighbor1", "neighbor2", "neighbor3"}
 	}
@@ -633,12 +636,60 @@ func TestPersistence(t *testing.T) {
 	}
 }
 
+func TestStoreBackend(t *testing.T) {
+	dir := t.TempDir()
+	
+	// 旧版 accusation.json
+	legacy := DefaultAccusationConfig("node1")
+	legacy.DataDir = dir
+	am1, _ := NewAccusationManager(legacy)
+	am1.mu.Lock()
+	am1.accusations["old"] = &Accusation{AccusationID: "old", Accuser: "a", Accused: "b", Status: StatusVerified}
+	am1.mu.Unlock()
+	am1.save()
+	
+	store, err := storage.OpenLogDB(filepath.Join(dir, "node.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	config := DefaultAccusationConfig("node1")
+	config.DataDir = dir
+	config.Store = store
+	
+	// 首次加载导入旧文件
+	am2, _ := NewAccusationManager(config)
+	if _, err := am2.GetAccusation("old"); err != nil {
+		t.Fatalf("legacy accusation not imported: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "accusation.json")); !os.IsNotExist(err) {
+		t.Error("legacy file should be retired after import")
+	}
+	if store.Len("accusation/accusations") != 1 {
+		t.Errorf("store holds %d accusations, want 1", store.Len("accusation/accusations"))
+	}
+	
+	am2.mu.Lock()
+	am2.accusations["new"] = &Accusation{AccusationID: "new", Accuser: "c", Accused: "d", Status: StatusPending}
+	am2.markLocked("new")
+	am2.mu.Unlock()
+	am2.save()
+	
+	am3, _ := NewAccusationManager(config)
+	if _, err := am3.GetAccusation("new"); err != nil {
+		t.Errorf("accusation saved to the store not loaded: %v", err)
+	}
+	if _, err := am3.GetAccusation("old"); err != nil {
+		t.Errorf("imported accusation lost: %v", err)
+	}
+}
+
 func TestSignatureValidation(t *testing.T) {
 	config := DefaultAccusationConfig("node1")
 	config.DataDir = tempDir(t)
-	config.GetReputationFunc = func(nodeID string) float64 {
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 		return 50.0
-	}
+	}}
 	
 	signed := false
 	config.SignFunc = func(data []byte) (string, error) {
@@ -714,9 +765,9 @@ func TestVerifySignature(t *testing.T) {
 func TestCallbacks(t *testing.T) {
 	config := DefaultAccusationConfig("node1")
 	config.DataDir = tempDir(t)
-	config.GetReputationFunc = func(nodeID string) float64 {
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 		return 50.0
-	}
+	}}
 	
 	var createdAcc *Accusation
 	var receivedAcc *Accusation
@@ -826,9 +877,9 @@ func TestSetters(t *testing.T) {
 func TestAccusationTypes(t *testing.T) {
 	config := DefaultAccusationConfig("node1")
 	config.DataDir = tempDir(t)
-	config.GetReputationFunc = func(nodeID string) float64 {
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 {
 		return 50.0
-	}
+	}}
 	
 	am, _ := NewAccusationManager(config)
 	
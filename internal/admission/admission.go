@@ -0,0 +1,467 @@
+// Package admission 入站连接准入（关系证明）
+//
+// 开启后，连入本节点的节点必须满足以下任一条件，否则在 libp2p 完成安全握手后、
+// 任何应用协议之前断开：
+//   - 凭创世邀请码加入了网络（邀请树中登记的 libp2p 节点）；
+//   - 持有本节点或受信节点签发、尚未过期的邀请；
+//   - 持有本节点或受信节点签发的有效背书；
+//   - 本节点记录过它的声誉，且不低于 MinReputation。
+//
+// 受信节点由调用方给出（通常是本节点直接背书的节点）。本节点主动发起的连接不受限制。
+package admission
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// 默认参数
+const (
+	DefaultInvitationTTL = 7 * 24 * time.Hour
+	DefaultMinReputation = 100.0
+	DefaultMaxDecisions  = 100
+)
+
+// 准入错误
+var (
+	ErrNilConfig          = errors.New("admission config cannot be nil")
+	ErrEmptyNodeID        = errors.New("node ID cannot be empty")
+	ErrEmptyInvitee       = errors.New("invitee cannot be empty")
+	ErrSelfInvitation     = errors.New("cannot invite self")
+	ErrNoSignFunc         = errors.New("invitation sign function not set")
+	ErrInvalidInvitation  = errors.New("invalid invitation signature")
+	ErrInvitationExpired  = errors.New("invitation expired")
+	ErrUntrustedInviter   = errors.New("inviter is not a trusted peer")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrNotInviter         = errors.New("only the inviter can revoke an invitation")
+)
+
+// Reason 准入判定依据
+type Reason string
+
+const (
+	ReasonOpen        Reason = "open"        // 未开启关系证明
+	ReasonAllowList   Reason = "allowlist"   // 配置中始终允许的节点
+	ReasonMember      Reason = "member"      // 凭创世邀请码加入网络
+	ReasonInvitation  Reason = "invitation"  // 持有有效邀请
+	ReasonEndorsement Reason = "endorsement" // 受信节点背书
+	ReasonReputation  Reason = "reputation"  // 声誉达到下限
+	ReasonDenied      Reason = "denied"      // 没有任何关系证明
+)
+
+// Invitation 邀请：邀请人允许被邀请节点在过期前连入
+type Invitation struct {
+	ID        string    `json:"id"`
+	Inviter   string    `json:"inviter"` // 邀请人节点ID
+	Invitee   string    `json:"invitee"` // 被邀请节点ID
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Signature []byte    `json:"signature"`
+}
+
+// SignData 邀请签名的原始数据
+func (inv *Invitation) SignData() []byte {
+	return []byte(fmt.Sprintf("invite|%s|%s|%s|%d|%d",
+		inv.Inviter, inv.Invitee, inv.Note, inv.CreatedAt.Unix(), inv.ExpiresAt.Unix()))
+}
+
+// Valid 邀请在指定时间是否有效
+func (inv *Invitation) Valid(now time.Time) bool {
+	return now.Before(inv.ExpiresAt)
+}
+
+// Decision 一次准入判定
+type Decision struct {
+	PeerID  string    `json:"peer_id"`
+	Allowed bool      `json:"allowed"`
+	Reason  Reason    `json:"reason"`
+	Detail  string    `json:"detail,omitempty"` // 邀请ID、背书人或声誉分
+	At      time.Time `json:"at"`
+}
+
+// Config 准入配置
+type Config struct {
+	NodeID        string
+	DataDir       string
+	Enabled       bool          // 是否要求关系证明，关闭时所有入站连接都被接受
+	MinReputation float64       // 声誉下限，0 表示不按声誉准入
+	InvitationTTL time.Duration // 邀请默认有效期
+	Allow         []string      // 始终允许连入的节点（如引导节点）
+	MaxDecisions  int           // 保留的最近判定条数
+
+	// 签名函数（本节点私钥）
+	SignFunc func(data []byte) ([]byte, error)
+	// 验签函数（根据邀请人节点ID校验）
+	VerifyFunc func(nodeID string, data, signature []byte) (bool, error)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:        nodeID,
+		DataDir:       "./data/admission",
+		MinReputation: DefaultMinReputation,
+		InvitationTTL: DefaultInvitationTTL,
+		MaxDecisions:  DefaultMaxDecisions,
+	}
+}
+
+// Status 准入状态
+type Status struct {
+	Enabled       bool        `json:"enabled"`
+	MinReputation float64     `json:"min_reputation"`
+	Invitations   int         `json:"invitations"` // 有效邀请数
+	Admitted      int64       `json:"admitted"`
+	Denied        int64       `json:"denied"`
+	Recent        []*Decision `json:"recent"` // 最近的判定，新的在前
+}
+
+// Manager 入站连接准入管理器
+type Manager struct {
+	mu          sync.RWMutex
+	config      *Config
+	allow       map[string]bool
+	invitations map[string]*Invitation // ID -> Invitation
+
+	trusted    func(nodeID string) bool
+	member     func(peerID string) bool
+	endorsers  func(subject string) []string
+	reputation func(nodeID string) (float64, bool)
+
+	decisions []*Decision
+	admitted  int64
+	denied    int64
+}
+
+// New 创建准入管理器
+func New(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+	if config.InvitationTTL <= 0 {
+		config.InvitationTTL = DefaultInvitationTTL
+	}
+	if config.MaxDecisions <= 0 {
+		config.MaxDecisions = DefaultMaxDecisions
+	}
+
+	m := &Manager{
+		config:      config,
+		allow:       make(map[string]bool),
+		invitations: make(map[string]*Invitation),
+	}
+	for _, id := range config.Allow {
+		m.allow[id] = true
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		m.load()
+	}
+	return m, nil
+}
+
+// SetTrustedFunc 设置受信节点判断：受信节点签发的邀请和背书可用于准入
+func (m *Manager) SetTrustedFunc(fn func(nodeID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.trusted = fn
+}
+
+// SetMemberFunc 设置网络成员判断：凭创世邀请码加入的节点可以连入
+func (m *Manager) SetMemberFunc(fn func(peerID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.member = fn
+}
+
+// SetEndorsersFunc 设置查询节点有效背书人的函数
+func (m *Manager) SetEndorsersFunc(fn func(subject string) []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endorsers = fn
+}
+
+// SetReputationFunc 设置声誉查询函数，第二个返回值表示本节点是否记录过该节点的声誉
+func (m *Manager) SetReputationFunc(fn func(nodeID string) (float64, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reputation = fn
+}
+
+// Invite 签发邀请，允许 invitee 在 ttl 内连入；ttl 为 0 时使用默认有效期
+func (m *Manager) Invite(invitee, note string, ttl time.Duration) (*Invitation, error) {
+	if invitee == "" {
+		return nil, ErrEmptyInvitee
+	}
+	if invitee == m.config.NodeID {
+		return nil, ErrSelfInvitation
+	}
+	if m.config.SignFunc == nil {
+		return nil, ErrNoSignFunc
+	}
+	if ttl <= 0 {
+		ttl = m.config.InvitationTTL
+	}
+
+	now := time.Now()
+	inv := &Invitation{
+		Inviter:   m.config.NodeID,
+		Invitee:   invitee,
+		Note:      note,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	inv.ID = invitationID(inv)
+	sig, err := m.config.SignFunc(inv.SignData())
+	if err != nil {
+		return nil, fmt.Errorf("签名邀请失败: %w", err)
+	}
+	inv.Signature = sig
+
+	m.mu.Lock()
+	m.invitations[inv.ID] = inv
+	m.save()
+	m.mu.Unlock()
+
+	c := *inv
+	return &c, nil
+}
+
+// AddInvitation 添加受信节点签发的邀请（校验签名），返回保存的邀请
+func (m *Manager) AddInvitation(inv *Invitation) (*Invitation, error) {
+	if inv == nil || inv.Inviter == "" || inv.Invitee == "" {
+		return nil, ErrInvalidInvitation
+	}
+	if !inv.Valid(time.Now()) {
+		return nil, ErrInvitationExpired
+	}
+	if !m.isTrusted(inv.Inviter) {
+		return nil, ErrUntrustedInviter
+	}
+	if m.config.VerifyFunc != nil {
+		ok, err := m.config.VerifyFunc(inv.Inviter, inv.SignData(), inv.Signature)
+		if err != nil || !ok {
+			return nil, ErrInvalidInvitation
+		}
+	}
+
+	c := *inv
+	c.ID = invitationID(&c)
+
+	m.mu.Lock()
+	m.invitations[c.ID] = &c
+	m.save()
+	m.mu.Unlock()
+
+	result := c
+	return &result, nil
+}
+
+// RevokeInvitation 撤销本节点签发的邀请
+func (m *Manager) RevokeInvitation(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inv, ok := m.invitations[id]
+	if !ok {
+		return ErrInvitationNotFound
+	}
+	if inv.Inviter != m.config.NodeID {
+		return ErrNotInviter
+	}
+	delete(m.invitations, id)
+	m.save()
+	return nil
+}
+
+// Invitations 列出有效邀请；invitee 非空时只返回邀请该节点的
+func (m *Manager) Invitations(invitee string) []*Invitation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var result []*Invitation
+	for _, inv := range m.invitations {
+		if inv.Valid(now) && (invitee == "" || inv.Invitee == invitee) {
+			c := *inv
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// Evaluate 判断节点能否连入，不记录判定
+func (m *Manager) Evaluate(peerID string) *Decision {
+	d := &Decision{PeerID: peerID, Allowed: true, At: time.Now()}
+
+	m.mu.RLock()
+	enabled, allowed := m.config.Enabled, m.allow[peerID]
+	var invitations []*Invitation
+	for _, inv := range m.invitations {
+		if inv.Invitee == peerID && inv.Valid(d.At) {
+			invitations = append(invitations, inv)
+		}
+	}
+	member, endorsers, reputation := m.member, m.endorsers, m.reputation
+	m.mu.RUnlock()
+
+	switch {
+	case !enabled:
+		d.Reason = ReasonOpen
+		return d
+	case allowed:
+		d.Reason = ReasonAllowList
+		return d
+	case member != nil && member(peerID):
+		d.Reason = ReasonMember
+		return d
+	}
+
+	// 受信关系可能在签发后被撤销，邀请人和背书人都按当前的受信节点判断
+	for _, inv := range invitations {
+		if m.isTrusted(inv.Inviter) {
+			d.Reason, d.Detail = ReasonInvitation, inv.ID
+			return d
+		}
+	}
+	if endorsers != nil {
+		for _, endorser := range endorsers(peerID) {
+			if m.isTrusted(endorser) {
+				d.Reason, d.Detail = ReasonEndorsement, endorser
+				return d
+			}
+		}
+	}
+	if min := m.config.MinReputation; min > 0 && reputation != nil {
+		if score, ok := reputation(peerID); ok && score >= min {
+			d.Reason, d.Detail = ReasonReputation, fmt.Sprintf("%.2f", score)
+			return d
+		}
+	}
+
+	d.Allowed, d.Reason = false, ReasonDenied
+	return d
+}
+
+// Admit 判断入站连接能否建立并记录判定，供连接网关调用
+func (m *Manager) Admit(peerID string) bool {
+	d := m.Evaluate(peerID)
+	if d.Reason == ReasonOpen {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d.Allowed {
+		m.admitted++
+	} else {
+		m.denied++
+	}
+	m.decisions = append(m.decisions, d)
+	if len(m.decisions) > m.config.MaxDecisions {
+		m.decisions = m.decisions[len(m.decisions)-m.config.MaxDecisions:]
+	}
+	return d.Allowed
+}
+
+// Status 返回准入状态
+func (m *Manager) Status() *Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	s := &Status{
+		Enabled:       m.config.Enabled,
+		MinReputation: m.config.MinReputation,
+		Admitted:      m.admitted,
+		Denied:        m.denied,
+		Recent:        make([]*Decision, 0, len(m.decisions)),
+	}
+	for _, inv := range m.invitations {
+		if inv.Valid(now) {
+			s.Invitations++
+		}
+	}
+	for i := len(m.decisions) - 1; i >= 0; i-- {
+		d := *m.decisions[i]
+		s.Recent = append(s.Recent, &d)
+	}
+	return s
+}
+
+// Stats 实现 stats.Provider
+func (m *Manager) Stats() []stats.Metric {
+	s := m.Status()
+	return []stats.Metric{
+		stats.Counter("admitted", float64(s.Admitted), "通过关系证明连入的节点数"),
+		stats.Counter("denied", float64(s.Denied), "没有关系证明被拒绝的入站连接数"),
+		stats.Gauge("invitations", float64(s.Invitations), "有效邀请数"),
+	}
+}
+
+// isTrusted 本节点和受信节点签发的邀请、背书有效
+func (m *Manager) isTrusted(nodeID string) bool {
+	if nodeID == m.config.NodeID {
+		return true
+	}
+	m.mu.RLock()
+	trusted := m.trusted
+	m.mu.RUnlock()
+	return trusted != nil && trusted(nodeID)
+}
+
+func invitationID(inv *Invitation) string {
+	hash := sha256.Sum256(inv.SignData())
+	return hex.EncodeToString(hash[:16])
+}
+
+// save 保存邀请，过期的邀请不再保存（调用方持有锁）
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	now := time.Now()
+	list := make([]*Invitation, 0, len(m.invitations))
+	for id, inv := range m.invitations {
+		if !inv.Valid(now) {
+			delete(m.invitations, id)
+			continue
+		}
+		list = append(list, inv)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "invitations.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "invitations.json"))
+	if err != nil {
+		return
+	}
+	var list []*Invitation
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, inv := range list {
+		m.invitations[inv.ID] = inv
+	}
+}
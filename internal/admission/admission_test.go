@@ -0,0 +1,193 @@
+package admission
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试用签名：节点ID + 数据哈希
+func testSign(nodeID string) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		sum := sha256.Sum256(data)
+		return append([]byte(nodeID+":"), sum[:]...), nil
+	}
+}
+
+func testVerify(nodeID string, data, signature []byte) (bool, error) {
+	want, _ := testSign(nodeID)(data)
+	return string(want) == string(signature), nil
+}
+
+func newTestManager(t *testing.T, nodeID string) *Manager {
+	t.Helper()
+	config := DefaultConfig(nodeID)
+	config.DataDir = t.TempDir()
+	config.Enabled = true
+	config.SignFunc = testSign(nodeID)
+	config.VerifyFunc = testVerify
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("New(nil) error = %v", err)
+	}
+	if _, err := New(&Config{}); !errors.Is(err, ErrEmptyNodeID) {
+		t.Errorf("New(empty) error = %v", err)
+	}
+}
+
+func TestAdmitOpen(t *testing.T) {
+	config := DefaultConfig("self")
+	config.DataDir = ""
+	m, _ := New(config)
+	if !m.Admit("stranger") {
+		t.Error("stranger rejected while gating is disabled")
+	}
+	if s := m.Status(); s.Admitted != 0 || len(s.Recent) != 0 {
+		t.Errorf("open admissions recorded: %+v", s)
+	}
+}
+
+func TestAdmitInvitation(t *testing.T) {
+	m := newTestManager(t, "self")
+	if m.Admit("invitee") {
+		t.Fatal("invitee admitted without proof")
+	}
+
+	inv, err := m.Invite("invitee", "new operator", time.Hour)
+	if err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	if _, err := m.Invite("self", "", 0); !errors.Is(err, ErrSelfInvitation) {
+		t.Errorf("self invitation error = %v", err)
+	}
+	d := m.Evaluate("invitee")
+	if !d.Allowed || d.Reason != ReasonInvitation || d.Detail != inv.ID {
+		t.Errorf("decision = %+v", d)
+	}
+
+	// 邀请持久化
+	reloaded, _ := New(m.config)
+	if got := reloaded.Invitations("invitee"); len(got) != 1 || got[0].ID != inv.ID {
+		t.Errorf("reloaded invitations = %+v", got)
+	}
+
+	if err := m.RevokeInvitation(inv.ID); err != nil {
+		t.Fatalf("RevokeInvitation() error = %v", err)
+	}
+	if m.Admit("invitee") {
+		t.Error("invitee admitted after revocation")
+	}
+}
+
+func TestAddInvitation(t *testing.T) {
+	m := newTestManager(t, "self")
+	friend := newTestManager(t, "friend")
+	trusted := map[string]bool{"friend": true}
+	m.SetTrustedFunc(func(id string) bool { return trusted[id] })
+
+	inv, _ := friend.Invite("invitee", "", time.Hour)
+	forged := *inv
+	forged.Invitee = "mallory"
+	if _, err := m.AddInvitation(&forged); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("forged invitation error = %v", err)
+	}
+	stranger := newTestManager(t, "stranger")
+	other, _ := stranger.Invite("invitee", "", time.Hour)
+	if _, err := m.AddInvitation(other); !errors.Is(err, ErrUntrustedInviter) {
+		t.Errorf("untrusted inviter error = %v", err)
+	}
+	expired := *inv
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := m.AddInvitation(&expired); !errors.Is(err, ErrInvitationExpired) {
+		t.Errorf("expired invitation error = %v", err)
+	}
+
+	if _, err := m.AddInvitation(inv); err != nil {
+		t.Fatalf("AddInvitation() error = %v", err)
+	}
+	if err := m.RevokeInvitation(inv.ID); !errors.Is(err, ErrNotInviter) {
+		t.Errorf("revoke foreign invitation error = %v", err)
+	}
+	if !m.Admit("invitee") {
+		t.Error("invitee of trusted peer rejected")
+	}
+
+	// 邀请人不再受信后邀请失效
+	trusted["friend"] = false
+	if m.Admit("invitee") {
+		t.Error("invitation from no longer trusted peer accepted")
+	}
+}
+
+func TestAdmitEndorsementAndReputation(t *testing.T) {
+	m := newTestManager(t, "self")
+	m.SetTrustedFunc(func(id string) bool { return id == "friend" })
+	m.SetEndorsersFunc(func(subject string) []string {
+		return map[string][]string{
+			"endorsed":  {"stranger", "friend"},
+			"by-self":   {"self"},
+			"by-others": {"stranger"},
+		}[subject]
+	})
+	m.SetMemberFunc(func(id string) bool { return id == "member" })
+	m.SetReputationFunc(func(id string) (float64, bool) {
+		switch id {
+		case "reputable":
+			return 150, true
+		case "newcomer":
+			return 10, true
+		}
+		return 0, false
+	})
+
+	tests := []struct {
+		peer   string
+		reason Reason
+	}{
+		{"member", ReasonMember},
+		{"endorsed", ReasonEndorsement},
+		{"by-self", ReasonEndorsement},
+		{"by-others", ReasonDenied},
+		{"reputable", ReasonReputation},
+		{"newcomer", ReasonDenied},
+		{"unknown", ReasonDenied},
+	}
+	for _, tt := range tests {
+		d := m.Evaluate(tt.peer)
+		if d.Reason != tt.reason || d.Allowed != (tt.reason != ReasonDenied) {
+			t.Errorf("Evaluate(%s) = %+v, want %s", tt.peer, d, tt.reason)
+		}
+	}
+	if d := m.Evaluate("endorsed"); d.Detail != "friend" {
+		t.Errorf("endorser = %q, want friend", d.Detail)
+	}
+}
+
+func TestAdmitStatus(t *testing.T) {
+	config := DefaultConfig("self")
+	config.DataDir = ""
+	config.Enabled = true
+	config.Allow = []string{"bootstrap"}
+	config.MaxDecisions = 2
+	m, _ := New(config)
+
+	m.Admit("bootstrap")
+	m.Admit("stranger")
+	m.Admit("other")
+
+	s := m.Status()
+	if s.Admitted != 1 || s.Denied != 2 || len(s.Recent) != 2 {
+		t.Fatalf("status = %+v", s)
+	}
+	if s.Recent[0].PeerID != "other" || s.Recent[1].PeerID != "stranger" {
+		t.Errorf("recent = %s, %s", s.Recent[0].PeerID, s.Recent[1].PeerID)
+	}
+}
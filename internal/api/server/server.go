@@ -9,6 +9,7 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/node"
 )
@@ -22,6 +23,9 @@ const (
 	StatusBusy    NodeStatus = "busy"
 )
 
+// stopGracePeriod 停止时等待进行中调用结束的时长
+const stopGracePeriod = 5 * time.Second
+
 // NodeEntry 节点条目
 type NodeEntry struct {
 	NodeID       string
@@ -43,8 +47,15 @@ type Server struct {
 
 	mu    sync.RWMutex
 	nodes map[string]*NodeEntry
+
+	stream *messageStream // 消息流服务，EnableMessageStream 后注册
+
+	observeLatency LatencyObserver // 调用耗时观测，SetLatencyObserver 后启用
 }
 
+// LatencyObserver 调用耗时观测：method 为完整方法名，code 为 gRPC 状态码名称
+type LatencyObserver func(method, code string, elapsed time.Duration)
+
 // NewServer 创建 gRPC 服务器
 func NewServer(n *node.Node, listenAddr string) *Server {
 	return &Server{
@@ -61,8 +72,18 @@ func (s *Server) Start() error {
 		return fmt.Errorf("监听失败: %w", err)
 	}
 
-	s.grpcServer = grpc.NewServer()
+	var opts []grpc.ServerOption
+	if s.observeLatency != nil {
+		opts = append(opts, grpc.ChainUnaryInterceptor(s.unaryLatency), grpc.ChainStreamInterceptor(s.streamLatency))
+	}
+	if s.stream != nil {
+		opts = append(opts, grpc.ForceServerCodec(streamCodec{}), grpc.StreamInterceptor(s.stream.streamInterceptor))
+	}
+	s.grpcServer = grpc.NewServer(opts...)
 	RegisterToolNetworkServer(s.grpcServer, s)
+	if s.stream != nil {
+		RegisterMessageStreamServer(s.grpcServer, s.stream)
+	}
 
 	fmt.Printf("🌐 gRPC 服务启动: %s\n", s.listenAddr)
 
@@ -75,10 +96,46 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// EnableMessageStream 启用消息流服务（SubscribeMessages、SendMessageStream），须在 Start 之前调用
+func (s *Server) EnableMessageStream(config *MessageStreamConfig) {
+	s.stream = newMessageStream(config)
+}
+
+// SetLatencyObserver 设置调用耗时观测（如导出为 Prometheus 直方图），须在 Start 之前调用
+func (s *Server) SetLatencyObserver(fn LatencyObserver) {
+	s.observeLatency = fn
+}
+
+func (s *Server) unaryLatency(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.observeLatency(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return resp, err
+}
+
+// streamLatency 流式调用按整个流的持续时间记录
+func (s *Server) streamLatency(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.observeLatency(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return err
+}
+
 // Stop 停止 gRPC 服务器
 func (s *Server) Stop() {
-	if s.grpcServer != nil {
+	if s.grpcServer == nil {
+		return
+	}
+	// 消息流是长连接，不会自行结束，等待超时后强制关闭
+	done := make(chan struct{})
+	go func() {
 		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(stopGracePeriod):
+		s.grpcServer.Stop()
 	}
 }
 
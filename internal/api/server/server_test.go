@@ -4,6 +4,10 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNodeStatus_Constants(t *testing.T) {
@@ -182,3 +186,22 @@ func TestUnimplementedToolNetworkServer(t *testing.T) {
 		t.Error("Heartbeat 应该返回 nil")
 	}
 }
+
+func TestLatencyObserver(t *testing.T) {
+	s := NewServer(nil, ":0")
+	var method, code string
+	s.SetLatencyObserver(func(m, c string, elapsed time.Duration) {
+		method, code = m, c
+	})
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/agentnetwork.ToolNetwork/GetNodeInfo"}
+	_, err := s.unaryLatency(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "节点不存在")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("应透传处理函数的错误, 得到 %v", err)
+	}
+	if method != info.FullMethod || code != "NotFound" {
+		t.Errorf("观测结果错误: method=%q code=%q", method, code)
+	}
+}
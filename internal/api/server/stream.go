@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// 消息流
+//
+// Agent 进程与节点保持一条 gRPC 长连接，不必轮询 HTTP API：
+// SubscribeMessages 推送新邮件、留言、任务和声誉事件（与 HTTP 事件流同源），
+// SendMessageStream 在同一连接上连续发送邮件和留言，每条请求返回一条结果。
+//
+// 推送按客户端授予的额度进行：每推送一个事件消耗一个额度，客户端处理完后再追加。
+// 额度用完时事件留在节点的事件缓冲中；落后超过缓冲时先推送 resync 事件，客户端应重新拉取状态。
+
+const (
+	defaultStreamCredits = 64      // 首条订阅请求未指定额度时的初始额度
+	maxStreamCredits     = 1 << 20 // 累计额度上限
+
+	// eventResync 补发不完整时推送，提示客户端重新拉取状态
+	eventResync = "resync"
+)
+
+// 发送类型
+const (
+	SendKindMail     = "mail"
+	SendKindBulletin = "bulletin"
+)
+
+// MessageStreamConfig 消息流配置
+type MessageStreamConfig struct {
+	Events          *httpapi.EventHub
+	Authorize       func(token string) bool // 校验 metadata authorization: Bearer <token>，nil 表示不校验
+	SendMail        func(to, subject, content string, encrypted bool) (string, error)
+	PublishBulletin func(topic, content string, ttl int64) (string, error)
+}
+
+// messageStream 消息流服务
+type messageStream struct {
+	config *MessageStreamConfig
+}
+
+func newMessageStream(config *MessageStreamConfig) *messageStream {
+	return &messageStream{config: config}
+}
+
+// authorize 校验调用方令牌
+func (ms *messageStream) authorize(ctx context.Context) error {
+	if ms.config.Authorize == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if ms.config.Authorize(strings.TrimPrefix(v, "Bearer ")) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// streamInterceptor 只校验消息流服务的调用
+func (ms *messageStream) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if strings.HasPrefix(info.FullMethod, "/"+MessageStreamServiceName+"/") {
+		if err := ms.authorize(stream.Context()); err != nil {
+			return err
+		}
+	}
+	return handler(srv, stream)
+}
+
+// SubscribeMessages 按额度推送事件，客户端关闭发送方向时结束
+func (ms *messageStream) SubscribeMessages(stream grpc.ServerStream) error {
+	if ms.config.Events == nil {
+		return status.Error(codes.Unavailable, "event stream not available")
+	}
+	first := new(SubscribeRequest)
+	if err := stream.RecvMsg(first); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	for _, t := range first.Types {
+		if !httpapi.IsEventType(t) {
+			return status.Errorf(codes.InvalidArgument, "unknown event type: %s", t)
+		}
+	}
+	window := int64(first.Credits)
+	if window == 0 {
+		window = defaultStreamCredits
+	}
+
+	// 额度在单独的协程中接收，推送阻塞时也能及时更新
+	ctx := stream.Context()
+	credits := make(chan uint32, 16)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req := new(SubscribeRequest)
+			if err := stream.RecvMsg(req); err != nil {
+				recvErr <- err
+				return
+			}
+			if req.Credits == 0 {
+				continue
+			}
+			select {
+			case credits <- req.Credits:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// lastID 为已取出（推送或待推送）的最后一个事件，重新订阅时从这里补发
+	lastID := first.LastEventId
+	sub := ms.config.Events.Subscribe(first.Types, lastID)
+	defer func() { sub.Close() }()
+	pending := backlogEvents(sub, &lastID)
+
+	for {
+		if window > 0 && len(pending) > 0 {
+			ev := pending[0]
+			pending = pending[1:]
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+			window--
+			continue
+		}
+
+		// 没有额度时不读取事件，积压的事件留在事件缓冲中
+		var next <-chan httpapi.Event
+		if window > 0 {
+			next = sub.C()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case n := <-credits:
+			window += int64(n)
+			if window > maxStreamCredits {
+				window = maxStreamCredits
+			}
+		case ev, ok := <-next:
+			if !ok {
+				// 接收队列积压被断开，从最后取出的事件之后重新订阅补发
+				sub = ms.config.Events.Subscribe(first.Types, lastID)
+				pending = backlogEvents(sub, &lastID)
+				continue
+			}
+			pending = append(pending, toMessageEvent(ev))
+			lastID = ev.ID
+		}
+	}
+}
+
+// backlogEvents 订阅时需要补发的事件，缓冲不完整时以 resync 开头
+func backlogEvents(sub *httpapi.EventSubscription, lastID *uint64) []*MessageEvent {
+	var events []*MessageEvent
+	if sub.Gap {
+		events = append(events, &MessageEvent{Type: eventResync})
+	}
+	for _, ev := range sub.Backlog {
+		events = append(events, toMessageEvent(ev))
+		*lastID = ev.ID
+	}
+	return events
+}
+
+func toMessageEvent(ev httpapi.Event) *MessageEvent {
+	msg := &MessageEvent{Id: ev.ID, Type: ev.Type, Time: ev.Time.UnixMilli()}
+	if ev.Data != nil {
+		msg.Data, _ = json.Marshal(ev.Data)
+	}
+	return msg
+}
+
+// SendMessageStream 依次处理发送请求，每条请求返回一条结果；单条失败不结束流
+func (ms *messageStream) SendMessageStream(stream grpc.ServerStream) error {
+	for {
+		req := new(SendRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		result := &SendResult{RequestId: req.RequestId}
+		if id, err := ms.send(req); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.MessageId = id
+		}
+		if err := stream.SendMsg(result); err != nil {
+			return err
+		}
+	}
+}
+
+func (ms *messageStream) send(req *SendRequest) (string, error) {
+	if req.Content == "" {
+		return "", fmt.Errorf("content is required")
+	}
+	switch req.Kind {
+	case SendKindMail:
+		if req.To == "" {
+			return "", fmt.Errorf("to is required")
+		}
+		if ms.config.SendMail == nil {
+			return "", fmt.Errorf("mailbox not available")
+		}
+		return ms.config.SendMail(req.To, req.Subject, req.Content, req.Encrypted)
+	case SendKindBulletin:
+		if req.Topic == "" {
+			return "", fmt.Errorf("topic is required")
+		}
+		if ms.config.PublishBulletin == nil {
+			return "", fmt.Errorf("bulletin board not available")
+		}
+		return ms.config.PublishBulletin(req.Topic, req.Content, req.TtlSeconds)
+	default:
+		return "", fmt.Errorf("unknown kind %q (expected %s or %s)", req.Kind, SendKindMail, SendKindBulletin)
+	}
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// startTestMessageStream 在内存连接上启动消息流服务，返回客户端
+func startTestMessageStream(t *testing.T, config *MessageStreamConfig) *MessageStreamClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	ms := newMessageStream(config)
+	srv := grpc.NewServer(grpc.ForceServerCodec(streamCodec{}), grpc.StreamInterceptor(ms.streamInterceptor))
+	RegisterMessageStreamServer(srv, ms)
+	go srv.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return NewMessageStreamClient(conn)
+}
+
+func waitSubscribed(t *testing.T, hub *httpapi.EventHub) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); hub.Subscribers() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("stream did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func recvEvent(t *testing.T, stream grpc.ClientStream) *MessageEvent {
+	t.Helper()
+	ev := new(MessageEvent)
+	if err := stream.RecvMsg(ev); err != nil {
+		t.Fatalf("RecvMsg failed: %v", err)
+	}
+	return ev
+}
+
+func TestSubscribeMessages(t *testing.T) {
+	hub := httpapi.NewEventHub(256)
+	client := startTestMessageStream(t, &MessageStreamConfig{
+		Events:    hub,
+		Authorize: func(token string) bool { return token == "secret" },
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 未带令牌被拒绝
+	stream, err := client.SubscribeMessages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.SendMsg(&SubscribeRequest{})
+	if err := stream.RecvMsg(new(MessageEvent)); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	hub.Publish(httpapi.EventMail, map[string]string{"id": "m1"})
+	stream, err = client.SubscribeMessages(authCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 从事件 0 之后补发，只要邮件，额度 2
+	if err := stream.SendMsg(&SubscribeRequest{Types: []string{httpapi.EventMail}, LastEventId: 0, Credits: 2}); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribed(t, hub)
+	hub.Publish(httpapi.EventTask, "skip")
+	hub.Publish(httpapi.EventMail, map[string]string{"id": "m2"})
+	hub.Publish(httpapi.EventMail, map[string]string{"id": "m3"})
+
+	if ev := recvEvent(t, stream); ev.Type != httpapi.EventMail || string(ev.Data) != `{"id":"m2"}` {
+		t.Errorf("expected m2, got %+v", ev)
+	}
+	if ev := recvEvent(t, stream); string(ev.Data) != `{"id":"m3"}` || ev.Time == 0 {
+		t.Errorf("expected m3, got %+v", ev)
+	}
+
+	// 额度用完后不再推送，追加额度后继续
+	hub.Publish(httpapi.EventMail, map[string]string{"id": "m4"})
+	got := make(chan *MessageEvent, 1)
+	go func() {
+		ev := new(MessageEvent)
+		if stream.RecvMsg(ev) == nil {
+			got <- ev
+		}
+	}()
+	select {
+	case ev := <-got:
+		t.Fatalf("event pushed without credit: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+	stream.SendMsg(&SubscribeRequest{Credits: 1})
+	select {
+	case ev := <-got:
+		if string(ev.Data) != `{"id":"m4"}` {
+			t.Errorf("expected m4, got %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("event not pushed after granting credit")
+	}
+	stream.CloseSend()
+}
+
+func TestSubscribeMessagesBackPressure(t *testing.T) {
+	hub := httpapi.NewEventHub(512)
+	client := startTestMessageStream(t, &MessageStreamConfig{Events: hub})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.SubscribeMessages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.SendMsg(&SubscribeRequest{Credits: 1})
+	waitSubscribed(t, hub)
+	hub.Publish(httpapi.EventTask, 0)
+	first := recvEvent(t, stream)
+
+	// 客户端不追加额度时事件积压，超过接收队列后订阅被断开，追加额度后从缓冲中补发，不丢失
+	const total = 200
+	for i := 1; i <= total; i++ {
+		hub.Publish(httpapi.EventTask, i)
+	}
+	stream.SendMsg(&SubscribeRequest{Credits: total})
+	for i := 1; i <= total; i++ {
+		ev := recvEvent(t, stream)
+		if ev.Id != first.Id+uint64(i) || string(ev.Data) != fmt.Sprint(i) {
+			t.Fatalf("event %d: got id %d data %s", i, ev.Id, ev.Data)
+		}
+	}
+
+	// 落后超过事件缓冲时先推送 resync
+	small := httpapi.NewEventHub(4)
+	client = startTestMessageStream(t, &MessageStreamConfig{Events: small})
+	for i := 0; i < 10; i++ {
+		small.Publish(httpapi.EventTask, i)
+	}
+	stream, err = client.SubscribeMessages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.SendMsg(&SubscribeRequest{LastEventId: 1})
+	if ev := recvEvent(t, stream); ev.Type != eventResync || ev.Id != 0 {
+		t.Errorf("expected resync, got %+v", ev)
+	}
+	if ev := recvEvent(t, stream); ev.Id != 7 {
+		t.Errorf("expected buffered event 7 after resync, got %+v", ev)
+	}
+
+	stream, _ = client.SubscribeMessages(ctx)
+	stream.SendMsg(&SubscribeRequest{Types: []string{"nope"}})
+	if err := stream.RecvMsg(new(MessageEvent)); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for unknown type, got %v", err)
+	}
+}
+
+func TestSendMessageStream(t *testing.T) {
+	var mails []string
+	client := startTestMessageStream(t, &MessageStreamConfig{
+		SendMail: func(to, subject, content string, encrypted bool) (string, error) {
+			mails = append(mails, fmt.Sprintf("%s|%s|%s|%v", to, subject, content, encrypted))
+			return fmt.Sprintf("mail-%d", len(mails)), nil
+		},
+		PublishBulletin: func(topic, content string, ttl int64) (string, error) {
+			if topic == "closed" {
+				return "", errors.New("topic closed")
+			}
+			return "b-" + topic, nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.SendMessageStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*SendRequest{
+		{RequestId: "1", Kind: SendKindMail, To: "peer", Subject: "hi", Content: "hello", Encrypted: true},
+		{RequestId: "2", Kind: SendKindBulletin, Topic: "news", Content: "post", TtlSeconds: 60},
+		{RequestId: "3", Kind: SendKindBulletin, Topic: "closed", Content: "post"},
+		{RequestId: "4", Kind: "sms", Content: "x"},
+		{RequestId: "5", Kind: SendKindMail, Content: "no recipient"},
+	}
+	want := []SendResult{
+		{RequestId: "1", Success: true, MessageId: "mail-1"},
+		{RequestId: "2", Success: true, MessageId: "b-news"},
+		{RequestId: "3", Error: "topic closed"},
+		{RequestId: "4"},
+		{RequestId: "5", Error: "to is required"},
+	}
+	for i, req := range requests {
+		if err := stream.SendMsg(req); err != nil {
+			t.Fatal(err)
+		}
+		res := new(SendResult)
+		if err := stream.RecvMsg(res); err != nil {
+			t.Fatalf("request %s: %v", req.RequestId, err)
+		}
+		if res.RequestId != want[i].RequestId || res.Success != want[i].Success || res.MessageId != want[i].MessageId {
+			t.Errorf("request %s: got %+v", req.RequestId, res)
+		}
+		if want[i].Error != "" && res.Error != want[i].Error {
+			t.Errorf("request %s: expected error %q, got %q", req.RequestId, want[i].Error, res.Error)
+		}
+		if !res.Success && res.Error == "" {
+			t.Errorf("request %s: failure without error", req.RequestId)
+		}
+	}
+	if len(mails) != 1 || mails[0] != "peer|hi|hello|true" {
+		t.Errorf("unexpected mails %v", mails)
+	}
+
+	stream.CloseSend()
+	if err := stream.RecvMsg(new(SendResult)); err == nil {
+		t.Error("expected stream to end after CloseSend")
+	}
+}
+
+func TestStreamWireRoundTrip(t *testing.T) {
+	in := &SendRequest{RequestId: "r", Kind: SendKindMail, To: "peer", Subject: "s", Content: "中文内容", Encrypted: true, TtlSeconds: 30}
+	out := new(SendRequest)
+	if err := out.unmarshalWire(in.marshalWire()); err != nil || *out != *in {
+		t.Errorf("round trip mismatch: %+v, %v", out, err)
+	}
+
+	sub := &SubscribeRequest{Types: []string{"mail", "task"}, LastEventId: 42, Credits: 8}
+	decoded := new(SubscribeRequest)
+	if err := decoded.unmarshalWire(sub.marshalWire()); err != nil || len(decoded.Types) != 2 || decoded.LastEventId != 42 || decoded.Credits != 8 {
+		t.Errorf("round trip mismatch: %+v, %v", decoded, err)
+	}
+	if err := decoded.unmarshalWire([]byte{0x0a, 0x05, 'a'}); err == nil {
+		t.Error("truncated message should fail")
+	}
+}
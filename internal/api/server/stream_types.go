@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 消息流的消息类型，字段编号与 api/proto/messagestream.proto 一致
+//
+// 没有 protoc 生成代码，消息用 protowire 手工编解码，用 protoc 生成的其他语言客户端可以直接调用。
+
+// SubscribeRequest 订阅请求：第一条声明订阅，之后每条追加推送额度
+type SubscribeRequest struct {
+	Types       []string // 事件类型（mail、bulletin、task、reputation），为空表示全部
+	LastEventId uint64   // 从该事件之后开始推送，断线重连时补发
+	Credits     uint32   // 追加允许推送的事件数
+}
+
+// MessageEvent 推送的事件
+type MessageEvent struct {
+	Id   uint64 // 事件 ID，resync 事件为 0
+	Type string
+	Time int64  // Unix 毫秒
+	Data []byte // 事件内容（JSON）
+}
+
+// SendRequest 发送请求
+type SendRequest struct {
+	RequestId  string // 客户端请求 ID，原样返回
+	Kind       string // mail 或 bulletin
+	To         string // 邮件收件人
+	Subject    string // 邮件主题
+	Topic      string // 留言话题
+	Content    string
+	Encrypted  bool  // 邮件端到端加密
+	TtlSeconds int64 // 留言有效期，0 表示默认
+}
+
+// SendResult 发送结果
+type SendResult struct {
+	RequestId string
+	Success   bool
+	MessageId string
+	Error     string
+}
+
+// wireMessage 手工编解码的消息
+type wireMessage interface {
+	marshalWire() []byte
+	unmarshalWire(b []byte) error
+}
+
+func (m *SubscribeRequest) marshalWire() []byte {
+	var b []byte
+	for _, t := range m.Types {
+		b = appendString(b, 1, t)
+	}
+	b = appendVarint(b, 2, m.LastEventId)
+	b = appendVarint(b, 3, uint64(m.Credits))
+	return b
+}
+
+func (m *SubscribeRequest) unmarshalWire(b []byte) error {
+	*m = SubscribeRequest{}
+	return walkWire(b, func(num protowire.Number, v uint64, s []byte) {
+		switch num {
+		case 1:
+			m.Types = append(m.Types, string(s))
+		case 2:
+			m.LastEventId = v
+		case 3:
+			m.Credits = uint32(v)
+		}
+	})
+}
+
+func (m *MessageEvent) marshalWire() []byte {
+	var b []byte
+	b = appendVarint(b, 1, m.Id)
+	b = appendString(b, 2, m.Type)
+	b = appendVarint(b, 3, uint64(m.Time))
+	b = appendBytes(b, 4, m.Data)
+	return b
+}
+
+func (m *MessageEvent) unmarshalWire(b []byte) error {
+	*m = MessageEvent{}
+	return walkWire(b, func(num protowire.Number, v uint64, s []byte) {
+		switch num {
+		case 1:
+			m.Id = v
+		case 2:
+			m.Type = string(s)
+		case 3:
+			m.Time = int64(v)
+		case 4:
+			m.Data = append([]byte(nil), s...)
+		}
+	})
+}
+
+func (m *SendRequest) marshalWire() []byte {
+	var b []byte
+	b = appendString(b, 1, m.RequestId)
+	b = appendString(b, 2, m.Kind)
+	b = appendString(b, 3, m.To)
+	b = appendString(b, 4, m.Subject)
+	b = appendString(b, 5, m.Topic)
+	b = appendString(b, 6, m.Content)
+	b = appendVarint(b, 7, protowire.EncodeBool(m.Encrypted))
+	b = appendVarint(b, 8, uint64(m.TtlSeconds))
+	return b
+}
+
+func (m *SendRequest) unmarshalWire(b []byte) error {
+	*m = SendRequest{}
+	return walkWire(b, func(num protowire.Number, v uint64, s []byte) {
+		switch num {
+		case 1:
+			m.RequestId = string(s)
+		case 2:
+			m.Kind = string(s)
+		case 3:
+			m.To = string(s)
+		case 4:
+			m.Subject = string(s)
+		case 5:
+			m.Topic = string(s)
+		case 6:
+			m.Content = string(s)
+		case 7:
+			m.Encrypted = protowire.DecodeBool(v)
+		case 8:
+			m.TtlSeconds = int64(v)
+		}
+	})
+}
+
+func (m *SendResult) marshalWire() []byte {
+	var b []byte
+	b = appendString(b, 1, m.RequestId)
+	b = appendVarint(b, 2, protowire.EncodeBool(m.Success))
+	b = appendString(b, 3, m.MessageId)
+	b = appendString(b, 4, m.Error)
+	return b
+}
+
+func (m *SendResult) unmarshalWire(b []byte) error {
+	*m = SendResult{}
+	return walkWire(b, func(num protowire.Number, v uint64, s []byte) {
+		switch num {
+		case 1:
+			m.RequestId = string(s)
+		case 2:
+			m.Success = protowire.DecodeBool(v)
+		case 3:
+			m.MessageId = string(s)
+		case 4:
+			m.Error = string(s)
+		}
+	})
+}
+
+// proto3 不编码零值字段
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// walkWire 逐个解析字段，varint 字段传 v，长度前缀字段传 s，未知字段跳过
+func walkWire(b []byte, field func(num protowire.Number, v uint64, s []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			field(num, v, nil)
+			b = b[n:]
+		case protowire.BytesType:
+			s, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			field(num, 0, s)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return nil
+}
+
+// streamCodec 编解码消息流的消息，其他消息交给默认的 proto 编解码器
+type streamCodec struct{}
+
+func (streamCodec) Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(wireMessage); ok {
+		return m.marshalWire(), nil
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Marshal(v)
+	}
+	return nil, fmt.Errorf("cannot marshal %T", v)
+}
+
+func (streamCodec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(wireMessage); ok {
+		return m.unmarshalWire(data)
+	}
+	if c := encoding.GetCodec("proto"); c != nil {
+		return c.Unmarshal(data, v)
+	}
+	return fmt.Errorf("cannot unmarshal %T", v)
+}
+
+func (streamCodec) Name() string {
+	return "proto"
+}
+
+// MessageStreamServer 消息流服务接口
+type MessageStreamServer interface {
+	SubscribeMessages(stream grpc.ServerStream) error
+	SendMessageStream(stream grpc.ServerStream) error
+}
+
+// MessageStreamServiceName 消息流服务名
+const MessageStreamServiceName = "toolnetwork.MessageStream"
+
+var messageStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: MessageStreamServiceName,
+	HandlerType: (*MessageStreamServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "SubscribeMessages",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(MessageStreamServer).SubscribeMessages(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName: "SendMessageStream",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				return srv.(MessageStreamServer).SendMessageStream(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "messagestream.proto",
+}
+
+// RegisterMessageStreamServer 注册消息流服务
+func RegisterMessageStreamServer(s *grpc.Server, srv MessageStreamServer) {
+	s.RegisterService(&messageStreamServiceDesc, srv)
+}
+
+// MessageStreamClient 消息流客户端
+type MessageStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewMessageStreamClient 创建消息流客户端
+func NewMessageStreamClient(cc grpc.ClientConnInterface) *MessageStreamClient {
+	return &MessageStreamClient{cc: cc}
+}
+
+// SubscribeMessages 打开订阅流，发送 SubscribeRequest、接收 MessageEvent
+func (c *MessageStreamClient) SubscribeMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.open(ctx, 0, opts)
+}
+
+// SendMessageStream 打开发送流，发送 SendRequest、接收 SendResult
+func (c *MessageStreamClient) SendMessageStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.open(ctx, 1, opts)
+}
+
+func (c *MessageStreamClient) open(ctx context.Context, i int, opts []grpc.CallOption) (grpc.ClientStream, error) {
+	desc := &messageStreamServiceDesc.Streams[i]
+	opts = append([]grpc.CallOption{grpc.ForceCodec(streamCodec{})}, opts...)
+	return c.cc.NewStream(ctx, desc, "/"+MessageStreamServiceName+"/"+desc.StreamName, opts...)
+}
@@ -0,0 +1,761 @@
+// Package audit 超级节点审计：确定性重放与偏离检测
+//
+// 执行方交付时保存执行记录（输入、输出和结果哈希，由执行方签名）。超级节点取得执行记录后
+// 重新计算输出摘要；设置了重放函数时再按输入重新执行一次，与执行方声明的结果哈希比对，
+// 给出签名的通过/不通过结论。也可以不重放，直接提交人工审计结论。
+//
+// 同一任务收到 MinAuditors 份结论后按通过比例形成共识，与共识不一致的审计者记为偏离：
+// 放过了被共识否决的结果为严重偏离，否决了被共识认可的结果为轻微偏离。
+// 开启自动惩罚时按偏离程度扣减审计者声誉并罚没其审计抵押。
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// 默认参数
+const (
+	DefaultMinAuditors   = 3
+	DefaultPassThreshold = 0.6
+	DefaultMaxDeviations = 1000
+)
+
+// 偏离程度
+const (
+	SeverityMinor  = "minor"  // 否决了共识认可的结果
+	SeveritySevere = "severe" // 放过了共识否决的结果
+)
+
+// 审计错误
+var (
+	ErrNilConfig          = errors.New("audit config cannot be nil")
+	ErrEmptyNodeID        = errors.New("node ID cannot be empty")
+	ErrEmptyTaskID        = errors.New("task ID cannot be empty")
+	ErrNoSignFunc         = errors.New("audit sign function not set")
+	ErrTranscriptNotFound = errors.New("transcript not found")
+	ErrInvalidTranscript  = errors.New("invalid transcript signature")
+	ErrInvalidAudit       = errors.New("invalid audit signature")
+	ErrNotAuditor         = errors.New("node is not an eligible auditor")
+	ErrSelfAudit          = errors.New("executor cannot audit its own task")
+	ErrDuplicateAudit     = errors.New("auditor already submitted a conclusion for this task")
+	ErrNoConsensus        = errors.New("no consensus for task yet")
+	ErrUnknownSeverity    = errors.New("unknown deviation severity")
+	ErrInvalidPenalty     = errors.New("invalid penalty rule")
+)
+
+// Transcript 执行记录：执行方对同一输入给出的输出和结果哈希
+type Transcript struct {
+	TaskID     string    `json:"task_id"`
+	Executor   string    `json:"executor"`
+	Input      []byte    `json:"input,omitempty"`
+	Output     []byte    `json:"output"`
+	ResultHash string    `json:"result_hash"` // 执行方声明的交付物摘要
+	CreatedAt  time.Time `json:"created_at"`
+	Signature  []byte    `json:"signature"`
+}
+
+// SignData 执行记录签名的原始数据，输入和输出以摘要参与签名
+func (t *Transcript) SignData() []byte {
+	return []byte(fmt.Sprintf("transcript|%s|%s|%s|%s|%s|%d",
+		t.TaskID, t.Executor, HashResult(t.Input), HashResult(t.Output), t.ResultHash, t.CreatedAt.Unix()))
+}
+
+// Audit 一名审计者对任务结果的签名结论
+type Audit struct {
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id"`
+	Auditor    string    `json:"auditor"`
+	Passed     bool      `json:"passed"`
+	ResultHash string    `json:"result_hash,omitempty"` // 审计者复算得到的摘要，人工结论为空
+	Replayed   bool      `json:"replayed"`              // 是否按输入重新执行
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Signature  []byte    `json:"signature"`
+}
+
+// SignData 审计结论签名的原始数据
+func (a *Audit) SignData() []byte {
+	return []byte(fmt.Sprintf("audit|%s|%s|%t|%s|%t|%s|%d",
+		a.TaskID, a.Auditor, a.Passed, a.ResultHash, a.Replayed, a.Reason, a.CreatedAt.Unix()))
+}
+
+// Consensus 任务的审计共识
+type Consensus struct {
+	TaskID    string    `json:"task_id"`
+	Passed    bool      `json:"passed"`
+	PassCount int       `json:"pass_count"`
+	FailCount int       `json:"fail_count"`
+	Auditors  []string  `json:"auditors"` // 参与形成共识的审计者
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// Deviation 审计者结论与共识不一致的记录
+type Deviation struct {
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id"`
+	AuditID    string    `json:"audit_id"`
+	Auditor    string    `json:"auditor"`
+	Expected   bool      `json:"expected"` // 共识结论
+	Actual     bool      `json:"actual"`   // 审计者结论
+	Severity   string    `json:"severity"`
+	DetectedAt time.Time `json:"detected_at"`
+	Penalty    *Penalty  `json:"penalty,omitempty"` // 已执行的惩罚
+}
+
+// PenaltyRule 某一偏离程度的惩罚力度
+type PenaltyRule struct {
+	Severity   string  `json:"severity"`
+	RepPenalty float64 `json:"rep_penalty"` // 扣减的声誉分
+	SlashRatio float64 `json:"slash_ratio"` // 审计抵押的罚没比例，0 表示不罚没
+}
+
+// Penalty 一次惩罚的执行结果
+type Penalty struct {
+	NodeID    string    `json:"node_id"`
+	Severity  string    `json:"severity"`
+	Reason    string    `json:"reason"`
+	RepDelta  float64   `json:"rep_delta"`
+	Slashed   float64   `json:"slashed"`
+	Error     string    `json:"error,omitempty"` // 声誉或抵押未能扣减的原因
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Config 审计配置
+type Config struct {
+	NodeID        string
+	DataDir       string
+	MinAuditors   int     // 形成共识所需的结论数
+	PassThreshold float64 // 通过结论占比达到该值即共识通过
+	AutoPenalty   bool    // 检测到偏离后自动惩罚
+	MaxDeviations int     // 保留的偏离记录条数
+	Penalties     map[string]PenaltyRule
+
+	// 签名函数（本节点私钥）
+	SignFunc func(data []byte) ([]byte, error)
+	// 验签函数（根据执行方或审计者节点ID校验）
+	VerifyFunc func(nodeID string, data, signature []byte) (bool, error)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:        nodeID,
+		DataDir:       "./data/audit",
+		MinAuditors:   DefaultMinAuditors,
+		PassThreshold: DefaultPassThreshold,
+		AutoPenalty:   true,
+		MaxDeviations: DefaultMaxDeviations,
+		Penalties:     DefaultPenalties(),
+	}
+}
+
+// DefaultPenalties 默认惩罚力度
+func DefaultPenalties() map[string]PenaltyRule {
+	return map[string]PenaltyRule{
+		SeverityMinor:  {Severity: SeverityMinor, RepPenalty: 5, SlashRatio: 0.1},
+		SeveritySevere: {Severity: SeveritySevere, RepPenalty: 20, SlashRatio: 0.3},
+	}
+}
+
+// Manager 审计管理器
+type Manager struct {
+	mu          sync.RWMutex
+	config      *Config
+	transcripts map[string]*Transcript // taskID -> 执行记录
+	audits      map[string][]*Audit    // taskID -> 审计结论
+	consensus   map[string]*Consensus  // taskID -> 共识
+	deviations  []*Deviation
+
+	auditor    func(nodeID string) bool
+	fetch      func(taskID string) (*Transcript, error)
+	reexecute  func(t *Transcript) ([]byte, error)
+	reputation func(nodeID string, delta float64, reason string) error
+	slash      func(nodeID string, ratio float64, reason string, evidence []string) (float64, error)
+}
+
+// New 创建审计管理器
+func New(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+	if config.MinAuditors <= 0 {
+		config.MinAuditors = DefaultMinAuditors
+	}
+	if config.PassThreshold <= 0 || config.PassThreshold > 1 {
+		config.PassThreshold = DefaultPassThreshold
+	}
+	if config.MaxDeviations <= 0 {
+		config.MaxDeviations = DefaultMaxDeviations
+	}
+	if config.Penalties == nil {
+		config.Penalties = DefaultPenalties()
+	}
+
+	m := &Manager{
+		config:      config,
+		transcripts: make(map[string]*Transcript),
+		audits:      make(map[string][]*Audit),
+		consensus:   make(map[string]*Consensus),
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		m.load()
+	}
+	return m, nil
+}
+
+// SetAuditorFunc 设置审计资格判断（通常是超级节点），未设置时不接受任何结论
+func (m *Manager) SetAuditorFunc(fn func(nodeID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auditor = fn
+}
+
+// SetFetchFunc 设置本地没有执行记录时向执行方获取的函数
+func (m *Manager) SetFetchFunc(fn func(taskID string) (*Transcript, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetch = fn
+}
+
+// SetReexecuteFunc 设置确定性重放函数：按执行记录的输入重新执行并返回输出
+func (m *Manager) SetReexecuteFunc(fn func(t *Transcript) ([]byte, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reexecute = fn
+}
+
+// SetReputationFunc 设置声誉扣减函数
+func (m *Manager) SetReputationFunc(fn func(nodeID string, delta float64, reason string) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reputation = fn
+}
+
+// SetSlashFunc 设置审计抵押罚没函数，返回罚没金额
+func (m *Manager) SetSlashFunc(fn func(nodeID string, ratio float64, reason string, evidence []string) (float64, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slash = fn
+}
+
+// HashResult 交付物摘要（sha256 十六进制），与任务交付使用的摘要一致
+func HashResult(result []byte) string {
+	sum := sha256.Sum256(result)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordTranscript 执行方保存并签名本节点的执行记录
+func (m *Manager) RecordTranscript(taskID string, input, output []byte) (*Transcript, error) {
+	if taskID == "" {
+		return nil, ErrEmptyTaskID
+	}
+	if m.config.SignFunc == nil {
+		return nil, ErrNoSignFunc
+	}
+
+	t := &Transcript{
+		TaskID:     taskID,
+		Executor:   m.config.NodeID,
+		Input:      input,
+		Output:     output,
+		ResultHash: HashResult(output),
+		CreatedAt:  time.Now(),
+	}
+	sig, err := m.config.SignFunc(t.SignData())
+	if err != nil {
+		return nil, fmt.Errorf("签名执行记录失败: %w", err)
+	}
+	t.Signature = sig
+
+	m.mu.Lock()
+	m.transcripts[taskID] = t
+	m.save()
+	m.mu.Unlock()
+
+	c := *t
+	return &c, nil
+}
+
+// AddTranscript 保存其他节点的执行记录（校验执行方签名）
+// 输出与声明摘要不符的记录同样保存，它是审计不通过的证据。
+func (m *Manager) AddTranscript(t *Transcript) error {
+	if t == nil || t.TaskID == "" || t.Executor == "" {
+		return ErrInvalidTranscript
+	}
+	if m.config.VerifyFunc != nil {
+		ok, err := m.config.VerifyFunc(t.Executor, t.SignData(), t.Signature)
+		if err != nil || !ok {
+			return ErrInvalidTranscript
+		}
+	}
+
+	c := *t
+	m.mu.Lock()
+	m.transcripts[t.TaskID] = &c
+	m.save()
+	m.mu.Unlock()
+	return nil
+}
+
+// Transcript 获取本节点保存的执行记录
+func (m *Manager) Transcript(taskID string) (*Transcript, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.transcripts[taskID]
+	if !ok {
+		return nil, ErrTranscriptNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+// FetchTranscript 获取任务的执行记录，本地没有时通过获取函数向执行方请求
+func (m *Manager) FetchTranscript(taskID string) (*Transcript, error) {
+	if t, err := m.Transcript(taskID); err == nil {
+		return t, nil
+	}
+	m.mu.RLock()
+	fetch := m.fetch
+	m.mu.RUnlock()
+	if fetch == nil {
+		return nil, ErrTranscriptNotFound
+	}
+
+	fetched, err := fetch(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTranscriptNotFound, err)
+	}
+	if fetched == nil || fetched.TaskID != taskID {
+		return nil, ErrTranscriptNotFound
+	}
+	if err := m.AddTranscript(fetched); err != nil {
+		return nil, err
+	}
+	c := *fetched
+	return &c, nil
+}
+
+// Audit 本节点复核任务结果并记录签名结论
+// claimedHash 为任务交付时登记的摘要，非空时必须与执行记录声明的一致。
+func (m *Manager) Audit(taskID, claimedHash string) (*Audit, error) {
+	t, err := m.FetchTranscript(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Executor == m.config.NodeID {
+		return nil, ErrSelfAudit
+	}
+
+	m.mu.RLock()
+	reexecute := m.reexecute
+	m.mu.RUnlock()
+
+	a := &Audit{TaskID: taskID, Passed: true, ResultHash: HashResult(t.Output)}
+	switch {
+	case claimedHash != "" && !strings.EqualFold(claimedHash, t.ResultHash):
+		a.Passed, a.Reason = false, fmt.Sprintf("transcript hash %s differs from delivered hash %s", t.ResultHash, claimedHash)
+	case !strings.EqualFold(a.ResultHash, t.ResultHash):
+		a.Passed, a.Reason = false, fmt.Sprintf("output hashes to %s, executor claimed %s", a.ResultHash, t.ResultHash)
+	case reexecute != nil:
+		output, err := reexecute(t)
+		if err != nil {
+			return nil, fmt.Errorf("重放执行失败: %w", err)
+		}
+		a.Replayed = true
+		if replayed := HashResult(output); replayed != a.ResultHash {
+			a.Passed, a.ResultHash = false, replayed
+			a.Reason = fmt.Sprintf("re-execution produced %s, executor claimed %s", replayed, t.ResultHash)
+		}
+	}
+	if a.Passed {
+		a.Reason = "result hash verified"
+		if a.Replayed {
+			a.Reason = "re-execution matches result hash"
+		}
+	}
+	return m.sign(a)
+}
+
+// RecordAudit 本节点提交人工审计结论（不重放）
+func (m *Manager) RecordAudit(taskID string, passed bool, reason string) (*Audit, error) {
+	if taskID == "" {
+		return nil, ErrEmptyTaskID
+	}
+	return m.sign(&Audit{TaskID: taskID, Passed: passed, Reason: reason})
+}
+
+// SubmitAudit 接收其他审计者的结论（校验签名和审计资格）
+func (m *Manager) SubmitAudit(a *Audit) error {
+	if a == nil || a.TaskID == "" || a.Auditor == "" {
+		return ErrInvalidAudit
+	}
+	if m.config.VerifyFunc != nil {
+		ok, err := m.config.VerifyFunc(a.Auditor, a.SignData(), a.Signature)
+		if err != nil || !ok {
+			return ErrInvalidAudit
+		}
+	}
+	c := *a
+	c.ID = auditID(&c)
+	return m.add(&c)
+}
+
+// Audits 列出任务已收到的审计结论
+func (m *Manager) Audits(taskID string) []*Audit {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Audit, 0, len(m.audits[taskID]))
+	for _, a := range m.audits[taskID] {
+		c := *a
+		result = append(result, &c)
+	}
+	return result
+}
+
+// Consensus 获取任务的审计共识
+func (m *Manager) Consensus(taskID string) (*Consensus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.consensus[taskID]
+	if !ok {
+		return nil, ErrNoConsensus
+	}
+	result := *c
+	result.Auditors = append([]string(nil), c.Auditors...)
+	return &result, nil
+}
+
+// PassRate 任务审计结论中通过的比例，没有结论时为 0
+func (m *Manager) PassRate(taskID string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	audits := m.audits[taskID]
+	if len(audits) == 0 {
+		return 0
+	}
+	passed := 0
+	for _, a := range audits {
+		if a.Passed {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(audits))
+}
+
+// Deviations 列出偏离记录，新的在前；auditor 非空时只返回该审计者的
+func (m *Manager) Deviations(auditor string, limit int) []*Deviation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []*Deviation
+	for i := len(m.deviations) - 1; i >= 0; i-- {
+		d := m.deviations[i]
+		if auditor != "" && d.Auditor != auditor {
+			continue
+		}
+		c := *d
+		result = append(result, &c)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// PenaltyRules 当前的惩罚力度
+func (m *Manager) PenaltyRules() map[string]PenaltyRule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := make(map[string]PenaltyRule, len(m.config.Penalties))
+	for k, v := range m.config.Penalties {
+		rules[k] = v
+	}
+	return rules
+}
+
+// SetPenaltyRule 调整某一偏离程度的惩罚力度
+func (m *Manager) SetPenaltyRule(rule PenaltyRule) error {
+	if rule.Severity != SeverityMinor && rule.Severity != SeveritySevere {
+		return fmt.Errorf("%w: %q", ErrUnknownSeverity, rule.Severity)
+	}
+	if rule.RepPenalty < 0 || rule.SlashRatio < 0 || rule.SlashRatio > 1 {
+		return ErrInvalidPenalty
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.Penalties[rule.Severity] = rule
+	m.save()
+	return nil
+}
+
+// ManualPenalty 按偏离程度手动惩罚节点
+func (m *Manager) ManualPenalty(nodeID, severity, reason string) (*Penalty, error) {
+	if nodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+	if severity == "" {
+		severity = SeverityMinor
+	}
+	if severity != SeverityMinor && severity != SeveritySevere {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSeverity, severity)
+	}
+	if reason == "" {
+		reason = "manual"
+	}
+	return m.penalize(nodeID, severity, "audit_penalty:"+reason, []string{"manual:" + reason}), nil
+}
+
+// Stats 实现 stats.Provider
+func (m *Manager) Stats() []stats.Metric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	audits, severe := 0, 0
+	for _, list := range m.audits {
+		audits += len(list)
+	}
+	for _, d := range m.deviations {
+		if d.Severity == SeveritySevere {
+			severe++
+		}
+	}
+	return []stats.Metric{
+		stats.Gauge("transcripts", float64(len(m.transcripts)), "保存的执行记录数"),
+		stats.Gauge("audits", float64(audits), "收到的审计结论数"),
+		stats.Gauge("consensus", float64(len(m.consensus)), "已形成共识的任务数"),
+		stats.Gauge("deviations", float64(len(m.deviations)), "偏离共识的审计结论数"),
+		stats.Gauge("severe_deviations", float64(severe), "放过共识否决结果的审计结论数"),
+	}
+}
+
+// sign 以本节点身份签名并记录结论
+func (m *Manager) sign(a *Audit) (*Audit, error) {
+	if m.config.SignFunc == nil {
+		return nil, ErrNoSignFunc
+	}
+	a.Auditor = m.config.NodeID
+	a.CreatedAt = time.Now()
+	sig, err := m.config.SignFunc(a.SignData())
+	if err != nil {
+		return nil, fmt.Errorf("签名审计结论失败: %w", err)
+	}
+	a.Signature = sig
+	a.ID = auditID(a)
+	if err := m.add(a); err != nil {
+		return nil, err
+	}
+	c := *a
+	return &c, nil
+}
+
+// add 记录结论并检测偏离，需要惩罚的偏离在释放锁后执行
+func (m *Manager) add(a *Audit) error {
+	m.mu.Lock()
+	if m.auditor == nil || !m.auditor(a.Auditor) {
+		m.mu.Unlock()
+		return ErrNotAuditor
+	}
+	if t, ok := m.transcripts[a.TaskID]; ok && t.Executor == a.Auditor {
+		m.mu.Unlock()
+		return ErrSelfAudit
+	}
+	for _, existing := range m.audits[a.TaskID] {
+		if existing.Auditor == a.Auditor {
+			m.mu.Unlock()
+			return ErrDuplicateAudit
+		}
+	}
+	m.audits[a.TaskID] = append(m.audits[a.TaskID], a)
+
+	var found []*Deviation
+	if c, ok := m.consensus[a.TaskID]; ok {
+		// 共识形成后到达的结论与共识比较
+		if d := m.deviationLocked(c, a); d != nil {
+			found = append(found, d)
+		}
+	} else if c := m.decideLocked(a.TaskID); c != nil {
+		for _, audit := range m.audits[a.TaskID] {
+			if d := m.deviationLocked(c, audit); d != nil {
+				found = append(found, d)
+			}
+		}
+	}
+	autoPenalty := m.config.AutoPenalty
+	m.save()
+	m.mu.Unlock()
+
+	if !autoPenalty {
+		return nil
+	}
+	for _, d := range found {
+		evidence := []string{"audit_id:" + d.AuditID, "task_id:" + d.TaskID, "deviation:" + d.ID}
+		p := m.penalize(d.Auditor, d.Severity, "audit_deviation:"+d.Severity, evidence)
+		m.mu.Lock()
+		d.Penalty = p
+		m.save()
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// decideLocked 结论数达到 MinAuditors 时形成共识（调用方持有锁）
+func (m *Manager) decideLocked(taskID string) *Consensus {
+	audits := m.audits[taskID]
+	if len(audits) < m.config.MinAuditors {
+		return nil
+	}
+
+	c := &Consensus{TaskID: taskID, DecidedAt: time.Now()}
+	for _, a := range audits {
+		if a.Passed {
+			c.PassCount++
+		} else {
+			c.FailCount++
+		}
+		c.Auditors = append(c.Auditors, a.Auditor)
+	}
+	sort.Strings(c.Auditors)
+	c.Passed = float64(c.PassCount)/float64(len(audits)) >= m.config.PassThreshold
+	m.consensus[taskID] = c
+	return c
+}
+
+// deviationLocked 结论与共识不一致时记录偏离（调用方持有锁）
+func (m *Manager) deviationLocked(c *Consensus, a *Audit) *Deviation {
+	if a.Passed == c.Passed {
+		return nil
+	}
+	d := &Deviation{
+		TaskID:     a.TaskID,
+		AuditID:    a.ID,
+		Auditor:    a.Auditor,
+		Expected:   c.Passed,
+		Actual:     a.Passed,
+		Severity:   SeverityMinor,
+		DetectedAt: time.Now(),
+	}
+	if a.Passed {
+		d.Severity = SeveritySevere
+	}
+	sum := sha256.Sum256([]byte("deviation|" + a.ID))
+	d.ID = hex.EncodeToString(sum[:16])
+
+	m.deviations = append(m.deviations, d)
+	if len(m.deviations) > m.config.MaxDeviations {
+		m.deviations = m.deviations[len(m.deviations)-m.config.MaxDeviations:]
+	}
+	return d
+}
+
+// penalize 扣减声誉并罚没审计抵押，任一步失败不影响另一步
+func (m *Manager) penalize(nodeID, severity, reason string, evidence []string) *Penalty {
+	m.mu.RLock()
+	rule := m.config.Penalties[severity]
+	reputation, slash := m.reputation, m.slash
+	m.mu.RUnlock()
+
+	p := &Penalty{NodeID: nodeID, Severity: severity, Reason: reason, AppliedAt: time.Now()}
+	var errs []string
+	if reputation != nil && rule.RepPenalty > 0 {
+		if err := reputation(nodeID, -rule.RepPenalty, reason); err != nil {
+			errs = append(errs, "reputation: "+err.Error())
+		} else {
+			p.RepDelta = -rule.RepPenalty
+		}
+	}
+	if slash != nil && rule.SlashRatio > 0 {
+		amount, err := slash(nodeID, rule.SlashRatio, reason, evidence)
+		if err != nil {
+			errs = append(errs, "collateral: "+err.Error())
+		} else {
+			p.Slashed = amount
+		}
+	}
+	p.Error = strings.Join(errs, "; ")
+	return p
+}
+
+func auditID(a *Audit) string {
+	hash := sha256.Sum256(a.SignData())
+	return hex.EncodeToString(hash[:16])
+}
+
+// state 持久化内容
+type state struct {
+	Transcripts []*Transcript          `json:"transcripts"`
+	Audits      []*Audit               `json:"audits"`
+	Consensus   []*Consensus           `json:"consensus"`
+	Deviations  []*Deviation           `json:"deviations"`
+	Penalties   map[string]PenaltyRule `json:"penalties"`
+}
+
+// save 保存审计状态（调用方持有锁）
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	s := state{Deviations: m.deviations, Penalties: m.config.Penalties}
+	for _, t := range m.transcripts {
+		s.Transcripts = append(s.Transcripts, t)
+	}
+	for _, list := range m.audits {
+		s.Audits = append(s.Audits, list...)
+	}
+	for _, c := range m.consensus {
+		s.Consensus = append(s.Consensus, c)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "audit.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "audit.json"))
+	if err != nil {
+		return
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return
+	}
+	for _, t := range s.Transcripts {
+		m.transcripts[t.TaskID] = t
+	}
+	sort.Slice(s.Audits, func(i, j int) bool { return s.Audits[i].CreatedAt.Before(s.Audits[j].CreatedAt) })
+	for _, a := range s.Audits {
+		m.audits[a.TaskID] = append(m.audits[a.TaskID], a)
+	}
+	for _, c := range s.Consensus {
+		m.consensus[c.TaskID] = c
+	}
+	m.deviations = s.Deviations
+	for k, v := range s.Penalties {
+		m.config.Penalties[k] = v
+	}
+}
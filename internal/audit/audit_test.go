@@ -0,0 +1,243 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试用签名：节点ID + 数据哈希
+func testSign(nodeID string) func(data []byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		sum := sha256.Sum256(data)
+		return append([]byte(nodeID+":"), sum[:]...), nil
+	}
+}
+
+func testVerify(nodeID string, data, signature []byte) (bool, error) {
+	want, _ := testSign(nodeID)(data)
+	return string(want) == string(signature), nil
+}
+
+func newTestManager(t *testing.T, nodeID string) *Manager {
+	t.Helper()
+	config := DefaultConfig(nodeID)
+	config.DataDir = t.TempDir()
+	config.SignFunc = testSign(nodeID)
+	config.VerifyFunc = testVerify
+	m, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m.SetAuditorFunc(func(id string) bool { return strings.HasPrefix(id, "sn") })
+	return m
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("New(nil) error = %v", err)
+	}
+	if _, err := New(&Config{}); !errors.Is(err, ErrEmptyNodeID) {
+		t.Errorf("New(empty) error = %v", err)
+	}
+}
+
+func TestAuditTranscript(t *testing.T) {
+	executor := newTestManager(t, "executor")
+	auditor := newTestManager(t, "sn1")
+
+	if _, err := auditor.Audit("task-1", ""); !errors.Is(err, ErrTranscriptNotFound) {
+		t.Errorf("Audit() without transcript error = %v", err)
+	}
+
+	// 本地没有执行记录时向执行方获取
+	transcript, err := executor.RecordTranscript("task-1", []byte("2+2"), []byte("4"))
+	if err != nil {
+		t.Fatalf("RecordTranscript() error = %v", err)
+	}
+	auditor.SetFetchFunc(executor.Transcript)
+	first, err := auditor.Audit("task-1", transcript.ResultHash)
+	if err != nil {
+		t.Fatalf("Audit() error = %v", err)
+	}
+	if !first.Passed || first.Auditor != "sn1" || len(first.Signature) == 0 || first.ResultHash != HashResult([]byte("4")) {
+		t.Errorf("audit = %+v", first)
+	}
+
+	// 交付登记的摘要与执行记录不符
+	executor.RecordTranscript("task-2", []byte("2+2"), []byte("4"))
+	if a, _ := auditor.Audit("task-2", HashResult([]byte("5"))); a == nil || a.Passed {
+		t.Errorf("audit with mismatched delivery = %+v", a)
+	}
+
+	// 转发途中篡改输出，执行方签名失效
+	tampered, _ := executor.Transcript("task-1")
+	tampered.TaskID = "task-3"
+	tampered.Output = []byte("5")
+	if err := auditor.AddTranscript(tampered); !errors.Is(err, ErrInvalidTranscript) {
+		t.Errorf("tampered transcript error = %v", err)
+	}
+	// 执行方签名的记录本身输出与声明摘要不符
+	forged := &Transcript{TaskID: "task-3", Executor: "executor", Output: []byte("5"), ResultHash: HashResult([]byte("4"))}
+	forged.Signature, _ = testSign("executor")(forged.SignData())
+	if err := auditor.AddTranscript(forged); err != nil {
+		t.Fatalf("AddTranscript() error = %v", err)
+	}
+	if a, _ := auditor.Audit("task-3", ""); a == nil || a.Passed {
+		t.Errorf("audit of mismatched output = %+v", a)
+	}
+
+	// 执行记录持久化
+	reloaded, _ := New(auditor.config)
+	if _, err := reloaded.Transcript("task-3"); err != nil {
+		t.Errorf("reloaded transcript error = %v", err)
+	}
+	if got := reloaded.Audits("task-1"); len(got) != 1 || got[0].ID != first.ID {
+		t.Errorf("reloaded audits = %+v", got)
+	}
+}
+
+func TestAuditReexecute(t *testing.T) {
+	executor := newTestManager(t, "executor")
+	auditor := newTestManager(t, "sn1")
+	auditor.SetFetchFunc(executor.Transcript)
+	auditor.SetReexecuteFunc(func(t *Transcript) ([]byte, error) {
+		if string(t.Input) == "2+2" {
+			return []byte("4"), nil
+		}
+		return []byte("?"), nil
+	})
+
+	executor.RecordTranscript("good", []byte("2+2"), []byte("4"))
+	executor.RecordTranscript("bad", []byte("2+3"), []byte("4"))
+
+	if a, err := auditor.Audit("good", ""); err != nil || !a.Passed || !a.Replayed {
+		t.Errorf("Audit(good) = %+v, %v", a, err)
+	}
+	a, err := auditor.Audit("bad", "")
+	if err != nil || a.Passed || a.ResultHash != HashResult([]byte("?")) {
+		t.Errorf("Audit(bad) = %+v, %v", a, err)
+	}
+}
+
+func TestSubmitAudit(t *testing.T) {
+	m := newTestManager(t, "self")
+	sn2 := newTestManager(t, "sn2")
+
+	a, err := sn2.RecordAudit("task-1", true, "looks right")
+	if err != nil {
+		t.Fatalf("RecordAudit() error = %v", err)
+	}
+	forged := *a
+	forged.Passed = false
+	if err := m.SubmitAudit(&forged); !errors.Is(err, ErrInvalidAudit) {
+		t.Errorf("forged audit error = %v", err)
+	}
+	if err := m.SubmitAudit(a); err != nil {
+		t.Fatalf("SubmitAudit() error = %v", err)
+	}
+	if err := m.SubmitAudit(a); !errors.Is(err, ErrDuplicateAudit) {
+		t.Errorf("duplicate audit error = %v", err)
+	}
+
+	outsider := newTestManager(t, "outsider")
+	outsider.SetAuditorFunc(func(string) bool { return true })
+	b, _ := outsider.RecordAudit("task-1", true, "")
+	if err := m.SubmitAudit(b); !errors.Is(err, ErrNotAuditor) {
+		t.Errorf("non-supernode audit error = %v", err)
+	}
+	if _, err := m.RecordAudit("task-1", true, ""); !errors.Is(err, ErrNotAuditor) {
+		t.Errorf("local audit by non-supernode error = %v", err)
+	}
+}
+
+func TestDeviationPenalty(t *testing.T) {
+	m := newTestManager(t, "self")
+	reputation := map[string]float64{}
+	slashed := map[string]float64{}
+	m.SetReputationFunc(func(id string, delta float64, reason string) error {
+		reputation[id] += delta
+		return nil
+	})
+	m.SetSlashFunc(func(id string, ratio float64, reason string, evidence []string) (float64, error) {
+		if id == "sn4" {
+			return 0, errors.New("no collateral")
+		}
+		slashed[id] += ratio * 100
+		return ratio * 100, nil
+	})
+
+	submit := func(taskID, auditor string, passed bool) {
+		t.Helper()
+		peer := newTestManager(t, auditor)
+		a, _ := peer.RecordAudit(taskID, passed, "")
+		if err := m.SubmitAudit(a); err != nil {
+			t.Fatalf("SubmitAudit(%s) error = %v", auditor, err)
+		}
+	}
+
+	// sn3 放过了被否决的结果
+	submit("task-1", "sn1", false)
+	submit("task-1", "sn2", false)
+	if _, err := m.Consensus("task-1"); !errors.Is(err, ErrNoConsensus) {
+		t.Errorf("consensus before MinAuditors error = %v", err)
+	}
+	submit("task-1", "sn3", true)
+	c, err := m.Consensus("task-1")
+	if err != nil || c.Passed || c.FailCount != 2 {
+		t.Fatalf("consensus = %+v, %v", c, err)
+	}
+	// 共识后到达的结论同样比较：sn4 否决了被认可的结果
+	submit("task-2", "sn1", true)
+	submit("task-2", "sn2", true)
+	submit("task-2", "sn3", true)
+	submit("task-2", "sn4", false)
+
+	deviations := m.Deviations("", 0)
+	if len(deviations) != 2 {
+		t.Fatalf("deviations = %+v", deviations)
+	}
+	severe, minor := deviations[1], deviations[0]
+	if severe.Auditor != "sn3" || severe.Severity != SeveritySevere || severe.Expected || !severe.Actual {
+		t.Errorf("severe deviation = %+v", severe)
+	}
+	if minor.Auditor != "sn4" || minor.Severity != SeverityMinor || minor.Penalty == nil || minor.Penalty.Error == "" {
+		t.Errorf("minor deviation = %+v", minor)
+	}
+	if reputation["sn3"] != -20 || slashed["sn3"] != 30 || reputation["sn4"] != -5 || slashed["sn4"] != 0 {
+		t.Errorf("reputation = %v, slashed = %v", reputation, slashed)
+	}
+	if rate := m.PassRate("task-2"); rate != 0.75 {
+		t.Errorf("PassRate() = %v", rate)
+	}
+}
+
+func TestPenaltyRules(t *testing.T) {
+	m := newTestManager(t, "self")
+	if err := m.SetPenaltyRule(PenaltyRule{Severity: "fatal"}); !errors.Is(err, ErrUnknownSeverity) {
+		t.Errorf("unknown severity error = %v", err)
+	}
+	if err := m.SetPenaltyRule(PenaltyRule{Severity: SeverityMinor, SlashRatio: 1.5}); !errors.Is(err, ErrInvalidPenalty) {
+		t.Errorf("invalid ratio error = %v", err)
+	}
+	if err := m.SetPenaltyRule(PenaltyRule{Severity: SeverityMinor, RepPenalty: 8}); err != nil {
+		t.Fatalf("SetPenaltyRule() error = %v", err)
+	}
+
+	var delta float64
+	m.SetReputationFunc(func(id string, d float64, reason string) error {
+		delta = d
+		return nil
+	})
+	p, err := m.ManualPenalty("peer", "", "spam audits")
+	if err != nil || p.RepDelta != -8 || p.Slashed != 0 || delta != -8 {
+		t.Errorf("ManualPenalty() = %+v, %v", p, err)
+	}
+
+	// 惩罚力度持久化
+	reloaded, _ := New(m.config)
+	if rule := reloaded.PenaltyRules()[SeverityMinor]; rule.RepPenalty != 8 {
+		t.Errorf("reloaded rule = %+v", rule)
+	}
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// APIKeyPrefix API Key 密钥的前缀，便于与节点主令牌区分
+const APIKeyPrefix = "dak_"
+
+// keyReloadInterval 检查 Key 文件是否被其他进程修改的最小间隔
+const keyReloadInterval = time.Second
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrKeyRevoked  = errors.New("api key already revoked")
+	ErrEmptyRole   = errors.New("role is required")
+)
+
+// APIKey 一个 API Key；只保存密钥的哈希，明文仅在创建时返回一次
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Role      Role       `json:"role"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// KeyStore 持久化在数据目录中的 API Key 列表
+// CLI 与运行中的节点共用同一个文件：节点只读，校验时发现文件被修改即重新加载，
+// 因此 `token create` / `token revoke` 无需重启节点即可生效。
+type KeyStore struct {
+	path string
+
+	mu        sync.Mutex
+	keys      map[string]*APIKey // id -> key
+	byHash    map[string]*APIKey // hash -> key
+	modTime   time.Time
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+// NewKeyStore 打开 Key 文件，文件不存在时为空列表
+func NewKeyStore(path string) (*KeyStore, error) {
+	s := &KeyStore{
+		path:   path,
+		keys:   make(map[string]*APIKey),
+		byHash: make(map[string]*APIKey),
+		now:    time.Now,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 从文件读取 Key 列表（调用方持有锁或尚未共享）
+func (s *KeyStore) load() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var list []*APIKey
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	s.keys = make(map[string]*APIKey, len(list))
+	s.byHash = make(map[string]*APIKey, len(list))
+	for _, key := range list {
+		s.keys[key.ID] = key
+		s.byHash[key.Hash] = key
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// reloadIfChanged 文件修改时间变化时重新加载（调用方持有锁）
+func (s *KeyStore) reloadIfChanged() {
+	now := s.now()
+	if now.Sub(s.checkedAt) < keyReloadInterval {
+		return
+	}
+	s.checkedAt = now
+	info, err := os.Stat(s.path)
+	if err != nil || info.ModTime().Equal(s.modTime) {
+		return
+	}
+	if err := s.load(); err != nil {
+		// 读到写了一半的文件时保留旧列表，下次再试
+		s.modTime = time.Time{}
+	}
+}
+
+// save 写入文件（调用方持有锁）
+func (s *KeyStore) save() error {
+	list := make([]*APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		list = append(list, key)
+	}
+	sortKeys(list)
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// Create 为角色签发新 Key，返回 Key 和只显示这一次的密钥
+func (s *KeyStore) Create(role Role, name string) (*APIKey, string, error) {
+	if role == "" {
+		return nil, "", ErrEmptyRole
+	}
+	id, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	secret = APIKeyPrefix + secret
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	key := &APIKey{
+		ID:        "key-" + id,
+		Name:      name,
+		Role:      role,
+		Hash:      hashSecret(secret),
+		CreatedAt: s.now().UTC(),
+	}
+	s.keys[key.ID] = key
+	s.byHash[key.Hash] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		delete(s.byHash, key.Hash)
+		return nil, "", err
+	}
+	copied := *key
+	return &copied, secret, nil
+}
+
+// Revoke 撤销 Key；撤销记录保留在文件中
+func (s *KeyStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return ErrKeyRevoked
+	}
+	now := s.now().UTC()
+	key.RevokedAt = &now
+	if err := s.save(); err != nil {
+		key.RevokedAt = nil
+		return err
+	}
+	return nil
+}
+
+// Get 按 ID 查询 Key（含已撤销的）
+func (s *KeyStore) Get(id string) (*APIKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *key
+	return &copied, true
+}
+
+// List 全部 Key，按创建时间排序
+func (s *KeyStore) List() []*APIKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	list := make([]*APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		copied := *key
+		list = append(list, &copied)
+	}
+	sortKeys(list)
+	return list
+}
+
+// Authenticate 返回密钥对应的未撤销 Key
+func (s *KeyStore) Authenticate(secret string) (*APIKey, bool) {
+	if secret == "" {
+		return nil, false
+	}
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	key, ok := s.byHash[hash]
+	if !ok || key.RevokedAt != nil {
+		return nil, false
+	}
+	copied := *key
+	return &copied, true
+}
+
+// Revoked 密钥是否属于已撤销的 Key
+func (s *KeyStore) Revoked(secret string) bool {
+	if secret == "" {
+		return false
+	}
+	hash := hashSecret(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloadIfChanged()
+
+	key, ok := s.byHash[hash]
+	return ok && key.RevokedAt != nil
+}
+
+func sortKeys(list []*APIKey) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
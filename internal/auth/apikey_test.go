@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth", "api_keys.json")
+	store, err := NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("NewKeyStore() error = %v", err)
+	}
+
+	if _, _, err := store.Create("", "x"); !errors.Is(err, ErrEmptyRole) {
+		t.Errorf("empty role error = %v", err)
+	}
+	key, secret, err := store.Create(RoleOperator, "ci")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, APIKeyPrefix) || key.Hash == "" || strings.Contains(key.Hash, secret) {
+		t.Errorf("key = %+v, secret = %q", key, secret)
+	}
+
+	got, ok := store.Authenticate(secret)
+	if !ok || got.ID != key.ID || got.Role != RoleOperator {
+		t.Errorf("Authenticate() = %+v, %v", got, ok)
+	}
+	if _, ok := store.Authenticate("dak_wrong"); ok {
+		t.Error("wrong secret accepted")
+	}
+
+	// 重新打开后仍能校验
+	reopened, err := NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	if _, ok := reopened.Authenticate(secret); !ok {
+		t.Error("persisted key not accepted")
+	}
+
+	if err := store.Revoke("key-missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Revoke(missing) error = %v", err)
+	}
+	if err := store.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := store.Revoke(key.ID); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("Revoke twice error = %v", err)
+	}
+	if _, ok := store.Authenticate(secret); ok {
+		t.Error("revoked key accepted")
+	}
+	if list := store.List(); len(list) != 1 || list[0].RevokedAt == nil {
+		t.Errorf("List() = %+v", list)
+	}
+}
+
+func TestKeyStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	node, _ := NewKeyStore(path)
+	now := time.Now()
+	node.now = func() time.Time { return now }
+
+	// 另一个进程（CLI）签发的 Key 在检查间隔之后生效
+	cli, _ := NewKeyStore(path)
+	key, secret, err := cli.Create(RoleReadOnly, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, ok := node.Authenticate(secret); !ok {
+		t.Fatal("first check should load the new file")
+	}
+
+	if err := cli.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, ok := node.Authenticate(secret); !ok {
+		t.Error("file should not be re-read within the reload interval")
+	}
+	now = now.Add(keyReloadInterval)
+	if _, ok := node.Authenticate(secret); ok {
+		t.Error("revocation by another process not picked up")
+	}
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 基于角色的访问控制
+//
+// HTTP API 与管理后台除节点主令牌（拥有全部权限）外，还接受 `token create` 签发的多个 API Key。
+// 每个 Key 属于一个角色，角色是一组权限；每条路由按路由权限表映射到所需的权限，
+// 未列出的路由按方法归类：GET/HEAD 需要 read，其余需要 operate。
+// 罚没、手动惩罚、声誉修改、熔断与维护等只有 admin 可以执行，争议裁决、托管仲裁人设置与仲裁签名需要 arbitrate。
+
+// Permission 权限
+type Permission string
+
+const (
+	PermRead      Permission = "read"      // 查询
+	PermOperate   Permission = "operate"   // 日常写操作：消息、任务、留言板、转账、投票等
+	PermArbitrate Permission = "arbitrate" // 仲裁：争议裁决、托管结算签名、申诉处理
+	PermAdmin     Permission = "admin"     // 管理：罚没、惩罚、声誉修改、熔断、维护、密钥轮换
+)
+
+// Role 角色
+type Role string
+
+const (
+	RoleReadOnly   Role = "read-only"
+	RoleOperator   Role = "operator"
+	RoleArbitrator Role = "arbitrator"
+	RoleAdmin      Role = "admin"
+)
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrInvalidRoute      = errors.New("route path must start with /")
+)
+
+// RouteRule 路由权限规则
+type RouteRule struct {
+	Method     string     `json:"method,omitempty"` // 为空时匹配任意方法
+	Path       string     `json:"path"`             // 以 / 结尾时按前缀匹配
+	Permission Permission `json:"permission"`
+}
+
+// matches 规则是否匹配请求
+func (r RouteRule) matches(method, path string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	if strings.HasSuffix(r.Path, "/") {
+		return strings.HasPrefix(path, r.Path)
+	}
+	return path == r.Path
+}
+
+// RouteTable 路由权限表
+type RouteTable []RouteRule
+
+// Required 请求所需的权限
+// 多条规则匹配时取路径最长的，路径相同时指定了方法的优先；没有规则匹配时按方法归类。
+func (t RouteTable) Required(method, path string) Permission {
+	var best *RouteRule
+	for i := range t {
+		rule := &t[i]
+		if !rule.matches(method, path) {
+			continue
+		}
+		if best == nil || len(rule.Path) > len(best.Path) ||
+			(len(rule.Path) == len(best.Path) && best.Method == "" && rule.Method != "") {
+			best = rule
+		}
+	}
+	if best != nil {
+		return best.Permission
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return PermRead
+	}
+	return PermOperate
+}
+
+// merge 用 override 中的规则替换方法和路径相同的规则，其余追加
+func (t RouteTable) merge(override RouteTable) RouteTable {
+	merged := append(RouteTable{}, t...)
+	for _, rule := range override {
+		replaced := false
+		for i := range merged {
+			if strings.EqualFold(merged[i].Method, rule.Method) && merged[i].Path == rule.Path {
+				merged[i] = rule
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// Policy 角色定义与 HTTP API、管理后台的路由权限表
+type Policy struct {
+	Roles       map[Role][]Permission `json:"roles,omitempty"`
+	HTTPRoutes  RouteTable            `json:"http_routes,omitempty"`
+	AdminRoutes RouteTable            `json:"admin_routes,omitempty"`
+}
+
+// DefaultPolicy 返回默认策略
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Roles: map[Role][]Permission{
+			RoleReadOnly:   {PermRead},
+			RoleOperator:   {PermRead, PermOperate},
+			RoleArbitrator: {PermRead, PermArbitrate},
+			RoleAdmin:      {PermRead, PermOperate, PermArbitrate, PermAdmin},
+		},
+		HTTPRoutes: RouteTable{
+			{Path: "/api/v1/collateral/slash-by-node", Permission: PermAdmin},
+			{Path: "/api/v1/audit/manual-penalty", Permission: PermAdmin},
+			{Method: http.MethodPost, Path: "/api/v1/audit/penalty-config", Permission: PermAdmin},
+			{Path: "/api/v1/reputation/update", Permission: PermAdmin},
+			{Path: "/api/v1/reputation/webhook/keys/rotate", Permission: PermAdmin},
+			{Path: "/api/v1/incentive/award", Permission: PermAdmin},
+			{Path: "/api/v1/incentive/propagate", Permission: PermAdmin},
+			{Method: http.MethodPost, Path: "/api/v1/node/maintenance", Permission: PermAdmin},
+			{Path: "/api/v1/breaker/directive", Permission: PermAdmin},
+			{Path: "/api/v1/breaker/override", Permission: PermAdmin},
+			{Path: "/api/v1/retention/hold", Permission: PermAdmin},
+			{Path: "/api/v1/retention/release", Permission: PermAdmin},
+			{Path: "/api/v1/recovery/decide", Permission: PermAdmin},
+			{Path: "/api/v1/genesis/invite/create", Permission: PermAdmin},
+			{Path: "/api/v1/admission/invite", Permission: PermAdmin},
+			{Path: "/api/v1/admission/invite/revoke", Permission: PermAdmin},
+			{Path: "/api/v1/billing/usage", Permission: PermAdmin},
+			{Path: "/api/v1/escrow/arbitrators", Permission: PermArbitrate},
+			{Path: "/api/v1/escrow/propose", Permission: PermArbitrate},
+			{Path: "/api/v1/escrow/arbitrator-signature", Permission: PermArbitrate},
+			{Path: "/api/v1/escrow/resolve", Permission: PermArbitrate},
+			{Path: "/api/v1/dispute/apply-suggestion", Permission: PermArbitrate},
+			{Path: "/api/v1/bulletin/hide", Permission: PermArbitrate},
+			{Path: "/api/v1/bulletin/appeal/resolve", Permission: PermArbitrate},
+		},
+		AdminRoutes: RouteTable{
+			{Path: "/api/auth/token/refresh", Permission: PermAdmin},
+			{Path: "/api/collateral/slash-by-node", Permission: PermAdmin},
+			{Path: "/api/audit/manual-penalty", Permission: PermAdmin},
+			{Method: http.MethodPost, Path: "/api/audit/penalty-config", Permission: PermAdmin},
+			{Path: "/api/reputation/update", Permission: PermAdmin},
+			{Path: "/api/incentive/award", Permission: PermAdmin},
+			{Path: "/api/incentive/propagate", Permission: PermAdmin},
+			{Path: "/api/genesis/invite/create", Permission: PermAdmin},
+			{Path: "/api/escrow/arbitrator-signature", Permission: PermArbitrate},
+			{Path: "/api/escrow/resolve", Permission: PermArbitrate},
+			{Path: "/api/dispute/apply-suggestion", Permission: PermArbitrate},
+		},
+	}
+}
+
+// LoadPolicy 读取策略文件并合并到默认策略
+// 文件中的角色覆盖同名角色或新增角色，路由规则替换方法和路径相同的默认规则或追加；文件不存在时返回默认策略。
+func LoadPolicy(path string) (*Policy, error) {
+	policy := DefaultPolicy()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file Policy
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for role, perms := range file.Roles {
+		policy.Roles[role] = perms
+	}
+	policy.HTTPRoutes = policy.HTTPRoutes.merge(file.HTTPRoutes)
+	policy.AdminRoutes = policy.AdminRoutes.merge(file.AdminRoutes)
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Validate 检查角色和路由规则引用的权限是否有效
+func (p *Policy) Validate() error {
+	for role, perms := range p.Roles {
+		if role == "" {
+			return ErrUnknownRole
+		}
+		for _, perm := range perms {
+			if !validPermission(perm) {
+				return fmt.Errorf("role %s: %w: %s", role, ErrUnknownPermission, perm)
+			}
+		}
+	}
+	for _, table := range []RouteTable{p.HTTPRoutes, p.AdminRoutes} {
+		for _, rule := range table {
+			if !strings.HasPrefix(rule.Path, "/") {
+				return fmt.Errorf("%w: %q", ErrInvalidRoute, rule.Path)
+			}
+			if !validPermission(rule.Permission) {
+				return fmt.Errorf("route %s: %w: %s", rule.Path, ErrUnknownPermission, rule.Permission)
+			}
+		}
+	}
+	return nil
+}
+
+func validPermission(perm Permission) bool {
+	switch perm {
+	case PermRead, PermOperate, PermArbitrate, PermAdmin:
+		return true
+	}
+	return false
+}
+
+// HasRole 角色是否已定义
+func (p *Policy) HasRole(role Role) bool {
+	_, ok := p.Roles[role]
+	return ok
+}
+
+// RoleNames 已定义的角色，按名称排序
+func (p *Policy) RoleNames() []string {
+	names := make([]string, 0, len(p.Roles))
+	for role := range p.Roles {
+		names = append(names, string(role))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Grants 角色是否拥有权限
+func (p *Policy) Grants(role Role, perm Permission) bool {
+	for _, granted := range p.Roles[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorizer 用 API Key 和一张路由权限表校验请求
+type Authorizer struct {
+	keys   *KeyStore
+	policy *Policy
+	routes RouteTable
+}
+
+// NewAuthorizer 创建校验器，routes 通常为 policy.HTTPRoutes 或 policy.AdminRoutes
+func NewAuthorizer(keys *KeyStore, policy *Policy, routes RouteTable) *Authorizer {
+	return &Authorizer{keys: keys, policy: policy, routes: routes}
+}
+
+// Authenticate 返回密钥对应的有效 Key
+func (a *Authorizer) Authenticate(secret string) (*APIKey, bool) {
+	return a.keys.Authenticate(secret)
+}
+
+// Key 按 ID 查询未撤销的 Key
+func (a *Authorizer) Key(id string) (*APIKey, bool) {
+	key, ok := a.keys.Get(id)
+	if !ok || key.RevokedAt != nil {
+		return nil, false
+	}
+	return key, true
+}
+
+// KeyActive Key 是否存在且未撤销
+func (a *Authorizer) KeyActive(id string) bool {
+	key, ok := a.keys.Get(id)
+	return ok && key.RevokedAt == nil
+}
+
+// Allowed 角色是否可以访问路由
+func (a *Authorizer) Allowed(role Role, method, path string) bool {
+	return a.policy.Grants(role, a.routes.Required(method, path))
+}
+
+// AllowedRole 按角色名校验路由权限，未定义的角色没有任何权限
+func (a *Authorizer) AllowedRole(role, method, path string) bool {
+	return a.Allowed(Role(role), method, path)
+}
+
+// Revoked 令牌是否为已撤销的 API Key
+func (a *Authorizer) Revoked(token string) bool {
+	return a.keys.Revoked(token)
+}
+
+// Authorize 校验令牌对路由的访问权限；令牌不是有效的 API Key 时 role 为空
+func (a *Authorizer) Authorize(token, method, path string) (role string, allowed bool) {
+	key, ok := a.keys.Authenticate(token)
+	if !ok {
+		return "", false
+	}
+	return string(key.Role), a.Allowed(key.Role, method, path)
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRouteTableRequired(t *testing.T) {
+	routes := DefaultPolicy().HTTPRoutes
+	tests := []struct {
+		method, path string
+		want         Permission
+	}{
+		{"GET", "/api/v1/node/info", PermRead},
+		{"POST", "/api/v1/message/send", PermOperate},
+		{"POST", "/api/v1/collateral/slash-by-node", PermAdmin},
+		{"GET", "/api/v1/audit/penalty-config", PermRead},
+		{"POST", "/api/v1/audit/penalty-config", PermAdmin},
+		{"POST", "/api/v1/escrow/arbitrator-signature", PermArbitrate},
+		{"POST", "/api/v1/escrow/arbitrators", PermArbitrate},
+		{"POST", "/api/v1/task/settle", PermOperate},
+		{"POST", "/api/v1/bulletin/appeal", PermOperate},
+	}
+	for _, tt := range tests {
+		if got := routes.Required(tt.method, tt.path); got != tt.want {
+			t.Errorf("Required(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// 前缀规则，路径更长或指定了方法的规则优先
+	table := RouteTable{
+		{Path: "/api/v1/dispute/", Permission: PermArbitrate},
+		{Method: "GET", Path: "/api/v1/dispute/", Permission: PermRead},
+		{Path: "/api/v1/dispute/file", Permission: PermOperate},
+	}
+	if got := table.Required("POST", "/api/v1/dispute/evidence"); got != PermArbitrate {
+		t.Errorf("prefix rule = %s", got)
+	}
+	if got := table.Required("GET", "/api/v1/dispute/list"); got != PermRead {
+		t.Errorf("method rule = %s", got)
+	}
+	if got := table.Required("POST", "/api/v1/dispute/file"); got != PermOperate {
+		t.Errorf("exact rule = %s", got)
+	}
+}
+
+func TestPolicyGrants(t *testing.T) {
+	policy := DefaultPolicy()
+	a := NewAuthorizer(nil, policy, policy.HTTPRoutes)
+
+	if !a.Allowed(RoleReadOnly, "GET", "/api/v1/node/info") || a.Allowed(RoleReadOnly, "POST", "/api/v1/message/send") {
+		t.Error("read-only should only read")
+	}
+	if !a.Allowed(RoleOperator, "POST", "/api/v1/message/send") || a.Allowed(RoleOperator, "POST", "/api/v1/collateral/slash-by-node") {
+		t.Error("operator should write but not slash")
+	}
+	if !a.Allowed(RoleArbitrator, "POST", "/api/v1/escrow/resolve") || a.Allowed(RoleArbitrator, "POST", "/api/v1/task/create") {
+		t.Error("arbitrator should arbitrate but not operate")
+	}
+	if !a.Allowed(RoleAdmin, "POST", "/api/v1/audit/manual-penalty") {
+		t.Error("admin should be allowed everything")
+	}
+	if a.Allowed("unknown", "GET", "/api/v1/node/info") {
+		t.Error("undefined role should be denied")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rbac.json")
+
+	policy, err := LoadPolicy(path)
+	if err != nil || !policy.HasRole(RoleOperator) {
+		t.Fatalf("LoadPolicy(missing) = %v, %v", policy, err)
+	}
+
+	os.WriteFile(path, []byte(`{
+		"roles": {"auditor": ["read", "arbitrate"], "operator": ["read"]},
+		"http_routes": [
+			{"path": "/api/v1/collateral/slash-by-node", "permission": "arbitrate"},
+			{"method": "GET", "path": "/api/v1/log/export", "permission": "admin"}
+		]
+	}`), 0600)
+	policy, err = LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if !policy.Grants("auditor", PermArbitrate) || policy.Grants(RoleOperator, PermOperate) || !policy.Grants(RoleAdmin, PermAdmin) {
+		t.Errorf("roles = %v", policy.Roles)
+	}
+	if got := policy.HTTPRoutes.Required("POST", "/api/v1/collateral/slash-by-node"); got != PermArbitrate {
+		t.Errorf("overridden rule = %s", got)
+	}
+	if got := policy.HTTPRoutes.Required("GET", "/api/v1/log/export"); got != PermAdmin {
+		t.Errorf("added rule = %s", got)
+	}
+	if len(policy.HTTPRoutes) != len(DefaultPolicy().HTTPRoutes)+1 {
+		t.Errorf("HTTPRoutes len = %d", len(policy.HTTPRoutes))
+	}
+
+	os.WriteFile(path, []byte(`{"roles": {"bad": ["everything"]}}`), 0600)
+	if _, err := LoadPolicy(path); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("invalid permission error = %v", err)
+	}
+	os.WriteFile(path, []byte(`{"admin_routes": [{"path": "api/x", "permission": "read"}]}`), 0600)
+	if _, err := LoadPolicy(path); !errors.Is(err, ErrInvalidRoute) {
+		t.Errorf("invalid route error = %v", err)
+	}
+}
@@ -0,0 +1,567 @@
+// Package backup 实现数据目录的加密离线备份
+// 快照为数据目录的 tar.gz 归档，使用口令派生的 AES-256-GCM 密钥加密后推送到 S3 兼容存储；
+// 远端对象附带密文 SHA-256 元数据用于完整性校验，恢复时先校验再解密解包。
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidConfig     = errors.New("invalid backup config")
+	ErrPassphraseMissing = errors.New("backup passphrase is required")
+	ErrObjectNotFound    = errors.New("backup object not found")
+	ErrIntegrity         = errors.New("backup integrity check failed")
+	ErrDecrypt           = errors.New("backup decryption failed (wrong passphrase or corrupted data)")
+	ErrTargetNotEmpty    = errors.New("restore target is not empty")
+)
+
+const (
+	magic          = "DAANBAK1"
+	saltSize       = 16
+	maxIterations  = 10000000
+	objectSuffix   = ".tar.gz.enc"
+	metaSHA256     = "daan-sha256"
+	metaCreated    = "daan-created"
+	keyTimeLayout  = "20060102T150405Z"
+	defaultPrefix  = "daan-backups"
+	snapshotPrefix = "snapshot-"
+)
+
+// kdfIterations PBKDF2 迭代次数，写入快照头部，调整后旧快照仍可解密
+var kdfIterations = 600000
+
+// Config 备份配置
+type Config struct {
+	SourceDir  string   // 备份的数据目录
+	Exclude    []string // 相对 SourceDir 排除的路径（目录或文件）
+	Prefix     string   // 远端对象前缀
+	Passphrase string   // 加密口令
+
+	Interval time.Duration // 定时备份间隔，0 表示不定时
+	KeepLast int           // 至少保留最近的快照数
+	MaxAge   time.Duration // 超过此时长的快照会被清理（仍保留 KeepLast 个），0 表示不按时间清理
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(sourceDir string) *Config {
+	return &Config{
+		SourceDir: sourceDir,
+		Exclude:   []string{"backup", "logs", "node.pid"},
+		Prefix:    defaultPrefix,
+		Interval:  24 * time.Hour,
+		KeepLast:  7,
+		MaxAge:    30 * 24 * time.Hour,
+	}
+}
+
+// Snapshot 远端快照
+type Snapshot struct {
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"`
+}
+
+// Status 定时备份状态
+type Status struct {
+	LastBackup *Snapshot `json:"last_backup,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	LastRunAt  time.Time `json:"last_run_at,omitempty"`
+	NextRunAt  time.Time `json:"next_run_at,omitempty"`
+	Pruned     int       `json:"pruned"`
+}
+
+// Manager 备份管理器
+type Manager struct {
+	config *Config
+	store  ObjectStore
+
+	mu     sync.Mutex
+	status Status
+	stopCh chan struct{}
+	now    func() time.Time
+}
+
+// NewManager 创建备份管理器
+func NewManager(config *Config, store ObjectStore) (*Manager, error) {
+	if config == nil || config.SourceDir == "" {
+		return nil, fmt.Errorf("%w: source dir is required", ErrInvalidConfig)
+	}
+	if store == nil {
+		return nil, fmt.Errorf("%w: object store is required", ErrInvalidConfig)
+	}
+	if config.Passphrase == "" {
+		return nil, ErrPassphraseMissing
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultPrefix
+	}
+	return &Manager{
+		config: config,
+		store:  store,
+		now:    time.Now,
+	}, nil
+}
+
+// Push 创建加密快照并上传
+func (m *Manager) Push(ctx context.Context) (*Snapshot, error) {
+	archive, err := m.archive()
+	if err != nil {
+		return nil, fmt.Errorf("archive data dir: %w", err)
+	}
+	data, err := encrypt(archive, m.config.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	created := m.now().UTC()
+	sum := sha256.Sum256(data)
+	snap := &Snapshot{
+		Key:       m.objectKey(created),
+		CreatedAt: created,
+		Size:      int64(len(data)),
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+	meta := map[string]string{
+		metaSHA256:  snap.SHA256,
+		metaCreated: created.Format(time.RFC3339),
+	}
+	if err := m.store.Put(ctx, snap.Key, data, meta); err != nil {
+		return nil, fmt.Errorf("upload snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// List 列出远端快照（按时间从新到旧）
+func (m *Manager) List(ctx context.Context) ([]*Snapshot, error) {
+	objects, err := m.store.List(ctx, m.config.Prefix+"/"+snapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*Snapshot, 0, len(objects))
+	for _, obj := range objects {
+		created, ok := parseObjectKey(obj.Key)
+		if !ok {
+			continue
+		}
+		snapshots = append(snapshots, &Snapshot{Key: obj.Key, CreatedAt: created, Size: obj.Size})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Verify 下载快照并校验完整性：密文 SHA-256 与上传时记录的一致，且能用口令解密并完整解包
+func (m *Manager) Verify(ctx context.Context, key string) (*Snapshot, error) {
+	archive, snap, err := m.fetch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := walkArchive(archive, func(*tar.Header, io.Reader) error { return nil }); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntegrity, err)
+	}
+	return snap, nil
+}
+
+// Restore 校验并解密快照，解包到 targetDir
+// targetDir 非空时需要 force，已有同名文件会被覆盖。
+func (m *Manager) Restore(ctx context.Context, key, targetDir string, force bool) (*Snapshot, error) {
+	if !force {
+		if entries, err := os.ReadDir(targetDir); err == nil && len(entries) > 0 {
+			return nil, ErrTargetNotEmpty
+		}
+	}
+
+	archive, snap, err := m.fetch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := extract(archive, targetDir); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Latest 最新的快照
+func (m *Manager) Latest(ctx context.Context) (*Snapshot, error) {
+	snapshots, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, ErrObjectNotFound
+	}
+	return snapshots[0], nil
+}
+
+// Prune 按保留策略清理远端快照，返回被删除的对象
+// 始终保留最近 KeepLast 个快照；其余快照超过 MaxAge 时删除，MaxAge 为 0 时删除全部多余快照。
+func (m *Manager) Prune(ctx context.Context) ([]string, error) {
+	snapshots, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := m.config.KeepLast
+	if keep < 1 {
+		keep = 1
+	}
+	cutoff := time.Time{}
+	if m.config.MaxAge > 0 {
+		cutoff = m.now().Add(-m.config.MaxAge)
+	}
+
+	var deleted []string
+	for i, snap := range snapshots {
+		if i < keep {
+			continue
+		}
+		if !cutoff.IsZero() && snap.CreatedAt.After(cutoff) {
+			continue
+		}
+		if err := m.store.Delete(ctx, snap.Key); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", snap.Key, err)
+		}
+		deleted = append(deleted, snap.Key)
+	}
+	return deleted, nil
+}
+
+// RunOnce 执行一次备份并按保留策略清理
+func (m *Manager) RunOnce(ctx context.Context) (*Snapshot, error) {
+	snap, err := m.Push(ctx)
+	var pruned []string
+	if err == nil {
+		pruned, err = m.Prune(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.LastRunAt = m.now()
+	m.status.Pruned += len(pruned)
+	if snap != nil {
+		m.status.LastBackup = snap
+	}
+	m.status.LastError = ""
+	if err != nil {
+		m.status.LastError = err.Error()
+	}
+	return snap, err
+}
+
+// Start 按 Interval 定时备份
+func (m *Manager) Start() {
+	if m.config.Interval <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.status.NextRunAt = m.now().Add(m.config.Interval)
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), m.config.Interval)
+				m.RunOnce(ctx)
+				cancel()
+
+				m.mu.Lock()
+				m.status.NextRunAt = m.now().Add(m.config.Interval)
+				m.mu.Unlock()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时备份
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopCh != nil {
+		close(m.stopCh)
+		m.stopCh = nil
+	}
+}
+
+// Status 返回定时备份状态
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// ============ 内部方法 ============
+
+func (m *Manager) objectKey(created time.Time) string {
+	return m.config.Prefix + "/" + snapshotPrefix + created.Format(keyTimeLayout) + objectSuffix
+}
+
+func parseObjectKey(key string) (time.Time, bool) {
+	name := path.Base(key)
+	if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, objectSuffix) {
+		return time.Time{}, false
+	}
+	ts := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), objectSuffix)
+	created, err := time.Parse(keyTimeLayout, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+// fetch 下载、校验并解密快照
+func (m *Manager) fetch(ctx context.Context, key string) ([]byte, *Snapshot, error) {
+	data, meta, err := m.store.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	expected := meta[metaSHA256]
+	if expected == "" {
+		return nil, nil, fmt.Errorf("%w: object has no checksum metadata", ErrIntegrity)
+	}
+	if actual != expected {
+		return nil, nil, fmt.Errorf("%w: sha256 %s, expected %s", ErrIntegrity, actual, expected)
+	}
+
+	archive, err := decrypt(data, m.config.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	created, _ := parseObjectKey(key)
+	return archive, &Snapshot{Key: key, CreatedAt: created, Size: int64(len(data)), SHA256: actual}, nil
+}
+
+func (m *Manager) excluded(rel string) bool {
+	for _, ex := range m.config.Exclude {
+		ex = filepath.ToSlash(filepath.Clean(ex))
+		if rel == ex || strings.HasPrefix(rel, ex+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// archive 将数据目录打包为 tar.gz
+func (m *Manager) archive() ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	root := m.config.SourceDir
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if m.excluded(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil // 跳过 socket、符号链接等
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, info.Size())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func walkArchive(archive []byte, fn func(hdr *tar.Header, r io.Reader) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extract 解包到目标目录，拒绝越出目标目录的路径
+func extract(archive []byte, targetDir string) error {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+	return walkArchive(archive, func(hdr *tar.Header, r io.Reader) error {
+		name := filepath.FromSlash(hdr.Name)
+		if filepath.IsAbs(name) || strings.HasPrefix(filepath.Clean(name), "..") {
+			return fmt.Errorf("%w: unsafe path %q", ErrIntegrity, hdr.Name)
+		}
+		dst := filepath.Join(targetDir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			return os.MkdirAll(dst, 0755)
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, r)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// encrypt 格式: magic | PBKDF2 迭代次数(uint32) | salt | nonce | AES-256-GCM 密文
+func encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(magic)+4+saltSize+len(nonce))
+	header = append(header, magic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(kdfIterations))
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	// 头部作为附加数据参与认证
+	out := make([]byte, len(header), len(header)+len(plaintext)+gcm.Overhead())
+	copy(out, header)
+	return gcm.Seal(out, nonce, plaintext, header), nil
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	prefixLen := len(magic) + 4 + saltSize
+	if len(data) < prefixLen || string(data[:len(magic)]) != magic {
+		return nil, fmt.Errorf("%w: unknown format", ErrDecrypt)
+	}
+	iterations := int(binary.BigEndian.Uint32(data[len(magic):]))
+	if iterations < 1 || iterations > maxIterations {
+		return nil, fmt.Errorf("%w: invalid header", ErrDecrypt)
+	}
+	salt := data[len(magic)+4 : prefixLen]
+	gcm, err := newGCM(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	headerLen := prefixLen + gcm.NonceSize()
+	if len(data) < headerLen+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: truncated", ErrDecrypt)
+	}
+
+	header := data[:headerLen]
+	nonce := data[prefixLen:headerLen]
+	plaintext, err := gcm.Open(nil, nonce, data[headerLen:], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseMissing
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,405 @@
+// Package bandwidth 出站带宽的按节点公平调度
+//
+// 每个对端节点一个出站队列，发送方在写出数据前调用 Acquire 申请字节额度，
+// 调度器按差额轮询（Deficit Round Robin）在各队列间分配：每轮每个有排队的节点
+// 获得 Quantum × 权重 的额度，额度足够时放行队首请求。权重由声誉和关系
+// （邻居、超级节点）决定，因此单个话多的节点只能用掉自己那一份，
+// 其他节点的发送不会被饿死。配置了总带宽预算时，放行速度同时受预算限制。
+// 流协议的出站写入经 GateStream 接入同一个调度器。
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSchedulerStopped = errors.New("bandwidth scheduler stopped")
+	ErrQueueFull        = errors.New("outbound queue full")
+)
+
+// PeerRelation 本节点与对端的关系，用于计算调度权重
+type PeerRelation struct {
+	Reputation float64 // 对端声誉（0-100）
+	Neighbor   bool    // 是否为邻居
+	Supernode  bool    // 是否为超级节点
+}
+
+// PeerRelationFunc 查询对端关系
+type PeerRelationFunc func(peerID string) PeerRelation
+
+// Config 调度器配置
+type Config struct {
+	Rate     int64 // 出站带宽预算（字节/秒），0 表示不限速，只按公平顺序放行
+	Quantum  int   // 权重为 1 的节点每轮获得的字节额度
+	MaxQueue int   // 每个节点最多排队的请求数
+
+	// 权重 = (1 + 声誉/100 × ReputationFactor) × 关系倍数，限制在 [MinWeight, MaxWeight]
+	ReputationFactor    float64
+	NeighborMultiplier  float64
+	SupernodeMultiplier float64
+	MinWeight           float64
+	MaxWeight           float64
+}
+
+// DefaultConfig 返回默认配置，默认不限总带宽，只按权重公平排序
+func DefaultConfig() *Config {
+	return &Config{
+		Rate:                0,
+		Quantum:             64 * 1024, // 与文件传输分片大小一致
+		MaxQueue:            256,
+		ReputationFactor:    1.0,
+		NeighborMultiplier:  1.5,
+		SupernodeMultiplier: 2.0,
+		MinWeight:           0.25,
+		MaxWeight:           8.0,
+	}
+}
+
+// Weight 按声誉和关系计算调度权重
+func (c *Config) Weight(rel PeerRelation) float64 {
+	rep := math.Max(0, math.Min(100, rel.Reputation))
+	w := 1 + rep/100*c.ReputationFactor
+	if rel.Neighbor {
+		w *= c.NeighborMultiplier
+	}
+	if rel.Supernode {
+		w *= c.SupernodeMultiplier
+	}
+	return math.Max(c.MinWeight, math.Min(c.MaxWeight, w))
+}
+
+// request 一次额度申请
+type request struct {
+	peerID   string
+	size     int
+	enqueued time.Time
+	granted  chan struct{}
+	canceled bool
+}
+
+// peerQueue 单个节点的出站队列与统计
+type peerQueue struct {
+	id      string
+	weight  float64
+	deficit float64
+	queue   []*request
+	active  bool // 是否在轮询列表中
+
+	queuedBytes int64
+	sentBytes   int64
+	grants      int64
+	dropped     int64
+	waitTotal   time.Duration
+}
+
+// PeerShare 单个节点的出站占用统计
+type PeerShare struct {
+	PeerID         string  `json:"peer_id"`
+	Weight         float64 `json:"weight"`
+	FairShare      float64 `json:"fair_share"` // 按权重在有流量的节点中应得的份额
+	Share          float64 `json:"share"`      // 实际放行字节占比
+	SentBytes      int64   `json:"sent_bytes"`
+	QueuedRequests int     `json:"queued_requests"`
+	QueuedBytes    int64   `json:"queued_bytes"`
+	Grants         int64   `json:"grants"`
+	Dropped        int64   `json:"dropped"` // 队列满被拒绝的请求数
+	AvgWaitMs      float64 `json:"avg_wait_ms"`
+}
+
+// Stats 调度器统计
+type Stats struct {
+	Rate        int64        `json:"rate"`
+	TotalBytes  int64        `json:"total_bytes"`
+	ActivePeers int          `json:"active_peers"` // 当前有排队的节点数
+	Peers       []*PeerShare `json:"peers"`        // 按放行字节数降序
+}
+
+// FairScheduler 出站带宽公平调度器
+type FairScheduler struct {
+	mu       sync.Mutex
+	config   *Config
+	peers    map[string]*peerQueue
+	active   []*peerQueue // 轮询列表
+	total    int64
+	relation PeerRelationFunc
+
+	wake    chan struct{}
+	stopCh  chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewFairScheduler 创建调度器
+func NewFairScheduler(config *Config) *FairScheduler {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &FairScheduler{
+		config: config,
+		peers:  make(map[string]*peerQueue),
+		wake:   make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+	}
+}
+
+// SetPeerRelationFunc 设置对端关系查询函数，未设置时所有节点权重为 1
+func (s *FairScheduler) SetPeerRelationFunc(fn PeerRelationFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relation = fn
+}
+
+// Start 启动调度
+func (s *FairScheduler) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Stop 停止调度，所有等待中的申请返回 ErrSchedulerStopped
+func (s *FairScheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	close(s.stopCh)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Acquire 申请向 peerID 发送 size 字节，阻塞到调度器放行或 ctx 取消
+func (s *FairScheduler) Acquire(ctx context.Context, peerID string, size int) error {
+	if size <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return ErrSchedulerStopped
+	}
+	pq := s.peerLocked(peerID)
+	if len(pq.queue) >= s.config.MaxQueue {
+		pq.dropped++
+		s.mu.Unlock()
+		return ErrQueueFull
+	}
+	req := &request{peerID: peerID, size: size, enqueued: time.Now(), granted: make(chan struct{})}
+	pq.queue = append(pq.queue, req)
+	pq.queuedBytes += int64(size)
+	if !pq.active {
+		pq.weight = s.weightLocked(peerID)
+		pq.active = true
+		s.active = append(s.active, pq)
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-req.granted:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-req.granted:
+			return nil // 已放行，额度已计入
+		default:
+		}
+		req.canceled = true
+		return ctx.Err()
+	case <-s.stopCh:
+		return ErrSchedulerStopped
+	}
+}
+
+// GateStream 包装向 peerID 的流：每次写入前按写入大小申请额度，超过 Quantum 的写入分段申请，
+// 大块写入不会一次占满一轮额度。读取和关闭沿用原流；ctx 取消或调度器停止后写入返回错误。
+func (s *FairScheduler) GateStream(ctx context.Context, peerID string, rw io.ReadWriteCloser) io.ReadWriteCloser {
+	return &gatedStream{ReadWriteCloser: rw, s: s, ctx: ctx, peerID: peerID}
+}
+
+// gatedStream 写入经调度器放行的流
+type gatedStream struct {
+	io.ReadWriteCloser
+	s      *FairScheduler
+	ctx    context.Context
+	peerID string
+}
+
+func (g *gatedStream) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if q := g.s.config.Quantum; q > 0 && n > q {
+			n = q
+		}
+		if err := g.s.Acquire(g.ctx, g.peerID, n); err != nil {
+			return written, err
+		}
+		m, err := g.ReadWriteCloser.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Stats 返回各节点的出站占用
+func (s *FairScheduler) Stats() *Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &Stats{Rate: s.config.Rate, TotalBytes: s.total, ActivePeers: len(s.active)}
+	var activeWeight float64
+	for _, pq := range s.active {
+		activeWeight += pq.weight
+	}
+	for _, pq := range s.peers {
+		share := &PeerShare{
+			PeerID:         pq.id,
+			Weight:         pq.weight,
+			SentBytes:      pq.sentBytes,
+			QueuedRequests: len(pq.queue),
+			QueuedBytes:    pq.queuedBytes,
+			Grants:         pq.grants,
+			Dropped:        pq.dropped,
+		}
+		if pq.active && activeWeight > 0 {
+			share.FairShare = pq.weight / activeWeight
+		}
+		if s.total > 0 {
+			share.Share = float64(pq.sentBytes) / float64(s.total)
+		}
+		if pq.grants > 0 {
+			share.AvgWaitMs = float64(pq.waitTotal.Microseconds()) / 1000 / float64(pq.grants)
+		}
+		stats.Peers = append(stats.Peers, share)
+	}
+	sort.Slice(stats.Peers, func(i, j int) bool {
+		if stats.Peers[i].SentBytes != stats.Peers[j].SentBytes {
+			return stats.Peers[i].SentBytes > stats.Peers[j].SentBytes
+		}
+		return stats.Peers[i].PeerID < stats.Peers[j].PeerID
+	})
+	return stats
+}
+
+// run 调度循环：按带宽预算控制放行速度，到点后按 DRR 顺序选出下一个请求
+func (s *FairScheduler) run() {
+	defer s.wg.Done()
+	var nextFree time.Time
+
+	for {
+		if s.config.Rate > 0 {
+			if wait := time.Until(nextFree); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-s.stopCh:
+					timer.Stop()
+					return
+				}
+			}
+		}
+
+		req, ok := s.next()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.stopCh:
+				return
+			}
+		}
+
+		if s.config.Rate > 0 {
+			if now := time.Now(); nextFree.Before(now) {
+				nextFree = now
+			}
+			nextFree = nextFree.Add(time.Duration(float64(req.size) / float64(s.config.Rate) * float64(time.Second)))
+		}
+	}
+}
+
+// next 按 DRR 选出下一个请求，记账后放行
+// 放行与 Acquire 的取消检查在同一把锁下进行，已取消的请求不会被计入。
+func (s *FairScheduler) next() (*request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.active) > 0 {
+		pq := s.active[0]
+		s.dropCanceledLocked(pq)
+		if len(pq.queue) == 0 {
+			s.deactivateLocked()
+			continue
+		}
+
+		head := pq.queue[0]
+		if pq.deficit < float64(head.size) {
+			// 本轮额度不足：补充额度后移到队尾，下一轮再放行
+			pq.deficit += float64(s.config.Quantum) * pq.weight
+			s.active = append(s.active[1:], pq)
+			continue
+		}
+
+		pq.deficit -= float64(head.size)
+		pq.queue = pq.queue[1:]
+		pq.queuedBytes -= int64(head.size)
+		pq.sentBytes += int64(head.size)
+		pq.grants++
+		pq.waitTotal += time.Since(head.enqueued)
+		s.total += int64(head.size)
+		if len(pq.queue) == 0 {
+			s.deactivateLocked()
+		}
+		close(head.granted)
+		return head, true
+	}
+	return nil, false
+}
+
+// deactivateLocked 队首节点已无排队请求，移出轮询列表并清空额度
+func (s *FairScheduler) deactivateLocked() {
+	pq := s.active[0]
+	pq.deficit = 0
+	pq.active = false
+	s.active = s.active[1:]
+}
+
+func (s *FairScheduler) dropCanceledLocked(pq *peerQueue) {
+	for len(pq.queue) > 0 && pq.queue[0].canceled {
+		pq.queuedBytes -= int64(pq.queue[0].size)
+		pq.queue = pq.queue[1:]
+	}
+}
+
+func (s *FairScheduler) peerLocked(peerID string) *peerQueue {
+	pq, ok := s.peers[peerID]
+	if !ok {
+		pq = &peerQueue{id: peerID, weight: 1}
+		s.peers[peerID] = pq
+	}
+	return pq
+}
+
+func (s *FairScheduler) weightLocked(peerID string) float64 {
+	if s.relation == nil {
+		return 1
+	}
+	return s.config.Weight(s.relation(peerID))
+}
@@ -0,0 +1,218 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enqueue 并发提交申请，等待全部入队（调度器未启动，请求一直排队）
+func enqueue(t *testing.T, s *FairScheduler, counts map[string]int, size int) {
+	want := 0
+	for peerID, n := range counts {
+		want += n
+		for i := 0; i < n; i++ {
+			go s.Acquire(context.Background(), peerID, size)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		queued := 0
+		for _, p := range s.Stats().Peers {
+			queued += p.QueuedRequests
+		}
+		if queued == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("requests not queued in time")
+}
+
+// drain 逐个放行，返回放行顺序
+func drain(s *FairScheduler) []string {
+	var order []string
+	for {
+		req, ok := s.next()
+		if !ok {
+			return order
+		}
+		order = append(order, req.peerID)
+	}
+}
+
+func countIn(order []string, peerID string) int {
+	n := 0
+	for _, id := range order {
+		if id == peerID {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWeight(t *testing.T) {
+	c := DefaultConfig()
+	cases := []struct {
+		rel  PeerRelation
+		want float64
+	}{
+		{PeerRelation{}, 1},
+		{PeerRelation{Reputation: 100}, 2},
+		{PeerRelation{Reputation: 100, Neighbor: true}, 3},
+		{PeerRelation{Reputation: 100, Neighbor: true, Supernode: true}, 6},
+		{PeerRelation{Reputation: 500, Neighbor: true, Supernode: true}, 6},
+		{PeerRelation{Reputation: -50}, 1},
+	}
+	for _, tc := range cases {
+		if got := c.Weight(tc.rel); got != tc.want {
+			t.Errorf("Weight(%+v) = %v, want %v", tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestChattyPeerDoesNotStarveOthers(t *testing.T) {
+	config := DefaultConfig()
+	config.Quantum = 100
+	s := NewFairScheduler(config)
+
+	enqueue(t, s, map[string]int{"chatty": 50, "quiet": 5}, 100)
+	order := drain(s)
+	if len(order) != 55 {
+		t.Fatalf("expected 55 grants, got %d", len(order))
+	}
+	// quiet 的 5 个请求与 chatty 交替放行，而不是排在 chatty 的 50 个之后
+	if got := countIn(order[:10], "quiet"); got != 5 {
+		t.Errorf("expected quiet peer interleaved, got %d of 5 in first 10 grants: %v", got, order[:10])
+	}
+}
+
+func TestWeightedShare(t *testing.T) {
+	config := DefaultConfig()
+	config.Quantum = 100
+	s := NewFairScheduler(config)
+	s.SetPeerRelationFunc(func(peerID string) PeerRelation {
+		return PeerRelation{Supernode: peerID == "super"}
+	})
+
+	enqueue(t, s, map[string]int{"super": 40, "normal": 40}, 100)
+
+	stats := s.Stats()
+	for _, p := range stats.Peers {
+		want := 1.0 / 3
+		if p.PeerID == "super" {
+			want = 2.0 / 3
+		}
+		if p.FairShare < want-0.01 || p.FairShare > want+0.01 {
+			t.Errorf("%s: expected fair share %.2f, got %.2f", p.PeerID, want, p.FairShare)
+		}
+	}
+
+	// 双方都有积压时，超级节点（权重 2）获得 2/3 的放行
+	order := drain(s)
+	if got := countIn(order[:30], "super"); got != 20 {
+		t.Errorf("expected 20 of first 30 grants for supernode, got %d", got)
+	}
+
+	stats = s.Stats()
+	if stats.TotalBytes != 8000 || stats.ActivePeers != 0 || len(stats.Peers) != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	for _, p := range stats.Peers {
+		if p.Share != 0.5 || p.Grants != 40 || p.QueuedBytes != 0 {
+			t.Errorf("expected equal lifetime share after draining, got %+v", p)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.Rate = 100 * 1024 // 100KB/s
+	s := NewFairScheduler(config)
+	s.Start()
+	defer s.Stop()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := s.Acquire(context.Background(), "p", 10*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 第一次立即放行，之后每 10KB 需要约 100ms
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected rate limiting, finished in %v", elapsed)
+	}
+}
+
+func TestAcquireCancelAndQueueFull(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxQueue = 1
+	s := NewFairScheduler(config) // 未启动，请求一直排队
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- s.Acquire(ctx, "p", 10) }()
+
+	time.Sleep(5 * time.Millisecond)
+	if err := s.Acquire(context.Background(), "p", 10); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	stats := s.Stats()
+	if stats.Peers[0].Dropped != 1 {
+		t.Errorf("expected 1 dropped request, got %+v", stats.Peers[0])
+	}
+
+	s.Stop()
+	if err := s.Acquire(context.Background(), "p", 10); !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("expected ErrSchedulerStopped, got %v", err)
+	}
+}
+
+// bufferStream 记录写入内容的流
+type bufferStream struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (b *bufferStream) Write(p []byte) (int, error) {
+	b.writes = append(b.writes, len(p))
+	return b.Buffer.Write(p)
+}
+
+func (b *bufferStream) Close() error { return nil }
+
+func TestGateStream(t *testing.T) {
+	config := DefaultConfig()
+	config.Quantum = 1024
+	s := NewFairScheduler(config)
+	s.Start()
+
+	stream := &bufferStream{}
+	gated := s.GateStream(context.Background(), "p", stream)
+	data := bytes.Repeat([]byte("x"), 2500)
+	if n, err := gated.Write(data); err != nil || n != len(data) {
+		t.Fatalf("write: n=%d err=%v", n, err)
+	}
+	// 超过 Quantum 的写入分段申请和写入
+	if len(stream.writes) != 3 || stream.writes[0] != 1024 || stream.writes[2] != 452 {
+		t.Errorf("expected writes split by quantum, got %v", stream.writes)
+	}
+	if !bytes.Equal(stream.Bytes(), data) {
+		t.Error("written data mismatch")
+	}
+	if stats := s.Stats(); len(stats.Peers) != 1 || stats.Peers[0].PeerID != "p" {
+		t.Errorf("expected stream writes accounted to p, got %+v", stats.Peers)
+	}
+
+	s.Stop()
+	if _, err := gated.Write([]byte("y")); !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("expected ErrSchedulerStopped, got %v", err)
+	}
+}
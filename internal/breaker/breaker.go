@@ -0,0 +1,523 @@
+// Package breaker 实现全网熔断指令
+// 某类消息出现垃圾风暴或被利用漏洞时，超级节点发布带签名、限时的熔断指令，
+// 各节点验签后对该类消息限速或暂停；本地运维可以覆盖网络指令，指令到期自动失效。
+package breaker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// 消息类别
+const (
+	ClassMail       = "mail"
+	ClassBulletin   = "bulletin"
+	ClassAccusation = "accusation"
+	ClassVote       = "vote"
+	ClassTask       = "task"
+)
+
+// Classes 可以熔断的消息类别
+var Classes = []string{ClassMail, ClassBulletin, ClassAccusation, ClassVote, ClassTask}
+
+// BulletinTopic 熔断指令在留言板上使用的话题
+const BulletinTopic = "circuit-breaker"
+
+const directiveLabel = "daan-breaker-v1"
+
+// Action 熔断动作
+type Action string
+
+const (
+	ActionPause     Action = "pause"      // 暂停该类消息
+	ActionRateLimit Action = "rate_limit" // 限速：每分钟最多 Rate 条
+	ActionLift      Action = "lift"       // 提前解除熔断
+	ActionIgnore    Action = "ignore"     // 仅本地覆盖：不执行网络指令
+)
+
+var (
+	ErrNilConfig        = errors.New("config cannot be nil")
+	ErrEmptyNodeID      = errors.New("node ID cannot be empty")
+	ErrUnknownClass     = errors.New("unknown message class")
+	ErrInvalidDirective = errors.New("invalid circuit breaker directive")
+	ErrNotSupernode     = errors.New("issuer is not a supernode")
+	ErrBadSignature     = errors.New("circuit breaker directive signature invalid")
+	ErrExpired          = errors.New("circuit breaker directive expired")
+	ErrStale            = errors.New("circuit breaker directive superseded")
+	ErrNoOverride       = errors.New("no local override for class")
+	ErrPaused           = errors.New("message class paused by circuit breaker")
+	ErrRateLimited      = errors.New("message class rate limited by circuit breaker")
+)
+
+// Directive 熔断指令
+type Directive struct {
+	ID        string    `json:"id"`
+	Class     string    `json:"class"`
+	Action    Action    `json:"action"`
+	Rate      int       `json:"rate,omitempty"` // rate_limit 时每分钟允许的条数
+	Reason    string    `json:"reason"`
+	Issuer    string    `json:"issuer"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Signature []byte    `json:"signature"`
+}
+
+// SignData 签名载荷
+func (d *Directive) SignData() []byte {
+	var b bytes.Buffer
+	for _, field := range []string{
+		directiveLabel, d.ID, d.Class, string(d.Action), strconv.Itoa(d.Rate), d.Reason, d.Issuer,
+		strconv.FormatInt(d.IssuedAt.UnixNano(), 10), strconv.FormatInt(d.ExpiresAt.UnixNano(), 10),
+	} {
+		b.WriteString(field)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// Active 指令在指定时间点是否生效
+func (d *Directive) Active(now time.Time) bool {
+	return d != nil && d.Action != ActionLift && now.Before(d.ExpiresAt)
+}
+
+// Override 本地运维覆盖，优先于网络指令
+type Override struct {
+	Class  string    `json:"class"`
+	Action Action    `json:"action"` // pause / rate_limit / ignore
+	Rate   int       `json:"rate,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until,omitempty"` // 为零表示需手动解除
+}
+
+// Active 覆盖在指定时间点是否生效
+func (o *Override) Active(now time.Time) bool {
+	return o != nil && (o.Until.IsZero() || now.Before(o.Until))
+}
+
+// ClassStatus 消息类别的熔断状态
+type ClassStatus struct {
+	Class     string     `json:"class"`
+	Action    Action     `json:"action,omitempty"` // 当前执行的动作，为空表示不限制
+	Rate      int        `json:"rate,omitempty"`
+	Source    string     `json:"source,omitempty"` // network / local
+	Until     time.Time  `json:"until,omitempty"`
+	Directive *Directive `json:"directive,omitempty"` // 最近一条网络指令（含已解除和已覆盖的）
+	Override  *Override  `json:"override,omitempty"`
+	Rejected  uint64     `json:"rejected"` // 熔断拒绝的消息数
+}
+
+// Config 熔断配置
+type Config struct {
+	NodeID       string
+	DataDir      string
+	MaxDuration  time.Duration // 网络指令的最长有效期
+	MaxClockSkew time.Duration // 允许的签发时间偏差
+
+	SignFunc   func(data []byte) ([]byte, error)
+	VerifyFunc func(signerID string, data, signature []byte) (bool, error)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:       nodeID,
+		DataDir:      "./data/breaker",
+		MaxDuration:  24 * time.Hour,
+		MaxClockSkew: 5 * time.Minute,
+	}
+}
+
+// rateWindow 限速计数窗口（一分钟）
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// Manager 熔断管理器
+type Manager struct {
+	mu         sync.Mutex
+	config     *Config
+	directives map[string]*Directive // 类别 -> 最近一条网络指令
+	overrides  map[string]*Override  // 类别 -> 本地覆盖
+	windows    map[string]*rateWindow
+	rejected   map[string]uint64
+
+	isSupernode func(nodeID string) bool // 未设置时不接受任何网络指令
+
+	// OnDirective 接受新的网络指令后调用
+	OnDirective func(d *Directive)
+}
+
+// NewManager 创建熔断管理器
+func NewManager(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+
+	m := &Manager{
+		config:     config,
+		directives: make(map[string]*Directive),
+		overrides:  make(map[string]*Override),
+		windows:    make(map[string]*rateWindow),
+		rejected:   make(map[string]uint64),
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		m.load()
+	}
+	return m, nil
+}
+
+// SetSupernodeFunc 设置超级节点判断函数，只接受超级节点签发的指令
+func (m *Manager) SetSupernodeFunc(fn func(nodeID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.isSupernode = fn
+}
+
+// supernode 判断节点是否为超级节点
+func (m *Manager) supernode(nodeID string) bool {
+	m.mu.Lock()
+	fn := m.isSupernode
+	m.mu.Unlock()
+	return fn != nil && fn(nodeID)
+}
+
+func validClass(class string) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Issue 以本节点（须为超级节点）身份签发熔断指令并在本地生效，调用方负责广播
+func (m *Manager) Issue(class string, action Action, rate int, reason string, duration time.Duration) (*Directive, error) {
+	if m.config.SignFunc == nil {
+		return nil, errors.New("sign function not configured")
+	}
+	if !m.supernode(m.config.NodeID) {
+		return nil, ErrNotSupernode
+	}
+	if duration <= 0 || duration > m.config.MaxDuration {
+		duration = m.config.MaxDuration
+	}
+
+	now := time.Now()
+	d := &Directive{
+		Class:     class,
+		Action:    action,
+		Rate:      rate,
+		Reason:    reason,
+		Issuer:    m.config.NodeID,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(duration),
+	}
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", d.Issuer, d.Class, now.UnixNano())))
+	d.ID = "cb_" + hex.EncodeToString(digest[:8])
+	if err := m.check(d); err != nil {
+		return nil, err
+	}
+	signature, err := m.config.SignFunc(d.SignData())
+	if err != nil {
+		return nil, err
+	}
+	d.Signature = signature
+	if err := m.Apply(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// check 校验指令字段
+func (m *Manager) check(d *Directive) error {
+	if !validClass(d.Class) {
+		return fmt.Errorf("%w: %q", ErrUnknownClass, d.Class)
+	}
+	switch d.Action {
+	case ActionPause, ActionLift:
+	case ActionRateLimit:
+		if d.Rate <= 0 {
+			return fmt.Errorf("%w: rate_limit requires a positive rate", ErrInvalidDirective)
+		}
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidDirective, d.Action)
+	}
+	if d.ID == "" || d.Issuer == "" || !d.ExpiresAt.After(d.IssuedAt) {
+		return ErrInvalidDirective
+	}
+	if d.ExpiresAt.Sub(d.IssuedAt) > m.config.MaxDuration {
+		return fmt.Errorf("%w: lasts longer than %s", ErrInvalidDirective, m.config.MaxDuration)
+	}
+	return nil
+}
+
+// Apply 验证并接受网络指令：签发者须为超级节点、签名有效、未过期，且比该类别已有的指令新
+func (m *Manager) Apply(d *Directive) error {
+	if d == nil {
+		return ErrInvalidDirective
+	}
+	if err := m.check(d); err != nil {
+		return err
+	}
+	now := time.Now()
+	if d.IssuedAt.After(now.Add(m.config.MaxClockSkew)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidDirective)
+	}
+	if !now.Before(d.ExpiresAt) {
+		return ErrExpired
+	}
+	if !m.supernode(d.Issuer) {
+		return fmt.Errorf("%w: %s", ErrNotSupernode, d.Issuer)
+	}
+	if m.config.VerifyFunc == nil {
+		return errors.New("verify function not configured")
+	}
+	if ok, err := m.config.VerifyFunc(d.Issuer, d.SignData(), d.Signature); err != nil || !ok {
+		return ErrBadSignature
+	}
+
+	m.mu.Lock()
+	if current, ok := m.directives[d.Class]; ok {
+		if current.ID == d.ID {
+			m.mu.Unlock()
+			return nil
+		}
+		if !d.IssuedAt.After(current.IssuedAt) {
+			m.mu.Unlock()
+			return ErrStale
+		}
+	}
+	copied := *d
+	m.directives[d.Class] = &copied
+	delete(m.windows, d.Class)
+	m.save()
+	m.mu.Unlock()
+
+	if m.OnDirective != nil {
+		m.OnDirective(&copied)
+	}
+	return nil
+}
+
+// SetOverride 设置本地覆盖，duration 为 0 表示需手动解除
+func (m *Manager) SetOverride(class string, action Action, rate int, reason string, duration time.Duration) (*Override, error) {
+	if !validClass(class) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownClass, class)
+	}
+	switch action {
+	case ActionPause, ActionIgnore:
+	case ActionRateLimit:
+		if rate <= 0 {
+			return nil, fmt.Errorf("%w: rate_limit requires a positive rate", ErrInvalidDirective)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown override action %q", ErrInvalidDirective, action)
+	}
+
+	now := time.Now()
+	o := &Override{Class: class, Action: action, Rate: rate, Reason: reason, Since: now}
+	if duration > 0 {
+		o.Until = now.Add(duration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides[class] = o
+	delete(m.windows, class)
+	m.save()
+	copied := *o
+	return &copied, nil
+}
+
+// ClearOverride 解除本地覆盖，恢复执行网络指令
+func (m *Manager) ClearOverride(class string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.overrides[class]; !ok {
+		return fmt.Errorf("%w: %s", ErrNoOverride, class)
+	}
+	delete(m.overrides, class)
+	delete(m.windows, class)
+	m.save()
+	return nil
+}
+
+// effectiveLocked 类别当前执行的动作：有效的本地覆盖优先，其次有效的网络指令
+func (m *Manager) effectiveLocked(class string, now time.Time) (action Action, rate int, source string, until time.Time) {
+	if o := m.overrides[class]; o.Active(now) {
+		if o.Action == ActionIgnore {
+			return "", 0, "local", o.Until
+		}
+		return o.Action, o.Rate, "local", o.Until
+	}
+	if d := m.directives[class]; d.Active(now) {
+		return d.Action, d.Rate, "network", d.ExpiresAt
+	}
+	return "", 0, "", time.Time{}
+}
+
+// Allow 检查一条该类别的消息是否放行，放行时计入限速窗口
+func (m *Manager) Allow(class string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	action, rate, source, until := m.effectiveLocked(class, now)
+	switch action {
+	case ActionPause:
+		m.rejected[class]++
+		return fmt.Errorf("%w: %s paused by %s breaker until %s", ErrPaused, class, source, formatUntil(until))
+	case ActionRateLimit:
+		w := m.windows[class]
+		if w == nil || now.Sub(w.start) >= time.Minute {
+			w = &rateWindow{start: now}
+			m.windows[class] = w
+		}
+		if w.count >= rate {
+			m.rejected[class]++
+			return fmt.Errorf("%w: %s limited to %d per minute by %s breaker", ErrRateLimited, class, rate, source)
+		}
+		w.count++
+	}
+	return nil
+}
+
+func formatUntil(t time.Time) string {
+	if t.IsZero() {
+		return "cleared"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Status 各消息类别的熔断状态
+func (m *Manager) Status() []ClassStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.expireLocked(now)
+	result := make([]ClassStatus, 0, len(Classes))
+	for _, class := range Classes {
+		s := ClassStatus{Class: class, Rejected: m.rejected[class]}
+		s.Action, s.Rate, s.Source, s.Until = m.effectiveLocked(class, now)
+		if d, ok := m.directives[class]; ok {
+			copied := *d
+			s.Directive = &copied
+		}
+		if o, ok := m.overrides[class]; ok {
+			copied := *o
+			s.Override = &copied
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// expireLocked 清理过期的本地覆盖；网络指令保留到过期后一个最长有效期，防止旧指令重放
+func (m *Manager) expireLocked(now time.Time) {
+	changed := false
+	for class, o := range m.overrides {
+		if !o.Active(now) {
+			delete(m.overrides, class)
+			changed = true
+		}
+	}
+	for class, d := range m.directives {
+		if now.Sub(d.ExpiresAt) > m.config.MaxDuration {
+			delete(m.directives, class)
+			changed = true
+		}
+	}
+	if changed {
+		m.save()
+	}
+}
+
+// Stats 实现 stats.Provider
+func (m *Manager) Stats() []stats.Metric {
+	var paused, limited, overrides int
+	var rejected uint64
+	for _, s := range m.Status() {
+		switch s.Action {
+		case ActionPause:
+			paused++
+		case ActionRateLimit:
+			limited++
+		}
+		if s.Override != nil {
+			overrides++
+		}
+		rejected += s.Rejected
+	}
+	return []stats.Metric{
+		stats.Gauge("paused_classes", float64(paused), "暂停中的消息类别数"),
+		stats.Gauge("rate_limited_classes", float64(limited), "限速中的消息类别数"),
+		stats.Gauge("local_overrides", float64(overrides), "本地覆盖数"),
+		stats.Counter("rejected_messages", float64(rejected), "熔断拒绝的消息数"),
+	}
+}
+
+type breakerState struct {
+	Directives []*Directive `json:"directives"`
+	Overrides  []*Override  `json:"overrides"`
+}
+
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	var state breakerState
+	for _, class := range Classes {
+		if d, ok := m.directives[class]; ok {
+			state.Directives = append(state.Directives, d)
+		}
+		if o, ok := m.overrides[class]; ok {
+			state.Overrides = append(state.Overrides, o)
+		}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "breaker.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "breaker.json"))
+	if err != nil {
+		return
+	}
+	var state breakerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+	for _, d := range state.Directives {
+		if d != nil && validClass(d.Class) {
+			m.directives[d.Class] = d
+		}
+	}
+	for _, o := range state.Overrides {
+		if o != nil && validClass(o.Class) {
+			m.overrides[o.Class] = o
+		}
+	}
+}
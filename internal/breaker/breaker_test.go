@@ -0,0 +1,263 @@
+package breaker
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testNetwork 两个超级节点和一个普通节点，用节点ID查找公钥
+type testNetwork struct {
+	keys       map[string]ed25519.PrivateKey
+	supernodes map[string]bool
+}
+
+func newTestNetwork(t *testing.T) *testNetwork {
+	n := &testNetwork{
+		keys:       make(map[string]ed25519.PrivateKey),
+		supernodes: map[string]bool{"super-1": true, "super-2": true},
+	}
+	for _, id := range []string{"super-1", "super-2", "node-1"} {
+		_, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("生成密钥失败: %v", err)
+		}
+		n.keys[id] = priv
+	}
+	return n
+}
+
+func (n *testNetwork) manager(t *testing.T, nodeID, dataDir string) *Manager {
+	cfg := DefaultConfig(nodeID)
+	cfg.DataDir = dataDir
+	cfg.SignFunc = func(data []byte) ([]byte, error) {
+		return ed25519.Sign(n.keys[nodeID], data), nil
+	}
+	cfg.VerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+		priv, ok := n.keys[signerID]
+		if !ok {
+			return false, errors.New("unknown signer")
+		}
+		return ed25519.Verify(priv.Public().(ed25519.PublicKey), data, signature), nil
+	}
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("创建管理器失败: %v", err)
+	}
+	m.SetSupernodeFunc(func(id string) bool { return n.supernodes[id] })
+	return m
+}
+
+func TestNewManager(t *testing.T) {
+	if _, err := NewManager(nil); err != ErrNilConfig {
+		t.Errorf("期望 ErrNilConfig, 得到 %v", err)
+	}
+	if _, err := NewManager(&Config{}); err != ErrEmptyNodeID {
+		t.Errorf("期望 ErrEmptyNodeID, 得到 %v", err)
+	}
+}
+
+func TestIssueAndApply(t *testing.T) {
+	net := newTestNetwork(t)
+	super := net.manager(t, "super-1", t.TempDir())
+	node := net.manager(t, "node-1", t.TempDir())
+
+	var received *Directive
+	node.OnDirective = func(d *Directive) { received = d }
+
+	if _, err := node.Issue(ClassAccusation, ActionPause, 0, "spam", time.Hour); !errors.Is(err, ErrNotSupernode) {
+		t.Errorf("普通节点不能签发指令, 得到 %v", err)
+	}
+	if _, err := super.Issue("gossip", ActionPause, 0, "spam", time.Hour); !errors.Is(err, ErrUnknownClass) {
+		t.Errorf("期望 ErrUnknownClass, 得到 %v", err)
+	}
+	if _, err := super.Issue(ClassMail, ActionRateLimit, 0, "spam", time.Hour); !errors.Is(err, ErrInvalidDirective) {
+		t.Errorf("限速必须指定速率, 得到 %v", err)
+	}
+
+	d, err := super.Issue(ClassAccusation, ActionPause, 0, "指控风暴", time.Hour)
+	if err != nil {
+		t.Fatalf("签发指令失败: %v", err)
+	}
+	if err := super.Allow(ClassAccusation); !errors.Is(err, ErrPaused) {
+		t.Errorf("签发者本地应立即生效, 得到 %v", err)
+	}
+
+	if err := node.Apply(d); err != nil {
+		t.Fatalf("接受指令失败: %v", err)
+	}
+	if received == nil || received.ID != d.ID {
+		t.Error("应回调 OnDirective")
+	}
+	if err := node.Apply(d); err != nil {
+		t.Errorf("重复的指令应忽略: %v", err)
+	}
+	if err := node.Allow(ClassAccusation); !errors.Is(err, ErrPaused) {
+		t.Errorf("指控应被暂停, 得到 %v", err)
+	}
+	if err := node.Allow(ClassMail); err != nil {
+		t.Errorf("其他类别不受影响: %v", err)
+	}
+
+	// 篡改内容
+	forged := *d
+	forged.Class = ClassMail
+	if err := node.Apply(&forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("期望 ErrBadSignature, 得到 %v", err)
+	}
+
+	// 普通节点自签的指令
+	fake := *d
+	fake.ID = "cb_fake"
+	fake.Issuer = "node-1"
+	fake.Signature = ed25519.Sign(net.keys["node-1"], fake.SignData())
+	if err := super.Apply(&fake); !errors.Is(err, ErrNotSupernode) {
+		t.Errorf("期望 ErrNotSupernode, 得到 %v", err)
+	}
+
+	// 超过最长有效期
+	long := *d
+	long.ID = "cb_long"
+	long.ExpiresAt = long.IssuedAt.Add(48 * time.Hour)
+	long.Signature = ed25519.Sign(net.keys["super-1"], long.SignData())
+	if err := node.Apply(&long); !errors.Is(err, ErrInvalidDirective) {
+		t.Errorf("期望 ErrInvalidDirective, 得到 %v", err)
+	}
+}
+
+func TestExpiryAndLift(t *testing.T) {
+	net := newTestNetwork(t)
+	super := net.manager(t, "super-2", t.TempDir())
+	node := net.manager(t, "node-1", t.TempDir())
+
+	sign := func(d *Directive) *Directive {
+		d.Signature = ed25519.Sign(net.keys[d.Issuer], d.SignData())
+		return d
+	}
+	now := time.Now().Truncate(time.Second)
+
+	expired := sign(&Directive{
+		ID: "cb_old", Class: ClassVote, Action: ActionPause, Issuer: "super-2",
+		IssuedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour),
+	})
+	if err := node.Apply(expired); !errors.Is(err, ErrExpired) {
+		t.Errorf("期望 ErrExpired, 得到 %v", err)
+	}
+
+	pause := sign(&Directive{
+		ID: "cb_pause", Class: ClassVote, Action: ActionPause, Issuer: "super-2",
+		IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour),
+	})
+	if err := node.Apply(pause); err != nil {
+		t.Fatalf("接受指令失败: %v", err)
+	}
+
+	lift, err := super.Issue(ClassVote, ActionLift, 0, "已修复", time.Hour)
+	if err != nil {
+		t.Fatalf("签发解除指令失败: %v", err)
+	}
+	if err := node.Apply(lift); err != nil {
+		t.Fatalf("接受解除指令失败: %v", err)
+	}
+	if err := node.Allow(ClassVote); err != nil {
+		t.Errorf("解除后应放行: %v", err)
+	}
+	// 解除之后旧指令重放无效
+	replay := *pause
+	replay.ID = "cb_replay"
+	sign(&replay)
+	if err := node.Apply(&replay); !errors.Is(err, ErrStale) {
+		t.Errorf("期望 ErrStale, 得到 %v", err)
+	}
+
+	// 即将到期的指令到期后自动失效
+	short := sign(&Directive{
+		ID: "cb_short", Class: ClassTask, Action: ActionPause, Issuer: "super-2",
+		IssuedAt: now.Add(-time.Minute), ExpiresAt: time.Now().Add(50 * time.Millisecond),
+	})
+	if err := node.Apply(short); err != nil {
+		t.Fatalf("接受指令失败: %v", err)
+	}
+	if err := node.Allow(ClassTask); !errors.Is(err, ErrPaused) {
+		t.Errorf("任务应被暂停, 得到 %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := node.Allow(ClassTask); err != nil {
+		t.Errorf("指令到期后应放行: %v", err)
+	}
+}
+
+func TestRateLimitAndOverride(t *testing.T) {
+	net := newTestNetwork(t)
+	dir := t.TempDir()
+	super := net.manager(t, "super-1", t.TempDir())
+	node := net.manager(t, "node-1", dir)
+
+	d, err := super.Issue(ClassMail, ActionRateLimit, 2, "邮件风暴", time.Hour)
+	if err != nil {
+		t.Fatalf("签发指令失败: %v", err)
+	}
+	if err := node.Apply(d); err != nil {
+		t.Fatalf("接受指令失败: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := node.Allow(ClassMail); err != nil {
+			t.Fatalf("第 %d 条应放行: %v", i+1, err)
+		}
+	}
+	if err := node.Allow(ClassMail); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("超过速率应拒绝, 得到 %v", err)
+	}
+
+	// 本地覆盖：运维判断是误报，忽略网络指令
+	if _, err := node.SetOverride(ClassMail, ActionIgnore, 0, "误报", 0); err != nil {
+		t.Fatalf("设置覆盖失败: %v", err)
+	}
+	if err := node.Allow(ClassMail); err != nil {
+		t.Errorf("忽略网络指令后应放行: %v", err)
+	}
+	if _, err := node.SetOverride(ClassBulletin, ActionPause, 0, "本地暂停", time.Hour); err != nil {
+		t.Fatalf("设置覆盖失败: %v", err)
+	}
+	if err := node.Allow(ClassBulletin); !errors.Is(err, ErrPaused) {
+		t.Errorf("本地暂停应生效, 得到 %v", err)
+	}
+
+	var mail ClassStatus
+	for _, s := range node.Status() {
+		if s.Class == ClassMail {
+			mail = s
+		}
+	}
+	if mail.Source != "local" || mail.Action != "" || mail.Directive == nil || mail.Rejected != 1 {
+		t.Errorf("邮件熔断状态错误: %+v", mail)
+	}
+
+	// 重启后恢复状态
+	reloaded := net.manager(t, "node-1", dir)
+	if err := reloaded.Allow(ClassBulletin); !errors.Is(err, ErrPaused) {
+		t.Errorf("重启后本地覆盖应保留, 得到 %v", err)
+	}
+
+	if err := node.ClearOverride(ClassMail); err != nil {
+		t.Fatalf("解除覆盖失败: %v", err)
+	}
+	if err := node.ClearOverride(ClassMail); !errors.Is(err, ErrNoOverride) {
+		t.Errorf("期望 ErrNoOverride, 得到 %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		node.Allow(ClassMail)
+	}
+	if err := node.Allow(ClassMail); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("解除覆盖后恢复执行网络指令, 得到 %v", err)
+	}
+
+	metrics := map[string]float64{}
+	for _, metric := range node.Stats() {
+		metrics[metric.Name] = metric.Value
+	}
+	if metrics["paused_classes"] != 1 || metrics["rate_limited_classes"] != 1 {
+		t.Errorf("熔断统计错误: %v", metrics)
+	}
+}
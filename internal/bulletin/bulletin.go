@@ -13,6 +13,12 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/clockskew"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 // 错误定义
@@ -29,6 +35,7 @@ var (
 	ErrDuplicateMessage  = errors.New("duplicate message")
 	ErrMessageTooLarge   = errors.New("message content too large")
 	ErrInvalidMessageID  = errors.New("invalid message ID")
+	ErrStorageLocked     = errors.New("bulletin storage is encrypted and cannot be unlocked")
 )
 
 // MessageStatus 消息状态
@@ -39,6 +46,7 @@ const (
 	StatusExpired  MessageStatus = "expired"  // 已过期
 	StatusRevoked  MessageStatus = "revoked"  // 已撤回
 	StatusPinned   MessageStatus = "pinned"   // 置顶
+	StatusHidden   MessageStatus = "hidden"   // 被版主隐藏
 )
 
 // Message 留言消息
@@ -56,6 +64,11 @@ type Message struct {
 	Tags            []string      `json:"tags"`             // 标签
 	ReplyTo         string        `json:"reply_to"`         // 回复的消息ID（可选）
 	Attachments     []string      `json:"attachments"`      // 附件（哈希引用）
+	Previews        []*LinkPreview `json:"previews,omitempty"` // 发布时提取的链接和附件预览
+	
+	// 定向加密（Encrypted 为 true 时 Content 为密文）
+	Encrypted   bool              `json:"encrypted,omitempty"`
+	WrappedKeys map[string]string `json:"wrapped_keys,omitempty"` // 接收方节点ID -> 包裹后的内容密钥
 }
 
 // MessageSummary 消息摘要（用于列表展示）
@@ -67,13 +80,16 @@ type MessageSummary struct {
 	Timestamp       time.Time     `json:"timestamp"`
 	ReputationScore float64       `json:"reputation_score"`
 	Status          MessageStatus `json:"status"`
+	Encrypted       bool          `json:"encrypted,omitempty"`
+	Previews        []*LinkPreview `json:"previews,omitempty"`
 }
 
 // Subscription 订阅信息
 type Subscription struct {
-	Topic       string    `json:"topic"`
-	SubscribedAt time.Time `json:"subscribed_at"`
-	MessageCount int64     `json:"message_count"` // 收到的消息数
+	Topic        string              `json:"topic"`
+	SubscribedAt time.Time           `json:"subscribed_at"`
+	MessageCount int64               `json:"message_count"`    // 收到的消息数（只计过滤后投递的）
+	Filter       *SubscriptionFilter `json:"filter,omitempty"` // 订阅过滤器，nil 表示不过滤
 }
 
 // BulletinConfig 留言板配置
@@ -87,6 +103,7 @@ type BulletinConfig struct {
 	PreviewLength    int           // 预览长度
 	CleanupInterval  time.Duration // 清理间隔
 	GossipEnabled    bool          // 是否启用Gossip广播
+	GossipSeenLimit  int           // 广播去重缓存上限（0 表示不限）
 	DHTEnabled       bool          // 是否启用DHT存储
 	
 	// 签名验证函数
@@ -95,6 +112,29 @@ type BulletinConfig struct {
 	
 	// 声誉查询函数
 	GetReputationFunc func(nodeID string) float64
+	
+	// 定向加密：用接收方公钥包裹内容密钥 / 用本节点私钥解包
+	WrapKeyFunc   func(recipient string, key []byte) ([]byte, error)
+	UnwrapKeyFunc func(wrapped []byte) ([]byte, error)
+	
+	// 版主治理：累计警告达到阈值时自动发起治理提案（0 表示不自动发起）
+	AuthorStrikeThreshold    int           // 作者被隐藏留言次数阈值
+	ModeratorStrikeThreshold int           // 版主隐藏被推翻次数阈值
+	AppealWindow             time.Duration // 隐藏后允许申诉的时间窗口（0 表示不限）
+	
+	// 静态加密：设置后本地数据加密落盘，未加密的旧数据照常加载
+	StorageKeyring *crypto.StorageKeyring
+	
+	// 接收其他节点的留言时，过期判断容忍的时钟偏差；本地清理也推迟同样的时长
+	ExpiryGrace time.Duration
+	
+	// 链接预览：发布时为正文链接和附件提取元数据（未设置 PreviewFunc 时不提取）
+	PreviewFunc    PreviewFunc
+	MaxPreviews    int           // 每条留言最多提取的预览数
+	PreviewTimeout time.Duration // 一条留言提取预览的总超时
+	
+	// 存储后端，非空时替代 bulletin.json（加密由后端负责），首次加载时导入旧文件
+	Store storage.Backend
 }
 
 // DefaultBulletinConfig 返回默认配置
@@ -109,7 +149,14 @@ func DefaultBulletinConfig(nodeID string) *BulletinConfig {
 		PreviewLength:      100,
 		CleanupInterval:    10 * time.Minute,
 		GossipEnabled:      true,
+		GossipSeenLimit:    50000,
 		DHTEnabled:         true,
+		AuthorStrikeThreshold:    3,
+		ModeratorStrikeThreshold: 3,
+		AppealWindow:             7 * 24 * time.Hour,
+		ExpiryGrace:              clockskew.DefaultConfig().Grace,
+		MaxPreviews:              5,
+		PreviewTimeout:           10 * time.Second,
 	}
 }
 
@@ -123,6 +170,17 @@ type BulletinBoard struct {
 	subscriptions map[string]*Subscription     // Topic -> Subscription
 	subscribers  map[string][]func(*Message)  // Topic -> callbacks
 	pinnedMessages []string                    // 置顶消息ID列表
+	moderations  map[string]*Moderation        // MessageID -> 隐藏记录
+	strikes      map[string]*StrikeRecord      // role:nodeID -> 警告计数
+	proposalFunc StrikeProposalFunc
+	admit        func(msg *Message) error // 外部消息准入检查（如熔断）
+	locked       bool // 磁盘上的加密数据无法解密，禁止保存以免覆盖
+	dirty        *storage.Tracker // 使用存储后端时，上次保存后变化的记录
+	skew         *clockskew.Tracker
+	gossip       GossipTransport
+	compat       *compat.Table // 广播主题的版本回退表，nil 表示只用 v2 主题
+	seen         map[string]time.Time // 广播去重：MessageID -> 过期时间
+	gossipStats  GossipStats
 	running      bool
 	stopCh       chan struct{}
 	
@@ -158,8 +216,15 @@ func NewBulletinBoard(config *BulletinConfig) (*BulletinBoard, error) {
 		subscriptions: make(map[string]*Subscription),
 		subscribers:   make(map[string][]func(*Message)),
 		pinnedMessages: make([]string, 0),
+		moderations:   make(map[string]*Moderation),
+		strikes:       make(map[string]*StrikeRecord),
+		dirty:         storage.NewTracker(),
+		seen:          make(map[string]time.Time),
 		stopCh:        make(chan struct{}),
 	}
+	skewConfig := clockskew.DefaultConfig()
+	skewConfig.Grace = config.ExpiryGrace
+	bb.skew = clockskew.NewTracker(skewConfig)
 	
 	// 加载持久化数据
 	if err := bb.load(); err != nil {
@@ -222,7 +287,7 @@ func (bb *BulletinBoard) cleanup() {
 	var expiredIDs []string
 	
 	for id, msg := range bb.messages {
-		if msg.Status != StatusPinned && now.After(msg.ExpiresAt) {
+		if msg.Status != StatusPinned && !bb.skew.Retained(msg.ExpiresAt, now) {
 			msg.Status = StatusExpired
 			expiredIDs = append(expiredIDs, id)
 		}
@@ -232,6 +297,7 @@ func (bb *BulletinBoard) cleanup() {
 	for _, id := range expiredIDs {
 		bb.removeMessageLocked(id)
 	}
+	bb.pruneSeenLocked(now)
 	
 	// 限制每个话题的消息数量
 	for topic, messageIDs := range bb.topicIndex {
@@ -271,6 +337,7 @@ func (bb *BulletinBoard) removeMessageLocked(messageID string) {
 	
 	// 从消息表删除
 	delete(bb.messages, messageID)
+	bb.dirty.Mark("messages", messageID)
 	
 	// 从话题索引删除
 	if ids, ok := bb.topicIndex[msg.Topic]; ok {
@@ -321,6 +388,12 @@ func (bb *BulletinBoard) PublishMessageWithOptions(content, topic string, tags [
 		return nil, ErrMessageTooLarge
 	}
 	
+	return bb.publish(content, topic, tags, attachments, replyTo, nil)
+}
+
+// publish 生成、签名并存储本节点发布的留言
+// sealed 非空时 content 已是密文
+func (bb *BulletinBoard) publish(content, topic string, tags []string, attachments []string, replyTo string, sealed map[string]string) (*Message, error) {
 	now := time.Now()
 	
 	// 生成消息ID
@@ -334,6 +407,12 @@ func (bb *BulletinBoard) PublishMessageWithOptions(content, topic string, tags [
 		reputationScore = bb.config.GetReputationFunc(bb.config.NodeID)
 	}
 	
+	// 加密留言不提取预览，以免泄露引用的内容
+	var previews []*LinkPreview
+	if sealed == nil {
+		previews = bb.extractPreviews(content, attachments)
+	}
+	
 	msg := &Message{
 		MessageID:       messageID,
 		Author:          bb.config.NodeID,
@@ -347,6 +426,9 @@ func (bb *BulletinBoard) PublishMessageWithOptions(content, topic string, tags [
 		Tags:            tags,
 		ReplyTo:         replyTo,
 		Attachments:     attachments,
+		Previews:        previews,
+		Encrypted:       sealed != nil,
+		WrappedKeys:     sealed,
 	}
 	
 	// 签名消息
@@ -363,6 +445,7 @@ func (bb *BulletinBoard) PublishMessageWithOptions(content, topic string, tags [
 	
 	// 存储消息
 	bb.messages[messageID] = msg
+	bb.dirty.Mark("messages", messageID)
 	
 	// 更新话题索引
 	bb.topicIndex[topic] = append(bb.topicIndex[topic], messageID)
@@ -383,6 +466,9 @@ func (bb *BulletinBoard) PublishMessageWithOptions(content, topic string, tags [
 	// 通知订阅者
 	bb.notifySubscribers(topic, msg)
 	
+	// 广播到全网
+	bb.broadcast(msg)
+	
 	return msg, nil
 }
 
@@ -394,9 +480,23 @@ func (bb *BulletinBoard) getSignData(msg *Message) []byte {
 		msg.Topic,
 		msg.Content,
 		msg.Timestamp.UnixNano())
+	if msg.Encrypted {
+		// 接收方列表也纳入签名，防止转发节点增删包裹密钥
+		data += "|" + wrappedKeysDigest(msg.WrappedKeys)
+	}
+	if len(msg.Previews) > 0 {
+		data += "|" + previewsDigest(msg.Previews)
+	}
 	return []byte(data)
 }
 
+// SetAdmitFunc 设置外部消息准入检查，返回错误的消息不会存储和转发
+func (bb *BulletinBoard) SetAdmitFunc(fn func(msg *Message) error) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	bb.admit = fn
+}
+
 // ReceiveMessage 接收外部消息（来自 Gossip 或 DHT）
 func (bb *BulletinBoard) ReceiveMessage(msg *Message, fromNode string) error {
 	if msg == nil {
@@ -406,8 +506,10 @@ func (bb *BulletinBoard) ReceiveMessage(msg *Message, fromNode string) error {
 		return ErrInvalidMessageID
 	}
 	
-	// 检查是否过期
-	if time.Now().After(msg.ExpiresAt) {
+	// 检查是否过期（容忍作者与本节点的时钟偏差）
+	now := time.Now()
+	bb.skew.ObserveTimestamp(msg.Timestamp, now)
+	if bb.skew.Expired(msg.ExpiresAt, now) {
 		return ErrMessageExpired
 	}
 	
@@ -426,26 +528,37 @@ func (bb *BulletinBoard) ReceiveMessage(msg *Message, fromNode string) error {
 		bb.mu.Unlock()
 		return ErrDuplicateMessage
 	}
+	if bb.admit != nil {
+		if err := bb.admit(msg); err != nil {
+			bb.mu.Unlock()
+			return err
+		}
+	}
 	
 	// 减少TTL
 	msg.TTL--
 	
 	// 存储消息
 	bb.messages[msg.MessageID] = msg
+	bb.dirty.Mark("messages", msg.MessageID)
 	
 	// 更新索引
 	bb.topicIndex[msg.Topic] = append(bb.topicIndex[msg.Topic], msg.MessageID)
 	bb.authorIndex[msg.Author] = append(bb.authorIndex[msg.Author], msg.MessageID)
 	
+	// 订阅过滤：不匹配的消息照常存储和转发，但不投递给订阅者
+	deliver := bb.matchesSubscriptionLocked(msg)
+	
 	// 更新订阅统计
-	if sub, ok := bb.subscriptions[msg.Topic]; ok {
+	if sub, ok := bb.subscriptions[msg.Topic]; ok && deliver {
 		sub.MessageCount++
+		bb.dirty.Mark("subscriptions", msg.Topic)
 	}
 	
 	bb.mu.Unlock()
 	
 	// 触发回调
-	if bb.OnMessageReceived != nil {
+	if bb.OnMessageReceived != nil && deliver {
 		bb.OnMessageReceived(msg)
 	}
 	if bb.OnGossipMessage != nil {
@@ -453,7 +566,9 @@ func (bb *BulletinBoard) ReceiveMessage(msg *Message, fromNode string) error {
 	}
 	
 	// 通知订阅者
-	bb.notifySubscribers(msg.Topic, msg)
+	if deliver {
+		bb.notifySubscribers(msg.Topic, msg)
+	}
 	
 	return nil
 }
@@ -580,7 +695,8 @@ func (bb *BulletinBoard) SearchMessages(keyword string, limit int) []*Message {
 			continue
 		}
 		// 简单关键词匹配
-		if containsIgnoreCase(msg.Content, keyword) || containsIgnoreCase(msg.Topic, keyword) {
+		// 密文内容不参与匹配
+		if (!msg.Encrypted && containsIgnoreCase(msg.Content, keyword)) || containsIgnoreCase(msg.Topic, keyword) {
 			results = append(results, msg)
 		}
 		if len(results) >= limit {
@@ -635,15 +751,16 @@ func (bb *BulletinBoard) SubscribeTopic(topic string, callback func(*Message)) e
 	}
 	
 	bb.mu.Lock()
-	defer bb.mu.Unlock()
 	
 	// 添加订阅
-	if _, exists := bb.subscriptions[topic]; !exists {
+	_, exists := bb.subscriptions[topic]
+	if !exists {
 		bb.subscriptions[topic] = &Subscription{
 			Topic:        topic,
 			SubscribedAt: time.Now(),
 			MessageCount: 0,
 		}
+		bb.dirty.Mark("subscriptions", topic)
 	}
 	
 	// 添加回调
@@ -655,6 +772,12 @@ func (bb *BulletinBoard) SubscribeTopic(topic string, callback func(*Message)) e
 	if bb.OnTopicSubscribed != nil {
 		go bb.OnTopicSubscribed(topic)
 	}
+	bb.mu.Unlock()
+	
+	// 新订阅的话题加入对应的广播主题
+	if !exists {
+		bb.joinGossip(topic)
+	}
 	
 	return nil
 }
@@ -666,14 +789,17 @@ func (bb *BulletinBoard) UnsubscribeTopic(topic string) error {
 	}
 	
 	bb.mu.Lock()
-	defer bb.mu.Unlock()
-	
 	if _, exists := bb.subscriptions[topic]; !exists {
+		bb.mu.Unlock()
 		return ErrNotSubscribed
 	}
 	
 	delete(bb.subscriptions, topic)
 	delete(bb.subscribers, topic)
+	bb.dirty.Mark("subscriptions", topic)
+	bb.mu.Unlock()
+	
+	bb.leaveGossip(topic)
 	
 	return nil
 }
@@ -706,6 +832,7 @@ func (bb *BulletinBoard) RevokeMessage(messageID string) error {
 	}
 	
 	msg.Status = StatusRevoked
+	bb.dirty.Mark("messages", messageID)
 	
 	// 触发回调
 	if bb.OnMessageRevoked != nil {
@@ -731,6 +858,7 @@ func (bb *BulletinBoard) PinMessage(messageID string) error {
 	
 	msg.Status = StatusPinned
 	bb.pinnedMessages = append(bb.pinnedMessages, messageID)
+	bb.dirty.Mark("messages", messageID)
 	
 	return nil
 }
@@ -750,6 +878,7 @@ func (bb *BulletinBoard) UnpinMessage(messageID string) error {
 	}
 	
 	msg.Status = StatusActive
+	bb.dirty.Mark("messages", messageID)
 	
 	// 从置顶列表移除
 	newPinned := make([]string, 0, len(bb.pinnedMessages)-1)
@@ -850,7 +979,9 @@ func (bb *BulletinBoard) GetMessageSummaries(topic string, limit, offset int) []
 	for i := offset; i < end; i++ {
 		msg := messages[i]
 		preview := msg.Content
-		if len(preview) > bb.config.PreviewLength {
+		if msg.Encrypted {
+			preview = encryptedPreview
+		} else if len(preview) > bb.config.PreviewLength {
 			preview = preview[:bb.config.PreviewLength] + "..."
 		}
 		summaries = append(summaries, &MessageSummary{
@@ -861,6 +992,8 @@ func (bb *BulletinBoard) GetMessageSummaries(topic string, limit, offset int) []
 			Timestamp:       msg.Timestamp,
 			ReputationScore: msg.ReputationScore,
 			Status:          msg.Status,
+			Encrypted:       msg.Encrypted,
+			Previews:        msg.Previews,
 		})
 	}
 	
@@ -872,9 +1005,11 @@ func (bb *BulletinBoard) GetMessagesForGossip(limit int) []*Message {
 	bb.mu.RLock()
 	defer bb.mu.RUnlock()
 	
+	// 按本地时钟已过期的不再转发（仅为容忍窗口而保留）
+	now := time.Now()
 	messages := make([]*Message, 0)
 	for _, msg := range bb.messages {
-		if msg.Status == StatusActive && msg.TTL > 0 {
+		if msg.Status == StatusActive && msg.TTL > 0 && !now.After(msg.ExpiresAt) {
 			messages = append(messages, msg)
 		}
 	}
@@ -923,6 +1058,30 @@ func (bb *BulletinBoard) GetStats() *BulletinStats {
 	}
 }
 
+// Stats 实现 stats.Provider
+func (bb *BulletinBoard) Stats() []stats.Metric {
+	s := bb.GetStats()
+	g := bb.GetGossipStats()
+	return []stats.Metric{
+		stats.Gauge("messages", float64(s.TotalMessages), "本地保存的留言数"),
+		stats.Gauge("active_messages", float64(s.ActiveMessages), "有效留言数"),
+		stats.Gauge("topics", float64(s.TotalTopics), "话题数"),
+		stats.Gauge("authors", float64(s.TotalAuthors), "作者数"),
+		stats.Gauge("subscriptions", float64(s.Subscriptions), "订阅数"),
+		stats.Gauge("pinned_messages", float64(s.PinnedMessages), "置顶留言数"),
+		stats.Counter("gossip_published", float64(g.Published), "广播的留言数"),
+		stats.Counter("gossip_received", float64(g.Received), "经广播收到并存储的留言数"),
+		stats.Counter("gossip_rejected", float64(g.Rejected), "校验失败的广播留言数"),
+		stats.Counter("gossip_duplicates", float64(g.Duplicates), "重复的广播留言数"),
+		stats.Gauge("gossip_seen", float64(g.Seen), "广播去重缓存条数"),
+	}
+}
+
+// SkewStats 返回接收留言时过期判断的时钟偏差统计
+func (bb *BulletinBoard) SkewStats() clockskew.Stats {
+	return bb.skew.Stats()
+}
+
 // VerifyMessage 验证消息签名
 func (bb *BulletinBoard) VerifyMessage(msg *Message) bool {
 	if bb.config.VerifyFunc == nil {
@@ -947,6 +1106,7 @@ func (bb *BulletinBoard) SetExpiry(messageID string, expiry time.Time) error {
 	}
 	
 	msg.ExpiresAt = expiry
+	bb.dirty.Mark("messages", messageID)
 	return nil
 }
 
@@ -955,33 +1115,96 @@ type persistState struct {
 	Messages      map[string]*Message     `json:"messages"`
 	Subscriptions map[string]*Subscription `json:"subscriptions"`
 	PinnedMessages []string                `json:"pinned_messages"`
+	Moderations   map[string]*Moderation   `json:"moderations,omitempty"`
+	Strikes       map[string]*StrikeRecord `json:"strikes,omitempty"`
 }
 
 // save 保存数据
 func (bb *BulletinBoard) save() error {
+	if bb.config.Store != nil {
+		bb.mu.RLock()
+		snap, err := storage.EncodeState(storePrefix, bb.stateLocked(), bb.dirty)
+		locked := bb.locked
+		bb.mu.RUnlock()
+		if locked {
+			return ErrStorageLocked
+		}
+		if err != nil {
+			return err
+		}
+		_, err = snap.Write(bb.config.Store)
+		return err
+	}
 	if bb.config.DataDir == "" {
 		return nil
 	}
 	
 	bb.mu.RLock()
-	state := &persistState{
-		Messages:       bb.messages,
-		Subscriptions:  bb.subscriptions,
-		PinnedMessages: bb.pinnedMessages,
-	}
+	data, err := json.MarshalIndent(bb.stateLocked(), "", "  ")
+	locked := bb.locked
 	bb.mu.RUnlock()
-	
-	data, err := json.MarshalIndent(state, "", "  ")
+	if locked {
+		return ErrStorageLocked
+	}
 	if err != nil {
 		return err
 	}
 	
+	if kr := bb.config.StorageKeyring; kr != nil {
+		if data, err = kr.Seal(data); err != nil {
+			return fmt.Errorf("failed to encrypt bulletin data: %w", err)
+		}
+	}
+	
 	filePath := filepath.Join(bb.config.DataDir, "bulletin.json")
 	return os.WriteFile(filePath, data, 0644)
 }
 
-// load 加载数据
+// stateLocked 当前持久化状态
+func (bb *BulletinBoard) stateLocked() *persistState {
+	return &persistState{
+		Messages:       bb.messages,
+		Subscriptions:  bb.subscriptions,
+		PinnedMessages: bb.pinnedMessages,
+		Moderations:    bb.moderations,
+		Strikes:        bb.strikes,
+	}
+}
+
+// storePrefix 留言板数据在存储后端中的 bucket 前缀
+const storePrefix = "bulletin"
+
+// load 加载数据：使用存储后端时，后端为空则导入旧的 bulletin.json
 func (bb *BulletinBoard) load() error {
+	store := bb.config.Store
+	if store == nil {
+		return bb.loadFile()
+	}
+	
+	var state persistState
+	found, err := storage.LoadState(store, storePrefix, &state)
+	if err != nil {
+		return err
+	}
+	if found {
+		bb.applyState(&state)
+		return nil
+	}
+	if bb.config.DataDir == "" {
+		return nil
+	}
+	if err := bb.loadFile(); err != nil {
+		return err
+	}
+	bb.dirty.MarkAll()
+	if err := bb.save(); err != nil {
+		return err
+	}
+	return storage.RetireLegacy(filepath.Join(bb.config.DataDir, "bulletin.json"))
+}
+
+// loadFile 从 bulletin.json 加载
+func (bb *BulletinBoard) loadFile() error {
 	if bb.config.DataDir == "" {
 		return nil
 	}
@@ -995,11 +1218,28 @@ func (bb *BulletinBoard) load() error {
 		return err
 	}
 	
+	if crypto.IsSealed(data) {
+		kr := bb.config.StorageKeyring
+		if kr != nil {
+			data, err = kr.Open(data)
+		}
+		if kr == nil || err != nil {
+			bb.locked = true
+			return ErrStorageLocked
+		}
+	}
+	
 	var state persistState
 	if err := json.Unmarshal(data, &state); err != nil {
 		return err
 	}
 	
+	bb.applyState(&state)
+	return nil
+}
+
+// applyState 用加载的状态替换内存数据并重建索引
+func (bb *BulletinBoard) applyState(state *persistState) {
 	bb.mu.Lock()
 	defer bb.mu.Unlock()
 	
@@ -1012,14 +1252,26 @@ func (bb *BulletinBoard) load() error {
 	if state.PinnedMessages != nil {
 		bb.pinnedMessages = state.PinnedMessages
 	}
+	if state.Moderations != nil {
+		bb.moderations = state.Moderations
+	}
+	if state.Strikes != nil {
+		bb.strikes = state.Strikes
+	}
 	
 	// 重建索引
 	for id, msg := range bb.messages {
 		bb.topicIndex[msg.Topic] = append(bb.topicIndex[msg.Topic], id)
 		bb.authorIndex[msg.Author] = append(bb.authorIndex[msg.Author], id)
 	}
-	
-	return nil
+}
+
+// Reseal 用密钥环的当前密钥重写磁盘数据（轮换密钥后调用）
+func (bb *BulletinBoard) Reseal() error {
+	if bb.config.Store != nil {
+		return bb.config.Store.Compact()
+	}
+	return bb.save()
 }
 
 // Clear 清空所有数据
@@ -1031,4 +1283,5 @@ func (bb *BulletinBoard) Clear() {
 	bb.topicIndex = make(map[string][]string)
 	bb.authorIndex = make(map[string][]string)
 	bb.pinnedMessages = make([]string, 0)
+	bb.dirty.MarkAll()
 }
@@ -1,11 +1,16 @@
 package bulletin
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 func createTestBoard(t *testing.T) *BulletinBoard {
@@ -311,6 +316,25 @@ func TestReceiveMessageErrors(t *testing.T) {
 			t.Errorf("expected ErrDuplicateMessage, got %v", err)
 		}
 	})
+	
+	t.Run("rejected by admit func", func(t *testing.T) {
+		paused := errors.New("paused")
+		bb.SetAdmitFunc(func(msg *Message) error {
+			if msg.Topic == "spam" {
+				return paused
+			}
+			return nil
+		})
+		defer bb.SetAdmitFunc(nil)
+		
+		msg := &Message{MessageID: "spam-msg", ExpiresAt: time.Now().Add(time.Hour), Topic: "spam"}
+		if err := bb.ReceiveMessage(msg, "node"); err != paused {
+			t.Errorf("expected admit error, got %v", err)
+		}
+		if _, err := bb.QueryMessage("spam-msg"); err == nil {
+			t.Error("rejected message should not be stored")
+		}
+	})
 }
 
 func TestReceiveMessageWithSignatureVerification(t *testing.T) {
@@ -676,6 +700,41 @@ func TestPersistence(t *testing.T) {
 	}
 }
 
+func TestStoreBackend(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := storage.OpenLogDB(filepath.Join(tmpDir, "node.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	config := &BulletinConfig{
+		NodeID:          "store-node",
+		DataDir:         tmpDir,
+		MaxContentSize:  65536,
+		DefaultTTL:      10,
+		DefaultExpiry:   24 * time.Hour,
+		CleanupInterval: time.Minute,
+		Store:           store,
+	}
+	
+	bb1, _ := NewBulletinBoard(config)
+	bb1.PublishMessage("Stored message", "store-topic")
+	bb1.SubscribeTopicWithFilter("store-topic", &SubscriptionFilter{Keywords: []string{"stored"}}, nil)
+	bb1.save()
+	if _, err := os.Stat(filepath.Join(tmpDir, "bulletin.json")); !os.IsNotExist(err) {
+		t.Error("bulletin.json should not be written when a store is configured")
+	}
+	
+	bb2, _ := NewBulletinBoard(config)
+	messages, _ := bb2.QueryByTopic("store-topic", 10, 0)
+	if len(messages) != 1 {
+		t.Errorf("messages count = %d, want 1", len(messages))
+	}
+	if f := bb2.GetSubscriptionFilter("store-topic"); f == nil || f.Keywords[0] != "stored" {
+		t.Errorf("subscription filter not restored: %+v", f)
+	}
+}
+
 func TestCallbacks(t *testing.T) {
 	bb := createTestBoard(t)
 	
@@ -974,3 +1033,99 @@ func TestPersistenceWithDataDir(t *testing.T) {
 		t.Error("expected bulletin.json to exist")
 	}
 }
+
+func TestEncryptedStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyring, err := crypto.OpenStorageKeyring("", []byte("node-secret"))
+	if err != nil {
+		t.Fatalf("OpenStorageKeyring failed: %v", err)
+	}
+	
+	config := &BulletinConfig{
+		NodeID:          "persist-test",
+		DataDir:         tmpDir,
+		MaxContentSize:  65536,
+		DefaultTTL:      10,
+		DefaultExpiry:   24 * time.Hour,
+		CleanupInterval: time.Minute,
+		StorageKeyring:  keyring,
+	}
+	
+	bb, _ := NewBulletinBoard(config)
+	msg, _ := bb.PublishMessage("meet at the usual place", "private-topic")
+	if err := bb.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	
+	filePath := filepath.Join(tmpDir, "bulletin.json")
+	raw, _ := os.ReadFile(filePath)
+	if !crypto.IsSealed(raw) || bytes.Contains(raw, []byte("usual place")) {
+		t.Fatal("expected bulletin.json to be encrypted")
+	}
+	
+	// 轮换密钥后重写并重新加载
+	if _, err := keyring.Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if err := bb.Reseal(); err != nil {
+		t.Fatalf("Reseal failed: %v", err)
+	}
+	reloaded, _ := NewBulletinBoard(config)
+	if got, err := reloaded.QueryMessage(msg.MessageID); err != nil || got.Content != msg.Content {
+		t.Fatalf("expected message after reload, got %v %v", got, err)
+	}
+	
+	// 没有密钥环时不加载，也不覆盖加密数据
+	plainConfig := *config
+	plainConfig.StorageKeyring = nil
+	locked, _ := NewBulletinBoard(&plainConfig)
+	if len(locked.messages) != 0 {
+		t.Error("expected no messages without keyring")
+	}
+	raw, _ = os.ReadFile(filePath)
+	if err := locked.save(); !errors.Is(err, ErrStorageLocked) {
+		t.Errorf("expected ErrStorageLocked, got %v", err)
+	}
+	if after, _ := os.ReadFile(filePath); !bytes.Equal(after, raw) {
+		t.Error("locked board should not overwrite encrypted data")
+	}
+}
+
+func TestExpiryGrace(t *testing.T) {
+	config := DefaultBulletinConfig("test-node")
+	config.DataDir = ""
+	config.ExpiryGrace = time.Minute
+	bb, _ := NewBulletinBoard(config)
+	now := time.Now()
+	
+	// 作者时钟稍慢，按本节点时钟刚过期，仍在容忍窗口内
+	late := &Message{
+		MessageID: "late-msg",
+		Topic:     "test",
+		Timestamp: now.Add(-time.Hour),
+		ExpiresAt: now.Add(-30 * time.Second),
+		Status:    StatusActive,
+		TTL:       5,
+	}
+	if err := bb.ReceiveMessage(late, "node"); err != nil {
+		t.Fatalf("message within grace should be accepted: %v", err)
+	}
+	stale := &Message{MessageID: "stale-msg", ExpiresAt: now.Add(-2 * time.Minute)}
+	if err := bb.ReceiveMessage(stale, "node"); err != ErrMessageExpired {
+		t.Errorf("expected ErrMessageExpired, got %v", err)
+	}
+	
+	// 窗口内保留以供查询，但不再转发
+	bb.cleanup()
+	if _, err := bb.QueryMessage("late-msg"); err != nil {
+		t.Error("message within grace should survive cleanup")
+	}
+	if got := bb.GetMessagesForGossip(10); len(got) != 0 {
+		t.Errorf("locally expired message should not be gossiped: %v", got)
+	}
+	
+	stats := bb.SkewStats()
+	if stats.WithinGrace != 1 || stats.Rejected != 1 || stats.Samples != 2 {
+		t.Errorf("unexpected skew stats: %+v", stats)
+	}
+}
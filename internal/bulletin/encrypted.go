@@ -0,0 +1,182 @@
+package bulletin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+)
+
+// 定向加密留言：内容用随机 AES-256-GCM 密钥加密，内容密钥分别用每个接收方的
+// 公钥包裹后随消息公开传播。留言本身（作者、话题、时间）对所有节点可见，
+// 只有接收方能解密内容。
+
+var (
+	ErrNoRecipients      = errors.New("at least one recipient is required")
+	ErrNotRecipient      = errors.New("not a recipient of this message")
+	ErrNotEncrypted      = errors.New("message is not encrypted")
+	ErrEncryptionUnavail = errors.New("encryption not configured")
+	ErrDecryptFailed     = errors.New("failed to decrypt message")
+)
+
+// encryptedPreview 加密留言在摘要列表中的预览文本
+const encryptedPreview = "[encrypted]"
+
+// PublishEncrypted 发布仅指定接收方可读的留言
+// 作者自身总是被加入接收方，以便查看自己发布的内容
+func (bb *BulletinBoard) PublishEncrypted(content, topic string, recipients []string, tags []string) (*Message, error) {
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+	if topic == "" {
+		return nil, ErrEmptyTopic
+	}
+	if len(content) > bb.config.MaxContentSize {
+		return nil, ErrMessageTooLarge
+	}
+	if len(recipients) == 0 {
+		return nil, ErrNoRecipients
+	}
+	if bb.config.WrapKeyFunc == nil {
+		return nil, ErrEncryptionUnavail
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	ciphertext, err := sealContent(key, []byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := make(map[string]string, len(recipients)+1)
+	all := append(append([]string{}, recipients...), bb.config.NodeID)
+	for _, r := range all {
+		if r == "" {
+			continue
+		}
+		if _, done := wrapped[r]; done {
+			continue
+		}
+		w, err := bb.config.WrapKeyFunc(r, key)
+		if err != nil {
+			return nil, err
+		}
+		wrapped[r] = base64.StdEncoding.EncodeToString(w)
+	}
+
+	return bb.publish(ciphertext, topic, tags, nil, "", wrapped)
+}
+
+// DecryptMessage 解密本节点作为接收方的加密留言
+func (bb *BulletinBoard) DecryptMessage(messageID string) (string, error) {
+	msg, err := bb.QueryMessage(messageID)
+	if err != nil {
+		return "", err
+	}
+	if !msg.Encrypted {
+		return "", ErrNotEncrypted
+	}
+	if bb.config.UnwrapKeyFunc == nil {
+		return "", ErrEncryptionUnavail
+	}
+
+	bb.mu.RLock()
+	wrappedB64, ok := msg.WrappedKeys[bb.config.NodeID]
+	content := msg.Content
+	bb.mu.RUnlock()
+	if !ok {
+		return "", ErrNotRecipient
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedB64)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+	key, err := bb.config.UnwrapKeyFunc(wrapped)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+	plaintext, err := openContent(key, content)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+	return string(plaintext), nil
+}
+
+// Recipients 返回加密留言的接收方列表
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.WrappedKeys))
+	for r := range m.WrappedKeys {
+		recipients = append(recipients, r)
+	}
+	sort.Strings(recipients)
+	return recipients
+}
+
+// IsRecipient 判断节点是否为加密留言的接收方
+func (m *Message) IsRecipient(nodeID string) bool {
+	_, ok := m.WrappedKeys[nodeID]
+	return ok
+}
+
+// wrappedKeysDigest 对接收方及其包裹密钥计算摘要（按节点ID排序）
+func wrappedKeysDigest(wrapped map[string]string) string {
+	ids := make([]string, 0, len(wrapped))
+	for id := range wrapped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		h.Write([]byte(wrapped[id]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sealContent AES-256-GCM 加密，返回 base64(nonce|密文)
+func sealContent(key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openContent 解密 sealContent 的输出
+func openContent(key []byte, content string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrDecryptFailed
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
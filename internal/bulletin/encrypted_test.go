@@ -0,0 +1,136 @@
+package bulletin
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+)
+
+// encNode 测试用节点：留言板 + Ed25519 密钥
+type encNode struct {
+	id   string
+	priv ed25519.PrivateKey
+	bb   *BulletinBoard
+}
+
+func newEncNodes(t *testing.T, ids ...string) map[string]*encNode {
+	t.Helper()
+
+	pubKeys := make(map[string]ed25519.PublicKey)
+	nodes := make(map[string]*encNode)
+	for _, id := range ids {
+		pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+		pubKeys[id] = pub
+		nodes[id] = &encNode{id: id, priv: priv}
+	}
+
+	for _, n := range nodes {
+		priv := n.priv
+		config := DefaultBulletinConfig(n.id)
+		config.DataDir = t.TempDir()
+		config.SignFunc = func(data []byte) (string, error) { return "sig", nil }
+		config.WrapKeyFunc = func(recipient string, key []byte) ([]byte, error) {
+			pub, ok := pubKeys[recipient]
+			if !ok {
+				return nil, errors.New("unknown recipient")
+			}
+			return crypto.SealToEd25519(pub, key)
+		}
+		config.UnwrapKeyFunc = func(wrapped []byte) ([]byte, error) {
+			return crypto.OpenWithEd25519(priv, wrapped)
+		}
+		bb, err := NewBulletinBoard(config)
+		if err != nil {
+			t.Fatalf("创建留言板失败: %v", err)
+		}
+		n.bb = bb
+	}
+	return nodes
+}
+
+func TestPublishEncrypted(t *testing.T) {
+	nodes := newEncNodes(t, "author", "bidder-1", "bidder-2", "outsider")
+	author := nodes["author"].bb
+	secret := "交付地址与验收标准：仅限入围投标方"
+
+	msg, err := author.PublishEncrypted(secret, "tasks", []string{"bidder-1", "bidder-2", "bidder-1"}, []string{"shortlist"})
+	if err != nil {
+		t.Fatalf("发布加密留言失败: %v", err)
+	}
+	if !msg.Encrypted || strings.Contains(msg.Content, "验收") {
+		t.Fatal("内容应为密文")
+	}
+	if got := msg.Recipients(); len(got) != 3 || !msg.IsRecipient("author") {
+		t.Errorf("接收方应为两个投标方加作者, 得到 %v", got)
+	}
+
+	// 作者可以解密自己的留言
+	if plain, err := author.DecryptMessage(msg.MessageID); err != nil || plain != secret {
+		t.Errorf("作者解密失败: %q, %v", plain, err)
+	}
+
+	for id, n := range nodes {
+		if id == "author" {
+			continue
+		}
+		copied := *msg
+		if err := n.bb.ReceiveMessage(&copied, "author"); err != nil {
+			t.Fatalf("%s 接收失败: %v", id, err)
+		}
+		plain, err := n.bb.DecryptMessage(msg.MessageID)
+		if id == "outsider" {
+			if err != ErrNotRecipient {
+				t.Errorf("非接收方应返回 ErrNotRecipient, 得到 %v", err)
+			}
+			// 留言的存在本身是公开的
+			if summaries := n.bb.GetMessageSummaries("tasks", 10, 0); len(summaries) != 1 || summaries[0].Preview != encryptedPreview {
+				t.Errorf("非接收方应能看到加密留言摘要: %+v", summaries)
+			}
+			continue
+		}
+		if err != nil || plain != secret {
+			t.Errorf("%s 解密失败: %q, %v", id, plain, err)
+		}
+	}
+}
+
+func TestPublishEncryptedErrors(t *testing.T) {
+	nodes := newEncNodes(t, "author")
+	bb := nodes["author"].bb
+
+	if _, err := bb.PublishEncrypted("secret", "tasks", nil, nil); err != ErrNoRecipients {
+		t.Errorf("期望 ErrNoRecipients, 得到 %v", err)
+	}
+	if _, err := bb.PublishEncrypted("secret", "tasks", []string{"unknown"}, nil); err == nil {
+		t.Error("无法包裹密钥时应失败")
+	}
+
+	plain, _ := bb.PublishMessage("hello", "tasks")
+	if _, err := bb.DecryptMessage(plain.MessageID); err != ErrNotEncrypted {
+		t.Errorf("期望 ErrNotEncrypted, 得到 %v", err)
+	}
+
+	bb.config.WrapKeyFunc = nil
+	if _, err := bb.PublishEncrypted("secret", "tasks", []string{"author"}, nil); err != ErrEncryptionUnavail {
+		t.Errorf("期望 ErrEncryptionUnavail, 得到 %v", err)
+	}
+}
+
+func TestEncryptedSignatureCoversRecipients(t *testing.T) {
+	nodes := newEncNodes(t, "author", "bidder")
+	msg, err := nodes["author"].bb.PublishEncrypted("secret", "tasks", []string{"bidder"}, nil)
+	if err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+
+	before := string(nodes["author"].bb.getSignData(msg))
+	tampered := *msg
+	tampered.WrappedKeys = map[string]string{"author": msg.WrappedKeys["author"]}
+	if string(nodes["author"].bb.getSignData(&tampered)) == before {
+		t.Error("删除接收方后签名数据应变化")
+	}
+}
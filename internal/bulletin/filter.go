@@ -0,0 +1,225 @@
+package bulletin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 订阅过滤
+//
+// 热门话题的消息量很大，逐条推给 Agent 会把它淹没。每个订阅可以带一个过滤器，
+// 收到的消息先在本节点匹配，不匹配的消息照常存储和转发，但不计入订阅统计，
+// 也不触发 OnMessageReceived（节点据此推送事件流）和订阅回调。
+// 本节点自己发布的消息不受过滤。
+
+var ErrInvalidFilter = errors.New("invalid subscription filter")
+
+// 可识别的语言，按文字系统粗略判断，也可由消息标签 "lang:<code>" 显式声明
+var supportedLanguages = map[string]bool{
+	"zh": true, "ja": true, "ko": true, "ru": true, "ar": true, "en": true,
+}
+
+// SubscriptionFilter 订阅过滤器，各条件同时满足才投递，空条件不限
+type SubscriptionFilter struct {
+	Keywords      []string `json:"keywords,omitempty"`       // 内容或标签包含任一关键词（不区分大小写）
+	Authors       []string `json:"authors,omitempty"`        // 作者白名单
+	MinReputation float64  `json:"min_reputation,omitempty"` // 作者最低声誉
+	Languages     []string `json:"languages,omitempty"`      // 语言代码，如 zh、en
+}
+
+// normalize 去除空白条目，关键词和语言代码转为小写，并校验语言代码
+func (f *SubscriptionFilter) normalize() (*SubscriptionFilter, error) {
+	out := &SubscriptionFilter{MinReputation: f.MinReputation}
+	for _, kw := range f.Keywords {
+		if kw = strings.ToLower(strings.TrimSpace(kw)); kw != "" {
+			out.Keywords = append(out.Keywords, kw)
+		}
+	}
+	for _, a := range f.Authors {
+		if a = strings.TrimSpace(a); a != "" {
+			out.Authors = append(out.Authors, a)
+		}
+	}
+	for _, lang := range f.Languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" {
+			continue
+		}
+		if !supportedLanguages[lang] {
+			return nil, fmt.Errorf("%w: unsupported language %q", ErrInvalidFilter, lang)
+		}
+		out.Languages = append(out.Languages, lang)
+	}
+	if out.empty() {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func (f *SubscriptionFilter) empty() bool {
+	return len(f.Keywords) == 0 && len(f.Authors) == 0 && f.MinReputation == 0 && len(f.Languages) == 0
+}
+
+// Matches 判断消息是否满足过滤条件，reputation 为作者的声誉
+// 加密消息的正文不可读，只按作者和声誉判断；无法判断语言的消息不按语言过滤。
+func (f *SubscriptionFilter) Matches(msg *Message, reputation float64) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Authors) > 0 && !containsString(f.Authors, msg.Author) {
+		return false
+	}
+	if f.MinReputation != 0 && reputation < f.MinReputation {
+		return false
+	}
+	if msg.Encrypted {
+		return true
+	}
+	if len(f.Keywords) > 0 && !matchesKeyword(f.Keywords, msg) {
+		return false
+	}
+	if len(f.Languages) > 0 {
+		if lang := MessageLanguage(msg); lang != "" && !containsString(f.Languages, lang) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesKeyword(keywords []string, msg *Message) bool {
+	content := strings.ToLower(msg.Content)
+	for _, kw := range keywords {
+		if strings.Contains(content, kw) {
+			return true
+		}
+		for _, tag := range msg.Tags {
+			if strings.ToLower(tag) == kw {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageLanguage 消息语言：优先取 "lang:<code>" 标签，否则按正文文字系统判断
+func MessageLanguage(msg *Message) string {
+	for _, tag := range msg.Tags {
+		if code, ok := strings.CutPrefix(strings.ToLower(tag), "lang:"); ok && code != "" {
+			return code
+		}
+	}
+	return DetectLanguage(msg.Content)
+}
+
+// DetectLanguage 按占多数的文字系统粗略判断语言，无法判断时返回空字符串
+// 汉字中夹有假名视为日文；拉丁字母统一视为英文。
+func DetectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, arabic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	lang, best := "", 0
+	for _, c := range []struct {
+		code  string
+		count int
+	}{
+		{"zh", han}, {"ko", hangul}, {"ru", cyrillic}, {"ar", arabic}, {"en", latin},
+	} {
+		if c.count > best {
+			lang, best = c.code, c.count
+		}
+	}
+	if kana > 0 && (lang == "zh" || kana >= best) {
+		return "ja"
+	}
+	return lang
+}
+
+// SubscribeTopicWithFilter 订阅话题并设置过滤器
+func (bb *BulletinBoard) SubscribeTopicWithFilter(topic string, filter *SubscriptionFilter, callback func(*Message)) error {
+	if filter != nil {
+		if _, err := filter.normalize(); err != nil {
+			return err
+		}
+	}
+	if err := bb.SubscribeTopic(topic, callback); err != nil {
+		return err
+	}
+	return bb.SetSubscriptionFilter(topic, filter)
+}
+
+// SetSubscriptionFilter 设置已订阅话题的过滤器，nil 或空过滤器表示不过滤
+func (bb *BulletinBoard) SetSubscriptionFilter(topic string, filter *SubscriptionFilter) error {
+	if topic == "" {
+		return ErrEmptyTopic
+	}
+	if filter != nil {
+		var err error
+		if filter, err = filter.normalize(); err != nil {
+			return err
+		}
+	}
+
+	bb.mu.Lock()
+	sub, exists := bb.subscriptions[topic]
+	if !exists {
+		bb.mu.Unlock()
+		return ErrNotSubscribed
+	}
+	sub.Filter = filter
+	bb.dirty.Mark("subscriptions", topic)
+	bb.mu.Unlock()
+
+	bb.save()
+	return nil
+}
+
+// GetSubscriptionFilter 获取话题的过滤器，未订阅或未设置时返回 nil
+func (bb *BulletinBoard) GetSubscriptionFilter(topic string) *SubscriptionFilter {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+
+	if sub, ok := bb.subscriptions[topic]; ok && sub.Filter != nil {
+		copied := *sub.Filter
+		return &copied
+	}
+	return nil
+}
+
+// matchesSubscriptionLocked 收到的消息是否应投递：未订阅的话题不过滤
+func (bb *BulletinBoard) matchesSubscriptionLocked(msg *Message) bool {
+	sub, ok := bb.subscriptions[msg.Topic]
+	if !ok || sub.Filter == nil {
+		return true
+	}
+	reputation := msg.ReputationScore
+	if bb.config.GetReputationFunc != nil {
+		reputation = bb.config.GetReputationFunc(msg.Author)
+	}
+	return sub.Filter.Matches(msg, reputation)
+}
@@ -0,0 +1,123 @@
+package bulletin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionFilter(t *testing.T) {
+	bb := createTestBoard(t)
+	bb.config.GetReputationFunc = func(nodeID string) float64 {
+		if nodeID == "node-low" {
+			return 10
+		}
+		return 80
+	}
+
+	var mu sync.Mutex
+	var delivered, events []string
+	bb.OnMessageReceived = func(msg *Message) {
+		mu.Lock()
+		events = append(events, msg.MessageID)
+		mu.Unlock()
+	}
+	err := bb.SubscribeTopicWithFilter("market", &SubscriptionFilter{
+		Keywords:      []string{" GPU "},
+		Authors:       []string{"node-a", "node-low"},
+		MinReputation: 50,
+		Languages:     []string{"ZH", "en"},
+	}, func(msg *Message) {
+		mu.Lock()
+		delivered = append(delivered, msg.MessageID)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("SubscribeTopicWithFilter failed: %v", err)
+	}
+
+	receive := func(id, author, content string, tags ...string) {
+		bb.ReceiveMessage(&Message{
+			MessageID: id,
+			Author:    author,
+			Topic:     "market",
+			Content:   content,
+			Tags:      tags,
+			Timestamp: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+			Status:    StatusActive,
+			TTL:       5,
+		}, "relay")
+	}
+	receive("match-en", "node-a", "Renting GPU hours cheaply")
+	receive("match-zh", "node-a", "出租 GPU 算力")
+	receive("match-tag", "node-a", "hourly compute", "gpu")
+	receive("other-author", "node-b", "GPU for sale")
+	receive("low-rep", "node-low", "GPU for sale")
+	receive("no-keyword", "node-a", "selling storage")
+	receive("wrong-lang", "node-a", "Продаю GPU")
+	receive("tagged-lang", "node-a", "GPU", "lang:ja")
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]bool{"match-en": true, "match-zh": true, "match-tag": true}
+	if len(delivered) != len(want) || len(events) != len(want) {
+		t.Fatalf("delivered %v, events %v; want %d matches", delivered, events, len(want))
+	}
+	for _, id := range delivered {
+		if !want[id] {
+			t.Errorf("unexpected delivery of %s", id)
+		}
+	}
+
+	// 不匹配的消息照常存储，订阅统计只计投递的消息
+	if msgs, _ := bb.QueryByTopic("market", 20, 0); len(msgs) != 8 {
+		t.Errorf("stored %d messages, want 8", len(msgs))
+	}
+	if subs := bb.GetSubscriptions(); len(subs) != 1 || subs[0].MessageCount != 3 {
+		t.Errorf("unexpected subscription stats: %+v", subs)
+	}
+
+	// 过滤器已规范化
+	f := bb.GetSubscriptionFilter("market")
+	if f == nil || f.Keywords[0] != "gpu" || f.Languages[0] != "zh" {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	if err := bb.SetSubscriptionFilter("market", &SubscriptionFilter{}); err != nil || bb.GetSubscriptionFilter("market") != nil {
+		t.Errorf("empty filter should clear the subscription filter, err = %v", err)
+	}
+}
+
+func TestSubscriptionFilterErrors(t *testing.T) {
+	bb := createTestBoard(t)
+
+	if err := bb.SetSubscriptionFilter("nope", &SubscriptionFilter{Keywords: []string{"x"}}); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("expected ErrNotSubscribed, got %v", err)
+	}
+	err := bb.SubscribeTopicWithFilter("t", &SubscriptionFilter{Languages: []string{"klingon"}}, nil)
+	if !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("expected ErrInvalidFilter, got %v", err)
+	}
+	if len(bb.GetSubscriptions()) != 0 {
+		t.Error("an invalid filter should not create the subscription")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"hello world":   "en",
+		"你好，世界":         "zh",
+		"こんにちは世界":       "ja",
+		"안녕하세요":         "ko",
+		"Привет, мир":   "ru",
+		"مرحبا":         "ar",
+		"12345 !!!":     "",
+		"GPU 算力出租，价格面议": "zh",
+	} {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package bulletin
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
+)
+
+// 广播主题按版本区分前缀。v2 节点在 v1 的弃用窗口内同时订阅 v1 主题，并把本节点发布的
+// 留言也发布到 v1 主题（兼容模式），新旧节点都能收到彼此的留言；两个主题上的同一条留言
+// 由去重缓存合并。
+const (
+	// GossipTopicPrefix 留言话题对应的 pubsub 主题前缀（v2）
+	GossipTopicPrefix = "/daan/bulletin/v2/"
+	// LegacyGossipTopicPrefix v1 主题前缀
+	LegacyGossipTopicPrefix = "/daan/bulletin/"
+	// GossipCompatFamily 留言广播在协议版本回退表中的协议族名称
+	GossipCompatFamily = "bulletin_gossip"
+)
+
+// GossipTopic 返回留言话题对应的 pubsub 主题
+func GossipTopic(topic string) string {
+	return GossipTopicPrefix + topic
+}
+
+// GossipFamily 留言广播的协议族：v2 主题前缀，v1 主题前缀兼容到 sunset（零值表示不设截止）
+func GossipFamily(sunset time.Time) compat.Family {
+	return compat.Family{
+		Name:    GossipCompatFamily,
+		Current: GossipTopicPrefix,
+		Legacy:  []compat.Legacy{{ID: LegacyGossipTopicPrefix, Sunset: sunset}},
+	}
+}
+
+// gossipCopy v2 节点发布到 v1 主题的留言副本。多出的 compat 字段被 v1 节点忽略，
+// 用于把这些副本与真正来自 v1 节点的留言区分开，旧版本流量统计不计入副本。
+type gossipCopy struct {
+	*Message
+	Compat int `json:"compat"`
+}
+
+// isGossipCopy 判断 v1 主题上的数据是否为 v2 节点发布的副本
+func isGossipCopy(data []byte) bool {
+	var probe struct {
+		Compat int `json:"compat"`
+	}
+	return json.Unmarshal(data, &probe) == nil && probe.Compat > 0
+}
+
+// GossipTransport 留言的网络广播通道（GossipSub）
+// validate 在转发前调用，返回 false 的消息既不投递也不继续传播；handler 只收到其他节点的消息。
+type GossipTransport interface {
+	Publish(topic string, data []byte) error
+	Subscribe(topic string, validate func(data []byte) bool, handler func(data []byte, from string)) error
+	Unsubscribe(topic string) error
+}
+
+// GossipStats 广播收发统计
+type GossipStats struct {
+	Published  int64 `json:"published"`
+	Received   int64 `json:"received"`
+	Rejected   int64 `json:"rejected"`   // 解码、过期或签名校验失败
+	Duplicates int64 `json:"duplicates"` // 已见过的消息
+	Seen       int   `json:"seen"`       // 去重缓存条数
+}
+
+// SetCompat 设置广播主题的版本回退表，需在 SetGossip 之前调用。
+// 表中登记了 GossipFamily 时，在 v1 的弃用窗口内同时使用 v1 主题，并按版本统计收发的留言。
+func (bb *BulletinBoard) SetCompat(table *compat.Table) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	bb.compat = table
+}
+
+// gossipPrefixes 当前使用的主题前缀，v2 在前
+func (bb *BulletinBoard) gossipPrefixes() []string {
+	bb.mu.RLock()
+	table := bb.compat
+	bb.mu.RUnlock()
+	if table != nil {
+		if prefixes := table.Protocols(GossipCompatFamily); len(prefixes) > 0 {
+			return prefixes
+		}
+	}
+	return []string{GossipTopicPrefix}
+}
+
+func (bb *BulletinBoard) compatTable() *compat.Table {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+	return bb.compat
+}
+
+// SetGossip 设置广播通道，并为已订阅的话题和版主治理记录加入对应主题
+// 之后本节点发布的留言会广播到全网，订阅话题的留言经校验和去重后存储。
+func (bb *BulletinBoard) SetGossip(transport GossipTransport) {
+	bb.mu.Lock()
+	bb.gossip = transport
+	topics := make([]string, 0, len(bb.subscriptions))
+	for topic := range bb.subscriptions {
+		topics = append(topics, topic)
+	}
+	bb.mu.Unlock()
+
+	if transport == nil {
+		return
+	}
+	for _, topic := range topics {
+		bb.joinGossip(topic)
+	}
+	if bb.gossipTransport() != nil {
+		transport.Subscribe(ModerationTopic,
+			func(data []byte) bool { _, err := bb.decodeModeration(data); return err == nil },
+			func(data []byte, from string) { bb.ReceiveModeration(data, from) })
+	}
+}
+
+// joinGossip 订阅话题对应的 pubsub 主题（兼容模式下包括 v1 主题）
+func (bb *BulletinBoard) joinGossip(topic string) {
+	transport := bb.gossipTransport()
+	if transport == nil {
+		return
+	}
+	for _, prefix := range bb.gossipPrefixes() {
+		transport.Subscribe(prefix+topic, bb.gossipValidator(prefix), bb.gossipHandler(prefix))
+	}
+}
+
+// leaveGossip 退出话题对应的 pubsub 主题，v1 主题可能在加入后才过弃用截止时间，一并退出
+func (bb *BulletinBoard) leaveGossip(topic string) {
+	if transport := bb.gossipTransport(); transport != nil {
+		transport.Unsubscribe(GossipTopic(topic))
+		if bb.compatTable() != nil {
+			transport.Unsubscribe(LegacyGossipTopicPrefix + topic)
+		}
+	}
+}
+
+// gossipValidator 主题校验：过了弃用截止时间的旧版本主题上的留言被拒绝，不再转发
+func (bb *BulletinBoard) gossipValidator(prefix string) func(data []byte) bool {
+	if prefix == GossipTopicPrefix {
+		return bb.validateGossip
+	}
+	return func(data []byte) bool {
+		if table := bb.compatTable(); table != nil && !table.Active(GossipCompatFamily, prefix) {
+			table.Accept(GossipCompatFamily, prefix, "", compat.Inbound) // 计入拒绝次数
+			return false
+		}
+		return bb.validateGossip(data)
+	}
+}
+
+// gossipHandler 按主题版本统计收到的留言后存储；v2 节点发布到 v1 主题的副本不计入旧版本流量
+func (bb *BulletinBoard) gossipHandler(prefix string) func(data []byte, from string) {
+	return func(data []byte, from string) {
+		if table := bb.compatTable(); table != nil {
+			if prefix != GossipTopicPrefix && isGossipCopy(data) {
+				if !table.Active(GossipCompatFamily, prefix) {
+					return
+				}
+			} else if !table.Accept(GossipCompatFamily, prefix, from, compat.Inbound) {
+				return
+			}
+		}
+		bb.handleGossip(data, from)
+	}
+}
+
+func (bb *BulletinBoard) gossipTransport() GossipTransport {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+	if !bb.config.GossipEnabled {
+		return nil
+	}
+	return bb.gossip
+}
+
+// broadcast 把本节点发布的留言广播到话题对应的主题，兼容模式下同时发布副本到 v1 主题
+// 发布成功后才记入去重缓存，因为 GossipSub 对本地发布的消息同样执行校验。
+func (bb *BulletinBoard) broadcast(msg *Message) {
+	transport := bb.gossipTransport()
+	if transport == nil {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if transport.Publish(GossipTopic(msg.Topic), data) != nil {
+		return
+	}
+	if table := bb.compatTable(); table != nil {
+		// 只统计 v2 发布，v1 副本是兼容模式的开销，不代表网络中的旧版本需求
+		table.Accept(GossipCompatFamily, GossipTopicPrefix, "", compat.Outbound)
+		if legacy := bb.gossipPrefixes()[1:]; len(legacy) > 0 {
+			copied, _ := json.Marshal(&gossipCopy{Message: msg, Compat: 2})
+			for _, prefix := range legacy {
+				transport.Publish(prefix+msg.Topic, copied)
+			}
+		}
+	}
+	bb.markSeen(msg)
+	bb.mu.Lock()
+	bb.gossipStats.Published++
+	bb.mu.Unlock()
+}
+
+// validateGossip 转发前的校验：可解码、未过期、签名有效且未见过
+// 校验失败的消息不会被 GossipSub 继续传播。
+func (bb *BulletinBoard) validateGossip(data []byte) bool {
+	msg, err := bb.decodeGossip(data)
+	if err != nil {
+		bb.mu.Lock()
+		bb.gossipStats.Rejected++
+		bb.mu.Unlock()
+		return false
+	}
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	if _, seen := bb.seen[msg.MessageID]; seen {
+		bb.gossipStats.Duplicates++
+		return false
+	}
+	return true
+}
+
+// handleGossip 存储校验通过的广播留言
+func (bb *BulletinBoard) handleGossip(data []byte, from string) {
+	msg, err := bb.decodeGossip(data)
+	if err != nil {
+		return
+	}
+	if !bb.markSeen(msg) {
+		bb.mu.Lock()
+		bb.gossipStats.Duplicates++
+		bb.mu.Unlock()
+		return
+	}
+	if err := bb.ReceiveMessage(msg, from); err == nil {
+		bb.mu.Lock()
+		bb.gossipStats.Received++
+		bb.mu.Unlock()
+		bb.save()
+	}
+}
+
+// decodeGossip 解码广播数据并校验过期时间和签名
+// 配置了验签函数时不接受未签名的广播。
+func (bb *BulletinBoard) decodeGossip(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.MessageID == "" || msg.Topic == "" {
+		return nil, ErrInvalidMessageID
+	}
+	if bb.skew.Expired(msg.ExpiresAt, time.Now()) {
+		return nil, ErrMessageExpired
+	}
+	if bb.config.VerifyFunc != nil && !bb.VerifyMessage(&msg) {
+		return nil, ErrInvalidSignature
+	}
+	return &msg, nil
+}
+
+// markSeen 记录已见过的消息，返回是否首次见到
+// 去重记录保留到消息过期，之后由清理任务删除；留言被清理后重放的旧消息仍会被拒绝。
+func (bb *BulletinBoard) markSeen(msg *Message) bool {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	if _, ok := bb.seen[msg.MessageID]; ok {
+		return false
+	}
+	if limit := bb.config.GossipSeenLimit; limit > 0 && len(bb.seen) >= limit {
+		// 一次删到上限的 90%，避免每条新消息都触发排序
+		bb.evictSeenLocked(len(bb.seen) - limit*9/10 + 1)
+	}
+	bb.seen[msg.MessageID] = msg.ExpiresAt
+	return true
+}
+
+// pruneSeenLocked 删除已过期消息的去重记录（调用方持有写锁）
+func (bb *BulletinBoard) pruneSeenLocked(now time.Time) {
+	for id, expiresAt := range bb.seen {
+		if !bb.skew.Retained(expiresAt, now) {
+			delete(bb.seen, id)
+		}
+	}
+}
+
+// evictSeenLocked 删除最早过期的 n 条去重记录（调用方持有写锁）
+func (bb *BulletinBoard) evictSeenLocked(n int) {
+	ids := make([]string, 0, len(bb.seen))
+	for id := range bb.seen {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bb.seen[ids[i]].Before(bb.seen[ids[j]])
+	})
+	if n > len(ids) {
+		n = len(ids)
+	}
+	for _, id := range ids[:n] {
+		delete(bb.seen, id)
+	}
+}
+
+// GetGossipStats 获取广播收发统计
+func (bb *BulletinBoard) GetGossipStats() GossipStats {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+	s := bb.gossipStats
+	s.Seen = len(bb.seen)
+	return s
+}
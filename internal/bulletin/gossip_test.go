@@ -0,0 +1,246 @@
+package bulletin
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/compat"
+)
+
+// gossipHub 内存中的 pubsub，按主题把消息投递给其他节点（先校验再投递）
+type gossipHub struct {
+	mu   sync.Mutex
+	subs map[string]map[string]*hubSub // topic -> nodeID -> 订阅
+}
+
+type hubSub struct {
+	validate func([]byte) bool
+	handler  func([]byte, string)
+}
+
+type hubTransport struct {
+	hub    *gossipHub
+	nodeID string
+}
+
+func (t *hubTransport) Publish(topic string, data []byte) error {
+	t.hub.mu.Lock()
+	subs := make([]*hubSub, 0)
+	for id, sub := range t.hub.subs[topic] {
+		if id != t.nodeID {
+			subs = append(subs, sub)
+		}
+	}
+	t.hub.mu.Unlock()
+	for _, sub := range subs {
+		if sub.validate(data) {
+			sub.handler(data, t.nodeID)
+		}
+	}
+	return nil
+}
+
+func (t *hubTransport) Subscribe(topic string, validate func([]byte) bool, handler func([]byte, string)) error {
+	t.hub.mu.Lock()
+	defer t.hub.mu.Unlock()
+	if t.hub.subs[topic] == nil {
+		t.hub.subs[topic] = make(map[string]*hubSub)
+	}
+	t.hub.subs[topic][t.nodeID] = &hubSub{validate, handler}
+	return nil
+}
+
+func (t *hubTransport) Unsubscribe(topic string) error {
+	t.hub.mu.Lock()
+	defer t.hub.mu.Unlock()
+	delete(t.hub.subs[topic], t.nodeID)
+	return nil
+}
+
+func newGossipBoard(t *testing.T, hub *gossipHub, nodeID string) *BulletinBoard {
+	t.Helper()
+	config := DefaultBulletinConfig(nodeID)
+	config.DataDir = t.TempDir()
+	config.SignFunc = func(data []byte) (string, error) { return nodeID + ":" + string(data), nil }
+	config.VerifyFunc = func(publicKey string, data []byte, signature string) bool {
+		return signature == publicKey+":"+string(data)
+	}
+	bb, err := NewBulletinBoard(config)
+	if err != nil {
+		t.Fatalf("NewBulletinBoard failed: %v", err)
+	}
+	bb.SetGossip(&hubTransport{hub: hub, nodeID: nodeID})
+	return bb
+}
+
+func TestGossipPropagation(t *testing.T) {
+	hub := &gossipHub{subs: make(map[string]map[string]*hubSub)}
+	alice := newGossipBoard(t, hub, "alice")
+	bob := newGossipBoard(t, hub, "bob")
+	carol := newGossipBoard(t, hub, "carol")
+
+	if err := bob.SubscribeTopic("news", nil); err != nil {
+		t.Fatalf("SubscribeTopic failed: %v", err)
+	}
+
+	msg, err := alice.PublishMessage("hello network", "news")
+	if err != nil {
+		t.Fatalf("PublishMessage failed: %v", err)
+	}
+	if _, err := bob.QueryMessage(msg.MessageID); err != nil {
+		t.Errorf("subscriber should receive the message: %v", err)
+	}
+	if _, err := carol.QueryMessage(msg.MessageID); err != ErrMessageNotFound {
+		t.Errorf("non-subscriber should not receive the message, got %v", err)
+	}
+	if s := alice.GetGossipStats(); s.Published != 1 {
+		t.Errorf("publisher stats = %+v", s)
+	}
+
+	// 重放：即使本地留言已被清理，去重缓存仍会拒绝
+	data, _ := json.Marshal(msg)
+	bob.mu.Lock()
+	bob.removeMessageLocked(msg.MessageID)
+	bob.mu.Unlock()
+	hub.subs[GossipTopic("news")]["bob"].handler(data, "mallory")
+	if _, err := bob.QueryMessage(msg.MessageID); err != ErrMessageNotFound {
+		t.Error("replayed message should be dropped")
+	}
+	if s := bob.GetGossipStats(); s.Received != 1 || s.Duplicates != 1 {
+		t.Errorf("subscriber stats = %+v", s)
+	}
+
+	// 退订后不再接收
+	bob.UnsubscribeTopic("news")
+	msg2, _ := alice.PublishMessage("second", "news")
+	if _, err := bob.QueryMessage(msg2.MessageID); err != ErrMessageNotFound {
+		t.Error("unsubscribed board should not receive messages")
+	}
+}
+
+func TestGossipValidation(t *testing.T) {
+	hub := &gossipHub{subs: make(map[string]map[string]*hubSub)}
+	bob := newGossipBoard(t, hub, "bob")
+
+	now := time.Now()
+	forged := &Message{
+		MessageID: "forged",
+		Author:    "alice",
+		Topic:     "news",
+		Content:   "fake",
+		Timestamp: now,
+		ExpiresAt: now.Add(time.Hour),
+		Signature: "mallory:whatever",
+	}
+	data, _ := json.Marshal(forged)
+	if bob.validateGossip(data) {
+		t.Error("forged signature should be rejected")
+	}
+
+	forged.Signature = ""
+	data, _ = json.Marshal(forged)
+	if bob.validateGossip(data) {
+		t.Error("unsigned message should be rejected when verification is configured")
+	}
+
+	expired := &Message{MessageID: "old", Author: "alice", Topic: "news", Timestamp: now.Add(-48 * time.Hour), ExpiresAt: now.Add(-24 * time.Hour)}
+	expired.Signature = "alice:" + string(bob.getSignData(expired))
+	data, _ = json.Marshal(expired)
+	if bob.validateGossip(data) {
+		t.Error("expired message should be rejected")
+	}
+	if s := bob.GetGossipStats(); s.Rejected != 3 {
+		t.Errorf("rejected = %d, want 3", s.Rejected)
+	}
+}
+
+func TestGossipLegacyTopicCompat(t *testing.T) {
+	hub := &gossipHub{subs: make(map[string]map[string]*hubSub)}
+	alice := newGossipBoard(t, hub, "alice")
+	bob := newGossipBoard(t, hub, "bob")
+	table := compat.NewTable()
+	sunset := time.Now().Add(time.Hour)
+	table.Register(GossipFamily(sunset))
+	alice.SetCompat(table)
+	alice.SubscribeTopic("news", nil)
+	bob.SubscribeTopic("news", nil)
+
+	// 未升级的节点只在 v1 主题上收发
+	var legacyReceived [][]byte
+	hub.subs[LegacyGossipTopicPrefix+"news"]["old"] = &hubSub{
+		validate: func([]byte) bool { return true },
+		handler:  func(data []byte, from string) { legacyReceived = append(legacyReceived, data) },
+	}
+	old := &hubTransport{hub: hub, nodeID: "old"}
+
+	// 兼容模式下 v2 节点的留言同时发布到 v1 主题，v1 节点按原格式解码
+	msg, _ := alice.PublishMessage("hello everyone", "news")
+	if len(legacyReceived) != 1 || !isGossipCopy(legacyReceived[0]) {
+		t.Fatalf("legacy node received %d messages", len(legacyReceived))
+	}
+	var decoded Message
+	if err := json.Unmarshal(legacyReceived[0], &decoded); err != nil || decoded.MessageID != msg.MessageID || !bob.VerifyMessage(&decoded) {
+		t.Errorf("legacy copy not readable by v1 nodes: %+v, %v", decoded, err)
+	}
+
+	// v1 节点的留言由兼容模式的 v2 节点收到
+	now := time.Now()
+	legacyMsg := &Message{MessageID: "from-old", Author: "old", Topic: "news", Content: "still here", Timestamp: now, ExpiresAt: now.Add(time.Hour)}
+	legacyMsg.Signature = "old:" + string(alice.getSignData(legacyMsg))
+	data, _ := json.Marshal(legacyMsg)
+	old.Publish(LegacyGossipTopicPrefix+"news", data)
+	if _, err := alice.QueryMessage("from-old"); err != nil {
+		t.Errorf("compat node should receive v1 messages: %v", err)
+	}
+	if _, err := bob.QueryMessage("from-old"); err != ErrMessageNotFound {
+		t.Error("node without compatibility mode should not join v1 topics")
+	}
+
+	status := table.Status()[0]
+	if v2, v1 := status.Versions[0], status.Versions[1]; v2.Outbound != 1 || v1.Inbound != 1 || v1.Outbound != 0 || v1.Peers != 1 {
+		t.Errorf("versions = %+v", status.Versions)
+	}
+
+	// 过了弃用截止时间后不再发布到 v1 主题，也不再接受 v1 留言
+	table.Register(GossipFamily(now.Add(-time.Minute)))
+	legacyReceived = nil
+	alice.PublishMessage("v2 only", "news")
+	if len(legacyReceived) != 0 {
+		t.Error("published to v1 topic after sunset")
+	}
+	legacyMsg.MessageID = "late"
+	legacyMsg.Signature = "old:" + string(alice.getSignData(legacyMsg))
+	data, _ = json.Marshal(legacyMsg)
+	old.Publish(LegacyGossipTopicPrefix+"news", data)
+	if _, err := alice.QueryMessage("late"); err != ErrMessageNotFound {
+		t.Error("v1 message accepted after sunset")
+	}
+	if v1 := table.Status()[0].Versions[1]; v1.Refused != 1 || v1.Active {
+		t.Errorf("v1 after sunset = %+v", v1)
+	}
+}
+
+func TestGossipSeenPruning(t *testing.T) {
+	hub := &gossipHub{subs: make(map[string]map[string]*hubSub)}
+	bb := newGossipBoard(t, hub, "bob")
+	bb.config.GossipSeenLimit = 10
+
+	now := time.Now()
+	for i := 0; i < 11; i++ {
+		bb.markSeen(&Message{MessageID: string(rune('a' + i)), ExpiresAt: now.Add(time.Duration(i+1) * time.Hour)})
+	}
+	if n := bb.GetGossipStats().Seen; n != 9 {
+		t.Errorf("seen = %d, want 9 after trimming to 90%% of the limit", n)
+	}
+
+	bb.markSeen(&Message{MessageID: "expired", ExpiresAt: now.Add(-24 * time.Hour)})
+	bb.cleanup()
+	bb.mu.RLock()
+	_, ok := bb.seen["expired"]
+	bb.mu.RUnlock()
+	if ok {
+		t.Error("expired entries should be pruned")
+	}
+}
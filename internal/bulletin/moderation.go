@@ -0,0 +1,445 @@
+package bulletin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 版主治理：版主隐藏留言会给作者记一次警告；作者可对隐藏提出申诉，
+// 由另一名版主裁决。申诉成立时恢复留言、撤销作者警告，并给原版主记一次警告。
+// 任一方警告数达到阈值时自动发起治理提案。
+//
+// 隐藏、申诉和裁决由记录操作的节点签名后广播，其他节点验签后在本地同样执行；
+// 警告阈值的提案只由记录操作的节点发起。
+
+// ModerationTopic 版主治理记录的广播主题，不计入节点间的留言配额
+const ModerationTopic = "/daan/bulletin-moderation/1.0.0"
+
+var (
+	ErrEmptyModerator     = errors.New("moderator cannot be empty")
+	ErrAlreadyHidden      = errors.New("message already hidden")
+	ErrNotHidden          = errors.New("message is not hidden")
+	ErrNotAuthor          = errors.New("only the author can appeal")
+	ErrAppealExists       = errors.New("appeal already filed")
+	ErrAppealWindowClosed = errors.New("appeal window has closed")
+	ErrNoPendingAppeal    = errors.New("no pending appeal")
+	ErrConflictOfInterest = errors.New("resolver must not be the hiding moderator or the author")
+	ErrUnknownModeration  = errors.New("unknown moderation action")
+)
+
+// ModerationAction 版主治理操作
+type ModerationAction string
+
+const (
+	ActionHide    ModerationAction = "hide"    // 版主隐藏留言
+	ActionAppeal  ModerationAction = "appeal"  // 作者申诉
+	ActionResolve ModerationAction = "resolve" // 另一名版主裁决申诉
+)
+
+// ModerationRecord 广播的版主治理操作，由记录操作的节点（Signer）签名
+// Actor 是实际的版主或作者，经签名请求认证的操作者可以不是记录节点本身。
+type ModerationRecord struct {
+	Action    ModerationAction `json:"action"`
+	MessageID string           `json:"message_id"`
+	Actor     string           `json:"actor"`
+	Text      string           `json:"text,omitempty"`     // 隐藏理由、申诉陈述或裁决说明
+	Overturn  bool             `json:"overturn,omitempty"` // 裁决是否推翻隐藏
+	Signer    string           `json:"signer"`
+	Timestamp int64            `json:"timestamp"`
+	Signature string           `json:"signature,omitempty"`
+}
+
+func (r *ModerationRecord) signData() []byte {
+	return []byte(fmt.Sprintf("bulletin:moderation:%s:%s:%s:%s:%t:%s:%d",
+		r.Action, r.MessageID, r.Actor, r.Text, r.Overturn, r.Signer, r.Timestamp))
+}
+
+// StrikeRole 警告对象的角色
+type StrikeRole string
+
+const (
+	StrikeAuthor    StrikeRole = "author"    // 留言被隐藏的作者
+	StrikeModerator StrikeRole = "moderator" // 隐藏被推翻的版主
+)
+
+// AppealStatus 申诉状态
+type AppealStatus string
+
+const (
+	AppealPending    AppealStatus = "pending"    // 待裁决
+	AppealUpheld     AppealStatus = "upheld"     // 维持隐藏
+	AppealOverturned AppealStatus = "overturned" // 推翻隐藏
+)
+
+// Appeal 作者对隐藏的申诉
+type Appeal struct {
+	Statement  string       `json:"statement"`
+	FiledAt    time.Time    `json:"filed_at"`
+	Status     AppealStatus `json:"status"`
+	Resolver   string       `json:"resolver,omitempty"`
+	ResolvedAt time.Time    `json:"resolved_at,omitempty"`
+	Note       string       `json:"note,omitempty"`
+}
+
+// Moderation 一次隐藏操作的记录
+type Moderation struct {
+	MessageID string    `json:"message_id"`
+	Author    string    `json:"author"`
+	Moderator string    `json:"moderator"`
+	Reason    string    `json:"reason"`
+	HiddenAt  time.Time `json:"hidden_at"`
+	Appeal    *Appeal   `json:"appeal,omitempty"`
+}
+
+// StrikeRecord 某个节点在某一角色下的警告计数
+type StrikeRecord struct {
+	NodeID      string     `json:"node_id"`
+	Role        StrikeRole `json:"role"`
+	Count       int        `json:"count"`
+	MessageIDs  []string   `json:"message_ids"`            // 计入警告的留言
+	ProposalID  string     `json:"proposal_id,omitempty"`  // 达到阈值后发起的治理提案
+	EscalatedAt time.Time  `json:"escalated_at,omitempty"` // 达到阈值的时间
+}
+
+// StrikeProposalFunc 警告达到阈值时发起治理提案，返回提案ID
+type StrikeProposalFunc func(role StrikeRole, nodeID, reason string) (string, error)
+
+// SetProposalFunc 设置警告达到阈值时调用的提案函数
+func (bb *BulletinBoard) SetProposalFunc(fn StrikeProposalFunc) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+	bb.proposalFunc = fn
+}
+
+// HideMessage 版主隐藏留言，并给作者记一次警告
+func (bb *BulletinBoard) HideMessage(messageID, moderator, reason string) (*Moderation, error) {
+	mod, escalate, err := bb.hideMessage(messageID, moderator, reason)
+	if err != nil {
+		return nil, err
+	}
+	bb.escalate(escalate)
+	bb.save()
+	bb.broadcastModeration(&ModerationRecord{Action: ActionHide, MessageID: messageID, Actor: moderator, Text: reason})
+	return mod, nil
+}
+
+func (bb *BulletinBoard) hideMessage(messageID, moderator, reason string) (*Moderation, *StrikeRecord, error) {
+	if moderator == "" {
+		return nil, nil, ErrEmptyModerator
+	}
+
+	bb.mu.Lock()
+	msg, ok := bb.messages[messageID]
+	if !ok {
+		bb.mu.Unlock()
+		return nil, nil, ErrMessageNotFound
+	}
+	if msg.Status == StatusHidden {
+		bb.mu.Unlock()
+		return nil, nil, ErrAlreadyHidden
+	}
+
+	mod := &Moderation{
+		MessageID: messageID,
+		Author:    msg.Author,
+		Moderator: moderator,
+		Reason:    reason,
+		HiddenAt:  time.Now(),
+	}
+	msg.Status = StatusHidden
+	bb.moderations[messageID] = mod
+	bb.dirty.Mark("messages", messageID)
+	bb.dirty.Mark("moderations", messageID)
+	escalate := bb.addStrikeLocked(StrikeAuthor, msg.Author, messageID)
+	result := *mod
+	bb.mu.Unlock()
+	return &result, escalate, nil
+}
+
+// AppealHide 作者对被隐藏的留言提出申诉
+func (bb *BulletinBoard) AppealHide(messageID, author, statement string) (*Moderation, error) {
+	mod, err := bb.appealHide(messageID, author, statement)
+	if err != nil {
+		return nil, err
+	}
+	bb.save()
+	bb.broadcastModeration(&ModerationRecord{Action: ActionAppeal, MessageID: messageID, Actor: author, Text: statement})
+	return mod, nil
+}
+
+func (bb *BulletinBoard) appealHide(messageID, author, statement string) (*Moderation, error) {
+	bb.mu.Lock()
+	mod, ok := bb.moderations[messageID]
+	var err error
+	switch {
+	case !ok:
+		err = ErrNotHidden
+	case mod.Author != author:
+		err = ErrNotAuthor
+	case mod.Appeal != nil:
+		err = ErrAppealExists
+	case bb.config.AppealWindow > 0 && time.Since(mod.HiddenAt) > bb.config.AppealWindow:
+		err = ErrAppealWindowClosed
+	}
+	if err != nil {
+		bb.mu.Unlock()
+		return nil, err
+	}
+
+	mod.Appeal = &Appeal{
+		Statement: statement,
+		FiledAt:   time.Now(),
+		Status:    AppealPending,
+	}
+	bb.dirty.Mark("moderations", messageID)
+	result := *mod
+	appeal := *mod.Appeal
+	result.Appeal = &appeal
+	bb.mu.Unlock()
+	return &result, nil
+}
+
+// ResolveAppeal 裁决申诉
+// overturn 为 true 时恢复留言、撤销作者的警告并给原版主记一次警告。
+func (bb *BulletinBoard) ResolveAppeal(messageID, resolver string, overturn bool, note string) (*Moderation, error) {
+	mod, escalate, err := bb.resolveAppeal(messageID, resolver, overturn, note)
+	if err != nil {
+		return nil, err
+	}
+	bb.escalate(escalate)
+	bb.save()
+	bb.broadcastModeration(&ModerationRecord{Action: ActionResolve, MessageID: messageID, Actor: resolver, Text: note, Overturn: overturn})
+	return mod, nil
+}
+
+func (bb *BulletinBoard) resolveAppeal(messageID, resolver string, overturn bool, note string) (*Moderation, *StrikeRecord, error) {
+	if resolver == "" {
+		return nil, nil, ErrEmptyModerator
+	}
+
+	bb.mu.Lock()
+	mod, ok := bb.moderations[messageID]
+	if !ok || mod.Appeal == nil || mod.Appeal.Status != AppealPending {
+		bb.mu.Unlock()
+		return nil, nil, ErrNoPendingAppeal
+	}
+	if resolver == mod.Moderator || resolver == mod.Author {
+		bb.mu.Unlock()
+		return nil, nil, ErrConflictOfInterest
+	}
+
+	mod.Appeal.Resolver = resolver
+	mod.Appeal.ResolvedAt = time.Now()
+	mod.Appeal.Note = note
+	mod.Appeal.Status = AppealUpheld
+	bb.dirty.Mark("moderations", messageID)
+
+	var escalate *StrikeRecord
+	if overturn {
+		mod.Appeal.Status = AppealOverturned
+		if msg, ok := bb.messages[messageID]; ok && msg.Status == StatusHidden {
+			msg.Status = StatusActive
+			bb.dirty.Mark("messages", messageID)
+		}
+		bb.removeStrikeLocked(StrikeAuthor, mod.Author, messageID)
+		escalate = bb.addStrikeLocked(StrikeModerator, mod.Moderator, messageID)
+	}
+	result := *mod
+	appeal := *mod.Appeal
+	result.Appeal = &appeal
+	bb.mu.Unlock()
+	return &result, escalate, nil
+}
+
+// broadcastModeration 签名并广播本节点记录的治理操作，未启用广播时不发送
+func (bb *BulletinBoard) broadcastModeration(rec *ModerationRecord) {
+	transport := bb.gossipTransport()
+	if transport == nil {
+		return
+	}
+	rec.Signer = bb.config.NodeID
+	rec.Timestamp = time.Now().UnixNano()
+	if bb.config.SignFunc != nil {
+		sig, err := bb.config.SignFunc(rec.signData())
+		if err != nil {
+			return
+		}
+		rec.Signature = sig
+	}
+	if data, err := json.Marshal(rec); err == nil {
+		transport.Publish(ModerationTopic, data)
+	}
+}
+
+// decodeModeration 解码治理记录；配置了验签函数时按记录节点验签，不接受未签名的记录
+func (bb *BulletinBoard) decodeModeration(data []byte) (*ModerationRecord, error) {
+	var rec ModerationRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	if rec.MessageID == "" || rec.Actor == "" || rec.Signer == "" {
+		return nil, ErrInvalidMessageID
+	}
+	if bb.config.VerifyFunc != nil && (rec.Signature == "" || !bb.config.VerifyFunc(rec.Signer, rec.signData(), rec.Signature)) {
+		return nil, ErrInvalidSignature
+	}
+	return &rec, nil
+}
+
+// ReceiveModeration 执行其他节点广播的治理记录
+// from 是 GossipSub 验证过的原始发布节点，必须与记录节点一致；警告达到阈值时不在本节点发起提案。
+func (bb *BulletinBoard) ReceiveModeration(data []byte, from string) error {
+	rec, err := bb.decodeModeration(data)
+	if err != nil {
+		return err
+	}
+	if rec.Signer != from {
+		return ErrInvalidSignature
+	}
+	switch rec.Action {
+	case ActionHide:
+		_, _, err = bb.hideMessage(rec.MessageID, rec.Actor, rec.Text)
+	case ActionAppeal:
+		_, err = bb.appealHide(rec.MessageID, rec.Actor, rec.Text)
+	case ActionResolve:
+		_, _, err = bb.resolveAppeal(rec.MessageID, rec.Actor, rec.Overturn, rec.Text)
+	default:
+		err = ErrUnknownModeration
+	}
+	if err != nil {
+		return err
+	}
+	bb.save()
+	return nil
+}
+
+// GetModeration 获取留言的隐藏记录
+func (bb *BulletinBoard) GetModeration(messageID string) (*Moderation, error) {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+
+	mod, ok := bb.moderations[messageID]
+	if !ok {
+		return nil, ErrNotHidden
+	}
+	result := *mod
+	if mod.Appeal != nil {
+		appeal := *mod.Appeal
+		result.Appeal = &appeal
+	}
+	return &result, nil
+}
+
+// PendingAppeals 列出待裁决的申诉，按提交时间排序
+func (bb *BulletinBoard) PendingAppeals() []*Moderation {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+
+	var result []*Moderation
+	for _, mod := range bb.moderations {
+		if mod.Appeal != nil && mod.Appeal.Status == AppealPending {
+			m := *mod
+			appeal := *mod.Appeal
+			m.Appeal = &appeal
+			result = append(result, &m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Appeal.FiledAt.Before(result[j].Appeal.FiledAt)
+	})
+	return result
+}
+
+// GetStrikes 获取节点在各角色下的警告记录
+func (bb *BulletinBoard) GetStrikes(nodeID string) []*StrikeRecord {
+	bb.mu.RLock()
+	defer bb.mu.RUnlock()
+
+	var result []*StrikeRecord
+	for _, role := range []StrikeRole{StrikeAuthor, StrikeModerator} {
+		if rec, ok := bb.strikes[strikeKey(role, nodeID)]; ok {
+			r := *rec
+			r.MessageIDs = append([]string(nil), rec.MessageIDs...)
+			result = append(result, &r)
+		}
+	}
+	return result
+}
+
+func strikeKey(role StrikeRole, nodeID string) string {
+	return string(role) + ":" + nodeID
+}
+
+// addStrikeLocked 记一次警告（需要持有锁）
+// 首次达到阈值时返回该记录，由调用方在释放锁后发起提案。
+func (bb *BulletinBoard) addStrikeLocked(role StrikeRole, nodeID, messageID string) *StrikeRecord {
+	key := strikeKey(role, nodeID)
+	rec, ok := bb.strikes[key]
+	if !ok {
+		rec = &StrikeRecord{NodeID: nodeID, Role: role}
+		bb.strikes[key] = rec
+	}
+	bb.dirty.Mark("strikes", key)
+	rec.Count++
+	rec.MessageIDs = append(rec.MessageIDs, messageID)
+
+	threshold := bb.config.AuthorStrikeThreshold
+	if role == StrikeModerator {
+		threshold = bb.config.ModeratorStrikeThreshold
+	}
+	if threshold <= 0 || rec.Count < threshold || !rec.EscalatedAt.IsZero() {
+		return nil
+	}
+	rec.EscalatedAt = time.Now()
+	return rec
+}
+
+// removeStrikeLocked 撤销一次警告（需要持有锁）
+func (bb *BulletinBoard) removeStrikeLocked(role StrikeRole, nodeID, messageID string) {
+	rec, ok := bb.strikes[strikeKey(role, nodeID)]
+	if !ok {
+		return
+	}
+	for i, id := range rec.MessageIDs {
+		if id == messageID {
+			rec.MessageIDs = append(rec.MessageIDs[:i], rec.MessageIDs[i+1:]...)
+			rec.Count--
+			bb.dirty.Mark("strikes", strikeKey(role, nodeID))
+			return
+		}
+	}
+}
+
+// escalate 为达到阈值的警告记录发起治理提案
+// 未设置提案函数或发起失败时保留 EscalatedAt，ProposalID 为空，便于人工跟进。
+func (bb *BulletinBoard) escalate(rec *StrikeRecord) {
+	if rec == nil {
+		return
+	}
+	bb.mu.RLock()
+	fn := bb.proposalFunc
+	role, nodeID, count := rec.Role, rec.NodeID, rec.Count
+	bb.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+
+	var reason string
+	if role == StrikeModerator {
+		reason = fmt.Sprintf("moderator %s had %d hides overturned on appeal", nodeID, count)
+	} else {
+		reason = fmt.Sprintf("author %s had %d messages hidden by moderators", nodeID, count)
+	}
+	proposalID, err := fn(role, nodeID, reason)
+	if err != nil {
+		return
+	}
+
+	bb.mu.Lock()
+	rec.ProposalID = proposalID
+	bb.dirty.Mark("strikes", strikeKey(role, nodeID))
+	bb.mu.Unlock()
+}
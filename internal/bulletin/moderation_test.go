@@ -0,0 +1,225 @@
+package bulletin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func receiveFrom(t *testing.T, bb *BulletinBoard, id, author string) {
+	t.Helper()
+	msg := &Message{
+		MessageID: id,
+		Author:    author,
+		Topic:     "general",
+		Content:   "content " + id,
+		Timestamp: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		Status:    StatusActive,
+		TTL:       5,
+	}
+	if err := bb.ReceiveMessage(msg, author); err != nil {
+		t.Fatalf("ReceiveMessage failed: %v", err)
+	}
+}
+
+func strikeCount(bb *BulletinBoard, nodeID string, role StrikeRole) int {
+	for _, rec := range bb.GetStrikes(nodeID) {
+		if rec.Role == role {
+			return rec.Count
+		}
+	}
+	return 0
+}
+
+func TestHideAndAppeal(t *testing.T) {
+	bb := createTestBoard(t)
+	receiveFrom(t, bb, "m1", "alice")
+
+	if _, err := bb.HideMessage("m1", "mod-a", "spam"); err != nil {
+		t.Fatalf("HideMessage failed: %v", err)
+	}
+	if _, err := bb.HideMessage("m1", "mod-a", "spam"); err != ErrAlreadyHidden {
+		t.Errorf("expected ErrAlreadyHidden, got %v", err)
+	}
+	msgs, _ := bb.QueryByTopic("general", 10, 0)
+	if len(msgs) != 0 {
+		t.Errorf("hidden message should not be listed, got %d", len(msgs))
+	}
+	if got := strikeCount(bb, "alice", StrikeAuthor); got != 1 {
+		t.Errorf("expected 1 author strike, got %d", got)
+	}
+
+	t.Run("only author can appeal", func(t *testing.T) {
+		if _, err := bb.AppealHide("m1", "mallory", "not spam"); err != ErrNotAuthor {
+			t.Errorf("expected ErrNotAuthor, got %v", err)
+		}
+	})
+
+	if _, err := bb.AppealHide("m1", "alice", "not spam"); err != nil {
+		t.Fatalf("AppealHide failed: %v", err)
+	}
+	if _, err := bb.AppealHide("m1", "alice", "again"); err != ErrAppealExists {
+		t.Errorf("expected ErrAppealExists, got %v", err)
+	}
+	if pending := bb.PendingAppeals(); len(pending) != 1 {
+		t.Errorf("expected 1 pending appeal, got %d", len(pending))
+	}
+
+	t.Run("hiding moderator cannot resolve", func(t *testing.T) {
+		if _, err := bb.ResolveAppeal("m1", "mod-a", true, ""); err != ErrConflictOfInterest {
+			t.Errorf("expected ErrConflictOfInterest, got %v", err)
+		}
+	})
+
+	mod, err := bb.ResolveAppeal("m1", "mod-b", true, "legitimate post")
+	if err != nil {
+		t.Fatalf("ResolveAppeal failed: %v", err)
+	}
+	if mod.Appeal.Status != AppealOverturned {
+		t.Errorf("expected overturned, got %s", mod.Appeal.Status)
+	}
+	if msg, _ := bb.QueryMessage("m1"); msg.Status != StatusActive {
+		t.Errorf("overturned message should be active, got %s", msg.Status)
+	}
+	if got := strikeCount(bb, "alice", StrikeAuthor); got != 0 {
+		t.Errorf("author strike should be withdrawn, got %d", got)
+	}
+	if got := strikeCount(bb, "mod-a", StrikeModerator); got != 1 {
+		t.Errorf("expected 1 moderator strike, got %d", got)
+	}
+	if _, err := bb.ResolveAppeal("m1", "mod-b", false, ""); err != ErrNoPendingAppeal {
+		t.Errorf("expected ErrNoPendingAppeal, got %v", err)
+	}
+}
+
+func TestAppealWindow(t *testing.T) {
+	bb := createTestBoard(t)
+	bb.config.AppealWindow = time.Millisecond
+	receiveFrom(t, bb, "m1", "alice")
+
+	if _, err := bb.HideMessage("m1", "mod-a", "spam"); err != nil {
+		t.Fatalf("HideMessage failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := bb.AppealHide("m1", "alice", "late"); err != ErrAppealWindowClosed {
+		t.Errorf("expected ErrAppealWindowClosed, got %v", err)
+	}
+}
+
+func TestStrikeThresholdProposals(t *testing.T) {
+	bb := createTestBoard(t)
+	bb.config.AuthorStrikeThreshold = 2
+	bb.config.ModeratorStrikeThreshold = 1
+
+	type call struct {
+		role   StrikeRole
+		nodeID string
+	}
+	var calls []call
+	bb.SetProposalFunc(func(role StrikeRole, nodeID, reason string) (string, error) {
+		calls = append(calls, call{role, nodeID})
+		return "proposal-" + nodeID, nil
+	})
+
+	for _, id := range []string{"s1", "s2", "s3"} {
+		receiveFrom(t, bb, id, "spammer")
+		if _, err := bb.HideMessage(id, "mod-a", "spam"); err != nil {
+			t.Fatalf("HideMessage failed: %v", err)
+		}
+	}
+	// 只在首次达到阈值时发起提案
+	if len(calls) != 1 || calls[0] != (call{StrikeAuthor, "spammer"}) {
+		t.Fatalf("expected one author proposal, got %v", calls)
+	}
+	for _, rec := range bb.GetStrikes("spammer") {
+		if rec.ProposalID != "proposal-spammer" {
+			t.Errorf("expected proposal ID to be recorded, got %q", rec.ProposalID)
+		}
+	}
+
+	if _, err := bb.AppealHide("s3", "spammer", "mistake"); err != nil {
+		t.Fatalf("AppealHide failed: %v", err)
+	}
+	if _, err := bb.ResolveAppeal("s3", "mod-b", true, ""); err != nil {
+		t.Fatalf("ResolveAppeal failed: %v", err)
+	}
+	if len(calls) != 2 || calls[1] != (call{StrikeModerator, "mod-a"}) {
+		t.Errorf("expected moderator proposal, got %v", calls)
+	}
+}
+
+func TestModerationPersistence(t *testing.T) {
+	bb := createTestBoard(t)
+	receiveFrom(t, bb, "m1", "alice")
+	if _, err := bb.HideMessage("m1", "mod-a", "spam"); err != nil {
+		t.Fatalf("HideMessage failed: %v", err)
+	}
+
+	reloaded, err := NewBulletinBoard(bb.config)
+	if err != nil {
+		t.Fatalf("NewBulletinBoard failed: %v", err)
+	}
+	if _, err := reloaded.GetModeration("m1"); err != nil {
+		t.Errorf("moderation record should survive reload: %v", err)
+	}
+	if got := strikeCount(reloaded, "alice", StrikeAuthor); got != 1 {
+		t.Errorf("expected strike to survive reload, got %d", got)
+	}
+}
+
+func TestModerationGossip(t *testing.T) {
+	hub := &gossipHub{subs: make(map[string]map[string]*hubSub)}
+	n1 := newGossipBoard(t, hub, "n1")
+	n2 := newGossipBoard(t, hub, "n2")
+	n2.config.AuthorStrikeThreshold = 1
+	proposals := 0
+	n2.SetProposalFunc(func(role StrikeRole, nodeID, reason string) (string, error) {
+		proposals++
+		return "p", nil
+	})
+	receiveFrom(t, n1, "m1", "alice")
+	receiveFrom(t, n2, "m1", "alice")
+
+	if _, err := n1.HideMessage("m1", "mod-a", "spam"); err != nil {
+		t.Fatalf("HideMessage failed: %v", err)
+	}
+	mod, err := n2.GetModeration("m1")
+	if err != nil || mod.Moderator != "mod-a" {
+		t.Fatalf("hide should reach n2 with the real moderator, got %+v, %v", mod, err)
+	}
+	if got := strikeCount(n2, "alice", StrikeAuthor); got != 1 {
+		t.Errorf("expected replicated author strike, got %d", got)
+	}
+	if proposals != 0 {
+		t.Errorf("receiving node should not raise proposals, got %d", proposals)
+	}
+
+	if _, err := n2.AppealHide("m1", "alice", "not spam"); err != nil {
+		t.Fatalf("AppealHide failed: %v", err)
+	}
+	if pending := n1.PendingAppeals(); len(pending) != 1 {
+		t.Fatalf("appeal should reach n1, got %d pending", len(pending))
+	}
+	if _, err := n1.ResolveAppeal("m1", "mod-b", true, "legitimate"); err != nil {
+		t.Fatalf("ResolveAppeal failed: %v", err)
+	}
+	if msg, _ := n2.QueryMessage("m1"); msg == nil || msg.Status == StatusHidden {
+		t.Errorf("overturned hide should restore the message on n2")
+	}
+
+	t.Run("signer must be the origin", func(t *testing.T) {
+		receiveFrom(t, n2, "m2", "bob")
+		rec := &ModerationRecord{Action: ActionHide, MessageID: "m2", Actor: "mod-a", Signer: "n1", Timestamp: 1}
+		rec.Signature, _ = n1.config.SignFunc(rec.signData())
+		data, _ := json.Marshal(rec)
+		if err := n2.ReceiveModeration(data, "mallory"); err != ErrInvalidSignature {
+			t.Errorf("expected ErrInvalidSignature, got %v", err)
+		}
+		rec.Actor = "mod-x"
+		data, _ = json.Marshal(rec)
+		if err := n2.ReceiveModeration(data, "n1"); err != ErrInvalidSignature {
+			t.Errorf("tampered record should be rejected, got %v", err)
+		}
+	})
+}
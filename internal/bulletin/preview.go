@@ -0,0 +1,295 @@
+package bulletin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// 链接预览：留言引用外部链接或附件哈希时，发布方在发布时提取安全的元数据
+// （标题、大小、内容哈希、MIME），随留言签名传播。列表接口直接返回预览，
+// Agent 不必逐个抓取链接就能筛选留言。预览只包含纯文本元数据，不包含正文。
+
+// 预览类型
+const (
+	PreviewKindURL        = "url"
+	PreviewKindAttachment = "attachment"
+)
+
+var (
+	ErrPreviewScheme  = errors.New("only http and https links can be previewed")
+	ErrPreviewBlocked = errors.New("link resolves to a private or local address")
+)
+
+// urlPattern 从留言正文中提取链接
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// titlePattern 提取 HTML 标题
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// maxTitleLength 预览标题的最大字符数
+const maxTitleLength = 200
+
+// LinkPreview 链接或附件的元数据预览
+type LinkPreview struct {
+	Ref         string `json:"ref"`  // 链接或附件哈希
+	Kind        string `json:"kind"` // url 或 attachment
+	Title       string `json:"title,omitempty"`
+	MIME        string `json:"mime,omitempty"`
+	Size        int64  `json:"size,omitempty"`         // 字节数，未知时为 0
+	ContentHash string `json:"content_hash,omitempty"` // 内容的 SHA-256（超过抓取上限时为空）
+	Truncated   bool   `json:"truncated,omitempty"`    // 内容超过抓取上限，只读取了开头
+	Error       string `json:"error,omitempty"`        // 提取失败原因（链接失效、被拒绝等）
+	FetchedAt   int64  `json:"fetched_at"`
+}
+
+// PreviewFunc 为链接或附件哈希提取预览
+type PreviewFunc func(ctx context.Context, ref string) (*LinkPreview, error)
+
+// previewRefs 收集留言引用的链接和附件（去重，最多 max 个）
+func previewRefs(content string, attachments []string, max int) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	add := func(ref string) {
+		ref = strings.TrimRight(ref, ".,;:!?)]}")
+		if ref == "" || seen[ref] || len(refs) >= max {
+			return
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	for _, link := range urlPattern.FindAllString(content, -1) {
+		add(link)
+	}
+	for _, a := range attachments {
+		add(a)
+	}
+	return refs
+}
+
+// extractPreviews 发布时为留言引用的内容提取预览
+// 单个引用提取失败不影响发布，失败原因记录在预览的 Error 中。
+func (bb *BulletinBoard) extractPreviews(content string, attachments []string) []*LinkPreview {
+	fn := bb.config.PreviewFunc
+	if fn == nil || bb.config.MaxPreviews <= 0 {
+		return nil
+	}
+	refs := previewRefs(content, attachments, bb.config.MaxPreviews)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	timeout := bb.config.PreviewTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	previews := make([]*LinkPreview, 0, len(refs))
+	for _, ref := range refs {
+		p, err := fn(ctx, ref)
+		if p == nil {
+			p = &LinkPreview{Ref: ref, Kind: previewKind(ref), FetchedAt: time.Now().Unix()}
+		}
+		if err != nil {
+			p.Error = err.Error()
+		}
+		p.Ref = ref
+		p.Title = sanitizeTitle(p.Title)
+		previews = append(previews, p)
+	}
+	return previews
+}
+
+// previewsDigest 预览列表的摘要，纳入留言签名防止转发节点篡改
+func previewsDigest(previews []*LinkPreview) string {
+	data, _ := json.Marshal(previews)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func previewKind(ref string) string {
+	if strings.Contains(ref, "://") {
+		return PreviewKindURL
+	}
+	return PreviewKindAttachment
+}
+
+// sanitizeTitle 去掉控制字符、合并空白并截断
+func sanitizeTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength]) + "..."
+	}
+	return title
+}
+
+// PreviewerConfig 链接预览抓取配置
+type PreviewerConfig struct {
+	Timeout      time.Duration // 单个链接的抓取超时
+	MaxBytes     int64         // 最多读取的字节数
+	MaxRedirects int           // 最多跟随的重定向次数
+	AllowPrivate bool          // 允许抓取内网和本机地址（仅用于测试）
+	UserAgent    string
+
+	// AttachmentFunc 查询附件哈希对应的本地元数据，未设置或查不到时只记录哈希
+	AttachmentFunc func(hash string) (*LinkPreview, error)
+}
+
+// DefaultPreviewerConfig 返回默认配置
+func DefaultPreviewerConfig() *PreviewerConfig {
+	return &PreviewerConfig{
+		Timeout:      5 * time.Second,
+		MaxBytes:     512 * 1024,
+		MaxRedirects: 3,
+		UserAgent:    "AgentNetwork-Preview/1.0",
+	}
+}
+
+// Previewer 安全抓取链接元数据
+// 只抓取 http/https，拒绝解析到内网和本机的地址（包括重定向后），
+// 读取量有上限，只保留标题、大小、哈希和 MIME。
+type Previewer struct {
+	config *PreviewerConfig
+	client *http.Client
+}
+
+// NewPreviewer 创建链接预览抓取器
+func NewPreviewer(config *PreviewerConfig) *Previewer {
+	if config == nil {
+		config = DefaultPreviewerConfig()
+	}
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivate {
+		// 在建立连接前检查解析后的地址，DNS 重绑定也无法绕过
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrPreviewBlocked
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   config.Timeout,
+		ResponseHeaderTimeout: config.Timeout,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", config.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrPreviewScheme
+			}
+			return nil
+		},
+	}
+	return &Previewer{config: config, client: client}
+}
+
+// Preview 提取链接或附件哈希的预览，可直接用作 BulletinConfig.PreviewFunc
+func (p *Previewer) Preview(ctx context.Context, ref string) (*LinkPreview, error) {
+	preview := &LinkPreview{Ref: ref, Kind: previewKind(ref), FetchedAt: time.Now().Unix()}
+	if preview.Kind == PreviewKindAttachment {
+		preview.ContentHash = ref
+		if p.config.AttachmentFunc == nil {
+			return preview, nil
+		}
+		meta, err := p.config.AttachmentFunc(ref)
+		if err != nil || meta == nil {
+			return preview, err
+		}
+		meta.Ref, meta.Kind, meta.FetchedAt = ref, PreviewKindAttachment, preview.FetchedAt
+		if meta.ContentHash == "" {
+			meta.ContentHash = ref
+		}
+		return meta, nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return preview, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return preview, ErrPreviewScheme
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return preview, err
+	}
+	req.Header.Set("User-Agent", p.config.UserAgent)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrPreviewBlocked) {
+			return preview, ErrPreviewBlocked
+		}
+		return preview, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return preview, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.config.MaxBytes+1))
+	if err != nil {
+		return preview, err
+	}
+	if int64(len(body)) > p.config.MaxBytes {
+		body = body[:p.config.MaxBytes]
+		preview.Truncated = true
+	} else {
+		sum := sha256.Sum256(body)
+		preview.ContentHash = hex.EncodeToString(sum[:])
+		preview.Size = int64(len(body))
+	}
+	if resp.ContentLength > 0 {
+		preview.Size = resp.ContentLength
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		preview.MIME = mediaType
+	} else {
+		preview.MIME, _, _ = strings.Cut(http.DetectContentType(body), ";")
+	}
+	if preview.MIME == "text/html" {
+		if m := titlePattern.FindSubmatch(body); m != nil {
+			preview.Title = sanitizeTitle(html.UnescapeString(string(m[1])))
+		}
+	}
+	return preview, nil
+}
+
+// isPublicIP 判断地址是否可公开访问
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
@@ -0,0 +1,122 @@
+package bulletin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreviewer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html><head><title> Q3 &amp; Q4\n report </title></head><body>...</body></html>")
+		case "/big":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", "4096")
+			w.Write(make([]byte, 4096))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	config := DefaultPreviewerConfig()
+	config.AllowPrivate = true
+	config.MaxBytes = 1024
+	p := NewPreviewer(config)
+
+	page, err := p.Preview(context.Background(), srv.URL+"/report")
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if page.Title != "Q3 & Q4 report" || page.MIME != "text/html" || page.Size == 0 || len(page.ContentHash) != 64 {
+		t.Errorf("unexpected html preview: %+v", page)
+	}
+
+	big, err := p.Preview(context.Background(), srv.URL+"/big")
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if !big.Truncated || big.ContentHash != "" || big.Size != 4096 {
+		t.Errorf("oversized content should be truncated without hash: %+v", big)
+	}
+
+	if _, err := p.Preview(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("expected error for 404")
+	}
+	if _, err := p.Preview(context.Background(), "ftp://example.org/file"); err != ErrPreviewScheme {
+		t.Errorf("expected ErrPreviewScheme, got %v", err)
+	}
+
+	// 默认配置拒绝内网和本机地址
+	strict := NewPreviewer(nil)
+	if _, err := strict.Preview(context.Background(), srv.URL+"/report"); !errors.Is(err, ErrPreviewBlocked) {
+		t.Errorf("expected ErrPreviewBlocked for loopback, got %v", err)
+	}
+
+	attachment, err := p.Preview(context.Background(), "9f86d081884c7d65")
+	if err != nil || attachment.Kind != PreviewKindAttachment || attachment.ContentHash != "9f86d081884c7d65" {
+		t.Errorf("unexpected attachment preview: %+v, %v", attachment, err)
+	}
+}
+
+func TestPublishWithPreviews(t *testing.T) {
+	bb := createTestBoard(t)
+	bb.config.MaxPreviews = 2
+	bb.config.SignFunc = func(data []byte) (string, error) { return string(data), nil }
+	bb.config.PreviewFunc = func(ctx context.Context, ref string) (*LinkPreview, error) {
+		if strings.Contains(ref, "dead") {
+			return nil, errors.New("HTTP 404")
+		}
+		return &LinkPreview{Ref: ref, Kind: previewKind(ref), Title: "Spec\x00 v2", MIME: "text/html"}, nil
+	}
+
+	msg, err := bb.PublishMessageWithOptions("see https://example.org/spec, https://example.org/dead and https://example.org/spec", "general", nil, []string{"abc123"}, "")
+	if err != nil {
+		t.Fatalf("PublishMessageWithOptions failed: %v", err)
+	}
+	if len(msg.Previews) != 2 {
+		t.Fatalf("expected 2 previews (deduplicated, capped), got %d", len(msg.Previews))
+	}
+	if msg.Previews[0].Ref != "https://example.org/spec" || msg.Previews[0].Title != "Spec v2" {
+		t.Errorf("unexpected first preview: %+v", msg.Previews[0])
+	}
+	if msg.Previews[1].Error != "HTTP 404" || msg.Previews[1].Kind != PreviewKindURL {
+		t.Errorf("failed link should be recorded with error: %+v", msg.Previews[1])
+	}
+
+	summaries := bb.GetMessageSummaries("general", 10, 0)
+	if len(summaries) != 1 || len(summaries[0].Previews) != 2 {
+		t.Errorf("summaries should include previews: %+v", summaries)
+	}
+
+	// 预览纳入签名，转发节点篡改预览会导致验签失败
+	receiver := createTestBoard(t)
+	receiver.config.VerifyFunc = func(publicKey string, data []byte, signature string) bool {
+		return string(data) == signature
+	}
+	forged := *msg
+	forged.Previews = []*LinkPreview{{Ref: "https://example.org/spec", Kind: PreviewKindURL, Title: "Totally safe"}}
+	if err := receiver.ReceiveMessage(&forged, "relay"); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for tampered previews, got %v", err)
+	}
+	if err := receiver.ReceiveMessage(msg, "relay"); err != nil {
+		t.Errorf("ReceiveMessage failed: %v", err)
+	}
+
+	// 加密留言不提取预览
+	bb.config.WrapKeyFunc = func(recipient string, key []byte) ([]byte, error) { return key, nil }
+	sealed, err := bb.PublishEncrypted("private https://example.org/spec", "general", []string{"peer"}, nil)
+	if err != nil {
+		t.Fatalf("PublishEncrypted failed: %v", err)
+	}
+	if len(sealed.Previews) != 0 {
+		t.Error("encrypted messages must not carry previews")
+	}
+}
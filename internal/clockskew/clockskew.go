@@ -0,0 +1,188 @@
+// Package clockskew 过期判断的时钟偏差容忍窗口与偏差统计
+//
+// 各节点时钟存在偏差，同一条消息在不同节点上过期的时刻略有先后：发送方认为仍然
+// 有效的消息，接收方可能已判定过期而拒收，查询时出现“消息不存在”的冲突。
+// Tracker 在接收、转发、读取等跨节点的边界上判断过期时额外放宽一个容忍窗口
+// （Grace），只有超出窗口的消息才视为过期。
+//
+// 每次在边界上观察到的偏差（消息晚于过期时间到达的时长、发送方时间戳领先本地
+// 时钟的时长）都会作为样本记录下来，Stats 给出分位数和建议的窗口大小，
+// 用于调整默认值。超过 MaxSample 的偏差视为重放的陈旧消息而不是时钟偏差，
+// 只计数不采样。
+package clockskew
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config 容忍窗口配置
+type Config struct {
+	Grace      time.Duration // 过期后仍接受的时长
+	MaxSample  time.Duration // 计入偏差样本的上限
+	SampleSize int           // 保留的最近样本数
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Grace:      2 * time.Minute,
+		MaxSample:  30 * time.Minute,
+		SampleSize: 1024,
+	}
+}
+
+// Stats 偏差统计（JSON 中时长以毫秒表示）
+type Stats struct {
+	Grace          time.Duration
+	Checks         int64 // 边界上的过期判断次数
+	WithinGrace    int64 // 按本地时钟已过期、因容忍窗口而接受的次数
+	Rejected       int64 // 超出窗口被判定过期的次数
+	FutureStamped  int64 // 发送方时间戳领先本地时钟的次数
+	Stale          int64 // 偏差超过采样上限的次数
+	Samples        int
+	P50            time.Duration
+	P95            time.Duration
+	P99            time.Duration
+	Max            time.Duration
+	SuggestedGrace time.Duration // 按 P99 向上取整到秒，无样本时为当前窗口
+}
+
+// MarshalJSON 时长字段输出为毫秒
+func (s Stats) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) int64 { return d.Milliseconds() }
+	return json.Marshal(map[string]interface{}{
+		"grace_ms":           ms(s.Grace),
+		"checks":             s.Checks,
+		"within_grace":       s.WithinGrace,
+		"rejected":           s.Rejected,
+		"future_stamped":     s.FutureStamped,
+		"stale":              s.Stale,
+		"samples":            s.Samples,
+		"p50_ms":             ms(s.P50),
+		"p95_ms":             ms(s.P95),
+		"p99_ms":             ms(s.P99),
+		"max_ms":             ms(s.Max),
+		"suggested_grace_ms": ms(s.SuggestedGrace),
+	})
+}
+
+// Tracker 带容忍窗口的过期判断与偏差统计，可并发使用
+type Tracker struct {
+	mu      sync.Mutex
+	config  Config
+	samples []time.Duration // 环形缓冲
+	next    int
+	stats   Stats
+}
+
+// NewTracker 创建 Tracker，config 为 nil 时使用默认配置
+func NewTracker(config *Config) *Tracker {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	cfg := *config
+	if cfg.Grace < 0 {
+		cfg.Grace = 0
+	}
+	if cfg.SampleSize <= 0 {
+		cfg.SampleSize = DefaultConfig().SampleSize
+	}
+	return &Tracker{
+		config:  cfg,
+		samples: make([]time.Duration, 0, cfg.SampleSize),
+	}
+}
+
+// Grace 返回容忍窗口
+func (t *Tracker) Grace() time.Duration {
+	return t.config.Grace
+}
+
+// Expired 在跨节点边界上判断消息是否过期（超出容忍窗口），并记录偏差
+func (t *Tracker) Expired(expiresAt, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.Checks++
+	late := now.Sub(expiresAt)
+	if late <= 0 {
+		return false
+	}
+	t.record(late)
+	if late <= t.config.Grace {
+		t.stats.WithinGrace++
+		return false
+	}
+	t.stats.Rejected++
+	return true
+}
+
+// ObserveTimestamp 记录发送方时间戳领先本地时钟的偏差
+func (t *Tracker) ObserveTimestamp(sent, now time.Time) {
+	ahead := sent.Sub(now)
+	if ahead <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.FutureStamped++
+	t.record(ahead)
+}
+
+// Retained 本地清理时判断消息是否仍应保留（过期时间加容忍窗口之内）
+// 保留到窗口结束，时钟较慢的节点在此期间查询或拉取时仍能取到。
+func (t *Tracker) Retained(expiresAt, now time.Time) bool {
+	return !now.After(expiresAt.Add(t.config.Grace))
+}
+
+// Stats 返回偏差统计
+func (t *Tracker) Stats() Stats {
+	t.mu.Lock()
+	stats := t.stats
+	sorted := append([]time.Duration(nil), t.samples...)
+	t.mu.Unlock()
+
+	stats.Grace = t.config.Grace
+	stats.Samples = len(sorted)
+	stats.SuggestedGrace = t.config.Grace
+	if len(sorted) == 0 {
+		return stats
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.P50 = percentile(sorted, 0.50)
+	stats.P95 = percentile(sorted, 0.95)
+	stats.P99 = percentile(sorted, 0.99)
+	stats.Max = sorted[len(sorted)-1]
+	stats.SuggestedGrace = ((stats.P99 + time.Second - 1) / time.Second) * time.Second
+	return stats
+}
+
+// record 记录一个偏差样本（调用方持有锁）
+func (t *Tracker) record(d time.Duration) {
+	if t.config.MaxSample > 0 && d > t.config.MaxSample {
+		t.stats.Stale++
+		return
+	}
+	if len(t.samples) < t.config.SampleSize {
+		t.samples = append(t.samples, d)
+		return
+	}
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % t.config.SampleSize
+}
+
+// percentile 取已排序样本的分位数（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(float64(len(sorted))*p)) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,93 @@
+package clockskew
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExpiredWithinGrace(t *testing.T) {
+	tr := NewTracker(&Config{Grace: time.Minute, MaxSample: 10 * time.Minute, SampleSize: 16})
+	now := time.Now()
+
+	if tr.Expired(now.Add(time.Second), now) {
+		t.Error("unexpired message reported as expired")
+	}
+	if tr.Expired(now.Add(-30*time.Second), now) {
+		t.Error("message within grace reported as expired")
+	}
+	if !tr.Expired(now.Add(-2*time.Minute), now) {
+		t.Error("message beyond grace should be expired")
+	}
+	// 陈旧消息照常拒绝，但不计入偏差样本
+	if !tr.Expired(now.Add(-time.Hour), now) {
+		t.Error("stale message should be expired")
+	}
+
+	stats := tr.Stats()
+	if stats.Checks != 4 || stats.WithinGrace != 1 || stats.Rejected != 2 || stats.Stale != 1 {
+		t.Errorf("unexpected counters: %+v", stats)
+	}
+	if stats.Samples != 2 || stats.Max != 2*time.Minute {
+		t.Errorf("unexpected samples: %+v", stats)
+	}
+}
+
+func TestRetained(t *testing.T) {
+	tr := NewTracker(&Config{Grace: time.Minute})
+	now := time.Now()
+	if !tr.Retained(now.Add(-30*time.Second), now) {
+		t.Error("message within grace should be retained")
+	}
+	if tr.Retained(now.Add(-2*time.Minute), now) {
+		t.Error("message beyond grace should not be retained")
+	}
+}
+
+func TestSuggestedGrace(t *testing.T) {
+	tr := NewTracker(&Config{Grace: 2 * time.Minute, MaxSample: time.Hour, SampleSize: 100})
+	if got := tr.Stats().SuggestedGrace; got != 2*time.Minute {
+		t.Errorf("suggested grace without samples = %v, want current grace", got)
+	}
+
+	now := time.Now()
+	for i := 1; i <= 100; i++ {
+		tr.ObserveTimestamp(now.Add(time.Duration(i)*100*time.Millisecond), now)
+	}
+	tr.ObserveTimestamp(now.Add(-time.Second), now) // 时间戳落后不是偏差
+
+	stats := tr.Stats()
+	if stats.FutureStamped != 100 || stats.Samples != 100 {
+		t.Errorf("unexpected counters: %+v", stats)
+	}
+	if stats.P50 != 5*time.Second || stats.P99 != 9900*time.Millisecond {
+		t.Errorf("p50 = %v, p99 = %v", stats.P50, stats.P99)
+	}
+	if stats.SuggestedGrace != 10*time.Second {
+		t.Errorf("suggested grace = %v, want 10s", stats.SuggestedGrace)
+	}
+
+	// 样本数达到上限后覆盖最旧的样本
+	for i := 0; i < 100; i++ {
+		tr.ObserveTimestamp(now.Add(time.Second), now)
+	}
+	if stats := tr.Stats(); stats.Samples != 100 || stats.Max != time.Second {
+		t.Errorf("ring buffer not rotated: %+v", stats)
+	}
+}
+
+func TestStatsJSON(t *testing.T) {
+	tr := NewTracker(&Config{Grace: 90 * time.Second})
+	now := time.Now()
+	tr.Expired(now.Add(-1500*time.Millisecond), now)
+
+	data, err := json.Marshal(tr.Stats())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var m map[string]float64
+	json.Unmarshal(data, &m)
+	if m["grace_ms"] != 90000 || m["max_ms"] != 1500 || m["suggested_grace_ms"] != 2000 || m["within_grace"] != 1 {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+}
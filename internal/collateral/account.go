@@ -0,0 +1,395 @@
+package collateral
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 抵押账户
+//
+// 每个节点按用途（如 supernode_auditor、guarantee）各有一个抵押账户，余额随存入和提取变化。
+// 锁定部分（如超级节点候选抵押）不能提取；罚没按余额比例扣除，优先从锁定部分扣，
+// 每次罚没都记录原因和证据，可按节点和用途查询。设置代币账本后，存入在账本上锁定代币，
+// 提取解锁，罚没从锁定代币中销毁。
+
+var (
+	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrEvidenceRequired  = errors.New("slash evidence is required")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// Funds 代币账本
+type Funds interface {
+	LockTokens(nodeID string, amount float64) error
+	UnlockTokens(nodeID string, amount float64) error
+	SlashTokens(nodeID string, amount float64) error // 从锁定代币中销毁
+}
+
+// Account 节点某一用途的抵押账户
+type Account struct {
+	NodeID     string    `json:"node_id"`
+	Purpose    string    `json:"purpose"`
+	Balance    float64   `json:"balance"`               // 余额（含锁定部分）
+	Locked     float64   `json:"locked"`                // 锁定金额
+	LockReason string    `json:"lock_reason,omitempty"` // 最近一次锁定的原因
+	Slashed    float64   `json:"slashed"`               // 累计罚没
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Available 可提取金额
+func (a *Account) Available() float64 {
+	return a.Balance - a.Locked
+}
+
+// Status 账户状态：有锁定为 locked，有余额为 active，余额被罚没清空为 slashed，全部提取为 returned
+func (a *Account) Status() string {
+	switch {
+	case a.Locked > 0:
+		return CollateralStatusLocked
+	case a.Balance > 0:
+		return CollateralStatusActive
+	case a.Slashed > 0:
+		return CollateralStatusSlashed
+	}
+	return CollateralStatusReturned
+}
+
+// SetFunds 设置代币账本，之后的存入会在账本上锁定
+func (cm *CollateralManager) SetFunds(funds Funds) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.funds = funds
+}
+
+// SetDataDir 设置数据目录并加载已保存的抵押记录，之后每次变更都会持久化
+func (cm *CollateralManager) SetDataDir(dir string) error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data dir: %w", err)
+	}
+	cm.dataDir = dir
+	return cm.loadLocked()
+}
+
+// Deposit 向抵押账户存入，账户不存在时创建
+func (cm *CollateralManager) Deposit(nodeID, purpose string, amount float64) (*Account, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if nodeID == "" || purpose == "" {
+		return nil, errors.New("node ID and purpose are required")
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.funds != nil {
+		if err := cm.funds.LockTokens(nodeID, amount); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+		}
+	}
+
+	now := time.Now()
+	key := makeNodePurposeKey(nodeID, purpose)
+	account, ok := cm.accounts[key]
+	if !ok {
+		account = &Account{NodeID: nodeID, Purpose: purpose, CreatedAt: now}
+		cm.accounts[key] = account
+	}
+	account.Balance += amount
+	account.UpdatedAt = now
+
+	cm.saveLocked()
+	return copyAccount(account), nil
+}
+
+// Withdraw 从抵押账户提取未锁定的部分
+func (cm *CollateralManager) Withdraw(nodeID, purpose string, amount float64) (*Account, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	account, ok := cm.accounts[makeNodePurposeKey(nodeID, purpose)]
+	if !ok {
+		return nil, ErrCollateralNotFound
+	}
+	if amount > account.Available() {
+		if amount <= account.Balance {
+			return nil, fmt.Errorf("%w: %.2f of %.2f is locked", ErrCollateralLocked, account.Locked, account.Balance)
+		}
+		return nil, fmt.Errorf("%w: available %.2f", ErrInsufficientAmount, account.Available())
+	}
+
+	if cm.funds != nil {
+		if err := cm.funds.UnlockTokens(nodeID, amount); err != nil {
+			return nil, err
+		}
+	}
+	account.Balance -= amount
+	account.UpdatedAt = time.Now()
+
+	cm.saveLocked()
+	return copyAccount(account), nil
+}
+
+// Lock 锁定抵押账户中的金额
+func (cm *CollateralManager) Lock(nodeID, purpose string, amount float64, reason string) (*Account, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	account, ok := cm.accounts[makeNodePurposeKey(nodeID, purpose)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s collateral for %s", ErrCollateralNotFound, purpose, nodeID)
+	}
+	if amount > account.Available() {
+		return nil, fmt.Errorf("%w: available %.2f, need %.2f", ErrInsufficientAmount, account.Available(), amount)
+	}
+	account.Locked += amount
+	account.LockReason = reason
+	account.UpdatedAt = time.Now()
+
+	cm.saveLocked()
+	return copyAccount(account), nil
+}
+
+// Unlock 解锁抵押账户中的金额，超过锁定金额时全部解锁（罚没可能已扣减锁定部分）
+func (cm *CollateralManager) Unlock(nodeID, purpose string, amount float64) (*Account, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	account, ok := cm.accounts[makeNodePurposeKey(nodeID, purpose)]
+	if !ok {
+		return nil, ErrCollateralNotFound
+	}
+	if amount <= 0 || amount > account.Locked {
+		amount = account.Locked
+	}
+	account.Locked -= amount
+	if account.Locked == 0 {
+		account.LockReason = ""
+	}
+	account.UpdatedAt = time.Now()
+
+	cm.saveLocked()
+	return copyAccount(account), nil
+}
+
+// GetAccount 获取抵押账户
+func (cm *CollateralManager) GetAccount(nodeID, purpose string) (*Account, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	account, ok := cm.accounts[makeNodePurposeKey(nodeID, purpose)]
+	if !ok {
+		return nil, ErrCollateralNotFound
+	}
+	return copyAccount(account), nil
+}
+
+// ListAccounts 列出抵押账户（nodeID 为空时列出全部），按节点和用途排序
+func (cm *CollateralManager) ListAccounts(nodeID string) []*Account {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	result := make([]*Account, 0, len(cm.accounts))
+	for _, account := range cm.accounts {
+		if nodeID == "" || account.NodeID == nodeID {
+			result = append(result, copyAccount(account))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].NodeID != result[j].NodeID {
+			return result[i].NodeID < result[j].NodeID
+		}
+		return result[i].Purpose < result[j].Purpose
+	})
+	return result
+}
+
+// SlashAccount 按余额比例罚没抵押账户，必须附带证据
+// ratio <= 0 时使用默认比例 SlashRatio，超过 1 时按 1 计。
+func (cm *CollateralManager) SlashAccount(nodeID, purpose, reason string, evidence []string, ratio float64) (*SlashEvent, error) {
+	if len(evidence) == 0 {
+		return nil, ErrEvidenceRequired
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	account, ok := cm.accounts[makeNodePurposeKey(nodeID, purpose)]
+	if !ok {
+		return nil, ErrCollateralNotFound
+	}
+	if ratio <= 0 {
+		ratio = SlashRatio
+	}
+	if ratio > 1.0 {
+		ratio = 1.0
+	}
+	amount := account.Balance * ratio
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: %s collateral of %s is empty", ErrInsufficientAmount, purpose, nodeID)
+	}
+
+	if cm.funds != nil {
+		// 罚没优先扣锁定部分；账本上整个账户余额都是锁定的
+		if err := cm.funds.SlashTokens(nodeID, amount); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	account.Balance -= amount
+	account.Slashed += amount
+	if account.Locked > amount {
+		account.Locked -= amount
+	} else {
+		account.Locked = 0
+		account.LockReason = ""
+	}
+	account.UpdatedAt = now
+
+	cm.slashCounter++
+	event := &SlashEvent{
+		ID:        fmt.Sprintf("slash_%d_%d", now.UnixNano(), cm.slashCounter),
+		Owner:     nodeID,
+		Purpose:   purpose,
+		Ratio:     ratio,
+		Amount:    amount,
+		Remaining: account.Balance,
+		Reason:    reason,
+		Evidence:  append([]string(nil), evidence...),
+		Timestamp: now,
+	}
+	cm.slashHistory[nodeID] = append(cm.slashHistory[nodeID], event)
+	cm.totalSlashed[nodeID] += amount
+
+	cm.saveLocked()
+	copied := *event
+	return &copied, nil
+}
+
+// QuerySlashHistory 查询罚没记录，最新的在前；nodeID、purpose 为空时不过滤，limit <= 0 时返回全部
+func (cm *CollateralManager) QuerySlashHistory(nodeID, purpose string, limit int) []*SlashEvent {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var result []*SlashEvent
+	for owner, events := range cm.slashHistory {
+		if nodeID != "" && owner != nodeID {
+			continue
+		}
+		for _, e := range events {
+			if purpose != "" && e.Purpose != purpose {
+				continue
+			}
+			copied := *e
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp.After(result[j].Timestamp) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+func copyAccount(a *Account) *Account {
+	copied := *a
+	return &copied
+}
+
+// === 持久化 ===
+
+type collateralData struct {
+	Collaterals  map[string]*Collateral   `json:"collaterals"`
+	Accounts     map[string]*Account      `json:"accounts"`
+	SlashHistory map[string][]*SlashEvent `json:"slash_history"`
+}
+
+// Version 返回抵押记录的版本号，每次写入后递增
+func (cm *CollateralManager) Version() uint64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.version
+}
+
+// saveLocked 保存抵押记录并递增版本号（调用方持有锁）
+func (cm *CollateralManager) saveLocked() {
+	cm.version++
+	if cm.dataDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(&collateralData{
+		Collaterals:  cm.collaterals,
+		Accounts:     cm.accounts,
+		SlashHistory: cm.slashHistory,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(cm.dataDir, "collateral.json"), data, 0644)
+}
+
+// loadLocked 加载抵押记录并重建索引（调用方持有锁）
+func (cm *CollateralManager) loadLocked() error {
+	raw, err := os.ReadFile(filepath.Join(cm.dataDir, "collateral.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+
+	var data collateralData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	if data.Collaterals != nil {
+		cm.collaterals = data.Collaterals
+	}
+	if data.Accounts != nil {
+		cm.accounts = data.Accounts
+	}
+	if data.SlashHistory != nil {
+		cm.slashHistory = data.SlashHistory
+	}
+
+	cm.byOwner = make(map[string][]string)
+	cm.byNodePurpose = make(map[string]string)
+	ids := make([]string, 0, len(cm.collaterals))
+	for id := range cm.collaterals {
+		ids = append(ids, id)
+	}
+	// 同一节点同一用途保留最后创建的抵押物映射
+	sort.Slice(ids, func(i, j int) bool { return cm.collaterals[ids[i]].CreatedAt.Before(cm.collaterals[ids[j]].CreatedAt) })
+	for _, id := range ids {
+		c := cm.collaterals[id]
+		cm.byOwner[c.Owner] = append(cm.byOwner[c.Owner], id)
+		cm.byNodePurpose[makeNodePurposeKey(c.Owner, c.Purpose)] = id
+	}
+	cm.idCounter = int64(len(cm.collaterals))
+
+	cm.totalSlashed = make(map[string]float64)
+	for owner, events := range cm.slashHistory {
+		for _, e := range events {
+			cm.totalSlashed[owner] += e.Amount
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package collateral
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeFunds struct {
+	locked map[string]float64
+	burned map[string]float64
+}
+
+func newFakeFunds() *fakeFunds {
+	return &fakeFunds{locked: make(map[string]float64), burned: make(map[string]float64)}
+}
+
+func (f *fakeFunds) LockTokens(nodeID string, amount float64) error {
+	f.locked[nodeID] += amount
+	return nil
+}
+
+func (f *fakeFunds) UnlockTokens(nodeID string, amount float64) error {
+	f.locked[nodeID] -= amount
+	return nil
+}
+
+func (f *fakeFunds) SlashTokens(nodeID string, amount float64) error {
+	f.locked[nodeID] -= amount
+	f.burned[nodeID] += amount
+	return nil
+}
+
+func TestAccountDepositWithdrawLock(t *testing.T) {
+	cm := NewCollateralManager()
+	funds := newFakeFunds()
+	cm.SetFunds(funds)
+
+	if _, err := cm.Deposit("nodeA", "supernode_auditor", 0); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount, got %v", err)
+	}
+	cm.Deposit("nodeA", "supernode_auditor", 100)
+	acc, err := cm.Deposit("nodeA", "supernode_auditor", 50)
+	if err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	if acc.Balance != 150 || funds.locked["nodeA"] != 150 {
+		t.Errorf("balance = %.2f, ledger locked = %.2f", acc.Balance, funds.locked["nodeA"])
+	}
+
+	// 不同用途是独立账户
+	cm.Deposit("nodeA", "guarantee", 10)
+	if list := cm.ListAccounts("nodeA"); len(list) != 2 || list[0].Purpose != "guarantee" {
+		t.Errorf("ListAccounts = %+v", list)
+	}
+
+	if _, err := cm.Lock("nodeA", "supernode_auditor", 120, "candidacy"); err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := cm.Withdraw("nodeA", "supernode_auditor", 50); !errors.Is(err, ErrCollateralLocked) {
+		t.Errorf("expected ErrCollateralLocked, got %v", err)
+	}
+	if _, err := cm.Withdraw("nodeA", "supernode_auditor", 200); !errors.Is(err, ErrInsufficientAmount) {
+		t.Errorf("expected ErrInsufficientAmount, got %v", err)
+	}
+	acc, err = cm.Withdraw("nodeA", "supernode_auditor", 30)
+	if err != nil {
+		t.Fatalf("Withdraw failed: %v", err)
+	}
+	if acc.Balance != 120 || acc.Available() != 0 || funds.locked["nodeA"] != 130 {
+		t.Errorf("after withdraw: %+v, ledger locked = %.2f", acc, funds.locked["nodeA"])
+	}
+
+	acc, _ = cm.Unlock("nodeA", "supernode_auditor", 0)
+	if acc.Locked != 0 || acc.LockReason != "" {
+		t.Errorf("after unlock: %+v", acc)
+	}
+}
+
+func TestSlashAccount(t *testing.T) {
+	cm := NewCollateralManager()
+	funds := newFakeFunds()
+	cm.SetFunds(funds)
+	cm.Deposit("nodeA", "supernode_auditor", 100)
+	cm.Lock("nodeA", "supernode_auditor", 40, "candidacy")
+
+	if _, err := cm.SlashAccount("nodeA", "supernode_auditor", "deviation", nil, 0.1); !errors.Is(err, ErrEvidenceRequired) {
+		t.Errorf("expected ErrEvidenceRequired, got %v", err)
+	}
+
+	// 审计偏离走 SlashByNodePurpose，命中抵押账户
+	event, err := cm.SlashByNodePurpose("nodeA", "supernode_auditor", "audit_deviation:severe", []string{"audit_id:a1"}, 0.3)
+	if err != nil {
+		t.Fatalf("SlashByNodePurpose failed: %v", err)
+	}
+	if event.Amount != 30 || event.Remaining != 70 || event.Purpose != "supernode_auditor" || event.ID == "" {
+		t.Errorf("event = %+v", event)
+	}
+	acc, _ := cm.GetAccount("nodeA", "supernode_auditor")
+	if acc.Balance != 70 || acc.Locked != 10 || acc.Slashed != 30 || funds.burned["nodeA"] != 30 {
+		t.Errorf("account = %+v, burned = %.2f", acc, funds.burned["nodeA"])
+	}
+
+	// 可以多次罚没，按当前余额计算
+	cm.SlashAccount("nodeA", "supernode_auditor", "second", []string{"e2"}, 0.5)
+	acc, _ = cm.GetAccount("nodeA", "supernode_auditor")
+	if acc.Balance != 35 || acc.Locked != 0 || cm.GetTotalSlashed("nodeA") != 65 {
+		t.Errorf("after second slash: %+v, total = %.2f", acc, cm.GetTotalSlashed("nodeA"))
+	}
+
+	history := cm.QuerySlashHistory("nodeA", "", 0)
+	if len(history) != 2 || history[0].Reason != "second" {
+		t.Errorf("history = %+v", history)
+	}
+	if got := cm.QuerySlashHistory("", "guarantee", 0); len(got) != 0 {
+		t.Errorf("filtered history = %+v", got)
+	}
+	if got := cm.QuerySlashHistory("", "", 1); len(got) != 1 {
+		t.Errorf("limited history len = %d", len(got))
+	}
+}
+
+func TestCollateralPersistence(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewCollateralManager()
+	if err := cm.SetDataDir(dir); err != nil {
+		t.Fatalf("SetDataDir failed: %v", err)
+	}
+	cm.Deposit("nodeA", "supernode_auditor", 100)
+	cm.Lock("nodeA", "supernode_auditor", 50, "candidacy")
+	cm.SlashAccount("nodeA", "supernode_auditor", "deviation", []string{"e1"}, 0.2)
+	c, _ := cm.CreateCollateral("nodeB", CollateralTypeToken, "guarantee", 10, 0)
+
+	reloaded := NewCollateralManager()
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	acc, err := reloaded.GetAccount("nodeA", "supernode_auditor")
+	if err != nil || acc.Balance != 80 || acc.Locked != 30 {
+		t.Errorf("reloaded account = %+v, err = %v", acc, err)
+	}
+	if reloaded.GetTotalSlashed("nodeA") != 20 || len(reloaded.GetSlashHistory("nodeA")) != 1 {
+		t.Errorf("reloaded slash history lost")
+	}
+	if got, err := reloaded.GetCollateralByNodePurpose("nodeB", "guarantee"); err != nil || got.ID != c.ID {
+		t.Errorf("reloaded collateral = %+v, err = %v", got, err)
+	}
+}
@@ -85,6 +85,7 @@ type CollateralProof struct {
 
 // SlashEvent 惩罚事件
 type SlashEvent struct {
+	ID           string    `json:"id,omitempty"`            // 罚没记录ID（抵押账户）
 	CollateralID string    `json:"collateral_id"` // 抵押物ID
 	Owner        string    `json:"owner"`         // 被惩罚者
 	Amount       float64   `json:"amount"`        // 惩罚金额
@@ -92,6 +93,11 @@ type SlashEvent struct {
 	Evidence     []string  `json:"evidence"`      // 证据
 	Beneficiary  string    `json:"beneficiary"`   // 受益人
 	Timestamp    time.Time `json:"timestamp"`     // 时间
+
+	// 抵押账户罚没：用途、比例和罚没后余额
+	Purpose   string  `json:"purpose,omitempty"`
+	Ratio     float64 `json:"ratio,omitempty"`
+	Remaining float64 `json:"remaining,omitempty"`
 }
 
 // CollateralManager 抵押物管理器
@@ -102,6 +108,11 @@ type CollateralManager struct {
 	slashHistory  map[string][]*SlashEvent   // owner -> []SlashEvent
 	totalSlashed  map[string]float64         // owner -> total slashed amount
 	idCounter     int64                       // ID计数器
+	accounts      map[string]*Account        // NodeID+Purpose -> 抵押账户
+	slashCounter  int64
+	funds         Funds  // 代币账本，未设置时只在抵押账户内记账
+	dataDir       string // 为空时不持久化
+	version       uint64 // 修改计数（快照读用）
 	mu            sync.RWMutex
 }
 
@@ -114,6 +125,7 @@ func NewCollateralManager() *CollateralManager {
 		slashHistory:  make(map[string][]*SlashEvent),
 		totalSlashed:  make(map[string]float64),
 		idCounter:     0,
+		accounts:      make(map[string]*Account),
 	}
 }
 
@@ -149,6 +161,7 @@ func (cm *CollateralManager) CreateCollateral(owner, collateralType, purpose str
 	// Task44: 建立NodeID+Purpose到CollateralID映射
 	key := makeNodePurposeKey(owner, purpose)
 	cm.byNodePurpose[key] = collateral.ID
+	cm.saveLocked()
 
 	return collateral, nil
 }
@@ -168,6 +181,7 @@ func (cm *CollateralManager) ActivateCollateral(collateralID string) error {
 	}
 
 	collateral.Status = CollateralStatusActive
+	cm.saveLocked()
 	return nil
 }
 
@@ -190,6 +204,7 @@ func (cm *CollateralManager) LockCollateral(collateralID, beneficiary, reason st
 	collateral.LockedAt = &now
 	collateral.Beneficiary = beneficiary
 	collateral.Metadata["lock_reason"] = reason
+	cm.saveLocked()
 
 	return nil
 }
@@ -239,6 +254,7 @@ func (cm *CollateralManager) SlashCollateral(collateralID, reason string, eviden
 
 	cm.slashHistory[collateral.Owner] = append(cm.slashHistory[collateral.Owner], event)
 	cm.totalSlashed[collateral.Owner] += slashAmount
+	cm.saveLocked()
 
 	return event, nil
 }
@@ -264,6 +280,7 @@ func (cm *CollateralManager) ReturnCollateral(collateralID string) error {
 	now := time.Now()
 	collateral.Status = CollateralStatusReturned
 	collateral.ReturnedAt = &now
+	cm.saveLocked()
 
 	return nil
 }
@@ -413,6 +430,9 @@ func (cm *CollateralManager) ExpireCollaterals() int {
 			count++
 		}
 	}
+	if count > 0 {
+		cm.saveLocked()
+	}
 	return count
 }
 
@@ -497,7 +517,11 @@ func (cm *CollateralManager) GetCollateralByNodePurpose(nodeID, purpose string)
 }
 
 // SlashByNodePurpose Task44: 根据节点ID和用途惩罚抵押物（审计偏离闭环专用）
+// 节点有该用途的抵押账户时罚没账户余额，否则罚没按用途登记的单笔抵押物。
 func (cm *CollateralManager) SlashByNodePurpose(nodeID, purpose, reason string, evidence []string, slashRatio float64) (*SlashEvent, error) {
+	if _, err := cm.GetAccount(nodeID, purpose); err == nil {
+		return cm.SlashAccount(nodeID, purpose, reason, evidence, slashRatio)
+	}
 	c, err := cm.GetCollateralByNodePurpose(nodeID, purpose)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,316 @@
+// Package compat 协议版本回退表
+//
+// 协议升级后，未升级的节点只认识旧的协议ID（或 pubsub 主题），直接切换会让新旧节点
+// 之间的流全部失败。回退表为每个协议族登记当前版本和仍兼容的旧版本：发起流时按新到旧
+// 的顺序提供全部版本由 multistream 协商，接收方同时为旧版本注册处理器（兼容模式）。
+// 每个旧版本有弃用截止时间，过期后不再提供也不再接受。
+//
+// 按版本统计收发的流和消息以及最近使用旧版本的节点数，运营者据此判断网络中还剩多少
+// 旧版本流量，决定何时关闭兼容模式。
+package compat
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// Direction 流量方向
+type Direction string
+
+const (
+	Inbound  Direction = "inbound"  // 对方发起的流或收到的消息
+	Outbound Direction = "outbound" // 本节点发起的流或发布的消息
+)
+
+// DefaultPeerWindow 统计旧版本节点数的时间窗口
+const DefaultPeerWindow = 24 * time.Hour
+
+// maxTrackedPeers 每个版本记录的节点数上限，超出后新节点只计流量不计节点数
+const maxTrackedPeers = 10000
+
+// Legacy 兼容的旧版本
+type Legacy struct {
+	ID     string    // 协议ID 或主题前缀
+	Sunset time.Time // 弃用截止时间，零值表示不设截止
+}
+
+// Family 协议族：当前版本和按新到旧排列的旧版本
+type Family struct {
+	Name    string // 协议族名称，用作统计指标前缀（如 mailbox_key）
+	Current string
+	Legacy  []Legacy
+}
+
+// VersionStatus 单个版本的使用情况
+type VersionStatus struct {
+	ID       string     `json:"id"`
+	Legacy   bool       `json:"legacy"`
+	Sunset   *time.Time `json:"sunset,omitempty"`
+	Active   bool       `json:"active"` // 仍在提供（当前版本或未过弃用截止时间）
+	Inbound  int64      `json:"inbound"`
+	Outbound int64      `json:"outbound"`
+	Refused  int64      `json:"refused"` // 过了弃用截止时间被拒绝的次数
+	Peers    int        `json:"peers"`   // 窗口内使用该版本的节点数
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// FamilyStatus 协议族的使用情况，LegacyShare 为旧版本流量占全部流量的比例
+type FamilyStatus struct {
+	Name        string          `json:"name"`
+	Versions    []VersionStatus `json:"versions"`
+	LegacyShare float64         `json:"legacy_share"`
+}
+
+type traffic struct {
+	inbound  int64
+	outbound int64
+	refused  int64
+	lastSeen time.Time
+	peers    map[string]time.Time // 节点ID -> 最近使用时间
+}
+
+type family struct {
+	Family
+	traffic map[string]*traffic // 版本ID -> 流量
+}
+
+// Table 协议版本回退表
+type Table struct {
+	mu         sync.Mutex
+	families   map[string]*family
+	order      []string
+	peerWindow time.Duration
+	now        func() time.Time
+}
+
+// NewTable 创建回退表
+func NewTable() *Table {
+	return &Table{
+		families:   make(map[string]*family),
+		peerWindow: DefaultPeerWindow,
+		now:        time.Now,
+	}
+}
+
+// Register 登记协议族，同名协议族被替换（流量统计清零）
+func (t *Table) Register(f Family) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.families[f.Name]; !ok {
+		t.order = append(t.order, f.Name)
+	}
+	f.Legacy = append([]Legacy(nil), f.Legacy...)
+	fam := &family{Family: f, traffic: make(map[string]*traffic)}
+	fam.traffic[f.Current] = &traffic{peers: make(map[string]time.Time)}
+	for _, l := range f.Legacy {
+		fam.traffic[l.ID] = &traffic{peers: make(map[string]time.Time)}
+	}
+	t.families[f.Name] = fam
+}
+
+// Protocols 发起流或发布消息时使用的版本：当前版本在前，其后为未过弃用截止时间的旧版本。
+// 未登记的协议族返回 nil。
+func (t *Table) Protocols(name string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.families[name]
+	if !ok {
+		return nil
+	}
+	now := t.now()
+	ids := []string{f.Current}
+	for _, l := range f.Legacy {
+		if l.active(now) {
+			ids = append(ids, l.ID)
+		}
+	}
+	return ids
+}
+
+// IsLegacy 判断 id 是否为协议族的旧版本
+func (t *Table) IsLegacy(name, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if f, ok := t.families[name]; ok {
+		return f.legacy(id) != nil
+	}
+	return false
+}
+
+// Active 判断 id 是否仍在提供：当前版本，或未过弃用截止时间的旧版本
+func (t *Table) Active(name, id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.families[name]
+	if !ok {
+		return false
+	}
+	return f.active(id, t.now())
+}
+
+// Accept 记录一次使用 id 的流或消息，返回是否接受。
+// 过了弃用截止时间的旧版本计入拒绝次数；未登记的版本不接受也不计数。
+func (t *Table) Accept(name, id, peerID string, dir Direction) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.families[name]
+	if !ok {
+		return false
+	}
+	tr, ok := f.traffic[id]
+	if !ok {
+		return false
+	}
+	now := t.now()
+	if !f.active(id, now) {
+		tr.refused++
+		return false
+	}
+	if dir == Outbound {
+		tr.outbound++
+	} else {
+		tr.inbound++
+	}
+	tr.lastSeen = now
+	if peerID != "" {
+		if _, seen := tr.peers[peerID]; seen || len(tr.peers) < maxTrackedPeers {
+			tr.peers[peerID] = now
+		}
+	}
+	return true
+}
+
+// Status 各协议族按版本的使用情况，协议族按登记顺序排列
+func (t *Table) Status() []FamilyStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	result := make([]FamilyStatus, 0, len(t.order))
+	for _, name := range t.order {
+		f := t.families[name]
+		status := FamilyStatus{Name: name, Versions: []VersionStatus{t.versionLocked(f, f.Current, nil, now)}}
+		var total, legacy int64
+		total = status.Versions[0].Inbound + status.Versions[0].Outbound
+		for i := range f.Legacy {
+			v := t.versionLocked(f, f.Legacy[i].ID, &f.Legacy[i], now)
+			status.Versions = append(status.Versions, v)
+			total += v.Inbound + v.Outbound
+			legacy += v.Inbound + v.Outbound
+		}
+		if total > 0 {
+			status.LegacyShare = float64(legacy) / float64(total)
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// Peers 窗口内使用 id 的节点，按最近使用时间从新到旧排列，最多 limit 个（<= 0 不限）
+func (t *Table) Peers(name, id string, limit int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.families[name]
+	if !ok {
+		return nil
+	}
+	tr, ok := f.traffic[id]
+	if !ok {
+		return nil
+	}
+	now := t.now()
+	peers := make([]string, 0, len(tr.peers))
+	for peerID, seen := range tr.peers {
+		if now.Sub(seen) <= t.peerWindow {
+			peers = append(peers, peerID)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		a, b := tr.peers[peers[i]], tr.peers[peers[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return peers[i] < peers[j]
+	})
+	if limit > 0 && len(peers) > limit {
+		peers = peers[:limit]
+	}
+	return peers
+}
+
+// versionLocked 单个版本的使用情况，同时清理窗口外的节点（调用方持有锁）
+func (t *Table) versionLocked(f *family, id string, l *Legacy, now time.Time) VersionStatus {
+	tr := f.traffic[id]
+	for peerID, seen := range tr.peers {
+		if now.Sub(seen) > t.peerWindow {
+			delete(tr.peers, peerID)
+		}
+	}
+	v := VersionStatus{
+		ID:       id,
+		Legacy:   l != nil,
+		Active:   f.active(id, now),
+		Inbound:  tr.inbound,
+		Outbound: tr.outbound,
+		Refused:  tr.refused,
+		Peers:    len(tr.peers),
+	}
+	if l != nil && !l.Sunset.IsZero() {
+		sunset := l.Sunset
+		v.Sunset = &sunset
+	}
+	if !tr.lastSeen.IsZero() {
+		lastSeen := tr.lastSeen
+		v.LastSeen = &lastSeen
+	}
+	return v
+}
+
+// Stats 实现 stats.Provider：每个协议族的旧版本收发次数、拒绝次数、节点数和流量占比
+func (t *Table) Stats() []stats.Metric {
+	var metrics []stats.Metric
+	for _, f := range t.Status() {
+		var inbound, outbound, refused int64
+		var peers int
+		for _, v := range f.Versions {
+			if !v.Legacy {
+				continue
+			}
+			inbound += v.Inbound
+			outbound += v.Outbound
+			refused += v.Refused
+			peers += v.Peers
+		}
+		metrics = append(metrics,
+			stats.Counter(f.Name+"_legacy_inbound", float64(inbound), "对方以旧版本协议发起的流或收到的旧版本消息数"),
+			stats.Counter(f.Name+"_legacy_outbound", float64(outbound), "协商为旧版本协议的出站流或发布的旧版本消息数"),
+			stats.Counter(f.Name+"_legacy_refused", float64(refused), "过了弃用截止时间被拒绝的旧版本流或消息数"),
+			stats.Gauge(f.Name+"_legacy_peers", float64(peers), "窗口期内（默认 24 小时）使用旧版本协议的节点数"),
+			stats.Gauge(f.Name+"_legacy_share", f.LegacyShare, "旧版本流量占全部流量的比例"),
+		)
+	}
+	return metrics
+}
+
+func (l *Legacy) active(now time.Time) bool {
+	return l.Sunset.IsZero() || now.Before(l.Sunset)
+}
+
+func (f *family) legacy(id string) *Legacy {
+	for i := range f.Legacy {
+		if f.Legacy[i].ID == id {
+			return &f.Legacy[i]
+		}
+	}
+	return nil
+}
+
+func (f *family) active(id string, now time.Time) bool {
+	if id == f.Current {
+		return true
+	}
+	l := f.legacy(id)
+	return l != nil && l.active(now)
+}
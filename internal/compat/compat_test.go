@@ -0,0 +1,108 @@
+package compat
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestTable(now time.Time) *Table {
+	t := NewTable()
+	t.now = func() time.Time { return now }
+	t.Register(Family{
+		Name:    "mailbox_key",
+		Current: "/daan/mailbox/key/2.0.0",
+		Legacy: []Legacy{
+			{ID: "/daan/mailbox/key/1.5.0"},
+			{ID: "/daan/mailbox/key/1.0.0", Sunset: now.Add(time.Hour)},
+		},
+	})
+	return t
+}
+
+func TestProtocolsFallbackOrder(t *testing.T) {
+	now := time.Now()
+	table := newTestTable(now)
+
+	got := table.Protocols("mailbox_key")
+	want := []string{"/daan/mailbox/key/2.0.0", "/daan/mailbox/key/1.5.0", "/daan/mailbox/key/1.0.0"}
+	if len(got) != len(want) {
+		t.Fatalf("Protocols = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Protocols = %v, want %v", got, want)
+		}
+	}
+	if table.Protocols("unknown") != nil {
+		t.Error("unknown family should have no protocols")
+	}
+	if !table.IsLegacy("mailbox_key", "/daan/mailbox/key/1.0.0") || table.IsLegacy("mailbox_key", "/daan/mailbox/key/2.0.0") {
+		t.Error("IsLegacy mismatch")
+	}
+
+	// 过了弃用截止时间后不再提供
+	table.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if got := table.Protocols("mailbox_key"); len(got) != 2 || got[1] != "/daan/mailbox/key/1.5.0" {
+		t.Errorf("Protocols after sunset = %v", got)
+	}
+	if table.Active("mailbox_key", "/daan/mailbox/key/1.0.0") {
+		t.Error("sunset version should be inactive")
+	}
+}
+
+func TestAcceptCountsLegacyTraffic(t *testing.T) {
+	now := time.Now()
+	table := newTestTable(now)
+	const v1, v2 = "/daan/mailbox/key/1.0.0", "/daan/mailbox/key/2.0.0"
+
+	table.Accept("mailbox_key", v2, "alice", Inbound)
+	table.Accept("mailbox_key", v2, "bob", Outbound)
+	table.Accept("mailbox_key", v1, "carol", Inbound)
+	table.Accept("mailbox_key", v1, "carol", Outbound)
+	if table.Accept("mailbox_key", "/daan/mailbox/key/0.9.0", "dave", Inbound) {
+		t.Error("unregistered version accepted")
+	}
+
+	status := table.Status()
+	if len(status) != 1 || len(status[0].Versions) != 3 {
+		t.Fatalf("status = %+v", status)
+	}
+	legacy := status[0].Versions[2]
+	if legacy.ID != v1 || !legacy.Legacy || legacy.Inbound != 1 || legacy.Outbound != 1 || legacy.Peers != 1 || legacy.Sunset == nil || legacy.LastSeen == nil {
+		t.Errorf("legacy status = %+v", legacy)
+	}
+	if status[0].LegacyShare != 0.5 {
+		t.Errorf("legacy share = %v, want 0.5", status[0].LegacyShare)
+	}
+
+	// 过了弃用截止时间的流被拒绝并计数，窗口外的节点不再计入
+	table.now = func() time.Time { return now.Add(25 * time.Hour) }
+	if table.Accept("mailbox_key", v1, "carol", Inbound) {
+		t.Error("sunset version accepted")
+	}
+	if peers := table.Peers("mailbox_key", v1, 0); len(peers) != 0 {
+		t.Errorf("peers outside window = %v", peers)
+	}
+
+	metrics := make(map[string]float64)
+	for _, m := range table.Stats() {
+		metrics[m.Name] = m.Value
+	}
+	if metrics["mailbox_key_legacy_inbound"] != 1 || metrics["mailbox_key_legacy_outbound"] != 1 ||
+		metrics["mailbox_key_legacy_refused"] != 1 || metrics["mailbox_key_legacy_peers"] != 0 || metrics["mailbox_key_legacy_share"] != 0.5 {
+		t.Errorf("metrics = %v", metrics)
+	}
+}
+
+func TestPeersOrderedByLastSeen(t *testing.T) {
+	now := time.Now()
+	table := newTestTable(now)
+	const v1 = "/daan/mailbox/key/1.0.0"
+	for i, peer := range []string{"alice", "bob", "carol"} {
+		table.now = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		table.Accept("mailbox_key", v1, peer, Inbound)
+	}
+	if got := table.Peers("mailbox_key", v1, 2); len(got) != 2 || got[0] != "carol" || got[1] != "bob" {
+		t.Errorf("Peers = %v, want carol, bob", got)
+	}
+}
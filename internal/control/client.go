@@ -0,0 +1,164 @@
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// ErrNotRunning 控制套接字不可连接，节点未运行
+var ErrNotRunning = errors.New("node is not running")
+
+// Client 控制接口客户端，供托盘程序和命令行使用
+type Client struct {
+	socketPath string
+	http       *http.Client
+	stream     *http.Client // 通知流不设超时
+}
+
+// NewClient 创建客户端
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{
+		socketPath: socketPath,
+		http:       &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		stream:     &http.Client{Transport: transport},
+	}
+}
+
+func (c *Client) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, fmt.Errorf("%w: %s", ErrNotRunning, c.socketPath)
+		}
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("control API %s: %s", resp.Status, body.Error)
+	}
+	return resp, nil
+}
+
+func (c *Client) call(method, path string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(method, "http://control"+path, bytes.NewReader(nil))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(c.http, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Status 节点状态；节点未运行时返回 ErrNotRunning
+func (c *Client) Status() (map[string]interface{}, error) {
+	return c.call(http.MethodGet, "/v1/status")
+}
+
+// Stop 请求节点退出
+func (c *Client) Stop() error {
+	_, err := c.call(http.MethodPost, "/v1/stop")
+	return err
+}
+
+// WaitStopped 等待节点关闭控制套接字
+func (c *Client) WaitStopped(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := c.Status(); errors.Is(err, ErrNotRunning) {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("node still running after %s", timeout)
+}
+
+// Start 节点已运行时直接返回状态；否则调用 launch 拉起节点，并等待控制套接字可用
+func (c *Client) Start(launch func() error, timeout time.Duration) (map[string]interface{}, error) {
+	status, err := c.call(http.MethodPost, "/v1/start")
+	if !errors.Is(err, ErrNotRunning) {
+		return status, err
+	}
+	if launch == nil {
+		return nil, err
+	}
+	if err := launch(); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := c.Status()
+		if err == nil || !errors.Is(err, ErrNotRunning) || time.Now().After(deadline) {
+			return status, err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// Notifications 订阅通知流，每收到一个事件调用一次 fn，直到 ctx 取消或连接断开
+func (c *Client) Notifications(ctx context.Context, types []string, lastID uint64, fn func(httpapi.Event)) error {
+	query := url.Values{}
+	if len(types) > 0 {
+		query.Set("types", strings.Join(types, ","))
+	}
+	if lastID > 0 {
+		query.Set("last_id", strconv.FormatUint(lastID, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://control/v1/notifications?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(c.stream, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ev httpapi.Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return fmt.Errorf("decode notification: %w", err)
+		}
+		fn(ev)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,291 @@
+// Package control 实现供桌面托盘程序使用的本地控制接口
+//
+// 控制接口只监听本机的 Unix 套接字（Windows 10 1803 起同样支持 AF_UNIX），
+// 与面向网络的 HTTP API 完全隔离：不经过 TCP 端口，也不需要管理令牌，
+// 访问控制依赖套接字文件的权限（仅节点运行用户可读写）。
+//
+// 接口：
+//
+//	GET  /v1/status         节点运行状态
+//	POST /v1/start          节点已在运行时幂等返回；未运行时由客户端拉起守护进程
+//	POST /v1/stop           请求节点优雅退出
+//	GET  /v1/notifications  通知流，每行一个 JSON 事件（?types=mail,task&last_id=N）
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+// SocketName 数据目录下默认的控制套接字文件名
+const SocketName = "control.sock"
+
+// EventResync 通知缓冲已缺少断点之后的事件，托盘应重新拉取状态
+const EventResync = "resync"
+
+// DefaultKeepAlive 通知流的保活间隔
+const DefaultKeepAlive = 30 * time.Second
+
+var (
+	ErrNilConfig     = errors.New("config is nil")
+	ErrEmptySocket   = errors.New("socket path is empty")
+	ErrAlreadyInUse  = errors.New("control socket is in use by another node")
+	ErrServerRunning = errors.New("control server already running")
+)
+
+// Config 控制接口配置
+type Config struct {
+	SocketPath string        // 套接字路径
+	KeepAlive  time.Duration // 通知流保活间隔
+}
+
+// DefaultConfig 返回数据目录下的默认配置
+func DefaultConfig(dataDir string) *Config {
+	return &Config{
+		SocketPath: filepath.Join(dataDir, SocketName),
+		KeepAlive:  DefaultKeepAlive,
+	}
+}
+
+// Server 本地控制服务
+type Server struct {
+	config *Config
+
+	// StatusFunc 返回节点状态，nil 时只返回 running
+	StatusFunc func() map[string]interface{}
+	// StopFunc 请求节点退出，在响应写出后异步调用
+	StopFunc func()
+	// Events 通知来源，nil 时通知流不可用
+	Events *httpapi.EventHub
+
+	mu       sync.Mutex
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer 创建控制服务
+func NewServer(config *Config) (*Server, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.SocketPath == "" {
+		return nil, ErrEmptySocket
+	}
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = DefaultKeepAlive
+	}
+	return &Server{config: config}, nil
+}
+
+// SocketPath 套接字路径
+func (s *Server) SocketPath() string {
+	return s.config.SocketPath
+}
+
+// Start 监听套接字
+// 遗留的套接字文件可连通时说明另一个节点正在使用同一数据目录，拒绝启动；否则视为上次异常退出的残留并删除。
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener != nil {
+		return ErrServerRunning
+	}
+
+	path := s.config.SocketPath
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return fmt.Errorf("%w: %s", ErrAlreadyInUse, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := restrictSocket(path); err != nil {
+		listener.Close()
+		os.Remove(path)
+		return err
+	}
+
+	s.listener = listener
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go s.server.Serve(listener)
+	return nil
+}
+
+// Stop 关闭服务并删除套接字文件
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	server := s.server
+	s.listener, s.server = nil, nil
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// 通知流是长连接，超时后直接断开
+		err = server.Close()
+	}
+	os.Remove(s.config.SocketPath)
+	return err
+}
+
+// Handler 控制接口路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/start", s.handleStart)
+	mux.HandleFunc("/v1/stop", s.handleStop)
+	mux.HandleFunc("/v1/notifications", s.handleNotifications)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *Server) status() map[string]interface{} {
+	status := map[string]interface{}{}
+	if s.StatusFunc != nil {
+		for k, v := range s.StatusFunc() {
+			status[k] = v
+		}
+	}
+	status["running"] = true
+	return status
+}
+
+// handleStatus 节点状态
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleStart 能应答即说明节点已在运行
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	status := s.status()
+	status["message"] = "already running"
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleStop 先应答再退出，托盘可通过套接字断开确认节点已停止
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.StopFunc == nil {
+		writeError(w, http.StatusNotImplemented, "stop not supported")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"message": "stopping"})
+	go s.StopFunc()
+}
+
+// handleNotifications 通知流
+// 每行一个事件 JSON；断开后带上最后收到的事件 ID 重连可补发缓冲中的事件。
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.Events == nil {
+		writeError(w, http.StatusNotImplemented, "notifications not available")
+		return
+	}
+
+	query := r.URL.Query()
+	var types []string
+	if raw := query.Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !httpapi.IsEventType(t) {
+				writeError(w, http.StatusBadRequest, "unknown event type: "+t)
+				return
+			}
+			types = append(types, t)
+		}
+	}
+	var lastID uint64
+	if raw := query.Get("last_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid last_id")
+			return
+		}
+		lastID = id
+	}
+
+	sub := s.Events.Subscribe(types, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	if sub.Gap {
+		enc.Encode(httpapi.Event{Type: EventResync, Time: time.Now()})
+	}
+	for _, ev := range sub.Backlog {
+		enc.Encode(ev)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(s.config.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			enc.Encode(ev)
+		case <-keepAlive.C:
+			// 空行保活，客户端忽略
+			w.Write([]byte("\n"))
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/httpapi"
+)
+
+func newTestServer(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	s, err := NewServer(DefaultConfig(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { s.Stop() })
+	return s, NewClient(s.SocketPath())
+}
+
+func TestNewServer(t *testing.T) {
+	if _, err := NewServer(nil); !errors.Is(err, ErrNilConfig) {
+		t.Errorf("NewServer(nil) error = %v", err)
+	}
+	if _, err := NewServer(&Config{}); !errors.Is(err, ErrEmptySocket) {
+		t.Errorf("NewServer(empty) error = %v", err)
+	}
+}
+
+func TestStatusAndStop(t *testing.T) {
+	s, c := newTestServer(t)
+	s.StatusFunc = func() map[string]interface{} {
+		return map[string]interface{}{"node_id": "node-1", "running": false}
+	}
+	stopped := make(chan struct{})
+	s.StopFunc = func() { close(stopped) }
+
+	status, err := c.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status["node_id"] != "node-1" || status["running"] != true {
+		t.Errorf("status = %v", status)
+	}
+	if info, err := os.Stat(s.SocketPath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, %v", info.Mode(), err)
+	}
+
+	// 已运行时 start 不调用 launch
+	launched := false
+	if _, err := c.Start(func() error { launched = true; return nil }, time.Second); err != nil || launched {
+		t.Errorf("Start() on running node: launched = %v, err = %v", launched, err)
+	}
+
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("StopFunc not called")
+	}
+
+	s.Stop()
+	if _, err := c.Status(); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Status() after stop error = %v", err)
+	}
+	if _, err := os.Stat(s.SocketPath()); !os.IsNotExist(err) {
+		t.Errorf("socket file left behind: %v", err)
+	}
+}
+
+func TestStartLaunches(t *testing.T) {
+	config := DefaultConfig(t.TempDir())
+	c := NewClient(config.SocketPath)
+	s, _ := NewServer(config)
+	defer s.Stop()
+
+	if _, err := c.Start(nil, time.Second); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Start(nil) error = %v", err)
+	}
+	status, err := c.Start(s.Start, time.Second)
+	if err != nil || status["running"] != true {
+		t.Errorf("Start() = %v, %v", status, err)
+	}
+}
+
+func TestStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, SocketName)
+	os.WriteFile(path, nil, 0600)
+
+	s, _ := NewServer(DefaultConfig(dir))
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start() over stale socket error = %v", err)
+	}
+	defer s.Stop()
+
+	other, _ := NewServer(DefaultConfig(dir))
+	if err := other.Start(); !errors.Is(err, ErrAlreadyInUse) {
+		t.Errorf("second Start() error = %v", err)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s, c := newTestServer(t)
+	if err := c.Notifications(context.Background(), nil, 0, func(httpapi.Event) {}); err == nil {
+		t.Error("notifications without event hub succeeded")
+	}
+
+	hub := httpapi.NewEventHub(16)
+	s.Events = hub
+	first := hub.Publish(httpapi.EventMail, map[string]interface{}{"id": "m1"})
+	hub.Publish(httpapi.EventMail, map[string]interface{}{"id": "m2"})
+
+	if err := c.Notifications(context.Background(), []string{"unknown"}, 0, func(httpapi.Event) {}); err == nil {
+		t.Error("unknown event type accepted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan httpapi.Event, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Notifications(ctx, []string{httpapi.EventMail, httpapi.EventTask}, first.ID, func(ev httpapi.Event) {
+			received <- ev
+		})
+	}()
+
+	// 补发 last_id 之后的事件
+	ev := <-received
+	if ev.Type != httpapi.EventMail || ev.ID != first.ID+1 {
+		t.Errorf("backlog event = %+v", ev)
+	}
+	for hub.Subscribers() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.Publish(httpapi.EventReputation, nil)
+	hub.Publish(httpapi.EventTask, map[string]interface{}{"id": "t1"})
+	select {
+	case ev := <-received:
+		if ev.Type != httpapi.EventTask {
+			t.Errorf("filtered event = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("live event not received")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Notifications() error = %v", err)
+	}
+}
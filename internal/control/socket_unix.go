@@ -0,0 +1,10 @@
+//go:build !windows
+
+package control
+
+import "os"
+
+// restrictSocket 套接字仅允许节点运行用户访问 (Unix)
+func restrictSocket(path string) error {
+	return os.Chmod(path, 0600)
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package control
+
+// restrictSocket Windows 上 AF_UNIX 套接字文件继承数据目录的 ACL，不支持 chmod；
+// 命名管道需要额外依赖，这里沿用 AF_UNIX（Windows 10 1803 起可用）。
+func restrictSocket(path string) error {
+	return nil
+}
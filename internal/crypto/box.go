@@ -0,0 +1,161 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+)
+
+// 节点身份密钥为 Ed25519，加密时转换为对应的 X25519 密钥做 ECDH，
+// 无需为每个节点额外分发加密公钥。
+//
+// 密文格式: 临时公钥(32) | nonce(12) | AES-256-GCM 密文
+
+var (
+	ErrInvalidPublicKey  = errors.New("invalid ed25519 public key")
+	ErrInvalidPrivateKey = errors.New("invalid ed25519 private key")
+	ErrSealedTooShort    = errors.New("sealed data too short")
+	ErrDecryptFailed     = errors.New("decryption failed")
+)
+
+const boxKDFLabel = "daan-box-v1"
+
+// curve25519 素数 p = 2^255 - 19
+var curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// SealToEd25519 使用接收方的 Ed25519 公钥加密数据
+func SealToEd25519(recipient ed25519.PublicKey, plaintext []byte) ([]byte, error) {
+	recipientX, err := ed25519PublicToX25519(recipient)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := ecdh.X25519().NewPublicKey(recipientX)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	eph, err := ecdh.X25519().GenerateKey(crand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := eph.ECDH(remote)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	gcm, err := boxCipher(shared, eph.PublicKey().Bytes(), recipientX)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 32+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, eph.PublicKey().Bytes()...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// OpenWithEd25519 使用本节点的 Ed25519 私钥解密 SealToEd25519 的输出
+func OpenWithEd25519(priv ed25519.PrivateKey, sealed []byte) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+	local, err := ecdh.X25519().NewPrivateKey(ed25519PrivateToX25519(priv))
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	const nonceSize = 12
+	if len(sealed) < 32+nonceSize {
+		return nil, ErrSealedTooShort
+	}
+	ephPub, err := ecdh.X25519().NewPublicKey(sealed[:32])
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	shared, err := local.ECDH(ephPub)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+
+	gcm, err := boxCipher(shared, sealed[:32], local.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, sealed[32:32+nonceSize], sealed[32+nonceSize:], nil)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return plaintext, nil
+}
+
+// boxCipher 由共享密钥派生 AES-256-GCM
+func boxCipher(shared, ephPub, recipientX []byte) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write([]byte(boxKDFLabel))
+	h.Write(shared)
+	h.Write(ephPub)
+	h.Write(recipientX)
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ed25519PublicToX25519 将 Edwards 公钥转换为 Montgomery 形式: u = (1+y)/(1-y) mod p
+func ed25519PublicToX25519(pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	// 小端序 y 坐标，最高位为 x 的符号位
+	le := make([]byte, 32)
+	copy(le, pub)
+	le[31] &= 0x7f
+	y := new(big.Int).SetBytes(reverse(le))
+	if y.Cmp(curveP) >= 0 {
+		return nil, ErrInvalidPublicKey
+	}
+
+	one := big.NewInt(1)
+	den := new(big.Int).Sub(one, y)
+	den.Mod(den, curveP)
+	if den.Sign() == 0 {
+		return nil, ErrInvalidPublicKey
+	}
+	den.ModInverse(den, curveP)
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, den)
+	u.Mod(u, curveP)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return reverse(out), nil
+}
+
+// ed25519PrivateToX25519 由 Ed25519 私钥种子派生 X25519 标量（与签名所用标量一致）
+func ed25519PrivateToX25519(priv ed25519.PrivateKey) []byte {
+	h := sha512.Sum512(priv.Seed())
+	scalar := h[:32]
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+	return scalar
+}
+
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
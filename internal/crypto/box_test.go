@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"testing"
+)
+
+func TestEd25519ToX25519Consistent(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		pub, priv, _ := ed25519.GenerateKey(crand.Reader)
+
+		fromPub, err := ed25519PublicToX25519(pub)
+		if err != nil {
+			t.Fatalf("公钥转换失败: %v", err)
+		}
+		xPriv, err := ecdh.X25519().NewPrivateKey(ed25519PrivateToX25519(priv))
+		if err != nil {
+			t.Fatalf("私钥转换失败: %v", err)
+		}
+		if !bytes.Equal(fromPub, xPriv.PublicKey().Bytes()) {
+			t.Fatal("公钥与私钥转换结果不一致")
+		}
+	}
+}
+
+func TestSealOpenEd25519(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(crand.Reader)
+	_, otherPriv, _ := ed25519.GenerateKey(crand.Reader)
+	plaintext := []byte("任务详情仅对入围节点可见")
+
+	sealed, err := SealToEd25519(pub, plaintext)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Error("密文不应包含明文")
+	}
+
+	opened, err := OpenWithEd25519(priv, sealed)
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("解密结果不一致: %s", opened)
+	}
+
+	if _, err := OpenWithEd25519(otherPriv, sealed); err != ErrDecryptFailed {
+		t.Errorf("非接收方解密应失败, 得到 %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := OpenWithEd25519(priv, sealed); err != ErrDecryptFailed {
+		t.Errorf("篡改密文应解密失败, 得到 %v", err)
+	}
+	if _, err := OpenWithEd25519(priv, sealed[:10]); err != ErrSealedTooShort {
+		t.Errorf("期望 ErrSealedTooShort, 得到 %v", err)
+	}
+	if _, err := SealToEd25519(pub[:16], plaintext); err != ErrInvalidPublicKey {
+		t.Errorf("期望 ErrInvalidPublicKey, 得到 %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 本地存储的静态加密（邮箱、留言板等落盘数据）
+//
+// 数据用随机生成的 AES-256 数据加密密钥（DEK）加密，DEK 保存在密钥环文件中，
+// 密钥环本身再用主密钥加密。主密钥由节点私钥或单独的存储密钥派生，因此更换
+// 主密钥只需重新加密密钥环，不必重写数据。轮换 DEK 后新数据使用新密钥，
+// 旧密钥保留用于读取尚未重写的文件。
+//
+// 密文格式（JSON 信封）: {"sealed":"daan-storage-v1","key_id":"...","data":base64(nonce|密文)}
+
+var (
+	ErrNotSealed      = errors.New("data is not sealed")
+	ErrUnknownKeyID   = errors.New("unknown storage key")
+	ErrKeyringLocked  = errors.New("cannot unlock storage keyring")
+	ErrInvalidKeySize = errors.New("storage master key must not be empty")
+)
+
+const (
+	storageSealVersion = "daan-storage-v1"
+	keyringKeyID       = "master"
+)
+
+// DeriveStorageKey 从节点私钥或存储密钥派生用途相关的 32 字节密钥
+func DeriveStorageKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(storageSealVersion + "|" + purpose))
+	return mac.Sum(nil)
+}
+
+// sealedEnvelope 加密数据信封
+type sealedEnvelope struct {
+	Sealed string `json:"sealed"`
+	KeyID  string `json:"key_id"`
+	Data   []byte `json:"data"`
+}
+
+// IsSealed 判断数据是否为 StorageKeyring 加密的信封
+func IsSealed(data []byte) bool {
+	var env sealedEnvelope
+	return json.Unmarshal(data, &env) == nil && env.Sealed == storageSealVersion
+}
+
+// StorageKey 数据加密密钥
+type StorageKey struct {
+	ID        string `json:"id"`
+	Key       []byte `json:"key"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// StorageKeyInfo 密钥信息（不含密钥本身）
+type StorageKeyInfo struct {
+	ID        string `json:"id"`
+	CreatedAt int64  `json:"created_at"`
+	Active    bool   `json:"active"`
+}
+
+type keyringState struct {
+	Active string        `json:"active"`
+	Keys   []*StorageKey `json:"keys"`
+}
+
+// StorageKeyring 静态加密密钥环
+type StorageKeyring struct {
+	mu     sync.RWMutex
+	path   string
+	master []byte
+	active string
+	keys   map[string]*StorageKey
+}
+
+// OpenStorageKeyring 打开或创建密钥环文件
+// 密钥环依次尝试用 master 和 previous 解锁；用旧主密钥解锁成功时立即用 master
+// 重新加密，以此完成主密钥更换。path 为空时只在内存中保存（用于测试）。
+func OpenStorageKeyring(path string, master []byte, previous ...[]byte) (*StorageKeyring, error) {
+	if len(master) == 0 {
+		return nil, ErrInvalidKeySize
+	}
+	kr := &StorageKeyring{
+		path:   path,
+		master: DeriveStorageKey(master, "keyring"),
+		keys:   make(map[string]*StorageKey),
+	}
+
+	data, err := os.ReadFile(path)
+	if path == "" || os.IsNotExist(err) {
+		if _, err := kr.Rotate(); err != nil {
+			return nil, err
+		}
+		return kr, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage keyring: %w", err)
+	}
+
+	candidates := append([][]byte{master}, previous...)
+	for i, candidate := range candidates {
+		if len(candidate) == 0 {
+			continue
+		}
+		plaintext, err := openEnvelope(data, func(keyID string) ([]byte, bool) {
+			return DeriveStorageKey(candidate, "keyring"), keyID == keyringKeyID
+		})
+		if err != nil {
+			continue
+		}
+		var state keyringState
+		if err := json.Unmarshal(plaintext, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse storage keyring: %w", err)
+		}
+		for _, k := range state.Keys {
+			kr.keys[k.ID] = k
+		}
+		if _, ok := kr.keys[state.Active]; !ok {
+			return nil, fmt.Errorf("%w: active key %s", ErrUnknownKeyID, state.Active)
+		}
+		kr.active = state.Active
+		if i > 0 {
+			if err := kr.save(); err != nil {
+				return nil, err
+			}
+		}
+		return kr, nil
+	}
+	return nil, ErrKeyringLocked
+}
+
+// Rotate 生成新的数据加密密钥并设为当前密钥，返回新密钥 ID
+// 已加密的数据仍可用旧密钥读取，调用方应随后重写存储以使用新密钥。
+func (kr *StorageKeyring) Rotate() (string, error) {
+	key := make([]byte, 32)
+	if _, err := crand.Read(key); err != nil {
+		return "", err
+	}
+	id := make([]byte, 8)
+	if _, err := crand.Read(id); err != nil {
+		return "", err
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	sk := &StorageKey{ID: hex.EncodeToString(id), Key: key, CreatedAt: time.Now().Unix()}
+	previous := kr.active
+	kr.keys[sk.ID] = sk
+	kr.active = sk.ID
+	if err := kr.saveLocked(); err != nil {
+		delete(kr.keys, sk.ID)
+		kr.active = previous
+		return "", err
+	}
+	return sk.ID, nil
+}
+
+// ActiveKeyID 返回当前用于加密的密钥 ID
+func (kr *StorageKeyring) ActiveKeyID() string {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.active
+}
+
+// Keys 返回所有密钥信息，按创建时间排序
+func (kr *StorageKeyring) Keys() []StorageKeyInfo {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	infos := make([]StorageKeyInfo, 0, len(kr.keys))
+	for _, k := range kr.keys {
+		infos = append(infos, StorageKeyInfo{ID: k.ID, CreatedAt: k.CreatedAt, Active: k.ID == kr.active})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].CreatedAt != infos[j].CreatedAt {
+			return infos[i].CreatedAt < infos[j].CreatedAt
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Seal 用当前密钥加密数据
+func (kr *StorageKeyring) Seal(plaintext []byte) ([]byte, error) {
+	kr.mu.RLock()
+	key := kr.keys[kr.active]
+	kr.mu.RUnlock()
+	if key == nil {
+		return nil, ErrUnknownKeyID
+	}
+	return sealEnvelope(key.ID, key.Key, plaintext)
+}
+
+// Open 解密 Seal 的输出，数据由哪个密钥加密就用哪个密钥解密
+func (kr *StorageKeyring) Open(data []byte) ([]byte, error) {
+	return openEnvelope(data, func(keyID string) ([]byte, bool) {
+		kr.mu.RLock()
+		defer kr.mu.RUnlock()
+		if k, ok := kr.keys[keyID]; ok {
+			return k.Key, true
+		}
+		return nil, false
+	})
+}
+
+func (kr *StorageKeyring) save() error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	return kr.saveLocked()
+}
+
+func (kr *StorageKeyring) saveLocked() error {
+	if kr.path == "" {
+		return nil
+	}
+	state := &keyringState{Active: kr.active}
+	for _, k := range kr.keys {
+		state.Keys = append(state.Keys, k)
+	}
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	data, err := sealEnvelope(keyringKeyID, kr.master, plaintext)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(kr.path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(kr.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write storage keyring: %w", err)
+	}
+	return nil
+}
+
+func sealEnvelope(keyID string, key, plaintext []byte) ([]byte, error) {
+	gcm, err := newStorageGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// 密钥 ID 作为附加数据，防止信封被改写为用其他密钥解密
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(keyID))
+	return json.Marshal(&sealedEnvelope{Sealed: storageSealVersion, KeyID: keyID, Data: sealed})
+}
+
+func openEnvelope(data []byte, lookup func(keyID string) ([]byte, bool)) ([]byte, error) {
+	var env sealedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Sealed != storageSealVersion {
+		return nil, ErrNotSealed
+	}
+	key, ok := lookup(env.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, env.KeyID)
+	}
+	gcm, err := newStorageGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Data) < gcm.NonceSize() {
+		return nil, ErrSealedTooShort
+	}
+	nonce := env.Data[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, env.Data[gcm.NonceSize():], []byte(env.KeyID))
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return plaintext, nil
+}
+
+func newStorageGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
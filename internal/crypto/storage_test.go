@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorageKeyringSealOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage_keys.json")
+	kr, err := OpenStorageKeyring(path, []byte("node-key"))
+	if err != nil {
+		t.Fatalf("创建密钥环失败: %v", err)
+	}
+	plaintext := []byte(`{"inbox":{"m1":"离线消息"}}`)
+
+	sealed, err := kr.Seal(plaintext)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if !IsSealed(sealed) || IsSealed(plaintext) {
+		t.Error("IsSealed 判断错误")
+	}
+	if bytes.Contains(sealed, []byte("离线消息")) {
+		t.Error("密文不应包含明文")
+	}
+
+	// 轮换后旧数据仍可读，新数据使用新密钥
+	oldID := kr.ActiveKeyID()
+	newID, err := kr.Rotate()
+	if err != nil || newID == oldID {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	resealed, _ := kr.Seal(plaintext)
+
+	// 重新打开密钥环，两份密文都能解密
+	reopened, err := OpenStorageKeyring(path, []byte("node-key"))
+	if err != nil {
+		t.Fatalf("重新打开密钥环失败: %v", err)
+	}
+	if reopened.ActiveKeyID() != newID || len(reopened.Keys()) != 2 {
+		t.Errorf("密钥环状态错误: active=%s keys=%v", reopened.ActiveKeyID(), reopened.Keys())
+	}
+	for _, data := range [][]byte{sealed, resealed} {
+		got, err := reopened.Open(data)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("解密失败: %v", err)
+		}
+	}
+
+	if _, err := reopened.Open(plaintext); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("明文应返回 ErrNotSealed，实际: %v", err)
+	}
+
+	// 篡改信封中的密钥 ID 后无法解密
+	var env sealedEnvelope
+	json.Unmarshal(resealed, &env)
+	env.KeyID = oldID
+	tampered, _ := json.Marshal(&env)
+	if _, err := reopened.Open(tampered); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("篡改密钥 ID 应解密失败，实际: %v", err)
+	}
+}
+
+func TestStorageKeyringMasterChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage_keys.json")
+	kr, _ := OpenStorageKeyring(path, []byte("node-key"))
+	sealed, _ := kr.Seal([]byte("draft"))
+
+	if _, err := OpenStorageKeyring(path, []byte("storage-key")); !errors.Is(err, ErrKeyringLocked) {
+		t.Fatalf("错误的主密钥应无法解锁，实际: %v", err)
+	}
+
+	// 用旧主密钥解锁后改用新主密钥保存
+	migrated, err := OpenStorageKeyring(path, []byte("storage-key"), []byte("node-key"))
+	if err != nil {
+		t.Fatalf("更换主密钥失败: %v", err)
+	}
+	if got, err := migrated.Open(sealed); err != nil || string(got) != "draft" {
+		t.Errorf("更换主密钥后数据应仍可解密: %v", err)
+	}
+	if _, err := OpenStorageKeyring(path, []byte("storage-key")); err != nil {
+		t.Errorf("密钥环应已改用新主密钥: %v", err)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("密钥环文件权限应为 0600，实际: %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,263 @@
+package dispute
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 监管链导出包：把一个争议相关的全部材料——争议记录与证据、证据原件、
+// 指责、回执、调解记录哈希链、仲裁员签名投票、罚没记录——打包成一个
+// 自包含的 JSON 文件，由导出节点签名。包可以脱离网络审阅或提交给外部仲裁方，
+// 审阅方只需要导出节点的公钥即可校验每份材料的哈希、记录链和整体签名。
+
+// BundleFormat 导出包格式标识
+const BundleFormat = "agentnetwork-dispute-bundle/v1"
+
+// 附件类型
+const (
+	AttachmentAccusation   = "accusation"    // 引发争议的指责记录
+	AttachmentEvidenceBlob = "evidence_blob" // 证据原件，ID 为对应的证据ID
+	AttachmentReceipt      = "receipt"       // 投递、交付等回执
+	AttachmentSlash        = "slash"         // 裁决导致的罚没记录
+)
+
+var (
+	ErrBundleSigningUnavail = errors.New("bundle signing not configured")
+	ErrBundleFormat         = errors.New("unsupported bundle format")
+	ErrBundleTampered       = errors.New("bundle content does not match digest")
+	ErrBundleSignature      = errors.New("invalid bundle signature")
+	ErrBundleAttachment     = errors.New("bundle attachment hash mismatch")
+)
+
+// BundleAttachment 随争议打包的外部材料，Hash 为 Data 的 SHA-256
+type BundleAttachment struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	Data []byte `json:"data"`
+	Hash string `json:"hash"`
+}
+
+// NewBundleAttachment 创建附件，value 为 []byte 时原样保存，否则编码为 JSON
+func NewBundleAttachment(kind, id string, value interface{}) (BundleAttachment, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return BundleAttachment{}, err
+		}
+	}
+	return BundleAttachment{Kind: kind, ID: id, Data: data, Hash: sha256Hex(data)}, nil
+}
+
+// CustodyBundle 争议监管链导出包
+type CustodyBundle struct {
+	Format         string             `json:"format"`
+	DisputeID      string             `json:"dispute_id"`
+	Exporter       string             `json:"exporter"`
+	ExportedAt     int64              `json:"exported_at"`
+	Dispute        *Dispute           `json:"dispute"`                   // 含证据、仲裁投票（仲裁员签名）和裁决
+	TranscriptHead string             `json:"transcript_head,omitempty"` // 调解记录链头哈希
+	Attachments    []BundleAttachment `json:"attachments,omitempty"`
+
+	Digest    string `json:"digest"` // 以上内容的 SHA-256
+	Algorithm string `json:"algorithm"`
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature"`
+}
+
+// BundleReport 导出包校验结果
+type BundleReport struct {
+	DisputeID      string         `json:"dispute_id"`
+	Exporter       string         `json:"exporter"`
+	Digest         string         `json:"digest"`
+	TranscriptHead string         `json:"transcript_head,omitempty"`
+	Messages       int            `json:"messages"`
+	Evidence       int            `json:"evidence"`
+	Votes          int            `json:"votes"`
+	Attachments    map[string]int `json:"attachments"` // 按类型计数
+}
+
+// ExportBundle 导出争议的监管链包
+// 本节点保存的证据原件自动附上，其他材料（指责、外部证据原件、回执、罚没）由调用方作为附件传入。
+// 调解记录只导出密文和哈希链，频道密钥不进入导出包。
+func (dm *DisputeManager) ExportBundle(disputeID, exporter string, attachments []BundleAttachment) (*CustodyBundle, error) {
+	if dm.config.BundleSignFunc == nil {
+		return nil, ErrBundleSigningUnavail
+	}
+
+	dm.mu.RLock()
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		dm.mu.RUnlock()
+		return nil, ErrDisputeNotFound
+	}
+	raw, err := json.Marshal(dispute)
+	dm.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Dispute
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	bundle := &CustodyBundle{
+		Format:     BundleFormat,
+		DisputeID:  disputeID,
+		Exporter:   exporter,
+		ExportedAt: time.Now().Unix(),
+		Dispute:    &snapshot,
+		Algorithm:  dm.config.BundleAlgorithm,
+		PublicKey:  dm.config.BundlePublicKey,
+	}
+	if snapshot.Channel != nil {
+		snapshot.Channel.WrappedKeys = nil
+		head, err := verifyTranscript(disputeID, snapshot.Channel.Transcript)
+		if err != nil {
+			return nil, err
+		}
+		bundle.TranscriptHead = head
+	}
+	passed := make(map[string]bool)
+	for _, a := range attachments {
+		a.Hash = sha256Hex(a.Data)
+		bundle.Attachments = append(bundle.Attachments, a)
+		if a.Kind == AttachmentEvidenceBlob {
+			passed[a.ID] = true
+		}
+	}
+	// 本节点保存的证据原件随包导出
+	for _, e := range snapshot.Evidence {
+		if !e.Attached || passed[e.ID] {
+			continue
+		}
+		data, err := dm.readEvidenceBlob(e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("evidence %s: %w", e.ID, err)
+		}
+		bundle.Attachments = append(bundle.Attachments, BundleAttachment{Kind: AttachmentEvidenceBlob, ID: e.ID, Data: data, Hash: sha256Hex(data)})
+	}
+	sort.SliceStable(bundle.Attachments, func(i, j int) bool {
+		if bundle.Attachments[i].Kind != bundle.Attachments[j].Kind {
+			return bundle.Attachments[i].Kind < bundle.Attachments[j].Kind
+		}
+		return bundle.Attachments[i].ID < bundle.Attachments[j].ID
+	})
+
+	digest, err := bundleDigest(bundle)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Digest = digest
+	sig, err := dm.config.BundleSignFunc([]byte(digest))
+	if err != nil {
+		return nil, err
+	}
+	bundle.Signature = sig
+	return bundle, nil
+}
+
+// VerifyBundle 脱离网络校验导出包
+// verify 为 nil 时按 Algorithm 内置校验（目前支持 ed25519）。
+// 校验内容：整体摘要与签名、每个附件的哈希、证据原件与证据记录的哈希、
+// 调解记录哈希链与归档的链头、仲裁员引用的记录区间。
+func VerifyBundle(b *CustodyBundle, verify func(publicKey, data, signature []byte) (bool, error)) (*BundleReport, error) {
+	if b.Format != BundleFormat {
+		return nil, ErrBundleFormat
+	}
+	if b.Dispute == nil || b.Dispute.ID != b.DisputeID {
+		return nil, fmt.Errorf("%w: dispute record missing", ErrBundleTampered)
+	}
+	digest, err := bundleDigest(b)
+	if err != nil {
+		return nil, err
+	}
+	if digest != b.Digest {
+		return nil, ErrBundleTampered
+	}
+	if verify == nil {
+		if b.Algorithm != "ed25519" || len(b.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrBundleSignature, b.Algorithm)
+		}
+		verify = func(pub, data, sig []byte) (bool, error) {
+			return ed25519.Verify(pub, data, sig), nil
+		}
+	}
+	if ok, err := verify(b.PublicKey, []byte(b.Digest), b.Signature); err != nil || !ok {
+		return nil, ErrBundleSignature
+	}
+
+	report := &BundleReport{
+		DisputeID:   b.DisputeID,
+		Exporter:    b.Exporter,
+		Digest:      b.Digest,
+		Evidence:    len(b.Dispute.Evidence),
+		Votes:       len(b.Dispute.Votes),
+		Attachments: make(map[string]int),
+	}
+
+	evidenceHash := make(map[string]string, len(b.Dispute.Evidence))
+	for _, e := range b.Dispute.Evidence {
+		evidenceHash[e.ID] = e.Hash
+	}
+	for _, a := range b.Attachments {
+		if sha256Hex(a.Data) != a.Hash {
+			return nil, fmt.Errorf("%w: %s %s", ErrBundleAttachment, a.Kind, a.ID)
+		}
+		if a.Kind == AttachmentEvidenceBlob {
+			if want, ok := evidenceHash[a.ID]; ok && want != "" && want != a.Hash {
+				return nil, fmt.Errorf("%w: evidence %s", ErrBundleAttachment, a.ID)
+			}
+		}
+		report.Attachments[a.Kind]++
+	}
+
+	if ch := b.Dispute.Channel; ch != nil {
+		head, err := verifyTranscript(b.DisputeID, ch.Transcript)
+		if err != nil {
+			return nil, err
+		}
+		if head != b.TranscriptHead {
+			return nil, fmt.Errorf("%w: transcript head", ErrBundleTampered)
+		}
+		// 频道关闭时归档的链头必须与导出的记录一致
+		for _, e := range b.Dispute.Evidence {
+			if e.Type == EvidenceTranscript && e.Hash != head {
+				return nil, fmt.Errorf("%w: archived transcript head", ErrTranscriptBroken)
+			}
+		}
+		for _, v := range b.Dispute.Votes {
+			for _, c := range v.Citations {
+				if err := VerifyCitation(b.DisputeID, ch.Transcript, c); err != nil {
+					return nil, fmt.Errorf("vote by %s: %w", v.ArbitratorID, err)
+				}
+			}
+		}
+		report.TranscriptHead = head
+		report.Messages = len(ch.Transcript)
+	}
+	return report, nil
+}
+
+// bundleDigest 计算不含摘要和签名字段的导出包哈希
+func bundleDigest(b *CustodyBundle) (string, error) {
+	unsigned := *b
+	unsigned.Digest = ""
+	unsigned.Signature = nil
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(data), nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package dispute
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCustodyBundle(t *testing.T) {
+	keys := mediationKeys("complainant1", "defendant1", "arb1", "arb2", "arb3")
+	dm := newMediationManager(t, t.TempDir(), "arb1", keys)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+
+	dispute := startMediation(t, dm)
+	blob := []byte("delivered report v1, section 3 missing")
+	dm.SubmitEvidence(dispute.ID, "complainant1", "file", "report.pdf", sha256Hex(blob))
+	evidenceID := dispute.Evidence[len(dispute.Evidence)-1].ID
+	dm.PostMessage(dispute.ID, "defendant1", "Section 3 was out of scope")
+	for _, arb := range []string{"arb1", "arb2", "arb3"} {
+		cite := []TranscriptCitation{{From: 1, To: 1}}
+		if err := dm.SubmitVoteWithCitations(dispute.ID, arb, "complainant1", "Admission", "sig-"+arb, cite); err != nil {
+			t.Fatalf("SubmitVoteWithCitations failed: %v", err)
+		}
+	}
+	if _, err := dm.FinalizeArbitration(dispute.ID); err != nil {
+		t.Fatalf("FinalizeArbitration failed: %v", err)
+	}
+
+	evidenceBlob, _ := NewBundleAttachment(AttachmentEvidenceBlob, evidenceID, blob)
+	accusation, _ := NewBundleAttachment(AttachmentAccusation, "acc-1", map[string]string{"accuser": "complainant1", "accused": "defendant1"})
+	slash, _ := NewBundleAttachment(AttachmentSlash, "slash-1", map[string]interface{}{"owner": "defendant1", "amount": 10})
+	attachments := []BundleAttachment{slash, evidenceBlob, accusation}
+
+	if _, err := dm.ExportBundle(dispute.ID, "node-1", attachments); err != ErrBundleSigningUnavail {
+		t.Fatalf("expected ErrBundleSigningUnavail, got %v", err)
+	}
+	dm.config.BundleSignFunc = func(data []byte) ([]byte, error) { return ed25519.Sign(priv, data), nil }
+	dm.config.BundlePublicKey = pub
+	dm.config.BundleAlgorithm = "ed25519"
+
+	bundle, err := dm.ExportBundle(dispute.ID, "node-1", attachments)
+	if err != nil {
+		t.Fatalf("ExportBundle failed: %v", err)
+	}
+	if bundle.Dispute.Channel.WrappedKeys != nil {
+		t.Error("channel keys must not be exported")
+	}
+
+	// 离线审阅方从文件加载后校验
+	data, _ := json.Marshal(bundle)
+	load := func() *CustodyBundle {
+		var b CustodyBundle
+		if err := json.Unmarshal(data, &b); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		return &b
+	}
+	report, err := VerifyBundle(load(), nil)
+	if err != nil {
+		t.Fatalf("VerifyBundle failed: %v", err)
+	}
+	if report.Messages != 1 || report.Votes != 3 || report.Attachments[AttachmentEvidenceBlob] != 1 || report.TranscriptHead == "" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// 篡改附件
+	tampered := load()
+	tampered.Attachments[0].Data = []byte("forged")
+	if _, err := VerifyBundle(tampered, nil); err != ErrBundleTampered {
+		t.Errorf("expected ErrBundleTampered, got %v", err)
+	}
+
+	// 篡改后重算摘要，没有私钥无法重新签名
+	tampered.Attachments[0].Hash = sha256Hex(tampered.Attachments[0].Data)
+	tampered.Digest, _ = bundleDigest(tampered)
+	if _, err := VerifyBundle(tampered, nil); err != ErrBundleSignature {
+		t.Errorf("expected ErrBundleSignature, got %v", err)
+	}
+
+	// 篡改调解记录
+	tampered = load()
+	tampered.Dispute.Channel.Transcript[0].SenderID = "arb2"
+	tampered.Digest, _ = bundleDigest(tampered)
+	tampered.Signature = ed25519.Sign(priv, []byte(tampered.Digest))
+	if _, err := VerifyBundle(tampered, nil); !errors.Is(err, ErrTranscriptBroken) {
+		t.Errorf("expected ErrTranscriptBroken, got %v", err)
+	}
+
+	// 证据原件与证据记录的哈希不符（导出方附错了文件）
+	wrongBlob, _ := NewBundleAttachment(AttachmentEvidenceBlob, evidenceID, []byte("another file"))
+	bundle, _ = dm.ExportBundle(dispute.ID, "node-1", []BundleAttachment{wrongBlob})
+	if _, err := VerifyBundle(bundle, nil); !errors.Is(err, ErrBundleAttachment) {
+		t.Errorf("expected ErrBundleAttachment, got %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+package dispute
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 调解频道：仲裁开始时为申诉方、被诉方和仲裁员建立的加密对话。
+// 消息用频道密钥 AES-256-GCM 加密，频道密钥分别用每个成员的公钥包裹。
+// 记录按密文组成哈希链，无需解密即可校验完整性；争议结束时频道关闭，
+// 链头哈希作为证据归档，仲裁员可在投票理由中引用记录区间。
+
+var (
+	ErrChannelNotOpen    = errors.New("mediation channel not open")
+	ErrChannelClosed     = errors.New("mediation channel closed")
+	ErrNotChannelMember  = errors.New("not a mediation channel member")
+	ErrEncryptionUnavail = errors.New("mediation channel encryption not configured")
+	ErrEmptyMessage      = errors.New("empty mediation message")
+	ErrMessageTooLarge   = errors.New("mediation message too large")
+	ErrTranscriptBroken  = errors.New("mediation transcript hash chain broken")
+	ErrInvalidCitation   = errors.New("invalid transcript citation")
+)
+
+// EvidenceTranscript 调解记录归档证据的类型
+const EvidenceTranscript = "transcript"
+
+// MediationChannel 调解频道
+type MediationChannel struct {
+	Members     []string          `json:"members"`
+	WrappedKeys map[string]string `json:"wrapped_keys"` // 成员节点ID -> 包裹后的频道密钥
+	Transcript  []TranscriptEntry `json:"transcript"`
+	OpenedAt    int64             `json:"opened_at"`
+	Closed      bool              `json:"closed"`
+	ClosedAt    int64             `json:"closed_at,omitempty"`
+}
+
+// TranscriptEntry 调解记录条目（内容为密文）
+type TranscriptEntry struct {
+	Seq        int    `json:"seq"` // 从 1 开始
+	SenderID   string `json:"sender_id"`
+	Ciphertext string `json:"ciphertext"` // base64(nonce|密文)
+	SentAt     int64  `json:"sent_at"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+}
+
+// TranscriptMessage 解密后的调解消息
+type TranscriptMessage struct {
+	Seq      int    `json:"seq"`
+	SenderID string `json:"sender_id"`
+	Content  string `json:"content"`
+	SentAt   int64  `json:"sent_at"`
+	Hash     string `json:"hash"`
+}
+
+// TranscriptCitation 对调解记录区间 [From, To] 的引用
+// StartHash 为 From 条目的前驱哈希，EndHash 为 To 条目的哈希，
+// 持有记录的任何一方都可以据此校验被引用的内容未被改动。
+type TranscriptCitation struct {
+	ArbitratorID string `json:"arbitrator_id,omitempty"`
+	From         int    `json:"from"`
+	To           int    `json:"to"`
+	StartHash    string `json:"start_hash,omitempty"`
+	EndHash      string `json:"end_hash,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+// IsMember 判断节点是否为频道成员
+func (c *MediationChannel) IsMember(nodeID string) bool {
+	_, ok := c.WrappedKeys[nodeID]
+	return ok
+}
+
+// Head 返回记录链头哈希
+func (c *MediationChannel) Head(disputeID string) string {
+	if len(c.Transcript) == 0 {
+		return transcriptGenesis(disputeID)
+	}
+	return c.Transcript[len(c.Transcript)-1].Hash
+}
+
+// PostMessage 向调解频道发送消息
+func (dm *DisputeManager) PostMessage(disputeID, senderID, content string) (*TranscriptEntry, error) {
+	if content == "" {
+		return nil, ErrEmptyMessage
+	}
+	if dm.config.MaxChannelMessage > 0 && len(content) > dm.config.MaxChannelMessage {
+		return nil, ErrMessageTooLarge
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	channel := dispute.Channel
+	if channel == nil {
+		return nil, ErrChannelNotOpen
+	}
+	if channel.Closed {
+		return nil, ErrChannelClosed
+	}
+	if !channel.IsMember(senderID) {
+		return nil, ErrNotChannelMember
+	}
+
+	key, err := dm.channelKeyLocked(dispute)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := sealMessage(key, []byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := TranscriptEntry{
+		Seq:        len(channel.Transcript) + 1,
+		SenderID:   senderID,
+		Ciphertext: ciphertext,
+		SentAt:     time.Now().Unix(),
+		PrevHash:   channel.Head(disputeID),
+	}
+	entry.Hash = transcriptEntryHash(&entry)
+	channel.Transcript = append(channel.Transcript, entry)
+	dispute.UpdatedAt = time.Now().Unix()
+
+	dm.save()
+	return &entry, nil
+}
+
+// ReadTranscript 解密调解记录，仅频道成员可读
+func (dm *DisputeManager) ReadTranscript(disputeID, readerID string) ([]TranscriptMessage, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	channel := dispute.Channel
+	if channel == nil {
+		return nil, ErrChannelNotOpen
+	}
+	if !channel.IsMember(readerID) {
+		return nil, ErrNotChannelMember
+	}
+
+	key, err := dm.channelKeyLocked(dispute)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]TranscriptMessage, 0, len(channel.Transcript))
+	for _, e := range channel.Transcript {
+		plaintext, err := openMessage(key, e.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt message %d: %w", e.Seq, err)
+		}
+		messages = append(messages, TranscriptMessage{
+			Seq:      e.Seq,
+			SenderID: e.SenderID,
+			Content:  string(plaintext),
+			SentAt:   e.SentAt,
+			Hash:     e.Hash,
+		})
+	}
+	return messages, nil
+}
+
+// VerifyTranscript 校验调解记录哈希链，返回链头哈希
+func (dm *DisputeManager) VerifyTranscript(disputeID string) (string, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return "", ErrDisputeNotFound
+	}
+	if dispute.Channel == nil {
+		return "", ErrChannelNotOpen
+	}
+	return verifyTranscript(disputeID, dispute.Channel.Transcript)
+}
+
+// openChannelLocked 为争议建立调解频道，调用方需持有写锁
+func (dm *DisputeManager) openChannelLocked(dispute *Dispute, arbitrators []string) error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	members := append([]string{dispute.ComplainantID, dispute.DefendantID}, arbitrators...)
+	channel := &MediationChannel{
+		WrappedKeys: make(map[string]string, len(members)),
+		Transcript:  make([]TranscriptEntry, 0),
+		OpenedAt:    time.Now().Unix(),
+	}
+	for _, m := range members {
+		if m == "" || channel.IsMember(m) {
+			continue
+		}
+		wrapped, err := dm.config.WrapKeyFunc(m, key)
+		if err != nil {
+			return err
+		}
+		channel.Members = append(channel.Members, m)
+		channel.WrappedKeys[m] = base64.StdEncoding.EncodeToString(wrapped)
+	}
+
+	dispute.Channel = channel
+	dm.channelKeys[dispute.ID] = key
+	return nil
+}
+
+// channelKeyLocked 取频道密钥，缓存未命中时用本节点私钥解包
+func (dm *DisputeManager) channelKeyLocked(dispute *Dispute) ([]byte, error) {
+	if key, ok := dm.channelKeys[dispute.ID]; ok {
+		return key, nil
+	}
+	if dm.config.UnwrapKeyFunc == nil {
+		return nil, ErrEncryptionUnavail
+	}
+	for _, m := range dispute.Channel.Members {
+		wrapped, err := base64.StdEncoding.DecodeString(dispute.Channel.WrappedKeys[m])
+		if err != nil {
+			continue
+		}
+		if key, err := dm.config.UnwrapKeyFunc(wrapped); err == nil {
+			dm.channelKeys[dispute.ID] = key
+			return key, nil
+		}
+	}
+	return nil, ErrNotChannelMember
+}
+
+// archiveChannelLocked 关闭调解频道并把链头哈希归档为证据
+func (dm *DisputeManager) archiveChannelLocked(dispute *Dispute) {
+	channel := dispute.Channel
+	if channel == nil || channel.Closed {
+		return
+	}
+	now := time.Now().Unix()
+	channel.Closed = true
+	channel.ClosedAt = now
+	delete(dm.channelKeys, dispute.ID)
+
+	dispute.Evidence = append(dispute.Evidence, Evidence{
+		ID:          dm.generateID(),
+		DisputeID:   dispute.ID,
+		SubmitterID: "system",
+		Type:        EvidenceTranscript,
+		Content:     fmt.Sprintf("mediation transcript, %d messages", len(channel.Transcript)),
+		Hash:        channel.Head(dispute.ID),
+		SubmittedAt: now,
+		Verified:    true,
+	})
+}
+
+// resolveCitationsLocked 校验仲裁员引用的记录区间并补全区间哈希
+func (dm *DisputeManager) resolveCitationsLocked(dispute *Dispute, arbitratorID string, citations []TranscriptCitation) ([]TranscriptCitation, error) {
+	if len(citations) == 0 {
+		return nil, nil
+	}
+	channel := dispute.Channel
+	if channel == nil {
+		return nil, ErrChannelNotOpen
+	}
+	if !channel.IsMember(arbitratorID) {
+		return nil, ErrNotChannelMember
+	}
+
+	resolved := make([]TranscriptCitation, 0, len(citations))
+	for _, c := range citations {
+		if c.From < 1 || c.To < c.From || c.To > len(channel.Transcript) {
+			return nil, fmt.Errorf("%w: [%d, %d] outside transcript of %d messages", ErrInvalidCitation, c.From, c.To, len(channel.Transcript))
+		}
+		c.ArbitratorID = arbitratorID
+		c.StartHash = channel.Transcript[c.From-1].PrevHash
+		c.EndHash = channel.Transcript[c.To-1].Hash
+		resolved = append(resolved, c)
+	}
+	return resolved, nil
+}
+
+// verifyTranscript 校验记录哈希链，返回链头哈希
+func verifyTranscript(disputeID string, transcript []TranscriptEntry) (string, error) {
+	prev := transcriptGenesis(disputeID)
+	for i := range transcript {
+		e := &transcript[i]
+		if e.Seq != i+1 || e.PrevHash != prev || e.Hash != transcriptEntryHash(e) {
+			return "", fmt.Errorf("%w at message %d", ErrTranscriptBroken, i+1)
+		}
+		prev = e.Hash
+	}
+	return prev, nil
+}
+
+// VerifyCitation 用调解记录校验引用的区间哈希
+func VerifyCitation(disputeID string, transcript []TranscriptEntry, c TranscriptCitation) error {
+	if _, err := verifyTranscript(disputeID, transcript); err != nil {
+		return err
+	}
+	if c.From < 1 || c.To < c.From || c.To > len(transcript) {
+		return ErrInvalidCitation
+	}
+	if transcript[c.From-1].PrevHash != c.StartHash || transcript[c.To-1].Hash != c.EndHash {
+		return ErrInvalidCitation
+	}
+	return nil
+}
+
+// transcriptGenesis 记录链的起始哈希，绑定争议ID防止记录被挪用
+func transcriptGenesis(disputeID string) string {
+	sum := sha256.Sum256([]byte("dispute-transcript|" + disputeID))
+	return hex.EncodeToString(sum[:])
+}
+
+func transcriptEntryHash(e *TranscriptEntry) string {
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(e.Seq)))
+	h.Write([]byte{0})
+	h.Write([]byte(e.SenderID))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(e.SentAt, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Ciphertext))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sealMessage AES-256-GCM 加密，返回 base64(nonce|密文)
+func sealMessage(key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// openMessage 解密 sealMessage 的输出
+func openMessage(key []byte, ciphertext string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
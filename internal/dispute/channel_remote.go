@@ -0,0 +1,79 @@
+package dispute
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// 调解频道的远程访问：争议保存在申诉方节点上，被诉方和仲裁员经 ChannelProtocol 向该节点
+// 发言和读取记录。请求方身份取自加密连接的对端节点ID，不由请求内容声明；
+// 消息在保存争议的节点上用频道密钥加密入链，读取时由该节点解密后只返回给频道成员。
+
+// ChannelProtocol 调解频道远程访问的协议ID（使用时按网络命名空间加前缀）
+const ChannelProtocol = "/daan/dispute-channel/1.0.0"
+
+const (
+	maxChannelRequestSize  = 1 << 20  // 单条请求的最大长度
+	maxChannelResponseSize = 16 << 20 // 单条响应（完整记录）的最大长度
+)
+
+// channelErrors 可以跨节点还原的错误，响应中按错误文本匹配
+var channelErrors = []error{
+	ErrDisputeNotFound, ErrChannelNotOpen, ErrChannelClosed, ErrNotChannelMember,
+	ErrEncryptionUnavail, ErrEmptyMessage, ErrMessageTooLarge,
+}
+
+// ChannelRequest 远程成员的请求：Content 非空表示发言，为空表示读取记录
+type ChannelRequest struct {
+	DisputeID string `json:"dispute_id"`
+	Content   string `json:"content,omitempty"`
+}
+
+// ChannelResponse 保存争议的节点的响应
+type ChannelResponse struct {
+	Entry    *TranscriptEntry    `json:"entry,omitempty"`
+	Messages []TranscriptMessage `json:"messages,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// HandleChannelRequest 处理其他成员经 ChannelProtocol 发来的一条请求，from 为连接的对端节点ID
+func (dm *DisputeManager) HandleChannelRequest(rw io.ReadWriter, from string) error {
+	var req ChannelRequest
+	if err := json.NewDecoder(io.LimitReader(rw, maxChannelRequestSize)).Decode(&req); err != nil {
+		return err
+	}
+
+	var resp ChannelResponse
+	var err error
+	if req.Content != "" {
+		resp.Entry, err = dm.PostMessage(req.DisputeID, from, req.Content)
+	} else {
+		resp.Messages, err = dm.ReadTranscript(req.DisputeID, from)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return json.NewEncoder(rw).Encode(&resp)
+}
+
+// RequestChannel 经已建立的流向保存争议的节点发出一条请求
+// 对方拒绝时返回其错误，已知的错误还原为本包的错误值。
+func RequestChannel(rw io.ReadWriter, req *ChannelRequest) (*ChannelResponse, error) {
+	if err := json.NewEncoder(rw).Encode(req); err != nil {
+		return nil, err
+	}
+	var resp ChannelResponse
+	if err := json.NewDecoder(io.LimitReader(rw, maxChannelResponseSize)).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		for _, known := range channelErrors {
+			if resp.Error == known.Error() {
+				return nil, known
+			}
+		}
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,46 @@
+package dispute
+
+import (
+	"net"
+	"testing"
+)
+
+// requestRemote 模拟 from 节点经 ChannelProtocol 访问 dm 上的调解频道
+func requestRemote(t *testing.T, dm *DisputeManager, from string, req *ChannelRequest) (*ChannelResponse, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		dm.HandleChannelRequest(server, from)
+	}()
+	return RequestChannel(client, req)
+}
+
+func TestRemoteChannel(t *testing.T) {
+	keys := mediationKeys("complainant1", "defendant1", "arb1", "arb2", "arb3")
+	dm := newMediationManager(t, t.TempDir(), "complainant1", keys)
+	dispute := startMediation(t, dm)
+
+	resp, err := requestRemote(t, dm, "defendant1", &ChannelRequest{DisputeID: dispute.ID, Content: "Section 3 was out of scope"})
+	if err != nil || resp.Entry == nil || resp.Entry.SenderID != "defendant1" {
+		t.Fatalf("remote post = %+v, %v", resp, err)
+	}
+	dm.PostMessage(dispute.ID, "complainant1", "It was in the brief")
+
+	resp, err = requestRemote(t, dm, "arb2", &ChannelRequest{DisputeID: dispute.ID})
+	if err != nil || len(resp.Messages) != 2 || resp.Messages[0].Content != "Section 3 was out of scope" {
+		t.Fatalf("remote read = %+v, %v", resp, err)
+	}
+
+	// 发言人取自连接对端，非成员既不能发言也不能读取
+	if _, err := requestRemote(t, dm, "outsider", &ChannelRequest{DisputeID: dispute.ID, Content: "hi"}); err != ErrNotChannelMember {
+		t.Errorf("expected ErrNotChannelMember, got %v", err)
+	}
+	if _, err := requestRemote(t, dm, "outsider", &ChannelRequest{DisputeID: dispute.ID}); err != ErrNotChannelMember {
+		t.Errorf("expected ErrNotChannelMember, got %v", err)
+	}
+	if _, err := requestRemote(t, dm, "arb1", &ChannelRequest{DisputeID: "missing"}); err != ErrDisputeNotFound {
+		t.Errorf("expected ErrDisputeNotFound, got %v", err)
+	}
+}
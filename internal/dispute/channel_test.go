@@ -0,0 +1,176 @@
+package dispute
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+)
+
+// newMediationManager 测试用争议管理器，本节点以 local 身份解包频道密钥
+func newMediationManager(t *testing.T, dataDir, local string, keys map[string]ed25519.PrivateKey) *DisputeManager {
+	t.Helper()
+	config := DefaultDisputeConfig()
+	config.DataDir = dataDir
+	config.WrapKeyFunc = func(member string, key []byte) ([]byte, error) {
+		priv, ok := keys[member]
+		if !ok {
+			return nil, errors.New("unknown member")
+		}
+		return crypto.SealToEd25519(priv.Public().(ed25519.PublicKey), key)
+	}
+	config.UnwrapKeyFunc = func(wrapped []byte) ([]byte, error) {
+		return crypto.OpenWithEd25519(keys[local], wrapped)
+	}
+	return NewDisputeManager(config)
+}
+
+func mediationKeys(ids ...string) map[string]ed25519.PrivateKey {
+	keys := make(map[string]ed25519.PrivateKey)
+	for _, id := range ids {
+		_, priv, _ := ed25519.GenerateKey(rand.Reader)
+		keys[id] = priv
+	}
+	return keys
+}
+
+func startMediation(t *testing.T, dm *DisputeManager) *Dispute {
+	t.Helper()
+	dispute, err := dm.CreateDispute("task1", "complainant1", "defendant1", DisputeQualityIssue, "Output unusable", 100.0)
+	if err != nil {
+		t.Fatalf("CreateDispute failed: %v", err)
+	}
+	dm.SubmitEvidence(dispute.ID, "complainant1", "text", "Evidence", "hash")
+	dm.StartReview(dispute.ID)
+	if err := dm.StartArbitration(dispute.ID, []string{"arb1", "arb2", "arb3"}); err != nil {
+		t.Fatalf("StartArbitration failed: %v", err)
+	}
+	return dispute
+}
+
+func TestMediationChannel(t *testing.T) {
+	keys := mediationKeys("complainant1", "defendant1", "arb1", "arb2", "arb3")
+	dir := t.TempDir()
+	dm := newMediationManager(t, dir, "arb1", keys)
+	dispute := startMediation(t, dm)
+
+	if dispute.Channel == nil || len(dispute.Channel.Members) != 5 {
+		t.Fatalf("channel should include both parties and arbitrators: %+v", dispute.Channel)
+	}
+
+	if _, err := dm.PostMessage(dispute.ID, "complainant1", "The report is missing section 3"); err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+	if _, err := dm.PostMessage(dispute.ID, "defendant1", "Section 3 was out of scope"); err != nil {
+		t.Fatalf("PostMessage failed: %v", err)
+	}
+	if _, err := dm.PostMessage(dispute.ID, "outsider", "hi"); err != ErrNotChannelMember {
+		t.Errorf("expected ErrNotChannelMember, got %v", err)
+	}
+	if _, err := dm.PostMessage(dispute.ID, "arb1", ""); err != ErrEmptyMessage {
+		t.Errorf("expected ErrEmptyMessage, got %v", err)
+	}
+
+	// 落盘的只有密文
+	if strings.Contains(dispute.Channel.Transcript[0].Ciphertext, "section") {
+		t.Error("transcript should be stored encrypted")
+	}
+
+	// 重启后由成员私钥恢复频道密钥
+	reloaded := newMediationManager(t, dir, "arb1", keys)
+	messages, err := reloaded.ReadTranscript(dispute.ID, "arb1")
+	if err != nil {
+		t.Fatalf("ReadTranscript failed: %v", err)
+	}
+	if len(messages) != 2 || messages[1].SenderID != "defendant1" || messages[1].Content != "Section 3 was out of scope" {
+		t.Errorf("unexpected transcript: %+v", messages)
+	}
+	if _, err := reloaded.ReadTranscript(dispute.ID, "outsider"); err != ErrNotChannelMember {
+		t.Errorf("expected ErrNotChannelMember, got %v", err)
+	}
+	stranger := newMediationManager(t, dir, "outsider", mediationKeys("outsider"))
+	if _, err := stranger.ReadTranscript(dispute.ID, "arb1"); err != ErrNotChannelMember {
+		t.Errorf("node without a wrapped key should not decrypt, got %v", err)
+	}
+}
+
+func TestMediationTranscriptEvidence(t *testing.T) {
+	keys := mediationKeys("complainant1", "defendant1", "arb1", "arb2", "arb3")
+	dm := newMediationManager(t, t.TempDir(), "arb1", keys)
+	dispute := startMediation(t, dm)
+
+	dm.PostMessage(dispute.ID, "complainant1", "Delivered file fails checksum")
+	dm.PostMessage(dispute.ID, "defendant1", "I uploaded the wrong version")
+	dm.PostMessage(dispute.ID, "arb1", "Noted")
+
+	cite := []TranscriptCitation{{From: 1, To: 2, Note: "defendant concedes the wrong file"}}
+	if err := dm.SubmitVoteWithCitations(dispute.ID, "arb1", "complainant1", "Admission", "sig1", []TranscriptCitation{{From: 2, To: 4}}); !errors.Is(err, ErrInvalidCitation) {
+		t.Errorf("expected ErrInvalidCitation, got %v", err)
+	}
+	if err := dm.SubmitVoteWithCitations(dispute.ID, "arb1", "complainant1", "Admission", "sig1", cite); err != nil {
+		t.Fatalf("SubmitVoteWithCitations failed: %v", err)
+	}
+	dm.SubmitVote(dispute.ID, "arb2", "complainant1", "Agree", "sig2")
+	dm.SubmitVote(dispute.ID, "arb3", "defendant1", "Honest mistake", "sig3")
+
+	resolution, err := dm.FinalizeArbitration(dispute.ID)
+	if err != nil {
+		t.Fatalf("FinalizeArbitration failed: %v", err)
+	}
+	if len(resolution.Citations) != 1 || resolution.Citations[0].ArbitratorID != "arb1" {
+		t.Fatalf("resolution should carry the majority citations: %+v", resolution.Citations)
+	}
+
+	// 频道关闭，链头哈希归档为证据
+	if _, err := dm.PostMessage(dispute.ID, "arb1", "late"); err != ErrChannelClosed {
+		t.Errorf("expected ErrChannelClosed, got %v", err)
+	}
+	head, err := dm.VerifyTranscript(dispute.ID)
+	if err != nil {
+		t.Fatalf("VerifyTranscript failed: %v", err)
+	}
+	archived := dispute.Evidence[len(dispute.Evidence)-1]
+	if archived.Type != EvidenceTranscript || archived.Hash != head || !archived.Verified {
+		t.Errorf("unexpected transcript evidence: %+v", archived)
+	}
+
+	transcript := dispute.Channel.Transcript
+	if err := VerifyCitation(dispute.ID, transcript, resolution.Citations[0]); err != nil {
+		t.Errorf("VerifyCitation failed: %v", err)
+	}
+
+	// 改动被引用区间内的记录后校验失败
+	tampered := append([]TranscriptEntry(nil), transcript...)
+	tampered[1].SenderID = "arb2"
+	if err := VerifyCitation(dispute.ID, tampered, resolution.Citations[0]); !errors.Is(err, ErrTranscriptBroken) {
+		t.Errorf("expected ErrTranscriptBroken, got %v", err)
+	}
+	// 记录不能挪用到其他争议
+	if _, err := verifyTranscript("dispute_other", transcript); !errors.Is(err, ErrTranscriptBroken) {
+		t.Errorf("expected ErrTranscriptBroken, got %v", err)
+	}
+}
+
+func TestMediationChannelDisabled(t *testing.T) {
+	dm := NewDisputeManager(&DisputeConfig{
+		DataDir:           t.TempDir(),
+		MinEvidenceCount:  1,
+		MinVotesRequired:  3,
+		ArbitrationPeriod: 72 * time.Hour,
+	})
+	dispute := startMediation(t, dm)
+
+	if dispute.Channel != nil {
+		t.Error("channel should not open without key wrapping")
+	}
+	if _, err := dm.PostMessage(dispute.ID, "arb1", "hello"); err != ErrChannelNotOpen {
+		t.Errorf("expected ErrChannelNotOpen, got %v", err)
+	}
+	if err := dm.SubmitVoteWithCitations(dispute.ID, "arb1", "complainant1", "r", "s", []TranscriptCitation{{From: 1, To: 1}}); err != ErrChannelNotOpen {
+		t.Errorf("expected ErrChannelNotOpen, got %v", err)
+	}
+}
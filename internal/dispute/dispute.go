@@ -15,12 +15,14 @@ import (
 )
 
 var (
-	ErrDisputeNotFound  = errors.New("dispute not found")
-	ErrDisputeResolved  = errors.New("dispute already resolved")
-	ErrInvalidEvidence  = errors.New("invalid evidence")
-	ErrUnauthorized     = errors.New("unauthorized")
-	ErrVotingClosed     = errors.New("voting is closed")
-	ErrAlreadyVoted     = errors.New("already voted")
+	ErrDisputeNotFound      = errors.New("dispute not found")
+	ErrDisputeResolved      = errors.New("dispute already resolved")
+	ErrInvalidEvidence      = errors.New("invalid evidence")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrVotingClosed         = errors.New("voting is closed")
+	ErrAlreadyVoted         = errors.New("already voted")
+	ErrEvidenceHashMismatch = errors.New("evidence hash mismatch")
+	ErrEvidenceTooLarge     = errors.New("evidence too large")
 )
 
 // DisputeStatus 争议状态
@@ -35,6 +37,17 @@ const (
 	DisputeExpired     DisputeStatus = "expired"      // 已过期
 )
 
+// DisputeStage 争议预审阶段：filed → evidence → suggested → resolved
+// 新证据或证据验证会使已有建议失效，阶段退回 evidence。
+type DisputeStage string
+
+const (
+	StageFiled     DisputeStage = "filed"     // 已立案，尚无证据
+	StageEvidence  DisputeStage = "evidence"  // 举证中
+	StageSuggested DisputeStage = "suggested" // 已生成裁决建议
+	StageResolved  DisputeStage = "resolved"  // 已裁决或驳回
+)
+
 // DisputeType 争议类型
 type DisputeType string
 
@@ -58,8 +71,9 @@ const (
 
 // Dispute 争议记录
 type Dispute struct {
-	ID     string `json:"id"`
-	TaskID string `json:"task_id"`
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	EscrowID string `json:"escrow_id,omitempty"` // 关联的押金托管
 
 	// 参与方
 	ComplainantID string `json:"complainant_id"` // 申诉方
@@ -75,14 +89,22 @@ type Dispute struct {
 
 	// 状态
 	Status DisputeStatus `json:"status"`
+	Stage  DisputeStage  `json:"stage"`
+
+	// 最近一次规则引擎给出的裁决建议
+	Suggestion *AutoResolveSuggestion `json:"suggestion,omitempty"`
 
 	// 解决方案
 	Resolution       *Resolution `json:"resolution,omitempty"`
 	ResolutionType   ResolutionType `json:"resolution_type,omitempty"`
 
 	// 仲裁投票
-	Votes      []ArbitrationVote `json:"votes,omitempty"`
-	VoteDeadline int64           `json:"vote_deadline,omitempty"`
+	Arbitrators  []string          `json:"arbitrators,omitempty"`
+	Votes        []ArbitrationVote `json:"votes,omitempty"`
+	VoteDeadline int64             `json:"vote_deadline,omitempty"`
+
+	// 调解频道（仲裁期间双方与仲裁员的加密对话）
+	Channel *MediationChannel `json:"channel,omitempty"`
 
 	// 时间
 	CreatedAt  int64 `json:"created_at"`
@@ -101,6 +123,10 @@ type Evidence struct {
 	Hash        string `json:"hash"`
 	SubmittedAt int64  `json:"submitted_at"`
 	Verified    bool   `json:"verified"`
+	VerifiedBy  string `json:"verified_by,omitempty"`
+	VerifiedAt  int64  `json:"verified_at,omitempty"`
+	Size        int64  `json:"size,omitempty"`     // 原件大小
+	Attached    bool   `json:"attached,omitempty"` // 原件由本节点保存，验证时重新计算哈希
 }
 
 // Resolution 解决方案
@@ -111,6 +137,8 @@ type Resolution struct {
 	Penalty       float64 `json:"penalty"`         // 对败诉方的惩罚
 	Reason        string  `json:"reason"`
 	ResolvedBy    string  `json:"resolved_by"` // 解决者（system/committee/mutual）
+
+	Citations []TranscriptCitation `json:"citations,omitempty"` // 裁决理由引用的调解记录
 }
 
 // ArbitrationVote 仲裁投票
@@ -120,6 +148,8 @@ type ArbitrationVote struct {
 	Reason       string `json:"reason"`
 	VotedAt      int64  `json:"voted_at"`
 	Signature    string `json:"signature"`
+
+	Citations []TranscriptCitation `json:"citations,omitempty"`
 }
 
 // DisputeConfig 争议处理配置
@@ -131,6 +161,17 @@ type DisputeConfig struct {
 	ExpirationPeriod  time.Duration // 过期期
 	MinEvidenceCount  int           // 最少证据数
 	MinVotesRequired  int           // 最少仲裁票数
+	MaxEvidenceSize   int64         // 单份证据原件最大字节数，0 表示不限制
+
+	// 调解频道：频道密钥用每个成员的公钥包裹，未设置时仲裁不开启调解频道
+	WrapKeyFunc       func(member string, key []byte) ([]byte, error)
+	UnwrapKeyFunc     func(wrapped []byte) ([]byte, error)
+	MaxChannelMessage int // 单条调解消息最大字节数，0 表示不限制
+
+	// 监管链导出包签名（节点身份私钥），未设置时无法导出
+	BundleSignFunc  func(data []byte) ([]byte, error)
+	BundlePublicKey []byte
+	BundleAlgorithm string // 例如 ed25519
 }
 
 // DefaultDisputeConfig 返回默认配置
@@ -143,6 +184,8 @@ func DefaultDisputeConfig() *DisputeConfig {
 		ExpirationPeriod:  7 * 24 * time.Hour,
 		MinEvidenceCount:  1,
 		MinVotesRequired:  3,
+		MaxEvidenceSize:   4 << 20,
+		MaxChannelMessage: 4096,
 	}
 }
 
@@ -153,14 +196,25 @@ type DisputeManager struct {
 
 	// 争议记录
 	disputes map[string]*Dispute // disputeID -> dispute
+	version  uint64              // 每次保存时递增
 
 	// 索引
 	disputesByTask   map[string]string   // taskID -> disputeID
+	disputesByEscrow map[string]string   // escrowID -> disputeID
 	disputesByNode   map[string][]string // nodeID -> []disputeID
 	disputesByStatus map[DisputeStatus][]string
 
 	// 自动解决规则
 	autoRules []AutoResolveRule
+
+	// 合规保全检查：返回 true 的争议不会被标记为过期
+	retentionHeld func(disputeID string) bool
+
+	// 争议裁决后的回调（如按裁决为关联托管提出结算方案）
+	onResolved func(dispute *Dispute)
+
+	// 调解频道密钥缓存（不落盘，重启后从包裹密钥恢复）
+	channelKeys map[string][]byte
 }
 
 // AutoResolveRule 自动解决规则
@@ -192,15 +246,32 @@ func NewDisputeManager(config *DisputeConfig) *DisputeManager {
 		config:           config,
 		disputes:         make(map[string]*Dispute),
 		disputesByTask:   make(map[string]string),
+		disputesByEscrow: make(map[string]string),
 		disputesByNode:   make(map[string][]string),
 		disputesByStatus: make(map[DisputeStatus][]string),
 		autoRules:        defaultAutoRules(),
+		channelKeys:      make(map[string][]byte),
 	}
 
 	dm.load()
 	return dm
 }
 
+// SetRetentionHoldFunc 设置合规保全检查函数
+func (dm *DisputeManager) SetRetentionHoldFunc(fn func(disputeID string) bool) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.retentionHeld = fn
+}
+
+// SetResolutionHandler 设置争议裁决（自动建议获批或委员会裁决）后的回调
+// 回调在持有管理器锁时调用，不能再调用 DisputeManager 的方法。
+func (dm *DisputeManager) SetResolutionHandler(fn func(dispute *Dispute)) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.onResolved = fn
+}
+
 // RegisterAutoRule 注册自动解决规则
 func (dm *DisputeManager) RegisterAutoRule(rule AutoResolveRule) {
 	dm.mu.Lock()
@@ -213,14 +284,22 @@ func (dm *DisputeManager) CreateDispute(taskID, complainantID, defendantID strin
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
+	return dm.createDisputeLocked(taskID, "", complainantID, defendantID, disputeType, description, amount)
+}
+
+func (dm *DisputeManager) createDisputeLocked(taskID, escrowID, complainantID, defendantID string, disputeType DisputeType, description string, amount float64) (*Dispute, error) {
 	// 检查是否已存在
 	if _, exists := dm.disputesByTask[taskID]; exists {
 		return nil, errors.New("dispute already exists for this task")
 	}
+	if _, exists := dm.disputesByEscrow[escrowID]; escrowID != "" && exists {
+		return nil, errors.New("dispute already exists for this escrow")
+	}
 
 	dispute := &Dispute{
 		ID:            dm.generateID(),
 		TaskID:        taskID,
+		EscrowID:      escrowID,
 		ComplainantID: complainantID,
 		DefendantID:   defendantID,
 		Type:          disputeType,
@@ -228,6 +307,7 @@ func (dm *DisputeManager) CreateDispute(taskID, complainantID, defendantID strin
 		Amount:        amount,
 		Evidence:      make([]Evidence, 0),
 		Status:        DisputePending,
+		Stage:         StageFiled,
 		CreatedAt:     time.Now().Unix(),
 		UpdatedAt:     time.Now().Unix(),
 		ExpiresAt:     time.Now().Add(dm.config.ExpirationPeriod).Unix(),
@@ -235,6 +315,9 @@ func (dm *DisputeManager) CreateDispute(taskID, complainantID, defendantID strin
 
 	dm.disputes[dispute.ID] = dispute
 	dm.disputesByTask[taskID] = dispute.ID
+	if escrowID != "" {
+		dm.disputesByEscrow[escrowID] = dispute.ID
+	}
 	dm.disputesByNode[complainantID] = append(dm.disputesByNode[complainantID], dispute.ID)
 	dm.disputesByNode[defendantID] = append(dm.disputesByNode[defendantID], dispute.ID)
 	dm.disputesByStatus[DisputePending] = append(dm.disputesByStatus[DisputePending], dispute.ID)
@@ -275,12 +358,15 @@ func (dm *DisputeManager) SubmitEvidence(disputeID, submitterID, evidenceType, c
 
 	dispute.Evidence = append(dispute.Evidence, evidence)
 	dispute.UpdatedAt = time.Now().Unix()
+	dm.invalidateSuggestionLocked(dispute)
 
 	dm.save()
 	return nil
 }
 
 // VerifyEvidence Task44: 验证证据（将证据标记为已验证）
+// 本节点保存了原件的证据重新计算哈希，与提交时记录的不一致返回 ErrEvidenceHashMismatch；
+// 只提交了外部哈希的证据由验证者人工核对。
 func (dm *DisputeManager) VerifyEvidence(disputeID, evidenceID, verifierID string) error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
@@ -291,12 +377,29 @@ func (dm *DisputeManager) VerifyEvidence(disputeID, evidenceID, verifierID strin
 	}
 
 	for i := range dispute.Evidence {
-		if dispute.Evidence[i].ID == evidenceID {
-			dispute.Evidence[i].Verified = true
-			dispute.UpdatedAt = time.Now().Unix()
-			dm.save()
+		evidence := &dispute.Evidence[i]
+		if evidence.ID != evidenceID {
+			continue
+		}
+		if evidence.Attached {
+			data, err := dm.readEvidenceBlob(evidence.Hash)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrEvidenceHashMismatch, err)
+			}
+			if sha256Hex(data) != evidence.Hash {
+				return ErrEvidenceHashMismatch
+			}
+		}
+		if evidence.Verified {
 			return nil
 		}
+		evidence.Verified = true
+		evidence.VerifiedBy = verifierID
+		evidence.VerifiedAt = time.Now().Unix()
+		dispute.UpdatedAt = evidence.VerifiedAt
+		dm.invalidateSuggestionLocked(dispute)
+		dm.save()
+		return nil
 	}
 
 	return ErrInvalidEvidence
@@ -350,52 +453,61 @@ func (dm *DisputeManager) TryAutoResolve(disputeID string) (*AutoResolveSuggesti
 		return nil, fmt.Errorf("cannot auto resolve: dispute status is %s", dispute.Status)
 	}
 
+	return dm.suggestLocked(dispute)
+}
+
+// suggestLocked 按规则引擎计算裁决建议并记录在争议上
+// 规则只看已验证的证据；置信度随已验证证据的比例从 0.5 升到 1。
+func (dm *DisputeManager) suggestLocked(dispute *Dispute) (*AutoResolveSuggestion, error) {
 	// Task44: 检查证据是否已验证
 	var warnings []string
 	var missingEvidence []string
-	hasVerifiedEvidence := false
+	verified := make([]Evidence, 0, len(dispute.Evidence))
 	for _, e := range dispute.Evidence {
 		if e.Verified {
-			hasVerifiedEvidence = true
+			verified = append(verified, e)
 		} else {
 			warnings = append(warnings, fmt.Sprintf("evidence '%s' (type=%s) is not verified", e.ID, e.Type))
 		}
 	}
-	if !hasVerifiedEvidence {
+	if len(verified) == 0 {
 		missingEvidence = append(missingEvidence, "at least one verified evidence required")
 	}
+	view := *dispute
+	view.Evidence = verified
 
 	// 尝试匹配规则
 	for _, rule := range dm.autoRules {
-		if rule.Type == dispute.Type && rule.Condition(dispute) {
-			resolution := rule.Resolution(dispute)
-			resolution.ResolvedBy = "system_suggestion" // Task44: 标记为建议而非最终裁决
-
-			// Task44: 计算置信度（基于已验证证据比例）
-			confidence := 0.5 // 基础置信度
-			verifiedCount := 0
-			for _, e := range dispute.Evidence {
-				if e.Verified {
-					verifiedCount++
-				}
-			}
-			if len(dispute.Evidence) > 0 {
-				confidence = 0.5 + 0.5*float64(verifiedCount)/float64(len(dispute.Evidence))
-			}
+		if rule.Type != dispute.Type || !rule.Condition(&view) {
+			continue
+		}
+		resolution := rule.Resolution(&view)
+		resolution.ResolvedBy = "system_suggestion" // Task44: 标记为建议而非最终裁决
+
+		confidence := 0.5 // 基础置信度
+		if len(dispute.Evidence) > 0 {
+			confidence = 0.5 + 0.5*float64(len(verified))/float64(len(dispute.Evidence))
+		}
 
+		suggestion := &AutoResolveSuggestion{
+			DisputeID:       dispute.ID,
+			MatchedRule:     rule.Description,
+			Suggestion:      resolution,
+			Confidence:      confidence,
+			MissingEvidence: missingEvidence,
+			Warnings:        warnings,
 			// Task44: 仅当所有关键证据已验证时才允许自动执行
-			canAutoExecute := hasVerifiedEvidence && len(warnings) == 0
-
-			return &AutoResolveSuggestion{
-				DisputeID:       disputeID,
-				MatchedRule:     rule.Description,
-				Suggestion:      resolution,
-				Confidence:      confidence,
-				MissingEvidence: missingEvidence,
-				Warnings:        warnings,
-				CanAutoExecute:  canAutoExecute,
-			}, nil
+			CanAutoExecute: len(verified) > 0 && len(warnings) == 0,
 		}
+
+		stored := *suggestion
+		storedResolution := *resolution
+		stored.Suggestion = &storedResolution
+		dispute.Suggestion = &stored
+		dispute.Stage = StageSuggested
+		dispute.UpdatedAt = time.Now().Unix()
+		dm.save()
+		return suggestion, nil
 	}
 
 	return nil, errors.New("no matching auto-resolve rule")
@@ -419,6 +531,10 @@ func (dm *DisputeManager) ApplyAutoResolution(disputeID string, suggestion *Auto
 		return nil, fmt.Errorf("cannot apply resolution: dispute status is %s", dispute.Status)
 	}
 
+	return dm.applySuggestionLocked(dispute, suggestion, approverID)
+}
+
+func (dm *DisputeManager) applySuggestionLocked(dispute *Dispute, suggestion *AutoResolveSuggestion, approverID string) (*Resolution, error) {
 	// Task44: 仅当满足条件时才允许执行
 	if !suggestion.CanAutoExecute {
 		return nil, errors.New("suggestion cannot be auto-executed: missing verified evidence")
@@ -430,11 +546,15 @@ func (dm *DisputeManager) ApplyAutoResolution(disputeID string, suggestion *Auto
 	dispute.Resolution = resolution
 	dispute.ResolutionType = ResolutionAutomatic
 	dispute.Status = DisputeResolved
+	dispute.Stage = StageResolved
 	dispute.ResolvedAt = time.Now().Unix()
 	dispute.UpdatedAt = time.Now().Unix()
 
-	dm.updateStatusIndex(disputeID, DisputeInReview, DisputeResolved)
+	dm.updateStatusIndex(dispute.ID, DisputeInReview, DisputeResolved)
 	dm.save()
+	if dm.onResolved != nil {
+		dm.onResolved(dispute)
+	}
 
 	return resolution, nil
 }
@@ -457,8 +577,15 @@ func (dm *DisputeManager) StartArbitration(disputeID string, arbitrators []strin
 		return fmt.Errorf("need at least %d arbitrators", dm.config.MinVotesRequired)
 	}
 
+	if dm.config.WrapKeyFunc != nil {
+		if err := dm.openChannelLocked(dispute, arbitrators); err != nil {
+			return fmt.Errorf("open mediation channel: %w", err)
+		}
+	}
+
 	oldStatus := dispute.Status
 	dispute.Status = DisputeArbitration
+	dispute.Arbitrators = append([]string(nil), arbitrators...)
 	dispute.Votes = make([]ArbitrationVote, 0)
 	dispute.VoteDeadline = time.Now().Add(dm.config.ArbitrationPeriod).Unix()
 	dispute.UpdatedAt = time.Now().Unix()
@@ -471,6 +598,11 @@ func (dm *DisputeManager) StartArbitration(disputeID string, arbitrators []strin
 
 // SubmitVote 提交仲裁投票
 func (dm *DisputeManager) SubmitVote(disputeID, arbitratorID, voteFor, reason, signature string) error {
+	return dm.SubmitVoteWithCitations(disputeID, arbitratorID, voteFor, reason, signature, nil)
+}
+
+// SubmitVoteWithCitations 提交仲裁投票，并在理由中引用调解记录区间
+func (dm *DisputeManager) SubmitVoteWithCitations(disputeID, arbitratorID, voteFor, reason, signature string, citations []TranscriptCitation) error {
 	dm.mu.Lock()
 	defer dm.mu.Unlock()
 
@@ -500,12 +632,18 @@ func (dm *DisputeManager) SubmitVote(disputeID, arbitratorID, voteFor, reason, s
 		return fmt.Errorf("vote_for must be either complainant or defendant")
 	}
 
+	cited, err := dm.resolveCitationsLocked(dispute, arbitratorID, citations)
+	if err != nil {
+		return err
+	}
+
 	vote := ArbitrationVote{
 		ArbitratorID: arbitratorID,
 		VoteFor:      voteFor,
 		Reason:       reason,
 		VotedAt:      time.Now().Unix(),
 		Signature:    signature,
+		Citations:    cited,
 	}
 
 	dispute.Votes = append(dispute.Votes, vote)
@@ -564,15 +702,26 @@ func (dm *DisputeManager) FinalizeArbitration(disputeID string) (*Resolution, er
 		Reason:         fmt.Sprintf("Committee arbitration: %d votes for winner", maxVotes),
 		ResolvedBy:     "committee",
 	}
+	// 裁决理由沿用多数方仲裁员引用的调解记录
+	for _, vote := range dispute.Votes {
+		if vote.VoteFor == winner {
+			resolution.Citations = append(resolution.Citations, vote.Citations...)
+		}
+	}
 
+	dm.archiveChannelLocked(dispute)
 	dispute.Resolution = resolution
 	dispute.ResolutionType = ResolutionCommittee
 	dispute.Status = DisputeResolved
+	dispute.Stage = StageResolved
 	dispute.ResolvedAt = time.Now().Unix()
 	dispute.UpdatedAt = time.Now().Unix()
 
 	dm.updateStatusIndex(disputeID, DisputeArbitration, DisputeResolved)
 	dm.save()
+	if dm.onResolved != nil {
+		dm.onResolved(dispute)
+	}
 
 	return resolution, nil
 }
@@ -591,8 +740,10 @@ func (dm *DisputeManager) DismissDispute(disputeID, reason string) error {
 		return ErrDisputeResolved
 	}
 
+	dm.archiveChannelLocked(dispute)
 	oldStatus := dispute.Status
 	dispute.Status = DisputeDismissed
+	dispute.Stage = StageResolved
 	dispute.Resolution = &Resolution{
 		Reason:     reason,
 		ResolvedBy: "system",
@@ -677,6 +828,9 @@ func (dm *DisputeManager) CheckExpiredDisputes() []string {
 	for id, dispute := range dm.disputes {
 		if dispute.Status != DisputeResolved && dispute.Status != DisputeDismissed && dispute.Status != DisputeExpired {
 			if now > dispute.ExpiresAt {
+				if dm.retentionHeld != nil && dm.retentionHeld(id) {
+					continue
+				}
 				oldStatus := dispute.Status
 				dispute.Status = DisputeExpired
 				dispute.UpdatedAt = now
@@ -773,6 +927,12 @@ func (dm *DisputeManager) load() {
 		// 重建索引
 		for id, d := range dm.disputes {
 			dm.disputesByTask[d.TaskID] = id
+			if d.EscrowID != "" {
+				dm.disputesByEscrow[d.EscrowID] = id
+			}
+			if d.Stage == "" {
+				d.Stage = deriveStage(d)
+			}
 			dm.disputesByNode[d.ComplainantID] = append(dm.disputesByNode[d.ComplainantID], id)
 			dm.disputesByNode[d.DefendantID] = append(dm.disputesByNode[d.DefendantID], id)
 			dm.disputesByStatus[d.Status] = append(dm.disputesByStatus[d.Status], id)
@@ -780,7 +940,15 @@ func (dm *DisputeManager) load() {
 	}
 }
 
+// Version 返回争议记录的版本号，每次写入后递增
+func (dm *DisputeManager) Version() uint64 {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.version
+}
+
 func (dm *DisputeManager) save() {
+	dm.version++
 	if err := os.MkdirAll(dm.config.DataDir, 0755); err != nil {
 		return
 	}
@@ -515,6 +515,30 @@ func TestCheckExpiredDisputes(t *testing.T) {
 	}
 }
 
+func TestCheckExpiredDisputesRetentionHold(t *testing.T) {
+	dm := NewDisputeManager(&DisputeConfig{
+		DataDir:          t.TempDir(),
+		ExpirationPeriod: 1 * time.Hour,
+	})
+
+	dispute, _ := dm.CreateDispute("task1", "comp1", "def1", DisputeOther, "Issue", 100.0)
+	dm.mu.Lock()
+	dm.disputes[dispute.ID].ExpiresAt = time.Now().Unix() - 10
+	dm.mu.Unlock()
+
+	held := true
+	dm.SetRetentionHoldFunc(func(id string) bool { return held && id == dispute.ID })
+
+	if expired := dm.CheckExpiredDisputes(); len(expired) != 0 {
+		t.Errorf("Held dispute should not expire, got %v", expired)
+	}
+
+	held = false
+	if expired := dm.CheckExpiredDisputes(); len(expired) != 1 {
+		t.Errorf("Expected 1 expired dispute after release, got %d", len(expired))
+	}
+}
+
 func TestDisputePersistence(t *testing.T) {
 	tempDir := t.TempDir()
 	config := &DisputeConfig{
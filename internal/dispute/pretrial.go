@@ -0,0 +1,207 @@
+package dispute
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 争议预审：立案（可关联押金托管）、附带原件的举证、哈希验证、规则引擎建议和批准执行。
+// 证据原件按 SHA-256 内容寻址保存在 <DataDir>/evidence 下，验证时重新计算哈希。
+
+var (
+	ErrNoSuggestion = errors.New("no resolution suggestion for dispute")
+)
+
+// CreateEscrowDispute 针对押金托管创建争议，同一托管只能有一个争议
+func (dm *DisputeManager) CreateEscrowDispute(escrowID, taskID, complainantID, defendantID string, disputeType DisputeType, description string, amount float64) (*Dispute, error) {
+	if escrowID == "" {
+		return nil, errors.New("escrow_id is required")
+	}
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	return dm.createDisputeLocked(taskID, escrowID, complainantID, defendantID, disputeType, description, amount)
+}
+
+// GetDisputeByEscrow 根据押金托管获取争议
+func (dm *DisputeManager) GetDisputeByEscrow(escrowID string) (*Dispute, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	dispute, exists := dm.disputes[dm.disputesByEscrow[escrowID]]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// AttachEvidence 提交附带原件的证据，证据哈希为原件的 SHA-256
+func (dm *DisputeManager) AttachEvidence(disputeID, submitterID, evidenceType, description string, data []byte) (*Evidence, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidEvidence
+	}
+	if max := dm.config.MaxEvidenceSize; max > 0 && int64(len(data)) > max {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrEvidenceTooLarge, len(data), max)
+	}
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	if dispute.Status == DisputeResolved || dispute.Status == DisputeDismissed {
+		return nil, ErrDisputeResolved
+	}
+	if submitterID != dispute.ComplainantID && submitterID != dispute.DefendantID {
+		return nil, ErrUnauthorized
+	}
+
+	hash := sha256Hex(data)
+	if err := dm.writeEvidenceBlob(hash, data); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	evidence := Evidence{
+		ID:          dm.generateID(),
+		DisputeID:   disputeID,
+		SubmitterID: submitterID,
+		Type:        evidenceType,
+		Content:     description,
+		Hash:        hash,
+		SubmittedAt: now,
+		Size:        int64(len(data)),
+		Attached:    true,
+	}
+	dispute.Evidence = append(dispute.Evidence, evidence)
+	dispute.UpdatedAt = now
+	dm.invalidateSuggestionLocked(dispute)
+
+	dm.save()
+	return &evidence, nil
+}
+
+// EvidenceBlob 读取证据原件，并校验与记录的哈希一致
+func (dm *DisputeManager) EvidenceBlob(disputeID, evidenceID string) ([]byte, error) {
+	dm.mu.RLock()
+	dispute, exists := dm.disputes[disputeID]
+	var hash string
+	if exists {
+		for _, e := range dispute.Evidence {
+			if e.ID == evidenceID && e.Attached {
+				hash = e.Hash
+			}
+		}
+	}
+	dm.mu.RUnlock()
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	if hash == "" {
+		return nil, ErrInvalidEvidence
+	}
+
+	data, err := dm.readEvidenceBlob(hash)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != hash {
+		return nil, ErrEvidenceHashMismatch
+	}
+	return data, nil
+}
+
+// Suggest 生成裁决建议；争议仍在等待处理且证据足够时先转入审核
+func (dm *DisputeManager) Suggest(disputeID string) (*AutoResolveSuggestion, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	if !dm.config.AutoResolveRules {
+		return nil, errors.New("auto resolve is disabled")
+	}
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+
+	if dispute.Status == DisputePending {
+		if len(dispute.Evidence) < dm.config.MinEvidenceCount {
+			return nil, fmt.Errorf("insufficient evidence: need at least %d, got %d", dm.config.MinEvidenceCount, len(dispute.Evidence))
+		}
+		dispute.Status = DisputeInReview
+		dispute.UpdatedAt = time.Now().Unix()
+		dm.updateStatusIndex(disputeID, DisputePending, DisputeInReview)
+		dm.save()
+	}
+	if dispute.Status != DisputeInReview {
+		return nil, fmt.Errorf("cannot suggest resolution: dispute status is %s", dispute.Status)
+	}
+	return dm.suggestLocked(dispute)
+}
+
+// ApplySuggestion 批准并执行争议上记录的最近一次建议
+// 建议生成后又有新证据或证据验证时建议已失效，需要重新生成。
+func (dm *DisputeManager) ApplySuggestion(disputeID, approverID string) (*Resolution, error) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	dispute, exists := dm.disputes[disputeID]
+	if !exists {
+		return nil, ErrDisputeNotFound
+	}
+	if dispute.Status != DisputeInReview {
+		return nil, fmt.Errorf("cannot apply resolution: dispute status is %s", dispute.Status)
+	}
+	if dispute.Suggestion == nil {
+		return nil, ErrNoSuggestion
+	}
+
+	suggestion := *dispute.Suggestion
+	resolution := *suggestion.Suggestion
+	suggestion.Suggestion = &resolution
+	return dm.applySuggestionLocked(dispute, &suggestion, approverID)
+}
+
+// invalidateSuggestionLocked 证据变化后作废已有建议
+func (dm *DisputeManager) invalidateSuggestionLocked(dispute *Dispute) {
+	if dispute.Stage == StageResolved {
+		return
+	}
+	dispute.Suggestion = nil
+	dispute.Stage = deriveStage(dispute)
+}
+
+// deriveStage 按争议内容推断预审阶段（兼容没有记录阶段的旧数据）
+func deriveStage(d *Dispute) DisputeStage {
+	switch {
+	case d.Status == DisputeResolved || d.Status == DisputeDismissed:
+		return StageResolved
+	case d.Suggestion != nil:
+		return StageSuggested
+	case len(d.Evidence) > 0:
+		return StageEvidence
+	}
+	return StageFiled
+}
+
+func (dm *DisputeManager) evidencePath(hash string) string {
+	return filepath.Join(dm.config.DataDir, "evidence", hash)
+}
+
+func (dm *DisputeManager) writeEvidenceBlob(hash string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(dm.config.DataDir, "evidence"), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dm.evidencePath(hash), data, 0644)
+}
+
+func (dm *DisputeManager) readEvidenceBlob(hash string) ([]byte, error) {
+	if hash == "" || filepath.Base(hash) != hash {
+		return nil, ErrInvalidEvidence
+	}
+	return os.ReadFile(dm.evidencePath(hash))
+}
@@ -0,0 +1,144 @@
+package dispute
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func newPretrialManager(t *testing.T) *DisputeManager {
+	config := DefaultDisputeConfig()
+	config.DataDir = t.TempDir()
+	return NewDisputeManager(config)
+}
+
+func TestPretrialStages(t *testing.T) {
+	dm := newPretrialManager(t)
+	d, err := dm.CreateEscrowDispute("escrow_1", "task1", "requester", "worker", DisputeNonDelivery, "nothing delivered", 10.0)
+	if err != nil {
+		t.Fatalf("CreateEscrowDispute failed: %v", err)
+	}
+	if d.Stage != StageFiled || d.EscrowID != "escrow_1" {
+		t.Fatalf("new dispute: stage %s escrow %q", d.Stage, d.EscrowID)
+	}
+	if _, err := dm.CreateEscrowDispute("escrow_1", "task2", "requester", "worker", DisputeNonDelivery, "again", 1.0); err == nil {
+		t.Error("expected duplicate escrow dispute to be rejected")
+	}
+	if got, err := dm.GetDisputeByEscrow("escrow_1"); err != nil || got.ID != d.ID {
+		t.Errorf("GetDisputeByEscrow = %v, %v", got, err)
+	}
+
+	if _, err := dm.Suggest(d.ID); err == nil {
+		t.Error("expected suggestion without evidence to fail")
+	}
+	ev, err := dm.AttachEvidence(d.ID, "requester", "text", "chat log", []byte("deadline passed, no delivery"))
+	if err != nil {
+		t.Fatalf("AttachEvidence failed: %v", err)
+	}
+	if d.Stage != StageEvidence {
+		t.Errorf("after evidence: stage %s", d.Stage)
+	}
+
+	// 未验证的证据只给出低置信度、不可执行的建议
+	s, err := dm.Suggest(d.ID)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if s.CanAutoExecute || s.Confidence != 0.5 || len(s.MissingEvidence) == 0 {
+		t.Errorf("unverified suggestion = %+v", s)
+	}
+	if d.Status != DisputeInReview || d.Stage != StageSuggested {
+		t.Errorf("after suggest: status %s stage %s", d.Status, d.Stage)
+	}
+	if _, err := dm.ApplySuggestion(d.ID, "approver"); err == nil {
+		t.Error("expected unverified suggestion to be rejected")
+	}
+
+	// 验证使建议失效，需要重新生成
+	if err := dm.VerifyEvidence(d.ID, ev.ID, "verifier"); err != nil {
+		t.Fatalf("VerifyEvidence failed: %v", err)
+	}
+	if d.Stage != StageEvidence || d.Suggestion != nil {
+		t.Errorf("after verify: stage %s suggestion %v", d.Stage, d.Suggestion)
+	}
+	if _, err := dm.ApplySuggestion(d.ID, "approver"); !errors.Is(err, ErrNoSuggestion) {
+		t.Errorf("expected ErrNoSuggestion, got %v", err)
+	}
+	if s, err = dm.Suggest(d.ID); err != nil || !s.CanAutoExecute || s.Confidence != 1.0 {
+		t.Fatalf("verified suggestion = %+v, %v", s, err)
+	}
+
+	var resolved []string
+	dm.SetResolutionHandler(func(d *Dispute) { resolved = append(resolved, d.EscrowID) })
+	res, err := dm.ApplySuggestion(d.ID, "approver")
+	if err != nil {
+		t.Fatalf("ApplySuggestion failed: %v", err)
+	}
+	if res.Winner != "requester" || d.Status != DisputeResolved || d.Stage != StageResolved {
+		t.Errorf("resolution %+v, status %s stage %s", res, d.Status, d.Stage)
+	}
+	if len(resolved) != 1 || resolved[0] != "escrow_1" {
+		t.Errorf("resolution handler calls = %v", resolved)
+	}
+
+	reloaded := NewDisputeManager(dm.config)
+	if got, err := reloaded.GetDisputeByEscrow("escrow_1"); err != nil || got.Stage != StageResolved {
+		t.Errorf("reloaded dispute = %+v, %v", got, err)
+	}
+}
+
+func TestEvidenceHashVerification(t *testing.T) {
+	dm := newPretrialManager(t)
+	d, _ := dm.CreateDispute("task1", "requester", "worker", DisputeQualityIssue, "broken output", 5.0)
+
+	if _, err := dm.AttachEvidence(d.ID, "outsider", "file", "x", []byte("data")); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", err)
+	}
+	dm.config.MaxEvidenceSize = 4
+	if _, err := dm.AttachEvidence(d.ID, "worker", "file", "x", []byte("too large")); !errors.Is(err, ErrEvidenceTooLarge) {
+		t.Errorf("expected ErrEvidenceTooLarge, got %v", err)
+	}
+	dm.config.MaxEvidenceSize = 0
+
+	ev, err := dm.AttachEvidence(d.ID, "worker", "file", "output.bin", []byte("original output"))
+	if err != nil {
+		t.Fatalf("AttachEvidence failed: %v", err)
+	}
+	if ev.Hash != sha256Hex([]byte("original output")) || ev.Size != 15 {
+		t.Errorf("evidence = %+v", ev)
+	}
+	if data, err := dm.EvidenceBlob(d.ID, ev.ID); err != nil || string(data) != "original output" {
+		t.Errorf("EvidenceBlob = %q, %v", data, err)
+	}
+
+	// 原件被篡改后验证失败
+	if err := os.WriteFile(dm.evidencePath(ev.Hash), []byte("tampered output"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.VerifyEvidence(d.ID, ev.ID, "verifier"); !errors.Is(err, ErrEvidenceHashMismatch) {
+		t.Errorf("expected ErrEvidenceHashMismatch, got %v", err)
+	}
+	if _, err := dm.EvidenceBlob(d.ID, ev.ID); !errors.Is(err, ErrEvidenceHashMismatch) {
+		t.Errorf("expected EvidenceBlob to detect tampering, got %v", err)
+	}
+	if d.Evidence[0].Verified {
+		t.Error("tampered evidence must not be marked verified")
+	}
+}
+
+func TestSuggestionUsesVerifiedEvidenceOnly(t *testing.T) {
+	dm := newPretrialManager(t)
+	d, _ := dm.CreateDispute("task1", "requester", "worker", DisputeNonDelivery, "nothing delivered", 10.0)
+	complaint, _ := dm.AttachEvidence(d.ID, "requester", "text", "complaint", []byte("no delivery"))
+	dm.AttachEvidence(d.ID, "worker", "delivery_proof", "receipt", []byte("delivered at 10:00"))
+	dm.VerifyEvidence(d.ID, complaint.ID, "verifier")
+
+	// 被诉方的交付证明未验证，规则按未交付处理，但警告并禁止自动执行
+	s, err := dm.Suggest(d.ID)
+	if err != nil {
+		t.Fatalf("Suggest failed: %v", err)
+	}
+	if s.Suggestion.Winner != "requester" || s.Confidence != 0.75 || s.CanAutoExecute || len(s.Warnings) != 1 {
+		t.Errorf("suggestion = %+v", s)
+	}
+}
@@ -23,6 +23,13 @@ var (
 	ErrEscrowDisputed     = errors.New("escrow is disputed")
 	ErrUnauthorized       = errors.New("unauthorized operation")
 	ErrAlreadyDeposited   = errors.New("already deposited")
+	ErrBudgetExceeded     = errors.New("child escrow exceeds parent budget")
+	ErrChildrenPending    = errors.New("child escrows are not settled")
+	ErrNotArbitrator      = errors.New("not an arbitrator of this escrow")
+	ErrInvalidArbitrators = errors.New("invalid arbitrator set")
+	ErrNoResolution       = errors.New("no resolution proposed")
+	ErrSettlement         = errors.New("ledger settlement failed")
+	ErrArbitratorConsent  = errors.New("all participants must sign to change arbitrators of a locked escrow")
 )
 
 // EscrowStatus 押金状态
@@ -74,6 +81,21 @@ type Escrow struct {
 	DisputeReason string `json:"dispute_reason,omitempty"`
 	DisputedBy    string `json:"disputed_by,omitempty"`
 	DisputedAt    int64  `json:"disputed_at,omitempty"`
+
+	// 嵌套托管（任务转包）
+	ParentID  string   `json:"parent_id,omitempty"` // 上级托管
+	Children  []string `json:"children,omitempty"`  // 从本托管划拨出的下级托管
+	Allocated float64  `json:"allocated,omitempty"` // 已划拨给下级托管的金额
+	Funded    float64  `json:"funded,omitempty"`    // 本托管中来自上级托管划拨的金额
+
+	// 仲裁
+	Arbitrators []string    `json:"arbitrators,omitempty"` // 仲裁者集合，争议结算只接受其中成员的签名
+	Threshold   int         `json:"threshold,omitempty"`   // 所需仲裁签名数
+	Resolution  *Resolution `json:"resolution,omitempty"`  // 待仲裁者签名的结算方案
+
+	// 账本
+	Locked        map[string]float64 `json:"locked,omitempty"`         // nodeID -> 在代币账本上锁定的押金
+	FundedPayouts map[string]float64 `json:"funded_payouts,omitempty"` // 划拨预算部分的应付款，由上级托管结算时支付
 }
 
 // EscrowConfig 托管配置
@@ -86,6 +108,10 @@ type EscrowConfig struct {
 	AutoReleaseDelay      time.Duration // 自动释放延迟
 	MinArbitratorSigs     int           // Task44: 争议释放所需最少仲裁签名数
 	ArbitratorSigThreshold float64      // Task44: 仲裁签名阈值比例 (0-1)
+	ExpiryCheckInterval   time.Duration // 超时退款扫描间隔
+
+	// VerifyFunc 按签名者节点ID验证签名；设置后存入、释放、退款和仲裁签名都必须是对相应载荷的有效签名
+	VerifyFunc func(signerID string, data []byte, signature string) bool
 }
 
 // DefaultEscrowConfig 返回默认配置
@@ -99,6 +125,7 @@ func DefaultEscrowConfig() *EscrowConfig {
 		AutoReleaseDelay:      24 * time.Hour, // 1天
 		MinArbitratorSigs:     2,              // Task44: 默认需要至少2个仲裁签名
 		ArbitratorSigThreshold: 0.5,           // Task44: 默认需要>50%仲裁签名
+		ExpiryCheckInterval:   time.Minute,
 	}
 }
 
@@ -109,11 +136,17 @@ type EscrowManager struct {
 
 	// 托管记录
 	escrows map[string]*Escrow // escrowID -> escrow
+	version uint64             // 写入计数，快照读据此判断读取期间是否有修改
 
 	// 索引
 	escrowsByTask   map[string]string   // taskID -> escrowID
 	escrowsByNode   map[string][]string // nodeID -> []escrowID
 	escrowsByStatus map[EscrowStatus][]string
+
+	// 代币账本，未设置时押金只在托管内记账
+	funds Funds
+
+	stopCh chan struct{}
 }
 
 // NewEscrowManager 创建押金托管管理器
@@ -220,6 +253,20 @@ func (em *EscrowManager) Deposit(escrowID, nodeID string, amount float64, signat
 	if amount < required {
 		return fmt.Errorf("%w: required %.2f, got %.2f", ErrInsufficientFunds, required, amount)
 	}
+	if !em.verify(nodeID, DepositPayload(escrowID, nodeID, amount), signature) {
+		return ErrInvalidSignature
+	}
+
+	// 在账本上锁定
+	if em.funds != nil {
+		if err := em.funds.LockTokens(nodeID, amount); err != nil {
+			return fmt.Errorf("%w: %v", ErrInsufficientFunds, err)
+		}
+		if escrow.Locked == nil {
+			escrow.Locked = make(map[string]float64)
+		}
+		escrow.Locked[nodeID] = amount
+	}
 
 	// 存入
 	escrow.Deposits[nodeID] = amount
@@ -260,16 +307,25 @@ func (em *EscrowManager) Release(escrowID, releaseToNodeID string, amount float6
 	}
 
 	// 验证签名（需要多数参与方签名）
-	signedCount := len(signatures)
-	requiredSigns := (len(escrow.Participants) + 1) / 2 // 超过半数
+	signedCount, err := em.countParticipantSigs(escrow, ReleasePayload(escrowID, releaseToNodeID, amount), signatures)
+	if err != nil {
+		return err
+	}
+	requiredSigns := len(escrow.Participants)/2 + 1 // 超过半数
 	if signedCount < requiredSigns {
 		return fmt.Errorf("insufficient signatures: need %d, got %d", requiredSigns, signedCount)
 	}
 
+	// 下级托管结清前不能释放，且已划拨部分不可再释放
+	if em.hasPendingChildren(escrow) {
+		return ErrChildrenPending
+	}
+
 	// 检查金额
-	if amount > escrow.TotalAmount {
+	if amount > escrow.TotalAmount-escrow.Allocated {
 		return ErrInsufficientFunds
 	}
+	settleErr := em.settleLocked(escrow, map[string]float64{releaseToNodeID: amount})
 
 	// 执行释放
 	escrow.UnlockSignatures = signatures
@@ -282,7 +338,7 @@ func (em *EscrowManager) Release(escrowID, releaseToNodeID string, amount float6
 	em.updateStatusIndex(escrow.ID, EscrowLocked, EscrowReleased)
 	em.save()
 
-	return nil
+	return settleErr
 }
 
 // Refund 退款给存入方
@@ -300,21 +356,27 @@ func (em *EscrowManager) Refund(escrowID string, signatures map[string]string) e
 	}
 
 	// 验证签名
-	signedCount := len(signatures)
-	requiredSigns := (len(escrow.Participants) + 1) / 2
+	signedCount, err := em.countParticipantSigs(escrow, RefundPayload(escrowID), signatures)
+	if err != nil {
+		return err
+	}
+	requiredSigns := len(escrow.Participants)/2 + 1 // 超过半数
 	if signedCount < requiredSigns {
 		return fmt.Errorf("insufficient signatures: need %d, got %d", requiredSigns, signedCount)
 	}
 
+	if em.hasPendingChildren(escrow) {
+		return ErrChildrenPending
+	}
+
+	oldStatus := escrow.Status
 	escrow.UnlockSignatures = signatures
-	escrow.Status = EscrowRefunded
-	escrow.ReleasedAt = time.Now().Unix()
-	escrow.ReleaseCondition = "refund"
+	settleErr := em.refundLocked(escrow, "refund")
 
-	em.updateStatusIndex(escrow.ID, EscrowLocked, EscrowRefunded)
+	em.updateStatusIndex(escrow.ID, oldStatus, EscrowRefunded)
 	em.save()
 
-	return nil
+	return settleErr
 }
 
 // Dispute 发起争议
@@ -369,28 +431,37 @@ func (em *EscrowManager) ResolveDispute(escrowID string, releaseToNodeID string,
 	if escrow.Status != EscrowDisputed {
 		return errors.New("escrow is not in disputed state")
 	}
-
-	// Task44: 验证仲裁签名数量是否满足阈值
-	if len(arbitratorSigs) < em.config.MinArbitratorSigs {
-		return fmt.Errorf("insufficient arbitrator signatures: need at least %d, got %d", 
-			em.config.MinArbitratorSigs, len(arbitratorSigs))
+	if amount <= 0 {
+		amount = escrow.TotalAmount - escrow.Allocated
+	}
+	if amount > escrow.TotalAmount-escrow.Allocated {
+		return ErrInsufficientFunds
+	}
+	if em.hasPendingChildren(escrow) {
+		return ErrChildrenPending
 	}
 
-	escrow.ReleasedTo = releaseToNodeID
-	escrow.ReleasedAmount = amount
-	escrow.ReleasedAt = time.Now().Unix()
-	escrow.Status = EscrowReleased
-	escrow.ReleaseCondition = "dispute_resolution_multisig"
+	// Task44: 验证仲裁签名数量是否满足阈值，每个签名都必须来自仲裁者集合并覆盖结算载荷
+	payload := ResolutionPayload(escrowID, releaseToNodeID, amount)
+	for arbitratorID, sig := range arbitratorSigs {
+		if err := em.checkArbitratorSig(escrow, arbitratorID, payload, sig); err != nil {
+			return err
+		}
+	}
+	if required := em.requiredArbitratorSigs(escrow); len(arbitratorSigs) < required {
+		return fmt.Errorf("insufficient arbitrator signatures: need at least %d, got %d",
+			required, len(arbitratorSigs))
+	}
 
+	clearArbitratorSigs(escrow)
 	// Task44: 存储所有仲裁签名（而不是单一签名）
 	for arbitratorID, sig := range arbitratorSigs {
-		escrow.UnlockSignatures["arbitrator_"+arbitratorID] = sig
+		escrow.UnlockSignatures[arbitratorSigPrefix+arbitratorID] = sig
 	}
-
-	em.updateStatusIndex(escrow.ID, EscrowDisputed, EscrowReleased)
+	err := em.resolveLocked(escrow, releaseToNodeID, amount)
 	em.save()
 
-	return nil
+	return err
 }
 
 // SubmitArbitratorSignature Task44: 提交单个仲裁签名（用于逐步收集签名）
@@ -407,25 +478,35 @@ func (em *EscrowManager) SubmitArbitratorSignature(escrowID, arbitratorID, signa
 		return false, errors.New("escrow is not in disputed state")
 	}
 
+	// 配置了验签时，签名必须针对已提出的结算方案
+	var payload []byte
+	if escrow.Resolution != nil {
+		payload = ResolutionPayload(escrowID, escrow.Resolution.Winner, escrow.Resolution.Amount)
+	} else if em.config.VerifyFunc != nil {
+		return false, ErrNoResolution
+	}
+	if err := em.checkArbitratorSig(escrow, arbitratorID, payload, signature); err != nil {
+		return false, err
+	}
+
 	if escrow.UnlockSignatures == nil {
 		escrow.UnlockSignatures = make(map[string]string)
 	}
 
-	key := "arbitrator_" + arbitratorID
+	key := arbitratorSigPrefix + arbitratorID
 	escrow.UnlockSignatures[key] = signature
 
-	// 计算当前仲裁签名数量
-	arbCount := 0
-	for k := range escrow.UnlockSignatures {
-		if len(k) > 11 && k[:11] == "arbitrator_" {
-			arbCount++
-		}
+	// 达到阈值且已有结算方案时自动结算
+	reached := len(arbitratorSigners(escrow)) >= em.requiredArbitratorSigs(escrow)
+	var err error
+	if reached && escrow.Resolution != nil {
+		err = em.resolveLocked(escrow, escrow.Resolution.Winner, escrow.Resolution.Amount)
 	}
 
 	em.save()
 
 	// 返回是否已达到阈值
-	return arbCount >= em.config.MinArbitratorSigs, nil
+	return reached, err
 }
 
 // GetArbitratorSignatureCount Task44: 获取当前仲裁签名数量
@@ -438,14 +519,7 @@ func (em *EscrowManager) GetArbitratorSignatureCount(escrowID string) (int, int,
 		return 0, 0, ErrEscrowNotFound
 	}
 
-	arbCount := 0
-	for k := range escrow.UnlockSignatures {
-		if len(k) > 11 && k[:11] == "arbitrator_" {
-			arbCount++
-		}
-	}
-
-	return arbCount, em.config.MinArbitratorSigs, nil
+	return len(arbitratorSigners(escrow)), em.requiredArbitratorSigs(escrow), nil
 }
 
 // Forfeit 没收押金
@@ -471,11 +545,13 @@ func (em *EscrowManager) Forfeit(escrowID, violatorID string, reason string) err
 	if deposit, ok := escrow.Deposits[violatorID]; ok {
 		escrow.ReleasedAmount = deposit
 	}
+	settleErr := em.forfeitLocked(escrow, violatorID)
 
 	em.updateStatusIndex(escrow.ID, oldStatus, EscrowForfeited)
+	em.returnToParent(escrow)
 	em.save()
 
-	return nil
+	return settleErr
 }
 
 // GetEscrow 获取押金托管信息
@@ -654,7 +730,15 @@ func (em *EscrowManager) load() {
 	}
 }
 
+// Version 返回托管记录的版本号，每次写入后递增
+func (em *EscrowManager) Version() uint64 {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+	return em.version
+}
+
 func (em *EscrowManager) save() {
+	em.version++
 	if err := os.MkdirAll(em.config.DataDir, 0755); err != nil {
 		return
 	}
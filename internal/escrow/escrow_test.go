@@ -137,11 +137,15 @@ func TestEscrowRelease(t *testing.T) {
 	em.Deposit(escrow.ID, "requester", 10.0, "sig1")
 	em.Deposit(escrow.ID, "executor", 5.0, "sig2")
 
-	// Release to executor
+	// Release to executor: one of two participants is not a majority
 	signatures := map[string]string{
-		"requester": "unlock_sig1",
+		"executor": "unlock_sig2",
+	}
+	if err := em.Release(escrow.ID, "executor", 15.0, signatures); err == nil {
+		t.Error("Release with one of two signatures should fail")
 	}
 
+	signatures["requester"] = "unlock_sig1"
 	err := em.Release(escrow.ID, "executor", 15.0, signatures)
 	if err != nil {
 		t.Errorf("Release failed: %v", err)
@@ -179,7 +183,11 @@ func TestEscrowRefund(t *testing.T) {
 	signatures := map[string]string{
 		"requester": "refund_sig",
 	}
+	if err := em.Refund(escrow.ID, signatures); err == nil {
+		t.Error("Refund with one of two signatures should fail")
+	}
 
+	signatures["executor"] = "refund_sig2"
 	err := em.Refund(escrow.ID, signatures)
 	if err != nil {
 		t.Errorf("Refund failed: %v", err)
@@ -0,0 +1,274 @@
+package escrow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 仲裁者集合与多签验证
+//
+// 每个托管可以指定仲裁者集合和阈值。争议结算时只接受集合内仲裁者的签名，
+// 配置了 VerifyFunc 后每个签名都必须是该仲裁者对结算载荷（托管ID、受益方、金额）的有效签名，
+// 因此签名不能挪用到别的托管或别的结算方案上。未指定集合的托管沿用 MinArbitratorSigs 计数。
+
+const arbitratorSigPrefix = "arbitrator_"
+
+// Resolution 待仲裁者签名的争议结算方案
+type Resolution struct {
+	Winner     string  `json:"winner"`
+	Amount     float64 `json:"amount"`
+	ProposedBy string  `json:"proposed_by,omitempty"`
+	ProposedAt int64   `json:"proposed_at"`
+}
+
+// DepositPayload 存入押金时签名的载荷
+func DepositPayload(escrowID, nodeID string, amount float64) []byte {
+	return []byte(fmt.Sprintf("escrow:%s:deposit:%s:%.8f", escrowID, nodeID, amount))
+}
+
+// ReleasePayload 参与方同意释放时签名的载荷
+func ReleasePayload(escrowID, releaseTo string, amount float64) []byte {
+	return []byte(fmt.Sprintf("escrow:%s:release:%s:%.8f", escrowID, releaseTo, amount))
+}
+
+// RefundPayload 参与方同意退款时签名的载荷
+func RefundPayload(escrowID string) []byte {
+	return []byte(fmt.Sprintf("escrow:%s:refund", escrowID))
+}
+
+// ResolutionPayload 仲裁者对争议结算方案签名的载荷
+func ResolutionPayload(escrowID, winner string, amount float64) []byte {
+	return []byte(fmt.Sprintf("escrow:%s:resolve:%s:%.8f", escrowID, winner, amount))
+}
+
+// ArbitratorsPayload 参与方同意更换仲裁者集合时签名的载荷（集合去重排序，threshold 为请求中的原值）
+func ArbitratorsPayload(escrowID string, arbitrators []string, threshold int) []byte {
+	return []byte(fmt.Sprintf("escrow:%s:arbitrators:%s:%d", escrowID, strings.Join(normalizeIDs(arbitrators), ","), threshold))
+}
+
+// SetArbitrators 设置托管的仲裁者集合和阈值（threshold 为 0 时按配置比例计算）
+// 仲裁者不能是托管参与方；进入争议后不能再更换。托管锁定后押金已全部到位，
+// 更换仲裁者需要每个参与方对 ArbitratorsPayload 的签名（signatures 为 参与方ID -> 签名），
+// 避免一方单独换上自己选定的仲裁者。
+func (em *EscrowManager) SetArbitrators(escrowID string, arbitrators []string, threshold int, signatures map[string]string) (*Escrow, error) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	escrow, exists := em.escrows[escrowID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+	switch escrow.Status {
+	case EscrowPending, EscrowLocked:
+	case EscrowDisputed:
+		return nil, ErrEscrowDisputed
+	default:
+		return nil, fmt.Errorf("cannot set arbitrators: escrow status is %s", escrow.Status)
+	}
+
+	set := normalizeIDs(arbitrators)
+	for _, id := range set {
+		if isParticipant(escrow, id) {
+			return nil, fmt.Errorf("%w: participant %s cannot arbitrate", ErrInvalidArbitrators, id)
+		}
+	}
+	if len(set) == 0 {
+		return nil, fmt.Errorf("%w: no arbitrators", ErrInvalidArbitrators)
+	}
+	if escrow.Status == EscrowLocked {
+		signed, err := em.countParticipantSigs(escrow, ArbitratorsPayload(escrowID, set, threshold), signatures)
+		if err != nil {
+			return nil, err
+		}
+		if signed < len(escrow.Participants) {
+			return nil, fmt.Errorf("%w: need %d, got %d", ErrArbitratorConsent, len(escrow.Participants), signed)
+		}
+	}
+
+	if threshold == 0 {
+		threshold = em.thresholdFor(len(set))
+	}
+	if min := em.thresholdFloor(len(set)); threshold < min || threshold > len(set) {
+		return nil, fmt.Errorf("%w: threshold must be between %d and %d", ErrInvalidArbitrators, min, len(set))
+	}
+
+	escrow.Arbitrators = set
+	escrow.Threshold = threshold
+	em.save()
+	return escrow, nil
+}
+
+// ProposeResolution 为争议中的托管提出结算方案（amount 为 0 表示全部可用余额）
+// 之前收集的仲裁签名作废，仲裁者需要对新方案重新签名。
+func (em *EscrowManager) ProposeResolution(escrowID, winner string, amount float64, proposer string) (*Resolution, error) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	escrow, exists := em.escrows[escrowID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+	if escrow.Status != EscrowDisputed {
+		return nil, fmt.Errorf("escrow is not in disputed state")
+	}
+	if !isParticipant(escrow, winner) {
+		return nil, fmt.Errorf("%w: winner %s is not a participant", ErrUnauthorized, winner)
+	}
+	available := escrow.TotalAmount - escrow.Allocated
+	if amount <= 0 {
+		amount = available
+	}
+	if amount > available {
+		return nil, ErrInsufficientFunds
+	}
+
+	escrow.Resolution = &Resolution{
+		Winner:     winner,
+		Amount:     amount,
+		ProposedBy: proposer,
+		ProposedAt: time.Now().Unix(),
+	}
+	clearArbitratorSigs(escrow)
+	em.save()
+
+	copied := *escrow.Resolution
+	return &copied, nil
+}
+
+// GetArbitratorSigners 获取已提交签名的仲裁者
+func (em *EscrowManager) GetArbitratorSigners(escrowID string) ([]string, error) {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	escrow, exists := em.escrows[escrowID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+	return arbitratorSigners(escrow), nil
+}
+
+// requiredArbitratorSigs 托管结算所需的仲裁签名数（调用方持有锁）
+func (em *EscrowManager) requiredArbitratorSigs(escrow *Escrow) int {
+	if escrow.Threshold > 0 {
+		return escrow.Threshold
+	}
+	if len(escrow.Arbitrators) > 0 {
+		return em.thresholdFor(len(escrow.Arbitrators))
+	}
+	return em.config.MinArbitratorSigs
+}
+
+// thresholdFor n 名仲裁者时的默认阈值：超过 ArbitratorSigThreshold 比例，且不少于 MinArbitratorSigs
+func (em *EscrowManager) thresholdFor(n int) int {
+	t := 1
+	if em.config.ArbitratorSigThreshold > 0 {
+		t = int(float64(n)*em.config.ArbitratorSigThreshold) + 1
+	}
+	if floor := em.thresholdFloor(n); t < floor {
+		t = floor
+	}
+	if t > n {
+		t = n
+	}
+	return t
+}
+
+// thresholdFloor 阈值下限：MinArbitratorSigs，仲裁者不足时为全部仲裁者
+func (em *EscrowManager) thresholdFloor(n int) int {
+	floor := em.config.MinArbitratorSigs
+	if floor < 1 {
+		floor = 1
+	}
+	if floor > n {
+		floor = n
+	}
+	return floor
+}
+
+// checkArbitratorSig 校验仲裁签名的来源和内容（调用方持有锁）
+func (em *EscrowManager) checkArbitratorSig(escrow *Escrow, arbitratorID string, payload []byte, signature string) error {
+	if len(escrow.Arbitrators) > 0 && !containsID(escrow.Arbitrators, arbitratorID) {
+		return fmt.Errorf("%w: %s", ErrNotArbitrator, arbitratorID)
+	}
+	if em.config.VerifyFunc != nil && len(escrow.Arbitrators) == 0 {
+		// 没有仲裁者集合时无从判断签名者是否有权仲裁
+		return fmt.Errorf("%w: no arbitrators assigned", ErrNotArbitrator)
+	}
+	if !em.verify(arbitratorID, payload, signature) {
+		return fmt.Errorf("%w: arbitrator %s", ErrInvalidSignature, arbitratorID)
+	}
+	return nil
+}
+
+// countParticipantSigs 统计参与方对载荷的有效签名数，非参与方的签名不计入（调用方持有锁）
+func (em *EscrowManager) countParticipantSigs(escrow *Escrow, payload []byte, signatures map[string]string) (int, error) {
+	count := 0
+	for nodeID, sig := range signatures {
+		if !isParticipant(escrow, nodeID) {
+			continue
+		}
+		if !em.verify(nodeID, payload, sig) {
+			return 0, fmt.Errorf("%w: participant %s", ErrInvalidSignature, nodeID)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// verify 验证签名；未配置 VerifyFunc 时不验签，只按签名数计数
+func (em *EscrowManager) verify(signerID string, payload []byte, signature string) bool {
+	if em.config.VerifyFunc == nil {
+		return true
+	}
+	return signature != "" && em.config.VerifyFunc(signerID, payload, signature)
+}
+
+// normalizeIDs 去掉空白和重复的节点ID并排序
+func normalizeIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	set := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		set = append(set, id)
+	}
+	sort.Strings(set)
+	return set
+}
+
+func arbitratorSigners(escrow *Escrow) []string {
+	var signers []string
+	for k := range escrow.UnlockSignatures {
+		if strings.HasPrefix(k, arbitratorSigPrefix) {
+			signers = append(signers, strings.TrimPrefix(k, arbitratorSigPrefix))
+		}
+	}
+	sort.Strings(signers)
+	return signers
+}
+
+func clearArbitratorSigs(escrow *Escrow) {
+	for k := range escrow.UnlockSignatures {
+		if strings.HasPrefix(k, arbitratorSigPrefix) {
+			delete(escrow.UnlockSignatures, k)
+		}
+	}
+}
+
+func isParticipant(escrow *Escrow, nodeID string) bool {
+	return containsID(escrow.Participants, nodeID)
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
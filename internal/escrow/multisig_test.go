@@ -0,0 +1,193 @@
+package escrow
+
+import (
+	"errors"
+	"testing"
+)
+
+// testSign 测试签名：签名者ID + 载荷
+func testSign(signerID string, payload []byte) string {
+	return signerID + "|" + string(payload)
+}
+
+func newVerifyingManager(t *testing.T) *EscrowManager {
+	config := DefaultEscrowConfig()
+	config.DataDir = t.TempDir()
+	config.VerifyFunc = func(signerID string, data []byte, signature string) bool {
+		return signature == testSign(signerID, data)
+	}
+	return NewEscrowManager(config)
+}
+
+// disputedEscrow requester 与 executor 各存入押金后由 requester 发起争议
+func disputedEscrow(t *testing.T, em *EscrowManager) *Escrow {
+	escrow, err := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 5.0})
+	if err != nil {
+		t.Fatalf("CreateEscrow failed: %v", err)
+	}
+	for node, amount := range map[string]float64{"requester": 10.0, "executor": 5.0} {
+		if err := em.Deposit(escrow.ID, node, amount, testSign(node, DepositPayload(escrow.ID, node, amount))); err != nil {
+			t.Fatalf("Deposit failed: %v", err)
+		}
+	}
+	arbitrators := []string{"arb3", "arb1", "arb2", "arb1"}
+	consent := map[string]string{}
+	for _, node := range []string{"requester", "executor"} {
+		consent[node] = testSign(node, ArbitratorsPayload(escrow.ID, arbitrators, 0))
+	}
+	if _, err := em.SetArbitrators(escrow.ID, arbitrators, 0, consent); err != nil {
+		t.Fatalf("SetArbitrators failed: %v", err)
+	}
+	if err := em.Dispute(escrow.ID, "requester", "quality"); err != nil {
+		t.Fatalf("Dispute failed: %v", err)
+	}
+	return escrow
+}
+
+func TestSetArbitrators(t *testing.T) {
+	em := newVerifyingManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0})
+
+	if _, err := em.SetArbitrators(escrow.ID, []string{"requester", "arb1"}, 0, nil); !errors.Is(err, ErrInvalidArbitrators) {
+		t.Errorf("participant as arbitrator: expected ErrInvalidArbitrators, got %v", err)
+	}
+	if _, err := em.SetArbitrators(escrow.ID, []string{"arb1", "arb2"}, 3, nil); !errors.Is(err, ErrInvalidArbitrators) {
+		t.Errorf("threshold above set size: expected ErrInvalidArbitrators, got %v", err)
+	}
+	if _, err := em.SetArbitrators(escrow.ID, []string{"arb1", "arb2", "arb3"}, 1, nil); !errors.Is(err, ErrInvalidArbitrators) {
+		t.Errorf("threshold below MinArbitratorSigs: expected ErrInvalidArbitrators, got %v", err)
+	}
+
+	updated, err := em.SetArbitrators(escrow.ID, []string{"arb1", "arb2", "arb3", "arb4", "arb5"}, 0, nil)
+	if err != nil {
+		t.Fatalf("SetArbitrators failed: %v", err)
+	}
+	if updated.Threshold != 3 {
+		t.Errorf("threshold = %d, want 3 (more than half of 5)", updated.Threshold)
+	}
+}
+
+func TestSetArbitratorsLockedNeedsAllParticipants(t *testing.T) {
+	em := newVerifyingManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 5.0})
+	for node, amount := range map[string]float64{"requester": 10.0, "executor": 5.0} {
+		if err := em.Deposit(escrow.ID, node, amount, testSign(node, DepositPayload(escrow.ID, node, amount))); err != nil {
+			t.Fatalf("Deposit failed: %v", err)
+		}
+	}
+
+	arbitrators := []string{"arb1", "arb2", "arb3"}
+	payload := ArbitratorsPayload(escrow.ID, arbitrators, 2)
+	onlyRequester := map[string]string{"requester": testSign("requester", payload), "arb1": "ignored"}
+	if _, err := em.SetArbitrators(escrow.ID, arbitrators, 2, onlyRequester); !errors.Is(err, ErrArbitratorConsent) {
+		t.Errorf("one participant: expected ErrArbitratorConsent, got %v", err)
+	}
+	wrongSet := map[string]string{
+		"requester": testSign("requester", payload),
+		"executor":  testSign("executor", ArbitratorsPayload(escrow.ID, []string{"arb1", "arb2", "arb4"}, 2)),
+	}
+	if _, err := em.SetArbitrators(escrow.ID, arbitrators, 2, wrongSet); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signature over another set: expected ErrInvalidSignature, got %v", err)
+	}
+
+	both := map[string]string{
+		"requester": testSign("requester", payload),
+		"executor":  testSign("executor", ArbitratorsPayload(escrow.ID, []string{"arb3", " arb1", "arb2", "arb2"}, 2)),
+	}
+	updated, err := em.SetArbitrators(escrow.ID, arbitrators, 2, both)
+	if err != nil {
+		t.Fatalf("SetArbitrators with both signatures failed: %v", err)
+	}
+	if len(updated.Arbitrators) != 3 || updated.Threshold != 2 {
+		t.Errorf("arbitrators = %v threshold %d, want 3 arbitrators threshold 2", updated.Arbitrators, updated.Threshold)
+	}
+}
+
+func TestArbitratorThresholdSignatures(t *testing.T) {
+	em := newVerifyingManager(t)
+	escrow := disputedEscrow(t, em)
+
+	if _, err := em.SubmitArbitratorSignature(escrow.ID, "arb1", "x"); !errors.Is(err, ErrNoResolution) {
+		t.Errorf("expected ErrNoResolution before a proposal, got %v", err)
+	}
+	if _, err := em.ProposeResolution(escrow.ID, "stranger", 0, "requester"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized for non-participant winner, got %v", err)
+	}
+	res, err := em.ProposeResolution(escrow.ID, "executor", 12.0, "requester")
+	if err != nil {
+		t.Fatalf("ProposeResolution failed: %v", err)
+	}
+	payload := ResolutionPayload(escrow.ID, res.Winner, res.Amount)
+
+	// 签名来源和内容都要校验
+	if _, err := em.SubmitArbitratorSignature(escrow.ID, "mallory", testSign("mallory", payload)); !errors.Is(err, ErrNotArbitrator) {
+		t.Errorf("expected ErrNotArbitrator, got %v", err)
+	}
+	other := ResolutionPayload(escrow.ID, "requester", 15.0)
+	if _, err := em.SubmitArbitratorSignature(escrow.ID, "arb1", testSign("arb1", other)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signature over another resolution: expected ErrInvalidSignature, got %v", err)
+	}
+
+	reached, err := em.SubmitArbitratorSignature(escrow.ID, "arb1", testSign("arb1", payload))
+	if err != nil || reached {
+		t.Fatalf("first signature: reached=%v err=%v", reached, err)
+	}
+	if count, required, _ := em.GetArbitratorSignatureCount(escrow.ID); count != 1 || required != 2 {
+		t.Errorf("count = %d/%d, want 1/2", count, required)
+	}
+
+	// 达到阈值后自动按方案结算
+	reached, err = em.SubmitArbitratorSignature(escrow.ID, "arb3", testSign("arb3", payload))
+	if err != nil || !reached {
+		t.Fatalf("second signature: reached=%v err=%v", reached, err)
+	}
+	if escrow.Status != EscrowReleased || escrow.ReleasedTo != "executor" || escrow.ReleasedAmount != 12.0 {
+		t.Errorf("expected release to executor, got %s %s %.2f", escrow.Status, escrow.ReleasedTo, escrow.ReleasedAmount)
+	}
+}
+
+func TestResolveDisputeVerifiesSignatures(t *testing.T) {
+	em := newVerifyingManager(t)
+	escrow := disputedEscrow(t, em)
+
+	// amount 为 0 时结算全部可用余额，签名必须覆盖实际金额
+	payload := ResolutionPayload(escrow.ID, "requester", 15.0)
+	forged := map[string]string{"arb1": testSign("arb1", payload), "arb2": "forged"}
+	if err := em.ResolveDispute(escrow.ID, "requester", 0, forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := em.ResolveDispute(escrow.ID, "requester", 0, map[string]string{"arb1": testSign("arb1", payload)}); err == nil {
+		t.Error("expected insufficient signatures")
+	}
+
+	sigs := map[string]string{"arb1": testSign("arb1", payload), "arb2": testSign("arb2", payload)}
+	if err := em.ResolveDispute(escrow.ID, "requester", 0, sigs); err != nil {
+		t.Fatalf("ResolveDispute failed: %v", err)
+	}
+	if escrow.ReleasedAmount != 15.0 {
+		t.Errorf("released amount = %.2f, want 15", escrow.ReleasedAmount)
+	}
+}
+
+func TestReleaseVerifiesParticipantSignatures(t *testing.T) {
+	em := newVerifyingManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 5.0})
+	if err := em.Deposit(escrow.ID, "requester", 10.0, "bad"); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for deposit, got %v", err)
+	}
+	em.Deposit(escrow.ID, "requester", 10.0, testSign("requester", DepositPayload(escrow.ID, "requester", 10.0)))
+	em.Deposit(escrow.ID, "executor", 5.0, testSign("executor", DepositPayload(escrow.ID, "executor", 5.0)))
+
+	payload := ReleasePayload(escrow.ID, "executor", 15.0)
+	if err := em.Release(escrow.ID, "executor", 15.0, map[string]string{"stranger": testSign("stranger", payload)}); err == nil {
+		t.Error("non-participant signatures should not count")
+	}
+	// 两方托管需要双方签名，一方不能把全部押金释放给自己
+	if err := em.Release(escrow.ID, "executor", 15.0, map[string]string{"executor": testSign("executor", payload)}); err == nil {
+		t.Error("one of two signatures should not be a majority")
+	}
+	sigs := map[string]string{"requester": testSign("requester", payload), "executor": testSign("executor", payload)}
+	if err := em.Release(escrow.ID, "executor", 15.0, sigs); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+}
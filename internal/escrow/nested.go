@@ -0,0 +1,151 @@
+package escrow
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreateChildEscrow 从上级托管划拨预算，创建转包任务的下级托管
+// payerID 为转包方（上级任务的执行者），划拨金额计入其在下级托管中的押金；
+// requiredDeposits 为承包方需要另行存入的押金，全部存入后下级托管锁定。
+// 所有下级托管的划拨总额不能超过上级托管的可用余额。
+func (em *EscrowManager) CreateChildEscrow(parentID, taskID, payerID string, budget float64, requiredDeposits map[string]float64) (*Escrow, error) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	parent, exists := em.escrows[parentID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+	if parent.Status != EscrowLocked {
+		return nil, ErrEscrowNotLocked
+	}
+	if _, exists := em.escrowsByTask[taskID]; exists {
+		return nil, fmt.Errorf("escrow already exists for task %s", taskID)
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("invalid budget: %.2f", budget)
+	}
+	if available := parent.TotalAmount - parent.Allocated; budget > available {
+		return nil, fmt.Errorf("%w: requested %.2f, available %.2f", ErrBudgetExceeded, budget, available)
+	}
+
+	participants := []string{payerID}
+	required := map[string]float64{payerID: budget}
+	for nodeID, amount := range requiredDeposits {
+		if nodeID == payerID {
+			continue
+		}
+		if amount < em.config.MinDeposit {
+			return nil, fmt.Errorf("deposit for %s is below minimum: %.2f < %.2f", nodeID, amount, em.config.MinDeposit)
+		}
+		if amount > em.config.MaxDeposit {
+			return nil, fmt.Errorf("deposit for %s exceeds maximum: %.2f > %.2f", nodeID, amount, em.config.MaxDeposit)
+		}
+		required[nodeID] = amount
+		participants = append(participants, nodeID)
+	}
+
+	now := time.Now()
+	child := &Escrow{
+		ID:               em.generateID(),
+		TaskID:           taskID,
+		Deposits:         map[string]float64{payerID: budget},
+		TotalAmount:      budget,
+		RequiredDeposits: required,
+		Status:           EscrowPending,
+		LockSignatures:   make(map[string]string),
+		UnlockSignatures: make(map[string]string),
+		Participants:     participants,
+		CreatedAt:        now.Unix(),
+		LockedUntil:      parent.LockedUntil,
+		ParentID:         parent.ID,
+		Funded:           budget,
+	}
+	if child.LockedUntil == 0 {
+		child.LockedUntil = now.Add(em.config.DefaultLockTime).Unix()
+	}
+	if len(participants) == 1 {
+		child.Status = EscrowLocked
+		child.LockedAt = now.Unix()
+	}
+
+	parent.Allocated += budget
+	parent.Children = append(parent.Children, child.ID)
+
+	em.escrows[child.ID] = child
+	em.escrowsByTask[taskID] = child.ID
+	em.escrowsByStatus[child.Status] = append(em.escrowsByStatus[child.Status], child.ID)
+	for _, nodeID := range participants {
+		em.escrowsByNode[nodeID] = append(em.escrowsByNode[nodeID], child.ID)
+	}
+
+	em.save()
+	return child, nil
+}
+
+// GetChildEscrows 获取下级托管
+func (em *EscrowManager) GetChildEscrows(escrowID string) ([]*Escrow, error) {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	escrow, exists := em.escrows[escrowID]
+	if !exists {
+		return nil, ErrEscrowNotFound
+	}
+
+	children := make([]*Escrow, 0, len(escrow.Children))
+	for _, id := range escrow.Children {
+		if child, ok := em.escrows[id]; ok {
+			children = append(children, child)
+		}
+	}
+	return children, nil
+}
+
+// GetEscrowChain 获取从根托管到指定托管的链路
+func (em *EscrowManager) GetEscrowChain(escrowID string) ([]*Escrow, error) {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	var chain []*Escrow
+	for id := escrowID; id != ""; {
+		escrow, exists := em.escrows[id]
+		if !exists {
+			return nil, ErrEscrowNotFound
+		}
+		chain = append([]*Escrow{escrow}, chain...)
+		id = escrow.ParentID
+	}
+	return chain, nil
+}
+
+// hasPendingChildren 是否还有未结清的下级托管（调用方需持有锁）
+func (em *EscrowManager) hasPendingChildren(escrow *Escrow) bool {
+	for _, id := range escrow.Children {
+		child, ok := em.escrows[id]
+		if !ok {
+			continue
+		}
+		switch child.Status {
+		case EscrowPending, EscrowLocked, EscrowDisputed:
+			return true
+		}
+	}
+	return false
+}
+
+// returnToParent 下级托管退款或没收时，划拨的预算退回上级托管（调用方需持有锁）
+func (em *EscrowManager) returnToParent(escrow *Escrow) {
+	if escrow.ParentID == "" || escrow.Funded == 0 {
+		return
+	}
+	parent, ok := em.escrows[escrow.ParentID]
+	if !ok {
+		return
+	}
+	parent.Allocated -= escrow.Funded
+	if parent.Allocated < 0 {
+		parent.Allocated = 0
+	}
+}
@@ -0,0 +1,95 @@
+package escrow
+
+import (
+	"errors"
+	"testing"
+)
+
+func createLockedEscrow(t *testing.T, em *EscrowManager, taskID string) *Escrow {
+	escrow, err := em.CreateEscrow(taskID, map[string]float64{"requester": 10.0})
+	if err != nil {
+		t.Fatalf("CreateEscrow failed: %v", err)
+	}
+	if err := em.Deposit(escrow.ID, "requester", 10.0, "sig"); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	return escrow
+}
+
+func TestCreateChildEscrow(t *testing.T) {
+	em := NewEscrowManager(&EscrowConfig{DataDir: t.TempDir(), MinDeposit: 0.1, MaxDeposit: 1000.0})
+	parent := createLockedEscrow(t, em, "task1")
+
+	child, err := em.CreateChildEscrow(parent.ID, "task1-sub1", "worker", 6.0, map[string]float64{"sub": 1.0})
+	if err != nil {
+		t.Fatalf("CreateChildEscrow failed: %v", err)
+	}
+	if child.Status != EscrowPending || child.ParentID != parent.ID {
+		t.Errorf("child escrow should be pending under parent, got %s / %s", child.Status, child.ParentID)
+	}
+	if parent.Allocated != 6.0 {
+		t.Errorf("parent allocated = %.2f, want 6", parent.Allocated)
+	}
+
+	// 超出上级可用余额
+	if _, err := em.CreateChildEscrow(parent.ID, "task1-sub2", "worker", 5.0, nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	// 承包方存入押金后锁定
+	if err := em.Deposit(child.ID, "sub", 1.0, "sig"); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	if child.Status != EscrowLocked || child.TotalAmount != 7.0 {
+		t.Errorf("child should be locked with 7, got %s / %.2f", child.Status, child.TotalAmount)
+	}
+
+	chain, _ := em.GetEscrowChain(child.ID)
+	if len(chain) != 2 || chain[0].ID != parent.ID {
+		t.Errorf("unexpected chain: %v", chain)
+	}
+}
+
+func TestChildEscrowBlocksParentRelease(t *testing.T) {
+	em := NewEscrowManager(&EscrowConfig{DataDir: t.TempDir(), MinDeposit: 0.1, MaxDeposit: 1000.0})
+	parent := createLockedEscrow(t, em, "task1")
+	child, _ := em.CreateChildEscrow(parent.ID, "task1-sub1", "worker", 4.0, nil)
+
+	sigs := map[string]string{"requester": "unlock"}
+	if err := em.Release(parent.ID, "worker", 6.0, sigs); !errors.Is(err, ErrChildrenPending) {
+		t.Errorf("expected ErrChildrenPending, got %v", err)
+	}
+
+	if err := em.Release(child.ID, "sub", 4.0, map[string]string{"worker": "unlock"}); err != nil {
+		t.Fatalf("child release failed: %v", err)
+	}
+	// 已划拨给下级的预算不能再次释放
+	if err := em.Release(parent.ID, "worker", 10.0, sigs); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if err := em.Release(parent.ID, "worker", 6.0, sigs); err != nil {
+		t.Errorf("parent release failed: %v", err)
+	}
+}
+
+func TestChildEscrowForfeitReturnsBudget(t *testing.T) {
+	em := NewEscrowManager(&EscrowConfig{DataDir: t.TempDir(), MinDeposit: 0.1, MaxDeposit: 1000.0})
+	parent := createLockedEscrow(t, em, "task1")
+	child, _ := em.CreateChildEscrow(parent.ID, "task1-sub1", "worker", 8.0, map[string]float64{"sub": 2.0})
+	em.Deposit(child.ID, "sub", 2.0, "sig")
+
+	if err := em.Forfeit(child.ID, "sub", "failed delivery"); err != nil {
+		t.Fatalf("Forfeit failed: %v", err)
+	}
+	if child.ReleasedAmount != 2.0 {
+		t.Errorf("forfeited amount = %.2f, want 2", child.ReleasedAmount)
+	}
+	if parent.Allocated != 0 {
+		t.Errorf("budget should return to parent, allocated = %.2f", parent.Allocated)
+	}
+
+	// 预算退回后可以重新转包
+	if _, err := em.CreateChildEscrow(parent.ID, "task1-sub2", "worker", 8.0, nil); err != nil {
+		t.Errorf("re-subcontract failed: %v", err)
+	}
+}
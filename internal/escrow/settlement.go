@@ -0,0 +1,226 @@
+package escrow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 押金在代币账本上的锁定与结算
+//
+// 存入押金时在账本上锁定相应代币；托管结清时先解锁全部押金，再由各存入方按押金比例
+// 向受益方转账。下级托管中由上级划拨的预算不在账本上重复锁定，这部分应付款记入
+// FundedPayouts，由上级托管结算时一并支付。
+
+// Funds 代币账本
+type Funds interface {
+	LockTokens(nodeID string, amount float64) error
+	UnlockTokens(nodeID string, amount float64) error
+	Transfer(fromNodeID, toNodeID string, amount float64) error
+}
+
+// SetFunds 设置代币账本，之后的存入会在账本上锁定
+func (em *EscrowManager) SetFunds(funds Funds) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.funds = funds
+}
+
+// resolveLocked 按结算方案把争议中的托管释放给受益方（调用方持有锁）
+func (em *EscrowManager) resolveLocked(escrow *Escrow, winner string, amount float64) error {
+	err := em.settleLocked(escrow, map[string]float64{winner: amount})
+
+	escrow.ReleasedTo = winner
+	escrow.ReleasedAmount = amount
+	escrow.ReleasedAt = time.Now().Unix()
+	escrow.Status = EscrowReleased
+	escrow.ReleaseCondition = "dispute_resolution_multisig"
+	em.updateStatusIndex(escrow.ID, EscrowDisputed, EscrowReleased)
+	return err
+}
+
+// refundLocked 退还全部押金，划拨的预算退回上级托管（调用方持有锁，负责更新状态索引）
+func (em *EscrowManager) refundLocked(escrow *Escrow, condition string) error {
+	err := em.settleLocked(escrow, nil)
+
+	escrow.Status = EscrowRefunded
+	escrow.ReleasedAt = time.Now().Unix()
+	escrow.ReleaseCondition = condition
+	em.returnToParent(escrow)
+	return err
+}
+
+// settleLocked 解锁账本上的押金并按比例支付 payouts（调用方持有锁）
+// 已释放的下级托管欠下的划拨预算应付款并入本次支付。
+func (em *EscrowManager) settleLocked(escrow *Escrow, payouts map[string]float64) error {
+	all := make(map[string]float64, len(payouts))
+	for to, amount := range payouts {
+		all[to] += amount
+	}
+	for _, id := range escrow.Children {
+		child, ok := em.escrows[id]
+		if !ok || child.Status != EscrowReleased {
+			continue
+		}
+		for to, amount := range child.FundedPayouts {
+			all[to] += amount
+		}
+	}
+
+	var errs []error
+	if em.funds != nil {
+		for nodeID, amount := range escrow.Locked {
+			if err := em.funds.UnlockTokens(nodeID, amount); err != nil {
+				errs = append(errs, fmt.Errorf("unlock %s: %w", nodeID, err))
+			}
+		}
+	}
+	if escrow.TotalAmount <= 0 {
+		return joinSettlement(errs)
+	}
+
+	for to, amount := range all {
+		for nodeID, deposit := range escrow.Deposits {
+			share := amount * deposit / escrow.TotalAmount
+			if share <= 0 || nodeID == to {
+				continue
+			}
+			if _, locked := escrow.Locked[nodeID]; !locked || em.funds == nil {
+				// 划拨预算部分由上级托管支付
+				if escrow.ParentID != "" {
+					if escrow.FundedPayouts == nil {
+						escrow.FundedPayouts = make(map[string]float64)
+					}
+					escrow.FundedPayouts[to] += share
+				}
+				continue
+			}
+			if err := em.funds.Transfer(nodeID, to, share); err != nil {
+				errs = append(errs, fmt.Errorf("transfer %s -> %s: %w", nodeID, to, err))
+			}
+		}
+	}
+	return joinSettlement(errs)
+}
+
+// forfeitLocked 没收违规方押金，按押金比例补偿其他存入方；没有其他存入方时保持锁定（调用方持有锁）
+func (em *EscrowManager) forfeitLocked(escrow *Escrow, violatorID string) error {
+	if em.funds == nil {
+		return nil
+	}
+	var errs []error
+	var others float64
+	for nodeID, amount := range escrow.Locked {
+		if nodeID == violatorID {
+			continue
+		}
+		others += escrow.Deposits[nodeID]
+		if err := em.funds.UnlockTokens(nodeID, amount); err != nil {
+			errs = append(errs, fmt.Errorf("unlock %s: %w", nodeID, err))
+		}
+	}
+
+	forfeited, locked := escrow.Locked[violatorID]
+	if !locked || others <= 0 {
+		return joinSettlement(errs)
+	}
+	if err := em.funds.UnlockTokens(violatorID, forfeited); err != nil {
+		return joinSettlement(append(errs, fmt.Errorf("unlock %s: %w", violatorID, err)))
+	}
+	for nodeID := range escrow.Locked {
+		if nodeID == violatorID {
+			continue
+		}
+		share := forfeited * escrow.Deposits[nodeID] / others
+		if err := em.funds.Transfer(violatorID, nodeID, share); err != nil {
+			errs = append(errs, fmt.Errorf("transfer %s -> %s: %w", violatorID, nodeID, err))
+		}
+	}
+	return joinSettlement(errs)
+}
+
+// RefundExpired 退还超时的托管，返回退款的托管ID
+// 未凑齐押金且超过锁定期限的、锁定超过期限仍未释放的、争议超过 DisputeTimeout 仍未裁决的托管都会退款；
+// 下级托管未结清的托管跳过。
+func (em *EscrowManager) RefundExpired(now time.Time) []string {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	ts := now.Unix()
+	var refunded []string
+	for id, escrow := range em.escrows {
+		var condition string
+		switch escrow.Status {
+		case EscrowPending, EscrowLocked:
+			if escrow.LockedUntil > 0 && ts > escrow.LockedUntil {
+				condition = "timeout_refund"
+			}
+		case EscrowDisputed:
+			if em.config.DisputeTimeout > 0 && escrow.DisputedAt > 0 &&
+				now.Sub(time.Unix(escrow.DisputedAt, 0)) > em.config.DisputeTimeout {
+				condition = "dispute_timeout_refund"
+			}
+		}
+		if condition == "" || em.hasPendingChildren(escrow) {
+			continue
+		}
+		oldStatus := escrow.Status
+		em.refundLocked(escrow, condition)
+		em.updateStatusIndex(id, oldStatus, EscrowRefunded)
+		refunded = append(refunded, id)
+	}
+
+	if len(refunded) > 0 {
+		em.save()
+	}
+	return refunded
+}
+
+// Start 启动超时退款扫描（间隔为 ExpiryCheckInterval）
+func (em *EscrowManager) Start() {
+	interval := em.config.ExpiryCheckInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	em.mu.Lock()
+	if em.stopCh != nil {
+		em.mu.Unlock()
+		return
+	}
+	em.stopCh = make(chan struct{})
+	stopCh := em.stopCh
+	em.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				em.RefundExpired(time.Now())
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止超时退款扫描
+func (em *EscrowManager) Stop() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	if em.stopCh != nil {
+		close(em.stopCh)
+		em.stopCh = nil
+	}
+}
+
+func joinSettlement(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrSettlement, errors.Join(errs...))
+}
@@ -0,0 +1,152 @@
+package escrow
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// memFunds 内存代币账本
+type memFunds struct {
+	available map[string]float64
+	locked    map[string]float64
+}
+
+func newMemFunds(balances map[string]float64) *memFunds {
+	return &memFunds{available: balances, locked: make(map[string]float64)}
+}
+
+func (f *memFunds) LockTokens(nodeID string, amount float64) error {
+	if f.available[nodeID] < amount {
+		return errors.New("insufficient balance")
+	}
+	f.available[nodeID] -= amount
+	f.locked[nodeID] += amount
+	return nil
+}
+
+func (f *memFunds) UnlockTokens(nodeID string, amount float64) error {
+	if f.locked[nodeID] < amount {
+		return errors.New("insufficient locked balance")
+	}
+	f.locked[nodeID] -= amount
+	f.available[nodeID] += amount
+	return nil
+}
+
+func (f *memFunds) Transfer(from, to string, amount float64) error {
+	if f.available[from] < amount {
+		return errors.New("insufficient balance")
+	}
+	f.available[from] -= amount
+	f.available[to] += amount
+	return nil
+}
+
+func (f *memFunds) expect(t *testing.T, nodeID string, available, locked float64) {
+	t.Helper()
+	if math.Abs(f.available[nodeID]-available) > 1e-9 || math.Abs(f.locked[nodeID]-locked) > 1e-9 {
+		t.Errorf("%s: available %.2f locked %.2f, want %.2f / %.2f",
+			nodeID, f.available[nodeID], f.locked[nodeID], available, locked)
+	}
+}
+
+func newFundedManager(t *testing.T) (*EscrowManager, *memFunds) {
+	em := NewEscrowManager(&EscrowConfig{DataDir: t.TempDir(), MinDeposit: 0.1, MaxDeposit: 1000.0, DisputeTimeout: time.Hour})
+	funds := newMemFunds(map[string]float64{"requester": 20.0, "executor": 5.0})
+	em.SetFunds(funds)
+	return em, funds
+}
+
+func TestDepositLocksFunds(t *testing.T) {
+	em, funds := newFundedManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 8.0})
+
+	if err := em.Deposit(escrow.ID, "executor", 8.0, "sig"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if err := em.Deposit(escrow.ID, "requester", 10.0, "sig"); err != nil {
+		t.Fatalf("Deposit failed: %v", err)
+	}
+	funds.expect(t, "requester", 10.0, 10.0)
+}
+
+func TestReleaseTransfersFunds(t *testing.T) {
+	em, funds := newFundedManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 5.0})
+	em.Deposit(escrow.ID, "requester", 10.0, "sig")
+	em.Deposit(escrow.ID, "executor", 5.0, "sig")
+
+	// 释放 12 给执行方：委托方按押金比例支付 8，其余退回
+	if err := em.Release(escrow.ID, "executor", 12.0, map[string]string{"requester": "sig", "executor": "sig"}); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	funds.expect(t, "requester", 12.0, 0)
+	funds.expect(t, "executor", 13.0, 0)
+}
+
+func TestForfeitCompensatesOthers(t *testing.T) {
+	em, funds := newFundedManager(t)
+	escrow, _ := em.CreateEscrow("task1", map[string]float64{"requester": 10.0, "executor": 5.0})
+	em.Deposit(escrow.ID, "requester", 10.0, "sig")
+	em.Deposit(escrow.ID, "executor", 5.0, "sig")
+
+	if err := em.Forfeit(escrow.ID, "executor", "violation"); err != nil {
+		t.Fatalf("Forfeit failed: %v", err)
+	}
+	funds.expect(t, "requester", 25.0, 0)
+	funds.expect(t, "executor", 0, 0)
+}
+
+func TestChildEscrowFundedPayouts(t *testing.T) {
+	em, funds := newFundedManager(t)
+	funds.available["sub"] = 1.0
+	parent := createLockedEscrow(t, em, "task1")
+	child, _ := em.CreateChildEscrow(parent.ID, "task1-sub1", "worker", 4.0, map[string]float64{"sub": 1.0})
+	em.Deposit(child.ID, "sub", 1.0, "sig")
+
+	// 下级托管释放 5 给承包方：承包方自己的押金退回，划拨预算的 4 由上级托管结算时支付
+	if err := em.Release(child.ID, "sub", 5.0, map[string]string{"worker": "sig", "sub": "sig"}); err != nil {
+		t.Fatalf("child release failed: %v", err)
+	}
+	funds.expect(t, "sub", 1.0, 0)
+
+	if err := em.Release(parent.ID, "worker", 6.0, map[string]string{"requester": "sig"}); err != nil {
+		t.Fatalf("parent release failed: %v", err)
+	}
+	funds.expect(t, "sub", 5.0, 0)
+	funds.expect(t, "worker", 6.0, 0)
+	funds.expect(t, "requester", 10.0, 0)
+}
+
+func TestRefundExpired(t *testing.T) {
+	em, funds := newFundedManager(t)
+
+	pending, _ := em.CreateEscrow("task1", map[string]float64{"requester": 5.0, "executor": 5.0})
+	em.Deposit(pending.ID, "requester", 5.0, "sig")
+
+	disputed, _ := em.CreateEscrow("task2", map[string]float64{"requester": 5.0})
+	em.Deposit(disputed.ID, "requester", 5.0, "sig")
+	em.Dispute(disputed.ID, "requester", "no delivery")
+	funds.expect(t, "requester", 10.0, 10.0)
+
+	if refunded := em.RefundExpired(time.Now()); len(refunded) != 0 {
+		t.Errorf("nothing should expire yet, got %v", refunded)
+	}
+
+	refunded := em.RefundExpired(time.Now().Add(8 * 24 * time.Hour))
+	if len(refunded) != 2 {
+		t.Fatalf("expected 2 refunds, got %v", refunded)
+	}
+	if pending.Status != EscrowRefunded || pending.ReleaseCondition != "timeout_refund" {
+		t.Errorf("pending escrow: %s / %s", pending.Status, pending.ReleaseCondition)
+	}
+	if disputed.Status != EscrowRefunded || disputed.ReleaseCondition != "dispute_timeout_refund" {
+		t.Errorf("disputed escrow: %s / %s", disputed.Status, disputed.ReleaseCondition)
+	}
+	funds.expect(t, "requester", 20.0, 0)
+	if n := len(em.GetEscrowsByStatus(EscrowRefunded)); n != 2 {
+		t.Errorf("status index has %d refunded escrows, want 2", n)
+	}
+}
@@ -0,0 +1,395 @@
+// Package feature 实现协议特性开关的协调激活
+// 节点在心跳中通告自己已就绪的特性（如 mailbox-v2），当达到阈值比例的超级节点通告就绪后，
+// 该特性在全网激活。激活是单向的，激活后不会因就绪比例回落而关闭。
+package feature
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNilConfig      = errors.New("config cannot be nil")
+	ErrEmptyNodeID    = errors.New("node ID cannot be empty")
+	ErrInvalidFeature = errors.New("invalid feature")
+	ErrUnknownFeature = errors.New("unknown feature")
+	ErrFeatureExists  = errors.New("feature already registered")
+)
+
+// 内置特性
+const (
+	FeatureMailboxV2         = "mailbox-v2"
+	FeatureEscrowV2          = "escrow-v2"
+	FeatureAdaptiveHeartbeat = "adaptive-heartbeat"
+)
+
+// State 特性激活状态
+type State string
+
+const (
+	StateDefined   State = "defined"   // 已定义，尚无超级节点通告就绪
+	StateSignaling State = "signaling" // 部分超级节点已就绪，未达阈值
+	StateActive    State = "active"    // 已在全网激活
+)
+
+var featureNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{0,63}$`)
+
+// Feature 特性定义
+type Feature struct {
+	Name          string  `json:"name"`
+	Description   string  `json:"description,omitempty"`
+	Threshold     float64 `json:"threshold"`      // 激活所需的超级节点就绪比例（0-1），0 使用默认值
+	MinSupernodes int     `json:"min_supernodes"` // 参与计票的最少超级节点数，0 使用默认值
+}
+
+// DefaultFeatures 返回内置特性
+func DefaultFeatures() []Feature {
+	return []Feature{
+		{Name: FeatureMailboxV2, Description: "mailbox v2 wire format"},
+		{Name: FeatureEscrowV2, Description: "escrow v2 settlement rules"},
+		{Name: FeatureAdaptiveHeartbeat, Description: "heartbeats advertise their interval and back off on stable networks"},
+	}
+}
+
+// Status 特性的激活状态
+type Status struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	State       State     `json:"state"`
+	Threshold   float64   `json:"threshold"`
+	Ready       int       `json:"ready"`      // 已通告就绪的超级节点数
+	Supernodes  int       `json:"supernodes"` // 当前超级节点数
+	Ratio       float64   `json:"ratio"`
+	LocalReady  bool      `json:"local_ready"` // 本节点是否已就绪
+	ActivatedAt time.Time `json:"activated_at,omitempty"`
+	ReadyNodes  []string  `json:"ready_nodes,omitempty"`
+}
+
+// Config 特性激活配置
+type Config struct {
+	NodeID           string
+	DataDir          string
+	DefaultThreshold float64       // 默认激活阈值
+	MinSupernodes    int           // 默认最少超级节点数，避免网络初期少数节点即可激活
+	SignalTTL        time.Duration // 就绪通告的有效期，超过后需由新心跳续期
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:           nodeID,
+		DataDir:          "./data/features",
+		DefaultThreshold: 0.75,
+		MinSupernodes:    3,
+		SignalTTL:        72 * time.Hour, // 心跳每天一次，允许错过两次
+	}
+}
+
+// signal 节点的就绪通告
+type signal struct {
+	features map[string]bool
+	seenAt   time.Time
+}
+
+// Manager 特性激活管理器
+type Manager struct {
+	mu         sync.RWMutex
+	config     *Config
+	features   map[string]*Feature
+	localReady map[string]bool
+	activated  map[string]time.Time
+	signals    map[string]*signal // nodeID -> 最近的通告
+
+	supernodesFunc func() []string
+	now            func() time.Time
+
+	// 回调
+	OnActivated func(status *Status)
+}
+
+// NewManager 创建特性激活管理器，并注册内置特性
+func NewManager(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+
+	m := &Manager{
+		config:     config,
+		features:   make(map[string]*Feature),
+		localReady: make(map[string]bool),
+		activated:  make(map[string]time.Time),
+		signals:    make(map[string]*signal),
+		now:        time.Now,
+	}
+	for _, f := range DefaultFeatures() {
+		if err := m.Register(f); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		m.load()
+	}
+
+	return m, nil
+}
+
+// SetSupernodesFunc 设置获取当前超级节点列表的函数
+func (m *Manager) SetSupernodesFunc(fn func() []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.supernodesFunc = fn
+}
+
+// Register 注册特性
+func (m *Manager) Register(f Feature) error {
+	if !featureNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("%w: name %q", ErrInvalidFeature, f.Name)
+	}
+	if f.Threshold < 0 || f.Threshold > 1 {
+		return fmt.Errorf("%w: threshold %.2f out of range", ErrInvalidFeature, f.Threshold)
+	}
+	if f.MinSupernodes < 0 {
+		return fmt.Errorf("%w: negative min_supernodes", ErrInvalidFeature)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.features[f.Name]; exists {
+		return ErrFeatureExists
+	}
+	m.features[f.Name] = &f
+	return nil
+}
+
+// SetReady 设置本节点对某特性的就绪状态，下次心跳时通告
+func (m *Manager) SetReady(name string, ready bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.features[name]; !exists {
+		return ErrUnknownFeature
+	}
+	if ready {
+		m.localReady[name] = true
+	} else {
+		delete(m.localReady, name)
+	}
+	m.save()
+	return nil
+}
+
+// ReadyFeatures 本节点已就绪的特性（写入心跳）
+func (m *Manager) ReadyFeatures() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]string, 0, len(m.localReady))
+	for name := range m.localReady {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ObserveSignal 记录对端心跳中通告的就绪特性，并检查是否有特性达到激活条件
+// 每次通告覆盖该节点之前的通告，未知特性被忽略。返回因此激活的特性。
+func (m *Manager) ObserveSignal(nodeID string, features []string) []*Status {
+	if nodeID == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	s := &signal{features: make(map[string]bool), seenAt: m.now()}
+	for _, name := range features {
+		if _, known := m.features[name]; known {
+			s.features[name] = true
+		}
+	}
+	m.signals[nodeID] = s
+	m.mu.Unlock()
+
+	return m.Evaluate()
+}
+
+// Evaluate 按当前超级节点的就绪通告检查激活条件，返回新激活的特性
+func (m *Manager) Evaluate() []*Status {
+	m.mu.Lock()
+	supernodes := m.currentSupernodesLocked()
+	var activated []*Status
+	for name, f := range m.features {
+		if _, active := m.activated[name]; active {
+			continue
+		}
+		status := m.statusLocked(f, supernodes)
+		if status.Supernodes < m.minSupernodes(f) || status.Ratio < m.threshold(f) {
+			continue
+		}
+		m.activated[name] = m.now()
+		status.State = StateActive
+		status.ActivatedAt = m.activated[name]
+		activated = append(activated, status)
+	}
+	if len(activated) > 0 {
+		m.save()
+	}
+	callback := m.OnActivated
+	m.mu.Unlock()
+
+	if callback != nil {
+		for _, status := range activated {
+			callback(status)
+		}
+	}
+	return activated
+}
+
+// IsActive 特性是否已激活
+func (m *Manager) IsActive(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, active := m.activated[name]
+	return active
+}
+
+// GetStatus 获取特性的激活状态
+func (m *Manager) GetStatus(name string) (*Status, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, exists := m.features[name]
+	if !exists {
+		return nil, ErrUnknownFeature
+	}
+	return m.statusLocked(f, m.currentSupernodesLocked()), nil
+}
+
+// ListStatus 获取所有特性的激活状态，按名称排序
+func (m *Manager) ListStatus() []*Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	supernodes := m.currentSupernodesLocked()
+	result := make([]*Status, 0, len(m.features))
+	for _, f := range m.features {
+		result = append(result, m.statusLocked(f, supernodes))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// currentSupernodesLocked 当前超级节点集合（调用方需持有锁）
+func (m *Manager) currentSupernodesLocked() []string {
+	if m.supernodesFunc == nil {
+		return nil
+	}
+	return m.supernodesFunc()
+}
+
+// statusLocked 计算特性状态（调用方需持有锁）
+func (m *Manager) statusLocked(f *Feature, supernodes []string) *Status {
+	status := &Status{
+		Name:        f.Name,
+		Description: f.Description,
+		State:       StateDefined,
+		Threshold:   m.threshold(f),
+		Supernodes:  len(supernodes),
+		LocalReady:  m.localReady[f.Name],
+	}
+
+	now := m.now()
+	for _, nodeID := range supernodes {
+		ready := false
+		if nodeID == m.config.NodeID {
+			ready = m.localReady[f.Name]
+		} else if s, ok := m.signals[nodeID]; ok && now.Sub(s.seenAt) <= m.config.SignalTTL {
+			ready = s.features[f.Name]
+		}
+		if ready {
+			status.Ready++
+			status.ReadyNodes = append(status.ReadyNodes, nodeID)
+		}
+	}
+	sort.Strings(status.ReadyNodes)
+	if status.Supernodes > 0 {
+		status.Ratio = float64(status.Ready) / float64(status.Supernodes)
+	}
+
+	if at, active := m.activated[f.Name]; active {
+		status.State = StateActive
+		status.ActivatedAt = at
+	} else if status.Ready > 0 {
+		status.State = StateSignaling
+	}
+	return status
+}
+
+func (m *Manager) threshold(f *Feature) float64 {
+	if f.Threshold > 0 {
+		return f.Threshold
+	}
+	return m.config.DefaultThreshold
+}
+
+func (m *Manager) minSupernodes(f *Feature) int {
+	if f.MinSupernodes > 0 {
+		return f.MinSupernodes
+	}
+	if m.config.MinSupernodes > 0 {
+		return m.config.MinSupernodes
+	}
+	return 1
+}
+
+// persistState 持久化状态
+type persistState struct {
+	LocalReady []string             `json:"local_ready"`
+	Activated  map[string]time.Time `json:"activated"`
+}
+
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	state := persistState{Activated: m.activated}
+	for name := range m.localReady {
+		state.LocalReady = append(state.LocalReady, name)
+	}
+	sort.Strings(state.LocalReady)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "features.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "features.json"))
+	if err != nil {
+		return
+	}
+	var state persistState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+	for _, name := range state.LocalReady {
+		m.localReady[name] = true
+	}
+	for name, at := range state.Activated {
+		m.activated[name] = at
+	}
+}
@@ -0,0 +1,127 @@
+package feature
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T, dataDir string) *Manager {
+	t.Helper()
+	config := DefaultConfig("self")
+	config.DataDir = dataDir
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	m.SetSupernodesFunc(func() []string { return []string{"self", "sn1", "sn2", "sn3"} })
+	return m
+}
+
+func TestNewManager(t *testing.T) {
+	if _, err := NewManager(nil); err != ErrNilConfig {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+	if _, err := NewManager(&Config{}); err != ErrEmptyNodeID {
+		t.Errorf("expected ErrEmptyNodeID, got %v", err)
+	}
+
+	m := newTestManager(t, "")
+	if len(m.ListStatus()) != len(DefaultFeatures()) {
+		t.Errorf("expected built-in features to be registered")
+	}
+	if err := m.Register(Feature{Name: "Bad Name"}); !errors.Is(err, ErrInvalidFeature) {
+		t.Errorf("expected ErrInvalidFeature, got %v", err)
+	}
+	if err := m.Register(Feature{Name: FeatureMailboxV2}); err != ErrFeatureExists {
+		t.Errorf("expected ErrFeatureExists, got %v", err)
+	}
+	if err := m.SetReady("unknown", true); err != ErrUnknownFeature {
+		t.Errorf("expected ErrUnknownFeature, got %v", err)
+	}
+}
+
+func TestFeatureActivation(t *testing.T) {
+	m := newTestManager(t, t.TempDir())
+
+	var activated []string
+	m.OnActivated = func(status *Status) { activated = append(activated, status.Name) }
+
+	if err := m.SetReady(FeatureMailboxV2, true); err != nil {
+		t.Fatalf("SetReady failed: %v", err)
+	}
+	if ready := m.ReadyFeatures(); len(ready) != 1 || ready[0] != FeatureMailboxV2 {
+		t.Errorf("ReadyFeatures = %v", ready)
+	}
+
+	// 非超级节点的通告不计票，未知特性被忽略
+	m.ObserveSignal("ordinary", []string{FeatureMailboxV2})
+	m.ObserveSignal("sn1", []string{FeatureMailboxV2, "unknown"})
+
+	status, err := m.GetStatus(FeatureMailboxV2)
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if status.State != StateSignaling || status.Ready != 2 || status.Ratio != 0.5 {
+		t.Errorf("unexpected status before threshold: %+v", status)
+	}
+
+	// 3/4 达到 75% 阈值
+	if newly := m.ObserveSignal("sn2", []string{FeatureMailboxV2}); len(newly) != 1 {
+		t.Fatalf("expected activation, got %v", newly)
+	}
+	if !m.IsActive(FeatureMailboxV2) || m.IsActive(FeatureEscrowV2) {
+		t.Error("only mailbox-v2 should be active")
+	}
+	if len(activated) != 1 {
+		t.Errorf("OnActivated called %d times", len(activated))
+	}
+
+	// 激活后就绪比例回落不影响
+	m.ObserveSignal("sn1", nil)
+	if status, _ := m.GetStatus(FeatureMailboxV2); status.State != StateActive || status.ActivatedAt.IsZero() {
+		t.Errorf("activation should be sticky: %+v", status)
+	}
+}
+
+func TestFeatureSignalExpiry(t *testing.T) {
+	m := newTestManager(t, "")
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	m.SetReady(FeatureEscrowV2, true)
+	m.ObserveSignal("sn1", []string{FeatureEscrowV2})
+
+	// sn1 的通告过期，sn2 的通告不足以激活
+	now = now.Add(m.config.SignalTTL + time.Minute)
+	m.ObserveSignal("sn2", []string{FeatureEscrowV2})
+	if status, _ := m.GetStatus(FeatureEscrowV2); status.Ready != 2 || status.State != StateSignaling {
+		t.Errorf("expired signal should not count: %+v", status)
+	}
+}
+
+func TestFeatureMinSupernodes(t *testing.T) {
+	m := newTestManager(t, "")
+	m.SetSupernodesFunc(func() []string { return []string{"self"} })
+
+	m.SetReady(FeatureMailboxV2, true)
+	if newly := m.Evaluate(); len(newly) != 0 {
+		t.Errorf("should not activate with fewer than %d supernodes", m.config.MinSupernodes)
+	}
+}
+
+func TestFeaturePersistence(t *testing.T) {
+	dir := t.TempDir()
+	m := newTestManager(t, dir)
+	m.SetReady(FeatureMailboxV2, true)
+	m.ObserveSignal("sn1", []string{FeatureMailboxV2})
+	m.ObserveSignal("sn2", []string{FeatureMailboxV2})
+
+	reloaded := newTestManager(t, dir)
+	if !reloaded.IsActive(FeatureMailboxV2) {
+		t.Error("activation should survive restart")
+	}
+	if ready := reloaded.ReadyFeatures(); len(ready) != 1 {
+		t.Errorf("local readiness should survive restart, got %v", ready)
+	}
+}
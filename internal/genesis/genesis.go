@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,11 @@ var (
 	ErrInviterNotTrusted       = errors.New("邀请节点不可信")
 	ErrInvitationExpired       = errors.New("邀请函已过期")
 	ErrNodeAlreadyJoined       = errors.New("节点已加入网络")
+	ErrInvitationUsed          = errors.New("邀请函已被使用")
+	ErrNodeNotFound            = errors.New("节点未加入网络")
+	ErrPeerIDMismatch          = errors.New("libp2p 节点ID与邀请函不匹配")
+	ErrInvalidJoinSignature    = errors.New("加入请求签名无效")
+	ErrInviterKeyMismatch      = errors.New("邀请函公钥与邀请节点登记的公钥不一致")
 )
 
 // GenesisInfo 创世信息
@@ -59,9 +65,11 @@ type BootstrapNode struct {
 
 // Invitation 邀请函
 type Invitation struct {
+	ID             string `json:"id"`               // 邀请函ID(随机)，每个ID只能使用一次
 	InviterNodeID  string `json:"inviter_node_id"`  // 邀请节点ID
 	InviterKey     string `json:"inviter_key"`      // 邀请节点公钥(hex)
 	NewNodeKey     string `json:"new_node_key"`     // 新节点公钥(hex)
+	PeerID         string `json:"peer_id"`          // 新节点的 libp2p 节点ID，只有该身份能按邀请连入
 	Timestamp      int64  `json:"timestamp"`        // 邀请时间戳
 	ExpiresAt      int64  `json:"expires_at"`       // 过期时间戳
 	InitReputation int64  `json:"init_reputation"`  // 初始声誉
@@ -72,9 +80,10 @@ type Invitation struct {
 type JoinRequest struct {
 	NewNodeID   string      `json:"new_node_id"`   // 新节点ID
 	NewNodeKey  string      `json:"new_node_key"`  // 新节点公钥(hex)
+	PeerID      string      `json:"peer_id"`       // 新节点的 libp2p 节点ID
 	Invitation  *Invitation `json:"invitation"`    // 邀请函
 	Timestamp   int64       `json:"timestamp"`     // 请求时间戳
-	Signature   string      `json:"signature"`     // 新节点签名(hex)
+	Signature   string      `json:"signature"`     // 新节点用邀请函绑定的密钥签名(hex)，证明持有该密钥
 }
 
 // JoinResponse 加入响应
@@ -107,6 +116,8 @@ type GenesisManager struct {
 	
 	// 已加入节点
 	joinedNodes map[string]*JoinedNode
+	// 本节点签发或经本节点使用的邀请函
+	invitations map[string]*InvitationRecord
 	mu          sync.RWMutex
 }
 
@@ -117,6 +128,10 @@ type JoinedNode struct {
 	Reputation int64     `json:"reputation"`
 	JoinedAt   time.Time `json:"joined_at"`
 	InviterID  string    `json:"inviter_id"`
+	// 加入时使用的邀请函ID，与 InviterID 一起构成邀请树
+	InvitationID string `json:"invitation_id,omitempty"`
+	// 邀请函绑定的 libp2p 节点ID，入站准入按它放行
+	PeerID string `json:"peer_id,omitempty"`
 }
 
 // NewGenesisManager 创建创世管理器
@@ -124,6 +139,7 @@ func NewGenesisManager(dataDir string) (*GenesisManager, error) {
 	gm := &GenesisManager{
 		dataDir:     dataDir,
 		joinedNodes: make(map[string]*JoinedNode),
+		invitations: make(map[string]*InvitationRecord),
 	}
 
 	// 尝试加载已有的创世信息
@@ -149,11 +165,23 @@ func NewGenesisManager(dataDir string) (*GenesisManager, error) {
 	nodesPath := filepath.Join(dataDir, "joined_nodes.json")
 	if data, err := os.ReadFile(nodesPath); err == nil {
 		var nodes map[string]*JoinedNode
-		if err := json.Unmarshal(data, &nodes); err == nil {
+		if err := json.Unmarshal(data, &nodes); err == nil && nodes != nil {
 			gm.joinedNodes = nodes
 		}
 	}
 
+	// 其他节点分发的创世信息：创世节点是邀请树的根
+	gm.ensureGenesisNodeLocked()
+
+	// 加载邀请函记录
+	invitationsPath := filepath.Join(dataDir, "invitations.json")
+	if data, err := os.ReadFile(invitationsPath); err == nil {
+		var records map[string]*InvitationRecord
+		if err := json.Unmarshal(data, &records); err == nil && records != nil {
+			gm.invitations = records
+		}
+	}
+
 	return gm, nil
 }
 
@@ -233,9 +261,29 @@ func (gm *GenesisManager) LoadGenesis(genesisJSON []byte) error {
 	}
 
 	gm.genesis = &genesis
+	if gm.ensureGenesisNodeLocked() {
+		return gm.saveNodes()
+	}
 	return nil
 }
 
+// ensureGenesisNodeLocked 把创世节点登记为邀请树的根，使其签发的邀请函在本节点可以验证；返回是否新登记
+func (gm *GenesisManager) ensureGenesisNodeLocked() bool {
+	if gm.genesis == nil {
+		return false
+	}
+	if _, ok := gm.joinedNodes[gm.genesis.GenesisNodeID]; ok {
+		return false
+	}
+	gm.joinedNodes[gm.genesis.GenesisNodeID] = &JoinedNode{
+		NodeID:     gm.genesis.GenesisNodeID,
+		PublicKey:  gm.genesis.GenesisKey,
+		Reputation: 100, // 创世节点高声誉
+		JoinedAt:   time.UnixMilli(gm.genesis.Timestamp),
+	}
+	return true
+}
+
 // GetGenesis 获取创世信息
 func (gm *GenesisManager) GetGenesis() *GenesisInfo {
 	gm.mu.RLock()
@@ -243,10 +291,10 @@ func (gm *GenesisManager) GetGenesis() *GenesisInfo {
 	return gm.genesis
 }
 
-// CreateInvitation 创建邀请函
-func (gm *GenesisManager) CreateInvitation(newNodeKeyHex string) (*Invitation, error) {
-	gm.mu.RLock()
-	defer gm.mu.RUnlock()
+// CreateInvitation 创建邀请函，绑定新节点的创世公钥和 libp2p 节点ID
+func (gm *GenesisManager) CreateInvitation(newNodeKeyHex, peerID string) (*Invitation, error) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
 
 	if gm.genesis == nil {
 		return nil, ErrGenesisNotFound
@@ -259,17 +307,32 @@ func (gm *GenesisManager) CreateInvitation(newNodeKeyHex string) (*Invitation, e
 	// 检查自己的声誉是否足够
 	myNode, ok := gm.joinedNodes[gm.nodeID]
 	if !ok {
-		return nil, errors.New("当前节点未加入网络")
+		return nil, fmt.Errorf("%w: 当前节点未加入网络", ErrInviterNotTrusted)
 	}
 	if myNode.Reputation < gm.genesis.MinInviterReputation {
-		return nil, fmt.Errorf("声誉不足，需要 %d，当前 %d", gm.genesis.MinInviterReputation, myNode.Reputation)
+		return nil, fmt.Errorf("%w: 声誉不足，需要 %d，当前 %d", ErrInviterNotTrusted, gm.genesis.MinInviterReputation, myNode.Reputation)
+	}
+
+	// 邀请函绑定新节点公钥，只有持有对应私钥的节点ID才能加入
+	if _, err := parsePublicKey(newNodeKeyHex); err != nil {
+		return nil, fmt.Errorf("解析新节点公钥失败: %w", err)
+	}
+	if peerID == "" {
+		return nil, fmt.Errorf("%w: 缺少新节点的 libp2p 节点ID", ErrInvalidInvitation)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("生成邀请函ID失败: %w", err)
 	}
 
 	now := time.Now()
 	invitation := &Invitation{
+		ID:             hex.EncodeToString(id),
 		InviterNodeID:  gm.nodeID,
 		InviterKey:     hex.EncodeToString(sm2.Compress(gm.publicKey)),
 		NewNodeKey:     newNodeKeyHex,
+		PeerID:         peerID,
 		Timestamp:      now.UnixMilli(),
 		ExpiresAt:      now.Add(time.Duration(gm.genesis.InvitationValidHours) * time.Hour).UnixMilli(),
 		InitReputation: gm.genesis.InitialReputation,
@@ -282,6 +345,11 @@ func (gm *GenesisManager) CreateInvitation(newNodeKeyHex string) (*Invitation, e
 	}
 	invitation.Signature = signature
 
+	gm.invitations[invitation.ID] = &InvitationRecord{Invitation: invitation}
+	if err := gm.saveInvitations(); err != nil {
+		return nil, fmt.Errorf("保存邀请函失败: %w", err)
+	}
+
 	return invitation, nil
 }
 
@@ -298,6 +366,15 @@ func (gm *GenesisManager) verifyInvitationLocked(invitation *Invitation) error {
 		return ErrGenesisNotFound
 	}
 
+	if invitation == nil || invitation.ID == "" {
+		return ErrInvalidInvitation
+	}
+
+	// 检查是否已使用
+	if record, ok := gm.invitations[invitation.ID]; ok && record.UsedBy != "" {
+		return ErrInvitationUsed
+	}
+
 	// 检查过期
 	if time.Now().UnixMilli() > invitation.ExpiresAt {
 		return ErrInvitationExpired
@@ -314,28 +391,21 @@ func (gm *GenesisManager) verifyInvitationLocked(invitation *Invitation) error {
 		return ErrInviterNotTrusted
 	}
 
-	// 验证签名
-	pubKey, err := parsePublicKey(invitation.InviterKey)
+	// 签名须出自邀请节点登记的公钥，邀请函自带的公钥不可信
+	if !strings.EqualFold(invitation.InviterKey, inviter.PublicKey) {
+		return ErrInviterKeyMismatch
+	}
+	pubKey, err := parsePublicKey(inviter.PublicKey)
 	if err != nil {
 		return fmt.Errorf("解析邀请者公钥失败: %w", err)
 	}
 
-	// 构建签名数据
-	signData := fmt.Sprintf("%s|%s|%s|%d|%d|%d",
-		invitation.InviterNodeID,
-		invitation.InviterKey,
-		invitation.NewNodeKey,
-		invitation.Timestamp,
-		invitation.ExpiresAt,
-		invitation.InitReputation,
-	)
-
 	sigBytes, err := hex.DecodeString(invitation.Signature)
 	if err != nil {
 		return fmt.Errorf("解析签名失败: %w", err)
 	}
 
-	hash := sm3.Sm3Sum([]byte(signData))
+	hash := sm3.Sm3Sum([]byte(invitation.signData()))
 	if !pubKey.Verify(hash[:], sigBytes) {
 		return ErrInvalidInvitation
 	}
@@ -352,8 +422,8 @@ func (gm *GenesisManager) ProcessJoinRequest(req *JoinRequest) (*JoinResponse, e
 		return nil, ErrGenesisNotFound
 	}
 
-	// 检查节点是否已加入
-	if _, ok := gm.joinedNodes[req.NewNodeID]; ok {
+	// 检查节点是否已加入，同一 libp2p 身份也只能加入一次
+	if _, ok := gm.joinedNodes[req.NewNodeID]; ok || gm.peerJoinedLocked(req.PeerID) {
 		return &JoinResponse{
 			Accepted:  false,
 			Reason:    "节点已加入网络",
@@ -385,6 +455,24 @@ func (gm *GenesisManager) ProcessJoinRequest(req *JoinRequest) (*JoinResponse, e
 		}, errors.New("公钥与邀请函不匹配")
 	}
 
+	// 邀请函绑定的 libp2p 身份，入站准入按它放行
+	if req.Invitation.PeerID == "" || req.PeerID != req.Invitation.PeerID {
+		return &JoinResponse{
+			Accepted:  false,
+			Reason:    ErrPeerIDMismatch.Error(),
+			Timestamp: time.Now().UnixMilli(),
+		}, ErrPeerIDMismatch
+	}
+
+	// 新节点须用邀请函绑定的私钥签名，只知道邀请码和公钥的一方无法冒用
+	if !verifyJoinSignature(newPubKey, req) {
+		return &JoinResponse{
+			Accepted:  false,
+			Reason:    ErrInvalidJoinSignature.Error(),
+			Timestamp: time.Now().UnixMilli(),
+		}, ErrInvalidJoinSignature
+	}
+
 	// 生成节点ID
 	nodeID := generateNodeIDFromKey(newPubKey)
 	if nodeID != req.NewNodeID {
@@ -395,15 +483,26 @@ func (gm *GenesisManager) ProcessJoinRequest(req *JoinRequest) (*JoinResponse, e
 		}, errors.New("节点ID不匹配")
 	}
 
-	// 添加新节点
+	// 添加新节点，初始声誉取创世配置，不采信邀请函中的值
 	gm.joinedNodes[nodeID] = &JoinedNode{
-		NodeID:     nodeID,
-		PublicKey:  req.NewNodeKey,
-		Reputation: req.Invitation.InitReputation,
-		JoinedAt:   time.Now(),
-		InviterID:  req.Invitation.InviterNodeID,
+		NodeID:       nodeID,
+		PublicKey:    req.NewNodeKey,
+		Reputation:   gm.genesis.InitialReputation,
+		JoinedAt:     time.Now(),
+		InviterID:    req.Invitation.InviterNodeID,
+		InvitationID: req.Invitation.ID,
+		PeerID:       req.PeerID,
 	}
 
+	// 标记邀请函已使用，其他节点签发的邀请函也在此登记
+	record, ok := gm.invitations[req.Invitation.ID]
+	if !ok {
+		record = &InvitationRecord{Invitation: req.Invitation}
+		gm.invitations[req.Invitation.ID] = record
+	}
+	record.UsedBy = nodeID
+	record.UsedAt = time.Now().UnixMilli()
+
 	// 推荐邻居
 	neighbors := gm.recommendNeighbors(nodeID)
 
@@ -411,7 +510,7 @@ func (gm *GenesisManager) ProcessJoinRequest(req *JoinRequest) (*JoinResponse, e
 	response := &JoinResponse{
 		Accepted:        true,
 		NodeID:          nodeID,
-		InitReputation:  req.Invitation.InitReputation,
+		InitReputation:  gm.genesis.InitialReputation,
 		Neighbors:       neighbors,
 		Timestamp:       time.Now().UnixMilli(),
 		ResponderNodeID: gm.nodeID,
@@ -425,8 +524,9 @@ func (gm *GenesisManager) ProcessJoinRequest(req *JoinRequest) (*JoinResponse, e
 		}
 	}
 
-	// 保存节点列表
+	// 保存节点列表和邀请函记录
 	gm.saveNodes()
+	gm.saveInvitations()
 
 	return response, nil
 }
@@ -507,6 +607,25 @@ func (gm *GenesisManager) IsNodeJoined(nodeID string) bool {
 	return ok
 }
 
+// IsPeerJoined 检查 libp2p 节点是否凭邀请函加入
+func (gm *GenesisManager) IsPeerJoined(peerID string) bool {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	return gm.peerJoinedLocked(peerID)
+}
+
+func (gm *GenesisManager) peerJoinedLocked(peerID string) bool {
+	if peerID == "" {
+		return false
+	}
+	for _, node := range gm.joinedNodes {
+		if node.PeerID == peerID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetNodeID 获取当前节点ID
 func (gm *GenesisManager) GetNodeID() string {
 	return gm.nodeID
@@ -546,16 +665,7 @@ func (gm *GenesisManager) signGenesis(genesis *GenesisInfo) (string, error) {
 
 // signInvitation 签名邀请函
 func (gm *GenesisManager) signInvitation(inv *Invitation) (string, error) {
-	signData := fmt.Sprintf("%s|%s|%s|%d|%d|%d",
-		inv.InviterNodeID,
-		inv.InviterKey,
-		inv.NewNodeKey,
-		inv.Timestamp,
-		inv.ExpiresAt,
-		inv.InitReputation,
-	)
-
-	hash := sm3.Sm3Sum([]byte(signData))
+	hash := sm3.Sm3Sum([]byte(inv.signData()))
 	sig, err := gm.privateKey.Sign(rand.Reader, hash[:], nil)
 	if err != nil {
 		return "", err
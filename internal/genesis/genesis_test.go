@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -12,6 +14,22 @@ import (
 	"github.com/tjfoc/gmsm/sm2"
 )
 
+// testPeerID 邀请函绑定的 libp2p 节点ID
+const testPeerID = "12D3KooWInvitee"
+
+// signedJoinRequest 受邀方用自己的私钥签名的加入请求
+func signedJoinRequest(invitation *Invitation, priv *sm2.PrivateKey) *JoinRequest {
+	req := &JoinRequest{
+		NewNodeID:  generateNodeID(&priv.PublicKey),
+		NewNodeKey: hex.EncodeToString(sm2.Compress(&priv.PublicKey)),
+		PeerID:     invitation.PeerID,
+		Invitation: invitation,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+	req.Signature, _ = signJoinRequest(priv, req)
+	return req
+}
+
 func TestNewGenesisManager(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -133,6 +151,10 @@ func TestLoadGenesis(t *testing.T) {
 	if loaded.NetworkName != genesis.NetworkName {
 		t.Errorf("网络名称不匹配: got %s, want %s", loaded.NetworkName, genesis.NetworkName)
 	}
+	// 创世节点登记为邀请树的根，其签发的邀请函可以在本节点验证
+	if !gm2.IsNodeJoined(genesis.GenesisNodeID) {
+		t.Error("加载创世信息后创世节点应该已加入")
+	}
 }
 
 func TestLoadGenesisInvalidSignature(t *testing.T) {
@@ -163,7 +185,7 @@ func TestCreateInvitation(t *testing.T) {
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(&newPriv.PublicKey))
 
 	// 创建邀请函
-	invitation, err := gm.CreateInvitation(newPubKeyHex)
+	invitation, err := gm.CreateInvitation(newPubKeyHex, testPeerID)
 	if err != nil {
 		t.Fatalf("创建邀请函失败: %v", err)
 	}
@@ -197,7 +219,7 @@ func TestVerifyInvitationExpired(t *testing.T) {
 	newPriv, _ := sm2.GenerateKey(rand.Reader)
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(&newPriv.PublicKey))
 
-	invitation, _ := gm.CreateInvitation(newPubKeyHex)
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
 
 	// 手动设置过期时间
 	invitation.ExpiresAt = time.Now().Add(-1 * time.Hour).UnixMilli()
@@ -221,15 +243,10 @@ func TestProcessJoinRequest(t *testing.T) {
 	newNodeID := generateNodeID(newPubKey)
 
 	// 创建邀请函
-	invitation, _ := gm.CreateInvitation(newPubKeyHex)
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
 
 	// 创建加入请求
-	joinReq := &JoinRequest{
-		NewNodeID:  newNodeID,
-		NewNodeKey: newPubKeyHex,
-		Invitation: invitation,
-		Timestamp:  time.Now().UnixMilli(),
-	}
+	joinReq := signedJoinRequest(invitation, newPriv)
 
 	// 处理加入请求
 	resp, err := gm.ProcessJoinRequest(joinReq)
@@ -270,16 +287,10 @@ func TestProcessJoinRequestNodeAlreadyJoined(t *testing.T) {
 	newPriv, _ := sm2.GenerateKey(rand.Reader)
 	newPubKey := &newPriv.PublicKey
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(newPubKey))
-	newNodeID := generateNodeID(newPubKey)
 
-	invitation, _ := gm.CreateInvitation(newPubKeyHex)
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
 
-	joinReq := &JoinRequest{
-		NewNodeID:  newNodeID,
-		NewNodeKey: newPubKeyHex,
-		Invitation: invitation,
-		Timestamp:  time.Now().UnixMilli(),
-	}
+	joinReq := signedJoinRequest(invitation, newPriv)
 
 	// 第一次加入
 	gm.ProcessJoinRequest(joinReq)
@@ -356,13 +367,8 @@ func TestPersistence(t *testing.T) {
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(newPubKey))
 	newNodeID := generateNodeID(newPubKey)
 
-	invitation, _ := gm1.CreateInvitation(newPubKeyHex)
-	joinReq := &JoinRequest{
-		NewNodeID:  newNodeID,
-		NewNodeKey: newPubKeyHex,
-		Invitation: invitation,
-		Timestamp:  time.Now().UnixMilli(),
-	}
+	invitation, _ := gm1.CreateInvitation(newPubKeyHex, testPeerID)
+	joinReq := signedJoinRequest(invitation, newPriv)
 	gm1.ProcessJoinRequest(joinReq)
 
 	// 重新加载
@@ -402,15 +408,9 @@ func TestNeighborRecommendation(t *testing.T) {
 		newPriv, _ := sm2.GenerateKey(rand.Reader)
 		newPubKey := &newPriv.PublicKey
 		newPubKeyHex := hex.EncodeToString(sm2.Compress(newPubKey))
-		newNodeID := generateNodeID(newPubKey)
-
-		invitation, _ := gm.CreateInvitation(newPubKeyHex)
-		joinReq := &JoinRequest{
-			NewNodeID:  newNodeID,
-			NewNodeKey: newPubKeyHex,
-			Invitation: invitation,
-			Timestamp:  time.Now().UnixMilli(),
-		}
+
+		invitation, _ := gm.CreateInvitation(newPubKeyHex, fmt.Sprintf("peer-%d", i))
+		joinReq := signedJoinRequest(invitation, newPriv)
 		gm.ProcessJoinRequest(joinReq)
 	}
 
@@ -420,13 +420,8 @@ func TestNeighborRecommendation(t *testing.T) {
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(newPubKey))
 	newNodeID := generateNodeID(newPubKey)
 
-	invitation, _ := gm.CreateInvitation(newPubKeyHex)
-	joinReq := &JoinRequest{
-		NewNodeID:  newNodeID,
-		NewNodeKey: newPubKeyHex,
-		Invitation: invitation,
-		Timestamp:  time.Now().UnixMilli(),
-	}
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
+	joinReq := signedJoinRequest(invitation, newPriv)
 
 	resp, err := gm.ProcessJoinRequest(joinReq)
 	if err != nil {
@@ -448,6 +443,210 @@ func TestNeighborRecommendation(t *testing.T) {
 	t.Logf("推荐了 %d 个邻居", len(resp.Neighbors))
 }
 
+func TestInvitationSingleUse(t *testing.T) {
+	tempDir := t.TempDir()
+
+	gm, _ := NewGenesisManager(tempDir)
+	gm.InitGenesis("TestNetwork", "1.0.0")
+
+	newPriv, _ := sm2.GenerateKey(rand.Reader)
+	newPubKeyHex := hex.EncodeToString(sm2.Compress(&newPriv.PublicKey))
+
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
+	code, err := EncodeInvitation(invitation)
+	if err != nil {
+		t.Fatalf("编码邀请码失败: %v", err)
+	}
+
+	// 公钥与邀请函不一致时拒绝，邀请函仍可使用
+	otherPriv, _ := sm2.GenerateKey(rand.Reader)
+	if _, err := gm.Join(code, hex.EncodeToString(sm2.Compress(&otherPriv.PublicKey)), testPeerID, ""); err == nil {
+		t.Error("使用其他公钥加入应该失败")
+	}
+
+	resp, err := gm.Join(code, newPubKeyHex, testPeerID, signedJoinRequest(invitation, newPriv).Signature)
+	if err != nil {
+		t.Fatalf("使用邀请码加入失败: %v", err)
+	}
+	if resp.NodeID != generateNodeID(&newPriv.PublicKey) {
+		t.Errorf("节点ID不匹配: got %s", resp.NodeID)
+	}
+
+	// 邀请函只能使用一次，重启后仍然有效
+	if err := gm.VerifyInvitation(invitation); err != ErrInvitationUsed {
+		t.Errorf("预期 ErrInvitationUsed, got %v", err)
+	}
+	gm2, _ := NewGenesisManager(tempDir)
+	if err := gm2.VerifyInvitation(invitation); err != ErrInvitationUsed {
+		t.Errorf("重新加载后预期 ErrInvitationUsed, got %v", err)
+	}
+	records := gm2.GetInvitations()
+	if len(records) != 1 || records[0].UsedBy != resp.NodeID {
+		t.Errorf("邀请函记录错误: %+v", records)
+	}
+
+	// 篡改邀请码
+	if _, err := DecodeInvitation("not-a-code!"); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("预期 ErrInvalidInvitation, got %v", err)
+	}
+	forged := *invitation
+	forged.ID = "forged"
+	if err := gm.VerifyInvitation(&forged); err != ErrInvalidInvitation {
+		t.Errorf("篡改邀请函ID应返回 ErrInvalidInvitation, got %v", err)
+	}
+	if _, err := gm.CreateInvitation("zz", testPeerID); err == nil {
+		t.Error("无效公钥不应创建邀请函")
+	}
+}
+
+func TestJoinProofOfPossession(t *testing.T) {
+	gm, _ := NewGenesisManager(t.TempDir())
+	gm.InitGenesis("TestNetwork", "1.0.0")
+
+	invitee, _ := NewGenesisManager(t.TempDir())
+	pubkey, err := invitee.EnsureKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gm.CreateInvitation(pubkey, ""); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("缺少 libp2p 节点ID应返回 ErrInvalidInvitation, got %v", err)
+	}
+	invitation, _ := gm.CreateInvitation(pubkey, testPeerID)
+	code, _ := EncodeInvitation(invitation)
+
+	if _, err := invitee.SignJoin(code, "12D3KooWOther"); err != ErrPeerIDMismatch {
+		t.Errorf("为其他 libp2p 身份签名应返回 ErrPeerIDMismatch, got %v", err)
+	}
+	signature, err := invitee.SignJoin(code, testPeerID)
+	if err != nil {
+		t.Fatalf("签名加入请求失败: %v", err)
+	}
+
+	// 只知道邀请码和公钥的一方无法加入，换用其他 libp2p 身份也不行
+	if _, err := gm.Join(code, pubkey, testPeerID, ""); err != ErrInvalidJoinSignature {
+		t.Errorf("缺少签名应返回 ErrInvalidJoinSignature, got %v", err)
+	}
+	otherPriv, _ := sm2.GenerateKey(rand.Reader)
+	forged := signedJoinRequest(invitation, otherPriv)
+	if _, err := gm.Join(code, pubkey, testPeerID, forged.Signature); err != ErrInvalidJoinSignature {
+		t.Errorf("其他密钥的签名应返回 ErrInvalidJoinSignature, got %v", err)
+	}
+	if _, err := gm.Join(code, pubkey, "12D3KooWOther", signature); err != ErrPeerIDMismatch {
+		t.Errorf("其他 libp2p 身份应返回 ErrPeerIDMismatch, got %v", err)
+	}
+
+	if _, err := gm.Join(code, pubkey, testPeerID, signature); err != nil {
+		t.Fatalf("加入失败: %v", err)
+	}
+	if !gm.IsPeerJoined(testPeerID) || gm.IsPeerJoined("12D3KooWOther") || gm.IsPeerJoined("") {
+		t.Error("只有邀请函绑定的 libp2p 身份应登记为已加入")
+	}
+
+	// 同一 libp2p 身份不能凭另一张邀请函再次加入
+	otherPub := hex.EncodeToString(sm2.Compress(&otherPriv.PublicKey))
+	again, _ := gm.CreateInvitation(otherPub, testPeerID)
+	if _, err := gm.ProcessJoinRequest(signedJoinRequest(again, otherPriv)); err != ErrNodeAlreadyJoined {
+		t.Errorf("预期 ErrNodeAlreadyJoined, got %v", err)
+	}
+}
+
+func TestForgedInviterKey(t *testing.T) {
+	gm, _ := NewGenesisManager(t.TempDir())
+	gm.InitGenesis("TestNetwork", "1.0.0")
+
+	// 陌生人用自己的密钥签发邀请函，冒充创世节点并自定初始声誉
+	strangerPriv, _ := sm2.GenerateKey(rand.Reader)
+	newPriv, _ := sm2.GenerateKey(rand.Reader)
+	forged := &Invitation{
+		ID:             "forged",
+		InviterNodeID:  gm.GetNodeID(),
+		InviterKey:     hex.EncodeToString(sm2.Compress(&strangerPriv.PublicKey)),
+		NewNodeKey:     hex.EncodeToString(sm2.Compress(&newPriv.PublicKey)),
+		PeerID:         testPeerID,
+		Timestamp:      time.Now().UnixMilli(),
+		ExpiresAt:      time.Now().Add(time.Hour).UnixMilli(),
+		InitReputation: 100000,
+	}
+	stranger := &GenesisManager{privateKey: strangerPriv}
+	forged.Signature, _ = stranger.signInvitation(forged)
+
+	if err := gm.VerifyInvitation(forged); err != ErrInviterKeyMismatch {
+		t.Errorf("预期 ErrInviterKeyMismatch, got %v", err)
+	}
+	if _, err := gm.ProcessJoinRequest(signedJoinRequest(forged, newPriv)); err != ErrInviterKeyMismatch {
+		t.Errorf("伪造邀请函的加入请求应被拒绝, got %v", err)
+	}
+	if gm.IsPeerJoined(testPeerID) {
+		t.Error("伪造邀请函不应让节点加入")
+	}
+
+	// 真实邀请函中的初始声誉被篡改时签名失效，新节点声誉始终取创世配置
+	invitation, _ := gm.CreateInvitation(forged.NewNodeKey, testPeerID)
+	tampered := *invitation
+	tampered.InitReputation = 100000
+	if _, err := gm.ProcessJoinRequest(signedJoinRequest(&tampered, newPriv)); err != ErrInvalidInvitation {
+		t.Errorf("篡改初始声誉应返回 ErrInvalidInvitation, got %v", err)
+	}
+	resp, err := gm.ProcessJoinRequest(signedJoinRequest(invitation, newPriv))
+	if err != nil {
+		t.Fatalf("加入失败: %v", err)
+	}
+	if rep, _ := gm.GetNodeReputation(resp.NodeID); rep != gm.GetGenesis().InitialReputation || resp.InitReputation != rep {
+		t.Errorf("初始声誉应取创世配置: got %d / %d", rep, resp.InitReputation)
+	}
+}
+
+func TestInvitationGraph(t *testing.T) {
+	tempDir := t.TempDir()
+
+	gm, _ := NewGenesisManager(tempDir)
+	genesis, _ := gm.InitGenesis("TestNetwork", "1.0.0")
+	root := genesis.GenesisNodeID
+
+	// 创世节点 -> a -> b -> c，a -> d，e 的邀请者未登记
+	now := time.Now()
+	for i, n := range []struct{ id, inviter string }{
+		{"a", root}, {"b", "a"}, {"c", "b"}, {"d", "a"}, {"e", "unknown"},
+	} {
+		gm.joinedNodes[n.id] = &JoinedNode{NodeID: n.id, InviterID: n.inviter, JoinedAt: now.Add(time.Duration(i+1) * time.Second)}
+	}
+	newPriv, _ := sm2.GenerateKey(rand.Reader)
+	gm.CreateInvitation(hex.EncodeToString(sm2.Compress(&newPriv.PublicKey)), testPeerID)
+
+	graph, err := gm.InvitationGraph("")
+	if err != nil {
+		t.Fatalf("构建邀请图失败: %v", err)
+	}
+	if len(graph.Nodes) != 6 || graph.MaxDepth != 3 || graph.Pending != 1 {
+		t.Errorf("邀请图错误: nodes=%d max_depth=%d pending=%d", len(graph.Nodes), graph.MaxDepth, graph.Pending)
+	}
+	if len(graph.Roots) != 2 {
+		t.Errorf("根节点错误: %v", graph.Roots)
+	}
+	byID := make(map[string]GraphNode)
+	for _, n := range graph.Nodes {
+		byID[n.NodeID] = n
+	}
+	if n := byID["a"]; n.Depth != 1 || n.Invited != 2 || n.Descendants != 3 {
+		t.Errorf("节点 a 错误: %+v", n)
+	}
+	if n := byID[root]; n.Invited != 1 || n.Descendants != 4 {
+		t.Errorf("创世节点错误: %+v", n)
+	}
+
+	sub, err := gm.InvitationGraph("a")
+	if err != nil {
+		t.Fatalf("构建子树失败: %v", err)
+	}
+	if len(sub.Nodes) != 4 || sub.MaxDepth != 2 || len(sub.Roots) != 1 || sub.Roots[0] != "a" {
+		t.Errorf("子树错误: %+v", sub)
+	}
+
+	if _, err := gm.InvitationGraph("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("预期 ErrNodeNotFound, got %v", err)
+	}
+}
+
 func BenchmarkCreateInvitation(b *testing.B) {
 	tempDir := b.TempDir()
 	gm, _ := NewGenesisManager(tempDir)
@@ -458,7 +657,7 @@ func BenchmarkCreateInvitation(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		gm.CreateInvitation(newPubKeyHex)
+		gm.CreateInvitation(newPubKeyHex, testPeerID)
 	}
 }
 
@@ -469,7 +668,7 @@ func BenchmarkVerifyInvitation(b *testing.B) {
 
 	newPriv, _ := sm2.GenerateKey(rand.Reader)
 	newPubKeyHex := hex.EncodeToString(sm2.Compress(&newPriv.PublicKey))
-	invitation, _ := gm.CreateInvitation(newPubKeyHex)
+	invitation, _ := gm.CreateInvitation(newPubKeyHex, testPeerID)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
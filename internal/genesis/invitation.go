@@ -0,0 +1,315 @@
+package genesis
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+)
+
+// 邀请函状态
+const (
+	InvitationPending = "pending" // 未使用且未过期
+	InvitationUsed    = "used"    // 已有节点用它加入
+	InvitationExpired = "expired" // 过期未使用
+)
+
+// InvitationRecord 邀请函的签发和使用记录
+type InvitationRecord struct {
+	Invitation *Invitation `json:"invitation"`
+	UsedBy     string      `json:"used_by,omitempty"` // 用该邀请函加入的节点ID
+	UsedAt     int64       `json:"used_at,omitempty"` // 使用时间戳
+}
+
+// Status 邀请函当前状态
+func (r *InvitationRecord) Status(now time.Time) string {
+	switch {
+	case r.UsedBy != "":
+		return InvitationUsed
+	case now.UnixMilli() > r.Invitation.ExpiresAt:
+		return InvitationExpired
+	}
+	return InvitationPending
+}
+
+// signData 邀请函的签名数据，ID 参与签名使每张邀请函只能使用一次
+func (inv *Invitation) signData() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d|%d",
+		inv.ID,
+		inv.InviterNodeID,
+		inv.InviterKey,
+		inv.NewNodeKey,
+		inv.PeerID,
+		inv.Timestamp,
+		inv.ExpiresAt,
+		inv.InitReputation,
+	)
+}
+
+// EncodeInvitation 把邀请函编码为便于分享的邀请码
+func EncodeInvitation(inv *Invitation) (string, error) {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeInvitation 解析邀请码，不验证签名
+func DecodeInvitation(code string) (*Invitation, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(code))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvitation, err)
+	}
+	var inv Invitation
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvitation, err)
+	}
+	return &inv, nil
+}
+
+// signData 加入请求的签名数据，邀请函ID只能使用一次，签名不能被重放
+func (req *JoinRequest) signData() string {
+	return fmt.Sprintf("join|%s|%s|%s", req.Invitation.ID, req.NewNodeKey, req.PeerID)
+}
+
+// SignJoin 受邀方用本节点密钥为邀请码签名，证明持有邀请函绑定的私钥
+// peerID 是本节点的 libp2p 节点ID，须与邀请函一致。
+func (gm *GenesisManager) SignJoin(code, peerID string) (string, error) {
+	invitation, err := DecodeInvitation(code)
+	if err != nil {
+		return "", err
+	}
+
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+	if gm.privateKey == nil {
+		return "", errors.New("无私钥，无法签名加入请求")
+	}
+	pubKey := hex.EncodeToString(sm2.Compress(gm.publicKey))
+	if invitation.NewNodeKey != pubKey {
+		return "", errors.New("公钥与邀请函不匹配")
+	}
+	if invitation.PeerID != peerID {
+		return "", ErrPeerIDMismatch
+	}
+	return signJoinRequest(gm.privateKey, &JoinRequest{NewNodeKey: pubKey, PeerID: peerID, Invitation: invitation})
+}
+
+// Join 用邀请码、新节点公钥、libp2p 节点ID和受邀方签名加入网络，节点ID由公钥推导
+func (gm *GenesisManager) Join(code, newNodeKeyHex, peerID, signature string) (*JoinResponse, error) {
+	req, err := NewJoinRequest(code, newNodeKeyHex, peerID, signature)
+	if err != nil {
+		return nil, err
+	}
+	return gm.ProcessJoinRequest(req)
+}
+
+// NewJoinRequest 由邀请码和受邀方签名组装加入请求，签名在处理时校验
+func NewJoinRequest(code, newNodeKeyHex, peerID, signature string) (*JoinRequest, error) {
+	invitation, err := DecodeInvitation(code)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := parsePublicKey(newNodeKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("解析新节点公钥失败: %w", err)
+	}
+	return &JoinRequest{
+		NewNodeID:  generateNodeID(pubKey),
+		NewNodeKey: newNodeKeyHex,
+		PeerID:     peerID,
+		Invitation: invitation,
+		Timestamp:  time.Now().UnixMilli(),
+		Signature:  signature,
+	}, nil
+}
+
+func signJoinRequest(priv *sm2.PrivateKey, req *JoinRequest) (string, error) {
+	hash := sm3.Sm3Sum([]byte(req.signData()))
+	sig, err := priv.Sign(rand.Reader, hash[:], nil)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+func verifyJoinSignature(pubKey *sm2.PublicKey, req *JoinRequest) bool {
+	sig, err := hex.DecodeString(req.Signature)
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+	hash := sm3.Sm3Sum([]byte(req.signData()))
+	return pubKey.Verify(hash[:], sig)
+}
+
+// EnsureKey 返回本节点公钥，还没有密钥时生成并保存，用于申请邀请函
+func (gm *GenesisManager) EnsureKey() (string, error) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+
+	if gm.privateKey == nil {
+		priv, err := sm2.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", fmt.Errorf("生成密钥失败: %w", err)
+		}
+		gm.privateKey = priv
+		gm.publicKey = &priv.PublicKey
+		gm.nodeID = generateNodeID(gm.publicKey)
+		if err := gm.save(); err != nil {
+			return "", fmt.Errorf("保存失败: %w", err)
+		}
+	}
+	return hex.EncodeToString(sm2.Compress(gm.publicKey)), nil
+}
+
+// GetInvitations 获取邀请函记录，按签发时间排序
+func (gm *GenesisManager) GetInvitations() []*InvitationRecord {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	records := make([]*InvitationRecord, 0, len(gm.invitations))
+	for _, record := range gm.invitations {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Invitation.Timestamp < records[j].Invitation.Timestamp
+	})
+	return records
+}
+
+// GraphNode 邀请树中的节点
+type GraphNode struct {
+	NodeID       string    `json:"node_id"`
+	InviterID    string    `json:"inviter_id,omitempty"`
+	InvitationID string    `json:"invitation_id,omitempty"`
+	Depth        int       `json:"depth"`       // 距根节点的邀请层数
+	Invited      int       `json:"invited"`     // 直接邀请加入的节点数
+	Descendants  int       `json:"descendants"` // 邀请子树中的节点数（不含自身）
+	Reputation   int64     `json:"reputation"`
+	JoinedAt     time.Time `json:"joined_at"`
+}
+
+// InvitationGraph 邀请关系图，用于女巫分析：
+// 少数节点在短时间内邀请大量低声誉节点、或邀请链异常深时值得关注
+type InvitationGraph struct {
+	Roots    []string    `json:"roots"` // 无邀请者（创世节点）或邀请者未登记的节点
+	Nodes    []GraphNode `json:"nodes"` // 按加入时间排序
+	MaxDepth int         `json:"max_depth"`
+
+	// 本节点登记的邀请函统计
+	Pending int `json:"pending"`
+	Used    int `json:"used"`
+	Expired int `json:"expired"`
+}
+
+// InvitationGraph 构建邀请关系图，root 非空时只返回以该节点为根的子树
+func (gm *GenesisManager) InvitationGraph(root string) (*InvitationGraph, error) {
+	gm.mu.RLock()
+	defer gm.mu.RUnlock()
+
+	if root != "" {
+		if _, ok := gm.joinedNodes[root]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, root)
+		}
+	}
+
+	// inviter 返回登记在册的邀请者，根节点返回空
+	inviter := func(node *JoinedNode) string {
+		if node.NodeID == root {
+			return ""
+		}
+		if _, ok := gm.joinedNodes[node.InviterID]; !ok {
+			return ""
+		}
+		return node.InviterID
+	}
+
+	nodes := make(map[string]*GraphNode, len(gm.joinedNodes))
+	for id, node := range gm.joinedNodes {
+		nodes[id] = &GraphNode{
+			NodeID:       id,
+			InviterID:    node.InviterID,
+			InvitationID: node.InvitationID,
+			Reputation:   node.Reputation,
+			JoinedAt:     node.JoinedAt,
+		}
+	}
+
+	// 沿邀请链向上计算层数和各祖先的子树大小，visited 防止损坏数据中的环
+	inRoot := make(map[string]bool, len(nodes))
+	for id, node := range gm.joinedNodes {
+		visited := map[string]bool{id: true}
+		depth := 0
+		top := id
+		for parent := inviter(node); parent != "" && !visited[parent]; parent = inviter(gm.joinedNodes[parent]) {
+			visited[parent] = true
+			if depth == 0 {
+				nodes[parent].Invited++
+			}
+			nodes[parent].Descendants++
+			depth++
+			top = parent
+		}
+		nodes[id].Depth = depth
+		inRoot[id] = root == "" || top == root
+	}
+
+	graph := &InvitationGraph{Roots: []string{}, Nodes: []GraphNode{}}
+	for id, node := range nodes {
+		if !inRoot[id] {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, *node)
+		if node.Depth == 0 {
+			graph.Roots = append(graph.Roots, id)
+		}
+		if node.Depth > graph.MaxDepth {
+			graph.MaxDepth = node.Depth
+		}
+	}
+	sort.Strings(graph.Roots)
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		if !graph.Nodes[i].JoinedAt.Equal(graph.Nodes[j].JoinedAt) {
+			return graph.Nodes[i].JoinedAt.Before(graph.Nodes[j].JoinedAt)
+		}
+		return graph.Nodes[i].NodeID < graph.Nodes[j].NodeID
+	})
+
+	now := time.Now()
+	for _, record := range gm.invitations {
+		switch record.Status(now) {
+		case InvitationPending:
+			graph.Pending++
+		case InvitationUsed:
+			graph.Used++
+		case InvitationExpired:
+			graph.Expired++
+		}
+	}
+	return graph, nil
+}
+
+// saveInvitations 保存邀请函记录
+func (gm *GenesisManager) saveInvitations() error {
+	if err := os.MkdirAll(gm.dataDir, 0755); err != nil {
+		return err
+	}
+
+	invitationsPath := filepath.Join(gm.dataDir, "invitations.json")
+	data, err := json.MarshalIndent(gm.invitations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(invitationsPath, data, 0644)
+}
@@ -0,0 +1,112 @@
+package heartbeat
+
+import (
+	"sync"
+	"time"
+)
+
+// 自适应间隔的默认上下限
+const (
+	DefaultMinInterval = 10 * time.Second
+	DefaultMaxInterval = 2 * time.Minute
+)
+
+const (
+	// adaptiveStableRounds 连续稳定多少轮后放宽一次
+	adaptiveStableRounds = 3
+	// adaptiveBackoff 每次放宽的倍数
+	adaptiveBackoff = 1.5
+)
+
+// IntervalStatus 自适应间隔的当前状态
+type IntervalStatus struct {
+	CurrentMs    int64     `json:"current_ms"`
+	MinMs        int64     `json:"min_ms"`
+	MaxMs        int64     `json:"max_ms"`
+	StableRounds int       `json:"stable_rounds"`         // 连续稳定的轮数
+	Tightened    int64     `json:"tightened"`             // 因故障收紧的次数
+	LastReason   string    `json:"last_reason,omitempty"` // 最近一次收紧的原因
+	LastChange   time.Time `json:"last_change,omitempty"`
+}
+
+// AdaptiveInterval 自适应发送间隔：网络稳定时每连续 3 轮放宽 1.5 倍直到上限，
+// 出现故障立即收紧到下限。上下限相同时为固定间隔。
+type AdaptiveInterval struct {
+	mu         sync.Mutex
+	min, max   time.Duration
+	current    time.Duration
+	stable     int
+	tightened  int64
+	lastReason string
+	lastChange time.Time
+	now        func() time.Time
+}
+
+// NewAdaptiveInterval 以 initial 为初始间隔创建自适应间隔，initial 超出 [min, max] 时取边界值
+func NewAdaptiveInterval(initial, min, max time.Duration) *AdaptiveInterval {
+	if min > max {
+		min, max = max, min
+	}
+	a := &AdaptiveInterval{min: min, max: max, current: initial, now: time.Now}
+	a.current = a.clamp(initial)
+	return a
+}
+
+func (a *AdaptiveInterval) clamp(d time.Duration) time.Duration {
+	if d < a.min {
+		return a.min
+	}
+	if d > a.max {
+		return a.max
+	}
+	return d
+}
+
+// Current 当前间隔
+func (a *AdaptiveInterval) Current() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.current
+}
+
+// Stable 记录一轮没有故障，返回下一轮的间隔
+func (a *AdaptiveInterval) Stable() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stable++
+	if a.stable >= adaptiveStableRounds && a.current < a.max {
+		a.current = a.clamp(time.Duration(float64(a.current) * adaptiveBackoff))
+		a.stable = 0
+		a.lastChange = a.now()
+	}
+	return a.current
+}
+
+// Unstable 记录一轮出现故障，间隔收紧到下限，返回下一轮的间隔
+func (a *AdaptiveInterval) Unstable(reason string) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stable = 0
+	a.tightened++
+	a.lastReason = reason
+	if a.current != a.min {
+		a.current = a.min
+		a.lastChange = a.now()
+	}
+	return a.current
+}
+
+// Status 当前状态
+func (a *AdaptiveInterval) Status() IntervalStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return IntervalStatus{
+		CurrentMs:    a.current.Milliseconds(),
+		MinMs:        a.min.Milliseconds(),
+		MaxMs:        a.max.Milliseconds(),
+		StableRounds: a.stable,
+		Tightened:    a.tightened,
+		LastReason:   a.lastReason,
+		LastChange:   a.lastChange,
+	}
+}
@@ -18,19 +18,23 @@ const (
 	StatusIdle    Status = "idle"
 	StatusWorking Status = "working"
 	StatusBlocked Status = "blocked"
+	// StatusMaintenance 维护中：对端据此豁免失职惩罚
+	StatusMaintenance Status = "maintenance"
 )
 
 // Packet 心跳包
 type Packet struct {
-	Version       string        `json:"version"`
-	Type          string        `json:"type"`
-	AgentID       string        `json:"agent_id"`
-	Timestamp     string        `json:"timestamp"`
-	Status        Status        `json:"status"`
-	CurrentTask   *string       `json:"current_task"`
-	Contributions Contributions `json:"contributions"`
-	ProtocolHash  string        `json:"protocol_hash"`
-	Signature     string        `json:"signature"`
+	Version          string        `json:"version"`
+	Type             string        `json:"type"`
+	AgentID          string        `json:"agent_id"`
+	Timestamp        string        `json:"timestamp"`
+	Status           Status        `json:"status"`
+	CurrentTask      *string       `json:"current_task"`
+	MaintenanceUntil string        `json:"maintenance_until,omitempty"`
+	Features         []string      `json:"features,omitempty"` // 已就绪的协议特性，用于协调激活
+	Contributions    Contributions `json:"contributions"`
+	ProtocolHash     string        `json:"protocol_hash"`
+	Signature        string        `json:"signature"`
 }
 
 // Contributions 贡献数据
@@ -48,6 +52,13 @@ type Service struct {
 	status Status
 	task   *string
 	mu     sync.RWMutex
+
+	// 维护模式（进入前的状态在退出时恢复）
+	maintenanceUntil  time.Time
+	statusBeforeMaint Status
+
+	// 已就绪的协议特性
+	featuresFunc func() []string
 }
 
 // NewService 创建心跳服务
@@ -118,13 +129,25 @@ func (s *Service) createPacket() *Packet {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	var maintenanceUntil string
+	if s.status == StatusMaintenance && !s.maintenanceUntil.IsZero() {
+		maintenanceUntil = s.maintenanceUntil.UTC().Format(time.RFC3339)
+	}
+
+	var features []string
+	if s.featuresFunc != nil {
+		features = s.featuresFunc()
+	}
+
 	return &Packet{
-		Version:     s.config.Version,
-		Type:        "heartbeat",
-		AgentID:     s.config.AgentID,
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-		Status:      s.status,
-		CurrentTask: s.task,
+		Version:          s.config.Version,
+		Type:             "heartbeat",
+		AgentID:          s.config.AgentID,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339),
+		Status:           s.status,
+		CurrentTask:      s.task,
+		MaintenanceUntil: maintenanceUntil,
+		Features:         features,
 		Contributions: Contributions{
 			PRsMerged:    0,
 			PRsReviewed:  0,
@@ -142,6 +165,36 @@ func (s *Service) SetStatus(status Status) {
 	s.status = status
 }
 
+// SetMaintenance 进入或退出维护状态，until 为零表示未设定截止时间
+func (s *Service) SetMaintenance(enabled bool, until time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if enabled {
+		if s.status != StatusMaintenance {
+			s.statusBeforeMaint = s.status
+		}
+		s.status = StatusMaintenance
+		s.maintenanceUntil = until
+		return
+	}
+
+	if s.status == StatusMaintenance {
+		s.status = s.statusBeforeMaint
+		if s.status == "" {
+			s.status = StatusIdle
+		}
+	}
+	s.maintenanceUntil = time.Time{}
+}
+
+// SetFeaturesFunc 设置获取本节点已就绪特性的函数，每次心跳时调用
+func (s *Service) SetFeaturesFunc(fn func() []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.featuresFunc = fn
+}
+
 // SetTask 设置当前任务
 func (s *Service) SetTask(task *string) {
 	s.mu.Lock()
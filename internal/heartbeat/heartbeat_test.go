@@ -170,6 +170,47 @@ func TestService_Start(t *testing.T) {
 	}
 }
 
+func TestService_SetMaintenance(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	service.SetStatus(StatusWorking)
+	until := time.Now().Add(time.Hour)
+	service.SetMaintenance(true, until)
+
+	packet := service.createPacket()
+	if packet.Status != StatusMaintenance {
+		t.Errorf("状态错误: %s", packet.Status)
+	}
+	if packet.MaintenanceUntil != until.UTC().Format(time.RFC3339) {
+		t.Errorf("维护截止时间错误: %s", packet.MaintenanceUntil)
+	}
+
+	service.SetMaintenance(false, time.Time{})
+	packet = service.createPacket()
+	if packet.Status != StatusWorking {
+		t.Errorf("退出维护后应恢复原状态, 得到 %s", packet.Status)
+	}
+	if packet.MaintenanceUntil != "" {
+		t.Error("退出维护后不应包含截止时间")
+	}
+}
+
+func TestService_Features(t *testing.T) {
+	service, cleanup := setupTestService(t)
+	defer cleanup()
+
+	if packet := service.createPacket(); packet.Features != nil {
+		t.Errorf("未设置特性时不应通告: %v", packet.Features)
+	}
+
+	service.SetFeaturesFunc(func() []string { return []string{"mailbox-v2"} })
+	packet := service.createPacket()
+	if len(packet.Features) != 1 || packet.Features[0] != "mailbox-v2" {
+		t.Errorf("特性通告错误: %v", packet.Features)
+	}
+}
+
 func TestStatus_Constants(t *testing.T) {
 	if StatusIdle != "idle" {
 		t.Error("StatusIdle 常量错误")
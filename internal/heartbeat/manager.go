@@ -0,0 +1,633 @@
+package heartbeat
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// 节点心跳
+//
+// HeartbeatManager 定期签名并经 pubsub 广播心跳包（协议哈希、运行时长、能力和已就绪特性），
+// 同时接收其他节点的心跳，跟踪已知节点的存活情况。存活度是心跳到达间隔的指数滑动平均：
+// 按时到达的心跳为 1，间隔拉长时按比例降低；超过 OfflineAfter 未收到心跳视为离线，存活度为 0。
+
+// Topic 心跳广播的 pubsub 主题（使用时按网络命名空间加前缀）
+const Topic = "/daan/heartbeat/1.0.0"
+
+// maxBeatSize 心跳包的最大长度
+const maxBeatSize = 8 * 1024
+
+// livenessAlpha 存活度滑动平均的平滑系数
+const livenessAlpha = 0.2
+
+var (
+	ErrInvalidBeat   = errors.New("invalid heartbeat")
+	ErrBeatSignature = errors.New("invalid heartbeat signature")
+	ErrStaleBeat     = errors.New("stale heartbeat")
+	ErrReplayedBeat  = errors.New("replayed heartbeat")
+)
+
+// SignFunc 用节点私钥签名
+type SignFunc func(data []byte) (string, error)
+
+// VerifyFunc 按节点ID验证签名
+type VerifyFunc func(signer string, data []byte, signature string) bool
+
+// Transport 心跳的广播通道（GossipSub）
+// validate 在转发前调用，返回 false 的心跳既不处理也不继续传播；handler 只收到其他节点的心跳。
+type Transport interface {
+	Publish(topic string, data []byte) error
+	Subscribe(topic string, validate func(data []byte) bool, handler func(data []byte, from string)) error
+}
+
+// Beat 节点心跳包
+type Beat struct {
+	NodeID       string   `json:"node_id"`
+	Seq          uint64   `json:"seq"`        // 本次启动以来的序号，从 1 开始
+	StartedAt    int64    `json:"started_at"` // 节点启动时间（Unix 秒），重启后序号重新计数
+	Timestamp    int64    `json:"timestamp"`  // 发送时间（Unix 毫秒）
+	Uptime       int64    `json:"uptime"`     // 运行时长（秒）
+	Role         string   `json:"role,omitempty"`
+	Status       Status   `json:"status"`
+	ProtocolHash string   `json:"protocol_hash"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Features     []string `json:"features,omitempty"` // 已就绪的协议特性，用于协调激活
+	// 距下一次心跳的间隔（毫秒），对端据此判断离线；自适应心跳激活前不携带
+	IntervalMs int64  `json:"interval_ms,omitempty"`
+	Signature  string `json:"signature"`
+}
+
+// signingBytes 签名内容：去掉签名字段后的 JSON
+func (b *Beat) signingBytes() []byte {
+	unsigned := *b
+	unsigned.Signature = ""
+	data, _ := json.Marshal(&unsigned)
+	return data
+}
+
+// ProtocolHash 按节点支持的协议ID计算协议哈希，协议集合相同的节点哈希相同
+func ProtocolHash(protocols []string) string {
+	sorted := append([]string(nil), protocols...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Peer 已知节点的存活情况
+type Peer struct {
+	NodeID       string    `json:"node_id"`
+	Role         string    `json:"role,omitempty"`
+	Status       Status    `json:"status"`
+	ProtocolHash string    `json:"protocol_hash"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	Features     []string  `json:"features,omitempty"`
+	Seq          uint64    `json:"seq"`
+	StartedAt    time.Time `json:"started_at"`
+	Uptime       int64     `json:"uptime"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Beats        int64     `json:"beats"`
+	Online       bool      `json:"online"`
+	Liveness     float64   `json:"liveness"`
+	IntervalMs   int64     `json:"interval_ms"` // 对端通告的心跳间隔，未通告时为本节点配置的 Interval
+
+	reliability float64       // 心跳到达间隔的滑动平均
+	interval    time.Duration // 对端的心跳间隔
+}
+
+// ManagerConfig 心跳管理器配置
+type ManagerConfig struct {
+	NodeID       string
+	Role         string
+	Interval     time.Duration // 初始发送间隔，自适应心跳未激活时也是间隔上限
+	MinInterval  time.Duration // 自适应间隔下限，出现故障后收紧到该值（0 表示同 Interval）
+	MaxInterval  time.Duration // 自适应间隔上限，网络稳定时逐步放宽到该值（0 表示同 Interval）
+	OfflineAfter time.Duration // 超过该时长（且超过对端间隔的 3 倍）未收到心跳视为离线
+	ForgetAfter  time.Duration // 超过该时长未收到心跳不再跟踪
+	MaxSkew      time.Duration // 心跳时间戳与本地时间的最大偏差
+	SignFunc     SignFunc
+	VerifyFunc   VerifyFunc
+}
+
+// DefaultManagerConfig 返回默认配置
+func DefaultManagerConfig(nodeID string) *ManagerConfig {
+	return &ManagerConfig{
+		NodeID:       nodeID,
+		Interval:     30 * time.Second,
+		MinInterval:  DefaultMinInterval,
+		MaxInterval:  DefaultMaxInterval,
+		OfflineAfter: 90 * time.Second,
+		ForgetAfter:  24 * time.Hour,
+		MaxSkew:      5 * time.Minute,
+	}
+}
+
+// ManagerStatus 本节点的心跳状态和已知节点
+type ManagerStatus struct {
+	NodeID     string    `json:"node_id"`
+	Role       string    `json:"role,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	Uptime     int64     `json:"uptime"`
+	Seq        uint64    `json:"seq"`
+	IntervalMs int64     `json:"interval_ms"` // 当前发送间隔
+	// 自适应间隔的状态；Backoff 为 false 时间隔不超过 Interval
+	Interval     IntervalStatus `json:"interval"`
+	Backoff      bool           `json:"backoff"`
+	LastSent     time.Time      `json:"last_sent,omitempty"`
+	ProtocolHash string         `json:"protocol_hash"`
+	Capabilities []string       `json:"capabilities,omitempty"`
+	Sent         int64          `json:"sent"`
+	Received     int64          `json:"received"`
+	Rejected     int64          `json:"rejected"`
+	Online       int            `json:"online"`
+	Peers        []*Peer        `json:"peers"`
+}
+
+// HeartbeatManager 节点心跳管理器
+type HeartbeatManager struct {
+	config    *ManagerConfig
+	startedAt time.Time
+	now       func() time.Time
+
+	mu               sync.RWMutex
+	seq              uint64
+	lastSent         time.Time
+	lastProtocolHash string
+	peers            map[string]*Peer
+	sent             int64
+	received         int64
+	rejected         int64
+
+	// 自适应间隔：wasOnline 为上一轮在线的节点，disruption 为本轮收到心跳时发现的故障
+	interval   *AdaptiveInterval
+	wasOnline  map[string]bool
+	disruption string
+
+	protocolHashFunc func() string
+	capabilitiesFunc func() []string
+	featuresFunc     func() []string
+	statusFunc       func() Status
+	backoffFunc      func() bool
+	onBeat           func(*Beat)
+}
+
+// NewHeartbeatManager 创建心跳管理器
+func NewHeartbeatManager(config *ManagerConfig) (*HeartbeatManager, error) {
+	if config == nil {
+		return nil, errors.New("config is required")
+	}
+	if config.NodeID == "" {
+		return nil, errors.New("node ID is required")
+	}
+	if config.SignFunc == nil || config.VerifyFunc == nil {
+		return nil, errors.New("sign and verify functions are required")
+	}
+	if config.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	minInterval, maxInterval := config.MinInterval, config.MaxInterval
+	if minInterval <= 0 {
+		minInterval = config.Interval
+	}
+	if maxInterval <= 0 {
+		maxInterval = config.Interval
+	}
+	if minInterval > maxInterval {
+		return nil, errors.New("min interval must not exceed max interval")
+	}
+	return &HeartbeatManager{
+		config:    config,
+		startedAt: time.Now(),
+		now:       time.Now,
+		peers:     make(map[string]*Peer),
+		interval:  NewAdaptiveInterval(config.Interval, minInterval, maxInterval),
+		wasOnline: make(map[string]bool),
+	}, nil
+}
+
+// SetProtocolHashFunc 设置获取本节点协议哈希的函数，每次心跳时调用
+func (m *HeartbeatManager) SetProtocolHashFunc(fn func() string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.protocolHashFunc = fn
+}
+
+// SetCapabilitiesFunc 设置获取本节点能力的函数，每次心跳时调用
+func (m *HeartbeatManager) SetCapabilitiesFunc(fn func() []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capabilitiesFunc = fn
+}
+
+// SetFeaturesFunc 设置获取本节点已就绪特性的函数，每次心跳时调用
+func (m *HeartbeatManager) SetFeaturesFunc(fn func() []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.featuresFunc = fn
+}
+
+// SetStatusFunc 设置获取本节点状态的函数（如维护中），未设置时为 idle
+func (m *HeartbeatManager) SetStatusFunc(fn func() Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statusFunc = fn
+}
+
+// SetBackoffFunc 设置是否允许间隔放宽到 Interval 以上，未设置时不允许。
+// 放宽后心跳携带间隔，只有全网都能识别该字段（自适应心跳特性激活）后才能开启，
+// 否则旧版本节点会按固定间隔把本节点判为离线。
+func (m *HeartbeatManager) SetBackoffFunc(fn func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backoffFunc = fn
+}
+
+// SetOnBeat 设置收到有效心跳时的回调
+func (m *HeartbeatManager) SetOnBeat(fn func(*Beat)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onBeat = fn
+}
+
+// NextBeat 生成并签名本节点的下一个心跳包
+func (m *HeartbeatManager) NextBeat() (*Beat, error) {
+	m.mu.Lock()
+	m.seq++
+	now := m.now()
+	beat := &Beat{
+		NodeID:    m.config.NodeID,
+		Seq:       m.seq,
+		StartedAt: m.startedAt.Unix(),
+		Timestamp: now.UnixMilli(),
+		Uptime:    int64(now.Sub(m.startedAt).Seconds()),
+		Role:      m.config.Role,
+		Status:    StatusIdle,
+	}
+	protocolHash, capabilities, features, status := m.protocolHashFunc, m.capabilitiesFunc, m.featuresFunc, m.statusFunc
+	m.mu.Unlock()
+
+	if interval, backoff := m.currentInterval(); backoff {
+		beat.IntervalMs = interval.Milliseconds()
+	}
+
+	if protocolHash != nil {
+		beat.ProtocolHash = protocolHash()
+	}
+	if capabilities != nil {
+		beat.Capabilities = capabilities()
+	}
+	if features != nil {
+		beat.Features = features()
+	}
+	if status != nil {
+		beat.Status = status()
+	}
+	sig, err := m.config.SignFunc(beat.signingBytes())
+	if err != nil {
+		return nil, fmt.Errorf("sign heartbeat: %w", err)
+	}
+	beat.Signature = sig
+	return beat, nil
+}
+
+// Send 生成心跳包并广播
+func (m *HeartbeatManager) Send(transport Transport) error {
+	beat, err := m.NextBeat()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(beat)
+	if err != nil {
+		return err
+	}
+	if err := transport.Publish(Topic, data); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.sent++
+	m.lastSent = m.now()
+	m.lastProtocolHash = beat.ProtocolHash
+	m.mu.Unlock()
+	return nil
+}
+
+// decode 解码并校验心跳包：签名者为节点本身、时间戳在允许范围内、序号没有回退
+// from 为广播的原始发送节点，为空时不检查
+func (m *HeartbeatManager) decode(data []byte, from string) (*Beat, error) {
+	if len(data) > maxBeatSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidBeat, len(data))
+	}
+	beat := new(Beat)
+	if err := json.Unmarshal(data, beat); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBeat, err)
+	}
+	if beat.NodeID == "" || beat.Seq == 0 {
+		return nil, fmt.Errorf("%w: missing node ID or sequence", ErrInvalidBeat)
+	}
+	if from != "" && from != beat.NodeID {
+		return nil, fmt.Errorf("%w: sent by %s", ErrBeatSignature, from)
+	}
+	if !m.config.VerifyFunc(beat.NodeID, beat.signingBytes(), beat.Signature) {
+		return nil, ErrBeatSignature
+	}
+	skew := m.now().Sub(time.UnixMilli(beat.Timestamp))
+	if skew > m.config.MaxSkew || skew < -m.config.MaxSkew {
+		return nil, fmt.Errorf("%w: %s from local time", ErrStaleBeat, skew.Round(time.Second))
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.peers[beat.NodeID]; ok {
+		started := p.StartedAt.Unix()
+		if beat.StartedAt < started || (beat.StartedAt == started && beat.Seq <= p.Seq) {
+			return nil, fmt.Errorf("%w: seq %d from %s", ErrReplayedBeat, beat.Seq, beat.NodeID)
+		}
+	}
+	return beat, nil
+}
+
+// Validate 转发前的校验，失败的心跳不会继续传播
+func (m *HeartbeatManager) Validate(data []byte) bool {
+	if _, err := m.decode(data, ""); err != nil {
+		m.mu.Lock()
+		m.rejected++
+		m.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// Handle 处理收到的心跳：更新节点存活情况并回调，本节点自己的心跳被忽略
+func (m *HeartbeatManager) Handle(data []byte, from string) error {
+	beat, err := m.decode(data, from)
+	if err != nil {
+		m.mu.Lock()
+		m.rejected++
+		m.mu.Unlock()
+		return err
+	}
+	if beat.NodeID == m.config.NodeID {
+		return nil
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	p, ok := m.peers[beat.NodeID]
+	if !ok {
+		p = &Peer{NodeID: beat.NodeID, FirstSeen: now, reliability: 1}
+		m.peers[beat.NodeID] = p
+	} else {
+		// 到达间隔与对端上次通告的间隔比较
+		if gap := now.Sub(p.LastSeen); gap > 0 {
+			sample := float64(m.peerInterval(p)) / float64(gap)
+			if sample > 1 {
+				sample = 1
+			}
+			p.reliability = livenessAlpha*sample + (1-livenessAlpha)*p.reliability
+		}
+		if beat.StartedAt > p.StartedAt.Unix() && m.disruption == "" {
+			m.disruption = fmt.Sprintf("%s restarted", beat.NodeID)
+		}
+	}
+	p.interval = time.Duration(beat.IntervalMs) * time.Millisecond
+	p.Role = beat.Role
+	p.Status = beat.Status
+	p.ProtocolHash = beat.ProtocolHash
+	p.Capabilities = beat.Capabilities
+	p.Features = beat.Features
+	p.Seq = beat.Seq
+	p.StartedAt = time.Unix(beat.StartedAt, 0)
+	p.Uptime = beat.Uptime
+	p.LastSeen = now
+	p.Beats++
+	m.received++
+	onBeat := m.onBeat
+	m.mu.Unlock()
+
+	if onBeat != nil {
+		onBeat(beat)
+	}
+	return nil
+}
+
+// Liveness 节点的存活度（0-1），离线或未知节点为 0
+func (m *HeartbeatManager) Liveness(nodeID string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.peers[nodeID]
+	if !ok {
+		return 0
+	}
+	return m.livenessLocked(p, m.now())
+}
+
+func (m *HeartbeatManager) livenessLocked(p *Peer, now time.Time) float64 {
+	offlineAfter := m.config.OfflineAfter
+	if d := 3 * m.peerInterval(p); d > offlineAfter {
+		offlineAfter = d
+	}
+	if now.Sub(p.LastSeen) > offlineAfter {
+		return 0
+	}
+	return p.reliability
+}
+
+// peerInterval 对端通告的心跳间隔，未通告（旧版本或自适应心跳未激活）时为 Interval
+func (m *HeartbeatManager) peerInterval(p *Peer) time.Duration {
+	if p.interval > 0 {
+		return p.interval
+	}
+	return m.config.Interval
+}
+
+// currentInterval 当前发送间隔和是否允许放宽到 Interval 以上
+func (m *HeartbeatManager) currentInterval() (time.Duration, bool) {
+	m.mu.RLock()
+	backoffFunc := m.backoffFunc
+	m.mu.RUnlock()
+
+	interval := m.interval.Current()
+	backoff := backoffFunc != nil && backoffFunc()
+	if !backoff && interval > m.config.Interval {
+		interval = m.config.Interval
+	}
+	return interval, backoff
+}
+
+// adapt 根据上一轮的情况调整发送间隔并返回下一轮的间隔：
+// 发送失败、上一轮在线的节点离线、已知节点重启视为故障，收紧到下限；否则视为稳定
+func (m *HeartbeatManager) adapt(sendErr error) time.Duration {
+	m.mu.Lock()
+	now := m.now()
+	reason := m.disruption
+	m.disruption = ""
+	online := make(map[string]bool, len(m.peers))
+	for id, p := range m.peers {
+		if m.livenessLocked(p, now) > 0 {
+			online[id] = true
+		} else if m.wasOnline[id] && reason == "" {
+			reason = fmt.Sprintf("%s went offline", id)
+		}
+	}
+	m.wasOnline = online
+	m.mu.Unlock()
+
+	if sendErr != nil {
+		reason = fmt.Sprintf("send failed: %v", sendErr)
+	}
+	if reason != "" {
+		m.interval.Unstable(reason)
+	} else {
+		m.interval.Stable()
+	}
+	interval, _ := m.currentInterval()
+	return interval
+}
+
+// Peer 获取节点的存活情况
+func (m *HeartbeatManager) Peer(nodeID string) (*Peer, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.peers[nodeID]
+	if !ok {
+		return nil, false
+	}
+	return m.snapshotLocked(p, m.now()), true
+}
+
+// Peers 所有已知节点的存活情况，在线的在前，其后按最近心跳时间从新到旧排列
+func (m *HeartbeatManager) Peers() []*Peer {
+	m.mu.RLock()
+	now := m.now()
+	result := make([]*Peer, 0, len(m.peers))
+	for _, p := range m.peers {
+		result = append(result, m.snapshotLocked(p, now))
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Online != result[j].Online {
+			return result[i].Online
+		}
+		if !result[i].LastSeen.Equal(result[j].LastSeen) {
+			return result[i].LastSeen.After(result[j].LastSeen)
+		}
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
+
+func (m *HeartbeatManager) snapshotLocked(p *Peer, now time.Time) *Peer {
+	copied := *p
+	copied.Capabilities = append([]string(nil), p.Capabilities...)
+	copied.Features = append([]string(nil), p.Features...)
+	copied.Liveness = m.livenessLocked(p, now)
+	copied.Online = copied.Liveness > 0
+	copied.IntervalMs = m.peerInterval(p).Milliseconds()
+	return &copied
+}
+
+// Status 本节点的心跳状态和已知节点
+func (m *HeartbeatManager) Status() *ManagerStatus {
+	peers := m.Peers()
+	interval, backoff := m.currentInterval()
+	intervalStatus := m.interval.Status()
+	intervalStatus.CurrentMs = interval.Milliseconds()
+	if !backoff && intervalStatus.MaxMs > m.config.Interval.Milliseconds() {
+		intervalStatus.MaxMs = m.config.Interval.Milliseconds()
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := &ManagerStatus{
+		NodeID:       m.config.NodeID,
+		Role:         m.config.Role,
+		StartedAt:    m.startedAt,
+		Uptime:       int64(m.now().Sub(m.startedAt).Seconds()),
+		Seq:          m.seq,
+		IntervalMs:   interval.Milliseconds(),
+		Interval:     intervalStatus,
+		Backoff:      backoff,
+		LastSent:     m.lastSent,
+		ProtocolHash: m.lastProtocolHash,
+		Sent:         m.sent,
+		Received:     m.received,
+		Rejected:     m.rejected,
+		Peers:        peers,
+	}
+	if m.capabilitiesFunc != nil {
+		status.Capabilities = m.capabilitiesFunc()
+	}
+	for _, p := range peers {
+		if p.Online {
+			status.Online++
+		}
+	}
+	return status
+}
+
+// Stats 实现 stats.Provider
+func (m *HeartbeatManager) Stats() []stats.Metric {
+	status := m.Status()
+	return []stats.Metric{
+		stats.Counter("sent", float64(status.Sent), "广播的心跳数"),
+		stats.Counter("received", float64(status.Received), "收到的有效心跳数"),
+		stats.Counter("rejected", float64(status.Rejected), "校验失败的心跳数"),
+		stats.Gauge("known_peers", float64(len(status.Peers)), "跟踪中的节点数"),
+		stats.Gauge("online_peers", float64(status.Online), "心跳在线的节点数"),
+		stats.Gauge("uptime_seconds", float64(status.Uptime), "本节点运行时长"),
+		stats.Gauge("interval_seconds", float64(status.IntervalMs)/1000, "当前心跳发送间隔"),
+	}
+}
+
+// prune 删除超过 ForgetAfter 未收到心跳的节点
+func (m *HeartbeatManager) prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, p := range m.peers {
+		if now.Sub(p.LastSeen) > m.config.ForgetAfter {
+			delete(m.peers, id)
+		}
+	}
+}
+
+// Run 订阅心跳主题，立即发送一次心跳，之后按自适应间隔发送，直到 ctx 结束
+func (m *HeartbeatManager) Run(ctx context.Context, transport Transport) error {
+	if err := transport.Subscribe(Topic, m.Validate, func(data []byte, from string) {
+		m.Handle(data, from)
+	}); err != nil {
+		return err
+	}
+	sendErr := m.Send(transport)
+	if sendErr != nil {
+		fmt.Printf("发送心跳失败: %v\n", sendErr)
+	}
+
+	interval, _ := m.currentInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			// 先调整间隔，本轮心跳通告的就是到下一轮的间隔
+			interval = m.adapt(sendErr)
+			if sendErr = m.Send(transport); sendErr != nil {
+				fmt.Printf("发送心跳失败: %v\n", sendErr)
+			}
+			m.prune()
+			timer.Reset(interval)
+		}
+	}
+}
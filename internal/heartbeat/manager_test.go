@@ -0,0 +1,352 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试用签名：节点ID + 数据哈希，足以区分伪造和篡改
+func testSign(nodeID string) SignFunc {
+	return func(data []byte) (string, error) {
+		return testSignature(nodeID, data), nil
+	}
+}
+
+func testSignature(nodeID string, data []byte) string {
+	return nodeID + ":" + ProtocolHash([]string{string(data)})
+}
+
+func testVerify(signer string, data []byte, signature string) bool {
+	return signature == testSignature(signer, data)
+}
+
+func newTestManager(t *testing.T, nodeID string, now *time.Time) *HeartbeatManager {
+	t.Helper()
+	cfg := DefaultManagerConfig(nodeID)
+	cfg.SignFunc = testSign(nodeID)
+	cfg.VerifyFunc = testVerify
+	m, err := NewHeartbeatManager(cfg)
+	if err != nil {
+		t.Fatalf("NewHeartbeatManager: %v", err)
+	}
+	m.now = func() time.Time { return *now }
+	m.startedAt = *now
+	return m
+}
+
+type captureTransport struct {
+	published [][]byte
+}
+
+func (c *captureTransport) Publish(topic string, data []byte) error {
+	c.published = append(c.published, data)
+	return nil
+}
+
+func (c *captureTransport) Subscribe(string, func([]byte) bool, func([]byte, string)) error {
+	return nil
+}
+
+func TestNewHeartbeatManagerValidation(t *testing.T) {
+	if _, err := NewHeartbeatManager(nil); err == nil {
+		t.Error("nil config accepted")
+	}
+	if _, err := NewHeartbeatManager(DefaultManagerConfig("node-a")); err == nil {
+		t.Error("config without sign/verify accepted")
+	}
+}
+
+func TestHeartbeatSendAndHandle(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+	alice.SetProtocolHashFunc(func() string { return ProtocolHash([]string{"/daan/b", "/daan/a"}) })
+	alice.SetCapabilitiesFunc(func() []string { return []string{"relay", "storage"} })
+	alice.SetStatusFunc(func() Status { return StatusMaintenance })
+
+	var observed []*Beat
+	bob.SetOnBeat(func(b *Beat) { observed = append(observed, b) })
+
+	tr := &captureTransport{}
+	now = now.Add(10 * time.Second)
+	if err := alice.Send(tr); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(tr.published) != 1 {
+		t.Fatalf("published %d beats", len(tr.published))
+	}
+	if !bob.Validate(tr.published[0]) {
+		t.Fatal("valid beat rejected by validator")
+	}
+	if err := bob.Handle(tr.published[0], "alice"); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	if len(observed) != 1 || observed[0].Seq != 1 || observed[0].Uptime != 10 || observed[0].Status != StatusMaintenance {
+		t.Fatalf("observed = %+v", observed)
+	}
+	if observed[0].ProtocolHash != ProtocolHash([]string{"/daan/a", "/daan/b"}) {
+		t.Error("protocol hash should not depend on protocol order")
+	}
+	p, ok := bob.Peer("alice")
+	if !ok || !p.Online || p.Liveness != 1 || len(p.Capabilities) != 2 {
+		t.Errorf("peer = %+v", p)
+	}
+
+	status := bob.Status()
+	if status.Received != 1 || status.Online != 1 || len(status.Peers) != 1 {
+		t.Errorf("status = %+v", status)
+	}
+	if got := alice.Status(); got.Sent != 1 || got.Seq != 1 || got.ProtocolHash == "" {
+		t.Errorf("sender status = %+v", got)
+	}
+
+	// 自己的心跳被忽略
+	if err := alice.Handle(tr.published[0], "alice"); err != nil {
+		t.Fatalf("Handle own beat: %v", err)
+	}
+	if len(alice.Peers()) != 0 {
+		t.Error("own beat should not be tracked")
+	}
+}
+
+func TestHeartbeatRejectsInvalidBeats(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+
+	first, _ := alice.NextBeat()
+	data, _ := json.Marshal(first)
+	if err := bob.Handle(data, "alice"); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+
+	// 重放
+	if err := bob.Handle(data, "alice"); !errors.Is(err, ErrReplayedBeat) {
+		t.Errorf("replay err = %v", err)
+	}
+
+	// 转发者冒充发送者
+	second, _ := alice.NextBeat()
+	data, _ = json.Marshal(second)
+	if err := bob.Handle(data, "mallory"); !errors.Is(err, ErrBeatSignature) {
+		t.Errorf("spoofed sender err = %v", err)
+	}
+
+	// 篡改内容
+	second.Capabilities = []string{"relay"}
+	data, _ = json.Marshal(second)
+	if err := bob.Handle(data, "alice"); !errors.Is(err, ErrBeatSignature) {
+		t.Errorf("tampered err = %v", err)
+	}
+	if bob.Validate(data) {
+		t.Error("tampered beat passed validator")
+	}
+
+	// 时间戳偏差过大
+	stale, _ := alice.NextBeat()
+	now = now.Add(10 * time.Minute)
+	data, _ = json.Marshal(stale)
+	if err := bob.Handle(data, "alice"); !errors.Is(err, ErrStaleBeat) {
+		t.Errorf("stale err = %v", err)
+	}
+
+	// 格式错误
+	if err := bob.Handle([]byte("{"), "alice"); !errors.Is(err, ErrInvalidBeat) {
+		t.Errorf("malformed err = %v", err)
+	}
+	if err := bob.Handle([]byte(strings.Repeat(" ", maxBeatSize+1)), "alice"); !errors.Is(err, ErrInvalidBeat) {
+		t.Errorf("oversized err = %v", err)
+	}
+
+	if got := bob.Status().Rejected; got != 7 {
+		t.Errorf("rejected = %d, want 7", got)
+	}
+}
+
+func TestHeartbeatRestartResetsSequence(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+	for i := 0; i < 3; i++ {
+		b, _ := alice.NextBeat()
+		data, _ := json.Marshal(b)
+		bob.Handle(data, "alice")
+		now = now.Add(30 * time.Second)
+	}
+
+	restarted := newTestManager(t, "alice", &now)
+	b, _ := restarted.NextBeat()
+	data, _ := json.Marshal(b)
+	if err := bob.Handle(data, "alice"); err != nil {
+		t.Fatalf("beat after restart rejected: %v", err)
+	}
+	if p, _ := bob.Peer("alice"); p.Seq != 1 || p.Beats != 4 {
+		t.Errorf("peer after restart = %+v", p)
+	}
+}
+
+func TestHeartbeatLiveness(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+
+	deliver := func() {
+		b, _ := alice.NextBeat()
+		data, _ := json.Marshal(b)
+		if err := bob.Handle(data, "alice"); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	}
+
+	deliver()
+	if got := bob.Liveness("alice"); got != 1 {
+		t.Fatalf("initial liveness = %v", got)
+	}
+	if got := bob.Liveness("unknown"); got != 0 {
+		t.Errorf("unknown liveness = %v", got)
+	}
+
+	// 按时到达保持为 1
+	now = now.Add(30 * time.Second)
+	deliver()
+	if got := bob.Liveness("alice"); got != 1 {
+		t.Errorf("on-time liveness = %v", got)
+	}
+
+	// 间隔拉长到两倍：0.2*0.5 + 0.8*1 = 0.9
+	now = now.Add(60 * time.Second)
+	deliver()
+	if got := bob.Liveness("alice"); got < 0.899 || got > 0.901 {
+		t.Errorf("late liveness = %v, want 0.9", got)
+	}
+
+	// 超过 OfflineAfter 视为离线
+	now = now.Add(91 * time.Second)
+	if got := bob.Liveness("alice"); got != 0 {
+		t.Errorf("offline liveness = %v", got)
+	}
+	if p, _ := bob.Peer("alice"); p.Online {
+		t.Error("peer should be offline")
+	}
+
+	// 超过 ForgetAfter 不再跟踪
+	now = now.Add(25 * time.Hour)
+	bob.prune()
+	if _, ok := bob.Peer("alice"); ok {
+		t.Error("peer should be forgotten")
+	}
+}
+
+func TestHeartbeatStats(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+	b, _ := alice.NextBeat()
+	data, _ := json.Marshal(b)
+	bob.Handle(data, "alice")
+
+	metrics := make(map[string]float64)
+	for _, m := range bob.Stats() {
+		metrics[m.Name] = m.Value
+	}
+	if metrics["received"] != 1 || metrics["known_peers"] != 1 || metrics["online_peers"] != 1 {
+		t.Errorf("metrics = %v", metrics)
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := NewAdaptiveInterval(5*time.Minute, 10*time.Second, 2*time.Minute)
+	if got := a.Current(); got != 2*time.Minute {
+		t.Fatalf("initial interval not clamped: %v", got)
+	}
+	if got := a.Unstable("peer offline"); got != 10*time.Second {
+		t.Fatalf("unstable interval = %v", got)
+	}
+
+	// 每连续 3 轮稳定放宽 1.5 倍
+	for i := 0; i < 2; i++ {
+		if got := a.Stable(); got != 10*time.Second {
+			t.Fatalf("round %d: interval = %v", i+1, got)
+		}
+	}
+	if got := a.Stable(); got != 15*time.Second {
+		t.Fatalf("backed off interval = %v", got)
+	}
+	for i := 0; i < 30; i++ {
+		a.Stable()
+	}
+	if got := a.Current(); got != 2*time.Minute {
+		t.Errorf("interval should stop at max, got %v", got)
+	}
+
+	status := a.Status()
+	if status.Tightened != 1 || status.LastReason != "peer offline" || status.MinMs != 10000 || status.MaxMs != 120000 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestHeartbeatAdaptiveInterval(t *testing.T) {
+	now := time.Now()
+	alice := newTestManager(t, "alice", &now)
+	bob := newTestManager(t, "bob", &now)
+	deliver := func(from, to *HeartbeatManager) *Beat {
+		b, _ := from.NextBeat()
+		data, _ := json.Marshal(b)
+		if err := to.Handle(data, b.NodeID); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+		return b
+	}
+
+	// 特性未激活时间隔不超过 Interval，心跳不携带间隔
+	for i := 0; i < 3; i++ {
+		alice.adapt(nil)
+	}
+	if status := alice.Status(); status.IntervalMs != 30000 || status.Interval.MaxMs != 30000 || status.Backoff {
+		t.Fatalf("status without backoff = %+v", status)
+	}
+	if b := deliver(alice, bob); b.IntervalMs != 0 {
+		t.Fatalf("beat advertised interval %d before activation", b.IntervalMs)
+	}
+
+	// 激活后放宽，对端按通告的间隔判断离线
+	alice.SetBackoffFunc(func() bool { return true })
+	if status := alice.Status(); status.IntervalMs != 45000 || !status.Backoff {
+		t.Fatalf("status with backoff = %+v", status)
+	}
+	if b := deliver(alice, bob); b.IntervalMs != 45000 {
+		t.Fatalf("beat interval = %d", b.IntervalMs)
+	}
+	now = now.Add(100 * time.Second)
+	if p, _ := bob.Peer("alice"); !p.Online || p.IntervalMs != 45000 {
+		t.Errorf("peer within 3 advertised intervals = %+v", p)
+	}
+	now = now.Add(40 * time.Second)
+	if p, _ := bob.Peer("alice"); p.Online {
+		t.Error("peer should be offline after 3 advertised intervals")
+	}
+
+	// 上一轮在线的节点离线后收紧到下限
+	deliver(bob, alice)
+	if got := alice.adapt(nil); got != 45*time.Second {
+		t.Fatalf("stable round changed interval to %v", got)
+	}
+	now = now.Add(2 * time.Minute)
+	if got := alice.adapt(nil); got != DefaultMinInterval {
+		t.Fatalf("interval after peer went offline = %v", got)
+	}
+	if status := alice.Status(); status.Interval.Tightened != 1 || !strings.Contains(status.Interval.LastReason, "bob") {
+		t.Errorf("interval status = %+v", status.Interval)
+	}
+
+	// 发送失败同样收紧
+	alice.adapt(nil)
+	alice.adapt(nil)
+	if got := alice.adapt(errors.New("no peers")); got != DefaultMinInterval {
+		t.Errorf("interval after send failure = %v", got)
+	}
+}
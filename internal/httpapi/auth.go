@@ -172,8 +172,8 @@ func (tm *TokenManager) GetConfig() *AuthConfig {
 // TokenAuthMiddleware 创建 Token 认证中间件
 func (tm *TokenManager) TokenAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 健康检查端点无需认证
-		if r.URL.Path == "/health" || r.URL.Path == "/status" {
+		// 健康检查与探针端点无需认证
+		if isProbePath(r.URL.Path) {
 			next(w, r)
 			return
 		}
@@ -202,6 +202,41 @@ func (tm *TokenManager) TokenAuthMiddleware(next http.HandlerFunc) http.HandlerF
 	}
 }
 
+// RoleAuthorizer 按 API Key 的角色校验路由权限（节点主令牌不经过该校验）
+type RoleAuthorizer interface {
+	// Authorize 令牌不是有效的 API Key 时 role 为空；Key 的角色无权访问该路由时 allowed 为 false
+	Authorize(token, method, path string) (role string, allowed bool)
+	// AllowedRole 角色能否访问该路由，用于按配置角色限权的兼容令牌
+	AllowedRole(role, method, path string) bool
+	// Revoked 令牌是否为已撤销的 API Key
+	Revoked(token string) bool
+}
+
+// authorizeRole 用 API Key 或兼容令牌认证请求，失败时写入 401（未知令牌）或 403（角色无权限）
+func (s *Server) authorizeRole(w http.ResponseWriter, r *http.Request, token string) bool {
+	if token == "" {
+		s.writeError(w, http.StatusUnauthorized, "invalid or missing API token")
+		return false
+	}
+	var role string
+	var allowed bool
+	if s.config.Roles != nil {
+		role, allowed = s.config.Roles.Authorize(token, r.Method, r.URL.Path)
+	}
+	if role == "" {
+		role, allowed = s.authorizeCompat(token, r.Method, r.URL.Path)
+	}
+	if role == "" {
+		s.writeError(w, http.StatusUnauthorized, "invalid or missing API token")
+		return false
+	}
+	if !allowed {
+		s.writeError(w, http.StatusForbidden, fmt.Sprintf("role %s is not allowed to %s %s", role, r.Method, r.URL.Path))
+		return false
+	}
+	return true
+}
+
 // PrintTokenInfo 打印 Token 信息到控制台（首次启动时调用）
 func PrintTokenInfo(token string, listenAddr string) {
 	fmt.Println()
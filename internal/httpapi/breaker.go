@@ -0,0 +1,118 @@
+package httpapi
+
+import "net/http"
+
+// 熔断
+//
+// 超级节点可以对某类消息签发全网熔断指令（暂停或限速），各节点在本地执行，
+// 运维可以用本地覆盖放宽或收紧。中间件按路由判断消息类别，被熔断的请求返回 503。
+
+// breakerRoutes 受熔断约束的路由（仅 POST）及其消息类别
+var breakerRoutes = map[string]string{
+	"/api/v1/message/send":           "mail",
+	"/api/v1/message/receive":        "mail",
+	"/api/v1/mailbox/send":           "mail",
+	"/api/v1/bulletin/publish":       "bulletin",
+	"/api/v1/accusation/create":      "accusation",
+	"/api/v1/voting/proposal/create": "vote",
+	"/api/v1/voting/vote":            "vote",
+	"/api/v1/task/create":            "task",
+	"/api/v1/task/from-template":     "task",
+	"/api/v1/task/subcontract":       "task",
+}
+
+// rejectByBreaker 请求所属的消息类别被熔断时拒绝请求
+func (s *Server) rejectByBreaker(w http.ResponseWriter, r *http.Request) bool {
+	if s.BreakerAllowFunc == nil || r.Method != http.MethodPost {
+		return false
+	}
+	class, ok := breakerRoutes[r.URL.Path]
+	if !ok {
+		return false
+	}
+	if err := s.BreakerAllowFunc(class); err != nil {
+		s.writeError(w, http.StatusServiceUnavailable, err.Error())
+		return true
+	}
+	return false
+}
+
+// handleBreakerStatus 各消息类别的熔断状态
+func (s *Server) handleBreakerStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.BreakerStatusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "circuit breaker not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.BreakerStatusFunc())
+}
+
+// handleBreakerDirective 签发熔断指令并广播（仅超级节点）
+func (s *Server) handleBreakerDirective(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req BreakerDirectiveRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if req.Action == "rate_limit" && req.Rate <= 0 {
+		s.writeError(w, http.StatusBadRequest, "rate must be positive for rate_limit")
+		return
+	}
+	if s.BreakerIssueFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "circuit breaker not available")
+		return
+	}
+
+	directive, err := s.BreakerIssueFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, directive)
+}
+
+// handleBreakerOverride 设置或解除本地熔断覆盖
+func (s *Server) handleBreakerOverride(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req BreakerOverrideRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if req.Action == "rate_limit" && req.Rate <= 0 {
+		s.writeError(w, http.StatusBadRequest, "rate must be positive for rate_limit")
+		return
+	}
+
+	if req.Action == "clear" {
+		if s.BreakerClearOverrideFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "circuit breaker not available")
+			return
+		}
+		if err := s.BreakerClearOverrideFunc(req.Class); err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{"class": req.Class, "cleared": true})
+		return
+	}
+
+	if s.BreakerOverrideFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "circuit breaker not available")
+		return
+	}
+	override, err := s.BreakerOverrideFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, override)
+}
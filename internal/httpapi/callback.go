@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 节点回调签名相关请求头
+// 对端调用本节点的回调端点（如 /api/v1/message/receive）时，用自己的节点身份私钥对请求签名。
+const (
+	CallbackNodeHeader      = "X-NodeID"             // 调用方节点ID（即公钥）
+	CallbackSignatureHeader = "X-Signature"          // base64 签名
+	CallbackTimestampHeader = "X-Timestamp"          // 签名时间（Unix 秒）
+	CallbackNonceHeader     = "X-Nonce"              // 一次性随机串，防重放
+	CallbackSigVersion      = "daan-callback-sig-v1" // 签名载荷版本前缀
+	MaxCallbackNonceLength  = 128
+)
+
+var (
+	ErrCallbackUnsigned  = errors.New("callback request is not signed")
+	ErrCallbackSignature = errors.New("callback signature does not match the claimed node")
+	ErrCallbackExpired   = errors.New("callback timestamp outside the accepted window")
+	ErrCallbackReplayed  = errors.New("callback nonce already used")
+)
+
+// CallbackSigningPayload 构造回调请求签名载荷
+// 载荷绑定调用方、请求方法与路径、时间、nonce 和原始请求体的 SHA-256。
+func CallbackSigningPayload(nodeID, method, uri string, timestamp int64, nonce string, body []byte) []byte {
+	digest := sha256.Sum256(body)
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%s\n%d\n%s\n%s",
+		CallbackSigVersion, nodeID, method, uri, timestamp, nonce, hex.EncodeToString(digest[:])))
+}
+
+// SignCallbackRequest 为发往其他节点回调端点的请求签名（调用方使用）
+// body 必须与请求实际发送的请求体一致。
+func SignCallbackRequest(req *http.Request, nodeID string, body []byte, nonce string, sign func(data []byte) ([]byte, error)) error {
+	timestamp := time.Now().Unix()
+	signature, err := sign(CallbackSigningPayload(nodeID, req.Method, req.URL.RequestURI(), timestamp, nonce, body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(CallbackNodeHeader, nodeID)
+	req.Header.Set(CallbackTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(CallbackNonceHeader, nonce)
+	req.Header.Set(CallbackSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+	return nil
+}
+
+// verifyCallback 校验回调请求签名并消费 nonce，返回调用方节点ID
+// 读取后的请求体会放回 r.Body，后续仍可正常解码。
+func (s *Server) verifyCallback(r *http.Request) (string, int, error) {
+	if s.config.CallbackVerifyFunc == nil {
+		return "", http.StatusNotImplemented, errors.New("callback verification not available")
+	}
+
+	nodeID := r.Header.Get(CallbackNodeHeader)
+	nonce := r.Header.Get(CallbackNonceHeader)
+	if nodeID == "" || nonce == "" || r.Header.Get(CallbackSignatureHeader) == "" || r.Header.Get(CallbackTimestampHeader) == "" {
+		return "", http.StatusUnauthorized, ErrCallbackUnsigned
+	}
+	if len(nonce) > MaxCallbackNonceLength {
+		return "", http.StatusBadRequest, fmt.Errorf("nonce longer than %d characters", MaxCallbackNonceLength)
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(CallbackTimestampHeader), 10, 64)
+	if err != nil {
+		return "", http.StatusBadRequest, errors.New("timestamp must be unix seconds")
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(CallbackSignatureHeader))
+	if err != nil {
+		return "", http.StatusBadRequest, errors.New("signature must be base64")
+	}
+	if !s.callbackGuard.fresh(timestamp) {
+		return "", http.StatusUnauthorized, ErrCallbackExpired
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	payload := CallbackSigningPayload(nodeID, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if valid, err := s.config.CallbackVerifyFunc(nodeID, payload, signature); err != nil || !valid {
+		return "", http.StatusUnauthorized, ErrCallbackSignature
+	}
+
+	// 签名通过后才登记 nonce，伪造的请求无法抢占他人的 nonce
+	if !s.callbackGuard.use(nodeID, nonce, timestamp) {
+		return "", http.StatusUnauthorized, ErrCallbackReplayed
+	}
+	return nodeID, 0, nil
+}
+
+// replayGuard 回调重放保护：时间戳必须在窗口内，窗口内同一节点的 nonce 只能使用一次
+type replayGuard struct {
+	mu        sync.Mutex
+	window    time.Duration
+	seen      map[string]time.Time // 节点ID + nonce -> 过期时间
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newReplayGuard(window time.Duration) *replayGuard {
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	return &replayGuard{
+		window: window,
+		seen:   make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// fresh 时间戳是否在允许的时钟偏差内
+func (g *replayGuard) fresh(timestamp int64) bool {
+	skew := g.now().Sub(time.Unix(timestamp, 0))
+	return skew <= g.window && skew >= -g.window
+}
+
+// use 登记 nonce，窗口内已用过时返回 false
+// nonce 只需记到其时间戳离开窗口为止，之后带该时间戳的请求会被 fresh 拒绝。
+func (g *replayGuard) use(nodeID, nonce string, timestamp int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if now.Sub(g.lastSweep) > g.window {
+		for key, expires := range g.seen {
+			if now.After(expires) {
+				delete(g.seen, key)
+			}
+		}
+		g.lastSweep = now
+	}
+
+	key := nodeID + "\n" + nonce
+	if expires, ok := g.seen[key]; ok && !now.After(expires) {
+		return false
+	}
+	g.seen[key] = time.Unix(timestamp, 0).Add(g.window)
+	return true
+}
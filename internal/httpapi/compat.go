@@ -0,0 +1,296 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// 兼容模式请求头
+const (
+	EnvelopeHeader = "X-API-Envelope" // standard | none
+	CasingHeader   = "X-API-Casing"   // preserve | snake | camel
+)
+
+// EnvelopeMode 响应封装模式
+type EnvelopeMode string
+
+const (
+	EnvelopeStandard EnvelopeMode = "standard" // {"success":..,"data":..,"code":..}
+	EnvelopeNone     EnvelopeMode = "none"     // 直接返回 data，错误返回 {"error":..,"code":..}
+)
+
+// CasingMode 响应字段命名风格
+type CasingMode string
+
+const (
+	CasingPreserve CasingMode = "preserve" // 保持原样
+	CasingSnake    CasingMode = "snake"    // snake_case
+	CasingCamel    CasingMode = "camel"    // camelCase
+)
+
+// CompatDefaultRole 未指定角色的兼容令牌的角色：只能查询
+const CompatDefaultRole = "read-only"
+
+var (
+	ErrInvalidEnvelope   = errors.New("invalid envelope mode")
+	ErrInvalidCasing     = errors.New("invalid casing mode")
+	ErrInvalidCompatRole = errors.New("role is only allowed on compat tokens")
+)
+
+// CompatMode 客户端兼容模式
+type CompatMode struct {
+	Envelope EnvelopeMode `json:"envelope,omitempty"`
+	Casing   CasingMode   `json:"casing,omitempty"`
+	// Role 兼容令牌作为访问令牌时的角色（仅用于 tokens），默认 read-only
+	Role string `json:"role,omitempty"`
+}
+
+// Validate 校验兼容模式取值，空值表示沿用上一级设置
+func (m CompatMode) Validate() error {
+	switch m.Envelope {
+	case "", EnvelopeStandard, EnvelopeNone:
+	default:
+		return ErrInvalidEnvelope
+	}
+	switch m.Casing {
+	case "", CasingPreserve, CasingSnake, CasingCamel:
+	default:
+		return ErrInvalidCasing
+	}
+	return nil
+}
+
+// merge 用 o 中非空的字段覆盖 m
+func (m CompatMode) merge(o CompatMode) CompatMode {
+	if o.Envelope != "" {
+		m.Envelope = o.Envelope
+	}
+	if o.Casing != "" {
+		m.Casing = o.Casing
+	}
+	return m
+}
+
+// CompatConfig 兼容模式配置
+// Tokens 为按客户端令牌指定的兼容模式，这些令牌同时可作为访问令牌使用，
+// 便于给尚未迁移的旧客户端单独发放令牌。作为访问令牌时按 Role 限权；
+// 列出的是 API Key 时按 Key 的角色限权，撤销 Key 后令牌随之失效。
+type CompatConfig struct {
+	Default CompatMode            `json:"default"`
+	Tokens  map[string]CompatMode `json:"tokens,omitempty"`
+}
+
+// LoadCompatConfig 从 JSON 文件加载兼容模式配置，文件不存在时返回 nil
+func LoadCompatConfig(path string) (*CompatConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg CompatConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Default.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Default.Role != "" {
+		return nil, ErrInvalidCompatRole
+	}
+	for _, mode := range cfg.Tokens {
+		if err := mode.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// compatWriter 携带本次请求兼容模式（及响应签名参数）的 ResponseWriter
+type compatWriter struct {
+	http.ResponseWriter
+	mode    CompatMode
+	signing *responseSigning // 为 nil 时不签名
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接（事件流需要 Flush）
+func (w *compatWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compatModeOf 取出请求的兼容模式（直接调用 handler 时为默认模式）
+func compatModeOf(w http.ResponseWriter) CompatMode {
+	if cw, ok := w.(*compatWriter); ok {
+		return cw.mode
+	}
+	return CompatMode{Envelope: EnvelopeStandard, Casing: CasingPreserve}
+}
+
+// resolveCompat 依次应用：服务默认值 -> 令牌配置 -> 请求头
+func (s *Server) resolveCompat(r *http.Request, token string) (CompatMode, error) {
+	mode := CompatMode{Envelope: EnvelopeStandard, Casing: CasingPreserve}
+	if cfg := s.config.Compat; cfg != nil {
+		mode = mode.merge(cfg.Default)
+		if token != "" {
+			if tm, ok := cfg.Tokens[token]; ok {
+				mode = mode.merge(tm)
+			}
+		}
+	}
+
+	header := CompatMode{
+		Envelope: EnvelopeMode(strings.ToLower(r.Header.Get(EnvelopeHeader))),
+		Casing:   CasingMode(strings.ToLower(r.Header.Get(CasingHeader))),
+	}
+	if err := header.Validate(); err != nil {
+		return mode, err
+	}
+	return mode.merge(header), nil
+}
+
+// authorizeCompat 校验兼容令牌的访问权限；令牌不是有效的兼容令牌时 role 为空。
+// 节点主令牌已撤销或令牌是已撤销的 API Key 时兼容令牌不再有效；
+// 未配置角色校验时只有 read-only 的兼容令牌可用，且只能查询。
+func (s *Server) authorizeCompat(token, method, path string) (role string, allowed bool) {
+	if token == "" || s.config.Compat == nil {
+		return "", false
+	}
+	mode, ok := s.config.Compat.Tokens[token]
+	if !ok || s.tokenManager.GetToken() == "" {
+		return "", false
+	}
+	if s.config.Roles != nil && s.config.Roles.Revoked(token) {
+		return "", false
+	}
+	role = mode.Role
+	if role == "" {
+		role = CompatDefaultRole
+	}
+	if s.config.Roles == nil {
+		return role, role == CompatDefaultRole && (method == http.MethodGet || method == http.MethodHead)
+	}
+	return role, s.config.Roles.AllowedRole(role, method, path)
+}
+
+// encodeResponse 按兼容模式输出响应，需要时对最终响应体签名
+func encodeResponse(w http.ResponseWriter, status int, resp Response) {
+	mode := compatModeOf(w)
+	if mode.Envelope != EnvelopeStandard && mode.Envelope != "" {
+		w.Header().Set(EnvelopeHeader, string(mode.Envelope))
+	}
+
+	if mode.Casing == CasingSnake || mode.Casing == CasingCamel {
+		resp.Data = convertKeys(resp.Data, mode.Casing)
+	}
+
+	var body interface{} = resp
+	if mode.Envelope == EnvelopeNone {
+		if !resp.Success {
+			body = map[string]interface{}{
+				"error": resp.Error,
+				"code":  resp.Code,
+			}
+		} else {
+			body = resp.Data
+		}
+	}
+
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(body)
+	if cw, ok := w.(*compatWriter); ok && cw.signing != nil {
+		signResponse(w, cw.signing, status, buf.Bytes())
+	}
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// convertKeys 递归转换 JSON 对象的键名
+func convertKeys(data interface{}, casing CasingMode) interface{} {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return data
+	}
+	return convertValue(generic, casing)
+}
+
+func convertValue(v interface{}, casing CasingMode) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[convertKey(k, casing)] = convertValue(item, casing)
+		}
+		return out
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertValue(item, casing)
+		}
+		return val
+	}
+	return v
+}
+
+func convertKey(key string, casing CasingMode) string {
+	if casing == CasingSnake {
+		return toSnakeCase(key)
+	}
+	return toCamelCase(key)
+}
+
+// toSnakeCase nodeID -> node_id, HTTPAddr -> http_addr
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' {
+				prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toCamelCase node_id -> nodeId
+func toCamelCase(s string) string {
+	parts := strings.Split(s, "_")
+	var b strings.Builder
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if i == 0 || b.Len() == 0 {
+			b.WriteString(p)
+			continue
+		}
+		r := []rune(p)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	if b.Len() == 0 {
+		return s
+	}
+	return b.String()
+}
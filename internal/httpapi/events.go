@@ -0,0 +1,268 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 实时事件推送（Server-Sent Events）
+//
+// 客户端连接 GET /api/v1/events/stream 后持续接收新邮件、留言、任务和声誉变化事件，
+// 不必轮询。每个事件带递增 ID，断线重连时浏览器会自动带上 Last-Event-ID，
+// 服务端从最近的事件缓冲中补发；缓冲已不包含断点之后的全部事件时先发送 resync 事件，
+// 客户端应重新拉取一次完整状态。
+
+// 事件类型
+const (
+	EventMail       = "mail"
+	EventBulletin   = "bulletin"
+	EventTask       = "task"
+	EventReputation = "reputation"
+
+	// eventResync 补发不完整时发送，提示客户端重新拉取状态
+	eventResync = "resync"
+)
+
+const (
+	defaultEventBuffer    = 256              // 保留用于补发的最近事件数
+	eventSubscriberQueue  = 64               // 单个连接的待发送队列，积压时断开，由客户端重连补发
+	defaultEventKeepAlive = 15 * time.Second // 注释行心跳，防止代理断开空闲连接
+)
+
+// Event 推送事件
+type Event struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+type eventSub struct {
+	types map[string]bool // 为空表示订阅全部类型
+	ch    chan Event
+}
+
+func (sub *eventSub) wants(eventType string) bool {
+	return len(sub.types) == 0 || sub.types[eventType]
+}
+
+// EventHub 事件分发器
+type EventHub struct {
+	mu     sync.Mutex
+	nextID uint64
+	recent []Event // 最近的事件，按 ID 递增
+	size   int
+	subs   map[*eventSub]struct{}
+}
+
+// NewEventHub 创建事件分发器，bufferSize 为保留用于补发的事件数
+func NewEventHub(bufferSize int) *EventHub {
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBuffer
+	}
+	return &EventHub{
+		size: bufferSize,
+		subs: make(map[*eventSub]struct{}),
+	}
+}
+
+// Publish 发布事件，发送队列已满的订阅者会被断开
+func (h *EventHub) Publish(eventType string, data interface{}) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	ev := Event{ID: h.nextID, Type: eventType, Time: time.Now(), Data: data}
+	h.recent = append(h.recent, ev)
+	if len(h.recent) > h.size {
+		h.recent = h.recent[len(h.recent)-h.size:]
+	}
+	for sub := range h.subs {
+		if !sub.wants(eventType) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+	return ev
+}
+
+// Subscribers 当前订阅连接数
+func (h *EventHub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// subscribe 注册订阅者，返回 lastID 之后需要补发的事件；gap 表示缓冲中已缺少部分事件
+func (h *EventHub) subscribe(types []string, lastID uint64) (sub *eventSub, backlog []Event, gap bool) {
+	sub = &eventSub{types: make(map[string]bool), ch: make(chan Event, eventSubscriberQueue)}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if lastID > 0 && lastID < h.nextID {
+		if len(h.recent) == 0 || h.recent[0].ID > lastID+1 {
+			gap = true
+		}
+		for _, ev := range h.recent {
+			if ev.ID > lastID && sub.wants(ev.Type) {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	h.subs[sub] = struct{}{}
+	return sub, backlog, gap
+}
+
+func (h *EventHub) unsubscribe(sub *eventSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// EventSubscription 事件订阅，供 SSE 以外的推送通道（如 gRPC 消息流）使用
+type EventSubscription struct {
+	hub     *EventHub
+	sub     *eventSub
+	Backlog []Event // lastID 之后需要补发的事件
+	Gap     bool    // 缓冲中已缺少部分事件，客户端应重新拉取状态
+}
+
+// Subscribe 订阅事件，types 为空表示全部类型
+// 接收队列积压时订阅被断开（C 关闭），可从最后收到的事件 ID 重新订阅补发。
+func (h *EventHub) Subscribe(types []string, lastID uint64) *EventSubscription {
+	sub, backlog, gap := h.subscribe(types, lastID)
+	return &EventSubscription{hub: h, sub: sub, Backlog: backlog, Gap: gap}
+}
+
+// C 事件通道
+func (es *EventSubscription) C() <-chan Event {
+	return es.sub.ch
+}
+
+// Close 取消订阅
+func (es *EventSubscription) Close() {
+	es.hub.unsubscribe(es.sub)
+}
+
+// IsEventType 是否为事件流支持的事件类型
+func IsEventType(eventType string) bool {
+	switch eventType {
+	case EventMail, EventBulletin, EventTask, EventReputation:
+		return true
+	}
+	return false
+}
+
+// Events 事件分发器
+func (s *Server) Events() *EventHub {
+	return s.events
+}
+
+// PublishEvent 向事件流发布事件（服务未创建时忽略）
+func (s *Server) PublishEvent(eventType string, data interface{}) {
+	if s == nil || s.events == nil {
+		return
+	}
+	s.events.Publish(eventType, data)
+}
+
+// handleEventStream 事件流，可用 ?types=mail,bulletin 过滤类型
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var types []string
+	if raw := getQueryParam(r, "types", ""); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !IsEventType(t) {
+				s.writeError(w, http.StatusBadRequest, "unknown event type: "+t)
+				return
+			}
+			types = append(types, t)
+		}
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = getQueryParam(r, "last_event_id", "")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	// 长连接不受服务端写超时限制
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	sub, backlog, gap := s.events.subscribe(types, lastID)
+	defer s.events.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if gap {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventResync)
+	}
+	for _, ev := range backlog {
+		writeSSE(w, ev)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(s.eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.ch:
+			if !ok {
+				// 积压过多被断开，客户端带 Last-Event-ID 重连补发
+				return
+			}
+			writeSSE(w, ev)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE 按 text/event-stream 格式写出单个事件
+func writeSSE(w http.ResponseWriter, ev Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+}
@@ -4,7 +4,6 @@ package httpapi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -38,20 +37,66 @@ type Config struct {
 	APIToken       string // API Token（为空则自动生成）
 	AuthEnabled    bool   // 是否启用 Token 认证（默认启用）
 	
-	// 签名函数（用于验证请求）
+	// 签名函数（仅 validateSignature 使用，不防重放）；请求签名的强制校验见 SignedRequests
 	VerifyFunc func(nodeID string, data []byte, signature string) bool
+
+	// 对端回调签名校验（用调用方节点ID对应的公钥验签），未配置时回调端点拒绝请求
+	CallbackVerifyFunc func(signerID string, data, signature []byte) (bool, error)
+	CallbackMaxSkew    time.Duration // 回调时间戳允许的偏差，也是 nonce 的记忆时长
+
+	// 响应兼容模式（字段命名风格、是否使用 Response 封装），为空则使用标准格式
+	Compat *CompatConfig
+
+	// 响应签名（使用节点身份私钥），其他 agent 可把带签名的响应缓存下来作为证据
+	ResponseSignFunc   func(data []byte) ([]byte, error)
+	ResponseVerifyFunc func(signerID string, data, signature []byte) (bool, error)
+	SigningPublicKey   []byte
+	SigningAlgorithm   string        // 例如 ed25519
+	SignResponses      bool          // true 时对所有响应签名，否则仅对带 X-Sign-Response: 1 的请求签名
+	ManifestTTL        time.Duration // 节点清单（同样用节点身份私钥签名）的缓存有效期
+
+	// 请求体校验：true 时拒绝未知字段（返回 422），否则仅在请求带 X-Reject-Unknown-Fields: 1 时拒绝
+	RejectUnknownFields bool
+
+	// 网络命名空间（在节点信息中展示）
+	Namespace string
+
+	// 变更类 API 调用审计日志（与应用日志分离），为 nil 时不记录
+	MutationAudit *MutationAuditLog
+
+	// API 使用统计（按路由和客户端 SDK 版本），为 nil 时不统计
+	APIUsage *APIUsage
+	// 弃用的路由和 SDK 版本，调用时响应带 Deprecation/Sunset/Warning 头
+	Deprecations *DeprecationConfig
+	// 请求耗时观测，为 nil 时不记录
+	RequestObserver RequestObserver
+	// 按令牌计量 API 用量（计费导出），为 nil 时不计量
+	TokenMeter *TokenMeter
+	// 令牌对应的计费账户，未设置时按令牌 ID 计量
+	MeterAccount MeterAccountFunc
+
+	// 按路由的认证策略（路径 -> 策略，以 / 结尾的路径按前缀匹配），未列出的路由只要求 Token
+	RoutePolicies map[string]AuthPolicy
+	// 节点签名策略下签名者的登记检查，未设置时要求签名的路由返回 501
+	IdentityRegisteredFunc func(nodeID string) bool
+	// 签名请求模式：未在 RoutePolicies 中配置的变更类请求都要求 Token 加节点签名
+	SignedRequests bool
+	// 多 API Key 的角色权限校验，未设置时只接受 APIToken（及兼容模式令牌）
+	Roles RoleAuthorizer
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig(nodeID string) *Config {
 	return &Config{
-		NodeID:       nodeID,
-		ListenAddr:   ":18345",
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		EnableCORS:   true,
-		MaxBodySize:  10 * 1024 * 1024, // 10MB
-		AuthEnabled:  true,             // 默认启用认证
+		NodeID:          nodeID,
+		ListenAddr:      ":18345",
+		ReadTimeout:     30 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		EnableCORS:      true,
+		MaxBodySize:     10 * 1024 * 1024, // 10MB
+		AuthEnabled:     true,             // 默认启用认证
+		CallbackMaxSkew: 5 * time.Minute,
+		ManifestTTL:     10 * time.Minute,
 	}
 }
 
@@ -63,6 +108,13 @@ type Response struct {
 	Code    int         `json:"code"`
 }
 
+// ProbeCheck 存活/就绪探针的单项检查结果
+type ProbeCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
 // NodeInfoResponse 节点信息响应
 type NodeInfoResponse struct {
 	NodeID    string   `json:"node_id"`
@@ -70,6 +122,7 @@ type NodeInfoResponse struct {
 	Status    string   `json:"status"`
 	Uptime    int64    `json:"uptime"`
 	Version   string   `json:"version"`
+	Namespace string   `json:"namespace,omitempty"` // 网络命名空间，默认网络为空
 }
 
 // PeerInfo 节点信息
@@ -83,7 +136,7 @@ type PeerInfo struct {
 
 // MessageRequest 消息请求
 type MessageRequest struct {
-	To        string                 `json:"to"`
+	To        string                 `json:"to"` // 发送时必填，接收回调中可为空
 	Type      string                 `json:"type"`
 	Content   string                 `json:"content"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
@@ -93,16 +146,320 @@ type MessageRequest struct {
 // TaskRequest 任务请求
 type TaskRequest struct {
 	TaskID      string                 `json:"task_id"`
-	Type        string                 `json:"type"`
+	Type        string                 `json:"type" validate:"required"`
+	Title       string                 `json:"title,omitempty"` // 空时取描述或类型
 	Description string                 `json:"description"`
+	Reward      float64                `json:"reward,omitempty" validate:"min=0"`
+	Deadline    int64                  `json:"deadline,omitempty" validate:"min=0"` // 截止时间（Unix 秒），0 表示不限
 	Target      string                 `json:"target,omitempty"`
 	Payload     map[string]interface{} `json:"payload,omitempty"`
 	Signature   string                 `json:"signature,omitempty"`
+
+	PayloadFormats []string `json:"payload_formats,omitempty" validate:"oneof=application/json application/x-protobuf application/wasm"` // 可提供的载荷格式（按优先顺序）
+
+	// 验收策略（发布时选定，结算前强制执行）
+	Verification       string `json:"verification,omitempty" validate:"oneof=manual hash commit_reveal witness redundant supernode_audit"` // 空表示 manual
+	ExpectedHash       string `json:"expected_hash,omitempty"`                        // hash：期望的交付物摘要
+	RedundancyRequired int    `json:"redundancy_required,omitempty" validate:"min=0"` // redundant：需一致的结果数 N
+	RedundancyTotal    int    `json:"redundancy_total,omitempty" validate:"min=0"`    // redundant：执行份数 M
+}
+
+// TaskAcceptRequest 接受任务请求
+type TaskAcceptRequest struct {
+	TaskID         string   `json:"task_id" validate:"required"`
+	PayloadFormats []string `json:"payload_formats,omitempty" validate:"oneof=application/json application/x-protobuf application/wasm"` // 本节点支持的载荷格式，空表示使用能力登记
+}
+
+// TaskSubmitRequest 执行方提交任务结果
+type TaskSubmitRequest struct {
+	TaskID     string `json:"task_id" validate:"required"`
+	Result     string `json:"result"`
+	ResultHash string `json:"result_hash,omitempty"` // 交付物摘要，空时按 result 计算
+}
+
+// TaskConfirmRequest 委托方验收交付或结算任务
+type TaskConfirmRequest struct {
+	TaskID string `json:"task_id" validate:"required"`
+}
+
+// TaskFromTemplateRequest 从模板创建任务请求
+type TaskFromTemplateRequest struct {
+	TemplateID string                 `json:"template_id" validate:"required"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Reward     float64                `json:"reward,omitempty" validate:"min=0"` // 0 表示使用模板默认预算
+}
+
+// TaskSubcontractRequest 转包请求
+type TaskSubcontractRequest struct {
+	ParentTaskID string  `json:"parent_task_id" validate:"required"`
+	Title        string  `json:"title" validate:"required"`
+	Description  string  `json:"description,omitempty"`
+	Reward       float64 `json:"reward" validate:"required,min=0"`    // 从上级任务报酬中拆分
+	Executor     string  `json:"executor,omitempty"`                  // 定向转包目标，空表示公开发布
+	Deadline     int64   `json:"deadline,omitempty" validate:"min=0"` // 不能晚于上级任务
+}
+
+// TaskAuditRequest 超级节点审计结论
+type TaskAuditRequest struct {
+	TaskID   string `json:"task_id" validate:"required"`
+	Decision string `json:"decision" validate:"required,oneof=approve reject"`
+	Note     string `json:"note,omitempty"`
+}
+
+// TaskReviewAssignRequest 为审计任务分配评审人
+type TaskReviewAssignRequest struct {
+	TaskID string `json:"task_id" validate:"required"`
+	Count  int    `json:"count" validate:"required,min=1,max=15"` // 评审人数 K
+}
+
+// TaskReviewRespondRequest 本节点接受或拒绝评审分配
+type TaskReviewRespondRequest struct {
+	TaskID   string `json:"task_id" validate:"required"`
+	Decision string `json:"decision" validate:"required,oneof=accept decline"`
+}
+
+// TaskProgressRequest 执行方报告任务进度
+type TaskProgressRequest struct {
+	TaskID     string  `json:"task_id" validate:"required"`
+	Percent    float64 `json:"percent" validate:"min=0,max=100"`
+	Checkpoint string  `json:"checkpoint,omitempty"` // 检查点哈希
+	Log        string  `json:"log,omitempty" validate:"max=4096"`
+	Message    string  `json:"message,omitempty"`
+}
+
+// TaskStorageRequest 存储任务的数据文件：存储方登记持有的文件，委托方按原始文件登记承诺
+type TaskStorageRequest struct {
+	TaskID string `json:"task_id" validate:"required"`
+	Path   string `json:"path" validate:"required"`
+}
+
+// TaskStorageChallengeRequest 立即对存储任务发起一次挑战
+type TaskStorageChallengeRequest struct {
+	TaskID string `json:"task_id" validate:"required"`
+}
+
+// TaskOfferPolicyRequest 替换邀约优先级策略（四项权重之和须大于 0）
+type TaskOfferPolicyRequest struct {
+	BudgetWeight           float64 `json:"budget_weight" validate:"min=0"`
+	ReputationWeight       float64 `json:"reputation_weight" validate:"min=0"`
+	DeadlineWeight         float64 `json:"deadline_weight" validate:"min=0"`
+	CapabilityWeight       float64 `json:"capability_weight" validate:"min=0"`
+	ReferenceBudgetPerUnit float64 `json:"reference_budget_per_unit" validate:"required,min=0"`
+	ReferenceReputation    float64 `json:"reference_reputation" validate:"required,min=0"`
+	DeadlineHorizonSeconds int64   `json:"deadline_horizon_seconds" validate:"required,min=1"`
+	MinLeadTimeSeconds     int64   `json:"min_lead_time_seconds" validate:"min=0"`
+}
+
+// TaskTemplateIDRequest 指定模板的请求
+type TaskTemplateIDRequest struct {
+	TemplateID string `json:"template_id" validate:"required"`
+}
+
+// TransferSendRequest 发起文件传输请求
+type TransferSendRequest struct {
+	PeerID string `json:"peer_id" validate:"required"`
+	Path   string `json:"path" validate:"required"` // 发件目录内的文件路径，相对路径相对于发件目录
+}
+
+// TransferIDRequest 指定传输的请求
+type TransferIDRequest struct {
+	TransferID string `json:"transfer_id" validate:"required"`
+}
+
+// RetentionHoldRequest 设置合规保全请求
+type RetentionHoldRequest struct {
+	Kind       string `json:"kind" validate:"required,oneof=mailbox_thread dispute ledger_range"`
+	Target     string `json:"target"` // 会话ID 或争议ID
+	RangeStart uint64 `json:"range_start,omitempty"`
+	RangeEnd   uint64 `json:"range_end,omitempty"`
+	Reason     string `json:"reason" validate:"required"`
+}
+
+// RetentionReleaseRequest 解除合规保全请求
+type RetentionReleaseRequest struct {
+	HoldID string `json:"hold_id" validate:"required"`
+	Reason string `json:"reason"`
+}
+
+// ReputationService 本节点的声誉存储，由 reputation.Manager 适配提供
+type ReputationService interface {
+	GetReputation(nodeID string) float64
+	AdjustReputation(nodeID string, delta float64, reason string) (float64, error)
+	ReputationRanking(limit int) []map[string]interface{}
+	ReputationHistory(nodeID string, limit int) []map[string]interface{}
+	// ReputationDetail 原始分数、阻尼后的生效分数及当前统计周期，未记录的节点返回 nil
+	ReputationDetail(nodeID string) map[string]interface{}
+}
+
+// EscrowService 任务押金托管与仲裁多签，由 escrow.EscrowManager 适配提供
+// 找不到托管时返回包装 ErrNotFound 的错误，签名无效或签名者无权时返回包装 ErrUnauthorized 的错误。
+type EscrowService interface {
+	ListEscrows(status string) []map[string]interface{}
+	GetEscrow(escrowID string) (map[string]interface{}, error)
+	CreateEscrow(taskID string, deposits map[string]float64) (map[string]interface{}, error)
+	Deposit(escrowID, nodeID string, amount float64, signature string) (map[string]interface{}, error)
+	SetArbitrators(escrowID string, arbitrators []string, threshold int, signatures map[string]string) (map[string]interface{}, error)
+	Dispute(escrowID, reason string) (map[string]interface{}, error)
+	ProposeResolution(escrowID, winner string, amount float64) (map[string]interface{}, error)
+	SubmitArbitratorSignature(escrowID, arbitratorID, signature string) (map[string]interface{}, error)
+	SignatureCount(escrowID string) (map[string]interface{}, error)
+	Resolve(escrowID, winner string, amount float64, signatures map[string]string) (map[string]interface{}, error)
+}
+
+// EscrowCreateRequest 创建押金托管请求
+type EscrowCreateRequest struct {
+	TaskID   string             `json:"task_id" validate:"required"`
+	Deposits map[string]float64 `json:"deposits" validate:"min=1"` // nodeID -> 要求的押金
+}
+
+// EscrowDepositRequest 存入押金请求（node_id 为空时由本节点存入并签名）
+type EscrowDepositRequest struct {
+	EscrowID  string  `json:"escrow_id" validate:"required"`
+	NodeID    string  `json:"node_id,omitempty"`
+	Amount    float64 `json:"amount" validate:"required"`
+	Signature string  `json:"signature,omitempty"`
+}
+
+// EscrowArbitratorsRequest 设置仲裁者集合请求（threshold 为 0 时按默认比例）
+// 托管锁定后需要每个参与方对更换的签名，本节点是参与方时由本节点补签。
+type EscrowArbitratorsRequest struct {
+	EscrowID    string            `json:"escrow_id" validate:"required"`
+	Arbitrators []string          `json:"arbitrators" validate:"min=1"`
+	Threshold   int               `json:"threshold,omitempty" validate:"min=0"`
+	Signatures  map[string]string `json:"signatures,omitempty"` // 参与方ID -> 签名
+}
+
+// EscrowDisputeRequest 本节点对托管发起争议
+type EscrowDisputeRequest struct {
+	EscrowID string `json:"escrow_id" validate:"required"`
+	Reason   string `json:"reason" validate:"required"`
+}
+
+// EscrowProposeRequest 提出争议结算方案（amount 为 0 表示全部可用余额）
+type EscrowProposeRequest struct {
+	EscrowID string  `json:"escrow_id" validate:"required"`
+	Winner   string  `json:"winner" validate:"required"`
+	Amount   float64 `json:"amount,omitempty" validate:"min=0"`
+}
+
+// EscrowSignatureRequest 提交仲裁签名（signature 为空时由本节点作为仲裁者签名）
+type EscrowSignatureRequest struct {
+	EscrowID     string `json:"escrow_id" validate:"required"`
+	ArbitratorID string `json:"arbitrator_id,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+}
+
+// EscrowResolveRequest 携带仲裁签名直接结算争议
+type EscrowResolveRequest struct {
+	EscrowID   string            `json:"escrow_id" validate:"required"`
+	Winner     string            `json:"winner" validate:"required"`
+	Amount     float64           `json:"amount,omitempty" validate:"min=0"`
+	Signatures map[string]string `json:"signatures" validate:"min=1"` // arbitratorID -> signature
+}
+
+// DisputeService 争议预审，由 dispute.DisputeManager 适配提供
+// 找不到争议时返回包装 ErrNotFound 的错误，非争议参与方时返回包装 ErrUnauthorized 的错误。
+type DisputeService interface {
+	ListDisputes(status string) []map[string]interface{}
+	GetDispute(disputeID string) (map[string]interface{}, error)
+	FileDispute(req DisputeFileRequest) (map[string]interface{}, error)
+	AttachEvidence(disputeID, evidenceType, description string, data []byte) (map[string]interface{}, error)
+	VerifyEvidence(disputeID, evidenceID, verifierID string) error
+	Suggestion(disputeID string) (map[string]interface{}, error)
+	ApplySuggestion(disputeID, approverID string) (map[string]interface{}, error)
+}
+
+// DisputeFileRequest 立案请求（关联托管时 task_id 可省略，取托管的任务）
+type DisputeFileRequest struct {
+	TaskID      string  `json:"task_id,omitempty"`
+	EscrowID    string  `json:"escrow_id,omitempty"`
+	Defendant   string  `json:"defendant" validate:"required"`
+	Type        string  `json:"type" validate:"required,oneof=non_delivery quality_issue non_payment false_delivery timeout other"`
+	Description string  `json:"description" validate:"required"`
+	Amount      float64 `json:"amount,omitempty" validate:"min=0"`
+}
+
+// DisputeChannelMessageRequest 调解频道发言请求，holder 为保存争议的节点，省略时为本节点
+type DisputeChannelMessageRequest struct {
+	DisputeID string `json:"dispute_id" validate:"required"`
+	Holder    string `json:"holder,omitempty"`
+	Content   string `json:"content" validate:"required"`
+}
+
+// DisputeEvidenceRequest 举证请求，data 为证据原件（JSON 中以 base64 编码）
+type DisputeEvidenceRequest struct {
+	DisputeID   string `json:"dispute_id" validate:"required"`
+	Type        string `json:"type" validate:"required"`
+	Description string `json:"description,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// CollateralService 按节点和用途划分的抵押账户（存入、提取、罚没和罚没记录）
+type CollateralService interface {
+	ListCollaterals(nodeID, status string) []map[string]interface{}
+	GetCollateral(nodeID, purpose string) (map[string]interface{}, error)
+	Deposit(purpose string, amount float64) (map[string]interface{}, error)
+	Withdraw(purpose string, amount float64) (map[string]interface{}, error)
+	Slash(req CollateralSlashRequest) (map[string]interface{}, error)
+	SlashHistory(nodeID, purpose string, limit int) []map[string]interface{}
+}
+
+// CollateralAmountRequest 本节点存入或提取抵押
+type CollateralAmountRequest struct {
+	Purpose string  `json:"purpose" validate:"required"`
+	Amount  float64 `json:"amount" validate:"required,min=0"`
+}
+
+// CollateralSlashRequest 按比例罚没节点某一用途的抵押（ratio 为 0 时按默认比例）
+type CollateralSlashRequest struct {
+	NodeID   string  `json:"node_id" validate:"required"`
+	Purpose  string  `json:"purpose" validate:"required"`
+	Ratio    float64 `json:"ratio" validate:"min=0,max=1"`
+	Reason   string  `json:"reason" validate:"required"`
+	Evidence string  `json:"evidence" validate:"required"`
+}
+
+// TokenService $DAAN 代币账本（余额、签名转账、流水和任务预付报酬）
+// 找不到预付报酬时返回包装 ErrNotFound 的错误，转账签名无效时返回包装 ErrUnauthorized 的错误。
+type TokenService interface {
+	Balance(nodeID string) map[string]interface{}
+	Transfer(req TokenTransferRequest) (map[string]interface{}, error)
+	History(nodeID string, limit int) []map[string]interface{}
+	GetTaskPayment(taskID string) (map[string]interface{}, error)
+	HoldTaskPayment(taskID string, amount float64) (map[string]interface{}, error)
+	ReleaseTaskPayment(taskID string, payouts map[string]float64) (map[string]interface{}, error)
+	RefundTaskPayment(taskID string) (map[string]interface{}, error)
+}
+
+// TokenTransferRequest 转账请求
+// from 为空时由本节点转出并签名；替其他节点提交时必须带该节点对载荷的 signature 和 nonce。
+type TokenTransferRequest struct {
+	From      string  `json:"from,omitempty"`
+	To        string  `json:"to" validate:"required"`
+	Amount    float64 `json:"amount" validate:"required,min=0"`
+	Memo      string  `json:"memo,omitempty"`
+	Nonce     uint64  `json:"nonce,omitempty"`
+	Signature string  `json:"signature,omitempty"`
+}
+
+// TokenTaskPaymentRequest 任务预付报酬的锁定、支付或退回
+type TokenTaskPaymentRequest struct {
+	TaskID  string             `json:"task_id" validate:"required"`
+	Amount  float64            `json:"amount,omitempty" validate:"min=0"`
+	Payouts map[string]float64 `json:"payouts,omitempty"`
+}
+
+// SnapshotQueryService 跨争议、托管和抵押存储的联合查询
+// 每次查询的各部分来自同一快照，结果中的 snapshot 给出快照时间和各存储的版本号；
+// 找不到争议时返回包装 ErrNotFound 的错误。
+type SnapshotQueryService interface {
+	DisputeCase(disputeID string) (map[string]interface{}, error)
+	NodeExposure(nodeID string) (map[string]interface{}, error)
 }
 
 // ReputationRequest 声誉请求
 type ReputationRequest struct {
-	NodeID    string  `json:"node_id"`
+	NodeID    string  `json:"node_id" validate:"required"`
 	Delta     float64 `json:"delta,omitempty"`
 	Reason    string  `json:"reason,omitempty"`
 	Signature string  `json:"signature,omitempty"`
@@ -110,7 +467,7 @@ type ReputationRequest struct {
 
 // AccusationRequest 指责请求
 type AccusationRequest struct {
-	Accused   string `json:"accused"`
+	Accused   string `json:"accused" validate:"required"`
 	Type      string `json:"type"`
 	Reason    string `json:"reason"`
 	Evidence  string `json:"evidence,omitempty"`
@@ -119,7 +476,7 @@ type AccusationRequest struct {
 
 // NeighborRequest 邻居请求
 type NeighborRequest struct {
-	NodeID    string   `json:"node_id"`
+	NodeID    string   `json:"node_id" validate:"required"`
 	Addresses []string `json:"addresses,omitempty"`
 }
 
@@ -130,87 +487,265 @@ type MailboxMessage struct {
 	To        string `json:"to"`
 	Subject   string `json:"subject"`
 	Content   string `json:"content"`
+	Encrypted bool   `json:"encrypted,omitempty"` // 端到端加密邮件，发件人读取已发出的邮件时 content 为空
 	Timestamp int64  `json:"timestamp"`
 	Read      bool   `json:"read"`
+	DeliverAt int64  `json:"deliver_at,omitempty"` // 两阶段发送的投递时间，之前可撤回
+	Status    string `json:"status,omitempty"`     // 投递状态：pending、delivered（收到收件人回执）、relayed、read 等
 }
 
 // MailboxSendRequest 邮箱发送请求
+// DelaySeconds > 0 时两阶段发送：消息先暂存，延迟结束前可通过 /mailbox/cancel 撤回；
+// 为 0 时使用节点默认延迟，Immediate 为 true 时跳过暂存直接发送。
 type MailboxSendRequest struct {
-	To        string `json:"to"`
-	Subject   string `json:"subject"`
-	Content   string `json:"content"`
-	Encrypted bool   `json:"encrypted,omitempty"`
+	To           string `json:"to" validate:"required"`
+	Subject      string `json:"subject"`
+	Content      string `json:"content"`
+	Encrypted    bool   `json:"encrypted,omitempty"`
+	DelaySeconds int    `json:"delay_seconds,omitempty" validate:"min=0,max=86400"`
+	Immediate    bool   `json:"immediate,omitempty"`
+	Confirmed    bool   `json:"confirmed,omitempty"` // 已确认收件人警告（低信任、低声誉或隔离中），否则返回 409
+}
+
+// MailboxContact 通讯录联系人
+// Trust 为 trusted、normal（默认）或 low；Encryption 为 always、never 或空（按发信请求）。
+type MailboxContact struct {
+	NodeID     string `json:"node_id" validate:"required"`
+	Label      string `json:"label,omitempty" validate:"max=64"`
+	Notes      string `json:"notes,omitempty" validate:"max=2000"`
+	Trust      string `json:"trust,omitempty" validate:"oneof=trusted normal low"`
+	Encryption string `json:"encryption,omitempty" validate:"oneof=always never"`
+	CreatedAt  int64  `json:"created_at,omitempty"`
+	UpdatedAt  int64  `json:"updated_at,omitempty"`
+}
+
+// MailboxRecipientWarning 发信警告
+type MailboxRecipientWarning struct {
+	Code    string `json:"code"` // low_trust, low_reputation, quarantined
+	Message string `json:"message"`
+}
+
+// MailboxRecipientCheck 发信前的收件人检查
+type MailboxRecipientCheck struct {
+	NodeID      string                    `json:"node_id"`
+	Contact     *MailboxContact           `json:"contact,omitempty"`
+	Reputation  *float64                  `json:"reputation,omitempty"`
+	Quarantined bool                      `json:"quarantined"`
+	Encrypt     *bool                     `json:"encrypt,omitempty"` // 联系人的加密偏好，为空时按请求
+	Warnings    []MailboxRecipientWarning `json:"warnings"`
+}
+
+// MailboxSendResult 邮箱发送结果
+type MailboxSendResult struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`               // sent 或 staged
+	DeliverAt int64  `json:"deliver_at,omitempty"` // 暂存消息的投递时间
 }
 
 // BulletinMessage 留言板消息
 type BulletinMessage struct {
-	ID        string `json:"id"`
-	Author    string `json:"author"`
-	Topic     string `json:"topic"`
-	Content   string `json:"content"`
-	Timestamp int64  `json:"timestamp"`
-	TTL       int64  `json:"ttl"`
+	ID         string             `json:"id"`
+	Author     string             `json:"author"`
+	Topic      string             `json:"topic"`
+	Content    string             `json:"content"`
+	Timestamp  int64              `json:"timestamp"`
+	TTL        int64              `json:"ttl"`
+	Encrypted  bool               `json:"encrypted,omitempty"`  // 为 true 时 Content 为密文
+	Recipients []string           `json:"recipients,omitempty"` // 加密留言的接收方
+	Previews   []*BulletinPreview `json:"previews,omitempty"`   // 链接和附件预览
+}
+
+// BulletinFilter 订阅过滤器，收到的留言在本节点匹配后才推送到事件流
+type BulletinFilter struct {
+	Keywords      []string `json:"keywords,omitempty"`       // 内容或标签包含任一关键词
+	Authors       []string `json:"authors,omitempty"`        // 作者白名单
+	MinReputation float64  `json:"min_reputation,omitempty"` // 作者最低声誉
+	Languages     []string `json:"languages,omitempty"`      // 语言代码，如 zh、en
+}
+
+// BulletinSubscription 话题订阅
+type BulletinSubscription struct {
+	Topic        string          `json:"topic"`
+	SubscribedAt int64           `json:"subscribed_at"`
+	MessageCount int64           `json:"message_count"` // 过滤后投递的消息数
+	Filter       *BulletinFilter `json:"filter,omitempty"`
+}
+
+// BulletinPreview 留言引用的链接或附件的元数据预览（发布时提取）
+type BulletinPreview struct {
+	Ref         string `json:"ref"`
+	Kind        string `json:"kind"` // url 或 attachment
+	Title       string `json:"title,omitempty"`
+	MIME        string `json:"mime,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	ContentHash string `json:"content_hash,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+	Error       string `json:"error,omitempty"`
 }
 
 // BulletinPublishRequest 留言发布请求
+// Recipients 非空时发布仅接收方可解密的加密留言
 type BulletinPublishRequest struct {
-	Topic     string `json:"topic"`
-	Content   string `json:"content"`
-	TTL       int64  `json:"ttl,omitempty"`
-	Signature string `json:"signature,omitempty"`
+	Topic      string   `json:"topic"`
+	Content    string   `json:"content" validate:"required"`
+	TTL        int64    `json:"ttl,omitempty" validate:"min=0"`
+	Signature  string   `json:"signature,omitempty"`
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// BulletinHideRequest 版主隐藏留言请求
+type BulletinHideRequest struct {
+	MessageID string `json:"message_id" validate:"required"`
+	Reason    string `json:"reason" validate:"required,max=500"`
+}
+
+// BulletinAppealRequest 作者申诉请求
+type BulletinAppealRequest struct {
+	MessageID string `json:"message_id" validate:"required"`
+	Statement string `json:"statement" validate:"required,max=2000"`
+}
+
+// BulletinResolveAppealRequest 申诉裁决请求
+type BulletinResolveAppealRequest struct {
+	MessageID string `json:"message_id" validate:"required"`
+	Decision  string `json:"decision" validate:"required,oneof=uphold overturn"`
+	Note      string `json:"note,omitempty" validate:"max=500"`
 }
 
 // ProposalRequest 提案请求
 type ProposalRequest struct {
-	Title       string `json:"title"`
+	Title       string `json:"title" validate:"required"`
 	Type        string `json:"type"`
 	Description string `json:"description,omitempty"`
 	Target      string `json:"target,omitempty"`
+	// 参数提案（type 为 proposal 且指定 key 时）
+	Key         string `json:"key,omitempty"`
+	Value       string `json:"value,omitempty"`
+	CanaryTrial int64  `json:"canary_trial,omitempty" validate:"min=0"` // 金丝雀试运行时长（秒），0 表示通过后直接全网生效
 }
 
 // VoteRequest 投票请求
 type VoteRequest struct {
-	ProposalID string `json:"proposal_id"`
-	Vote       string `json:"vote"` // yes/no/abstain
+	ProposalID string `json:"proposal_id" validate:"required"`
+	Vote       string `json:"vote" validate:"required,oneof=yes no abstain"`
+}
+
+// DelegationRequest 投票委托请求（scope 为空表示全部提案类型）
+type DelegationRequest struct {
+	Delegate string `json:"delegate" validate:"required"`
+	Scope    string `json:"scope,omitempty" validate:"oneof=kick restore promote demote proposal"`
+}
+
+// RevokeDelegationRequest 撤销投票委托请求
+type RevokeDelegationRequest struct {
+	Scope string `json:"scope,omitempty" validate:"oneof=kick restore promote demote proposal"`
+}
+
+// CanaryOptInRequest 金丝雀发布自愿参与请求
+type CanaryOptInRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// MaintenanceRequest 维护模式请求
+type MaintenanceRequest struct {
+	Enabled  bool   `json:"enabled"`
+	Reason   string `json:"reason,omitempty"`
+	Duration int64  `json:"duration,omitempty" validate:"min=0"` // 维护时长（秒），0 表示使用默认上限
+}
+
+// BreakerDirectiveRequest 签发熔断指令请求（仅超级节点）
+type BreakerDirectiveRequest struct {
+	Class    string `json:"class" validate:"required,oneof=mail bulletin accusation vote task"`
+	Action   string `json:"action" validate:"required,oneof=pause rate_limit lift"`
+	Rate     int    `json:"rate,omitempty" validate:"min=0"` // rate_limit 时每分钟允许的条数
+	Reason   string `json:"reason" validate:"required,max=256"`
+	Duration int64  `json:"duration,omitempty" validate:"min=0"` // 有效期（秒），0 表示使用最长有效期
+}
+
+// BreakerOverrideRequest 本地熔断覆盖请求，action 为 clear 时解除覆盖
+type BreakerOverrideRequest struct {
+	Class    string `json:"class" validate:"required,oneof=mail bulletin accusation vote task"`
+	Action   string `json:"action" validate:"required,oneof=pause rate_limit ignore clear"`
+	Rate     int    `json:"rate,omitempty" validate:"min=0"`
+	Reason   string `json:"reason,omitempty" validate:"max=256"`
+	Duration int64  `json:"duration,omitempty" validate:"min=0"` // 覆盖时长（秒），0 表示需手动解除
+}
+
+// FeatureReadyRequest 设置本节点对协议特性的就绪状态
+type FeatureReadyRequest struct {
+	Name  string `json:"name" validate:"required,max=64"`
+	Ready bool   `json:"ready"`
+}
+
+// EndorseRequest 密钥背书请求
+type EndorseRequest struct {
+	Subject    string `json:"subject" validate:"required"`
+	SubjectKey string `json:"subject_key" validate:"required"`
+	Statement  string `json:"statement,omitempty"`
+	TTL        int64  `json:"ttl,omitempty" validate:"min=0"` // 有效期（秒），0 表示使用默认值
+}
+
+// AdmissionInviteRequest 入站连接邀请请求
+type AdmissionInviteRequest struct {
+	PeerID string `json:"peer_id" validate:"required"`
+	Note   string `json:"note,omitempty"`
+	TTL    int64  `json:"ttl,omitempty" validate:"min=0"` // 有效期（秒），0 表示使用默认值
+}
+
+// IdentityProofRequest 外部身份证明签发请求
+// Location 为 gist/帖子 URL，DNS 证明可省略
+type IdentityProofRequest struct {
+	Kind     string `json:"kind" validate:"required,oneof=github_gist moltbook dns_txt"`
+	Handle   string `json:"handle" validate:"required,max=253"`
+	Location string `json:"location,omitempty" validate:"max=2048"`
 }
 
 // SuperNodeApplyRequest 超级节点申请请求
 type SuperNodeApplyRequest struct {
-	Stake int64 `json:"stake"`
+	Stake int64 `json:"stake" validate:"min=1"`
 }
 
 // SuperNodeVoteRequest 超级节点投票请求
 type SuperNodeVoteRequest struct {
-	Candidate string `json:"candidate"`
+	Candidate string `json:"candidate" validate:"required"`
 }
 
 // AuditSubmitRequest 审计提交请求
 type AuditSubmitRequest struct {
-	Target  string `json:"target"`
+	Target  string `json:"target" validate:"required"`
 	Passed  bool   `json:"passed"`
 	Details string `json:"details,omitempty"`
 }
 
-// GenesisInviteRequest 创世邀请请求
+// AuditRunRequest 复核任务结果请求
+type AuditRunRequest struct {
+	TaskID     string `json:"task_id" validate:"required"`
+	ResultHash string `json:"result_hash,omitempty"` // 任务交付时登记的摘要，给出时必须与执行记录一致
+}
+
+// GenesisInviteRequest 创世邀请请求，邀请函绑定受邀节点的创世公钥和 libp2p 节点ID
 type GenesisInviteRequest struct {
 	ForPubkey string `json:"for_pubkey"`
+	PeerID    string `json:"peer_id"`
 }
 
-// GenesisJoinRequest 加入网络请求
+// GenesisJoinRequest 加入网络请求，signature 是受邀方用创世私钥对邀请码的签名
 type GenesisJoinRequest struct {
-	Invitation string `json:"invitation"`
-	Pubkey     string `json:"pubkey"`
+	Invitation string `json:"invitation" validate:"required"`
+	Pubkey     string `json:"pubkey" validate:"required"`
+	PeerID     string `json:"peer_id" validate:"required"`
+	Signature  string `json:"signature" validate:"required"`
 }
 
 // IncentiveAwardRequest 激励奖励请求
 type IncentiveAwardRequest struct {
-	NodeID   string `json:"node_id"`
-	TaskType string `json:"task_type"`
+	NodeID   string `json:"node_id" validate:"required"`
+	TaskType string `json:"task_type" validate:"required"`
 }
 
 // IncentivePropagateRequest 声誉传播请求
 type IncentivePropagateRequest struct {
-	Target string  `json:"target"`
+	Target string  `json:"target" validate:"required"`
 	Delta  float64 `json:"delta"`
 }
 
@@ -233,11 +768,40 @@ type Server struct {
 	
 	// 数据获取函数
 	GetPeersFunc       func() []*PeerInfo
-	GetReputationFunc  func(nodeID string) float64
 	SendMessageFunc    func(to string, msg *MessageRequest) error
 	CreateTaskFunc     func(task *TaskRequest) (string, error)
+	AcceptTaskFunc     func(req *TaskAcceptRequest) (map[string]interface{}, error) // 协商载荷格式后接单
+	TaskStatusFunc     func(taskID string) (map[string]interface{}, error)
+	TaskSubmitFunc     func(req *TaskSubmitRequest) (map[string]interface{}, error)
+	TaskListFunc       func(status string, limit int) ([]map[string]interface{}, error)
+	TaskConfirmFunc    func(taskID string) (map[string]interface{}, error) // 以本节点为委托方验收交付
+	TaskSettleFunc     func(taskID string) (map[string]interface{}, error) // 结算本节点发布的任务，支付报酬
 	CreateAccusation   func(acc *AccusationRequest) (string, error)
 	
+	// 存活与就绪探针（/livez、/readyz），未设置时只检查 HTTP 服务本身
+	LivenessChecksFunc  func() []ProbeCheck
+	ReadinessChecksFunc func() []ProbeCheck
+	
+	// 维护模式
+	MaintenanceStatusFunc func() map[string]interface{}
+	MaintenanceSetFunc    func(enabled bool, reason string, duration time.Duration) (map[string]interface{}, error)
+	InMaintenanceFunc     func() bool
+	
+	// 熔断：BreakerAllowFunc 检查消息类别是否放行，返回 breaker.ErrPaused / ErrRateLimited 包装的错误
+	BreakerAllowFunc         func(class string) error
+	BreakerStatusFunc        func() interface{}
+	BreakerIssueFunc         func(req *BreakerDirectiveRequest) (interface{}, error)
+	BreakerOverrideFunc      func(req *BreakerOverrideRequest) (interface{}, error)
+	BreakerClearOverrideFunc func(class string) error
+	
+	// 协议特性激活
+	FeatureListFunc   func() []map[string]interface{}
+	FeatureStatusFunc func(name string) (map[string]interface{}, error)
+	FeatureReadyFunc  func(name string, ready bool) (map[string]interface{}, error)
+
+	// 节点清单的动态部分（版本、特性、协议、密钥算法、资源上限、公开端点），签名由服务端完成
+	ManifestFunc func() *Manifest
+	
 	// 邻居管理
 	GetNeighborsFunc    func(limit int) []*PeerInfo
 	GetBestNeighbors    func(count int) []*PeerInfo
@@ -245,6 +809,31 @@ type Server struct {
 	RemoveNeighborFunc  func(nodeID string) error
 	PingNeighborFunc    func(nodeID string) (int64, bool)
 	
+	// 网络调参
+	GossipTuningFunc func() map[string]interface{}
+	
+	// GossipSub 节点评分，peerID 为空时返回阈值和全部节点；未启用评分时返回 nil
+	GossipScoresFunc func(peerID string) map[string]interface{}
+	
+	// 出站带宽公平调度
+	OutboundFairnessFunc func() map[string]interface{}
+	
+	// 协议版本兼容：各协议族按版本的流量和最近使用旧版本的节点
+	ProtocolCompatFunc func() map[string]interface{}
+	
+	// 握手协商的节点间用量配额，peerID 为空时返回本节点通告的上限和全部节点状态
+	PeerQuotaFunc func(peerID string) (map[string]interface{}, error)
+	
+	// 过期判断的时钟偏差统计
+	ClockSkewFunc func() map[string]interface{}
+	
+	// 节点心跳，nodeID 为空时返回本节点心跳状态和全部已知节点的存活情况
+	HeartbeatStatusFunc func(nodeID string) (map[string]interface{}, error)
+	
+	// 本地存储静态加密
+	StorageKeysFunc      func() map[string]interface{}
+	StorageRotateKeyFunc func() (map[string]interface{}, error)
+	
 	// 邮箱功能
 	MailboxSendFunc     func(to, subject, content string, encrypted bool) (string, error)
 	MailboxInboxFunc    func(limit, offset int) ([]*MailboxMessage, int)
@@ -252,6 +841,25 @@ type Server struct {
 	MailboxReadFunc     func(messageID string) (*MailboxMessage, error)
 	MailboxMarkReadFunc func(messageID string) error
 	MailboxDeleteFunc   func(messageID string) error
+	MailboxPublicFunc   func(limit, offset int) ([]*MailboxMessage, int)
+	MailboxKnownFunc    func() []string
+	MailboxSetKnownFunc func(nodeID string, known bool) error
+
+	// 两阶段发送（暂存、撤回），MailboxStageFunc 为 nil 时不支持暂存
+	MailboxStageFunc  func(req *MailboxSendRequest) (*MailboxSendResult, error)
+	MailboxCancelFunc func(messageID string) (*MailboxMessage, error)
+	MailboxStagedFunc func() []*MailboxMessage
+
+	// 通讯录与发信检查，MailboxCheckFunc 非空时发信前检查收件人并按联系人的加密偏好加密
+	MailboxContactsFunc      func(query string, limit int) []*MailboxContact
+	MailboxSaveContactFunc   func(c *MailboxContact) (*MailboxContact, error)
+	MailboxDeleteContactFunc func(nodeID string) error
+	MailboxCheckFunc         func(to string) *MailboxRecipientCheck
+
+	// 离线中继暂存的邮件，仅中继节点设置
+	MailboxPendingFunc func(receiver string) map[string]interface{}
+	// 投递路由决策和各路径的投递统计
+	MailboxRoutesFunc func(receiver, messageID string, limit int) map[string]interface{}
 	
 	// 留言板功能
 	BulletinPublishFunc   func(topic, content string, ttl int64) (string, error)
@@ -259,16 +867,98 @@ type Server struct {
 	BulletinByTopicFunc   func(topic string, limit int) []*BulletinMessage
 	BulletinByAuthorFunc  func(author string, limit int) []*BulletinMessage
 	BulletinSearchFunc    func(keyword string, limit int) []*BulletinMessage
-	BulletinSubscribeFunc func(topic string) error
+	BulletinSubscribeFunc func(topic string, filter *BulletinFilter) error
 	BulletinUnsubscribe   func(topic string) error
 	BulletinRevokeFunc    func(messageID string) error
 	
+	// 订阅过滤（修改已有订阅的过滤器、列出订阅）
+	BulletinFilterFunc        func(topic string, filter *BulletinFilter) error
+	BulletinSubscriptionsFunc func() []*BulletinSubscription
+	
+	// 定向加密留言（仅接收方可解密）
+	BulletinPublishEncryptedFunc func(topic, content string, recipients []string) (string, error)
+	BulletinDecryptFunc          func(messageID string) (string, error)
+	
+	// 留言板版主治理（隐藏、申诉与警告），actor 为验签通过的请求身份，未签名时为本节点
+	BulletinHideFunc          func(actor, messageID, reason string) (map[string]interface{}, error)
+	BulletinAppealFunc        func(actor, messageID, statement string) (map[string]interface{}, error)
+	BulletinResolveAppealFunc func(actor, messageID string, overturn bool, note string) (map[string]interface{}, error)
+	BulletinAppealsFunc       func() []map[string]interface{}
+	BulletinStrikesFunc       func(nodeID string) []map[string]interface{}
+	
+	// 任务模板
+	TaskTemplateListFunc   func() []map[string]interface{}
+	TaskTemplateSaveFunc   func(template map[string]interface{}) (map[string]interface{}, error)
+	TaskTemplateShareFunc  func(templateID string) (string, error)
+	TaskTemplateDeleteFunc func(templateID string) error
+	TaskFromTemplateFunc   func(req *TaskFromTemplateRequest) (map[string]interface{}, error)
+	
+	// 任务转包
+	TaskSubcontractFunc      func(req *TaskSubcontractRequest) (map[string]interface{}, error)
+	TaskSubcontractFailFunc  func(subtaskID, reason string) ([]map[string]interface{}, error)
+	TaskSubcontractChainFunc func(taskID string) ([]map[string]interface{}, error)
+	
+	// 任务验收
+	TaskAuditFunc        func(taskID string, approved bool, note string) (map[string]interface{}, error) // 以本节点为超级节点审计
+	TaskVerificationFunc func(taskID string) (map[string]interface{}, error)
+	
+	// 审计评审人分配（按声誉和能力确定性选出 K 名，排除利益冲突，超时补选）
+	TaskReviewersFunc       func(taskID string) (map[string]interface{}, error)
+	TaskReviewersAssignFunc func(taskID string, count int) (map[string]interface{}, error)
+	TaskReviewRespondFunc   func(taskID string, accept bool) (map[string]interface{}, error) // 以本节点为评审人响应
+	
+	// 任务进度（执行方报告，推送给委托方）
+	TaskProgressFunc       func(taskID string) (map[string]interface{}, error)
+	TaskProgressReportFunc func(req *TaskProgressRequest) (map[string]interface{}, error)
+	
+	// 存储证明（委托方随机抽取分块挑战存储方，存储方以 Merkle 路径证明仍持有数据）
+	TaskStorageHoldFunc       func(req *TaskStorageRequest) (map[string]interface{}, error) // 以本节点为存储方登记持有的文件
+	TaskStorageCommitFunc     func(req *TaskStorageRequest) (map[string]interface{}, error) // 以本节点为委托方登记承诺
+	TaskStorageChallengeFunc  func(taskID string) (map[string]interface{}, error)
+	TaskStorageChallengesFunc func(taskID string) (map[string]interface{}, error)
+	
+	// 待接任务邀约队列（按报酬、声誉、截止时间、能力匹配排序）
+	TaskOffersFunc         func(limit int) []map[string]interface{}
+	TaskOfferScoreFunc     func(taskID string) (map[string]interface{}, error)
+	TaskOfferPolicyFunc    func() map[string]interface{}
+	TaskOfferPolicySetFunc func(req *TaskOfferPolicyRequest) (map[string]interface{}, error)
+	
+	// 文件传输
+	TransferSendFunc   func(peerID, path string) (map[string]interface{}, error)
+	TransferStatusFunc func(transferID string) (map[string]interface{}, error)
+	TransferPauseFunc  func(transferID string) error
+	TransferResumeFunc func(transferID string) error
+	TransferAcceptFunc func(transferID string) error
+	TransferRejectFunc func(transferID string) error
+	TransferListFunc   func(status string) []map[string]interface{}
+	
+	// 合规保全
+	RetentionHoldFunc    func(actor string, req *RetentionHoldRequest) (map[string]interface{}, error)
+	RetentionReleaseFunc func(holdID, actor, reason string) (map[string]interface{}, error)
+	RetentionListFunc    func(includeReleased bool) []map[string]interface{}
+	RetentionAuditFunc   func() ([]map[string]interface{}, error)
+	
+	// 节点日志：提交的条目由本节点签名保存，查询由只读副本执行；未设置时提交和查询为空操作
+	LogSubmitFunc   func(eventType string, details map[string]interface{}) (map[string]interface{}, error)
+	LogQueryFunc    func(nodeID, eventType string, limit int) []map[string]interface{}
+	LogReplicasFunc func() []map[string]interface{} // 日志只读副本的复制延迟
+	
 	// 投票功能
-	VotingCreateFunc    func(title, voteType, desc, target string) (string, error)
+	VotingCreateFunc    func(req *ProposalRequest) (string, error)
 	VotingListFunc      func(status string) []map[string]interface{}
 	VotingGetFunc       func(proposalID string) (map[string]interface{}, error)
 	VotingVoteFunc      func(proposalID, vote string) error
 	VotingFinalizeFunc  func(proposalID string) (string, error)
+	VotingTallyFunc     func(proposalID string) (map[string]interface{}, error)
+	
+	// 投票委托
+	VotingDelegateFunc         func(delegate, scope string) (map[string]interface{}, error)
+	VotingRevokeDelegationFunc func(scope string) error
+	VotingDelegationsFunc      func(nodeID string) []map[string]interface{}
+	
+	// 参数变更的金丝雀发布
+	VotingCanaryFunc      func(proposalID string) (map[string]interface{}, error)
+	VotingCanaryOptInFunc func(optIn bool) ([]string, error)
 	
 	// 超级节点
 	SuperNodeListFunc       func() []map[string]interface{}
@@ -280,22 +970,112 @@ type Server struct {
 	SuperNodeFinalizeFunc   func(electionID string) ([]string, error)
 	SuperNodeAuditSubmit    func(target string, passed bool, details string) (string, error)
 	SuperNodeAuditResult    func(target string) (float64, error)
+	SuperNodeScoreboardFunc func(nodeID string) ([]map[string]interface{}, error) // nodeID 为空返回全部
 	
 	// 创世节点
 	GenesisInfoFunc         func() map[string]interface{}
-	GenesisCreateInviteFunc func(forPubkey string) (string, error)
+	GenesisCreateInviteFunc func(forPubkey, peerID string) (string, error)
 	GenesisVerifyInviteFunc func(invitation string) (bool, string, error)
-	GenesisJoinFunc         func(invitation, pubkey string) (string, []string, error)
+	GenesisJoinFunc         func(req *GenesisJoinRequest) (string, []string, error)
+	GenesisGraphFunc        func(root string) (map[string]interface{}, error) // root 为空返回完整邀请树
 	
 	// 激励系统
-	IncentiveAwardFunc     func(nodeID, taskType string) (float64, error)
-	IncentivePropagateFunc func(target string, delta float64) (int, error)
-	IncentiveHistoryFunc   func(nodeID string, limit int) []map[string]interface{}
-	IncentiveToleranceFunc func(nodeID string) (int, int)
+	IncentiveAwardFunc           func(nodeID, taskType string) (float64, error)
+	IncentivePropagateFunc       func(target string, delta float64) (int, error)
+	IncentiveHistoryFunc         func(nodeID string, limit int) []map[string]interface{}
+	IncentiveToleranceFunc       func(nodeID string) (int, int)
+	IncentiveToleranceInputsFunc func(nodeID string) map[string]interface{} // 初始耐受值的计算依据（声誉、关系、系数）
+	
+	// 声誉（查询、调整、排行、历史），为 nil 时相应端点返回 501
+	Reputation ReputationService
+	
+	// 押金托管与仲裁多签，为 nil 时相应端点返回 501
+	Escrow EscrowService
+	
+	// 争议预审（立案、举证、裁决建议），为 nil 时相应端点返回 501
+	Dispute DisputeService
+	
+	// 调解频道：以本节点为成员发言和读取记录，holder 为保存争议的节点（申诉方），为空表示本节点
+	DisputeChannelPostFunc       func(disputeID, holder, content string) (map[string]interface{}, error)
+	DisputeChannelTranscriptFunc func(disputeID, holder string) (map[string]interface{}, error)
+	
+	// 导出本节点保存的争议的监管链包（本节点签名），为 nil 时返回 501
+	DisputeBundleFunc func(disputeID string) (map[string]interface{}, error)
+	
+	// 抵押账户，为 nil 时相应端点返回 501
+	Collateral CollateralService
+	
+	// $DAAN 代币账本，为 nil 时相应端点返回 501
+	Token TokenService
+	
+	// 争议、托管和抵押的快照一致联合查询，为 nil 时相应端点返回 501
+	SnapshotQuery SnapshotQueryService
 	
 	// 声誉扩展
-	ReputationRankingFunc func(limit int) []map[string]interface{}
-	ReputationHistoryFunc func(nodeID string, limit int) []map[string]interface{}
+	ReputationWebhookFunc func(header http.Header, body []byte) (map[string]interface{}, error)
+	ReputationLookupFunc  func(nodeID string) (map[string]interface{}, error) // 远端节点声誉（带缓存）
+	
+	// Webhook 推送签名密钥（公钥公开发布）
+	WebhookKeysFunc      func() []map[string]interface{}
+	WebhookKeyRotateFunc func() (map[string]interface{}, error)
+	
+	// 信任网背书
+	TrustEndorseFunc       func(req *EndorseRequest) (map[string]interface{}, error)
+	TrustEndorsementsFunc  func(nodeID string) []map[string]interface{}
+	TrustDistanceFunc      func(nodeID string) int
+	
+	// 外部身份证明
+	TrustProofCreateFunc func(req *IdentityProofRequest) (map[string]interface{}, error)
+	TrustProofVerifyFunc func(proofID string) (map[string]interface{}, error)
+	TrustProofImportFunc func(record []byte) (map[string]interface{}, error)
+	TrustProofsFunc      func(nodeID string) map[string]interface{}
+	
+	// 入站连接准入（关系证明）
+	AdmissionStatusFunc      func() map[string]interface{}
+	AdmissionInviteFunc      func(req *AdmissionInviteRequest) (map[string]interface{}, error)
+	AdmissionRevokeFunc      func(invitationID string) error
+	AdmissionInvitationsFunc func(peerID string) []map[string]interface{}
+	AdmissionImportFunc      func(invitation []byte) (map[string]interface{}, error)
+	
+	// 审计：执行记录复核、共识与偏离惩罚
+	AuditTranscriptFunc    func(taskID string) (map[string]interface{}, error)
+	AuditRunFunc           func(req *AuditRunRequest) (map[string]interface{}, error)
+	AuditConsensusFunc     func(taskID string) (map[string]interface{}, error)
+	AuditDeviationsFunc    func(auditor string, limit int) []map[string]interface{}
+	AuditPenaltyConfigFunc func() map[string]PenaltyConfig
+	AuditSetPenaltyFunc    func(config PenaltyConfig) error
+	AuditManualPenaltyFunc func(nodeID, severity, reason string) (map[string]interface{}, error)
+	
+	// 账本校验：重放事件账本和代币流水，与当前状态交叉比对
+	LedgerVerifyFunc func() map[string]interface{}
+	LedgerUsageFunc  func() map[string]interface{}
+	
+	// 邻居回拨的可达性证明
+	ReachabilityFunc       func(nodeID string) map[string]interface{}
+	ReachabilityTestFunc   func(nodeID string) (map[string]interface{}, error)
+	ReachabilityImportFunc func(attestation []byte) (map[string]interface{}, error)
+	
+	// 按能力发现 Agent（DHT rendezvous）
+	DiscoveryAnnounceFunc      func(skill string) (map[string]interface{}, error)
+	DiscoveryWithdrawFunc      func(skill string) error
+	DiscoveryAnnouncementsFunc func() []map[string]interface{}
+	DiscoveryFindFunc          func(skill string, limit int) ([]map[string]interface{}, error)
+	
+	// 密钥社交恢复
+	RecoveryEscrowFunc      func(guardians []string, threshold int) (map[string]interface{}, error)
+	RecoveryHoldFunc        func(record []byte) (map[string]interface{}, error)
+	RecoveryEscrowGetFunc   func(escrowID string) (map[string]interface{}, error)
+	RecoveryRequestFunc     func(escrowID, requester, reason string) (map[string]interface{}, error)
+	RecoveryRequestsFunc    func() []map[string]interface{}
+	RecoveryDecideFunc      func(requestID string, approve bool, note string) (map[string]interface{}, error)
+	RecoveryCeremonyFunc    func(escrow []byte) (map[string]interface{}, error)
+	RecoveryShareFunc       func(ceremonyID string, share []byte) (map[string]interface{}, error)
+	RecoveryCeremonyGetFunc func(ceremonyID string) (map[string]interface{}, error)
+	RecoveryLogFunc         func(escrowID string) []map[string]interface{}
+	
+	// 模块统计
+	StatsFunc       func() map[string]interface{}
+	StatsModuleFunc func(module string) (map[string]interface{}, error)
 	
 	// 指责扩展
 	AccusationDetailFunc  func(accID string) (map[string]interface{}, error)
@@ -303,6 +1083,13 @@ type Server struct {
 	
 	// Token 认证管理器
 	tokenManager *TokenManager
+
+	// 回调重放保护
+	callbackGuard *replayGuard
+
+	// 实时事件推送
+	events         *EventHub
+	eventKeepAlive time.Duration
 }
 
 // NewServer 创建 HTTP API 服务器
@@ -323,10 +1110,13 @@ func NewServer(config *Config) (*Server, error) {
 	tokenManager := NewTokenManager(authConfig)
 	
 	s := &Server{
-		config:       config,
-		handlers:     make(map[string]http.HandlerFunc),
-		startTime:    time.Now(),
-		tokenManager: tokenManager,
+		config:         config,
+		handlers:       make(map[string]http.HandlerFunc),
+		startTime:      time.Now(),
+		tokenManager:   tokenManager,
+		callbackGuard:  newReplayGuard(config.CallbackMaxSkew),
+		events:         NewEventHub(defaultEventBuffer),
+		eventKeepAlive: defaultEventKeepAlive,
 	}
 	
 	return s, nil
@@ -404,6 +1194,14 @@ func (s *Server) GetAPIToken() string {
 	return s.tokenManager.GetToken()
 }
 
+// ValidateAPIToken 校验 API Token（供 gRPC 等其他入口复用 HTTP API 的认证）
+func (s *Server) ValidateAPIToken(token string) bool {
+	if s.tokenManager == nil {
+		return false
+	}
+	return s.tokenManager.ValidateToken(token)
+}
+
 // SetAPIToken 设置 API Token
 func (s *Server) SetAPIToken(token string) {
 	if s.tokenManager != nil {
@@ -438,12 +1236,26 @@ func (s *Server) GetAuthConfig() *AuthConfig {
 func (s *Server) registerRoutes(mux *http.ServeMux) {
 	// 健康检查
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/livez", s.handleLivez)
+	mux.HandleFunc("/readyz", s.handleReadyz)
 	mux.HandleFunc("/status", s.handleStatus)
 	
 	// 节点管理
 	mux.HandleFunc("/api/v1/node/info", s.handleNodeInfo)
 	mux.HandleFunc("/api/v1/node/peers", s.handlePeers)
 	mux.HandleFunc("/api/v1/node/register", s.handleNodeRegister)
+	mux.HandleFunc("/api/v1/node/maintenance", s.handleNodeMaintenance)
+	mux.HandleFunc("/api/v1/breaker/status", s.handleBreakerStatus)
+	mux.HandleFunc("/api/v1/breaker/directive", s.handleBreakerDirective)
+	mux.HandleFunc("/api/v1/breaker/override", s.handleBreakerOverride)
+	mux.HandleFunc("/api/v1/node/features", s.handleFeatureList)
+	mux.HandleFunc("/api/v1/node/features/", s.handleFeatureStatus)
+	mux.HandleFunc("/api/v1/node/features/ready", s.handleFeatureReady)
+	mux.HandleFunc("/api/v1/node/signing-key", s.handleSigningKey)
+	mux.HandleFunc(ManifestPath, s.handleManifest)
+	mux.HandleFunc("/api/v1/node/verify-response", s.handleVerifyResponse)
+	mux.HandleFunc("/api/v1/node/api-usage", s.handleAPIUsage)
+	mux.HandleFunc("/api/v1/billing/usage", s.handleBillingUsage)
 	
 	// 邻居管理
 	mux.HandleFunc("/api/v1/neighbor/list", s.handleNeighborList)
@@ -452,6 +1264,20 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/neighbor/remove", s.handleNeighborRemove)
 	mux.HandleFunc("/api/v1/neighbor/ping", s.handleNeighborPing)
 	
+	// 网络
+	mux.HandleFunc("/api/v1/network/gossip-tuning", s.handleGossipTuning)
+	mux.HandleFunc("/api/v1/network/gossip-scores", s.handleGossipScores)
+	mux.HandleFunc("/api/v1/network/outbound", s.handleOutboundFairness)
+	mux.HandleFunc("/api/v1/network/protocol-compat", s.handleProtocolCompat)
+	mux.HandleFunc("/api/v1/network/peer-quota", s.handlePeerQuota)
+	mux.HandleFunc("/api/v1/network/clock-skew", s.handleClockSkew)
+	
+	// 心跳
+	mux.HandleFunc("/api/v1/heartbeat/status", s.handleHeartbeatStatus)
+	
+	// 存储加密
+	mux.HandleFunc("/api/v1/storage/keys", s.handleStorageKeys)
+	
 	// 消息
 	mux.HandleFunc("/api/v1/message/send", s.handleSendMessage)
 	mux.HandleFunc("/api/v1/message/receive", s.handleReceiveMessage)
@@ -463,6 +1289,14 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/mailbox/read/", s.handleMailboxRead)
 	mux.HandleFunc("/api/v1/mailbox/mark-read", s.handleMailboxMarkRead)
 	mux.HandleFunc("/api/v1/mailbox/delete", s.handleMailboxDelete)
+	mux.HandleFunc("/api/v1/mailbox/public", s.handleMailboxPublic)
+	mux.HandleFunc("/api/v1/mailbox/known", s.handleMailboxKnown)
+	mux.HandleFunc("/api/v1/mailbox/staged", s.handleMailboxStaged)
+	mux.HandleFunc("/api/v1/mailbox/cancel", s.handleMailboxCancel)
+	mux.HandleFunc("/api/v1/mailbox/contacts", s.handleMailboxContacts)
+	mux.HandleFunc("/api/v1/mailbox/contacts/check", s.handleMailboxContactCheck)
+	mux.HandleFunc("/api/v1/mailbox/pending", s.handleMailboxPending)
+	mux.HandleFunc("/api/v1/mailbox/routes", s.handleMailboxRoutes)
 	
 	// 留言板
 	mux.HandleFunc("/api/v1/bulletin/publish", s.handleBulletinPublish)
@@ -472,7 +1306,15 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/bulletin/search", s.handleBulletinSearch)
 	mux.HandleFunc("/api/v1/bulletin/subscribe", s.handleBulletinSubscribe)
 	mux.HandleFunc("/api/v1/bulletin/unsubscribe", s.handleBulletinUnsubscribe)
+	mux.HandleFunc("/api/v1/bulletin/subscription/filter", s.handleBulletinFilter)
+	mux.HandleFunc("/api/v1/bulletin/subscriptions", s.handleBulletinSubscriptions)
 	mux.HandleFunc("/api/v1/bulletin/revoke", s.handleBulletinRevoke)
+	mux.HandleFunc("/api/v1/bulletin/decrypt/", s.handleBulletinDecrypt)
+	mux.HandleFunc("/api/v1/bulletin/hide", s.handleBulletinHide)
+	mux.HandleFunc("/api/v1/bulletin/appeal", s.handleBulletinAppeal)
+	mux.HandleFunc("/api/v1/bulletin/appeal/resolve", s.handleBulletinResolveAppeal)
+	mux.HandleFunc("/api/v1/bulletin/appeals", s.handleBulletinAppeals)
+	mux.HandleFunc("/api/v1/bulletin/strikes/", s.handleBulletinStrikes)
 	
 	// 任务
 	mux.HandleFunc("/api/v1/task/create", s.handleCreateTask)
@@ -480,14 +1322,92 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/task/accept", s.handleTaskAccept)
 	mux.HandleFunc("/api/v1/task/submit", s.handleTaskSubmit)
 	mux.HandleFunc("/api/v1/task/list", s.handleTaskList)
+	mux.HandleFunc("/api/v1/task/confirm", s.handleTaskConfirm)
+	mux.HandleFunc("/api/v1/task/settle", s.handleTaskSettle)
+	mux.HandleFunc("/api/v1/task/from-template", s.handleTaskFromTemplate)
+	mux.HandleFunc("/api/v1/task/subcontract", s.handleTaskSubcontract)
+	mux.HandleFunc("/api/v1/task/subcontract/fail", s.handleTaskSubcontractFail)
+	mux.HandleFunc("/api/v1/task/chain", s.handleTaskChain)
+	mux.HandleFunc("/api/v1/task/audit", s.handleTaskAudit)
+	mux.HandleFunc("/api/v1/task/verification", s.handleTaskVerification)
+	mux.HandleFunc("/api/v1/task/reviewers", s.handleTaskReviewers)
+	mux.HandleFunc("/api/v1/task/reviewers/assign", s.handleTaskReviewersAssign)
+	mux.HandleFunc("/api/v1/task/reviewers/respond", s.handleTaskReviewRespond)
+	mux.HandleFunc("/api/v1/task/progress", s.handleTaskProgressReport)
+	mux.HandleFunc("/api/v1/task/progress/", s.handleTaskProgress)
+	mux.HandleFunc("/api/v1/task/storage/hold", s.handleTaskStorageHold)
+	mux.HandleFunc("/api/v1/task/storage/commit", s.handleTaskStorageCommit)
+	mux.HandleFunc("/api/v1/task/storage/challenge", s.handleTaskStorageChallenge)
+	mux.HandleFunc("/api/v1/task/storage/challenges", s.handleTaskStorageChallenges)
+	mux.HandleFunc("/api/v1/task/offers", s.handleTaskOffers)
+	mux.HandleFunc("/api/v1/task/offers/policy", s.handleTaskOfferPolicy)
+	mux.HandleFunc("/api/v1/task/offers/", s.handleTaskOfferScore)
+	mux.HandleFunc("/api/v1/task/template/list", s.handleTaskTemplateList)
+	mux.HandleFunc("/api/v1/task/template/save", s.handleTaskTemplateSave)
+	mux.HandleFunc("/api/v1/task/template/share", s.handleTaskTemplateShare)
+	mux.HandleFunc("/api/v1/task/template/delete", s.handleTaskTemplateDelete)
+	
+	// 文件传输
+	mux.HandleFunc("/api/v1/transfer/send", s.handleTransferSend)
+	mux.HandleFunc("/api/v1/transfer/status/", s.handleTransferStatus)
+	mux.HandleFunc("/api/v1/transfer/pause", s.handleTransferPause)
+	mux.HandleFunc("/api/v1/transfer/resume", s.handleTransferResume)
+	mux.HandleFunc("/api/v1/transfer/accept", s.handleTransferAccept)
+	mux.HandleFunc("/api/v1/transfer/reject", s.handleTransferReject)
+	mux.HandleFunc("/api/v1/transfer/list", s.handleTransferList)
+	
+	// 合规保全
+	mux.HandleFunc("/api/v1/retention/hold", s.handleRetentionHold)
+	mux.HandleFunc("/api/v1/retention/release", s.handleRetentionRelease)
+	mux.HandleFunc("/api/v1/retention/holds", s.handleRetentionHolds)
+	mux.HandleFunc("/api/v1/retention/audit", s.handleRetentionAudit)
 	
 	// 声誉
 	mux.HandleFunc("/api/v1/reputation/query", s.handleReputationQuery)
 	mux.HandleFunc("/api/v1/reputation/update", s.handleReputationUpdate)
 	mux.HandleFunc("/api/v1/reputation/ranking", s.handleReputationRanking)
 	mux.HandleFunc("/api/v1/reputation/history", s.handleReputationHistory)
+	mux.HandleFunc("/api/v1/reputation/webhook", s.handleReputationWebhook)
+	mux.HandleFunc(WebhookKeysPath, s.handleWebhookKeys)
+	mux.HandleFunc("/api/v1/reputation/webhook/keys/rotate", s.handleWebhookKeyRotate)
+	
+	// 信任网
+	mux.HandleFunc("/api/v1/trust/endorse", s.handleTrustEndorse)
+	mux.HandleFunc("/api/v1/trust/endorsements", s.handleTrustEndorsements)
+	mux.HandleFunc("/api/v1/trust/distance", s.handleTrustDistance)
+	mux.HandleFunc("/api/v1/trust/proof", s.handleTrustProofCreate)
+	mux.HandleFunc("/api/v1/trust/proof/verify", s.handleTrustProofVerify)
+	mux.HandleFunc("/api/v1/trust/proof/import", s.handleTrustProofImport)
+	mux.HandleFunc("/api/v1/trust/proofs", s.handleTrustProofs)
+	mux.HandleFunc("/api/v1/admission/status", s.handleAdmissionStatus)
+	mux.HandleFunc("/api/v1/admission/invite", s.handleAdmissionInvite)
+	mux.HandleFunc("/api/v1/admission/invite/revoke", s.handleAdmissionRevoke)
+	mux.HandleFunc("/api/v1/admission/invitations", s.handleAdmissionInvitations)
+	mux.HandleFunc("/api/v1/admission/invitations/import", s.handleAdmissionImport)
+	mux.HandleFunc("/api/v1/reachability/test", s.handleReachabilityTest)
+	mux.HandleFunc("/api/v1/reachability/attestations", s.handleReachabilityImport)
+	mux.HandleFunc("/api/v1/reachability/", s.handleReachability)
+	
+	// 按能力发现 Agent
+	mux.HandleFunc("/api/v1/discovery/announce", s.handleDiscoveryAnnounce)
+	mux.HandleFunc("/api/v1/discovery/withdraw", s.handleDiscoveryWithdraw)
+	mux.HandleFunc("/api/v1/discovery/find", s.handleDiscoveryFind)
+	mux.HandleFunc("/api/v1/recovery/escrow", s.handleRecoveryEscrowCreate)
+	mux.HandleFunc("/api/v1/recovery/escrow/", s.handleRecoveryEscrowGet)
+	mux.HandleFunc("/api/v1/recovery/hold", s.handleRecoveryHold)
+	mux.HandleFunc("/api/v1/recovery/request", s.handleRecoveryRequest)
+	mux.HandleFunc("/api/v1/recovery/requests", s.handleRecoveryRequests)
+	mux.HandleFunc("/api/v1/recovery/decide", s.handleRecoveryDecide)
+	mux.HandleFunc("/api/v1/recovery/ceremony", s.handleRecoveryCeremonyStart)
+	mux.HandleFunc("/api/v1/recovery/ceremony/share", s.handleRecoveryCeremonyShare)
+	mux.HandleFunc("/api/v1/recovery/ceremony/", s.handleRecoveryCeremony)
+	mux.HandleFunc("/api/v1/recovery/log", s.handleRecoveryLog)
+	mux.HandleFunc("/api/v1/stats", s.handleStats)
+	mux.HandleFunc("/api/v1/stats/", s.handleStatsModule)
+	mux.HandleFunc("/api/v1/events/stream", s.handleEventStream)
 	
 	// 指责
+	mux.HandleFunc("/api/v1/auth/policies", s.handleAuthPolicies)
 	mux.HandleFunc("/api/v1/accusation/create", s.handleAccusationCreate)
 	mux.HandleFunc("/api/v1/accusation/list", s.handleAccusationList)
 	mux.HandleFunc("/api/v1/accusation/detail/", s.handleAccusationDetail)
@@ -505,6 +1425,12 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/voting/proposal/", s.handleVotingGet)
 	mux.HandleFunc("/api/v1/voting/vote", s.handleVotingVote)
 	mux.HandleFunc("/api/v1/voting/proposal/finalize", s.handleVotingFinalize)
+	mux.HandleFunc("/api/v1/voting/tally/", s.handleVotingTally)
+	mux.HandleFunc("/api/v1/voting/delegate", s.handleVotingDelegate)
+	mux.HandleFunc("/api/v1/voting/delegate/revoke", s.handleVotingRevokeDelegation)
+	mux.HandleFunc("/api/v1/voting/delegations", s.handleVotingDelegations)
+	mux.HandleFunc("/api/v1/voting/canary/opt-in", s.handleVotingCanaryOptIn)
+	mux.HandleFunc("/api/v1/voting/canary/", s.handleVotingCanary)
 	
 	// 超级节点
 	mux.HandleFunc("/api/v1/supernode/list", s.handleSuperNodeList)
@@ -516,39 +1442,72 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/supernode/election/finalize", s.handleSuperNodeElectionFinalize)
 	mux.HandleFunc("/api/v1/supernode/audit/submit", s.handleSuperNodeAuditSubmit)
 	mux.HandleFunc("/api/v1/supernode/audit/result", s.handleSuperNodeAuditResult)
+	mux.HandleFunc("/api/v1/supernode/scoreboard", s.handleSuperNodeScoreboard)
 	
 	// 创世节点
 	mux.HandleFunc("/api/v1/genesis/info", s.handleGenesisInfo)
 	mux.HandleFunc("/api/v1/genesis/invite/create", s.handleGenesisInviteCreate)
 	mux.HandleFunc("/api/v1/genesis/invite/verify", s.handleGenesisInviteVerify)
 	mux.HandleFunc("/api/v1/genesis/join", s.handleGenesisJoin)
+	mux.HandleFunc("/api/v1/genesis/invite/graph", s.handleGenesisInviteGraph)
 	
 	// 日志
 	mux.HandleFunc("/api/v1/log/submit", s.handleLogSubmit)
 	mux.HandleFunc("/api/v1/log/query", s.handleLogQuery)
 	mux.HandleFunc("/api/v1/log/export", s.handleLogExport)
+	mux.HandleFunc("/api/v1/log/replicas", s.handleLogReplicas)
 	
 	// 审计集成
+	mux.HandleFunc("/api/v1/audit/transcript", s.handleAuditTranscript)
+	mux.HandleFunc("/api/v1/audit/run", s.handleAuditRun)
+	mux.HandleFunc("/api/v1/audit/consensus", s.handleAuditConsensus)
 	mux.HandleFunc("/api/v1/audit/deviations", s.handleAuditDeviations)
 	mux.HandleFunc("/api/v1/audit/penalty-config", s.handleAuditPenaltyConfig)
 	mux.HandleFunc("/api/v1/audit/manual-penalty", s.handleAuditManualPenalty)
+	mux.HandleFunc("/api/v1/audit/mutations", s.handleMutationAudit)
+	mux.HandleFunc("/api/v1/audit/mutations/export", s.handleMutationAuditExport)
+	mux.HandleFunc("/api/v1/audit/mutations/verify", s.handleMutationAuditVerify)
 	
 	// 抵押物管理
 	mux.HandleFunc("/api/v1/collateral/list", s.handleCollateralList)
 	mux.HandleFunc("/api/v1/collateral/by-node", s.handleCollateralByNode)
+	mux.HandleFunc("/api/v1/collateral/deposit", s.handleCollateralDeposit)
+	mux.HandleFunc("/api/v1/collateral/withdraw", s.handleCollateralWithdraw)
 	mux.HandleFunc("/api/v1/collateral/slash-by-node", s.handleCollateralSlashByNode)
 	mux.HandleFunc("/api/v1/collateral/slash-history", s.handleCollateralSlashHistory)
 	
+	// 代币账本
+	mux.HandleFunc("/api/v1/token/balance", s.handleTokenBalance)
+	mux.HandleFunc("/api/v1/token/transfer", s.handleTokenTransfer)
+	mux.HandleFunc("/api/v1/token/history", s.handleTokenHistory)
+	mux.HandleFunc("/api/v1/token/task-payment", s.handleTokenTaskPayment)
+	mux.HandleFunc("/api/v1/token/task-payment/release", s.handleTokenTaskPaymentRelease)
+	mux.HandleFunc("/api/v1/token/task-payment/refund", s.handleTokenTaskPaymentRefund)
+	mux.HandleFunc("/api/v1/ledger/verify", s.handleLedgerVerify)
+	mux.HandleFunc("/api/v1/ledger/usage", s.handleLedgerUsage)
+	
 	// 争议预审
 	mux.HandleFunc("/api/v1/dispute/list", s.handleDisputeList)
+	mux.HandleFunc("/api/v1/dispute/file", s.handleDisputeFile)
+	mux.HandleFunc("/api/v1/dispute/evidence", s.handleDisputeEvidence)
 	mux.HandleFunc("/api/v1/dispute/suggestion/", s.handleDisputeSuggestion)
 	mux.HandleFunc("/api/v1/dispute/verify-evidence", s.handleDisputeVerifyEvidence)
 	mux.HandleFunc("/api/v1/dispute/apply-suggestion", s.handleDisputeApplySuggestion)
 	mux.HandleFunc("/api/v1/dispute/detail/", s.handleDisputeDetail)
+	mux.HandleFunc("/api/v1/dispute/case/", s.handleDisputeCase)
+	mux.HandleFunc("/api/v1/dispute/channel/message", s.handleDisputeChannelMessage)
+	mux.HandleFunc("/api/v1/dispute/channel/transcript", s.handleDisputeChannelTranscript)
+	mux.HandleFunc("/api/v1/dispute/bundle/", s.handleDisputeBundle)
+	mux.HandleFunc("/api/v1/node/exposure", s.handleNodeExposure)
 	
 	// 托管多签
 	mux.HandleFunc("/api/v1/escrow/list", s.handleEscrowList)
 	mux.HandleFunc("/api/v1/escrow/detail/", s.handleEscrowDetail)
+	mux.HandleFunc("/api/v1/escrow/create", s.handleEscrowCreate)
+	mux.HandleFunc("/api/v1/escrow/deposit", s.handleEscrowDeposit)
+	mux.HandleFunc("/api/v1/escrow/arbitrators", s.handleEscrowArbitrators)
+	mux.HandleFunc("/api/v1/escrow/dispute", s.handleEscrowDispute)
+	mux.HandleFunc("/api/v1/escrow/propose", s.handleEscrowPropose)
 	mux.HandleFunc("/api/v1/escrow/arbitrator-signature", s.handleEscrowArbitratorSignature)
 	mux.HandleFunc("/api/v1/escrow/signature-count/", s.handleEscrowSignatureCount)
 	mux.HandleFunc("/api/v1/escrow/resolve", s.handleEscrowResolve)
@@ -566,7 +1525,8 @@ func (s *Server) middleware(next http.Handler) http.Handler {
 		if s.config.EnableCORS {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-NodeID, X-Signature, X-API-Token")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-NodeID, X-Signature, X-Timestamp, X-Nonce, X-API-Token, X-API-Envelope, X-API-Casing, X-Sign-Response, X-Client-Version")
+			w.Header().Set("Access-Control-Expose-Headers", "X-API-Envelope, X-Response-Signer, X-Response-Timestamp, X-Response-Signature, Deprecation, Sunset, Link, Warning")
 		}
 		
 		// 预检请求
@@ -575,39 +1535,111 @@ func (s *Server) middleware(next http.Handler) http.Handler {
 			return
 		}
 		
+		// 请求耗时（含预检之外的全部请求，按路由模式和状态码）
+		if s.config.RequestObserver != nil {
+			var done func(*http.Request)
+			w, done = s.observeRequest(w, r)
+			defer func() { done(r) }()
+		}
+		
+		// 使用统计与弃用提示：按路由模式统计（未通过认证或未匹配路由的请求不计入）
+		sdk, version := clientOf(r)
+		deprecated := s.applyDeprecation(w, r, sdk, version)
+		if s.config.APIUsage != nil {
+			defer func() {
+				if r.Pattern != "" {
+					s.config.APIUsage.Record(r.Method, r.Pattern, sdk, version, deprecated, time.Now())
+				}
+			}()
+		}
+		
 		// 限制请求体大小
 		r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxBodySize)
 		
 		// 设置 JSON 响应头
 		w.Header().Set("Content-Type", "application/json")
 		
-		// Token 认证（健康检查端点除外）
-		if r.URL.Path != "/health" && r.URL.Path != "/status" {
+		// 兼容模式：服务默认值 -> 令牌配置 -> 请求头
+		token := r.Header.Get(TokenHeader)
+		if token == "" {
+			token = r.URL.Query().Get(TokenQueryParam)
+		}
+		
+		// 按令牌计量：请求数、请求和响应字节数（通过认证的请求才计入）
+		var meter *meterWriter
+		if s.config.TokenMeter != nil && token != "" {
+			var record func()
+			meter, record = s.meterRequest(w, r, token)
+			w = meter
+			defer record()
+		}
+		
+		// 变更类请求通过认证后写入审计日志
+		aw := s.beginAudit(w, r, token)
+		if aw != nil {
+			defer aw.finish()
+			w = aw
+		}
+		
+		mode, err := s.resolveCompat(r, token)
+		cw := &compatWriter{ResponseWriter: w, mode: mode}
+		if s.shouldSignResponse(r) {
+			cw.signing = &responseSigning{
+				signer: s.config.NodeID,
+				method: r.Method,
+				uri:    r.URL.RequestURI(),
+				sign:   s.config.ResponseSignFunc,
+			}
+		}
+		if err != nil {
+			s.writeError(cw, http.StatusBadRequest, err.Error())
+			return
+		}
+		w = cw
+		
+		// Token 认证（健康检查与探针、签名公钥发现端点和节点清单、自带 HMAC 签名校验的 Webhook 回调
+		// 和配置为仅节点签名的路由除外）
+		policy := s.routePolicy(r.Method, r.URL.Path)
+		if policy != AuthPolicySignature && !isProbePath(r.URL.Path) && r.URL.Path != "/api/v1/node/signing-key" && r.URL.Path != ManifestPath && r.URL.Path != WebhookKeysPath && r.URL.Path != "/api/v1/reputation/webhook" {
 			if s.tokenManager != nil && s.tokenManager.IsAuthEnabled() {
-				token := r.Header.Get(TokenHeader)
-				if token == "" {
-					token = r.URL.Query().Get(TokenQueryParam)
-				}
-				if !s.tokenManager.ValidateToken(token) {
-					w.WriteHeader(http.StatusUnauthorized)
-					json.NewEncoder(w).Encode(Response{
-						Success: false,
-						Error:   "invalid or missing API token",
-						Code:    http.StatusUnauthorized,
-					})
+				if !s.tokenManager.ValidateToken(token) && !s.authorizeRole(w, r, token) {
 					return
 				}
+				if aw != nil {
+					aw.authenticated = true
+				}
+			}
+		}
+		
+		// 要求节点签名的路由：签名者必须是已登记的网络身份
+		if policy != AuthPolicyToken {
+			verified, status, err := s.verifyIdentity(r)
+			if err != nil {
+				s.writeError(w, status, err.Error())
+				return
 			}
+			r = verified
+			if aw != nil {
+				aw.authenticated = true
+				aw.record.ActorNodeID = VerifiedNodeID(r)
+			}
+		}
+		
+		// 熔断中的消息类别
+		if s.rejectByBreaker(w, r) {
+			return
 		}
 		
+		if meter != nil {
+			meter.admitted = true
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
 // 响应辅助函数
 func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(Response{
+	encodeResponse(w, status, Response{
 		Success: status >= 200 && status < 300,
 		Data:    data,
 		Code:    status,
@@ -615,8 +1647,7 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{})
 }
 
 func (s *Server) writeError(w http.ResponseWriter, status int, err string) {
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(Response{
+	encodeResponse(w, status, Response{
 		Success: false,
 		Error:   err,
 		Code:    status,
@@ -632,6 +1663,49 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// isProbePath 健康检查与探针端点，供编排系统免令牌访问
+func isProbePath(path string) bool {
+	switch path {
+	case "/health", "/livez", "/readyz", "/status":
+		return true
+	}
+	return false
+}
+
+// handleLivez 存活探针：进程是否健康，失败时应重启节点
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	s.writeProbe(w, r, s.LivenessChecksFunc)
+}
+
+// handleReadyz 就绪探针：节点是否可以接收流量，失败时应暂停转发请求而不是重启
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.writeProbe(w, r, s.ReadinessChecksFunc)
+}
+
+// writeProbe 执行检查项，全部通过返回 200，否则返回 503 并附带每项的结果
+func (s *Server) writeProbe(w http.ResponseWriter, r *http.Request, checksFunc func() []ProbeCheck) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	checks := []ProbeCheck{}
+	if checksFunc != nil {
+		checks = checksFunc()
+	}
+	status, code := "ok", http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+	s.writeJSON(w, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
 // handleStatus 状态信息
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
@@ -646,6 +1720,114 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// rejectInMaintenance 维护模式下拒绝新任务和选举类请求
+func (s *Server) rejectInMaintenance(w http.ResponseWriter) bool {
+	if s.InMaintenanceFunc != nil && s.InMaintenanceFunc() {
+		s.writeError(w, http.StatusServiceUnavailable, "node is in maintenance mode")
+		return true
+	}
+	return false
+}
+
+// handleNodeMaintenance 查询或切换维护模式
+func (s *Server) handleNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		status := map[string]interface{}{"enabled": false}
+		if s.MaintenanceStatusFunc != nil {
+			status = s.MaintenanceStatusFunc()
+		}
+		s.writeJSON(w, http.StatusOK, status)
+	
+	case http.MethodPost:
+		var req MaintenanceRequest
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+		if s.MaintenanceSetFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "maintenance mode not available")
+			return
+		}
+		
+		status, err := s.MaintenanceSetFunc(req.Enabled, req.Reason, time.Duration(req.Duration)*time.Second)
+		if err != nil {
+			s.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, status)
+	
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleFeatureList 列出协议特性的激活状态
+func (s *Server) handleFeatureList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	features := []map[string]interface{}{}
+	if s.FeatureListFunc != nil {
+		features = s.FeatureListFunc()
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"features": features,
+		"count":    len(features),
+	})
+}
+
+// handleFeatureStatus 查询单个协议特性的激活状态
+func (s *Server) handleFeatureStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/node/features/")
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "feature name required")
+		return
+	}
+	
+	if s.FeatureStatusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "feature activation not available")
+		return
+	}
+	
+	status, err := s.FeatureStatusFunc(name)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+// handleFeatureReady 设置本节点对协议特性的就绪状态（随下次心跳通告）
+func (s *Server) handleFeatureReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req FeatureReadyRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.FeatureReadyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "feature activation not available")
+		return
+	}
+	
+	status, err := s.FeatureReadyFunc(req.Name, req.Ready)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
 // handleNodeInfo 节点信息
 func (s *Server) handleNodeInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -663,6 +1845,7 @@ func (s *Server) handleNodeInfo(w http.ResponseWriter, r *http.Request) {
 		Status:    "online",
 		Uptime:    uptime,
 		Version:   "1.0.0",
+		Namespace: s.config.Namespace,
 	}
 	
 	s.writeJSON(w, http.StatusOK, info)
@@ -698,13 +1881,11 @@ func (s *Server) handleSendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req MessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
-	
 	if req.To == "" {
-		s.writeError(w, http.StatusBadRequest, "recipient is required")
+		s.writeValidationError(w, missingFields("to"))
 		return
 	}
 	
@@ -728,17 +1909,18 @@ func (s *Server) handleReceiveMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// 发送方必须用节点私钥签名，X-NodeID 只有验签通过才可信
+	from, status, err := s.verifyCallback(r)
+	if err != nil {
+		s.writeError(w, status, err.Error())
+		return
+	}
+	
 	var req MessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	from := r.Header.Get("X-NodeID")
-	if from == "" {
-		from = "unknown"
-	}
-	
 	if s.OnMessageReceived != nil {
 		s.OnMessageReceived(from, &req)
 	}
@@ -755,9 +1937,20 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	if s.rejectInMaintenance(w) {
+		return
+	}
+	
 	var req TaskRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	switch {
+	case req.Verification == "hash" && req.ExpectedHash == "":
+		s.writeValidationError(w, missingFields("expected_hash"))
+		return
+	case req.Verification == "redundant" && (req.RedundancyRequired == 0 || req.RedundancyTotal == 0):
+		s.writeValidationError(w, missingFields("redundancy_required", "redundancy_total"))
 		return
 	}
 	
@@ -773,6 +1966,7 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 	} else {
 		taskID = req.TaskID
 	}
+	s.PublishEvent(EventTask, map[string]interface{}{"action": "created", "task_id": taskID})
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"task_id": taskID,
@@ -793,7 +1987,16 @@ func (s *Server) handleTaskStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// TODO: 查询实际任务状态
+	if s.TaskStatusFunc != nil {
+		status, err := s.TaskStatusFunc(taskID)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, status)
+		return
+	}
+	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"task_id": taskID,
 		"status":  "pending",
@@ -812,15 +2015,30 @@ func (s *Server) handleReputationQuery(w http.ResponseWriter, r *http.Request) {
 		nodeID = s.config.NodeID
 	}
 	
-	var reputation float64 = 50.0
-	if s.GetReputationFunc != nil {
-		reputation = s.GetReputationFunc(nodeID)
+	// 其他节点的声誉走远端查询缓存，返回证明及缓存时长
+	if nodeID != s.config.NodeID && s.ReputationLookupFunc != nil {
+		result, err := s.ReputationLookupFunc(nodeID)
+		if err != nil {
+			s.writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, result)
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+	if s.Reputation == nil {
+		s.writeError(w, http.StatusNotImplemented, "reputation not available")
+		return
+	}
+	
+	result := map[string]interface{}{
 		"node_id":    nodeID,
-		"reputation": reputation,
-	})
+		"reputation": s.Reputation.GetReputation(nodeID),
+	}
+	for k, v := range s.Reputation.ReputationDetail(nodeID) {
+		result[k] = v
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
 // handleReputationUpdate 更新声誉
@@ -831,15 +2049,24 @@ func (s *Server) handleReputationUpdate(w http.ResponseWriter, r *http.Request)
 	}
 	
 	var req ReputationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.Reputation == nil {
+		s.writeError(w, http.StatusNotImplemented, "reputation not available")
 		return
 	}
 	
-	// TODO: 实际更新声誉
+	value, err := s.Reputation.AdjustReputation(req.NodeID, req.Delta, req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"node_id": req.NodeID,
-		"updated": true,
+		"node_id":    req.NodeID,
+		"updated":    true,
+		"reputation": value,
 	})
 }
 
@@ -851,17 +2078,15 @@ func (s *Server) handleAccusationCreate(w http.ResponseWriter, r *http.Request)
 	}
 	
 	var req AccusationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.Accused == "" {
-		s.writeError(w, http.StatusBadRequest, "accused is required")
-		return
+	// 路由要求节点签名时以验签通过的身份为准
+	from := VerifiedNodeID(r)
+	if from == "" {
+		from = r.Header.Get("X-NodeID")
 	}
-	
-	from := r.Header.Get("X-NodeID")
 	if from == "" {
 		from = s.config.NodeID
 	}
@@ -908,12 +2133,29 @@ func (s *Server) handleLogSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var logEntry map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&logEntry); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &logEntry) {
+		return
+	}
+	
+	if s.LogSubmitFunc != nil {
+		eventType, _ := logEntry["event_type"].(string)
+		if eventType == "" {
+			s.writeError(w, http.StatusBadRequest, "event_type is required")
+			return
+		}
+		delete(logEntry, "event_type")
+		entry, err := s.LogSubmitFunc(eventType, logEntry)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"submitted": true,
+			"entry":     entry,
+		})
 		return
 	}
 	
-	// TODO: 存储日志
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"submitted": true,
 	})
@@ -937,14 +2179,16 @@ func (s *Server) handleLogQuery(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
-	// TODO: 查询实际日志
-	_ = nodeID
-	_ = eventType
-	_ = limit
+	logs := []map[string]interface{}{}
+	if s.LogQueryFunc != nil {
+		if result := s.LogQueryFunc(nodeID, eventType, limit); result != nil {
+			logs = result
+		}
+	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"logs":  []interface{}{},
-		"count": 0,
+		"logs":  logs,
+		"count": len(logs),
 	})
 }
 
@@ -967,15 +2211,6 @@ func (s *Server) GetListenAddr() string {
 	return s.config.ListenAddr
 }
 
-// parseBody 解析请求体
-func parseBody(r *http.Request, v interface{}) error {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(body, v)
-}
-
 // getQueryParam 获取查询参数
 func getQueryParam(r *http.Request, key string, defaultValue string) string {
 	value := r.URL.Query().Get(key)
@@ -1053,16 +2288,10 @@ func (s *Server) handleNodeRegister(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req struct {
-		Pubkey    string `json:"pubkey"`
+		Pubkey    string `json:"pubkey" validate:"required,min=16"`
 		Signature string `json:"signature"`
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	
-	if req.Pubkey == "" {
-		s.writeError(w, http.StatusBadRequest, "pubkey required")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1126,13 +2355,7 @@ func (s *Server) handleNeighborAdd(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req NeighborRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	
-	if req.NodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1155,13 +2378,7 @@ func (s *Server) handleNeighborRemove(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req NeighborRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	
-	if req.NodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1184,13 +2401,7 @@ func (s *Server) handleNeighborPing(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req NeighborRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
-	
-	if req.NodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1206,6 +2417,155 @@ func (s *Server) handleNeighborPing(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// ============== 网络调参 ==============
+
+// handleGossipTuning 查询 GossipSub 自适应扇出/心跳参数及负载指标
+func (s *Server) handleGossipTuning(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.GossipTuningFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "gossip tuning not enabled")
+		return
+	}
+	
+	status := s.GossipTuningFunc()
+	if status == nil {
+		s.writeError(w, http.StatusNotImplemented, "gossip tuning not enabled")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+// handleGossipScores 查询 GossipSub 节点评分（?peer_id= 指定节点）
+func (s *Server) handleGossipScores(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.GossipScoresFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "gossip scoring not enabled")
+		return
+	}
+	
+	scores := s.GossipScoresFunc(getQueryParam(r, "peer_id", ""))
+	if scores == nil {
+		s.writeError(w, http.StatusNotImplemented, "gossip scoring not enabled")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, scores)
+}
+
+// handleOutboundFairness 查询出站带宽调度的各节点占用
+func (s *Server) handleOutboundFairness(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.OutboundFairnessFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "outbound scheduler not enabled")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.OutboundFairnessFunc())
+}
+
+// handleProtocolCompat 查询协议版本回退表的各版本流量，判断网络中还有多少旧版本节点
+func (s *Server) handleProtocolCompat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.ProtocolCompatFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "protocol compatibility not enabled")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.ProtocolCompatFunc())
+}
+
+// handlePeerQuota 查询节点间配额状态（?peer_id= 指定节点）
+func (s *Server) handlePeerQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.PeerQuotaFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "peer quotas not enabled")
+		return
+	}
+	
+	state, err := s.PeerQuotaFunc(getQueryParam(r, "peer_id", ""))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, state)
+}
+
+// handleHeartbeatStatus 查询本节点心跳状态和已知节点的存活情况
+func (s *Server) handleHeartbeatStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.HeartbeatStatusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "heartbeat not enabled")
+		return
+	}
+	
+	status, err := s.HeartbeatStatusFunc(getQueryParam(r, "node_id", ""))
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+// handleClockSkew 查询邮件和留言过期判断的时钟偏差统计
+func (s *Server) handleClockSkew(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.ClockSkewFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "clock skew statistics not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.ClockSkewFunc())
+}
+
+// handleStorageKeys GET 查询静态加密密钥，POST 轮换数据加密密钥并重写本地存储
+func (s *Server) handleStorageKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if s.StorageKeysFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "storage encryption not enabled")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.StorageKeysFunc())
+	case http.MethodPost:
+		if s.StorageRotateKeyFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "storage encryption not enabled")
+			return
+		}
+		result, err := s.StorageRotateKeyFunc()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, result)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
 // ============== 邮箱功能 ==============
 
 func (s *Server) handleMailboxSend(w http.ResponseWriter, r *http.Request) {
@@ -1215,13 +2575,39 @@ func (s *Server) handleMailboxSend(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req MailboxSendRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.To == "" {
-		s.writeError(w, http.StatusBadRequest, "recipient required")
+	// 发给低信任、低声誉或隔离中的收件人需要确认
+	if s.MailboxCheckFunc != nil {
+		check := s.MailboxCheckFunc(req.To)
+		if len(check.Warnings) > 0 && !req.Confirmed {
+			encodeResponse(w, http.StatusConflict, Response{
+				Success: false,
+				Data:    check,
+				Error:   "recipient warnings must be confirmed (set confirmed: true)",
+				Code:    http.StatusConflict,
+			})
+			return
+		}
+		if check.Encrypt != nil {
+			req.Encrypted = *check.Encrypt
+		}
+	}
+	
+	// 两阶段发送：由节点决定是否暂存（请求延迟或节点默认延迟）
+	if s.MailboxStageFunc != nil && !req.Immediate {
+		result, err := s.MailboxStageFunc(&req)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, result)
+		return
+	}
+	if req.DelaySeconds > 0 && !req.Immediate {
+		s.writeError(w, http.StatusNotImplemented, "staged send not available")
 		return
 	}
 	
@@ -1321,10 +2707,9 @@ func (s *Server) handleMailboxMarkRead(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req struct {
-		MessageID string `json:"message_id"`
+		MessageID string `json:"message_id" validate:"required"`
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1347,10 +2732,9 @@ func (s *Server) handleMailboxDelete(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	var req struct {
-		MessageID string `json:"message_id"`
+		MessageID string `json:"message_id" validate:"required"`
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
@@ -1366,1381 +2750,4063 @@ func (s *Server) handleMailboxDelete(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ============== 留言板功能 ==============
-
-func (s *Server) handleBulletinPublish(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleMailboxPublic 列出公开文件夹（陌生发件人的消息）
+func (s *Server) handleMailboxPublic(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req BulletinPublishRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
-	}
+	limit := getIntQueryParam(r, "limit", 20)
+	offset := getIntQueryParam(r, "offset", 0)
 	
-	if req.Content == "" {
-		s.writeError(w, http.StatusBadRequest, "content required")
-		return
+	var messages []*MailboxMessage
+	total := 0
+	if s.MailboxPublicFunc != nil {
+		messages, total = s.MailboxPublicFunc(limit, offset)
 	}
-	
-	messageID := fmt.Sprintf("blt_%d", time.Now().UnixNano())
-	if s.BulletinPublishFunc != nil {
-		var err error
-		messageID, err = s.BulletinPublishFunc(req.Topic, req.Content, req.TTL)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if messages == nil {
+		messages = []*MailboxMessage{}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"message_id": messageID,
-		"status":     "published",
+		"messages": messages,
+		"total":    total,
 	})
 }
 
-func (s *Server) handleBulletinGet(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-	
-	messageID := extractPathParam(r, "/api/v1/bulletin/message/")
-	if messageID == "" {
-		s.writeError(w, http.StatusBadRequest, "message_id required")
-		return
-	}
-	
-	if s.BulletinGetFunc != nil {
-		msg, err := s.BulletinGetFunc(messageID)
-		if err != nil {
-			s.writeError(w, http.StatusNotFound, err.Error())
+// handleMailboxKnown 查看或调整已知发件人
+// GET 列出已知发件人；POST {"node_id": "...", "known": false} 加入或移出
+func (s *Server) handleMailboxKnown(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		senders := []string{}
+		if s.MailboxKnownFunc != nil {
+			senders = s.MailboxKnownFunc()
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"senders": senders,
+			"count":   len(senders),
+		})
+	case http.MethodPost:
+		var req struct {
+			NodeID string `json:"node_id" validate:"required"`
+			Known  *bool  `json:"known,omitempty"`
+		}
+		if !s.decodeBody(w, r, &req) {
 			return
 		}
-		s.writeJSON(w, http.StatusOK, msg)
-		return
+		if s.MailboxSetKnownFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "mailbox not available")
+			return
+		}
+		known := req.Known == nil || *req.Known
+		if err := s.MailboxSetKnownFunc(req.NodeID, known); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"node_id": req.NodeID,
+			"known":   known,
+		})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 	}
-	
-	s.writeError(w, http.StatusNotFound, "message not found")
 }
 
-func (s *Server) handleBulletinByTopic(w http.ResponseWriter, r *http.Request) {
+// handleMailboxStaged 列出等待投递的暂存消息
+func (s *Server) handleMailboxStaged(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	topic := extractPathParam(r, "/api/v1/bulletin/topic/")
-	limit := getIntQueryParam(r, "limit", 20)
-	
-	var messages []*BulletinMessage
-	if s.BulletinByTopicFunc != nil {
-		messages = s.BulletinByTopicFunc(topic, limit)
+	var messages []*MailboxMessage
+	if s.MailboxStagedFunc != nil {
+		messages = s.MailboxStagedFunc()
 	}
 	if messages == nil {
-		messages = []*BulletinMessage{}
+		messages = []*MailboxMessage{}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"messages": messages,
-		"count":    len(messages),
+		"total":    len(messages),
 	})
 }
 
-func (s *Server) handleBulletinByAuthor(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleMailboxCancel 在撤回窗口内撤回暂存消息
+// 消息不存在、已投递或窗口已结束时返回 409
+func (s *Server) handleMailboxCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	author := extractPathParam(r, "/api/v1/bulletin/author/")
-	limit := getIntQueryParam(r, "limit", 20)
+	var req struct {
+		MessageID string `json:"message_id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
 	
-	var messages []*BulletinMessage
-	if s.BulletinByAuthorFunc != nil {
-		messages = s.BulletinByAuthorFunc(author, limit)
+	if s.MailboxCancelFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "staged send not available")
+		return
 	}
-	if messages == nil {
-		messages = []*BulletinMessage{}
+	msg, err := s.MailboxCancelFunc(req.MessageID)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
+		"message_id": msg.ID,
+		"status":     "cancelled",
 	})
 }
 
-func (s *Server) handleBulletinSearch(w http.ResponseWriter, r *http.Request) {
+// handleMailboxContacts 通讯录
+// GET 列出联系人，q 非空时按标签、节点 ID 和备注补全；POST 添加或更新；DELETE ?node_id= 删除
+func (s *Server) handleMailboxContacts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var contacts []*MailboxContact
+		if s.MailboxContactsFunc != nil {
+			contacts = s.MailboxContactsFunc(getQueryParam(r, "q", ""), getIntQueryParam(r, "limit", 0))
+		}
+		if contacts == nil {
+			contacts = []*MailboxContact{}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"contacts": contacts,
+			"count":    len(contacts),
+		})
+	case http.MethodPost:
+		var req MailboxContact
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+		if s.MailboxSaveContactFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "mailbox not available")
+			return
+		}
+		contact, err := s.MailboxSaveContactFunc(&req)
+		if err != nil {
+			s.writeServiceError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, contact)
+	case http.MethodDelete:
+		nodeID := getQueryParam(r, "node_id", "")
+		if nodeID == "" {
+			s.writeError(w, http.StatusBadRequest, "node_id is required")
+			return
+		}
+		if s.MailboxDeleteContactFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "mailbox not available")
+			return
+		}
+		if err := s.MailboxDeleteContactFunc(nodeID); err != nil {
+			s.writeServiceError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"node_id": nodeID,
+			"deleted": true,
+		})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleMailboxContactCheck 发信前检查收件人，返回联系人信息和警告
+func (s *Server) handleMailboxContactCheck(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	keyword := getQueryParam(r, "keyword", "")
-	limit := getIntQueryParam(r, "limit", 20)
-	
-	var messages []*BulletinMessage
-	if s.BulletinSearchFunc != nil {
-		messages = s.BulletinSearchFunc(keyword, limit)
+	to := getQueryParam(r, "to", "")
+	if to == "" {
+		s.writeError(w, http.StatusBadRequest, "to is required")
+		return
 	}
-	if messages == nil {
-		messages = []*BulletinMessage{}
+	if s.MailboxCheckFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "mailbox not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
-	})
+	s.writeJSON(w, http.StatusOK, s.MailboxCheckFunc(to))
 }
 
-func (s *Server) handleBulletinSubscribe(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleMailboxPending 查询作为中继暂存的邮件（不含内容）和待转交的送达回执数
+func (s *Server) handleMailboxPending(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		Topic string `json:"topic"`
+	if s.MailboxPendingFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "mailbox relay not enabled")
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	s.writeJSON(w, http.StatusOK, s.MailboxPendingFunc(getQueryParam(r, "receiver", "")))
+}
+
+// handleMailboxRoutes 查询最近的投递路由决策（候选路径、尝试和最终经由的路径）和各路径的投递统计
+func (s *Server) handleMailboxRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	if s.BulletinSubscribeFunc != nil {
-		if err := s.BulletinSubscribeFunc(req.Topic); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if s.MailboxRoutesFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "mailbox not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "subscribed",
-		"topic":  req.Topic,
-	})
+	s.writeJSON(w, http.StatusOK, s.MailboxRoutesFunc(getQueryParam(r, "receiver", ""), getQueryParam(r, "message_id", ""), getIntQueryParam(r, "limit", 20)))
 }
 
-func (s *Server) handleBulletinUnsubscribe(w http.ResponseWriter, r *http.Request) {
+// ============== 留言板功能 ==============
+
+func (s *Server) handleBulletinPublish(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		Topic string `json:"topic"`
+	var req BulletinPublishRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	if len(req.Recipients) > 0 {
+		if s.BulletinPublishEncryptedFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "encrypted bulletins not available")
+			return
+		}
+		messageID, err := s.BulletinPublishEncryptedFunc(req.Topic, req.Content, req.Recipients)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"message_id": messageID,
+			"status":     "published",
+			"encrypted":  true,
+		})
 		return
 	}
 	
-	if s.BulletinUnsubscribe != nil {
-		if err := s.BulletinUnsubscribe(req.Topic); err != nil {
+	messageID := fmt.Sprintf("blt_%d", time.Now().UnixNano())
+	if s.BulletinPublishFunc != nil {
+		var err error
+		messageID, err = s.BulletinPublishFunc(req.Topic, req.Content, req.TTL)
+		if err != nil {
 			s.writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "unsubscribed",
-		"topic":  req.Topic,
+		"message_id": messageID,
+		"status":     "published",
 	})
 }
 
-func (s *Server) handleBulletinRevoke(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+func (s *Server) handleBulletinGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		MessageID string `json:"message_id"`
-	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	messageID := extractPathParam(r, "/api/v1/bulletin/message/")
+	if messageID == "" {
+		s.writeError(w, http.StatusBadRequest, "message_id required")
 		return
 	}
 	
-	if s.BulletinRevokeFunc != nil {
-		if err := s.BulletinRevokeFunc(req.MessageID); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
+	if s.BulletinGetFunc != nil {
+		msg, err := s.BulletinGetFunc(messageID)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
 			return
 		}
+		s.writeJSON(w, http.StatusOK, msg)
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "revoked",
-	})
+	s.writeError(w, http.StatusNotFound, "message not found")
 }
 
-// ============== 任务扩展 ==============
-
-func (s *Server) handleTaskAccept(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleBulletinDecrypt 解密本节点作为接收方的加密留言
+func (s *Server) handleBulletinDecrypt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		TaskID string `json:"task_id"`
-	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	messageID := extractPathParam(r, "/api/v1/bulletin/decrypt/")
+	if messageID == "" {
+		s.writeError(w, http.StatusBadRequest, "message_id required")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status":  "accepted",
-		"task_id": req.TaskID,
-	})
-}
-
-func (s *Server) handleTaskSubmit(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	if s.BulletinDecryptFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "encrypted bulletins not available")
 		return
 	}
-	
-	var req struct {
-		TaskID string `json:"task_id"`
-		Result string `json:"result"`
-	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	content, err := s.BulletinDecryptFunc(messageID)
+	if err != nil {
+		s.writeError(w, http.StatusForbidden, err.Error())
 		return
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status":  "submitted",
-		"task_id": req.TaskID,
+		"message_id": messageID,
+		"content":    content,
 	})
 }
 
-func (s *Server) handleTaskList(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinByTopic(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	status := getQueryParam(r, "status", "")
+	topic := extractPathParam(r, "/api/v1/bulletin/topic/")
 	limit := getIntQueryParam(r, "limit", 20)
-	_ = status
-	_ = limit
+	
+	var messages []*BulletinMessage
+	if s.BulletinByTopicFunc != nil {
+		messages = s.BulletinByTopicFunc(topic, limit)
+	}
+	if messages == nil {
+		messages = []*BulletinMessage{}
+	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"tasks": []interface{}{},
-		"count": 0,
+		"messages": messages,
+		"count":    len(messages),
 	})
 }
 
-// ============== 声誉扩展 ==============
-
-func (s *Server) handleReputationRanking(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinByAuthor(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	limit := getIntQueryParam(r, "limit", 10)
+	author := extractPathParam(r, "/api/v1/bulletin/author/")
+	limit := getIntQueryParam(r, "limit", 20)
 	
-	var rankings []map[string]interface{}
-	if s.ReputationRankingFunc != nil {
-		rankings = s.ReputationRankingFunc(limit)
+	var messages []*BulletinMessage
+	if s.BulletinByAuthorFunc != nil {
+		messages = s.BulletinByAuthorFunc(author, limit)
 	}
-	if rankings == nil {
-		rankings = []map[string]interface{}{}
+	if messages == nil {
+		messages = []*BulletinMessage{}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"rankings": rankings,
+		"messages": messages,
+		"count":    len(messages),
 	})
 }
 
-func (s *Server) handleReputationHistory(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinSearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	keyword := getQueryParam(r, "keyword", "")
 	limit := getIntQueryParam(r, "limit", 20)
 	
-	var history []map[string]interface{}
-	if s.ReputationHistoryFunc != nil {
-		history = s.ReputationHistoryFunc(nodeID, limit)
+	var messages []*BulletinMessage
+	if s.BulletinSearchFunc != nil {
+		messages = s.BulletinSearchFunc(keyword, limit)
 	}
-	if history == nil {
-		history = []map[string]interface{}{}
+	if messages == nil {
+		messages = []*BulletinMessage{}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"node_id": nodeID,
-		"history": history,
+		"messages": messages,
+		"count":    len(messages),
 	})
 }
 
-// ============== 指责扩展 ==============
-
-func (s *Server) handleAccusationDetail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+func (s *Server) handleBulletinSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	accID := extractPathParam(r, "/api/v1/accusation/detail/")
-	if accID == "" {
-		s.writeError(w, http.StatusBadRequest, "accusation_id required")
+	var req struct {
+		Topic  string          `json:"topic" validate:"required"`
+		Filter *BulletinFilter `json:"filter,omitempty"`
+	}
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if s.AccusationDetailFunc != nil {
-		detail, err := s.AccusationDetailFunc(accID)
-		if err != nil {
-			s.writeError(w, http.StatusNotFound, err.Error())
+	if s.BulletinSubscribeFunc != nil {
+		if err := s.BulletinSubscribeFunc(req.Topic, req.Filter); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		s.writeJSON(w, http.StatusOK, detail)
-		return
 	}
 	
-	s.writeError(w, http.StatusNotFound, "accusation not found")
+	resp := map[string]interface{}{
+		"status": "subscribed",
+		"topic":  req.Topic,
+	}
+	if req.Filter != nil {
+		resp["filter"] = req.Filter
+	}
+	s.writeJSON(w, http.StatusOK, resp)
 }
 
-func (s *Server) handleAccusationAnalyze(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleBulletinFilter 修改已订阅话题的过滤器，filter 为空表示取消过滤
+func (s *Server) handleBulletinFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", "")
-	if nodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	var req struct {
+		Topic  string          `json:"topic" validate:"required"`
+		Filter *BulletinFilter `json:"filter,omitempty"`
+	}
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	var analysis map[string]interface{}
-	if s.AccusationAnalyzeFunc != nil {
-		analysis = s.AccusationAnalyzeFunc(nodeID)
+	if s.BulletinFilterFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "subscription filters not available")
+		return
 	}
-	if analysis == nil {
-		analysis = map[string]interface{}{
-			"node_id":        nodeID,
-			"total_received": 0,
-			"credibility":    1.0,
-		}
+	if err := s.BulletinFilterFunc(req.Topic, req.Filter); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, analysis)
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "updated",
+		"topic":  req.Topic,
+		"filter": req.Filter,
+	})
 }
 
-// ============== 激励系统 ==============
-
-func (s *Server) handleIncentiveAward(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+func (s *Server) handleBulletinSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req IncentiveAwardRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
-		return
+	var subs []*BulletinSubscription
+	if s.BulletinSubscriptionsFunc != nil {
+		subs = s.BulletinSubscriptionsFunc()
+	}
+	if subs == nil {
+		subs = []*BulletinSubscription{}
 	}
 	
-	reward := 0.0
-	if s.IncentiveAwardFunc != nil {
-		var err error
-		reward, err = s.IncentiveAwardFunc(req.NodeID, req.TaskType)
-		if err != nil {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"subscriptions": subs,
+		"count":         len(subs),
+	})
+}
+
+func (s *Server) handleBulletinUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		Topic string `json:"topic" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.BulletinUnsubscribe != nil {
+		if err := s.BulletinUnsubscribe(req.Topic); err != nil {
 			s.writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"reward": reward,
+		"status": "unsubscribed",
+		"topic":  req.Topic,
 	})
 }
 
-func (s *Server) handleIncentivePropagate(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinRevoke(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req IncentivePropagateRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req struct {
+		MessageID string `json:"message_id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	propagatedTo := 0
-	if s.IncentivePropagateFunc != nil {
-		var err error
-		propagatedTo, err = s.IncentivePropagateFunc(req.Target, req.Delta)
-		if err != nil {
+	if s.BulletinRevokeFunc != nil {
+		if err := s.BulletinRevokeFunc(req.MessageID); err != nil {
 			s.writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"propagated_to": propagatedTo,
+		"status": "revoked",
 	})
 }
 
-func (s *Server) handleIncentiveHistory(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// requestActor 写入治理和审计记录的操作者：路由要求节点签名时以验签通过的身份为准，否则为本节点
+func (s *Server) requestActor(r *http.Request) string {
+	if nodeID := VerifiedNodeID(r); nodeID != "" {
+		return nodeID
+	}
+	return s.config.NodeID
+}
+
+// handleBulletinHide 版主隐藏留言（作者记一次警告）
+func (s *Server) handleBulletinHide(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
-	limit := getIntQueryParam(r, "limit", 20)
+	var req BulletinHideRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
 	
-	var rewards []map[string]interface{}
-	if s.IncentiveHistoryFunc != nil {
-		rewards = s.IncentiveHistoryFunc(nodeID, limit)
+	if s.BulletinHideFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "bulletin moderation not available")
+		return
 	}
-	if rewards == nil {
-		rewards = []map[string]interface{}{}
+	result, err := s.BulletinHideFunc(s.requestActor(r), req.MessageID, req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"rewards": rewards,
-	})
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleIncentiveTolerance(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleBulletinAppeal 作者对被隐藏的留言提出申诉
+func (s *Server) handleBulletinAppeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	var req BulletinAppealRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
 	
-	tolerance, maxTolerance := 0, 10
-	if s.IncentiveToleranceFunc != nil {
-		tolerance, maxTolerance = s.IncentiveToleranceFunc(nodeID)
+	if s.BulletinAppealFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "bulletin moderation not available")
+		return
+	}
+	result, err := s.BulletinAppealFunc(s.requestActor(r), req.MessageID, req.Statement)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"node_id":   nodeID,
-		"tolerance": tolerance,
-		"max":       maxTolerance,
-	})
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-// ============== 投票系统 ==============
-
-func (s *Server) handleVotingCreate(w http.ResponseWriter, r *http.Request) {
+// handleBulletinResolveAppeal 裁决申诉，overturn 时恢复留言并给原版主记警告
+func (s *Server) handleBulletinResolveAppeal(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req ProposalRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req BulletinResolveAppealRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.Title == "" {
-		s.writeError(w, http.StatusBadRequest, "title required")
+	if s.BulletinResolveAppealFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "bulletin moderation not available")
 		return
 	}
-	
-	proposalID := fmt.Sprintf("prop_%d", time.Now().UnixNano())
-	if s.VotingCreateFunc != nil {
-		var err error
-		proposalID, err = s.VotingCreateFunc(req.Title, req.Type, req.Description, req.Target)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	result, err := s.BulletinResolveAppealFunc(s.requestActor(r), req.MessageID, req.Decision == "overturn", req.Note)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"proposal_id": proposalID,
-		"status":      "created",
-	})
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleVotingList(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinAppeals(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	status := getQueryParam(r, "status", "")
-	
-	var proposals []map[string]interface{}
-	if s.VotingListFunc != nil {
-		proposals = s.VotingListFunc(status)
-	}
-	if proposals == nil {
-		proposals = []map[string]interface{}{}
+	appeals := []map[string]interface{}{}
+	if s.BulletinAppealsFunc != nil {
+		if list := s.BulletinAppealsFunc(); list != nil {
+			appeals = list
+		}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"proposals": proposals,
+		"appeals": appeals,
+		"count":   len(appeals),
 	})
 }
 
-func (s *Server) handleVotingGet(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleBulletinStrikes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	proposalID := extractPathParam(r, "/api/v1/voting/proposal/")
-	if proposalID == "" || proposalID == "create" || proposalID == "list" || proposalID == "finalize" {
-		s.writeError(w, http.StatusBadRequest, "proposal_id required")
+	nodeID := extractPathParam(r, "/api/v1/bulletin/strikes/")
+	if nodeID == "" {
+		s.writeError(w, http.StatusBadRequest, "node_id required")
 		return
 	}
 	
-	if s.VotingGetFunc != nil {
-		proposal, err := s.VotingGetFunc(proposalID)
-		if err != nil {
-			s.writeError(w, http.StatusNotFound, err.Error())
-			return
+	strikes := []map[string]interface{}{}
+	if s.BulletinStrikesFunc != nil {
+		if list := s.BulletinStrikesFunc(nodeID); list != nil {
+			strikes = list
 		}
-		s.writeJSON(w, http.StatusOK, proposal)
-		return
 	}
 	
-	s.writeError(w, http.StatusNotFound, "proposal not found")
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": nodeID,
+		"strikes": strikes,
+	})
 }
 
-func (s *Server) handleVotingVote(w http.ResponseWriter, r *http.Request) {
+// ============== 任务扩展 ==============
+
+func (s *Server) handleTaskAccept(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req VoteRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if s.rejectInMaintenance(w) {
 		return
 	}
 	
-	if req.ProposalID == "" {
-		s.writeError(w, http.StatusBadRequest, "proposal_id required")
+	var req TaskAcceptRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if s.VotingVoteFunc != nil {
-		if err := s.VotingVoteFunc(req.ProposalID, req.Vote); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
+	if s.AcceptTaskFunc != nil {
+		result, err := s.AcceptTaskFunc(&req)
+		if err != nil {
+			s.writeError(w, http.StatusConflict, err.Error())
 			return
 		}
+		s.meterTaskStart(r, req.TaskID)
+		s.PublishEvent(EventTask, map[string]interface{}{"action": "accepted", "task_id": req.TaskID})
+		s.writeJSON(w, http.StatusOK, result)
+		return
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "voted",
+		"status":  "accepted",
+		"task_id": req.TaskID,
 	})
 }
 
-func (s *Server) handleVotingFinalize(w http.ResponseWriter, r *http.Request) {
+// handleTaskSubmit 执行方提交任务结果
+func (s *Server) handleTaskSubmit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		ProposalID string `json:"proposal_id"`
-	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req TaskSubmitRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	result := "unknown"
-	if s.VotingFinalizeFunc != nil {
-		var err error
-		result, err = s.VotingFinalizeFunc(req.ProposalID)
+	if s.TaskSubmitFunc != nil {
+		result, err := s.TaskSubmitFunc(&req)
 		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
+			s.writeError(w, http.StatusConflict, err.Error())
 			return
 		}
+		s.meterTaskFinish(req.TaskID)
+		s.PublishEvent(EventTask, map[string]interface{}{"action": "submitted", "task_id": req.TaskID})
+		s.writeJSON(w, http.StatusOK, result)
+		return
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"result": result,
+		"status":  "submitted",
+		"task_id": req.TaskID,
 	})
 }
 
-// ============== 超级节点 ==============
-
-func (s *Server) handleSuperNodeList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleTaskConfirm 委托方验收执行方的交付
+func (s *Server) handleTaskConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var supernodes []map[string]interface{}
-	if s.SuperNodeListFunc != nil {
-		supernodes = s.SuperNodeListFunc()
-	}
-	if supernodes == nil {
-		supernodes = []map[string]interface{}{}
+	var req TaskConfirmRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"supernodes": supernodes,
-	})
-}
-
-func (s *Server) handleSuperNodeCandidates(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	if s.TaskConfirmFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task confirmation not available")
 		return
 	}
 	
-	var candidates []map[string]interface{}
-	if s.SuperNodeCandidatesFunc != nil {
-		candidates = s.SuperNodeCandidatesFunc()
-	}
-	if candidates == nil {
-		candidates = []map[string]interface{}{}
+	result, err := s.TaskConfirmFunc(req.TaskID)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
 	}
-	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"candidates": candidates,
-	})
+	s.PublishEvent(EventTask, map[string]interface{}{"action": "verified", "task_id": req.TaskID})
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleSuperNodeApply(w http.ResponseWriter, r *http.Request) {
+// handleTaskSettle 委托方结算已验收的任务
+func (s *Server) handleTaskSettle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req SuperNodeApplyRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req TaskConfirmRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if s.SuperNodeApplyFunc != nil {
-		if err := s.SuperNodeApplyFunc(req.Stake); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if s.TaskSettleFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task settlement not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "applied",
-	})
+	result, err := s.TaskSettleFunc(req.TaskID)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.PublishEvent(EventTask, map[string]interface{}{"action": "settled", "task_id": req.TaskID})
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleSuperNodeWithdraw(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleTaskList 任务列表，可用 ?status= 过滤
+func (s *Server) handleTaskList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	if s.SuperNodeWithdrawFunc != nil {
-		if err := s.SuperNodeWithdrawFunc(); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
+	status := getQueryParam(r, "status", "")
+	limit := getIntQueryParam(r, "limit", 20)
+	
+	tasks := []map[string]interface{}{}
+	if s.TaskListFunc != nil {
+		list, err := s.TaskListFunc(status, limit)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
+		if list != nil {
+			tasks = list
+		}
 	}
 	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "withdrawn",
+		"tasks": tasks,
+		"count": len(tasks),
 	})
 }
 
-func (s *Server) handleSuperNodeVote(w http.ResponseWriter, r *http.Request) {
+// ============== 任务转包 ==============
+
+// handleTaskSubcontract 将已接受任务的一部分转包给其他节点
+func (s *Server) handleTaskSubcontract(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req SuperNodeVoteRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if s.rejectInMaintenance(w) {
 		return
 	}
 	
-	if req.Candidate == "" {
-		s.writeError(w, http.StatusBadRequest, "candidate required")
+	var req TaskSubcontractRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if s.SuperNodeVoteFunc != nil {
-		if err := s.SuperNodeVoteFunc(req.Candidate); err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if s.TaskSubcontractFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task subcontracting not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status": "voted",
-	})
+	result, err := s.TaskSubcontractFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleSuperNodeElectionStart(w http.ResponseWriter, r *http.Request) {
+// handleTaskSubcontractFail 报告转包子任务失败，处罚沿转包链向上传递
+func (s *Server) handleTaskSubcontractFail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	electionID := fmt.Sprintf("elec_%d", time.Now().UnixNano())
-	if s.SuperNodeStartElection != nil {
-		var err error
-		electionID, err = s.SuperNodeStartElection()
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	var req struct {
+		TaskID string `json:"task_id" validate:"required"`
+		Reason string `json:"reason" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskSubcontractFailFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task subcontracting not available")
+		return
 	}
 	
+	penalties, err := s.TaskSubcontractFailFunc(req.TaskID, req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"election_id": electionID,
-		"status":      "started",
+		"task_id":   req.TaskID,
+		"penalties": penalties,
 	})
 }
 
-func (s *Server) handleSuperNodeElectionFinalize(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleTaskChain 查询任务的转包链（从原始任务到指定任务）
+func (s *Server) handleTaskChain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		ElectionID string `json:"election_id"`
-	}
-	if err := parseBody(r, &req); err != nil {
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id is required")
+		return
+	}
+	
+	if s.TaskSubcontractChainFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task subcontracting not available")
+		return
+	}
+	
+	chain, err := s.TaskSubcontractChainFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task_id": taskID,
+		"chain":   chain,
+		"depth":   len(chain) - 1,
+	})
+}
+
+// ============== 任务验收 ==============
+
+// handleTaskAudit 超级节点对采用审计验收的任务给出结论
+func (s *Server) handleTaskAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskAuditRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskAuditFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task audit not available")
+		return
+	}
+	
+	record, err := s.TaskAuditFunc(req.TaskID, req.Decision == "approve", req.Note)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, record)
+}
+
+// handleTaskVerification 查询任务的验收记录
+func (s *Server) handleTaskVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id is required")
+		return
+	}
+	
+	if s.TaskVerificationFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task verification not available")
+		return
+	}
+	
+	record, err := s.TaskVerificationFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, record)
+}
+
+// handleTaskReviewers 查询任务的评审人分配
+func (s *Server) handleTaskReviewers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id is required")
+		return
+	}
+	
+	if s.TaskReviewersFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reviewer assignment not available")
+		return
+	}
+	
+	assignment, err := s.TaskReviewersFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, assignment)
+}
+
+// handleTaskReviewersAssign 为任务分配 K 名评审人
+func (s *Server) handleTaskReviewersAssign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskReviewAssignRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskReviewersAssignFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reviewer assignment not available")
+		return
+	}
+	
+	assignment, err := s.TaskReviewersAssignFunc(req.TaskID, req.Count)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, assignment)
+}
+
+// handleTaskReviewRespond 本节点接受或拒绝评审分配，拒绝后自动补选
+func (s *Server) handleTaskReviewRespond(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskReviewRespondRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskReviewRespondFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reviewer assignment not available")
+		return
+	}
+	
+	assignment, err := s.TaskReviewRespondFunc(req.TaskID, req.Decision == "accept")
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, assignment)
+}
+
+// handleTaskProgress 查询任务进度 /api/v1/task/progress/{task_id}
+func (s *Server) handleTaskProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := strings.TrimPrefix(r.URL.Path, "/api/v1/task/progress/")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id required")
+		return
+	}
+	
+	if s.TaskProgressFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task progress not available")
+		return
+	}
+	
+	progress, err := s.TaskProgressFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, progress)
+}
+
+// handleTaskProgressReport 执行方报告进度
+func (s *Server) handleTaskProgressReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskProgressRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskProgressReportFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task progress not available")
+		return
+	}
+	
+	update, err := s.TaskProgressReportFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, update)
+}
+
+// handleTaskStorageHold 存储方登记为任务持有的数据文件，返回文件的 Merkle 根
+func (s *Server) handleTaskStorageHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskStorageRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskStorageHoldFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "storage proofs not available")
+		return
+	}
+	
+	result, err := s.TaskStorageHoldFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleTaskStorageCommit 委托方按原始文件登记存储承诺，之后定期挑战执行方
+func (s *Server) handleTaskStorageCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskStorageRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskStorageCommitFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "storage proofs not available")
+		return
+	}
+	
+	commitment, err := s.TaskStorageCommitFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, commitment)
+}
+
+// handleTaskStorageChallenge 立即挑战存储方并返回挑战结果
+func (s *Server) handleTaskStorageChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskStorageChallengeRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.TaskStorageChallengeFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "storage proofs not available")
+		return
+	}
+	
+	challenge, err := s.TaskStorageChallengeFunc(req.TaskID)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, challenge)
+}
+
+// handleTaskStorageChallenges 查询存储承诺及其挑战记录 /api/v1/task/storage/challenges?task_id=
+func (s *Server) handleTaskStorageChallenges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id is required")
+		return
+	}
+	
+	if s.TaskStorageChallengesFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "storage proofs not available")
+		return
+	}
+	
+	result, err := s.TaskStorageChallengesFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleTaskOffers 按优先级列出待接任务邀约 /api/v1/task/offers?limit=
+func (s *Server) handleTaskOffers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.TaskOffersFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task offers not available")
+		return
+	}
+	
+	offers := s.TaskOffersFunc(getIntQueryParam(r, "limit", 50))
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"offers": offers,
+		"count":  len(offers),
+	})
+}
+
+// handleTaskOfferScore 查询单个邀约的评分明细 /api/v1/task/offers/{task_id}
+func (s *Server) handleTaskOfferScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := strings.TrimPrefix(r.URL.Path, "/api/v1/task/offers/")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id required")
+		return
+	}
+	
+	if s.TaskOfferScoreFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task offers not available")
+		return
+	}
+	
+	score, err := s.TaskOfferScoreFunc(taskID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, score)
+}
+
+// handleTaskOfferPolicy 查询（GET）或调整（POST）邀约优先级策略
+func (s *Server) handleTaskOfferPolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if s.TaskOfferPolicyFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "task offers not available")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.TaskOfferPolicyFunc())
+	case http.MethodPost:
+		var req TaskOfferPolicyRequest
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+		if s.TaskOfferPolicySetFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "task offers not available")
+			return
+		}
+		policy, err := s.TaskOfferPolicySetFunc(&req)
+		if err != nil {
+			s.writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, policy)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// ============== 任务模板 ==============
+
+// handleTaskFromTemplate 根据模板创建任务，参数按模板 schema 校验
+func (s *Server) handleTaskFromTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.rejectInMaintenance(w) {
+		return
+	}
+	
+	var req TaskFromTemplateRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TaskFromTemplateFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task templates not available")
+		return
+	}
+	
+	task, err := s.TaskFromTemplateFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) handleTaskTemplateList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var templates []map[string]interface{}
+	if s.TaskTemplateListFunc != nil {
+		templates = s.TaskTemplateListFunc()
+	}
+	if templates == nil {
+		templates = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+func (s *Server) handleTaskTemplateSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var template map[string]interface{}
+	if !s.decodeBody(w, r, &template) {
+		return
+	}
+	if s.TaskTemplateSaveFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task templates not available")
+		return
+	}
+	
+	saved, err := s.TaskTemplateSaveFunc(template)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, saved)
+}
+
+// handleTaskTemplateShare 通过留言板共享模板
+func (s *Server) handleTaskTemplateShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskTemplateIDRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TaskTemplateShareFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task templates not available")
+		return
+	}
+	
+	messageID, err := s.TaskTemplateShareFunc(req.TemplateID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"template_id": req.TemplateID,
+		"message_id":  messageID,
+	})
+}
+
+func (s *Server) handleTaskTemplateDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TaskTemplateIDRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TaskTemplateDeleteFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "task templates not available")
+		return
+	}
+	
+	if err := s.TaskTemplateDeleteFunc(req.TemplateID); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"template_id": req.TemplateID,
+		"deleted":     true,
+	})
+}
+
+// ============== 文件传输 ==============
+
+func (s *Server) handleTransferSend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TransferSendRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TransferSendFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "file transfer not available")
+		return
+	}
+	
+	transfer, err := s.TransferSendFunc(req.PeerID, req.Path)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, transfer)
+}
+
+func (s *Server) handleTransferStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	transferID := extractPathParam(r, "/api/v1/transfer/status/")
+	if transferID == "" {
+		s.writeError(w, http.StatusBadRequest, "transfer_id required")
+		return
+	}
+	if s.TransferStatusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "file transfer not available")
+		return
+	}
+	
+	transfer, err := s.TransferStatusFunc(transferID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, transfer)
+}
+
+func (s *Server) handleTransferPause(w http.ResponseWriter, r *http.Request) {
+	s.handleTransferAction(w, r, s.TransferPauseFunc, "paused")
+}
+
+func (s *Server) handleTransferResume(w http.ResponseWriter, r *http.Request) {
+	s.handleTransferAction(w, r, s.TransferResumeFunc, "resumed")
+}
+
+// handleTransferAccept 接受等待中的入站传输
+func (s *Server) handleTransferAccept(w http.ResponseWriter, r *http.Request) {
+	s.handleTransferAction(w, r, s.TransferAcceptFunc, "accepted")
+}
+
+// handleTransferReject 拒绝等待中的入站传输
+func (s *Server) handleTransferReject(w http.ResponseWriter, r *http.Request) {
+	s.handleTransferAction(w, r, s.TransferRejectFunc, "rejected")
+}
+
+// handleTransferAction 暂停/恢复/接受/拒绝传输的公共处理
+func (s *Server) handleTransferAction(w http.ResponseWriter, r *http.Request, action func(string) error, result string) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req TransferIDRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if action == nil {
+		s.writeError(w, http.StatusNotImplemented, "file transfer not available")
+		return
+	}
+	
+	if err := action(req.TransferID); err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"transfer_id": req.TransferID,
+		"status":      result,
+	})
+}
+
+func (s *Server) handleTransferList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	status := getQueryParam(r, "status", "")
+	
+	var transfers []map[string]interface{}
+	if s.TransferListFunc != nil {
+		transfers = s.TransferListFunc(status)
+	}
+	if transfers == nil {
+		transfers = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"transfers": transfers,
+		"count":     len(transfers),
+	})
+}
+
+// ============== 合规保全 ==============
+
+func (s *Server) handleRetentionHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req RetentionHoldRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.RetentionHoldFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "retention holds not available")
+		return
+	}
+	
+	hold, err := s.RetentionHoldFunc(s.requestActor(r), &req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, hold)
+}
+
+func (s *Server) handleRetentionRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req RetentionReleaseRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.RetentionReleaseFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "retention holds not available")
+		return
+	}
+	
+	hold, err := s.RetentionReleaseFunc(req.HoldID, s.requestActor(r), req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, hold)
+}
+
+func (s *Server) handleRetentionHolds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	includeReleased := getQueryParam(r, "all", "") == "true"
+	
+	var holds []map[string]interface{}
+	if s.RetentionListFunc != nil {
+		holds = s.RetentionListFunc(includeReleased)
+	}
+	if holds == nil {
+		holds = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"holds": holds,
+		"count": len(holds),
+	})
+}
+
+func (s *Server) handleRetentionAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.RetentionAuditFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "retention holds not available")
+		return
+	}
+	
+	entries, err := s.RetentionAuditFunc()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []map[string]interface{}{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// ============== 声誉扩展 ==============
+
+func (s *Server) handleReputationRanking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	limit := getIntQueryParam(r, "limit", 10)
+	
+	var rankings []map[string]interface{}
+	if s.Reputation != nil {
+		rankings = s.Reputation.ReputationRanking(limit)
+	}
+	if rankings == nil {
+		rankings = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rankings": rankings,
+	})
+}
+
+func (s *Server) handleReputationHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	limit := getIntQueryParam(r, "limit", 20)
+	
+	var history []map[string]interface{}
+	if s.Reputation != nil {
+		history = s.Reputation.ReputationHistory(nodeID, limit)
+	}
+	if history == nil {
+		history = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": nodeID,
+		"history": history,
+	})
+}
+
+// handleReputationWebhook 外部评分系统回调（签名校验由 ReputationWebhookFunc 完成）
+func (s *Server) handleReputationWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.ReputationWebhookFunc == nil {
+		s.writeError(w, http.StatusNotFound, "reputation webhook not configured")
+		return
+	}
+	
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	
+	result, err := s.ReputationWebhookFunc(r.Header, body)
+	if err != nil {
+		s.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// WebhookKeysPath Webhook 签名公钥的发布地址（免 Token）
+const WebhookKeysPath = "/.well-known/daan-webhook-keys"
+
+// handleWebhookKeys 发布 Webhook 推送签名公钥，接收方按请求头中的密钥ID选择公钥验签
+func (s *Server) handleWebhookKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.WebhookKeysFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "webhook signing key not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": s.config.NodeID,
+		"keys":    s.WebhookKeysFunc(),
+	})
+}
+
+// handleWebhookKeyRotate 轮换 Webhook 签名密钥，旧公钥在宽限期内继续发布
+func (s *Server) handleWebhookKeyRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.WebhookKeyRotateFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "webhook signing key not available")
+		return
+	}
+	result, err := s.WebhookKeyRotateFunc()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// ============== 信任网 ==============
+
+// handleTrustEndorse 为其他节点的密钥签发背书
+func (s *Server) handleTrustEndorse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req EndorseRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TrustEndorseFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "endorsements not available")
+		return
+	}
+	
+	endorsement, err := s.TrustEndorseFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, endorsement)
+}
+
+// handleTrustEndorsements 查询节点收到的背书
+func (s *Server) handleTrustEndorsements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	
+	var endorsements []map[string]interface{}
+	if s.TrustEndorsementsFunc != nil {
+		endorsements = s.TrustEndorsementsFunc(nodeID)
+	}
+	if endorsements == nil {
+		endorsements = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":      nodeID,
+		"endorsements": endorsements,
+		"count":        len(endorsements),
+	})
+}
+
+// handleTrustDistance 查询本节点到目标节点的背书距离（-1 表示不可达）
+func (s *Server) handleTrustDistance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", "")
+	if nodeID == "" {
+		s.writeError(w, http.StatusBadRequest, "node_id is required")
+		return
+	}
+	
+	distance := -1
+	if s.TrustDistanceFunc != nil {
+		distance = s.TrustDistanceFunc(nodeID)
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":  nodeID,
+		"distance": distance,
+		"endorsed": distance > 0,
+	})
+}
+
+// handleTrustProofCreate 签发外部身份证明，返回需要发布的令牌
+func (s *Server) handleTrustProofCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req IdentityProofRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if req.Kind != "dns_txt" && req.Location == "" {
+		s.writeValidationError(w, missingFields("location"))
+		return
+	}
+	if s.TrustProofCreateFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "identity proofs not available")
+		return
+	}
+	
+	proof, err := s.TrustProofCreateFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, proof)
+}
+
+// handleTrustProofVerify 抓取并重新校验证明
+func (s *Server) handleTrustProofVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		ID string `json:"id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.TrustProofVerifyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "identity proofs not available")
+		return
+	}
+	
+	proof, err := s.TrustProofVerifyFunc(req.ID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, proof)
+}
+
+// handleTrustProofImport 导入其他节点公布的证明记录（校验通过才保存）
+func (s *Server) handleTrustProofImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.TrustProofImportFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "identity proofs not available")
+		return
+	}
+	
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	
+	proof, err := s.TrustProofImportFunc(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, proof)
+}
+
+// handleReachability 查询节点的公共可达性分数及其证明
+func (s *Server) handleReachability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := extractPathParam(r, "/api/v1/reachability/")
+	if nodeID == "" {
+		s.writeError(w, http.StatusBadRequest, "node_id required")
+		return
+	}
+	if s.ReachabilityFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reachability attestations not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.ReachabilityFunc(nodeID))
+}
+
+// handleReachabilityTest 对节点发起回拨测试并签发可达性证明
+func (s *Server) handleReachabilityTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		NodeID string `json:"node_id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.ReachabilityTestFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reachability attestations not available")
+		return
+	}
+	attestation, err := s.ReachabilityTestFunc(req.NodeID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, attestation)
+}
+
+// handleReachabilityImport 导入其他节点签发的可达性证明（验签通过才计入）
+func (s *Server) handleReachabilityImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.ReachabilityImportFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "reachability attestations not available")
+		return
+	}
+	
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	
+	score, err := s.ReachabilityImportFunc(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, score)
+}
+
+// DiscoveryAnnounceRequest 宣告本节点提供的技能
+type DiscoveryAnnounceRequest struct {
+	Skills []string `json:"skills" validate:"required,min=1,max=32"`
+}
+
+// handleDiscoveryAnnounce GET 列出本节点宣告的技能，POST 在 DHT 中宣告技能并定期重新宣告
+func (s *Server) handleDiscoveryAnnounce(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if s.DiscoveryAnnouncementsFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "discovery not available")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"announcements": s.DiscoveryAnnouncementsFunc(),
+		})
+	case http.MethodPost:
+		var req DiscoveryAnnounceRequest
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+		if s.DiscoveryAnnounceFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "discovery not available")
+			return
+		}
+		announced := make([]map[string]interface{}, 0, len(req.Skills))
+		for _, skill := range req.Skills {
+			a, err := s.DiscoveryAnnounceFunc(skill)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			announced = append(announced, a)
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"announcements": announced,
+		})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleDiscoveryWithdraw 停止宣告技能
+func (s *Server) handleDiscoveryWithdraw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		Skill string `json:"skill" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.DiscoveryWithdrawFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "discovery not available")
+		return
+	}
+	if err := s.DiscoveryWithdrawFunc(req.Skill); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"skill":     req.Skill,
+		"withdrawn": true,
+	})
+}
+
+// handleDiscoveryFind 按技能查找提供者
+// GET /api/v1/discovery/find?skill=translation&limit=20
+func (s *Server) handleDiscoveryFind(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	skill := r.URL.Query().Get("skill")
+	if skill == "" {
+		s.writeError(w, http.StatusBadRequest, "skill required")
+		return
+	}
+	limit := getIntQueryParam(r, "limit", 20)
+	if s.DiscoveryFindFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "discovery not available")
+		return
+	}
+	providers, err := s.DiscoveryFindFunc(skill, limit)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"skill":     skill,
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+// handleStats 各模块统计的汇总快照
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.StatsFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "stats not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.StatsFunc())
+}
+
+// handleStatsModule 单个模块的统计
+func (s *Server) handleStatsModule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	module := extractPathParam(r, "/api/v1/stats/")
+	if module == "" {
+		s.writeError(w, http.StatusBadRequest, "module required")
+		return
+	}
+	if s.StatsModuleFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "stats not available")
+		return
+	}
+	result, err := s.StatsModuleFunc(module)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleTrustProofs 查询节点的身份证明及其带来的起始声誉
+func (s *Server) handleTrustProofs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	if s.TrustProofsFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "identity proofs not available")
+		return
+	}
+	
+	s.writeJSON(w, http.StatusOK, s.TrustProofsFunc(nodeID))
+}
+
+// ============== 入站连接准入 ==============
+
+// handleAdmissionStatus 查询入站准入状态和最近的判定
+func (s *Server) handleAdmissionStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.AdmissionStatusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "admission not available")
+		return
+	}
+	
+	s.writeJSON(w, http.StatusOK, s.AdmissionStatusFunc())
+}
+
+// handleAdmissionInvite 邀请节点连入本节点
+func (s *Server) handleAdmissionInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req AdmissionInviteRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.AdmissionInviteFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "admission not available")
+		return
+	}
+	
+	invitation, err := s.AdmissionInviteFunc(&req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, invitation)
+}
+
+// handleAdmissionRevoke 撤销本节点签发的邀请
+func (s *Server) handleAdmissionRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		ID string `json:"id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.AdmissionRevokeFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "admission not available")
+		return
+	}
+	
+	if err := s.AdmissionRevokeFunc(req.ID); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"id": req.ID, "status": "revoked"})
+}
+
+// handleAdmissionInvitations 列出有效邀请，peer_id 非空时只返回邀请该节点的
+func (s *Server) handleAdmissionInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var invitations []map[string]interface{}
+	if s.AdmissionInvitationsFunc != nil {
+		invitations = s.AdmissionInvitationsFunc(getQueryParam(r, "peer_id", ""))
+	}
+	if invitations == nil {
+		invitations = []map[string]interface{}{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"invitations": invitations})
+}
+
+// handleAdmissionImport 导入受信节点签发的邀请
+func (s *Server) handleAdmissionImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.AdmissionImportFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "admission not available")
+		return
+	}
+	
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		s.writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 	
-	var elected []string
-	if s.SuperNodeFinalizeFunc != nil {
-		var err error
-		elected, err = s.SuperNodeFinalizeFunc(req.ElectionID)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	invitation, err := s.AdmissionImportFunc(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, invitation)
+}
+
+// ============== 指责扩展 ==============
+
+func (s *Server) handleAccusationDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	accID := extractPathParam(r, "/api/v1/accusation/detail/")
+	if accID == "" {
+		s.writeError(w, http.StatusBadRequest, "accusation_id required")
+		return
+	}
+	
+	if s.AccusationDetailFunc != nil {
+		detail, err := s.AccusationDetailFunc(accID)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, detail)
+		return
+	}
+	
+	s.writeError(w, http.StatusNotFound, "accusation not found")
+}
+
+func (s *Server) handleAccusationAnalyze(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", "")
+	if nodeID == "" {
+		s.writeError(w, http.StatusBadRequest, "node_id required")
+		return
+	}
+	
+	var analysis map[string]interface{}
+	if s.AccusationAnalyzeFunc != nil {
+		analysis = s.AccusationAnalyzeFunc(nodeID)
+	}
+	if analysis == nil {
+		analysis = map[string]interface{}{
+			"node_id":        nodeID,
+			"total_received": 0,
+			"credibility":    1.0,
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, analysis)
+}
+
+// ============== 激励系统 ==============
+
+func (s *Server) handleIncentiveAward(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req IncentiveAwardRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	reward := 0.0
+	if s.IncentiveAwardFunc != nil {
+		var err error
+		reward, err = s.IncentiveAwardFunc(req.NodeID, req.TaskType)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reward": reward,
+	})
+}
+
+func (s *Server) handleIncentivePropagate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req IncentivePropagateRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	propagatedTo := 0
+	if s.IncentivePropagateFunc != nil {
+		var err error
+		propagatedTo, err = s.IncentivePropagateFunc(req.Target, req.Delta)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"propagated_to": propagatedTo,
+	})
+}
+
+func (s *Server) handleIncentiveHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	limit := getIntQueryParam(r, "limit", 20)
+	
+	var rewards []map[string]interface{}
+	if s.IncentiveHistoryFunc != nil {
+		rewards = s.IncentiveHistoryFunc(nodeID, limit)
+	}
+	if rewards == nil {
+		rewards = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rewards": rewards,
+	})
+}
+
+func (s *Server) handleIncentiveTolerance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", s.config.NodeID)
+	
+	tolerance, maxTolerance := 0, 10
+	if s.IncentiveToleranceFunc != nil {
+		tolerance, maxTolerance = s.IncentiveToleranceFunc(nodeID)
+	}
+	
+	result := map[string]interface{}{
+		"node_id":   nodeID,
+		"tolerance": tolerance,
+		"max":       maxTolerance,
+	}
+	if s.IncentiveToleranceInputsFunc != nil {
+		if inputs := s.IncentiveToleranceInputsFunc(nodeID); inputs != nil {
+			result["inputs"] = inputs
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// ============== 投票系统 ==============
+
+func (s *Server) handleVotingCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req ProposalRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	proposalID := fmt.Sprintf("prop_%d", time.Now().UnixNano())
+	if s.VotingCreateFunc != nil {
+		var err error
+		proposalID, err = s.VotingCreateFunc(&req)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"proposal_id": proposalID,
+		"status":      "created",
+	})
+}
+
+func (s *Server) handleVotingList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	status := getQueryParam(r, "status", "")
+	
+	var proposals []map[string]interface{}
+	if s.VotingListFunc != nil {
+		proposals = s.VotingListFunc(status)
+	}
+	if proposals == nil {
+		proposals = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"proposals": proposals,
+	})
+}
+
+func (s *Server) handleVotingGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	proposalID := extractPathParam(r, "/api/v1/voting/proposal/")
+	if proposalID == "" || proposalID == "create" || proposalID == "list" || proposalID == "finalize" {
+		s.writeError(w, http.StatusBadRequest, "proposal_id required")
+		return
+	}
+	
+	if s.VotingGetFunc != nil {
+		proposal, err := s.VotingGetFunc(proposalID)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, proposal)
+		return
+	}
+	
+	s.writeError(w, http.StatusNotFound, "proposal not found")
+}
+
+func (s *Server) handleVotingVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req VoteRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.VotingVoteFunc != nil {
+		if err := s.VotingVoteFunc(req.ProposalID, req.Vote); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "voted",
+	})
+}
+
+func (s *Server) handleVotingFinalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		ProposalID string `json:"proposal_id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	result := "unknown"
+	if s.VotingFinalizeFunc != nil {
+		var err error
+		result, err = s.VotingFinalizeFunc(req.ProposalID)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"result": result,
+	})
+}
+
+// handleVotingTally 查询提案当前计票，含每条委托的去向
+func (s *Server) handleVotingTally(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	proposalID := extractPathParam(r, "/api/v1/voting/tally/")
+	if proposalID == "" {
+		s.writeError(w, http.StatusBadRequest, "proposal_id required")
+		return
+	}
+	if s.VotingTallyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "voting not available")
+		return
+	}
+	
+	tally, err := s.VotingTallyFunc(proposalID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, tally)
+}
+
+// handleVotingDelegate 把本节点的投票权委托给其他节点
+func (s *Server) handleVotingDelegate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req DelegationRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.VotingDelegateFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "vote delegation not available")
+		return
+	}
+	
+	delegation, err := s.VotingDelegateFunc(req.Delegate, req.Scope)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, delegation)
+}
+
+// handleVotingRevokeDelegation 撤销本节点在指定范围内的委托
+func (s *Server) handleVotingRevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req RevokeDelegationRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.VotingRevokeDelegationFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "vote delegation not available")
+		return
+	}
+	
+	if err := s.VotingRevokeDelegationFunc(req.Scope); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "revoked",
+		"scope":  req.Scope,
+	})
+}
+
+// handleVotingDelegations 列出有效委托，node_id 指定时只列出与该节点相关的
+func (s *Server) handleVotingDelegations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var delegations []map[string]interface{}
+	if s.VotingDelegationsFunc != nil {
+		delegations = s.VotingDelegationsFunc(getQueryParam(r, "node_id", ""))
+	}
+	if delegations == nil {
+		delegations = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"delegations": delegations,
+	})
+}
+
+// handleVotingCanary 参数提案的金丝雀发布状态和对比结果
+func (s *Server) handleVotingCanary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	proposalID := extractPathParam(r, "/api/v1/voting/canary/")
+	if proposalID == "" {
+		s.writeError(w, http.StatusBadRequest, "proposal_id required")
+		return
+	}
+	if s.VotingCanaryFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "canary rollout not available")
+		return
+	}
+	
+	canary, err := s.VotingCanaryFunc(proposalID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, canary)
+}
+
+// handleVotingCanaryOptIn 设置本节点是否作为参数变更的金丝雀节点
+func (s *Server) handleVotingCanaryOptIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req CanaryOptInRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.VotingCanaryOptInFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "canary rollout not available")
+		return
+	}
+	
+	nodes, err := s.VotingCanaryOptInFunc(req.Enabled)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":      req.Enabled,
+		"canary_nodes": nodes,
+	})
+}
+
+// ============== 超级节点 ==============
+
+func (s *Server) handleSuperNodeList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var supernodes []map[string]interface{}
+	if s.SuperNodeListFunc != nil {
+		supernodes = s.SuperNodeListFunc()
+	}
+	if supernodes == nil {
+		supernodes = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"supernodes": supernodes,
+	})
+}
+
+func (s *Server) handleSuperNodeCandidates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var candidates []map[string]interface{}
+	if s.SuperNodeCandidatesFunc != nil {
+		candidates = s.SuperNodeCandidatesFunc()
+	}
+	if candidates == nil {
+		candidates = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"candidates": candidates,
+	})
+}
+
+func (s *Server) handleSuperNodeApply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.rejectInMaintenance(w) {
+		return
+	}
+	
+	var req SuperNodeApplyRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.SuperNodeApplyFunc != nil {
+		if err := s.SuperNodeApplyFunc(req.Stake); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "applied",
+	})
+}
+
+func (s *Server) handleSuperNodeWithdraw(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.SuperNodeWithdrawFunc != nil {
+		if err := s.SuperNodeWithdrawFunc(); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "withdrawn",
+	})
+}
+
+func (s *Server) handleSuperNodeVote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.rejectInMaintenance(w) {
+		return
+	}
+	
+	var req SuperNodeVoteRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.SuperNodeVoteFunc != nil {
+		if err := s.SuperNodeVoteFunc(req.Candidate); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "voted",
+	})
+}
+
+func (s *Server) handleSuperNodeElectionStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.rejectInMaintenance(w) {
+		return
+	}
+	
+	electionID := fmt.Sprintf("elec_%d", time.Now().UnixNano())
+	if s.SuperNodeStartElection != nil {
+		var err error
+		electionID, err = s.SuperNodeStartElection()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"election_id": electionID,
+		"status":      "started",
+	})
+}
+
+func (s *Server) handleSuperNodeElectionFinalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		ElectionID string `json:"election_id" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	var elected []string
+	if s.SuperNodeFinalizeFunc != nil {
+		var err error
+		elected, err = s.SuperNodeFinalizeFunc(req.ElectionID)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if elected == nil {
+		elected = []string{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"elected": elected,
+		"status":  "finalized",
+	})
+}
+
+func (s *Server) handleSuperNodeAuditSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req AuditSubmitRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	auditID := fmt.Sprintf("audit_%d", time.Now().UnixNano())
+	if s.SuperNodeAuditSubmit != nil {
+		var err error
+		auditID, err = s.SuperNodeAuditSubmit(req.Target, req.Passed, req.Details)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"audit_id": auditID,
+		"status":   "submitted",
+	})
+}
+
+func (s *Server) handleSuperNodeAuditResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	target := getQueryParam(r, "target", "")
+	if target == "" {
+		s.writeError(w, http.StatusBadRequest, "target required")
+		return
+	}
+	
+	passRate := 0.0
+	if s.SuperNodeAuditResult != nil {
+		var err error
+		passRate, err = s.SuperNodeAuditResult(target)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"target":    target,
+		"pass_rate": passRate,
+	})
+}
+
+// handleSuperNodeScoreboard 超级节点履职记分板
+func (s *Server) handleSuperNodeScoreboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", "")
+	
+	var scores []map[string]interface{}
+	if s.SuperNodeScoreboardFunc != nil {
+		var err error
+		scores, err = s.SuperNodeScoreboardFunc(nodeID)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	}
+	if scores == nil {
+		scores = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scoreboard": scores,
+	})
+}
+
+// ============== 创世节点 ==============
+
+func (s *Server) handleGenesisInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var info map[string]interface{}
+	if s.GenesisInfoFunc != nil {
+		info = s.GenesisInfoFunc()
+	}
+	if info == nil {
+		info = map[string]interface{}{
+			"genesis_id": "unknown",
+			"created_at": time.Now().Format(time.RFC3339),
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleGenesisInviteCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req GenesisInviteRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	invitationID := fmt.Sprintf("inv_%d", time.Now().UnixNano())
+	if s.GenesisCreateInviteFunc != nil {
+		var err error
+		invitationID, err = s.GenesisCreateInviteFunc(req.ForPubkey, req.PeerID)
+		if err != nil {
+			s.writeServiceError(w, err)
+			return
+		}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"invitation_id": invitationID,
+		"status":        "created",
+	})
+}
+
+func (s *Server) handleGenesisInviteVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		Invitation string `json:"invitation" validate:"required"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	valid := false
+	inviter := ""
+	result := map[string]interface{}{}
+	if s.GenesisVerifyInviteFunc != nil {
+		var err error
+		valid, inviter, err = s.GenesisVerifyInviteFunc(req.Invitation)
+		if err != nil {
+			// 无效、过期或已使用的邀请函报告原因
+			valid = false
+			result["reason"] = err.Error()
+		}
+	}
+	
+	result["valid"] = valid
+	result["inviter"] = inviter
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGenesisJoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req GenesisJoinRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	nodeID := ""
+	var neighbors []string
+	if s.GenesisJoinFunc != nil {
+		var err error
+		nodeID, neighbors, err = s.GenesisJoinFunc(&req)
+		if err != nil {
+			s.writeServiceError(w, err)
+			return
+		}
+	}
+	if neighbors == nil {
+		neighbors = []string{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":   nodeID,
+		"neighbors": neighbors,
+		"status":    "joined",
+	})
+}
+
+// handleGenesisInviteGraph 返回邀请树（谁邀请了谁），用于女巫分析
+// GET /api/v1/genesis/invite/graph?root=<节点ID>
+func (s *Server) handleGenesisInviteGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.GenesisGraphFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "genesis not available")
+		return
+	}
+	
+	graph, err := s.GenesisGraphFunc(getQueryParam(r, "root", ""))
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, graph)
+}
+
+// ============== 日志扩展 ==============
+
+func (s *Server) handleLogExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	format := getQueryParam(r, "format", "json")
+	_ = format
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"file":   "logs_export.json",
+		"status": "exported",
+	})
+}
+
+// handleLogReplicas 日志只读副本的复制延迟
+func (s *Server) handleLogReplicas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.LogReplicasFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "log replicas not available")
+		return
+	}
+	replicas := s.LogReplicasFunc()
+	if replicas == nil {
+		replicas = []map[string]interface{}{}
+	}
+	
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"replicas": replicas,
+		"count":    len(replicas),
+	})
+}
+
+// ============== 审计集成 ==============
+
+// PenaltyConfig 惩罚配置
+type PenaltyConfig struct {
+	Severity    string  `json:"severity" validate:"required,oneof=minor severe"`
+	RepPenalty  float64 `json:"rep_penalty" validate:"min=0"`
+	SlashRatio  float64 `json:"slash_ratio" validate:"min=0,max=1"`
+}
+
+// handleAuditTranscript 查询任务的执行记录，本节点没有时向执行方获取
+func (s *Server) handleAuditTranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id required")
+		return
+	}
+	if s.AuditTranscriptFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "audit not available")
+		return
+	}
+	
+	transcript, err := s.AuditTranscriptFunc(taskID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, transcript)
+}
+
+// handleAuditRun 本节点复核任务结果并提交签名结论
+func (s *Server) handleAuditRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req AuditRunRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.AuditRunFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "audit not available")
+		return
+	}
+	
+	audit, err := s.AuditRunFunc(&req)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, audit)
+}
+
+// handleAuditConsensus 查询任务的审计结论和共识
+func (s *Server) handleAuditConsensus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	taskID := getQueryParam(r, "task_id", "")
+	if taskID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id required")
+		return
+	}
+	if s.AuditConsensusFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "audit not available")
+		return
+	}
+	
+	result, err := s.AuditConsensusFunc(taskID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleLedgerVerify 重放账本并报告所有签名、哈希链和状态不一致之处
+// 校验结果在 data 中返回，发现问题时 ok 为 false，HTTP 状态仍为 200。
+func (s *Server) handleLedgerVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.LedgerVerifyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "ledger verification not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.LedgerVerifyFunc())
+}
+
+// handleLedgerUsage 按事件类型报告账本的保留事件数、占用字节、已压缩数量和保留期
+func (s *Server) handleLedgerUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.LedgerUsageFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "ledger usage not available")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.LedgerUsageFunc())
+}
+
+// handleAuditDeviations 列出与共识不一致的审计结论，可按 auditor 过滤
+func (s *Server) handleAuditDeviations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.AuditDeviationsFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "audit not available")
+		return
+	}
+	
+	deviations := s.AuditDeviationsFunc(getQueryParam(r, "auditor", ""), getIntQueryParam(r, "limit", 20))
+	if deviations == nil {
+		deviations = []map[string]interface{}{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deviations": deviations,
+		"total":      len(deviations),
+	})
+}
+
+func (s *Server) handleAuditPenaltyConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		if s.AuditPenaltyConfigFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "audit not available")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, s.AuditPenaltyConfigFunc())
+		return
+	}
+	
+	if r.Method == http.MethodPost {
+		var req PenaltyConfig
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+		if s.AuditSetPenaltyFunc == nil {
+			s.writeError(w, http.StatusNotImplemented, "audit not available")
+			return
+		}
+		if err := s.AuditSetPenaltyFunc(req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":  "updated",
+			"config":  req,
+		})
+		return
+	}
+	
+	s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func (s *Server) handleAuditManualPenalty(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req struct {
+		NodeID   string `json:"node_id" validate:"required"`
+		Severity string `json:"severity" validate:"oneof=minor severe"`
+		Reason   string `json:"reason"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.AuditManualPenaltyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "audit not available")
+		return
+	}
+	
+	penalty, err := s.AuditManualPenaltyFunc(req.NodeID, req.Severity, req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, penalty)
+}
+
+// ============== 抵押物管理 ==============
+
+func (s *Server) handleCollateralList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
+		return
+	}
+	
+	collaterals := s.Collateral.ListCollaterals(getQueryParam(r, "node_id", ""), getQueryParam(r, "status", ""))
+	if collaterals == nil {
+		collaterals = []map[string]interface{}{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"collaterals": collaterals,
+		"total":       len(collaterals),
+	})
+}
+
+// handleCollateralByNode 查询节点某一用途的抵押账户，未指定用途时列出该节点的全部账户
+func (s *Server) handleCollateralByNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	nodeID := getQueryParam(r, "node_id", "")
+	purpose := getQueryParam(r, "purpose", "")
+	
+	if nodeID == "" {
+		s.writeError(w, http.StatusBadRequest, "node_id required")
+		return
+	}
+	
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
+		return
+	}
+	
+	if purpose == "" {
+		collaterals := s.Collateral.ListCollaterals(nodeID, "")
+		if collaterals == nil {
+			collaterals = []map[string]interface{}{}
+		}
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"node_id":     nodeID,
+			"collaterals": collaterals,
+			"total":       len(collaterals),
+		})
+		return
+	}
+	
+	collateral, err := s.Collateral.GetCollateral(nodeID, purpose)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
 	}
-	if elected == nil {
-		elected = []string{}
+	s.writeJSON(w, http.StatusOK, collateral)
+}
+
+// handleCollateralDeposit 本节点向某一用途的抵押账户存入
+func (s *Server) handleCollateralDeposit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"elected": elected,
-		"status":  "finalized",
-	})
+	var req CollateralAmountRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
+		return
+	}
+	
+	account, err := s.Collateral.Deposit(req.Purpose, req.Amount)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, account)
 }
 
-func (s *Server) handleSuperNodeAuditSubmit(w http.ResponseWriter, r *http.Request) {
+// handleCollateralWithdraw 本节点从抵押账户提取未锁定的部分
+func (s *Server) handleCollateralWithdraw(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req AuditSubmitRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req CollateralAmountRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.Target == "" {
-		s.writeError(w, http.StatusBadRequest, "target required")
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
 		return
 	}
 	
-	auditID := fmt.Sprintf("audit_%d", time.Now().UnixNano())
-	if s.SuperNodeAuditSubmit != nil {
-		var err error
-		auditID, err = s.SuperNodeAuditSubmit(req.Target, req.Passed, req.Details)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	account, err := s.Collateral.Withdraw(req.Purpose, req.Amount)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, account)
+}
+
+func (s *Server) handleCollateralSlashByNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"audit_id": auditID,
-		"status":   "submitted",
-	})
+	var req CollateralSlashRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
+		return
+	}
+	
+	result, err := s.Collateral.Slash(req)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleSuperNodeAuditResult(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleCollateralSlashHistory(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	target := getQueryParam(r, "target", "")
-	if target == "" {
-		s.writeError(w, http.StatusBadRequest, "target required")
+	if s.Collateral == nil {
+		s.writeError(w, http.StatusNotImplemented, "collateral not available")
 		return
 	}
 	
-	passRate := 0.0
-	if s.SuperNodeAuditResult != nil {
-		var err error
-		passRate, err = s.SuperNodeAuditResult(target)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	history := s.Collateral.SlashHistory(getQueryParam(r, "node_id", ""), getQueryParam(r, "purpose", ""), getIntQueryParam(r, "limit", 20))
+	if history == nil {
+		history = []map[string]interface{}{}
 	}
-	
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"target":    target,
-		"pass_rate": passRate,
+		"history": history,
+		"total":   len(history),
 	})
 }
 
-// ============== 创世节点 ==============
+// ============== 代币账本 ==============
 
-func (s *Server) handleGenesisInfo(w http.ResponseWriter, r *http.Request) {
+// handleTokenBalance 查询代币余额，node_id 省略时为本节点
+func (s *Server) handleTokenBalance(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var info map[string]interface{}
-	if s.GenesisInfoFunc != nil {
-		info = s.GenesisInfoFunc()
-	}
-	if info == nil {
-		info = map[string]interface{}{
-			"genesis_id": "unknown",
-			"created_at": time.Now().Format(time.RFC3339),
-		}
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, info)
+	s.writeJSON(w, http.StatusOK, s.Token.Balance(getQueryParam(r, "node_id", "")))
 }
 
-func (s *Server) handleGenesisInviteCreate(w http.ResponseWriter, r *http.Request) {
+// handleTokenTransfer 签名转账
+func (s *Server) handleTokenTransfer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req GenesisInviteRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req TokenTransferRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	invitationID := fmt.Sprintf("inv_%d", time.Now().UnixNano())
-	if s.GenesisCreateInviteFunc != nil {
-		var err error
-		invitationID, err = s.GenesisCreateInviteFunc(req.ForPubkey)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"invitation_id": invitationID,
-		"status":        "created",
-	})
+	entry, err := s.Token.Transfer(req)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, entry)
 }
 
-func (s *Server) handleGenesisInviteVerify(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// handleTokenHistory 代币流水，最新的在前
+func (s *Server) handleTokenHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		Invitation string `json:"invitation"`
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	history := s.Token.History(getQueryParam(r, "node_id", ""), getIntQueryParam(r, "limit", 50))
+	if history == nil {
+		history = []map[string]interface{}{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+		"total":   len(history),
+	})
+}
+
+// handleTokenTaskPayment GET 查询任务预付报酬，POST 由本节点为任务预付报酬
+func (s *Server) handleTokenTaskPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	valid := false
-	inviter := ""
-	if s.GenesisVerifyInviteFunc != nil {
-		var err error
-		valid, inviter, err = s.GenesisVerifyInviteFunc(req.Invitation)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
+	var req TokenTaskPaymentRequest
+	if r.Method == http.MethodPost {
+		if !s.decodeBody(w, r, &req) {
+			return
+		}
+	} else {
+		req.TaskID = getQueryParam(r, "task_id", "")
+		if req.TaskID == "" {
+			s.writeError(w, http.StatusBadRequest, "task_id required")
 			return
 		}
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"valid":   valid,
-		"inviter": inviter,
-	})
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
+		return
+	}
+	
+	var payment map[string]interface{}
+	var err error
+	if r.Method == http.MethodPost {
+		payment, err = s.Token.HoldTaskPayment(req.TaskID, req.Amount)
+	} else {
+		payment, err = s.Token.GetTaskPayment(req.TaskID)
+	}
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, payment)
 }
 
-func (s *Server) handleGenesisJoin(w http.ResponseWriter, r *http.Request) {
+// handleTokenTaskPaymentRelease 按份额支付任务预付报酬，剩余部分退回
+func (s *Server) handleTokenTaskPaymentRelease(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req GenesisJoinRequest
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req TokenTaskPaymentRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.Invitation == "" || req.Pubkey == "" {
-		s.writeError(w, http.StatusBadRequest, "invitation and pubkey required")
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
 		return
 	}
 	
-	nodeID := ""
-	var neighbors []string
-	if s.GenesisJoinFunc != nil {
-		var err error
-		nodeID, neighbors, err = s.GenesisJoinFunc(req.Invitation, req.Pubkey)
-		if err != nil {
-			s.writeError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	payment, err := s.Token.ReleaseTaskPayment(req.TaskID, req.Payouts)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
 	}
-	if neighbors == nil {
-		neighbors = []string{}
+	s.writeJSON(w, http.StatusOK, payment)
+}
+
+// handleTokenTaskPaymentRefund 退回全部任务预付报酬
+func (s *Server) handleTokenTaskPaymentRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"node_id":   nodeID,
-		"neighbors": neighbors,
-		"status":    "joined",
-	})
+	var req TokenTaskPaymentRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	
+	if s.Token == nil {
+		s.writeError(w, http.StatusNotImplemented, "token ledger not available")
+		return
+	}
+	
+	payment, err := s.Token.RefundTaskPayment(req.TaskID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, payment)
 }
 
-// ============== 日志扩展 ==============
+// ============== 争议预审 ==============
 
-func (s *Server) handleLogExport(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDisputeList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	format := getQueryParam(r, "format", "json")
-	_ = format
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
+		return
+	}
 	
+	disputes := s.Dispute.ListDisputes(getQueryParam(r, "status", ""))
+	if disputes == nil {
+		disputes = []map[string]interface{}{}
+	}
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"file":   "logs_export.json",
-		"status": "exported",
+		"disputes": disputes,
+		"total":    len(disputes),
 	})
 }
 
-// ============== 审计集成 ==============
+// handleDisputeFile 本节点作为申诉方立案，可关联押金托管
+func (s *Server) handleDisputeFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req DisputeFileRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if req.TaskID == "" && req.EscrowID == "" {
+		s.writeError(w, http.StatusBadRequest, "task_id or escrow_id required")
+		return
+	}
+	
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
+		return
+	}
+	
+	dispute, err := s.Dispute.FileDispute(req)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, dispute)
+}
 
-// AuditDeviation 审计偏离记录
-type AuditDeviation struct {
-	AuditID    string `json:"audit_id"`
-	AuditorID  string `json:"auditor_id"`
-	TargetID   string `json:"target_id"`
-	Expected   bool   `json:"expected"`
-	Actual     bool   `json:"actual"`
-	Severity   string `json:"severity"`
-	Timestamp  int64  `json:"timestamp"`
+// handleDisputeEvidence 本节点提交附带原件的证据
+func (s *Server) handleDisputeEvidence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	var req DisputeEvidenceRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.Data) == 0 {
+		s.writeError(w, http.StatusBadRequest, "data required")
+		return
+	}
+	
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
+		return
+	}
+	
+	evidence, err := s.Dispute.AttachEvidence(req.DisputeID, req.Type, req.Description, req.Data)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, evidence)
 }
 
-// PenaltyConfig 惩罚配置
-type PenaltyConfig struct {
-	Severity    string  `json:"severity"`
-	RepPenalty  float64 `json:"rep_penalty"`
-	SlashRatio  float64 `json:"slash_ratio"`
+// handleDisputeSuggestion 由规则引擎按已验证证据生成裁决建议
+func (s *Server) handleDisputeSuggestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	// 从URL提取争议ID
+	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/suggestion/")
+	if disputeID == "" {
+		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+		return
+	}
+	
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
+		return
+	}
+	
+	suggestion, err := s.Dispute.Suggestion(disputeID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, suggestion)
 }
 
-func (s *Server) handleAuditDeviations(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+func (s *Server) handleDisputeVerifyEvidence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	limit := getIntQueryParam(r, "limit", 20)
-	
-	// 返回模拟数据，实际应从审计模块获取
-	deviations := []AuditDeviation{}
-	_ = limit
-	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"deviations": deviations,
-		"total":      0,
-	})
-}
-
-func (s *Server) handleAuditPenaltyConfig(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		// 获取惩罚配置
-		config := map[string]PenaltyConfig{
-			"minor":  {Severity: "minor", RepPenalty: 5, SlashRatio: 0.1},
-			"severe": {Severity: "severe", RepPenalty: 20, SlashRatio: 0.3},
-		}
-		s.writeJSON(w, http.StatusOK, config)
+	var req struct {
+		DisputeID  string `json:"dispute_id" validate:"required"`
+		EvidenceID string `json:"evidence_id" validate:"required"`
+		VerifierID string `json:"verifier_id"`
+	}
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if r.Method == http.MethodPost {
-		var req PenaltyConfig
-		if err := parseBody(r, &req); err != nil {
-			s.writeError(w, http.StatusBadRequest, "invalid request body")
-			return
-		}
-		
-		s.writeJSON(w, http.StatusOK, map[string]interface{}{
-			"status":  "updated",
-			"config":  req,
-		})
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
 		return
 	}
 	
-	s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	if err := s.Dispute.VerifyEvidence(req.DisputeID, req.EvidenceID, req.VerifierID); err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"verified":    true,
+		"dispute_id":  req.DisputeID,
+		"evidence_id": req.EvidenceID,
+	})
 }
 
-func (s *Server) handleAuditManualPenalty(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDisputeApplySuggestion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
 	var req struct {
-		NodeID   string `json:"node_id"`
-		Severity string `json:"severity"`
-		Reason   string `json:"reason"`
+		DisputeID  string `json:"dispute_id" validate:"required"`
+		ApproverID string `json:"approver_id"`
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.NodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"penalty_applied": true,
-		"node_id":         req.NodeID,
-		"rep_delta":       -5.0,
-		"slashed":         100,
-	})
-}
-
-// ============== 抵押物管理 ==============
-
-// Collateral 抵押物
-type Collateral struct {
-	ID        string  `json:"id"`
-	NodeID    string  `json:"node_id"`
-	Purpose   string  `json:"purpose"`
-	Amount    float64 `json:"amount"`
-	Slashed   float64 `json:"slashed"`
-	Status    string  `json:"status"`
-	CreatedAt int64   `json:"created_at"`
+	result, err := s.Dispute.ApplySuggestion(req.DisputeID, req.ApproverID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleCollateralList(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDisputeDetail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	status := getQueryParam(r, "status", "")
-	_ = status
+	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/detail/")
+	if disputeID == "" {
+		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+		return
+	}
 	
-	collaterals := []Collateral{}
+	if s.Dispute == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute not available")
+		return
+	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"collaterals": collaterals,
-		"total":       0,
-	})
+	dispute, err := s.Dispute.GetDispute(disputeID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, dispute)
 }
 
-func (s *Server) handleCollateralByNode(w http.ResponseWriter, r *http.Request) {
+// handleDisputeCase 争议及关联托管、双方抵押账户（同一快照）
+func (s *Server) handleDisputeCase(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", "")
-	purpose := getQueryParam(r, "purpose", "")
+	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/case/")
+	if disputeID == "" {
+		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+		return
+	}
 	
-	if nodeID == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id required")
+	if s.SnapshotQuery == nil {
+		s.writeError(w, http.StatusNotImplemented, "snapshot query not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"collateral_id": "coll-" + nodeID[:8],
-		"node_id":       nodeID,
-		"purpose":       purpose,
-		"amount":        1000.0,
-		"slashed":       0.0,
-		"status":        "active",
-	})
+	result, err := s.SnapshotQuery.DisputeCase(disputeID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleCollateralSlashByNode(w http.ResponseWriter, r *http.Request) {
+// handleDisputeChannelMessage 以本节点为成员在调解频道中发言
+func (s *Server) handleDisputeChannelMessage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		NodeID   string  `json:"node_id"`
-		Purpose  string  `json:"purpose"`
-		Ratio    float64 `json:"ratio"`
-		Reason   string  `json:"reason"`
-		Evidence string  `json:"evidence"`
+	var req DisputeChannelMessageRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	if s.DisputeChannelPostFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "mediation channel not available")
+		return
+	}
+	
+	entry, err := s.DisputeChannelPostFunc(req.DisputeID, req.Holder, req.Content)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, entry)
+}
+
+// handleDisputeChannelTranscript 以本节点为成员读取解密后的调解记录
+func (s *Server) handleDisputeChannelTranscript(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	if req.NodeID == "" || req.Purpose == "" {
-		s.writeError(w, http.StatusBadRequest, "node_id and purpose required")
+	disputeID := r.URL.Query().Get("dispute_id")
+	if disputeID == "" {
+		s.writeError(w, http.StatusBadRequest, "dispute_id required")
 		return
 	}
 	
-	slashedAmount := 1000.0 * req.Ratio
+	if s.DisputeChannelTranscriptFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "mediation channel not available")
+		return
+	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"slashed_amount": slashedAmount,
-		"remaining":      1000.0 - slashedAmount,
-		"status":         "slashed",
-	})
+	result, err := s.DisputeChannelTranscriptFunc(disputeID, r.URL.Query().Get("holder"))
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-func (s *Server) handleCollateralSlashHistory(w http.ResponseWriter, r *http.Request) {
+// handleDisputeBundle 导出争议的监管链包
+func (s *Server) handleDisputeBundle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	nodeID := getQueryParam(r, "node_id", "")
-	limit := getIntQueryParam(r, "limit", 20)
-	_, _ = nodeID, limit
+	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/bundle/")
+	if disputeID == "" {
+		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+		return
+	}
 	
-	history := []map[string]interface{}{}
+	if s.DisputeBundleFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "dispute bundle export not available")
+		return
+	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"history": history,
-		"total":   0,
-	})
+	bundle, err := s.DisputeBundleFunc(disputeID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, bundle)
 }
 
-// ============== 争议预审 ==============
-
-// Dispute 争议记录
-type Dispute struct {
-	ID         string   `json:"id"`
-	Plaintiff  string   `json:"plaintiff"`
-	Defendant  string   `json:"defendant"`
-	Status     string   `json:"status"`
-	Evidence   []string `json:"evidence"`
-	CreatedAt  int64    `json:"created_at"`
+// handleNodeExposure 节点的争议、托管和抵押敞口（同一快照），node_id 省略时为本节点
+func (s *Server) handleNodeExposure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	
+	if s.SnapshotQuery == nil {
+		s.writeError(w, http.StatusNotImplemented, "snapshot query not available")
+		return
+	}
+	
+	result, err := s.SnapshotQuery.NodeExposure(getQueryParam(r, "node_id", ""))
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
-// DisputeSuggestion 争议解决建议
-type DisputeSuggestion struct {
-	Resolution      string   `json:"suggested_resolution"`
-	Confidence      float64  `json:"confidence"`
-	CanAutoExecute  bool     `json:"can_auto_execute"`
-	MissingEvidence []string `json:"missing_evidence"`
-	Warnings        []string `json:"warnings"`
+// ============== 托管多签 ==============
+
+// writeServiceError 按错误类型返回状态码：对象不存在 404，签名无效或无权 403，其余 400
+func (s *Server) writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		s.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrUnauthorized):
+		s.writeError(w, http.StatusForbidden, err.Error())
+	default:
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	}
 }
 
-func (s *Server) handleDisputeList(w http.ResponseWriter, r *http.Request) {
+// handleEscrowList 列出托管，可按 status 过滤
+func (s *Server) handleEscrowList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	status := getQueryParam(r, "status", "")
-	_ = status
-	
-	disputes := []Dispute{}
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
+	}
 	
+	escrows := s.Escrow.ListEscrows(getQueryParam(r, "status", ""))
+	if escrows == nil {
+		escrows = []map[string]interface{}{}
+	}
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"disputes": disputes,
-		"total":    0,
+		"escrows": escrows,
+		"total":   len(escrows),
 	})
 }
 
-func (s *Server) handleDisputeSuggestion(w http.ResponseWriter, r *http.Request) {
+// handleEscrowDetail 查询托管详情（押金、仲裁者、待签名方案）
+func (s *Server) handleEscrowDetail(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	// 从URL提取争议ID
-	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/suggestion/")
-	if disputeID == "" {
-		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+	escrowID := strings.TrimPrefix(r.URL.Path, "/api/v1/escrow/detail/")
+	if escrowID == "" {
+		s.writeError(w, http.StatusBadRequest, "escrow_id required")
 		return
 	}
 	
-	suggestion := DisputeSuggestion{
-		Resolution:      "favor_plaintiff",
-		Confidence:      0.85,
-		CanAutoExecute:  false,
-		MissingEvidence: []string{"delivery_proof"},
-		Warnings:        []string{"证据未全部验证"},
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, suggestion)
+	escrow, err := s.Escrow.GetEscrow(escrowID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
 }
 
-func (s *Server) handleDisputeVerifyEvidence(w http.ResponseWriter, r *http.Request) {
+// handleEscrowCreate 为任务创建押金托管
+func (s *Server) handleEscrowCreate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		DisputeID  string `json:"dispute_id"`
-		EvidenceID string `json:"evidence_id"`
-		VerifierID string `json:"verifier_id"`
+	var req EscrowCreateRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"verified":    true,
-		"dispute_id":  req.DisputeID,
-		"evidence_id": req.EvidenceID,
-	})
+	escrow, err := s.Escrow.CreateEscrow(req.TaskID, req.Deposits)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
 }
 
-func (s *Server) handleDisputeApplySuggestion(w http.ResponseWriter, r *http.Request) {
+// handleEscrowDeposit 存入押金，押金在代币账本上锁定
+func (s *Server) handleEscrowDeposit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		DisputeID  string `json:"dispute_id"`
-		ApproverID string `json:"approver_id"`
+	var req EscrowDepositRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"applied":    true,
-		"resolution": "favor_plaintiff",
-		"dispute_id": req.DisputeID,
-	})
+	escrow, err := s.Escrow.Deposit(req.EscrowID, req.NodeID, req.Amount, req.Signature)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
 }
 
-func (s *Server) handleDisputeDetail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleEscrowArbitrators 设置托管的仲裁者集合和签名阈值
+func (s *Server) handleEscrowArbitrators(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	disputeID := strings.TrimPrefix(r.URL.Path, "/api/v1/dispute/detail/")
-	if disputeID == "" {
-		s.writeError(w, http.StatusBadRequest, "dispute_id required")
+	var req EscrowArbitratorsRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	dispute := Dispute{
-		ID:        disputeID,
-		Plaintiff: "node-A",
-		Defendant: "node-B",
-		Status:    "pending",
-		Evidence:  []string{},
-		CreatedAt: time.Now().Unix(),
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, dispute)
-}
-
-// ============== 托管多签 ==============
-
-// Escrow 托管记录
-type Escrow struct {
-	ID          string  `json:"id"`
-	Amount      float64 `json:"amount"`
-	Depositor   string  `json:"depositor"`
-	Beneficiary string  `json:"beneficiary"`
-	Status      string  `json:"status"`
-	CreatedAt   int64   `json:"created_at"`
+	escrow, err := s.Escrow.SetArbitrators(req.EscrowID, req.Arbitrators, req.Threshold, req.Signatures)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
 }
 
-func (s *Server) handleEscrowList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleEscrowDispute 本节点对托管发起争议
+func (s *Server) handleEscrowDispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	status := getQueryParam(r, "status", "")
-	_ = status
+	var req EscrowDisputeRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
 	
-	escrows := []Escrow{}
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
+	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"escrows": escrows,
-		"total":   0,
-	})
+	escrow, err := s.Escrow.Dispute(req.EscrowID, req.Reason)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
 }
 
-func (s *Server) handleEscrowDetail(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// handleEscrowPropose 提出争议结算方案，仲裁者随后对方案签名
+func (s *Server) handleEscrowPropose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	escrowID := strings.TrimPrefix(r.URL.Path, "/api/v1/escrow/detail/")
-	if escrowID == "" {
-		s.writeError(w, http.StatusBadRequest, "escrow_id required")
+	var req EscrowProposeRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	escrow := Escrow{
-		ID:          escrowID,
-		Amount:      1000,
-		Depositor:   "node-A",
-		Beneficiary: "node-B",
-		Status:      "active",
-		CreatedAt:   time.Now().Unix(),
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, escrow)
+	resolution, err := s.Escrow.ProposeResolution(req.EscrowID, req.Winner, req.Amount)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, resolution)
 }
 
+// handleEscrowArbitratorSignature 提交仲裁者对结算方案的签名，达到阈值后自动结算
 func (s *Server) handleEscrowArbitratorSignature(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		EscrowID    string `json:"escrow_id"`
-		ArbitratorID string `json:"arbitrator_id"`
-		Signature   string `json:"signature"`
+	var req EscrowSignatureRequest
+	if !s.decodeBody(w, r, &req) {
+		return
 	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"submitted":     true,
-		"current_count": 1,
-		"required":      2,
-	})
+	result, err := s.Escrow.SubmitArbitratorSignature(req.EscrowID, req.ArbitratorID, req.Signature)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
 
+// handleEscrowSignatureCount 查询已收集的仲裁签名数和阈值
 func (s *Server) handleEscrowSignatureCount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -2753,39 +6819,40 @@ func (s *Server) handleEscrowSignatureCount(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"escrow_id":     escrowID,
-		"current_count": 1,
-		"required":      2,
-		"signers":       []string{"arb-001"},
-	})
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
+		return
+	}
+	
+	count, err := s.Escrow.SignatureCount(escrowID)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, count)
 }
 
+// handleEscrowResolve 携带足够的仲裁签名结算争议，每个签名都按结算载荷验证
 func (s *Server) handleEscrowResolve(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	
-	var req struct {
-		EscrowID   string            `json:"escrow_id"`
-		Winner     string            `json:"winner"`
-		Signatures map[string]string `json:"signatures"`
-	}
-	if err := parseBody(r, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid request body")
+	var req EscrowResolveRequest
+	if !s.decodeBody(w, r, &req) {
 		return
 	}
 	
-	if req.EscrowID == "" || req.Winner == "" {
-		s.writeError(w, http.StatusBadRequest, "escrow_id and winner required")
+	if s.Escrow == nil {
+		s.writeError(w, http.StatusNotImplemented, "escrow not available")
 		return
 	}
 	
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"resolved":  true,
-		"winner":    req.Winner,
-		"amount":    1000.0,
-		"escrow_id": req.EscrowID,
-	})
+	result, err := s.Escrow.Resolve(req.EscrowID, req.Winner, req.Amount, req.Signatures)
+	if err != nil {
+		s.writeServiceError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
 }
@@ -1,12 +1,25 @@
 package httpapi
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
 )
 
 func TestNewServer(t *testing.T) {
@@ -122,6 +135,57 @@ func TestHandleHealth(t *testing.T) {
 	}
 }
 
+func TestHandleProbes(t *testing.T) {
+	s := createTestServer()
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	// 探针不需要 API 令牌
+	probe := func(path string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data, _ := resp.Data.(map[string]interface{})
+		return w.Code, data
+	}
+	
+	// 未配置检查项时只要 HTTP 服务可用即为存活/就绪
+	if code, data := probe("/livez"); code != http.StatusOK || data["status"] != "ok" {
+		t.Errorf("expected livez ok, got %d %v", code, data)
+	}
+	
+	ready := false
+	s.ReadinessChecksFunc = func() []ProbeCheck {
+		return []ProbeCheck{
+			{Name: "migrations", OK: true},
+			{Name: "peers", OK: ready, Detail: "0/1 connected"},
+		}
+	}
+	code, data := probe("/readyz")
+	if code != http.StatusServiceUnavailable || data["status"] != "unavailable" {
+		t.Fatalf("expected readyz 503, got %d %v", code, data)
+	}
+	checks := data["checks"].([]interface{})
+	if len(checks) != 2 || checks[1].(map[string]interface{})["detail"] != "0/1 connected" {
+		t.Errorf("expected per-check detail, got %v", checks)
+	}
+	
+	ready = true
+	if code, _ := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("expected readyz 200, got %d", code)
+	}
+	
+	req := httptest.NewRequest(http.MethodPost, "/livez", nil)
+	w := httptest.NewRecorder()
+	s.handleLivez(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
 func TestHandleStatus(t *testing.T) {
 	s := createTestServer()
 	
@@ -159,6 +223,24 @@ func TestHandleNodeInfo(t *testing.T) {
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status 200, got %d", w.Code)
 		}
+		if strings.Contains(w.Body.String(), "namespace") {
+			t.Error("default network should omit namespace")
+		}
+	})
+	
+	t.Run("namespace", func(t *testing.T) {
+		s.config.Namespace = "acme"
+		defer func() { s.config.Namespace = "" }()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/info", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleNodeInfo(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if ns := resp.Data.(map[string]interface{})["namespace"]; ns != "acme" {
+			t.Errorf("expected namespace acme, got %v", ns)
+		}
 	})
 	
 	t.Run("POST request", func(t *testing.T) {
@@ -251,8 +333,8 @@ func TestHandleSendMessage(t *testing.T) {
 		
 		s.handleSendMessage(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 	
@@ -282,30 +364,103 @@ func TestHandleSendMessage(t *testing.T) {
 func TestHandleReceiveMessage(t *testing.T) {
 	s := createTestServer()
 	
+	var receivedFrom string
 	var receivedMsg *MessageRequest
 	s.OnMessageReceived = func(from string, msg *MessageRequest) {
+		receivedFrom = from
 		receivedMsg = msg
 	}
 	
+	// 测试签名：签名为 节点ID + 载荷
+	sign := func(nodeID string) func([]byte) ([]byte, error) {
+		return func(data []byte) ([]byte, error) {
+			return append([]byte(nodeID+":"), data...), nil
+		}
+	}
+	s.config.CallbackVerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+		return bytes.Equal(signature, append([]byte(signerID+":"), data...)), nil
+	}
+	
 	msg := MessageRequest{
 		Type:    "text",
 		Content: "Test message",
 	}
 	body, _ := json.Marshal(msg)
 	
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/message/receive", bytes.NewReader(body))
-	req.Header.Set("X-NodeID", "sender1")
-	w := httptest.NewRecorder()
+	send := func(prepare func(req *http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/message/receive", bytes.NewReader(body))
+		prepare(req)
+		w := httptest.NewRecorder()
+		s.handleReceiveMessage(w, req)
+		return w
+	}
 	
-	s.handleReceiveMessage(w, req)
+	t.Run("signed", func(t *testing.T) {
+		w := send(func(req *http.Request) {
+			SignCallbackRequest(req, "sender1", body, "nonce-1", sign("sender1"))
+		})
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if receivedMsg == nil || receivedMsg.Content != "Test message" || receivedFrom != "sender1" {
+			t.Errorf("expected message from sender1, got %v from %q", receivedMsg, receivedFrom)
+		}
+	})
 	
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	tests := []struct {
+		name    string
+		prepare func(req *http.Request)
+		status  int
+	}{
+		{"unsigned", func(req *http.Request) {
+			req.Header.Set("X-NodeID", "sender1")
+		}, http.StatusUnauthorized},
+		{"replayed nonce", func(req *http.Request) {
+			SignCallbackRequest(req, "sender1", body, "nonce-1", sign("sender1"))
+		}, http.StatusUnauthorized},
+		{"claimed another node", func(req *http.Request) {
+			SignCallbackRequest(req, "sender1", body, "nonce-2", sign("mallory"))
+		}, http.StatusUnauthorized},
+		{"tampered body", func(req *http.Request) {
+			SignCallbackRequest(req, "sender1", []byte(`{"type":"text","content":"other"}`), "nonce-3", sign("sender1"))
+		}, http.StatusUnauthorized},
+		{"stale timestamp", func(req *http.Request) {
+			SignCallbackRequest(req, "sender1", body, "nonce-4", sign("sender1"))
+			req.Header.Set(CallbackTimestampHeader, fmt.Sprint(time.Now().Add(-10*time.Minute).Unix()))
+		}, http.StatusUnauthorized},
 	}
-	
-	if receivedMsg == nil {
-		t.Error("expected message to be received")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receivedMsg = nil
+			w := send(tt.prepare)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if receivedMsg != nil {
+				t.Error("message should not be delivered")
+			}
+		})
 	}
+	
+	t.Run("same nonce from another node", func(t *testing.T) {
+		w := send(func(req *http.Request) {
+			SignCallbackRequest(req, "sender2", body, "nonce-1", sign("sender2"))
+		})
+		if w.Code != http.StatusOK || receivedFrom != "sender2" {
+			t.Errorf("expected status 200 from sender2, got %d", w.Code)
+		}
+	})
+	
+	t.Run("verification unavailable", func(t *testing.T) {
+		s := createTestServer()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/message/receive", bytes.NewReader(body))
+		SignCallbackRequest(req, "sender1", body, "nonce-5", sign("sender1"))
+		w := httptest.NewRecorder()
+		s.handleReceiveMessage(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
 }
 
 func TestHandleCreateTask(t *testing.T) {
@@ -381,9 +536,61 @@ func TestHandleTaskStatus(t *testing.T) {
 	})
 }
 
+// fakeReputation 内存中的声誉服务
+type fakeReputation struct {
+	scores map[string]float64
+}
+
+func newFakeReputation() *fakeReputation {
+	return &fakeReputation{scores: map[string]float64{"node2": 75}}
+}
+
+func (f *fakeReputation) GetReputation(nodeID string) float64 {
+	return f.scores[nodeID]
+}
+
+func (f *fakeReputation) AdjustReputation(nodeID string, delta float64, reason string) (float64, error) {
+	if reason == "" {
+		return 0, fmt.Errorf("reason required")
+	}
+	f.scores[nodeID] += delta
+	return f.scores[nodeID], nil
+}
+
+func (f *fakeReputation) ReputationRanking(limit int) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for id, score := range f.scores {
+		result = append(result, map[string]interface{}{"node_id": id, "score": score})
+	}
+	return result
+}
+
+func (f *fakeReputation) ReputationHistory(nodeID string, limit int) []map[string]interface{} {
+	return nil
+}
+
+func (f *fakeReputation) ReputationDetail(nodeID string) map[string]interface{} {
+	score, ok := f.scores[nodeID]
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{"raw": score + 10, "damped": score}
+}
+
 func TestHandleReputationQuery(t *testing.T) {
 	s := createTestServer()
 	
+	t.Run("not configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query", nil)
+		w := httptest.NewRecorder()
+		s.handleReputationQuery(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	s.Reputation = newFakeReputation()
+	
 	t.Run("default node", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query", nil)
 		w := httptest.NewRecorder()
@@ -404,10 +611,6 @@ func TestHandleReputationQuery(t *testing.T) {
 	})
 	
 	t.Run("specific node", func(t *testing.T) {
-		s.GetReputationFunc = func(nodeID string) float64 {
-			return 75.0
-		}
-		
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query?node_id=node2", nil)
 		w := httptest.NewRecorder()
 		
@@ -420,6 +623,46 @@ func TestHandleReputationQuery(t *testing.T) {
 		if data["reputation"].(float64) != 75.0 {
 			t.Errorf("expected reputation 75.0, got %v", data["reputation"])
 		}
+		if data["raw"] != 85.0 || data["damped"] != 75.0 {
+			t.Errorf("expected raw and damped scores, got %v", data)
+		}
+	})
+	
+	t.Run("remote lookup", func(t *testing.T) {
+		s.ReputationLookupFunc = func(nodeID string) (map[string]interface{}, error) {
+			if nodeID == "unreachable" {
+				return nil, fmt.Errorf("peer unreachable")
+			}
+			return map[string]interface{}{"node_id": nodeID, "reputation": 60.0, "cache_age_ms": 1500, "stale": true}, nil
+		}
+		defer func() { s.ReputationLookupFunc = nil }()
+		
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query?node_id=node2", nil)
+		w := httptest.NewRecorder()
+		s.handleReputationQuery(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["cache_age_ms"].(float64) != 1500 || data["stale"] != true {
+			t.Errorf("expected cached lookup result, got %v", data)
+		}
+		
+		// 本节点不走远端查询
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query", nil)
+		w = httptest.NewRecorder()
+		s.handleReputationQuery(w, req)
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if _, ok := resp.Data.(map[string]interface{})["cache_age_ms"]; ok {
+			t.Error("local node should not use remote lookup")
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/reputation/query?node_id=unreachable", nil)
+		w = httptest.NewRecorder()
+		s.handleReputationQuery(w, req)
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("expected status 502, got %d", w.Code)
+		}
 	})
 }
 
@@ -436,11 +679,33 @@ func TestHandleReputationUpdate(t *testing.T) {
 	r := httptest.NewRequest(http.MethodPost, "/api/v1/reputation/update", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 	
+	s.handleReputationUpdate(w, r)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without reputation service, got %d", w.Code)
+	}
+	
+	s.Reputation = newFakeReputation()
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/reputation/update", bytes.NewReader(body))
+	w = httptest.NewRecorder()
 	s.handleReputationUpdate(w, r)
 	
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["reputation"] != 80.0 {
+		t.Errorf("expected reputation 80, got %v", data["reputation"])
+	}
+	
+	// 服务拒绝的调整返回 400
+	body, _ = json.Marshal(ReputationRequest{NodeID: "node2", Delta: 1})
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/reputation/update", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	s.handleReputationUpdate(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
 }
 
 func TestHandleAccusationCreate(t *testing.T) {
@@ -476,8 +741,8 @@ func TestHandleAccusationCreate(t *testing.T) {
 		
 		s.handleAccusationCreate(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 	
@@ -535,6 +800,24 @@ func TestHandleLogSubmit(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
+	
+	var gotType string
+	var gotDetails map[string]interface{}
+	s.LogSubmitFunc = func(eventType string, details map[string]interface{}) (map[string]interface{}, error) {
+		gotType, gotDetails = eventType, details
+		return map[string]interface{}{"log_id": "log1"}, nil
+	}
+	w = httptest.NewRecorder()
+	s.handleLogSubmit(w, httptest.NewRequest(http.MethodPost, "/api/v1/log/submit", bytes.NewReader(body)))
+	if w.Code != http.StatusOK || gotType != "task_complete" || gotDetails["task_id"] != "task123" || gotDetails["event_type"] != nil {
+		t.Errorf("submit = %d, %q, %v", w.Code, gotType, gotDetails)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleLogSubmit(w, httptest.NewRequest(http.MethodPost, "/api/v1/log/submit", bytes.NewBufferString(`{"task_id":"task123"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without event_type, got %d", w.Code)
+	}
 }
 
 func TestHandleLogQuery(t *testing.T) {
@@ -548,8 +831,47 @@ func TestHandleLogQuery(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
+	
+	s.LogQueryFunc = func(nodeID, eventType string, limit int) []map[string]interface{} {
+		if nodeID != "node1" || limit != 50 {
+			t.Errorf("query = %q, %q, %d", nodeID, eventType, limit)
+		}
+		return []map[string]interface{}{{"log_id": "log1"}}
+	}
+	w = httptest.NewRecorder()
+	s.handleLogQuery(w, req)
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["count"] != float64(1) {
+		t.Errorf("expected 1 log, got %d %v", w.Code, resp.Data)
+	}
 }
 
+
+func TestHandleLogReplicas(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/log/replicas", nil)
+	w := httptest.NewRecorder()
+	s.handleLogReplicas(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.LogReplicasFunc = func() []map[string]interface{} {
+		return []map[string]interface{}{{"id": 0, "lag_entries": 3}}
+	}
+	w = httptest.NewRecorder()
+	s.handleLogReplicas(w, req)
+	
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["count"] != float64(1) {
+		t.Errorf("expected 1 replica, got %d %v", w.Code, resp.Data)
+	}
+}
 func TestRegisterHandler(t *testing.T) {
 	s := createTestServer()
 	
@@ -755,8 +1077,8 @@ func TestHandleNeighborAdd(t *testing.T) {
 		
 		s.handleNeighborAdd(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
@@ -791,8 +1113,8 @@ func TestHandleMailboxSend(t *testing.T) {
 		
 		s.handleMailboxSend(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
@@ -810,6 +1132,240 @@ func TestHandleMailboxInbox(t *testing.T) {
 	}
 }
 
+func TestHandleMailboxPublicAndKnown(t *testing.T) {
+	s := createTestServer()
+	known := map[string]bool{}
+	s.MailboxPublicFunc = func(limit, offset int) ([]*MailboxMessage, int) {
+		return []*MailboxMessage{{ID: "pub-1", From: "stranger"}}, 1
+	}
+	s.MailboxKnownFunc = func() []string {
+		var ids []string
+		for id := range known {
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	s.MailboxSetKnownFunc = func(nodeID string, k bool) error {
+		if k {
+			known[nodeID] = true
+		} else {
+			delete(known, nodeID)
+		}
+		return nil
+	}
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/public", nil)
+	w := httptest.NewRecorder()
+	s.handleMailboxPublic(w, req)
+	
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"].(float64) != 1 {
+		t.Errorf("expected 1 public message, got %v", data["total"])
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/known", strings.NewReader(`{"node_id":"stranger"}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxKnown(w, req)
+	if w.Code != http.StatusOK || !known["stranger"] {
+		t.Fatalf("expected sender to be promoted, got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/known", nil)
+	w = httptest.NewRecorder()
+	s.handleMailboxKnown(w, req)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["count"].(float64) != 1 {
+		t.Errorf("expected 1 known sender, got %v", data["count"])
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/known", strings.NewReader(`{"node_id":"stranger","known":false}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxKnown(w, req)
+	if w.Code != http.StatusOK || known["stranger"] {
+		t.Errorf("expected sender to be removed, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/known", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxKnown(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestHandleMailboxStagedSend(t *testing.T) {
+	s := createTestServer()
+	
+	body := `{"to":"recipient1","content":"Hello","delay_seconds":30}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/send", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.handleMailboxSend(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("staged send without MailboxStageFunc: expected 501, got %d", w.Code)
+	}
+	
+	staged := map[string]*MailboxMessage{}
+	s.MailboxStageFunc = func(r *MailboxSendRequest) (*MailboxSendResult, error) {
+		msg := &MailboxMessage{ID: "msg-1", To: r.To, DeliverAt: time.Now().Unix() + int64(r.DelaySeconds)}
+		staged[msg.ID] = msg
+		return &MailboxSendResult{MessageID: msg.ID, Status: "staged", DeliverAt: msg.DeliverAt}, nil
+	}
+	s.MailboxStagedFunc = func() []*MailboxMessage {
+		var messages []*MailboxMessage
+		for _, msg := range staged {
+			messages = append(messages, msg)
+		}
+		return messages
+	}
+	s.MailboxCancelFunc = func(messageID string) (*MailboxMessage, error) {
+		msg, ok := staged[messageID]
+		if !ok {
+			return nil, fmt.Errorf("message is not staged")
+		}
+		delete(staged, messageID)
+		return msg, nil
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/send", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	s.handleMailboxSend(w, req)
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); w.Code != http.StatusOK || data["status"] != "staged" {
+		t.Fatalf("expected staged message, got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/send", strings.NewReader(`{"to":"recipient1","delay_seconds":-1}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxSend(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("negative delay: expected 422, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/staged", nil)
+	w = httptest.NewRecorder()
+	s.handleMailboxStaged(w, req)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"].(float64) != 1 {
+		t.Errorf("expected 1 staged message, got %v", data["total"])
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/cancel", strings.NewReader(`{"message_id":"msg-1"}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxCancel(w, req)
+	if w.Code != http.StatusOK || len(staged) != 0 {
+		t.Fatalf("expected message to be cancelled, got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/cancel", strings.NewReader(`{"message_id":"msg-1"}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxCancel(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("second cancel: expected 409, got %d", w.Code)
+	}
+}
+
+func TestHandleMailboxContacts(t *testing.T) {
+	s := createTestServer()
+	
+	contacts := map[string]*MailboxContact{}
+	s.MailboxContactsFunc = func(query string, limit int) []*MailboxContact {
+		var result []*MailboxContact
+		for _, c := range contacts {
+			if strings.HasPrefix(strings.ToLower(c.Label), query) {
+				result = append(result, c)
+			}
+		}
+		return result
+	}
+	s.MailboxSaveContactFunc = func(c *MailboxContact) (*MailboxContact, error) {
+		contacts[c.NodeID] = c
+		return c, nil
+	}
+	s.MailboxDeleteContactFunc = func(nodeID string) error {
+		if _, ok := contacts[nodeID]; !ok {
+			return fmt.Errorf("%w: contact", ErrNotFound)
+		}
+		delete(contacts, nodeID)
+		return nil
+	}
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/contacts", strings.NewReader(`{"node_id":"mallory","label":"Mallory","trust":"low"}`))
+	w := httptest.NewRecorder()
+	s.handleMailboxContacts(w, req)
+	if w.Code != http.StatusOK || contacts["mallory"] == nil {
+		t.Fatalf("save contact: got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/contacts", strings.NewReader(`{"node_id":"bob","trust":"suspicious"}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxContacts(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown trust level: expected 422, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/contacts?q=mal", nil)
+	w = httptest.NewRecorder()
+	s.handleMailboxContacts(w, req)
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["count"].(float64) != 1 {
+		t.Errorf("expected 1 matching contact, got %v", data["count"])
+	}
+	
+	// 发信检查：有警告时需要确认，联系人的加密偏好覆盖请求
+	var sentEncrypted bool
+	sent := 0
+	s.MailboxSendFunc = func(to, subject, content string, encrypted bool) (string, error) {
+		sent++
+		sentEncrypted = encrypted
+		return "msg-1", nil
+	}
+	encrypt := true
+	s.MailboxCheckFunc = func(to string) *MailboxRecipientCheck {
+		check := &MailboxRecipientCheck{NodeID: to, Encrypt: &encrypt, Warnings: []MailboxRecipientWarning{}}
+		if c := contacts[to]; c != nil && c.Trust == "low" {
+			check.Warnings = append(check.Warnings, MailboxRecipientWarning{Code: "low_trust", Message: "low-trust contact"})
+		}
+		return check
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/contacts/check?to=mallory", nil)
+	w = httptest.NewRecorder()
+	s.handleMailboxContactCheck(w, req)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); w.Code != http.StatusOK || len(data["warnings"].([]interface{})) != 1 {
+		t.Errorf("check: got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/send", strings.NewReader(`{"to":"mallory","content":"hi"}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxSend(w, req)
+	if w.Code != http.StatusConflict || sent != 0 || !strings.Contains(w.Body.String(), "low_trust") {
+		t.Errorf("unconfirmed send: expected 409 with warnings, got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/send", strings.NewReader(`{"to":"mallory","content":"hi","confirmed":true}`))
+	w = httptest.NewRecorder()
+	s.handleMailboxSend(w, req)
+	if w.Code != http.StatusOK || sent != 1 || !sentEncrypted {
+		t.Errorf("confirmed send: got %d, sent %d, encrypted %v", w.Code, sent, sentEncrypted)
+	}
+	
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/mailbox/contacts?node_id=mallory", nil)
+	w = httptest.NewRecorder()
+	s.handleMailboxContacts(w, req)
+	if w.Code != http.StatusOK || len(contacts) != 0 {
+		t.Errorf("delete: got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.handleMailboxContacts(w, httptest.NewRequest(http.MethodDelete, "/api/v1/mailbox/contacts?node_id=mallory", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("delete missing contact: expected 404, got %d", w.Code)
+	}
+}
+
 func TestHandleBulletinPublish(t *testing.T) {
 	s := createTestServer()
 	
@@ -840,35 +1396,216 @@ func TestHandleBulletinPublish(t *testing.T) {
 		
 		s.handleBulletinPublish(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
 
-func TestHandleBulletinByTopic(t *testing.T) {
+func TestHandleBulletinEncrypted(t *testing.T) {
 	s := createTestServer()
 	
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/topic/tasks?limit=10", nil)
-	w := httptest.NewRecorder()
-	
-	s.handleBulletinByTopic(w, req)
+	t.Run("not configured", func(t *testing.T) {
+		body, _ := json.Marshal(BulletinPublishRequest{
+			Topic:      "tasks",
+			Content:    "details",
+			Recipients: []string{"bidder1"},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/publish", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinPublish(w, req)
+		
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
 	
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	var gotRecipients []string
+	s.BulletinPublishEncryptedFunc = func(topic, content string, recipients []string) (string, error) {
+		gotRecipients = recipients
+		return "enc-msg", nil
 	}
-}
-
-func TestHandleBulletinSearch(t *testing.T) {
-	s := createTestServer()
-	
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/search?keyword=task&limit=10", nil)
-	w := httptest.NewRecorder()
-	
-	s.handleBulletinSearch(w, req)
-	
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	s.BulletinDecryptFunc = func(messageID string) (string, error) {
+		if messageID != "enc-msg" {
+			return "", fmt.Errorf("not a recipient of this message")
+		}
+		return "details", nil
+	}
+	
+	t.Run("publish", func(t *testing.T) {
+		body, _ := json.Marshal(BulletinPublishRequest{
+			Topic:      "tasks",
+			Content:    "details",
+			Recipients: []string{"bidder1", "bidder2"},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/publish", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinPublish(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if len(gotRecipients) != 2 {
+			t.Errorf("expected 2 recipients, got %v", gotRecipients)
+		}
+	})
+	
+	t.Run("decrypt", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/decrypt/enc-msg", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinDecrypt(w, req)
+		
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["content"] != "details" {
+			t.Errorf("expected decrypted content, got %d %v", w.Code, resp.Data)
+		}
+	})
+	
+	t.Run("not recipient", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/decrypt/other", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinDecrypt(w, req)
+		
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleBulletinModeration(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("not available", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"message_id":"m1","reason":"spam"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/hide", body)
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinHide(w, req)
+		
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	var overturned bool
+	var hideActor string
+	s.BulletinHideFunc = func(actor, messageID, reason string) (map[string]interface{}, error) {
+		hideActor = actor
+		return map[string]interface{}{"message_id": messageID, "reason": reason}, nil
+	}
+	s.BulletinResolveAppealFunc = func(actor, messageID string, overturn bool, note string) (map[string]interface{}, error) {
+		if messageID != "m1" {
+			return nil, fmt.Errorf("no pending appeal")
+		}
+		overturned = overturn
+		return map[string]interface{}{"message_id": messageID}, nil
+	}
+	s.BulletinStrikesFunc = func(nodeID string) []map[string]interface{} {
+		return []map[string]interface{}{{"node_id": nodeID, "role": "moderator", "count": 1}}
+	}
+	
+	t.Run("hide requires reason", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"message_id":"m1"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/hide", body)
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinHide(w, req)
+		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("hide actor", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"message_id":"m1","reason":"spam"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/hide", body)
+		w := httptest.NewRecorder()
+		s.handleBulletinHide(w, req)
+		if w.Code != http.StatusOK || hideActor != "test-node" {
+			t.Errorf("unsigned request should act as the local node, got %d %q", w.Code, hideActor)
+		}
+		
+		body = bytes.NewBufferString(`{"message_id":"m1","reason":"spam"}`)
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/hide", body)
+		req = req.WithContext(context.WithValue(req.Context(), identityContextKey{}, "mod-a"))
+		w = httptest.NewRecorder()
+		s.handleBulletinHide(w, req)
+		if w.Code != http.StatusOK || hideActor != "mod-a" {
+			t.Errorf("signed request should act as the verified node, got %d %q", w.Code, hideActor)
+		}
+	})
+	
+	t.Run("resolve decision", func(t *testing.T) {
+		body := bytes.NewBufferString(`{"message_id":"m1","decision":"maybe"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/appeal/resolve", body)
+		w := httptest.NewRecorder()
+		s.handleBulletinResolveAppeal(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422 for bad decision, got %d", w.Code)
+		}
+		
+		body = bytes.NewBufferString(`{"message_id":"m1","decision":"overturn"}`)
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/appeal/resolve", body)
+		w = httptest.NewRecorder()
+		s.handleBulletinResolveAppeal(w, req)
+		if w.Code != http.StatusOK || !overturned {
+			t.Errorf("expected overturn, got %d %v", w.Code, overturned)
+		}
+		
+		body = bytes.NewBufferString(`{"message_id":"m2","decision":"uphold"}`)
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/bulletin/appeal/resolve", body)
+		w = httptest.NewRecorder()
+		s.handleBulletinResolveAppeal(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", w.Code)
+		}
+	})
+	
+	t.Run("strikes", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/strikes/mod-a", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleBulletinStrikes(w, req)
+		
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		strikes, _ := data["strikes"].([]interface{})
+		if w.Code != http.StatusOK || len(strikes) != 1 {
+			t.Errorf("expected 1 strike record, got %d %v", w.Code, resp.Data)
+		}
+	})
+}
+
+func TestHandleBulletinByTopic(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/topic/tasks?limit=10", nil)
+	w := httptest.NewRecorder()
+	
+	s.handleBulletinByTopic(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestHandleBulletinSearch(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/search?keyword=task&limit=10", nil)
+	w := httptest.NewRecorder()
+	
+	s.handleBulletinSearch(w, req)
+	
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
 	}
 }
 
@@ -901,8 +1638,8 @@ func TestHandleVotingCreate(t *testing.T) {
 		
 		s.handleVotingCreate(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
@@ -936,12 +1673,192 @@ func TestHandleVotingVote(t *testing.T) {
 		
 		s.handleVotingVote(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
 
+func TestHandleVotingDelegation(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/voting/delegate",
+		bytes.NewBufferString(`{"delegate":"12D3KooWA","scope":"kick"}`))
+	w := httptest.NewRecorder()
+	s.handleVotingDelegate(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	delegations := map[string]string{}
+	s.VotingDelegateFunc = func(delegate, scope string) (map[string]interface{}, error) {
+		if delegate == s.config.NodeID {
+			return nil, fmt.Errorf("cannot delegate to self")
+		}
+		delegations[scope] = delegate
+		return map[string]interface{}{"delegate_id": delegate, "scope": scope}, nil
+	}
+	s.VotingRevokeDelegationFunc = func(scope string) error {
+		if _, ok := delegations[scope]; !ok {
+			return fmt.Errorf("delegation not found")
+		}
+		delete(delegations, scope)
+		return nil
+	}
+	s.VotingDelegationsFunc = func(nodeID string) []map[string]interface{} {
+		var result []map[string]interface{}
+		for scope, delegate := range delegations {
+			result = append(result, map[string]interface{}{"delegate_id": delegate, "scope": scope})
+		}
+		return result
+	}
+	
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"delegate":"12D3KooWA","scope":"kick"}`, http.StatusOK},
+		{"all proposal types", `{"delegate":"12D3KooWB"}`, http.StatusOK},
+		{"invalid scope", `{"delegate":"12D3KooWA","scope":"budget"}`, http.StatusUnprocessableEntity},
+		{"missing delegate", `{"scope":"kick"}`, http.StatusUnprocessableEntity},
+		{"self", `{"delegate":"test-node"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/voting/delegate", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			s.handleVotingDelegate(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/voting/delegations", nil)
+	w = httptest.NewRecorder()
+	s.handleVotingDelegations(w, req)
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if list, _ := data["delegations"].([]interface{}); len(list) != 2 {
+		t.Errorf("expected 2 delegations, got %v", data["delegations"])
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/voting/delegate/revoke", bytes.NewBufferString(`{"scope":"kick"}`))
+	w = httptest.NewRecorder()
+	s.handleVotingRevokeDelegation(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/voting/delegate/revoke", bytes.NewBufferString(`{"scope":"kick"}`))
+	s.handleVotingRevokeDelegation(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for missing delegation, got %d", w.Code)
+	}
+}
+
+func TestHandleVotingTally(t *testing.T) {
+	s := createTestServer()
+	s.VotingTallyFunc = func(proposalID string) (map[string]interface{}, error) {
+		if proposalID != "prop123" {
+			return nil, fmt.Errorf("proposal not found")
+		}
+		return map[string]interface{}{
+			"yes_weight":       132.0,
+			"delegated_weight": 88.0,
+			"delegations": []map[string]interface{}{
+				{"delegator_id": "b", "chain": []string{"a"}, "voter_id": "a", "status": "counted"},
+			},
+		}, nil
+	}
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/voting/tally/prop123", nil)
+	w := httptest.NewRecorder()
+	s.handleVotingTally(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if data["delegated_weight"] != 88.0 {
+		t.Errorf("expected delegated weight 88, got %v", data["delegated_weight"])
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/voting/tally/missing", nil)
+	w = httptest.NewRecorder()
+	s.handleVotingTally(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandleVotingCanary(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/voting/canary/prop123", nil)
+	w := httptest.NewRecorder()
+	s.handleVotingCanary(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	optedIn := false
+	s.VotingCanaryFunc = func(proposalID string) (map[string]interface{}, error) {
+		if proposalID != "prop123" {
+			return nil, fmt.Errorf("proposal not found")
+		}
+		return map[string]interface{}{"phase": "trial", "nodes": []string{"node-a"}}, nil
+	}
+	s.VotingCanaryOptInFunc = func(optIn bool) ([]string, error) {
+		optedIn = optIn
+		if optIn {
+			return []string{"node-a", s.config.NodeID}, nil
+		}
+		return []string{"node-a"}, nil
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/voting/canary/prop123", nil)
+	w = httptest.NewRecorder()
+	s.handleVotingCanary(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	if data, _ := resp.Data.(map[string]interface{}); data["phase"] != "trial" {
+		t.Errorf("expected trial phase, got %v", resp.Data)
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/voting/canary/missing", nil)
+	w = httptest.NewRecorder()
+	s.handleVotingCanary(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/voting/canary/opt-in", bytes.NewBufferString(`{"enabled":true}`))
+	w = httptest.NewRecorder()
+	s.handleVotingCanaryOptIn(w, req)
+	if w.Code != http.StatusOK || !optedIn {
+		t.Fatalf("expected opt-in, got %d %s", w.Code, w.Body.String())
+	}
+	resp = Response{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if data, _ := resp.Data.(map[string]interface{}); len(data["canary_nodes"].([]interface{})) != 2 {
+		t.Errorf("expected 2 canary nodes, got %v", resp.Data)
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/voting/canary/opt-in", nil)
+	w = httptest.NewRecorder()
+	s.handleVotingCanaryOptIn(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
 func TestHandleSuperNodeList(t *testing.T) {
 	s := createTestServer()
 	
@@ -955,6 +1872,44 @@ func TestHandleSuperNodeList(t *testing.T) {
 	}
 }
 
+func TestHandleSuperNodeScoreboard(t *testing.T) {
+	s := createTestServer()
+	s.SuperNodeScoreboardFunc = func(nodeID string) ([]map[string]interface{}, error) {
+		if nodeID == "unknown" {
+			return nil, fmt.Errorf("duty score not found")
+		}
+		return []map[string]interface{}{{"node_id": "super1", "failed_epochs": 2}}, nil
+	}
+	
+	t.Run("all", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/supernode/scoreboard", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleSuperNodeScoreboard(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data := resp.Data.(map[string]interface{})
+		if len(data["scoreboard"].([]interface{})) != 1 {
+			t.Errorf("expected 1 entry, got %v", data["scoreboard"])
+		}
+	})
+	
+	t.Run("unknown node", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/supernode/scoreboard?node_id=unknown", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleSuperNodeScoreboard(w, req)
+		
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+}
+
 func TestHandleSuperNodeApply(t *testing.T) {
 	s := createTestServer()
 	
@@ -998,8 +1953,8 @@ func TestHandleSuperNodeVote(t *testing.T) {
 		
 		s.handleSuperNodeVote(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
 		}
 	})
 }
@@ -1024,6 +1979,8 @@ func TestHandleGenesisJoin(t *testing.T) {
 		body, _ := json.Marshal(GenesisJoinRequest{
 			Invitation: "inv123",
 			Pubkey:     "pubkey123",
+			PeerID:     "12D3KooWInvitee",
+			Signature:  "3045...",
 		})
 		
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/genesis/join", bytes.NewReader(body))
@@ -1046,10 +2003,80 @@ func TestHandleGenesisJoin(t *testing.T) {
 		
 		s.handleGenesisJoin(w, req)
 		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleGenesisInvite(t *testing.T) {
+	s := createTestServer()
+	
+	s.GenesisCreateInviteFunc = func(forPubkey, peerID string) (string, error) {
+		if forPubkey == "" {
+			return "", errors.New("invalid public key")
+		}
+		return "code-" + forPubkey, nil
+	}
+	s.GenesisVerifyInviteFunc = func(invitation string) (bool, string, error) {
+		if invitation == "used" {
+			return false, "inviter-1", errors.New("invitation already used")
+		}
+		return true, "inviter-1", nil
+	}
+	
+	t.Run("create maps errors to 400", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/genesis/invite/create", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		s.handleGenesisInviteCreate(w, req)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("expected status 400, got %d", w.Code)
 		}
 	})
+	
+	t.Run("verify reports reason", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/genesis/invite/verify", bytes.NewBufferString(`{"invitation":"used"}`))
+		w := httptest.NewRecorder()
+		s.handleGenesisInviteVerify(w, req)
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["valid"] != false || data["reason"] != "invitation already used" {
+			t.Errorf("unexpected response %d %v", w.Code, resp.Data)
+		}
+	})
+	
+	t.Run("graph", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/genesis/invite/graph", nil)
+		w := httptest.NewRecorder()
+		s.handleGenesisInviteGraph(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+		
+		s.GenesisGraphFunc = func(root string) (map[string]interface{}, error) {
+			if root == "missing" {
+				return nil, fmt.Errorf("%w: node %s", ErrNotFound, root)
+			}
+			return map[string]interface{}{"roots": []string{"genesis"}, "max_depth": 1}, nil
+		}
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/genesis/invite/graph", nil)
+		w = httptest.NewRecorder()
+		s.handleGenesisInviteGraph(w, req)
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["max_depth"] != float64(1) {
+			t.Errorf("unexpected response %d %v", w.Code, resp.Data)
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/genesis/invite/graph?root=missing", nil)
+		w = httptest.NewRecorder()
+		s.handleGenesisInviteGraph(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
 }
 
 func TestHandleIncentiveAward(t *testing.T) {
@@ -1081,10 +2108,26 @@ func TestHandleIncentiveTolerance(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
+	
+	s.IncentiveToleranceInputsFunc = func(nodeID string) map[string]interface{} {
+		return map[string]interface{}{"reputation": 80.0, "relation": "neighbor"}
+	}
+	w = httptest.NewRecorder()
+	s.handleIncentiveTolerance(w, req)
+	
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	inputs, ok := resp.Data["inputs"].(map[string]interface{})
+	if !ok || inputs["relation"] != "neighbor" {
+		t.Errorf("expected tolerance inputs in response, got %v", resp.Data)
+	}
 }
 
 func TestHandleReputationRanking(t *testing.T) {
 	s := createTestServer()
+	s.Reputation = newFakeReputation()
 	
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/reputation/ranking?limit=10", nil)
 	w := httptest.NewRecorder()
@@ -1094,50 +2137,4258 @@ func TestHandleReputationRanking(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status 200, got %d", w.Code)
 	}
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if rankings := resp.Data.(map[string]interface{})["rankings"].([]interface{}); len(rankings) != 1 {
+		t.Errorf("expected 1 ranking entry, got %v", rankings)
+	}
 }
 
-func TestHandleAccusationAnalyze(t *testing.T) {
+func TestHandleNodeMaintenance(t *testing.T) {
 	s := createTestServer()
 	
-	t.Run("with node_id", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/accusation/analyze?node_id=node1", nil)
+	inMaintenance := false
+	s.InMaintenanceFunc = func() bool { return inMaintenance }
+	s.MaintenanceStatusFunc = func() map[string]interface{} {
+		return map[string]interface{}{"enabled": inMaintenance}
+	}
+	s.MaintenanceSetFunc = func(enabled bool, reason string, duration time.Duration) (map[string]interface{}, error) {
+		inMaintenance = enabled
+		return map[string]interface{}{"enabled": enabled, "reason": reason}, nil
+	}
+	
+	t.Run("enable", func(t *testing.T) {
+		body := `{"enabled":true,"reason":"disk upgrade","duration":3600}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/node/maintenance", bytes.NewBufferString(body))
 		w := httptest.NewRecorder()
 		
-		s.handleAccusationAnalyze(w, req)
+		s.handleNodeMaintenance(w, req)
 		
 		if w.Code != http.StatusOK {
 			t.Errorf("expected status 200, got %d", w.Code)
 		}
+		if !inMaintenance {
+			t.Error("expected maintenance mode to be enabled")
+		}
 	})
 	
-	t.Run("missing node_id", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/accusation/analyze", nil)
+	t.Run("rejects new tasks", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/create", bytes.NewBufferString(`{"type":"compute"}`))
 		w := httptest.NewRecorder()
 		
-		s.handleAccusationAnalyze(w, req)
+		s.handleCreateTask(w, req)
 		
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("expected status 400, got %d", w.Code)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", w.Code)
 		}
 	})
-}
-
-func TestExtractPathParam(t *testing.T) {
-	t.Run("valid prefix", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/message/msg123", nil)
+	
+	t.Run("pauses elections", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/supernode/vote", bytes.NewBufferString(`{"candidate":"c1"}`))
+		w := httptest.NewRecorder()
 		
-		param := extractPathParam(req, "/api/v1/bulletin/message/")
-		if param != "msg123" {
-			t.Errorf("expected 'msg123', got %s", param)
+		s.handleSuperNodeVote(w, req)
+		
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", w.Code)
 		}
 	})
 	
-	t.Run("no match", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/other/path", nil)
+	t.Run("status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/maintenance", nil)
+		w := httptest.NewRecorder()
 		
-		param := extractPathParam(req, "/api/v1/bulletin/message/")
-		if param != "" {
-			t.Errorf("expected empty string, got %s", param)
+		s.handleNodeMaintenance(w, req)
+		
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data := resp.Data.(map[string]interface{})
+		if data["enabled"] != true {
+			t.Errorf("expected enabled=true, got %v", data["enabled"])
 		}
 	})
+	
+	t.Run("invalid duration", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/node/maintenance", bytes.NewBufferString(`{"enabled":true,"duration":-1}`))
+		w := httptest.NewRecorder()
+		
+		s.handleNodeMaintenance(w, req)
+		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleFeatures(t *testing.T) {
+	s := createTestServer()
+	
+	ready := map[string]bool{}
+	s.FeatureListFunc = func() []map[string]interface{} {
+		return []map[string]interface{}{{"name": "mailbox-v2", "state": "defined"}}
+	}
+	s.FeatureStatusFunc = func(name string) (map[string]interface{}, error) {
+		if name != "mailbox-v2" {
+			return nil, errors.New("unknown feature")
+		}
+		return map[string]interface{}{"name": name, "local_ready": ready[name]}, nil
+	}
+	s.FeatureReadyFunc = func(name string, r bool) (map[string]interface{}, error) {
+		ready[name] = r
+		return s.FeatureStatusFunc(name)
+	}
+	
+	w := httptest.NewRecorder()
+	s.handleFeatureList(w, httptest.NewRequest(http.MethodGet, "/api/v1/node/features", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleFeatureReady(w, httptest.NewRequest(http.MethodPost, "/api/v1/node/features/ready", bytes.NewBufferString(`{"name":"mailbox-v2","ready":true}`)))
+	if w.Code != http.StatusOK || !ready["mailbox-v2"] {
+		t.Errorf("expected readiness to be set, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleFeatureReady(w, httptest.NewRequest(http.MethodPost, "/api/v1/node/features/ready", bytes.NewBufferString(`{"ready":true}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 without name, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleFeatureStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/node/features/escrow-v9", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandleReputationWebhook(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("not configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/reputation/webhook", bytes.NewBufferString("{}"))
+		w := httptest.NewRecorder()
+		
+		s.handleReputationWebhook(w, req)
+		
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+	
+	s.ReputationWebhookFunc = func(header http.Header, body []byte) (map[string]interface{}, error) {
+		if header.Get("X-Daan-Signature") != "good" {
+			return nil, ErrUnauthorized
+		}
+		return map[string]interface{}{"applied": true}, nil
+	}
+	
+	t.Run("rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/reputation/webhook", bytes.NewBufferString("{}"))
+		w := httptest.NewRecorder()
+		
+		s.handleReputationWebhook(w, req)
+		
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", w.Code)
+		}
+	})
+	
+	t.Run("accepted without API token", func(t *testing.T) {
+		mux := http.NewServeMux()
+		s.registerRoutes(mux)
+		
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/reputation/webhook", bytes.NewBufferString("{}"))
+		req.Header.Set("X-Daan-Signature", "good")
+		w := httptest.NewRecorder()
+		
+		s.middleware(mux).ServeHTTP(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+}
+
+func TestRouteAuthPolicies(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	policies, err := ParseRoutePolicies("recommended, /api/v1/voting/=signature")
+	if err != nil {
+		t.Fatalf("ParseRoutePolicies failed: %v", err)
+	}
+	s.config.RoutePolicies = policies
+	s.config.CallbackVerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+		return bytes.Equal(signature, append([]byte(signerID+":"), data...)), nil
+	}
+	sign := func(nodeID string) func([]byte) ([]byte, error) {
+		return func(data []byte) ([]byte, error) {
+			return append([]byte(nodeID+":"), data...), nil
+		}
+	}
+	var accuser string
+	s.OnAccusationCreate = func(from string, req *AccusationRequest) {
+		accuser = from
+	}
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	body := []byte(`{"accused":"node-b","reason":"spam"}`)
+	send := func(path, token, signer, nonce string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+		if signer != "" {
+			SignCallbackRequest(req, signer, body, nonce, sign(signer))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := send("/api/v1/accusation/create", "secret", "node-a", "n1"); w.Code != http.StatusNotImplemented {
+		t.Errorf("without identity registry: expected 501, got %d", w.Code)
+	}
+	s.config.IdentityRegisteredFunc = func(nodeID string) bool { return nodeID == "node-a" }
+	
+	if w := send("/api/v1/accusation/create", "secret", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("token only: expected 401, got %d", w.Code)
+	}
+	if w := send("/api/v1/accusation/create", "", "node-a", "n2"); w.Code != http.StatusUnauthorized {
+		t.Errorf("signature only on both-policy route: expected 401, got %d", w.Code)
+	}
+	if w := send("/api/v1/accusation/create", "secret", "node-x", "n3"); w.Code != http.StatusForbidden {
+		t.Errorf("unregistered signer: expected 403, got %d", w.Code)
+	}
+	if w := send("/api/v1/accusation/create", "secret", "node-a", "n4"); w.Code != http.StatusOK || accuser != "node-a" {
+		t.Errorf("token and signature: expected 200 from node-a, got %d %q", w.Code, accuser)
+	}
+	if w := send("/api/v1/accusation/create", "secret", "node-a", "n4"); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed signature: expected 401, got %d", w.Code)
+	}
+	
+	// 精确匹配优先于前缀：/voting/vote 为 both，其余 /voting/ 路由只要签名
+	if w := send("/api/v1/voting/vote", "", "node-a", "n5"); w.Code != http.StatusUnauthorized {
+		t.Errorf("voting vote without token: expected 401, got %d", w.Code)
+	}
+	if w := send("/api/v1/voting/proposal/finalize", "", "node-a", "n6"); w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+		t.Errorf("signature-only route should not require token, got %d", w.Code)
+	}
+	if w := send("/api/v1/mailbox/send", "secret", "", ""); w.Code == http.StatusUnauthorized {
+		t.Errorf("unlisted route should only require token, got %d", w.Code)
+	}
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/policies", nil)
+	req.Header.Set(TokenHeader, "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); len(data["routes"].([]interface{})) != 5 {
+		t.Errorf("expected 5 route policies, got %v", data["routes"])
+	}
+	
+	for _, bad := range []string{"/api/v1/x=admin", "accusation=both", "/api/v1/x"} {
+		if _, err := ParseRoutePolicies(bad); !errors.Is(err, ErrInvalidAuthPolicy) {
+			t.Errorf("%q: expected ErrInvalidAuthPolicy, got %v", bad, err)
+		}
+	}
+}
+
+type fakeRoles map[string]string
+
+func (f fakeRoles) Authorize(token, method, path string) (string, bool) {
+	role := f[token]
+	if role == "revoked" {
+		return "", false
+	}
+	return role, f.AllowedRole(role, method, path)
+}
+
+func (f fakeRoles) AllowedRole(role, method, path string) bool {
+	return role == "admin" || (role == "read-only" && method == http.MethodGet)
+}
+
+func (f fakeRoles) Revoked(token string) bool {
+	return f[token] == "revoked"
+}
+
+func TestRoleAuthorizer(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(`{}`)))
+		req.Header.Set(TokenHeader, token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	
+	// 未配置角色校验时只接受主令牌
+	if code := send(http.MethodGet, "/api/v1/node/info", "dak_reader"); code != http.StatusUnauthorized {
+		t.Errorf("without authorizer: expected 401, got %d", code)
+	}
+	
+	s.config.Roles = fakeRoles{"dak_reader": "read-only", "dak_admin": "admin"}
+	if code := send(http.MethodGet, "/api/v1/node/info", "dak_reader"); code != http.StatusOK {
+		t.Errorf("read-only GET: expected 200, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "dak_reader"); code != http.StatusForbidden {
+		t.Errorf("read-only POST: expected 403, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "dak_admin"); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Errorf("admin key should pass auth, got %d", code)
+	}
+	if code := send(http.MethodGet, "/api/v1/node/info", "dak_unknown"); code != http.StatusUnauthorized {
+		t.Errorf("unknown key: expected 401, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "secret"); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Errorf("master token should not be role-checked, got %d", code)
+	}
+}
+
+func TestCompatTokenScope(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	s.config.Compat = &CompatConfig{Tokens: map[string]CompatMode{
+		"legacy-reader": {Envelope: EnvelopeNone},
+		"legacy-admin":  {Role: "admin"},
+		"dak_revoked":   {Casing: CasingSnake},
+	}}
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(`{}`)))
+		req.Header.Set(TokenHeader, token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	
+	// 未配置角色校验：兼容令牌只能查询
+	if code := send(http.MethodGet, "/api/v1/node/info", "legacy-reader"); code != http.StatusOK {
+		t.Errorf("compat GET: expected 200, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "legacy-reader"); code != http.StatusForbidden {
+		t.Errorf("compat POST without authorizer: expected 403, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "legacy-admin"); code != http.StatusForbidden {
+		t.Errorf("compat role without authorizer: expected 403, got %d", code)
+	}
+	
+	// 按配置的角色限权，已撤销的 API Key 即使列在兼容配置中也无效
+	s.config.Roles = fakeRoles{"dak_revoked": "revoked"}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "legacy-reader"); code != http.StatusForbidden {
+		t.Errorf("read-only compat token on admin route: expected 403, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/collateral/slash-by-node", "legacy-admin"); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Errorf("admin compat token should pass auth, got %d", code)
+	}
+	if code := send(http.MethodGet, "/api/v1/node/info", "dak_revoked"); code != http.StatusUnauthorized {
+		t.Errorf("revoked key: expected 401, got %d", code)
+	}
+	
+	// 撤销节点主令牌后兼容令牌一并失效
+	s.tokenManager.RevokeToken()
+	if code := send(http.MethodGet, "/api/v1/node/info", "legacy-reader"); code != http.StatusUnauthorized {
+		t.Errorf("compat token after revocation: expected 401, got %d", code)
+	}
+}
+
+func TestSignedRequestMode(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	s.config.SignedRequests = true
+	s.config.RoutePolicies = map[string]AuthPolicy{"/api/v1/bulletin/": AuthPolicyToken}
+	s.config.IdentityRegisteredFunc = func(nodeID string) bool { return nodeID == "node-a" }
+	s.config.CallbackVerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+		return bytes.Equal(signature, append([]byte(signerID+":"), data...)), nil
+	}
+	sent := false
+	s.MailboxSendFunc = func(to, subject, content string, encrypted bool) (string, error) {
+		sent = true
+		return "mail_1", nil
+	}
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	send := func(method, path, body, signer, nonce string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set(TokenHeader, "secret")
+		if signer != "" {
+			SignCallbackRequest(req, signer, []byte(body), nonce, func(data []byte) ([]byte, error) {
+				return append([]byte(signer+":"), data...), nil
+			})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	mail := `{"to":"node-b","subject":"hi","content":"hello"}`
+	if w := send(http.MethodPost, "/api/v1/mailbox/send", mail, "", ""); w.Code != http.StatusUnauthorized || sent {
+		t.Errorf("unsigned mutation: expected 401, got %d", w.Code)
+	}
+	if w := send(http.MethodPost, "/api/v1/mailbox/send", mail, "node-x", "n1"); w.Code != http.StatusForbidden {
+		t.Errorf("unregistered signer: expected 403, got %d", w.Code)
+	}
+	if w := send(http.MethodPost, "/api/v1/mailbox/send", mail, "node-a", "n2"); w.Code != http.StatusOK || !sent {
+		t.Errorf("signed mutation: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := send(http.MethodPost, "/api/v1/mailbox/send", mail, "node-a", "n2"); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed nonce: expected 401, got %d", w.Code)
+	}
+	if w := send(http.MethodGet, "/api/v1/node/info", "", "", ""); w.Code != http.StatusOK {
+		t.Errorf("reads only require the token, got %d", w.Code)
+	}
+	// 显式配置为 token 的路由豁免签名
+	if w := send(http.MethodPost, "/api/v1/bulletin/publish", `{}`, "", ""); w.Code == http.StatusUnauthorized {
+		t.Errorf("opted-out route should not require a signature, got %d", w.Code)
+	}
+	// 自行验签的回调只校验一次，nonce 不会被中间件提前消耗
+	msg := `{"to":"test-node","content":"hi"}`
+	if w := send(http.MethodPost, "/api/v1/message/receive", msg, "node-a", "n3"); w.Code != http.StatusOK {
+		t.Errorf("signed callback: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	
+	w := send(http.MethodGet, "/api/v1/auth/policies", "", "", "")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	data := resp.Data.(map[string]interface{})
+	if data["signed_requests"] != true || data["mutation_default"] != string(AuthPolicyBoth) || len(data["exempt"].([]interface{})) != len(signedRequestExempt) {
+		t.Errorf("unexpected policy listing: %v", data)
+	}
+}
+
+func TestHandleReachability(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(TokenHeader, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := do(http.MethodGet, "/api/v1/reachability/node-b", ""); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", w.Code)
+	}
+	
+	attested := map[string]bool{}
+	s.ReachabilityFunc = func(nodeID string) map[string]interface{} {
+		return map[string]interface{}{"node_id": nodeID, "attesters": len(attested)}
+	}
+	s.ReachabilityTestFunc = func(nodeID string) (map[string]interface{}, error) {
+		if nodeID == "self" {
+			return nil, errors.New("nodes cannot attest their own reachability")
+		}
+		attested[nodeID] = true
+		return map[string]interface{}{"subject": nodeID, "reachable": true}, nil
+	}
+	s.ReachabilityImportFunc = func(attestation []byte) (map[string]interface{}, error) {
+		if !strings.Contains(string(attestation), "signature") {
+			return nil, errors.New("invalid reachability attestation")
+		}
+		return map[string]interface{}{"score": 0.6}, nil
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/reachability/test", `{"node_id":"node-b"}`); w.Code != http.StatusOK || !attested["node-b"] {
+		t.Errorf("dial-back test: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/reachability/test", `{"node_id":"self"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("self test: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/reachability/test", `{}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing node_id: expected 422, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/reachability/attestations", `{"subject":"node-b"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unsigned attestation: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/reachability/attestations", `{"subject":"node-b","signature":"c2ln"}`); w.Code != http.StatusOK {
+		t.Errorf("import: expected 200, got %d", w.Code)
+	}
+	
+	w := do(http.MethodGet, "/api/v1/reachability/node-b", "")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["node_id"] != "node-b" || data["attesters"].(float64) != 1 {
+		t.Errorf("unexpected reachability: %v", resp.Data)
+	}
+}
+
+func TestHandleDiscovery(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(TokenHeader, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := do(http.MethodGet, "/api/v1/discovery/find?skill=translation", ""); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", w.Code)
+	}
+	
+	announced := map[string]bool{}
+	s.DiscoveryAnnounceFunc = func(skill string) (map[string]interface{}, error) {
+		if strings.Contains(skill, " ") {
+			return nil, errors.New("invalid skill")
+		}
+		announced[skill] = true
+		return map[string]interface{}{"skill": skill, "rendezvous": "daan/agents/" + skill}, nil
+	}
+	s.DiscoveryWithdrawFunc = func(skill string) error {
+		if !announced[skill] {
+			return errors.New("skill not announced")
+		}
+		delete(announced, skill)
+		return nil
+	}
+	s.DiscoveryAnnouncementsFunc = func() []map[string]interface{} {
+		list := []map[string]interface{}{}
+		for skill := range announced {
+			list = append(list, map[string]interface{}{"skill": skill})
+		}
+		return list
+	}
+	var findLimit int
+	s.DiscoveryFindFunc = func(skill string, limit int) ([]map[string]interface{}, error) {
+		findLimit = limit
+		return []map[string]interface{}{{"node_id": "node-b", "addrs": []string{"/ip4/10.0.0.2/tcp/4001"}}}, nil
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/discovery/announce", `{"skills":["translation","ocr"]}`); w.Code != http.StatusOK || !announced["translation"] || !announced["ocr"] {
+		t.Errorf("announce: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/discovery/announce", `{"skills":[]}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("empty skills: expected 422, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/discovery/announce", `{"skills":["bad skill"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid skill: expected 400, got %d", w.Code)
+	}
+	
+	w := do(http.MethodGet, "/api/v1/discovery/find?skill=translation&limit=5", "")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, ok := resp.Data.(map[string]interface{}); !ok || data["count"].(float64) != 1 || findLimit != 5 {
+		t.Errorf("unexpected find result: %d %v", w.Code, resp.Data)
+	}
+	if w := do(http.MethodGet, "/api/v1/discovery/find", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("missing skill: expected 400, got %d", w.Code)
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/discovery/withdraw", `{"skill":"ocr"}`); w.Code != http.StatusOK || announced["ocr"] {
+		t.Errorf("withdraw: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/discovery/withdraw", `{"skill":"ocr"}`); w.Code != http.StatusNotFound {
+		t.Errorf("withdraw twice: expected 404, got %d", w.Code)
+	}
+	w = do(http.MethodGet, "/api/v1/discovery/announce", "")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if list := resp.Data.(map[string]interface{})["announcements"].([]interface{}); len(list) != 1 {
+		t.Errorf("expected 1 remaining announcement, got %v", list)
+	}
+}
+
+func TestHandleBreaker(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(TokenHeader, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := do(http.MethodGet, "/api/v1/breaker/status", ""); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", w.Code)
+	}
+	
+	paused := map[string]bool{}
+	s.BreakerAllowFunc = func(class string) error {
+		if paused[class] {
+			return errors.New("message class paused by circuit breaker")
+		}
+		return nil
+	}
+	s.BreakerIssueFunc = func(req *BreakerDirectiveRequest) (interface{}, error) {
+		paused[req.Class] = req.Action == "pause"
+		return map[string]interface{}{"class": req.Class, "action": req.Action}, nil
+	}
+	s.BreakerOverrideFunc = func(req *BreakerOverrideRequest) (interface{}, error) {
+		paused[req.Class] = req.Action == "pause"
+		return map[string]interface{}{"class": req.Class, "action": req.Action}, nil
+	}
+	s.BreakerClearOverrideFunc = func(class string) error {
+		return errors.New("no local override for class")
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/breaker/directive", `{"class":"gossip","action":"pause","reason":"spam"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown class: expected 422, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/breaker/directive", `{"class":"mail","action":"rate_limit","reason":"spam"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("rate_limit without rate: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/breaker/directive", `{"class":"accusation","action":"pause","reason":"spam storm","duration":3600}`); w.Code != http.StatusOK {
+		t.Errorf("issue: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	
+	// 熔断中的类别被拒绝，其他类别和只读请求不受影响
+	if w := do(http.MethodPost, "/api/v1/accusation/create", `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("paused accusation: expected 503, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/v1/accusation/list", ""); w.Code == http.StatusServiceUnavailable {
+		t.Error("reads should not be blocked by the breaker")
+	}
+	if w := do(http.MethodPost, "/api/v1/bulletin/publish", `{}`); w.Code == http.StatusServiceUnavailable {
+		t.Error("bulletin should not be blocked")
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/breaker/override", `{"class":"accusation","action":"ignore","reason":"false alarm"}`); w.Code != http.StatusOK {
+		t.Errorf("override: expected 200, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/accusation/create", `{}`); w.Code == http.StatusServiceUnavailable {
+		t.Error("override should lift the breaker locally")
+	}
+	if w := do(http.MethodPost, "/api/v1/breaker/override", `{"class":"mail","action":"clear"}`); w.Code != http.StatusNotFound {
+		t.Errorf("clear missing override: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleRecovery(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(TokenHeader, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/recovery/escrow", `{"guardians":["g1","g2"],"threshold":2}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", w.Code)
+	}
+	
+	pending := map[string]string{}
+	s.RecoveryEscrowFunc = func(guardians []string, threshold int) (map[string]interface{}, error) {
+		if threshold > len(guardians) {
+			return nil, errors.New("invalid guardian set")
+		}
+		return map[string]interface{}{"escrow": map[string]interface{}{"id": "esc-1"}}, nil
+	}
+	s.RecoveryRequestFunc = func(escrowID, requester, reason string) (map[string]interface{}, error) {
+		if _, ok := pending[requester]; ok {
+			return nil, errors.New("recovery request already pending")
+		}
+		pending[requester] = escrowID
+		return map[string]interface{}{"id": "req-1", "requester": requester}, nil
+	}
+	s.RecoveryDecideFunc = func(requestID string, approve bool, note string) (map[string]interface{}, error) {
+		if !approve {
+			return map[string]interface{}{"status": "rejected"}, nil
+		}
+		return map[string]interface{}{"share": map[string]interface{}{"request_id": requestID}}, nil
+	}
+	s.RecoveryShareFunc = func(ceremonyID string, share []byte) (map[string]interface{}, error) {
+		if !strings.Contains(string(share), "signature") {
+			return nil, errors.New("invalid released share")
+		}
+		return map[string]interface{}{"id": ceremonyID, "status": "completed"}, nil
+	}
+	s.RecoveryCeremonyGetFunc = func(ceremonyID string) (map[string]interface{}, error) {
+		return nil, errors.New("recovery ceremony not found")
+	}
+	
+	if w := do(http.MethodPost, "/api/v1/recovery/escrow", `{"guardians":["g1","g2"],"threshold":2}`); w.Code != http.StatusOK {
+		t.Errorf("escrow: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/escrow", `{"guardians":["g1"],"threshold":3}`); w.Code != http.StatusBadRequest {
+		t.Errorf("threshold above guardians: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/request", `{"escrow_id":"esc-1"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing requester: expected 422, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/request", `{"escrow_id":"esc-1","requester":"new-node"}`); w.Code != http.StatusOK || pending["new-node"] != "esc-1" {
+		t.Errorf("request: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/request", `{"escrow_id":"esc-1","requester":"new-node"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate request: expected 409, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/decide", `{"request_id":"req-1","approve":true}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "share") {
+		t.Errorf("approve: expected released share, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/ceremony/share", `{"ceremony_id":"cer-1","share":{"index":1}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unsigned share: expected 400, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/v1/recovery/ceremony/share", `{"ceremony_id":"cer-1","share":{"index":1,"signature":"c2ln"}}`); w.Code != http.StatusOK {
+		t.Errorf("share: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/v1/recovery/ceremony/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown ceremony: expected 404, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/v1/recovery/requests", ""); w.Code != http.StatusOK {
+		t.Errorf("requests: expected 200, got %d", w.Code)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(TokenHeader, "secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	if w := do("/api/v1/stats"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501, got %d", w.Code)
+	}
+	
+	s.StatsFunc = func() map[string]interface{} {
+		return map[string]interface{}{"modules": []interface{}{map[string]interface{}{"module": "mailbox"}}}
+	}
+	s.StatsModuleFunc = func(module string) (map[string]interface{}, error) {
+		if module != "mailbox" {
+			return nil, errors.New("unknown stats module: " + module)
+		}
+		return map[string]interface{}{"module": module, "metrics": []interface{}{}}, nil
+	}
+	
+	if w := do("/api/v1/stats"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "mailbox") {
+		t.Errorf("snapshot: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("/api/v1/stats/mailbox"); w.Code != http.StatusOK {
+		t.Errorf("module: expected 200, got %d", w.Code)
+	}
+	if w := do("/api/v1/stats/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown module: expected 404, got %d", w.Code)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	s.eventKeepAlive = 20 * time.Millisecond
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	ts := httptest.NewServer(s.middleware(mux))
+	defer ts.Close()
+	
+	s.PublishEvent(EventMail, map[string]interface{}{"id": "m1"})
+	s.PublishEvent(EventBulletin, map[string]interface{}{"id": "b1"})
+	s.PublishEvent(EventMail, map[string]interface{}{"id": "m2"})
+	
+	open := func(query, lastID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/events/stream"+query, nil)
+		req.Header.Set(TokenHeader, "secret")
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("connect failed: %v", err)
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+	// next 读取下一个事件（跳过心跳注释），返回 event 和 data 行
+	next := func(rd *bufio.Reader) (string, string) {
+		t.Helper()
+		var event, data string
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event != "":
+				return event, data
+			}
+		}
+	}
+	
+	if resp, _ := open("?types=gossip", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown type: expected 400, got %d", resp.StatusCode)
+	}
+	
+	// 断线重连：补发 Last-Event-ID 之后且类型匹配的事件
+	resp, rd := open("?types=mail", "1")
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if event, data := next(rd); event != EventMail || !strings.Contains(data, "m2") {
+		t.Errorf("expected replayed m2, got %s %s", event, data)
+	}
+	
+	for deadline := time.Now().Add(time.Second); s.events.Subscribers() == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	s.PublishEvent(EventBulletin, map[string]interface{}{"id": "b2"})
+	s.PublishEvent(EventMail, map[string]interface{}{"id": "m3"})
+	if event, data := next(rd); event != EventMail || !strings.Contains(data, "m3") {
+		t.Errorf("expected live m3 (bulletin filtered out), got %s %s", event, data)
+	}
+	
+	// 补发缓冲不足时提示重新拉取
+	hub := NewEventHub(2)
+	for i := 0; i < 5; i++ {
+		hub.Publish(EventTask, i)
+	}
+	sub, backlog, gap := hub.subscribe(nil, 1)
+	if !gap || len(backlog) != 2 || backlog[0].ID != 4 {
+		t.Errorf("expected gap with the 2 buffered events, got gap=%v backlog=%+v", gap, backlog)
+	}
+	hub.unsubscribe(sub)
+	
+	// 积压过多的订阅者被断开
+	slow, _, _ := hub.subscribe([]string{EventTask}, 0)
+	for i := 0; i < eventSubscriberQueue+1; i++ {
+		hub.Publish(EventTask, i)
+	}
+	if hub.Subscribers() != 0 {
+		t.Errorf("slow subscriber should be dropped, %d subscribers left", hub.Subscribers())
+	}
+	for range slow.ch {
+	}
+	
+	// 供其他推送通道使用的订阅：断开后从最后收到的事件 ID 重新订阅补发
+	es := hub.Subscribe([]string{EventTask}, 0)
+	hub.Publish(EventMail, "skip")
+	last := hub.Publish(EventTask, "t1")
+	if ev := <-es.C(); ev.ID != last.ID {
+		t.Errorf("expected task event %d, got %+v", last.ID, ev)
+	}
+	es.Close()
+	hub.Publish(EventTask, "t2")
+	es = hub.Subscribe([]string{EventTask}, last.ID)
+	defer es.Close()
+	if es.Gap || len(es.Backlog) != 1 || es.Backlog[0].Data != "t2" {
+		t.Errorf("expected t2 replayed after reconnect, got gap=%v backlog=%+v", es.Gap, es.Backlog)
+	}
+	if !IsEventType(EventReputation) || IsEventType("resync") {
+		t.Error("IsEventType should accept only published event types")
+	}
+}
+
+func TestHandleWebhookKeys(t *testing.T) {
+	s := createTestServer()
+	s.tokenManager.SetToken("secret")
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	keys := []map[string]interface{}{{"key_id": "k1", "algorithm": "ed25519", "active": true}}
+	s.WebhookKeysFunc = func() []map[string]interface{} { return keys }
+	s.WebhookKeyRotateFunc = func() (map[string]interface{}, error) {
+		keys = append([]map[string]interface{}{{"key_id": "k2", "active": true}}, keys...)
+		keys[1]["active"] = false
+		return keys[0], nil
+	}
+	
+	// 公钥发布地址不需要 Token
+	req := httptest.NewRequest(http.MethodGet, WebhookKeysPath, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "k1") {
+		t.Fatalf("keys: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/reputation/webhook/keys/rotate", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("rotate without token: expected 401, got %d", w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/reputation/webhook/keys/rotate", nil)
+	req.Header.Set(TokenHeader, "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "k2") {
+		t.Errorf("rotate: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if len(keys) != 2 {
+		t.Errorf("retired key should still be published, got %v", keys)
+	}
+}
+
+func TestHandleTaskScheduler(t *testing.T) {
+	s := createTestServer()
+	tasks := map[string]map[string]interface{}{
+		"t1": {"task_id": "t1", "status": "accepted", "stage": "accepted"},
+	}
+	s.TaskStatusFunc = func(taskID string) (map[string]interface{}, error) {
+		if task, ok := tasks[taskID]; ok {
+			return task, nil
+		}
+		return nil, fmt.Errorf("task not found")
+	}
+	s.TaskSubmitFunc = func(req *TaskSubmitRequest) (map[string]interface{}, error) {
+		task, ok := tasks[req.TaskID]
+		if !ok || task["status"] != "accepted" {
+			return nil, fmt.Errorf("invalid status transition")
+		}
+		task["status"], task["stage"] = "delivered", "submitted"
+		return task, nil
+	}
+	s.TaskListFunc = func(status string, limit int) ([]map[string]interface{}, error) {
+		if status != "" && status != "accepted" && status != "delivered" {
+			return nil, fmt.Errorf("unknown status: %s", status)
+		}
+		var list []map[string]interface{}
+		for _, task := range tasks {
+			if status == "" || task["status"] == status {
+				list = append(list, task)
+			}
+		}
+		return list, nil
+	}
+	
+	w := httptest.NewRecorder()
+	s.handleTaskStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/task/status?task_id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown task: expected 404, got %d", w.Code)
+	}
+	
+	body := `{"task_id":"t1","result":"done"}`
+	w = httptest.NewRecorder()
+	s.handleTaskSubmit(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/submit", strings.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stage":"submitted"`) {
+		t.Fatalf("submit: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.handleTaskSubmit(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/submit", strings.NewReader(body)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("second submit: expected 409, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleTaskStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/task/status?task_id=t1", nil))
+	if !strings.Contains(w.Body.String(), `"status":"delivered"`) {
+		t.Errorf("status should reflect submission: %s", w.Body.String())
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleTaskList(w, httptest.NewRequest(http.MethodGet, "/api/v1/task/list?status=delivered", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"count":1`) {
+		t.Errorf("list: expected one delivered task, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.handleTaskList(w, httptest.NewRequest(http.MethodGet, "/api/v1/task/list?status=bogus", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad status filter: expected 400, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleTaskConfirm(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/confirm", strings.NewReader(`{"task_id":"t1"}`)))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("confirm without a task book: expected 501, got %d", w.Code)
+	}
+	transition := func(from, to, stage string) func(taskID string) (map[string]interface{}, error) {
+		return func(taskID string) (map[string]interface{}, error) {
+			task, ok := tasks[taskID]
+			if !ok || task["status"] != from {
+				return nil, fmt.Errorf("invalid status transition")
+			}
+			task["status"], task["stage"] = to, stage
+			return task, nil
+		}
+	}
+	s.TaskConfirmFunc = transition("delivered", "verified", "verified")
+	s.TaskSettleFunc = transition("verified", "settled", "rewarded")
+	
+	w = httptest.NewRecorder()
+	s.handleTaskSettle(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/settle", strings.NewReader(`{"task_id":"t1"}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("settle before confirmation: expected 409, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.handleTaskConfirm(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/confirm", strings.NewReader(`{"task_id":"t1"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"verified"`) {
+		t.Fatalf("confirm: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.handleTaskSettle(w, httptest.NewRequest(http.MethodPost, "/api/v1/task/settle", strings.NewReader(`{"task_id":"t1"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"stage":"rewarded"`) {
+		t.Errorf("settle: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleBulletinSubscriptionFilter(t *testing.T) {
+	s := createTestServer()
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	
+	filters := make(map[string]*BulletinFilter)
+	s.BulletinSubscribeFunc = func(topic string, filter *BulletinFilter) error {
+		filters[topic] = filter
+		return nil
+	}
+	s.BulletinFilterFunc = func(topic string, filter *BulletinFilter) error {
+		if _, ok := filters[topic]; !ok {
+			return errors.New("not subscribed to topic")
+		}
+		filters[topic] = filter
+		return nil
+	}
+	s.BulletinSubscriptionsFunc = func() []*BulletinSubscription {
+		var subs []*BulletinSubscription
+		for topic, f := range filters {
+			subs = append(subs, &BulletinSubscription{Topic: topic, Filter: f})
+		}
+		return subs
+	}
+	
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	
+	w := post("/api/v1/bulletin/subscribe", map[string]interface{}{
+		"topic":  "market",
+		"filter": BulletinFilter{Keywords: []string{"gpu"}, MinReputation: 50},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("subscribe: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if f := filters["market"]; f == nil || f.Keywords[0] != "gpu" || f.MinReputation != 50 {
+		t.Errorf("filter not passed through: %+v", f)
+	}
+	
+	w = post("/api/v1/bulletin/subscription/filter", map[string]interface{}{
+		"topic":  "market",
+		"filter": BulletinFilter{Languages: []string{"zh"}},
+	})
+	if w.Code != http.StatusOK || filters["market"].Languages[0] != "zh" {
+		t.Errorf("update filter: got %d, filter %+v", w.Code, filters["market"])
+	}
+	if w := post("/api/v1/bulletin/subscription/filter", map[string]interface{}{"topic": "other"}); w.Code != http.StatusBadRequest {
+		t.Errorf("filter on unknown topic: expected 400, got %d", w.Code)
+	}
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/subscriptions", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var resp struct {
+		Data struct {
+			Subscriptions []*BulletinSubscription `json:"subscriptions"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Subscriptions) != 1 || resp.Data.Subscriptions[0].Filter == nil {
+		t.Errorf("unexpected subscriptions: %s", w.Body.String())
+	}
+	
+	s.BulletinFilterFunc = nil
+	if w := post("/api/v1/bulletin/subscription/filter", map[string]interface{}{"topic": "market"}); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501 without filter support, got %d", w.Code)
+	}
+}
+
+func TestHandleAccusationAnalyze(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("with node_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/accusation/analyze?node_id=node1", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAccusationAnalyze(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+	
+	t.Run("missing node_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/accusation/analyze", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAccusationAnalyze(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestExtractPathParam(t *testing.T) {
+	t.Run("valid prefix", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/bulletin/message/msg123", nil)
+		
+		param := extractPathParam(req, "/api/v1/bulletin/message/")
+		if param != "msg123" {
+			t.Errorf("expected 'msg123', got %s", param)
+		}
+	})
+	
+	t.Run("no match", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/other/path", nil)
+		
+		param := extractPathParam(req, "/api/v1/bulletin/message/")
+		if param != "" {
+			t.Errorf("expected empty string, got %s", param)
+		}
+	})
+}
+
+func TestHandleTrustEndorsements(t *testing.T) {
+	s := createTestServer()
+	
+	var endorsed *EndorseRequest
+	s.TrustEndorseFunc = func(req *EndorseRequest) (map[string]interface{}, error) {
+		endorsed = req
+		return map[string]interface{}{"subject": req.Subject}, nil
+	}
+	s.TrustEndorsementsFunc = func(nodeID string) []map[string]interface{} {
+		return []map[string]interface{}{{"endorser": "A", "subject": nodeID}}
+	}
+	s.TrustDistanceFunc = func(nodeID string) int {
+		if nodeID == "B" {
+			return 1
+		}
+		return -1
+	}
+	
+	t.Run("endorse", func(t *testing.T) {
+		body := `{"subject":"B","subject_key":"abcd","statement":"met in person"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/trust/endorse", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		
+		s.handleTrustEndorse(w, req)
+		
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+		if endorsed == nil || endorsed.Statement != "met in person" {
+			t.Errorf("unexpected endorse request: %+v", endorsed)
+		}
+	})
+	
+	t.Run("endorse missing key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/trust/endorse", bytes.NewBufferString(`{"subject":"B"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleTrustEndorse(w, req)
+		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/trust/endorsements?node_id=B", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleTrustEndorsements(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["count"].(float64) != 1 {
+			t.Errorf("expected 1 endorsement, got %v", data["count"])
+		}
+	})
+	
+	t.Run("distance", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/trust/distance?node_id=B", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleTrustDistance(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["distance"].(float64) != 1 || data["endorsed"] != true {
+			t.Errorf("unexpected distance response: %v", data)
+		}
+	})
+}
+
+func TestHandleTrustProofs(t *testing.T) {
+	s := createTestServer()
+	
+	var created *IdentityProofRequest
+	s.TrustProofCreateFunc = func(req *IdentityProofRequest) (map[string]interface{}, error) {
+		created = req
+		return map[string]interface{}{"id": "p1", "token": "daan-identity-proof:v1;..."}, nil
+	}
+	s.TrustProofVerifyFunc = func(proofID string) (map[string]interface{}, error) {
+		if proofID != "p1" {
+			return nil, fmt.Errorf("identity proof not found")
+		}
+		return map[string]interface{}{"id": proofID, "status": "verified"}, nil
+	}
+	s.TrustProofsFunc = func(nodeID string) map[string]interface{} {
+		return map[string]interface{}{"node_id": nodeID, "starter_reputation": 5.0}
+	}
+	
+	t.Run("create", func(t *testing.T) {
+		body := `{"kind":"github_gist","handle":"alice","location":"https://gist.github.com/alice/1"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/trust/proof", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		
+		s.handleTrustProofCreate(w, req)
+		
+		if w.Code != http.StatusOK || created == nil || created.Handle != "alice" {
+			t.Errorf("expected proof to be created, got %d %+v", w.Code, created)
+		}
+	})
+	
+	t.Run("create validation", func(t *testing.T) {
+		for _, body := range []string{
+			`{"kind":"twitter","handle":"alice","location":"https://x.com/alice"}`,
+			`{"kind":"moltbook","handle":"alice"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/trust/proof", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+			
+			s.handleTrustProofCreate(w, req)
+			
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected status 422, got %d", body, w.Code)
+			}
+		}
+	})
+	
+	t.Run("verify", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/trust/proof/verify", bytes.NewBufferString(`{"id":"p2"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleTrustProofVerify(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/trust/proofs?node_id=B", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleTrustProofs(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["node_id"] != "B" || data["starter_reputation"].(float64) != 5 {
+			t.Errorf("unexpected proofs response: %v", data)
+		}
+	})
+}
+
+func TestHandleAdmission(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("not available", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admission/status", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAdmissionStatus(w, req)
+		
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	invitations := map[string]string{}
+	s.AdmissionInviteFunc = func(req *AdmissionInviteRequest) (map[string]interface{}, error) {
+		invitations["inv1"] = req.PeerID
+		return map[string]interface{}{"id": "inv1", "invitee": req.PeerID}, nil
+	}
+	s.AdmissionRevokeFunc = func(id string) error {
+		if _, ok := invitations[id]; !ok {
+			return fmt.Errorf("invitation not found")
+		}
+		delete(invitations, id)
+		return nil
+	}
+	s.AdmissionImportFunc = func(invitation []byte) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("inviter is not a trusted peer")
+	}
+	
+	t.Run("invite", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admission/invite", bytes.NewBufferString(`{"peer_id":"B","ttl":3600}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAdmissionInvite(w, req)
+		
+		if w.Code != http.StatusOK || invitations["inv1"] != "B" {
+			t.Errorf("expected invitation for B, got %d %v", w.Code, invitations)
+		}
+	})
+	
+	t.Run("invite validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admission/invite", bytes.NewBufferString(`{"note":"no peer"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAdmissionInvite(w, req)
+		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("revoke", func(t *testing.T) {
+		for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admission/invite/revoke", bytes.NewBufferString(`{"id":"inv1"}`))
+			w := httptest.NewRecorder()
+			
+			s.handleAdmissionRevoke(w, req)
+			
+			if w.Code != want {
+				t.Errorf("expected status %d, got %d", want, w.Code)
+			}
+		}
+	})
+	
+	t.Run("import untrusted", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admission/invitations/import", bytes.NewBufferString(`{"id":"inv2"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAdmissionImport(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("list empty", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admission/invitations", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAdmissionInvitations(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if list, ok := data["invitations"].([]interface{}); !ok || len(list) != 0 {
+			t.Errorf("expected empty invitation list, got %v", data)
+		}
+	})
+}
+
+func TestHandleAudit(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("not available", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audit/deviations", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAuditDeviations(w, req)
+		
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	var penalties []string
+	s.AuditTranscriptFunc = func(taskID string) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("%w: transcript", ErrNotFound)
+		}
+		return map[string]interface{}{"task_id": taskID, "result_hash": "abc"}, nil
+	}
+	s.AuditRunFunc = func(req *AuditRunRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": req.TaskID, "passed": req.ResultHash == "abc"}, nil
+	}
+	s.AuditDeviationsFunc = func(auditor string, limit int) []map[string]interface{} {
+		return []map[string]interface{}{{"auditor": auditor, "severity": "severe"}}
+	}
+	s.AuditSetPenaltyFunc = func(config PenaltyConfig) error { return nil }
+	s.AuditManualPenaltyFunc = func(nodeID, severity, reason string) (map[string]interface{}, error) {
+		penalties = append(penalties, nodeID+":"+severity)
+		return map[string]interface{}{"node_id": nodeID, "rep_delta": -5.0}, nil
+	}
+	
+	t.Run("transcript", func(t *testing.T) {
+		for taskID, want := range map[string]int{"task_1": http.StatusOK, "task_2": http.StatusNotFound} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/audit/transcript?task_id="+taskID, nil)
+			w := httptest.NewRecorder()
+			
+			s.handleAuditTranscript(w, req)
+			
+			if w.Code != want {
+				t.Errorf("%s: expected status %d, got %d", taskID, want, w.Code)
+			}
+		}
+	})
+	
+	t.Run("run", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/audit/run", bytes.NewBufferString(`{"task_id":"task_1","result_hash":"abc"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAuditRun(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["passed"] != true {
+			t.Errorf("expected passed audit, got %d %v", w.Code, data)
+		}
+	})
+	
+	t.Run("consensus not available", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audit/consensus?task_id=task_1", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAuditConsensus(w, req)
+		
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	t.Run("deviations", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/audit/deviations?auditor=sn1", nil)
+		w := httptest.NewRecorder()
+		
+		s.handleAuditDeviations(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["total"] != float64(1) {
+			t.Errorf("expected one deviation, got %d %v", w.Code, data)
+		}
+	})
+	
+	t.Run("penalty config validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/audit/penalty-config", bytes.NewBufferString(`{"severity":"fatal","rep_penalty":5}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAuditPenaltyConfig(w, req)
+		
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("manual penalty", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/audit/manual-penalty", bytes.NewBufferString(`{"node_id":"sn1","severity":"minor"}`))
+		w := httptest.NewRecorder()
+		
+		s.handleAuditManualPenalty(w, req)
+		
+		if w.Code != http.StatusOK || len(penalties) != 1 || penalties[0] != "sn1:minor" {
+			t.Errorf("expected penalty for sn1, got %d %v", w.Code, penalties)
+		}
+	})
+}
+
+func TestHandleLedgerVerify(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/verify", nil)
+	w := httptest.NewRecorder()
+	s.handleLedgerVerify(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.LedgerVerifyFunc = func() map[string]interface{} {
+		return map[string]interface{}{"ok": false, "issues": 1}
+	}
+	w = httptest.NewRecorder()
+	s.handleLedgerVerify(w, req)
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["ok"] != false || data["issues"] != 1.0 {
+		t.Errorf("verify = %d %s", w.Code, w.Body.String())
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleLedgerVerify(w, httptest.NewRequest(http.MethodPost, "/api/v1/ledger/verify", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleLedgerUsage(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/usage", nil)
+	w := httptest.NewRecorder()
+	s.handleLedgerUsage(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.LedgerUsageFunc = func() map[string]interface{} {
+		return map[string]interface{}{"types": []map[string]interface{}{{"type": "HEARTBEAT", "events": 3}}}
+	}
+	w = httptest.NewRecorder()
+	s.handleLedgerUsage(w, req)
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if types, _ := data["types"].([]interface{}); w.Code != http.StatusOK || len(types) != 1 {
+		t.Errorf("usage = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleTaskTemplates(t *testing.T) {
+	s := createTestServer()
+	
+	templates := map[string]map[string]interface{}{}
+	s.TaskTemplateSaveFunc = func(template map[string]interface{}) (map[string]interface{}, error) {
+		template["id"] = "tpl_1"
+		templates["tpl_1"] = template
+		return template, nil
+	}
+	s.TaskTemplateListFunc = func() []map[string]interface{} {
+		list := []map[string]interface{}{}
+		for _, tpl := range templates {
+			list = append(list, tpl)
+		}
+		return list
+	}
+	s.TaskTemplateShareFunc = func(templateID string) (string, error) {
+		return "msg-1", nil
+	}
+	s.TaskFromTemplateFunc = func(req *TaskFromTemplateRequest) (map[string]interface{}, error) {
+		if _, ok := req.Params["query"]; !ok {
+			return nil, fmt.Errorf("missing required parameter: query")
+		}
+		return map[string]interface{}{"template_id": req.TemplateID, "title": "Search: " + req.Params["query"].(string)}, nil
+	}
+	
+	t.Run("save and list", func(t *testing.T) {
+		body := `{"name":"Paper search","type":"search","title_pattern":"Search: {{query}}"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/template/save", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		s.handleTaskTemplateSave(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/task/template/list", nil)
+		w = httptest.NewRecorder()
+		s.handleTaskTemplateList(w, req)
+		
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["count"].(float64) != 1 {
+			t.Errorf("expected 1 template, got %v", data["count"])
+		}
+	})
+	
+	t.Run("share", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/template/share", bytes.NewBufferString(`{"template_id":"tpl_1"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskTemplateShare(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+	
+	t.Run("create from template", func(t *testing.T) {
+		body := `{"template_id":"tpl_1","params":{"query":"LLM"}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/from-template", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		s.handleTaskFromTemplate(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+	
+	t.Run("invalid params", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/from-template", bytes.NewBufferString(`{"template_id":"tpl_1"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskFromTemplate(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("missing template id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/from-template", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		s.handleTaskFromTemplate(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleTransfer(t *testing.T) {
+	s := createTestServer()
+	
+	transfers := map[string]map[string]interface{}{}
+	s.TransferSendFunc = func(peerID, path string) (map[string]interface{}, error) {
+		if path == "/missing" {
+			return nil, fmt.Errorf("no such file")
+		}
+		transfers["transfer_1"] = map[string]interface{}{"id": "transfer_1", "receiver_id": peerID, "status": "pending"}
+		return transfers["transfer_1"], nil
+	}
+	s.TransferStatusFunc = func(transferID string) (map[string]interface{}, error) {
+		if t, ok := transfers[transferID]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("transfer not found")
+	}
+	s.TransferPauseFunc = func(transferID string) error {
+		if _, ok := transfers[transferID]; !ok {
+			return fmt.Errorf("transfer not found")
+		}
+		transfers[transferID]["status"] = "paused"
+		return nil
+	}
+	s.TransferListFunc = func(status string) []map[string]interface{} {
+		list := []map[string]interface{}{}
+		for _, t := range transfers {
+			if status == "" || t["status"] == status {
+				list = append(list, t)
+			}
+		}
+		return list
+	}
+	
+	t.Run("send", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfer/send", bytes.NewBufferString(`{"peer_id":"peer1","path":"/tmp/a.bin"}`))
+		w := httptest.NewRecorder()
+		s.handleTransferSend(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/transfer/send", bytes.NewBufferString(`{"peer_id":"peer1","path":"/missing"}`))
+		w = httptest.NewRecorder()
+		s.handleTransferSend(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/transfer/status/transfer_1", nil)
+		w := httptest.NewRecorder()
+		s.handleTransferStatus(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/transfer/status/unknown", nil)
+		w = httptest.NewRecorder()
+		s.handleTransferStatus(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+	
+	t.Run("pause and list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfer/pause", bytes.NewBufferString(`{"transfer_id":"transfer_1"}`))
+		w := httptest.NewRecorder()
+		s.handleTransferPause(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/transfer/list?status=paused", nil)
+		w = httptest.NewRecorder()
+		s.handleTransferList(w, req)
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data := resp.Data.(map[string]interface{})
+		if data["count"].(float64) != 1 {
+			t.Errorf("expected 1 paused transfer, got %v", data["count"])
+		}
+	})
+	
+	t.Run("resume not configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfer/resume", bytes.NewBufferString(`{"transfer_id":"transfer_1"}`))
+		w := httptest.NewRecorder()
+		s.handleTransferResume(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+	
+	t.Run("accept and reject", func(t *testing.T) {
+		transfers["transfer_2"] = map[string]interface{}{"id": "transfer_2", "status": "pending"}
+		s.TransferAcceptFunc = func(transferID string) error {
+			if transfers[transferID]["status"] != "pending" {
+				return fmt.Errorf("cannot accept")
+			}
+			transfers[transferID]["status"] = "accepted"
+			return nil
+		}
+		s.TransferRejectFunc = func(transferID string) error {
+			if transfers[transferID]["status"] != "pending" {
+				return fmt.Errorf("cannot reject")
+			}
+			transfers[transferID]["status"] = "cancelled"
+			return nil
+		}
+		
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transfer/accept", bytes.NewBufferString(`{"transfer_id":"transfer_2"}`))
+		w := httptest.NewRecorder()
+		s.handleTransferAccept(w, req)
+		if w.Code != http.StatusOK || transfers["transfer_2"]["status"] != "accepted" {
+			t.Fatalf("accept: expected 200, got %d %s", w.Code, w.Body.String())
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/transfer/reject", bytes.NewBufferString(`{"transfer_id":"transfer_2"}`))
+		w = httptest.NewRecorder()
+		s.handleTransferReject(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("rejecting an accepted transfer: expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleRetention(t *testing.T) {
+	s := createTestServer()
+	
+	var lastActor string
+	s.RetentionHoldFunc = func(actor string, req *RetentionHoldRequest) (map[string]interface{}, error) {
+		if req.Kind != "dispute" {
+			return nil, fmt.Errorf("invalid hold kind")
+		}
+		lastActor = actor
+		return map[string]interface{}{"id": "hold_1", "kind": req.Kind, "target": req.Target}, nil
+	}
+	s.RetentionReleaseFunc = func(holdID, actor, reason string) (map[string]interface{}, error) {
+		if holdID != "hold_1" {
+			return nil, fmt.Errorf("retention hold not found")
+		}
+		lastActor = actor
+		return map[string]interface{}{"id": holdID, "released_by": actor}, nil
+	}
+	
+	t.Run("hold requires reason", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/retention/hold", bytes.NewBufferString(`{"kind":"dispute","target":"d1"}`))
+		w := httptest.NewRecorder()
+		s.handleRetentionHold(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("hold and release", func(t *testing.T) {
+		// 请求体中的 actor 不被采信，未签名时记为本节点
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/retention/hold", bytes.NewBufferString(`{"kind":"dispute","target":"d1","reason":"litigation","actor":"alice"}`))
+		w := httptest.NewRecorder()
+		s.handleRetentionHold(w, req)
+		if w.Code != http.StatusOK || lastActor != "test-node" {
+			t.Fatalf("expected status 200 with the local node as actor, got %d (%s)", w.Code, lastActor)
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/retention/release", bytes.NewBufferString(`{"hold_id":"hold_1"}`))
+		req = req.WithContext(context.WithValue(req.Context(), identityContextKey{}, "bob"))
+		w = httptest.NewRecorder()
+		s.handleRetentionRelease(w, req)
+		if w.Code != http.StatusOK || lastActor != "bob" {
+			t.Fatalf("expected status 200 with the verified actor bob, got %d (%s)", w.Code, lastActor)
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/retention/release", bytes.NewBufferString(`{"hold_id":"hold_x"}`))
+		w = httptest.NewRecorder()
+		s.handleRetentionRelease(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("audit not configured", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/retention/audit", nil)
+		w := httptest.NewRecorder()
+		s.handleRetentionAudit(w, req)
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleTaskAccept(t *testing.T) {
+	s := createTestServer()
+	s.AcceptTaskFunc = func(req *TaskAcceptRequest) (map[string]interface{}, error) {
+		for _, f := range req.PayloadFormats {
+			if f == "application/wasm" {
+				return map[string]interface{}{"task_id": req.TaskID, "payload_format": f}, nil
+			}
+		}
+		return nil, fmt.Errorf("payload format mismatch: task offers [application/wasm], agent supports %v", req.PayloadFormats)
+	}
+	
+	t.Run("negotiated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/accept", bytes.NewBufferString(`{"task_id":"task_1","payload_formats":["application/json","application/wasm"]}`))
+		w := httptest.NewRecorder()
+		s.handleTaskAccept(w, req)
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || resp.Data.(map[string]interface{})["payload_format"] != "application/wasm" {
+			t.Fatalf("expected negotiated wasm, got %d %v", w.Code, resp.Data)
+		}
+	})
+	
+	t.Run("mismatch", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/accept", bytes.NewBufferString(`{"task_id":"task_1","payload_formats":["application/json"]}`))
+		w := httptest.NewRecorder()
+		s.handleTaskAccept(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", w.Code)
+		}
+	})
+	
+	t.Run("unknown format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/accept", bytes.NewBufferString(`{"task_id":"task_1","payload_formats":["text/x-python"]}`))
+		w := httptest.NewRecorder()
+		s.handleTaskAccept(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleTaskSubcontract(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/task/subcontract", bytes.NewBufferString(`{"parent_task_id":"task_1","title":"part","reward":2}`))
+	w := httptest.NewRecorder()
+	s.handleTaskSubcontract(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.TaskSubcontractFunc = func(req *TaskSubcontractRequest) (map[string]interface{}, error) {
+		if req.Reward > 8 {
+			return nil, fmt.Errorf("subcontract budget exceeded")
+		}
+		return map[string]interface{}{"task_id": "task_2", "parent_task_id": req.ParentTaskID}, nil
+	}
+	s.TaskSubcontractChainFunc = func(taskID string) ([]map[string]interface{}, error) {
+		if taskID != "task_2" {
+			return nil, fmt.Errorf("task not found")
+		}
+		return []map[string]interface{}{{"id": "task_1"}, {"id": "task_2"}}, nil
+	}
+	
+	t.Run("missing reward", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/subcontract", bytes.NewBufferString(`{"parent_task_id":"task_1","title":"part"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskSubcontract(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("over budget", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/subcontract", bytes.NewBufferString(`{"parent_task_id":"task_1","title":"part","reward":9}`))
+		w := httptest.NewRecorder()
+		s.handleTaskSubcontract(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("subcontract and chain", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/subcontract", bytes.NewBufferString(`{"parent_task_id":"task_1","title":"part","reward":2}`))
+		w := httptest.NewRecorder()
+		s.handleTaskSubcontract(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/task/chain?task_id=task_2", nil)
+		w = httptest.NewRecorder()
+		s.handleTaskChain(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		if data["depth"] != float64(1) {
+			t.Errorf("expected depth 1, got %v", data["depth"])
+		}
+	})
+}
+
+func TestHandleTaskVerification(t *testing.T) {
+	s := createTestServer()
+	
+	t.Run("conditional fields", func(t *testing.T) {
+		for _, body := range []string{
+			`{"type":"compute","verification":"hash"}`,
+			`{"type":"compute","verification":"redundant","redundancy_total":3}`,
+			`{"type":"compute","verification":"vote"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/task/create", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+			s.handleCreateTask(w, req)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected status 422, got %d", body, w.Code)
+			}
+		}
+	})
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/task/audit", bytes.NewBufferString(`{"task_id":"task_1","decision":"approve"}`))
+	w := httptest.NewRecorder()
+	s.handleTaskAudit(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.TaskAuditFunc = func(taskID string, approved bool, note string) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("not allowed by task verification method")
+		}
+		return map[string]interface{}{"task_id": taskID, "passed": approved, "decided": true}, nil
+	}
+	s.TaskVerificationFunc = func(taskID string) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": taskID, "method": "supernode_audit", "decided": false}, nil
+	}
+	
+	t.Run("reject", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/audit", bytes.NewBufferString(`{"task_id":"task_1","decision":"reject","note":"output differs"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskAudit(w, req)
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["passed"] != false {
+			t.Errorf("expected rejected audit, got %d %v", w.Code, data)
+		}
+	})
+	
+	t.Run("wrong method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/audit", bytes.NewBufferString(`{"task_id":"task_2","decision":"approve"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskAudit(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", w.Code)
+		}
+	})
+	
+	t.Run("query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task/verification?task_id=task_1", nil)
+		w := httptest.NewRecorder()
+		s.handleTaskVerification(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleTaskReviewers(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/task/reviewers/assign", bytes.NewBufferString(`{"task_id":"task_1","count":3}`))
+	w := httptest.NewRecorder()
+	s.handleTaskReviewersAssign(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.TaskReviewersAssignFunc = func(taskID string, count int) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": taskID, "required": count}, nil
+	}
+	s.TaskReviewRespondFunc = func(taskID string, accept bool) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("not an assigned reviewer")
+		}
+		return map[string]interface{}{"task_id": taskID, "accepted": accept}, nil
+	}
+	s.TaskReviewersFunc = func(taskID string) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": taskID, "reviewers": []interface{}{}}, nil
+	}
+	
+	t.Run("assign", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/reviewers/assign", bytes.NewBufferString(`{"task_id":"task_1","count":3}`))
+		w := httptest.NewRecorder()
+		s.handleTaskReviewersAssign(w, req)
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["required"] != 3.0 {
+			t.Errorf("expected assignment, got %d %v", w.Code, data)
+		}
+	})
+	
+	t.Run("invalid count", func(t *testing.T) {
+		for _, body := range []string{`{"task_id":"task_1"}`, `{"task_id":"task_1","count":100}`} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/task/reviewers/assign", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+			s.handleTaskReviewersAssign(w, req)
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("%s: expected status 422, got %d", body, w.Code)
+			}
+		}
+	})
+	
+	t.Run("respond", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/reviewers/respond", bytes.NewBufferString(`{"task_id":"task_1","decision":"decline"}`))
+		w := httptest.NewRecorder()
+		s.handleTaskReviewRespond(w, req)
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if w.Code != http.StatusOK || data["accepted"] != false {
+			t.Errorf("expected declined response, got %d %v", w.Code, data)
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/task/reviewers/respond", bytes.NewBufferString(`{"task_id":"task_2","decision":"accept"}`))
+		w = httptest.NewRecorder()
+		s.handleTaskReviewRespond(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", w.Code)
+		}
+	})
+	
+	t.Run("query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task/reviewers?task_id=task_1", nil)
+		w := httptest.NewRecorder()
+		s.handleTaskReviewers(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+}
+
+func TestHandleTaskStorage(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/task/storage/commit", bytes.NewBufferString(`{"task_id":"task_1","path":"/data/f"}`))
+	w := httptest.NewRecorder()
+	s.handleTaskStorageCommit(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var committed *TaskStorageRequest
+	s.TaskStorageCommitFunc = func(req *TaskStorageRequest) (map[string]interface{}, error) {
+		committed = req
+		return map[string]interface{}{"task_id": req.TaskID, "chunk_count": 1}, nil
+	}
+	s.TaskStorageHoldFunc = func(req *TaskStorageRequest) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("task not assigned to me")
+	}
+	s.TaskStorageChallengeFunc = func(taskID string) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": taskID, "status": "passed"}, nil
+	}
+	s.TaskStorageChallengesFunc = func(taskID string) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("storage commitment not found")
+		}
+		return map[string]interface{}{"challenges": []interface{}{}}, nil
+	}
+	
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		path    string
+		body    string
+		status  int
+	}{
+		{"commit", s.handleTaskStorageCommit, http.MethodPost, "/api/v1/task/storage/commit", `{"task_id":"task_1","path":"/data/f"}`, http.StatusOK},
+		{"commit without path", s.handleTaskStorageCommit, http.MethodPost, "/api/v1/task/storage/commit", `{"task_id":"task_1"}`, http.StatusUnprocessableEntity},
+		{"hold rejected", s.handleTaskStorageHold, http.MethodPost, "/api/v1/task/storage/hold", `{"task_id":"task_2","path":"/data/f"}`, http.StatusConflict},
+		{"challenge", s.handleTaskStorageChallenge, http.MethodPost, "/api/v1/task/storage/challenge", `{"task_id":"task_1"}`, http.StatusOK},
+		{"challenges", s.handleTaskStorageChallenges, http.MethodGet, "/api/v1/task/storage/challenges?task_id=task_1", "", http.StatusOK},
+		{"challenges unknown", s.handleTaskStorageChallenges, http.MethodGet, "/api/v1/task/storage/challenges?task_id=task_9", "", http.StatusNotFound},
+		{"challenges without task", s.handleTaskStorageChallenges, http.MethodGet, "/api/v1/task/storage/challenges", "", http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+	if committed == nil || committed.Path != "/data/f" {
+		t.Errorf("expected commit request to reach the hook, got %+v", committed)
+	}
+}
+
+func TestHandleTaskProgress(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/task/progress/task_1", nil)
+	w := httptest.NewRecorder()
+	s.handleTaskProgress(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var reported *TaskProgressRequest
+	s.TaskProgressReportFunc = func(req *TaskProgressRequest) (map[string]interface{}, error) {
+		if req.TaskID != "task_1" {
+			return nil, fmt.Errorf("task not assigned to me")
+		}
+		reported = req
+		return map[string]interface{}{"task_id": req.TaskID, "seq": 1, "percent": req.Percent}, nil
+	}
+	s.TaskProgressFunc = func(taskID string) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("task not found")
+		}
+		return map[string]interface{}{"task_id": taskID, "executors": []interface{}{}}, nil
+	}
+	
+	t.Run("report", func(t *testing.T) {
+		body := `{"task_id":"task_1","percent":40,"checkpoint":"sha256:ab","log":"step 4/10\n"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/progress", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		s.handleTaskProgressReport(w, req)
+		if w.Code != http.StatusOK || reported == nil || reported.Percent != 40 || reported.Checkpoint != "sha256:ab" {
+			t.Errorf("expected reported progress, got %d %+v", w.Code, reported)
+		}
+	})
+	
+	t.Run("invalid percent", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/progress", bytes.NewBufferString(`{"task_id":"task_1","percent":120}`))
+		w := httptest.NewRecorder()
+		s.handleTaskProgressReport(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422, got %d", w.Code)
+		}
+	})
+	
+	t.Run("not executor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/task/progress", bytes.NewBufferString(`{"task_id":"task_2","percent":10}`))
+		w := httptest.NewRecorder()
+		s.handleTaskProgressReport(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status 409, got %d", w.Code)
+		}
+	})
+	
+	t.Run("query", func(t *testing.T) {
+		for path, status := range map[string]int{
+			"/api/v1/task/progress/task_1": http.StatusOK,
+			"/api/v1/task/progress/task_9": http.StatusNotFound,
+			"/api/v1/task/progress/":       http.StatusBadRequest,
+		} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			s.handleTaskProgress(w, req)
+			if w.Code != status {
+				t.Errorf("%s: expected status %d, got %d", path, status, w.Code)
+			}
+		}
+	})
+}
+
+func TestHandleTaskOffers(t *testing.T) {
+	s := createTestServer()
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/task/offers", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var gotLimit int
+	s.TaskOffersFunc = func(limit int) []map[string]interface{} {
+		gotLimit = limit
+		return []map[string]interface{}{{"rank": 1, "task": map[string]interface{}{"task_id": "task_1"}}}
+	}
+	s.TaskOfferScoreFunc = func(taskID string) (map[string]interface{}, error) {
+		if taskID != "task_1" {
+			return nil, fmt.Errorf("offer not found")
+		}
+		return map[string]interface{}{"task_id": taskID, "total": 0.5, "factors": map[string]interface{}{}}, nil
+	}
+	var applied *TaskOfferPolicyRequest
+	s.TaskOfferPolicyFunc = func() map[string]interface{} {
+		return map[string]interface{}{"budget_weight": 0.35}
+	}
+	s.TaskOfferPolicySetFunc = func(req *TaskOfferPolicyRequest) (map[string]interface{}, error) {
+		if req.BudgetWeight+req.ReputationWeight+req.DeadlineWeight+req.CapabilityWeight == 0 {
+			return nil, fmt.Errorf("invalid priority policy: weights must sum to a positive value")
+		}
+		applied = req
+		return map[string]interface{}{"budget_weight": req.BudgetWeight}, nil
+	}
+	
+	t.Run("list", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task/offers?limit=5", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || gotLimit != 5 {
+			t.Errorf("expected ranked offers with limit 5, got %d limit=%d", w.Code, gotLimit)
+		}
+	})
+	
+	t.Run("score", func(t *testing.T) {
+		for path, status := range map[string]int{
+			"/api/v1/task/offers/task_1": http.StatusOK,
+			"/api/v1/task/offers/task_9": http.StatusNotFound,
+		} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != status {
+				t.Errorf("%s: expected status %d, got %d", path, status, w.Code)
+			}
+		}
+	})
+	
+	t.Run("policy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/task/offers/policy", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+		
+		base := `"reference_budget_per_unit":10,"reference_reputation":100,"deadline_horizon_seconds":3600`
+		for body, status := range map[string]int{
+			`{"budget_weight":0.5,` + base + `}`: http.StatusOK,
+			`{"budget_weight":-1,` + base + `}`:  http.StatusUnprocessableEntity,
+			`{"budget_weight":0,` + base + `}`:   http.StatusUnprocessableEntity,
+			`{"budget_weight":0.5}`:              http.StatusUnprocessableEntity,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/task/offers/policy", bytes.NewBufferString(body))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != status {
+				t.Errorf("%s: expected status %d, got %d", body, status, w.Code)
+			}
+		}
+		if applied == nil || applied.DeadlineHorizonSeconds != 3600 {
+			t.Errorf("expected policy update to be applied, got %+v", applied)
+		}
+	})
+}
+
+func TestHandleGossipTuning(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/network/gossip-tuning", nil)
+	w := httptest.NewRecorder()
+	s.handleGossipTuning(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.GossipTuningFunc = func() map[string]interface{} {
+		return map[string]interface{}{
+			"current": map[string]interface{}{"d": 4, "heartbeat_interval": 2000000000},
+			"load":    0.8,
+		}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleGossipTuning(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if data["load"] != 0.8 {
+		t.Errorf("expected load 0.8, got %v", data["load"])
+	}
+	
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/network/gossip-tuning", nil)
+	w = httptest.NewRecorder()
+	s.handleGossipTuning(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleOutboundFairness(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/network/outbound", nil)
+	w := httptest.NewRecorder()
+	s.handleOutboundFairness(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.OutboundFairnessFunc = func() map[string]interface{} {
+		return map[string]interface{}{
+			"rate":        10485760,
+			"total_bytes": 4096,
+			"peers": []map[string]interface{}{
+				{"peer_id": "12D3KooWA", "weight": 2, "share": 0.75},
+			},
+		}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleOutboundFairness(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if peers, _ := data["peers"].([]interface{}); len(peers) != 1 {
+		t.Errorf("expected 1 peer, got %v", data["peers"])
+	}
+}
+
+func TestHandleProtocolCompat(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/network/protocol-compat", nil)
+	w := httptest.NewRecorder()
+	s.handleProtocolCompat(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.ProtocolCompatFunc = func() map[string]interface{} {
+		return map[string]interface{}{
+			"families":     []map[string]interface{}{{"name": "mailbox_key", "legacy_share": 0.25}},
+			"legacy_peers": map[string][]string{"/daan/mailbox/key/1.0.0": {"12D3KooWA"}},
+		}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleProtocolCompat(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if families, _ := data["families"].([]interface{}); len(families) != 1 {
+		t.Errorf("expected 1 family, got %v", data["families"])
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleProtocolCompat(w, httptest.NewRequest(http.MethodPost, "/api/v1/network/protocol-compat", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleGossipScores(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleGossipScores(w, httptest.NewRequest(http.MethodGet, "/api/v1/network/gossip-scores", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.GossipScoresFunc = func(peerID string) map[string]interface{} {
+		if peerID == "" {
+			return map[string]interface{}{"peers": []interface{}{}}
+		}
+		return map[string]interface{}{"peer_id": peerID, "status": "graylisted", "connected": false}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleGossipScores(w, httptest.NewRequest(http.MethodGet, "/api/v1/network/gossip-scores?peer_id=12D3KooWA", nil))
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["status"] != "graylisted" || data["peer_id"] != "12D3KooWA" {
+		t.Errorf("expected peer score, got %d %v", w.Code, data)
+	}
+}
+
+func TestHandlePeerQuota(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handlePeerQuota(w, httptest.NewRequest(http.MethodGet, "/api/v1/network/peer-quota", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.PeerQuotaFunc = func(peerID string) (map[string]interface{}, error) {
+		if peerID == "" {
+			return map[string]interface{}{"limits": map[string]interface{}{"mail_per_hour": 120}}, nil
+		}
+		if peerID != "12D3KooWA" {
+			return nil, fmt.Errorf("no quota state for %s", peerID)
+		}
+		return map[string]interface{}{"peer_id": peerID, "throttled": true}, nil
+	}
+	
+	w = httptest.NewRecorder()
+	s.handlePeerQuota(w, httptest.NewRequest(http.MethodGet, "/api/v1/network/peer-quota?peer_id=12D3KooWA", nil))
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["throttled"] != true {
+		t.Errorf("expected throttled peer state, got %d %v", w.Code, data)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handlePeerQuota(w, httptest.NewRequest(http.MethodGet, "/api/v1/network/peer-quota?peer_id=unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandleHeartbeatStatus(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleHeartbeatStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/heartbeat/status", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.HeartbeatStatusFunc = func(nodeID string) (map[string]interface{}, error) {
+		if nodeID == "" {
+			return map[string]interface{}{"online": 1, "peers": []interface{}{}}, nil
+		}
+		if nodeID != "12D3KooWA" {
+			return nil, fmt.Errorf("no heartbeat from %s", nodeID)
+		}
+		return map[string]interface{}{"node_id": nodeID, "liveness": 0.9}, nil
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleHeartbeatStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/heartbeat/status?node_id=12D3KooWA", nil))
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["liveness"] != 0.9 {
+		t.Errorf("expected peer liveness, got %d %v", w.Code, data)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleHeartbeatStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/heartbeat/status?node_id=unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleHeartbeatStatus(w, httptest.NewRequest(http.MethodPost, "/api/v1/heartbeat/status", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleClockSkew(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/network/clock-skew", nil)
+	w := httptest.NewRecorder()
+	s.handleClockSkew(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.ClockSkewFunc = func() map[string]interface{} {
+		return map[string]interface{}{
+			"mailbox": map[string]interface{}{"grace_ms": 120000, "within_grace": 3},
+		}
+	}
+	w = httptest.NewRecorder()
+	s.handleClockSkew(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if mb, _ := data["mailbox"].(map[string]interface{}); mb["within_grace"] != float64(3) {
+		t.Errorf("unexpected response: %v", resp.Data)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleClockSkew(w, httptest.NewRequest(http.MethodPost, "/api/v1/network/clock-skew", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleStorageKeys(t *testing.T) {
+	s := createTestServer()
+	
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/storage/keys", nil)
+	w := httptest.NewRecorder()
+	s.handleStorageKeys(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	active := "k1"
+	s.StorageKeysFunc = func() map[string]interface{} {
+		return map[string]interface{}{"active_key_id": active}
+	}
+	s.StorageRotateKeyFunc = func() (map[string]interface{}, error) {
+		active = "k2"
+		return map[string]interface{}{"active_key_id": active, "resealed": []string{"mailbox", "bulletin"}}, nil
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleStorageKeys(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/storage/keys", nil)
+	w = httptest.NewRecorder()
+	s.handleStorageKeys(w, req)
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if data["active_key_id"] != "k2" {
+		t.Errorf("expected rotated key k2, got %v", data["active_key_id"])
+	}
+	
+	s.StorageRotateKeyFunc = func() (map[string]interface{}, error) {
+		return nil, fmt.Errorf("disk full")
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/storage/keys", nil)
+	w = httptest.NewRecorder()
+	s.handleStorageKeys(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestCompatMode(t *testing.T) {
+	config := DefaultConfig("test-node")
+	config.APIToken = "node-token"
+	config.Compat = &CompatConfig{
+		Default: CompatMode{Casing: CasingPreserve},
+		Tokens: map[string]CompatMode{
+			"legacy-token": {Envelope: EnvelopeNone, Casing: CasingSnake},
+		},
+	}
+	s, _ := NewServer(config)
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	t.Run("standard envelope by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		if !resp.Success || resp.Data == nil {
+			t.Errorf("expected enveloped response, got %s", w.Body.String())
+		}
+	})
+	
+	t.Run("bare response via header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set(EnvelopeHeader, "none")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		var data map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&data)
+		if data["status"] != "ok" {
+			t.Errorf("expected bare data, got %v", data)
+		}
+		if _, ok := data["success"]; ok {
+			t.Error("bare response should not contain envelope fields")
+		}
+		if w.Header().Get(EnvelopeHeader) != "none" {
+			t.Error("expected envelope mode echoed in header")
+		}
+	})
+	
+	t.Run("token profile", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/info", nil)
+		req.Header.Set(TokenHeader, "legacy-token")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		if w.Code == http.StatusUnauthorized {
+			t.Fatal("compat token should be accepted")
+		}
+		var data map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&data)
+		if _, ok := data["success"]; ok {
+			t.Errorf("expected bare response for legacy token, got %v", data)
+		}
+	})
+	
+	t.Run("header overrides token profile", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set(TokenHeader, "legacy-token")
+		req.Header.Set(EnvelopeHeader, "standard")
+		req.Header.Set(CasingHeader, "camel")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		if _, ok := data["nodeId"]; !ok {
+			t.Errorf("expected camelCase keys, got %v", resp.Data)
+		}
+	})
+	
+	t.Run("bare error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/info", nil)
+		req.Header.Set(EnvelopeHeader, "none")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", w.Code)
+		}
+		var data map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&data)
+		if data["error"] == nil || data["success"] != nil {
+			t.Errorf("expected bare error, got %v", data)
+		}
+	})
+	
+	t.Run("invalid mode", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set(CasingHeader, "kebab")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestCaseConversion(t *testing.T) {
+	snake := map[string]string{
+		"nodeID":     "node_id",
+		"HTTPAddr":   "http_addr",
+		"peerCount":  "peer_count",
+		"node_id":    "node_id",
+		"status":     "status",
+		"lastSeenAt": "last_seen_at",
+	}
+	for in, want := range snake {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+	
+	camel := map[string]string{
+		"node_id":      "nodeId",
+		"last_seen_at": "lastSeenAt",
+		"status":       "status",
+		"peerCount":    "peerCount",
+	}
+	for in, want := range camel {
+		if got := toCamelCase(in); got != want {
+			t.Errorf("toCamelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+	
+	nested := convertKeys(map[string]interface{}{
+		"peerList": []interface{}{map[string]interface{}{"nodeID": "a", "score": 1.5}},
+	}, CasingSnake).(map[string]interface{})
+	list := nested["peer_list"].([]interface{})
+	if list[0].(map[string]interface{})["node_id"] != "a" {
+		t.Errorf("nested keys not converted: %v", nested)
+	}
+}
+
+func TestResponseSigning(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	
+	config := DefaultConfig("test-node")
+	config.AuthEnabled = false
+	config.ResponseSignFunc = func(data []byte) ([]byte, error) {
+		return ed25519.Sign(priv, data), nil
+	}
+	config.ResponseVerifyFunc = func(signerID string, data, signature []byte) (bool, error) {
+		if signerID != "test-node" {
+			return false, fmt.Errorf("unknown signer")
+		}
+		return ed25519.Verify(pub, data, signature), nil
+	}
+	config.SigningPublicKey = pub
+	config.SigningAlgorithm = "ed25519"
+	s, _ := NewServer(config)
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	t.Run("unsigned unless requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/info", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Header().Get(ResponseSignatureHeader) != "" {
+			t.Error("response should not be signed without X-Sign-Response")
+		}
+	})
+	
+	t.Run("signed response verifies", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/info?x=1", nil)
+		req.Header.Set(SignResponseHeader, "1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		
+		sig, err := base64.StdEncoding.DecodeString(w.Header().Get(ResponseSignatureHeader))
+		if err != nil || len(sig) == 0 {
+			t.Fatalf("missing signature header: %v", w.Header())
+		}
+		if w.Header().Get(ResponseSignerHeader) != "test-node" {
+			t.Errorf("unexpected signer %q", w.Header().Get(ResponseSignerHeader))
+		}
+		ts, _ := strconv.ParseInt(w.Header().Get(ResponseTimestampHeader), 10, 64)
+		
+		// 重新格式化后的响应体仍可验签
+		var pretty bytes.Buffer
+		json.Indent(&pretty, w.Body.Bytes(), "", "  ")
+		payload, err := ResponseSigningPayload("test-node", http.MethodGet, "/api/v1/node/info?x=1", http.StatusOK, ts, pretty.Bytes())
+		if err != nil {
+			t.Fatalf("ResponseSigningPayload() error = %v", err)
+		}
+		if !ed25519.Verify(pub, payload, sig) {
+			t.Fatal("signature does not verify over canonical body")
+		}
+		
+		// 通过验证端点校验，并确认换了路径即失效
+		verify := func(uri string) bool {
+			body, _ := json.Marshal(VerifyResponseRequest{
+				Signer:    "test-node",
+				Method:    http.MethodGet,
+				URI:       uri,
+				Status:    http.StatusOK,
+				Timestamp: ts,
+				Body:      json.RawMessage(pretty.Bytes()),
+				Signature: base64.StdEncoding.EncodeToString(sig),
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/node/verify-response", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			var resp Response
+			json.NewDecoder(w.Body).Decode(&resp)
+			return resp.Data.(map[string]interface{})["valid"] == true
+		}
+		if !verify("/api/v1/node/info?x=1") {
+			t.Error("verify endpoint rejected a valid signature")
+		}
+		if verify("/api/v1/reputation/query") {
+			t.Error("signature should be bound to the request URI")
+		}
+	})
+	
+	t.Run("signing key discovery", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/node/signing-key", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data := resp.Data.(map[string]interface{})
+		if data["public_key"] != base64.StdEncoding.EncodeToString(pub) || data["algorithm"] != "ed25519" {
+			t.Errorf("unexpected signing key response: %v", data)
+		}
+	})
+}
+
+func TestNodeManifest(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	verify := func(signerID string, data, signature []byte) (bool, error) {
+		if signerID != "test-node" {
+			return false, fmt.Errorf("unknown signer")
+		}
+		return ed25519.Verify(pub, data, signature), nil
+	}
+	
+	config := DefaultConfig("test-node")
+	config.APIToken = "secret"
+	s, _ := NewServer(config)
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	fetch := func() (*httptest.ResponseRecorder, *Manifest) {
+		req := httptest.NewRequest(http.MethodGet, ManifestPath, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var resp struct {
+			Data *Manifest `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp.Data
+	}
+	
+	if w, _ := fetch(); w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 without signing key, got %d", w.Code)
+	}
+	
+	config.ResponseSignFunc = func(data []byte) ([]byte, error) {
+		return ed25519.Sign(priv, data), nil
+	}
+	config.SigningPublicKey = pub
+	config.SigningAlgorithm = "ed25519"
+	s.ManifestFunc = func() *Manifest {
+		return &Manifest{
+			Version:       "0.1.0",
+			Features:      []string{"escrow"},
+			Protocols:     []string{"/daan/message/1.0.0"},
+			KeyAlgorithms: map[string]string{"identity": "ed25519"},
+			Limits:        map[string]int64{"outbound_rate": 1024},
+			Endpoints:     map[string]string{"grpc": ":50051"},
+		}
+	}
+	
+	// 无需 Token 即可获取
+	w, m := fetch()
+	if w.Code != http.StatusOK || m == nil {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=600" {
+		t.Errorf("unexpected Cache-Control %q", w.Header().Get("Cache-Control"))
+	}
+	if m.NodeID != "test-node" || m.Version != "0.1.0" || m.Limits["max_body_size"] != config.MaxBodySize || m.Limits["outbound_rate"] != 1024 {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	if m.Endpoints["http"] != config.ListenAddr || m.Endpoints["grpc"] != ":50051" {
+		t.Errorf("unexpected endpoints: %v", m.Endpoints)
+	}
+	if err := VerifyManifest(m, verify, time.Now()); err != nil {
+		t.Fatalf("VerifyManifest() error = %v", err)
+	}
+	if err := VerifyManifest(m, verify, time.Now().Add(time.Hour)); !errors.Is(err, ErrManifestExpired) {
+		t.Errorf("expected ErrManifestExpired, got %v", err)
+	}
+	
+	tampered := *m
+	tampered.Limits = map[string]int64{"max_body_size": 1}
+	if err := VerifyManifest(&tampered, verify, time.Now()); !errors.Is(err, ErrManifestSignature) {
+		t.Errorf("expected ErrManifestSignature, got %v", err)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	s := createTestServer()
+	
+	decode := func(w *httptest.ResponseRecorder) (Response, []interface{}) {
+		var resp Response
+		json.NewDecoder(w.Body).Decode(&resp)
+		data, _ := resp.Data.(map[string]interface{})
+		fields, _ := data["fields"].([]interface{})
+		return resp, fields
+	}
+	
+	t.Run("lists all offending fields", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/genesis/join", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+		s.handleGenesisJoin(w, req)
+		resp, fields := decode(w)
+		if w.Code != http.StatusUnprocessableEntity || resp.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status 422, got %d", w.Code)
+		}
+		if len(fields) != 4 {
+			t.Fatalf("expected 4 field errors, got %v", resp.Data)
+		}
+		if !strings.Contains(resp.Error, "invitation is required") || !strings.Contains(resp.Error, "pubkey is required") ||
+			!strings.Contains(resp.Error, "peer_id is required") || !strings.Contains(resp.Error, "signature is required") {
+			t.Errorf("unexpected error message: %s", resp.Error)
+		}
+	})
+	
+	t.Run("oneof", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/voting/vote", bytes.NewBufferString(`{"proposal_id":"p1","vote":"maybe"}`))
+		w := httptest.NewRecorder()
+		s.handleVotingVote(w, req)
+		_, fields := decode(w)
+		if w.Code != http.StatusUnprocessableEntity || len(fields) != 1 {
+			t.Fatalf("expected 422 with one field error, got %d", w.Code)
+		}
+		if f := fields[0].(map[string]interface{}); f["field"] != "vote" || f["rule"] != "oneof" {
+			t.Errorf("unexpected field error: %v", f)
+		}
+	})
+	
+	t.Run("wrong type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/node/maintenance", bytes.NewBufferString(`{"enabled":true,"duration":"2h"}`))
+		w := httptest.NewRecorder()
+		s.handleNodeMaintenance(w, req)
+		_, fields := decode(w)
+		if w.Code != http.StatusUnprocessableEntity || len(fields) != 1 {
+			t.Fatalf("expected 422 with one field error, got %d", w.Code)
+		}
+		if f := fields[0].(map[string]interface{}); f["field"] != "duration" || f["message"] != "must be an integer" {
+			t.Errorf("unexpected field error: %v", f)
+		}
+	})
+	
+	t.Run("malformed json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/neighbor/add", bytes.NewBufferString(`{"node_id":`))
+		w := httptest.NewRecorder()
+		s.handleNeighborAdd(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	})
+	
+	t.Run("unknown fields", func(t *testing.T) {
+		body := `{"node_id":"peer1","adresses":["/ip4/1.2.3.4/tcp/4001"]}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/neighbor/add", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		s.handleNeighborAdd(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unknown fields should be ignored by default, got %d", w.Code)
+		}
+		
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/neighbor/add", bytes.NewBufferString(body))
+		req.Header.Set(StrictFieldsHeader, "1")
+		w = httptest.NewRecorder()
+		s.handleNeighborAdd(w, req)
+		_, fields := decode(w)
+		if w.Code != http.StatusUnprocessableEntity || len(fields) != 1 || fields[0].(map[string]interface{})["field"] != "adresses" {
+			t.Fatalf("expected 422 for unknown field, got %d %v", w.Code, fields)
+		}
+		
+		strict := createTestServer()
+		strict.config.RejectUnknownFields = true
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/neighbor/add", bytes.NewBufferString(body))
+		w = httptest.NewRecorder()
+		strict.handleNeighborAdd(w, req)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status 422 in strict mode, got %d", w.Code)
+		}
+	})
+}
+
+func TestMutationAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "mutations.jsonl")
+	auditConfig := DefaultMutationAuditConfig(path)
+	// 测试签名：哈希加密钥后缀，校验时按同一密钥重算
+	auditConfig.SignFunc = func(data []byte) ([]byte, error) { return append(data, "|node-key"...), nil }
+	auditConfig.VerifyFunc = func(data, signature []byte) (bool, error) {
+		return string(signature) == string(data)+"|node-key", nil
+	}
+	if _, err := OpenMutationAuditLog(DefaultMutationAuditConfig(path)); !errors.Is(err, ErrAuditNoSigner) {
+		t.Errorf("expected ErrAuditNoSigner, got %v", err)
+	}
+	audit, err := OpenMutationAuditLog(auditConfig)
+	if err != nil {
+		t.Fatalf("OpenMutationAuditLog failed: %v", err)
+	}
+	config := DefaultConfig("test-node")
+	config.APIToken = "admin-token"
+	config.MutationAudit = audit
+	s, _ := NewServer(config)
+	s.TaskProgressReportFunc = func(req *TaskProgressRequest) (map[string]interface{}, error) {
+		if req.TaskID != "task_1" {
+			return nil, fmt.Errorf("task not assigned to me")
+		}
+		return map[string]interface{}{"task_id": req.TaskID}, nil
+	}
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	body := `{"task_id":"task_1","percent":10}`
+	if w := call(http.MethodPost, "/api/v1/task/progress", "admin-token", body); w.Code != http.StatusOK {
+		t.Fatalf("handler should still see the request body, got %d %s", w.Code, w.Body.String())
+	}
+	call(http.MethodPost, "/api/v1/task/progress", "admin-token", `{"task_id":"task_2","percent":10}`)
+	call(http.MethodPost, "/api/v1/task/progress", "stolen-token", body)
+	call(http.MethodGet, "/api/v1/node/info", "admin-token", "")
+	
+	records, err := audit.Query(nil)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected 2 mutation records (reads and unauthenticated calls are not audited), got %d %v", len(records), err)
+	}
+	first := records[1]
+	if first.ActorTokenID != TokenID("admin-token") || first.Result != AuditResultSuccess || first.PayloadHash != hashHex([]byte(body)) {
+		t.Errorf("unexpected record: %+v", first)
+	}
+	if strings.Contains(first.ActorTokenID, "admin-token") {
+		t.Error("audit record must not contain the token itself")
+	}
+	if first.Signature == "" {
+		t.Error("audit record should be signed")
+	}
+	
+	t.Run("query filters", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/audit/mutations?actor="+TokenID("admin-token")+"&result=failure", "admin-token", "")
+		var resp struct {
+			Data struct {
+				Records []*MutationRecord `json:"records"`
+			} `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if w.Code != http.StatusOK || len(resp.Data.Records) != 1 || resp.Data.Records[0].Status != http.StatusConflict {
+			t.Errorf("expected the one failed admin call, got %d %s", w.Code, w.Body.String())
+		}
+	})
+	
+	t.Run("export and verify", func(t *testing.T) {
+		w := call(http.MethodGet, "/api/v1/audit/mutations/export", "admin-token", "")
+		var resp struct {
+			Data struct {
+				HeadHash string            `json:"head_hash"`
+				Records  []*MutationRecord `json:"records"`
+			} `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		exported := resp.Data.Records
+		if len(exported) != 2 || exported[1].Hash != resp.Data.HeadHash {
+			t.Fatalf("unexpected export: %s", w.Body.String())
+		}
+		if err := VerifyMutationRecords(exported, auditConfig.VerifyFunc); err != nil {
+			t.Errorf("exported chain should verify: %v", err)
+		}
+		
+		// 没有节点私钥时，改写记录后重算的哈希链也无法通过签名校验
+		exported[0].ActorTokenID = TokenID("someone-else")
+		if err := VerifyMutationRecords(exported, auditConfig.VerifyFunc); !errors.Is(err, ErrAuditChainBroken) {
+			t.Errorf("expected ErrAuditChainBroken, got %v", err)
+		}
+		exported[0].Hash = exported[0].computeHash()
+		exported[1].PrevHash = exported[0].Hash
+		exported[1].Hash = exported[1].computeHash()
+		if err := VerifyMutationRecords(exported, nil); err != nil {
+			t.Errorf("recomputed chain should pass the hash check: %v", err)
+		}
+		if err := VerifyMutationRecords(exported, auditConfig.VerifyFunc); !errors.Is(err, ErrAuditChainBroken) {
+			t.Errorf("recomputed chain must fail the signature check, got %v", err)
+		}
+	})
+	
+	t.Run("tampering detected after restart", func(t *testing.T) {
+		audit.Close()
+		data, _ := os.ReadFile(path)
+		tampered := strings.Replace(string(data), "/api/v1/task/progress", "/api/v1/task/cancel", 1) // 改写第 1 条
+		os.WriteFile(path, []byte(tampered), 0600)
+		
+		// 断裂的链不再追加：原文件留作证据，新链从头开始
+		reopened, err := OpenMutationAuditLog(auditConfig)
+		if !errors.Is(err, ErrAuditChainBroken) || reopened == nil {
+			t.Fatalf("expected a usable log reporting ErrAuditChainBroken, got %v", err)
+		}
+		defer reopened.Close()
+		if moved, _ := filepath.Glob(path + ".broken-*"); len(moved) != 1 {
+			t.Errorf("broken chain should be moved aside, got %v", moved)
+		}
+		if err := reopened.Append(&MutationRecord{Method: http.MethodPost, Endpoint: "/api/v1/test"}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		if count, _, err := reopened.Verify(); err != nil || count != 1 {
+			t.Errorf("new chain should verify from genesis, got %d %v", count, err)
+		}
+	})
+}
+
+func TestMutationAuditRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mutations.jsonl")
+	config := DefaultMutationAuditConfig(path)
+	config.MaxSize = 1
+	config.MaxBackups = 2
+	config.SignFunc = func(data []byte) ([]byte, error) { return []byte("sig"), nil }
+	audit, err := OpenMutationAuditLog(config)
+	if err != nil {
+		t.Fatalf("OpenMutationAuditLog failed: %v", err)
+	}
+	defer audit.Close()
+	
+	// 每条记录后轮转，只保留当前文件和 2 个轮转文件
+	for i := 0; i < 5; i++ {
+		if err := audit.Append(&MutationRecord{Method: http.MethodPost, Endpoint: "/api/v1/test"}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backups beyond MaxBackups should be removed: %v", err)
+	}
+	records, err := audit.Export(nil)
+	if err != nil || len(records) != 2 || records[0].Seq != 4 || records[1].Seq != 5 {
+		t.Fatalf("expected the retained records 4 and 5, got %+v %v", records, err)
+	}
+	if count, head, err := audit.Verify(); err != nil || count != 2 || head != records[1].Hash {
+		t.Errorf("retained chain should verify across files, got %d %s %v", count, head, err)
+	}
+	if seq, _ := audit.Head(); seq != 5 {
+		t.Errorf("head seq = %d, want 5", seq)
+	}
+}
+
+func TestRequestObserver(t *testing.T) {
+	type observation struct {
+		method, route string
+		status        int
+	}
+	var observed []observation
+	config := DefaultConfig("test-node")
+	config.APIToken = "admin-token"
+	config.RequestObserver = func(method, route string, status int, elapsed time.Duration) {
+		if elapsed < 0 {
+			t.Errorf("negative elapsed time: %v", elapsed)
+		}
+		observed = append(observed, observation{method, route, status})
+	}
+	s, _ := NewServer(config)
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	call := func(path, token string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(TokenHeader, token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	call("/api/v1/node/info", "admin-token")
+	call("/api/v1/mailbox/read/msg-1", "wrong-token")
+	call("/no/such/route", "admin-token")
+	
+	want := []observation{
+		{http.MethodGet, "/api/v1/node/info", http.StatusOK},
+		{http.MethodGet, "unmatched", http.StatusUnauthorized},
+		{http.MethodGet, "unmatched", http.StatusNotFound},
+	}
+	if len(observed) != len(want) {
+		t.Fatalf("expected %d observations, got %v", len(want), observed)
+	}
+	for i := range want {
+		if observed[i] != want[i] {
+			t.Errorf("observation %d: expected %+v, got %+v", i, want[i], observed[i])
+		}
+	}
+}
+
+func TestAPIUsageTelemetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_usage.json")
+	usage, err := OpenAPIUsage(path)
+	if err != nil {
+		t.Fatalf("OpenAPIUsage failed: %v", err)
+	}
+	config := DefaultConfig("test-node")
+	config.APIToken = "admin-token"
+	config.APIUsage = usage
+	config.Deprecations = &DeprecationConfig{
+		Routes: map[string]DeprecatedRoute{
+			"/api/v1/mailbox/read/": {Sunset: "2027-01-01", Replacement: "/api/v1/mailbox/message/"},
+			"/api/v1/node/peers":    {},
+		},
+		MinSDKVersions: map[string]string{"agentnetwork-python": "1.4.0"},
+	}
+	s, _ := NewServer(config)
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	call := func(path, client, userAgent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(TokenHeader, "admin-token")
+		if client != "" {
+			req.Header.Set(ClientVersionHeader, client)
+		}
+		if userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	w := call("/api/v1/mailbox/read/msg_1", "agentnetwork-python/1.2.0", "")
+	if w.Header().Get("Deprecation") != "true" {
+		t.Error("deprecated route should carry a Deprecation header")
+	}
+	if sunset := w.Header().Get("Sunset"); sunset != "Fri, 01 Jan 2027 00:00:00 GMT" {
+		t.Errorf("unexpected Sunset header %q", sunset)
+	}
+	if link := w.Header().Get("Link"); link != `</api/v1/mailbox/message/>; rel="successor-version"` {
+		t.Errorf("unexpected Link header %q", link)
+	}
+	warnings := w.Header().Values("Warning")
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "upgrade to 1.4.0") || !strings.Contains(warnings[1], "/api/v1/mailbox/read/ is deprecated") {
+		t.Errorf("expected SDK and route warnings, got %q", warnings)
+	}
+	
+	w = call("/api/v1/node/info", "agentnetwork-python/1.4.0", "")
+	if w.Header().Get("Deprecation") != "" || len(w.Header().Values("Warning")) != 0 {
+		t.Errorf("current SDK on a live route should not be warned: %v", w.Header())
+	}
+	call("/api/v1/node/info", "", "agentnetwork-go/0.9.1 (linux)")
+	call("/api/v1/node/info", "", "")
+	call("/api/v1/no-such-route", "agentnetwork-python/1.2.0", "")
+	
+	w = call("/api/v1/node/api-usage?quiet_days=0", "", "")
+	var resp struct {
+		Data UsageReport `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("api-usage failed: %d %v", w.Code, err)
+	}
+	report := resp.Data
+	
+	counts := make(map[string]uint64)
+	for _, r := range report.Routes {
+		counts[r.Method+" "+r.Route] = r.Count
+	}
+	if counts["GET /api/v1/node/info"] != 3 || counts["GET /api/v1/mailbox/read/"] != 1 {
+		t.Errorf("unexpected route counts %v", counts)
+	}
+	if _, ok := counts["GET /api/v1/no-such-route"]; ok {
+		t.Error("unmatched paths should not be tracked")
+	}
+	clients := make(map[string]*ClientUsage)
+	for _, c := range report.Clients {
+		clients[c.Client] = c
+	}
+	if c := clients["agentnetwork-python/1.2.0"]; c == nil || c.Count != 1 || c.DeprecatedCalls != 1 {
+		t.Errorf("unexpected python 1.2.0 usage %+v", c)
+	}
+	if c := clients["agentnetwork-go/0.9.1"]; c == nil || c.SDK != "agentnetwork-go" {
+		t.Errorf("User-Agent should identify the client, got %+v", c)
+	}
+	if clients[usageUnknownClient] == nil {
+		t.Error("requests without client identification should count as unknown")
+	}
+	if len(report.DeprecatedSDKs) != 1 || report.DeprecatedSDKs[0].Client != "agentnetwork-python/1.2.0" {
+		t.Errorf("unexpected deprecated SDKs %+v", report.DeprecatedSDKs)
+	}
+	
+	if len(report.Deprecated) != 2 {
+		t.Fatalf("expected 2 deprecated routes, got %+v", report.Deprecated)
+	}
+	for _, d := range report.Deprecated {
+		switch d.Route {
+		case "/api/v1/mailbox/read/":
+			if d.Count != 1 || d.SafeToRemove || d.Clients["agentnetwork-python/1.2.0"] != 1 {
+				t.Errorf("route still in use must not be safe to remove: %+v", d)
+			}
+		case "/api/v1/node/peers":
+			if d.Count != 0 || !d.SafeToRemove {
+				t.Errorf("unused route should be safe to remove after the quiet period: %+v", d)
+			}
+		}
+	}
+	if quiet := usage.Report(config.Deprecations, 30*24*time.Hour, time.Now()); quiet.Deprecated[1].SafeToRemove {
+		t.Error("routes should not be safe to remove before tracking covers the quiet period")
+	}
+	
+	// 统计保存后重新打开继续累计
+	if err := usage.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened, err := OpenAPIUsage(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	reopened.Record(http.MethodGet, "/api/v1/node/info", "agentnetwork-go", "0.9.1", false, time.Now())
+	for _, r := range reopened.Report(nil, 0, time.Now()).Routes {
+		if r.Route == "/api/v1/node/info" && r.Count != 4 {
+			t.Errorf("expected counts to survive a restart, got %d", r.Count)
+		}
+	}
+	
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.0", "1.4.0", -1},
+		{"v1.10", "1.9.9", 1},
+		{"2.0.0-beta.1", "2", 0},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// fakeEscrow 内存中的托管服务，仲裁签名形如 "<仲裁者>:<托管ID>"
+type fakeEscrow struct {
+	escrows map[string]map[string]interface{}
+	signers map[string][]string
+}
+
+func newFakeEscrow() *fakeEscrow {
+	return &fakeEscrow{escrows: map[string]map[string]interface{}{}, signers: map[string][]string{}}
+}
+
+func (f *fakeEscrow) get(escrowID string) (map[string]interface{}, error) {
+	e, ok := f.escrows[escrowID]
+	if !ok {
+		return nil, fmt.Errorf("%w: escrow %s", ErrNotFound, escrowID)
+	}
+	return e, nil
+}
+
+func (f *fakeEscrow) ListEscrows(status string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, e := range f.escrows {
+		if status == "" || e["status"] == status {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (f *fakeEscrow) GetEscrow(escrowID string) (map[string]interface{}, error) {
+	return f.get(escrowID)
+}
+
+func (f *fakeEscrow) CreateEscrow(taskID string, deposits map[string]float64) (map[string]interface{}, error) {
+	id := "escrow_" + taskID
+	f.escrows[id] = map[string]interface{}{"id": id, "task_id": taskID, "status": "pending"}
+	return f.escrows[id], nil
+}
+
+func (f *fakeEscrow) Deposit(escrowID, nodeID string, amount float64, signature string) (map[string]interface{}, error) {
+	e, err := f.get(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	e["status"] = "locked"
+	return e, nil
+}
+
+func (f *fakeEscrow) SetArbitrators(escrowID string, arbitrators []string, threshold int, signatures map[string]string) (map[string]interface{}, error) {
+	e, err := f.get(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	e["arbitrators"], e["threshold"] = arbitrators, threshold
+	return e, nil
+}
+
+func (f *fakeEscrow) Dispute(escrowID, reason string) (map[string]interface{}, error) {
+	e, err := f.get(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	e["status"] = "disputed"
+	return e, nil
+}
+
+func (f *fakeEscrow) ProposeResolution(escrowID, winner string, amount float64) (map[string]interface{}, error) {
+	if _, err := f.get(escrowID); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"winner": winner, "amount": amount}, nil
+}
+
+func (f *fakeEscrow) SubmitArbitratorSignature(escrowID, arbitratorID, signature string) (map[string]interface{}, error) {
+	if _, err := f.get(escrowID); err != nil {
+		return nil, err
+	}
+	if signature != arbitratorID+":"+escrowID {
+		return nil, fmt.Errorf("%w: invalid signature", ErrUnauthorized)
+	}
+	f.signers[escrowID] = append(f.signers[escrowID], arbitratorID)
+	return f.SignatureCount(escrowID)
+}
+
+func (f *fakeEscrow) SignatureCount(escrowID string) (map[string]interface{}, error) {
+	if _, err := f.get(escrowID); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"current_count": len(f.signers[escrowID]), "required": 2}, nil
+}
+
+func (f *fakeEscrow) Resolve(escrowID, winner string, amount float64, signatures map[string]string) (map[string]interface{}, error) {
+	e, err := f.get(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	if len(signatures) < 2 {
+		return nil, fmt.Errorf("insufficient arbitrator signatures")
+	}
+	e["status"], e["released_to"] = "released", winner
+	return e, nil
+}
+
+func TestHandleEscrow(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleEscrowList(w, httptest.NewRequest(http.MethodGet, "/api/v1/escrow/list", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.Escrow = newFakeEscrow()
+	post := func(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+	
+	if w := post(s.handleEscrowCreate, "/api/v1/escrow/create", `{"task_id":"t1"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create without deposits: expected 422, got %d", w.Code)
+	}
+	if w := post(s.handleEscrowCreate, "/api/v1/escrow/create", `{"task_id":"t1","deposits":{"alice":10}}`); w.Code != http.StatusOK {
+		t.Fatalf("create: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(s.handleEscrowDeposit, "/api/v1/escrow/deposit", `{"escrow_id":"escrow_t1","amount":10}`); w.Code != http.StatusOK {
+		t.Errorf("deposit: expected 200, got %d", w.Code)
+	}
+	if w := post(s.handleEscrowArbitrators, "/api/v1/escrow/arbitrators", `{"escrow_id":"escrow_t1","arbitrators":["a1","a2","a3"]}`); w.Code != http.StatusOK {
+		t.Errorf("arbitrators: expected 200, got %d", w.Code)
+	}
+	if w := post(s.handleEscrowDispute, "/api/v1/escrow/dispute", `{"escrow_id":"missing","reason":"late"}`); w.Code != http.StatusNotFound {
+		t.Errorf("dispute unknown escrow: expected 404, got %d", w.Code)
+	}
+	if w := post(s.handleEscrowDispute, "/api/v1/escrow/dispute", `{"escrow_id":"escrow_t1","reason":"late"}`); w.Code != http.StatusOK {
+		t.Errorf("dispute: expected 200, got %d", w.Code)
+	}
+	if w := post(s.handleEscrowPropose, "/api/v1/escrow/propose", `{"escrow_id":"escrow_t1","winner":"alice"}`); w.Code != http.StatusOK {
+		t.Errorf("propose: expected 200, got %d", w.Code)
+	}
+	
+	t.Run("arbitrator signatures", func(t *testing.T) {
+		if w := post(s.handleEscrowArbitratorSignature, "/api/v1/escrow/arbitrator-signature", `{"escrow_id":"escrow_t1","arbitrator_id":"a1","signature":"forged"}`); w.Code != http.StatusForbidden {
+			t.Errorf("forged signature: expected 403, got %d", w.Code)
+		}
+		if w := post(s.handleEscrowArbitratorSignature, "/api/v1/escrow/arbitrator-signature", `{"escrow_id":"escrow_t1","arbitrator_id":"a1","signature":"a1:escrow_t1"}`); w.Code != http.StatusOK {
+			t.Errorf("signature: expected 200, got %d", w.Code)
+		}
+		
+		w := httptest.NewRecorder()
+		s.handleEscrowSignatureCount(w, httptest.NewRequest(http.MethodGet, "/api/v1/escrow/signature-count/escrow_t1", nil))
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["current_count"] != 1.0 || data["required"] != 2.0 {
+			t.Errorf("expected 1/2 signatures, got %v", data)
+		}
+	})
+	
+	t.Run("resolve", func(t *testing.T) {
+		if w := post(s.handleEscrowResolve, "/api/v1/escrow/resolve", `{"escrow_id":"escrow_t1","winner":"alice","signatures":{"a1":"x"}}`); w.Code != http.StatusBadRequest {
+			t.Errorf("insufficient signatures: expected 400, got %d", w.Code)
+		}
+		if w := post(s.handleEscrowResolve, "/api/v1/escrow/resolve", `{"escrow_id":"escrow_t1","winner":"alice","signatures":{"a1":"x","a2":"y"}}`); w.Code != http.StatusOK {
+			t.Errorf("resolve: expected 200, got %d", w.Code)
+		}
+		
+		w := httptest.NewRecorder()
+		s.handleEscrowList(w, httptest.NewRequest(http.MethodGet, "/api/v1/escrow/list?status=released", nil))
+		var resp Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		data := resp.Data.(map[string]interface{})
+		if data["total"] != 1.0 {
+			t.Errorf("expected 1 released escrow, got %v", data)
+		}
+	})
+}
+
+// fakeDispute 内存中的争议服务，只有被诉方 bob
+type fakeDispute struct {
+	disputes map[string]map[string]interface{}
+}
+
+func (f *fakeDispute) ListDisputes(status string) []map[string]interface{} {
+	var list []map[string]interface{}
+	for _, d := range f.disputes {
+		if status == "" || d["status"] == status {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+func (f *fakeDispute) GetDispute(disputeID string) (map[string]interface{}, error) {
+	d, ok := f.disputes[disputeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: dispute %s", ErrNotFound, disputeID)
+	}
+	return d, nil
+}
+
+func (f *fakeDispute) FileDispute(req DisputeFileRequest) (map[string]interface{}, error) {
+	if req.Defendant != "bob" {
+		return nil, fmt.Errorf("%w: %s is not a party", ErrUnauthorized, req.Defendant)
+	}
+	d := map[string]interface{}{"id": "d1", "task_id": req.TaskID, "escrow_id": req.EscrowID, "status": "pending", "stage": "filed"}
+	f.disputes["d1"] = d
+	return d, nil
+}
+
+func (f *fakeDispute) AttachEvidence(disputeID, evidenceType, description string, data []byte) (map[string]interface{}, error) {
+	d, err := f.GetDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+	d["stage"] = "evidence"
+	return map[string]interface{}{"id": "e1", "type": evidenceType, "size": len(data)}, nil
+}
+
+func (f *fakeDispute) VerifyEvidence(disputeID, evidenceID, verifierID string) error {
+	if evidenceID != "e1" {
+		return errors.New("evidence hash mismatch")
+	}
+	_, err := f.GetDispute(disputeID)
+	return err
+}
+
+func (f *fakeDispute) Suggestion(disputeID string) (map[string]interface{}, error) {
+	d, err := f.GetDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+	d["stage"] = "suggested"
+	return map[string]interface{}{"dispute_id": disputeID, "confidence": 1.0, "can_auto_execute": true}, nil
+}
+
+func (f *fakeDispute) ApplySuggestion(disputeID, approverID string) (map[string]interface{}, error) {
+	d, err := f.GetDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+	d["status"] = "resolved"
+	return map[string]interface{}{"applied": true, "dispute_id": disputeID}, nil
+}
+
+func TestHandleDisputeChannel(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleDisputeChannelTranscript(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/channel/transcript?dispute_id=d1", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var posted []string
+	s.DisputeChannelPostFunc = func(disputeID, holder, content string) (map[string]interface{}, error) {
+		if holder == "stranger" {
+			return nil, fmt.Errorf("%w: not a mediation channel member", ErrUnauthorized)
+		}
+		posted = append(posted, holder+"|"+content)
+		return map[string]interface{}{"seq": len(posted)}, nil
+	}
+	s.DisputeChannelTranscriptFunc = func(disputeID, holder string) (map[string]interface{}, error) {
+		if disputeID != "d1" {
+			return nil, fmt.Errorf("%w: dispute %s", ErrNotFound, disputeID)
+		}
+		return map[string]interface{}{"dispute_id": disputeID, "holder": holder, "messages": posted}, nil
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleDisputeChannelMessage(w, httptest.NewRequest(http.MethodPost, "/api/v1/dispute/channel/message", bytes.NewBufferString(body)))
+		return w
+	}
+	
+	if w := post(`{"dispute_id":"d1"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("message without content: expected 422, got %d", w.Code)
+	}
+	if w := post(`{"dispute_id":"d1","holder":"stranger","content":"hi"}`); w.Code != http.StatusForbidden {
+		t.Errorf("non-member: expected 403, got %d", w.Code)
+	}
+	if w := post(`{"dispute_id":"d1","holder":"alice","content":"section 3 is missing"}`); w.Code != http.StatusOK {
+		t.Fatalf("message: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if len(posted) != 1 || posted[0] != "alice|section 3 is missing" {
+		t.Errorf("unexpected posts %v", posted)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleDisputeChannelTranscript(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/channel/transcript?dispute_id=d1&holder=alice", nil))
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, ok := resp.Data.(map[string]interface{}); w.Code != http.StatusOK || !ok || data["holder"] != "alice" {
+		t.Errorf("transcript: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.handleDisputeChannelTranscript(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/channel/transcript?dispute_id=missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown dispute: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleDisputeBundle(t *testing.T) {
+	s := createTestServer()
+	
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleDisputeBundle(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	if w := get("/api/v1/dispute/bundle/d1"); w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.DisputeBundleFunc = func(disputeID string) (map[string]interface{}, error) {
+		if disputeID != "d1" {
+			return nil, fmt.Errorf("%w: dispute %s", ErrNotFound, disputeID)
+		}
+		return map[string]interface{}{"dispute_id": disputeID, "digest": "abc"}, nil
+	}
+	if w := get("/api/v1/dispute/bundle/"); w.Code != http.StatusBadRequest {
+		t.Errorf("missing id: expected 400, got %d", w.Code)
+	}
+	if w := get("/api/v1/dispute/bundle/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown dispute: expected 404, got %d", w.Code)
+	}
+	w := get("/api/v1/dispute/bundle/d1")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, ok := resp.Data.(map[string]interface{}); w.Code != http.StatusOK || !ok || data["digest"] != "abc" {
+		t.Errorf("bundle: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleDispute(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleDisputeList(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/list", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.Dispute = &fakeDispute{disputes: map[string]map[string]interface{}{}}
+	post := func(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+	get := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	
+	if w := post(s.handleDisputeFile, "/api/v1/dispute/file", `{"defendant":"bob","type":"non_delivery","description":"late"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("file without task or escrow: expected 400, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeFile, "/api/v1/dispute/file", `{"task_id":"t1","defendant":"bob","type":"fraud","description":"late"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown type: expected 422, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeFile, "/api/v1/dispute/file", `{"task_id":"t1","defendant":"carol","type":"non_delivery","description":"late"}`); w.Code != http.StatusForbidden {
+		t.Errorf("non-party defendant: expected 403, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeFile, "/api/v1/dispute/file", `{"escrow_id":"escrow_t1","defendant":"bob","type":"non_delivery","description":"late"}`); w.Code != http.StatusOK {
+		t.Fatalf("file: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	
+	if w := post(s.handleDisputeEvidence, "/api/v1/dispute/evidence", `{"dispute_id":"d1","type":"text"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("evidence without data: expected 400, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeEvidence, "/api/v1/dispute/evidence", `{"dispute_id":"d1","type":"text","data":"bm8gZGVsaXZlcnk="}`); w.Code != http.StatusOK {
+		t.Errorf("evidence: expected 200, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeVerifyEvidence, "/api/v1/dispute/verify-evidence", `{"dispute_id":"d1","evidence_id":"e2"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("hash mismatch: expected 400, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeVerifyEvidence, "/api/v1/dispute/verify-evidence", `{"dispute_id":"missing","evidence_id":"e1"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown dispute: expected 404, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeVerifyEvidence, "/api/v1/dispute/verify-evidence", `{"dispute_id":"d1","evidence_id":"e1"}`); w.Code != http.StatusOK {
+		t.Errorf("verify: expected 200, got %d", w.Code)
+	}
+	
+	if w := get(s.handleDisputeSuggestion, "/api/v1/dispute/suggestion/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("suggestion for unknown dispute: expected 404, got %d", w.Code)
+	}
+	if w := get(s.handleDisputeSuggestion, "/api/v1/dispute/suggestion/d1"); w.Code != http.StatusOK {
+		t.Errorf("suggestion: expected 200, got %d", w.Code)
+	}
+	if w := post(s.handleDisputeApplySuggestion, "/api/v1/dispute/apply-suggestion", `{"dispute_id":"d1","approver_id":"alice"}`); w.Code != http.StatusOK {
+		t.Errorf("apply: expected 200, got %d", w.Code)
+	}
+	
+	w = get(s.handleDisputeList, "/api/v1/dispute/list?status=resolved")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"] != 1.0 {
+		t.Errorf("expected 1 resolved dispute, got %v", data)
+	}
+	w = get(s.handleDisputeDetail, "/api/v1/dispute/detail/d1")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["stage"] != "suggested" || data["escrow_id"] != "escrow_t1" {
+		t.Errorf("detail = %v", data)
+	}
+}
+
+// fakeCollateral 内存中的抵押服务，本节点为 self
+type fakeCollateral struct {
+	balances map[string]float64 // purpose -> 本节点余额
+	slashes  []map[string]interface{}
+}
+
+func (f *fakeCollateral) ListCollaterals(nodeID, status string) []map[string]interface{} {
+	var list []map[string]interface{}
+	for purpose, balance := range f.balances {
+		if nodeID == "" || nodeID == "self" {
+			list = append(list, map[string]interface{}{"node_id": "self", "purpose": purpose, "balance": balance})
+		}
+	}
+	return list
+}
+
+func (f *fakeCollateral) GetCollateral(nodeID, purpose string) (map[string]interface{}, error) {
+	balance, ok := f.balances[purpose]
+	if nodeID != "self" || !ok {
+		return nil, fmt.Errorf("%w: no %s collateral for %s", ErrNotFound, purpose, nodeID)
+	}
+	return map[string]interface{}{"node_id": nodeID, "purpose": purpose, "balance": balance}, nil
+}
+
+func (f *fakeCollateral) Deposit(purpose string, amount float64) (map[string]interface{}, error) {
+	f.balances[purpose] += amount
+	return f.GetCollateral("self", purpose)
+}
+
+func (f *fakeCollateral) Withdraw(purpose string, amount float64) (map[string]interface{}, error) {
+	if f.balances[purpose] < amount {
+		return nil, errors.New("insufficient collateral amount")
+	}
+	f.balances[purpose] -= amount
+	return f.GetCollateral("self", purpose)
+}
+
+func (f *fakeCollateral) Slash(req CollateralSlashRequest) (map[string]interface{}, error) {
+	if _, err := f.GetCollateral(req.NodeID, req.Purpose); err != nil {
+		return nil, err
+	}
+	amount := f.balances[req.Purpose] * req.Ratio
+	f.balances[req.Purpose] -= amount
+	event := map[string]interface{}{"node_id": req.NodeID, "slashed_amount": amount, "remaining": f.balances[req.Purpose], "evidence": req.Evidence}
+	f.slashes = append(f.slashes, event)
+	return event, nil
+}
+
+func (f *fakeCollateral) SlashHistory(nodeID, purpose string, limit int) []map[string]interface{} {
+	return f.slashes
+}
+
+func TestHandleCollateral(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleCollateralList(w, httptest.NewRequest(http.MethodGet, "/api/v1/collateral/list", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.Collateral = &fakeCollateral{balances: map[string]float64{}}
+	post := func(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+	get := func(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+	
+	if w := post(s.handleCollateralDeposit, "/api/v1/collateral/deposit", `{"purpose":"supernode_auditor","amount":-5}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("negative deposit: expected 422, got %d", w.Code)
+	}
+	if w := post(s.handleCollateralDeposit, "/api/v1/collateral/deposit", `{"purpose":"supernode_auditor","amount":100}`); w.Code != http.StatusOK {
+		t.Fatalf("deposit: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(s.handleCollateralWithdraw, "/api/v1/collateral/withdraw", `{"purpose":"supernode_auditor","amount":500}`); w.Code != http.StatusBadRequest {
+		t.Errorf("over-withdraw: expected 400, got %d", w.Code)
+	}
+	
+	if w := get(s.handleCollateralByNode, "/api/v1/collateral/by-node?node_id=other&purpose=supernode_auditor"); w.Code != http.StatusNotFound {
+		t.Errorf("missing account: expected 404, got %d", w.Code)
+	}
+	w = get(s.handleCollateralByNode, "/api/v1/collateral/by-node?node_id=self")
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"] != 1.0 {
+		t.Errorf("by-node = %v", data)
+	}
+	
+	if w := post(s.handleCollateralSlashByNode, "/api/v1/collateral/slash-by-node", `{"node_id":"self","purpose":"supernode_auditor","ratio":0.3,"reason":"deviation"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("slash without evidence: expected 422, got %d", w.Code)
+	}
+	if w := post(s.handleCollateralSlashByNode, "/api/v1/collateral/slash-by-node", `{"node_id":"self","purpose":"supernode_auditor","ratio":0.3,"reason":"deviation","evidence":"audit_id:a1"}`); w.Code != http.StatusOK {
+		t.Fatalf("slash: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	w = get(s.handleCollateralSlashHistory, "/api/v1/collateral/slash-history?node_id=self")
+	resp = Response{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"] != 1.0 {
+		t.Errorf("slash history = %v", data)
+	}
+}
+
+type fakeSnapshotQuery struct{}
+
+func (fakeSnapshotQuery) DisputeCase(disputeID string) (map[string]interface{}, error) {
+	if disputeID != "dispute_1" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, disputeID)
+	}
+	return map[string]interface{}{
+		"dispute":  map[string]interface{}{"id": disputeID},
+		"snapshot": map[string]interface{}{"timestamp": "2026-01-01T00:00:00Z"},
+	}, nil
+}
+
+func (fakeSnapshotQuery) NodeExposure(nodeID string) (map[string]interface{}, error) {
+	if nodeID == "" {
+		nodeID = "self"
+	}
+	return map[string]interface{}{"node_id": nodeID, "snapshot": map[string]interface{}{}}, nil
+}
+
+func TestHandleSnapshotQueries(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleDisputeCase(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/case/dispute_1", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.SnapshotQuery = fakeSnapshotQuery{}
+	w = httptest.NewRecorder()
+	s.handleDisputeCase(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/case/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing dispute: expected 404, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.handleDisputeCase(w, httptest.NewRequest(http.MethodGet, "/api/v1/dispute/case/dispute_1", nil))
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, ok := resp.Data.(map[string]interface{}); !ok || data["snapshot"] == nil {
+		t.Errorf("case = %d %s", w.Code, w.Body.String())
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleNodeExposure(w, httptest.NewRequest(http.MethodGet, "/api/v1/node/exposure", nil))
+	resp = Response{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data, ok := resp.Data.(map[string]interface{}); !ok || data["node_id"] != "self" {
+		t.Errorf("exposure = %d %s", w.Code, w.Body.String())
+	}
+}
+
+type fakeToken struct {
+	balances map[string]float64
+	history  []map[string]interface{}
+	payments map[string]float64
+}
+
+func (f *fakeToken) Balance(nodeID string) map[string]interface{} {
+	if nodeID == "" {
+		nodeID = "self"
+	}
+	return map[string]interface{}{"node_id": nodeID, "balance": f.balances[nodeID]}
+}
+
+func (f *fakeToken) Transfer(req TokenTransferRequest) (map[string]interface{}, error) {
+	if req.From != "" && req.Signature == "" {
+		return nil, fmt.Errorf("%w: signature required", ErrUnauthorized)
+	}
+	if f.balances["self"] < req.Amount {
+		return nil, errors.New("insufficient balance")
+	}
+	f.balances["self"] -= req.Amount
+	f.balances[req.To] += req.Amount
+	entry := map[string]interface{}{"type": "transfer", "to": req.To, "amount": req.Amount}
+	f.history = append(f.history, entry)
+	return entry, nil
+}
+
+func (f *fakeToken) History(nodeID string, limit int) []map[string]interface{} {
+	return f.history
+}
+
+func (f *fakeToken) GetTaskPayment(taskID string) (map[string]interface{}, error) {
+	amount, ok := f.payments[taskID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, taskID)
+	}
+	return map[string]interface{}{"task_id": taskID, "amount": amount, "status": "held"}, nil
+}
+
+func (f *fakeToken) HoldTaskPayment(taskID string, amount float64) (map[string]interface{}, error) {
+	f.payments[taskID] = amount
+	return f.GetTaskPayment(taskID)
+}
+
+func (f *fakeToken) ReleaseTaskPayment(taskID string, payouts map[string]float64) (map[string]interface{}, error) {
+	if _, err := f.GetTaskPayment(taskID); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"task_id": taskID, "status": "released", "payouts": payouts}, nil
+}
+
+func (f *fakeToken) RefundTaskPayment(taskID string) (map[string]interface{}, error) {
+	if _, err := f.GetTaskPayment(taskID); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"task_id": taskID, "status": "refunded"}, nil
+}
+
+func TestHandleToken(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleTokenBalance(w, httptest.NewRequest(http.MethodGet, "/api/v1/token/balance", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	s.Token = &fakeToken{balances: map[string]float64{"self": 50}, payments: map[string]float64{}}
+	post := func(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)))
+		return w
+	}
+	
+	if w := post(s.handleTokenTransfer, "/api/v1/token/transfer", `{"amount":5}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("transfer without recipient: expected 422, got %d", w.Code)
+	}
+	if w := post(s.handleTokenTransfer, "/api/v1/token/transfer", `{"from":"other","to":"peer","amount":5}`); w.Code != http.StatusForbidden {
+		t.Errorf("unsigned transfer for another node: expected 403, got %d", w.Code)
+	}
+	if w := post(s.handleTokenTransfer, "/api/v1/token/transfer", `{"to":"peer","amount":20}`); w.Code != http.StatusOK {
+		t.Fatalf("transfer: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleTokenBalance(w, httptest.NewRequest(http.MethodGet, "/api/v1/token/balance?node_id=peer", nil))
+	var resp Response
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["balance"] != 20.0 {
+		t.Errorf("balance = %v", data)
+	}
+	w = httptest.NewRecorder()
+	s.handleTokenHistory(w, httptest.NewRequest(http.MethodGet, "/api/v1/token/history", nil))
+	resp = Response{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if data := resp.Data.(map[string]interface{}); data["total"] != 1.0 {
+		t.Errorf("history = %v", data)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleTokenTaskPayment(w, httptest.NewRequest(http.MethodGet, "/api/v1/token/task-payment?task_id=task1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing payment: expected 404, got %d", w.Code)
+	}
+	if w := post(s.handleTokenTaskPayment, "/api/v1/token/task-payment", `{"task_id":"task1","amount":10}`); w.Code != http.StatusOK {
+		t.Fatalf("hold: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(s.handleTokenTaskPaymentRelease, "/api/v1/token/task-payment/release", `{"task_id":"task1","payouts":{"worker":10}}`); w.Code != http.StatusOK {
+		t.Errorf("release: expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if w := post(s.handleTokenTaskPaymentRefund, "/api/v1/token/task-payment/refund", `{}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("refund without task_id: expected 422, got %d", w.Code)
+	}
+}
+
+func TestHandleMailboxRoutes(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleMailboxRoutes(w, httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/routes", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var gotReceiver, gotMessage string
+	var gotLimit int
+	s.MailboxRoutesFunc = func(receiver, messageID string, limit int) map[string]interface{} {
+		gotReceiver, gotMessage, gotLimit = receiver, messageID, limit
+		return map[string]interface{}{"decisions": []interface{}{}, "paths": []interface{}{}}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleMailboxRoutes(w, httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/routes?receiver=12D3KooWB&message_id=m1&limit=5", nil))
+	if w.Code != http.StatusOK || gotReceiver != "12D3KooWB" || gotMessage != "m1" || gotLimit != 5 {
+		t.Errorf("got %d receiver=%q message=%q limit=%d", w.Code, gotReceiver, gotMessage, gotLimit)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleMailboxRoutes(w, httptest.NewRequest(http.MethodPost, "/api/v1/mailbox/routes", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestHandleMailboxPending(t *testing.T) {
+	s := createTestServer()
+	
+	w := httptest.NewRecorder()
+	s.handleMailboxPending(w, httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/pending", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501, got %d", w.Code)
+	}
+	
+	var gotReceiver string
+	s.MailboxPendingFunc = func(receiver string) map[string]interface{} {
+		gotReceiver = receiver
+		return map[string]interface{}{"enabled": true, "messages": 2}
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleMailboxPending(w, httptest.NewRequest(http.MethodGet, "/api/v1/mailbox/pending?receiver=12D3KooWB", nil))
+	var resp Response
+	json.NewDecoder(w.Body).Decode(&resp)
+	data, _ := resp.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || data["messages"] != float64(2) || gotReceiver != "12D3KooWB" {
+		t.Errorf("expected relay status, got %d %v receiver=%q", w.Code, data, gotReceiver)
+	}
+	
+	w = httptest.NewRecorder()
+	s.handleMailboxPending(w, httptest.NewRequest(http.MethodDelete, "/api/v1/mailbox/pending", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", w.Code)
+	}
+}
+
+func TestTokenMetering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_metering.json")
+	meter, err := OpenTokenMeter(path)
+	if err != nil {
+		t.Fatalf("OpenTokenMeter failed: %v", err)
+	}
+	config := DefaultConfig("test-node")
+	config.APIToken = "admin-token"
+	config.TokenMeter = meter
+	config.MeterAccount = func(token string) (string, string) {
+		if token == "agent-token" {
+			return "key-agent", "agent-1"
+		}
+		return "", ""
+	}
+	s, _ := NewServer(config)
+	s.config.Roles = fakeRoles{"agent-token": "admin"}
+	s.AcceptTaskFunc = func(req *TaskAcceptRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": req.TaskID}, nil
+	}
+	s.TaskSubmitFunc = func(req *TaskSubmitRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"task_id": req.TaskID}, nil
+	}
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	handler := s.middleware(mux)
+	
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(TokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	
+	call(http.MethodGet, "/api/v1/node/info", "agent-token", "")
+	accept := `{"task_id":"task-1"}`
+	call(http.MethodPost, "/api/v1/task/accept", "agent-token", accept)
+	call(http.MethodPost, "/api/v1/task/submit", "agent-token", `{"task_id":"task-1","result":"ok"}`)
+	call(http.MethodGet, "/api/v1/node/info", "admin-token", "")
+	// 认证失败的请求不计量
+	if w := call(http.MethodGet, "/api/v1/node/info", "bogus", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown token, got %d", w.Code)
+	}
+	
+	month := time.Now().UTC().Format("2006-01")
+	usage := meter.Usage(month, "key-agent")
+	if len(usage) != 1 {
+		t.Fatalf("expected one record for key_agent, got %+v", usage)
+	}
+	u := usage[0]
+	if u.Name != "agent-1" || u.Requests != 3 || u.Tasks != 1 || u.BytesIn < uint64(len(accept)) || u.BytesOut == 0 {
+		t.Errorf("unexpected agent usage %+v", u)
+	}
+	if admin := meter.Usage(month, TokenID("admin-token")); len(admin) != 1 || admin[0].Requests != 1 {
+		t.Errorf("master token should be metered by token ID, got %+v", admin)
+	}
+	if bogus := meter.Usage("", TokenID("bogus")); len(bogus) != 0 {
+		t.Errorf("unauthenticated request metered: %+v", bogus)
+	}
+	
+	// JSON 导出带合计
+	w := call(http.MethodGet, "/api/v1/billing/usage?month="+month, "admin-token", "")
+	var resp struct {
+		Data MeterExport `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("billing usage failed: %d %v", w.Code, err)
+	}
+	if resp.Data.Month != month || resp.Data.Totals.Accounts != 2 || resp.Data.Totals.Tasks != 1 {
+		t.Errorf("unexpected export %+v", resp.Data)
+	}
+	
+	// CSV 导出
+	w = call(http.MethodGet, "/api/v1/billing/usage?format=csv&account=key-agent", "admin-token", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv export: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "month,account,name,requests") || !strings.HasPrefix(lines[1], month+",key-agent,agent-1,3,") {
+		t.Errorf("unexpected csv %q", lines)
+	}
+	
+	if w := call(http.MethodGet, "/api/v1/billing/usage?month=10-2026", "admin-token", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid month: expected 400, got %d", w.Code)
+	}
+	if w := call(http.MethodGet, "/api/v1/billing/usage?format=xml", "admin-token", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid format: expected 400, got %d", w.Code)
+	}
+	
+	// 重启后累计
+	if err := meter.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	reopened, err := OpenTokenMeter(path)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if usage := reopened.Usage(month, "key-agent"); len(usage) != 1 || usage[0].Requests != 3 {
+		t.Errorf("usage not persisted: %+v", usage)
+	}
+}
+
+func TestTokenMeterTaskSeconds(t *testing.T) {
+	meter, _ := OpenTokenMeter("")
+	start := time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC)
+	meter.StartTask("key-a", "agent-a", "task-1", start)
+	// 重复接单不重新计时
+	meter.StartTask("key-a", "agent-a", "task-1", start.Add(time.Hour))
+	
+	// 跨月的任务计入提交当月
+	if seconds := meter.FinishTask("task-1", start.Add(90*time.Minute)); seconds != 5400 {
+		t.Errorf("expected 5400 task seconds, got %v", seconds)
+	}
+	if usage := meter.Usage("2026-10", "key-a"); len(usage) != 1 || usage[0].TaskSeconds != 5400 || usage[0].Tasks != 1 {
+		t.Errorf("unexpected october usage %+v", usage)
+	}
+	if usage := meter.Usage("2026-09", ""); len(usage) != 0 {
+		t.Errorf("task seconds should not be split across months: %+v", usage)
+	}
+	if seconds := meter.FinishTask("task-1", start.Add(2*time.Hour)); seconds != 0 {
+		t.Errorf("finished task counted twice: %v", seconds)
+	}
+}
+
+// TestMutatingRoutePermissions 遍历所有接受写请求的路由：路由权限表未列出的写路由按方法归为 operate，
+// 必须在 operateRoutes 中登记，新增的写路由需要在这里或 auth.DefaultPolicy 中明确归类
+func TestMutatingRoutePermissions(t *testing.T) {
+	operateRoutes := map[string]bool{
+		// 不检查方法的只读路由
+		"/health": true, "/status": true,
+		"/api/v1/node/register": true, "/api/v1/node/features/ready": true, "/api/v1/node/verify-response": true,
+		"/api/v1/neighbor/add": true, "/api/v1/neighbor/remove": true, "/api/v1/neighbor/ping": true,
+		"/api/v1/storage/keys": true,
+		"/api/v1/message/send": true, "/api/v1/message/receive": true,
+		"/api/v1/mailbox/send": true, "/api/v1/mailbox/mark-read": true, "/api/v1/mailbox/delete": true, "/api/v1/mailbox/known": true, "/api/v1/mailbox/cancel": true, "/api/v1/mailbox/contacts": true,
+		"/api/v1/bulletin/publish": true, "/api/v1/bulletin/subscribe": true, "/api/v1/bulletin/unsubscribe": true, "/api/v1/bulletin/subscription/filter": true, "/api/v1/bulletin/revoke": true, "/api/v1/bulletin/appeal": true,
+		"/api/v1/task/create": true, "/api/v1/task/accept": true, "/api/v1/task/submit": true, "/api/v1/task/from-template": true, "/api/v1/task/subcontract": true, "/api/v1/task/subcontract/fail": true,
+		"/api/v1/task/audit": true, "/api/v1/task/reviewers/assign": true, "/api/v1/task/reviewers/respond": true, "/api/v1/task/progress": true, "/api/v1/task/offers/policy": true,
+		"/api/v1/task/storage/hold": true, "/api/v1/task/storage/commit": true, "/api/v1/task/storage/challenge": true,
+		"/api/v1/task/template/save": true, "/api/v1/task/template/share": true, "/api/v1/task/template/delete": true,
+		// 委托方验收和结算自己的任务，与 token/task-payment/release 一样只动用本节点预付的报酬
+		"/api/v1/task/confirm": true, "/api/v1/task/settle": true,
+		"/api/v1/transfer/send": true, "/api/v1/transfer/pause": true, "/api/v1/transfer/resume": true, "/api/v1/transfer/accept": true, "/api/v1/transfer/reject": true,
+		"/api/v1/reputation/webhook": true,
+		"/api/v1/trust/endorse": true, "/api/v1/trust/proof": true, "/api/v1/trust/proof/verify": true, "/api/v1/trust/proof/import": true,
+		"/api/v1/admission/invitations/import": true,
+		"/api/v1/reachability/test": true, "/api/v1/reachability/attestations": true,
+		"/api/v1/discovery/announce": true, "/api/v1/discovery/withdraw": true,
+		"/api/v1/recovery/escrow": true, "/api/v1/recovery/hold": true, "/api/v1/recovery/request": true, "/api/v1/recovery/ceremony": true, "/api/v1/recovery/ceremony/share": true,
+		"/api/v1/accusation/create": true,
+		"/api/v1/voting/proposal/create": true, "/api/v1/voting/vote": true, "/api/v1/voting/proposal/finalize": true, "/api/v1/voting/delegate": true, "/api/v1/voting/delegate/revoke": true, "/api/v1/voting/canary/opt-in": true,
+		"/api/v1/supernode/apply": true, "/api/v1/supernode/withdraw": true, "/api/v1/supernode/vote": true, "/api/v1/supernode/election/start": true, "/api/v1/supernode/election/finalize": true, "/api/v1/supernode/audit/submit": true,
+		"/api/v1/genesis/invite/verify": true, "/api/v1/genesis/join": true,
+		"/api/v1/log/submit": true,
+		"/api/v1/audit/run": true,
+		"/api/v1/collateral/deposit": true, "/api/v1/collateral/withdraw": true,
+		"/api/v1/token/transfer": true, "/api/v1/token/task-payment": true, "/api/v1/token/task-payment/release": true, "/api/v1/token/task-payment/refund": true,
+		"/api/v1/dispute/file": true, "/api/v1/dispute/evidence": true, "/api/v1/dispute/verify-evidence": true, "/api/v1/dispute/channel/message": true,
+		"/api/v1/escrow/create": true, "/api/v1/escrow/deposit": true, "/api/v1/escrow/dispute": true,
+	}
+	
+	src, err := os.ReadFile("httpapi.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := createTestServer()
+	mux := http.NewServeMux()
+	s.registerRoutes(mux)
+	table := auth.DefaultPolicy().HTTPRoutes
+	
+	seen := make(map[string]bool)
+	for _, m := range regexp.MustCompile(`mux\.HandleFunc\("([^"]+)"`).FindAllStringSubmatch(string(src), -1) {
+		route := m[1]
+		if route == "/api/v1/events/stream" {
+			continue // 长连接，只接受 GET
+		}
+		path := route
+		if strings.HasSuffix(path, "/") {
+			path += "x"
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}")))
+		if w.Code == http.StatusMethodNotAllowed {
+			continue
+		}
+		seen[route] = true
+		perm := table.Required(http.MethodPost, path)
+		if perm == auth.PermOperate && !operateRoutes[route] {
+			t.Errorf("%s accepts writes but is not classified in auth.DefaultPolicy or operateRoutes", route)
+		}
+		if perm != auth.PermOperate && operateRoutes[route] {
+			t.Errorf("%s requires %s, remove it from operateRoutes", route, perm)
+		}
+	}
+	for route := range operateRoutes {
+		if !seen[route] {
+			t.Errorf("%s is listed in operateRoutes but does not accept writes", route)
+		}
+	}
+	if perm := table.Required(http.MethodPost, "/api/v1/escrow/arbitrators"); perm != auth.PermArbitrate {
+		t.Errorf("escrow/arbitrators requires %s, want arbitrate", perm)
+	}
 }
@@ -0,0 +1,163 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 按路由的身份要求
+//
+// 默认所有接口只要求 API Token。指责、投票等代表本网络身份发言的操作可以
+// 额外要求调用方用已登记的网络身份私钥对请求签名（签名方式与节点回调相同，
+// 见 SignCallbackRequest），签名者必须通过 IdentityRegisteredFunc 的登记检查。
+//
+// 签名请求模式（Config.SignedRequests）下，未单独配置策略的变更类请求（POST/PUT/PATCH/DELETE）
+// 一律按 AuthPolicyBoth 处理；需要豁免的路由在 RoutePolicies 中配置为 token。
+// 时间戳窗口和 nonce 防重放与节点回调共用同一套校验。
+
+// AuthPolicy 路由的认证策略
+type AuthPolicy string
+
+const (
+	AuthPolicyToken     AuthPolicy = "token"     // 仅 API Token（默认）
+	AuthPolicySignature AuthPolicy = "signature" // 仅已登记身份的节点签名
+	AuthPolicyBoth      AuthPolicy = "both"      // API Token 和节点签名都需要
+)
+
+var (
+	ErrIdentityNotRegistered = errors.New("signer is not a registered network identity")
+	ErrInvalidAuthPolicy     = errors.New("invalid route auth policy")
+)
+
+type identityContextKey struct{}
+
+// signedRequestExempt 签名请求模式下默认豁免的路由：处理函数自行校验签名
+// （消息回调用节点签名、声誉 Webhook 用 HMAC），在中间件再校验一次会重复消耗 nonce
+var signedRequestExempt = []string{
+	"/api/v1/message/receive",
+	"/api/v1/reputation/webhook",
+}
+
+// RecommendedRoutePolicies 推荐策略：创建指责、发起提案、投票和委托投票权要求 Token 加节点签名
+func RecommendedRoutePolicies() map[string]AuthPolicy {
+	return map[string]AuthPolicy{
+		"/api/v1/accusation/create":      AuthPolicyBoth,
+		"/api/v1/voting/proposal/create": AuthPolicyBoth,
+		"/api/v1/voting/vote":            AuthPolicyBoth,
+		"/api/v1/voting/delegate":        AuthPolicyBoth,
+	}
+}
+
+// ParseRoutePolicies 解析 "路径=策略" 列表（逗号分隔），"recommended" 展开为推荐策略
+// 以 / 结尾的路径匹配该前缀下的所有路由。
+func ParseRoutePolicies(s string) (map[string]AuthPolicy, error) {
+	policies := make(map[string]AuthPolicy)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if item == "recommended" {
+			for path, p := range RecommendedRoutePolicies() {
+				policies[path] = p
+			}
+			continue
+		}
+		path, policy, ok := strings.Cut(item, "=")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("%w: %q (expected path=policy)", ErrInvalidAuthPolicy, item)
+		}
+		p := AuthPolicy(policy)
+		if p != AuthPolicyToken && p != AuthPolicySignature && p != AuthPolicyBoth {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAuthPolicy, policy)
+		}
+		policies[path] = p
+	}
+	return policies, nil
+}
+
+// routePolicy 返回请求的认证策略：显式配置的策略优先（精确匹配优先，其次最长的前缀匹配），
+// 未配置时签名请求模式下的变更类请求要求 Token 加节点签名，其余只要求 Token
+func (s *Server) routePolicy(method, path string) AuthPolicy {
+	if p, ok := s.configuredPolicy(path); ok {
+		return p
+	}
+	if s.config.SignedRequests && isMutation(method) && !isSignedRequestExempt(path) {
+		return AuthPolicyBoth
+	}
+	return AuthPolicyToken
+}
+
+// configuredPolicy 查找 RoutePolicies 中路由的策略
+func (s *Server) configuredPolicy(path string) (AuthPolicy, bool) {
+	if p, ok := s.config.RoutePolicies[path]; ok {
+		return p, true
+	}
+	best, policy := "", AuthPolicyToken
+	for prefix, p := range s.config.RoutePolicies {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best, policy = prefix, p
+		}
+	}
+	return policy, best != ""
+}
+
+func isSignedRequestExempt(path string) bool {
+	for _, p := range signedRequestExempt {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyIdentity 校验请求的节点签名并检查签名者已登记，返回带签名者身份的请求
+func (s *Server) verifyIdentity(r *http.Request) (*http.Request, int, error) {
+	if s.config.IdentityRegisteredFunc == nil {
+		return nil, http.StatusNotImplemented, errors.New("identity registry not available")
+	}
+	nodeID, status, err := s.verifyCallback(r)
+	if err != nil {
+		return nil, status, err
+	}
+	if !s.config.IdentityRegisteredFunc(nodeID) {
+		return nil, http.StatusForbidden, ErrIdentityNotRegistered
+	}
+	return r.WithContext(context.WithValue(r.Context(), identityContextKey{}, nodeID)), 0, nil
+}
+
+// VerifiedNodeID 返回请求中验签通过的节点身份，路由不要求签名时为空
+func VerifiedNodeID(r *http.Request) string {
+	nodeID, _ := r.Context().Value(identityContextKey{}).(string)
+	return nodeID
+}
+
+// handleAuthPolicies 列出按路由配置的认证策略和签名请求模式
+func (s *Server) handleAuthPolicies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	routes := make([]map[string]interface{}, 0, len(s.config.RoutePolicies))
+	for path, p := range s.config.RoutePolicies {
+		routes = append(routes, map[string]interface{}{"path": path, "policy": p})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i]["path"].(string) < routes[j]["path"].(string)
+	})
+	result := map[string]interface{}{
+		"default":         AuthPolicyToken,
+		"routes":          routes,
+		"signed_requests": s.config.SignedRequests,
+	}
+	if s.config.SignedRequests {
+		result["mutation_default"] = AuthPolicyBoth
+		result["exempt"] = signedRequestExempt
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,133 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ManifestPath 节点清单端点，无需认证，供其他 agent 获取后缓存
+const ManifestPath = "/api/v1/manifest"
+
+// ManifestSigVersion 清单签名载荷版本前缀
+const ManifestSigVersion = "daan-manifest-v1"
+
+var (
+	ErrManifestExpired   = errors.New("manifest expired")
+	ErrManifestSignature = errors.New("invalid manifest signature")
+)
+
+// Manifest 节点自描述清单
+// 其他 agent 据此决定如何与本节点交互：用哪些协议、能调用哪些特性、请求体和速率上限。
+// 清单整体由节点身份私钥签名，ExpiresAt 之前可直接使用缓存。
+type Manifest struct {
+	NodeID        string            `json:"node_id"`
+	Version       string            `json:"version"`
+	Namespace     string            `json:"namespace,omitempty"`
+	Features      []string          `json:"features"`       // 已在全网激活的特性
+	Protocols     []string          `json:"protocols"`      // 支持的 P2P 流协议（含版本）
+	APIVersions   []string          `json:"api_versions"`   // HTTP API 版本
+	KeyAlgorithms map[string]string `json:"key_algorithms"` // 用途 -> 算法，如 identity、encryption
+	Limits        map[string]int64  `json:"limits"`         // 资源上限，如 max_body_size、outbound_rate
+	Endpoints     map[string]string `json:"endpoints"`      // 公开端点，如 http、grpc、p2p
+	IssuedAt      int64             `json:"issued_at"`      // Unix 秒
+	ExpiresAt     int64             `json:"expires_at"`     // Unix 秒，缓存有效期
+	Algorithm     string            `json:"algorithm"`      // 签名算法
+	PublicKey     string            `json:"public_key"`     // base64 签名公钥
+	Signature     string            `json:"signature,omitempty"`
+}
+
+// ManifestSigningPayload 构造清单签名载荷（签名字段置空后的规范化 JSON 摘要）
+func ManifestSigningPayload(m *Manifest) ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	body, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := CanonicalJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(canonical)
+	return []byte(fmt.Sprintf("%s\n%s\n%s", ManifestSigVersion, m.NodeID, hex.EncodeToString(digest[:]))), nil
+}
+
+// VerifyManifest 校验他人节点的清单签名和有效期，verify 按节点ID对应的公钥验签
+func VerifyManifest(m *Manifest, verify func(signerID string, data, signature []byte) (bool, error), now time.Time) error {
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil || len(signature) == 0 {
+		return ErrManifestSignature
+	}
+	payload, err := ManifestSigningPayload(m)
+	if err != nil {
+		return err
+	}
+	valid, err := verify(m.NodeID, payload, signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrManifestSignature, err)
+	}
+	if !valid {
+		return ErrManifestSignature
+	}
+	if now.Unix() > m.ExpiresAt {
+		return ErrManifestExpired
+	}
+	return nil
+}
+
+// handleManifest 返回本节点签名的自描述清单（无需认证）
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.ResponseSignFunc == nil || len(s.config.SigningPublicKey) == 0 {
+		s.writeError(w, http.StatusNotImplemented, ErrSigningUnavailable.Error())
+		return
+	}
+
+	m := &Manifest{}
+	if s.ManifestFunc != nil {
+		m = s.ManifestFunc()
+	}
+	now := time.Now()
+	m.NodeID = s.config.NodeID
+	m.Namespace = s.config.Namespace
+	m.APIVersions = []string{"v1"}
+	if m.Limits == nil {
+		m.Limits = make(map[string]int64)
+	}
+	m.Limits["max_body_size"] = s.config.MaxBodySize
+	if m.Endpoints == nil {
+		m.Endpoints = make(map[string]string)
+	}
+	if _, ok := m.Endpoints["http"]; !ok {
+		m.Endpoints["http"] = s.config.ListenAddr
+	}
+	m.IssuedAt = now.Unix()
+	m.ExpiresAt = now.Add(s.config.ManifestTTL).Unix()
+	m.Algorithm = s.config.SigningAlgorithm
+	m.PublicKey = base64.StdEncoding.EncodeToString(s.config.SigningPublicKey)
+
+	payload, err := ManifestSigningPayload(m)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	signature, err := s.config.ResponseSignFunc(payload)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.config.ManifestTTL.Seconds())))
+	s.writeJSON(w, http.StatusOK, m)
+}
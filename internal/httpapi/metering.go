@@ -0,0 +1,470 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API 计量与计费导出
+//
+// 托管多个 Agent 时按调用方令牌计量 API 用量：请求数、请求和响应字节数、任务秒数
+// （令牌接单到提交结果的时长），按自然月（UTC）汇总。API Key 按 Key ID 计量，
+// 其他令牌按令牌 ID。计量记录定期和关闭时保存到文件，按月导出为 CSV 或 JSON。
+
+const (
+	meterMonthLayout   = "2006-01"
+	meterSaveInterval  = 5 * time.Minute
+	maxMeterAccounts   = 1000 // 每月计量的账户数上限，超出后计入 other
+	maxMeterMonths     = 24   // 保留的月数
+	maxMeterTasks      = 10000
+	meterOtherAccount  = "other"
+	meterCSVHeader     = "month,account,name,requests,errors,bytes_in,bytes_out,task_seconds,tasks,first_seen,last_seen"
+	meterTaskExpiry    = 30 * 24 * time.Hour // 接单后超过该时长仍未提交的任务不再计时
+	meterDefaultFormat = "json"
+)
+
+// MeterAccountFunc 令牌对应的计费账户和名称，account 为空时按令牌 ID 计量
+type MeterAccountFunc func(token string) (account, name string)
+
+// MeterUsage 一个账户一个月的用量
+type MeterUsage struct {
+	Month       string  `json:"month"` // 2006-01（UTC）
+	Account     string  `json:"account"`
+	Name        string  `json:"name,omitempty"`
+	Requests    uint64  `json:"requests"`
+	Errors      uint64  `json:"errors"` // 状态码 >= 400 的请求
+	BytesIn     uint64  `json:"bytes_in"`
+	BytesOut    uint64  `json:"bytes_out"`
+	TaskSeconds float64 `json:"task_seconds"`
+	Tasks       uint64  `json:"tasks"` // 已提交结果的任务数
+	FirstSeen   int64   `json:"first_seen"`
+	LastSeen    int64   `json:"last_seen"`
+}
+
+// meterTask 已接单、尚未提交结果的任务
+type meterTask struct {
+	Account   string `json:"account"`
+	Name      string `json:"name,omitempty"`
+	StartedAt int64  `json:"started_at"`
+}
+
+// TokenMeter 按令牌计量的 API 用量
+type TokenMeter struct {
+	mu       sync.Mutex
+	path     string
+	usage    map[string]*MeterUsage // month|account -> 用量
+	tasks    map[string]*meterTask  // taskID -> 接单的账户
+	dirty    bool
+	lastSave time.Time
+}
+
+type meterFile struct {
+	Usage []*MeterUsage         `json:"usage"`
+	Tasks map[string]*meterTask `json:"tasks,omitempty"`
+}
+
+// OpenTokenMeter 打开计量记录，path 为空时只在内存中计量
+func OpenTokenMeter(path string) (*TokenMeter, error) {
+	m := &TokenMeter{
+		path:     path,
+		usage:    make(map[string]*MeterUsage),
+		tasks:    make(map[string]*meterTask),
+		lastSave: time.Now(),
+	}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	var f meterFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return m, err
+	}
+	for _, u := range f.Usage {
+		m.usage[u.Month+"|"+u.Account] = u
+	}
+	for id, t := range f.Tasks {
+		m.tasks[id] = t
+	}
+	return m, nil
+}
+
+// entryLocked 账户当月的用量记录，不存在时创建
+func (m *TokenMeter) entryLocked(account, name string, now time.Time) *MeterUsage {
+	month := now.UTC().Format(meterMonthLayout)
+	key := month + "|" + account
+	u, ok := m.usage[key]
+	if !ok {
+		accounts := 0
+		for _, existing := range m.usage {
+			if existing.Month == month {
+				accounts++
+			}
+		}
+		if accounts >= maxMeterAccounts {
+			account, name = meterOtherAccount, ""
+			key = month + "|" + account
+			u = m.usage[key]
+		}
+		if u == nil {
+			u = &MeterUsage{Month: month, Account: account, FirstSeen: now.Unix()}
+			m.usage[key] = u
+		}
+	}
+	if name != "" {
+		u.Name = name
+	}
+	u.LastSeen = now.Unix()
+	return u
+}
+
+// Record 记录一次请求
+func (m *TokenMeter) Record(account, name string, bytesIn, bytesOut int64, status int, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.entryLocked(account, name, now)
+	u.Requests++
+	if status >= 400 {
+		u.Errors++
+	}
+	if bytesIn > 0 {
+		u.BytesIn += uint64(bytesIn)
+	}
+	if bytesOut > 0 {
+		u.BytesOut += uint64(bytesOut)
+	}
+	m.touchLocked(now)
+}
+
+// StartTask 账户接单，开始计时（重复接单不重新计时）
+func (m *TokenMeter) StartTask(account, name, taskID string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[taskID]; ok || taskID == "" {
+		return
+	}
+	if len(m.tasks) >= maxMeterTasks {
+		m.pruneTasksLocked(now)
+		if len(m.tasks) >= maxMeterTasks {
+			return
+		}
+	}
+	m.tasks[taskID] = &meterTask{Account: account, Name: name, StartedAt: now.Unix()}
+	m.touchLocked(now)
+}
+
+// FinishTask 任务提交结果，把接单以来的时长计入接单账户提交当月的用量，返回计入的秒数
+func (m *TokenMeter) FinishTask(taskID string, now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tasks[taskID]
+	if !ok {
+		return 0
+	}
+	delete(m.tasks, taskID)
+	seconds := now.Sub(time.Unix(t.StartedAt, 0)).Seconds()
+	if seconds < 0 {
+		seconds = 0
+	}
+	u := m.entryLocked(t.Account, t.Name, now)
+	u.TaskSeconds += seconds
+	u.Tasks++
+	m.touchLocked(now)
+	return seconds
+}
+
+// pruneTasksLocked 删除超过 meterTaskExpiry 仍未提交的任务
+func (m *TokenMeter) pruneTasksLocked(now time.Time) {
+	cutoff := now.Add(-meterTaskExpiry).Unix()
+	for id, t := range m.tasks {
+		if t.StartedAt < cutoff {
+			delete(m.tasks, id)
+		}
+	}
+}
+
+// touchLocked 标记有未保存的变化，距上次保存超过 meterSaveInterval 时保存
+func (m *TokenMeter) touchLocked(now time.Time) {
+	m.dirty = true
+	if m.path != "" && now.Sub(m.lastSave) >= meterSaveInterval {
+		m.saveLocked(now)
+	}
+}
+
+// Save 保存计量记录
+func (m *TokenMeter) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.path == "" || !m.dirty {
+		return nil
+	}
+	return m.saveLocked(time.Now())
+}
+
+func (m *TokenMeter) saveLocked(now time.Time) error {
+	// 只保留最近 maxMeterMonths 个月
+	oldest := now.UTC().AddDate(0, -(maxMeterMonths - 1), 0).Format(meterMonthLayout)
+	for key, u := range m.usage {
+		if u.Month < oldest {
+			delete(m.usage, key)
+		}
+	}
+	m.pruneTasksLocked(now)
+
+	f := meterFile{Usage: make([]*MeterUsage, 0, len(m.usage)), Tasks: m.tasks}
+	for _, u := range m.usage {
+		f.Usage = append(f.Usage, u)
+	}
+	sortMeterUsage(f.Usage)
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return err
+	}
+	m.dirty = false
+	m.lastSave = now
+	return nil
+}
+
+// Usage 按月和账户查询用量，参数为空时不过滤，按月份、账户排序
+func (m *TokenMeter) Usage(month, account string) []*MeterUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]*MeterUsage, 0)
+	for _, u := range m.usage {
+		if (month == "" || u.Month == month) && (account == "" || u.Account == account) {
+			copied := *u
+			result = append(result, &copied)
+		}
+	}
+	sortMeterUsage(result)
+	return result
+}
+
+func sortMeterUsage(usage []*MeterUsage) {
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Month != usage[j].Month {
+			return usage[i].Month < usage[j].Month
+		}
+		return usage[i].Account < usage[j].Account
+	})
+}
+
+// MeterTotals 导出范围内的合计
+type MeterTotals struct {
+	Accounts    int     `json:"accounts"`
+	Requests    uint64  `json:"requests"`
+	Errors      uint64  `json:"errors"`
+	BytesIn     uint64  `json:"bytes_in"`
+	BytesOut    uint64  `json:"bytes_out"`
+	TaskSeconds float64 `json:"task_seconds"`
+	Tasks       uint64  `json:"tasks"`
+}
+
+// MeterExport 计费导出（JSON 格式）
+type MeterExport struct {
+	Month       string        `json:"month,omitempty"` // 为空表示全部月份
+	GeneratedAt int64         `json:"generated_at"`
+	Records     []*MeterUsage `json:"records"`
+	Totals      MeterTotals   `json:"totals"`
+}
+
+// NewMeterExport 汇总用量记录
+func NewMeterExport(month string, usage []*MeterUsage, now time.Time) *MeterExport {
+	export := &MeterExport{Month: month, GeneratedAt: now.Unix(), Records: usage}
+	accounts := make(map[string]bool)
+	for _, u := range usage {
+		accounts[u.Account] = true
+		export.Totals.Requests += u.Requests
+		export.Totals.Errors += u.Errors
+		export.Totals.BytesIn += u.BytesIn
+		export.Totals.BytesOut += u.BytesOut
+		export.Totals.TaskSeconds += u.TaskSeconds
+		export.Totals.Tasks += u.Tasks
+	}
+	export.Totals.Accounts = len(accounts)
+	return export
+}
+
+// WriteMeterCSV 把用量记录写为 CSV，每行一个账户一个月，时间为 Unix 秒
+func WriteMeterCSV(w io.Writer, usage []*MeterUsage) error {
+	cw := csv.NewWriter(w)
+	cw.Write(strings.Split(meterCSVHeader, ","))
+	for _, u := range usage {
+		cw.Write([]string{
+			u.Month, u.Account, u.Name,
+			strconv.FormatUint(u.Requests, 10),
+			strconv.FormatUint(u.Errors, 10),
+			strconv.FormatUint(u.BytesIn, 10),
+			strconv.FormatUint(u.BytesOut, 10),
+			strconv.FormatFloat(u.TaskSeconds, 'f', 3, 64),
+			strconv.FormatUint(u.Tasks, 10),
+			strconv.FormatInt(u.FirstSeen, 10),
+			strconv.FormatInt(u.LastSeen, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// requestToken 请求携带的 API 令牌（请求头或 URL 参数）
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get(TokenHeader); token != "" {
+		return token
+	}
+	return r.URL.Query().Get(TokenQueryParam)
+}
+
+// meterAccount 令牌对应的计费账户
+func (s *Server) meterAccount(token string) (string, string) {
+	if s.config.MeterAccount != nil {
+		if account, name := s.config.MeterAccount(token); account != "" {
+			return account, name
+		}
+	}
+	return TokenID(token), ""
+}
+
+// meterTaskStart 请求方令牌接单，开始计量任务秒数
+func (s *Server) meterTaskStart(r *http.Request, taskID string) {
+	if s.config.TokenMeter == nil {
+		return
+	}
+	account, name := s.meterAccount(requestToken(r))
+	s.config.TokenMeter.StartTask(account, name, taskID, time.Now())
+}
+
+// meterTaskFinish 任务提交结果，结束计时
+func (s *Server) meterTaskFinish(taskID string) {
+	if s.config.TokenMeter != nil {
+		s.config.TokenMeter.FinishTask(taskID, time.Now())
+	}
+}
+
+// meterWriter 统计响应字节数和状态码
+type meterWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	admitted bool // 通过认证进入 handler 的请求才计量
+}
+
+func (w *meterWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *meterWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *meterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// meterBody 统计读取的请求体字节数
+type meterBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *meterBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// meterRequest 包装请求体和响应以计量字节数，返回计量用的响应和请求结束时调用的记录函数
+func (s *Server) meterRequest(w http.ResponseWriter, r *http.Request, token string) (*meterWriter, func()) {
+	mw := &meterWriter{ResponseWriter: w}
+	body := &meterBody{ReadCloser: r.Body}
+	r.Body = body
+	return mw, func() {
+		if !mw.admitted {
+			return
+		}
+		status := mw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		account, name := s.meterAccount(token)
+		s.config.TokenMeter.Record(account, name, body.bytes, mw.bytes, status, time.Now())
+	}
+}
+
+// handleBillingUsage 按令牌计量的 API 用量导出
+// GET /api/v1/billing/usage?month=2026-10&account=key-xxx&format=json|csv
+func (s *Server) handleBillingUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.TokenMeter == nil {
+		s.writeError(w, http.StatusNotImplemented, "api metering not enabled")
+		return
+	}
+	q := r.URL.Query()
+	month := q.Get("month")
+	if month != "" {
+		if _, err := time.Parse(meterMonthLayout, month); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid month (use YYYY-MM)")
+			return
+		}
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = meterDefaultFormat
+	}
+	usage := s.config.TokenMeter.Usage(month, q.Get("account"))
+
+	switch format {
+	case "json":
+		s.writeJSON(w, http.StatusOK, NewMeterExport(month, usage, time.Now()))
+	case "csv":
+		period := month
+		if period == "" {
+			period = "all"
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="api-usage-%s.csv"`, period))
+		w.WriteHeader(http.StatusOK)
+		WriteMeterCSV(w, usage)
+	default:
+		s.writeError(w, http.StatusBadRequest, "format must be json or csv")
+	}
+}
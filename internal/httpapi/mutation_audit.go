@@ -0,0 +1,611 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 变更审计日志错误
+var (
+	ErrAuditChainBroken = errors.New("mutation audit chain broken")
+	ErrAuditLogClosed   = errors.New("mutation audit log closed")
+	ErrAuditNoSigner    = errors.New("mutation audit log requires a sign function")
+)
+
+// 审计结果
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// auditGenesis 审计链第一条记录的 prev_hash
+var auditGenesis = hashHex([]byte("httpapi-mutation-audit"))
+
+// MutationRecord 一次变更类 API 调用（POST/PUT/PATCH/DELETE）
+// 只记录令牌 ID（令牌哈希前缀）和请求体哈希，不落盘令牌和请求内容本身。
+type MutationRecord struct {
+	Seq          uint64 `json:"seq"`
+	Timestamp    int64  `json:"timestamp"`
+	ActorTokenID string `json:"actor_token_id"`          // 调用方令牌 ID，无令牌为 anonymous
+	ActorNodeID  string `json:"actor_node_id,omitempty"` // 要求节点签名的路由为验签通过的节点，否则为 X-NodeID 请求头（调用方自报，仅供参考）
+	RemoteAddr   string `json:"remote_addr,omitempty"`
+	Method       string `json:"method"`
+	Endpoint     string `json:"endpoint"`
+	PayloadHash  string `json:"payload_hash"` // 请求体 SHA-256
+	PayloadSize  int    `json:"payload_size"`
+	Status       int    `json:"status"`
+	Result       string `json:"result"` // success（2xx）或 failure
+	PrevHash     string `json:"prev_hash"`
+	Hash         string `json:"hash"`
+	Signature    string `json:"signature"` // 节点身份对 hash 的签名（base64）
+}
+
+// computeHash 记录哈希覆盖除 hash 外的全部字段，并链接上一条记录
+func (r *MutationRecord) computeHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%d|%s|%s|%s|%s|%s|%s|%d|%d|%s|%s",
+		r.Seq, r.Timestamp, r.ActorTokenID, r.ActorNodeID, r.RemoteAddr,
+		r.Method, r.Endpoint, r.PayloadHash, r.PayloadSize, r.Status, r.Result, r.PrevHash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MutationFilter 审计记录查询条件，零值字段不参与过滤
+type MutationFilter struct {
+	Actor    string // 令牌 ID
+	Endpoint string // 路径前缀
+	Method   string
+	Result   string
+	Since    int64 // Unix 秒，含
+	Until    int64 // Unix 秒，含
+	Limit    int   // 只返回最新的 N 条
+}
+
+func (f *MutationFilter) match(r *MutationRecord) bool {
+	switch {
+	case f.Actor != "" && r.ActorTokenID != f.Actor:
+		return false
+	case f.Endpoint != "" && !strings.HasPrefix(r.Endpoint, f.Endpoint):
+		return false
+	case f.Method != "" && !strings.EqualFold(r.Method, f.Method):
+		return false
+	case f.Result != "" && r.Result != f.Result:
+		return false
+	case f.Since > 0 && r.Timestamp < f.Since:
+		return false
+	case f.Until > 0 && r.Timestamp > f.Until:
+		return false
+	}
+	return true
+}
+
+// MutationAuditConfig 审计日志配置
+type MutationAuditConfig struct {
+	Path       string                                     // 当前日志文件，轮转后的文件依次为 Path.1、Path.2…（数字越大越旧）
+	MaxSize    int64                                      // 单个文件达到该大小（字节）后轮转，<=0 不轮转
+	MaxBackups int                                        // 保留的轮转文件数，更旧的被删除
+	SignFunc   func(data []byte) ([]byte, error)          // 节点身份签名，签在每条记录的 hash 上
+	VerifyFunc func(data, signature []byte) (bool, error) // 校验本节点的签名，为 nil 时只校验哈希链
+}
+
+// DefaultMutationAuditConfig 返回默认配置：单个文件 16MB，保留 4 个轮转文件
+func DefaultMutationAuditConfig(path string) *MutationAuditConfig {
+	return &MutationAuditConfig{
+		Path:       path,
+		MaxSize:    16 << 20,
+		MaxBackups: 4,
+	}
+}
+
+// MutationAuditLog 与应用日志分离的变更审计日志
+// 每行一条 JSON 记录，按哈希链串联并由节点身份逐条签名；删改或插入任何一条都能被 Verify 发现，
+// 没有节点私钥也无法重算出有效的链。
+type MutationAuditLog struct {
+	mu     sync.Mutex
+	config *MutationAuditConfig
+	file   *os.File
+	size   int64
+	seq    uint64
+	head   string
+
+	now func() time.Time
+}
+
+// OpenMutationAuditLog 打开（不存在时创建）审计日志
+// 已有记录的哈希链或签名无效时不在其后追加：原有文件改名为 <文件>.broken-<时间戳> 留作证据，
+// 从创世哈希开始新链，返回可用的日志并同时返回 ErrAuditChainBroken 供调用方告警。
+func OpenMutationAuditLog(config *MutationAuditConfig) (*MutationAuditLog, error) {
+	if config == nil || config.SignFunc == nil {
+		return nil, ErrAuditNoSigner
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0700); err != nil {
+		return nil, err
+	}
+	l := &MutationAuditLog{config: config, head: auditGenesis, now: time.Now}
+
+	records, err := l.readLocked()
+	if err == nil {
+		err = VerifyMutationRecords(records, config.VerifyFunc)
+	}
+	var broken error
+	switch {
+	case errors.Is(err, ErrAuditChainBroken):
+		suffix := ".broken-" + strconv.FormatInt(l.now().Unix(), 10)
+		for _, path := range l.files() {
+			if err := os.Rename(path, path+suffix); err != nil {
+				return nil, err
+			}
+		}
+		broken = fmt.Errorf("%w (moved aside as *%s, starting a new chain)", err, suffix)
+	case err != nil:
+		return nil, err
+	case len(records) > 0:
+		l.seq = records[len(records)-1].Seq
+		l.head = records[len(records)-1].Hash
+	}
+
+	if err := l.openFileLocked(); err != nil {
+		return nil, err
+	}
+	return l, broken
+}
+
+// Append 追加一条记录，填入序号、前序哈希、本条哈希和签名；当前文件超过上限时轮转
+func (l *MutationAuditLog) Append(rec *MutationRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrAuditLogClosed
+	}
+	if rec.Timestamp == 0 {
+		rec.Timestamp = l.now().Unix()
+	}
+	rec.Seq = l.seq + 1
+	rec.PrevHash = l.head
+	rec.Hash = rec.computeHash()
+	signature, err := l.config.SignFunc([]byte(rec.Hash))
+	if err != nil {
+		return err
+	}
+	rec.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	n, err := l.file.Write(append(line, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	l.seq = rec.Seq
+	l.head = rec.Hash
+
+	if l.config.MaxSize > 0 && l.size >= l.config.MaxSize {
+		return l.rotateLocked()
+	}
+	return nil
+}
+
+// rotateLocked 当前文件改名为 Path.1，已有的轮转文件依次后移，超出 MaxBackups 的删除
+// 哈希链跨文件延续，新文件的第一条记录链接上一个文件的最后一条。
+func (l *MutationAuditLog) rotateLocked() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	path := l.config.Path
+	for i := l.config.MaxBackups; i >= 1; i-- {
+		if err := os.Rename(path+"."+strconv.Itoa(i), path+"."+strconv.Itoa(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		return err
+	}
+	os.Remove(path + "." + strconv.Itoa(l.config.MaxBackups+1))
+	return l.openFileLocked()
+}
+
+func (l *MutationAuditLog) openFileLocked() error {
+	file, err := os.OpenFile(l.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// files 现存的日志文件，从最旧的轮转文件到当前文件
+func (l *MutationAuditLog) files() []string {
+	var files []string
+	for i := l.config.MaxBackups; i >= 1; i-- {
+		path := l.config.Path + "." + strconv.Itoa(i)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	if _, err := os.Stat(l.config.Path); err == nil {
+		files = append(files, l.config.Path)
+	}
+	return files
+}
+
+// Query 按条件查询，最新的在前
+func (l *MutationAuditLog) Query(filter *MutationFilter) ([]*MutationRecord, error) {
+	records, err := l.read()
+	if err != nil && !errors.Is(err, ErrAuditChainBroken) {
+		return nil, err
+	}
+	if filter == nil {
+		filter = &MutationFilter{}
+	}
+	result := make([]*MutationRecord, 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		if !filter.match(records[i]) {
+			continue
+		}
+		result = append(result, records[i])
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Export 按时间顺序导出匹配的记录，用于合规审查
+// 不带过滤条件导出的是完整链，可用 VerifyMutationRecords 离线校验。
+func (l *MutationAuditLog) Export(filter *MutationFilter) ([]*MutationRecord, error) {
+	records, err := l.read()
+	if err != nil && !errors.Is(err, ErrAuditChainBroken) {
+		return nil, err
+	}
+	result := make([]*MutationRecord, 0, len(records))
+	for _, rec := range records {
+		if filter == nil || filter.match(rec) {
+			result = append(result, rec)
+		}
+	}
+	return result, nil
+}
+
+// Verify 从保留的最旧记录开始校验哈希链和签名，返回记录数和链头哈希
+func (l *MutationAuditLog) Verify() (uint64, string, error) {
+	records, err := l.read()
+	if err != nil {
+		return uint64(len(records)), "", err
+	}
+	if err := VerifyMutationRecords(records, l.config.VerifyFunc); err != nil {
+		return uint64(len(records)), "", err
+	}
+	if len(records) == 0 {
+		return 0, auditGenesis, nil
+	}
+	return uint64(len(records)), records[len(records)-1].Hash, nil
+}
+
+// Head 当前链头哈希和记录数
+func (l *MutationAuditLog) Head() (uint64, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq, l.head
+}
+
+// Close 关闭日志文件
+func (l *MutationAuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *MutationAuditLog) read() ([]*MutationRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.readLocked()
+}
+
+// readLocked 按时间顺序读取全部保留文件中的记录
+func (l *MutationAuditLog) readLocked() ([]*MutationRecord, error) {
+	var records []*MutationRecord
+	var broken error
+	for _, path := range l.files() {
+		part, err := readMutationRecords(path)
+		records = append(records, part...)
+		switch {
+		case errors.Is(err, ErrAuditChainBroken):
+			if broken == nil {
+				broken = err
+			}
+		case err != nil:
+			return records, err
+		}
+	}
+	return records, broken
+}
+
+// VerifyMutationRecords 校验一段连续导出的审计记录
+// 从第 1 条开始的记录以创世哈希为起点，其余以第一条记录的 prev_hash 为起点。
+// verify 不为 nil 时还按节点身份校验每条记录对 hash 的签名。
+func VerifyMutationRecords(records []*MutationRecord, verify func(data, signature []byte) (bool, error)) error {
+	if len(records) == 0 {
+		return nil
+	}
+	prev := records[0].PrevHash
+	if records[0].Seq == 1 {
+		prev = auditGenesis
+	}
+	for i, rec := range records {
+		if i > 0 && rec.Seq != records[i-1].Seq+1 {
+			return fmt.Errorf("%w: seq %d follows %d", ErrAuditChainBroken, rec.Seq, records[i-1].Seq)
+		}
+		if rec.PrevHash != prev {
+			return fmt.Errorf("%w: seq %d does not link to previous record", ErrAuditChainBroken, rec.Seq)
+		}
+		if rec.computeHash() != rec.Hash {
+			return fmt.Errorf("%w: seq %d hash mismatch", ErrAuditChainBroken, rec.Seq)
+		}
+		if verify != nil {
+			signature, err := base64.StdEncoding.DecodeString(rec.Signature)
+			if err != nil || len(signature) == 0 {
+				return fmt.Errorf("%w: seq %d is not signed", ErrAuditChainBroken, rec.Seq)
+			}
+			if ok, err := verify([]byte(rec.Hash), signature); err != nil || !ok {
+				return fmt.Errorf("%w: seq %d signature invalid", ErrAuditChainBroken, rec.Seq)
+			}
+		}
+		prev = rec.Hash
+	}
+	return nil
+}
+
+// readMutationRecords 读取全部记录，无法解析的行被跳过并以 ErrAuditChainBroken 报告
+func readMutationRecords(path string) ([]*MutationRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []*MutationRecord
+	var broken error
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec MutationRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			if broken == nil {
+				broken = fmt.Errorf("%w: unreadable record after seq %d", ErrAuditChainBroken, len(records))
+			}
+			continue
+		}
+		records = append(records, &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, err
+	}
+	return records, broken
+}
+
+// ============== 中间件 ==============
+
+// TokenID 令牌的公开标识（SHA-256 前 8 字节），审计记录中用它代替令牌本身
+func TokenID(token string) string {
+	if token == "" {
+		return "anonymous"
+	}
+	return "tok_" + hashHex([]byte(token))[:16]
+}
+
+// isMutation 是否为需要审计的变更类请求
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// auditedWriter 记录 handler 写出的状态码，请求结束时追加审计记录
+type auditedWriter struct {
+	http.ResponseWriter
+	log    *MutationAuditLog
+	record *MutationRecord
+	status int
+
+	authenticated bool // 通过令牌认证或节点签名校验后才记录
+}
+
+func (w *auditedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// errReader 在请求体读完后返回读取时遇到的错误（如超出大小限制），保持 handler 看到的行为不变
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// beginAudit 变更类请求读取并哈希请求体，返回包装后的 ResponseWriter；其他请求返回 nil
+func (s *Server) beginAudit(w http.ResponseWriter, r *http.Request, token string) *auditedWriter {
+	if s.config.MutationAudit == nil || !isMutation(r.Method) {
+		return nil
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(payload), errReader{err}))
+	} else {
+		r.Body = io.NopCloser(bytes.NewReader(payload))
+	}
+
+	return &auditedWriter{
+		ResponseWriter: w,
+		log:            s.config.MutationAudit,
+		record: &MutationRecord{
+			ActorTokenID: TokenID(token),
+			ActorNodeID:  r.Header.Get("X-NodeID"),
+			RemoteAddr:   r.RemoteAddr,
+			Method:       r.Method,
+			Endpoint:     r.URL.Path,
+			PayloadHash:  hashHex(payload),
+			PayloadSize:  len(payload),
+		},
+	}
+}
+
+// finish 通过认证的请求追加审计记录，写入失败只告警不影响已发出的响应
+// 未通过认证的请求不记录，匿名调用不能借此写满磁盘。
+func (w *auditedWriter) finish() {
+	if !w.authenticated {
+		return
+	}
+	w.record.Status = w.status
+	if w.record.Status == 0 {
+		w.record.Status = http.StatusOK
+	}
+	w.record.Result = AuditResultFailure
+	if w.record.Status >= 200 && w.record.Status < 300 {
+		w.record.Result = AuditResultSuccess
+	}
+	if err := w.log.Append(w.record); err != nil {
+		fmt.Fprintf(os.Stderr, "写入变更审计日志失败: %v\n", err)
+	}
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ============== 查询与导出 ==============
+
+// mutationFilterFromQuery 解析查询参数 actor、endpoint、method、result、since、until
+func mutationFilterFromQuery(r *http.Request) *MutationFilter {
+	q := r.URL.Query()
+	return &MutationFilter{
+		Actor:    q.Get("actor"),
+		Endpoint: q.Get("endpoint"),
+		Method:   q.Get("method"),
+		Result:   q.Get("result"),
+		Since:    int64(getIntQueryParam(r, "since", 0)),
+		Until:    int64(getIntQueryParam(r, "until", 0)),
+	}
+}
+
+// handleMutationAudit 查询变更审计记录（最新的在前）
+func (s *Server) handleMutationAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.MutationAudit == nil {
+		s.writeError(w, http.StatusNotImplemented, "mutation audit log not enabled")
+		return
+	}
+
+	filter := mutationFilterFromQuery(r)
+	filter.Limit = getIntQueryParam(r, "limit", 100)
+	records, err := s.config.MutationAudit.Query(filter)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"records": records,
+		"count":   len(records),
+	})
+}
+
+// handleMutationAuditExport 按时间顺序导出审计记录和当前链头，供合规审查
+// 需要可转交的证据时带 X-Sign-Response: 1 请求，由节点身份对导出内容签名。
+func (s *Server) handleMutationAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.MutationAudit == nil {
+		s.writeError(w, http.StatusNotImplemented, "mutation audit log not enabled")
+		return
+	}
+
+	records, err := s.config.MutationAudit.Export(mutationFilterFromQuery(r))
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	total, head := s.config.MutationAudit.Head()
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":     s.config.NodeID,
+		"exported_at": time.Now().Unix(),
+		"head_seq":    total,
+		"head_hash":   head,
+		"records":     records,
+		"count":       len(records),
+	})
+}
+
+// handleMutationAuditVerify 从头校验审计日志哈希链
+func (s *Server) handleMutationAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.MutationAudit == nil {
+		s.writeError(w, http.StatusNotImplemented, "mutation audit log not enabled")
+		return
+	}
+
+	count, head, err := s.config.MutationAudit.Verify()
+	result := map[string]interface{}{
+		"valid": err == nil,
+		"count": count,
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	} else {
+		result["head_hash"] = head
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,263 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// 密钥社交恢复
+//
+// 托管方把私钥的加密备份和 Shamir 份额分给受托节点；丢失私钥后以新身份发起
+// 恢复仪式，向受托节点提交恢复请求，受托节点运营者确认后发回重新包裹的份额，
+// 凑齐门限即可还原私钥。恢复请求接口可以配置为签名认证（-route-auth
+// /api/v1/recovery/request=signature），此时请求方即验签通过的节点。
+
+// RecoveryEscrowRequest 创建私钥托管请求
+type RecoveryEscrowRequest struct {
+	Guardians []string `json:"guardians" validate:"required"`
+	Threshold int      `json:"threshold" validate:"min=2"`
+}
+
+// RecoveryRequestRequest 向受托节点提交恢复请求
+type RecoveryRequestRequest struct {
+	EscrowID  string `json:"escrow_id" validate:"required"`
+	Requester string `json:"requester"` // 请求方新节点ID，签名认证时取签名者
+	Reason    string `json:"reason,omitempty"`
+}
+
+// RecoveryDecideRequest 受托节点运营者确认或拒绝恢复请求
+type RecoveryDecideRequest struct {
+	RequestID string `json:"request_id" validate:"required"`
+	Approve   bool   `json:"approve"`
+	Note      string `json:"note,omitempty"`
+}
+
+// RecoveryShareRequest 向恢复仪式提交受托节点发回的份额
+type RecoveryShareRequest struct {
+	CeremonyID string          `json:"ceremony_id" validate:"required"`
+	Share      json.RawMessage `json:"share"`
+}
+
+// handleRecoveryEscrowCreate 托管本节点私钥，返回需要分发给各受托节点的托管记录和份额
+func (s *Server) handleRecoveryEscrowCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req RecoveryEscrowRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.RecoveryEscrowFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	result, err := s.RecoveryEscrowFunc(req.Guardians, req.Threshold)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryHold 受托节点保存分给自己的份额（请求体为 {"escrow": ..., "envelope": ...}）
+func (s *Server) handleRecoveryHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.RecoveryHoldFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	result, err := s.RecoveryHoldFunc(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryEscrowGet 查询本节点保管的托管记录，请求方用它发起恢复仪式
+func (s *Server) handleRecoveryEscrowGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	escrowID := extractPathParam(r, "/api/v1/recovery/escrow/")
+	if s.RecoveryEscrowGetFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	escrow, err := s.RecoveryEscrowGetFunc(escrowID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, escrow)
+}
+
+// handleRecoveryRequest 受托节点受理恢复请求（限频），等待运营者确认
+func (s *Server) handleRecoveryRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req RecoveryRequestRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if nodeID := VerifiedNodeID(r); nodeID != "" {
+		req.Requester = nodeID
+	}
+	if req.Requester == "" {
+		s.writeError(w, http.StatusUnprocessableEntity, "requester is required")
+		return
+	}
+	if s.RecoveryRequestFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	result, err := s.RecoveryRequestFunc(req.EscrowID, req.Requester, req.Reason)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryRequests 列出收到的恢复请求
+func (s *Server) handleRecoveryRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	requests := []map[string]interface{}{}
+	if s.RecoveryRequestsFunc != nil {
+		requests = s.RecoveryRequestsFunc()
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"requests": requests,
+		"count":    len(requests),
+	})
+}
+
+// handleRecoveryDecide 确认恢复请求并返回发给请求方的份额，或拒绝请求
+func (s *Server) handleRecoveryDecide(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req RecoveryDecideRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.RecoveryDecideFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	result, err := s.RecoveryDecideFunc(req.RequestID, req.Approve, req.Note)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryCeremonyStart 以本节点（新身份）发起恢复仪式，请求体为托管记录
+func (s *Server) handleRecoveryCeremonyStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.RecoveryCeremonyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	result, err := s.RecoveryCeremonyFunc(body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryCeremonyShare 提交受托节点发回的份额，凑齐门限后还原私钥
+func (s *Server) handleRecoveryCeremonyShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req RecoveryShareRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if len(req.Share) == 0 {
+		s.writeError(w, http.StatusUnprocessableEntity, "share is required")
+		return
+	}
+	if s.RecoveryShareFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	result, err := s.RecoveryShareFunc(req.CeremonyID, req.Share)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryCeremony 查询恢复仪式进度
+func (s *Server) handleRecoveryCeremony(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ceremonyID := extractPathParam(r, "/api/v1/recovery/ceremony/")
+	if s.RecoveryCeremonyGetFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "social recovery not available")
+		return
+	}
+	result, err := s.RecoveryCeremonyGetFunc(ceremonyID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// handleRecoveryLog 恢复日志，可用 ?escrow_id= 过滤
+func (s *Server) handleRecoveryLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	entries := []map[string]interface{}{}
+	if s.RecoveryLogFunc != nil {
+		entries = s.RecoveryLogFunc(getQueryParam(r, "escrow_id", ""))
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
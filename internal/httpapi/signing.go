@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 响应签名相关请求/响应头
+const (
+	SignResponseHeader      = "X-Sign-Response"      // 请求头：值为 1 时要求对本次响应签名
+	ResponseSignerHeader    = "X-Response-Signer"    // 签名节点ID（即公钥）
+	ResponseTimestampHeader = "X-Response-Timestamp" // 签名时间（Unix 秒）
+	ResponseSignatureHeader = "X-Response-Signature" // base64 签名
+	ResponseSigVersion      = "daan-response-sig-v1" // 签名载荷版本前缀
+)
+
+var (
+	ErrSigningUnavailable = errors.New("response signing not configured")
+	ErrInvalidSignature   = errors.New("invalid response signature")
+)
+
+// VerifyResponseRequest 校验他人节点响应签名的请求
+type VerifyResponseRequest struct {
+	Signer    string          `json:"signer" validate:"required"`
+	Method    string          `json:"method"`
+	URI       string          `json:"uri"` // 请求路径（含查询串）
+	Status    int             `json:"status"`
+	Timestamp int64           `json:"timestamp"`
+	Body      json.RawMessage `json:"body" validate:"required"`
+	Signature string          `json:"signature" validate:"required"` // base64
+}
+
+// CanonicalJSON 规范化 JSON：对象键排序、去除空白、数字保持原文
+// 签名只覆盖规范化后的内容，缓存或代理重新格式化响应不影响验签。
+func CanonicalJSON(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// ResponseSigningPayload 构造响应签名载荷
+// 载荷绑定签名节点、请求方法与路径、状态码和时间，防止把一个端点的响应挪作另一个端点的证据。
+func ResponseSigningPayload(signer, method, uri string, status int, timestamp int64, body []byte) ([]byte, error) {
+	canonical, err := CanonicalJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(canonical)
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%s\n%d\n%d\n%s",
+		ResponseSigVersion, signer, method, uri, status, timestamp, hex.EncodeToString(digest[:]))), nil
+}
+
+// shouldSignResponse 是否需要对本次响应签名
+func (s *Server) shouldSignResponse(r *http.Request) bool {
+	if s.config.ResponseSignFunc == nil {
+		return false
+	}
+	return s.config.SignResponses || r.Header.Get(SignResponseHeader) == "1"
+}
+
+// signResponse 对编码后的响应体签名并写入响应头（须在 WriteHeader 之前调用）
+func signResponse(w http.ResponseWriter, sig *responseSigning, status int, body []byte) {
+	timestamp := time.Now().Unix()
+	payload, err := ResponseSigningPayload(sig.signer, sig.method, sig.uri, status, timestamp, body)
+	if err != nil {
+		return
+	}
+	signature, err := sig.sign(payload)
+	if err != nil {
+		return
+	}
+	h := w.Header()
+	h.Set(ResponseSignerHeader, sig.signer)
+	h.Set(ResponseTimestampHeader, strconv.FormatInt(timestamp, 10))
+	h.Set(ResponseSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+}
+
+// responseSigning 本次请求的响应签名参数
+type responseSigning struct {
+	signer string
+	method string
+	uri    string
+	sign   func(data []byte) ([]byte, error)
+}
+
+// handleSigningKey 公开本节点的响应签名公钥（无需认证）
+func (s *Server) handleSigningKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.ResponseSignFunc == nil || len(s.config.SigningPublicKey) == 0 {
+		s.writeError(w, http.StatusNotImplemented, ErrSigningUnavailable.Error())
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":        s.config.NodeID,
+		"algorithm":      s.config.SigningAlgorithm,
+		"public_key":     base64.StdEncoding.EncodeToString(s.config.SigningPublicKey),
+		"version":        ResponseSigVersion,
+		"always_signed":  s.config.SignResponses,
+		"request_header": SignResponseHeader,
+	})
+}
+
+// handleVerifyResponse 校验其他节点签名的响应（用于核对缓存的证据）
+func (s *Server) handleVerifyResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req VerifyResponseRequest
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if s.config.ResponseVerifyFunc == nil {
+		s.writeError(w, http.StatusNotImplemented, "response verification not available")
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "signature must be base64")
+		return
+	}
+	payload, err := ResponseSigningPayload(req.Signer, req.Method, req.URI, req.Status, req.Timestamp, req.Body)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "body is not valid JSON")
+		return
+	}
+
+	valid, err := s.config.ResponseVerifyFunc(req.Signer, payload, signature)
+	result := map[string]interface{}{
+		"valid":     err == nil && valid,
+		"signer":    req.Signer,
+		"signed_at": time.Unix(req.Timestamp, 0).UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	} else if !valid {
+		result["error"] = ErrInvalidSignature.Error()
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
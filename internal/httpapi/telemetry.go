@@ -0,0 +1,518 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API 使用统计与弃用提示
+//
+// 按路由（ServeMux 匹配的模式，不含路径参数）和客户端 SDK 版本统计调用次数，
+// 报告中列出弃用路由最近一次被调用的时间和仍在调用的客户端，据此判断何时可以删除旧端点。
+// 调用弃用路由或使用低于最低支持版本的 SDK 时，响应带 Deprecation/Sunset/Warning 头。
+
+// ClientVersionHeader 客户端 SDK 标识，格式 <sdk>/<version>，未设置时取 User-Agent 的第一个产品标识
+const ClientVersionHeader = "X-Client-Version"
+
+const (
+	maxUsageClients      = 1000 // 统计的客户端数上限，超出后计入 other
+	maxRouteClients      = 100  // 每个路由记录的客户端数上限
+	usageSaveInterval    = 5 * time.Minute
+	usageOtherClient     = "other"
+	usageUnknownClient   = "unknown"
+	defaultQuietDays     = 30
+	deprecationWarnAgent = "-" // Warning 头的 warn-agent
+)
+
+// DeprecatedRoute 弃用路由
+type DeprecatedRoute struct {
+	Sunset      string `json:"sunset,omitempty"`      // 计划删除日期（2006-01-02 或 RFC 3339）
+	Replacement string `json:"replacement,omitempty"` // 替代端点
+	Message     string `json:"message,omitempty"`
+}
+
+// DeprecationConfig 弃用配置
+type DeprecationConfig struct {
+	// Routes 路径 -> 弃用信息，以 / 结尾的路径按前缀匹配
+	Routes map[string]DeprecatedRoute `json:"routes,omitempty"`
+	// MinSDKVersions SDK 名称 -> 最低支持版本，低于该版本的客户端收到警告
+	MinSDKVersions map[string]string `json:"min_sdk_versions,omitempty"`
+}
+
+// LoadDeprecationConfig 从 JSON 文件加载弃用配置，文件不存在时返回 nil
+func LoadDeprecationConfig(path string) (*DeprecationConfig, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg DeprecationConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for path, route := range cfg.Routes {
+		if route.Sunset != "" {
+			if _, err := parseSunset(route.Sunset); err != nil {
+				return nil, fmt.Errorf("route %s: invalid sunset %q", path, route.Sunset)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// route 路径对应的弃用信息（精确匹配优先，其次最长前缀）
+func (c *DeprecationConfig) route(path string) (string, *DeprecatedRoute) {
+	if c == nil {
+		return "", nil
+	}
+	if d, ok := c.Routes[path]; ok {
+		return path, &d
+	}
+	best := ""
+	for prefix := range c.Routes {
+		if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	d := c.Routes[best]
+	return best, &d
+}
+
+// deprecatedSDK 客户端 SDK 低于最低支持版本时返回最低版本
+func (c *DeprecationConfig) deprecatedSDK(sdk, version string) (string, bool) {
+	if c == nil || version == "" {
+		return "", false
+	}
+	minVersion, ok := c.MinSDKVersions[sdk]
+	if !ok {
+		return "", false
+	}
+	return minVersion, compareVersions(version, minVersion) < 0
+}
+
+func parseSunset(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// compareVersions 按点分数字比较版本号，忽略前缀 v 和预发布后缀
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// clientOf 请求的客户端 SDK 名称和版本
+func clientOf(r *http.Request) (sdk, version string) {
+	ident := strings.TrimSpace(r.Header.Get(ClientVersionHeader))
+	if ident == "" {
+		ident = strings.TrimSpace(r.UserAgent())
+		if i := strings.IndexByte(ident, ' '); i >= 0 {
+			ident = ident[:i]
+		}
+	}
+	if ident == "" {
+		return usageUnknownClient, ""
+	}
+	sdk, version, _ = strings.Cut(ident, "/")
+	return strings.ToLower(sdk), version
+}
+
+// clientKey 统计中的客户端标识
+func clientKey(sdk, version string) string {
+	if version == "" {
+		return sdk
+	}
+	return sdk + "/" + version
+}
+
+// RouteUsage 路由调用统计
+type RouteUsage struct {
+	Method   string            `json:"method"`
+	Route    string            `json:"route"`
+	Count    uint64            `json:"count"`
+	LastSeen int64             `json:"last_seen"`
+	Clients  map[string]uint64 `json:"clients,omitempty"`
+}
+
+// ClientUsage 客户端调用统计
+type ClientUsage struct {
+	Client          string `json:"client"`
+	SDK             string `json:"sdk"`
+	Version         string `json:"version,omitempty"`
+	Count           uint64 `json:"count"`
+	DeprecatedCalls uint64 `json:"deprecated_calls"` // 调用弃用路由的次数
+	LastSeen        int64  `json:"last_seen"`
+}
+
+// APIUsage API 使用统计，定期和关闭时保存到文件，重启后累计
+type APIUsage struct {
+	mu       sync.Mutex
+	path     string
+	since    int64
+	routes   map[string]*RouteUsage
+	clients  map[string]*ClientUsage
+	dirty    bool
+	lastSave time.Time
+}
+
+type usageFile struct {
+	Since   int64          `json:"since"`
+	Routes  []*RouteUsage  `json:"routes"`
+	Clients []*ClientUsage `json:"clients"`
+}
+
+// OpenAPIUsage 打开使用统计，path 为空时只在内存中统计
+func OpenAPIUsage(path string) (*APIUsage, error) {
+	u := &APIUsage{
+		path:     path,
+		since:    time.Now().Unix(),
+		routes:   make(map[string]*RouteUsage),
+		clients:  make(map[string]*ClientUsage),
+		lastSave: time.Now(),
+	}
+	if path == "" {
+		return u, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return u, nil
+	}
+	if err != nil {
+		return u, err
+	}
+	var f usageFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return u, err
+	}
+	if f.Since > 0 {
+		u.since = f.Since
+	}
+	for _, r := range f.Routes {
+		u.routes[r.Method+" "+r.Route] = r
+	}
+	for _, c := range f.Clients {
+		u.clients[c.Client] = c
+	}
+	return u, nil
+}
+
+// Record 记录一次调用
+func (u *APIUsage) Record(method, route, sdk, version string, deprecated bool, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	key := clientKey(sdk, version)
+	client, ok := u.clients[key]
+	if !ok {
+		if len(u.clients) >= maxUsageClients {
+			key, sdk, version = usageOtherClient, usageOtherClient, ""
+			client = u.clients[key]
+		}
+		if client == nil {
+			client = &ClientUsage{Client: key, SDK: sdk, Version: version}
+			u.clients[key] = client
+		}
+	}
+	client.Count++
+	client.LastSeen = now.Unix()
+	if deprecated {
+		client.DeprecatedCalls++
+	}
+
+	routeKey := method + " " + route
+	usage, ok := u.routes[routeKey]
+	if !ok {
+		usage = &RouteUsage{Method: method, Route: route, Clients: make(map[string]uint64)}
+		u.routes[routeKey] = usage
+	}
+	usage.Count++
+	usage.LastSeen = now.Unix()
+	if usage.Clients == nil {
+		usage.Clients = make(map[string]uint64)
+	}
+	if _, ok := usage.Clients[key]; !ok && len(usage.Clients) >= maxRouteClients {
+		key = usageOtherClient
+	}
+	usage.Clients[key]++
+
+	u.dirty = true
+	if u.path != "" && now.Sub(u.lastSave) >= usageSaveInterval {
+		u.saveLocked(now)
+	}
+}
+
+// Save 保存统计
+func (u *APIUsage) Save() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.path == "" || !u.dirty {
+		return nil
+	}
+	return u.saveLocked(time.Now())
+}
+
+func (u *APIUsage) saveLocked(now time.Time) error {
+	f := usageFile{Since: u.since}
+	for _, r := range u.routes {
+		f.Routes = append(f.Routes, r)
+	}
+	for _, c := range u.clients {
+		f.Clients = append(f.Clients, c)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return err
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, u.path); err != nil {
+		return err
+	}
+	u.dirty = false
+	u.lastSave = now
+	return nil
+}
+
+// DeprecatedRouteReport 弃用路由的使用情况
+type DeprecatedRouteReport struct {
+	Route        string            `json:"route"`
+	Sunset       string            `json:"sunset,omitempty"`
+	Replacement  string            `json:"replacement,omitempty"`
+	Count        uint64            `json:"count"`
+	LastSeen     int64             `json:"last_seen,omitempty"`
+	Clients      map[string]uint64 `json:"clients,omitempty"`
+	SafeToRemove bool              `json:"safe_to_remove"` // 统计时长和最近一次调用都超过静默期
+}
+
+// UsageReport API 使用报告
+type UsageReport struct {
+	Since          int64                    `json:"since"`
+	QuietDays      int                      `json:"quiet_days"`
+	Routes         []*RouteUsage            `json:"routes"`
+	Clients        []*ClientUsage           `json:"clients"`
+	Deprecated     []*DeprecatedRouteReport `json:"deprecated"`
+	DeprecatedSDKs []*ClientUsage           `json:"deprecated_sdks"` // 低于最低支持版本的客户端
+}
+
+// Report 生成使用报告，quiet 为判断弃用路由可以删除的静默期
+func (u *APIUsage) Report(deprecations *DeprecationConfig, quiet time.Duration, now time.Time) *UsageReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	report := &UsageReport{
+		Since:          u.since,
+		QuietDays:      int(quiet / (24 * time.Hour)),
+		Routes:         make([]*RouteUsage, 0, len(u.routes)),
+		Clients:        make([]*ClientUsage, 0, len(u.clients)),
+		Deprecated:     make([]*DeprecatedRouteReport, 0),
+		DeprecatedSDKs: make([]*ClientUsage, 0),
+	}
+	for _, r := range u.routes {
+		copied := *r
+		copied.Clients = copyCounts(r.Clients)
+		report.Routes = append(report.Routes, &copied)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool {
+		if report.Routes[i].Count != report.Routes[j].Count {
+			return report.Routes[i].Count > report.Routes[j].Count
+		}
+		return report.Routes[i].Method+report.Routes[i].Route < report.Routes[j].Method+report.Routes[j].Route
+	})
+	for _, c := range u.clients {
+		copied := *c
+		report.Clients = append(report.Clients, &copied)
+		if _, old := deprecations.deprecatedSDK(c.SDK, c.Version); old {
+			report.DeprecatedSDKs = append(report.DeprecatedSDKs, &copied)
+		}
+	}
+	sort.Slice(report.Clients, func(i, j int) bool { return report.Clients[i].Client < report.Clients[j].Client })
+	sort.Slice(report.DeprecatedSDKs, func(i, j int) bool { return report.DeprecatedSDKs[i].Client < report.DeprecatedSDKs[j].Client })
+
+	if deprecations != nil {
+		cutoff := now.Add(-quiet).Unix()
+		for path, d := range deprecations.Routes {
+			dr := &DeprecatedRouteReport{Route: path, Sunset: d.Sunset, Replacement: d.Replacement, Clients: make(map[string]uint64)}
+			for _, r := range u.routes {
+				if !routeCovers(path, r.Route) {
+					continue
+				}
+				dr.Count += r.Count
+				if r.LastSeen > dr.LastSeen {
+					dr.LastSeen = r.LastSeen
+				}
+				for client, n := range r.Clients {
+					dr.Clients[client] += n
+				}
+			}
+			dr.SafeToRemove = u.since <= cutoff && (dr.LastSeen == 0 || dr.LastSeen < cutoff)
+			report.Deprecated = append(report.Deprecated, dr)
+		}
+		sort.Slice(report.Deprecated, func(i, j int) bool { return report.Deprecated[i].Route < report.Deprecated[j].Route })
+	}
+	return report
+}
+
+// routeCovers 弃用配置中的路径是否覆盖统计的路由模式
+func routeCovers(path, route string) bool {
+	if path == route {
+		return true
+	}
+	return strings.HasSuffix(path, "/") && strings.HasPrefix(route, path)
+}
+
+func copyCounts(m map[string]uint64) map[string]uint64 {
+	out := make(map[string]uint64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// applyDeprecation 为弃用路由和过旧的 SDK 设置响应头，返回是否为弃用路由
+func (s *Server) applyDeprecation(w http.ResponseWriter, r *http.Request, sdk, version string) bool {
+	cfg := s.config.Deprecations
+	if cfg == nil {
+		return false
+	}
+	h := w.Header()
+	if minVersion, old := cfg.deprecatedSDK(sdk, version); old {
+		h.Add("Warning", fmt.Sprintf(`299 %s "%s/%s is deprecated, upgrade to %s or later"`, deprecationWarnAgent, sdk, version, minVersion))
+	}
+	path, d := cfg.route(r.URL.Path)
+	if d == nil {
+		return false
+	}
+	h.Set("Deprecation", "true")
+	msg := d.Message
+	if msg == "" {
+		msg = path + " is deprecated"
+	}
+	if d.Sunset != "" {
+		if t, err := parseSunset(d.Sunset); err == nil {
+			h.Set("Sunset", t.UTC().Format(http.TimeFormat))
+			msg += ", removal planned " + t.UTC().Format("2006-01-02")
+		}
+	}
+	if d.Replacement != "" {
+		h.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, d.Replacement))
+		msg += ", use " + d.Replacement
+	}
+	h.Add("Warning", fmt.Sprintf(`299 %s %q`, deprecationWarnAgent, msg))
+	return true
+}
+
+// handleAPIUsage API 使用报告
+// GET /api/v1/node/api-usage?quiet_days=30
+func (s *Server) handleAPIUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.config.APIUsage == nil {
+		s.writeError(w, http.StatusNotImplemented, "api usage tracking not enabled")
+		return
+	}
+	quietDays := defaultQuietDays
+	if v := r.URL.Query().Get("quiet_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.writeError(w, http.StatusBadRequest, "invalid quiet_days")
+			return
+		}
+		quietDays = n
+	}
+	quiet := time.Duration(quietDays) * 24 * time.Hour
+	s.writeJSON(w, http.StatusOK, s.config.APIUsage.Report(s.config.Deprecations, quiet, time.Now()))
+}
+
+// RequestObserver 请求耗时观测（如导出为 Prometheus 直方图），route 为 ServeMux 匹配的模式，未匹配时为 unmatched
+type RequestObserver func(method, route string, status int, elapsed time.Duration)
+
+// statusRecorder 记录 handler 写出的状态码，未显式写出时为 200
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// observeRequest 包装响应以记录状态码，返回请求结束时调用的观测函数
+func (s *Server) observeRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func(r *http.Request)) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+	return rec, func(r *http.Request) {
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.config.RequestObserver(r.Method, route, status, time.Since(start))
+	}
+}
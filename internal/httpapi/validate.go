@@ -0,0 +1,284 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StrictFieldsHeader 请求头为 1 时拒绝请求体中的未知字段（Config.RejectUnknownFields 对所有请求生效）
+const StrictFieldsHeader = "X-Reject-Unknown-Fields"
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError 请求体校验失败，列出全部出错字段
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	return "request validation failed: " + strings.Join(parts, "; ")
+}
+
+// missingFields 构造必填字段缺失的校验错误（用于无法用标签表达的条件必填）
+func missingFields(names ...string) *ValidationError {
+	verr := &ValidationError{}
+	for _, name := range names {
+		verr.add(name, "required", "is required")
+	}
+	return verr
+}
+
+func (e *ValidationError) add(field, rule, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// decodeBody 解析并校验请求体，失败时写入错误响应并返回 false
+// 语法错误返回 400；字段类型错误、未知字段和 validate 标签校验失败统一返回 422。
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	strict := s.config.RejectUnknownFields || r.Header.Get(StrictFieldsHeader) == "1"
+	if err := decodeStrict(r, v, strict); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			s.writeValidationError(w, verr)
+			return false
+		}
+		s.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	if verr := validateStruct(v); verr != nil {
+		s.writeValidationError(w, verr)
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeValidationError(w http.ResponseWriter, verr *ValidationError) {
+	encodeResponse(w, http.StatusUnprocessableEntity, Response{
+		Success: false,
+		Data:    verr,
+		Error:   verr.Error(),
+		Code:    http.StatusUnprocessableEntity,
+	})
+}
+
+// decodeStrict 解码 JSON，把类型错误和未知字段转换为 ValidationError
+// 空请求体按 {} 处理，缺失的必填字段由标签校验报告。
+func decodeStrict(r *http.Request, v interface{}, rejectUnknown bool) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if rejectUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			field := typeErr.Field
+			if field == "" {
+				field = "(body)"
+			}
+			return &ValidationError{Fields: []FieldError{{
+				Field:   field,
+				Rule:    "type",
+				Message: "must be " + jsonTypeName(typeErr.Type),
+			}}}
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return &ValidationError{Fields: []FieldError{{Field: field, Rule: "unknown", Message: "is not a known field"}}}
+		}
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// ============== 标签校验 ==============
+
+// validateStruct 按 validate 标签校验结构体字段，字段名使用 json 名称
+//
+// 支持的规则（逗号分隔）：
+//
+//	required     非零值（字符串去除空白后非空）
+//	min=N,max=N  数值范围，或字符串/切片/映射长度范围
+//	oneof=a b c  取值必须是列出的之一（切片逐个元素检查）
+func validateStruct(v interface{}) *ValidationError {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	verr := &ValidationError{}
+	for _, f := range structRules(rv.Type()) {
+		fv := rv.Field(f.index)
+		for _, rule := range f.rules {
+			if msg := rule.check(fv); msg != "" {
+				verr.add(f.name, rule.name, msg)
+				break
+			}
+		}
+	}
+	if len(verr.Fields) == 0 {
+		return nil
+	}
+	return verr
+}
+
+type fieldRule struct {
+	name  string
+	param string
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []fieldRule
+}
+
+var ruleCache sync.Map // reflect.Type -> []fieldRules
+
+func structRules(t reflect.Type) []fieldRules {
+	if cached, ok := ruleCache.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+	var result []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := sf.Name
+		if jsonTag := sf.Tag.Get("json"); jsonTag != "" {
+			if n := strings.Split(jsonTag, ",")[0]; n != "" && n != "-" {
+				name = n
+			}
+		}
+		fr := fieldRules{index: i, name: name}
+		for _, part := range strings.Split(tag, ",") {
+			rule := fieldRule{name: part}
+			if k, v, ok := strings.Cut(part, "="); ok {
+				rule = fieldRule{name: k, param: v}
+			}
+			fr.rules = append(fr.rules, rule)
+		}
+		result = append(result, fr)
+	}
+	ruleCache.Store(t, result)
+	return result
+}
+
+func (rule fieldRule) check(v reflect.Value) string {
+	switch rule.name {
+	case "required":
+		if v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "" || v.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("httpapi: invalid %s rule %q", rule.name, rule.param))
+		}
+		n, unit := measure(v)
+		bound := "at least"
+		if rule.name == "max" {
+			bound = "at most"
+		}
+		if rule.name == "min" && n < limit || rule.name == "max" && n > limit {
+			if unit != "" {
+				return fmt.Sprintf("must have %s %s %s", bound, rule.param, unit)
+			}
+			return fmt.Sprintf("must be %s %s", bound, rule.param)
+		}
+	case "oneof":
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				if msg := rule.check(v.Index(i)); msg != "" {
+					return fmt.Sprintf("[%d] %s", i, msg)
+				}
+			}
+			return ""
+		}
+		if v.Kind() == reflect.String && v.String() == "" {
+			return "" // 空值由 required 规则处理
+		}
+		value := fmt.Sprint(v.Interface())
+		options := strings.Fields(rule.param)
+		for _, opt := range options {
+			if value == opt {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(options, ", ")
+	default:
+		panic("httpapi: unknown validate rule " + rule.name)
+	}
+	return ""
+}
+
+// measure 返回数值，或字符串（按字符）/切片/映射的长度及其单位
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	case reflect.String:
+		return float64(len([]rune(v.String()))), "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), "items"
+	}
+	return 0, ""
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,140 @@
+package incentive
+
+import (
+	"sort"
+	"time"
+)
+
+// PropagationSummary 已整理的传播记录按来源/目标的汇总
+// 逐条记录超过保留时长或条数上限后删除，只保留汇总，统计和对账仍可用。
+type PropagationSummary struct {
+	SourceNodeID         string    `json:"source_node_id"`
+	TargetNodeID         string    `json:"target_node_id"`
+	Count                int64     `json:"count"`                  // 已整理的记录数
+	TotalOriginalScore   float64   `json:"total_original_score"`   // 原始分数合计
+	TotalPropagatedScore float64   `json:"total_propagated_score"` // 传播后分数合计
+	MaxDepth             int       `json:"max_depth"`
+	FirstAt              time.Time `json:"first_at"`
+	LastAt               time.Time `json:"last_at"`
+}
+
+// compactionLoop 后台定期整理传播记录
+func (im *IncentiveManager) compactionLoop() {
+	interval := im.config.CompactionInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if im.CompactPropagations(time.Now()) > 0 {
+				im.save()
+			}
+		case <-im.stopCh:
+			return
+		}
+	}
+}
+
+// CompactPropagations 把过期和超出上限的传播记录并入汇总，返回整理的记录数
+func (im *IncentiveManager) CompactPropagations(now time.Time) int {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	return im.compactLocked(now)
+}
+
+// compactLocked 先按保留时长整理，再把最旧的记录整理到上限以内（调用方持有写锁）
+func (im *IncentiveManager) compactLocked(now time.Time) int {
+	removed := 0
+	if retention := im.config.PropagationRetention; retention > 0 {
+		cutoff := now.Add(-retention)
+		for id, record := range im.propagations {
+			if record.Timestamp.Before(cutoff) {
+				im.summarizeLocked(id, record)
+				removed++
+			}
+		}
+	}
+	if limit := im.config.MaxPropagationRecords; limit > 0 && len(im.propagations) > limit {
+		removed += im.evictOldestLocked(len(im.propagations) - limit)
+	}
+	return removed
+}
+
+// enforceRecordCapLocked 新增记录后检查上限
+// 超出时一次整理到上限的 90%，避免每条新记录都触发排序。
+func (im *IncentiveManager) enforceRecordCapLocked() {
+	limit := im.config.MaxPropagationRecords
+	if limit <= 0 || len(im.propagations) <= limit {
+		return
+	}
+	im.evictOldestLocked(len(im.propagations) - limit*9/10)
+}
+
+// evictOldestLocked 把最旧的 n 条记录并入汇总
+func (im *IncentiveManager) evictOldestLocked(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	records := make([]*PropagationRecord, 0, len(im.propagations))
+	for _, record := range im.propagations {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	if n > len(records) {
+		n = len(records)
+	}
+	for _, record := range records[:n] {
+		im.summarizeLocked(record.PropagationID, record)
+	}
+	return n
+}
+
+// summarizeLocked 把单条记录并入对应来源/目标的汇总并删除
+func (im *IncentiveManager) summarizeLocked(id string, record *PropagationRecord) {
+	key := record.SourceNodeID + "|" + record.TargetNodeID
+	summary, ok := im.summaries[key]
+	if !ok {
+		summary = &PropagationSummary{
+			SourceNodeID: record.SourceNodeID,
+			TargetNodeID: record.TargetNodeID,
+			FirstAt:      record.Timestamp,
+		}
+		im.summaries[key] = summary
+	}
+	im.dirty.Mark("propagation_summaries", key)
+	im.dirty.Mark("propagations", id)
+	summary.Count++
+	summary.TotalOriginalScore += record.OriginalScore
+	summary.TotalPropagatedScore += record.PropagatedScore
+	if record.Depth > summary.MaxDepth {
+		summary.MaxDepth = record.Depth
+	}
+	if record.Timestamp.Before(summary.FirstAt) {
+		summary.FirstAt = record.Timestamp
+	}
+	if record.Timestamp.After(summary.LastAt) {
+		summary.LastAt = record.Timestamp
+	}
+	delete(im.propagations, id)
+}
+
+// GetPropagationSummaries 获取与节点相关的传播汇总（作为来源或目标）
+func (im *IncentiveManager) GetPropagationSummaries(nodeID string) []*PropagationSummary {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	summaries := make([]*PropagationSummary, 0)
+	for _, summary := range im.summaries {
+		if summary.SourceNodeID == nodeID || summary.TargetNodeID == nodeID {
+			c := *summary
+			summaries = append(summaries, &c)
+		}
+	}
+	return summaries
+}
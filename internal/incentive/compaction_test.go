@@ -0,0 +1,88 @@
+package incentive
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCompactPropagations(t *testing.T) {
+	im := createTestManager(t)
+	im.config.PropagationRetention = 24 * time.Hour
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("old-%d", i)
+		im.propagations[id] = &PropagationRecord{
+			PropagationID:   id,
+			SourceNodeID:    "source-1",
+			TargetNodeID:    im.config.NodeID,
+			OriginalScore:   10,
+			PropagatedScore: 7,
+			Depth:           i + 1,
+			Timestamp:       now.Add(-time.Duration(48+i) * time.Hour),
+		}
+	}
+	if err := im.ReceivePropagation("source-2", 10, 1, "reward-1"); err != nil {
+		t.Fatalf("ReceivePropagation failed: %v", err)
+	}
+	before := im.GetStats()
+
+	if n := im.CompactPropagations(now); n != 3 {
+		t.Fatalf("compacted %d records, want 3", n)
+	}
+	if n := im.CompactPropagations(now); n != 0 {
+		t.Errorf("second compaction removed %d records, want 0", n)
+	}
+
+	after := im.GetStats()
+	if after.RetainedPropagations != 1 || after.PropagationSummaries != 1 {
+		t.Errorf("retained=%d summaries=%d, want 1 and 1", after.RetainedPropagations, after.PropagationSummaries)
+	}
+	if after.TotalPropagations != before.TotalPropagations || after.TotalPropagatedScore != before.TotalPropagatedScore {
+		t.Errorf("totals changed by compaction: before %+v after %+v", before, after)
+	}
+
+	summaries := im.GetPropagationSummaries("source-1")
+	if len(summaries) != 1 {
+		t.Fatalf("summaries count = %d, want 1", len(summaries))
+	}
+	s := summaries[0]
+	if s.Count != 3 || s.TotalPropagatedScore != 21 || s.MaxDepth != 3 || !s.FirstAt.Before(s.LastAt) {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+
+	// 汇总随状态持久化
+	if err := im.save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	reloaded, err := NewIncentiveManager(im.config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if stats := reloaded.GetStats(); stats.TotalPropagations != after.TotalPropagations || stats.PropagationSummaries != 1 {
+		t.Errorf("reloaded stats = %+v", stats)
+	}
+}
+
+func TestPropagationRecordCap(t *testing.T) {
+	im := createTestManager(t)
+	im.config.MaxPropagationRecords = 10
+
+	for i := 0; i < 11; i++ {
+		if err := im.ReceivePropagation(fmt.Sprintf("source-%d", i), 10, 1, "reward"); err != nil {
+			t.Fatalf("ReceivePropagation failed: %v", err)
+		}
+	}
+
+	stats := im.GetStats()
+	if stats.RetainedPropagations != 9 {
+		t.Errorf("retained = %d, want 9 after trimming to 90%% of the cap", stats.RetainedPropagations)
+	}
+	if stats.TotalPropagations != 11 {
+		t.Errorf("TotalPropagations = %d, want 11", stats.TotalPropagations)
+	}
+	if len(im.GetPropagationSummaries("source-0")) != 1 || len(im.GetPropagationRecords("source-10")) != 1 {
+		t.Error("the oldest records should be summarized first")
+	}
+}
@@ -12,6 +12,10 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 // 错误定义
@@ -26,6 +30,7 @@ var (
 	ErrRewardNotFound      = errors.New("reward not found")
 	ErrPropagationNotFound = errors.New("propagation not found")
 	ErrDuplicateReward     = errors.New("duplicate reward for task")
+	ErrToleranceNotFound   = errors.New("tolerance record not found for source")
 )
 
 // TaskType 任务类型
@@ -122,6 +127,8 @@ type ToleranceRecord struct {
 	RemainingTolerance float64  `json:"remaining_tolerance"` // 剩余耐受值
 	LastResetTime     time.Time `json:"last_reset_time"`     // 上次重置时间
 	NextResetTime     time.Time `json:"next_reset_time"`     // 下次重置时间
+	Inputs            *ToleranceInputs `json:"inputs,omitempty"` // 初始耐受值的计算依据
+	Manual            bool      `json:"manual,omitempty"`    // 手动设置，不随声誉变化重算
 }
 
 // TaskWeightConfig 任务权重配置
@@ -143,14 +150,28 @@ type IncentiveConfig struct {
 	MaxPropagationDepth  int                       // 最大传播深度
 	TaskWeights         map[TaskType]*TaskWeightConfig // 任务权重配置
 	
+	// 传播记录保留策略：超过保留时长或条数上限的记录并入按来源/目标的汇总后删除
+	PropagationRetention  time.Duration // 逐条记录的保留时长，0 表示不按时间清理
+	MaxPropagationRecords int           // 内存中逐条记录的上限，0 表示不限
+	CompactionInterval    time.Duration // 后台整理周期
+	
 	// 获取邻居函数
 	GetNeighborsFunc func(nodeID string) []string
 	
-	// 更新声誉函数
-	UpdateReputationFunc func(nodeID string, delta float64) error
-	
-	// 获取当前声誉函数
-	GetReputationFunc func(nodeID string) float64
+	// 声誉存储（通常为 reputation.Manager），为 nil 时只记录奖励不更新声誉
+	Reputation reputation.Store
+
+	// 耐受值倍数函数（如按信任网背书距离放宽来源节点的默认耐受值）
+	ToleranceMultiplierFunc func(sourceNodeID string) float64
+
+	// 来源关系函数（邻居、已背书、已隔离）
+	GetRelationFunc func(sourceNodeID string) SourceRelation
+
+	// 按声誉和关系计算初始耐受值的策略，为 nil 时所有来源使用 DefaultTolerance
+	TolerancePolicy *TolerancePolicy
+
+	// 存储后端，非空时替代 incentive.json，首次加载时导入旧文件
+	Store storage.Backend
 }
 
 // DefaultIncentiveConfig 返回默认配置
@@ -163,6 +184,10 @@ func DefaultIncentiveConfig(nodeID string) *IncentiveConfig {
 		ToleranceResetPeriod: 24 * time.Hour,
 		MinPropagationScore: 0.1,
 		MaxPropagationDepth: 5,
+		PropagationRetention:  7 * 24 * time.Hour,
+		MaxPropagationRecords: 10000,
+		CompactionInterval:    10 * time.Minute,
+		TolerancePolicy:     DefaultTolerancePolicy(),
 		TaskWeights: map[TaskType]*TaskWeightConfig{
 			TaskTypeGeneral:    {TaskType: TaskTypeGeneral, Weight: 1.0, MinScore: 1, MaxScore: 10},
 			TaskTypeRelay:      {TaskType: TaskTypeRelay, Weight: 1.2, MinScore: 1, MaxScore: 15},
@@ -182,7 +207,9 @@ type IncentiveManager struct {
 	rewards      map[string]*TaskReward                    // RewardID -> TaskReward
 	taskRewards  map[string]string                         // TaskID -> RewardID (防止重复)
 	propagations map[string]*PropagationRecord             // PropagationID -> Record
+	summaries    map[string]*PropagationSummary            // 来源|目标 -> 已整理记录的汇总
 	tolerances   map[string]map[string]*ToleranceRecord    // TargetNodeID -> SourceNodeID -> Record
+	dirty        *storage.Tracker                          // 使用存储后端时，上次保存后变化的记录
 	running      bool
 	stopCh       chan struct{}
 	
@@ -214,7 +241,9 @@ func NewIncentiveManager(config *IncentiveConfig) (*IncentiveManager, error) {
 		rewards:      make(map[string]*TaskReward),
 		taskRewards:  make(map[string]string),
 		propagations: make(map[string]*PropagationRecord),
+		summaries:    make(map[string]*PropagationSummary),
 		tolerances:   make(map[string]map[string]*ToleranceRecord),
+		dirty:        storage.NewTracker(),
 		stopCh:       make(chan struct{}),
 	}
 	
@@ -225,6 +254,10 @@ func NewIncentiveManager(config *IncentiveConfig) (*IncentiveManager, error) {
 	if err := im.load(); err != nil {
 		// 忽略加载错误
 	}
+	// 旧版本不清理传播记录，加载后先整理一次
+	im.mu.Lock()
+	im.compactLocked(time.Now())
+	im.mu.Unlock()
 	
 	return im, nil
 }
@@ -241,6 +274,7 @@ func (im *IncentiveManager) Start() {
 	im.mu.Unlock()
 	
 	go im.toleranceResetLoop()
+	go im.compactionLoop()
 }
 
 // Stop 停止激励系统
@@ -287,6 +321,10 @@ func (im *IncentiveManager) checkAndResetTolerances() {
 				record.RemainingTolerance = record.MaxTolerance
 				record.LastResetTime = now
 				record.NextResetTime = now.Add(im.config.ToleranceResetPeriod)
+				im.dirty.Mark("tolerances", targetID)
+				if targetID == im.config.NodeID {
+					im.refreshToleranceLocked(record)
+				}
 				
 				// 触发回调
 				if im.OnToleranceReset != nil && targetID == im.config.NodeID {
@@ -366,19 +404,23 @@ func (im *IncentiveManager) AwardTaskCompletionWithSource(nodeID, taskID string,
 	
 	im.rewards[rewardID] = reward
 	im.taskRewards[taskID] = rewardID
+	im.dirty.Mark("rewards", rewardID)
+	im.dirty.Mark("task_rewards", taskID)
 	
 	im.mu.Unlock()
 	
 	// 更新节点声誉
-	if im.config.UpdateReputationFunc != nil {
-		if err := im.config.UpdateReputationFunc(nodeID, finalScore); err == nil {
+	if im.config.Reputation != nil {
+		if err := im.config.Reputation.UpdateReputation(nodeID, finalScore); err == nil {
 			im.mu.Lock()
 			reward.Status = RewardStatusConfirmed
+			im.dirty.Mark("rewards", rewardID)
 			im.mu.Unlock()
 		}
 	} else {
 		im.mu.Lock()
 		reward.Status = RewardStatusConfirmed
+		im.dirty.Mark("rewards", rewardID)
 		im.mu.Unlock()
 	}
 	
@@ -436,6 +478,7 @@ func (im *IncentiveManager) PropagateReputation(rewardID string) ([]string, erro
 	im.mu.Lock()
 	reward.PropagatedTo = propagatedTo
 	reward.Status = RewardStatusPropagated
+	im.dirty.Mark("rewards", rewardID)
 	im.mu.Unlock()
 	
 	// 保存
@@ -472,7 +515,9 @@ func (im *IncentiveManager) propagateToNode(sourceNodeID, targetNodeID string, s
 	
 	// 检查耐受值
 	if tolerances, ok := im.tolerances[targetNodeID]; ok {
+		im.dirty.Mark("tolerances", targetNodeID)
 		if record, ok := tolerances[sourceNodeID]; ok {
+			im.refreshToleranceLocked(record)
 			if record.RemainingTolerance < propagatedScore {
 				im.mu.Unlock()
 				
@@ -489,14 +534,26 @@ func (im *IncentiveManager) propagateToNode(sourceNodeID, targetNodeID string, s
 		} else {
 			// 创建新的耐受值记录
 			now := time.Now()
+			// 首次传播照常接收，超出部分记为负的剩余耐受值；只有被隔离的来源直接拒绝
+			inputs := im.toleranceInputsFor(sourceNodeID)
+			if inputs.Relation == RelationQuarantined {
+				im.mu.Unlock()
+				
+				if im.OnToleranceExceeded != nil {
+					im.OnToleranceExceeded(sourceNodeID, targetNodeID, propagatedScore)
+				}
+				
+				return ErrToleranceExceeded
+			}
 			tolerances[sourceNodeID] = &ToleranceRecord{
 				SourceNodeID:       sourceNodeID,
 				TargetNodeID:       targetNodeID,
 				TotalReceived:      propagatedScore,
-				MaxTolerance:       im.config.DefaultTolerance,
-				RemainingTolerance: im.config.DefaultTolerance - propagatedScore,
+				MaxTolerance:       inputs.Tolerance,
+				RemainingTolerance: inputs.Tolerance - propagatedScore,
 				LastResetTime:      now,
 				NextResetTime:      now.Add(im.config.ToleranceResetPeriod),
+				Inputs:             inputs,
 			}
 		}
 	}
@@ -520,12 +577,14 @@ func (im *IncentiveManager) propagateToNode(sourceNodeID, targetNodeID string, s
 	}
 	
 	im.propagations[propagationID] = record
+	im.dirty.Mark("propagations", propagationID)
+	im.enforceRecordCapLocked()
 	
 	im.mu.Unlock()
 	
 	// 更新目标节点声誉
-	if im.config.UpdateReputationFunc != nil {
-		im.config.UpdateReputationFunc(targetNodeID, propagatedScore)
+	if im.config.Reputation != nil {
+		im.config.Reputation.UpdateReputation(targetNodeID, propagatedScore)
 	}
 	
 	return nil
@@ -612,6 +671,39 @@ func (im *IncentiveManager) GetRewardByTask(taskID string) (*TaskReward, error)
 	return reward, nil
 }
 
+// SlashReward 按比例扣减任务奖励（如存储证明失败），返回扣减的分数
+func (im *IncentiveManager) SlashReward(taskID string, ratio float64) (float64, error) {
+	if ratio <= 0 || ratio > 1 {
+		return 0, ErrInvalidScore
+	}
+	
+	im.mu.Lock()
+	rewardID, ok := im.taskRewards[taskID]
+	if !ok {
+		im.mu.Unlock()
+		return 0, ErrRewardNotFound
+	}
+	reward, ok := im.rewards[rewardID]
+	if !ok {
+		im.mu.Unlock()
+		return 0, ErrRewardNotFound
+	}
+	
+	cut := reward.FinalScore * ratio
+	reward.FinalScore -= cut
+	im.dirty.Mark("rewards", rewardID)
+	nodeID := reward.NodeID
+	im.mu.Unlock()
+	
+	if cut > 0 && im.config.Reputation != nil {
+		im.config.Reputation.UpdateReputation(nodeID, -cut)
+	}
+	
+	im.save()
+	
+	return cut, nil
+}
+
 // GetNodeRewards 获取节点的所有奖励
 func (im *IncentiveManager) GetNodeRewards(nodeID string) []*TaskReward {
 	im.mu.RLock()
@@ -679,7 +771,7 @@ func (im *IncentiveManager) ResetTolerance(sourceNodeID string) error {
 	
 	record, ok := tolerances[sourceNodeID]
 	if !ok {
-		return errors.New("tolerance record not found for source")
+		return ErrToleranceNotFound
 	}
 	
 	now := time.Now()
@@ -687,6 +779,7 @@ func (im *IncentiveManager) ResetTolerance(sourceNodeID string) error {
 	record.RemainingTolerance = record.MaxTolerance
 	record.LastResetTime = now
 	record.NextResetTime = now.Add(im.config.ToleranceResetPeriod)
+	im.dirty.Mark("tolerances", im.config.NodeID)
 	
 	return nil
 }
@@ -701,10 +794,12 @@ func (im *IncentiveManager) SetTolerance(sourceNodeID string, tolerance float64)
 		tolerances = make(map[string]*ToleranceRecord)
 		im.tolerances[im.config.NodeID] = tolerances
 	}
+	im.dirty.Mark("tolerances", im.config.NodeID)
 	
 	now := time.Now()
 	
 	if record, ok := tolerances[sourceNodeID]; ok {
+		record.Manual = true
 		record.MaxTolerance = tolerance
 		record.RemainingTolerance = tolerance - record.TotalReceived
 		if record.RemainingTolerance < 0 {
@@ -719,6 +814,7 @@ func (im *IncentiveManager) SetTolerance(sourceNodeID string, tolerance float64)
 			RemainingTolerance: tolerance,
 			LastResetTime:      now,
 			NextResetTime:      now.Add(im.config.ToleranceResetPeriod),
+			Manual:             true,
 		}
 	}
 }
@@ -757,6 +853,8 @@ type IncentiveStats struct {
 	TotalScore            float64 `json:"total_score"`
 	TotalPropagations     int64   `json:"total_propagations"`
 	TotalPropagatedScore  float64 `json:"total_propagated_score"`
+	RetainedPropagations  int     `json:"retained_propagations"`  // 内存中的逐条记录数
+	PropagationSummaries  int     `json:"propagation_summaries"`  // 来源/目标汇总数
 	ActiveTolerances      int     `json:"active_tolerances"`
 	ExceededTolerances    int     `json:"exceeded_tolerances"`
 	AverageRewardScore    float64 `json:"average_reward_score"`
@@ -769,7 +867,9 @@ func (im *IncentiveManager) GetStats() *IncentiveStats {
 	
 	stats := &IncentiveStats{
 		TotalRewards:      int64(len(im.rewards)),
-		TotalPropagations: int64(len(im.propagations)),
+		TotalPropagations:    int64(len(im.propagations)),
+		RetainedPropagations: len(im.propagations),
+		PropagationSummaries: len(im.summaries),
 	}
 	
 	for _, reward := range im.rewards {
@@ -779,6 +879,10 @@ func (im *IncentiveManager) GetStats() *IncentiveStats {
 	for _, record := range im.propagations {
 		stats.TotalPropagatedScore += record.PropagatedScore
 	}
+	for _, summary := range im.summaries {
+		stats.TotalPropagations += summary.Count
+		stats.TotalPropagatedScore += summary.TotalPropagatedScore
+	}
 	
 	if tolerances, ok := im.tolerances[im.config.NodeID]; ok {
 		stats.ActiveTolerances = len(tolerances)
@@ -796,6 +900,22 @@ func (im *IncentiveManager) GetStats() *IncentiveStats {
 	return stats
 }
 
+// Stats 实现 stats.Provider
+func (im *IncentiveManager) Stats() []stats.Metric {
+	s := im.GetStats()
+	return []stats.Metric{
+		stats.Counter("rewards_total", float64(s.TotalRewards), "累计奖励次数"),
+		stats.Counter("reward_score_total", s.TotalScore, "累计奖励分数"),
+		stats.Counter("propagations_total", float64(s.TotalPropagations), "累计传播记录数"),
+		stats.Counter("propagated_score_total", s.TotalPropagatedScore, "累计传播分数"),
+		stats.Gauge("retained_propagations", float64(s.RetainedPropagations), "内存中的逐条传播记录数"),
+		stats.Gauge("propagation_summaries", float64(s.PropagationSummaries), "传播记录的来源/目标汇总数"),
+		stats.Gauge("active_tolerances", float64(s.ActiveTolerances), "本节点的容忍记录数"),
+		stats.Gauge("exceeded_tolerances", float64(s.ExceededTolerances), "已耗尽的容忍记录数"),
+		stats.Gauge("average_reward_score", s.AverageRewardScore, "平均奖励分数"),
+	}
+}
+
 // GetTaskWeightConfig 获取任务权重配置
 func (im *IncentiveManager) GetTaskWeightConfig(taskType TaskType) *TaskWeightConfig {
 	im.mu.RLock()
@@ -833,6 +953,13 @@ func (im *IncentiveManager) SetDecayFactor(factor float64) error {
 	return nil
 }
 
+// SetRelationFunc 设置来源关系函数（邻居、已背书、已隔离），之后计算或重算的耐受值按新关系
+func (im *IncentiveManager) SetRelationFunc(fn func(sourceNodeID string) SourceRelation) {
+	im.mu.Lock()
+	im.config.GetRelationFunc = fn
+	im.mu.Unlock()
+}
+
 // SetDefaultTolerance 设置默认耐受值
 func (im *IncentiveManager) SetDefaultTolerance(tolerance float64) {
 	im.mu.Lock()
@@ -845,11 +972,28 @@ type persistState struct {
 	Rewards      map[string]*TaskReward                 `json:"rewards"`
 	TaskRewards  map[string]string                      `json:"task_rewards"`
 	Propagations map[string]*PropagationRecord          `json:"propagations"`
+	Summaries    map[string]*PropagationSummary         `json:"propagation_summaries,omitempty"`
 	Tolerances   map[string]map[string]*ToleranceRecord `json:"tolerances"`
 }
 
 // save 保存数据
 func (im *IncentiveManager) save() error {
+	if im.config.Store != nil {
+		im.mu.RLock()
+		snap, err := storage.EncodeState(storePrefix, &persistState{
+			Rewards:      im.rewards,
+			TaskRewards:  im.taskRewards,
+			Propagations: im.propagations,
+			Summaries:    im.summaries,
+			Tolerances:   im.tolerances,
+		}, im.dirty)
+		im.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		_, err = snap.Write(im.config.Store)
+		return err
+	}
 	if im.config.DataDir == "" {
 		return nil
 	}
@@ -868,6 +1012,11 @@ func (im *IncentiveManager) save() error {
 	for k, v := range im.propagations {
 		propagationsCopy[k] = v
 	}
+	summariesCopy := make(map[string]*PropagationSummary, len(im.summaries))
+	for k, v := range im.summaries {
+		c := *v
+		summariesCopy[k] = &c
+	}
 	tolerancesCopy := make(map[string]map[string]*ToleranceRecord)
 	for k, v := range im.tolerances {
 		innerCopy := make(map[string]*ToleranceRecord)
@@ -882,6 +1031,7 @@ func (im *IncentiveManager) save() error {
 		Rewards:      rewardsCopy,
 		TaskRewards:  taskRewardsCopy,
 		Propagations: propagationsCopy,
+		Summaries:    summariesCopy,
 		Tolerances:   tolerancesCopy,
 	}
 	
@@ -894,8 +1044,40 @@ func (im *IncentiveManager) save() error {
 	return os.WriteFile(filePath, data, 0644)
 }
 
-// load 加载数据
+// storePrefix 激励数据在存储后端中的 bucket 前缀
+const storePrefix = "incentive"
+
+// load 加载数据：使用存储后端时，后端为空则导入旧的 incentive.json
 func (im *IncentiveManager) load() error {
+	store := im.config.Store
+	if store == nil {
+		return im.loadFile()
+	}
+	
+	var state persistState
+	found, err := storage.LoadState(store, storePrefix, &state)
+	if err != nil {
+		return err
+	}
+	if found {
+		im.applyState(&state)
+		return nil
+	}
+	if im.config.DataDir == "" {
+		return nil
+	}
+	if err := im.loadFile(); err != nil {
+		return err
+	}
+	im.dirty.MarkAll()
+	if err := im.save(); err != nil {
+		return err
+	}
+	return storage.RetireLegacy(filepath.Join(im.config.DataDir, "incentive.json"))
+}
+
+// loadFile 从 incentive.json 加载
+func (im *IncentiveManager) loadFile() error {
 	if im.config.DataDir == "" {
 		return nil
 	}
@@ -914,6 +1096,12 @@ func (im *IncentiveManager) load() error {
 		return err
 	}
 	
+	im.applyState(&state)
+	return nil
+}
+
+// applyState 用加载的状态替换内存数据
+func (im *IncentiveManager) applyState(state *persistState) {
 	im.mu.Lock()
 	defer im.mu.Unlock()
 	
@@ -926,11 +1114,12 @@ func (im *IncentiveManager) load() error {
 	if state.Propagations != nil {
 		im.propagations = state.Propagations
 	}
+	if state.Summaries != nil {
+		im.summaries = state.Summaries
+	}
 	if state.Tolerances != nil {
 		im.tolerances = state.Tolerances
 	}
-	
-	return nil
 }
 
 // Clear 清空所有数据
@@ -941,6 +1130,8 @@ func (im *IncentiveManager) Clear() {
 	im.rewards = make(map[string]*TaskReward)
 	im.taskRewards = make(map[string]string)
 	im.propagations = make(map[string]*PropagationRecord)
+	im.summaries = make(map[string]*PropagationSummary)
 	im.tolerances = make(map[string]map[string]*ToleranceRecord)
 	im.tolerances[im.config.NodeID] = make(map[string]*ToleranceRecord)
+	im.dirty.MarkAll()
 }
@@ -4,6 +4,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
 )
 
 func createTestManager(t *testing.T) *IncentiveManager {
@@ -405,6 +407,131 @@ func TestToleranceCallback(t *testing.T) {
 	}
 }
 
+func TestToleranceMultiplier(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := &IncentiveConfig{
+		NodeID:              "test-node",
+		DataDir:             tmpDir,
+		DefaultDecayFactor:  0.7,
+		DefaultTolerance:    10.0,
+		ToleranceResetPeriod: 24 * time.Hour,
+		MinPropagationScore: 0.1,
+		MaxPropagationDepth: 5,
+		TaskWeights: map[TaskType]*TaskWeightConfig{
+			TaskTypeGeneral: {Weight: 1.0, MinScore: 1, MaxScore: 100},
+		},
+		ToleranceMultiplierFunc: func(source string) float64 {
+			if source == "endorsed" {
+				return 2.0
+			}
+			return 1.0
+		},
+	}
+	
+	im, _ := NewIncentiveManager(config)
+	
+	// 被背书的来源耐受值翻倍 (7 + 7 = 14 <= 20)
+	if err := im.ReceivePropagation("endorsed", 10, 1, "reward-1"); err != nil {
+		t.Fatalf("first propagation should succeed: %v", err)
+	}
+	if err := im.ReceivePropagation("endorsed", 10, 1, "reward-2"); err != nil {
+		t.Errorf("endorsed source should have doubled tolerance: %v", err)
+	}
+	if record := im.GetToleranceRecord("endorsed"); record.MaxTolerance != 20.0 {
+		t.Errorf("MaxTolerance = %f, want 20.0", record.MaxTolerance)
+	}
+	
+	// 未背书的来源保持默认耐受值
+	im.ReceivePropagation("stranger", 10, 1, "reward-3")
+	if err := im.ReceivePropagation("stranger", 10, 1, "reward-4"); err != ErrToleranceExceeded {
+		t.Errorf("expected ErrToleranceExceeded, got %v", err)
+	}
+}
+
+func TestReputationSensitiveTolerance(t *testing.T) {
+	reputations := map[string]float64{"trusted": 100, "newcomer": 10, "neighbor": 50, "bad": 50}
+	relations := map[string]SourceRelation{"neighbor": RelationNeighbor, "bad": RelationQuarantined}
+	
+	config := DefaultIncentiveConfig("test-node")
+	config.DataDir = t.TempDir()
+	config.DefaultTolerance = 10.0
+	config.Reputation = reputation.StoreFuncs{Get: func(nodeID string) float64 { return reputations[nodeID] }}
+	config.GetRelationFunc = func(nodeID string) SourceRelation { return relations[nodeID] }
+	
+	im, _ := NewIncentiveManager(config)
+	
+	// 10 × 声誉系数 × 关系系数
+	tests := map[string]float64{"trusted": 20, "newcomer": 2, "neighbor": 15}
+	for source, want := range tests {
+		if err := im.ReceivePropagation(source, 1, 1, "reward-"+source); err != nil {
+			t.Fatalf("propagation from %s failed: %v", source, err)
+		}
+		record := im.GetToleranceRecord(source)
+		if record.MaxTolerance != want {
+			t.Errorf("%s: MaxTolerance = %f, want %f", source, record.MaxTolerance, want)
+		}
+		if record.Inputs == nil || record.Inputs.Reputation != reputations[source] {
+			t.Errorf("%s: inputs not recorded: %+v", source, record.Inputs)
+		}
+	}
+	
+	// 被隔离的来源不接收传播
+	if err := im.ReceivePropagation("bad", 1, 1, "reward-bad"); err != ErrToleranceExceeded {
+		t.Errorf("expected ErrToleranceExceeded for quarantined source, got %v", err)
+	}
+	
+	// 首次传播超出初始耐受值时照常接收，之后的传播被拒绝
+	reputations["low"] = 10
+	if err := im.ReceivePropagation("low", 5, 1, "reward-low1"); err != nil {
+		t.Fatalf("first propagation should succeed: %v", err)
+	}
+	if record := im.GetToleranceRecord("low"); record.RemainingTolerance != -1.5 {
+		t.Errorf("RemainingTolerance = %f, want -1.5", record.RemainingTolerance)
+	}
+	if err := im.ReceivePropagation("low", 1, 1, "reward-low2"); err != ErrToleranceExceeded {
+		t.Errorf("expected ErrToleranceExceeded, got %v", err)
+	}
+	
+	// 小幅声誉变化不重算
+	reputations["newcomer"] = 15
+	im.ReceivePropagation("newcomer", 1, 1, "reward-n2")
+	if record := im.GetToleranceRecord("newcomer"); record.MaxTolerance != 2 {
+		t.Errorf("MaxTolerance = %f, want 2 after small change", record.MaxTolerance)
+	}
+	
+	// 声誉显著上升后重算，已接收的声誉继续计入
+	reputations["newcomer"] = 50
+	if !im.OnReputationChanged("newcomer") {
+		t.Fatal("expected recompute after significant reputation change")
+	}
+	record := im.GetToleranceRecord("newcomer")
+	if record.MaxTolerance != 10 || record.RemainingTolerance != 10-record.TotalReceived {
+		t.Errorf("unexpected record after recompute: %+v", record)
+	}
+	
+	// 手动设置的耐受值不随声誉变化
+	im.SetTolerance("trusted", 5)
+	reputations["trusted"] = 20
+	if im.OnReputationChanged("trusted") {
+		t.Error("manual tolerance should not be recomputed")
+	}
+	if _, err := im.RecomputeTolerance("trusted"); err != nil {
+		t.Fatalf("RecomputeTolerance failed: %v", err)
+	}
+	if record := im.GetToleranceRecord("trusted"); record.Manual || record.MaxTolerance != 4 {
+		t.Errorf("unexpected record after explicit recompute: %+v", record)
+	}
+	if _, err := im.RecomputeTolerance("unknown"); err != ErrToleranceNotFound {
+		t.Errorf("expected ErrToleranceNotFound, got %v", err)
+	}
+	
+	// 关系函数可在创建后设置
+	im.SetRelationFunc(func(nodeID string) SourceRelation { return RelationNeighbor })
+	if inputs := im.PreviewTolerance("newcomer"); inputs.Relation != RelationNeighbor || inputs.Tolerance != 15 {
+		t.Errorf("unexpected preview after SetRelationFunc: %+v", inputs)
+	}
+}
+
 func TestResetTolerance(t *testing.T) {
 	im := createTestManager(t)
 	
@@ -804,11 +931,11 @@ func TestUpdateReputationCallback(t *testing.T) {
 		TaskWeights: map[TaskType]*TaskWeightConfig{
 			TaskTypeGeneral: {Weight: 1.0, MinScore: 1, MaxScore: 100},
 		},
-		UpdateReputationFunc: func(nodeID string, delta float64) error {
+		Reputation: reputation.StoreFuncs{Update: func(nodeID string, delta float64) error {
 			updatedNodeID = nodeID
 			updatedDelta = delta
 			return nil
-		},
+		}},
 	}
 	
 	im, _ := NewIncentiveManager(config)
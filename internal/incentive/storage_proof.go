@@ -0,0 +1,599 @@
+package incentive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 存储证明相关错误
+var (
+	ErrEmptyChunks         = errors.New("chunks cannot be empty")
+	ErrInvalidChunkIndex   = errors.New("chunk index out of range")
+	ErrInvalidMerkleRoot   = errors.New("invalid merkle root")
+	ErrCommitmentNotFound  = errors.New("storage commitment not found")
+	ErrCommitmentExists    = errors.New("storage commitment already exists")
+	ErrChallengeNotFound   = errors.New("storage challenge not found")
+	ErrChallengeNotPending = errors.New("storage challenge is not pending")
+	ErrNilProof            = errors.New("proof cannot be nil")
+)
+
+// StorageChunkSize 存储承诺的分块大小，验证者和存储方按同一大小切分数据
+const StorageChunkSize = 64 * 1024
+
+// Merkle 哈希域分隔前缀，防止叶子节点与内部节点混淆
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// ChallengeStatus 存储挑战状态
+type ChallengeStatus string
+
+const (
+	ChallengePending ChallengeStatus = "pending" // 等待响应
+	ChallengePassed  ChallengeStatus = "passed"  // 证明通过
+	ChallengeFailed  ChallengeStatus = "failed"  // 证明失败
+	ChallengeExpired ChallengeStatus = "expired" // 超时未响应
+)
+
+// StorageCommitment 存储承诺（存储方声明持有的数据）
+type StorageCommitment struct {
+	TaskID     string    `json:"task_id"`     // 存储任务ID
+	NodeID     string    `json:"node_id"`     // 存储方节点
+	MerkleRoot string    `json:"merkle_root"` // 数据分块的 Merkle 根
+	ChunkCount int       `json:"chunk_count"` // 分块数量
+	CreatedAt  time.Time `json:"created_at"`
+	Passed     int       `json:"passed"` // 通过的挑战数
+	Failed     int       `json:"failed"` // 失败的挑战数
+}
+
+// StorageChallenge 存储挑战
+type StorageChallenge struct {
+	ChallengeID string          `json:"challenge_id"`
+	TaskID      string          `json:"task_id"`
+	NodeID      string          `json:"node_id"`     // 被挑战的存储方
+	VerifierID  string          `json:"verifier_id"` // 发起挑战的验证者
+	ChunkIndex  int             `json:"chunk_index"` // 随机抽取的分块
+	IssuedAt    time.Time       `json:"issued_at"`
+	Deadline    time.Time       `json:"deadline"`
+	Status      ChallengeStatus `json:"status"`
+	FailReason  string          `json:"fail_reason,omitempty"`
+}
+
+// StorageProof 存储方对挑战的响应
+type StorageProof struct {
+	ChallengeID string   `json:"challenge_id"`
+	ChunkIndex  int      `json:"chunk_index"`
+	ChunkData   []byte   `json:"chunk_data"` // 被挑战分块的原始数据
+	Path        []string `json:"path"`       // 自底向上的兄弟节点哈希（hex）
+}
+
+// ============ Merkle 树 ============
+
+// SplitChunks 将数据按固定大小切分
+func SplitChunks(data []byte, chunkSize int) [][]byte {
+	if chunkSize <= 0 || len(data) == 0 {
+		return nil
+	}
+	chunks := make([][]byte, 0, (len(data)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(data); start += chunkSize {
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunks = append(chunks, data[start:end])
+	}
+	return chunks
+}
+
+// MerkleTree 存储数据的 Merkle 树
+type MerkleTree struct {
+	levels [][][]byte // levels[0] 为叶子层，最后一层为根
+}
+
+// BuildMerkleTree 根据数据分块构建 Merkle 树
+// 奇数个节点时复制最后一个节点补齐
+func BuildMerkleTree(chunks [][]byte) (*MerkleTree, error) {
+	if len(chunks) == 0 {
+		return nil, ErrEmptyChunks
+	}
+
+	leaves := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		leaves[i] = hashLeaf(chunk)
+	}
+
+	levels := [][][]byte{leaves}
+	current := leaves
+	for len(current) > 1 {
+		next := make([][]byte, 0, (len(current)+1)/2)
+		for i := 0; i < len(current); i += 2 {
+			right := current[i]
+			if i+1 < len(current) {
+				right = current[i+1]
+			}
+			next = append(next, hashNode(current[i], right))
+		}
+		levels = append(levels, next)
+		current = next
+	}
+
+	return &MerkleTree{levels: levels}, nil
+}
+
+// Root 返回 Merkle 根（hex）
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(t.levels[len(t.levels)-1][0])
+}
+
+// ChunkCount 返回叶子数量
+func (t *MerkleTree) ChunkCount() int {
+	return len(t.levels[0])
+}
+
+// Proof 生成指定分块的 Merkle 路径
+func (t *MerkleTree) Proof(index int) ([]string, error) {
+	if index < 0 || index >= len(t.levels[0]) {
+		return nil, ErrInvalidChunkIndex
+	}
+
+	path := make([]string, 0, len(t.levels)-1)
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := index ^ 1
+		if sibling >= len(level) {
+			sibling = index
+		}
+		path = append(path, hex.EncodeToString(level[sibling]))
+		index /= 2
+	}
+	return path, nil
+}
+
+// VerifyMerkleProof 验证分块数据与 Merkle 路径是否能还原出根
+func VerifyMerkleProof(root string, index, chunkCount int, chunk []byte, path []string) bool {
+	if index < 0 || index >= chunkCount {
+		return false
+	}
+	if len(path) != merkleDepth(chunkCount) {
+		return false
+	}
+
+	expected, err := hex.DecodeString(root)
+	if err != nil {
+		return false
+	}
+
+	current := hashLeaf(chunk)
+	for _, siblingHex := range path {
+		sibling, err := hex.DecodeString(siblingHex)
+		if err != nil {
+			return false
+		}
+		if index%2 == 0 {
+			current = hashNode(current, sibling)
+		} else {
+			current = hashNode(sibling, current)
+		}
+		index /= 2
+	}
+
+	return bytes.Equal(current, expected)
+}
+
+// merkleDepth 计算给定叶子数的树高（不含根）
+func merkleDepth(chunkCount int) int {
+	depth := 0
+	for width := chunkCount; width > 1; width = (width + 1) / 2 {
+		depth++
+	}
+	return depth
+}
+
+func hashLeaf(chunk []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write(chunk)
+	return h.Sum(nil)
+}
+
+func hashNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// ============ 挑战-响应协议 ============
+
+// StorageProofConfig 存储证明配置
+type StorageProofConfig struct {
+	VerifierID         string        // 本节点（验证者）ID
+	DataDir            string        // 数据目录，为空时承诺只保存在内存中
+	ChallengeTimeout   time.Duration // 挑战响应超时
+	ChallengeRetention time.Duration // 已结束的挑战保留多久，供查询挑战记录，0 表示不删除
+	PenaltyRatio       float64       // 每次失败扣减的奖励比例
+
+	// 挑战失败时发起指责，由外部注入（通常对接 accusation 模块）
+	AccuseFunc func(nodeID, reason, evidence string) error
+}
+
+// DefaultStorageProofConfig 返回默认配置
+func DefaultStorageProofConfig(verifierID string) *StorageProofConfig {
+	return &StorageProofConfig{
+		VerifierID:         verifierID,
+		ChallengeTimeout:   5 * time.Minute,
+		ChallengeRetention: 24 * time.Hour,
+		PenaltyRatio:       0.5,
+	}
+}
+
+// StorageProofManager 存储证明管理器
+type StorageProofManager struct {
+	mu          sync.RWMutex
+	config      *StorageProofConfig
+	incentive   *IncentiveManager
+	commitments map[string]*StorageCommitment // TaskID -> Commitment
+	challenges  map[string]*StorageChallenge  // ChallengeID -> Challenge
+
+	// 回调
+	OnChallengePassed func(*StorageChallenge)
+	OnChallengeFailed func(*StorageChallenge)
+}
+
+// NewStorageProofManager 创建存储证明管理器
+func NewStorageProofManager(im *IncentiveManager, config *StorageProofConfig) (*StorageProofManager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.VerifierID == "" {
+		return nil, ErrEmptyNodeID
+	}
+
+	m := &StorageProofManager{
+		config:      config,
+		incentive:   im,
+		commitments: make(map[string]*StorageCommitment),
+		challenges:  make(map[string]*StorageChallenge),
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if err := m.load(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// RegisterCommitment 登记存储方的数据承诺
+func (m *StorageProofManager) RegisterCommitment(taskID, nodeID, merkleRoot string, chunkCount int) (*StorageCommitment, error) {
+	if taskID == "" {
+		return nil, ErrEmptyTaskID
+	}
+	if nodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+	if chunkCount <= 0 {
+		return nil, ErrEmptyChunks
+	}
+	if root, err := hex.DecodeString(merkleRoot); err != nil || len(root) != sha256.Size {
+		return nil, ErrInvalidMerkleRoot
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.commitments[taskID]; exists {
+		return nil, ErrCommitmentExists
+	}
+
+	commitment := &StorageCommitment{
+		TaskID:     taskID,
+		NodeID:     nodeID,
+		MerkleRoot: merkleRoot,
+		ChunkCount: chunkCount,
+		CreatedAt:  time.Now(),
+	}
+	m.commitments[taskID] = commitment
+	m.saveLocked()
+	return commitment, nil
+}
+
+// GetCommitment 获取存储承诺
+func (m *StorageProofManager) GetCommitment(taskID string) (*StorageCommitment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	commitment, ok := m.commitments[taskID]
+	if !ok {
+		return nil, ErrCommitmentNotFound
+	}
+	return commitment, nil
+}
+
+// Commitments 按任务ID排序返回所有存储承诺
+func (m *StorageProofManager) Commitments() []*StorageCommitment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	commitments := make([]*StorageCommitment, 0, len(m.commitments))
+	for _, commitment := range m.commitments {
+		commitments = append(commitments, commitment)
+	}
+	sort.Slice(commitments, func(i, j int) bool {
+		return commitments[i].TaskID < commitments[j].TaskID
+	})
+	return commitments
+}
+
+// IssueChallenge 对存储任务随机抽取一个分块发起挑战
+func (m *StorageProofManager) IssueChallenge(taskID string) (*StorageChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	commitment, ok := m.commitments[taskID]
+	if !ok {
+		return nil, ErrCommitmentNotFound
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(commitment.ChunkCount)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pick chunk: %w", err)
+	}
+	index := int(n.Int64())
+
+	now := time.Now()
+	idData := fmt.Sprintf("%s%s%d%d", taskID, commitment.NodeID, index, now.UnixNano())
+	hash := sha256.Sum256([]byte(idData))
+
+	challenge := &StorageChallenge{
+		ChallengeID: hex.EncodeToString(hash[:16]),
+		TaskID:      taskID,
+		NodeID:      commitment.NodeID,
+		VerifierID:  m.config.VerifierID,
+		ChunkIndex:  index,
+		IssuedAt:    now,
+		Deadline:    now.Add(m.config.ChallengeTimeout),
+		Status:      ChallengePending,
+	}
+	m.challenges[challenge.ChallengeID] = challenge
+	return challenge, nil
+}
+
+// GetChallenge 获取挑战
+func (m *StorageProofManager) GetChallenge(challengeID string) (*StorageChallenge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	challenge, ok := m.challenges[challengeID]
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+	return challenge, nil
+}
+
+// SubmitProof 提交挑战响应并验证
+// 返回证明是否通过；验证失败会扣减奖励并发起指责
+func (m *StorageProofManager) SubmitProof(proof *StorageProof) (bool, error) {
+	if proof == nil {
+		return false, ErrNilProof
+	}
+
+	m.mu.Lock()
+	challenge, ok := m.challenges[proof.ChallengeID]
+	if !ok {
+		m.mu.Unlock()
+		return false, ErrChallengeNotFound
+	}
+	if challenge.Status != ChallengePending {
+		m.mu.Unlock()
+		return false, ErrChallengeNotPending
+	}
+	commitment := m.commitments[challenge.TaskID]
+
+	var reason string
+	switch {
+	case time.Now().After(challenge.Deadline):
+		challenge.Status = ChallengeExpired
+		reason = "proof submitted after deadline"
+	case proof.ChunkIndex != challenge.ChunkIndex:
+		challenge.Status = ChallengeFailed
+		reason = fmt.Sprintf("wrong chunk index: expected %d, got %d", challenge.ChunkIndex, proof.ChunkIndex)
+	case !VerifyMerkleProof(commitment.MerkleRoot, proof.ChunkIndex, commitment.ChunkCount, proof.ChunkData, proof.Path):
+		challenge.Status = ChallengeFailed
+		reason = "merkle proof verification failed"
+	default:
+		challenge.Status = ChallengePassed
+		commitment.Passed++
+	}
+
+	if challenge.Status == ChallengePassed {
+		m.saveLocked()
+		m.mu.Unlock()
+		if m.OnChallengePassed != nil {
+			m.OnChallengePassed(challenge)
+		}
+		return true, nil
+	}
+
+	challenge.FailReason = reason
+	commitment.Failed++
+	m.saveLocked()
+	m.mu.Unlock()
+
+	m.handleFailure(challenge)
+	return false, nil
+}
+
+// ExpireChallenges 将超时未响应的挑战判定为失败，并删除超过保留期的已结束挑战，返回判定失败的数量
+func (m *StorageProofManager) ExpireChallenges() int {
+	now := time.Now()
+	expired := make([]*StorageChallenge, 0)
+
+	m.mu.Lock()
+	for id, challenge := range m.challenges {
+		if challenge.Status != ChallengePending {
+			if m.config.ChallengeRetention > 0 && now.Sub(challenge.Deadline) > m.config.ChallengeRetention {
+				delete(m.challenges, id)
+			}
+			continue
+		}
+		if now.After(challenge.Deadline) {
+			challenge.Status = ChallengeExpired
+			challenge.FailReason = "no response before deadline"
+			if commitment, ok := m.commitments[challenge.TaskID]; ok {
+				commitment.Failed++
+			}
+			expired = append(expired, challenge)
+		}
+	}
+	if len(expired) > 0 {
+		m.saveLocked()
+	}
+	m.mu.Unlock()
+
+	for _, challenge := range expired {
+		m.handleFailure(challenge)
+	}
+	return len(expired)
+}
+
+// handleFailure 处理失败的挑战：扣减奖励并发起指责
+func (m *StorageProofManager) handleFailure(challenge *StorageChallenge) {
+	if m.incentive != nil && m.config.PenaltyRatio > 0 {
+		m.incentive.SlashReward(challenge.TaskID, m.config.PenaltyRatio)
+	}
+
+	if m.config.AccuseFunc != nil {
+		reason := fmt.Sprintf("storage challenge failed for task %s: %s", challenge.TaskID, challenge.FailReason)
+		evidence := fmt.Sprintf("challenge_id=%s chunk_index=%d", challenge.ChallengeID, challenge.ChunkIndex)
+		m.config.AccuseFunc(challenge.NodeID, reason, evidence)
+	}
+
+	if m.OnChallengeFailed != nil {
+		m.OnChallengeFailed(challenge)
+	}
+}
+
+// GetChallengesByTask 获取任务的所有挑战
+func (m *StorageProofManager) GetChallengesByTask(taskID string) []*StorageChallenge {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	challenges := make([]*StorageChallenge, 0)
+	for _, challenge := range m.challenges {
+		if challenge.TaskID == taskID {
+			challenges = append(challenges, challenge)
+		}
+	}
+	return challenges
+}
+
+// saveLocked 将承诺写入 storage_commitments.json，调用方持有锁。
+// 挑战只在内存中保留，重启后未完成的挑战在下一轮重新发起。
+func (m *StorageProofManager) saveLocked() {
+	if m.config.DataDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.commitments, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(m.config.DataDir, "storage_commitments.json"), data, 0644)
+}
+
+// load 从 storage_commitments.json 加载承诺
+func (m *StorageProofManager) load() error {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "storage_commitments.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, &m.commitments)
+}
+
+// BuildStorageProof 存储方按 StorageChunkSize 切分持有的数据，为挑战抽取的分块生成证明
+func BuildStorageProof(challenge *StorageChallenge, data []byte) (*StorageProof, error) {
+	chunks := SplitChunks(data, StorageChunkSize)
+	tree, err := BuildMerkleTree(chunks)
+	if err != nil {
+		return nil, err
+	}
+	path, err := tree.Proof(challenge.ChunkIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageProof{
+		ChallengeID: challenge.ChallengeID,
+		ChunkIndex:  challenge.ChunkIndex,
+		ChunkData:   chunks[challenge.ChunkIndex],
+		Path:        path,
+	}, nil
+}
+
+// ============ 挑战的网络传输 ============
+//
+// 验证者向存储方打开流并发送挑战，存储方读取本地持有的数据生成证明后回复。
+// 验证者收到回复后交给 SubmitProof 判定，存储方拒绝或无法响应时挑战保持待响应，超时后判定失败。
+
+// StorageProofProtocol 存储挑战的流协议
+const StorageProofProtocol = "/daan/storage-proof/1.0.0"
+
+// maxStorageProofMessage 单条挑战或证明的大小上限（一个分块加 Merkle 路径）
+const maxStorageProofMessage = 4 * StorageChunkSize
+
+// storageProofReply 存储证明回复，存储方拒绝响应时只有 Error
+type storageProofReply struct {
+	Proof *StorageProof `json:"proof,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// RequestStorageProof 在流上发送挑战并读取存储方的证明
+func RequestStorageProof(rw io.ReadWriter, challenge *StorageChallenge) (*StorageProof, error) {
+	if err := json.NewEncoder(rw).Encode(challenge); err != nil {
+		return nil, err
+	}
+	var reply storageProofReply
+	if err := json.NewDecoder(io.LimitReader(rw, maxStorageProofMessage)).Decode(&reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	if reply.Proof == nil {
+		return nil, ErrNilProof
+	}
+	return reply.Proof, nil
+}
+
+// ServeStorageProof 读取一条挑战，用 prove 生成证明并回复
+func ServeStorageProof(rw io.ReadWriter, prove func(challenge *StorageChallenge) (*StorageProof, error)) error {
+	var challenge StorageChallenge
+	if err := json.NewDecoder(io.LimitReader(rw, maxStorageProofMessage)).Decode(&challenge); err != nil {
+		return err
+	}
+	var reply storageProofReply
+	proof, err := prove(&challenge)
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.Proof = proof
+	}
+	return json.NewEncoder(rw).Encode(&reply)
+}
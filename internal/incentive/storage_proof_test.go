@@ -0,0 +1,320 @@
+package incentive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMerkleTreeProof(t *testing.T) {
+	data := bytes.Repeat([]byte("agentnetwork-storage-"), 50)
+
+	for _, chunkSize := range []int{7, 64, 1000, 5000} {
+		chunks := SplitChunks(data, chunkSize)
+		tree, err := BuildMerkleTree(chunks)
+		if err != nil {
+			t.Fatalf("BuildMerkleTree failed: %v", err)
+		}
+		if tree.ChunkCount() != len(chunks) {
+			t.Errorf("expected %d chunks, got %d", len(chunks), tree.ChunkCount())
+		}
+
+		for i, chunk := range chunks {
+			path, err := tree.Proof(i)
+			if err != nil {
+				t.Fatalf("Proof(%d) failed: %v", i, err)
+			}
+			if !VerifyMerkleProof(tree.Root(), i, len(chunks), chunk, path) {
+				t.Errorf("chunkSize=%d: valid proof for chunk %d rejected", chunkSize, i)
+			}
+		}
+	}
+}
+
+func TestMerkleProofRejectsTampering(t *testing.T) {
+	chunks := SplitChunks([]byte("0123456789abcdefghijklmnopqrstuvwxyz"), 4)
+	tree, _ := BuildMerkleTree(chunks)
+	path, _ := tree.Proof(3)
+
+	t.Run("wrong data", func(t *testing.T) {
+		if VerifyMerkleProof(tree.Root(), 3, len(chunks), []byte("fake"), path) {
+			t.Error("expected tampered chunk to be rejected")
+		}
+	})
+
+	t.Run("wrong index", func(t *testing.T) {
+		if VerifyMerkleProof(tree.Root(), 2, len(chunks), chunks[3], path) {
+			t.Error("expected proof for wrong index to be rejected")
+		}
+	})
+
+	t.Run("truncated path", func(t *testing.T) {
+		if VerifyMerkleProof(tree.Root(), 3, len(chunks), chunks[3], path[:len(path)-1]) {
+			t.Error("expected truncated path to be rejected")
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		if _, err := tree.Proof(len(chunks)); err != ErrInvalidChunkIndex {
+			t.Errorf("expected ErrInvalidChunkIndex, got %v", err)
+		}
+	})
+}
+
+func TestBuildMerkleTreeEmpty(t *testing.T) {
+	if _, err := BuildMerkleTree(nil); err != ErrEmptyChunks {
+		t.Errorf("expected ErrEmptyChunks, got %v", err)
+	}
+}
+
+func setupStorageProof(t *testing.T) (*StorageProofManager, *IncentiveManager, *MerkleTree, [][]byte) {
+	t.Helper()
+
+	im := createTestManager(t)
+	im.SetTaskWeightConfig(TaskTypeStorage, 1.0, 1, 20)
+	if _, err := im.AwardTaskCompletionWithSource("storer", "task-storage-1", TaskTypeStorage, SourceStorageService, 10, "store file"); err != nil {
+		t.Fatalf("failed to award storage task: %v", err)
+	}
+
+	spm, err := NewStorageProofManager(im, DefaultStorageProofConfig("verifier"))
+	if err != nil {
+		t.Fatalf("failed to create storage proof manager: %v", err)
+	}
+
+	chunks := SplitChunks(bytes.Repeat([]byte("x"), 1000), 100)
+	tree, _ := BuildMerkleTree(chunks)
+	if _, err := spm.RegisterCommitment("task-storage-1", "storer", tree.Root(), tree.ChunkCount()); err != nil {
+		t.Fatalf("failed to register commitment: %v", err)
+	}
+
+	return spm, im, tree, chunks
+}
+
+func TestStorageChallengePass(t *testing.T) {
+	spm, im, tree, chunks := setupStorageProof(t)
+
+	challenge, err := spm.IssueChallenge("task-storage-1")
+	if err != nil {
+		t.Fatalf("IssueChallenge failed: %v", err)
+	}
+	if challenge.ChunkIndex < 0 || challenge.ChunkIndex >= len(chunks) {
+		t.Fatalf("chunk index %d out of range", challenge.ChunkIndex)
+	}
+
+	path, _ := tree.Proof(challenge.ChunkIndex)
+	ok, err := spm.SubmitProof(&StorageProof{
+		ChallengeID: challenge.ChallengeID,
+		ChunkIndex:  challenge.ChunkIndex,
+		ChunkData:   chunks[challenge.ChunkIndex],
+		Path:        path,
+	})
+	if err != nil || !ok {
+		t.Fatalf("expected proof to pass, ok=%v err=%v", ok, err)
+	}
+
+	reward, _ := im.GetRewardByTask("task-storage-1")
+	if reward.FinalScore != 10 {
+		t.Errorf("expected reward untouched, got %f", reward.FinalScore)
+	}
+
+	commitment, _ := spm.GetCommitment("task-storage-1")
+	if commitment.Passed != 1 {
+		t.Errorf("expected 1 passed challenge, got %d", commitment.Passed)
+	}
+
+	// 重复提交应被拒绝
+	if _, err := spm.SubmitProof(&StorageProof{ChallengeID: challenge.ChallengeID}); err != ErrChallengeNotPending {
+		t.Errorf("expected ErrChallengeNotPending, got %v", err)
+	}
+}
+
+func TestStorageChallengeFailCutsRewardAndAccuses(t *testing.T) {
+	spm, im, _, _ := setupStorageProof(t)
+
+	var mu sync.Mutex
+	var accused []string
+	spm.config.AccuseFunc = func(nodeID, reason, evidence string) error {
+		mu.Lock()
+		accused = append(accused, nodeID)
+		mu.Unlock()
+		return nil
+	}
+
+	challenge, _ := spm.IssueChallenge("task-storage-1")
+	ok, err := spm.SubmitProof(&StorageProof{
+		ChallengeID: challenge.ChallengeID,
+		ChunkIndex:  challenge.ChunkIndex,
+		ChunkData:   []byte("i did not store this"),
+	})
+	if err != nil {
+		t.Fatalf("SubmitProof failed: %v", err)
+	}
+	if ok {
+		t.Fatal("expected forged proof to fail")
+	}
+
+	got, _ := spm.GetChallenge(challenge.ChallengeID)
+	if got.Status != ChallengeFailed {
+		t.Errorf("expected status failed, got %s", got.Status)
+	}
+
+	reward, _ := im.GetRewardByTask("task-storage-1")
+	if reward.FinalScore != 5 {
+		t.Errorf("expected reward cut to 5, got %f", reward.FinalScore)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(accused) != 1 || accused[0] != "storer" {
+		t.Errorf("expected accusation against storer, got %v", accused)
+	}
+}
+
+func TestStorageChallengeExpire(t *testing.T) {
+	spm, im, _, _ := setupStorageProof(t)
+	spm.config.ChallengeTimeout = time.Millisecond
+
+	challenge, _ := spm.IssueChallenge("task-storage-1")
+	time.Sleep(5 * time.Millisecond)
+
+	if n := spm.ExpireChallenges(); n != 1 {
+		t.Fatalf("expected 1 expired challenge, got %d", n)
+	}
+
+	got, _ := spm.GetChallenge(challenge.ChallengeID)
+	if got.Status != ChallengeExpired {
+		t.Errorf("expected status expired, got %s", got.Status)
+	}
+
+	reward, _ := im.GetRewardByTask("task-storage-1")
+	if reward.FinalScore >= 10 {
+		t.Errorf("expected reward to be cut, got %f", reward.FinalScore)
+	}
+
+	// 已结束的挑战超过保留期后删除，待响应的挑战保留
+	spm.config.ChallengeRetention = time.Millisecond
+	spm.config.ChallengeTimeout = time.Hour
+	pending, _ := spm.IssueChallenge("task-storage-1")
+	time.Sleep(5 * time.Millisecond)
+	if n := spm.ExpireChallenges(); n != 0 {
+		t.Errorf("expected no newly expired challenges, got %d", n)
+	}
+	if _, err := spm.GetChallenge(challenge.ChallengeID); err != ErrChallengeNotFound {
+		t.Errorf("expected finished challenge to be pruned, got %v", err)
+	}
+	if _, err := spm.GetChallenge(pending.ChallengeID); err != nil {
+		t.Errorf("expected pending challenge to be kept, got %v", err)
+	}
+}
+
+func TestRegisterCommitmentValidation(t *testing.T) {
+	spm, _ := NewStorageProofManager(nil, DefaultStorageProofConfig("verifier"))
+
+	if _, err := spm.RegisterCommitment("t1", "n1", "not-hex", 4); err != ErrInvalidMerkleRoot {
+		t.Errorf("expected ErrInvalidMerkleRoot, got %v", err)
+	}
+	if _, err := spm.RegisterCommitment("t1", "n1", "", 0); err != ErrEmptyChunks {
+		t.Errorf("expected ErrEmptyChunks, got %v", err)
+	}
+	if _, err := spm.IssueChallenge("missing"); err != ErrCommitmentNotFound {
+		t.Errorf("expected ErrCommitmentNotFound, got %v", err)
+	}
+}
+
+func TestSlashReward(t *testing.T) {
+	im := createTestManager(t)
+	im.AwardTaskCompletion("node1", "task1", TaskTypeGeneral, 8, "")
+
+	cut, err := im.SlashReward("task1", 0.25)
+	if err != nil {
+		t.Fatalf("SlashReward failed: %v", err)
+	}
+	if cut != 2 {
+		t.Errorf("expected cut 2, got %f", cut)
+	}
+
+	if _, err := im.SlashReward("missing", 0.5); err != ErrRewardNotFound {
+		t.Errorf("expected ErrRewardNotFound, got %v", err)
+	}
+	if _, err := im.SlashReward("task1", 1.5); err != ErrInvalidScore {
+		t.Errorf("expected ErrInvalidScore, got %v", err)
+	}
+}
+
+func TestStorageProofOverStream(t *testing.T) {
+	spm, _ := NewStorageProofManager(nil, DefaultStorageProofConfig("verifier"))
+	data := bytes.Repeat([]byte("0123456789"), StorageChunkSize/4)
+	tree, _ := BuildMerkleTree(SplitChunks(data, StorageChunkSize))
+	if _, err := spm.RegisterCommitment("t1", "storer", tree.Root(), tree.ChunkCount()); err != nil {
+		t.Fatal(err)
+	}
+	challenge, _ := spm.IssueChallenge("t1")
+
+	// 请求端先写挑战，服务端读取后回复证明
+	var request bytes.Buffer
+	if err := json.NewEncoder(&request).Encode(challenge); err != nil {
+		t.Fatal(err)
+	}
+	var response bytes.Buffer
+	if err := ServeStorageProof(struct {
+		io.Reader
+		io.Writer
+	}{&request, &response}, func(c *StorageChallenge) (*StorageProof, error) {
+		return BuildStorageProof(c, data)
+	}); err != nil {
+		t.Fatalf("ServeStorageProof failed: %v", err)
+	}
+
+	proof, err := RequestStorageProof(struct {
+		io.Reader
+		io.Writer
+	}{&response, io.Discard}, challenge)
+	if err != nil {
+		t.Fatalf("RequestStorageProof failed: %v", err)
+	}
+	if ok, err := spm.SubmitProof(proof); err != nil || !ok {
+		t.Errorf("expected proof to pass, got %v, %v", ok, err)
+	}
+
+	// 存储方拒绝时返回其错误
+	request.Reset()
+	response.Reset()
+	json.NewEncoder(&request).Encode(challenge)
+	ServeStorageProof(struct {
+		io.Reader
+		io.Writer
+	}{&request, &response}, func(c *StorageChallenge) (*StorageProof, error) {
+		return nil, errors.New("not stored here")
+	})
+	if _, err := RequestStorageProof(struct {
+		io.Reader
+		io.Writer
+	}{&response, io.Discard}, challenge); err == nil || err.Error() != "not stored here" {
+		t.Errorf("expected storer error, got %v", err)
+	}
+}
+
+func TestStorageCommitmentsPersist(t *testing.T) {
+	config := DefaultStorageProofConfig("verifier")
+	config.DataDir = t.TempDir()
+	spm, err := NewStorageProofManager(nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := BuildMerkleTree(SplitChunks([]byte("stored data"), StorageChunkSize))
+	spm.RegisterCommitment("t2", "storer", tree.Root(), tree.ChunkCount())
+	spm.RegisterCommitment("t1", "storer", tree.Root(), tree.ChunkCount())
+
+	reloaded, err := NewStorageProofManager(nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitments := reloaded.Commitments()
+	if len(commitments) != 2 || commitments[0].TaskID != "t1" || commitments[1].MerkleRoot != tree.Root() {
+		t.Errorf("expected commitments to survive restart, got %+v", commitments)
+	}
+}
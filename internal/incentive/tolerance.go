@@ -0,0 +1,156 @@
+package incentive
+
+import (
+	"math"
+	"time"
+)
+
+// SourceRelation 来源节点与本节点的关系
+type SourceRelation string
+
+const (
+	RelationUnknown     SourceRelation = "unknown"     // 无特殊关系
+	RelationNeighbor    SourceRelation = "neighbor"    // 邻居
+	RelationEndorsed    SourceRelation = "endorsed"    // 本节点背书过的节点
+	RelationQuarantined SourceRelation = "quarantined" // 被隔离的节点，不接收其传播
+)
+
+// TolerancePolicy 按来源的声誉和关系计算初始耐受值
+//
+//	耐受值 = DefaultTolerance × 声誉系数 × 关系系数 × 外部倍数
+//	声誉系数 = clamp(来源声誉 / ReferenceReputation, MinReputationFactor, MaxReputationFactor)
+//
+// 外部倍数来自 ToleranceMultiplierFunc（如信任网背书距离）。
+type TolerancePolicy struct {
+	ReferenceReputation float64                    // 声誉系数为 1 时的声誉
+	MinReputationFactor float64                    // 声誉系数下限
+	MaxReputationFactor float64                    // 声誉系数上限
+	RelationFactors     map[SourceRelation]float64 // 关系系数，未列出的关系按 1 计算
+	RecomputeThreshold  float64                    // 来源声誉变化超过该值时重算耐受值
+}
+
+// DefaultTolerancePolicy 返回默认耐受值策略
+func DefaultTolerancePolicy() *TolerancePolicy {
+	return &TolerancePolicy{
+		ReferenceReputation: 50,
+		MinReputationFactor: 0.2,
+		MaxReputationFactor: 2.0,
+		RelationFactors: map[SourceRelation]float64{
+			RelationNeighbor:    1.5,
+			RelationEndorsed:    1.25,
+			RelationQuarantined: 0,
+		},
+		RecomputeThreshold: 10,
+	}
+}
+
+// ToleranceInputs 初始耐受值的计算依据
+type ToleranceInputs struct {
+	Base             float64        `json:"base"`
+	Reputation       float64        `json:"reputation"`
+	HasReputation    bool           `json:"has_reputation"` // 未接入声誉查询时声誉系数为 1
+	ReputationFactor float64        `json:"reputation_factor"`
+	Relation         SourceRelation `json:"relation"`
+	RelationFactor   float64        `json:"relation_factor"`
+	Multiplier       float64        `json:"multiplier"`
+	Tolerance        float64        `json:"tolerance"`
+	ComputedAt       time.Time      `json:"computed_at"`
+}
+
+// toleranceInputsFor 计算来源节点的初始耐受值及依据（调用方需持有锁）
+func (im *IncentiveManager) toleranceInputsFor(sourceNodeID string) *ToleranceInputs {
+	inputs := &ToleranceInputs{
+		Base:             im.config.DefaultTolerance,
+		ReputationFactor: 1,
+		Relation:         RelationUnknown,
+		RelationFactor:   1,
+		Multiplier:       1,
+		ComputedAt:       time.Now(),
+	}
+
+	if policy := im.config.TolerancePolicy; policy != nil {
+		if im.config.Reputation != nil && policy.ReferenceReputation > 0 {
+			inputs.Reputation = im.config.Reputation.GetReputation(sourceNodeID)
+			inputs.HasReputation = true
+			factor := inputs.Reputation / policy.ReferenceReputation
+			inputs.ReputationFactor = math.Min(math.Max(factor, policy.MinReputationFactor), policy.MaxReputationFactor)
+		}
+		if im.config.GetRelationFunc != nil {
+			if relation := im.config.GetRelationFunc(sourceNodeID); relation != "" {
+				inputs.Relation = relation
+			}
+		}
+		if factor, ok := policy.RelationFactors[inputs.Relation]; ok {
+			inputs.RelationFactor = factor
+		}
+	}
+
+	if im.config.ToleranceMultiplierFunc != nil {
+		if m := im.config.ToleranceMultiplierFunc(sourceNodeID); m > 0 {
+			inputs.Multiplier = m
+		}
+	}
+
+	inputs.Tolerance = inputs.Base * inputs.ReputationFactor * inputs.RelationFactor * inputs.Multiplier
+	return inputs
+}
+
+// applyToleranceInputs 用新的计算结果更新记录，已接收的声誉继续计入（调用方需持有锁）
+func applyToleranceInputs(record *ToleranceRecord, inputs *ToleranceInputs) {
+	record.Inputs = inputs
+	record.MaxTolerance = inputs.Tolerance
+	record.RemainingTolerance = math.Max(inputs.Tolerance-record.TotalReceived, 0)
+}
+
+// refreshToleranceLocked 来源声誉变化超过阈值或关系改变时重算耐受值（调用方需持有锁）
+// 手动设置的耐受值不会被重算。
+func (im *IncentiveManager) refreshToleranceLocked(record *ToleranceRecord) bool {
+	policy := im.config.TolerancePolicy
+	if policy == nil || record.Manual || record.Inputs == nil {
+		return false
+	}
+	inputs := im.toleranceInputsFor(record.SourceNodeID)
+	if inputs.Relation == record.Inputs.Relation && inputs.Multiplier == record.Inputs.Multiplier &&
+		math.Abs(inputs.Reputation-record.Inputs.Reputation) < policy.RecomputeThreshold {
+		return false
+	}
+	applyToleranceInputs(record, inputs)
+	im.dirty.Mark("tolerances", record.TargetNodeID)
+	return true
+}
+
+// RecomputeTolerance 立即按当前声誉和关系重算某来源的耐受值（如刚隔离或背书该节点）
+// 手动设置过的耐受值也会恢复为按策略计算。
+func (im *IncentiveManager) RecomputeTolerance(sourceNodeID string) (*ToleranceRecord, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	record, ok := im.tolerances[im.config.NodeID][sourceNodeID]
+	if !ok {
+		return nil, ErrToleranceNotFound
+	}
+	record.Manual = false
+	applyToleranceInputs(record, im.toleranceInputsFor(sourceNodeID))
+	im.dirty.Mark("tolerances", im.config.NodeID)
+	copied := *record
+	return &copied, nil
+}
+
+// OnReputationChanged 来源声誉变化时调用，变化超过阈值才重算
+func (im *IncentiveManager) OnReputationChanged(sourceNodeID string) bool {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	record, ok := im.tolerances[im.config.NodeID][sourceNodeID]
+	if !ok {
+		return false
+	}
+	return im.refreshToleranceLocked(record)
+}
+
+// PreviewTolerance 计算某来源当前应得的初始耐受值（不创建记录）
+func (im *IncentiveManager) PreviewTolerance(sourceNodeID string) *ToleranceInputs {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return im.toleranceInputsFor(sourceNodeID)
+}
@@ -0,0 +1,259 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy defines how long events are kept, per event type.
+// A zero duration keeps events forever.
+type RetentionPolicy struct {
+	Default time.Duration               // Retention for types without a rule
+	Rules   map[EventType]time.Duration // Per-type retention
+}
+
+// DefaultRetentionPolicy keeps heartbeats for 7 days and everything else forever.
+// Governance and membership events rebuild node state (see SnapshotManager),
+// so they should only be given a retention if snapshots cover them.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Rules: map[EventType]time.Duration{
+			EventHeartbeat: 7 * 24 * time.Hour,
+		},
+	}
+}
+
+// For returns the retention for an event type (0 = forever)
+func (p RetentionPolicy) For(eventType EventType) time.Duration {
+	if d, ok := p.Rules[eventType]; ok {
+		return d
+	}
+	return p.Default
+}
+
+// HoldFunc reports whether any sequence in [start, end] is under legal hold
+type HoldFunc func(start, end uint64) bool
+
+// PrunedRange records a run of consecutive events removed by compaction.
+// The hashes at both ends keep the chain verifiable across the gap.
+type PrunedRange struct {
+	FromSeq  uint64 `json:"from_seq"`
+	ToSeq    uint64 `json:"to_seq"`
+	PrevHash string `json:"prev_hash"` // PrevHash of the first removed event
+	LastHash string `json:"last_hash"` // Hash of the last removed event
+}
+
+// CompactionResult summarizes one compaction run
+type CompactionResult struct {
+	Removed   map[EventType]int `json:"removed"`   // Events removed per type
+	Held      int               `json:"held"`      // Expired events kept because of a legal hold
+	Remaining int               `json:"remaining"` // Events left in the ledger
+}
+
+// TypeUsage reports storage used by one event type
+type TypeUsage struct {
+	Type      EventType `json:"type"`
+	Events    int       `json:"events"`           // Retained events
+	Bytes     int64     `json:"bytes"`            // Serialized size of retained events
+	Pruned    uint64    `json:"pruned"`           // Events removed by compaction so far
+	Oldest    int64     `json:"oldest,omitempty"` // Timestamp of the oldest retained event
+	Retention string    `json:"retention"`        // Configured retention, "forever" if unlimited
+}
+
+// SetRetentionPolicy sets the retention policy enforced by Compact
+func (l *Ledger) SetRetentionPolicy(policy RetentionPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.retention = policy
+}
+
+// GetRetentionPolicy returns the current retention policy
+func (l *Ledger) GetRetentionPolicy() RetentionPolicy {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.retention
+}
+
+// SetHoldFunc sets the legal hold check consulted before removing events
+func (l *Ledger) SetHoldFunc(fn HoldFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.holdFunc = fn
+}
+
+// GetPrunedRanges returns the runs of events removed by compaction
+func (l *Ledger) GetPrunedRanges() []PrunedRange {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	result := make([]PrunedRange, len(l.pruned))
+	copy(result, l.pruned)
+	return result
+}
+
+// Compact removes events older than their type's retention.
+// Sequence numbers are never reused; removed runs are recorded as PrunedRange.
+func (l *Ledger) Compact() (*CompactionResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now().Unix()
+	result := &CompactionResult{Removed: make(map[EventType]int)}
+
+	kept := make([]*Event, 0, len(l.events))
+	ranges := make([]PrunedRange, 0, len(l.pruned))
+	var open *PrunedRange
+	extend := func(r PrunedRange) {
+		if open == nil {
+			open = &r
+			return
+		}
+		open.ToSeq = r.ToSeq
+		open.LastHash = r.LastHash
+	}
+	flush := func() {
+		if open != nil {
+			ranges = append(ranges, *open)
+			open = nil
+		}
+	}
+
+	// Walk retained events and existing ranges in sequence order, merging adjacent runs
+	ri := 0
+	for _, event := range l.events {
+		for ri < len(l.pruned) && l.pruned[ri].FromSeq < event.Sequence {
+			extend(l.pruned[ri])
+			ri++
+		}
+
+		retention := l.retention.For(event.Type)
+		if retention <= 0 || event.Timestamp+int64(retention/time.Second) > now {
+			flush()
+			kept = append(kept, event)
+			continue
+		}
+		if l.holdFunc != nil && l.holdFunc(event.Sequence, event.Sequence) {
+			result.Held++
+			flush()
+			kept = append(kept, event)
+			continue
+		}
+
+		extend(PrunedRange{FromSeq: event.Sequence, ToSeq: event.Sequence, PrevHash: event.PrevHash, LastHash: event.Hash})
+		result.Removed[event.Type]++
+	}
+	for ; ri < len(l.pruned); ri++ {
+		extend(l.pruned[ri])
+	}
+	flush()
+
+	result.Remaining = len(kept)
+	if len(kept) == len(l.events) {
+		return result, nil
+	}
+
+	l.events = kept
+	l.pruned = ranges
+	for eventType, n := range result.Removed {
+		l.prunedByType[eventType] += uint64(n)
+	}
+
+	// Rebuild indices
+	l.index = make(map[string][]*Event)
+	l.typeIndex = make(map[EventType][]*Event)
+	for _, event := range l.events {
+		l.index[event.NodeID] = append(l.index[event.NodeID], event)
+		l.typeIndex[event.Type] = append(l.typeIndex[event.Type], event)
+	}
+
+	if l.dataDir != "" {
+		if err := l.save(); err != nil {
+			return result, fmt.Errorf("failed to persist compacted ledger: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// StorageUsage reports retained events, bytes and compacted counts per event type
+func (l *Ledger) StorageUsage() []TypeUsage {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	usage := make(map[EventType]*TypeUsage)
+	get := func(eventType EventType) *TypeUsage {
+		u, ok := usage[eventType]
+		if !ok {
+			retention := "forever"
+			if d := l.retention.For(eventType); d > 0 {
+				retention = d.String()
+			}
+			u = &TypeUsage{Type: eventType, Retention: retention}
+			usage[eventType] = u
+		}
+		return u
+	}
+
+	for eventType, events := range l.typeIndex {
+		u := get(eventType)
+		for _, event := range events {
+			bytes, _ := json.Marshal(event)
+			u.Events++
+			u.Bytes += int64(len(bytes))
+			if u.Oldest == 0 || event.Timestamp < u.Oldest {
+				u.Oldest = event.Timestamp
+			}
+		}
+	}
+	for eventType, n := range l.prunedByType {
+		get(eventType).Pruned = n
+	}
+
+	result := make([]TypeUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, *u)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// StartCompaction runs Compact every interval until StopCompaction is called
+func (l *Ledger) StartCompaction(interval time.Duration) {
+	l.mu.Lock()
+	if l.compactStop != nil {
+		l.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	l.compactStop = stop
+	l.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := l.Compact(); err != nil {
+					fmt.Printf("Warning: ledger compaction failed: %v\n", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopCompaction stops the compaction job
+func (l *Ledger) StopCompaction() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.compactStop != nil {
+		close(l.compactStop)
+		l.compactStop = nil
+	}
+}
@@ -36,6 +36,12 @@ const (
 	// Committee events
 	EventCommitteeChange EventType = "COMMITTEE_CHANGE" // Committee member change
 	EventCommitteeVote   EventType = "COMMITTEE_VOTE"   // Committee voting event
+
+	// Governance events
+	EventGovernanceChange EventType = "GOVERNANCE_CHANGE" // Change applied by a passed governance proposal
+
+	// Liveness events (high volume, usually short retention)
+	EventHeartbeat EventType = "HEARTBEAT" // Periodic node heartbeat
 )
 
 // Event represents a single event in the ledger
@@ -132,6 +138,33 @@ type CommitteeChangeData struct {
 	Reason       string   `json:"reason"`
 }
 
+// GovernanceChangeData represents data for GOVERNANCE_CHANGE event
+type GovernanceChangeData struct {
+	ProposalID    string               `json:"proposal_id"`
+	Action        string               `json:"action"`                   // ban, unban, parameter
+	TargetNodeID  string               `json:"target_node_id,omitempty"` // Banned or restored node
+	ParameterKey  string               `json:"parameter_key,omitempty"`
+	OldValue      string               `json:"old_value,omitempty"`
+	NewValue      string               `json:"new_value,omitempty"`
+	ProposerID    string               `json:"proposer_id"`
+	Reason        string               `json:"reason"`
+	Votes         []GovernanceVoteData `json:"votes"` // Votes counted in the result
+	YesWeight     float64              `json:"yes_weight"`
+	NoWeight      float64              `json:"no_weight"`
+	AbstainWeight float64              `json:"abstain_weight"`
+	YesRatio      float64              `json:"yes_ratio"`
+	AppliedAt     int64                `json:"applied_at"`
+}
+
+// GovernanceVoteData is a single counted vote; DelegatorID is set when the
+// weight was cast by VoterID on behalf of a delegator
+type GovernanceVoteData struct {
+	VoterID     string  `json:"voter_id"`
+	DelegatorID string  `json:"delegator_id,omitempty"`
+	Choice      string  `json:"choice"`
+	Weight      float64 `json:"weight"`
+}
+
 // NewEvent creates a new event with the given parameters
 func NewEvent(seq uint64, eventType EventType, nodeID string, data interface{}, signerID, prevHash string) (*Event, error) {
 	dataBytes, err := json.Marshal(data)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
@@ -27,6 +28,13 @@ type Ledger struct {
 	signFunc   SignFunc   // Signing function
 	verifyFunc VerifyFunc // Verification function
 	
+	retention    RetentionPolicy      // Per-type retention enforced by Compact
+	holdFunc     HoldFunc             // Legal hold check, held events are never compacted
+	pruned       []PrunedRange        // Runs of events removed by compaction
+	prunedByType map[EventType]uint64 // Compacted event count per type
+	compactStop  chan struct{}        // Closes to stop the compaction job
+	now          func() time.Time
+	
 	mu sync.RWMutex
 }
 
@@ -39,6 +47,9 @@ func NewLedger(dataDir string) (*Ledger, error) {
 		typeIndex: make(map[EventType][]*Event),
 		lastSeq:   0,
 		lastHash:  "", // Genesis hash is empty
+		retention:    DefaultRetentionPolicy(),
+		prunedByType: make(map[EventType]uint64),
+		now:          time.Now,
 	}
 
 	// Create data directory if not exists
@@ -172,10 +183,11 @@ func (l *Ledger) GetEvent(seq uint64) *Event {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	if seq == 0 || seq > uint64(len(l.events)) {
-		return nil
+	i := l.position(seq)
+	if i == len(l.events) || l.events[i].Sequence != seq {
+		return nil // Never appended, or removed by compaction
 	}
-	return l.events[seq-1] // seq is 1-based
+	return l.events[i]
 }
 
 // GetEvents returns events in a range [startSeq, endSeq]
@@ -193,13 +205,21 @@ func (l *Ledger) GetEvents(startSeq, endSeq uint64) []*Event {
 		return nil
 	}
 
-	result := make([]*Event, 0, endSeq-startSeq+1)
-	for i := startSeq; i <= endSeq; i++ {
-		result = append(result, l.events[i-1])
+	// Compacted events leave gaps, so the range may hold fewer events than sequence numbers
+	result := make([]*Event, 0)
+	for i := l.position(startSeq); i < len(l.events) && l.events[i].Sequence <= endSeq; i++ {
+		result = append(result, l.events[i])
 	}
 	return result
 }
 
+// position returns the index of the first retained event with Sequence >= seq
+func (l *Ledger) position(seq uint64) int {
+	return sort.Search(len(l.events), func(i int) bool {
+		return l.events[i].Sequence >= seq
+	})
+}
+
 // GetEventsByNode returns all events related to a node
 func (l *Ledger) GetEventsByNode(nodeID string) []*Event {
 	l.mu.RLock()
@@ -270,15 +290,34 @@ func (l *Ledger) EventCount() int {
 	return len(l.events)
 }
 
-// VerifyChain verifies the integrity of the event chain
+// VerifyChain verifies the integrity of the event chain.
+// Runs removed by compaction are checked through the hashes kept in their PrunedRange.
 func (l *Ledger) VerifyChain() error {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
 	prevHash := ""
+	expectedSeq := uint64(1)
+	ri := 0
+	skipPruned := func(before uint64) error {
+		for ri < len(l.pruned) && l.pruned[ri].FromSeq < before {
+			r := l.pruned[ri]
+			if r.FromSeq != expectedSeq || r.PrevHash != prevHash {
+				return fmt.Errorf("pruned range %d-%d does not link to seq %d", r.FromSeq, r.ToSeq, expectedSeq-1)
+			}
+			prevHash = r.LastHash
+			expectedSeq = r.ToSeq + 1
+			ri++
+		}
+		return nil
+	}
+
 	for i, event := range l.events {
+		if err := skipPruned(event.Sequence); err != nil {
+			return err
+		}
+
 		// Check sequence
-		expectedSeq := uint64(i + 1)
 		if event.Sequence != expectedSeq {
 			return fmt.Errorf("sequence mismatch at index %d: expected %d, got %d",
 				i, expectedSeq, event.Sequence)
@@ -296,6 +335,14 @@ func (l *Ledger) VerifyChain() error {
 		}
 
 		prevHash = event.Hash
+		expectedSeq++
+	}
+
+	if err := skipPruned(l.lastSeq + 1); err != nil {
+		return err
+	}
+	if prevHash != l.lastHash {
+		return fmt.Errorf("last hash mismatch")
 	}
 
 	return nil
@@ -367,6 +414,9 @@ type ledgerData struct {
 	LastSeq  uint64   `json:"last_seq"`
 	LastHash string   `json:"last_hash"`
 	SavedAt  int64    `json:"saved_at"`
+
+	Pruned       []PrunedRange        `json:"pruned,omitempty"`
+	PrunedByType map[EventType]uint64 `json:"pruned_by_type,omitempty"`
 }
 
 // save persists the ledger to disk
@@ -376,6 +426,9 @@ func (l *Ledger) save() error {
 		LastSeq:  l.lastSeq,
 		LastHash: l.lastHash,
 		SavedAt:  time.Now().Unix(),
+
+		Pruned:       l.pruned,
+		PrunedByType: l.prunedByType,
 	}
 
 	bytes, err := json.MarshalIndent(data, "", "  ")
@@ -403,6 +456,11 @@ func (l *Ledger) load() error {
 	l.events = data.Events
 	l.lastSeq = data.LastSeq
 	l.lastHash = data.LastHash
+	l.pruned = data.Pruned
+	l.prunedByType = data.PrunedByType
+	if l.prunedByType == nil {
+		l.prunedByType = make(map[EventType]uint64)
+	}
 
 	// Rebuild indices
 	l.index = make(map[string][]*Event)
@@ -425,4 +483,6 @@ func (l *Ledger) Reset() {
 	l.typeIndex = make(map[EventType][]*Event)
 	l.lastSeq = 0
 	l.lastHash = ""
+	l.pruned = nil
+	l.prunedByType = make(map[EventType]uint64)
 }
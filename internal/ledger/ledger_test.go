@@ -1,9 +1,11 @@
 package ledger
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewEvent(t *testing.T) {
@@ -296,3 +298,157 @@ func TestLedgerReset(t *testing.T) {
 		t.Errorf("Expected last sequence 0 after reset")
 	}
 }
+
+func TestLedgerCompaction(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ledger_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ledger, err := NewLedger(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to create ledger: %v", err)
+	}
+
+	// seq 1-5: heartbeat, join, heartbeat, heartbeat, vote
+	ledger.AppendEvent(EventHeartbeat, "node1", map[string]int64{"uptime": 1}, "node1")
+	ledger.AppendEvent(EventNodeJoin, "node1", NodeJoinData{NodeID: "node1"}, "genesis")
+	ledger.AppendEvent(EventHeartbeat, "node1", map[string]int64{"uptime": 2}, "node1")
+	ledger.AppendEvent(EventHeartbeat, "node1", map[string]int64{"uptime": 3}, "node1")
+	ledger.AppendEvent(EventCommitteeVote, "node1", map[string]string{"vote": "agree"}, "node1")
+
+	// Nothing has expired yet
+	result, err := ledger.Compact()
+	if err != nil || len(result.Removed) != 0 || result.Remaining != 5 {
+		t.Fatalf("Expected no compaction, got %+v, %v", result, err)
+	}
+
+	// Eight days later, with seq 3 under legal hold
+	later := time.Now().Add(8 * 24 * time.Hour)
+	ledger.now = func() time.Time { return later }
+	ledger.SetHoldFunc(func(start, end uint64) bool { return start <= 3 && 3 <= end })
+
+	result, err = ledger.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if result.Removed[EventHeartbeat] != 2 || result.Held != 1 || result.Remaining != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if err := ledger.VerifyChain(); err != nil {
+		t.Errorf("Chain should verify across pruned ranges: %v", err)
+	}
+	if ledger.GetEvent(1) != nil || ledger.GetEvent(3) == nil || ledger.GetEvent(5) == nil {
+		t.Error("Expected seq 1 removed and seqs 3, 5 retained")
+	}
+
+	// Releasing the hold merges the adjacent runs
+	ledger.SetHoldFunc(nil)
+	if _, err := ledger.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	ranges := ledger.GetPrunedRanges()
+	if len(ranges) != 2 || ranges[0].ToSeq != 1 || ranges[1].FromSeq != 3 || ranges[1].ToSeq != 4 {
+		t.Errorf("Unexpected pruned ranges: %+v", ranges)
+	}
+	if events := ledger.GetEvents(1, 5); len(events) != 2 || events[0].Sequence != 2 || events[1].Sequence != 5 {
+		t.Errorf("Expected seqs 2 and 5, got %v", events)
+	}
+
+	// Appending continues the chain from the last sequence
+	event, _ := ledger.AppendEvent(EventHeartbeat, "node1", map[string]int64{"uptime": 4}, "node1")
+	if event.Sequence != 6 {
+		t.Errorf("Expected seq 6, got %d", event.Sequence)
+	}
+
+	for _, u := range ledger.StorageUsage() {
+		switch u.Type {
+		case EventHeartbeat:
+			if u.Events != 1 || u.Pruned != 3 || u.Bytes == 0 || u.Retention != "168h0m0s" {
+				t.Errorf("Unexpected heartbeat usage: %+v", u)
+			}
+		case EventCommitteeVote:
+			if u.Events != 1 || u.Pruned != 0 || u.Retention != "forever" {
+				t.Errorf("Unexpected vote usage: %+v", u)
+			}
+		}
+	}
+
+	// Pruned ranges survive a reload
+	reloaded, err := NewLedger(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load ledger: %v", err)
+	}
+	if err := reloaded.VerifyChain(); err != nil {
+		t.Errorf("Reloaded chain should verify: %v", err)
+	}
+	if reloaded.EventCount() != 3 || len(reloaded.GetPrunedRanges()) != 2 {
+		t.Errorf("Expected 3 events and 2 ranges after reload, got %d and %d",
+			reloaded.EventCount(), len(reloaded.GetPrunedRanges()))
+	}
+
+	// Tampering with a pruned range breaks verification
+	reloaded.pruned[1].LastHash = "forged"
+	if err := reloaded.VerifyChain(); err == nil {
+		t.Error("Expected verification to fail with a forged range")
+	}
+}
+
+func TestLedgerVerify(t *testing.T) {
+	ledger, _ := NewLedger("")
+	ledger.SetSignFunc(func(data []byte) (string, error) { return "sig:" + string(data), nil })
+	ledger.SetVerifyFunc(func(signerID string, data []byte, signature string) bool {
+		return signature == "sig:"+string(data)
+	})
+
+	ledger.AppendEvent(EventNodeJoin, "node1", NodeJoinData{NodeID: "node1", InitialReputation: 50}, "genesis")
+	ledger.AppendEvent(EventReputationChange, "node1", ReputationChangeData{NodeID: "node1", Delta: 10, NewValue: 60}, "self")
+	ledger.AppendEvent(EventReputationChange, "node2", ReputationChangeData{NodeID: "node2", Delta: -5, NewValue: 45}, "self")
+	ledger.AppendEvent(EventReputationChange, "node2", ReputationChangeData{NodeID: "node2", Delta: 5, NewValue: 50}, "self")
+
+	live := map[string]float64{"node1": 60, "node2": 50}
+	opts := VerifyOptions{
+		RequireSignatures: true,
+		Reputation: func(id string) (float64, bool) {
+			v, ok := live[id]
+			return v, ok
+		},
+	}
+	report := ledger.Verify(opts)
+	if !report.OK() || report.Events != 4 || report.Signed != 4 || report.NodesChecked != 2 || !report.SignaturesChecked {
+		t.Fatalf("Expected a clean report, got %+v", report)
+	}
+
+	// Live store drifted from the ledger
+	live["node1"] = 70
+	delete(live, "node2")
+	report = ledger.Verify(opts)
+	if len(report.Issues) != 2 || report.Issues[0].Kind != IssueState || report.Issues[0].NodeID != "node1" || report.Issues[1].NodeID != "node2" {
+		t.Errorf("Expected state divergence for node1 and node2, got %+v", report.Issues)
+	}
+	live["node1"], live["node2"] = 60, 50
+
+	// Tampered content, forged signature and a delta that does not add up are all reported
+	ledger.events[1].Data = []byte(`{"node_id":"node1","delta":10,"new_value":90}`)
+	ledger.events[2].Signature = "forged"
+	ledger.events[3].Data = []byte(`{"node_id":"node2","delta":1,"new_value":50}`)
+	ledger.events[3].Hash = ledger.events[3].ComputeHash()
+	report = ledger.Verify(opts)
+	found := map[string]bool{}
+	for _, issue := range report.Issues {
+		found[fmt.Sprintf("%s@%d", issue.Kind, issue.Sequence)] = true
+	}
+	// Re-hashing seq 4 also invalidates its signature and the recorded chain head
+	for _, want := range []string{"hash@2", "signature@3", "signature@4", "replay@4", "hash_link@0", "state@0"} {
+		if !found[want] {
+			t.Errorf("Expected issue %s, got %+v", want, report.Issues)
+		}
+	}
+
+	// Issues beyond MaxIssues are counted as truncated
+	opts.MaxIssues = 1
+	if report := ledger.Verify(opts); len(report.Issues) != 1 || !report.Truncated || report.OK() {
+		t.Errorf("Expected a truncated report, got %+v", report)
+	}
+}
@@ -167,7 +167,7 @@ func (sm *SnapshotManager) GetStateAt(ledger *Ledger, seq uint64) (*StateSnapsho
 	// Replay events from startSeq to seq
 	events := ledger.GetEvents(startSeq, seq)
 	for _, event := range events {
-		if err := sm.applyEvent(state, event); err != nil {
+		if err := applyEvent(state, event); err != nil {
 			return nil, fmt.Errorf("failed to apply event %d: %w", event.Sequence, err)
 		}
 		state.Sequence = event.Sequence
@@ -194,7 +194,7 @@ func (sm *SnapshotManager) buildStateFromEvents(ledger *Ledger) (*StateSnapshot,
 	events := ledger.GetEvents(1, lastSeq)
 
 	for _, event := range events {
-		if err := sm.applyEvent(state, event); err != nil {
+		if err := applyEvent(state, event); err != nil {
 			return nil, fmt.Errorf("failed to apply event %d: %w", event.Sequence, err)
 		}
 		state.Sequence = event.Sequence
@@ -207,8 +207,8 @@ func (sm *SnapshotManager) buildStateFromEvents(ledger *Ledger) (*StateSnapshot,
 	return state, nil
 }
 
-// applyEvent applies an event to the state
-func (sm *SnapshotManager) applyEvent(state *StateSnapshot, event *Event) error {
+// applyEvent applies an event to the state (shared by snapshots and Verify)
+func applyEvent(state *StateSnapshot, event *Event) error {
 	switch event.Type {
 	case EventNodeJoin:
 		var data NodeJoinData
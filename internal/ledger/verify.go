@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Issue kinds reported by Verify
+const (
+	IssueSequence  = "sequence"  // Sequence gap or reordering
+	IssueHashLink  = "hash_link" // PrevHash does not link to the previous event or pruned range
+	IssueHash      = "hash"      // Stored hash does not match the event content
+	IssueSignature = "signature" // Missing or invalid signature
+	IssueData      = "data"      // Event data cannot be replayed
+	IssueReplay    = "replay"    // Reputation delta does not lead to the recorded value
+	IssueState     = "state"     // Replayed state differs from the live store
+)
+
+// DefaultMaxIssues caps the issues collected by one Verify run
+const DefaultMaxIssues = 1000
+
+// DefaultTolerance is the allowed difference when comparing reputation values
+const DefaultTolerance = 1e-6
+
+// Issue is one problem found while verifying the ledger
+type Issue struct {
+	Kind      string    `json:"kind"`
+	Sequence  uint64    `json:"seq,omitempty"`
+	EventType EventType `json:"event_type,omitempty"`
+	NodeID    string    `json:"node_id,omitempty"`
+	Detail    string    `json:"detail"`
+}
+
+// VerifyOptions controls what Verify checks beyond the hash chain
+type VerifyOptions struct {
+	RequireSignatures bool // Report unsigned events as issues
+
+	// Reputation returns a node's reputation from the live store; nil skips the cross-check
+	Reputation func(nodeID string) (float64, bool)
+	Tolerance  float64 // Allowed reputation difference (0 = DefaultTolerance)
+	MaxIssues  int     // Issues kept in the report (0 = DefaultMaxIssues)
+}
+
+// VerifyReport is the result of replaying the ledger
+type VerifyReport struct {
+	Events            int     `json:"events"`
+	FirstSeq          uint64  `json:"first_seq"`
+	LastSeq           uint64  `json:"last_seq"`
+	PrunedRanges      int     `json:"pruned_ranges"`
+	Signed            int     `json:"signed"`
+	Unsigned          int     `json:"unsigned"`
+	SignaturesChecked bool    `json:"signatures_checked"` // False when no VerifyFunc is set
+	NodesChecked      int     `json:"nodes_checked"`      // Replayed reputations compared with the live store
+	Issues            []Issue `json:"issues"`
+	Truncated         bool    `json:"truncated,omitempty"` // More issues were found than MaxIssues
+}
+
+// OK reports whether verification found no issues
+func (r *VerifyReport) OK() bool {
+	return len(r.Issues) == 0 && !r.Truncated
+}
+
+func (r *VerifyReport) add(max int, issue Issue) {
+	if len(r.Issues) >= max {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// Verify replays the whole ledger and reports every problem instead of stopping at the first one.
+// It re-checks sequence numbers, hash links (across pruned ranges), event hashes and signatures,
+// rebuilds node state from the events and compares the replayed reputation with opts.Reputation.
+// Reputation changes for nodes without a NODE_JOIN event are replayed from their absolute values.
+func (l *Ledger) Verify(opts VerifyOptions) *VerifyReport {
+	l.mu.RLock()
+	events := make([]*Event, len(l.events))
+	copy(events, l.events)
+	pruned := make([]PrunedRange, len(l.pruned))
+	copy(pruned, l.pruned)
+	lastSeq, lastHash := l.lastSeq, l.lastHash
+	verify := l.verifyFunc
+	l.mu.RUnlock()
+
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DefaultTolerance
+	}
+	if opts.MaxIssues <= 0 {
+		opts.MaxIssues = DefaultMaxIssues
+	}
+	report := &VerifyReport{
+		Events:            len(events),
+		LastSeq:           lastSeq,
+		PrunedRanges:      len(pruned),
+		SignaturesChecked: verify != nil,
+		Issues:            []Issue{},
+	}
+	if len(events) > 0 {
+		report.FirstSeq = events[0].Sequence
+	}
+	add := func(issue Issue) { report.add(opts.MaxIssues, issue) }
+
+	state := &StateSnapshot{
+		Nodes:      make(map[string]*NodeState),
+		Guarantees: make(map[string]*GuaranteeState),
+	}
+	// Nodes whose previous value was replayed since the last pruned range,
+	// only these can be checked for delta continuity
+	continuous := make(map[string]bool)
+
+	prevHash := ""
+	expectedSeq := uint64(1)
+	ri := 0
+	skipPruned := func(before uint64) {
+		for ri < len(pruned) && pruned[ri].FromSeq < before {
+			r := pruned[ri]
+			if r.FromSeq != expectedSeq {
+				add(Issue{Kind: IssueSequence, Sequence: r.FromSeq,
+					Detail: fmt.Sprintf("pruned range %d-%d starts after seq %d", r.FromSeq, r.ToSeq, expectedSeq-1)})
+			}
+			if r.PrevHash != prevHash {
+				add(Issue{Kind: IssueHashLink, Sequence: r.FromSeq,
+					Detail: fmt.Sprintf("pruned range %d-%d does not link to seq %d", r.FromSeq, r.ToSeq, expectedSeq-1)})
+			}
+			prevHash = r.LastHash
+			expectedSeq = r.ToSeq + 1
+			continuous = make(map[string]bool)
+			ri++
+		}
+	}
+
+	for _, event := range events {
+		skipPruned(event.Sequence)
+		at := Issue{Sequence: event.Sequence, EventType: event.Type, NodeID: event.NodeID}
+
+		if event.Sequence != expectedSeq {
+			at.Kind, at.Detail = IssueSequence, fmt.Sprintf("expected seq %d, got %d", expectedSeq, event.Sequence)
+			add(at)
+		}
+		if event.PrevHash != prevHash {
+			at.Kind, at.Detail = IssueHashLink, "prev hash does not match the preceding event"
+			add(at)
+		}
+		if event.ComputeHash() != event.Hash {
+			at.Kind, at.Detail = IssueHash, "hash does not match the event content"
+			add(at)
+		}
+		switch {
+		case event.Signature == "":
+			report.Unsigned++
+			if opts.RequireSignatures {
+				at.Kind, at.Detail = IssueSignature, "event is not signed"
+				add(at)
+			}
+		default:
+			report.Signed++
+			if verify != nil && !verify(event.SignerID, []byte(event.Hash), event.Signature) {
+				at.Kind, at.Detail = IssueSignature, fmt.Sprintf("invalid signature by %s", event.SignerID)
+				add(at)
+			}
+		}
+
+		if issue := replayEvent(state, continuous, event, opts.Tolerance); issue != nil {
+			add(*issue)
+		}
+
+		// Continue from the stored values so one bad event is reported once
+		prevHash = event.Hash
+		expectedSeq = event.Sequence + 1
+	}
+	skipPruned(lastSeq + 1)
+	if expectedSeq != lastSeq+1 {
+		add(Issue{Kind: IssueSequence, Detail: fmt.Sprintf("chain ends at seq %d, ledger head is %d", expectedSeq-1, lastSeq)})
+	}
+	if prevHash != lastHash {
+		add(Issue{Kind: IssueHashLink, Detail: "last hash does not match the chain head"})
+	}
+
+	if opts.Reputation != nil {
+		ids := make([]string, 0, len(state.Nodes))
+		for id := range state.Nodes {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			report.NodesChecked++
+			replayed := state.Nodes[id].Reputation
+			live, ok := opts.Reputation(id)
+			switch {
+			case !ok:
+				add(Issue{Kind: IssueState, NodeID: id, Detail: fmt.Sprintf("ledger replays reputation %.4f, node missing from the live store", replayed)})
+			case math.Abs(live-replayed) > opts.Tolerance:
+				add(Issue{Kind: IssueState, NodeID: id, Detail: fmt.Sprintf("ledger replays reputation %.4f, live store has %.4f", replayed, live)})
+			}
+		}
+	}
+	return report
+}
+
+// replayEvent applies one event to the replayed state and checks reputation deltas against it
+func replayEvent(state *StateSnapshot, continuous map[string]bool, event *Event, tolerance float64) *Issue {
+	issue := func(kind, detail string) *Issue {
+		return &Issue{Kind: kind, Sequence: event.Sequence, EventType: event.Type, NodeID: event.NodeID, Detail: detail}
+	}
+
+	switch event.Type {
+	case EventReputationChange:
+		var data ReputationChangeData
+		if err := event.GetData(&data); err != nil || data.NodeID == "" {
+			return issue(IssueData, "reputation change without a node")
+		}
+		node, ok := state.Nodes[data.NodeID]
+		if !ok {
+			node = &NodeState{NodeID: data.NodeID, Status: "active", Guarantees: []string{}}
+			state.Nodes[data.NodeID] = node
+		}
+		var mismatch *Issue
+		if continuous[data.NodeID] && math.Abs(node.Reputation+data.Delta-data.NewValue) > tolerance {
+			mismatch = issue(IssueReplay, fmt.Sprintf("%.4f %+.4f does not give recorded %.4f", node.Reputation, data.Delta, data.NewValue))
+		}
+		applyEvent(state, event)
+		continuous[data.NodeID] = true
+		return mismatch
+
+	case EventNodeJoin:
+		var data NodeJoinData
+		if err := event.GetData(&data); err == nil {
+			continuous[data.NodeID] = true
+		}
+	}
+
+	if err := applyEvent(state, event); err != nil {
+		return issue(IssueData, err.Error())
+	}
+	return nil
+}
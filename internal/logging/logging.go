@@ -123,6 +123,10 @@ type LogConfig struct {
 	MinLevel         LogLevel      // 最低日志级别
 	EnableConsole    bool          // 是否输出到控制台
 	
+	// 只读副本：查询走副本，避免大查询长时间持有写入端的锁
+	ReadReplicas        int           // 副本数（0 表示查询直接读写入端）
+	ReplicaSyncInterval time.Duration // 副本后台追尾间隔
+	
 	// 签名函数
 	SignFunc   func(data []byte) (string, error)
 	VerifyFunc func(publicKey string, data []byte, signature string) bool
@@ -139,6 +143,8 @@ func DefaultLogConfig(nodeID string) *LogConfig {
 		CompressOldLogs: true,
 		MinLevel:        LevelInfo,
 		EnableConsole:   false,
+		ReadReplicas:        0,
+		ReplicaSyncInterval: time.Second,
 	}
 }
 
@@ -153,6 +159,10 @@ type Logger struct {
 	running    bool
 	stopCh     chan struct{}
 	
+	generation  uint64     // Clear/LoadFromFile 重排日志时递增，副本据此重新快照
+	replicas    []*Replica // 只读副本
+	nextReplica uint32     // 轮询选择副本
+	
 	// 回调
 	OnLog func(*LogEntry)
 }
@@ -185,6 +195,10 @@ func NewLogger(config *LogConfig) (*Logger, error) {
 		return nil, err
 	}
 	
+	for i := 0; i < config.ReadReplicas; i++ {
+		l.replicas = append(l.replicas, newReplica(i, l))
+	}
+	
 	return l, nil
 }
 
@@ -227,8 +241,20 @@ func (l *Logger) mainLoop() {
 	defer rotateTicker.Stop()
 	defer cleanupTicker.Stop()
 	
+	// 副本后台追尾，使查询时需要补齐的尾部保持很短
+	var replicaTick <-chan time.Time
+	if len(l.replicas) > 0 && l.config.ReplicaSyncInterval > 0 {
+		replicaTicker := time.NewTicker(l.config.ReplicaSyncInterval)
+		defer replicaTicker.Stop()
+		replicaTick = replicaTicker.C
+	}
+	
 	for {
 		select {
+		case <-replicaTick:
+			for _, r := range l.replicas {
+				r.follow()
+			}
 		case <-rotateTicker.C:
 			l.rotateIfNeeded()
 		case <-cleanupTicker.C:
@@ -558,14 +584,22 @@ type QueryFilter struct {
 	Limit     int
 }
 
-// Query 查询日志
+// Query 查询日志（配置了副本时由副本执行）
 func (l *Logger) Query(filter *QueryFilter) []*LogEntry {
+	if r := l.readReplica(); r != nil {
+		return r.Query(filter)
+	}
+	
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	
+	return filterEntries(l.entries, filter)
+}
+
+// filterEntries 按过滤器筛选日志
+func filterEntries(entries []*LogEntry, filter *QueryFilter) []*LogEntry {
 	result := make([]*LogEntry, 0)
 	
-	for _, entry := range l.entries {
+	for _, entry := range entries {
 		// 过滤节点ID
 		if filter.NodeID != "" && entry.NodeID != filter.NodeID {
 			continue
@@ -602,16 +636,24 @@ func (l *Logger) Query(filter *QueryFilter) []*LogEntry {
 
 // GetRecentLogs 获取最近的日志
 func (l *Logger) GetRecentLogs(count int) []*LogEntry {
+	if r := l.readReplica(); r != nil {
+		return r.GetRecentLogs(count)
+	}
+	
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	
-	total := len(l.entries)
+	return recentEntries(l.entries, count)
+}
+
+// recentEntries 取最后 count 条日志，最新的在前
+func recentEntries(entries []*LogEntry, count int) []*LogEntry {
+	total := len(entries)
 	if count > total {
 		count = total
 	}
 	
 	result := make([]*LogEntry, count)
-	copy(result, l.entries[total-count:])
+	copy(result, entries[total-count:])
 	
 	// 逆序（最新的在前）
 	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
@@ -623,16 +665,7 @@ func (l *Logger) GetRecentLogs(count int) []*LogEntry {
 
 // GetLogsByEventType 按事件类型获取日志
 func (l *Logger) GetLogsByEventType(eventType EventType) []*LogEntry {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	
-	result := make([]*LogEntry, 0)
-	for _, entry := range l.entries {
-		if entry.EventType == eventType {
-			result = append(result, entry)
-		}
-	}
-	return result
+	return l.Query(&QueryFilter{EventType: eventType, Level: LevelDebug})
 }
 
 // LogStats 日志统计
@@ -644,30 +677,20 @@ type LogStats struct {
 	NewestEntry    time.Time          `json:"newest_entry"`
 	FileCount      int                `json:"file_count"`
 	TotalFileSize  int64              `json:"total_file_size"`
+	Replicas       []*ReplicaStats    `json:"replicas,omitempty"`
 }
 
 // GetStats 获取统计信息
 func (l *Logger) GetStats() *LogStats {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	
-	stats := &LogStats{
-		TotalEntries:   len(l.entries),
-		EntriesByType:  make(map[EventType]int),
-		EntriesByLevel: make(map[LogLevel]int),
-	}
-	
-	for _, entry := range l.entries {
-		stats.EntriesByType[entry.EventType]++
-		stats.EntriesByLevel[entry.Level]++
-		
-		if stats.OldestEntry.IsZero() || entry.Timestamp.Before(stats.OldestEntry) {
-			stats.OldestEntry = entry.Timestamp
-		}
-		if entry.Timestamp.After(stats.NewestEntry) {
-			stats.NewestEntry = entry.Timestamp
-		}
+	var stats *LogStats
+	if r := l.readReplica(); r != nil {
+		stats = r.entryStats()
+	} else {
+		l.mu.RLock()
+		stats = entryStats(l.entries)
+		l.mu.RUnlock()
 	}
+	stats.Replicas = l.ReplicaStats()
 	
 	// 统计文件
 	if l.config.DataDir != "" {
@@ -689,6 +712,29 @@ func (l *Logger) GetStats() *LogStats {
 	return stats
 }
 
+// entryStats 统计日志条目的类型、级别和时间范围
+func entryStats(entries []*LogEntry) *LogStats {
+	stats := &LogStats{
+		TotalEntries:   len(entries),
+		EntriesByType:  make(map[EventType]int),
+		EntriesByLevel: make(map[LogLevel]int),
+	}
+	
+	for _, entry := range entries {
+		stats.EntriesByType[entry.EventType]++
+		stats.EntriesByLevel[entry.Level]++
+		
+		if stats.OldestEntry.IsZero() || entry.Timestamp.Before(stats.OldestEntry) {
+			stats.OldestEntry = entry.Timestamp
+		}
+		if entry.Timestamp.After(stats.NewestEntry) {
+			stats.NewestEntry = entry.Timestamp
+		}
+	}
+	
+	return stats
+}
+
 // Export 导出日志
 func (l *Logger) Export(writer io.Writer, format string, filter *QueryFilter) error {
 	entries := l.Query(filter)
@@ -780,6 +826,7 @@ func (l *Logger) LoadFromFile(filename string) error {
 	sort.Slice(l.entries, func(i, j int) bool {
 		return l.entries[i].Timestamp.Before(l.entries[j].Timestamp)
 	})
+	l.generation++
 	
 	return nil
 }
@@ -804,6 +851,7 @@ func (l *Logger) Clear() {
 	
 	l.entries = make([]*LogEntry, 0)
 	l.entryIndex = make(map[string]*LogEntry)
+	l.generation++
 }
 
 // SetMinLevel 设置最低日志级别
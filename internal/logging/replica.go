@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Replica 日志存储的只读副本
+//
+// 副本创建时对写入端做一次快照，之后只复制新追加的尾部（日志只追加，
+// 按位置追尾即可）；写入端执行 Clear/LoadFromFile 重排日志后副本重新快照。
+// 复制只在写入端读锁下拷贝指针，过滤、统计等耗时操作都在副本自己的锁下进行，
+// 不会阻塞 Log 的写入。查询前副本会先补齐尾部，因此写入后立即查询也能读到。
+type Replica struct {
+	id     int
+	source *Logger
+
+	syncMu sync.Mutex // 串行化后台追尾与查询前补齐
+
+	mu         sync.RWMutex
+	entries    []*LogEntry
+	generation uint64
+	applied    int // 已复制的写入端条目数
+	snapshots  int
+	lastSync   time.Time
+	maxLag     int // 后台追尾时观察到的最大落后条数
+
+	queries int64
+}
+
+// ReplicaStats 副本复制状态
+type ReplicaStats struct {
+	ID             int       `json:"id"`
+	AppliedEntries int       `json:"applied_entries"`
+	LagEntries     int       `json:"lag_entries"`     // 尚未复制的条目数
+	LagSeconds     float64   `json:"lag_seconds"`     // 最早未复制条目距今的秒数
+	MaxLagEntries  int       `json:"max_lag_entries"` // 后台追尾时观察到的最大落后条数
+	Snapshots      int       `json:"snapshots"`       // 写入端重排后的重新快照次数
+	Queries        int64     `json:"queries"`
+	LastSyncAt     time.Time `json:"last_sync_at"`
+}
+
+func newReplica(id int, source *Logger) *Replica {
+	r := &Replica{id: id, source: source}
+	r.sync()
+	return r
+}
+
+// readReplica 轮询选择一个副本，未配置副本时返回 nil
+func (l *Logger) readReplica() *Replica {
+	if len(l.replicas) == 0 {
+		return nil
+	}
+	n := atomic.AddUint32(&l.nextReplica, 1)
+	return l.replicas[int(n)%len(l.replicas)]
+}
+
+// Replicas 返回全部只读副本
+func (l *Logger) Replicas() []*Replica {
+	return l.replicas
+}
+
+// ReplicaStats 返回各副本的复制延迟
+func (l *Logger) ReplicaStats() []*ReplicaStats {
+	if len(l.replicas) == 0 {
+		return nil
+	}
+	result := make([]*ReplicaStats, len(l.replicas))
+	for i, r := range l.replicas {
+		result[i] = r.Stats()
+	}
+	return result
+}
+
+// follow 后台追尾，同时记录补齐前的落后条数
+func (r *Replica) follow() {
+	if lag := r.Stats().LagEntries; lag > 0 {
+		r.mu.Lock()
+		if lag > r.maxLag {
+			r.maxLag = lag
+		}
+		r.mu.Unlock()
+	}
+	r.sync()
+}
+
+// sync 从写入端复制新条目，写入端重排过则重新快照
+func (r *Replica) sync() {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	r.mu.RLock()
+	applied, generation := r.applied, r.generation
+	r.mu.RUnlock()
+
+	src := r.source
+	src.mu.RLock()
+	snapshot := src.generation != generation || len(src.entries) < applied
+	var tail []*LogEntry
+	if snapshot {
+		tail = append([]*LogEntry(nil), src.entries...)
+	} else if len(src.entries) > applied {
+		tail = append([]*LogEntry(nil), src.entries[applied:]...)
+	}
+	total, srcGeneration := len(src.entries), src.generation
+	src.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if snapshot {
+		r.entries = tail
+		r.snapshots++
+	} else {
+		r.entries = append(r.entries, tail...)
+	}
+	r.applied = total
+	r.generation = srcGeneration
+	r.lastSync = time.Now()
+}
+
+// Stats 返回副本复制状态
+func (r *Replica) Stats() *ReplicaStats {
+	r.mu.RLock()
+	stats := &ReplicaStats{
+		ID:             r.id,
+		AppliedEntries: r.applied,
+		MaxLagEntries:  r.maxLag,
+		Snapshots:      r.snapshots,
+		Queries:        atomic.LoadInt64(&r.queries),
+		LastSyncAt:     r.lastSync,
+	}
+	applied, generation := r.applied, r.generation
+	r.mu.RUnlock()
+
+	src := r.source
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	if src.generation != generation || len(src.entries) < applied {
+		stats.LagEntries = len(src.entries)
+		applied = 0
+	} else {
+		stats.LagEntries = len(src.entries) - applied
+	}
+	if stats.LagEntries > 0 {
+		stats.LagSeconds = time.Since(src.entries[applied].Timestamp).Seconds()
+	}
+	return stats
+}
+
+// Query 在副本上查询日志
+func (r *Replica) Query(filter *QueryFilter) []*LogEntry {
+	r.beginQuery()
+	defer r.mu.RUnlock()
+	return filterEntries(r.entries, filter)
+}
+
+// GetRecentLogs 在副本上获取最近的日志
+func (r *Replica) GetRecentLogs(count int) []*LogEntry {
+	r.beginQuery()
+	defer r.mu.RUnlock()
+	return recentEntries(r.entries, count)
+}
+
+func (r *Replica) entryStats() *LogStats {
+	r.beginQuery()
+	defer r.mu.RUnlock()
+	return entryStats(r.entries)
+}
+
+// beginQuery 补齐尾部后持有副本读锁，由调用方释放
+func (r *Replica) beginQuery() {
+	atomic.AddInt64(&r.queries, 1)
+	r.sync()
+	r.mu.RLock()
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplicaFollow(t *testing.T) {
+	config := DefaultLogConfig("node1")
+	config.DataDir = tempDir(t)
+	config.ReadReplicas = 2
+
+	l, _ := NewLogger(config)
+	defer l.Stop()
+
+	if len(l.Replicas()) != 2 {
+		t.Fatalf("expected 2 replicas, got %d", len(l.Replicas()))
+	}
+	for i := 0; i < 5; i++ {
+		l.Info(EventTaskCreate, nil)
+	}
+
+	r := l.Replicas()[0]
+	stats := r.Stats()
+	if stats.LagEntries != 5 || stats.LagSeconds <= 0 {
+		t.Errorf("expected lag of 5 entries, got %+v", stats)
+	}
+
+	// 查询前补齐尾部，写入后立即可见
+	if got := len(r.Query(&QueryFilter{})); got != 5 {
+		t.Errorf("expected 5 entries from replica, got %d", got)
+	}
+	if stats := r.Stats(); stats.LagEntries != 0 || stats.Queries != 1 {
+		t.Errorf("expected caught-up replica with 1 query, got %+v", stats)
+	}
+
+	t.Run("follow records max lag", func(t *testing.T) {
+		r2 := l.Replicas()[1]
+		l.Info(EventTaskAccept, nil)
+		r2.follow()
+		if stats := r2.Stats(); stats.MaxLagEntries != 6 || stats.LagEntries != 0 {
+			t.Errorf("expected max lag 6 and no current lag, got %+v", stats)
+		}
+	})
+
+	t.Run("resnapshot after clear", func(t *testing.T) {
+		l.Clear()
+		l.Info(EventTaskComplete, nil)
+		if got := r.Query(&QueryFilter{}); len(got) != 1 || got[0].EventType != EventTaskComplete {
+			t.Errorf("expected replica to resnapshot, got %d entries", len(got))
+		}
+		if stats := r.Stats(); stats.Snapshots != 1 {
+			t.Errorf("expected 1 resnapshot, got %d", stats.Snapshots)
+		}
+	})
+}
+
+func TestReplicaDoesNotBlockWriter(t *testing.T) {
+	config := DefaultLogConfig("node1")
+	config.DataDir = tempDir(t)
+	config.ReadReplicas = 1
+
+	l, _ := NewLogger(config)
+	defer l.Stop()
+
+	// 模拟副本上正在进行的长时间查询
+	r := l.Replicas()[0]
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.Info(EventTaskCreate, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writer blocked by replica query")
+	}
+}
+
+func TestGetStatsReportsReplicas(t *testing.T) {
+	config := DefaultLogConfig("node1")
+	config.DataDir = tempDir(t)
+	config.ReadReplicas = 1
+
+	l, _ := NewLogger(config)
+	defer l.Stop()
+	l.Info(EventTaskCreate, nil)
+
+	stats := l.GetStats()
+	if stats.TotalEntries != 1 || len(stats.Replicas) != 1 {
+		t.Errorf("expected 1 entry and 1 replica, got %d, %d", stats.TotalEntries, len(stats.Replicas))
+	}
+
+	// 默认不创建副本，查询直接读写入端
+	config = DefaultLogConfig("node2")
+	config.DataDir = tempDir(t)
+	direct, _ := NewLogger(config)
+	defer direct.Stop()
+	direct.Info(EventTaskCreate, nil)
+	if got := direct.Query(&QueryFilter{}); len(got) != 1 || direct.ReplicaStats() != nil {
+		t.Errorf("expected direct query without replicas, got %d entries", len(got))
+	}
+}
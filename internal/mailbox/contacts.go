@@ -0,0 +1,282 @@
+package mailbox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 通讯录
+//
+// 联系人保存标签、备注、信任等级和加密偏好，与邮件一起持久化（同样静态加密）。
+// 发信前用 CheckRecipient 检查收件人：联系人信任等级低、声誉低于 LowReputation
+// 或处于隔离期（如超出配额后被封禁）时返回警告，由调用方决定是否继续发送。
+
+var (
+	// ErrContactNotFound 通讯录中没有该节点
+	ErrContactNotFound = errors.New("contact not found")
+	// ErrInvalidContact 联系人字段不合法
+	ErrInvalidContact = errors.New("invalid contact")
+)
+
+// 联系人字段长度上限
+const (
+	maxContactLabel = 64
+	maxContactNotes = 2000
+)
+
+// TrustLevel 联系人信任等级
+type TrustLevel string
+
+const (
+	TrustTrusted TrustLevel = "trusted" // 信任
+	TrustNormal  TrustLevel = "normal"  // 一般（默认）
+	TrustLow     TrustLevel = "low"     // 低信任，发信时警告
+)
+
+// EncryptionPreference 发给联系人时的加密偏好
+type EncryptionPreference string
+
+const (
+	EncryptionDefault EncryptionPreference = ""       // 按发信请求
+	EncryptionAlways  EncryptionPreference = "always" // 总是端到端加密
+	EncryptionNever   EncryptionPreference = "never"  // 总是明文（如对方不支持加密）
+)
+
+// Contact 通讯录联系人
+type Contact struct {
+	NodeID     string               `json:"node_id"`
+	Label      string               `json:"label,omitempty"`
+	Notes      string               `json:"notes,omitempty"`
+	Trust      TrustLevel           `json:"trust"`
+	Encryption EncryptionPreference `json:"encryption,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+}
+
+// Encrypt 按联系人的加密偏好决定是否加密，c 为 nil 时按请求
+func (c *Contact) Encrypt(requested bool) bool {
+	if c == nil {
+		return requested
+	}
+	switch c.Encryption {
+	case EncryptionAlways:
+		return true
+	case EncryptionNever:
+		return false
+	}
+	return requested
+}
+
+// 发信警告
+const (
+	WarnLowTrust      = "low_trust"      // 联系人信任等级为 low
+	WarnLowReputation = "low_reputation" // 收件人声誉低于 LowReputation
+	WarnQuarantined   = "quarantined"    // 收件人处于隔离期
+)
+
+// RecipientWarning 发信警告
+type RecipientWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// RecipientCheck 发信前的收件人检查结果
+type RecipientCheck struct {
+	NodeID      string             `json:"node_id"`
+	Contact     *Contact           `json:"contact,omitempty"`
+	Reputation  *float64           `json:"reputation,omitempty"` // 未设置声誉查询时为空
+	Quarantined bool               `json:"quarantined"`
+	Warnings    []RecipientWarning `json:"warnings"`
+}
+
+func validateContact(c *Contact) error {
+	c.NodeID = strings.TrimSpace(c.NodeID)
+	c.Label = strings.TrimSpace(c.Label)
+	if c.NodeID == "" {
+		return fmt.Errorf("%w: node_id is required", ErrInvalidContact)
+	}
+	if utf8.RuneCountInString(c.Label) > maxContactLabel {
+		return fmt.Errorf("%w: label longer than %d characters", ErrInvalidContact, maxContactLabel)
+	}
+	if utf8.RuneCountInString(c.Notes) > maxContactNotes {
+		return fmt.Errorf("%w: notes longer than %d characters", ErrInvalidContact, maxContactNotes)
+	}
+	switch c.Trust {
+	case "":
+		c.Trust = TrustNormal
+	case TrustTrusted, TrustNormal, TrustLow:
+	default:
+		return fmt.Errorf("%w: trust must be trusted, normal or low", ErrInvalidContact)
+	}
+	switch c.Encryption {
+	case EncryptionDefault, EncryptionAlways, EncryptionNever:
+	default:
+		return fmt.Errorf("%w: encryption must be always, never or empty", ErrInvalidContact)
+	}
+	return nil
+}
+
+// SaveContact 添加或更新联系人（按节点 ID），保存后立即持久化
+func (m *Mailbox) SaveContact(c Contact) (*Contact, error) {
+	if err := validateContact(&c); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	c.CreatedAt, c.UpdatedAt = now, now
+	if old, ok := m.contacts[c.NodeID]; ok {
+		c.CreatedAt = old.CreatedAt
+	}
+	m.contacts[c.NodeID] = &c
+	m.dirty.Mark("contacts", c.NodeID)
+	saved := c
+	m.mu.Unlock()
+
+	if err := m.saveToDisk(); err != nil {
+		return &saved, err
+	}
+	return &saved, nil
+}
+
+// GetContact 获取联系人
+func (m *Mailbox) GetContact(nodeID string) (*Contact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.contacts[nodeID]
+	if !ok {
+		return nil, ErrContactNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// DeleteContact 删除联系人
+func (m *Mailbox) DeleteContact(nodeID string) error {
+	m.mu.Lock()
+	if _, ok := m.contacts[nodeID]; !ok {
+		m.mu.Unlock()
+		return ErrContactNotFound
+	}
+	delete(m.contacts, nodeID)
+	m.dirty.Mark("contacts", nodeID)
+	m.mu.Unlock()
+	return m.saveToDisk()
+}
+
+// ListContacts 列出联系人，按标签（无标签时按节点 ID）排序
+func (m *Mailbox) ListContacts() []*Contact {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Contact, 0, len(m.contacts))
+	for _, c := range m.contacts {
+		copied := *c
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := contactSortKey(result[i]), contactSortKey(result[j])
+		if a != b {
+			return a < b
+		}
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
+
+func contactSortKey(c *Contact) string {
+	if c.Label != "" {
+		return strings.ToLower(c.Label)
+	}
+	return strings.ToLower(c.NodeID)
+}
+
+// SearchContacts 写信时的收件人补全：标签或节点 ID 以 query 开头的排在前面，
+// 其次是标签、节点 ID 或备注中包含 query 的；query 为空时返回全部（limit <= 0 不限）
+func (m *Mailbox) SearchContacts(query string, limit int) []*Contact {
+	all := m.ListContacts()
+	q := strings.ToLower(strings.TrimSpace(query))
+	var result []*Contact
+	if q == "" {
+		result = all
+	} else {
+		var prefix, contains []*Contact
+		for _, c := range all {
+			label, id := strings.ToLower(c.Label), strings.ToLower(c.NodeID)
+			switch {
+			case strings.HasPrefix(label, q) || strings.HasPrefix(id, q):
+				prefix = append(prefix, c)
+			case strings.Contains(label, q) || strings.Contains(id, q) || strings.Contains(strings.ToLower(c.Notes), q):
+				contains = append(contains, c)
+			}
+		}
+		result = append(prefix, contains...)
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	if result == nil {
+		result = []*Contact{}
+	}
+	return result
+}
+
+// SetReputationFunc 设置发信检查使用的声誉查询
+func (m *Mailbox) SetReputationFunc(fn func(nodeID string) float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reputationOf = fn
+}
+
+// SetQuarantineFunc 设置发信检查使用的隔离状态查询
+func (m *Mailbox) SetQuarantineFunc(fn func(nodeID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quarantined = fn
+}
+
+// CheckRecipient 发信前检查收件人，Warnings 为空表示无需提醒
+func (m *Mailbox) CheckRecipient(nodeID string) *RecipientCheck {
+	m.mu.RLock()
+	var contact *Contact
+	if c, ok := m.contacts[nodeID]; ok {
+		copied := *c
+		contact = &copied
+	}
+	reputationOf, quarantined := m.reputationOf, m.quarantined
+	m.mu.RUnlock()
+
+	check := &RecipientCheck{NodeID: nodeID, Contact: contact, Warnings: []RecipientWarning{}}
+	name := nodeID
+	if contact != nil && contact.Label != "" {
+		name = contact.Label
+	}
+	if contact != nil && contact.Trust == TrustLow {
+		check.Warnings = append(check.Warnings, RecipientWarning{
+			Code:    WarnLowTrust,
+			Message: fmt.Sprintf("%s is marked as a low-trust contact", name),
+		})
+	}
+	if reputationOf != nil {
+		rep := reputationOf(nodeID)
+		check.Reputation = &rep
+		if rep < m.config.LowReputation {
+			check.Warnings = append(check.Warnings, RecipientWarning{
+				Code:    WarnLowReputation,
+				Message: fmt.Sprintf("%s has reputation %.1f (below %.1f)", name, rep, m.config.LowReputation),
+			})
+		}
+	}
+	if quarantined != nil && quarantined(nodeID) {
+		check.Quarantined = true
+		check.Warnings = append(check.Warnings, RecipientWarning{
+			Code:    WarnQuarantined,
+			Message: fmt.Sprintf("%s is quarantined; the message may be dropped", name),
+		})
+	}
+	return check
+}
@@ -0,0 +1,282 @@
+package mailbox
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+)
+
+// 端到端加密
+//
+// 加密邮件的内容用收件人的节点身份公钥加密（Ed25519 转换为 X25519 做 ECIES），
+// 发件人之外只有收件人能解密，经中继节点转发或暂存在中继节点时也只是密文。
+// 收件人公钥优先从节点ID中直接取得（Ed25519 节点ID内嵌公钥）；取不到时通过
+// KeyExchangeProtocol 与收件人握手：双方交换带签名的密钥通告，校验后缓存。
+//
+// v2 通告带签发时间并纳入签名，接收方拒绝与本地时间相差超过 MaxAnnouncementAge 的
+// 通告，截获的旧通告不能被重放。v1 通告没有签发时间，在弃用窗口内通过
+// KeyExchangeProtocolV1 以兼容模式继续交换，与未升级的节点互通。
+
+// 密钥交换协议ID（使用时按网络命名空间加前缀）
+const (
+	KeyExchangeProtocol   = "/daan/mailbox/key/2.0.0"
+	KeyExchangeProtocolV1 = "/daan/mailbox/key/1.0.0"
+)
+
+// MaxAnnouncementAge v2 通告的签发时间与本地时间的最大偏差
+const MaxAnnouncementAge = 10 * time.Minute
+
+// maxAnnouncementSize 密钥通告的最大长度
+const maxAnnouncementSize = 4096
+
+const (
+	keyAnnouncementLabel   = "daan-mailbox-key-v1"
+	keyAnnouncementLabelV2 = "daan-mailbox-key-v2"
+)
+
+var (
+	ErrNoRecipientKey        = errors.New("recipient encryption key unknown")
+	ErrInvalidAnnouncement   = errors.New("invalid key announcement")
+	ErrEncryptedForRecipient = errors.New("message is encrypted for the recipient")
+)
+
+// KeyAnnouncement 密钥通告：节点用身份私钥签名自己的公钥，证明持有对应私钥
+type KeyAnnouncement struct {
+	NodeID    string `json:"node_id"`
+	PublicKey []byte `json:"public_key"` // Ed25519 身份公钥
+	Signature []byte `json:"signature"`
+	Version   int    `json:"version,omitempty"`   // 2 为 v2，v1 通告没有该字段
+	IssuedAt  int64  `json:"issued_at,omitempty"` // v2 签发时间（Unix 秒）
+}
+
+// version 通告的协议版本
+func (a *KeyAnnouncement) version() int {
+	if a.Version == 0 {
+		return 1
+	}
+	return a.Version
+}
+
+func (a *KeyAnnouncement) signData() []byte {
+	var b bytes.Buffer
+	if a.version() >= 2 {
+		b.WriteString(keyAnnouncementLabelV2)
+		b.WriteByte('|')
+		b.WriteString(strconv.FormatInt(a.IssuedAt, 10))
+	} else {
+		b.WriteString(keyAnnouncementLabel)
+	}
+	b.WriteByte('|')
+	b.WriteString(a.NodeID)
+	b.WriteByte('|')
+	b.Write(a.PublicKey)
+	return b.Bytes()
+}
+
+// E2EConfig 端到端加密配置
+type E2EConfig struct {
+	NodeID     string
+	PrivateKey ed25519.PrivateKey // 节点身份私钥
+
+	// ResolveKey 从节点ID直接取得身份公钥，取不到时返回错误；nil 表示只使用握手得到的公钥
+	ResolveKey func(nodeID string) (ed25519.PublicKey, error)
+	// VerifyNodeID 校验公钥与节点ID对应，防止用他人的节点ID通告密钥；nil 表示不接受任何通告
+	VerifyNodeID func(nodeID string, pub ed25519.PublicKey) bool
+	// Handshake 与节点进行密钥交换，返回对方的密钥通告；nil 表示不握手
+	Handshake func(nodeID string) (*KeyAnnouncement, error)
+}
+
+// E2E 邮件端到端加密，Encrypt/Decrypt 分别作为邮箱的 EncryptFunc/DecryptFunc
+type E2E struct {
+	config *E2EConfig
+	legacy *KeyAnnouncement // v1 通告，内容固定
+	now    func() time.Time
+
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey // 握手得到的公钥: nodeID -> 公钥
+}
+
+// NewE2E 创建端到端加密
+func NewE2E(config *E2EConfig) (*E2E, error) {
+	if config == nil {
+		return nil, errors.New("config is required")
+	}
+	if config.NodeID == "" {
+		return nil, errors.New("node ID is required")
+	}
+	if len(config.PrivateKey) != ed25519.PrivateKeySize {
+		return nil, crypto.ErrInvalidPrivateKey
+	}
+
+	legacy := &KeyAnnouncement{
+		NodeID:    config.NodeID,
+		PublicKey: config.PrivateKey.Public().(ed25519.PublicKey),
+	}
+	legacy.Signature = ed25519.Sign(config.PrivateKey, legacy.signData())
+
+	return &E2E{
+		config: config,
+		legacy: legacy,
+		now:    time.Now,
+		keys:   make(map[string]ed25519.PublicKey),
+	}, nil
+}
+
+// Announcement 本节点的 v2 密钥通告，签发时间为当前时间
+func (e *E2E) Announcement() *KeyAnnouncement {
+	return e.announcement(2)
+}
+
+// announcement 按协议版本生成本节点的密钥通告
+func (e *E2E) announcement(version int) *KeyAnnouncement {
+	if version < 2 {
+		return e.legacy
+	}
+	ann := &KeyAnnouncement{
+		NodeID:    e.config.NodeID,
+		PublicKey: e.legacy.PublicKey,
+		Version:   2,
+		IssuedAt:  e.now().Unix(),
+	}
+	ann.Signature = ed25519.Sign(e.config.PrivateKey, ann.signData())
+	return ann
+}
+
+// AddPeerKey 校验并缓存节点的密钥通告
+func (e *E2E) AddPeerKey(ann *KeyAnnouncement) error {
+	if ann == nil || ann.NodeID == "" || len(ann.PublicKey) != ed25519.PublicKeySize {
+		return ErrInvalidAnnouncement
+	}
+	switch ann.version() {
+	case 1:
+	case 2:
+		age := e.now().Sub(time.Unix(ann.IssuedAt, 0))
+		if age > MaxAnnouncementAge || age < -MaxAnnouncementAge {
+			return fmt.Errorf("%w: issued %s from local time", ErrInvalidAnnouncement, age.Round(time.Second))
+		}
+	default:
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidAnnouncement, ann.Version)
+	}
+	pub := ed25519.PublicKey(ann.PublicKey)
+	if !ed25519.Verify(pub, ann.signData(), ann.Signature) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidAnnouncement)
+	}
+	if e.config.VerifyNodeID == nil || !e.config.VerifyNodeID(ann.NodeID, pub) {
+		return fmt.Errorf("%w: key does not match node ID %s", ErrInvalidAnnouncement, ann.NodeID)
+	}
+
+	e.mu.Lock()
+	e.keys[ann.NodeID] = pub
+	e.mu.Unlock()
+	return nil
+}
+
+// PeerKey 获取节点的公钥：依次查找缓存、从节点ID取得、握手交换
+func (e *E2E) PeerKey(nodeID string) (ed25519.PublicKey, error) {
+	e.mu.RLock()
+	pub, ok := e.keys[nodeID]
+	e.mu.RUnlock()
+	if ok {
+		return pub, nil
+	}
+
+	if e.config.ResolveKey != nil {
+		if pub, err := e.config.ResolveKey(nodeID); err == nil {
+			return pub, nil
+		}
+	}
+
+	if e.config.Handshake == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRecipientKey, nodeID)
+	}
+	ann, err := e.config.Handshake(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key exchange with %s failed: %v", ErrNoRecipientKey, nodeID, err)
+	}
+	if ann.NodeID != nodeID {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrInvalidAnnouncement, nodeID, ann.NodeID)
+	}
+	if err := e.AddPeerKey(ann); err != nil {
+		return nil, err
+	}
+	return ed25519.PublicKey(ann.PublicKey), nil
+}
+
+// Encrypt 用收件人的公钥加密邮件内容
+func (e *E2E) Encrypt(receiver string, data []byte) ([]byte, error) {
+	pub, err := e.PeerKey(receiver)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.SealToEd25519(pub, data)
+}
+
+// Decrypt 用本节点私钥解密发给自己的邮件内容
+func (e *E2E) Decrypt(data []byte) ([]byte, error) {
+	return crypto.OpenWithEd25519(e.config.PrivateKey, data)
+}
+
+// ExchangeKeys 作为发起方在流上交换 v2 密钥通告：先发送本节点的通告，再读取并校验对方的通告
+func (e *E2E) ExchangeKeys(rw io.ReadWriter) (*KeyAnnouncement, error) {
+	return e.exchangeKeys(rw, 2)
+}
+
+// ExchangeKeysV1 与只支持 v1 协议的节点交换密钥通告（兼容模式）
+func (e *E2E) ExchangeKeysV1(rw io.ReadWriter) (*KeyAnnouncement, error) {
+	return e.exchangeKeys(rw, 1)
+}
+
+func (e *E2E) exchangeKeys(rw io.ReadWriter, version int) (*KeyAnnouncement, error) {
+	if err := json.NewEncoder(rw).Encode(e.announcement(version)); err != nil {
+		return nil, err
+	}
+	ann, err := readAnnouncement(rw, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.AddPeerKey(ann); err != nil {
+		return nil, err
+	}
+	return ann, nil
+}
+
+// HandleKeyExchange 作为响应方处理 v2 密钥交换：读取并校验对方的通告，再回复本节点的通告
+func (e *E2E) HandleKeyExchange(rw io.ReadWriter) error {
+	return e.handleKeyExchange(rw, 2)
+}
+
+// HandleKeyExchangeV1 处理未升级节点发起的 v1 密钥交换（兼容模式）
+func (e *E2E) HandleKeyExchangeV1(rw io.ReadWriter) error {
+	return e.handleKeyExchange(rw, 1)
+}
+
+func (e *E2E) handleKeyExchange(rw io.ReadWriter, version int) error {
+	ann, err := readAnnouncement(rw, version)
+	if err != nil {
+		return err
+	}
+	if err := e.AddPeerKey(ann); err != nil {
+		return err
+	}
+	return json.NewEncoder(rw).Encode(e.announcement(version))
+}
+
+// readAnnouncement 读取通告，通告版本必须与流协商的协议版本一致
+func readAnnouncement(r io.Reader, version int) (*KeyAnnouncement, error) {
+	ann := new(KeyAnnouncement)
+	if err := json.NewDecoder(io.LimitReader(r, maxAnnouncementSize)).Decode(ann); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnnouncement, err)
+	}
+	if ann.version() != version {
+		return nil, fmt.Errorf("%w: v%d announcement on v%d protocol", ErrInvalidAnnouncement, ann.version(), version)
+	}
+	return ann, nil
+}
@@ -11,8 +11,29 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/clockskew"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
+)
+
+var (
+	// ErrRetentionHold 消息所在会话处于合规保全中，不可删除
+	ErrRetentionHold = errors.New("message thread is under retention hold")
+	// ErrStorageLocked 磁盘上的加密数据无法解密（未配置密钥环或密钥不匹配）
+	ErrStorageLocked = errors.New("mailbox storage is encrypted and cannot be unlocked")
+	// ErrPublicQuotaExceeded 陌生发件人在配额周期内已投递过公开收件箱
+	ErrPublicQuotaExceeded = errors.New("public inbox quota exceeded for sender")
+	// ErrPublicMessageTooLarge 投递到公开收件箱的消息超过大小上限
+	ErrPublicMessageTooLarge = errors.New("message too large for public inbox")
+	// ErrMessageExists 收件箱中已有该消息（重复投递）
+	ErrMessageExists = errors.New("message already exists")
+	// ErrUnsignedMessage 开启公开收件箱时拒收无法验证发件人的消息
+	ErrUnsignedMessage = errors.New("public inbox only accepts messages signed by the sender")
 )
 
 // MessageStatus 消息状态
@@ -24,6 +45,9 @@ const (
 	StatusRead      MessageStatus = "read"      // 已读
 	StatusExpired   MessageStatus = "expired"   // 已过期
 	StatusFailed    MessageStatus = "failed"    // 发送失败
+	StatusStaged    MessageStatus = "staged"    // 两阶段发送：等待撤回窗口结束
+	StatusCancelled MessageStatus = "cancelled" // 两阶段发送：已在窗口内撤回
+	StatusRelayed   MessageStatus = "relayed"   // 收件人不在线，已交给中继暂存
 )
 
 // Message 邮箱消息结构
@@ -39,6 +63,9 @@ type Message struct {
 	Status    MessageStatus `json:"status"`    // 消息状态
 	Signature []byte        `json:"signature"` // SM2 签名
 	ReadAt    *time.Time    `json:"read_at,omitempty"` // 阅读时间
+	DeliverAt *time.Time    `json:"deliver_at,omitempty"` // 两阶段发送的投递时间，之前可撤回
+	Relay     string        `json:"relay,omitempty"`      // 暂存该消息的中继节点
+	DeliveredAt *time.Time  `json:"delivered_at,omitempty"` // 送达回执中收件人确认收到的时间
 }
 
 // MessageSummary 消息摘要（用于列表展示）
@@ -49,6 +76,7 @@ type MessageSummary struct {
 	Timestamp time.Time     `json:"timestamp"`
 	Status    MessageStatus `json:"status"`
 	Encrypted bool          `json:"encrypted"`
+	ThreadID  string        `json:"thread_id"`
 }
 
 // SignFunc 签名函数类型
@@ -75,6 +103,34 @@ type MailboxConfig struct {
 	DefaultTTL      time.Duration // 默认消息存活时间
 	CleanupInterval time.Duration // 清理间隔
 	EnableEncrypt   bool          // 是否启用加密
+	ExpiryGrace     time.Duration // 过期判断的时钟偏差容忍窗口
+
+	// 公开收件箱：启用后陌生发件人的消息进入单独的公开文件夹并受配额限制
+	PublicInbox          bool
+	PublicQuota          int           // 每个陌生发件人在配额周期内可投递的消息数
+	PublicQuotaWindow    time.Duration // 配额周期
+	PublicMaxMessageSize int           // 公开收件箱单条消息内容上限（字节）
+	MaxPublicSize        int           // 公开文件夹最大消息数
+
+	// 发信检查：收件人声誉低于该值时警告（0 表示不检查）
+	LowReputation float64
+
+	// 存储后端，非空时替代 mailbox.json（加密由后端负责），首次加载时导入旧文件
+	Store storage.Backend
+
+	// 离线中继：启用后为不在线的节点暂存加密邮件，收件人重新连接后推送
+	Relay               bool
+	RelayTTL            time.Duration // 中继暂存的最长时间（不超过邮件自身的过期时间）
+	MaxRelayPerReceiver int           // 每个收件人最多暂存的邮件数
+	MaxRelayPerSender   int           // 每个发件人最多交来暂存的邮件数，单个节点不能占满全部暂存
+	MaxRelayMessages    int           // 全部暂存的邮件数上限
+
+	// 投递路径选择（见 routing.go）
+	SlowPathThreshold time.Duration // 直连期望耗时超过该值且有更快的中继时先交给中继（0 表示不判定）
+	RouteHistory      int           // 保留的路由决策条数
+
+	// 维护模式下暂缓通知的消息数上限，超出时丢弃最早的通知（消息仍在收件箱中）
+	MaxHeld int
 }
 
 // DefaultConfig 返回默认配置
@@ -87,6 +143,24 @@ func DefaultConfig(nodeID string) *MailboxConfig {
 		DefaultTTL:      48 * time.Hour,
 		CleanupInterval: 1 * time.Hour,
 		EnableEncrypt:   true,
+		ExpiryGrace:     clockskew.DefaultConfig().Grace,
+
+		PublicQuota:          1,
+		PublicQuotaWindow:    24 * time.Hour,
+		PublicMaxMessageSize: 4096,
+		MaxPublicSize:        200,
+
+		LowReputation: 5,
+
+		RelayTTL:            72 * time.Hour,
+		MaxRelayPerReceiver: 200,
+		MaxRelayPerSender:   500,
+		MaxRelayMessages:    10000,
+
+		SlowPathThreshold: 2 * time.Second,
+		RouteHistory:      defaultRouteHistory,
+
+		MaxHeld: 1000,
 	}
 }
 
@@ -98,17 +172,60 @@ type Mailbox struct {
 	pending  map[string][]*Message // 待投递消息: receiverID -> Messages (作为中继时使用)
 	mu       sync.RWMutex
 
+	// 公开收件箱
+	public     map[string]*Message    // 陌生发件人的消息: messageID -> Message
+	known      map[string]time.Time   // 已知发件人: nodeID -> 成为已知的时间
+	publicSeen map[string][]time.Time // 陌生发件人配额周期内的投递时间
+	contacts   map[string]*Contact    // 通讯录: nodeID -> Contact
+
+	// 发信检查使用的声誉和隔离状态查询
+	reputationOf func(nodeID string) float64
+	quarantined  func(nodeID string) bool
+
 	signFunc    SignFunc    // 签名函数
 	verifyFunc  VerifyFunc  // 验签函数
 	encryptFunc EncryptFunc // 加密函数
 	decryptFunc DecryptFunc // 解密函数
 	deliverFunc DeliverFunc // 在线投递函数
+	relayFunc   RelayFunc   // 离线时交给中继暂存的函数
+
+	// 中继：暂存时间（messageID -> 接收时间）和等待转交给原发件人的送达回执
+	relayStored    map[string]time.Time
+	receipts       map[string][]*Receipt // 原发件人 -> 回执
+	relayAccepted  int64
+	relayDelivered int64
+	relayExpired   int64
+	relayRejected  int64
+
+	// 投递路径统计与路由决策
+	routes *RouteTable
 
 	// 回调
 	onMessageReceived func(*Message)
 	onMessageSent     func(*Message)
 	onMessageRead     func(*Message)
 
+	// 维护模式下暂缓入站通知
+	holdInbound bool
+	held        []*Message
+
+	// 合规保全检查：返回 true 的会话不会被过期清理或删除
+	retentionHeld func(threadID string) bool
+
+	// 入站准入检查（如熔断），返回错误时拒收
+	admit func(msg *Message) error
+	// 按投递节点的准入检查（节点间配额），只用于经投递协议收到的邮件
+	peerAdmit func(peerID string) error
+
+	// 静态加密：设置后邮箱数据加密落盘
+	keyring *crypto.StorageKeyring
+	locked  bool // 磁盘上的加密数据无法解密，禁止保存以免覆盖
+	// 使用存储后端时，上次保存后变化的记录
+	dirty *storage.Tracker
+
+	// 接收、中继和拉取时按容忍窗口判断过期，并统计时钟偏差
+	skew *clockskew.Tracker
+
 	stopCh chan struct{}
 	wg     sync.WaitGroup
 }
@@ -135,7 +252,21 @@ func NewMailbox(config *MailboxConfig) (*Mailbox, error) {
 		outbox:  make(map[string]*Message),
 		pending: make(map[string][]*Message),
 		stopCh:  make(chan struct{}),
+
+		public:     make(map[string]*Message),
+		known:      make(map[string]time.Time),
+		publicSeen: make(map[string][]time.Time),
+		contacts:   make(map[string]*Contact),
+
+		relayStored: make(map[string]time.Time),
+		receipts:    make(map[string][]*Receipt),
+
+		routes: NewRouteTable(config.RouteHistory, config.SlowPathThreshold),
+		dirty:  storage.NewTracker(),
 	}
+	skewConfig := clockskew.DefaultConfig()
+	skewConfig.Grace = config.ExpiryGrace
+	mb.skew = clockskew.NewTracker(skewConfig)
 
 	return mb, nil
 }
@@ -175,6 +306,54 @@ func (m *Mailbox) SetDeliverFunc(fn DeliverFunc) {
 	m.deliverFunc = fn
 }
 
+// SetRetentionHoldFunc 设置合规保全检查函数
+func (m *Mailbox) SetRetentionHoldFunc(fn func(threadID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retentionHeld = fn
+}
+
+// SetAdmitFunc 设置入站消息准入检查，返回错误的消息不会存入收件箱
+func (m *Mailbox) SetAdmitFunc(fn func(msg *Message) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.admit = fn
+}
+
+// SetPeerAdmitFunc 设置按投递节点的准入检查，经投递或中继协议收到的每封邮件检查一次
+func (m *Mailbox) SetPeerAdmitFunc(fn func(peerID string) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peerAdmit = fn
+}
+
+// admitPeer 投递节点的准入检查，未设置时放行
+func (m *Mailbox) admitPeer(peerID string) error {
+	m.mu.RLock()
+	fn := m.peerAdmit
+	m.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(peerID)
+}
+
+// SetStorageKeyring 设置静态加密密钥环，需在 Start 之前调用
+// 未加密的旧数据照常加载，下次保存时加密。
+func (m *Mailbox) SetStorageKeyring(kr *crypto.StorageKeyring) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyring = kr
+}
+
+// Reseal 用密钥环的当前密钥重写磁盘数据（轮换密钥后调用）
+func (m *Mailbox) Reseal() error {
+	if m.config.Store != nil {
+		return m.config.Store.Compact()
+	}
+	return m.saveToDisk()
+}
+
 // SetOnMessageReceived 设置消息接收回调
 func (m *Mailbox) SetOnMessageReceived(fn func(*Message)) {
 	m.mu.Lock()
@@ -208,6 +387,10 @@ func (m *Mailbox) Start() error {
 	m.wg.Add(1)
 	go m.cleanupLoop()
 
+	// 投递撤回窗口已结束的暂存消息（含重启前暂存的）
+	m.wg.Add(1)
+	go m.stagedLoop()
+
 	return nil
 }
 
@@ -222,9 +405,48 @@ func (m *Mailbox) Stop() error {
 
 // SendMessage 发送消息
 func (m *Mailbox) SendMessage(receiver, subject string, content []byte, encrypt bool) (*Message, error) {
+	content, encrypted, err := m.sealContent(receiver, content, encrypt)
+	if err != nil {
+		return nil, err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	msg, err := m.newOutgoingLocked(receiver, subject, content, encrypted, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	msg.ExpiresAt = msg.Timestamp.Add(m.config.DefaultTTL)
+	msg.Status = StatusPending
+
+	// 存入发件箱并投递
+	m.outbox[msg.ID] = msg
+	m.markMessageLocked(msg.ID)
+	m.dispatchLocked(msg)
+
+	return msg, nil
+}
+
+// sealContent 按需加密外发内容，在持有锁之外调用（取得收件人公钥可能需要握手）
+// 请求加密但未设置加密函数时按明文发送。
+func (m *Mailbox) sealContent(receiver string, content []byte, encrypt bool) ([]byte, bool, error) {
+	m.mu.RLock()
+	encryptFunc := m.encryptFunc
+	m.mu.RUnlock()
+
+	if !encrypt || encryptFunc == nil || receiver == "" || len(content) == 0 {
+		return content, false, nil
+	}
+	sealed, err := encryptFunc(receiver, content)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return sealed, true, nil
+}
+
+// newOutgoingLocked 校验参数并构造已签名的外发消息（不存入发件箱），content 已由 sealContent 处理
+func (m *Mailbox) newOutgoingLocked(receiver, subject string, content []byte, encrypted bool, now time.Time) (*Message, error) {
 	if receiver == "" {
 		return nil, errors.New("receiver is required")
 	}
@@ -243,20 +465,8 @@ func (m *Mailbox) SendMessage(receiver, subject string, content []byte, encrypt
 		Receiver:  receiver,
 		Subject:   subject,
 		Content:   content,
-		Encrypted: false,
-		Timestamp: time.Now(),
-		ExpiresAt: time.Now().Add(m.config.DefaultTTL),
-		Status:    StatusPending,
-	}
-
-	// 加密内容（如果需要）
-	if encrypt && m.encryptFunc != nil {
-		encryptedContent, err := m.encryptFunc(receiver, content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to encrypt: %w", err)
-		}
-		msg.Content = encryptedContent
-		msg.Encrypted = true
+		Encrypted: encrypted,
+		Timestamp: now,
 	}
 
 	// 生成消息ID
@@ -272,24 +482,58 @@ func (m *Mailbox) SendMessage(receiver, subject string, content []byte, encrypt
 		msg.Signature = sig
 	}
 
-	// 尝试在线投递
-	if m.deliverFunc != nil {
-		err := m.deliverFunc(receiver, msg)
-		if err == nil {
-			msg.Status = StatusDelivered
-		}
-		// 投递失败时保持 pending 状态，等待稍后重试
-	}
+	return msg, nil
+}
 
-	// 存入发件箱
-	m.outbox[msg.ID] = msg
+// dispatchLocked 尝试在线投递发件箱中的消息，并把收件人记为已知发件人
+func (m *Mailbox) dispatchLocked(msg *Message) {
+	m.deliverLocked(msg)
+
+	// 主动发信或回复后对方成为已知发件人
+	m.promoteLocked(msg.Receiver, time.Now())
 
 	// 触发回调
 	if m.onMessageSent != nil {
 		go m.onMessageSent(msg)
 	}
+}
+
+// deliverLocked 尝试在线投递，失败时把加密消息交给中继暂存；直连被判定为慢路径
+// （ErrSlowPath）而中继都失败时再直连一次。都失败时保持 pending 状态，收件人重新连接后
+// 由 RetryPending 重试。投递和中继函数记录的尝试在结束时保存为一条路由决策。
+func (m *Mailbox) deliverLocked(msg *Message) {
+	if m.deliverFunc == nil {
+		return
+	}
+	defer m.routes.Finish(msg.ID)
+	m.markMessageLocked(msg.ID)
+
+	err := m.deliverFunc(msg.Receiver, msg)
+	if err == nil {
+		markDelivered(msg)
+		return
+	}
+	if msg.Encrypted && m.relayFunc != nil {
+		if relay, err := m.relayFunc(msg); err == nil {
+			msg.Status = StatusRelayed
+			msg.Relay = relay
+			return
+		}
+	}
+	if errors.Is(err, ErrSlowPath) && m.deliverFunc(msg.Receiver, msg) == nil {
+		markDelivered(msg)
+	}
+}
 
-	return msg, nil
+func markDelivered(msg *Message) {
+	now := time.Now()
+	msg.Status = StatusDelivered
+	msg.DeliveredAt = &now
+}
+
+// Routes 投递路径统计与路由决策，投递和中继函数经它选择中继并记录尝试
+func (m *Mailbox) Routes() *RouteTable {
+	return m.routes
 }
 
 // ReceiveMessage 接收消息（验证并存入收件箱）
@@ -306,17 +550,32 @@ func (m *Mailbox) ReceiveMessage(msg *Message) error {
 		return errors.New("message is not for this node")
 	}
 
-	// 检查是否已过期
-	if time.Now().After(msg.ExpiresAt) {
+	// 检查是否已过期（容忍发送方与本节点的时钟偏差）
+	now := time.Now()
+	m.skew.ObserveTimestamp(msg.Timestamp, now)
+	if m.skew.Expired(msg.ExpiresAt, now) {
 		return errors.New("message has expired")
 	}
 
 	// 检查是否已存在
 	if _, exists := m.inbox[msg.ID]; exists {
-		return errors.New("message already exists")
+		return ErrMessageExists
+	}
+	if _, exists := m.public[msg.ID]; exists {
+		return ErrMessageExists
+	}
+	if m.admit != nil {
+		if err := m.admit(msg); err != nil {
+			return err
+		}
 	}
 
-	// 验证签名
+	// 公开收件箱按发件人放行和计配额，发件人必须由签名证明
+	if m.config.PublicInbox && (m.verifyFunc == nil || len(msg.Signature) == 0) {
+		return ErrUnsignedMessage
+	}
+
+	// 先验签再判断发件人，冒充已知发件人的消息不能绕过公开收件箱，也不占用他人的配额
 	if m.verifyFunc != nil && len(msg.Signature) > 0 {
 		signData := m.getSignData(msg)
 		valid, err := m.verifyFunc(msg.Sender, signData, msg.Signature)
@@ -328,6 +587,52 @@ func (m *Mailbox) ReceiveMessage(msg *Message) error {
 		}
 	}
 
+	// 陌生发件人走公开收件箱
+	public := m.config.PublicInbox && !m.isKnownLocked(msg.Sender)
+	if public {
+		if err := m.checkPublicQuotaLocked(msg, now); err != nil {
+			return err
+		}
+		m.storePublicLocked(msg, now)
+		return nil
+	}
+
+	m.storeInboxLocked(msg)
+	return nil
+}
+
+// DeliverToSelf 把本节点生成的通知（如活动报告）直接存入自己的收件箱
+// 消息照常签名，但不经过网络投递，也不占用发件箱。
+func (m *Mailbox) DeliverToSelf(subject string, content []byte) (*Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg := &Message{
+		Sender:    m.config.NodeID,
+		Receiver:  m.config.NodeID,
+		Subject:   subject,
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+	if len(content) == 0 {
+		return nil, errors.New("content is required")
+	}
+	msg.ID = m.generateMessageID(msg)
+	msg.ExpiresAt = msg.Timestamp.Add(m.config.DefaultTTL)
+	if m.signFunc != nil {
+		sig, err := m.signFunc(m.getSignData(msg))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign: %w", err)
+		}
+		msg.Signature = sig
+	}
+
+	m.storeInboxLocked(msg)
+	return msg, nil
+}
+
+// storeInboxLocked 存入收件箱并通知（维护模式下暂缓通知）（调用方持有锁）
+func (m *Mailbox) storeInboxLocked(msg *Message) {
 	// 检查收件箱大小
 	if len(m.inbox) >= m.config.MaxInboxSize {
 		// 删除最旧的消息
@@ -339,13 +644,51 @@ func (m *Mailbox) ReceiveMessage(msg *Message) error {
 
 	// 存入收件箱
 	m.inbox[msg.ID] = msg
+	m.markMessageLocked(msg.ID)
+
+	// 维护模式下缓存，恢复后统一通知
+	if m.holdInbound {
+		m.held = append(m.held, msg)
+		if m.config.MaxHeld > 0 && len(m.held) > m.config.MaxHeld {
+			m.held = m.held[len(m.held)-m.config.MaxHeld:]
+		}
+		return
+	}
 
 	// 触发回调
 	if m.onMessageReceived != nil {
 		go m.onMessageReceived(msg)
 	}
+}
 
-	return nil
+// SetHoldInbound 设置是否暂缓入站通知（维护模式）
+// 暂缓期间消息照常存入收件箱，关闭时按接收顺序补发 onMessageReceived 回调
+func (m *Mailbox) SetHoldInbound(hold bool) {
+	m.mu.Lock()
+	m.holdInbound = hold
+	if hold {
+		m.mu.Unlock()
+		return
+	}
+	held := m.held
+	m.held = nil
+	cb := m.onMessageReceived
+	m.mu.Unlock()
+
+	if cb != nil && len(held) > 0 {
+		go func() {
+			for _, msg := range held {
+				cb(msg)
+			}
+		}()
+	}
+}
+
+// HeldCount 获取暂缓通知的消息数
+func (m *Mailbox) HeldCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.held)
 }
 
 // GetMessage 获取指定消息
@@ -359,20 +702,33 @@ func (m *Mailbox) GetMessage(messageID string) (*Message, error) {
 	if msg, ok := m.outbox[messageID]; ok {
 		return msg, nil
 	}
+	if msg, ok := m.public[messageID]; ok {
+		return msg, nil
+	}
 
 	return nil, errors.New("message not found")
 }
 
 // GetMessageContent 获取消息内容（解密如果需要）
+// 发件箱中的加密消息只有收件人能解密，返回 ErrEncryptedForRecipient。
 func (m *Mailbox) GetMessageContent(messageID string) ([]byte, error) {
 	m.mu.RLock()
 	msg, ok := m.inbox[messageID]
+	if !ok {
+		msg, ok = m.public[messageID]
+	}
+	if !ok {
+		msg, ok = m.outbox[messageID]
+	}
 	decryptFunc := m.decryptFunc
 	m.mu.RUnlock()
 
 	if !ok {
 		return nil, errors.New("message not found")
 	}
+	if msg.Encrypted && msg.Receiver != m.config.NodeID {
+		return nil, ErrEncryptedForRecipient
+	}
 
 	content := msg.Content
 	if msg.Encrypted && decryptFunc != nil {
@@ -392,6 +748,9 @@ func (m *Mailbox) MarkAsRead(messageID string) error {
 	defer m.mu.Unlock()
 
 	msg, ok := m.inbox[messageID]
+	if !ok {
+		msg, ok = m.public[messageID]
+	}
 	if !ok {
 		return errors.New("message not found")
 	}
@@ -403,6 +762,7 @@ func (m *Mailbox) MarkAsRead(messageID string) error {
 	now := time.Now()
 	msg.Status = StatusRead
 	msg.ReadAt = &now
+	m.markMessageLocked(messageID)
 
 	// 触发回调
 	if m.onMessageRead != nil {
@@ -416,50 +776,20 @@ func (m *Mailbox) MarkAsRead(messageID string) error {
 func (m *Mailbox) ListInbox(limit, offset int) []*MessageSummary {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-
-	messages := make([]*Message, 0, len(m.inbox))
-	for _, msg := range m.inbox {
-		messages = append(messages, msg)
-	}
-
-	// 按时间倒序排列
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].Timestamp.After(messages[j].Timestamp)
-	})
-
-	// 分页
-	if offset >= len(messages) {
-		return nil
-	}
-	end := offset + limit
-	if end > len(messages) {
-		end = len(messages)
-	}
-	messages = messages[offset:end]
-
-	// 转换为摘要
-	summaries := make([]*MessageSummary, len(messages))
-	for i, msg := range messages {
-		summaries[i] = &MessageSummary{
-			ID:        msg.ID,
-			Sender:    msg.Sender,
-			Subject:   msg.Subject,
-			Timestamp: msg.Timestamp,
-			Status:    msg.Status,
-			Encrypted: msg.Encrypted,
-		}
-	}
-
-	return summaries
+	return listSummaries(m.inbox, limit, offset)
 }
 
 // ListOutbox 列出发件箱消息
 func (m *Mailbox) ListOutbox(limit, offset int) []*MessageSummary {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return listSummaries(m.outbox, limit, offset)
+}
 
-	messages := make([]*Message, 0, len(m.outbox))
-	for _, msg := range m.outbox {
+// listSummaries 按时间倒序分页列出消息摘要（调用方需持有锁）
+func listSummaries(box map[string]*Message, limit, offset int) []*MessageSummary {
+	messages := make([]*Message, 0, len(box))
+	for _, msg := range box {
 		messages = append(messages, msg)
 	}
 
@@ -488,6 +818,7 @@ func (m *Mailbox) ListOutbox(limit, offset int) []*MessageSummary {
 			Timestamp: msg.Timestamp,
 			Status:    msg.Status,
 			Encrypted: msg.Encrypted,
+			ThreadID:  ThreadID(msg.Sender, msg.Receiver, msg.Subject),
 		}
 	}
 
@@ -499,14 +830,25 @@ func (m *Mailbox) DeleteMessage(messageID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.inbox[messageID]; ok {
+	m.markMessageLocked(messageID)
+	if msg, ok := m.inbox[messageID]; ok {
+		if m.isRetained(msg) {
+			return ErrRetentionHold
+		}
 		delete(m.inbox, messageID)
 		return nil
 	}
-	if _, ok := m.outbox[messageID]; ok {
+	if msg, ok := m.outbox[messageID]; ok {
+		if m.isRetained(msg) {
+			return ErrRetentionHold
+		}
 		delete(m.outbox, messageID)
 		return nil
 	}
+	if _, ok := m.public[messageID]; ok {
+		delete(m.public, messageID)
+		return nil
+	}
 
 	return errors.New("message not found")
 }
@@ -549,6 +891,15 @@ func (m *Mailbox) StoreForRelay(msg *Message) error {
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.storeRelayLocked(msg, time.Now())
+}
+
+// storeRelayLocked 校验过期时间和签名后存入待投递列表（调用方持有锁）
+func (m *Mailbox) storeRelayLocked(msg *Message, now time.Time) error {
+	m.skew.ObserveTimestamp(msg.Timestamp, now)
+	if m.skew.Expired(msg.ExpiresAt, now) {
+		return errors.New("message has expired")
+	}
 
 	// 验证签名
 	if m.verifyFunc != nil && len(msg.Signature) > 0 {
@@ -564,6 +915,7 @@ func (m *Mailbox) StoreForRelay(msg *Message) error {
 
 	// 存储消息
 	m.pending[msg.Receiver] = append(m.pending[msg.Receiver], msg)
+	m.dirty.Mark("pending", msg.Receiver)
 
 	return nil
 }
@@ -578,16 +930,25 @@ func (m *Mailbox) FetchPendingMessages(receiverID string, limit int) []*Message
 		return nil
 	}
 
-	// 获取指定数量
-	if limit <= 0 || limit > len(messages) {
-		limit = len(messages)
+	// 获取指定数量，超出容忍窗口的过期消息直接丢弃
+	now := time.Now()
+	result := make([]*Message, 0, len(messages))
+	taken := 0
+	for _, msg := range messages {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		taken++
+		if !m.skew.Expired(m.relayExpiryLocked(msg), now) {
+			result = append(result, msg)
+		}
+		delete(m.relayStored, msg.ID)
+		m.dirty.Mark("relay_stored", msg.ID)
 	}
 
-	result := make([]*Message, limit)
-	copy(result, messages[:limit])
-
 	// 从待处理列表中移除
-	m.pending[receiverID] = messages[limit:]
+	m.dirty.Mark("pending", receiverID)
+	m.pending[receiverID] = messages[taken:]
 	if len(m.pending[receiverID]) == 0 {
 		delete(m.pending, receiverID)
 	}
@@ -628,12 +989,44 @@ func (m *Mailbox) getSignData(msg *Message) []byte {
 	return []byte(data)
 }
 
-// removeOldestInbox 移除收件箱中最旧的消息
+// ThreadID 计算会话ID：双方节点（无序）加去掉 Re:/Fwd: 前缀的主题
+func ThreadID(a, b, subject string) string {
+	if b < a {
+		a, b = b, a
+	}
+	subject = strings.TrimSpace(subject)
+	for {
+		lower := strings.ToLower(subject)
+		trimmed := false
+		for _, prefix := range []string{"re:", "fwd:", "fw:"} {
+			if strings.HasPrefix(lower, prefix) {
+				subject = strings.TrimSpace(subject[len(prefix):])
+				trimmed = true
+				break
+			}
+		}
+		if !trimmed {
+			break
+		}
+	}
+	hash := sha256.Sum256([]byte(a + "|" + b + "|" + subject))
+	return "thread_" + hex.EncodeToString(hash[:16])
+}
+
+// isRetained 消息所在会话是否处于合规保全中（调用方需持有锁）
+func (m *Mailbox) isRetained(msg *Message) bool {
+	return m.retentionHeld != nil && m.retentionHeld(ThreadID(msg.Sender, msg.Receiver, msg.Subject))
+}
+
+// removeOldestInbox 移除收件箱中最旧的消息（跳过保全中的会话）
 func (m *Mailbox) removeOldestInbox() {
 	var oldest *Message
 	var oldestID string
 
 	for id, msg := range m.inbox {
+		if m.isRetained(msg) {
+			continue
+		}
 		if oldest == nil || msg.Timestamp.Before(oldest.Timestamp) {
 			oldest = msg
 			oldestID = id
@@ -642,6 +1035,7 @@ func (m *Mailbox) removeOldestInbox() {
 
 	if oldestID != "" {
 		delete(m.inbox, oldestID)
+		m.markMessageLocked(oldestID)
 	}
 }
 
@@ -669,28 +1063,51 @@ func (m *Mailbox) cleanup() {
 
 	now := time.Now()
 
+	// 过期后保留到容忍窗口结束，时钟较慢的节点仍能查到或拉取
+
 	// 清理收件箱
 	for id, msg := range m.inbox {
-		if now.After(msg.ExpiresAt) {
+		if !m.skew.Retained(msg.ExpiresAt, now) && !m.isRetained(msg) {
 			delete(m.inbox, id)
+			m.markMessageLocked(id)
 		}
 	}
 
 	// 清理发件箱
 	for id, msg := range m.outbox {
-		if now.After(msg.ExpiresAt) {
+		if !m.skew.Retained(msg.ExpiresAt, now) && !m.isRetained(msg) {
 			delete(m.outbox, id)
+			m.markMessageLocked(id)
 		}
 	}
 
-	// 清理待投递消息
+	// 清理公开文件夹和过期的配额记录
+	for id, msg := range m.public {
+		if !m.skew.Retained(msg.ExpiresAt, now) {
+			delete(m.public, id)
+			m.markMessageLocked(id)
+		}
+	}
+	m.prunePublicSeenLocked(now)
+
+	// 清理待投递消息（中继暂存的按 RelayTTL）
 	for receiver, messages := range m.pending {
 		filtered := make([]*Message, 0, len(messages))
 		for _, msg := range messages {
-			if !now.After(msg.ExpiresAt) {
+			if m.skew.Retained(m.relayExpiryLocked(msg), now) {
 				filtered = append(filtered, msg)
+				continue
+			}
+			if _, ok := m.relayStored[msg.ID]; ok {
+				delete(m.relayStored, msg.ID)
+				m.dirty.Mark("relay_stored", msg.ID)
+				m.relayExpired++
 			}
 		}
+		if len(filtered) == len(messages) {
+			continue
+		}
+		m.dirty.Mark("pending", receiver)
 		if len(filtered) == 0 {
 			delete(m.pending, receiver)
 		} else {
@@ -705,27 +1122,55 @@ type mailboxData struct {
 	Inbox   map[string]*Message   `json:"inbox"`
 	Outbox  map[string]*Message   `json:"outbox"`
 	Pending map[string][]*Message `json:"pending"`
+
+	Public     map[string]*Message    `json:"public,omitempty"`
+	Known      map[string]time.Time   `json:"known,omitempty"`
+	PublicSeen map[string][]time.Time `json:"public_seen,omitempty"`
+	Contacts   map[string]*Contact    `json:"contacts,omitempty"`
+
+	RelayStored map[string]time.Time  `json:"relay_stored,omitempty"`
+	Receipts    map[string][]*Receipt `json:"receipts,omitempty"`
 }
 
 // saveToDisk 保存到磁盘
 func (m *Mailbox) saveToDisk() error {
+	if m.config.Store != nil {
+		m.mu.RLock()
+		snap, err := storage.EncodeState(storePrefix, m.dataLocked(), m.dirty)
+		locked := m.locked
+		m.mu.RUnlock()
+		if locked {
+			return ErrStorageLocked
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode mailbox data: %w", err)
+		}
+		if _, err := snap.Write(m.config.Store); err != nil {
+			return fmt.Errorf("failed to write mailbox data: %w", err)
+		}
+		return nil
+	}
 	if m.config.DataDir == "" {
 		return nil
 	}
 
 	m.mu.RLock()
-	data := &mailboxData{
-		Inbox:   m.inbox,
-		Outbox:  m.outbox,
-		Pending: m.pending,
-	}
+	jsonData, err := json.MarshalIndent(m.dataLocked(), "", "  ")
+	keyring, locked := m.keyring, m.locked
 	m.mu.RUnlock()
-
-	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if locked {
+		return ErrStorageLocked
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal mailbox data: %w", err)
 	}
 
+	if keyring != nil {
+		if jsonData, err = keyring.Seal(jsonData); err != nil {
+			return fmt.Errorf("failed to encrypt mailbox data: %w", err)
+		}
+	}
+
 	filePath := filepath.Join(m.config.DataDir, "mailbox.json")
 	if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
 		return fmt.Errorf("failed to write mailbox data: %w", err)
@@ -734,8 +1179,64 @@ func (m *Mailbox) saveToDisk() error {
 	return nil
 }
 
-// loadFromDisk 从磁盘加载
+// dataLocked 当前持久化数据
+func (m *Mailbox) dataLocked() *mailboxData {
+	return &mailboxData{
+		Inbox:   m.inbox,
+		Outbox:  m.outbox,
+		Pending: m.pending,
+
+		Public:     m.public,
+		Known:      m.known,
+		PublicSeen: m.publicSeen,
+		Contacts:   m.contacts,
+
+		RelayStored: m.relayStored,
+		Receipts:    m.receipts,
+	}
+}
+
+// markMessageLocked 记下变化的邮件，收件箱、发件箱和公开文件夹按同一个ID保存（调用方持有锁）
+func (m *Mailbox) markMessageLocked(id string) {
+	m.dirty.Mark("inbox", id)
+	m.dirty.Mark("outbox", id)
+	m.dirty.Mark("public", id)
+}
+
+// storePrefix 邮箱数据在存储后端中的 bucket 前缀
+const storePrefix = "mailbox"
+
+// loadFromDisk 加载数据：使用存储后端时，后端为空则导入旧的 mailbox.json
 func (m *Mailbox) loadFromDisk() error {
+	store := m.config.Store
+	if store == nil {
+		return m.loadFile()
+	}
+
+	var data mailboxData
+	found, err := storage.LoadState(store, storePrefix, &data)
+	if err != nil {
+		return fmt.Errorf("failed to load mailbox data: %w", err)
+	}
+	if found {
+		m.applyData(&data)
+		return nil
+	}
+	if m.config.DataDir == "" {
+		return nil
+	}
+	if err := m.loadFile(); err != nil {
+		return err
+	}
+	m.dirty.MarkAll()
+	if err := m.saveToDisk(); err != nil {
+		return err
+	}
+	return storage.RetireLegacy(filepath.Join(m.config.DataDir, "mailbox.json"))
+}
+
+// loadFile 从 mailbox.json 加载
+func (m *Mailbox) loadFile() error {
 	if m.config.DataDir == "" {
 		return nil
 	}
@@ -750,11 +1251,35 @@ func (m *Mailbox) loadFromDisk() error {
 		return fmt.Errorf("failed to read mailbox data: %w", err)
 	}
 
+	if crypto.IsSealed(jsonData) {
+		m.mu.RLock()
+		keyring := m.keyring
+		m.mu.RUnlock()
+		if keyring != nil {
+			jsonData, err = keyring.Open(jsonData)
+		}
+		if keyring == nil || err != nil {
+			m.mu.Lock()
+			m.locked = true
+			m.mu.Unlock()
+			if err != nil {
+				return fmt.Errorf("failed to decrypt mailbox data: %w", err)
+			}
+			return ErrStorageLocked
+		}
+	}
+
 	var data mailboxData
 	if err := json.Unmarshal(jsonData, &data); err != nil {
 		return fmt.Errorf("failed to unmarshal mailbox data: %w", err)
 	}
 
+	m.applyData(&data)
+	return nil
+}
+
+// applyData 用加载的数据替换内存数据
+func (m *Mailbox) applyData(data *mailboxData) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -767,8 +1292,24 @@ func (m *Mailbox) loadFromDisk() error {
 	if data.Pending != nil {
 		m.pending = data.Pending
 	}
-
-	return nil
+	if data.Public != nil {
+		m.public = data.Public
+	}
+	if data.Known != nil {
+		m.known = data.Known
+	}
+	if data.PublicSeen != nil {
+		m.publicSeen = data.PublicSeen
+	}
+	if data.Contacts != nil {
+		m.contacts = data.Contacts
+	}
+	if data.RelayStored != nil {
+		m.relayStored = data.RelayStored
+	}
+	if data.Receipts != nil {
+		m.receipts = data.Receipts
+	}
 }
 
 // Stats 邮箱统计信息
@@ -777,6 +1318,13 @@ type Stats struct {
 	OutboxCount   int `json:"outbox_count"`
 	UnreadCount   int `json:"unread_count"`
 	PendingCount  int `json:"pending_count"` // 作为中继时的待投递消息总数
+	PublicCount   int `json:"public_count"`  // 公开文件夹消息数
+	KnownSenders  int `json:"known_senders"` // 已知发件人数
+}
+
+// SkewStats 返回过期判断的时钟偏差统计
+func (m *Mailbox) SkewStats() clockskew.Stats {
+	return m.skew.Stats()
 }
 
 // GetStats 获取统计信息
@@ -785,8 +1333,10 @@ func (m *Mailbox) GetStats() *Stats {
 	defer m.mu.RUnlock()
 
 	stats := &Stats{
-		InboxCount:  len(m.inbox),
-		OutboxCount: len(m.outbox),
+		InboxCount:   len(m.inbox),
+		OutboxCount:  len(m.outbox),
+		PublicCount:  len(m.public),
+		KnownSenders: len(m.known),
 	}
 
 	for _, msg := range m.inbox {
@@ -801,3 +1351,24 @@ func (m *Mailbox) GetStats() *Stats {
 
 	return stats
 }
+
+// Stats 实现 stats.Provider
+func (m *Mailbox) Stats() []stats.Metric {
+	s := m.GetStats()
+	m.mu.RLock()
+	relay := m.relaySummaryLocked()
+	m.mu.RUnlock()
+	return []stats.Metric{
+		stats.Gauge("inbox_messages", float64(s.InboxCount), "收件箱消息数"),
+		stats.Gauge("outbox_messages", float64(s.OutboxCount), "发件箱消息数"),
+		stats.Gauge("unread_messages", float64(s.UnreadCount), "未读消息数"),
+		stats.Gauge("pending_relay_messages", float64(s.PendingCount), "作为中继待投递的消息数"),
+		stats.Gauge("pending_receipts", float64(relay.PendingReceipts), "作为中继待转交给发件人的送达回执数"),
+		stats.Counter("relay_accepted", float64(relay.Accepted), "作为中继接收暂存的邮件数"),
+		stats.Counter("relay_delivered", float64(relay.Delivered), "作为中继推送给收件人并收到回执的邮件数"),
+		stats.Counter("relay_expired", float64(relay.Expired), "作为中继暂存超时被丢弃的邮件数"),
+		stats.Counter("relay_rejected", float64(relay.Rejected), "作为中继拒收或被收件人拒收的邮件数"),
+		stats.Gauge("public_messages", float64(s.PublicCount), "公开文件夹消息数"),
+		stats.Gauge("known_senders", float64(s.KnownSenders), "已知发件人数"),
+	}
+}
@@ -1,10 +1,21 @@
 package mailbox
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/storage"
 )
 
 // 测试辅助函数 - 创建测试配置
@@ -250,6 +261,161 @@ func TestReceiveMessage(t *testing.T) {
 	}
 }
 
+func TestReceiveMessageHoldInbound(t *testing.T) {
+	mb := createTestMailbox(t)
+
+	notified := make(chan string, 2)
+	mb.SetOnMessageReceived(func(msg *Message) {
+		notified <- msg.ID
+	})
+	mb.SetHoldInbound(true)
+
+	for _, id := range []string{"held-1", "held-2"} {
+		err := mb.ReceiveMessage(&Message{
+			ID:        id,
+			Sender:    "sender-001",
+			Receiver:  mb.config.NodeID,
+			Timestamp: time.Now(),
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("ReceiveMessage() error = %v", err)
+		}
+	}
+
+	if mb.GetInboxCount() != 2 {
+		t.Errorf("InboxCount = %d, want 2", mb.GetInboxCount())
+	}
+	if mb.HeldCount() != 2 {
+		t.Errorf("HeldCount = %d, want 2", mb.HeldCount())
+	}
+	select {
+	case id := <-notified:
+		t.Fatalf("unexpected notification for %s while held", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mb.SetHoldInbound(false)
+	for _, want := range []string{"held-1", "held-2"} {
+		select {
+		case id := <-notified:
+			if id != want {
+				t.Errorf("notified %s, want %s", id, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("missing notification for %s", want)
+		}
+	}
+	if mb.HeldCount() != 0 {
+		t.Errorf("HeldCount = %d, want 0", mb.HeldCount())
+	}
+}
+
+func TestReceiveMessageHoldInboundCap(t *testing.T) {
+	mb := createTestMailbox(t)
+	mb.config.MaxHeld = 2
+
+	notified := make(chan string, 3)
+	mb.SetOnMessageReceived(func(msg *Message) {
+		notified <- msg.ID
+	})
+	mb.SetHoldInbound(true)
+
+	for _, id := range []string{"held-1", "held-2", "held-3"} {
+		err := mb.ReceiveMessage(&Message{
+			ID:        id,
+			Sender:    "sender-001",
+			Receiver:  mb.config.NodeID,
+			Timestamp: time.Now(),
+			ExpiresAt: time.Now().Add(1 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("ReceiveMessage() error = %v", err)
+		}
+	}
+
+	// 超出上限时丢弃最早的通知，消息仍在收件箱中
+	if mb.GetInboxCount() != 3 {
+		t.Errorf("InboxCount = %d, want 3", mb.GetInboxCount())
+	}
+	if mb.HeldCount() != 2 {
+		t.Errorf("HeldCount = %d, want 2", mb.HeldCount())
+	}
+
+	mb.SetHoldInbound(false)
+	for _, want := range []string{"held-2", "held-3"} {
+		select {
+		case id := <-notified:
+			if id != want {
+				t.Errorf("notified %s, want %s", id, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("missing notification for %s", want)
+		}
+	}
+}
+
+func TestDeliverToSelf(t *testing.T) {
+	mb := createTestMailbox(t)
+	mb.SetSignFunc(mockSignFunc)
+	notified := make(chan string, 1)
+	mb.SetOnMessageReceived(func(msg *Message) {
+		notified <- msg.ID
+	})
+
+	if _, err := mb.DeliverToSelf("empty", nil); err == nil {
+		t.Error("expected error for empty content")
+	}
+	msg, err := mb.DeliverToSelf("Weekly report", []byte("Tasks completed 3"))
+	if err != nil {
+		t.Fatalf("DeliverToSelf() error = %v", err)
+	}
+	if msg.Sender != mb.config.NodeID || msg.Receiver != mb.config.NodeID || len(msg.Signature) == 0 {
+		t.Errorf("message = %+v", msg)
+	}
+	received, err := mb.GetMessage(msg.ID)
+	if err != nil || received.Status != StatusDelivered || mb.GetOutboxCount() != 0 {
+		t.Errorf("GetMessage() = %+v, %v, outbox %d", received, err, mb.GetOutboxCount())
+	}
+	select {
+	case id := <-notified:
+		if id != msg.ID {
+			t.Errorf("notified %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("missing notification")
+	}
+}
+
+func TestReceiveMessageAdmit(t *testing.T) {
+	mb := createTestMailbox(t)
+	mb.SetVerifyFunc(mockVerifyFunc)
+
+	paused := errors.New("mail paused")
+	mb.SetAdmitFunc(func(msg *Message) error { return paused })
+
+	msg := &Message{
+		ID:        "test-msg-admit",
+		Sender:    "sender-001",
+		Receiver:  mb.config.NodeID,
+		Content:   []byte("Hello"),
+		Timestamp: time.Now(),
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+		Signature: []byte("signature"),
+	}
+	if err := mb.ReceiveMessage(msg); err != paused {
+		t.Fatalf("ReceiveMessage() error = %v, want admit error", err)
+	}
+	if mb.GetInboxCount() != 0 {
+		t.Errorf("InboxCount = %d, want 0", mb.GetInboxCount())
+	}
+
+	mb.SetAdmitFunc(nil)
+	if err := mb.ReceiveMessage(msg); err != nil {
+		t.Fatalf("ReceiveMessage() error = %v", err)
+	}
+}
+
 func TestReceiveMessageErrors(t *testing.T) {
 	mb := createTestMailbox(t)
 
@@ -601,6 +767,132 @@ func TestPersistence(t *testing.T) {
 	}
 }
 
+func TestEncryptedPersistence(t *testing.T) {
+	config := createTestConfig(t)
+	filePath := filepath.Join(config.DataDir, "mailbox.json")
+	keyring, err := crypto.OpenStorageKeyring(filepath.Join(config.DataDir, "keys.json"), []byte("node-secret"))
+	if err != nil {
+		t.Fatalf("OpenStorageKeyring() error = %v", err)
+	}
+
+	// 未加密的旧数据照常加载，保存时加密
+	plain, _ := NewMailbox(config)
+	plain.SetSignFunc(mockSignFunc)
+	msg, _ := plain.SendMessage("receiver-001", "Secret plans", []byte("launch at dawn"), false)
+	if err := plain.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+
+	mb1, _ := NewMailbox(config)
+	mb1.SetStorageKeyring(keyring)
+	if err := mb1.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() plaintext error = %v", err)
+	}
+	if err := mb1.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+	raw, _ := os.ReadFile(filePath)
+	if !crypto.IsSealed(raw) || bytes.Contains(raw, []byte("Secret plans")) {
+		t.Fatalf("mailbox file should be encrypted: %s", raw)
+	}
+
+	// 轮换密钥后重写，新旧密钥加密的数据都可读取
+	oldKey := keyring.ActiveKeyID()
+	if _, err := keyring.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if err := mb1.Reseal(); err != nil {
+		t.Fatalf("Reseal() error = %v", err)
+	}
+	raw, _ = os.ReadFile(filePath)
+	if bytes.Contains(raw, []byte(oldKey)) {
+		t.Error("resealed file should use the new key")
+	}
+
+	mb2, _ := NewMailbox(config)
+	mb2.SetStorageKeyring(keyring)
+	if err := mb2.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() error = %v", err)
+	}
+	loaded, err := mb2.GetMessage(msg.ID)
+	if err != nil || string(loaded.Content) != "launch at dawn" {
+		t.Fatalf("GetMessage() = %v, %v", loaded, err)
+	}
+
+	// 没有密钥环时拒绝加载，也不覆盖加密数据
+	mb3, _ := NewMailbox(config)
+	if err := mb3.loadFromDisk(); !errors.Is(err, ErrStorageLocked) {
+		t.Errorf("loadFromDisk() error = %v, want ErrStorageLocked", err)
+	}
+	if err := mb3.saveToDisk(); !errors.Is(err, ErrStorageLocked) {
+		t.Errorf("saveToDisk() error = %v, want ErrStorageLocked", err)
+	}
+	if after, _ := os.ReadFile(filePath); !bytes.Equal(after, raw) {
+		t.Error("locked mailbox should not overwrite encrypted data")
+	}
+}
+
+func TestStoreBackend(t *testing.T) {
+	config := createTestConfig(t)
+	keyring, _ := crypto.OpenStorageKeyring(filepath.Join(config.DataDir, "keys.json"), []byte("node-secret"))
+
+	// 旧版加密的 mailbox.json
+	legacy, _ := NewMailbox(config)
+	legacy.SetSignFunc(mockSignFunc)
+	legacy.SetStorageKeyring(keyring)
+	msg, _ := legacy.SendMessage("receiver-001", "Secret plans", []byte("launch at dawn"), false)
+	if err := legacy.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+
+	dbPath := filepath.Join(config.DataDir, "node.db")
+	store, err := storage.OpenLogDB(dbPath, &storage.Options{Sealer: keyring})
+	if err != nil {
+		t.Fatalf("OpenLogDB() error = %v", err)
+	}
+	config.Store = store
+	mb1, _ := NewMailbox(config)
+	mb1.SetStorageKeyring(keyring)
+	if err := mb1.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() import error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.DataDir, "mailbox.json")); !os.IsNotExist(err) {
+		t.Error("legacy mailbox.json should be retired after import")
+	}
+	if _, err := os.Stat(filepath.Join(config.DataDir, "mailbox.json.migrated")); err != nil {
+		t.Errorf("legacy file should be kept as a backup: %v", err)
+	}
+	if err := mb1.DeleteMessage(msg.ID); err != nil {
+		t.Fatalf("DeleteMessage() error = %v", err)
+	}
+	mb1.SendMessage("receiver-002", "Second", []byte("hold position"), false)
+	if err := mb1.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+	store.Close()
+
+	if raw, _ := os.ReadFile(dbPath); bytes.Contains(raw, []byte("Second")) {
+		t.Error("store records should be sealed")
+	}
+
+	store, err = storage.OpenLogDB(dbPath, &storage.Options{Sealer: keyring})
+	if err != nil {
+		t.Fatalf("reopen store error = %v", err)
+	}
+	defer store.Close()
+	config.Store = store
+	mb2, _ := NewMailbox(config)
+	if err := mb2.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() error = %v", err)
+	}
+	if _, err := mb2.GetMessage(msg.ID); err == nil {
+		t.Error("deleted message should not come back from the store")
+	}
+	if stats := mb2.GetStats(); stats.OutboxCount != 1 {
+		t.Errorf("outbox count = %d, want 1", stats.OutboxCount)
+	}
+}
+
 func TestStartStop(t *testing.T) {
 	mb := createTestMailbox(t)
 
@@ -924,3 +1216,970 @@ func TestCleanup(t *testing.T) {
 		t.Error("Valid message should not be removed")
 	}
 }
+
+func TestThreadID(t *testing.T) {
+	base := ThreadID("alice", "bob", "Invoice 42")
+	if ThreadID("bob", "alice", "Re: Invoice 42") != base {
+		t.Error("Reply in the other direction should share the thread")
+	}
+	if ThreadID("alice", "bob", "RE: fwd: Invoice 42") != base {
+		t.Error("Stacked Re:/Fwd: prefixes should be stripped")
+	}
+	if ThreadID("alice", "carol", "Invoice 42") == base {
+		t.Error("Different parties should not share a thread")
+	}
+}
+
+func TestRetentionHold(t *testing.T) {
+	mb := createTestMailbox(t)
+
+	heldMsg := &Message{
+		ID:        "held-msg",
+		Sender:    "sender-001",
+		Receiver:  mb.config.NodeID,
+		Subject:   "Contract",
+		Content:   []byte("Hello"),
+		Timestamp: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-1 * time.Hour),
+	}
+	otherMsg := &Message{
+		ID:        "other-msg",
+		Sender:    "sender-001",
+		Receiver:  mb.config.NodeID,
+		Subject:   "Lunch",
+		Content:   []byte("Hello"),
+		Timestamp: time.Now().Add(-2 * time.Hour),
+		ExpiresAt: time.Now().Add(-1 * time.Hour),
+	}
+	mb.mu.Lock()
+	mb.inbox[heldMsg.ID] = heldMsg
+	mb.inbox[otherMsg.ID] = otherMsg
+	mb.mu.Unlock()
+
+	threadID := ThreadID(heldMsg.Sender, heldMsg.Receiver, heldMsg.Subject)
+	mb.SetRetentionHoldFunc(func(id string) bool { return id == threadID })
+
+	summaries := mb.ListInbox(10, 0)
+	if len(summaries) != 2 || summaries[0].ThreadID == "" {
+		t.Fatalf("ListInbox() should expose thread IDs, got %+v", summaries)
+	}
+
+	mb.cleanup()
+	if _, err := mb.GetMessage("held-msg"); err != nil {
+		t.Error("Held message should survive cleanup")
+	}
+	if _, err := mb.GetMessage("other-msg"); err == nil {
+		t.Error("Unheld expired message should be removed")
+	}
+
+	if err := mb.DeleteMessage("held-msg"); err != ErrRetentionHold {
+		t.Errorf("DeleteMessage() error = %v, want ErrRetentionHold", err)
+	}
+
+	mb.SetRetentionHoldFunc(nil)
+	if err := mb.DeleteMessage("held-msg"); err != nil {
+		t.Errorf("DeleteMessage() after release error = %v", err)
+	}
+}
+
+func TestExpiryGrace(t *testing.T) {
+	config := createTestConfig(t)
+	config.ExpiryGrace = time.Minute
+	mb, _ := NewMailbox(config)
+	now := time.Now()
+
+	// 发送方时钟稍快：按本节点时钟刚过期，仍在容忍窗口内
+	late := &Message{
+		ID:        "late-msg",
+		Sender:    "peer-a",
+		Receiver:  config.NodeID,
+		Content:   []byte("Hello"),
+		Timestamp: now.Add(10 * time.Second),
+		ExpiresAt: now.Add(-20 * time.Second),
+	}
+	if err := mb.ReceiveMessage(late); err != nil {
+		t.Fatalf("message within grace should be accepted: %v", err)
+	}
+	stale := &Message{
+		ID:        "stale-msg",
+		Sender:    "peer-a",
+		Receiver:  config.NodeID,
+		Content:   []byte("Hello"),
+		Timestamp: now.Add(-time.Hour),
+		ExpiresAt: now.Add(-2 * time.Minute),
+	}
+	if err := mb.ReceiveMessage(stale); err == nil {
+		t.Error("message beyond grace should be rejected")
+	}
+
+	// 中继：窗口内可存入，拉取时丢弃超出窗口的消息
+	relayed := &Message{ID: "relay-1", Receiver: "peer-b", ExpiresAt: now.Add(-30 * time.Second)}
+	if err := mb.StoreForRelay(relayed); err != nil {
+		t.Fatalf("relay within grace should be stored: %v", err)
+	}
+	if err := mb.StoreForRelay(&Message{ID: "relay-2", Receiver: "peer-b", ExpiresAt: now.Add(-time.Hour)}); err == nil {
+		t.Error("relay beyond grace should be rejected")
+	}
+	mb.mu.Lock()
+	mb.pending["peer-b"] = append(mb.pending["peer-b"], &Message{ID: "relay-3", Receiver: "peer-b", ExpiresAt: now.Add(-time.Hour)})
+	mb.mu.Unlock()
+	if got := mb.FetchPendingMessages("peer-b", 10); len(got) != 1 || got[0].ID != "relay-1" {
+		t.Errorf("FetchPendingMessages() = %v, want only relay-1", got)
+	}
+
+	// 清理时窗口内的消息保留
+	mb.cleanup()
+	if _, err := mb.GetMessage("late-msg"); err != nil {
+		t.Error("message within grace should survive cleanup")
+	}
+
+	stats := mb.SkewStats()
+	if stats.Grace != time.Minute || stats.WithinGrace < 2 || stats.Rejected < 2 || stats.FutureStamped != 1 {
+		t.Errorf("unexpected skew stats: %+v", stats)
+	}
+}
+
+func TestContacts(t *testing.T) {
+	config := createTestConfig(t)
+	config.LowReputation = 5
+	mb, _ := NewMailbox(config)
+
+	if _, err := mb.SaveContact(Contact{Label: "no id"}); !errors.Is(err, ErrInvalidContact) {
+		t.Errorf("expected ErrInvalidContact for missing node ID, got %v", err)
+	}
+	if _, err := mb.SaveContact(Contact{NodeID: "node-x", Trust: "unknown"}); !errors.Is(err, ErrInvalidContact) {
+		t.Errorf("expected ErrInvalidContact for unknown trust, got %v", err)
+	}
+
+	alice, err := mb.SaveContact(Contact{NodeID: "12D3KooWAlice", Label: "Alice", Notes: "translation agent", Encryption: EncryptionAlways})
+	if err != nil {
+		t.Fatalf("SaveContact() error = %v", err)
+	}
+	if alice.Trust != TrustNormal || alice.CreatedAt.IsZero() {
+		t.Errorf("contact = %+v", alice)
+	}
+	mb.SaveContact(Contact{NodeID: "12D3KooWMallory", Label: "Mallory", Notes: "spammed the bulletin, ask Alice", Trust: TrustLow})
+	mb.SaveContact(Contact{NodeID: "12D3KooWBob"})
+
+	if list := mb.ListContacts(); len(list) != 3 || list[0].NodeID != "12D3KooWBob" || list[1].Label != "Alice" {
+		t.Errorf("ListContacts() = %+v", list)
+	}
+	// 标签前缀优先于备注中的匹配
+	if found := mb.SearchContacts("ali", 0); len(found) != 2 || found[0].Label != "Alice" || found[1].Label != "Mallory" {
+		t.Errorf("SearchContacts(ali) = %+v", found)
+	}
+	if found := mb.SearchContacts("12d3koow", 1); len(found) != 1 {
+		t.Errorf("SearchContacts limit = %+v", found)
+	}
+
+	// 更新保留创建时间
+	updated, _ := mb.SaveContact(Contact{NodeID: "12D3KooWAlice", Label: "Alice (work)", Encryption: EncryptionAlways})
+	if !updated.CreatedAt.Equal(alice.CreatedAt) || updated.Notes != "" {
+		t.Errorf("updated = %+v", updated)
+	}
+	if !updated.Encrypt(false) || (*Contact)(nil).Encrypt(true) != true {
+		t.Error("encryption preference not applied")
+	}
+
+	// 发信检查
+	mb.SetReputationFunc(func(nodeID string) float64 {
+		if nodeID == "12D3KooWBob" {
+			return 2
+		}
+		return 10
+	})
+	mb.SetQuarantineFunc(func(nodeID string) bool { return nodeID == "stranger" })
+	if check := mb.CheckRecipient("12D3KooWAlice"); len(check.Warnings) != 0 || check.Contact == nil || *check.Reputation != 10 {
+		t.Errorf("CheckRecipient(alice) = %+v", check)
+	}
+	if check := mb.CheckRecipient("12D3KooWMallory"); len(check.Warnings) != 1 || check.Warnings[0].Code != WarnLowTrust {
+		t.Errorf("CheckRecipient(mallory) = %+v", check)
+	}
+	if check := mb.CheckRecipient("12D3KooWBob"); len(check.Warnings) != 1 || check.Warnings[0].Code != WarnLowReputation {
+		t.Errorf("CheckRecipient(bob) = %+v", check)
+	}
+	if check := mb.CheckRecipient("stranger"); !check.Quarantined || check.Contact != nil || check.Warnings[0].Code != WarnQuarantined {
+		t.Errorf("CheckRecipient(stranger) = %+v", check)
+	}
+
+	// 联系人随邮箱数据持久化
+	if err := mb.DeleteContact("12D3KooWBob"); err != nil {
+		t.Fatalf("DeleteContact() error = %v", err)
+	}
+	if err := mb.DeleteContact("12D3KooWBob"); !errors.Is(err, ErrContactNotFound) {
+		t.Errorf("expected ErrContactNotFound, got %v", err)
+	}
+	reloaded, _ := NewMailbox(config)
+	if err := reloaded.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() error = %v", err)
+	}
+	if c, err := reloaded.GetContact("12D3KooWMallory"); err != nil || c.Trust != TrustLow || len(reloaded.ListContacts()) != 2 {
+		t.Errorf("reloaded contact = %+v, %v", c, err)
+	}
+}
+
+func TestPublicInbox(t *testing.T) {
+	config := createTestConfig(t)
+	config.PublicInbox = true
+	config.PublicQuota = 1
+	config.PublicQuotaWindow = 24 * time.Hour
+	config.PublicMaxMessageSize = 16
+	config.MaxPublicSize = 10
+	mb, _ := NewMailbox(config)
+	mb.SetVerifyFunc(relayTestVerify)
+	received := make(chan *Message, 4)
+	mb.SetOnMessageReceived(func(msg *Message) { received <- msg })
+
+	newMsg := func(id, sender, content string) *Message {
+		msg := &Message{
+			ID:        id,
+			Sender:    sender,
+			Receiver:  config.NodeID,
+			Content:   []byte(content),
+			Timestamp: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		msg.Signature, _ = relayTestSign(sender)(mb.getSignData(msg))
+		return msg
+	}
+
+	// 未签名或签名者与发件人不符的消息被拒收，不占用发件人的配额
+	unsigned := newMsg("unsigned", "stranger", "hi")
+	unsigned.Signature = nil
+	if err := mb.ReceiveMessage(unsigned); !errors.Is(err, ErrUnsignedMessage) {
+		t.Errorf("expected ErrUnsignedMessage, got %v", err)
+	}
+	spoofed := newMsg("spoofed", "stranger", "hi")
+	spoofed.Signature, _ = relayTestSign("mallory")(mb.getSignData(spoofed))
+	if err := mb.ReceiveMessage(spoofed); err == nil {
+		t.Error("message signed by another node should be rejected")
+	}
+
+	// 陌生发件人进入公开文件夹，每个配额周期一条
+	if err := mb.ReceiveMessage(newMsg("pub-1", "stranger", "hi there")); err != nil {
+		t.Fatalf("first message from stranger should be accepted: %v", err)
+	}
+	if err := mb.ReceiveMessage(newMsg("pub-2", "stranger", "hello?")); !errors.Is(err, ErrPublicQuotaExceeded) {
+		t.Errorf("expected ErrPublicQuotaExceeded, got %v", err)
+	}
+	if err := mb.ReceiveMessage(newMsg("pub-3", "other", "this message is far too long")); !errors.Is(err, ErrPublicMessageTooLarge) {
+		t.Errorf("expected ErrPublicMessageTooLarge, got %v", err)
+	}
+	if mb.GetPublicCount() != 1 || mb.GetInboxCount() != 0 {
+		t.Errorf("public=%d inbox=%d, want 1 and 0", mb.GetPublicCount(), mb.GetInboxCount())
+	}
+	if list := mb.ListPublic(10, 0); len(list) != 1 || list[0].ID != "pub-1" {
+		t.Errorf("ListPublic() = %v", list)
+	}
+	if _, err := mb.GetMessageContent("pub-1"); err != nil {
+		t.Errorf("public message should be readable: %v", err)
+	}
+
+	// 回复后发件人成为已知，公开消息移入收件箱，后续消息不受配额限制
+	if _, err := mb.SendMessage("stranger", "Re: hi", []byte("welcome"), false); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if !mb.IsKnownSender("stranger") {
+		t.Error("sender should be known after reply")
+	}
+	if mb.GetPublicCount() != 0 || mb.GetInboxCount() != 1 {
+		t.Errorf("public=%d inbox=%d after promotion, want 0 and 1", mb.GetPublicCount(), mb.GetInboxCount())
+	}
+	if err := mb.ReceiveMessage(newMsg("known-1", "stranger", "thanks, here is the full spec")); err != nil {
+		t.Errorf("known sender should bypass public quotas: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if msg.ID != "known-1" {
+			t.Errorf("callback for %s, want only known-1", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected callback for known sender")
+	}
+
+	// 冒充已知发件人不能绕过公开收件箱
+	impostor := newMsg("impostor", "stranger", "i am stranger")
+	impostor.Signature, _ = relayTestSign("mallory")(mb.getSignData(impostor))
+	if err := mb.ReceiveMessage(impostor); err == nil || mb.GetInboxCount() != 2 {
+		t.Errorf("impostor of a known sender: err=%v inbox=%d", err, mb.GetInboxCount())
+	}
+
+	// 移出已知发件人后重新受配额限制
+	mb.RemoveKnownSender("stranger")
+	if err := mb.ReceiveMessage(newMsg("pub-4", "stranger", "again")); err != nil {
+		t.Errorf("removed sender should get a fresh quota: %v", err)
+	}
+	if got := mb.ListKnownSenders(); len(got) != 0 {
+		t.Errorf("ListKnownSenders() = %v, want empty", got)
+	}
+
+	// 已知发件人和配额记录随邮箱持久化
+	mb.AddKnownSender("friend")
+	if err := mb.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+	mb2, _ := NewMailbox(config)
+	if err := mb2.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() error = %v", err)
+	}
+	mb2.SetVerifyFunc(relayTestVerify)
+	if !mb2.IsKnownSender("friend") || mb2.GetPublicCount() != 1 {
+		t.Errorf("known=%v public=%d after reload", mb2.IsKnownSender("friend"), mb2.GetPublicCount())
+	}
+	if err := mb2.ReceiveMessage(newMsg("pub-5", "stranger", "third")); !errors.Is(err, ErrPublicQuotaExceeded) {
+		t.Errorf("quota should survive reload, got %v", err)
+	}
+}
+
+func TestStagedSend(t *testing.T) {
+	config := createTestConfig(t)
+	mb, _ := NewMailbox(config)
+	var delivered []string
+	mb.SetDeliverFunc(func(receiver string, msg *Message) error {
+		delivered = append(delivered, msg.ID)
+		return nil
+	})
+
+	if _, err := mb.StageMessage("receiver-001", "Oops", []byte("wrong peer"), false, 0); err != ErrInvalidDelay {
+		t.Errorf("expected ErrInvalidDelay, got %v", err)
+	}
+
+	wrong, err := mb.StageMessage("receiver-001", "Oops", []byte("wrong peer"), false, time.Minute)
+	if err != nil {
+		t.Fatalf("StageMessage failed: %v", err)
+	}
+	right, _ := mb.StageMessage("receiver-002", "Report", []byte("hello"), false, 2*time.Minute)
+	if wrong.Status != StatusStaged || len(delivered) != 0 {
+		t.Fatal("staged message should not be delivered before the window ends")
+	}
+	if staged := mb.ListStaged(); len(staged) != 2 || staged[0].ID != wrong.ID {
+		t.Fatalf("expected 2 staged messages ordered by delivery time, got %d", len(staged))
+	}
+
+	if _, err := mb.CancelStaged(wrong.ID); err != nil {
+		t.Fatalf("CancelStaged failed: %v", err)
+	}
+	if _, err := mb.CancelStaged(wrong.ID); err != ErrNotStaged {
+		t.Errorf("expected ErrNotStaged, got %v", err)
+	}
+
+	// 重启后暂存消息仍在，窗口结束后投递
+	reloaded, _ := NewMailbox(config)
+	reloaded.SetDeliverFunc(mb.deliverFunc)
+	if err := reloaded.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk failed: %v", err)
+	}
+	if staged := reloaded.ListStaged(); len(staged) != 1 || staged[0].ID != right.ID {
+		t.Fatalf("staged message should survive restart, got %d", len(staged))
+	}
+	if n := reloaded.dispatchStaged(time.Now().Add(time.Minute)); n != 0 {
+		t.Errorf("nothing should be due yet, dispatched %d", n)
+	}
+	if n := reloaded.dispatchStaged(time.Now().Add(3 * time.Minute)); n != 1 {
+		t.Fatalf("expected 1 dispatched message, got %d", n)
+	}
+	if len(delivered) != 1 || delivered[0] != right.ID {
+		t.Errorf("cancelled message must never be delivered, got %v", delivered)
+	}
+	msg, _ := reloaded.GetMessage(right.ID)
+	if msg.Status != StatusDelivered {
+		t.Errorf("expected delivered, got %s", msg.Status)
+	}
+	if _, err := reloaded.CancelStaged(right.ID); err != ErrNotStaged {
+		t.Errorf("delivered message cannot be cancelled, got %v", err)
+	}
+	if cancelled, _ := reloaded.GetMessage(wrong.ID); cancelled.Status != StatusCancelled {
+		t.Errorf("cancelled message should stay in outbox as cancelled, got %s", cancelled.Status)
+	}
+}
+
+// newTestE2E 创建测试用的端到端加密，ids 模拟节点ID与公钥的对应关系
+func newTestE2E(t *testing.T, nodeID string, ids map[string]ed25519.PublicKey) *E2E {
+	t.Helper()
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	ids[nodeID] = pub
+	e, err := NewE2E(&E2EConfig{
+		NodeID:     nodeID,
+		PrivateKey: priv,
+		VerifyNodeID: func(id string, key ed25519.PublicKey) bool {
+			return bytes.Equal(ids[id], key)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewE2E failed: %v", err)
+	}
+	return e
+}
+
+func TestE2EEncryptedMail(t *testing.T) {
+	ids := make(map[string]ed25519.PublicKey)
+	aliceKeys := newTestE2E(t, "alice", ids)
+	bobKeys := newTestE2E(t, "bob", ids)
+	// 收件人公钥通过握手交换
+	aliceKeys.config.Handshake = func(nodeID string) (*KeyAnnouncement, error) {
+		local, remote := net.Pipe()
+		defer local.Close()
+		go func() {
+			defer remote.Close()
+			bobKeys.HandleKeyExchange(remote)
+		}()
+		return aliceKeys.ExchangeKeys(local)
+	}
+
+	aliceConfig := createTestConfig(t)
+	aliceConfig.NodeID = "alice"
+	alice, _ := NewMailbox(aliceConfig)
+	alice.SetEncryptFunc(aliceKeys.Encrypt)
+	alice.SetDecryptFunc(aliceKeys.Decrypt)
+	bobConfig := createTestConfig(t)
+	bobConfig.NodeID = "bob"
+	bob, _ := NewMailbox(bobConfig)
+	bob.SetEncryptFunc(bobKeys.Encrypt)
+	bob.SetDecryptFunc(bobKeys.Decrypt)
+
+	// 经中继转发：中继只能看到密文
+	plaintext := []byte("只有 bob 能读到这封邮件")
+	var relayed []byte
+	alice.SetDeliverFunc(func(receiver string, msg *Message) error {
+		relayed = append([]byte(nil), msg.Content...)
+		copied := *msg
+		return bob.ReceiveMessage(&copied)
+	})
+	msg, err := alice.SendMessage("bob", "secret", plaintext, true)
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if !msg.Encrypted || bytes.Contains(relayed, plaintext) {
+		t.Fatal("relayed content should be ciphertext")
+	}
+
+	content, err := bob.GetMessageContent(msg.ID)
+	if err != nil || !bytes.Equal(content, plaintext) {
+		t.Fatalf("recipient should decrypt on read, got %q, %v", content, err)
+	}
+	if _, err := alice.GetMessageContent(msg.ID); !errors.Is(err, ErrEncryptedForRecipient) {
+		t.Errorf("sender cannot read encrypted outbox copy, got %v", err)
+	}
+
+	// 握手是双向的：bob 无需再握手即可回信
+	reply, err := bob.SendMessage("alice", "Re: secret", []byte("收到"), true)
+	if err != nil {
+		t.Fatalf("reply should use the key learned during handshake: %v", err)
+	}
+	alice.ReceiveMessage(reply)
+	if content, err := alice.GetMessageContent(reply.ID); err != nil || string(content) != "收到" {
+		t.Errorf("reply decrypt failed: %q, %v", content, err)
+	}
+
+	// 没有收件人公钥时拒绝发送，不会退化为明文
+	if _, err := bob.SendMessage("carol", "hi", []byte("hello"), true); !errors.Is(err, ErrNoRecipientKey) {
+		t.Errorf("expected ErrNoRecipientKey, got %v", err)
+	}
+	if n := bob.GetOutboxCount(); n != 1 {
+		t.Errorf("failed send must not reach the outbox, outbox = %d", n)
+	}
+}
+
+func TestE2EKeyAnnouncement(t *testing.T) {
+	ids := make(map[string]ed25519.PublicKey)
+	alice := newTestE2E(t, "alice", ids)
+	mallory := newTestE2E(t, "mallory", ids)
+
+	if err := alice.AddPeerKey(mallory.Announcement()); err != nil {
+		t.Fatalf("valid announcement rejected: %v", err)
+	}
+
+	// 冒用他人节点ID
+	forged := *mallory.Announcement()
+	forged.NodeID = "bob"
+	if err := alice.AddPeerKey(&forged); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("forged node ID should be rejected, got %v", err)
+	}
+	// 篡改公钥
+	tampered := *mallory.Announcement()
+	tampered.PublicKey = ids["alice"]
+	if err := alice.AddPeerKey(&tampered); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("tampered key should be rejected, got %v", err)
+	}
+
+	// 握手返回的通告不是目标节点时拒绝
+	alice.config.Handshake = func(string) (*KeyAnnouncement, error) { return mallory.Announcement(), nil }
+	if _, err := alice.PeerKey("bob"); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("handshake answered by another node should fail, got %v", err)
+	}
+	var reply bytes.Buffer
+	if err := alice.HandleKeyExchange(struct {
+		io.Reader
+		io.Writer
+	}{strings.NewReader("not json"), &reply}); !errors.Is(err, ErrInvalidAnnouncement) || reply.Len() != 0 {
+		t.Errorf("malformed handshake should fail without reply, got %v", err)
+	}
+}
+
+func TestE2EKeyExchangeVersions(t *testing.T) {
+	ids := make(map[string]ed25519.PublicKey)
+	alice := newTestE2E(t, "alice", ids)
+	bob := newTestE2E(t, "bob", ids)
+
+	exchange := func(initiate func(io.ReadWriter) (*KeyAnnouncement, error), handle func(io.ReadWriter) error) (*KeyAnnouncement, error) {
+		local, remote := net.Pipe()
+		defer local.Close()
+		go func() {
+			defer remote.Close()
+			handle(remote)
+		}()
+		return initiate(local)
+	}
+
+	// v2 通告带签发时间
+	ann, err := exchange(alice.ExchangeKeys, bob.HandleKeyExchange)
+	if err != nil || ann.Version != 2 || ann.IssuedAt == 0 {
+		t.Fatalf("v2 exchange: %+v, %v", ann, err)
+	}
+	// 兼容模式：与未升级节点交换的是没有版本字段的 v1 通告
+	ann, err = exchange(alice.ExchangeKeysV1, bob.HandleKeyExchangeV1)
+	if err != nil || ann.Version != 0 || ann.IssuedAt != 0 {
+		t.Fatalf("v1 exchange: %+v, %v", ann, err)
+	}
+	if _, err := alice.PeerKey("bob"); err != nil {
+		t.Errorf("key learned in compatibility mode not cached: %v", err)
+	}
+	// 协议版本与通告版本不一致时拒绝
+	if _, err := exchange(alice.ExchangeKeysV1, bob.HandleKeyExchange); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("v1 announcement on v2 protocol: got %v", err)
+	}
+
+	// 超出 MaxAnnouncementAge 的 v2 通告不能重放
+	stale := bob.Announcement()
+	alice.now = func() time.Time { return time.Now().Add(MaxAnnouncementAge + time.Minute) }
+	if err := alice.AddPeerKey(stale); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("stale announcement: got %v", err)
+	}
+	// 把 v2 通告改成 v1 会使签名失效
+	downgraded := *stale
+	downgraded.Version, downgraded.IssuedAt = 0, 0
+	if err := alice.AddPeerKey(&downgraded); !errors.Is(err, ErrInvalidAnnouncement) {
+		t.Errorf("downgraded announcement: got %v", err)
+	}
+}
+
+// 测试辅助函数 - 按节点区分的签名，回执和邮件的签名者可被校验
+func relayTestSign(nodeID string) SignFunc {
+	return func(data []byte) ([]byte, error) {
+		sum := sha256.Sum256(data)
+		return append([]byte(nodeID+":"), sum[:]...), nil
+	}
+}
+
+func relayTestVerify(signer string, data, signature []byte) (bool, error) {
+	expected, _ := relayTestSign(signer)(data)
+	return bytes.Equal(expected, signature), nil
+}
+
+func newRelayTestMailbox(t *testing.T, nodeID string, relay bool) *Mailbox {
+	t.Helper()
+	config := createTestConfig(t)
+	config.NodeID = nodeID
+	config.Relay = relay
+	config.RelayTTL = time.Hour
+	mb, err := NewMailbox(config)
+	if err != nil {
+		t.Fatalf("Failed to create mailbox: %v", err)
+	}
+	mb.SetSignFunc(relayTestSign(nodeID))
+	mb.SetVerifyFunc(relayTestVerify)
+	mb.SetEncryptFunc(mockEncryptFunc)
+	mb.SetDecryptFunc(mockDecryptFunc)
+	return mb
+}
+
+// handoff 模拟 RelayProtocol：把邮件副本交给中继
+func handoff(relay *Mailbox, relayID string) RelayFunc {
+	return func(msg *Message) (string, error) {
+		copied := *msg
+		result := relay.HandleRelayEnvelope(&Envelope{Messages: []*Message{&copied}}, msg.Sender)
+		if len(result.Accepted) != 1 {
+			return "", errors.New(result.Rejected[msg.ID])
+		}
+		return relayID, nil
+	}
+}
+
+func TestRelayStoreAndForward(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	relay := newRelayTestMailbox(t, "relay", true)
+	bob := newRelayTestMailbox(t, "bob", false)
+
+	// bob 不在线：直接投递失败，交给中继
+	alice.SetDeliverFunc(func(string, *Message) error { return errors.New("offline") })
+	alice.SetRelayFunc(handoff(relay, "relay"))
+	msg, err := alice.SendMessage("bob", "hi", []byte("store and forward"), true)
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if msg.Status != StatusRelayed || msg.Relay != "relay" {
+		t.Fatalf("message should be relayed, got status=%s relay=%q", msg.Status, msg.Relay)
+	}
+	if status := relay.RelayStatus("bob"); status.Messages != 1 || len(status.Pending) != 1 || status.Accepted != 1 {
+		t.Fatalf("relay status = %+v", status)
+	}
+
+	// bob 重新连接：中继推送，bob 签发回执
+	batch := relay.RelayBatch("bob", 10)
+	if batch == nil || len(batch.Messages) != 1 {
+		t.Fatalf("relay batch = %+v", batch)
+	}
+	result := bob.HandleEnvelope(batch, "relay")
+	if len(result.Receipts) != 1 || result.Receipts[0].Relay != "relay" {
+		t.Fatalf("delivery result = %+v", result)
+	}
+	if content, err := bob.GetMessageContent(msg.ID); err != nil || string(content) != "store and forward" {
+		t.Fatalf("recipient content = %q, %v", content, err)
+	}
+	if notify := relay.CompleteRelay("bob", batch, result); len(notify) != 1 || notify[0] != "alice" {
+		t.Fatalf("senders to notify = %v", notify)
+	}
+	if relay.RelayBatch("bob", 10) != nil {
+		t.Error("delivered message should be removed from the relay")
+	}
+
+	// 回执丢失后重复推送仍有回执
+	if again := bob.HandleEnvelope(batch, "relay"); len(again.Receipts) != 1 || len(again.Rejected) != 0 {
+		t.Errorf("duplicate delivery result = %+v", again)
+	}
+
+	// 中继把回执转交给 alice
+	back := relay.RelayBatch("alice", 10)
+	if back == nil || len(back.Receipts) != 1 || len(back.Messages) != 0 {
+		t.Fatalf("receipt batch = %+v", back)
+	}
+	alice.HandleEnvelope(back, "relay")
+	relay.CompleteRelay("alice", back, &DeliveryResult{})
+	if ids := relay.RelayReceivers(); len(ids) != 0 {
+		t.Errorf("relay should be empty, has %v", ids)
+	}
+	sent, _ := alice.GetMessage(msg.ID)
+	if sent.Status != StatusDelivered || sent.DeliveredAt == nil {
+		t.Errorf("outbox message after receipt: status=%s delivered_at=%v", sent.Status, sent.DeliveredAt)
+	}
+	if status := relay.RelayStatus(""); status.Delivered != 1 || status.PendingReceipts != 0 {
+		t.Errorf("relay counters = %+v", status)
+	}
+
+	// 冒充收件人的回执被拒绝
+	forged := *back.Receipts[0]
+	forged.Signature, _ = relayTestSign("mallory")(forged.signData())
+	if err := alice.HandleReceipt(&forged); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("forged receipt: got %v", err)
+	}
+}
+
+func TestRelayAcceptLimits(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	relay := newRelayTestMailbox(t, "relay", true)
+	relay.config.MaxRelayPerReceiver = 2
+	relay.config.MaxRelayPerSender = 3
+
+	sendTo := func(receiver string, encrypt bool) *Message {
+		msg, err := alice.SendMessage(receiver, "hi", []byte("hello"), encrypt)
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		copied := *msg
+		return &copied
+	}
+	send := func(encrypt bool) *Message { return sendTo("bob", encrypt) }
+
+	first := send(true)
+	if err := alice.AcceptRelay(first); !errors.Is(err, ErrRelayDisabled) {
+		t.Errorf("non-relay node: got %v", err)
+	}
+	if err := relay.AcceptRelay(send(false)); !errors.Is(err, ErrRelayPlaintext) {
+		t.Errorf("plaintext: got %v", err)
+	}
+	if err := relay.AcceptRelay(first); err != nil {
+		t.Fatalf("AcceptRelay failed: %v", err)
+	}
+	if err := relay.AcceptRelay(first); err != nil {
+		t.Errorf("repeated handoff should be accepted: %v", err)
+	}
+	if err := relay.AcceptRelay(send(true)); err != nil {
+		t.Fatalf("AcceptRelay failed: %v", err)
+	}
+	if err := relay.AcceptRelay(send(true)); !errors.Is(err, ErrRelayFull) {
+		t.Errorf("per-receiver cap: got %v", err)
+	}
+	
+	// 单个发件人不能占满中继
+	if err := relay.AcceptRelay(sendTo("carol", true)); err != nil {
+		t.Fatalf("AcceptRelay failed: %v", err)
+	}
+	if err := relay.AcceptRelay(sendTo("dave", true)); !errors.Is(err, ErrRelayFull) {
+		t.Errorf("per-sender cap: got %v", err)
+	}
+	
+	// 签名缺失或与内容不符的邮件被拒收，也不能冒充已暂存的邮件
+	unsigned := send(true)
+	unsigned.Signature = nil
+	if err := relay.AcceptRelay(unsigned); !errors.Is(err, ErrRelayUnsigned) {
+		t.Errorf("unsigned: got %v", err)
+	}
+	forged := *first
+	forged.Content = []byte("tampered")
+	if err := relay.AcceptRelay(&forged); !errors.Is(err, ErrRelayUnsigned) {
+		t.Errorf("forged copy of a queued message: got %v", err)
+	}
+
+	// 只接受发件人本人交来的邮件
+	result := relay.HandleRelayEnvelope(&Envelope{Messages: []*Message{send(true)}}, "mallory")
+	if len(result.Accepted) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("handoff by third party = %+v", result)
+	}
+	if status := relay.RelayStatus("bob"); status.Messages != 3 || status.Rejected != 3 {
+		t.Errorf("relay status = %+v", status)
+	}
+}
+
+func TestPeerAdmit(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	bob := newRelayTestMailbox(t, "bob", false)
+	relay := newRelayTestMailbox(t, "relay", true)
+
+	// 每个投递节点只放行一封
+	counts := map[string]int{}
+	admit := func(peerID string) error {
+		counts[peerID]++
+		if counts[peerID] > 1 {
+			return errors.New("quota exceeded")
+		}
+		return nil
+	}
+	bob.SetPeerAdmitFunc(admit)
+	relay.SetPeerAdmitFunc(admit)
+
+	send := func() *Message {
+		msg, err := alice.SendMessage("bob", "hi", []byte("hello"), true)
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		copied := *msg
+		return &copied
+	}
+	result := bob.HandleEnvelope(&Envelope{Messages: []*Message{send(), send()}}, "alice")
+	if len(result.Receipts) != 1 || len(result.Rejected) != 1 {
+		t.Errorf("direct delivery result = %+v", result)
+	}
+	if counts["alice"] != 2 {
+		t.Errorf("expected 2 checks for alice, got %d", counts["alice"])
+	}
+
+	counts = map[string]int{}
+	result = relay.HandleRelayEnvelope(&Envelope{Messages: []*Message{send(), send()}}, "alice")
+	if len(result.Accepted) != 1 || len(result.Rejected) != 1 {
+		t.Errorf("relay handoff result = %+v", result)
+	}
+}
+
+func TestDeliverSenderMismatch(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	bob := newRelayTestMailbox(t, "bob", false)
+
+	send := func() *Message {
+		msg, err := alice.SendMessage("bob", "hi", []byte("hello"), true)
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		copied := *msg
+		return &copied
+	}
+
+	// 第三方直接投递未签名的邮件，不能冒充发件人
+	unsigned := send()
+	unsigned.Signature = nil
+	result := bob.HandleEnvelope(&Envelope{Messages: []*Message{unsigned}}, "mallory")
+	if len(result.Receipts) != 0 || !strings.Contains(result.Rejected[unsigned.ID], ErrSenderMismatch.Error()) {
+		t.Errorf("unsigned message delivered by a third party = %+v", result)
+	}
+
+	// 转交的邮件须由发件人签名
+	forged := send()
+	forged.Signature, _ = relayTestSign("mallory")(bob.getSignData(forged))
+	if result := bob.HandleEnvelope(&Envelope{Messages: []*Message{forged}}, "mallory"); len(result.Receipts) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("message signed by the deliverer = %+v", result)
+	}
+
+	// 中继转交发件人签名的邮件和发件人直接投递都被接收
+	if result := bob.HandleEnvelope(&Envelope{Messages: []*Message{send()}}, "relay"); len(result.Receipts) != 1 {
+		t.Errorf("signed relayed message = %+v", result)
+	}
+	if result := bob.HandleEnvelope(&Envelope{Messages: []*Message{unsigned}}, "alice"); len(result.Receipts) != 1 {
+		t.Errorf("direct delivery by the sender = %+v", result)
+	}
+	if bob.GetInboxCount() != 2 {
+		t.Errorf("inbox = %d, want 2", bob.GetInboxCount())
+	}
+}
+
+func TestRelayTTL(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	relay := newRelayTestMailbox(t, "relay", true)
+	msg, _ := alice.SendMessage("bob", "hi", []byte("hello"), true)
+	copied := *msg
+	if err := relay.AcceptRelay(&copied); err != nil {
+		t.Fatalf("AcceptRelay failed: %v", err)
+	}
+
+	// 暂存时间不超过 RelayTTL，即使邮件本身还没过期
+	relay.mu.Lock()
+	relay.relayStored[msg.ID] = time.Now().Add(-2 * time.Hour)
+	relay.mu.Unlock()
+	if relay.RelayBatch("bob", 10) != nil {
+		t.Error("message past RelayTTL should not be pushed")
+	}
+	relay.cleanup()
+	if status := relay.RelayStatus(""); status.Messages != 0 || status.Expired != 1 {
+		t.Errorf("relay status after cleanup = %+v", status)
+	}
+}
+
+func TestRetryPending(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	online := false
+	var delivered []string
+	alice.SetDeliverFunc(func(receiver string, msg *Message) error {
+		if !online {
+			return errors.New("offline")
+		}
+		delivered = append(delivered, msg.ID)
+		return nil
+	})
+	msg, _ := alice.SendMessage("bob", "hi", []byte("hello"), true)
+	if msg.Status != StatusPending {
+		t.Fatalf("without a relay the message should stay pending, got %s", msg.Status)
+	}
+	if n := alice.RetryPending("bob"); n != 0 {
+		t.Errorf("retry while offline sent %d", n)
+	}
+
+	online = true
+	if n := alice.RetryPending("bob"); n != 1 || len(delivered) != 1 {
+		t.Fatalf("retry after reconnect sent %d", n)
+	}
+	if msg.Status != StatusDelivered || msg.DeliveredAt == nil {
+		t.Errorf("status after retry = %s", msg.Status)
+	}
+	if n := alice.RetryPending("bob"); n != 0 {
+		t.Errorf("delivered message retried again")
+	}
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	go func() {
+		defer remote.Close()
+		ServeEnvelope(remote, func(env *Envelope) *DeliveryResult {
+			result := &DeliveryResult{}
+			for _, msg := range env.Messages {
+				result.Accepted = append(result.Accepted, msg.ID)
+			}
+			return result
+		})
+	}()
+	result, err := SendEnvelope(local, &Envelope{Messages: []*Message{{ID: "m1"}, {ID: "m2"}}})
+	if err != nil {
+		t.Fatalf("SendEnvelope failed: %v", err)
+	}
+	if len(result.Accepted) != 2 || result.Accepted[1] != "m2" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// routedDelivery 按 cmd/node 的方式接入路由表：直连慢时先交给中继，中继按期望耗时排序尝试
+func routedDelivery(mb *Mailbox, online map[string]bool, relays []string, latency map[string]time.Duration) {
+	routes := mb.Routes()
+	mb.SetDeliverFunc(func(receiver string, msg *Message) error {
+		var handoff []string
+		if msg.Encrypted {
+			handoff = relays
+		}
+		if routes.DirectSlow(msg.ID, receiver, handoff) {
+			return ErrSlowPath
+		}
+		var err error
+		if !online[receiver] {
+			err = errors.New("offline")
+		}
+		routes.Attempt(msg.ID, receiver, receiver, PathDirect, latency[receiver], err)
+		return err
+	})
+	mb.SetRelayFunc(func(msg *Message) (string, error) {
+		for _, c := range routes.Rank(relays, PathRelay) {
+			var err error
+			if !online[c.Via] {
+				err = errors.New("relay offline")
+			}
+			routes.Attempt(msg.ID, msg.Receiver, c.Via, PathRelay, latency[c.Via], err)
+			if err == nil {
+				return c.Via, nil
+			}
+		}
+		return "", errors.New("no relay accepted the message")
+	})
+}
+
+func TestDeliveryRouting(t *testing.T) {
+	alice := newRelayTestMailbox(t, "alice", false)
+	alice.routes = NewRouteTable(0, 2*time.Second)
+	online := map[string]bool{"relay-slow": true, "relay-fast": true}
+	latency := map[string]time.Duration{"relay-slow": 400 * time.Millisecond, "relay-fast": 40 * time.Millisecond}
+	alice.Routes().SetLatencyFunc(func(id string) (time.Duration, bool) {
+		d, ok := latency[id]
+		return d, ok
+	})
+	routedDelivery(alice, online, []string{"relay-slow", "relay-fast"}, latency)
+
+	// 收件人不在线：交给期望耗时最低的中继
+	msg, _ := alice.SendMessage("bob", "hi", []byte("offline"), true)
+	if msg.Status != StatusRelayed || msg.Relay != "relay-fast" {
+		t.Fatalf("expected relay via relay-fast, got status=%s relay=%q", msg.Status, msg.Relay)
+	}
+	decisions := alice.Routes().Decisions("bob", msg.ID, 0)
+	if len(decisions) != 1 {
+		t.Fatalf("expected 1 decision, got %d", len(decisions))
+	}
+	d := decisions[0]
+	if d.DeliveredVia != "relay-fast" || d.Path != PathRelay || len(d.Attempts) != 2 || d.Attempts[0].Error == "" {
+		t.Errorf("decision = %+v", d)
+	}
+	if d.Candidates[1].Via != "relay-fast" || d.Candidates[2].Via != "relay-slow" {
+		t.Errorf("relay candidates should be ordered by cost, got %+v", d.Candidates)
+	}
+
+	// 收件人在线但直连很慢：先交给中继；中继都失败时再直连
+	alice.Routes().Attempt("earlier", "bob", "bob", PathDirect, 5*time.Second, nil)
+	alice.Routes().Attempt("earlier", "bob", "bob", PathDirect, 5*time.Second, nil)
+	alice.Routes().Finish("earlier")
+	online["bob"] = true
+	online["relay-fast"], online["relay-slow"] = false, false
+	msg, _ = alice.SendMessage("bob", "hi", []byte("slow"), true)
+	if msg.Status != StatusDelivered {
+		t.Fatalf("expected direct delivery after relays failed, got %s", msg.Status)
+	}
+	d = alice.Routes().Decisions("", "", 1)[0]
+	if !d.DirectSlow || d.DeliveredVia != "bob" || d.Path != PathDirect || len(d.Attempts) != 3 {
+		t.Errorf("slow path decision = %+v", d)
+	}
+
+	// 未加密的邮件不经中继，直连照常尝试
+	msg, _ = alice.SendMessage("bob", "hi", []byte("plain"), false)
+	if d := alice.Routes().Decisions("", msg.ID, 0); msg.Status != StatusDelivered || len(d) != 1 || d[0].DirectSlow {
+		t.Errorf("plain message: status=%s decisions=%+v", msg.Status, d)
+	}
+
+	for _, s := range alice.Routes().PathStats() {
+		if s.NodeID == "relay-fast" && (s.Successes != 1 || s.Failures != 1) {
+			t.Errorf("relay-fast stats = %+v", s)
+		}
+	}
+}
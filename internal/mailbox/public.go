@@ -0,0 +1,161 @@
+package mailbox
+
+import (
+	"sort"
+	"time"
+)
+
+// 公开收件箱
+//
+// 启用 PublicInbox 后，只有已知发件人的消息进入收件箱；陌生发件人的消息进入
+// 单独的公开文件夹，每个陌生发件人在配额周期内只能投递 PublicQuota 条，内容
+// 不超过 PublicMaxMessageSize。公开文件夹的消息不触发新消息回调，由用户主动
+// 查看。本节点向某个节点发信（包括回复公开文件夹中的消息）后，对方自动成为
+// 已知发件人，其在公开文件夹中的消息移入收件箱。
+
+// IsKnownSender 是否为已知发件人
+func (m *Mailbox) IsKnownSender(nodeID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isKnownLocked(nodeID)
+}
+
+// AddKnownSender 手动将节点加入已知发件人
+func (m *Mailbox) AddKnownSender(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.promoteLocked(nodeID, time.Now())
+}
+
+// RemoveKnownSender 将节点移出已知发件人，之后的消息重新进入公开收件箱
+func (m *Mailbox) RemoveKnownSender(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.known, nodeID)
+	m.dirty.Mark("known", nodeID)
+}
+
+// ListKnownSenders 列出已知发件人
+func (m *Mailbox) ListKnownSenders() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	senders := make([]string, 0, len(m.known))
+	for id := range m.known {
+		senders = append(senders, id)
+	}
+	sort.Strings(senders)
+	return senders
+}
+
+// ListPublic 列出公开文件夹消息
+func (m *Mailbox) ListPublic(limit, offset int) []*MessageSummary {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return listSummaries(m.public, limit, offset)
+}
+
+// GetPublicCount 获取公开文件夹消息总数
+func (m *Mailbox) GetPublicCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.public)
+}
+
+// isKnownLocked 自己和已知发件人视为已知（调用方需持有锁）
+func (m *Mailbox) isKnownLocked(nodeID string) bool {
+	if nodeID == m.config.NodeID {
+		return true
+	}
+	_, ok := m.known[nodeID]
+	return ok
+}
+
+// checkPublicQuotaLocked 检查消息大小和发件人配额（调用方需持有锁）
+func (m *Mailbox) checkPublicQuotaLocked(msg *Message, now time.Time) error {
+	if m.config.PublicMaxMessageSize > 0 && len(msg.Content) > m.config.PublicMaxMessageSize {
+		return ErrPublicMessageTooLarge
+	}
+	if m.config.PublicQuota <= 0 {
+		return ErrPublicQuotaExceeded
+	}
+	count := 0
+	for _, at := range m.publicSeen[msg.Sender] {
+		if now.Sub(at) < m.config.PublicQuotaWindow {
+			count++
+		}
+	}
+	if count >= m.config.PublicQuota {
+		return ErrPublicQuotaExceeded
+	}
+	return nil
+}
+
+// storePublicLocked 存入公开文件夹并计入配额，满时淘汰最旧的（调用方需持有锁）
+func (m *Mailbox) storePublicLocked(msg *Message, now time.Time) {
+	if m.config.MaxPublicSize > 0 && len(m.public) >= m.config.MaxPublicSize {
+		var oldestID string
+		var oldest time.Time
+		for id, old := range m.public {
+			if oldestID == "" || old.Timestamp.Before(oldest) {
+				oldestID, oldest = id, old.Timestamp
+			}
+		}
+		delete(m.public, oldestID)
+		m.markMessageLocked(oldestID)
+	}
+
+	msg.Status = StatusDelivered
+	m.public[msg.ID] = msg
+	m.publicSeen[msg.Sender] = append(m.publicSeen[msg.Sender], now)
+	m.markMessageLocked(msg.ID)
+	m.dirty.Mark("public_seen", msg.Sender)
+}
+
+// promoteLocked 将节点标记为已知，并把其公开文件夹中的消息移入收件箱（调用方需持有锁）
+func (m *Mailbox) promoteLocked(nodeID string, at time.Time) {
+	if nodeID == "" || nodeID == m.config.NodeID {
+		return
+	}
+	if _, ok := m.known[nodeID]; !ok {
+		m.known[nodeID] = at
+		m.dirty.Mark("known", nodeID)
+	}
+	if _, ok := m.publicSeen[nodeID]; ok {
+		delete(m.publicSeen, nodeID)
+		m.dirty.Mark("public_seen", nodeID)
+	}
+
+	for id, msg := range m.public {
+		if msg.Sender != nodeID {
+			continue
+		}
+		delete(m.public, id)
+		if len(m.inbox) >= m.config.MaxInboxSize {
+			m.removeOldestInbox()
+		}
+		m.inbox[id] = msg
+		m.markMessageLocked(id)
+	}
+}
+
+// prunePublicSeenLocked 清理超出配额周期的投递记录（调用方需持有锁）
+func (m *Mailbox) prunePublicSeenLocked(now time.Time) {
+	for sender, times := range m.publicSeen {
+		kept := times[:0]
+		for _, at := range times {
+			if now.Sub(at) < m.config.PublicQuotaWindow {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == len(times) {
+			continue
+		}
+		m.dirty.Mark("public_seen", sender)
+		if len(kept) == 0 {
+			delete(m.publicSeen, sender)
+		} else {
+			m.publicSeen[sender] = kept
+		}
+	}
+}
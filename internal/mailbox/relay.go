@@ -0,0 +1,561 @@
+package mailbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 离线中继（存储转发）
+//
+// 发件人经 DeliverProtocol 直接投递失败（收件人不在线）时，把加密邮件经 RelayProtocol
+// 交给在线的中继节点暂存。中继只接受加密邮件，暂存不超过 RelayTTL（也不超过邮件自身的
+// 过期时间）。收件人重新连接后，中继经 DeliverProtocol 推送暂存的邮件，收件人对每封
+// 收到的邮件回复签名的送达回执；中继删除已送达的邮件，把回执转交给原发件人，发件人
+// 据此把发件箱中的邮件标记为已送达。
+
+// 邮件投递协议ID（使用时按网络命名空间加前缀）
+const (
+	DeliverProtocol = "/daan/mailbox/deliver/1.0.0" // 投递邮件和送达回执
+	RelayProtocol   = "/daan/mailbox/relay/1.0.0"   // 把收件人离线的邮件交给中继暂存
+)
+
+// maxEnvelopeSize 投递协议单帧的最大长度
+const maxEnvelopeSize = 8 << 20
+
+// receiptLabel 送达回执签名的域分隔标签
+const receiptLabel = "daan-mailbox-receipt"
+
+var (
+	ErrRelayDisabled  = errors.New("relay mode is not enabled")
+	ErrRelayPlaintext = errors.New("relay only accepts encrypted messages")
+	ErrRelayFull      = errors.New("relay storage is full")
+	ErrRelayUnsigned  = errors.New("relay only accepts messages signed by the sender")
+	ErrInvalidReceipt = errors.New("invalid delivery receipt")
+	ErrSenderMismatch = errors.New("message was not delivered by its sender")
+)
+
+// RelayFunc 把收件人不在线的邮件交给中继暂存，返回接收的中继节点ID
+type RelayFunc func(msg *Message) (relay string, err error)
+
+// Receipt 送达回执：收件人签名确认已收到邮件
+type Receipt struct {
+	MessageID   string    `json:"message_id"`
+	Sender      string    `json:"sender"`          // 原发件人
+	Receiver    string    `json:"receiver"`        // 收件人（签名者）
+	Relay       string    `json:"relay,omitempty"` // 经中继投递时的中继节点
+	DeliveredAt time.Time `json:"delivered_at"`
+	Signature   []byte    `json:"signature"`
+}
+
+func (r *Receipt) signData() []byte {
+	return []byte(receiptLabel + "|" + r.MessageID + "|" + r.Sender + "|" + r.Receiver + "|" +
+		r.Relay + "|" + strconv.FormatInt(r.DeliveredAt.UnixNano(), 10))
+}
+
+// Envelope 投递协议的请求帧：邮件和转交的送达回执
+type Envelope struct {
+	Messages []*Message `json:"messages,omitempty"`
+	Receipts []*Receipt `json:"receipts,omitempty"`
+}
+
+// DeliveryResult 投递协议的应答帧
+type DeliveryResult struct {
+	Receipts []*Receipt        `json:"receipts,omitempty"` // 收件人对收到的邮件签发的回执
+	Accepted []string          `json:"accepted,omitempty"` // 中继接收暂存的邮件ID
+	Rejected map[string]string `json:"rejected,omitempty"` // 被拒收的邮件ID -> 原因
+}
+
+func (r *DeliveryResult) reject(id string, err error) {
+	if r.Rejected == nil {
+		r.Rejected = make(map[string]string)
+	}
+	r.Rejected[id] = err.Error()
+}
+
+// SendEnvelope 作为发起方发送一帧并读取应答
+func SendEnvelope(rw io.ReadWriter, env *Envelope) (*DeliveryResult, error) {
+	if err := json.NewEncoder(rw).Encode(env); err != nil {
+		return nil, err
+	}
+	result := new(DeliveryResult)
+	if err := json.NewDecoder(io.LimitReader(rw, maxEnvelopeSize)).Decode(result); err != nil {
+		return nil, fmt.Errorf("read delivery result: %w", err)
+	}
+	return result, nil
+}
+
+// ServeEnvelope 作为响应方读取一帧，交给 handle 处理后回复应答
+func ServeEnvelope(rw io.ReadWriter, handle func(env *Envelope) *DeliveryResult) error {
+	env := new(Envelope)
+	if err := json.NewDecoder(io.LimitReader(rw, maxEnvelopeSize)).Decode(env); err != nil {
+		return fmt.Errorf("read envelope: %w", err)
+	}
+	return json.NewEncoder(rw).Encode(handle(env))
+}
+
+// SetRelayFunc 设置中继暂存函数：在线投递失败的加密邮件交给中继
+func (m *Mailbox) SetRelayFunc(fn RelayFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.relayFunc = fn
+}
+
+// IsRelay 是否以中继模式运行
+func (m *Mailbox) IsRelay() bool {
+	return m.config.Relay
+}
+
+// HandleEnvelope 处理经 DeliverProtocol 收到的帧：发给本节点的邮件存入收件箱并签发回执，
+// 转交来的回执更新发件箱。from 为对端节点，直接投递时是发件人，经中继投递时是中继。
+func (m *Mailbox) HandleEnvelope(env *Envelope, from string) *DeliveryResult {
+	result := &DeliveryResult{}
+	for _, msg := range env.Messages {
+		if msg == nil {
+			continue
+		}
+		// 直接投递时对端必须是发件人；由他人（中继）转交的邮件须带发件人签名，由 ReceiveMessage 验签
+		if from != msg.Sender && !m.verifiable(msg) {
+			result.reject(msg.ID, fmt.Errorf("%w: message from %s delivered by %s", ErrSenderMismatch, msg.Sender, from))
+			continue
+		}
+		if err := m.admitPeer(from); err != nil {
+			result.reject(msg.ID, err)
+			continue
+		}
+		if err := m.ReceiveMessage(msg); err != nil && !errors.Is(err, ErrMessageExists) {
+			result.reject(msg.ID, err)
+			continue
+		}
+		// 重复的邮件（上次的回执丢失）同样回复回执，中继据此删除暂存
+		receipt, err := m.newReceipt(msg, from)
+		if err != nil {
+			result.reject(msg.ID, err)
+			continue
+		}
+		result.Receipts = append(result.Receipts, receipt)
+	}
+	for _, r := range env.Receipts {
+		m.HandleReceipt(r)
+	}
+	return result
+}
+
+// verifiable 邮件带签名且设置了验签函数，收件时可以确认发件人
+func (m *Mailbox) verifiable(msg *Message) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.verifyFunc != nil && len(msg.Signature) > 0
+}
+
+// newReceipt 为收到的邮件签发送达回执
+func (m *Mailbox) newReceipt(msg *Message, from string) (*Receipt, error) {
+	r := &Receipt{
+		MessageID:   msg.ID,
+		Sender:      msg.Sender,
+		Receiver:    m.config.NodeID,
+		DeliveredAt: time.Now().UTC(),
+	}
+	if from != "" && from != msg.Sender {
+		r.Relay = from
+	}
+	m.mu.RLock()
+	signFunc := m.signFunc
+	m.mu.RUnlock()
+	if signFunc != nil {
+		sig, err := signFunc(r.signData())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign receipt: %w", err)
+		}
+		r.Signature = sig
+	}
+	return r, nil
+}
+
+// verifyReceiptLocked 校验回执由收件人签名（未设置验签函数时只检查字段）
+func (m *Mailbox) verifyReceiptLocked(r *Receipt) error {
+	if r == nil || r.MessageID == "" || r.Receiver == "" {
+		return ErrInvalidReceipt
+	}
+	if m.verifyFunc == nil {
+		return nil
+	}
+	valid, err := m.verifyFunc(r.Receiver, r.signData(), r.Signature)
+	if err != nil || !valid {
+		return fmt.Errorf("%w: bad signature from %s", ErrInvalidReceipt, r.Receiver)
+	}
+	return nil
+}
+
+// HandleReceipt 处理发件箱邮件的送达回执，校验收件人签名后标记为已送达
+func (m *Mailbox) HandleReceipt(r *Receipt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.verifyReceiptLocked(r); err != nil {
+		return err
+	}
+	msg, ok := m.outbox[r.MessageID]
+	if !ok || msg.Receiver != r.Receiver || r.Sender != m.config.NodeID {
+		return fmt.Errorf("%w: no outgoing message %s to %s", ErrInvalidReceipt, r.MessageID, r.Receiver)
+	}
+	deliveredAt := r.DeliveredAt
+	msg.DeliveredAt = &deliveredAt
+	if msg.Status != StatusRead {
+		msg.Status = StatusDelivered
+	}
+	m.markMessageLocked(msg.ID)
+	return nil
+}
+
+// HandleRelayEnvelope 处理经 RelayProtocol 收到的帧：发件人把收件人离线的邮件交给本节点暂存。
+// 只接受发件人本人交来的邮件。
+func (m *Mailbox) HandleRelayEnvelope(env *Envelope, from string) *DeliveryResult {
+	result := &DeliveryResult{}
+	for _, msg := range env.Messages {
+		if msg == nil {
+			continue
+		}
+		if msg.Sender != from {
+			result.reject(msg.ID, fmt.Errorf("message from %s handed off by %s", msg.Sender, from))
+			continue
+		}
+		if err := m.admitPeer(from); err != nil {
+			result.reject(msg.ID, err)
+			continue
+		}
+		if err := m.AcceptRelay(msg); err != nil {
+			result.reject(msg.ID, err)
+			continue
+		}
+		result.Accepted = append(result.Accepted, msg.ID)
+	}
+	return result
+}
+
+// AcceptRelay 中继模式下暂存发给离线节点的邮件：只接受发件人签名有效的加密邮件，
+// 未设置验签函数时拒绝暂存。每个收件人、每个发件人和全部暂存的邮件数有上限。
+// 重复交来的邮件直接视为已接收。
+func (m *Mailbox) AcceptRelay(msg *Message) error {
+	if !m.config.Relay {
+		return ErrRelayDisabled
+	}
+	if msg == nil || msg.ID == "" || msg.Receiver == "" {
+		return errors.New("invalid message")
+	}
+	if !msg.Encrypted {
+		return ErrRelayPlaintext
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.Receiver == m.config.NodeID {
+		return errors.New("message is addressed to the relay itself")
+	}
+	// 先验签再查重和计数，伪造的邮件既不能冒充已暂存的邮件，也不占用发件人的配额
+	if m.verifyFunc == nil || len(msg.Signature) == 0 {
+		return ErrRelayUnsigned
+	}
+	if valid, err := m.verifyFunc(msg.Sender, m.getSignData(msg), msg.Signature); err != nil || !valid {
+		m.relayRejected++
+		return fmt.Errorf("%w: bad signature from %s", ErrRelayUnsigned, msg.Sender)
+	}
+	total, fromSender := 0, 0
+	for _, queued := range m.pending {
+		total += len(queued)
+		for _, q := range queued {
+			if q.Sender == msg.Sender {
+				fromSender++
+			}
+		}
+	}
+	for _, queued := range m.pending[msg.Receiver] {
+		if queued.ID == msg.ID {
+			return nil
+		}
+	}
+	if m.config.MaxRelayPerReceiver > 0 && len(m.pending[msg.Receiver]) >= m.config.MaxRelayPerReceiver {
+		m.relayRejected++
+		return fmt.Errorf("%w: %d messages queued for %s", ErrRelayFull, len(m.pending[msg.Receiver]), msg.Receiver)
+	}
+	if m.config.MaxRelayPerSender > 0 && fromSender >= m.config.MaxRelayPerSender {
+		m.relayRejected++
+		return fmt.Errorf("%w: %d messages queued from %s", ErrRelayFull, fromSender, msg.Sender)
+	}
+	if m.config.MaxRelayMessages > 0 && total >= m.config.MaxRelayMessages {
+		m.relayRejected++
+		return fmt.Errorf("%w: %d messages queued", ErrRelayFull, total)
+	}
+
+	now := time.Now()
+	if err := m.storeRelayLocked(msg, now); err != nil {
+		m.relayRejected++
+		return err
+	}
+	m.relayStored[msg.ID] = now
+	m.dirty.Mark("relay_stored", msg.ID)
+	m.relayAccepted++
+	return nil
+}
+
+// relayExpiryLocked 暂存邮件的过期时间：邮件自身的过期时间，中继接收的邮件不超过 RelayTTL
+func (m *Mailbox) relayExpiryLocked(msg *Message) time.Time {
+	stored, ok := m.relayStored[msg.ID]
+	if !ok || m.config.RelayTTL <= 0 {
+		return msg.ExpiresAt
+	}
+	if limit := stored.Add(m.config.RelayTTL); limit.Before(msg.ExpiresAt) {
+		return limit
+	}
+	return msg.ExpiresAt
+}
+
+// RelayBatch 待推送给 receiver 的暂存邮件（最多 limit 封，不移除）和等待转交给它的送达回执，
+// 都没有时返回 nil。推送完成后用 CompleteRelay 处理应答。
+func (m *Mailbox) RelayBatch(receiver string, limit int) *Envelope {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	env := &Envelope{}
+	for _, msg := range m.pending[receiver] {
+		if limit > 0 && len(env.Messages) == limit {
+			break
+		}
+		if !m.skew.Expired(m.relayExpiryLocked(msg), now) {
+			env.Messages = append(env.Messages, msg)
+		}
+	}
+	env.Receipts = append(env.Receipts, m.receipts[receiver]...)
+	if len(env.Messages) == 0 && len(env.Receipts) == 0 {
+		return nil
+	}
+	return env
+}
+
+// CompleteRelay 处理收件人对推送的应答：有回执或被拒收的邮件从暂存中删除，
+// 回执留待转交给原发件人；随本批次推送的回执不再保留。
+// 返回有回执待转交的发件人，调用方在其在线时推送。
+func (m *Mailbox) CompleteRelay(receiver string, sent *Envelope, result *DeliveryResult) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sentIDs := make(map[string]*Message, len(sent.Messages))
+	for _, msg := range sent.Messages {
+		sentIDs[msg.ID] = msg
+	}
+	done := make(map[string]bool)
+	senders := make(map[string]bool)
+	var ownReceipts []*Receipt
+	for _, r := range result.Receipts {
+		msg, ok := sentIDs[r.MessageID]
+		if !ok || r.Receiver != receiver || r.Sender != msg.Sender || m.verifyReceiptLocked(r) != nil {
+			continue
+		}
+		done[r.MessageID] = true
+		m.relayDelivered++
+		if msg.Sender == m.config.NodeID {
+			ownReceipts = append(ownReceipts, r)
+			continue
+		}
+		m.receipts[msg.Sender] = append(m.receipts[msg.Sender], r)
+		m.dirty.Mark("receipts", msg.Sender)
+		senders[msg.Sender] = true
+	}
+	for id := range result.Rejected {
+		if _, ok := sentIDs[id]; ok && !done[id] {
+			done[id] = true
+			m.relayRejected++
+		}
+	}
+	m.removeRelayedLocked(receiver, done)
+
+	// 已转交的回执
+	forwarded := make(map[string]bool, len(sent.Receipts))
+	for _, r := range sent.Receipts {
+		forwarded[r.MessageID] = true
+	}
+	if len(forwarded) > 0 {
+		kept := m.receipts[receiver][:0]
+		for _, r := range m.receipts[receiver] {
+			if !forwarded[r.MessageID] {
+				kept = append(kept, r)
+			}
+		}
+		m.dirty.Mark("receipts", receiver)
+		if len(kept) == 0 {
+			delete(m.receipts, receiver)
+		} else {
+			m.receipts[receiver] = kept
+		}
+	}
+
+	// 中继本身发出的邮件直接更新发件箱
+	for _, r := range ownReceipts {
+		if msg, ok := m.outbox[r.MessageID]; ok {
+			deliveredAt := r.DeliveredAt
+			msg.DeliveredAt = &deliveredAt
+			msg.Status = StatusDelivered
+			m.markMessageLocked(msg.ID)
+		}
+	}
+
+	notify := make([]string, 0, len(senders))
+	for s := range senders {
+		notify = append(notify, s)
+	}
+	sort.Strings(notify)
+	return notify
+}
+
+// removeRelayedLocked 从 receiver 的暂存中删除指定邮件
+func (m *Mailbox) removeRelayedLocked(receiver string, ids map[string]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	kept := m.pending[receiver][:0]
+	for _, msg := range m.pending[receiver] {
+		if ids[msg.ID] {
+			delete(m.relayStored, msg.ID)
+			m.dirty.Mark("relay_stored", msg.ID)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	m.dirty.Mark("pending", receiver)
+	if len(kept) == 0 {
+		delete(m.pending, receiver)
+	} else {
+		m.pending[receiver] = kept
+	}
+}
+
+// RelayReceivers 有暂存邮件或待转交回执的节点
+func (m *Mailbox) RelayReceivers() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	seen := make(map[string]bool)
+	for id, queued := range m.pending {
+		if len(queued) > 0 {
+			seen[id] = true
+		}
+	}
+	for id, queued := range m.receipts {
+		if len(queued) > 0 {
+			seen[id] = true
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RetryPending 重新投递发给 receiver 的待发邮件（收件人重新连接时调用），返回投递成功或交给中继的数量
+func (m *Mailbox) RetryPending(receiver string) int {
+	m.mu.Lock()
+	now := time.Now()
+	var retry []*Message
+	for _, msg := range m.outbox {
+		if msg.Receiver == receiver && msg.Status == StatusPending && now.Before(msg.ExpiresAt) {
+			retry = append(retry, msg)
+		}
+	}
+	sort.Slice(retry, func(i, j int) bool { return retry[i].Timestamp.Before(retry[j].Timestamp) })
+	sent := 0
+	for _, msg := range retry {
+		m.deliverLocked(msg)
+		if msg.Status != StatusPending {
+			sent++
+		}
+	}
+	m.mu.Unlock()
+
+	if sent > 0 {
+		m.saveToDisk()
+	}
+	return sent
+}
+
+// RelayEntry 中继暂存的邮件（不含内容）
+type RelayEntry struct {
+	ID        string    `json:"id"`
+	Sender    string    `json:"sender"`
+	Receiver  string    `json:"receiver"`
+	Size      int       `json:"size"`
+	StoredAt  time.Time `json:"stored_at,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RelayStatus 中继暂存情况
+type RelayStatus struct {
+	Enabled         bool          `json:"enabled"`
+	TTLSeconds      int64         `json:"ttl_seconds"`
+	Messages        int           `json:"messages"`
+	Receivers       int           `json:"receivers"`
+	PendingReceipts int           `json:"pending_receipts"`
+	Accepted        int64         `json:"accepted"`
+	Delivered       int64         `json:"delivered"`
+	Expired         int64         `json:"expired"`
+	Rejected        int64         `json:"rejected"`
+	Pending         []*RelayEntry `json:"pending"`
+}
+
+// RelayStatus 中继暂存情况，receiver 非空时只列出发给该节点的邮件，按暂存先后排列
+func (m *Mailbox) RelayStatus(receiver string) *RelayStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := m.relaySummaryLocked()
+	for id, queued := range m.pending {
+		if receiver != "" && id != receiver {
+			continue
+		}
+		for _, msg := range queued {
+			status.Pending = append(status.Pending, &RelayEntry{
+				ID:        msg.ID,
+				Sender:    msg.Sender,
+				Receiver:  msg.Receiver,
+				Size:      len(msg.Content),
+				StoredAt:  m.relayStored[msg.ID],
+				ExpiresAt: m.relayExpiryLocked(msg),
+			})
+		}
+	}
+	sort.Slice(status.Pending, func(i, j int) bool {
+		a, b := status.Pending[i], status.Pending[j]
+		if !a.StoredAt.Equal(b.StoredAt) {
+			return a.StoredAt.Before(b.StoredAt)
+		}
+		return a.ID < b.ID
+	})
+	return status
+}
+
+// relaySummaryLocked 中继的计数，不列出暂存的邮件
+func (m *Mailbox) relaySummaryLocked() *RelayStatus {
+	status := &RelayStatus{
+		Enabled:    m.config.Relay,
+		TTLSeconds: int64(m.config.RelayTTL.Seconds()),
+		Receivers:  len(m.pending),
+		Accepted:   m.relayAccepted,
+		Delivered:  m.relayDelivered,
+		Expired:    m.relayExpired,
+		Rejected:   m.relayRejected,
+		Pending:    []*RelayEntry{},
+	}
+	for _, queued := range m.receipts {
+		status.PendingReceipts += len(queued)
+	}
+	for _, queued := range m.pending {
+		status.Messages += len(queued)
+	}
+	return status
+}
@@ -0,0 +1,256 @@
+package mailbox
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 投递路径选择
+//
+// 每次投递尝试的耗时和结果按对端节点累计：延迟取发送耗时的滑动平均，没有本地记录时用
+// SetLatencyFunc 提供的实测往返延迟（如 libp2p peerstore），成功率做拉普拉斯平滑。
+// 候选中继按期望耗时（延迟 / 成功率）排序。直连的期望耗时超过 SlowPathThreshold 且有
+// 更快的中继时，加密邮件先交给中继，中继都失败后再直连一次。每封邮件的候选、尝试和最终
+// 经由的路径记为一条路由决策，用于排查投递延迟。
+
+// 路径类型
+const (
+	PathDirect = "direct" // 直接投递给收件人
+	PathRelay  = "relay"  // 交给中继暂存转发
+)
+
+const (
+	// unknownPathLatency 没有测量数据的路径按此延迟估计
+	unknownPathLatency = 500 * time.Millisecond
+	// pathLatencyAlpha 延迟滑动平均的权重
+	pathLatencyAlpha = 0.3
+	// defaultRouteHistory 未配置时保留的路由决策条数
+	defaultRouteHistory = 256
+)
+
+// ErrSlowPath 直连被判定为慢路径，本次先交给中继
+var ErrSlowPath = errors.New("direct path is slower than the best relay")
+
+// PathStats 经某个节点投递的统计
+type PathStats struct {
+	NodeID    string        `json:"node_id"`
+	Latency   time.Duration `json:"latency"` // 投递耗时的滑动平均
+	Successes int           `json:"successes"`
+	Failures  int           `json:"failures"`
+	LastError string        `json:"last_error,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Reliability 成功率（拉普拉斯平滑，没有记录时为 0.5）
+func (s *PathStats) Reliability() float64 {
+	return float64(s.Successes+1) / float64(s.Successes+s.Failures+2)
+}
+
+// RouteCandidate 候选路径
+type RouteCandidate struct {
+	Via         string        `json:"via"`
+	Path        string        `json:"path"`
+	Latency     time.Duration `json:"latency"`
+	Reliability float64       `json:"reliability"`
+	Cost        time.Duration `json:"cost"` // 期望耗时：延迟 / 成功率，越小越优先
+}
+
+// RouteAttempt 一次投递尝试
+type RouteAttempt struct {
+	Via     string        `json:"via"`
+	Path    string        `json:"path"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// RouteDecision 一封邮件的一次投递：候选路径、依次的尝试和最终经由的路径
+type RouteDecision struct {
+	MessageID    string           `json:"message_id"`
+	Receiver     string           `json:"receiver"`
+	Time         time.Time        `json:"time"`
+	DirectSlow   bool             `json:"direct_slow"` // 直连被判定为慢路径，先尝试中继
+	Candidates   []RouteCandidate `json:"candidates"`
+	Attempts     []RouteAttempt   `json:"attempts"`
+	DeliveredVia string           `json:"delivered_via,omitempty"` // 为空表示本次投递失败
+	Path         string           `json:"path,omitempty"`
+}
+
+// RouteTable 路径统计与路由决策记录，由邮箱创建（见 Mailbox.Routes），投递和中继函数记录尝试
+type RouteTable struct {
+	mu        sync.RWMutex
+	stats     map[string]*PathStats
+	open      map[string]*RouteDecision // 进行中的投递：messageID -> 决策
+	decisions []*RouteDecision          // 按时间顺序，超出上限时丢弃最旧的
+	limit     int
+	slow      time.Duration
+	latency   func(nodeID string) (time.Duration, bool)
+}
+
+// NewRouteTable 创建路由表，slow 为 0 时不判定慢路径
+func NewRouteTable(limit int, slow time.Duration) *RouteTable {
+	if limit <= 0 {
+		limit = defaultRouteHistory
+	}
+	return &RouteTable{
+		stats: make(map[string]*PathStats),
+		open:  make(map[string]*RouteDecision),
+		limit: limit,
+		slow:  slow,
+	}
+}
+
+// SetLatencyFunc 设置实测延迟来源，没有本地投递记录的路径按其估计
+func (t *RouteTable) SetLatencyFunc(fn func(nodeID string) (time.Duration, bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.latency = fn
+}
+
+// Rank 估计经各节点投递的代价并按代价排序，代价相同时保持原顺序
+func (t *RouteTable) Rank(vias []string, path string) []RouteCandidate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rankLocked(vias, path)
+}
+
+func (t *RouteTable) rankLocked(vias []string, path string) []RouteCandidate {
+	candidates := make([]RouteCandidate, 0, len(vias))
+	for _, via := range vias {
+		candidates = append(candidates, t.candidateLocked(via, path))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Cost < candidates[j].Cost
+	})
+	return candidates
+}
+
+func (t *RouteTable) candidateLocked(via, path string) RouteCandidate {
+	c := RouteCandidate{Via: via, Path: path, Latency: unknownPathLatency, Reliability: 0.5}
+	stats, ok := t.stats[via]
+	if ok {
+		c.Reliability = stats.Reliability()
+	}
+	if ok && stats.Latency > 0 {
+		c.Latency = stats.Latency
+	} else if t.latency != nil {
+		if rtt, found := t.latency(via); found && rtt > 0 {
+			c.Latency = rtt
+		}
+	}
+	c.Cost = time.Duration(float64(c.Latency) / c.Reliability)
+	return c
+}
+
+// DirectSlow 开始一封邮件的投递并判断直连是否为慢路径：直连期望耗时超过阈值且 relays 中
+// 有更快的中继。relays 为空表示本次不会交给中继。同一次投递中已跳过直连时返回 false，
+// 中继都失败后的直连照常进行。
+func (t *RouteTable) DirectSlow(messageID, receiver string, relays []string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.open[messageID]; ok {
+		return false
+	}
+	direct := t.candidateLocked(receiver, PathDirect)
+	ranked := t.rankLocked(relays, PathRelay)
+	d := &RouteDecision{
+		MessageID:  messageID,
+		Receiver:   receiver,
+		Time:       time.Now(),
+		Candidates: append([]RouteCandidate{direct}, ranked...),
+	}
+	d.DirectSlow = t.slow > 0 && direct.Cost > t.slow && len(ranked) > 0 && ranked[0].Cost < direct.Cost
+	t.open[messageID] = d
+	return d.DirectSlow
+}
+
+// Attempt 记录一次投递尝试的耗时和结果
+func (t *RouteTable) Attempt(messageID, receiver, via, path string, latency time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, ok := t.stats[via]
+	if !ok {
+		stats = &PathStats{NodeID: via}
+		t.stats[via] = stats
+	}
+	stats.UpdatedAt = time.Now()
+	if err != nil {
+		stats.Failures++
+		stats.LastError = err.Error()
+	} else {
+		stats.Successes++
+		if stats.Latency == 0 {
+			stats.Latency = latency
+		} else {
+			stats.Latency = time.Duration(pathLatencyAlpha*float64(latency) + (1-pathLatencyAlpha)*float64(stats.Latency))
+		}
+	}
+
+	d, ok := t.open[messageID]
+	if !ok {
+		d = &RouteDecision{MessageID: messageID, Receiver: receiver, Time: time.Now()}
+		t.open[messageID] = d
+	}
+	attempt := RouteAttempt{Via: via, Path: path, Latency: latency}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else if d.DeliveredVia == "" {
+		d.DeliveredVia = via
+		d.Path = path
+	}
+	d.Attempts = append(d.Attempts, attempt)
+}
+
+// Finish 结束一封邮件的本次投递，保存路由决策；既没有尝试也没有中继候选的投递不记录
+func (t *RouteTable) Finish(messageID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.open[messageID]
+	if !ok {
+		return
+	}
+	delete(t.open, messageID)
+	if len(d.Attempts) == 0 && len(d.Candidates) <= 1 {
+		return
+	}
+	t.decisions = append(t.decisions, d)
+	if len(t.decisions) > t.limit {
+		t.decisions = append([]*RouteDecision(nil), t.decisions[len(t.decisions)-t.limit:]...)
+	}
+}
+
+// Decisions 最近的路由决策（最新的在前），receiver 为空表示全部收件人，messageID 非空时只返回该邮件的
+func (t *RouteTable) Decisions(receiver, messageID string, limit int) []*RouteDecision {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := make([]*RouteDecision, 0)
+	for i := len(t.decisions) - 1; i >= 0; i-- {
+		d := t.decisions[i]
+		if (receiver != "" && d.Receiver != receiver) || (messageID != "" && d.MessageID != messageID) {
+			continue
+		}
+		result = append(result, d)
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// PathStats 各路径的投递统计，按节点ID排序
+func (t *RouteTable) PathStats() []PathStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := make([]PathStats, 0, len(t.stats))
+	for _, s := range t.stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NodeID < result[j].NodeID })
+	return result
+}
@@ -0,0 +1,129 @@
+package mailbox
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// stagedCheckInterval 检查暂存消息是否到期的间隔
+const stagedCheckInterval = time.Second
+
+var (
+	// ErrNotStaged 消息不是暂存状态（已投递、已撤回或不是两阶段发送）
+	ErrNotStaged = errors.New("message is not staged")
+	// ErrCancelWindowClosed 撤回窗口已结束，消息即将或已经投递
+	ErrCancelWindowClosed = errors.New("cancel window has closed")
+	// ErrInvalidDelay 两阶段发送的延迟必须大于 0
+	ErrInvalidDelay = errors.New("send delay must be positive")
+)
+
+// StageMessage 两阶段发送：消息先暂存在发件箱，delay 后才投递
+// 窗口内可用 CancelStaged 撤回。暂存消息随邮箱数据立即落盘，节点重启后继续计时。
+func (m *Mailbox) StageMessage(receiver, subject string, content []byte, encrypt bool, delay time.Duration) (*Message, error) {
+	if delay <= 0 {
+		return nil, ErrInvalidDelay
+	}
+
+	content, encrypted, err := m.sealContent(receiver, content, encrypt)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	msg, err := m.newOutgoingLocked(receiver, subject, content, encrypted, now)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	deliverAt := now.Add(delay)
+	msg.DeliverAt = &deliverAt
+	msg.ExpiresAt = deliverAt.Add(m.config.DefaultTTL)
+	msg.Status = StatusStaged
+	m.outbox[msg.ID] = msg
+	m.markMessageLocked(msg.ID)
+	m.mu.Unlock()
+
+	m.saveToDisk()
+	return msg, nil
+}
+
+// CancelStaged 在投递前撤回暂存的消息，消息以 cancelled 状态留在发件箱
+func (m *Mailbox) CancelStaged(messageID string) (*Message, error) {
+	m.mu.Lock()
+	msg, ok := m.outbox[messageID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, errors.New("message not found")
+	}
+	if msg.Status != StatusStaged {
+		m.mu.Unlock()
+		return nil, ErrNotStaged
+	}
+	if !time.Now().Before(*msg.DeliverAt) {
+		m.mu.Unlock()
+		return nil, ErrCancelWindowClosed
+	}
+	msg.Status = StatusCancelled
+	m.markMessageLocked(messageID)
+	m.mu.Unlock()
+
+	m.saveToDisk()
+	return msg, nil
+}
+
+// ListStaged 列出等待投递的暂存消息，最先投递的在前
+func (m *Mailbox) ListStaged() []*Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var staged []*Message
+	for _, msg := range m.outbox {
+		if msg.Status == StatusStaged {
+			staged = append(staged, msg)
+		}
+	}
+	sort.Slice(staged, func(i, j int) bool {
+		return staged[i].DeliverAt.Before(*staged[j].DeliverAt)
+	})
+	return staged
+}
+
+// dispatchStaged 投递撤回窗口已结束的暂存消息，返回投递的数量
+func (m *Mailbox) dispatchStaged(now time.Time) int {
+	m.mu.Lock()
+	dispatched := 0
+	for _, msg := range m.outbox {
+		if msg.Status != StatusStaged || msg.DeliverAt == nil || now.Before(*msg.DeliverAt) {
+			continue
+		}
+		msg.Status = StatusPending
+		m.markMessageLocked(msg.ID)
+		m.dispatchLocked(msg)
+		dispatched++
+	}
+	m.mu.Unlock()
+
+	if dispatched > 0 {
+		m.saveToDisk()
+	}
+	return dispatched
+}
+
+// stagedLoop 定期投递到期的暂存消息
+func (m *Mailbox) stagedLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(stagedCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.dispatchStaged(time.Now())
+		case <-m.stopCh:
+			return
+		}
+	}
+}
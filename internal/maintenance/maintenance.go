@@ -0,0 +1,300 @@
+// Package maintenance 实现节点级维护模式
+// 维护期间节点保持 P2P 连接并缓存入站邮件，但拒绝新任务、暂停选举参与，
+// 并通过心跳向其他节点通告维护状态，避免被判定为失职。
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNilConfig        = errors.New("config cannot be nil")
+	ErrEmptyNodeID      = errors.New("node ID cannot be empty")
+	ErrInMaintenance    = errors.New("node is in maintenance mode")
+	ErrNotInMaintenance = errors.New("node is not in maintenance mode")
+	ErrAlreadyEnabled   = errors.New("maintenance mode already enabled")
+	ErrInvalidDuration  = errors.New("maintenance duration exceeds the configured maximum")
+)
+
+// 维护模式下被拒绝的操作
+const (
+	OpNewTask  = "new_task"
+	OpElection = "election"
+)
+
+// Status 维护状态
+type Status struct {
+	NodeID  string    `json:"node_id"`
+	Enabled bool      `json:"enabled"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	Until   time.Time `json:"until,omitempty"` // 为零表示需手动关闭
+}
+
+// Active 在指定时间点是否处于维护状态
+func (s *Status) Active(now time.Time) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	return s.Until.IsZero() || now.Before(s.Until)
+}
+
+// Config 维护模式配置
+type Config struct {
+	NodeID      string
+	DataDir     string
+	MaxDuration time.Duration // 单次维护最长时长，超出或不设截止的请求被拒绝（0 表示不限）
+	PeerTTL     time.Duration // 对端维护通告的有效期（未带截止时间时）
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:      nodeID,
+		DataDir:     "./data/maintenance",
+		MaxDuration: 24 * time.Hour,
+		PeerTTL:     2 * time.Hour,
+	}
+}
+
+// Manager 维护模式管理器
+type Manager struct {
+	mu     sync.RWMutex
+	config *Config
+	status *Status
+
+	// 对端通告的维护状态: nodeID -> Status
+	peers map[string]*Status
+
+	// 到期自动退出维护的定时器
+	expiry *time.Timer
+
+	// 回调
+	OnEnabled  func(status *Status)
+	OnDisabled func(status *Status)
+}
+
+// NewManager 创建维护模式管理器
+func NewManager(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyNodeID
+	}
+
+	m := &Manager{
+		config: config,
+		status: &Status{NodeID: config.NodeID},
+		peers:  make(map[string]*Status),
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		m.load()
+		if m.status.Enabled && !m.status.Until.IsZero() {
+			m.expiry = time.AfterFunc(time.Until(m.status.Until), m.expire)
+		}
+	}
+
+	return m, nil
+}
+
+// Enable 进入维护模式，duration 为 0 表示需手动关闭（设置了 MaxDuration 时被拒绝）
+func (m *Manager) Enable(reason string, duration time.Duration) (*Status, error) {
+	if duration < 0 || (m.config.MaxDuration > 0 && (duration == 0 || duration > m.config.MaxDuration)) {
+		return nil, ErrInvalidDuration
+	}
+
+	m.mu.Lock()
+	if m.status.Active(time.Now()) {
+		m.mu.Unlock()
+		return nil, ErrAlreadyEnabled
+	}
+
+	now := time.Now()
+	m.status = &Status{
+		NodeID:  m.config.NodeID,
+		Enabled: true,
+		Reason:  reason,
+		Since:   now,
+	}
+	m.stopExpiry()
+	if duration > 0 {
+		m.status.Until = now.Add(duration)
+		m.expiry = time.AfterFunc(duration, m.expire)
+	}
+	status := *m.status
+	m.save()
+	m.mu.Unlock()
+
+	if m.OnEnabled != nil {
+		m.OnEnabled(&status)
+	}
+	return &status, nil
+}
+
+// Disable 退出维护模式
+func (m *Manager) Disable() (*Status, error) {
+	m.mu.Lock()
+	if !m.status.Enabled {
+		m.mu.Unlock()
+		return nil, ErrNotInMaintenance
+	}
+
+	previous := *m.status
+	m.status = &Status{NodeID: m.config.NodeID}
+	m.stopExpiry()
+	m.save()
+	m.mu.Unlock()
+
+	if m.OnDisabled != nil {
+		m.OnDisabled(&previous)
+	}
+	return &previous, nil
+}
+
+// expire 维护到期后自动退出，与手动关闭一样触发 OnDisabled
+func (m *Manager) expire() {
+	m.mu.Lock()
+	if !m.status.Enabled || m.status.Until.IsZero() || time.Now().Before(m.status.Until) {
+		m.mu.Unlock()
+		return
+	}
+	previous := *m.status
+	m.status = &Status{NodeID: m.config.NodeID}
+	m.expiry = nil
+	m.save()
+	onDisabled := m.OnDisabled
+	m.mu.Unlock()
+
+	if onDisabled != nil {
+		onDisabled(&previous)
+	}
+}
+
+// stopExpiry 取消到期定时器，调用方需持有锁
+func (m *Manager) stopExpiry() {
+	if m.expiry != nil {
+		m.expiry.Stop()
+		m.expiry = nil
+	}
+}
+
+// IsEnabled 当前是否处于维护模式（到期后自动失效）
+func (m *Manager) IsEnabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status.Active(time.Now())
+}
+
+// GetStatus 获取当前维护状态
+func (m *Manager) GetStatus() *Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := *m.status
+	if !status.Active(time.Now()) {
+		status = Status{NodeID: m.config.NodeID}
+	}
+	return &status
+}
+
+// CheckAllowed 检查操作在当前状态下是否被允许
+func (m *Manager) CheckAllowed(op string) error {
+	if !m.IsEnabled() {
+		return nil
+	}
+	switch op {
+	case OpNewTask, OpElection:
+		return ErrInMaintenance
+	}
+	return nil
+}
+
+// RecordPeerStatus 记录对端通告的维护状态
+func (m *Manager) RecordPeerStatus(status *Status) {
+	if status == nil || status.NodeID == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !status.Enabled {
+		delete(m.peers, status.NodeID)
+		return
+	}
+
+	s := *status
+	if s.Until.IsZero() && m.config.PeerTTL > 0 {
+		s.Until = time.Now().Add(m.config.PeerTTL)
+	}
+	m.peers[s.NodeID] = &s
+}
+
+// IsPeerInMaintenance 对端是否处于维护中（用于豁免失职惩罚）
+func (m *Manager) IsPeerInMaintenance(nodeID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.peers[nodeID].Active(time.Now())
+}
+
+// GetPeersInMaintenance 获取处于维护中的对端
+func (m *Manager) GetPeersInMaintenance() []*Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	result := make([]*Status, 0, len(m.peers))
+	for id, s := range m.peers {
+		if !s.Active(now) {
+			delete(m.peers, id)
+			continue
+		}
+		c := *s
+		result = append(result, &c)
+	}
+	return result
+}
+
+// GetStats 获取统计信息
+func (m *Manager) GetStats() map[string]interface{} {
+	status := m.GetStatus()
+	return map[string]interface{}{
+		"enabled":              status.Enabled,
+		"reason":               status.Reason,
+		"peers_in_maintenance": len(m.GetPeersInMaintenance()),
+	}
+}
+
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.status, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "maintenance.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "maintenance.json"))
+	if err != nil {
+		return
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return
+	}
+	status.NodeID = m.config.NodeID
+	m.status = &status
+}
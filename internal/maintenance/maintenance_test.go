@@ -0,0 +1,162 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	cfg := DefaultConfig("node-1")
+	cfg.DataDir = t.TempDir()
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("创建管理器失败: %v", err)
+	}
+	return m
+}
+
+func TestNewManager(t *testing.T) {
+	if _, err := NewManager(nil); err != ErrNilConfig {
+		t.Errorf("期望 ErrNilConfig, 得到 %v", err)
+	}
+	if _, err := NewManager(&Config{}); err != ErrEmptyNodeID {
+		t.Errorf("期望 ErrEmptyNodeID, 得到 %v", err)
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	m := newTestManager(t)
+
+	var enabled, disabled bool
+	m.OnEnabled = func(*Status) { enabled = true }
+	m.OnDisabled = func(*Status) { disabled = true }
+
+	if err := m.CheckAllowed(OpNewTask); err != nil {
+		t.Errorf("非维护模式应允许新任务: %v", err)
+	}
+
+	status, err := m.Enable("升级硬盘", time.Hour)
+	if err != nil {
+		t.Fatalf("进入维护模式失败: %v", err)
+	}
+	if !status.Enabled || status.Reason != "升级硬盘" || status.Until.IsZero() {
+		t.Errorf("维护状态错误: %+v", status)
+	}
+	if !enabled || !m.IsEnabled() {
+		t.Error("应处于维护模式")
+	}
+
+	if _, err := m.Enable("again", time.Hour); err != ErrAlreadyEnabled {
+		t.Errorf("期望 ErrAlreadyEnabled, 得到 %v", err)
+	}
+	if err := m.CheckAllowed(OpNewTask); err != ErrInMaintenance {
+		t.Errorf("维护模式应拒绝新任务, 得到 %v", err)
+	}
+	if err := m.CheckAllowed(OpElection); err != ErrInMaintenance {
+		t.Errorf("维护模式应暂停选举, 得到 %v", err)
+	}
+	if err := m.CheckAllowed("mailbox"); err != nil {
+		t.Errorf("维护模式不应影响其他操作: %v", err)
+	}
+
+	if _, err := m.Disable(); err != nil {
+		t.Fatalf("退出维护模式失败: %v", err)
+	}
+	if !disabled || m.IsEnabled() {
+		t.Error("应已退出维护模式")
+	}
+	if _, err := m.Disable(); err != ErrNotInMaintenance {
+		t.Errorf("期望 ErrNotInMaintenance, 得到 %v", err)
+	}
+}
+
+func TestMaxDurationAndExpiry(t *testing.T) {
+	cfg := DefaultConfig("node-1")
+	cfg.DataDir = ""
+	cfg.MaxDuration = 50 * time.Millisecond
+	m, _ := NewManager(cfg)
+
+	disabled := make(chan *Status, 1)
+	m.OnDisabled = func(s *Status) { disabled <- s }
+
+	// 设置了上限时，不设截止和超出上限的请求被拒绝
+	for _, d := range []time.Duration{0, -time.Second, 2 * cfg.MaxDuration} {
+		if _, err := m.Enable("long", d); err != ErrInvalidDuration {
+			t.Errorf("Enable(%v) 期望 ErrInvalidDuration, 得到 %v", d, err)
+		}
+	}
+
+	status, err := m.Enable("short", cfg.MaxDuration)
+	if err != nil || status.Until.Sub(status.Since) != cfg.MaxDuration {
+		t.Fatalf("维护状态错误: %+v, %v", status, err)
+	}
+
+	// 到期自动退出，与手动关闭一样触发 OnDisabled
+	select {
+	case s := <-disabled:
+		if s.Reason != "short" {
+			t.Errorf("OnDisabled 收到 %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("到期后应触发 OnDisabled")
+	}
+	if m.IsEnabled() {
+		t.Error("维护模式应已过期")
+	}
+	if m.GetStatus().Enabled {
+		t.Error("过期后状态应为未启用")
+	}
+}
+
+func TestUnlimitedDuration(t *testing.T) {
+	cfg := DefaultConfig("node-1")
+	cfg.DataDir = ""
+	cfg.MaxDuration = 0
+	m, _ := NewManager(cfg)
+
+	status, err := m.Enable("manual", 0)
+	if err != nil || !status.Until.IsZero() {
+		t.Fatalf("未设上限时应允许不设截止: %+v, %v", status, err)
+	}
+	if !m.IsEnabled() {
+		t.Error("应处于维护模式")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig("node-1")
+	cfg.DataDir = dir
+	m, _ := NewManager(cfg)
+	m.Enable("persist", time.Hour)
+
+	m2, _ := NewManager(cfg)
+	if !m2.IsEnabled() || m2.GetStatus().Reason != "persist" {
+		t.Error("重启后应恢复维护状态")
+	}
+}
+
+func TestPeerStatus(t *testing.T) {
+	m := newTestManager(t)
+
+	m.RecordPeerStatus(&Status{NodeID: "peer-a", Enabled: true, Reason: "upgrade"})
+	m.RecordPeerStatus(&Status{NodeID: "peer-b", Enabled: true, Until: time.Now().Add(-time.Minute)})
+
+	if !m.IsPeerInMaintenance("peer-a") {
+		t.Error("peer-a 应处于维护中")
+	}
+	if m.IsPeerInMaintenance("peer-b") {
+		t.Error("peer-b 的维护已过期")
+	}
+	if m.IsPeerInMaintenance("peer-c") {
+		t.Error("未知节点不应处于维护中")
+	}
+	if got := len(m.GetPeersInMaintenance()); got != 1 {
+		t.Errorf("期望 1 个维护中的节点, 得到 %d", got)
+	}
+
+	m.RecordPeerStatus(&Status{NodeID: "peer-a", Enabled: false})
+	if m.IsPeerInMaintenance("peer-a") {
+		t.Error("peer-a 已退出维护")
+	}
+}
@@ -0,0 +1,234 @@
+// Package metrics 以 Prometheus 格式导出节点指标
+// 模块统计注册表中的计数器和仪表值按 agentnetwork_<模块>_<指标> 导出（每次抓取时采集），
+// 另外记录 HTTP/gRPC 请求耗时直方图和邻居声誉分布直方图。
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace 指标名前缀
+const Namespace = "agentnetwork"
+
+var (
+	ErrNilConfig = errors.New("config cannot be nil")
+	ErrNoStats   = errors.New("stats registry is required")
+)
+
+// DefaultReputationBuckets 声誉分布直方图的默认分桶（邻居声誉为整数分）
+var DefaultReputationBuckets = []float64{0, 5, 10, 20, 30, 50, 75, 100, 150, 200}
+
+// Config 导出配置
+type Config struct {
+	Stats *stats.Registry
+
+	// ReputationBuckets 声誉分布直方图的分桶，声誉来源由 SetReputationFunc 设置
+	ReputationBuckets []float64
+
+	// LatencyBuckets HTTP/gRPC 耗时直方图的分桶（秒）
+	LatencyBuckets []float64
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(registry *stats.Registry) *Config {
+	return &Config{
+		Stats:             registry,
+		ReputationBuckets: DefaultReputationBuckets,
+		LatencyBuckets:    prometheus.DefBuckets,
+	}
+}
+
+// Exporter Prometheus 指标导出
+type Exporter struct {
+	registry    *prometheus.Registry
+	httpLatency *prometheus.HistogramVec
+	grpcLatency *prometheus.HistogramVec
+	reputation  *reputationCollector
+}
+
+// New 创建指标导出
+func New(config *Config) (*Exporter, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.Stats == nil {
+		return nil, ErrNoStats
+	}
+	latencyBuckets := config.LatencyBuckets
+	if len(latencyBuckets) == 0 {
+		latencyBuckets = prometheus.DefBuckets
+	}
+	reputationBuckets := config.ReputationBuckets
+	if len(reputationBuckets) == 0 {
+		reputationBuckets = DefaultReputationBuckets
+	}
+
+	e := &Exporter{
+		registry: prometheus.NewRegistry(),
+		httpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP API 请求耗时",
+			Buckets:   latencyBuckets,
+		}, []string{"method", "route", "code"}),
+		grpcLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "gRPC 调用耗时",
+			Buckets:   latencyBuckets,
+		}, []string{"method", "code"}),
+		reputation: &reputationCollector{
+			desc:    prometheus.NewDesc(Namespace+"_neighbor_reputation", "邻居声誉分布", nil, nil),
+			buckets: reputationBuckets,
+		},
+	}
+
+	cs := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{Namespace: Namespace}),
+		e.httpLatency,
+		e.grpcLatency,
+		&statsCollector{registry: config.Stats},
+		e.reputation,
+	}
+	for _, c := range cs {
+		if err := e.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// SetReputationFunc 设置声誉分布的来源（当前邻居的声誉分），未设置时不导出声誉直方图
+func (e *Exporter) SetReputationFunc(fn func() []float64) {
+	e.reputation.mu.Lock()
+	defer e.reputation.mu.Unlock()
+	e.reputation.source = fn
+}
+
+// Handler 返回 /metrics 的 HTTP 处理器
+func (e *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(e.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP 记录一次 HTTP API 请求，可直接用作 httpapi.RequestObserver
+func (e *Exporter) ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	e.httpLatency.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// ObserveGRPC 记录一次 gRPC 调用，code 为 gRPC 状态码名称
+func (e *Exporter) ObserveGRPC(method, code string, elapsed time.Duration) {
+	e.grpcLatency.WithLabelValues(method, code).Observe(elapsed.Seconds())
+}
+
+// MetricName 模块统计指标的导出名：非法字符替换为下划线，计数器加 _total 后缀
+func MetricName(module string, m stats.Metric) string {
+	name := Namespace + "_" + sanitize(module) + "_" + sanitize(m.Name)
+	if m.Kind == stats.KindCounter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// statsCollector 抓取时采集模块统计注册表
+// 指标集合随模块注册变化，Describe 不输出描述，作为 unchecked collector 注册。
+type statsCollector struct {
+	registry *stats.Registry
+}
+
+var moduleUpDesc = prometheus.NewDesc(Namespace+"_stats_module_up", "模块统计采集是否成功", []string{"module"}, nil)
+
+func (c *statsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, ms := range c.registry.Snapshot().Modules {
+		up := 1.0
+		if ms.Error != "" {
+			up = 0
+		}
+		ch <- prometheus.MustNewConstMetric(moduleUpDesc, prometheus.GaugeValue, up, ms.Module)
+
+		for _, m := range ms.Metrics {
+			help := m.Help
+			if help == "" {
+				help = ms.Module + " " + m.Name
+			}
+			desc := prometheus.NewDesc(MetricName(ms.Module, m), help, nil, nil)
+			valueType := prometheus.GaugeValue
+			if m.Kind == stats.KindCounter {
+				valueType = prometheus.CounterValue
+			}
+			metric, err := prometheus.NewConstMetric(desc, valueType, m.Value)
+			if err != nil {
+				metric = prometheus.NewInvalidMetric(desc, err)
+			}
+			ch <- metric
+		}
+	}
+}
+
+// reputationCollector 抓取时按当前邻居声誉生成直方图
+type reputationCollector struct {
+	desc    *prometheus.Desc
+	buckets []float64
+
+	mu     sync.Mutex
+	source func() []float64
+}
+
+func (c *reputationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *reputationCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	source := c.source
+	c.mu.Unlock()
+	if source == nil {
+		return
+	}
+	count, sum, buckets := histogram(source(), c.buckets)
+	ch <- prometheus.MustNewConstHistogram(c.desc, count, sum, buckets)
+}
+
+// histogram 统计样本的累计分桶计数
+func histogram(values, bounds []float64) (uint64, float64, map[float64]uint64) {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	buckets := make(map[float64]uint64, len(sorted))
+	for _, b := range sorted {
+		buckets[b] = 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+		for _, b := range sorted {
+			if v <= b {
+				buckets[b]++
+			}
+		}
+	}
+	return uint64(len(values)), sum, buckets
+}
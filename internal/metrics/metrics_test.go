@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		module string
+		metric stats.Metric
+		want   string
+	}{
+		{"mailbox", stats.Gauge("inbox_messages", 1, ""), "agentnetwork_mailbox_inbox_messages"},
+		{"task", stats.Counter("settled_tasks", 1, ""), "agentnetwork_task_settled_tasks_total"},
+		{"p2p", stats.Counter("bytes_total", 1, ""), "agentnetwork_p2p_bytes_total"},
+		{"rate-limit", stats.Gauge("hits.per.min", 1, ""), "agentnetwork_rate_limit_hits_per_min"},
+	}
+	for _, tt := range tests {
+		if got := MetricName(tt.module, tt.metric); got != tt.want {
+			t.Errorf("MetricName(%q, %q) = %q, want %q", tt.module, tt.metric.Name, got, tt.want)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	count, sum, buckets := histogram([]float64{3, 10, 60, 500}, []float64{50, 10, 100})
+	if count != 4 || sum != 573 {
+		t.Errorf("count=%d sum=%v, want 4 and 573", count, sum)
+	}
+	want := map[float64]uint64{10: 2, 50: 2, 100: 3}
+	for bound, n := range want {
+		if buckets[bound] != n {
+			t.Errorf("bucket %v = %d, want %d", bound, buckets[bound], n)
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	if _, err := New(nil); err != ErrNilConfig {
+		t.Errorf("expected ErrNilConfig, got %v", err)
+	}
+	if _, err := New(&Config{}); err != ErrNoStats {
+		t.Errorf("expected ErrNoStats, got %v", err)
+	}
+
+	registry := stats.NewRegistry()
+	registry.Register("mailbox", stats.ProviderFunc(func() []stats.Metric {
+		return []stats.Metric{stats.Gauge("pending_relay_messages", 7, "作为中继待投递的消息数")}
+	}))
+	registry.Register("broken", stats.ProviderFunc(func() []stats.Metric {
+		panic("boom")
+	}))
+
+	e, err := New(DefaultConfig(registry))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	e.SetReputationFunc(func() []float64 { return []float64{5, 40, 120} })
+	e.ObserveHTTP("GET", "/api/v1/node/info", 200, 15*time.Millisecond)
+	e.ObserveGRPC("/agentnetwork.ToolNetwork/GetNodeList", "OK", time.Millisecond)
+
+	w := httptest.NewRecorder()
+	e.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	for _, want := range []string{
+		"agentnetwork_mailbox_pending_relay_messages 7",
+		`agentnetwork_stats_module_up{module="broken"} 0`,
+		`agentnetwork_stats_module_up{module="mailbox"} 1`,
+		"agentnetwork_neighbor_reputation_count 3",
+		`agentnetwork_neighbor_reputation_bucket{le="50"} 2`,
+		`agentnetwork_http_request_duration_seconds_count{code="200",method="GET",route="/api/v1/node/info"} 1`,
+		`agentnetwork_grpc_request_duration_seconds_count{code="OK",method="/agentnetwork.ToolNetwork/GetNodeList"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Package migrate 本地持久化数据的版本化迁移
+//
+// 每个持久化存储（邮箱、留言板、任务等 JSON 文件）在数据目录的 schema.json 中
+// 记录当前数据版本。程序升级后启动时，按版本顺序对落后的存储执行迁移函数，
+// 全部成功后先把原文件备份到 backup/migrations/<时间>/，再写入新数据并更新版本。
+// 任一迁移失败时不写入任何文件。
+//
+// 版本号从 1 开始，第 N 个迁移把数据从版本 N-1 升级到 N。引入迁移框架之前写入
+// 的数据（有文件而 schema.json 中没有记录）视为版本 0；文件不存在的存储直接
+// 记为最新版本。数据版本高于程序已知的最新版本（降级运行）时拒绝启动。
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	// ErrNewerSchema 数据版本高于程序支持的版本（用旧版本程序打开了新数据）
+	ErrNewerSchema = errors.New("data schema is newer than this program supports")
+	// ErrInvalidStore 存储定义不合法（名称重复、迁移版本不连续等）
+	ErrInvalidStore = errors.New("invalid migration store definition")
+)
+
+// ManifestFile 数据目录中记录各存储版本的文件
+const ManifestFile = "schema.json"
+
+// 迁移步骤状态
+const (
+	StatusCurrent  = "current"  // 已是最新版本
+	StatusStamped  = "stamped"  // 首次记录版本（新存储或引入迁移框架前的数据）
+	StatusPending  = "pending"  // 需要迁移（dry-run）
+	StatusMigrated = "migrated" // 已迁移
+)
+
+// Migration 一次数据迁移，把存储从 Version-1 升级到 Version
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(data []byte) ([]byte, error)
+}
+
+// Codec 存储的编解码（例如静态加密），迁移函数只处理解码后的数据
+type Codec interface {
+	Decode(data []byte) ([]byte, error)
+	Encode(data []byte) ([]byte, error)
+}
+
+// Store 一个持久化存储及其迁移历史
+type Store struct {
+	Name       string      // 在 schema.json 中的名称
+	Path       string      // 相对数据目录的文件路径
+	Codec      Codec       // 可选
+	Migrations []Migration // 按版本升序
+}
+
+// Version 程序支持的最新版本
+func (s *Store) Version() int {
+	return len(s.Migrations)
+}
+
+// Manifest schema.json 内容
+type Manifest struct {
+	Stores map[string]*StoreVersion `json:"stores"`
+}
+
+// StoreVersion 存储的数据版本
+type StoreVersion struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Step 一个存储的迁移结果
+type Step struct {
+	Store   string   `json:"store"`
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Status  string   `json:"status"`
+	Applied []string `json:"applied,omitempty"` // 执行（或将要执行）的迁移说明
+}
+
+// Report 一次迁移的结果
+type Report struct {
+	DryRun    bool    `json:"dry_run"`
+	Steps     []*Step `json:"steps"`
+	BackupDir string  `json:"backup_dir,omitempty"` // 迁移前备份的位置，没有写入时为空
+}
+
+// Migrated 是否有存储被迁移（dry-run 时为需要迁移）
+func (r *Report) Migrated() bool {
+	for _, step := range r.Steps {
+		if step.Status == StatusMigrated || step.Status == StatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// Config 迁移配置
+type Config struct {
+	DataDir   string
+	BackupDir string // 迁移前备份目录，默认 <DataDir>/backup/migrations
+	DryRun    bool   // 只执行迁移函数校验结果，不写入任何文件
+}
+
+// Migrator 数据迁移器
+type Migrator struct {
+	config Config
+	stores []*Store
+	now    func() time.Time
+}
+
+// NewMigrator 创建迁移器
+func NewMigrator(config *Config, stores ...*Store) (*Migrator, error) {
+	if config == nil || config.DataDir == "" {
+		return nil, errors.New("data dir is required")
+	}
+	cfg := *config
+	if cfg.BackupDir == "" {
+		cfg.BackupDir = filepath.Join(cfg.DataDir, "backup", "migrations")
+	}
+
+	names := make(map[string]bool)
+	for _, store := range stores {
+		if store.Name == "" || store.Path == "" || names[store.Name] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidStore, store.Name)
+		}
+		names[store.Name] = true
+		for i, mig := range store.Migrations {
+			if mig.Version != i+1 || mig.Up == nil {
+				return nil, fmt.Errorf("%w: %s migration #%d has version %d", ErrInvalidStore, store.Name, i+1, mig.Version)
+			}
+		}
+	}
+
+	return &Migrator{config: cfg, stores: stores, now: time.Now}, nil
+}
+
+// pendingWrite 待写入的迁移结果
+type pendingWrite struct {
+	path string
+	data []byte
+}
+
+// Run 检查并执行迁移
+func (m *Migrator) Run() (*Report, error) {
+	manifest, manifestExists, err := m.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: m.config.DryRun}
+	var writes []pendingWrite
+	dirty := false // schema.json 需要更新
+
+	// 先在内存中完成全部迁移，任一失败都不写入
+	for _, store := range m.stores {
+		latest := store.Version()
+		path := filepath.Join(m.config.DataDir, store.Path)
+		_, statErr := os.Stat(path)
+		exists := statErr == nil
+
+		from := 0
+		if v, ok := manifest.Stores[store.Name]; ok {
+			from = v.Version
+		} else if !exists {
+			from = latest
+		}
+		if from > latest {
+			return nil, fmt.Errorf("%w: %s is at version %d, latest known is %d", ErrNewerSchema, store.Name, from, latest)
+		}
+
+		step := &Step{Store: store.Name, From: from, To: latest, Status: StatusCurrent}
+		report.Steps = append(report.Steps, step)
+		if v, ok := manifest.Stores[store.Name]; !ok {
+			step.Status = StatusStamped
+			dirty = true
+		} else if v.Version != latest {
+			dirty = true
+		}
+		if from == latest || !exists {
+			continue
+		}
+
+		data, err := m.migrate(store, path, from, step)
+		if err != nil {
+			return report, err
+		}
+		writes = append(writes, pendingWrite{path: path, data: data})
+		step.Status = StatusPending
+	}
+
+	if m.config.DryRun || !dirty {
+		return report, nil
+	}
+
+	// 备份原文件后再写入
+	if len(writes) > 0 {
+		backupDir := filepath.Join(m.config.BackupDir, m.now().UTC().Format("20060102T150405Z"))
+		files := make([]string, 0, len(writes)+1)
+		for _, w := range writes {
+			files = append(files, w.path)
+		}
+		if manifestExists {
+			files = append(files, m.manifestPath())
+		}
+		if err := m.backup(backupDir, files); err != nil {
+			return report, err
+		}
+		report.BackupDir = backupDir
+
+		for _, w := range writes {
+			if err := writeFileAtomic(w.path, w.data); err != nil {
+				return report, fmt.Errorf("failed to write migrated data (backup at %s): %w", backupDir, err)
+			}
+		}
+	}
+
+	now := m.now()
+	for _, step := range report.Steps {
+		if step.Status == StatusPending {
+			step.Status = StatusMigrated
+		}
+		if v, ok := manifest.Stores[step.Store]; !ok || v.Version != step.To {
+			manifest.Stores[step.Store] = &StoreVersion{Version: step.To, UpdatedAt: now}
+		}
+	}
+	if err := m.saveManifest(manifest); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// migrate 读取存储并依次执行 from 之后的迁移
+func (m *Migrator) migrate(store *Store, path string, from int, step *Step) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", store.Name, err)
+	}
+	if store.Codec != nil {
+		if data, err = store.Codec.Decode(data); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", store.Name, err)
+		}
+	}
+	for _, mig := range store.Migrations[from:] {
+		if data, err = mig.Up(data); err != nil {
+			return nil, fmt.Errorf("migration %s v%d (%s) failed: %w", store.Name, mig.Version, mig.Description, err)
+		}
+		step.Applied = append(step.Applied, fmt.Sprintf("v%d: %s", mig.Version, mig.Description))
+	}
+	if store.Codec != nil {
+		if data, err = store.Codec.Encode(data); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", store.Name, err)
+		}
+	}
+	return data, nil
+}
+
+// backup 把文件按相对数据目录的路径复制到备份目录
+func (m *Migrator) backup(dir string, files []string) error {
+	for _, file := range files {
+		rel, err := filepath.Rel(m.config.DataDir, file)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to back up %s: %w", rel, err)
+		}
+		target := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return fmt.Errorf("failed to create backup dir: %w", err)
+		}
+		if err := os.WriteFile(target, data, 0600); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) manifestPath() string {
+	return filepath.Join(m.config.DataDir, ManifestFile)
+}
+
+func (m *Migrator) loadManifest() (*Manifest, bool, error) {
+	manifest := &Manifest{Stores: make(map[string]*StoreVersion)}
+	data, err := os.ReadFile(m.manifestPath())
+	if os.IsNotExist(err) {
+		return manifest, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read schema manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, false, fmt.Errorf("failed to parse schema manifest: %w", err)
+	}
+	if manifest.Stores == nil {
+		manifest.Stores = make(map[string]*StoreVersion)
+	}
+	return manifest, true, nil
+}
+
+func (m *Migrator) saveManifest(manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.config.DataDir, 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(m.manifestPath(), data); err != nil {
+		return fmt.Errorf("failed to write schema manifest: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再改名，避免写入中途崩溃留下半个文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// JSONObject 把处理 JSON 对象的函数包装为迁移函数
+func JSONObject(fn func(obj map[string]interface{}) error) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		obj := make(map[string]interface{})
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		if err := fn(obj); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(obj, "", "  ")
+	}
+}
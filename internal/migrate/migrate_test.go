@@ -0,0 +1,180 @@
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// rot1 测试用编解码，模拟加密存储
+type rot1 struct{}
+
+func (rot1) Decode(data []byte) ([]byte, error) { return shift(data, -1), nil }
+func (rot1) Encode(data []byte) ([]byte, error) { return shift(data, 1), nil }
+
+func shift(data []byte, d int) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = byte(int(b) + d)
+	}
+	return out
+}
+
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, _ := json.Marshal(v)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readJSON(t *testing.T, path string, codec Codec) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codec != nil {
+		data, _ = codec.Decode(data)
+	}
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(data, &obj); err != nil {
+		t.Fatalf("invalid JSON in %s: %v", path, err)
+	}
+	return obj
+}
+
+// taskStore 两次迁移：v1 重命名字段，v2 增加默认值
+func taskStore() *Store {
+	return &Store{
+		Name: "tasks",
+		Path: "tasks/tasks.json",
+		Migrations: []Migration{
+			{Version: 1, Description: "rename owner to requester", Up: JSONObject(func(obj map[string]interface{}) error {
+				obj["requester"] = obj["owner"]
+				delete(obj, "owner")
+				return nil
+			})},
+			{Version: 2, Description: "default priority", Up: JSONObject(func(obj map[string]interface{}) error {
+				if _, ok := obj["priority"]; !ok {
+					obj["priority"] = "normal"
+				}
+				return nil
+			})},
+		},
+	}
+}
+
+func TestMigratorRun(t *testing.T) {
+	dir := t.TempDir()
+	tasksPath := filepath.Join(dir, "tasks", "tasks.json")
+	writeJSON(t, tasksPath, map[string]interface{}{"owner": "node-a"})
+	mailPath := filepath.Join(dir, "mailbox", "mailbox.json")
+	os.MkdirAll(filepath.Dir(mailPath), 0755)
+	sealed, _ := rot1{}.Encode([]byte(`{"inbox":{}}`))
+	os.WriteFile(mailPath, sealed, 0644)
+
+	mail := &Store{Name: "mailbox", Path: "mailbox/mailbox.json", Codec: rot1{}, Migrations: []Migration{
+		{Version: 1, Description: "add public folder", Up: JSONObject(func(obj map[string]interface{}) error {
+			obj["public"] = map[string]interface{}{}
+			return nil
+		})},
+	}}
+	fresh := &Store{Name: "bulletin", Path: "bulletin/bulletin.json", Migrations: taskStore().Migrations}
+
+	// dry-run 不写任何文件
+	dry, _ := NewMigrator(&Config{DataDir: dir, DryRun: true}, taskStore(), mail, fresh)
+	report, err := dry.Run()
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !report.Migrated() || report.Steps[0].Status != StatusPending || len(report.Steps[0].Applied) != 2 {
+		t.Errorf("unexpected dry-run report: %+v", report.Steps[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); !os.IsNotExist(err) {
+		t.Error("dry run should not write the manifest")
+	}
+	if obj := readJSON(t, tasksPath, nil); obj["owner"] != "node-a" {
+		t.Error("dry run should not modify data")
+	}
+
+	m, _ := NewMigrator(&Config{DataDir: dir}, taskStore(), mail, fresh)
+	m.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	report, err = m.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if obj := readJSON(t, tasksPath, nil); obj["requester"] != "node-a" || obj["priority"] != "normal" || obj["owner"] != nil {
+		t.Errorf("tasks not migrated: %v", obj)
+	}
+	if obj := readJSON(t, mailPath, rot1{}); obj["public"] == nil {
+		t.Errorf("encoded store not migrated: %v", obj)
+	}
+	if report.Steps[2].Status != StatusStamped || report.Steps[2].From != 2 {
+		t.Errorf("missing store should be stamped at latest: %+v", report.Steps[2])
+	}
+
+	// 原文件备份在 backup/migrations/<时间>/ 下
+	wantBackup := filepath.Join(dir, "backup", "migrations", "20260102T030405Z")
+	if report.BackupDir != wantBackup {
+		t.Errorf("BackupDir = %s, want %s", report.BackupDir, wantBackup)
+	}
+	if obj := readJSON(t, filepath.Join(wantBackup, "tasks", "tasks.json"), nil); obj["owner"] != "node-a" {
+		t.Errorf("backup should hold the original data: %v", obj)
+	}
+
+	// 再次运行无事可做
+	report, err = m.Run()
+	if err != nil || report.Migrated() {
+		t.Errorf("second run should be a no-op: %+v, %v", report, err)
+	}
+	for _, step := range report.Steps {
+		if step.Status != StatusCurrent {
+			t.Errorf("%s status = %s, want current", step.Store, step.Status)
+		}
+	}
+}
+
+func TestMigratorFailureWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	tasksPath := filepath.Join(dir, "tasks", "tasks.json")
+	writeJSON(t, tasksPath, map[string]interface{}{"owner": "node-a"})
+	original, _ := os.ReadFile(tasksPath)
+
+	broken := taskStore()
+	broken.Migrations[1].Up = func([]byte) ([]byte, error) { return nil, errors.New("boom") }
+	m, _ := NewMigrator(&Config{DataDir: dir}, broken)
+	if _, err := m.Run(); err == nil {
+		t.Fatal("expected migration error")
+	}
+	if data, _ := os.ReadFile(tasksPath); !bytes.Equal(data, original) {
+		t.Error("failed migration should leave data untouched")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); !os.IsNotExist(err) {
+		t.Error("failed migration should not write the manifest")
+	}
+}
+
+func TestMigratorRejectsNewerSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeJSON(t, filepath.Join(dir, ManifestFile), Manifest{Stores: map[string]*StoreVersion{"tasks": {Version: 5}}})
+	m, _ := NewMigrator(&Config{DataDir: dir}, taskStore())
+	if _, err := m.Run(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("expected ErrNewerSchema, got %v", err)
+	}
+}
+
+func TestNewMigratorValidation(t *testing.T) {
+	bad := &Store{Name: "x", Path: "x.json", Migrations: []Migration{{Version: 2, Up: JSONObject(func(map[string]interface{}) error { return nil })}}}
+	if _, err := NewMigrator(&Config{DataDir: t.TempDir()}, bad); !errors.Is(err, ErrInvalidStore) {
+		t.Errorf("expected ErrInvalidStore for gap in versions, got %v", err)
+	}
+	if _, err := NewMigrator(&Config{DataDir: t.TempDir()}, taskStore(), taskStore()); !errors.Is(err, ErrInvalidStore) {
+		t.Errorf("expected ErrInvalidStore for duplicate names, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package namespace 网络命名空间
+//
+// 多个逻辑网络共用同一批引导节点时，用命名空间隔离应用数据：命名空间混入
+// 流协议ID、DHT 协议前缀、发现用的 rendezvous 和 pubsub 主题。不同命名空间的
+// 节点即使直接相连，也协商不出共同的协议、加入不到同一主题、查不到彼此的 DHT
+// 记录，只共享 libp2p 的底层连接（identify、relay 等）。
+//
+// 空命名空间为默认网络，协议ID和主题保持原样，与未引入命名空间的节点互通。
+package namespace
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxLength 命名空间最大长度
+const MaxLength = 32
+
+// ErrInvalidNamespace 命名空间不合法
+var ErrInvalidNamespace = errors.New("invalid network namespace")
+
+// Validate 校验命名空间：小写字母、数字和连字符，不以连字符开头或结尾
+func Validate(ns string) error {
+	if ns == "" {
+		return nil
+	}
+	if len(ns) > MaxLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidNamespace, MaxLength)
+	}
+	for i, c := range ns {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && i > 0 && i < len(ns)-1:
+		default:
+			return fmt.Errorf("%w: %q (use lowercase letters, digits and inner hyphens)", ErrInvalidNamespace, ns)
+		}
+	}
+	return nil
+}
+
+// prefix 命名空间前缀，默认网络为空
+func prefix(ns string) string {
+	if ns == "" {
+		return ""
+	}
+	return "/ns/" + ns
+}
+
+// Protocol 返回命名空间内的流协议ID，例如 /daan/message/1.0.0 → /ns/acme/daan/message/1.0.0
+func Protocol(ns, id string) string {
+	return prefix(ns) + id
+}
+
+// Topic 返回命名空间内的 pubsub 主题
+func Topic(ns, topic string) string {
+	return prefix(ns) + topic
+}
+
+// Rendezvous 返回命名空间内的节点发现 rendezvous 字符串
+func Rendezvous(ns, base string) string {
+	return prefix(ns) + base
+}
+
+// DHTPrefix 返回 DHT 协议前缀，默认网络为空（使用 libp2p 默认的 /ipfs）
+func DHTPrefix(ns string) string {
+	if ns == "" {
+		return ""
+	}
+	return "/daan" + prefix(ns)
+}
@@ -0,0 +1,51 @@
+package namespace
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, ns := range []string{"", "acme", "team-1", "a"} {
+		if err := Validate(ns); err != nil {
+			t.Errorf("Validate(%q) = %v, want nil", ns, err)
+		}
+	}
+	for _, ns := range []string{"Acme", "-acme", "acme-", "ac/me", "ac me", "0123456789012345678901234567890123"} {
+		if err := Validate(ns); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidNamespace", ns, err)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	// 默认网络保持原有标识
+	if got := Protocol("", "/daan/message/1.0.0"); got != "/daan/message/1.0.0" {
+		t.Errorf("Protocol default = %s", got)
+	}
+	if got := Topic("", "/daan/task"); got != "/daan/task" {
+		t.Errorf("Topic default = %s", got)
+	}
+	if got := DHTPrefix(""); got != "" {
+		t.Errorf("DHTPrefix default = %s", got)
+	}
+
+	if got := Protocol("acme", "/daan/message/1.0.0"); got != "/ns/acme/daan/message/1.0.0" {
+		t.Errorf("Protocol = %s", got)
+	}
+	if got := Topic("acme", "/daan/task"); got != "/ns/acme/daan/task" {
+		t.Errorf("Topic = %s", got)
+	}
+	if got := Rendezvous("acme", "/daan/1.0.0"); got != "/ns/acme/daan/1.0.0" {
+		t.Errorf("Rendezvous = %s", got)
+	}
+	if got := DHTPrefix("acme"); got != "/daan/ns/acme" {
+		t.Errorf("DHTPrefix = %s", got)
+	}
+	if DHTPrefix("acme") == DHTPrefix("globex") {
+		t.Error("different namespaces must not share a DHT")
+	}
+	if Topic("acme", "/daan/task") == Topic("globex", "/daan/task") {
+		t.Error("different namespaces must not share topics")
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/heartbeat"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
 )
 
 // 错误定义
@@ -62,10 +65,12 @@ type NeighborConfig struct {
 	MaxNeighbors       int           `json:"max_neighbors"`        // 最大邻居数
 	MinReputation      int64         `json:"min_reputation"`       // 最低声誉要求
 	PingInterval       time.Duration `json:"ping_interval"`        // 心跳间隔
+	MinPingInterval    time.Duration `json:"min_ping_interval"`    // 自适应心跳间隔下限，心跳失败后收紧到该值（0 表示同 PingInterval）
+	MaxPingInterval    time.Duration `json:"max_ping_interval"`    // 自适应心跳间隔上限，邻居稳定时逐步放宽到该值（0 表示同 PingInterval）
 	PingTimeout        time.Duration `json:"ping_timeout"`         // 心跳超时
 	MaxPingFailures    int           `json:"max_ping_failures"`    // 最大心跳失败次数
 	RefreshInterval    time.Duration `json:"refresh_interval"`     // 刷新间隔
-	OfflineThreshold   time.Duration `json:"offline_threshold"`    // 离线阈值
+	OfflineThreshold   time.Duration `json:"offline_threshold"`    // 离线阈值，不低于当前心跳间隔的 2 倍
 }
 
 // DefaultConfig 默认配置
@@ -75,6 +80,8 @@ func DefaultConfig() *NeighborConfig {
 		MaxNeighbors:       15,
 		MinReputation:      5,
 		PingInterval:       30 * time.Second,
+		MinPingInterval:    heartbeat.DefaultMinInterval,
+		MaxPingInterval:    heartbeat.DefaultMaxInterval,
 		PingTimeout:        5 * time.Second,
 		MaxPingFailures:    3,
 		RefreshInterval:    5 * time.Minute,
@@ -88,6 +95,12 @@ type PingFunc func(nodeID string) error
 // ReputationFunc 获取节点声誉函数类型
 type ReputationFunc func(nodeID string) (int64, error)
 
+// MaintenanceFunc 判断节点是否已通告维护状态
+type MaintenanceFunc func(nodeID string) bool
+
+// LivenessFunc 获取节点心跳存活度（0-1）函数类型
+type LivenessFunc func(nodeID string) float64
+
 // CandidateProvider 候选邻居提供者
 type CandidateProvider interface {
 	GetCandidates(excludeIDs []string, count int) ([]*Neighbor, error)
@@ -104,6 +117,11 @@ type NeighborManager struct {
 	pingFunc       PingFunc
 	reputationFunc ReputationFunc
 	candidateProvider CandidateProvider
+	maintenanceFunc   MaintenanceFunc
+	livenessFunc      LivenessFunc
+	
+	// 自适应心跳间隔
+	pingInterval *heartbeat.AdaptiveInterval
 	
 	// 事件通知
 	onNeighborAdded   func(*Neighbor)
@@ -123,12 +141,21 @@ func NewNeighborManager(config *NeighborConfig) *NeighborManager {
 	
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	minInterval, maxInterval := config.MinPingInterval, config.MaxPingInterval
+	if minInterval <= 0 {
+		minInterval = config.PingInterval
+	}
+	if maxInterval <= 0 {
+		maxInterval = config.PingInterval
+	}
+	
 	return &NeighborManager{
-		config:     config,
-		neighbors:  make(map[string]*Neighbor),
-		candidates: make(map[string]*Neighbor),
-		ctx:        ctx,
-		cancel:     cancel,
+		config:       config,
+		neighbors:    make(map[string]*Neighbor),
+		candidates:   make(map[string]*Neighbor),
+		pingInterval: heartbeat.NewAdaptiveInterval(config.PingInterval, minInterval, maxInterval),
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -147,6 +174,25 @@ func (nm *NeighborManager) SetCandidateProvider(cp CandidateProvider) {
 	nm.candidateProvider = cp
 }
 
+// SetMaintenanceFunc 设置维护状态查询函数
+// 处于维护中的邻居心跳失败不计入失败次数，也不会因离线被移除
+func (nm *NeighborManager) SetMaintenanceFunc(fn MaintenanceFunc) {
+	nm.maintenanceFunc = fn
+}
+
+// inMaintenance 邻居是否处于维护中
+func (nm *NeighborManager) inMaintenance(nodeID string) bool {
+	return nm.maintenanceFunc != nil && nm.maintenanceFunc(nodeID)
+}
+
+// SetLivenessFunc 设置心跳存活度查询函数
+// 设置后信任分纳入心跳存活度，主动心跳检测的权重相应降低
+func (nm *NeighborManager) SetLivenessFunc(fn LivenessFunc) {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	nm.livenessFunc = fn
+}
+
 // SetOnNeighborAdded 设置邻居添加回调
 func (nm *NeighborManager) SetOnNeighborAdded(fn func(*Neighbor)) {
 	nm.onNeighborAdded = fn
@@ -348,6 +394,20 @@ func (nm *NeighborManager) UpdateNeighborReputation(nodeID string, reputation in
 	return nil
 }
 
+// SetNeighborType 修改邻居类型（如治理提案晋升或撤销超级节点）
+func (nm *NeighborManager) SetNeighborType(nodeID string, t NeighborType) error {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	
+	neighbor, ok := nm.neighbors[nodeID]
+	if !ok {
+		return ErrNeighborNotFound
+	}
+	
+	neighbor.Type = t
+	return nil
+}
+
 // UpdateNeighborContribution 更新邻居贡献
 func (nm *NeighborManager) UpdateNeighborContribution(nodeID string, delta int64) error {
 	nm.mu.Lock()
@@ -395,7 +455,7 @@ func (nm *NeighborManager) Ping(nodeID string) error {
 		neighbor.SuccessfulPings++
 		neighbor.FailedPings = 0
 		nm.updateTrustScoreLocked(neighbor)
-	} else {
+	} else if !nm.inMaintenance(nodeID) {
 		neighbor.FailedPings++
 		if neighbor.FailedPings >= nm.config.MaxPingFailures {
 			neighbor.PingStatus = StatusOffline
@@ -408,6 +468,23 @@ func (nm *NeighborManager) Ping(nodeID string) error {
 	return err
 }
 
+// RecordHeartbeat 记录收到邻居广播的心跳：刷新最近在线时间和信任分
+func (nm *NeighborManager) RecordHeartbeat(nodeID string) error {
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	
+	neighbor, ok := nm.neighbors[nodeID]
+	if !ok {
+		return ErrNeighborNotFound
+	}
+	
+	neighbor.LastSeen = time.Now()
+	neighbor.PingStatus = StatusOnline
+	nm.updateTrustScoreLocked(neighbor)
+	
+	return nil
+}
+
 // PingAll 对所有邻居进行心跳检测
 func (nm *NeighborManager) PingAll() map[string]error {
 	neighbors := nm.GetAllNeighbors()
@@ -431,6 +508,36 @@ func (nm *NeighborManager) PingAll() map[string]error {
 	return results
 }
 
+// adaptPingInterval 根据一轮心跳结果调整心跳间隔并返回下一轮的间隔：
+// 有邻居心跳失败时收紧到下限，否则视为稳定；维护中和已移除的邻居不计
+func (nm *NeighborManager) adaptPingInterval(results map[string]error) time.Duration {
+	var failed []string
+	for nodeID, err := range results {
+		if err != nil && !errors.Is(err, ErrNeighborNotFound) && !nm.inMaintenance(nodeID) {
+			failed = append(failed, nodeID)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return nm.pingInterval.Unstable(fmt.Sprintf("ping %s failed", failed[0]))
+	}
+	return nm.pingInterval.Stable()
+}
+
+// PingIntervalStatus 自适应心跳间隔的当前状态
+func (nm *NeighborManager) PingIntervalStatus() heartbeat.IntervalStatus {
+	return nm.pingInterval.Status()
+}
+
+// offlineThreshold 离线阈值，心跳间隔放宽后相应延长
+func (nm *NeighborManager) offlineThreshold() time.Duration {
+	threshold := nm.config.OfflineThreshold
+	if d := 2 * nm.pingInterval.Current(); d > threshold {
+		threshold = d
+	}
+	return threshold
+}
+
 // GetBestNeighbors 获取最优邻居（用于消息转发）
 func (nm *NeighborManager) GetBestNeighbors(count int) []*Neighbor {
 	nm.mu.RLock()
@@ -539,6 +646,31 @@ func (nm *NeighborManager) GetStats() map[string]interface{} {
 		"avg_trust_score": avgTrust,
 		"min_neighbors":   nm.config.MinNeighbors,
 		"max_neighbors":   nm.config.MaxNeighbors,
+		"ping_interval":   nm.pingInterval.Status(),
+	}
+}
+
+// Stats 实现 stats.Provider
+func (nm *NeighborManager) Stats() []stats.Metric {
+	nm.mu.RLock()
+	defer nm.mu.RUnlock()
+	online, offline := 0, 0
+	for _, n := range nm.neighbors {
+		switch n.PingStatus {
+		case StatusOnline:
+			online++
+		case StatusOffline:
+			offline++
+		}
+	}
+	return []stats.Metric{
+		stats.Gauge("neighbors", float64(len(nm.neighbors)), "邻居数"),
+		stats.Gauge("online_neighbors", float64(online), "在线邻居数"),
+		stats.Gauge("offline_neighbors", float64(offline), "离线邻居数"),
+		stats.Gauge("candidates", float64(len(nm.candidates)), "候选邻居数"),
+		stats.Gauge("min_neighbors", float64(nm.config.MinNeighbors), "邻居数下限"),
+		stats.Gauge("max_neighbors", float64(nm.config.MaxNeighbors), "邻居数上限"),
+		stats.Gauge("ping_interval_seconds", nm.pingInterval.Current().Seconds(), "当前邻居心跳间隔"),
 	}
 }
 
@@ -547,16 +679,22 @@ func (nm *NeighborManager) GetStats() map[string]interface{} {
 func (nm *NeighborManager) pingLoop() {
 	defer nm.wg.Done()
 	
-	ticker := time.NewTicker(nm.config.PingInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(nm.pingInterval.Current())
+	defer timer.Stop()
 	
 	for {
 		select {
 		case <-nm.ctx.Done():
 			return
-		case <-ticker.C:
-			nm.PingAll()
+		case <-timer.C:
+			results := nm.PingAll()
+			next := nm.pingInterval.Current()
+			// 未设置心跳函数时全部失败，不代表网络不稳定
+			if nm.pingFunc != nil {
+				next = nm.adaptPingInterval(results)
+			}
 			nm.checkOfflineNeighbors()
+			timer.Reset(next)
 		}
 	}
 }
@@ -582,8 +720,12 @@ func (nm *NeighborManager) checkOfflineNeighbors() {
 	defer nm.mu.Unlock()
 	
 	now := time.Now()
+	threshold := nm.offlineThreshold()
 	for nodeID, n := range nm.neighbors {
-		if now.Sub(n.LastSeen) > nm.config.OfflineThreshold {
+		if nm.inMaintenance(nodeID) {
+			continue
+		}
+		if now.Sub(n.LastSeen) > threshold {
 			if n.PingStatus != StatusOffline {
 				n.PingStatus = StatusOffline
 				if nm.onNeighborOffline != nil {
@@ -592,7 +734,7 @@ func (nm *NeighborManager) checkOfflineNeighbors() {
 			}
 			
 			// 如果长时间离线，移除
-			if now.Sub(n.LastSeen) > nm.config.OfflineThreshold*3 {
+			if now.Sub(n.LastSeen) > threshold*3 {
 				delete(nm.neighbors, nodeID)
 				if nm.onNeighborRemoved != nil {
 					go nm.onNeighborRemoved(n)
@@ -627,13 +769,24 @@ func (nm *NeighborManager) fillNeighbors() {
 		return
 	}
 	
-	// 从候选列表提升
+	// 从候选列表提升，信任分高的优先
 	nm.mu.Lock()
-	for nodeID, candidate := range nm.candidates {
+	ranked := make([]*Neighbor, 0, len(nm.candidates))
+	for _, candidate := range nm.candidates {
+		ranked = append(ranked, candidate)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].TrustScore != ranked[j].TrustScore {
+			return ranked[i].TrustScore > ranked[j].TrustScore
+		}
+		return ranked[i].NodeID < ranked[j].NodeID
+	})
+	for _, candidate := range ranked {
 		if currentCount >= nm.config.MinNeighbors {
 			break
 		}
 		if candidate.Reputation >= nm.config.MinReputation {
+			nodeID := candidate.NodeID
 			delete(nm.candidates, nodeID)
 			nm.neighbors[nodeID] = candidate
 			candidate.AddedAt = time.Now()
@@ -726,7 +879,11 @@ func (nm *NeighborManager) updateTrustScoreLocked(n *Neighbor) {
 		contributionScore = 1.0
 	}
 	
-	// 加权计算
+	// 加权计算，有心跳存活度时从心跳检测的权重中分出一部分
+	if nm.livenessFunc != nil {
+		n.TrustScore = pingRate*0.25 + nm.livenessFunc(n.NodeID)*0.15 + reputationScore*0.4 + contributionScore*0.2
+		return
+	}
 	n.TrustScore = pingRate*0.4 + reputationScore*0.4 + contributionScore*0.2
 }
 
@@ -172,6 +172,22 @@ func TestGetNeighborsByType(t *testing.T) {
 	}
 }
 
+func TestSetNeighborType(t *testing.T) {
+	nm := NewNeighborManager(nil)
+
+	nm.AddNeighbor(&Neighbor{NodeID: "s1", Reputation: 10, Type: TypeSuper})
+
+	if err := nm.SetNeighborType("s1", TypeNormal); err != nil {
+		t.Fatalf("修改邻居类型失败: %v", err)
+	}
+	if super := nm.GetNeighborsByType(TypeSuper); len(super) != 0 {
+		t.Errorf("撤销后仍有超级邻居: %d", len(super))
+	}
+	if err := nm.SetNeighborType("missing", TypeSuper); err != ErrNeighborNotFound {
+		t.Errorf("期望 ErrNeighborNotFound, got %v", err)
+	}
+}
+
 func TestIsNeighbor(t *testing.T) {
 	nm := NewNeighborManager(nil)
 
@@ -279,6 +295,67 @@ func TestPingFailure(t *testing.T) {
 	}
 }
 
+func TestPingFailureInMaintenance(t *testing.T) {
+	config := &NeighborConfig{
+		MinNeighbors:     1,
+		MaxNeighbors:     10,
+		MinReputation:    1,
+		MaxPingFailures:  2,
+		OfflineThreshold: time.Millisecond,
+	}
+	nm := NewNeighborManager(config)
+
+	nm.AddNeighbor(&Neighbor{NodeID: "node1", Reputation: 10})
+	nm.SetPingFunc(func(nodeID string) error {
+		return errors.New("ping failed")
+	})
+	nm.SetMaintenanceFunc(func(nodeID string) bool {
+		return nodeID == "node1"
+	})
+
+	nm.Ping("node1")
+	nm.Ping("node1")
+
+	n, _ := nm.GetNeighbor("node1")
+	if n.FailedPings != 0 || n.PingStatus == StatusOffline {
+		t.Errorf("维护中的邻居不应被计为失败: failed=%d status=%s", n.FailedPings, n.PingStatus)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	nm.checkOfflineNeighbors()
+	if !nm.IsNeighbor("node1") {
+		t.Error("维护中的邻居不应因离线被移除")
+	}
+}
+
+func TestRecordHeartbeatLiveness(t *testing.T) {
+	nm := NewNeighborManager(nil)
+
+	nm.AddNeighbor(&Neighbor{NodeID: "steady", Reputation: 100})
+	nm.AddNeighbor(&Neighbor{NodeID: "flaky", Reputation: 100})
+	nm.SetLivenessFunc(func(nodeID string) float64 {
+		if nodeID == "steady" {
+			return 1
+		}
+		return 0.2
+	})
+
+	if err := nm.RecordHeartbeat("unknown"); err != ErrNeighborNotFound {
+		t.Errorf("未知邻居应返回 ErrNeighborNotFound: %v", err)
+	}
+	nm.RecordHeartbeat("steady")
+	nm.RecordHeartbeat("flaky")
+
+	steady, _ := nm.GetNeighbor("steady")
+	flaky, _ := nm.GetNeighbor("flaky")
+	if steady.PingStatus != StatusOnline || steady.LastSeen.IsZero() {
+		t.Errorf("收到心跳后应为在线: status=%s", steady.PingStatus)
+	}
+	if steady.TrustScore <= flaky.TrustScore {
+		t.Errorf("心跳存活度高的邻居信任分应更高: steady=%.3f flaky=%.3f", steady.TrustScore, flaky.TrustScore)
+	}
+}
+
 func TestPingAll(t *testing.T) {
 	nm := NewNeighborManager(nil)
 
@@ -305,6 +382,54 @@ func TestPingAll(t *testing.T) {
 	}
 }
 
+func TestAdaptivePingInterval(t *testing.T) {
+	nm := NewNeighborManager(nil)
+
+	nm.AddNeighbor(&Neighbor{NodeID: "a", Reputation: 10})
+	nm.AddNeighbor(&Neighbor{NodeID: "b", Reputation: 10})
+	var failing atomic.Value
+	failing.Store("")
+	nm.SetPingFunc(func(nodeID string) error {
+		if nodeID == failing.Load().(string) {
+			return errors.New("ping failed")
+		}
+		return nil
+	})
+
+	// 连续 3 轮成功后放宽，离线阈值随之延长
+	var next time.Duration
+	for i := 0; i < 9; i++ {
+		next = nm.adaptPingInterval(nm.PingAll())
+	}
+	if next != 101250*time.Millisecond {
+		t.Fatalf("稳定后的心跳间隔错误: got %v", next)
+	}
+	if got := nm.offlineThreshold(); got != 2*next {
+		t.Errorf("离线阈值应为心跳间隔的 2 倍: got %v", got)
+	}
+
+	// 心跳失败后收紧到下限
+	failing.Store("b")
+	if next = nm.adaptPingInterval(nm.PingAll()); next != 10*time.Second {
+		t.Fatalf("失败后的心跳间隔错误: got %v", next)
+	}
+	if got := nm.offlineThreshold(); got != nm.config.OfflineThreshold {
+		t.Errorf("离线阈值不应低于配置: got %v", got)
+	}
+	if status := nm.PingIntervalStatus(); status.Tightened != 1 || status.CurrentMs != 10000 {
+		t.Errorf("心跳间隔状态错误: %+v", status)
+	}
+
+	// 维护中的邻居失败不收紧
+	nm.SetMaintenanceFunc(func(nodeID string) bool { return nodeID == "b" })
+	for i := 0; i < 3; i++ {
+		next = nm.adaptPingInterval(nm.PingAll())
+	}
+	if next != 15*time.Second {
+		t.Errorf("维护中的邻居不应影响心跳间隔: got %v", next)
+	}
+}
+
 func TestGetBestNeighbors(t *testing.T) {
 	config := &NeighborConfig{
 		MinNeighbors:  1,
@@ -373,6 +498,22 @@ func TestCandidates(t *testing.T) {
 	}
 }
 
+func TestFillNeighborsByTrustScore(t *testing.T) {
+	config := DefaultConfig()
+	config.MinNeighbors = 2
+	nm := NewNeighborManager(config)
+
+	nm.AddCandidate(&Neighbor{NodeID: "slow", Reputation: 10, TrustScore: 0.2})
+	nm.AddCandidate(&Neighbor{NodeID: "fast", Reputation: 10, TrustScore: 0.9})
+	nm.AddCandidate(&Neighbor{NodeID: "good", Reputation: 10, TrustScore: 0.6})
+	nm.AddCandidate(&Neighbor{NodeID: "untrusted", Reputation: 1, TrustScore: 1})
+	nm.fillNeighbors()
+
+	if !nm.IsNeighbor("fast") || !nm.IsNeighbor("good") || nm.NeighborCount() != 2 {
+		t.Errorf("应按信任分提升候选: %v", nm.GetAllNeighbors())
+	}
+}
+
 func TestNeedMoreNeighbors(t *testing.T) {
 	config := &NeighborConfig{
 		MinNeighbors:  3,
@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
 )
 
 // BroadcastMessage 广播消息
@@ -18,11 +23,16 @@ type BroadcastMessage struct {
 	Sender    string    `json:"sender"`
 	Timestamp time.Time `json:"timestamp"`
 	Payload   []byte    `json:"payload"`
+	// From GossipSub 验签过的原始发布节点，接收时填入，不随消息传输；Sender 由发布方自行声明
+	From string `json:"-"`
 }
 
 // TopicHandler 主题消息处理器
 type TopicHandler func(msg *BroadcastMessage)
 
+// TopicValidator 主题消息校验，返回 false 的消息既不投递也不转发给其他节点
+type TopicValidator func(msg *BroadcastMessage) bool
+
 // Subscription 订阅
 type Subscription struct {
 	topic   *pubsub.Topic
@@ -36,15 +46,27 @@ type Broadcaster struct {
 	host   host.Host
 	pubsub *pubsub.PubSub
 
-	topics map[string]*pubsub.Topic
-	subs   map[string]*Subscription
-	mu     sync.RWMutex
+	topics     map[string]*pubsub.Topic
+	subs       map[string]*Subscription
+	validators map[string]TopicValidator
+	mu         sync.RWMutex
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// 网络命名空间，加入 pubsub 主题时混入主题名
+	namespace string
+
+	// 自适应调参（未启用时为 nil）
+	tuner    *GossipTuner
+	tracer   *bandwidthTracer
+	psCancel context.CancelFunc
+
+	// 节点评分（未启用时为 nil）
+	scorer *GossipScorer
 }
 
-// NewBroadcaster 创建广播器
+// NewBroadcaster 创建广播器（使用 GossipSub 默认参数）
 func NewBroadcaster(h host.Host) (*Broadcaster, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -56,15 +78,250 @@ func NewBroadcaster(h host.Host) (*Broadcaster, error) {
 	}
 
 	return &Broadcaster{
-		host:   h,
-		pubsub: ps,
-		topics: make(map[string]*pubsub.Topic),
-		subs:   make(map[string]*Subscription),
-		ctx:    ctx,
-		cancel: cancel,
+		host:       h,
+		pubsub:     ps,
+		topics:     make(map[string]*pubsub.Topic),
+		subs:       make(map[string]*Subscription),
+		validators: make(map[string]TopicValidator),
+		ctx:        ctx,
+		cancel:     cancel,
 	}, nil
 }
 
+// NewBroadcasterWithTuning 创建根据带宽与 CPU 压力自适应调整扇出和心跳的广播器
+// 突发流量时在 config.Floor 与 config.Ceiling 之间收缩 mesh 度、放慢心跳，负载回落后再恢复；
+// 未开启 config.Rebuild 时保持初始参数（Ceiling），只计算并上报目标参数。
+func NewBroadcasterWithTuning(h host.Host, config *GossipTuningConfig) (*Broadcaster, error) {
+	return NewScoredBroadcaster(h, config, nil)
+}
+
+// NewScoredBroadcaster 创建自适应调参并按 scorer 给节点评分的广播器
+// scorer 为 nil 时与 NewBroadcasterWithTuning 相同；低分节点由 GossipSub 在心跳时移出 mesh。
+func NewScoredBroadcaster(h host.Host, config *GossipTuningConfig, scorer *GossipScorer) (*Broadcaster, error) {
+	if config == nil {
+		config = DefaultGossipTuningConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Broadcaster{
+		host:       h,
+		topics:     make(map[string]*pubsub.Topic),
+		subs:       make(map[string]*Subscription),
+		validators: make(map[string]TopicValidator),
+		ctx:        ctx,
+		cancel:     cancel,
+		tuner:      NewGossipTuner(config),
+		tracer:     &bandwidthTracer{},
+		scorer:     scorer,
+	}
+
+	ps, psCancel, err := b.newPubSub(config.Ceiling)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("创建 PubSub 失败: %w", err)
+	}
+	b.pubsub = ps
+	b.psCancel = psCancel
+
+	go b.tuneLoop(config.SampleInterval)
+	return b, nil
+}
+
+// newPubSub 以指定参数创建 GossipSub 实例
+func (b *Broadcaster) newPubSub(params GossipParams) (*pubsub.PubSub, context.CancelFunc, error) {
+	gsParams := pubsub.DefaultGossipSubParams()
+	gsParams.D = params.D
+	gsParams.Dlo = params.Dlo
+	gsParams.Dhi = params.Dhi
+	gsParams.Dlazy = params.Dlazy
+	gsParams.Dout = params.Dout()
+	if gsParams.Dscore > params.Dhi {
+		gsParams.Dscore = params.Dhi
+	}
+	gsParams.HeartbeatInterval = params.HeartbeatInterval
+
+	opts := []pubsub.Option{
+		pubsub.WithGossipSubParams(gsParams),
+		pubsub.WithRawTracer(b.tracer),
+	}
+	if b.scorer != nil {
+		opts = append(opts, b.scoreOptions()...)
+	}
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	ps, err := pubsub.NewGossipSub(ctx, b.host, opts...)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return ps, cancel, nil
+}
+
+// scoreOptions 启用节点评分的 GossipSub 选项
+// 只使用应用层分数和行为惩罚，不按主题计分；分数快照定期写回 scorer 供查询。
+func (b *Broadcaster) scoreOptions() []pubsub.Option {
+	cfg := b.scorer.Config()
+	params := &pubsub.PeerScoreParams{
+		SkipAtomicValidation: true,
+		AppSpecificScore: func(p peer.ID) float64 {
+			return b.scorer.AppScore(p.String())
+		},
+		AppSpecificWeight:         1,
+		BehaviourPenaltyWeight:    -10,
+		BehaviourPenaltyThreshold: 6,
+		BehaviourPenaltyDecay:     pubsub.ScoreParameterDecay(10 * time.Minute),
+		DecayInterval:             time.Second,
+		DecayToZero:               0.01,
+		RetainScore:               10 * time.Minute,
+	}
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:             cfg.Thresholds.Gossip,
+		PublishThreshold:            cfg.Thresholds.Publish,
+		GraylistThreshold:           cfg.Thresholds.Graylist,
+		AcceptPXThreshold:           cfg.Thresholds.AcceptPX,
+		OpportunisticGraftThreshold: cfg.Thresholds.OpportunisticGraft,
+	}
+	inspect := func(snapshot map[peer.ID]*pubsub.PeerScoreSnapshot) {
+		scores := make(map[string]RouterPeerScore, len(snapshot))
+		for p, s := range snapshot {
+			scores[p.String()] = RouterPeerScore{
+				Score:              s.Score,
+				AppSpecific:        s.AppSpecificScore,
+				BehaviourPenalty:   s.BehaviourPenalty,
+				IPColocationFactor: s.IPColocationFactor,
+			}
+		}
+		b.scorer.Update(scores)
+	}
+	interval := cfg.InspectInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return []pubsub.Option{
+		pubsub.WithPeerScore(params, thresholds),
+		pubsub.WithPeerScoreInspect(pubsub.ExtendedPeerScoreInspectFn(inspect), interval),
+	}
+}
+
+// GossipScorer 返回节点评分器，未启用评分时返回 nil
+func (b *Broadcaster) GossipScorer() *GossipScorer {
+	return b.scorer
+}
+
+// tuneLoop 定期采样负载并在需要时调参
+func (b *Broadcaster) tuneLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var bw rateMeter
+	var cpu cpuMeter
+	now := time.Now()
+	bw.rate(b.tracer.total(), now)
+	cpu.usage(processCPUTime(), runtime.NumCPU(), now)
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case now := <-ticker.C:
+			sample := PressureSample{
+				BandwidthBps: bw.rate(b.tracer.total(), now),
+				CPU:          cpu.usage(processCPUTime(), runtime.NumCPU(), now),
+			}
+			params, retune := b.tuner.Observe(sample, now)
+			if !retune || !b.tuner.config.Rebuild {
+				continue
+			}
+			if err := b.retune(params); err == nil {
+				b.tuner.Applied(params, now)
+			}
+		}
+	}
+}
+
+// retune 以新参数重建 GossipSub，并重新加入主题、恢复订阅
+// GossipSub 不支持运行时修改参数，因此调参需要重建实例，只在 GossipTuningConfig.Rebuild 开启时进行，
+// MinRetuneInterval 限制了重建频率。
+func (b *Broadcaster) retune(params GossipParams) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return b.ctx.Err()
+	}
+
+	for _, sub := range b.subs {
+		sub.cancel()
+		sub.sub.Cancel()
+	}
+	for _, topic := range b.topics {
+		topic.Close()
+	}
+	if b.psCancel != nil {
+		b.psCancel()
+	}
+
+	ps, psCancel, err := b.newPubSub(params)
+	if err != nil {
+		// 回退到当前参数，保证广播器可用
+		var fallbackErr error
+		ps, psCancel, fallbackErr = b.newPubSub(b.tuner.Current())
+		if fallbackErr != nil {
+			return fallbackErr
+		}
+	}
+	b.pubsub = ps
+	b.psCancel = psCancel
+
+	for name, validate := range b.validators {
+		b.registerValidatorLocked(name, validate)
+	}
+
+	topicNames := make([]string, 0, len(b.topics))
+	for name := range b.topics {
+		topicNames = append(topicNames, name)
+	}
+	b.topics = make(map[string]*pubsub.Topic)
+	for _, name := range topicNames {
+		b.getOrJoinTopicLocked(name)
+	}
+
+	oldSubs := b.subs
+	b.subs = make(map[string]*Subscription)
+	for name, old := range oldSubs {
+		topic, joinErr := b.getOrJoinTopicLocked(name)
+		if joinErr != nil {
+			continue
+		}
+		sub, subErr := topic.Subscribe()
+		if subErr != nil {
+			continue
+		}
+		ctx, cancel := context.WithCancel(b.ctx)
+		b.subs[name] = &Subscription{
+			topic:   topic,
+			sub:     sub,
+			handler: old.handler,
+			cancel:  cancel,
+		}
+		go b.receiveMessages(ctx, name, sub, old.handler)
+	}
+
+	return err
+}
+
+// GossipTuning 返回当前自适应参数与负载指标，未启用调参时返回 nil
+func (b *Broadcaster) GossipTuning() *GossipTuningStatus {
+	if b.tuner == nil {
+		return nil
+	}
+	status := b.tuner.Status()
+	return &status
+}
+
 // Broadcast 广播消息到指定主题
 func (b *Broadcaster) Broadcast(topicName string, payload []byte) error {
 	topic, err := b.getOrJoinTopic(topicName)
@@ -105,7 +362,59 @@ func (b *Broadcaster) Subscribe(topicName string, handler TopicHandler) error {
 	if _, exists := b.subs[topicName]; exists {
 		return fmt.Errorf("已订阅主题 %s", topicName)
 	}
+	return b.subscribeLocked(topicName, handler)
+}
+
+// SubscribeWithValidator 订阅主题并注册校验器
+// 校验在 GossipSub 转发前执行，本节点拒绝的消息不会继续传播；重建 GossipSub 时自动重新注册。
+func (b *Broadcaster) SubscribeWithValidator(topicName string, validate TopicValidator, handler TopicHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.subs[topicName]; exists {
+		return fmt.Errorf("已订阅主题 %s", topicName)
+	}
+	if err := b.registerValidatorLocked(topicName, validate); err != nil {
+		return err
+	}
+	if err := b.subscribeLocked(topicName, handler); err != nil {
+		b.pubsub.UnregisterTopicValidator(namespace.Topic(b.namespace, topicName))
+		return err
+	}
+	b.validators[topicName] = validate
+	return nil
+}
 
+// registerValidatorLocked 向 GossipSub 注册主题校验器（已持有锁）
+// 本节点发布的消息不经校验。
+func (b *Broadcaster) registerValidatorLocked(topicName string, validate TopicValidator) error {
+	self := b.host.ID()
+	err := b.pubsub.RegisterTopicValidator(namespace.Topic(b.namespace, topicName),
+		func(ctx context.Context, from peer.ID, msg *pubsub.Message) bool {
+			if from == self {
+				return true
+			}
+			var broadcastMsg BroadcastMessage
+			if err := json.Unmarshal(msg.Data, &broadcastMsg); err == nil {
+				broadcastMsg.From = msg.GetFrom().String()
+				if validate(&broadcastMsg) {
+					return true
+				}
+			}
+			// 被拒绝的消息计入转发节点的应用层分数
+			if b.scorer != nil {
+				b.scorer.RecordInvalid(from.String())
+			}
+			return false
+		})
+	if err != nil {
+		return fmt.Errorf("注册主题校验器失败: %w", err)
+	}
+	return nil
+}
+
+// subscribeLocked 订阅主题并启动接收协程（已持有锁）
+func (b *Broadcaster) subscribeLocked(topicName string, handler TopicHandler) error {
 	topic, err := b.getOrJoinTopicLocked(topicName)
 	if err != nil {
 		return err
@@ -146,6 +455,10 @@ func (b *Broadcaster) Unsubscribe(topicName string) error {
 	sub.cancel()
 	sub.sub.Cancel()
 	delete(b.subs, topicName)
+	if _, ok := b.validators[topicName]; ok {
+		b.pubsub.UnregisterTopicValidator(namespace.Topic(b.namespace, topicName))
+		delete(b.validators, topicName)
+	}
 
 	return nil
 }
@@ -170,6 +483,7 @@ func (b *Broadcaster) receiveMessages(ctx context.Context, topicName string, sub
 		if err := json.Unmarshal(msg.Data, &broadcastMsg); err != nil {
 			continue
 		}
+		broadcastMsg.From = msg.GetFrom().String()
 
 		// 调用处理器
 		if handler != nil {
@@ -178,6 +492,14 @@ func (b *Broadcaster) receiveMessages(ctx context.Context, topicName string, sub
 	}
 }
 
+// SetNamespace 设置网络命名空间，不同命名空间的节点不会加入同一主题
+// 需在广播或订阅之前调用；主题名对调用方保持不变。
+func (b *Broadcaster) SetNamespace(ns string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.namespace = ns
+}
+
 // getOrJoinTopic 获取或加入主题
 func (b *Broadcaster) getOrJoinTopic(topicName string) (*pubsub.Topic, error) {
 	b.mu.Lock()
@@ -191,7 +513,7 @@ func (b *Broadcaster) getOrJoinTopicLocked(topicName string) (*pubsub.Topic, err
 		return topic, nil
 	}
 
-	topic, err := b.pubsub.Join(topicName)
+	topic, err := b.pubsub.Join(namespace.Topic(b.namespace, topicName))
 	if err != nil {
 		return nil, fmt.Errorf("加入主题失败: %w", err)
 	}
@@ -256,6 +578,37 @@ func (b *Broadcaster) Stop() {
 	b.cancel()
 }
 
+// bandwidthTracer 统计 GossipSub 收发的 RPC 字节数
+type bandwidthTracer struct {
+	bytes atomic.Uint64
+}
+
+func (t *bandwidthTracer) total() uint64 {
+	return t.bytes.Load()
+}
+
+func (t *bandwidthTracer) RecvRPC(rpc *pubsub.RPC) {
+	t.bytes.Add(uint64(rpc.Size()))
+}
+
+func (t *bandwidthTracer) SendRPC(rpc *pubsub.RPC, p peer.ID) {
+	t.bytes.Add(uint64(rpc.Size()))
+}
+
+func (t *bandwidthTracer) AddPeer(p peer.ID, proto protocol.ID)        {}
+func (t *bandwidthTracer) RemovePeer(p peer.ID)                        {}
+func (t *bandwidthTracer) Join(topic string)                           {}
+func (t *bandwidthTracer) Leave(topic string)                          {}
+func (t *bandwidthTracer) Graft(p peer.ID, topic string)               {}
+func (t *bandwidthTracer) Prune(p peer.ID, topic string)               {}
+func (t *bandwidthTracer) ValidateMessage(msg *pubsub.Message)         {}
+func (t *bandwidthTracer) DeliverMessage(msg *pubsub.Message)          {}
+func (t *bandwidthTracer) RejectMessage(msg *pubsub.Message, r string) {}
+func (t *bandwidthTracer) DuplicateMessage(msg *pubsub.Message)        {}
+func (t *bandwidthTracer) ThrottlePeer(p peer.ID)                      {}
+func (t *bandwidthTracer) DropRPC(rpc *pubsub.RPC, p peer.ID)          {}
+func (t *bandwidthTracer) UndeliverableMessage(msg *pubsub.Message)    {}
+
 // ========== 预定义主题 ==========
 
 const (
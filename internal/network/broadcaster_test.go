@@ -217,6 +217,62 @@ func TestBroadcasterTwoNodes(t *testing.T) {
 	}
 }
 
+func TestBroadcasterValidator(t *testing.T) {
+	h1, _ := libp2p.New()
+	defer h1.Close()
+	h2, _ := libp2p.New()
+	defer h2.Close()
+
+	b1, err := NewBroadcaster(h1)
+	if err != nil {
+		t.Fatalf("创建广播器1失败: %v", err)
+	}
+	defer b1.Stop()
+	b2, err := NewBroadcaster(h2)
+	if err != nil {
+		t.Fatalf("创建广播器2失败: %v", err)
+	}
+	defer b2.Stop()
+
+	received := make(chan string, 2)
+	err = b2.SubscribeWithValidator("test-topic", func(msg *BroadcastMessage) bool {
+		return string(msg.Payload) != "reject"
+	}, func(msg *BroadcastMessage) {
+		received <- string(msg.Payload)
+	})
+	if err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if err := b2.SubscribeWithValidator("test-topic", nil, nil); err == nil {
+		t.Error("重复订阅应该失败")
+	}
+
+	h1.Peerstore().AddAddrs(h2.ID(), h2.Addrs(), time.Hour)
+	if err := h1.Connect(b1.ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	b1.Broadcast("test-topic", []byte("reject"))
+	b1.Broadcast("test-topic", []byte("accept"))
+
+	select {
+	case payload := <-received:
+		if payload != "accept" {
+			t.Errorf("校验失败的消息不应投递: %s", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待消息超时")
+	}
+
+	if err := b2.Unsubscribe("test-topic"); err != nil {
+		t.Fatalf("取消订阅失败: %v", err)
+	}
+	if len(b2.validators) != 0 {
+		t.Error("取消订阅后应移除校验器")
+	}
+}
+
 func TestBroadcasterJSON(t *testing.T) {
 	h, _ := libp2p.New()
 	defer h.Close()
@@ -350,3 +406,99 @@ func TestGetTopicPeers(t *testing.T) {
 		t.Errorf("单节点应该没有 peer，实际有 %d", len(peers))
 	}
 }
+
+func TestBroadcasterRetune(t *testing.T) {
+	h, _ := libp2p.New()
+	defer h.Close()
+
+	config := DefaultGossipTuningConfig()
+	b, err := NewBroadcasterWithTuning(h, config)
+	if err != nil {
+		t.Fatalf("创建广播器失败: %v", err)
+	}
+	defer b.Stop()
+
+	b.Subscribe("test-topic", func(msg *BroadcastMessage) {})
+	b.Broadcast("other-topic", []byte("hello"))
+
+	// 以下限参数重建 PubSub，订阅与已加入主题应保留
+	if err := b.retune(config.Floor); err != nil {
+		t.Fatalf("调参失败: %v", err)
+	}
+	b.tuner.Applied(config.Floor, time.Now())
+
+	if topics := b.GetSubscribedTopics(); len(topics) != 1 || topics[0] != "test-topic" {
+		t.Errorf("调参后订阅丢失: %v", topics)
+	}
+	if len(b.GetJoinedTopics()) != 2 {
+		t.Errorf("调参后应仍加入 2 个主题，实际 %d", len(b.GetJoinedTopics()))
+	}
+	if err := b.Broadcast("test-topic", []byte("after retune")); err != nil {
+		t.Errorf("调参后广播失败: %v", err)
+	}
+
+	status := b.GossipTuning()
+	if status == nil || status.Current != config.Floor || status.Retunes != 1 {
+		t.Errorf("调参状态错误: %+v", status)
+	}
+}
+
+func TestBroadcasterTuningKeepsLiveMesh(t *testing.T) {
+	h1, _ := libp2p.New()
+	defer h1.Close()
+	h2, _ := libp2p.New()
+	defer h2.Close()
+
+	// 预算极低，每次采样都要求降到 Floor；未开启 Rebuild 时只上报目标参数
+	config := DefaultGossipTuningConfig()
+	config.BandwidthBudget = 1
+	config.CPUBudget = 0
+	config.Smoothing = 1
+	config.SampleInterval = 50 * time.Millisecond
+	config.MinRetuneInterval = 0
+	b1, err := NewBroadcasterWithTuning(h1, config)
+	if err != nil {
+		t.Fatalf("创建广播器1失败: %v", err)
+	}
+	defer b1.Stop()
+	b2, err := NewBroadcaster(h2)
+	if err != nil {
+		t.Fatalf("创建广播器2失败: %v", err)
+	}
+	defer b2.Stop()
+	ps := b1.pubsub
+
+	received := make(chan *BroadcastMessage, 4)
+	if err := b2.Subscribe("test-topic", func(msg *BroadcastMessage) { received <- msg }); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if err := h1.Connect(b1.ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	b1.Broadcast("test-topic", []byte("load"))
+	time.Sleep(500 * time.Millisecond)
+
+	status := b1.GossipTuning()
+	if status.Target != config.Floor || status.Current != config.Ceiling || status.Retunes != 0 || status.Rebuild {
+		t.Errorf("未开启重建时应只上报目标参数: %+v", status)
+	}
+	if b1.pubsub != ps {
+		t.Error("未开启重建时不应替换 PubSub")
+	}
+
+	// mesh 未被重建，消息仍送达已连接的对端
+	if err := b1.Broadcast("test-topic", []byte("after tuning")); err != nil {
+		t.Fatalf("广播失败: %v", err)
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-received:
+			if string(msg.Payload) == "after tuning" {
+				return
+			}
+		case <-deadline:
+			t.Fatal("调参期间消息未送达对端")
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package network
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime 返回进程累计 CPU 时间（用户态 + 内核态）
+func processCPUTime() time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package network
+
+import "time"
+
+// processCPUTime Windows 下不采集 CPU 时间，自适应调参只参考带宽
+func processCPUTime() time.Duration {
+	return 0
+}
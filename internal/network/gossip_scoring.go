@@ -0,0 +1,314 @@
+package network
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GossipSub 节点评分
+//
+// 在 GossipSub 自带的协议层评分（投递、无效消息、行为惩罚）之上加入应用层分数：
+// 节点声誉低于中性值时扣分，高于中性值时少量加分；被主题校验器拒绝的消息按半衰期衰减计数扣分；
+// 处于配额限流或封禁期的节点扣分。综合分数为负的节点在心跳时被移出 mesh，
+// 低于各阈值后依次不再收发 gossip、不接收其发布、忽略其全部 RPC。
+
+// GossipScoreThresholds 评分阈值，含义与 GossipSub 的 PeerScoreThresholds 一致
+type GossipScoreThresholds struct {
+	Gossip             float64 `json:"gossip"`              // 低于该值不与其交换 gossip（<= 0）
+	Publish            float64 `json:"publish"`             // 低于该值不向其发布自己的消息（<= Gossip）
+	Graylist           float64 `json:"graylist"`            // 低于该值忽略其全部 RPC（<= Publish）
+	AcceptPX           float64 `json:"accept_px"`           // 高于该值才接受其 PRUNE 附带的节点交换（>= 0）
+	OpportunisticGraft float64 `json:"opportunistic_graft"` // mesh 中位分数低于该值时机会性嫁接高分节点（>= 0）
+}
+
+// GossipScoringConfig 节点评分配置
+type GossipScoringConfig struct {
+	NeutralReputation   float64 // 声誉等于该值时声誉分为 0（新节点的初始声誉）
+	ReputationPenalty   float64 // 声誉为 0 时的扣分，低于中性值按比例扣分
+	ReputationBonus     float64 // 声誉达到 NeutralReputation+ReputationBonusSpan 时的加分上限
+	ReputationBonusSpan float64
+
+	InvalidPenalty  float64       // 每条（衰减后）无效消息的扣分
+	InvalidHalfLife time.Duration // 无效消息计数的半衰期
+
+	QuotaThrottlePenalty float64 // 处于配额软限流区间时的扣分
+	QuotaBanPenalty      float64 // 因超额断开、处于封禁期时的扣分
+
+	Thresholds      GossipScoreThresholds
+	InspectInterval time.Duration // 从 GossipSub 读取分数快照的间隔
+}
+
+// DefaultGossipScoringConfig 返回默认配置
+// 声誉降到 0 或处于配额封禁期的节点分数低于 Graylist；7 条未衰减的无效消息即低于 Publish。
+func DefaultGossipScoringConfig() *GossipScoringConfig {
+	return &GossipScoringConfig{
+		NeutralReputation:    10,
+		ReputationPenalty:    100,
+		ReputationBonus:      10,
+		ReputationBonusSpan:  190,
+		InvalidPenalty:       8,
+		InvalidHalfLife:      10 * time.Minute,
+		QuotaThrottlePenalty: 20,
+		QuotaBanPenalty:      100,
+		Thresholds: GossipScoreThresholds{
+			Gossip:             -10,
+			Publish:            -50,
+			Graylist:           -80,
+			AcceptPX:           10,
+			OpportunisticGraft: 5,
+		},
+		InspectInterval: 10 * time.Second,
+	}
+}
+
+// 节点相对阈值的状态
+const (
+	GossipScoreOK        = "ok"         // 分数非负
+	GossipScorePruned    = "pruned"     // 分数为负，不在 mesh 中
+	GossipScoreNoGossip  = "no_gossip"  // 低于 Gossip 阈值
+	GossipScoreNoPublish = "no_publish" // 低于 Publish 阈值
+	GossipScoreGraylist  = "graylisted" // 低于 Graylist 阈值
+)
+
+// AppScoreComponents 应用层分数的组成
+type AppScoreComponents struct {
+	Reputation      float64 `json:"reputation"`
+	ReputationScore float64 `json:"reputation_score"`
+	InvalidMessages float64 `json:"invalid_messages"` // 衰减后的无效消息数
+	InvalidScore    float64 `json:"invalid_score"`
+	QuotaThrottled  bool    `json:"quota_throttled"`
+	QuotaBanned     bool    `json:"quota_banned"`
+	QuotaScore      float64 `json:"quota_score"`
+	Total           float64 `json:"total"`
+}
+
+// RouterPeerScore GossipSub 路由器给出的节点分数快照
+type RouterPeerScore struct {
+	Score              float64
+	AppSpecific        float64
+	BehaviourPenalty   float64
+	IPColocationFactor float64
+}
+
+// PeerGossipScore 节点的评分（用于 API 查询）
+type PeerGossipScore struct {
+	PeerID             string             `json:"peer_id"`
+	Score              float64            `json:"score"`
+	Status             string             `json:"status"`
+	AppSpecific        float64            `json:"app_specific"`
+	BehaviourPenalty   float64            `json:"behaviour_penalty"`
+	IPColocationFactor float64            `json:"ip_colocation_factor"`
+	App                AppScoreComponents `json:"app"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+type decayingCount struct {
+	value float64
+	at    time.Time
+}
+
+// GossipScorer 计算应用层分数并保存 GossipSub 的分数快照
+// 应用层计数独立于 GossipSub 实例，自适应调参重建 PubSub 后仍然保留。
+type GossipScorer struct {
+	config *GossipScoringConfig
+
+	mu         sync.Mutex
+	reputation func(peerID string) float64
+	quota      func(peerID string) (throttled, banned bool)
+	invalid    map[string]*decayingCount
+	scores     map[string]*PeerGossipScore
+	now        func() time.Time
+}
+
+// NewGossipScorer 创建节点评分器
+func NewGossipScorer(config *GossipScoringConfig) *GossipScorer {
+	if config == nil {
+		config = DefaultGossipScoringConfig()
+	}
+	return &GossipScorer{
+		config:  config,
+		invalid: make(map[string]*decayingCount),
+		scores:  make(map[string]*PeerGossipScore),
+		now:     time.Now,
+	}
+}
+
+// Config 评分配置
+func (s *GossipScorer) Config() *GossipScoringConfig {
+	return s.config
+}
+
+// SetReputationFunc 设置节点声誉查询
+func (s *GossipScorer) SetReputationFunc(fn func(peerID string) float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reputation = fn
+}
+
+// SetQuotaFunc 设置节点配额状态查询
+func (s *GossipScorer) SetQuotaFunc(fn func(peerID string) (throttled, banned bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quota = fn
+}
+
+// RecordInvalid 记录一条被主题校验器拒绝的消息
+func (s *GossipScorer) RecordInvalid(peerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	c, ok := s.invalid[peerID]
+	if !ok {
+		c = &decayingCount{at: now}
+		s.invalid[peerID] = c
+	}
+	c.value = s.decayed(c, now) + 1
+	c.at = now
+}
+
+// AppScore 节点的应用层分数，作为 GossipSub 的 AppSpecificScore
+func (s *GossipScorer) AppScore(peerID string) float64 {
+	return s.Components(peerID).Total
+}
+
+// Components 节点应用层分数的组成
+func (s *GossipScorer) Components(peerID string) AppScoreComponents {
+	s.mu.Lock()
+	reputation, quota := s.reputation, s.quota
+	var invalid float64
+	if c, ok := s.invalid[peerID]; ok {
+		invalid = s.decayed(c, s.now())
+		if invalid < 0.01 {
+			delete(s.invalid, peerID)
+			invalid = 0
+		}
+	}
+	s.mu.Unlock()
+
+	cfg := s.config
+	comp := AppScoreComponents{
+		Reputation:      cfg.NeutralReputation,
+		InvalidMessages: invalid,
+		InvalidScore:    -invalid * cfg.InvalidPenalty,
+	}
+	if reputation != nil {
+		comp.Reputation = reputation(peerID)
+	}
+	comp.ReputationScore = s.reputationScore(comp.Reputation)
+	if quota != nil {
+		comp.QuotaThrottled, comp.QuotaBanned = quota(peerID)
+	}
+	if comp.QuotaBanned {
+		comp.QuotaScore = -cfg.QuotaBanPenalty
+	} else if comp.QuotaThrottled {
+		comp.QuotaScore = -cfg.QuotaThrottlePenalty
+	}
+	comp.Total = comp.ReputationScore + comp.InvalidScore + comp.QuotaScore
+	return comp
+}
+
+func (s *GossipScorer) reputationScore(rep float64) float64 {
+	cfg := s.config
+	if rep < cfg.NeutralReputation {
+		if cfg.NeutralReputation <= 0 {
+			return 0
+		}
+		return -cfg.ReputationPenalty * (cfg.NeutralReputation - rep) / cfg.NeutralReputation
+	}
+	if cfg.ReputationBonusSpan <= 0 {
+		return 0
+	}
+	return cfg.ReputationBonus * math.Min((rep-cfg.NeutralReputation)/cfg.ReputationBonusSpan, 1)
+}
+
+// decayed 按半衰期衰减后的计数（调用方持有锁）
+func (s *GossipScorer) decayed(c *decayingCount, now time.Time) float64 {
+	if s.config.InvalidHalfLife <= 0 {
+		return c.value
+	}
+	elapsed := now.Sub(c.at)
+	if elapsed <= 0 {
+		return c.value
+	}
+	return c.value * math.Pow(0.5, float64(elapsed)/float64(s.config.InvalidHalfLife))
+}
+
+// Status 分数相对阈值的状态
+func (s *GossipScorer) Status(score float64) string {
+	t := s.config.Thresholds
+	switch {
+	case score < t.Graylist:
+		return GossipScoreGraylist
+	case score < t.Publish:
+		return GossipScoreNoPublish
+	case score < t.Gossip:
+		return GossipScoreNoGossip
+	case score < 0:
+		return GossipScorePruned
+	}
+	return GossipScoreOK
+}
+
+// Update 用 GossipSub 的分数快照替换已保存的分数，已断开的节点随之移除
+func (s *GossipScorer) Update(snapshot map[string]RouterPeerScore) {
+	now := s.now()
+	scores := make(map[string]*PeerGossipScore, len(snapshot))
+	for peerID, rs := range snapshot {
+		scores[peerID] = &PeerGossipScore{
+			PeerID:             peerID,
+			Score:              rs.Score,
+			Status:             s.Status(rs.Score),
+			AppSpecific:        rs.AppSpecific,
+			BehaviourPenalty:   rs.BehaviourPenalty,
+			IPColocationFactor: rs.IPColocationFactor,
+			App:                s.Components(peerID),
+			UpdatedAt:          now,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scores = scores
+}
+
+// Scores 所有节点的最新分数，分数低的在前
+func (s *GossipScorer) Scores() []*PeerGossipScore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]*PeerGossipScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		copied := *score
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score < result[j].Score
+		}
+		return result[i].PeerID < result[j].PeerID
+	})
+	return result
+}
+
+// Score 节点的最新分数；不在快照中的节点只返回应用层分数
+func (s *GossipScorer) Score(peerID string) (*PeerGossipScore, bool) {
+	s.mu.Lock()
+	score, ok := s.scores[peerID]
+	if ok {
+		copied := *score
+		s.mu.Unlock()
+		return &copied, true
+	}
+	s.mu.Unlock()
+
+	app := s.Components(peerID)
+	return &PeerGossipScore{
+		PeerID:      peerID,
+		Score:       app.Total,
+		Status:      s.Status(app.Total),
+		AppSpecific: app.Total,
+		App:         app,
+		UpdatedAt:   s.now(),
+	}, false
+}
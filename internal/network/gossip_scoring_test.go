@@ -0,0 +1,81 @@
+package network
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGossipScorerAppScore(t *testing.T) {
+	scorer := NewGossipScorer(nil)
+	now := time.Now()
+	scorer.now = func() time.Time { return now }
+
+	reputation := map[string]float64{"good": 200, "spammer": 2}
+	scorer.SetReputationFunc(func(peerID string) float64 {
+		if rep, ok := reputation[peerID]; ok {
+			return rep
+		}
+		return 10
+	})
+	throttled := map[string]bool{}
+	scorer.SetQuotaFunc(func(peerID string) (bool, bool) { return throttled[peerID], false })
+
+	if got := scorer.AppScore("new"); got != 0 {
+		t.Errorf("新节点应用层分数 = %v, want 0", got)
+	}
+	if got := scorer.AppScore("good"); got != 10 {
+		t.Errorf("高声誉节点分数 = %v, want 10", got)
+	}
+	spammer := scorer.AppScore("spammer")
+	if spammer != -80 || scorer.Status(spammer) != GossipScoreNoPublish {
+		t.Errorf("低声誉节点分数 = %v (%s), want -80", spammer, scorer.Status(spammer))
+	}
+
+	// 无效消息和配额限流叠加扣分，无效消息计数按半衰期衰减
+	scorer.RecordInvalid("new")
+	scorer.RecordInvalid("new")
+	throttled["new"] = true
+	comp := scorer.Components("new")
+	if comp.InvalidMessages != 2 || comp.InvalidScore != -16 || comp.QuotaScore != -20 || comp.Total != -36 {
+		t.Errorf("components = %+v", comp)
+	}
+	if scorer.Status(comp.Total) != GossipScoreNoGossip {
+		t.Errorf("status = %s, want %s", scorer.Status(comp.Total), GossipScoreNoGossip)
+	}
+	now = now.Add(10 * time.Minute)
+	throttled["new"] = false
+	if got := scorer.AppScore("new"); math.Abs(got+8) > 1e-9 {
+		t.Errorf("一个半衰期后分数 = %v, want -8", got)
+	}
+	now = now.Add(2 * time.Hour)
+	if got := scorer.AppScore("new"); got != 0 {
+		t.Errorf("衰减后分数 = %v, want 0", got)
+	}
+}
+
+func TestGossipScorerSnapshot(t *testing.T) {
+	scorer := NewGossipScorer(nil)
+	scorer.SetQuotaFunc(func(peerID string) (bool, bool) { return false, peerID == "banned" })
+
+	scorer.Update(map[string]RouterPeerScore{
+		"banned": {Score: -100, AppSpecific: -100},
+		"peer":   {Score: 3, AppSpecific: 0, BehaviourPenalty: 1},
+	})
+	scores := scorer.Scores()
+	if len(scores) != 2 || scores[0].PeerID != "banned" || scores[0].Status != GossipScoreGraylist || !scores[0].App.QuotaBanned {
+		t.Fatalf("Scores() = %+v", scores)
+	}
+	if score, ok := scorer.Score("peer"); !ok || score.Status != GossipScoreOK || score.BehaviourPenalty != 1 {
+		t.Errorf("Score(peer) = %+v, %v", score, ok)
+	}
+
+	// 快照中没有的节点只给出应用层分数；新快照移除已断开的节点
+	if score, ok := scorer.Score("other"); ok || score.Score != 0 {
+		t.Errorf("Score(other) = %+v, %v", score, ok)
+	}
+	scorer.Update(map[string]RouterPeerScore{"peer": {Score: 4}})
+	if scores := scorer.Scores(); len(scores) != 1 || scores[0].Score != 4 {
+		t.Errorf("Scores() after update = %+v", scores)
+	}
+}
@@ -0,0 +1,270 @@
+package network
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// GossipParams 可动态调整的 GossipSub 参数
+type GossipParams struct {
+	D                 int           `json:"d"`      // 目标 mesh 度（转发扇出）
+	Dlo               int           `json:"d_lo"`   // mesh 度下限
+	Dhi               int           `json:"d_hi"`   // mesh 度上限
+	Dlazy             int           `json:"d_lazy"` // 每次心跳 gossip 的节点数
+	HeartbeatInterval time.Duration `json:"heartbeat_interval"`
+}
+
+// Dout 满足 GossipSub 约束（Dout < Dlo 且 Dout <= D/2）的出站连接数
+func (p GossipParams) Dout() int {
+	dout := 2
+	if p.Dlo-1 < dout {
+		dout = p.Dlo - 1
+	}
+	if p.D/2 < dout {
+		dout = p.D / 2
+	}
+	if dout < 0 {
+		dout = 0
+	}
+	return dout
+}
+
+// PressureSample 一次负载采样
+type PressureSample struct {
+	BandwidthBps float64 `json:"bandwidth_bps"` // gossip 收发字节/秒
+	CPU          float64 `json:"cpu"`           // 进程 CPU 占用（0-1，按核数归一化）
+}
+
+// GossipTuningConfig 自适应调参配置
+// Floor 为拥塞时使用的最保守参数（低扇出、慢心跳），Ceiling 为空闲时的参数。
+type GossipTuningConfig struct {
+	Floor   GossipParams
+	Ceiling GossipParams
+
+	BandwidthBudget float64 // gossip 带宽预算（字节/秒），<=0 表示不按带宽调节
+	CPUBudget       float64 // CPU 预算（0-1），<=0 表示不按 CPU 调节
+
+	// 负载比（实测/预算）低于 LowWater 时使用 Ceiling，高于 HighWater 时使用 Floor，中间线性插值
+	LowWater  float64
+	HighWater float64
+
+	Smoothing         float64       // 负载 EWMA 平滑系数（0-1，越大越敏感）
+	SampleInterval    time.Duration // 采样间隔
+	MinRetuneInterval time.Duration // 两次调参的最小间隔（调参需要重建 PubSub）
+
+	// Rebuild 为 true 时按目标参数重建 PubSub。重建会丢失 mesh、已见消息缓存和协议层节点评分，
+	// 已连接的对端也不会向新实例重新打开流，因此默认关闭：只计算目标参数并在状态中上报。
+	Rebuild bool
+}
+
+// DefaultGossipTuningConfig 返回默认配置，Ceiling 与 GossipSub 默认参数一致
+func DefaultGossipTuningConfig() *GossipTuningConfig {
+	return &GossipTuningConfig{
+		Floor: GossipParams{
+			D:                 3,
+			Dlo:               2,
+			Dhi:               5,
+			Dlazy:             3,
+			HeartbeatInterval: 3 * time.Second,
+		},
+		Ceiling: GossipParams{
+			D:                 6,
+			Dlo:               5,
+			Dhi:               12,
+			Dlazy:             6,
+			HeartbeatInterval: 1 * time.Second,
+		},
+		BandwidthBudget:   256 * 1024,
+		CPUBudget:         0.5,
+		LowWater:          0.5,
+		HighWater:         1.0,
+		Smoothing:         0.3,
+		SampleInterval:    10 * time.Second,
+		MinRetuneInterval: 2 * time.Minute,
+	}
+}
+
+// GossipTuningStatus 自适应调参状态（用于监控）
+type GossipTuningStatus struct {
+	Current    GossipParams   `json:"current"`
+	Target     GossipParams   `json:"target"` // 按当前负载计算的参数，未启用重建时只上报不生效
+	Rebuild    bool           `json:"rebuild"`
+	Floor      GossipParams   `json:"floor"`
+	Ceiling    GossipParams   `json:"ceiling"`
+	Load       float64        `json:"load"`  // 平滑后的负载比
+	Scale      float64        `json:"scale"` // 1 = Ceiling，0 = Floor
+	LastSample PressureSample `json:"last_sample"`
+	Retunes    int            `json:"retunes"`
+	LastRetune time.Time      `json:"last_retune,omitempty"`
+}
+
+// GossipTuner 根据带宽与 CPU 压力计算 GossipSub 参数
+type GossipTuner struct {
+	mu         sync.Mutex
+	config     *GossipTuningConfig
+	load       float64
+	sampled    bool
+	current    GossipParams
+	target     GossipParams
+	lastSample PressureSample
+	retunes    int
+	lastRetune time.Time
+}
+
+// NewGossipTuner 创建调参器，初始参数为 Ceiling
+func NewGossipTuner(config *GossipTuningConfig) *GossipTuner {
+	if config == nil {
+		config = DefaultGossipTuningConfig()
+	}
+	return &GossipTuner{
+		config:  config,
+		current: config.Ceiling,
+		target:  config.Ceiling,
+	}
+}
+
+// Current 当前生效的参数
+func (t *GossipTuner) Current() GossipParams {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+// Observe 记录一次采样，返回目标参数以及是否需要调参
+// mesh 度没有变化或距离上次调参不足 MinRetuneInterval 时不调参，避免频繁重建 mesh。
+func (t *GossipTuner) Observe(sample PressureSample, now time.Time) (GossipParams, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	load := t.loadRatio(sample)
+	if !t.sampled {
+		t.load = load
+		t.sampled = true
+	} else {
+		t.load += t.config.Smoothing * (load - t.load)
+	}
+	t.lastSample = sample
+
+	target := t.paramsFor(t.scale())
+	t.target = target
+	if target == t.current {
+		return t.current, false
+	}
+	if target.D == t.current.D && target.HeartbeatInterval == t.current.HeartbeatInterval {
+		return t.current, false
+	}
+	if !t.lastRetune.IsZero() && now.Sub(t.lastRetune) < t.config.MinRetuneInterval {
+		return t.current, false
+	}
+	return target, true
+}
+
+// Applied 调参成功后记录新参数
+func (t *GossipTuner) Applied(params GossipParams, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = params
+	t.retunes++
+	t.lastRetune = now
+}
+
+// Status 返回调参状态
+func (t *GossipTuner) Status() GossipTuningStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return GossipTuningStatus{
+		Current:    t.current,
+		Target:     t.target,
+		Rebuild:    t.config.Rebuild,
+		Floor:      t.config.Floor,
+		Ceiling:    t.config.Ceiling,
+		Load:       t.load,
+		Scale:      t.scale(),
+		LastSample: t.lastSample,
+		Retunes:    t.retunes,
+		LastRetune: t.lastRetune,
+	}
+}
+
+// loadRatio 取带宽与 CPU 中压力较大的一项
+func (t *GossipTuner) loadRatio(sample PressureSample) float64 {
+	var load float64
+	if t.config.BandwidthBudget > 0 {
+		load = sample.BandwidthBps / t.config.BandwidthBudget
+	}
+	if t.config.CPUBudget > 0 {
+		load = math.Max(load, sample.CPU/t.config.CPUBudget)
+	}
+	return load
+}
+
+// scale 负载映射到 [0, 1]（调用方需持有锁）
+func (t *GossipTuner) scale() float64 {
+	low, high := t.config.LowWater, t.config.HighWater
+	if high <= low {
+		if t.load >= high {
+			return 0
+		}
+		return 1
+	}
+	return math.Max(0, math.Min(1, (high-t.load)/(high-low)))
+}
+
+// paramsFor 在 Floor 与 Ceiling 之间插值
+func (t *GossipTuner) paramsFor(scale float64) GossipParams {
+	f, c := t.config.Floor, t.config.Ceiling
+	lerp := func(a, b int) int {
+		return int(math.Round(float64(a) + scale*float64(b-a)))
+	}
+	p := GossipParams{
+		D:                 lerp(f.D, c.D),
+		Dlo:               lerp(f.Dlo, c.Dlo),
+		Dhi:               lerp(f.Dhi, c.Dhi),
+		Dlazy:             lerp(f.Dlazy, c.Dlazy),
+		HeartbeatInterval: f.HeartbeatInterval + time.Duration(scale*float64(c.HeartbeatInterval-f.HeartbeatInterval)),
+	}
+	// 保持 Dlo <= D <= Dhi
+	if p.Dlo > p.D {
+		p.Dlo = p.D
+	}
+	if p.Dhi < p.D {
+		p.Dhi = p.D
+	}
+	p.HeartbeatInterval = p.HeartbeatInterval.Round(100 * time.Millisecond)
+	return p
+}
+
+// rateMeter 由累计计数计算速率
+type rateMeter struct {
+	last     uint64
+	lastTime time.Time
+}
+
+func (m *rateMeter) rate(total uint64, now time.Time) float64 {
+	defer func() {
+		m.last = total
+		m.lastTime = now
+	}()
+	if m.lastTime.IsZero() || !now.After(m.lastTime) || total < m.last {
+		return 0
+	}
+	return float64(total-m.last) / now.Sub(m.lastTime).Seconds()
+}
+
+// cpuMeter 由进程 CPU 时间计算占用率
+type cpuMeter struct {
+	last     time.Duration
+	lastTime time.Time
+}
+
+func (m *cpuMeter) usage(cpuTime time.Duration, numCPU int, now time.Time) float64 {
+	defer func() {
+		m.last = cpuTime
+		m.lastTime = now
+	}()
+	if m.lastTime.IsZero() || !now.After(m.lastTime) || cpuTime < m.last || numCPU <= 0 {
+		return 0
+	}
+	return float64(cpuTime-m.last) / float64(now.Sub(m.lastTime)) / float64(numCPU)
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGossipTunerShrinksUnderPressure(t *testing.T) {
+	config := DefaultGossipTuningConfig()
+	config.Smoothing = 1
+	tuner := NewGossipTuner(config)
+	now := time.Now()
+
+	if params, retune := tuner.Observe(PressureSample{BandwidthBps: 1024, CPU: 0.05}, now); retune || params != config.Ceiling {
+		t.Errorf("空闲时应保持 Ceiling, got %+v retune=%v", params, retune)
+	}
+
+	params, retune := tuner.Observe(PressureSample{BandwidthBps: 4 * config.BandwidthBudget}, now.Add(time.Second))
+	if !retune {
+		t.Fatal("带宽超出预算时应调参")
+	}
+	if params != config.Floor {
+		t.Errorf("严重拥塞时应降到 Floor, got %+v", params)
+	}
+	tuner.Applied(params, now.Add(time.Second))
+
+	// CPU 压力同样生效，且处于调参冷却期内不重复调参
+	if _, retune := tuner.Observe(PressureSample{CPU: 0.05}, now.Add(2*time.Second)); retune {
+		t.Error("冷却期内不应调参")
+	}
+	params, retune = tuner.Observe(PressureSample{CPU: 0.05}, now.Add(config.MinRetuneInterval+2*time.Second))
+	if !retune || params != config.Ceiling {
+		t.Errorf("负载回落后应恢复 Ceiling, got %+v retune=%v", params, retune)
+	}
+}
+
+func TestGossipTunerInterpolation(t *testing.T) {
+	config := DefaultGossipTuningConfig()
+	config.Smoothing = 1
+	tuner := NewGossipTuner(config)
+
+	// 负载比 0.75 处于 LowWater 与 HighWater 中间
+	params, retune := tuner.Observe(PressureSample{CPU: 0.75 * config.CPUBudget}, time.Now())
+	if !retune {
+		t.Fatal("中等负载应调参")
+	}
+	if params.D <= config.Floor.D || params.D >= config.Ceiling.D {
+		t.Errorf("D = %d 应在 Floor 与 Ceiling 之间", params.D)
+	}
+	if params.Dlo > params.D || params.Dhi < params.D {
+		t.Errorf("参数不满足 Dlo <= D <= Dhi: %+v", params)
+	}
+	if params.HeartbeatInterval != 2*time.Second {
+		t.Errorf("HeartbeatInterval = %v, want 2s", params.HeartbeatInterval)
+	}
+	if dout := params.Dout(); dout >= params.Dlo || dout > params.D/2 {
+		t.Errorf("Dout = %d 不满足 GossipSub 约束", dout)
+	}
+
+	status := tuner.Status()
+	if status.Load != 0.75 || status.Scale != 0.5 || status.Current != config.Ceiling {
+		t.Errorf("状态错误: %+v", status)
+	}
+}
+
+func TestGossipTunerSmoothing(t *testing.T) {
+	config := DefaultGossipTuningConfig()
+	tuner := NewGossipTuner(config)
+	now := time.Now()
+
+	tuner.Observe(PressureSample{}, now)
+	// 单次突发被 EWMA 平滑，不足以触发调参
+	if _, retune := tuner.Observe(PressureSample{BandwidthBps: 1.5 * config.BandwidthBudget}, now.Add(time.Second)); retune {
+		t.Error("单次突发不应触发调参")
+	}
+}
+
+func TestRateMeter(t *testing.T) {
+	var m rateMeter
+	now := time.Now()
+	if r := m.rate(100, now); r != 0 {
+		t.Errorf("首次采样速率应为 0, got %v", r)
+	}
+	if r := m.rate(2100, now.Add(2*time.Second)); r != 1000 {
+		t.Errorf("rate = %v, want 1000", r)
+	}
+
+	var c cpuMeter
+	c.usage(0, 2, now)
+	if u := c.usage(time.Second, 2, now.Add(time.Second)); u != 0.5 {
+		t.Errorf("usage = %v, want 0.5", u)
+	}
+}
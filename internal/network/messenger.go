@@ -16,6 +16,8 @@ import (
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/multiformats/go-multiaddr"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
 )
 
 const (
@@ -60,6 +62,10 @@ type RequestHandlerFunc func(ctx context.Context, peerID peer.ID, payload []byte
 type Messenger struct {
 	host host.Host
 
+	// 网络命名空间内的协议ID
+	protoMessage protocol.ID
+	protoRequest protocol.ID
+
 	// 请求 ID 计数器
 	requestCounter uint64
 
@@ -85,15 +91,28 @@ func NewMessenger(h host.Host) *Messenger {
 		pendingRequests: make(map[uint64]chan *Message),
 		ctx:             ctx,
 		cancel:          cancel,
+		protoMessage:    ProtocolMessage,
+		protoRequest:    ProtocolRequest,
 	}
 
 	// 注册流处理器
-	h.SetStreamHandler(ProtocolMessage, m.handleMessageStream)
-	h.SetStreamHandler(ProtocolRequest, m.handleRequestStream)
+	h.SetStreamHandler(m.protoMessage, m.handleMessageStream)
+	h.SetStreamHandler(m.protoRequest, m.handleRequestStream)
 
 	return m
 }
 
+// SetNamespace 切换到网络命名空间内的协议ID，不同命名空间的节点无法互发消息
+// 需在收发消息之前调用。
+func (m *Messenger) SetNamespace(ns string) {
+	m.host.RemoveStreamHandler(m.protoMessage)
+	m.host.RemoveStreamHandler(m.protoRequest)
+	m.protoMessage = protocol.ID(namespace.Protocol(ns, string(ProtocolMessage)))
+	m.protoRequest = protocol.ID(namespace.Protocol(ns, string(ProtocolRequest)))
+	m.host.SetStreamHandler(m.protoMessage, m.handleMessageStream)
+	m.host.SetStreamHandler(m.protoRequest, m.handleRequestStream)
+}
+
 // SetMessageHandler 设置消息处理器
 func (m *Messenger) SetMessageHandler(handler MessageHandlerFunc) {
 	m.handlerMu.Lock()
@@ -183,7 +202,7 @@ func (m *Messenger) SendMessageWithTimeout(peerIDStr string, payload []byte, tim
 	ctx, cancel := context.WithTimeout(m.ctx, timeout)
 	defer cancel()
 
-	stream, err := m.host.NewStream(ctx, peerID, ProtocolMessage)
+	stream, err := m.host.NewStream(ctx, peerID, m.protoMessage)
 	if err != nil {
 		return fmt.Errorf("打开流失败: %w", err)
 	}
@@ -212,7 +231,7 @@ func (m *Messenger) RequestWithTimeout(peerIDStr string, payload []byte, timeout
 	ctx, cancel := context.WithTimeout(m.ctx, timeout)
 	defer cancel()
 
-	stream, err := m.host.NewStream(ctx, peerID, ProtocolRequest)
+	stream, err := m.host.NewStream(ctx, peerID, m.protoRequest)
 	if err != nil {
 		return nil, fmt.Errorf("打开流失败: %w", err)
 	}
@@ -398,8 +417,8 @@ func (m *Messenger) Disconnect(peerIDStr string) error {
 // Stop 停止消息通信器
 func (m *Messenger) Stop() {
 	m.cancel()
-	m.host.RemoveStreamHandler(ProtocolMessage)
-	m.host.RemoveStreamHandler(ProtocolRequest)
+	m.host.RemoveStreamHandler(m.protoMessage)
+	m.host.RemoveStreamHandler(m.protoRequest)
 }
 
 // ========== 便捷方法 ==========
@@ -0,0 +1,266 @@
+// Package dialer 出站拨号策略
+// 负责决定向哪些节点发起连接以及以何种顺序：
+// 优先重连持久化的邻居，其次按声誉从高到低排序，
+// 限制每分钟拨号次数，并对不可达节点做负缓存（指数退避）。
+package dialer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 拒绝拨号的原因
+const (
+	ReasonOK          = ""
+	ReasonRateLimited = "rate_limited"
+	ReasonUnreachable = "unreachable"
+	ReasonLowRep      = "low_reputation"
+)
+
+// Config 拨号策略配置
+type Config struct {
+	MaxDialsPerMinute   int           `json:"max_dials_per_minute"`   // 每分钟最大拨号次数（0 表示不限制）
+	NegativeCacheTTL    time.Duration `json:"negative_cache_ttl"`     // 首次失败后的负缓存时长
+	MaxNegativeCacheTTL time.Duration `json:"max_negative_cache_ttl"` // 负缓存时长上限
+	MinReputation       float64       `json:"min_reputation"`         // 最低声誉要求（优先节点不受限）
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		MaxDialsPerMinute:   30,
+		NegativeCacheTTL:    time.Minute,
+		MaxNegativeCacheTTL: time.Hour,
+		MinReputation:       0,
+	}
+}
+
+// ReputationFunc 查询节点声誉
+type ReputationFunc func(peerID string) float64
+
+// failureEntry 负缓存条目
+type failureEntry struct {
+	failures int
+	until    time.Time
+}
+
+// Policy 出站拨号策略
+type Policy struct {
+	mu       sync.RWMutex
+	config   *Config
+	repFunc  ReputationFunc
+	priority map[string]bool
+	failed   map[string]*failureEntry
+	dials    []time.Time // 最近一分钟内的拨号时间
+
+	// 统计
+	allowed     int64
+	rateLimited int64
+	suppressed  int64
+
+	now func() time.Time
+}
+
+// NewPolicy 创建拨号策略
+func NewPolicy(config *Config) *Policy {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Policy{
+		config:   config,
+		priority: make(map[string]bool),
+		failed:   make(map[string]*failureEntry),
+		now:      time.Now,
+	}
+}
+
+// SetReputationFunc 设置声誉查询函数
+func (p *Policy) SetReputationFunc(fn ReputationFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.repFunc = fn
+}
+
+// SetPriorityPeers 设置优先重连的节点（通常为持久化的邻居）
+func (p *Policy) SetPriorityPeers(peerIDs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.priority = make(map[string]bool, len(peerIDs))
+	for _, id := range peerIDs {
+		p.priority[id] = true
+	}
+}
+
+// IsPriority 是否为优先节点
+func (p *Policy) IsPriority(peerID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.priority[peerID]
+}
+
+// Allow 判断当前是否允许拨号该节点，允许时计入速率限制
+func (p *Policy) Allow(peerID string) (bool, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.isUnreachableLocked(peerID, now) {
+		p.suppressed++
+		return false, ReasonUnreachable
+	}
+
+	if !p.priority[peerID] && p.repFunc != nil && p.repFunc(peerID) < p.config.MinReputation {
+		p.suppressed++
+		return false, ReasonLowRep
+	}
+
+	if p.config.MaxDialsPerMinute > 0 {
+		p.pruneDialsLocked(now)
+		if len(p.dials) >= p.config.MaxDialsPerMinute {
+			p.rateLimited++
+			return false, ReasonRateLimited
+		}
+	}
+
+	p.dials = append(p.dials, now)
+	p.allowed++
+	return true, ReasonOK
+}
+
+// Rank 对候选节点排序：优先节点在前，其余按声誉降序；负缓存中的节点被剔除
+func (p *Policy) Rank(peerIDs []string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := p.now()
+	type candidate struct {
+		id       string
+		priority bool
+		rep      float64
+	}
+	candidates := make([]candidate, 0, len(peerIDs))
+	for _, id := range peerIDs {
+		if p.isUnreachableLocked(id, now) {
+			continue
+		}
+		c := candidate{id: id, priority: p.priority[id]}
+		if p.repFunc != nil {
+			c.rep = p.repFunc(id)
+		}
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority
+		}
+		return candidates[i].rep > candidates[j].rep
+	})
+
+	ranked := make([]string, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.id
+	}
+	return ranked
+}
+
+// RecordSuccess 记录拨号成功，清除负缓存
+func (p *Policy) RecordSuccess(peerID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failed, peerID)
+}
+
+// RecordFailure 记录拨号失败，负缓存时长按失败次数指数增长
+func (p *Policy) RecordFailure(peerID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.failed[peerID]
+	if !ok {
+		entry = &failureEntry{}
+		p.failed[peerID] = entry
+	}
+	entry.failures++
+
+	ttl := p.config.NegativeCacheTTL
+	for i := 1; i < entry.failures; i++ {
+		ttl *= 2
+		if p.config.MaxNegativeCacheTTL > 0 && ttl >= p.config.MaxNegativeCacheTTL {
+			ttl = p.config.MaxNegativeCacheTTL
+			break
+		}
+	}
+	entry.until = p.now().Add(ttl)
+}
+
+// IsUnreachable 节点是否处于负缓存中
+func (p *Policy) IsUnreachable(peerID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.isUnreachableLocked(peerID, p.now())
+}
+
+// RetryAfter 被负缓存或速率限制拒绝的节点还需等待多久才能再次拨号，已可拨号时为 0；
+// 声誉不足不是暂时状态，不在此计算
+func (p *Policy) RetryAfter(peerID string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var wait time.Duration
+	if entry, ok := p.failed[peerID]; ok && now.Before(entry.until) {
+		wait = entry.until.Sub(now)
+	}
+	if p.config.MaxDialsPerMinute > 0 {
+		p.pruneDialsLocked(now)
+		if len(p.dials) >= p.config.MaxDialsPerMinute {
+			// 最早的一次拨号移出一分钟窗口后腾出名额
+			if free := p.dials[0].Add(time.Minute).Sub(now); free > wait {
+				wait = free
+			}
+		}
+	}
+	return wait
+}
+
+// GetStats 获取统计信息
+func (p *Policy) GetStats() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.pruneDialsLocked(now)
+
+	unreachable := 0
+	for id := range p.failed {
+		if p.isUnreachableLocked(id, now) {
+			unreachable++
+		}
+	}
+
+	return map[string]interface{}{
+		"allowed":              p.allowed,
+		"rate_limited":         p.rateLimited,
+		"suppressed":           p.suppressed,
+		"dials_last_minute":    len(p.dials),
+		"unreachable_peers":    unreachable,
+		"priority_peers":       len(p.priority),
+		"max_dials_per_minute": p.config.MaxDialsPerMinute,
+	}
+}
+
+func (p *Policy) isUnreachableLocked(peerID string, now time.Time) bool {
+	entry, ok := p.failed[peerID]
+	return ok && now.Before(entry.until)
+}
+
+func (p *Policy) pruneDialsLocked(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(p.dials) && !p.dials[i].After(cutoff) {
+		i++
+	}
+	p.dials = p.dials[i:]
+}
@@ -0,0 +1,137 @@
+package dialer
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestPolicy(cfg *Config) (*Policy, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	p := NewPolicy(cfg)
+	p.now = clock.now
+	return p, clock
+}
+
+func TestRankPriorityAndReputation(t *testing.T) {
+	p, _ := newTestPolicy(nil)
+	reps := map[string]float64{"a": 10, "b": 80, "c": 50, "d": 5}
+	p.SetReputationFunc(func(id string) float64 { return reps[id] })
+	p.SetPriorityPeers([]string{"d"})
+
+	got := p.Rank([]string{"a", "b", "c", "d"})
+	want := []string{"d", "b", "c", "a"}
+	if len(got) != len(want) {
+		t.Fatalf("期望 %v, 得到 %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("期望 %v, 得到 %v", want, got)
+		}
+	}
+}
+
+func TestAllowRateLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxDialsPerMinute = 2
+	p, clock := newTestPolicy(cfg)
+
+	for _, id := range []string{"a", "b"} {
+		if ok, reason := p.Allow(id); !ok {
+			t.Fatalf("拨号 %s 应被允许, 原因: %s", id, reason)
+		}
+	}
+	if ok, reason := p.Allow("c"); ok || reason != ReasonRateLimited {
+		t.Fatalf("超过速率限制应被拒绝, 得到 ok=%v reason=%s", ok, reason)
+	}
+
+	if wait := p.RetryAfter("c"); wait != time.Minute {
+		t.Errorf("速率窗口腾出名额前应等待 1 分钟, 得到 %v", wait)
+	}
+
+	clock.advance(61 * time.Second)
+	if wait := p.RetryAfter("c"); wait != 0 {
+		t.Errorf("窗口过后不应再等待, 得到 %v", wait)
+	}
+	if ok, _ := p.Allow("c"); !ok {
+		t.Error("窗口过后应允许拨号")
+	}
+}
+
+func TestNegativeCacheBackoff(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.NegativeCacheTTL = time.Minute
+	cfg.MaxNegativeCacheTTL = 3 * time.Minute
+	p, clock := newTestPolicy(cfg)
+
+	p.RecordFailure("x")
+	if ok, reason := p.Allow("x"); ok || reason != ReasonUnreachable {
+		t.Fatalf("失败后应进入负缓存, 得到 ok=%v reason=%s", ok, reason)
+	}
+	clock.advance(20 * time.Second)
+	if wait := p.RetryAfter("x"); wait != 40*time.Second {
+		t.Errorf("应在负缓存过期后重试, 等待 %v", wait)
+	}
+	clock.advance(-20 * time.Second)
+	if len(p.Rank([]string{"x", "y"})) != 1 {
+		t.Error("负缓存中的节点不应出现在排序结果中")
+	}
+
+	clock.advance(time.Minute + time.Second)
+	if p.IsUnreachable("x") {
+		t.Fatal("首次负缓存应在 1 分钟后过期")
+	}
+
+	// 第二次失败：2 分钟
+	p.RecordFailure("x")
+	clock.advance(90 * time.Second)
+	if !p.IsUnreachable("x") {
+		t.Fatal("第二次失败的负缓存应为 2 分钟")
+	}
+
+	// 第三次失败：4 分钟，被上限截断为 3 分钟
+	clock.advance(time.Minute)
+	p.RecordFailure("x")
+	clock.advance(3*time.Minute + time.Second)
+	if p.IsUnreachable("x") {
+		t.Fatal("负缓存时长不应超过上限")
+	}
+
+	p.RecordFailure("x")
+	p.RecordSuccess("x")
+	if p.IsUnreachable("x") {
+		t.Error("成功后应清除负缓存")
+	}
+}
+
+func TestAllowMinReputation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MinReputation = 20
+	p, _ := newTestPolicy(cfg)
+	p.SetReputationFunc(func(id string) float64 {
+		if id == "good" {
+			return 50
+		}
+		return 1
+	})
+	p.SetPriorityPeers([]string{"neighbor"})
+
+	if ok, reason := p.Allow("bad"); ok || reason != ReasonLowRep {
+		t.Errorf("低声誉节点应被拒绝, 得到 ok=%v reason=%s", ok, reason)
+	}
+	if ok, _ := p.Allow("good"); !ok {
+		t.Error("高声誉节点应被允许")
+	}
+	if ok, _ := p.Allow("neighbor"); !ok {
+		t.Error("优先节点不受声誉下限约束")
+	}
+
+	stats := p.GetStats()
+	if stats["allowed"].(int64) != 2 || stats["suppressed"].(int64) != 1 {
+		t.Errorf("统计不正确: %v", stats)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/dialer"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
@@ -32,6 +35,22 @@ type Service struct {
 	peerChan chan peer.AddrInfo
 	mu       sync.RWMutex
 	peers    map[peer.ID]peer.AddrInfo
+
+	// 出站拨号策略（可选）
+	policy *dialer.Policy
+
+	// 拨号结果回调（可选），err 为空表示连接成功
+	onDialResult func(id peer.ID, err error)
+
+	// 已安排在负缓存或速率限制到期后重试拨号的节点
+	retrying map[peer.ID]bool
+
+	// DHT 中广播和查找的 rendezvous，随网络命名空间变化
+	rendezvous string
+	network    string
+
+	// 本节点宣告的技能: 技能 -> 宣告
+	announced map[string]*announcement
 }
 
 // NewService 创建节点发现服务
@@ -48,7 +67,54 @@ func NewService(h host.Host, kadDHT *dht.IpfsDHT) *Service {
 		cancel:     cancel,
 		peerChan:   make(chan peer.AddrInfo, 100),
 		peers:      make(map[peer.ID]peer.AddrInfo),
+		rendezvous: DiscoveryNamespace,
+		retrying:   make(map[peer.ID]bool),
+		announced:  make(map[string]*announcement),
+	}
+}
+
+// SetNamespace 设置网络命名空间，只发现同一命名空间的节点，需在 Start 之前调用
+func (s *Service) SetNamespace(ns string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rendezvous = namespace.Rendezvous(ns, DiscoveryNamespace)
+	s.network = ns
+}
+
+// SetDialPolicy 设置出站拨号策略，需在 Start 之前调用
+func (s *Service) SetDialPolicy(p *dialer.Policy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+}
+
+// SetDialResultFunc 设置拨号结果回调，需在 Start 之前调用
+func (s *Service) SetDialResultFunc(fn func(id peer.ID, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onDialResult = fn
+}
+
+// ReconnectPersisted 优先重连持久化的邻居
+func (s *Service) ReconnectPersisted(peers []peer.AddrInfo) {
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		ids = append(ids, p.ID.String())
 	}
+
+	s.mu.Lock()
+	if s.policy != nil {
+		s.policy.SetPriorityPeers(ids)
+	}
+	for _, p := range peers {
+		s.peers[p.ID] = p
+	}
+	s.mu.Unlock()
+
+	if len(peers) > 0 {
+		fmt.Printf("   ♻️  重连持久化邻居: %d 个\n", len(peers))
+	}
+	s.dialPeers(peers)
 }
 
 // Start 启动发现服务
@@ -79,7 +145,7 @@ func (s *Service) advertise() {
 		default:
 		}
 
-		dutil.Advertise(s.ctx, s.routingDsc, DiscoveryNamespace)
+		dutil.Advertise(s.ctx, s.routingDsc, s.rendezvous)
 		fmt.Printf("   📢 已广播节点信息到 DHT\n")
 
 		select {
@@ -99,13 +165,14 @@ func (s *Service) discover() {
 		default:
 		}
 
-		peerChan, err := s.routingDsc.FindPeers(s.ctx, DiscoveryNamespace)
+		peerChan, err := s.routingDsc.FindPeers(s.ctx, s.rendezvous)
 		if err != nil {
 			fmt.Printf("   ⚠️  发现节点失败: %v\n", err)
 			time.Sleep(DiscoveryInterval)
 			continue
 		}
 
+		var found []peer.AddrInfo
 		for p := range peerChan {
 			if p.ID == s.host.ID() {
 				continue // 跳过自己
@@ -115,13 +182,14 @@ func (s *Service) discover() {
 			if _, exists := s.peers[p.ID]; !exists {
 				s.peers[p.ID] = p
 				fmt.Printf("   🔗 发现新节点: %s\n", p.ID.String()[:12])
-
-				// 尝试连接
-				go s.connectPeer(p)
+				found = append(found, p)
 			}
 			s.mu.Unlock()
 		}
 
+		// 尝试连接
+		s.dialPeers(found)
+
 		select {
 		case <-s.ctx.Done():
 			return
@@ -130,16 +198,110 @@ func (s *Service) discover() {
 	}
 }
 
+// dialPeers 按拨号策略排序并连接节点
+func (s *Service) dialPeers(peers []peer.AddrInfo) {
+	s.mu.RLock()
+	policy := s.policy
+	s.mu.RUnlock()
+
+	if policy == nil {
+		for _, p := range peers {
+			go s.connectPeer(p)
+		}
+		return
+	}
+
+	byID := make(map[string]peer.AddrInfo, len(peers))
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		byID[p.ID.String()] = p
+		ids = append(ids, p.ID.String())
+	}
+
+	ranked := policy.Rank(ids)
+	for _, id := range ranked {
+		if ok, reason := policy.Allow(id); !ok {
+			fmt.Printf("   ⏭️  跳过拨号 %s: %s\n", id[:12], reason)
+			if reason == dialer.ReasonLowRep {
+				// 声誉可能回升，移出已发现列表，在下一轮发现中重新判断
+				s.mu.Lock()
+				delete(s.peers, byID[id].ID)
+				s.mu.Unlock()
+				continue
+			}
+			s.retryLater(byID[id], policy.RetryAfter(id))
+			continue
+		}
+		go s.connectPeer(byID[id])
+	}
+
+	// Rank 剔除了负缓存中的节点，负缓存到期后重试
+	for _, id := range ranked {
+		delete(byID, id)
+	}
+	for id, p := range byID {
+		s.retryLater(p, policy.RetryAfter(id))
+	}
+}
+
+// retryLater 在 delay 后重新按拨号策略拨号节点，已连接时不再拨号；同一节点只安排一次
+func (s *Service) retryLater(p peer.AddrInfo, delay time.Duration) {
+	s.mu.Lock()
+	if s.retrying[p.ID] {
+		s.mu.Unlock()
+		return
+	}
+	s.retrying[p.ID] = true
+	s.mu.Unlock()
+
+	if delay < time.Second {
+		delay = time.Second
+	}
+	time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		delete(s.retrying, p.ID)
+		s.mu.Unlock()
+
+		if s.ctx.Err() != nil || s.host.Network().Connectedness(p.ID) == network.Connected {
+			return
+		}
+		s.dialPeers([]peer.AddrInfo{p})
+	})
+}
+
 // connectPeer 连接到节点
 func (s *Service) connectPeer(p peer.AddrInfo) {
 	ctx, cancel := context.WithTimeout(s.ctx, 30*time.Second)
 	defer cancel()
 
-	if err := s.host.Connect(ctx, p); err != nil {
+	s.mu.RLock()
+	policy := s.policy
+	onDialResult := s.onDialResult
+	s.mu.RUnlock()
+
+	err := s.host.Connect(ctx, p)
+	if onDialResult != nil {
+		onDialResult(p.ID, err)
+	}
+	if err != nil {
 		fmt.Printf("   ⚠️  连接节点失败 %s: %v\n", p.ID.String()[:12], err)
+		if policy != nil {
+			// 移出已发现列表，负缓存过期后可在下一轮发现中重试；
+			// 持久化的邻居不一定能再被发现，直接在负缓存到期后重试
+			policy.RecordFailure(p.ID.String())
+			s.mu.Lock()
+			delete(s.peers, p.ID)
+			s.mu.Unlock()
+			if policy.IsPriority(p.ID.String()) {
+				s.retryLater(p, policy.RetryAfter(p.ID.String()))
+			}
+		}
 		return
 	}
 
+	if policy != nil {
+		policy.RecordSuccess(p.ID.String())
+	}
 	fmt.Printf("   ✅ 已连接节点: %s\n", p.ID.String()[:12])
 
 	select {
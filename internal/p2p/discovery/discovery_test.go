@@ -1,6 +1,8 @@
 package discovery
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,5 +17,31 @@ func TestDiscoveryConstants(t *testing.T) {
 	}
 }
 
+func TestNormalizeSkill(t *testing.T) {
+	for in, want := range map[string]string{
+		"translation":   "translation",
+		" Code-Review ": "code-review",
+		"ocr.zh_cn":     "ocr.zh_cn",
+	} {
+		if got, err := NormalizeSkill(in); err != nil || got != want {
+			t.Errorf("NormalizeSkill(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "  ", "a/b", "two words", strings.Repeat("a", 65)} {
+		if _, err := NormalizeSkill(bad); !errors.Is(err, ErrInvalidSkill) {
+			t.Errorf("NormalizeSkill(%q): expected ErrInvalidSkill, got %v", bad, err)
+		}
+	}
+}
+
+func TestSkillRendezvous(t *testing.T) {
+	if got := SkillRendezvous("", "translation"); got != "daan/agents/translation" {
+		t.Errorf("默认网络 rendezvous 错误: %s", got)
+	}
+	if got := SkillRendezvous("acme", "translation"); got != "/ns/acme/daan/agents/translation" {
+		t.Errorf("命名空间 rendezvous 错误: %s", got)
+	}
+}
+
 // 注意：更完整的 discovery 测试需要真实的 libp2p host 和 DHT
 // 这些测试在 node_test.go 中通过集成测试覆盖
@@ -0,0 +1,218 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+	"github.com/libp2p/go-libp2p/core/discovery"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// 按能力发现 Agent
+//
+// 节点在 DHT 中以 rendezvous 命名空间 daan/agents/<技能> 宣告自己提供的任务类型，
+// 其他节点按技能查找提供者。DHT 中的宣告会过期，宣告后定期重新宣告，直到撤回或节点停止。
+
+const (
+	// AgentNamespacePrefix 技能 rendezvous 命名空间前缀
+	AgentNamespacePrefix = "daan/agents/"
+	// AnnounceInterval 重新宣告的最长间隔（DHT 返回的 TTL 更短时按 TTL 提前重新宣告）
+	AnnounceInterval = 10 * time.Minute
+	// DefaultFindLimit 查找提供者时默认返回的数量
+	DefaultFindLimit = 20
+	// MaxFindLimit 查找提供者时最多返回的数量
+	MaxFindLimit = 100
+	// maxSkillLength 技能名称的最大长度
+	maxSkillLength = 64
+	// announceRetry 宣告失败后的重试间隔
+	announceRetry = time.Minute
+)
+
+var (
+	ErrInvalidSkill   = errors.New("invalid skill")
+	ErrNotAnnounced   = errors.New("skill not announced")
+	ErrServiceStopped = errors.New("discovery service stopped")
+)
+
+// NormalizeSkill 规范化技能名称：去除首尾空白并转为小写，只允许字母、数字和 - _ .
+func NormalizeSkill(skill string) (string, error) {
+	skill = strings.ToLower(strings.TrimSpace(skill))
+	if skill == "" || len(skill) > maxSkillLength {
+		return "", fmt.Errorf("%w: length must be 1-%d", ErrInvalidSkill, maxSkillLength)
+	}
+	for _, c := range skill {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' {
+			return "", fmt.Errorf("%w: %q contains %q", ErrInvalidSkill, skill, c)
+		}
+	}
+	return skill, nil
+}
+
+// SkillRendezvous 技能在网络命名空间 ns 中的 rendezvous，默认网络为 daan/agents/<技能>
+func SkillRendezvous(ns, skill string) string {
+	if ns == "" {
+		return AgentNamespacePrefix + skill
+	}
+	return namespace.Rendezvous(ns, "/"+AgentNamespacePrefix+skill)
+}
+
+// Announcement 本节点的技能宣告
+type Announcement struct {
+	Skill         string    `json:"skill"`
+	Rendezvous    string    `json:"rendezvous"`
+	Since         time.Time `json:"since"`
+	LastAnnounced time.Time `json:"last_announced,omitempty"`
+	NextAnnounce  time.Time `json:"next_announce"`
+	TTL           int64     `json:"ttl"` // DHT 返回的宣告有效期（秒）
+	Announces     int       `json:"announces"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// announcement 运行中的宣告
+type announcement struct {
+	Announcement
+	cancel context.CancelFunc
+}
+
+// Announce 在 DHT 中宣告本节点提供某技能并定期重新宣告，已宣告时返回现有宣告
+// 首次宣告在后台进行，结果见 Announcements。
+func (s *Service) Announce(skill string) (*Announcement, error) {
+	skill, err := NormalizeSkill(skill)
+	if err != nil {
+		return nil, err
+	}
+	if s.ctx.Err() != nil {
+		return nil, ErrServiceStopped
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.announced[skill]; ok {
+		copied := a.Announcement
+		return &copied, nil
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	a := &announcement{
+		Announcement: Announcement{
+			Skill:        skill,
+			Rendezvous:   SkillRendezvous(s.network, skill),
+			Since:        time.Now(),
+			NextAnnounce: time.Now(),
+		},
+		cancel: cancel,
+	}
+	s.announced[skill] = a
+	go s.reannounce(ctx, a)
+
+	copied := a.Announcement
+	return &copied, nil
+}
+
+// Withdraw 停止重新宣告某技能，DHT 中已有的宣告在 TTL 到期后失效
+func (s *Service) Withdraw(skill string) error {
+	skill, err := NormalizeSkill(skill)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.announced[skill]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotAnnounced, skill)
+	}
+	a.cancel()
+	delete(s.announced, skill)
+	return nil
+}
+
+// Announcements 本节点正在宣告的技能，按技能名称排序
+func (s *Service) Announcements() []Announcement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Announcement, 0, len(s.announced))
+	for _, a := range s.announced {
+		result = append(result, a.Announcement)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Skill < result[j].Skill })
+	return result
+}
+
+// reannounce 宣告技能，按 TTL 和 AnnounceInterval 中较短者定期重新宣告，失败时稍后重试
+func (s *Service) reannounce(ctx context.Context, a *announcement) {
+	for {
+		ttl, err := s.routingDsc.Advertise(ctx, a.Rendezvous, discovery.TTL(2*AnnounceInterval))
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := AnnounceInterval
+		now := time.Now()
+		s.mu.Lock()
+		if err != nil {
+			a.LastError = err.Error()
+			wait = announceRetry
+		} else {
+			a.LastError = ""
+			a.LastAnnounced = now
+			a.TTL = int64(ttl / time.Second)
+			a.Announces++
+			if ttl > 0 && ttl*4/5 < wait {
+				wait = ttl * 4 / 5
+			}
+		}
+		a.NextAnnounce = now.Add(wait)
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// FindProviders 查找提供某技能的节点（不含本节点），最多返回 limit 个
+func (s *Service) FindProviders(ctx context.Context, skill string, limit int) ([]peer.AddrInfo, error) {
+	skill, err := NormalizeSkill(skill)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultFindLimit
+	}
+	if limit > MaxFindLimit {
+		limit = MaxFindLimit
+	}
+
+	s.mu.RLock()
+	rendezvous := SkillRendezvous(s.network, skill)
+	s.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// 多取一个，结果中可能包含本节点
+	peerChan, err := s.routingDsc.FindPeers(ctx, rendezvous, discovery.Limit(limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]peer.AddrInfo, 0, limit)
+	seen := make(map[peer.ID]bool)
+	for p := range peerChan {
+		if p.ID == s.host.ID() || seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		providers = append(providers, p)
+		if len(providers) == limit {
+			break
+		}
+	}
+	return providers, nil
+}
@@ -0,0 +1,45 @@
+package host
+
+import (
+	"github.com/libp2p/go-libp2p/core/connmgr"
+	"github.com/libp2p/go-libp2p/core/control"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// inboundGater 入站连接网关：安全握手完成、对端身份确定后，按准入函数决定是否保留连接。
+// 出站连接和握手前的阶段不受限制。
+type inboundGater struct {
+	h *Host
+}
+
+var _ connmgr.ConnectionGater = (*inboundGater)(nil)
+
+func (g *inboundGater) InterceptPeerDial(peer.ID) bool { return true }
+
+func (g *inboundGater) InterceptAddrDial(peer.ID, multiaddr.Multiaddr) bool { return true }
+
+func (g *inboundGater) InterceptAccept(network.ConnMultiaddrs) bool { return true }
+
+func (g *inboundGater) InterceptSecured(dir network.Direction, p peer.ID, _ network.ConnMultiaddrs) bool {
+	if dir != network.DirInbound {
+		return true
+	}
+	g.h.mu.RLock()
+	admit := g.h.admit
+	g.h.mu.RUnlock()
+	// 准入函数设置之前拒绝所有入站连接，避免启动期间绕过关系证明
+	return admit != nil && admit(p.String())
+}
+
+func (g *inboundGater) InterceptUpgraded(network.Conn) (bool, control.DisconnectReason) {
+	return true, 0
+}
+
+// SetAdmitFunc 设置入站连接的准入函数，仅在 Config.GateInbound 开启时生效
+func (h *Host) SetAdmitFunc(fn func(peerID string) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.admit = fn
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	"github.com/libp2p/go-libp2p/core/routing"
 	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
 	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
@@ -18,6 +19,7 @@ import (
 	libp2ptls "github.com/libp2p/go-libp2p/p2p/security/tls"
 	"github.com/multiformats/go-multiaddr"
 
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/identity"
 )
 
@@ -38,6 +40,9 @@ type Config struct {
 	Role           NodeRole
 	EnableRelay    bool
 	EnableDHT      bool
+	Namespace      string // 网络命名空间，混入 DHT 协议前缀；空为默认网络
+	DeferBootstrap bool   // 启动时不自动连接引导节点，由调用方按需调用 ConnectBootstrapPeers
+	GateInbound    bool   // 入站连接需经 SetAdmitFunc 设置的准入函数放行
 }
 
 // DefaultConfig 返回默认配置
@@ -62,6 +67,7 @@ type Host struct {
 	cancel   context.CancelFunc
 	mu       sync.RWMutex
 	connChan chan peer.AddrInfo
+	admit    func(peerID string) bool // 入站准入函数（GateInbound）
 }
 
 // New 创建新的 P2P 主机
@@ -69,6 +75,9 @@ func New(cfg *Config) (*Host, error) {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
+	if err := namespace.Validate(cfg.Namespace); err != nil {
+		return nil, err
+	}
 
 	// 如果没有身份，创建一个
 	if cfg.Identity == nil {
@@ -128,6 +137,9 @@ func (h *Host) init() error {
 		libp2p.NATPortMap(),
 		libp2p.EnableNATService(),
 	}
+	if h.config.GateInbound {
+		opts = append(opts, libp2p.ConnectionGater(&inboundGater{h: h}))
+	}
 
 	// 根据角色配置
 	if h.config.Role == RoleRelay || h.config.Role == RoleBootstrap {
@@ -143,6 +155,7 @@ func (h *Host) init() error {
 	// DHT 路由
 	var kadDHT *dht.IpfsDHT
 	if h.config.EnableDHT {
+		dhtPrefix := namespace.DHTPrefix(h.config.Namespace)
 		opts = append(opts, libp2p.Routing(func(h host.Host) (routing.PeerRouting, error) {
 			var dhtOpts []dht.Option
 
@@ -156,6 +169,11 @@ func (h *Host) init() error {
 				}
 			}
 
+			// 不同命名空间使用不同的 DHT 协议，路由表和记录互不可见
+			if dhtPrefix != "" {
+				dhtOpts = append(dhtOpts, dht.ProtocolPrefix(protocol.ID(dhtPrefix)))
+			}
+
 			var err error
 			kadDHT, err = dht.New(context.Background(), h, dhtOpts...)
 			return kadDHT, err
@@ -215,15 +233,15 @@ func (h *Host) Start() error {
 	}
 
 	// 连接到引导节点
-	if len(h.config.BootstrapPeers) > 0 {
-		go h.connectBootstrapPeers()
+	if len(h.config.BootstrapPeers) > 0 && !h.config.DeferBootstrap {
+		go h.ConnectBootstrapPeers()
 	}
 
 	return nil
 }
 
-// connectBootstrapPeers 连接到引导节点
-func (h *Host) connectBootstrapPeers() {
+// ConnectBootstrapPeers 连接到引导节点（阻塞直到逐个尝试完毕）
+func (h *Host) ConnectBootstrapPeers() {
 	for _, addrStr := range h.config.BootstrapPeers {
 		ma, err := multiaddr.NewMultiaddr(addrStr)
 		if err != nil {
@@ -280,6 +298,11 @@ func (h *Host) ID() peer.ID {
 	return h.host.ID()
 }
 
+// Namespace 返回网络命名空间
+func (h *Host) Namespace() string {
+	return h.config.Namespace
+}
+
 // Addrs 返回监听地址
 func (h *Host) Addrs() []multiaddr.Multiaddr {
 	return h.host.Addrs()
@@ -0,0 +1,298 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNodeStarted       = errors.New("node already started")
+	ErrDuplicateService  = errors.New("service already registered")
+	ErrUnknownDependency = errors.New("unknown service dependency")
+	ErrDependencyCycle   = errors.New("service dependency cycle")
+)
+
+// DefaultServiceStopTimeout 停止嵌入服务和钩子的默认总时限
+const DefaultServiceStopTimeout = 10 * time.Second
+
+// Service 嵌入方挂到节点生命周期上的子系统
+// Start 在 P2P 主机与发现服务启动之后调用，Stop 在主机停止之前调用。
+type Service interface {
+	Start(ctx context.Context, n *Node) error
+	Stop(ctx context.Context) error
+}
+
+// StartHook 节点启动后执行的钩子
+type StartHook func(ctx context.Context, n *Node) error
+
+// StopHook 节点停止前执行的钩子
+type StopHook func(ctx context.Context) error
+
+// ServiceState 服务运行状态
+type ServiceState string
+
+const (
+	ServiceRegistered ServiceState = "registered"
+	ServiceRunning    ServiceState = "running"
+	ServiceFailed     ServiceState = "failed"
+	ServiceStopped    ServiceState = "stopped"
+)
+
+// ServiceStatus 服务状态
+type ServiceStatus struct {
+	Name      string       `json:"name"`
+	DependsOn []string     `json:"depends_on,omitempty"`
+	State     ServiceState `json:"state"`
+	Error     string       `json:"error,omitempty"`
+	StartedAt time.Time    `json:"started_at,omitempty"`
+}
+
+// registeredService 已注册的服务
+type registeredService struct {
+	name      string
+	svc       Service
+	dependsOn []string
+	state     ServiceState
+	err       error
+	startedAt time.Time
+}
+
+// lifecycle 嵌入服务与钩子
+type lifecycle struct {
+	opMu       sync.Mutex // 串行化启动与停止
+	mu         sync.Mutex
+	services   []*registeredService // 注册顺序
+	byName     map[string]*registeredService
+	startHooks []StartHook
+	stopHooks  []StopHook
+	started    []*registeredService // 已启动的服务（启动顺序），停止时逆序
+	running    bool
+}
+
+// RegisterService 注册嵌入服务，dependsOn 中的服务先于它启动、晚于它停止
+// 依赖可以稍后注册，启动时统一检查缺失和循环依赖。节点启动后不能再注册。
+func (n *Node) RegisterService(name string, svc Service, dependsOn ...string) error {
+	lc := &n.lifecycle
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.running {
+		return ErrNodeStarted
+	}
+	if lc.byName == nil {
+		lc.byName = make(map[string]*registeredService)
+	}
+	if _, exists := lc.byName[name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateService, name)
+	}
+	rs := &registeredService{
+		name:      name,
+		svc:       svc,
+		dependsOn: append([]string(nil), dependsOn...),
+		state:     ServiceRegistered,
+	}
+	lc.services = append(lc.services, rs)
+	lc.byName[name] = rs
+	return nil
+}
+
+// OnStart 注册启动钩子，在所有嵌入服务启动后按注册顺序执行
+func (n *Node) OnStart(hook StartHook) {
+	n.lifecycle.mu.Lock()
+	defer n.lifecycle.mu.Unlock()
+	n.lifecycle.startHooks = append(n.lifecycle.startHooks, hook)
+}
+
+// OnStop 注册停止钩子，在嵌入服务停止前按注册的逆序执行
+func (n *Node) OnStop(hook StopHook) {
+	n.lifecycle.mu.Lock()
+	defer n.lifecycle.mu.Unlock()
+	n.lifecycle.stopHooks = append(n.lifecycle.stopHooks, hook)
+}
+
+// Services 返回嵌入服务的状态（按启动顺序）
+func (n *Node) Services() []ServiceStatus {
+	lc := &n.lifecycle
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	ordered, err := lc.startOrder()
+	if err != nil {
+		ordered = lc.services
+	}
+	result := make([]ServiceStatus, 0, len(ordered))
+	for _, rs := range ordered {
+		status := ServiceStatus{
+			Name:      rs.name,
+			DependsOn: rs.dependsOn,
+			State:     rs.state,
+			StartedAt: rs.startedAt,
+		}
+		if rs.err != nil {
+			status.Error = rs.err.Error()
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// startServices 按依赖顺序启动嵌入服务，再执行启动钩子
+// 任一步失败时逆序停止已启动的服务并返回错误。服务和钩子在锁外调用，
+// 因此可以在其中查询 Services()。
+func (n *Node) startServices(ctx context.Context) error {
+	lc := &n.lifecycle
+	lc.opMu.Lock()
+	defer lc.opMu.Unlock()
+
+	lc.mu.Lock()
+	ordered, err := lc.startOrder()
+	if err != nil {
+		lc.mu.Unlock()
+		return err
+	}
+	lc.running = true
+	hooks := append([]StartHook(nil), lc.startHooks...)
+	lc.mu.Unlock()
+
+	for _, rs := range ordered {
+		if err := rs.svc.Start(ctx, n); err != nil {
+			lc.setState(rs, ServiceFailed, err)
+			n.rollback()
+			return fmt.Errorf("启动服务 %s 失败: %w", rs.name, err)
+		}
+		lc.setState(rs, ServiceRunning, nil)
+		lc.mu.Lock()
+		lc.started = append(lc.started, rs)
+		lc.mu.Unlock()
+	}
+
+	for i, hook := range hooks {
+		if err := hook(ctx, n); err != nil {
+			n.rollback()
+			return fmt.Errorf("启动钩子 #%d 失败: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// stopServices 逆序执行停止钩子，再按依赖的逆序停止嵌入服务
+// 单个钩子或服务出错不影响其余的停止，错误合并返回。
+func (n *Node) stopServices() error {
+	lc := &n.lifecycle
+	lc.opMu.Lock()
+	defer lc.opMu.Unlock()
+
+	lc.mu.Lock()
+	if !lc.running {
+		lc.mu.Unlock()
+		return nil
+	}
+	hooks := append([]StopHook(nil), lc.stopHooks...)
+	lc.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.stopTimeout())
+	defer cancel()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, fmt.Errorf("停止钩子 #%d: %w", i+1, err))
+		}
+	}
+	errs = append(errs, lc.stopStarted(ctx)...)
+	return errors.Join(errs...)
+}
+
+// rollback 启动失败时停止已启动的服务（调用方持有 opMu）
+func (n *Node) rollback() {
+	ctx, cancel := context.WithTimeout(context.Background(), n.stopTimeout())
+	defer cancel()
+	n.lifecycle.stopStarted(ctx)
+}
+
+// stopStarted 逆序停止已启动的服务，之后允许重新注册和启动
+func (lc *lifecycle) stopStarted(ctx context.Context) []error {
+	lc.mu.Lock()
+	started := lc.started
+	lc.started = nil
+	lc.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		rs := started[i]
+		if err := rs.svc.Stop(ctx); err != nil {
+			lc.setState(rs, ServiceFailed, err)
+			errs = append(errs, fmt.Errorf("停止服务 %s: %w", rs.name, err))
+			continue
+		}
+		lc.setState(rs, ServiceStopped, nil)
+	}
+
+	lc.mu.Lock()
+	lc.running = false
+	lc.mu.Unlock()
+	return errs
+}
+
+func (lc *lifecycle) setState(rs *registeredService, state ServiceState, err error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	rs.state, rs.err = state, err
+	if state == ServiceRunning {
+		rs.startedAt = time.Now()
+	}
+}
+
+// startOrder 按依赖拓扑排序，依赖相同时保持注册顺序
+func (lc *lifecycle) startOrder() ([]*registeredService, error) {
+	for _, rs := range lc.services {
+		for _, dep := range rs.dependsOn {
+			if _, ok := lc.byName[dep]; !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, rs.name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int, len(lc.services))
+	ordered := make([]*registeredService, 0, len(lc.services))
+
+	var visit func(rs *registeredService, path []string) error
+	visit = func(rs *registeredService, path []string) error {
+		switch marks[rs.name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, append(path, rs.name))
+		}
+		marks[rs.name] = visiting
+		for _, dep := range rs.dependsOn {
+			if err := visit(lc.byName[dep], append(path, rs.name)); err != nil {
+				return err
+			}
+		}
+		marks[rs.name] = done
+		ordered = append(ordered, rs)
+		return nil
+	}
+	for _, rs := range lc.services {
+		if err := visit(rs, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func (n *Node) stopTimeout() time.Duration {
+	if n.config.ServiceStopTimeout > 0 {
+		return n.config.ServiceStopTimeout
+	}
+	return DefaultServiceStopTimeout
+}
@@ -0,0 +1,154 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// recordingService 记录启停顺序的测试服务
+type recordingService struct {
+	name     string
+	events   *[]string
+	startErr error
+}
+
+func (s *recordingService) Start(ctx context.Context, n *Node) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+	*s.events = append(*s.events, "start "+s.name)
+	return nil
+}
+
+func (s *recordingService) Stop(ctx context.Context) error {
+	*s.events = append(*s.events, "stop "+s.name)
+	return nil
+}
+
+// newLifecycleNode 不创建 P2P 主机，只测试生命周期编排
+func newLifecycleNode() *Node {
+	return &Node{config: DefaultConfig()}
+}
+
+func TestLifecycle_DependencyOrder(t *testing.T) {
+	n := newLifecycleNode()
+	var events []string
+
+	// 依赖可以晚于使用方注册
+	mustRegister(t, n, "api", &recordingService{name: "api", events: &events}, "store", "index")
+	mustRegister(t, n, "index", &recordingService{name: "index", events: &events}, "store")
+	mustRegister(t, n, "store", &recordingService{name: "store", events: &events})
+
+	n.OnStart(func(ctx context.Context, n *Node) error {
+		events = append(events, "hook start")
+		return nil
+	})
+	n.OnStop(func(ctx context.Context) error {
+		events = append(events, "hook stop 1")
+		return nil
+	})
+	n.OnStop(func(ctx context.Context) error {
+		events = append(events, "hook stop 2")
+		return nil
+	})
+
+	if err := n.startServices(context.Background()); err != nil {
+		t.Fatalf("启动服务失败: %v", err)
+	}
+	if err := n.RegisterService("late", &recordingService{name: "late", events: &events}); !errors.Is(err, ErrNodeStarted) {
+		t.Errorf("启动后注册应返回 ErrNodeStarted，实际: %v", err)
+	}
+	for _, s := range n.Services() {
+		if s.State != ServiceRunning {
+			t.Errorf("服务 %s 状态应为 running，实际: %s", s.Name, s.State)
+		}
+	}
+
+	if err := n.stopServices(); err != nil {
+		t.Fatalf("停止服务失败: %v", err)
+	}
+
+	want := []string{
+		"start store", "start index", "start api", "hook start",
+		"hook stop 2", "hook stop 1", "stop api", "stop index", "stop store",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("启停顺序错误:\n got %v\nwant %v", events, want)
+	}
+
+	// 重复停止不再调用服务
+	if err := n.stopServices(); err != nil || len(events) != len(want) {
+		t.Errorf("重复停止应为空操作: %v %v", err, events)
+	}
+}
+
+func TestLifecycle_InvalidGraph(t *testing.T) {
+	var events []string
+
+	n := newLifecycleNode()
+	mustRegister(t, n, "a", &recordingService{name: "a", events: &events}, "missing")
+	if err := n.startServices(context.Background()); !errors.Is(err, ErrUnknownDependency) {
+		t.Errorf("应返回 ErrUnknownDependency，实际: %v", err)
+	}
+
+	n = newLifecycleNode()
+	mustRegister(t, n, "a", &recordingService{name: "a", events: &events}, "b")
+	mustRegister(t, n, "b", &recordingService{name: "b", events: &events}, "a")
+	if err := n.startServices(context.Background()); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("应返回 ErrDependencyCycle，实际: %v", err)
+	}
+	if err := n.RegisterService("a", &recordingService{name: "a", events: &events}); !errors.Is(err, ErrDuplicateService) {
+		t.Errorf("应返回 ErrDuplicateService，实际: %v", err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("依赖无效时不应启动任何服务: %v", events)
+	}
+}
+
+func TestLifecycle_StartFailureRollsBack(t *testing.T) {
+	n := newLifecycleNode()
+	var events []string
+	boom := errors.New("boom")
+
+	mustRegister(t, n, "store", &recordingService{name: "store", events: &events})
+	mustRegister(t, n, "index", &recordingService{name: "index", events: &events}, "store")
+	mustRegister(t, n, "api", &recordingService{name: "api", events: &events, startErr: boom}, "index")
+
+	if err := n.startServices(context.Background()); !errors.Is(err, boom) {
+		t.Fatalf("应返回服务启动错误，实际: %v", err)
+	}
+	want := []string{"start store", "start index", "stop index", "stop store"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("回滚顺序错误:\n got %v\nwant %v", events, want)
+	}
+
+	states := make(map[string]ServiceState)
+	for _, s := range n.Services() {
+		states[s.Name] = s.State
+	}
+	if states["api"] != ServiceFailed || states["store"] != ServiceStopped {
+		t.Errorf("回滚后状态错误: %v", states)
+	}
+
+	// 启动钩子失败同样回滚
+	n = newLifecycleNode()
+	events = nil
+	mustRegister(t, n, "store", &recordingService{name: "store", events: &events})
+	n.OnStart(func(ctx context.Context, n *Node) error { return boom })
+	if err := n.startServices(context.Background()); !errors.Is(err, boom) {
+		t.Fatalf("应返回钩子错误，实际: %v", err)
+	}
+	if want := []string{"start store", "stop store"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("钩子失败后回滚错误: %v", events)
+	}
+}
+
+func mustRegister(t *testing.T, n *Node, name string, svc Service, dependsOn ...string) {
+	t.Helper()
+	if err := n.RegisterService(name, svc, dependsOn...); err != nil {
+		t.Fatalf("注册服务 %s 失败: %v", name, err)
+	}
+}
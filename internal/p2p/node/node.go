@@ -2,14 +2,19 @@ package node
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/dialer"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/discovery"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/host"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/identity"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/p2p/peercache"
+	"github.com/libp2p/go-libp2p/core/peer"
 )
 
 // Config 节点配置
@@ -22,9 +27,28 @@ type Config struct {
 	BootstrapPeers []string
 	Role           host.NodeRole
 
+	// 拨号策略
+	DialPolicy     *dialer.Policy
+	PersistedPeers []string // 持久化邻居的完整 multiaddr（含 /p2p/ID），启动时优先重连
+
+	// 冷启动节点缓存：重启后先重连最近可用的节点
+	PeerCache *peercache.Cache
+	// 有可重连的节点时，等待该时长后已连接节点数仍低于 MinPeers 才连接引导节点；0 表示启动即连接引导节点
+	BootstrapFallback time.Duration
+	MinPeers          int
+
 	// 功能开关
 	EnableRelay bool
 	EnableDHT   bool
+
+	// 网络命名空间：共用引导节点的多个逻辑网络之间互相隔离，空为默认网络
+	Namespace string
+
+	// 入站连接需要关系证明，准入函数由 Host().SetAdmitFunc 设置
+	GateInbound bool
+
+	// 嵌入服务停止时限，0 使用 DefaultServiceStopTimeout
+	ServiceStopTimeout time.Duration
 }
 
 // DefaultConfig 返回默认配置
@@ -50,6 +74,8 @@ type Node struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	lifecycle lifecycle // 嵌入服务与启停钩子
 }
 
 // New 创建新节点
@@ -75,6 +101,9 @@ func New(cfg *Config) (*Node, error) {
 		Role:           cfg.Role,
 		EnableRelay:    cfg.EnableRelay,
 		EnableDHT:      cfg.EnableDHT,
+		Namespace:      cfg.Namespace,
+		DeferBootstrap: cfg.BootstrapFallback > 0,
+		GateInbound:    cfg.GateInbound,
 	}
 
 	h, err := host.New(hostCfg)
@@ -106,13 +135,45 @@ func (n *Node) Start() error {
 	}
 
 	// 如果 DHT 可用，启动发现服务
+	var persisted []peer.AddrInfo
 	if n.host.DHT() != nil {
 		n.discovery = discovery.NewService(n.host.Host(), n.host.DHT())
+		n.discovery.SetNamespace(n.config.Namespace)
+		if n.config.DialPolicy != nil {
+			n.discovery.SetDialPolicy(n.config.DialPolicy)
+		}
+		if n.config.PeerCache != nil {
+			n.discovery.SetDialResultFunc(n.recordDialResult)
+		}
+		if persisted = parsePeerAddrs(n.reconnectAddrs()); len(persisted) > 0 {
+			n.discovery.ReconnectPersisted(persisted)
+		}
 		if err := n.discovery.Start(); err != nil {
 			fmt.Printf("⚠️  启动发现服务失败: %v\n", err)
 		}
 	}
 
+	// 引导节点只在重连缓存节点不够用时才连接
+	if n.config.BootstrapFallback > 0 {
+		if len(persisted) > 0 {
+			go n.bootstrapFallback()
+		} else {
+			go n.host.ConnectBootstrapPeers()
+		}
+	}
+	if n.config.PeerCache != nil {
+		go n.snapshotPeerCacheLoop()
+	}
+
+	// 主机就绪后按依赖顺序启动嵌入服务
+	if err := n.startServices(n.ctx); err != nil {
+		if n.discovery != nil {
+			n.discovery.Stop()
+		}
+		n.host.Stop()
+		return err
+	}
+
 	fmt.Println("═══════════════════════════════════════════")
 	fmt.Printf("📊 当前连接节点数: %d\n", n.host.ConnectedPeers())
 	fmt.Println("═══════════════════════════════════════════")
@@ -120,6 +181,26 @@ func (n *Node) Start() error {
 	return nil
 }
 
+// parsePeerAddrs 解析节点地址并按节点合并，忽略无效条目
+func parsePeerAddrs(addrs []string) []peer.AddrInfo {
+	infos := make([]peer.AddrInfo, 0, len(addrs))
+	index := make(map[peer.ID]int)
+	for _, addr := range addrs {
+		info, err := peer.AddrInfoFromString(addr)
+		if err != nil {
+			fmt.Printf("⚠️  忽略无效的邻居地址 %s: %v\n", addr, err)
+			continue
+		}
+		if i, ok := index[info.ID]; ok {
+			infos[i].Addrs = append(infos[i].Addrs, info.Addrs...)
+			continue
+		}
+		index[info.ID] = len(infos)
+		infos = append(infos, *info)
+	}
+	return infos
+}
+
 // Run 运行节点（阻塞直到收到停止信号）
 func (n *Node) Run() error {
 	if err := n.Start(); err != nil {
@@ -140,17 +221,28 @@ func (n *Node) Run() error {
 
 // Stop 停止节点
 func (n *Node) Stop() error {
+	// 嵌入服务先于主机停止，停止时仍可使用网络
+	svcErr := n.stopServices()
+
 	n.cancel()
 
+	// 主机停止前记下当前连接的节点
+	if n.config.PeerCache != nil {
+		n.snapshotPeerCache()
+		if err := n.config.PeerCache.Save(); err != nil {
+			fmt.Printf("⚠️  保存节点缓存失败: %v\n", err)
+		}
+	}
+
 	if n.discovery != nil {
 		n.discovery.Stop()
 	}
 
 	if n.host != nil {
-		return n.host.Stop()
+		return errors.Join(svcErr, n.host.Stop())
 	}
 
-	return nil
+	return svcErr
 }
 
 // Identity 返回节点身份
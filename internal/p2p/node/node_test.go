@@ -214,3 +214,22 @@ func TestNode_MultipleNodes(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePeerAddrs(t *testing.T) {
+	const id = "QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"
+	infos := parsePeerAddrs([]string{
+		"/ip4/104.131.131.82/tcp/4001/p2p/" + id,
+		"/ip4/104.131.131.82/udp/4001/quic-v1/p2p/" + id,
+		"not-a-multiaddr",
+	})
+
+	if len(infos) != 1 {
+		t.Fatalf("期望合并为 1 个节点, 得到 %d", len(infos))
+	}
+	if infos[0].ID.String() != id {
+		t.Errorf("节点 ID 错误: %s", infos[0].ID)
+	}
+	if len(infos[0].Addrs) != 2 {
+		t.Errorf("期望 2 个地址, 得到 %d", len(infos[0].Addrs))
+	}
+}
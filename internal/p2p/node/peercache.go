@@ -0,0 +1,84 @@
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// maxCachedReconnects 启动时从节点缓存中重连的节点数上限
+	maxCachedReconnects = 32
+	// peerCacheSaveInterval 记录当前连接并保存节点缓存的间隔
+	peerCacheSaveInterval = 2 * time.Minute
+)
+
+// reconnectAddrs 启动时优先重连的地址：持久化邻居在前，其后为节点缓存中最近可用的节点
+func (n *Node) reconnectAddrs() []string {
+	addrs := append([]string(nil), n.config.PersistedPeers...)
+	if n.config.PeerCache == nil {
+		return addrs
+	}
+	for _, entry := range n.config.PeerCache.Candidates(maxCachedReconnects) {
+		if entry.PeerID == n.ID() {
+			continue
+		}
+		addrs = append(addrs, entry.PeerAddrs()...)
+	}
+	return addrs
+}
+
+// bootstrapFallback 等待缓存节点重连，连接数仍不足时再连接引导节点
+func (n *Node) bootstrapFallback() {
+	select {
+	case <-n.ctx.Done():
+		return
+	case <-time.After(n.config.BootstrapFallback):
+	}
+
+	connected := n.host.ConnectedPeers()
+	if connected >= n.config.MinPeers && connected > 0 {
+		fmt.Printf("   ♻️  已通过缓存节点连接 %d 个节点，跳过引导节点\n", connected)
+		return
+	}
+	fmt.Printf("   ↩️  缓存节点连接不足 (%d/%d)，连接引导节点\n", connected, n.config.MinPeers)
+	n.host.ConnectBootstrapPeers()
+}
+
+// recordDialResult 拨号失败计入节点缓存，成功的连接由定期快照记录
+func (n *Node) recordDialResult(id peer.ID, err error) {
+	if err != nil {
+		n.config.PeerCache.RecordFailure(id.String())
+	}
+}
+
+// snapshotPeerCache 把当前连接的节点及其已知地址记入节点缓存
+func (n *Node) snapshotPeerCache() {
+	h := n.host.Host()
+	for _, id := range h.Network().Peers() {
+		addrs := h.Peerstore().Addrs(id)
+		strs := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			strs = append(strs, addr.String())
+		}
+		n.config.PeerCache.RecordSeen(id.String(), strs)
+	}
+}
+
+// snapshotPeerCacheLoop 定期保存节点缓存，进程异常退出时也不会全部丢失
+func (n *Node) snapshotPeerCacheLoop() {
+	ticker := time.NewTicker(peerCacheSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+			n.snapshotPeerCache()
+			if err := n.config.PeerCache.Save(); err != nil {
+				fmt.Printf("⚠️  保存节点缓存失败: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Package peercache 持久化的冷启动节点缓存
+// 记录曾经连通的节点地址、最近在线时间和声誉提示，重启后优先重连
+// 最近可用的节点，只有这些节点都连不上时才回退到引导节点。
+package peercache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAddrsPerPeer 每个节点保留的地址数上限（保留最近观察到的）
+const maxAddrsPerPeer = 8
+
+// Entry 缓存的节点
+type Entry struct {
+	PeerID      string    `json:"peer_id"`
+	Addrs       []string  `json:"addrs"`
+	LastSeen    time.Time `json:"last_seen"`              // 最近一次连接成功
+	LastFailure time.Time `json:"last_failure,omitempty"` // 最近一次拨号失败
+	Failures    int       `json:"failures"`               // 连续拨号失败次数
+	Reputation  float64   `json:"reputation"`             // 保存时的声誉提示，启动时声誉系统尚未加载
+}
+
+// PeerAddrs 返回带 /p2p/ID 后缀的完整 multiaddr
+func (e *Entry) PeerAddrs() []string {
+	suffix := "/p2p/" + e.PeerID
+	addrs := make([]string, 0, len(e.Addrs))
+	for _, addr := range e.Addrs {
+		if !strings.HasSuffix(addr, suffix) {
+			addr += suffix
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Config 节点缓存配置
+type Config struct {
+	Path        string        `json:"path"`         // 缓存文件路径，空则只在内存中
+	MaxEntries  int           `json:"max_entries"`  // 最多缓存的节点数
+	MaxAge      time.Duration `json:"max_age"`      // 超过该时长未连通的节点不再重连并在保存时丢弃
+	MaxFailures int           `json:"max_failures"` // 连续失败达到该次数的节点不再重连
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		MaxEntries:  256,
+		MaxAge:      7 * 24 * time.Hour,
+		MaxFailures: 5,
+	}
+}
+
+// Cache 节点缓存
+type Cache struct {
+	mu      sync.RWMutex
+	config  *Config
+	entries map[string]*Entry
+	repFunc func(peerID string) (float64, bool)
+
+	now func() time.Time
+}
+
+// New 创建节点缓存并加载已保存的内容，文件不存在时为空缓存
+func New(config *Config) (*Cache, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	c := &Cache{
+		config:  config,
+		entries: make(map[string]*Entry),
+		now:     time.Now,
+	}
+	if config.Path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e != nil && e.PeerID != "" {
+			c.entries[e.PeerID] = e
+		}
+	}
+	return c, nil
+}
+
+// SetReputationFunc 设置声誉查询函数，保存时用于刷新声誉提示
+// 查询不到（ok 为 false）的节点保留原有提示。
+func (c *Cache) SetReputationFunc(fn func(peerID string) (rep float64, ok bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.repFunc = fn
+}
+
+// RecordSeen 记录连接成功的节点及其地址，清零连续失败次数
+func (c *Cache) RecordSeen(peerID string, addrs []string) {
+	if peerID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[peerID]
+	if !ok {
+		e = &Entry{PeerID: peerID}
+		c.entries[peerID] = e
+	}
+	e.LastSeen = c.now()
+	e.Failures = 0
+	for _, addr := range addrs {
+		e.Addrs = appendAddr(e.Addrs, addr)
+	}
+}
+
+// RecordFailure 记录拨号失败，只对已缓存的节点生效
+func (c *Cache) RecordFailure(peerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[peerID]; ok {
+		e.Failures++
+		e.LastFailure = c.now()
+	}
+}
+
+// Candidates 返回值得重连的节点，最近可用的排在前面
+// 排序：连续失败少的优先，其次最近连通的优先，再按声誉提示降序。limit <= 0 表示不限制。
+func (c *Cache) Candidates(limit int) []*Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	candidates := make([]*Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		if c.usableLocked(e, now) {
+			copied := *e
+			copied.Addrs = append([]string(nil), e.Addrs...)
+			candidates = append(candidates, &copied)
+		}
+	}
+	sortEntries(candidates)
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// Len 缓存的节点数
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Save 刷新声誉提示、丢弃过期和失败过多的节点后写入缓存文件
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	now := c.now()
+	kept := make([]*Entry, 0, len(c.entries))
+	for id, e := range c.entries {
+		if !c.usableLocked(e, now) {
+			delete(c.entries, id)
+			continue
+		}
+		if c.repFunc != nil {
+			if rep, ok := c.repFunc(id); ok {
+				e.Reputation = rep
+			}
+		}
+		kept = append(kept, e)
+	}
+	sortEntries(kept)
+	if c.config.MaxEntries > 0 && len(kept) > c.config.MaxEntries {
+		for _, e := range kept[c.config.MaxEntries:] {
+			delete(c.entries, e.PeerID)
+		}
+		kept = kept[:c.config.MaxEntries]
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if c.config.Path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.config.Path), 0700); err != nil {
+		return err
+	}
+	tmp := c.config.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.config.Path)
+}
+
+func (c *Cache) usableLocked(e *Entry, now time.Time) bool {
+	if len(e.Addrs) == 0 {
+		return false
+	}
+	if c.config.MaxFailures > 0 && e.Failures >= c.config.MaxFailures {
+		return false
+	}
+	return c.config.MaxAge <= 0 || now.Sub(e.LastSeen) <= c.config.MaxAge
+}
+
+func sortEntries(entries []*Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Failures != b.Failures {
+			return a.Failures < b.Failures
+		}
+		if !a.LastSeen.Equal(b.LastSeen) {
+			return a.LastSeen.After(b.LastSeen)
+		}
+		if a.Reputation != b.Reputation {
+			return a.Reputation > b.Reputation
+		}
+		return a.PeerID < b.PeerID
+	})
+}
+
+// appendAddr 把地址移到列表末尾（最近观察到的），超出上限时丢弃最旧的
+func appendAddr(addrs []string, addr string) []string {
+	if addr == "" {
+		return addrs
+	}
+	for i, existing := range addrs {
+		if existing == addr {
+			addrs = append(addrs[:i], addrs[i+1:]...)
+			break
+		}
+	}
+	addrs = append(addrs, addr)
+	if len(addrs) > maxAddrsPerPeer {
+		addrs = addrs[len(addrs)-maxAddrsPerPeer:]
+	}
+	return addrs
+}
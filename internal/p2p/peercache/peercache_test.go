@@ -0,0 +1,121 @@
+package peercache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(t *testing.T, cfg *Config) (*Cache, *fakeClock) {
+	t.Helper()
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	c.now = clock.now
+	return c, clock
+}
+
+func candidateIDs(entries []*Entry) []string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.PeerID
+	}
+	return ids
+}
+
+func TestCandidatesOrder(t *testing.T) {
+	c, clock := newTestCache(t, DefaultConfig())
+
+	c.RecordSeen("old", []string{"/ip4/10.0.0.1/tcp/4001"})
+	clock.advance(time.Hour)
+	c.RecordSeen("recent", []string{"/ip4/10.0.0.2/tcp/4001"})
+	c.RecordSeen("flaky", []string{"/ip4/10.0.0.3/tcp/4001"})
+	c.RecordFailure("flaky")
+	c.RecordFailure("unknown") // 未缓存的节点不记录
+
+	got := candidateIDs(c.Candidates(0))
+	want := []string{"recent", "old", "flaky"}
+	if len(got) != len(want) {
+		t.Fatalf("期望 %v, 得到 %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("期望 %v, 得到 %v", want, got)
+		}
+	}
+	if top := c.Candidates(1); len(top) != 1 || top[0].PeerID != "recent" {
+		t.Errorf("limit 未生效: %v", candidateIDs(top))
+	}
+
+	// 重新连通后清零失败次数
+	c.RecordSeen("flaky", nil)
+	if top := c.Candidates(1); top[0].PeerID != "flaky" {
+		t.Errorf("重新连通的节点应排在最前, 得到 %v", candidateIDs(c.Candidates(0)))
+	}
+
+	addrs := c.Candidates(1)[0].PeerAddrs()
+	if len(addrs) != 1 || addrs[0] != "/ip4/10.0.0.3/tcp/4001/p2p/flaky" {
+		t.Errorf("地址应带 /p2p/ 后缀: %v", addrs)
+	}
+}
+
+func TestCandidatesExcludeStaleAndFailing(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxAge = 24 * time.Hour
+	cfg.MaxFailures = 2
+	c, clock := newTestCache(t, cfg)
+
+	c.RecordSeen("stale", []string{"/ip4/10.0.0.1/tcp/4001"})
+	clock.advance(25 * time.Hour)
+	c.RecordSeen("dead", []string{"/ip4/10.0.0.2/tcp/4001"})
+	c.RecordFailure("dead")
+	c.RecordFailure("dead")
+	c.RecordSeen("noaddr", nil)
+	c.RecordSeen("good", []string{"/ip4/10.0.0.4/tcp/4001"})
+
+	if got := candidateIDs(c.Candidates(0)); len(got) != 1 || got[0] != "good" {
+		t.Errorf("只有最近可用且有地址的节点应被重连, 得到 %v", got)
+	}
+}
+
+func TestSaveAndReload(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Path = filepath.Join(t.TempDir(), "peer_cache.json")
+	cfg.MaxEntries = 2
+	c, clock := newTestCache(t, cfg)
+	c.SetReputationFunc(func(peerID string) (float64, bool) {
+		rep, ok := map[string]float64{"a": 80, "b": 20}[peerID]
+		return rep, ok
+	})
+
+	for _, id := range []string{"a", "b", "c"} {
+		c.RecordSeen(id, []string{"/ip4/10.0.0.1/tcp/4001", "/ip4/10.0.0.1/udp/4001/quic-v1"})
+	}
+	clock.advance(time.Minute)
+	c.RecordSeen("a", nil)
+	c.RecordSeen("b", nil)
+
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if c.Len() != 2 {
+		t.Errorf("超出上限的节点应被丢弃, 剩余 %d", c.Len())
+	}
+
+	reloaded, err := New(cfg)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	reloaded.now = clock.now
+	got := reloaded.Candidates(0)
+	if len(got) != 2 || got[0].PeerID != "a" || got[0].Reputation != 80 || len(got[0].Addrs) != 2 {
+		t.Errorf("重载后的缓存不符: %+v", got)
+	}
+}
@@ -0,0 +1,438 @@
+// Package pex 节点交换协议（Peer Exchange）
+// 已连接的节点定期通过 ProtocolID 流交换各自签名的邻居列表，收到的节点按时延和声誉
+// 评分后作为候选邻居；连接数不足时主动拨号评分最高的候选，引导节点全部不可用时
+// 网络仍能继续发现新节点。协议本身只依赖 io.ReadWriter，流的建立由调用方负责。
+package pex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProtocolID 节点交换协议ID（使用时按网络命名空间加前缀）
+const ProtocolID = "/daan/pex/1.0.0"
+
+const (
+	pexVersion = 1
+	// maxMessageSize 邻居列表消息的最大长度
+	maxMessageSize = 64 * 1024
+	// maxAddrsPerPeer 每个节点接受的地址数上限
+	maxAddrsPerPeer = 8
+)
+
+var (
+	ErrInvalidMessage   = errors.New("invalid pex message")
+	ErrInvalidSignature = errors.New("invalid pex message signature")
+	ErrStaleMessage     = errors.New("pex message timestamp out of range")
+)
+
+// PeerRecord 邻居列表中的一个节点
+type PeerRecord struct {
+	ID        string   `json:"id"`
+	Addrs     []string `json:"addrs"`
+	LatencyMs int64    `json:"latency_ms,omitempty"` // 通告方测得的往返时延，0 为未知
+}
+
+// Message 签名的邻居列表
+type Message struct {
+	Version   int          `json:"version"`
+	From      string       `json:"from"`
+	Peers     []PeerRecord `json:"peers"`
+	Timestamp int64        `json:"timestamp"`
+	Signature string       `json:"signature"`
+}
+
+// signingBytes 签名内容：除签名外的全部字段
+func (m *Message) signingBytes() []byte {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, _ := json.Marshal(&unsigned)
+	return data
+}
+
+// SignFunc 用本节点私钥签名
+type SignFunc func(data []byte) (string, error)
+
+// VerifyFunc 按签名者节点ID验签
+type VerifyFunc func(signer string, data []byte, signature string) bool
+
+// Config 节点交换配置
+type Config struct {
+	Interval        time.Duration `json:"interval"`          // 交换间隔
+	FanOut          int           `json:"fan_out"`           // 每轮交换的已连接节点数
+	MaxPeers        int           `json:"max_peers"`         // 单条消息最多携带的节点数
+	MaxAge          time.Duration `json:"max_age"`           // 消息时间戳与本地时间允许的偏差
+	MaxCandidates   int           `json:"max_candidates"`    // 最多保留的候选节点，超出时淘汰评分最低的
+	CandidateTTL    time.Duration `json:"candidate_ttl"`     // 候选超过该时长未被再次通告则丢弃
+	MaxDialFailures int           `json:"max_dial_failures"` // 连续拨号失败达到该次数的候选被丢弃
+	MinPeers        int           `json:"min_peers"`         // 已连接节点数低于该值时主动拨号候选
+	DialPerRound    int           `json:"dial_per_round"`    // 每轮最多拨号的候选数
+	ExchangeTimeout time.Duration `json:"exchange_timeout"`  // 单次交换的时限
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Interval:        2 * time.Minute,
+		FanOut:          4,
+		MaxPeers:        32,
+		MaxAge:          5 * time.Minute,
+		MaxCandidates:   256,
+		CandidateTTL:    time.Hour,
+		MaxDialFailures: 3,
+		MinPeers:        4,
+		DialPerRound:    2,
+		ExchangeTimeout: 10 * time.Second,
+	}
+}
+
+// Candidate 通过节点交换发现的候选节点
+type Candidate struct {
+	ID         string        `json:"id"`
+	Addrs      []string      `json:"addrs"`
+	Latency    time.Duration `json:"latency"`    // 各通告方测得的最小时延，0 为未知
+	Reputation float64       `json:"reputation"` // 本节点声誉系统中的声誉
+	Score      float64       `json:"score"`
+	Sources    int           `json:"sources"` // 通告该节点的邻居数
+	FirstSeen  time.Time     `json:"first_seen"`
+	LastSeen   time.Time     `json:"last_seen"`
+
+	via          map[string]bool
+	dialFailures int
+}
+
+// Score 候选评分，取值 [0, 1]：声誉按对数归一化（1000 为满分），时延 200ms 时得半分，
+// 时延未知按半分计，两者各占一半
+func Score(reputation float64, latency time.Duration) float64 {
+	rep := 0.0
+	if reputation > 0 {
+		rep = math.Min(1, math.Log10(1+reputation)/3)
+	}
+	lat := 0.5
+	if latency > 0 {
+		lat = 1 / (1 + float64(latency)/float64(200*time.Millisecond))
+	}
+	return (rep + lat) / 2
+}
+
+// Transport 节点交换使用的网络操作，由调用方基于 libp2p 主机实现
+type Transport interface {
+	// ConnectedPeers 当前已连接的节点
+	ConnectedPeers() []string
+	// OpenStream 打开到已连接节点的交换流
+	OpenStream(ctx context.Context, peerID string) (io.ReadWriteCloser, error)
+	// Connect 按候选节点的地址建立连接
+	Connect(ctx context.Context, c *Candidate) error
+}
+
+// Exchange 节点交换服务
+type Exchange struct {
+	self   string
+	config *Config
+	sign   SignFunc
+	verify VerifyFunc
+
+	mu           sync.RWMutex
+	candidates   map[string]*Candidate
+	localPeers   func() []PeerRecord
+	reputationOf func(nodeID string) float64
+	onCandidates func(from string, candidates []*Candidate)
+
+	now func() time.Time
+}
+
+// New 创建节点交换服务
+func New(self string, config *Config, sign SignFunc, verify VerifyFunc) *Exchange {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Exchange{
+		self:       self,
+		config:     config,
+		sign:       sign,
+		verify:     verify,
+		candidates: make(map[string]*Candidate),
+		now:        time.Now,
+	}
+}
+
+// SetLocalPeersFunc 设置本节点通告的邻居列表
+func (e *Exchange) SetLocalPeersFunc(fn func() []PeerRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.localPeers = fn
+}
+
+// SetReputationFunc 设置候选评分使用的声誉查询
+func (e *Exchange) SetReputationFunc(fn func(nodeID string) float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reputationOf = fn
+}
+
+// SetOnCandidates 设置收到邻居列表后的回调，参数为本次通告中更新的候选
+func (e *Exchange) SetOnCandidates(fn func(from string, candidates []*Candidate)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onCandidates = fn
+}
+
+// Exchange 作为发起方交换邻居列表：先发送本节点的列表，再读取对方的列表
+func (e *Exchange) Exchange(rw io.ReadWriter, peerID string) ([]*Candidate, error) {
+	msg, err := e.message()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(rw).Encode(msg); err != nil {
+		return nil, err
+	}
+	remote, err := e.read(rw, peerID)
+	if err != nil {
+		return nil, err
+	}
+	return e.accept(remote), nil
+}
+
+// HandleStream 作为响应方处理交换：读取对方的列表，校验通过后回复本节点的列表
+func (e *Exchange) HandleStream(rw io.ReadWriter, peerID string) error {
+	remote, err := e.read(rw, peerID)
+	if err != nil {
+		return err
+	}
+	msg, err := e.message()
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(rw).Encode(msg); err != nil {
+		return err
+	}
+	e.accept(remote)
+	return nil
+}
+
+// message 签名的本节点邻居列表
+func (e *Exchange) message() (*Message, error) {
+	e.mu.RLock()
+	localPeers := e.localPeers
+	e.mu.RUnlock()
+
+	msg := &Message{Version: pexVersion, From: e.self, Peers: []PeerRecord{}, Timestamp: e.now().Unix()}
+	if localPeers != nil {
+		for _, p := range localPeers() {
+			if len(msg.Peers) == e.config.MaxPeers {
+				break
+			}
+			if p.ID == "" || p.ID == e.self || len(p.Addrs) == 0 {
+				continue
+			}
+			msg.Peers = append(msg.Peers, p)
+		}
+	}
+	sig, err := e.sign(msg.signingBytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign pex message: %w", err)
+	}
+	msg.Signature = sig
+	return msg, nil
+}
+
+// read 读取并校验对方的邻居列表：发送方须是流的对端，签名有效，时间戳在允许范围内
+func (e *Exchange) read(r io.Reader, peerID string) (*Message, error) {
+	msg := new(Message)
+	if err := json.NewDecoder(io.LimitReader(r, maxMessageSize)).Decode(msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if msg.Version < 1 || msg.From != peerID {
+		return nil, ErrInvalidMessage
+	}
+	skew := e.now().Sub(time.Unix(msg.Timestamp, 0))
+	if skew > e.config.MaxAge || skew < -e.config.MaxAge {
+		return nil, ErrStaleMessage
+	}
+	if !e.verify(msg.From, msg.signingBytes(), msg.Signature) {
+		return nil, ErrInvalidSignature
+	}
+	return msg, nil
+}
+
+// accept 把对方列表中的节点合并到候选表，返回本次更新的候选
+func (e *Exchange) accept(msg *Message) []*Candidate {
+	e.mu.Lock()
+	now := e.now()
+	var updated []*Candidate
+	// 对方的列表上限可能与本节点不同，只取前 MaxPeers 个
+	peers := msg.Peers
+	if len(peers) > e.config.MaxPeers {
+		peers = peers[:e.config.MaxPeers]
+	}
+	for _, p := range peers {
+		if p.ID == "" || p.ID == e.self || p.ID == msg.From || len(p.Addrs) == 0 {
+			continue
+		}
+		addrs := p.Addrs
+		if len(addrs) > maxAddrsPerPeer {
+			addrs = addrs[:maxAddrsPerPeer]
+		}
+		latency := time.Duration(p.LatencyMs) * time.Millisecond
+		c, ok := e.candidates[p.ID]
+		if !ok {
+			c = &Candidate{ID: p.ID, FirstSeen: now, via: make(map[string]bool)}
+			e.candidates[p.ID] = c
+		}
+		c.Addrs = append([]string(nil), addrs...)
+		if latency > 0 && (c.Latency == 0 || latency < c.Latency) {
+			c.Latency = latency
+		}
+		c.via[msg.From] = true
+		c.Sources = len(c.via)
+		c.LastSeen = now
+		if e.reputationOf != nil {
+			c.Reputation = e.reputationOf(p.ID)
+		}
+		c.Score = Score(c.Reputation, c.Latency)
+		copied := *c
+		updated = append(updated, &copied)
+	}
+	e.pruneLocked(now)
+	onCandidates := e.onCandidates
+	e.mu.Unlock()
+
+	if onCandidates != nil && len(updated) > 0 {
+		onCandidates(msg.From, updated)
+	}
+	return updated
+}
+
+// pruneLocked 丢弃过期的候选，数量超出上限时淘汰评分最低的
+func (e *Exchange) pruneLocked(now time.Time) {
+	for id, c := range e.candidates {
+		if now.Sub(c.LastSeen) > e.config.CandidateTTL {
+			delete(e.candidates, id)
+		}
+	}
+	if e.config.MaxCandidates <= 0 || len(e.candidates) <= e.config.MaxCandidates {
+		return
+	}
+	ranked := e.rankedLocked()
+	for _, c := range ranked[e.config.MaxCandidates:] {
+		delete(e.candidates, c.ID)
+	}
+}
+
+// rankedLocked 候选按评分从高到低排序，评分相同时通告方多的在前
+func (e *Exchange) rankedLocked() []*Candidate {
+	ranked := make([]*Candidate, 0, len(e.candidates))
+	for _, c := range e.candidates {
+		ranked = append(ranked, c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if ranked[i].Sources != ranked[j].Sources {
+			return ranked[i].Sources > ranked[j].Sources
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	return ranked
+}
+
+// Candidates 评分最高的 limit 个候选（limit <= 0 不限），跳过 exclude 返回 true 的节点
+func (e *Exchange) Candidates(limit int, exclude func(nodeID string) bool) []*Candidate {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result := make([]*Candidate, 0)
+	for _, c := range e.rankedLocked() {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		if exclude != nil && exclude(c.ID) {
+			continue
+		}
+		copied := *c
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// RecordDialResult 记录拨号结果：成功时清零失败次数，连续失败达到上限时丢弃候选
+func (e *Exchange) RecordDialResult(nodeID string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.candidates[nodeID]
+	if !ok {
+		return
+	}
+	if err == nil {
+		c.dialFailures = 0
+		return
+	}
+	c.dialFailures++
+	if c.dialFailures >= e.config.MaxDialFailures {
+		delete(e.candidates, nodeID)
+	}
+}
+
+// Run 每隔 Interval 执行一轮交换，直到 ctx 取消
+func (e *Exchange) Run(ctx context.Context, t Transport) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		e.Round(ctx, t)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Round 执行一轮：与随机选出的 FanOut 个已连接节点交换邻居列表，
+// 已连接节点数低于 MinPeers 时拨号评分最高的未连接候选
+func (e *Exchange) Round(ctx context.Context, t Transport) {
+	peers := t.ConnectedPeers()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	for i, peerID := range peers {
+		if i == e.config.FanOut {
+			break
+		}
+		if err := e.exchangeWith(ctx, t, peerID); err != nil && ctx.Err() == nil {
+			fmt.Printf("⚠️  与 %s 交换邻居列表失败: %v\n", peerID, err)
+		}
+	}
+
+	if len(peers) >= e.config.MinPeers {
+		return
+	}
+	connected := make(map[string]bool, len(peers))
+	for _, peerID := range peers {
+		connected[peerID] = true
+	}
+	for _, c := range e.Candidates(e.config.DialPerRound, func(nodeID string) bool { return connected[nodeID] }) {
+		dialCtx, cancel := context.WithTimeout(ctx, e.config.ExchangeTimeout)
+		err := t.Connect(dialCtx, c)
+		cancel()
+		e.RecordDialResult(c.ID, err)
+	}
+}
+
+func (e *Exchange) exchangeWith(ctx context.Context, t Transport, peerID string) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.ExchangeTimeout)
+	defer cancel()
+	s, err := t.OpenStream(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	_, err = e.Exchange(s, peerID)
+	return err
+}
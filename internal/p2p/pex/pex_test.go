@@ -0,0 +1,220 @@
+package pex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// testSign 测试用签名：签名者ID与内容的摘要
+func testSign(self string) SignFunc {
+	return func(data []byte) (string, error) {
+		sum := sha256.Sum256(append([]byte(self), data...))
+		return hex.EncodeToString(sum[:]), nil
+	}
+}
+
+func testVerify(signer string, data []byte, signature string) bool {
+	sig, _ := testSign(signer)(data)
+	return sig == signature
+}
+
+func newTestExchange(self string, peers ...PeerRecord) *Exchange {
+	e := New(self, DefaultConfig(), testSign(self), testVerify)
+	e.SetLocalPeersFunc(func() []PeerRecord { return peers })
+	return e
+}
+
+// exchange 通过内存管道执行一次交换，返回发起方的结果和响应方的错误
+func exchange(t *testing.T, initiator, responder *Exchange) ([]*Candidate, error, error) {
+	t.Helper()
+	a, b := net.Pipe()
+	defer a.Close()
+	done := make(chan error, 1)
+	go func() {
+		defer b.Close()
+		done <- responder.HandleStream(b, initiator.self)
+	}()
+	candidates, err := initiator.Exchange(a, responder.self)
+	a.Close()
+	return candidates, err, <-done
+}
+
+func TestExchange(t *testing.T) {
+	alice := newTestExchange("alice",
+		PeerRecord{ID: "carol", Addrs: []string{"/ip4/10.0.0.3/tcp/4001"}, LatencyMs: 40},
+		PeerRecord{ID: "bob", Addrs: []string{"/ip4/10.0.0.2/tcp/4001"}}, // 对方自己，不作为候选
+	)
+	bob := newTestExchange("bob",
+		PeerRecord{ID: "dave", Addrs: []string{"/ip4/10.0.0.4/tcp/4001"}, LatencyMs: 900},
+		PeerRecord{ID: "alice", Addrs: []string{"/ip4/10.0.0.1/tcp/4001"}}, // 接收方自己
+		PeerRecord{ID: "erin"}, // 没有地址
+	)
+	alice.SetReputationFunc(func(nodeID string) float64 { return 100 })
+	var notified []string
+	alice.SetOnCandidates(func(from string, candidates []*Candidate) {
+		for _, c := range candidates {
+			notified = append(notified, from+">"+c.ID)
+		}
+	})
+
+	learned, err, respErr := exchange(t, alice, bob)
+	if err != nil || respErr != nil {
+		t.Fatalf("exchange failed: %v / %v", err, respErr)
+	}
+	if len(learned) != 1 || learned[0].ID != "dave" || learned[0].Latency != 900*time.Millisecond || learned[0].Reputation != 100 {
+		t.Fatalf("alice learned %+v", learned)
+	}
+	if len(notified) != 1 || notified[0] != "bob>dave" {
+		t.Errorf("notified = %v", notified)
+	}
+	if got := bob.Candidates(0, nil); len(got) != 1 || got[0].ID != "carol" || got[0].Sources != 1 {
+		t.Errorf("bob candidates = %+v", got)
+	}
+}
+
+func TestExchangeRejectsInvalidMessages(t *testing.T) {
+	bob := newTestExchange("bob")
+
+	// 签名者不是流的对端
+	mallory := newTestExchange("mallory", PeerRecord{ID: "x", Addrs: []string{"/ip4/10.0.0.9/tcp/1"}})
+	mallory.self = "alice"
+	mallory.sign = testSign("mallory")
+	if _, _, err := exchange(t, mallory, bob); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged signature: got %v, want ErrInvalidSignature", err)
+	}
+
+	// 时间戳超出允许范围
+	stale := newTestExchange("alice")
+	stale.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	if _, _, err := exchange(t, stale, bob); !errors.Is(err, ErrStaleMessage) {
+		t.Errorf("stale message: got %v, want ErrStaleMessage", err)
+	}
+
+	// 不是 JSON
+	a, b := net.Pipe()
+	go func() {
+		a.Write([]byte("not json\n"))
+		a.Close()
+	}()
+	if err := bob.HandleStream(b, "alice"); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("garbage: got %v, want ErrInvalidMessage", err)
+	}
+	if got := bob.Candidates(0, nil); len(got) != 0 {
+		t.Errorf("invalid messages added candidates: %+v", got)
+	}
+}
+
+func TestCandidatesRanking(t *testing.T) {
+	if Score(1000, 10*time.Millisecond) <= Score(10, 10*time.Millisecond) {
+		t.Error("higher reputation should score higher")
+	}
+	if Score(10, 50*time.Millisecond) <= Score(10, time.Second) {
+		t.Error("lower latency should score higher")
+	}
+	if s := Score(0, 0); s != 0.25 {
+		t.Errorf("Score(0, 0) = %v, want 0.25", s)
+	}
+
+	config := DefaultConfig()
+	config.MaxCandidates = 2
+	config.MaxDialFailures = 2
+	e := New("self", config, testSign("self"), testVerify)
+	rep := map[string]float64{"fast": 50, "slow": 50, "trusted": 900}
+	e.SetReputationFunc(func(nodeID string) float64 { return rep[nodeID] })
+	msg := &Message{From: "peer", Peers: []PeerRecord{
+		{ID: "slow", Addrs: []string{"/ip4/10.0.0.1/tcp/1"}, LatencyMs: 2000},
+		{ID: "fast", Addrs: []string{"/ip4/10.0.0.2/tcp/1"}, LatencyMs: 20},
+		{ID: "trusted", Addrs: []string{"/ip4/10.0.0.3/tcp/1"}, LatencyMs: 20},
+	}}
+	e.accept(msg)
+
+	got := e.Candidates(0, nil)
+	if len(got) != 2 || got[0].ID != "trusted" || got[1].ID != "fast" {
+		t.Fatalf("candidates = %+v, want trusted, fast", got)
+	}
+	if got := e.Candidates(1, func(id string) bool { return id == "trusted" }); len(got) != 1 || got[0].ID != "fast" {
+		t.Errorf("excluded candidates = %+v", got)
+	}
+
+	e.RecordDialResult("fast", errors.New("timeout"))
+	e.RecordDialResult("fast", errors.New("timeout"))
+	if got := e.Candidates(0, nil); len(got) != 1 {
+		t.Errorf("candidate not dropped after repeated dial failures: %+v", got)
+	}
+
+	e.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	e.accept(&Message{From: "peer"})
+	if got := e.Candidates(0, nil); len(got) != 0 {
+		t.Errorf("expired candidates kept: %+v", got)
+	}
+}
+
+// fakeTransport 内存中的传输：OpenStream 直接与对应的 Exchange 交换
+type fakeTransport struct {
+	connected []string
+	peers     map[string]*Exchange
+	self      string
+	dialed    []string
+}
+
+func (f *fakeTransport) ConnectedPeers() []string {
+	return append([]string(nil), f.connected...)
+}
+
+func (f *fakeTransport) OpenStream(ctx context.Context, peerID string) (io.ReadWriteCloser, error) {
+	a, b := net.Pipe()
+	go func() {
+		defer b.Close()
+		f.peers[peerID].HandleStream(b, f.self)
+	}()
+	return a, nil
+}
+
+func (f *fakeTransport) Connect(ctx context.Context, c *Candidate) error {
+	f.dialed = append(f.dialed, c.ID)
+	if c.ID == "unreachable" {
+		return errors.New("dial failed")
+	}
+	f.connected = append(f.connected, c.ID)
+	return nil
+}
+
+func TestRoundDialsCandidatesWhenBelowMinPeers(t *testing.T) {
+	// 引导节点下线后只剩一个连接，通过它学到的节点补足连接
+	relay := newTestExchange("relay",
+		PeerRecord{ID: "near", Addrs: []string{"/ip4/10.0.0.2/tcp/1"}, LatencyMs: 10},
+		PeerRecord{ID: "unreachable", Addrs: []string{"/ip4/10.0.0.3/tcp/1"}, LatencyMs: 5},
+		PeerRecord{ID: "far", Addrs: []string{"/ip4/10.0.0.4/tcp/1"}, LatencyMs: 3000},
+	)
+	config := DefaultConfig()
+	config.MinPeers = 3
+	config.DialPerRound = 2
+	config.MaxDialFailures = 1
+	self := New("self", config, testSign("self"), testVerify)
+	transport := &fakeTransport{connected: []string{"relay"}, peers: map[string]*Exchange{"relay": relay}, self: "self"}
+
+	self.Round(context.Background(), transport)
+	if len(transport.dialed) != 2 || transport.dialed[0] != "unreachable" || transport.dialed[1] != "near" {
+		t.Fatalf("dialed = %v, want unreachable, near", transport.dialed)
+	}
+	for _, c := range self.Candidates(0, nil) {
+		if c.ID == "unreachable" {
+			t.Error("unreachable candidate kept after dial failure")
+		}
+	}
+
+	// 连接数达到 MinPeers 后不再拨号
+	transport.dialed = nil
+	transport.connected = append(transport.connected, "other")
+	transport.peers["near"], transport.peers["other"] = newTestExchange("near"), newTestExchange("other")
+	self.Round(context.Background(), transport)
+	if len(transport.dialed) != 0 {
+		t.Errorf("dialed %v with enough peers", transport.dialed)
+	}
+}
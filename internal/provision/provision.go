@@ -0,0 +1,417 @@
+// Package provision 实现声明式节点配置
+// 一份清单描述节点的角色、端口、引导节点、特性开关、配额、Webhook 和定时任务。
+// apply 时与上次应用的清单比较，只应用有变化的部分，重复应用同一清单不产生变更。
+package provision
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+)
+
+// 清单版本
+const (
+	APIVersion = "agentnetwork/v1"
+	Kind       = "Node"
+)
+
+// AppliedFile 数据目录下记录已应用清单的文件
+const AppliedFile = "provision/applied.json"
+
+// 支持的定时任务类型
+const (
+	JobBackup = "backup"
+)
+
+var (
+	ErrInvalidManifest = errors.New("invalid manifest")
+)
+
+var (
+	envNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+	jobNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+	// backup 任务可设置的参数（对应 run/start 的 backup- 参数）
+	backupJobParams = map[string]bool{
+		"endpoint": true, "region": true, "bucket": true, "prefix": true,
+		"path-style": true, "keep": true, "max-age": true,
+	}
+)
+
+// Manifest 节点清单
+type Manifest struct {
+	APIVersion string          `yaml:"apiVersion" json:"api_version"`
+	Kind       string          `yaml:"kind" json:"kind"`
+	Name       string          `yaml:"name,omitempty" json:"name,omitempty"`
+	Role       string          `yaml:"role,omitempty" json:"role,omitempty"`
+	Namespace  string          `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Ports      Ports           `yaml:"ports,omitempty" json:"ports"`
+	Bootstrap  []string        `yaml:"bootstrap,omitempty" json:"bootstrap,omitempty"`
+	Features   map[string]bool `yaml:"features,omitempty" json:"features,omitempty"`
+	Quotas     Quotas          `yaml:"quotas,omitempty" json:"quotas"`
+	Webhooks   Webhooks        `yaml:"webhooks,omitempty" json:"webhooks"`
+	Jobs       []Job           `yaml:"jobs,omitempty" json:"jobs,omitempty"`
+}
+
+// Ports 监听端口，0 表示使用默认值
+type Ports struct {
+	P2P   int `yaml:"p2p,omitempty" json:"p2p,omitempty"`
+	HTTP  int `yaml:"http,omitempty" json:"http,omitempty"`
+	Admin int `yaml:"admin,omitempty" json:"admin,omitempty"`
+	GRPC  int `yaml:"grpc,omitempty" json:"grpc,omitempty"`
+}
+
+// Quotas 资源配额
+type Quotas struct {
+	OutboundRate *int64 `yaml:"outbound_rate,omitempty" json:"outbound_rate,omitempty"` // 出站带宽（字节/秒），0 表示不限速
+	PublicInbox  *bool  `yaml:"public_inbox,omitempty" json:"public_inbox,omitempty"`   // 陌生发件人进入公开收件箱
+}
+
+// Webhooks 声誉事件 Webhook
+// 密钥只能通过环境变量名引用，不写入清单。
+type Webhooks struct {
+	CallbackSecretEnv string          `yaml:"callback_secret_env,omitempty" json:"callback_secret_env,omitempty"` // 入站回调的共享密钥
+	Targets           []WebhookTarget `yaml:"targets,omitempty" json:"targets,omitempty"`
+}
+
+// WebhookTarget 事件推送目标
+type WebhookTarget struct {
+	URL       string `yaml:"url" json:"url"`
+	SecretEnv string `yaml:"secret_env,omitempty" json:"secret_env,omitempty"` // 可选，为空时只用节点的 Webhook 签名密钥签名
+}
+
+// Job 定时任务
+type Job struct {
+	Name     string            `yaml:"name" json:"name"`
+	Type     string            `yaml:"type" json:"type"`
+	Interval string            `yaml:"interval" json:"interval"` // 如 6h
+	Params   map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// Validate 校验清单
+func (m *Manifest) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if m.APIVersion != APIVersion {
+		add("apiVersion must be %q", APIVersion)
+	}
+	if m.Kind != Kind {
+		add("kind must be %q", Kind)
+	}
+	switch m.Role {
+	case "", "normal", "bootstrap", "relay":
+	default:
+		add("role must be one of normal, bootstrap, relay")
+	}
+	if err := namespace.Validate(m.Namespace); err != nil {
+		add("namespace: %v", err)
+	}
+
+	ports := map[int]string{}
+	for name, port := range map[string]int{"p2p": m.Ports.P2P, "http": m.Ports.HTTP, "admin": m.Ports.Admin, "grpc": m.Ports.GRPC} {
+		if port < 0 || port > 65535 {
+			add("ports.%s out of range", name)
+			continue
+		}
+		if port == 0 {
+			continue
+		}
+		if other, dup := ports[port]; dup {
+			add("ports.%s and ports.%s both use %d", name, other, port)
+		}
+		ports[port] = name
+	}
+
+	for _, addr := range m.Bootstrap {
+		if !strings.HasPrefix(addr, "/") || !strings.Contains(addr, "/p2p/") {
+			add("bootstrap address %q must be a multiaddr with /p2p/<peer id>", addr)
+		}
+	}
+	for name := range m.Features {
+		if name == "" {
+			add("feature name cannot be empty")
+		}
+	}
+	if m.Quotas.OutboundRate != nil && *m.Quotas.OutboundRate < 0 {
+		add("quotas.outbound_rate cannot be negative")
+	}
+
+	if env := m.Webhooks.CallbackSecretEnv; env != "" && !envNamePattern.MatchString(env) {
+		add("webhooks.callback_secret_env %q is not an environment variable name", env)
+	}
+	for i, t := range m.Webhooks.Targets {
+		if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("webhooks.targets[%d].url must be an http(s) URL", i)
+		}
+		if t.SecretEnv != "" && !envNamePattern.MatchString(t.SecretEnv) {
+			add("webhooks.targets[%d].secret_env must name an environment variable", i)
+		}
+	}
+
+	jobs := map[string]bool{}
+	for i, job := range m.Jobs {
+		if !jobNamePattern.MatchString(job.Name) {
+			add("jobs[%d].name %q is invalid", i, job.Name)
+		} else if jobs[job.Name] {
+			add("jobs[%d].name %q is duplicated", i, job.Name)
+		}
+		jobs[job.Name] = true
+		if d, err := time.ParseDuration(job.Interval); err != nil || d <= 0 {
+			add("jobs[%d].interval must be a positive duration", i)
+		}
+		switch job.Type {
+		case JobBackup:
+			if _, ok := job.Params["bucket"]; !ok {
+				add("jobs[%d] (backup) requires params.bucket", i)
+			}
+			for key := range job.Params {
+				if !backupJobParams[key] {
+					add("jobs[%d] (backup) has unknown param %q", i, key)
+				}
+			}
+		default:
+			add("jobs[%d].type must be %q", i, JobBackup)
+		}
+	}
+	if countJobs(m.Jobs, JobBackup) > 1 {
+		add("only one backup job is supported")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrInvalidManifest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func countJobs(jobs []Job, jobType string) int {
+	n := 0
+	for _, job := range jobs {
+		if job.Type == jobType {
+			n++
+		}
+	}
+	return n
+}
+
+// Action 变更类型
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionUpdate Action = "update"
+	ActionRemove Action = "remove"
+)
+
+// Change 一项配置变更
+type Change struct {
+	Path   string `json:"path"`
+	Action Action `json:"action"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Live   bool   `json:"live"` // 可在运行中的节点上直接生效，否则需重启
+}
+
+// String 返回便于阅读的变更描述
+func (c Change) String() string {
+	switch c.Action {
+	case ActionAdd:
+		return fmt.Sprintf("+ %s = %s", c.Path, c.To)
+	case ActionRemove:
+		return fmt.Sprintf("- %s (was %s)", c.Path, c.From)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.From, c.To)
+	}
+}
+
+// Diff 计算从 current（上次应用的清单，可为 nil）到 desired 的变更，按路径排序
+func Diff(current, desired *Manifest) []Change {
+	if current == nil {
+		current = &Manifest{}
+	}
+	if desired == nil {
+		desired = &Manifest{}
+	}
+
+	var changes []Change
+	compare := func(path, from, to string, live bool) {
+		switch {
+		case from == to:
+		case from == "":
+			changes = append(changes, Change{Path: path, Action: ActionAdd, To: to, Live: live})
+		case to == "":
+			changes = append(changes, Change{Path: path, Action: ActionRemove, From: from, Live: live})
+		default:
+			changes = append(changes, Change{Path: path, Action: ActionUpdate, From: from, To: to, Live: live})
+		}
+	}
+
+	compare("role", current.Role, desired.Role, false)
+	compare("namespace", current.Namespace, desired.Namespace, false)
+	compare("ports.p2p", portString(current.Ports.P2P), portString(desired.Ports.P2P), false)
+	compare("ports.http", portString(current.Ports.HTTP), portString(desired.Ports.HTTP), false)
+	compare("ports.admin", portString(current.Ports.Admin), portString(desired.Ports.Admin), false)
+	compare("ports.grpc", portString(current.Ports.GRPC), portString(desired.Ports.GRPC), false)
+	compare("bootstrap", strings.Join(sortedCopy(current.Bootstrap), ","), strings.Join(sortedCopy(desired.Bootstrap), ","), false)
+
+	currentFeatures, desiredFeatures := featureStrings(current.Features), featureStrings(desired.Features)
+	for _, name := range unionKeys(currentFeatures, desiredFeatures) {
+		compare("features."+name, currentFeatures[name], desiredFeatures[name], true)
+	}
+
+	compare("quotas.outbound_rate", int64PtrString(current.Quotas.OutboundRate), int64PtrString(desired.Quotas.OutboundRate), false)
+	compare("quotas.public_inbox", boolPtrString(current.Quotas.PublicInbox), boolPtrString(desired.Quotas.PublicInbox), false)
+
+	compare("webhooks.callback_secret_env", current.Webhooks.CallbackSecretEnv, desired.Webhooks.CallbackSecretEnv, false)
+	currentTargets, desiredTargets := map[string]string{}, map[string]string{}
+	for _, t := range current.Webhooks.Targets {
+		currentTargets[t.URL] = t.SecretEnv
+	}
+	for _, t := range desired.Webhooks.Targets {
+		desiredTargets[t.URL] = t.SecretEnv
+	}
+	for _, u := range unionKeys(currentTargets, desiredTargets) {
+		compare("webhooks.targets["+u+"]", targetString(currentTargets, u), targetString(desiredTargets, u), false)
+	}
+
+	currentJobs, desiredJobs := map[string]string{}, map[string]string{}
+	for _, job := range current.Jobs {
+		currentJobs[job.Name] = jobString(job)
+	}
+	for _, job := range desired.Jobs {
+		desiredJobs[job.Name] = jobString(job)
+	}
+	for _, name := range unionKeys(currentJobs, desiredJobs) {
+		compare("jobs."+name, currentJobs[name], desiredJobs[name], false)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// NeedsRestart 变更中是否有需要重启节点才能生效的项
+func NeedsRestart(changes []Change) bool {
+	for _, c := range changes {
+		if !c.Live {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadApplied 读取数据目录下上次应用的清单，从未应用过时返回 nil
+func LoadApplied(dataDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, AppliedFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse applied manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// SaveApplied 记录已应用的清单
+func SaveApplied(dataDir string, m *Manifest) error {
+	path := filepath.Join(dataDir, AppliedFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func portString(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", port)
+}
+
+func featureStrings(features map[string]bool) map[string]string {
+	result := make(map[string]string, len(features))
+	for name, enabled := range features {
+		result[name] = fmt.Sprintf("%t", enabled)
+	}
+	return result
+}
+
+func boolPtrString(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%t", *v)
+}
+
+func int64PtrString(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+func targetString(targets map[string]string, u string) string {
+	env, ok := targets[u]
+	if !ok {
+		return ""
+	}
+	return "secret from $" + env
+}
+
+func jobString(job Job) string {
+	keys := make([]string, 0, len(job.Params))
+	for k := range job.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{job.Type, "every " + job.Interval}
+	for _, k := range keys {
+		parts = append(parts, k+"="+job.Params[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedCopy(values []string) []string {
+	result := append([]string(nil), values...)
+	sort.Strings(result)
+	return result
+}
+
+func unionKeys(a, b map[string]string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,114 @@
+package provision
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testManifest() *Manifest {
+	rate := int64(1 << 20)
+	return &Manifest{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Name:       "edge-1",
+		Role:       "relay",
+		Ports:      Ports{P2P: 4001, HTTP: 18345},
+		Bootstrap:  []string{"/ip4/10.0.0.1/tcp/4001/p2p/12D3KooWA"},
+		Features:   map[string]bool{"mailbox-v2": true},
+		Quotas:     Quotas{OutboundRate: &rate},
+		Webhooks: Webhooks{
+			Targets: []WebhookTarget{{URL: "https://hooks.example.com/daan", SecretEnv: "DAAN_HOOK_SECRET"}},
+		},
+		Jobs: []Job{{Name: "nightly", Type: JobBackup, Interval: "24h", Params: map[string]string{"bucket": "daan"}}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testManifest().Validate(); err != nil {
+		t.Fatalf("valid manifest rejected: %v", err)
+	}
+
+	tests := map[string]func(m *Manifest){
+		"apiVersion":      func(m *Manifest) { m.APIVersion = "v0" },
+		"role":            func(m *Manifest) { m.Role = "super" },
+		"namespace":       func(m *Manifest) { m.Namespace = "Bad NS" },
+		"both use":        func(m *Manifest) { m.Ports.Admin = 4001 },
+		"bootstrap":       func(m *Manifest) { m.Bootstrap = []string{"10.0.0.1:4001"} },
+		"secret_env":      func(m *Manifest) { m.Webhooks.Targets[0].SecretEnv = "hunter2" },
+		"url":             func(m *Manifest) { m.Webhooks.Targets[0].URL = "ftp://x" },
+		"interval":        func(m *Manifest) { m.Jobs[0].Interval = "daily" },
+		"requires params": func(m *Manifest) { m.Jobs[0].Params = nil },
+		"unknown param":   func(m *Manifest) { m.Jobs[0].Params["passphrase"] = "x" },
+		"type":            func(m *Manifest) { m.Jobs[0].Type = "shell" },
+	}
+	for want, mutate := range tests {
+		m := testManifest()
+		mutate(m)
+		err := m.Validate()
+		if !errors.Is(err, ErrInvalidManifest) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected validation error mentioning %q, got %v", want, want, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	desired := testManifest()
+
+	// 首次应用：全部为新增
+	changes := Diff(nil, desired)
+	if len(changes) == 0 || !NeedsRestart(changes) {
+		t.Fatalf("expected initial changes requiring restart, got %v", changes)
+	}
+	for _, c := range changes {
+		if c.Action != ActionAdd {
+			t.Errorf("expected add on first apply, got %s", c)
+		}
+	}
+
+	// 重复应用同一清单没有变更
+	if changes := Diff(testManifest(), desired); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	// 只改特性开关可在线生效
+	next := testManifest()
+	next.Features = map[string]bool{"mailbox-v2": false, "escrow-v2": true}
+	changes = Diff(desired, next)
+	if len(changes) != 2 || NeedsRestart(changes) {
+		t.Fatalf("expected 2 live feature changes, got %v", changes)
+	}
+	if changes[0].Path != "features.escrow-v2" || changes[0].Action != ActionAdd {
+		t.Errorf("unexpected change: %s", changes[0])
+	}
+	if changes[1].Action != ActionUpdate || changes[1].From != "true" || changes[1].To != "false" {
+		t.Errorf("unexpected change: %s", changes[1])
+	}
+
+	// 引导节点顺序不影响比较，删除任务为 remove
+	next = testManifest()
+	next.Bootstrap = append(next.Bootstrap, "/ip4/10.0.0.2/tcp/4001/p2p/12D3KooWB")
+	next.Jobs = nil
+	changes = Diff(desired, next)
+	if len(changes) != 2 || changes[0].Path != "bootstrap" || changes[1].Path != "jobs.nightly" || changes[1].Action != ActionRemove {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+}
+
+func TestAppliedState(t *testing.T) {
+	dir := t.TempDir()
+
+	if m, err := LoadApplied(dir); err != nil || m != nil {
+		t.Fatalf("expected nothing applied yet, got %v, %v", m, err)
+	}
+	if err := SaveApplied(dir, testManifest()); err != nil {
+		t.Fatalf("SaveApplied failed: %v", err)
+	}
+	loaded, err := LoadApplied(dir)
+	if err != nil {
+		t.Fatalf("LoadApplied failed: %v", err)
+	}
+	if changes := Diff(loaded, testManifest()); len(changes) != 0 {
+		t.Errorf("round trip changed manifest: %v", changes)
+	}
+}
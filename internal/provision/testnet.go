@@ -0,0 +1,273 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/namespace"
+)
+
+// 本地测试网
+//
+// netgen 为 N 个节点生成密钥、清单和编排文件：第一个节点是引导节点，第二个是中继节点，
+// 其余为普通节点，都以引导节点和中继节点为引导地址。清单写入各节点数据目录的
+// AppliedFile，节点启动时自动作为参数默认值，编排文件只需指定数据目录。
+
+// 编排格式
+const (
+	FormatCompose = "compose"
+	FormatSystemd = "systemd"
+)
+
+// 测试网规模上限（compose 子网中每个节点占一个地址，systemd 下每个节点占一组本机端口）
+const (
+	MinTestnetNodes = 2
+	MaxTestnetNodes = 64
+)
+
+var ErrInvalidTestnet = errors.New("invalid testnet config")
+
+// TestnetConfig 测试网配置
+type TestnetConfig struct {
+	Nodes     int
+	Format    string // compose 或 systemd
+	Namespace string // 网络命名空间，空为默认网络
+	Image     string // compose 使用的镜像
+	Binary    string // systemd 单元启动的可执行文件
+	Subnet    string // compose 网络子网（/24）
+	BasePorts Ports  // 宿主机端口基数，第 i 个节点使用基数 + i
+}
+
+// DefaultTestnetConfig 返回默认测试网配置
+func DefaultTestnetConfig() *TestnetConfig {
+	return &TestnetConfig{
+		Nodes:     5,
+		Format:    FormatCompose,
+		Image:     "agentnetwork:dev",
+		Binary:    "/usr/local/bin/agentnetwork",
+		Subnet:    "172.30.0.0/24",
+		BasePorts: Ports{P2P: 9000, HTTP: 18345, Admin: 18080, GRPC: 50051},
+	}
+}
+
+// containerPorts 容器内各节点使用相同的端口
+var containerPorts = Ports{P2P: 9000, HTTP: 18345, Admin: 18080, GRPC: 50051}
+
+// TestnetNode 测试网中的一个节点
+type TestnetNode struct {
+	Name      string   `json:"name"`
+	Role      string   `json:"role"`
+	PeerID    string   `json:"peer_id"`
+	IP        string   `json:"ip"`         // 节点间互连的地址
+	Ports     Ports    `json:"ports"`      // 节点监听端口
+	HostPorts Ports    `json:"host_ports"` // 宿主机上访问节点的端口
+	Bootstrap []string `json:"bootstrap,omitempty"`
+}
+
+// Addr 节点的 P2P 地址
+func (n *TestnetNode) Addr() string {
+	return fmt.Sprintf("/ip4/%s/tcp/%d/p2p/%s", n.IP, n.Ports.P2P, n.PeerID)
+}
+
+// Manifest 节点清单
+func (n *TestnetNode) Manifest(namespace string) *Manifest {
+	return &Manifest{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Name:       n.Name,
+		Role:       n.Role,
+		Namespace:  namespace,
+		Ports:      n.Ports,
+		Bootstrap:  n.Bootstrap,
+	}
+}
+
+// TestnetNodeName 第 i 个节点的名称
+func TestnetNodeName(i int) string {
+	switch i {
+	case 0:
+		return "bootstrap"
+	case 1:
+		return "relay"
+	default:
+		return fmt.Sprintf("node%d", i-1)
+	}
+}
+
+// Validate 校验测试网配置
+func (c *TestnetConfig) Validate() error {
+	switch {
+	case c.Nodes < MinTestnetNodes || c.Nodes > MaxTestnetNodes:
+		return fmt.Errorf("%w: nodes must be between %d and %d", ErrInvalidTestnet, MinTestnetNodes, MaxTestnetNodes)
+	case c.Format != FormatCompose && c.Format != FormatSystemd:
+		return fmt.Errorf("%w: format must be %s or %s", ErrInvalidTestnet, FormatCompose, FormatSystemd)
+	}
+	if err := namespace.Validate(c.Namespace); err != nil {
+		return fmt.Errorf("%w: namespace: %v", ErrInvalidTestnet, err)
+	}
+	if c.Format == FormatCompose {
+		if _, err := subnetBase(c.Subnet); err != nil {
+			return err
+		}
+	}
+	// 每类端口占 [基数, 基数+N)，各区间不能越界或重叠
+	bases := map[string]int{"p2p": c.BasePorts.P2P, "http": c.BasePorts.HTTP, "admin": c.BasePorts.Admin, "grpc": c.BasePorts.GRPC}
+	for name, base := range bases {
+		if base <= 0 || base+c.Nodes-1 > 65535 {
+			return fmt.Errorf("%w: %s base port out of range", ErrInvalidTestnet, name)
+		}
+		for other, otherBase := range bases {
+			if other != name && base <= otherBase && otherBase < base+c.Nodes {
+				return fmt.Errorf("%w: %s and %s port ranges overlap", ErrInvalidTestnet, name, other)
+			}
+		}
+	}
+	return nil
+}
+
+// PlanTestnet 按节点密钥规划测试网，peerIDs 依次对应各节点
+func PlanTestnet(c *TestnetConfig, peerIDs []string) ([]*TestnetNode, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if len(peerIDs) != c.Nodes {
+		return nil, fmt.Errorf("%w: %d peer IDs for %d nodes", ErrInvalidTestnet, len(peerIDs), c.Nodes)
+	}
+	var base net.IP
+	if c.Format == FormatCompose {
+		base, _ = subnetBase(c.Subnet)
+	}
+
+	nodes := make([]*TestnetNode, c.Nodes)
+	for i := range nodes {
+		n := &TestnetNode{
+			Name:   TestnetNodeName(i),
+			Role:   "normal",
+			PeerID: peerIDs[i],
+			HostPorts: Ports{
+				P2P:   c.BasePorts.P2P + i,
+				HTTP:  c.BasePorts.HTTP + i,
+				Admin: c.BasePorts.Admin + i,
+				GRPC:  c.BasePorts.GRPC + i,
+			},
+		}
+		switch i {
+		case 0:
+			n.Role = "bootstrap"
+		case 1:
+			n.Role = "relay"
+		}
+		if c.Format == FormatCompose {
+			n.IP = net.IPv4(base[0], base[1], base[2], byte(10+i)).String()
+			n.Ports = containerPorts
+		} else {
+			n.IP = "127.0.0.1"
+			n.Ports = n.HostPorts
+		}
+		nodes[i] = n
+	}
+	for i, n := range nodes {
+		switch i {
+		case 0:
+		case 1:
+			n.Bootstrap = []string{nodes[0].Addr()}
+		default:
+			n.Bootstrap = []string{nodes[0].Addr(), nodes[1].Addr()}
+		}
+	}
+	return nodes, nil
+}
+
+// subnetBase compose 子网的网络地址，子网至少为 /24
+func subnetBase(subnet string) (net.IP, error) {
+	ip, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("%w: subnet %q is not an IPv4 CIDR", ErrInvalidTestnet, subnet)
+	}
+	if ones, _ := ipnet.Mask.Size(); ones > 24 {
+		return nil, fmt.Errorf("%w: subnet %q is smaller than /24", ErrInvalidTestnet, subnet)
+	}
+	return ipnet.IP.To4(), nil
+}
+
+// startArgs 节点启动参数：其余配置来自数据目录中的清单，第一个引导节点不要求已有连接即就绪
+func startArgs(n *TestnetNode, dataDir string) []string {
+	args := []string{"run", "-data", dataDir}
+	if n.Role == "bootstrap" {
+		args = append(args, "-ready-min-peers", "0")
+	}
+	return args
+}
+
+// ComposeFile 生成 docker-compose.yaml，各节点的数据目录挂载为 ./<节点名>
+func ComposeFile(c *TestnetConfig, nodes []*TestnetNode) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 本地测试网：%d 个节点（1 个引导节点、1 个中继节点）\n", len(nodes))
+	b.WriteString("# 由 agentnetwork netgen 生成，节点配置见各数据目录下的 node.yaml\n")
+	fmt.Fprintf(&b, "# 先构建镜像: docker build -t %s <仓库目录>\n", c.Image)
+	b.WriteString("# 启动: docker compose up -d\n\n")
+
+	b.WriteString("networks:\n  testnet:\n    driver: bridge\n    ipam:\n      config:\n")
+	fmt.Fprintf(&b, "        - subnet: %s\n\n", c.Subnet)
+
+	b.WriteString("services:\n")
+	for i, n := range nodes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		args := append([]string{"/app/agentnetwork"}, startArgs(n, "/data")...)
+		fmt.Fprintf(&b, "  %s:\n", n.Name)
+		fmt.Fprintf(&b, "    image: %s\n", c.Image)
+		fmt.Fprintf(&b, "    hostname: %s\n", n.Name)
+		fmt.Fprintf(&b, "    entrypoint: [%s]\n", quoteList(args))
+		if i > 0 {
+			b.WriteString("    depends_on:\n      bootstrap:\n        condition: service_healthy\n")
+		}
+		fmt.Fprintf(&b, "    volumes:\n      - ./%s:/data\n", n.Name)
+		b.WriteString("    ports:\n")
+		fmt.Fprintf(&b, "      - \"%d:%d\"\n", n.HostPorts.HTTP, n.Ports.HTTP)
+		fmt.Fprintf(&b, "      - \"%d:%d\"\n", n.HostPorts.Admin, n.Ports.Admin)
+		fmt.Fprintf(&b, "      - \"%d:%d\"\n", n.HostPorts.GRPC, n.Ports.GRPC)
+		fmt.Fprintf(&b, "    networks:\n      testnet:\n        ipv4_address: %s\n", n.IP)
+		b.WriteString("    healthcheck:\n")
+		fmt.Fprintf(&b, "      test: [\"CMD\", \"curl\", \"-f\", \"http://localhost:%d/health\"]\n", n.Ports.HTTP)
+		b.WriteString("      interval: 10s\n      timeout: 5s\n      retries: 5\n")
+		b.WriteString("    restart: unless-stopped\n")
+	}
+	return []byte(b.String())
+}
+
+// SystemdUnitName 节点的 systemd 单元名
+func SystemdUnitName(n *TestnetNode) string {
+	return "agentnetwork-" + n.Name + ".service"
+}
+
+// SystemdUnit 生成节点的 systemd 单元，数据目录为 <dataRoot>/<节点名>（绝对路径）
+func SystemdUnit(c *TestnetConfig, n *TestnetNode, dataRoot string) []byte {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=AgentNetwork testnet node %s (%s)\n", n.Name, n.Role)
+	if n.Role == "bootstrap" {
+		b.WriteString("After=network-online.target\n")
+	} else {
+		fmt.Fprintf(&b, "After=network-online.target agentnetwork-%s.service\n", TestnetNodeName(0))
+		fmt.Fprintf(&b, "Wants=agentnetwork-%s.service\n", TestnetNodeName(0))
+	}
+	b.WriteString("\n[Service]\n")
+	args := append([]string{c.Binary}, startArgs(n, filepath.Join(dataRoot, n.Name))...)
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	b.WriteString("Restart=on-failure\nRestartSec=5\n")
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return []byte(b.String())
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,136 @@
+package provision
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testPeerIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("12D3KooWPeer%d", i)
+	}
+	return ids
+}
+
+func TestPlanTestnet(t *testing.T) {
+	cfg := DefaultTestnetConfig()
+	nodes, err := PlanTestnet(cfg, testPeerIDs(cfg.Nodes))
+	if err != nil {
+		t.Fatalf("PlanTestnet failed: %v", err)
+	}
+	if len(nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(nodes))
+	}
+
+	roles := []string{"bootstrap", "relay", "normal", "normal", "normal"}
+	names := []string{"bootstrap", "relay", "node1", "node2", "node3"}
+	for i, n := range nodes {
+		if n.Role != roles[i] || n.Name != names[i] {
+			t.Errorf("node %d: expected %s/%s, got %s/%s", i, names[i], roles[i], n.Name, n.Role)
+		}
+		if n.Ports != containerPorts {
+			t.Errorf("%s: containers should share the same ports, got %+v", n.Name, n.Ports)
+		}
+		if n.HostPorts.HTTP != 18345+i {
+			t.Errorf("%s: expected host http port %d, got %d", n.Name, 18345+i, n.HostPorts.HTTP)
+		}
+		if err := n.Manifest("").Validate(); err != nil {
+			t.Errorf("%s: invalid manifest: %v", n.Name, err)
+		}
+	}
+
+	bootAddr := "/ip4/172.30.0.10/tcp/9000/p2p/12D3KooWPeer0"
+	if nodes[0].Addr() != bootAddr {
+		t.Errorf("unexpected bootstrap address %s", nodes[0].Addr())
+	}
+	if len(nodes[0].Bootstrap) != 0 {
+		t.Errorf("bootstrap node should not bootstrap from others: %v", nodes[0].Bootstrap)
+	}
+	if len(nodes[1].Bootstrap) != 1 || nodes[1].Bootstrap[0] != bootAddr {
+		t.Errorf("relay should bootstrap from the bootstrap node: %v", nodes[1].Bootstrap)
+	}
+	want := []string{bootAddr, "/ip4/172.30.0.11/tcp/9000/p2p/12D3KooWPeer1"}
+	for _, n := range nodes[2:] {
+		if strings.Join(n.Bootstrap, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected bootstrap %v, got %v", n.Name, want, n.Bootstrap)
+		}
+	}
+}
+
+func TestPlanTestnetSystemd(t *testing.T) {
+	cfg := DefaultTestnetConfig()
+	cfg.Format = FormatSystemd
+	cfg.Nodes = 3
+	nodes, err := PlanTestnet(cfg, testPeerIDs(3))
+	if err != nil {
+		t.Fatalf("PlanTestnet failed: %v", err)
+	}
+	if nodes[2].IP != "127.0.0.1" || nodes[2].Ports.P2P != 9002 || nodes[2].Ports != nodes[2].HostPorts {
+		t.Errorf("systemd nodes should listen on distinct local ports, got %+v", nodes[2])
+	}
+	if nodes[1].Bootstrap[0] != "/ip4/127.0.0.1/tcp/9000/p2p/12D3KooWPeer0" {
+		t.Errorf("unexpected bootstrap %v", nodes[1].Bootstrap)
+	}
+
+	unit := string(SystemdUnit(cfg, nodes[0], "/srv/testnet"))
+	if !strings.Contains(unit, "ExecStart=/usr/local/bin/agentnetwork run -data /srv/testnet/bootstrap -ready-min-peers 0\n") {
+		t.Errorf("unexpected bootstrap unit:\n%s", unit)
+	}
+	unit = string(SystemdUnit(cfg, nodes[2], "/srv/testnet"))
+	if !strings.Contains(unit, "Wants=agentnetwork-bootstrap.service") || strings.Contains(unit, "ready-min-peers") {
+		t.Errorf("unexpected node unit:\n%s", unit)
+	}
+	if SystemdUnitName(nodes[1]) != "agentnetwork-relay.service" {
+		t.Errorf("unexpected unit name %s", SystemdUnitName(nodes[1]))
+	}
+}
+
+func TestPlanTestnetErrors(t *testing.T) {
+	for name, mutate := range map[string]func(*TestnetConfig){
+		"too few nodes":  func(c *TestnetConfig) { c.Nodes = 1 },
+		"too many nodes": func(c *TestnetConfig) { c.Nodes = MaxTestnetNodes + 1 },
+		"format":         func(c *TestnetConfig) { c.Format = "k8s" },
+		"subnet":         func(c *TestnetConfig) { c.Subnet = "172.30.0.0/28" },
+		"port overlap":   func(c *TestnetConfig) { c.BasePorts.HTTP = c.BasePorts.P2P + 2 },
+		"port range":     func(c *TestnetConfig) { c.BasePorts.GRPC = 65534 },
+	} {
+		cfg := DefaultTestnetConfig()
+		mutate(cfg)
+		if _, err := PlanTestnet(cfg, testPeerIDs(cfg.Nodes)); !errors.Is(err, ErrInvalidTestnet) {
+			t.Errorf("%s: expected ErrInvalidTestnet, got %v", name, err)
+		}
+	}
+
+	if _, err := PlanTestnet(DefaultTestnetConfig(), testPeerIDs(3)); !errors.Is(err, ErrInvalidTestnet) {
+		t.Errorf("expected error for missing peer IDs, got %v", err)
+	}
+}
+
+func TestComposeFile(t *testing.T) {
+	cfg := DefaultTestnetConfig()
+	cfg.Nodes = 3
+	nodes, err := PlanTestnet(cfg, testPeerIDs(3))
+	if err != nil {
+		t.Fatalf("PlanTestnet failed: %v", err)
+	}
+	out := string(ComposeFile(cfg, nodes))
+	for _, want := range []string{
+		"- subnet: 172.30.0.0/24",
+		`entrypoint: ["/app/agentnetwork", "run", "-data", "/data", "-ready-min-peers", "0"]`,
+		`entrypoint: ["/app/agentnetwork", "run", "-data", "/data"]`,
+		"- ./relay:/data",
+		`- "18347:18345"`,
+		"ipv4_address: 172.30.0.12",
+		"condition: service_healthy",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("compose file missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "image: agentnetwork:dev") != 3 {
+		t.Errorf("expected 3 services:\n%s", out)
+	}
+}
@@ -0,0 +1,342 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 节点间协议用量配额
+//
+// 建立连接时双方通过 PeerQuotaProtocol 握手，各自通告愿意接收的用量上限（每小时邮件数、
+// 每小时留言数、每天任务报价数）。握手后双方都按对方的通告约束自己的发送，
+// 本节点则按自己通告的上限检查对方的入站用量：超出上限后进入软限流区间，消息被丢弃；
+// 超出软限流区间后断开连接，并在 BanDuration 内拒绝该节点的消息和握手。
+
+// PeerQuotaProtocol 配额握手协议ID（使用时按网络命名空间加前缀）
+const PeerQuotaProtocol = "/daan/quota/1.0.0"
+
+// maxAdvertisementSize 配额通告的最大长度
+const maxAdvertisementSize = 1024
+
+const peerQuotaVersion = 1
+
+var (
+	ErrPeerThrottled        = errors.New("peer quota exceeded, throttled")
+	ErrPeerDisconnected     = errors.New("peer quota violated, disconnected")
+	ErrInvalidAdvertisement = errors.New("invalid quota advertisement")
+)
+
+// Usage 受配额约束的协议用量
+type Usage string
+
+const (
+	UsageMail      Usage = "mail"       // 邮件，按小时计
+	UsageBulletin  Usage = "bulletin"   // 留言，按小时计
+	UsageTaskOffer Usage = "task_offer" // 任务报价，按天计
+)
+
+// window 用量的计数窗口
+func (u Usage) window() time.Duration {
+	if u == UsageTaskOffer {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// PeerLimits 节点通告的用量上限，0 表示不限
+type PeerLimits struct {
+	MailPerHour      int `json:"mail_per_hour"`
+	BulletinPerHour  int `json:"bulletin_per_hour"`
+	TaskOffersPerDay int `json:"task_offers_per_day"`
+}
+
+func (l PeerLimits) limit(u Usage) int {
+	switch u {
+	case UsageMail:
+		return l.MailPerHour
+	case UsageBulletin:
+		return l.BulletinPerHour
+	case UsageTaskOffer:
+		return l.TaskOffersPerDay
+	}
+	return 0
+}
+
+// DefaultPeerLimits 默认通告的用量上限
+func DefaultPeerLimits() PeerLimits {
+	return PeerLimits{
+		MailPerHour:      120,
+		BulletinPerHour:  60,
+		TaskOffersPerDay: 200,
+	}
+}
+
+// QuotaAdvertisement 握手时交换的配额通告
+type QuotaAdvertisement struct {
+	Version int        `json:"version"`
+	Limits  PeerLimits `json:"limits"`
+}
+
+// PeerQuotaConfig 节点间配额配置
+type PeerQuotaConfig struct {
+	Limits      PeerLimits    // 本节点通告的上限
+	SoftMargin  float64       // 软限流区间占上限的比例（至少 1 条）
+	BanDuration time.Duration // 断开后拒绝该节点的时间
+}
+
+// DefaultPeerQuotaConfig 返回默认配置
+func DefaultPeerQuotaConfig() *PeerQuotaConfig {
+	return &PeerQuotaConfig{
+		Limits:      DefaultPeerLimits(),
+		SoftMargin:  0.2,
+		BanDuration: time.Hour,
+	}
+}
+
+// UsageWindow 一种用量在当前窗口内的计数
+type UsageWindow struct {
+	Used      int       `json:"used"`
+	Limit     int       `json:"limit"`
+	Throttled int       `json:"throttled"` // 本窗口内被丢弃的条数
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// PeerQuotaState 节点的配额状态
+type PeerQuotaState struct {
+	PeerID       string                 `json:"peer_id"`
+	Negotiated   bool                   `json:"negotiated"`
+	HandshakeAt  time.Time              `json:"handshake_at,omitempty"`
+	LocalLimits  PeerLimits             `json:"local_limits"`            // 对方入站须遵守的本节点上限
+	RemoteLimits *PeerLimits            `json:"remote_limits,omitempty"` // 对方通告的上限，本节点发往对方时遵守
+	Usage        map[Usage]*UsageWindow `json:"usage"`
+	Throttled    bool                   `json:"throttled"`
+	BannedUntil  time.Time              `json:"banned_until,omitempty"`
+	Disconnects  int                    `json:"disconnects"`
+}
+
+type peerQuota struct {
+	remote      *PeerLimits
+	handshakeAt time.Time
+	usage       map[Usage]*UsageWindow
+	bannedUntil time.Time
+	disconnects int
+}
+
+// PeerQuotas 节点间协议用量配额
+type PeerQuotas struct {
+	config *PeerQuotaConfig
+
+	mu         sync.Mutex
+	peers      map[string]*peerQuota
+	disconnect func(peerID string)
+	now        func() time.Time
+}
+
+// NewPeerQuotas 创建节点间配额管理
+func NewPeerQuotas(config *PeerQuotaConfig) *PeerQuotas {
+	if config == nil {
+		config = DefaultPeerQuotaConfig()
+	}
+	return &PeerQuotas{
+		config: config,
+		peers:  make(map[string]*peerQuota),
+		now:    time.Now,
+	}
+}
+
+// SetDisconnectFunc 设置断开节点连接的回调
+func (pq *PeerQuotas) SetDisconnectFunc(fn func(peerID string)) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.disconnect = fn
+}
+
+// Limits 本节点通告的上限
+func (pq *PeerQuotas) Limits() PeerLimits {
+	return pq.config.Limits
+}
+
+// Handshake 作为发起方交换配额通告：先发送本节点的通告，再读取对方的通告
+func (pq *PeerQuotas) Handshake(rw io.ReadWriter, peerID string) (*PeerLimits, error) {
+	if pq.Banned(peerID) {
+		return nil, ErrPeerDisconnected
+	}
+	if err := json.NewEncoder(rw).Encode(pq.advertisement()); err != nil {
+		return nil, err
+	}
+	ad, err := readAdvertisement(rw)
+	if err != nil {
+		return nil, err
+	}
+	pq.setRemote(peerID, ad.Limits)
+	return &ad.Limits, nil
+}
+
+// HandleHandshake 作为响应方处理配额握手：读取对方的通告，再回复本节点的通告
+// 处于封禁期的节点不回复通告。
+func (pq *PeerQuotas) HandleHandshake(rw io.ReadWriter, peerID string) error {
+	if pq.Banned(peerID) {
+		return ErrPeerDisconnected
+	}
+	ad, err := readAdvertisement(rw)
+	if err != nil {
+		return err
+	}
+	pq.setRemote(peerID, ad.Limits)
+	return json.NewEncoder(rw).Encode(pq.advertisement())
+}
+
+func (pq *PeerQuotas) advertisement() *QuotaAdvertisement {
+	return &QuotaAdvertisement{Version: peerQuotaVersion, Limits: pq.config.Limits}
+}
+
+func readAdvertisement(r io.Reader) (*QuotaAdvertisement, error) {
+	ad := new(QuotaAdvertisement)
+	if err := json.NewDecoder(io.LimitReader(r, maxAdvertisementSize)).Decode(ad); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdvertisement, err)
+	}
+	if ad.Version < 1 || ad.Limits.MailPerHour < 0 || ad.Limits.BulletinPerHour < 0 || ad.Limits.TaskOffersPerDay < 0 {
+		return nil, ErrInvalidAdvertisement
+	}
+	return ad, nil
+}
+
+func (pq *PeerQuotas) setRemote(peerID string, limits PeerLimits) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	p := pq.peerLocked(peerID)
+	p.remote = &limits
+	p.handshakeAt = pq.now()
+}
+
+func (pq *PeerQuotas) peerLocked(peerID string) *peerQuota {
+	p, ok := pq.peers[peerID]
+	if !ok {
+		p = &peerQuota{usage: make(map[Usage]*UsageWindow)}
+		pq.peers[peerID] = p
+	}
+	return p
+}
+
+// Allow 记录一次来自节点的入站用量并检查配额
+// 未超出上限返回 nil；处于软限流区间返回 ErrPeerThrottled，调用方应丢弃该消息；
+// 超出软限流区间时断开连接并返回 ErrPeerDisconnected。未握手的节点同样按本节点上限检查。
+func (pq *PeerQuotas) Allow(peerID string, usage Usage) error {
+	pq.mu.Lock()
+	now := pq.now()
+	p := pq.peerLocked(peerID)
+	if now.Before(p.bannedUntil) {
+		pq.mu.Unlock()
+		return ErrPeerDisconnected
+	}
+
+	w := p.windowLocked(usage, pq.config.Limits.limit(usage), now)
+	w.Used++
+	if w.Limit <= 0 || w.Used <= w.Limit {
+		pq.mu.Unlock()
+		return nil
+	}
+	if w.Used <= w.Limit+pq.softMargin(w.Limit) {
+		w.Throttled++
+		pq.mu.Unlock()
+		return ErrPeerThrottled
+	}
+
+	// 超出软限流区间：断开并封禁，计数随新窗口重新开始
+	p.bannedUntil = now.Add(pq.config.BanDuration)
+	p.disconnects++
+	p.usage = make(map[Usage]*UsageWindow)
+	disconnect := pq.disconnect
+	pq.mu.Unlock()
+
+	if disconnect != nil {
+		disconnect(peerID)
+	}
+	return fmt.Errorf("%w: %s over %s quota", ErrPeerDisconnected, peerID, usage)
+}
+
+// windowLocked 取当前窗口，窗口到期后重新计数（调用方持有锁）
+func (p *peerQuota) windowLocked(usage Usage, limit int, now time.Time) *UsageWindow {
+	w, ok := p.usage[usage]
+	if !ok || !now.Before(w.ResetAt) {
+		w = &UsageWindow{ResetAt: now.Add(usage.window())}
+		p.usage[usage] = w
+	}
+	w.Limit = limit
+	return w
+}
+
+func (pq *PeerQuotas) softMargin(limit int) int {
+	margin := int(float64(limit) * pq.config.SoftMargin)
+	if margin < 1 {
+		margin = 1
+	}
+	return margin
+}
+
+// Banned 节点是否处于断开后的封禁期
+func (pq *PeerQuotas) Banned(peerID string) bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	p, ok := pq.peers[peerID]
+	return ok && pq.now().Before(p.bannedUntil)
+}
+
+// State 查询节点的配额状态
+func (pq *PeerQuotas) State(peerID string) (*PeerQuotaState, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	p, ok := pq.peers[peerID]
+	if !ok {
+		return nil, false
+	}
+	return pq.stateLocked(peerID, p), true
+}
+
+// States 所有有记录的节点的配额状态，按节点ID排序
+func (pq *PeerQuotas) States() []*PeerQuotaState {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	states := make([]*PeerQuotaState, 0, len(pq.peers))
+	for id, p := range pq.peers {
+		states = append(states, pq.stateLocked(id, p))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].PeerID < states[j].PeerID })
+	return states
+}
+
+func (pq *PeerQuotas) stateLocked(peerID string, p *peerQuota) *PeerQuotaState {
+	now := pq.now()
+	state := &PeerQuotaState{
+		PeerID:      peerID,
+		Negotiated:  p.remote != nil,
+		HandshakeAt: p.handshakeAt,
+		LocalLimits: pq.config.Limits,
+		Usage:       make(map[Usage]*UsageWindow),
+		Disconnects: p.disconnects,
+	}
+	if p.remote != nil {
+		remote := *p.remote
+		state.RemoteLimits = &remote
+	}
+	if now.Before(p.bannedUntil) {
+		state.BannedUntil = p.bannedUntil
+	}
+	for _, u := range []Usage{UsageMail, UsageBulletin, UsageTaskOffer} {
+		w, ok := p.usage[u]
+		if !ok || !now.Before(w.ResetAt) {
+			continue
+		}
+		copied := *w
+		state.Usage[u] = &copied
+		if copied.Limit > 0 && copied.Used > copied.Limit {
+			state.Throttled = true
+		}
+	}
+	return state
+}
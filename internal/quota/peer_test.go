@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestPeerQuotas(limits PeerLimits) (*PeerQuotas, *time.Time) {
+	config := DefaultPeerQuotaConfig()
+	config.Limits = limits
+	pq := NewPeerQuotas(config)
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	pq.now = func() time.Time { return now }
+	return pq, &now
+}
+
+func TestPeerQuotaHandshake(t *testing.T) {
+	a, _ := newTestPeerQuotas(PeerLimits{MailPerHour: 10, BulletinPerHour: 5, TaskOffersPerDay: 20})
+	b, _ := newTestPeerQuotas(PeerLimits{MailPerHour: 30})
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	errCh := make(chan error, 1)
+	go func() { errCh <- b.HandleHandshake(c2, "node-a") }()
+
+	remote, err := a.Handshake(c1, "node-b")
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("HandleHandshake failed: %v", err)
+	}
+	if remote.MailPerHour != 30 || remote.BulletinPerHour != 0 {
+		t.Errorf("remote limits = %+v", remote)
+	}
+
+	state, ok := b.State("node-a")
+	if !ok || !state.Negotiated || state.RemoteLimits.TaskOffersPerDay != 20 || state.LocalLimits.MailPerHour != 30 {
+		t.Errorf("responder state = %+v", state)
+	}
+}
+
+func TestPeerQuotaThrottleThenDisconnect(t *testing.T) {
+	pq, now := newTestPeerQuotas(PeerLimits{BulletinPerHour: 5})
+	var disconnected []string
+	pq.SetDisconnectFunc(func(peerID string) { disconnected = append(disconnected, peerID) })
+
+	for i := 0; i < 5; i++ {
+		if err := pq.Allow("peer1", UsageBulletin); err != nil {
+			t.Fatalf("post %d: %v", i, err)
+		}
+	}
+	// 上限 5，软限流区间 max(1, 5*0.2) = 1 条
+	if err := pq.Allow("peer1", UsageBulletin); !errors.Is(err, ErrPeerThrottled) {
+		t.Errorf("expected ErrPeerThrottled, got %v", err)
+	}
+	if state, _ := pq.State("peer1"); !state.Throttled || state.Usage[UsageBulletin].Throttled != 1 {
+		t.Errorf("expected throttled state, got %+v", state)
+	}
+	if err := pq.Allow("peer1", UsageBulletin); !errors.Is(err, ErrPeerDisconnected) {
+		t.Errorf("expected ErrPeerDisconnected, got %v", err)
+	}
+	if len(disconnected) != 1 || disconnected[0] != "peer1" {
+		t.Errorf("disconnect callback calls = %v", disconnected)
+	}
+
+	// 封禁期内拒绝消息和握手，其他用量和其他节点不受影响
+	if err := pq.Allow("peer1", UsageMail); !errors.Is(err, ErrPeerDisconnected) {
+		t.Errorf("expected banned peer to be rejected, got %v", err)
+	}
+	if _, err := pq.Handshake(nil, "peer1"); !errors.Is(err, ErrPeerDisconnected) {
+		t.Errorf("expected handshake rejection, got %v", err)
+	}
+	if err := pq.Allow("peer2", UsageBulletin); err != nil {
+		t.Errorf("other peer: %v", err)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if err := pq.Allow("peer1", UsageBulletin); err != nil {
+		t.Errorf("after ban: %v", err)
+	}
+	if state, _ := pq.State("peer1"); state.Disconnects != 1 || !state.BannedUntil.IsZero() || state.Usage[UsageBulletin].Used != 1 {
+		t.Errorf("state after ban = %+v", state)
+	}
+}
+
+func TestPeerQuotaWindows(t *testing.T) {
+	pq, now := newTestPeerQuotas(PeerLimits{MailPerHour: 2, TaskOffersPerDay: 2})
+
+	pq.Allow("peer1", UsageMail)
+	pq.Allow("peer1", UsageMail)
+	pq.Allow("peer1", UsageTaskOffer)
+	pq.Allow("peer1", UsageTaskOffer)
+
+	// 邮件按小时、任务报价按天重新计数
+	*now = now.Add(time.Hour)
+	if err := pq.Allow("peer1", UsageMail); err != nil {
+		t.Errorf("mail in new hour: %v", err)
+	}
+	if err := pq.Allow("peer1", UsageTaskOffer); !errors.Is(err, ErrPeerThrottled) {
+		t.Errorf("task offer in same day: expected ErrPeerThrottled, got %v", err)
+	}
+
+	// 上限为 0 表示不限
+	for i := 0; i < 100; i++ {
+		if err := pq.Allow("peer1", UsageBulletin); err != nil {
+			t.Fatalf("unlimited bulletin: %v", err)
+		}
+	}
+	if states := pq.States(); len(states) != 1 || states[0].Usage[UsageMail].Used != 1 {
+		t.Errorf("states = %+v", states)
+	}
+}
@@ -0,0 +1,345 @@
+// Package reachability 邻居协助的 NAT 可达性证明
+// 节点自报的可达性不可靠。邻居对节点发起回拨测试（新建直连而非复用已有连接），
+// 把结果签名为可达性证明；证明在邻居间传播，汇总后得到节点的公共可达性分数，
+// 供中继选择和任务调度使用。
+package reachability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// attestationVersion 签名载荷版本前缀
+const attestationVersion = "daan-reachability-v1"
+
+var (
+	ErrInvalidAttestation = errors.New("invalid reachability attestation")
+	ErrSelfAttestation    = errors.New("nodes cannot attest their own reachability")
+	ErrStaleAttestation   = errors.New("reachability attestation expired")
+	ErrDialBackUnavail    = errors.New("dial-back test not configured")
+)
+
+// Attestation 可达性证明：Attester 在 IssuedAt 时刻对 Subject 回拨测试的签名结果
+type Attestation struct {
+	Subject   string    `json:"subject"`
+	Attester  string    `json:"attester"`
+	Reachable bool      `json:"reachable"`
+	Addrs     []string  `json:"addrs,omitempty"` // 回拨成功的地址（失败时为尝试过的地址）
+	LatencyMs int64     `json:"latency_ms,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature []byte    `json:"signature,omitempty"`
+}
+
+// SigningPayload 证明的签名载荷
+func (a *Attestation) SigningPayload() []byte {
+	return []byte(strings.Join([]string{
+		attestationVersion,
+		a.Subject,
+		a.Attester,
+		strconv.FormatBool(a.Reachable),
+		strings.Join(a.Addrs, ","),
+		strconv.FormatInt(a.LatencyMs, 10),
+		strconv.FormatInt(a.IssuedAt.Unix(), 10),
+	}, "\n"))
+}
+
+// DialBackFunc 对 subject 发起回拨测试，返回拨通（或尝试过）的地址和耗时
+type DialBackFunc func(ctx context.Context, subject string) (addrs []string, latency time.Duration, err error)
+
+// Config 可达性服务配置
+type Config struct {
+	MaxAge       time.Duration // 证明有效期，过期的证明不参与汇总
+	HalfLife     time.Duration // 证明权重随时间减半的周期
+	PriorWeight  float64       // 先验权重：证明较少时分数向 0.5 收缩
+	MaxPerNode   int           // 每个节点保留的证明数（每个证明人只保留最新一条）
+	DialTimeout  time.Duration // 回拨测试超时
+	MaxClockSkew time.Duration // 允许证明时间超前本地时钟的时长
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		MaxAge:       24 * time.Hour,
+		HalfLife:     6 * time.Hour,
+		PriorWeight:  2,
+		MaxPerNode:   64,
+		DialTimeout:  10 * time.Second,
+		MaxClockSkew: 5 * time.Minute,
+	}
+}
+
+// Score 节点的公共可达性分数
+type Score struct {
+	NodeID       string    `json:"node_id"`
+	Score        float64   `json:"score"` // [0,1]，无证明时为 0.5
+	Attesters    int       `json:"attesters"`
+	Reachable    int       `json:"reachable"`
+	Unreachable  int       `json:"unreachable"`
+	LastAttested time.Time `json:"last_attested,omitempty"`
+}
+
+// Service 执行回拨测试、签发并汇总可达性证明
+type Service struct {
+	mu     sync.RWMutex
+	config *Config
+	self   string
+
+	sign       func(data []byte) ([]byte, error)
+	verify     func(signerID string, data, signature []byte) (bool, error)
+	dialBack   DialBackFunc
+	weightFunc func(attester string) float64
+	onAttested func(a *Attestation)
+
+	attestations map[string]map[string]*Attestation // subject -> attester -> 最新证明
+
+	now func() time.Time
+}
+
+// New 创建可达性服务，self 为本节点ID
+func New(self string, config *Config) *Service {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Service{
+		config:       config,
+		self:         self,
+		attestations: make(map[string]map[string]*Attestation),
+		now:          time.Now,
+	}
+}
+
+// SetSigner 设置签名（签发证明）和验签（接收证明）函数
+func (s *Service) SetSigner(sign func(data []byte) ([]byte, error), verify func(signerID string, data, signature []byte) (bool, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sign = sign
+	s.verify = verify
+}
+
+// SetDialBackFunc 设置回拨测试函数
+func (s *Service) SetDialBackFunc(fn DialBackFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialBack = fn
+}
+
+// SetWeightFunc 设置证明人权重（如按邻居关系或声誉），未设置时每个证明人权重为 1
+func (s *Service) SetWeightFunc(fn func(attester string) float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weightFunc = fn
+}
+
+// SetOnAttested 设置本节点签发证明后的回调（如广播给其他节点）
+func (s *Service) SetOnAttested(fn func(a *Attestation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAttested = fn
+}
+
+// Test 对 subject 做一次回拨测试，签发证明并计入汇总
+// 拨号失败不是错误，会签发 Reachable 为 false 的证明。
+func (s *Service) Test(ctx context.Context, subject string) (*Attestation, error) {
+	if subject == s.self {
+		return nil, ErrSelfAttestation
+	}
+	s.mu.RLock()
+	dialBack, sign := s.dialBack, s.sign
+	s.mu.RUnlock()
+	if dialBack == nil || sign == nil {
+		return nil, ErrDialBackUnavail
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.DialTimeout)
+	defer cancel()
+	addrs, latency, err := dialBack(ctx, subject)
+
+	a := &Attestation{
+		Subject:   subject,
+		Attester:  s.self,
+		Reachable: err == nil,
+		Addrs:     addrs,
+		IssuedAt:  s.now().Truncate(time.Second),
+	}
+	if a.Reachable {
+		a.LatencyMs = latency.Milliseconds()
+	}
+	if a.Signature, err = sign(a.SigningPayload()); err != nil {
+		return nil, err
+	}
+	s.store(a)
+
+	s.mu.RLock()
+	onAttested := s.onAttested
+	s.mu.RUnlock()
+	if onAttested != nil {
+		copied := *a
+		onAttested(&copied)
+	}
+	return a, nil
+}
+
+// Add 接收其他节点签发的证明，校验签名和时效后计入汇总
+func (s *Service) Add(a *Attestation) error {
+	if a == nil || a.Subject == "" || a.Attester == "" || len(a.Signature) == 0 {
+		return ErrInvalidAttestation
+	}
+	if a.Subject == a.Attester {
+		return ErrSelfAttestation
+	}
+	now := s.now()
+	if now.Sub(a.IssuedAt) > s.config.MaxAge || a.IssuedAt.Sub(now) > s.config.MaxClockSkew {
+		return ErrStaleAttestation
+	}
+
+	s.mu.RLock()
+	verify := s.verify
+	s.mu.RUnlock()
+	if verify == nil {
+		return fmt.Errorf("%w: no verifier configured", ErrInvalidAttestation)
+	}
+	if ok, err := verify(a.Attester, a.SigningPayload(), a.Signature); err != nil || !ok {
+		return fmt.Errorf("%w: bad signature from %s", ErrInvalidAttestation, a.Attester)
+	}
+	s.store(a)
+	return nil
+}
+
+// store 保存证明：每个证明人只保留最新一条，超出上限时淘汰最旧的
+func (s *Service) store(a *Attestation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byAttester, ok := s.attestations[a.Subject]
+	if !ok {
+		byAttester = make(map[string]*Attestation)
+		s.attestations[a.Subject] = byAttester
+	}
+	if old, ok := byAttester[a.Attester]; ok && old.IssuedAt.After(a.IssuedAt) {
+		return
+	}
+	byAttester[a.Attester] = a
+
+	if s.config.MaxPerNode > 0 && len(byAttester) > s.config.MaxPerNode {
+		var oldest *Attestation
+		for _, e := range byAttester {
+			if oldest == nil || e.IssuedAt.Before(oldest.IssuedAt) {
+				oldest = e
+			}
+		}
+		delete(byAttester, oldest.Attester)
+	}
+}
+
+// Attestations 返回节点未过期的证明（用于转发给其他节点），最新的在前
+func (s *Service) Attestations(subject string) []*Attestation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	var result []*Attestation
+	for _, a := range s.attestations[subject] {
+		if now.Sub(a.IssuedAt) <= s.config.MaxAge {
+			copied := *a
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].IssuedAt.After(result[j].IssuedAt)
+	})
+	return result
+}
+
+// Score 汇总节点的可达性分数
+// 每条证明的权重为证明人权重乘以时间衰减，分数为可达权重占比，
+// 并以 PriorWeight 的先验向 0.5 收缩，少数几条证明不会得到极端分数。
+func (s *Service) Score(subject string) Score {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	score := Score{NodeID: subject}
+	var reachable, total float64
+	for attester, a := range s.attestations[subject] {
+		age := now.Sub(a.IssuedAt)
+		if age > s.config.MaxAge {
+			continue
+		}
+		weight := 1.0
+		if s.weightFunc != nil {
+			weight = math.Max(s.weightFunc(attester), 0)
+		}
+		if s.config.HalfLife > 0 && age > 0 {
+			weight *= math.Pow(0.5, float64(age)/float64(s.config.HalfLife))
+		}
+		total += weight
+		score.Attesters++
+		if a.Reachable {
+			reachable += weight
+			score.Reachable++
+		} else {
+			score.Unreachable++
+		}
+		if a.IssuedAt.After(score.LastAttested) {
+			score.LastAttested = a.IssuedAt
+		}
+	}
+	score.Score = 0.5
+	if denom := total + s.config.PriorWeight; denom > 0 {
+		score.Score = (reachable + 0.5*s.config.PriorWeight) / denom
+	}
+	return score
+}
+
+// ScoreValue 返回可达性分数，没有任何有效证明时 ok 为 false
+func (s *Service) ScoreValue(nodeID string) (float64, bool) {
+	score := s.Score(nodeID)
+	return score.Score, score.Attesters > 0
+}
+
+// RankRelays 按可达性分数从高到低排列中继候选，分数相同时保持原顺序
+// minScore 以下的候选被剔除（没有证明的候选按 0.5 计）。
+func (s *Service) RankRelays(candidates []string, minScore float64) []string {
+	scores := make(map[string]float64, len(candidates))
+	ranked := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		sc := s.Score(id).Score
+		if sc < minScore {
+			continue
+		}
+		scores[id] = sc
+		ranked = append(ranked, id)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
+
+// Prune 清理过期证明，返回清理的数量
+func (s *Service) Prune() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	pruned := 0
+	for subject, byAttester := range s.attestations {
+		for attester, a := range byAttester {
+			if now.Sub(a.IssuedAt) > s.config.MaxAge {
+				delete(byAttester, attester)
+				pruned++
+			}
+		}
+		if len(byAttester) == 0 {
+			delete(s.attestations, subject)
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,139 @@
+package reachability
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试签名：签名为 节点ID + 载荷
+func testSign(nodeID string) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		return append([]byte(nodeID+":"), data...), nil
+	}
+}
+
+func testVerify(signerID string, data, signature []byte) (bool, error) {
+	return bytes.Equal(signature, append([]byte(signerID+":"), data...)), nil
+}
+
+func newTestService(self string) *Service {
+	s := New(self, nil)
+	s.SetSigner(testSign(self), testVerify)
+	return s
+}
+
+func TestDialBackAttestation(t *testing.T) {
+	alice := newTestService("alice")
+	if _, err := alice.Test(context.Background(), "bob"); err != ErrDialBackUnavail {
+		t.Fatalf("expected ErrDialBackUnavail, got %v", err)
+	}
+	alice.SetDialBackFunc(func(ctx context.Context, subject string) ([]string, time.Duration, error) {
+		if subject == "natted" {
+			return []string{"/ip4/10.0.0.2/tcp/4001"}, 0, errors.New("dial timeout")
+		}
+		return []string{"/ip4/1.2.3.4/tcp/4001"}, 40 * time.Millisecond, nil
+	})
+
+	var issued []*Attestation
+	alice.SetOnAttested(func(a *Attestation) { issued = append(issued, a) })
+
+	ok, err := alice.Test(context.Background(), "bob")
+	if err != nil || !ok.Reachable || ok.LatencyMs != 40 {
+		t.Fatalf("expected reachable attestation, got %+v, %v", ok, err)
+	}
+	if len(issued) != 1 || issued[0].Subject != "bob" {
+		t.Errorf("issued attestation should be reported, got %+v", issued)
+	}
+	failed, err := alice.Test(context.Background(), "natted")
+	if err != nil || failed.Reachable {
+		t.Fatalf("failed dial-back should yield unreachable attestation, got %+v, %v", failed, err)
+	}
+	if _, err := alice.Test(context.Background(), "alice"); err != ErrSelfAttestation {
+		t.Errorf("expected ErrSelfAttestation, got %v", err)
+	}
+
+	// 其他节点接收并校验 alice 签发的证明
+	carol := newTestService("carol")
+	if err := carol.Add(ok); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	forged := *failed
+	forged.Reachable = true
+	if err := carol.Add(&forged); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("tampered attestation: expected ErrInvalidAttestation, got %v", err)
+	}
+	self := &Attestation{Subject: "bob", Attester: "bob", Reachable: true, IssuedAt: time.Now()}
+	self.Signature, _ = testSign("bob")(self.SigningPayload())
+	if err := carol.Add(self); err != ErrSelfAttestation {
+		t.Errorf("self attestation: expected ErrSelfAttestation, got %v", err)
+	}
+	old := &Attestation{Subject: "bob", Attester: "dave", Reachable: true, IssuedAt: time.Now().Add(-48 * time.Hour)}
+	old.Signature, _ = testSign("dave")(old.SigningPayload())
+	if err := carol.Add(old); err != ErrStaleAttestation {
+		t.Errorf("expired attestation: expected ErrStaleAttestation, got %v", err)
+	}
+	if got := carol.Attestations("bob"); len(got) != 1 || got[0].Attester != "alice" {
+		t.Errorf("expected 1 forwarded attestation, got %v", got)
+	}
+}
+
+func TestScoreAggregation(t *testing.T) {
+	s := newTestService("self")
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.SetWeightFunc(func(attester string) float64 {
+		if attester == "sybil" {
+			return 0
+		}
+		return 1
+	})
+
+	add := func(subject, attester string, reachable bool, age time.Duration) {
+		t.Helper()
+		a := &Attestation{Subject: subject, Attester: attester, Reachable: reachable, IssuedAt: now.Add(-age)}
+		a.Signature, _ = testSign(attester)(a.SigningPayload())
+		if err := s.Add(a); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	if sc := s.Score("unknown"); sc.Score != 0.5 || sc.Attesters != 0 {
+		t.Errorf("no attestations should score 0.5, got %+v", sc)
+	}
+
+	for _, attester := range []string{"n1", "n2", "n3", "n4"} {
+		add("public", attester, true, time.Minute)
+		add("natted", attester, false, time.Minute)
+	}
+	add("natted", "sybil", true, 0)       // 权重为 0 的证明人不影响分数
+	add("public", "n1", false, time.Hour) // 同一证明人只保留最新一条
+
+	pub, nat := s.Score("public"), s.Score("natted")
+	if pub.Attesters != 4 || pub.Reachable != 4 || pub.Score < 0.8 {
+		t.Errorf("public node should score high: %+v", pub)
+	}
+	if nat.Unreachable != 4 || nat.Score > 0.2 {
+		t.Errorf("natted node should score low: %+v", nat)
+	}
+
+	// 单条证明不会得到极端分数
+	add("single", "n1", true, 0)
+	if sc := s.Score("single").Score; sc >= 0.8 || sc <= 0.5 {
+		t.Errorf("single attestation should be shrunk toward 0.5, got %v", sc)
+	}
+
+	if ranked := s.RankRelays([]string{"natted", "unknown", "public"}, 0.3); len(ranked) != 2 || ranked[0] != "public" || ranked[1] != "unknown" {
+		t.Errorf("unexpected relay ranking: %v", ranked)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if n := s.Prune(); n != 10 {
+		t.Errorf("expected 10 pruned attestations, got %d", n)
+	}
+	if _, ok := s.ScoreValue("public"); ok {
+		t.Error("expired attestations should not count")
+	}
+}
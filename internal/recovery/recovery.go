@@ -0,0 +1,687 @@
+// Package recovery 基于可信节点的密钥社交恢复
+//
+// 节点把私钥用随机内容密钥加密，内容密钥按 Shamir 方案分成 N 份，每份用一名
+// 受托节点的公钥包裹后交给对方保管，加密备份随份额一起分发。节点丢失私钥后，
+// 以新的临时身份发起恢复仪式：向受托节点提交恢复请求，每名受托节点的运营者
+// 确认后，把自己的份额改用请求方的公钥重新包裹并签名发回；凑齐 M 份即可还原
+// 内容密钥、解密备份。恢复请求按托管记录限频，所有操作记入日志。
+package recovery
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNilConfig          = errors.New("config cannot be nil")
+	ErrCryptoUnavail      = errors.New("key wrapping or signing not configured")
+	ErrInvalidGuardians   = errors.New("invalid guardian set")
+	ErrEscrowNotFound     = errors.New("escrow not found")
+	ErrNotGuardian        = errors.New("node is not a guardian of this escrow")
+	ErrRequestNotFound    = errors.New("recovery request not found")
+	ErrRequestDecided     = errors.New("recovery request already decided")
+	ErrRequestExpired     = errors.New("recovery request expired")
+	ErrRateLimited        = errors.New("too many recovery requests for this escrow")
+	ErrDuplicateRequest   = errors.New("requester already has a pending recovery request")
+	ErrCeremonyNotFound   = errors.New("recovery ceremony not found")
+	ErrCeremonyClosed     = errors.New("recovery ceremony is no longer collecting shares")
+	ErrInvalidRelease     = errors.New("invalid released share")
+	ErrRecoveryMismatch   = errors.New("reassembled key does not match escrow")
+	ErrRecoveryIncomplete = errors.New("not enough approved shares")
+)
+
+// RequestStatus 恢复请求状态
+type RequestStatus string
+
+const (
+	RequestPending  RequestStatus = "pending"
+	RequestApproved RequestStatus = "approved"
+	RequestRejected RequestStatus = "rejected"
+)
+
+// CeremonyStatus 恢复仪式状态
+type CeremonyStatus string
+
+const (
+	CeremonyCollecting CeremonyStatus = "collecting"
+	CeremonyCompleted  CeremonyStatus = "completed"
+	CeremonyFailed     CeremonyStatus = "failed"
+)
+
+// Escrow 托管记录：加密的私钥备份和份额分配，随份额一起交给每名受托节点
+type Escrow struct {
+	ID         string    `json:"id"`
+	Owner      string    `json:"owner"`
+	Guardians  []string  `json:"guardians"`
+	Threshold  int       `json:"threshold"`
+	Ciphertext []byte    `json:"ciphertext"` // AES-256-GCM 加密的私钥（nonce 在前）
+	KeyHash    string    `json:"key_hash"`   // 私钥的 SHA-256，用于校验还原结果
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ShareEnvelope 包裹给受托节点的份额
+type ShareEnvelope struct {
+	EscrowID string `json:"escrow_id"`
+	Guardian string `json:"guardian"`
+	Index    byte   `json:"index"`
+	Wrapped  []byte `json:"wrapped"`
+}
+
+// Request 受托节点收到的恢复请求
+type Request struct {
+	ID        string        `json:"id"`
+	EscrowID  string        `json:"escrow_id"`
+	Owner     string        `json:"owner"`
+	Requester string        `json:"requester"` // 请求方的新节点ID，份额将包裹给它
+	Reason    string        `json:"reason,omitempty"`
+	Status    RequestStatus `json:"status"`
+	CreatedAt time.Time     `json:"created_at"`
+	DecidedAt time.Time     `json:"decided_at,omitempty"`
+}
+
+// ReleasedShare 受托节点批准后发给请求方的份额
+type ReleasedShare struct {
+	EscrowID  string `json:"escrow_id"`
+	RequestID string `json:"request_id"`
+	Guardian  string `json:"guardian"`
+	Requester string `json:"requester"`
+	Index     byte   `json:"index"`
+	Wrapped   []byte `json:"wrapped"` // 用请求方公钥包裹的份额
+	Signature []byte `json:"signature"`
+}
+
+// signingPayload 受托节点签名的内容
+func (r *ReleasedShare) signingPayload() []byte {
+	sum := sha256.Sum256(r.Wrapped)
+	return []byte(strings.Join([]string{
+		"daan-recovery-release-v1",
+		r.EscrowID,
+		r.RequestID,
+		r.Guardian,
+		r.Requester,
+		strconv.Itoa(int(r.Index)),
+		hex.EncodeToString(sum[:]),
+	}, "\n"))
+}
+
+// Ceremony 请求方的恢复仪式
+type Ceremony struct {
+	ID          string                    `json:"id"`
+	Escrow      *Escrow                   `json:"escrow"`
+	Requester   string                    `json:"requester"`
+	Approvals   map[string]*ReleasedShare `json:"approvals"` // 受托节点 -> 已批准的份额
+	Status      CeremonyStatus            `json:"status"`
+	Error       string                    `json:"error,omitempty"`
+	CreatedAt   time.Time                 `json:"created_at"`
+	CompletedAt time.Time                 `json:"completed_at,omitempty"`
+
+	recovered []byte
+}
+
+// LogEntry 恢复日志
+type LogEntry struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	EscrowID string    `json:"escrow_id"`
+	Actor    string    `json:"actor,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// Config 社交恢复配置
+type Config struct {
+	NodeID        string
+	DataDir       string
+	MaxGuardians  int           // 受托节点数上限
+	RequestLimit  int           // 每个托管记录在 RequestWindow 内最多受理的恢复请求数
+	RequestWindow time.Duration // 限频窗口
+	RequestTTL    time.Duration // 恢复请求的有效期，过期后不能再批准
+	MaxLogEntries int
+
+	// 用接收方公钥包裹 / 用本节点私钥解包
+	WrapFunc   func(recipient string, data []byte) ([]byte, error)
+	UnwrapFunc func(wrapped []byte) ([]byte, error)
+	// 本节点签名 / 按节点ID验签
+	SignFunc   func(data []byte) ([]byte, error)
+	VerifyFunc func(signerID string, data, signature []byte) (bool, error)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig(nodeID string) *Config {
+	return &Config{
+		NodeID:        nodeID,
+		DataDir:       "./data/recovery",
+		MaxGuardians:  16,
+		RequestLimit:  3,
+		RequestWindow: 24 * time.Hour,
+		RequestTTL:    72 * time.Hour,
+		MaxLogEntries: 1000,
+	}
+}
+
+// Manager 社交恢复管理器，同时承担托管方、受托方和恢复请求方三种角色
+type Manager struct {
+	mu         sync.RWMutex
+	config     *Config
+	escrows    map[string]*Escrow        // 本节点保管或创建的托管记录
+	envelopes  map[string]*ShareEnvelope // 托管ID -> 本节点保管的份额
+	requests   map[string]*Request
+	ceremonies map[string]*Ceremony
+	log        []*LogEntry
+
+	now func() time.Time
+}
+
+// NewManager 创建社交恢复管理器
+func NewManager(config *Config) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilConfig
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+	m := &Manager{
+		config:     config,
+		escrows:    make(map[string]*Escrow),
+		envelopes:  make(map[string]*ShareEnvelope),
+		requests:   make(map[string]*Request),
+		ceremonies: make(map[string]*Ceremony),
+		now:        time.Now,
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CreateEscrow 托管本节点私钥：加密备份并把内容密钥分给受托节点，任意 threshold 名受托节点批准即可恢复
+// 返回的托管记录和份额需要交给各受托节点（每个份额只有对应受托节点能解包）。
+func (m *Manager) CreateEscrow(key []byte, guardians []string, threshold int) (*Escrow, []*ShareEnvelope, error) {
+	if m.config.WrapFunc == nil {
+		return nil, nil, ErrCryptoUnavail
+	}
+	seen := make(map[string]bool, len(guardians))
+	for _, g := range guardians {
+		if g == "" || g == m.config.NodeID || seen[g] {
+			return nil, nil, fmt.Errorf("%w: duplicate, empty or self guardian", ErrInvalidGuardians)
+		}
+		seen[g] = true
+	}
+	if len(guardians) < 2 || len(guardians) > m.config.MaxGuardians || threshold < 2 || threshold > len(guardians) {
+		return nil, nil, fmt.Errorf("%w: need 2 <= threshold <= guardians <= %d", ErrInvalidGuardians, m.config.MaxGuardians)
+	}
+
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := seal(contentKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	shares, err := SplitSecret(contentKey, len(guardians), threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := m.now()
+	keyHash := sha256.Sum256(key)
+	escrow := &Escrow{
+		ID:         newID(m.config.NodeID, now),
+		Owner:      m.config.NodeID,
+		Guardians:  append([]string(nil), guardians...),
+		Threshold:  threshold,
+		Ciphertext: ciphertext,
+		KeyHash:    hex.EncodeToString(keyHash[:]),
+		CreatedAt:  now,
+	}
+	envelopes := make([]*ShareEnvelope, 0, len(guardians))
+	for i, g := range guardians {
+		wrapped, err := m.config.WrapFunc(g, shares[i].Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("wrap share for %s: %w", g, err)
+		}
+		envelopes = append(envelopes, &ShareEnvelope{EscrowID: escrow.ID, Guardian: g, Index: shares[i].Index, Wrapped: wrapped})
+	}
+
+	m.mu.Lock()
+	m.escrows[escrow.ID] = escrow
+	m.appendLog("escrow_created", escrow.ID, m.config.NodeID, fmt.Sprintf("%d-of-%d", threshold, len(guardians)))
+	m.mu.Unlock()
+	return escrow, envelopes, m.save()
+}
+
+// HoldShare 受托节点保存分给自己的份额和托管记录
+func (m *Manager) HoldShare(escrow *Escrow, env *ShareEnvelope) error {
+	if escrow == nil || env == nil || env.EscrowID != escrow.ID {
+		return ErrInvalidRelease
+	}
+	if env.Guardian != m.config.NodeID || !contains(escrow.Guardians, m.config.NodeID) {
+		return ErrNotGuardian
+	}
+
+	m.mu.Lock()
+	m.escrows[escrow.ID] = escrow
+	m.envelopes[escrow.ID] = env
+	m.appendLog("share_held", escrow.ID, escrow.Owner, "")
+	m.mu.Unlock()
+	return m.save()
+}
+
+// HeldEscrows 本节点作为受托方保管的托管记录
+func (m *Manager) HeldEscrows() []*Escrow {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Escrow, 0, len(m.envelopes))
+	for id := range m.envelopes {
+		result = append(result, m.escrows[id])
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+// GetEscrow 返回托管记录（请求方从任一受托节点取得后发起恢复仪式）
+func (m *Manager) GetEscrow(escrowID string) (*Escrow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	escrow, ok := m.escrows[escrowID]
+	if !ok {
+		return nil, ErrEscrowNotFound
+	}
+	return escrow, nil
+}
+
+// SubmitRequest 受托节点受理恢复请求，等待运营者确认
+// 同一托管记录在限频窗口内的请求数有上限，同一请求方同时只能有一个待确认请求。
+func (m *Manager) SubmitRequest(escrowID, requester, reason string) (*Request, error) {
+	if requester == "" {
+		return nil, ErrInvalidRelease
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	escrow, ok := m.escrows[escrowID]
+	if !ok || m.envelopes[escrowID] == nil {
+		return nil, ErrNotGuardian
+	}
+	now := m.now()
+	recent := 0
+	for _, r := range m.requests {
+		if r.EscrowID != escrowID {
+			continue
+		}
+		if now.Sub(r.CreatedAt) < m.config.RequestWindow {
+			recent++
+		}
+		if r.Requester == requester && r.Status == RequestPending && now.Sub(r.CreatedAt) < m.config.RequestTTL {
+			return nil, ErrDuplicateRequest
+		}
+	}
+	if m.config.RequestLimit > 0 && recent >= m.config.RequestLimit {
+		m.appendLog("request_rate_limited", escrowID, requester, reason)
+		m.saveLocked()
+		return nil, ErrRateLimited
+	}
+
+	req := &Request{
+		ID:        newID(escrowID+requester, now),
+		EscrowID:  escrowID,
+		Owner:     escrow.Owner,
+		Requester: requester,
+		Reason:    reason,
+		Status:    RequestPending,
+		CreatedAt: now,
+	}
+	m.requests[req.ID] = req
+	m.appendLog("request_received", escrowID, requester, reason)
+	return req, m.saveLocked()
+}
+
+// Requests 列出恢复请求（最新的在前）
+func (m *Manager) Requests() []*Request {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Request, 0, len(m.requests))
+	for _, r := range m.requests {
+		copied := *r
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Approve 运营者确认请求：把本节点的份额改用请求方公钥包裹并签名后返回
+func (m *Manager) Approve(requestID string) (*ReleasedShare, error) {
+	if m.config.WrapFunc == nil || m.config.UnwrapFunc == nil || m.config.SignFunc == nil {
+		return nil, ErrCryptoUnavail
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[requestID]
+	if !ok {
+		return nil, ErrRequestNotFound
+	}
+	if req.Status != RequestPending {
+		return nil, ErrRequestDecided
+	}
+	if m.now().Sub(req.CreatedAt) > m.config.RequestTTL {
+		return nil, ErrRequestExpired
+	}
+	env := m.envelopes[req.EscrowID]
+	if env == nil {
+		return nil, ErrNotGuardian
+	}
+
+	share, err := m.config.UnwrapFunc(env.Wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap held share: %w", err)
+	}
+	wrapped, err := m.config.WrapFunc(req.Requester, share)
+	if err != nil {
+		return nil, err
+	}
+	released := &ReleasedShare{
+		EscrowID:  req.EscrowID,
+		RequestID: req.ID,
+		Guardian:  m.config.NodeID,
+		Requester: req.Requester,
+		Index:     env.Index,
+		Wrapped:   wrapped,
+	}
+	if released.Signature, err = m.config.SignFunc(released.signingPayload()); err != nil {
+		return nil, err
+	}
+
+	req.Status = RequestApproved
+	req.DecidedAt = m.now()
+	m.appendLog("request_approved", req.EscrowID, req.Requester, req.ID)
+	return released, m.saveLocked()
+}
+
+// Reject 运营者拒绝请求
+func (m *Manager) Reject(requestID, note string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[requestID]
+	if !ok {
+		return ErrRequestNotFound
+	}
+	if req.Status != RequestPending {
+		return ErrRequestDecided
+	}
+	req.Status = RequestRejected
+	req.DecidedAt = m.now()
+	m.appendLog("request_rejected", req.EscrowID, req.Requester, note)
+	return m.saveLocked()
+}
+
+// StartCeremony 请求方以本节点（新身份）发起恢复仪式
+func (m *Manager) StartCeremony(escrow *Escrow) (*Ceremony, error) {
+	if escrow == nil || escrow.ID == "" || escrow.Threshold < 2 || len(escrow.Guardians) < escrow.Threshold {
+		return nil, ErrEscrowNotFound
+	}
+	if m.config.UnwrapFunc == nil || m.config.VerifyFunc == nil {
+		return nil, ErrCryptoUnavail
+	}
+	now := m.now()
+	c := &Ceremony{
+		ID:        newID(escrow.ID+m.config.NodeID, now),
+		Escrow:    escrow,
+		Requester: m.config.NodeID,
+		Approvals: make(map[string]*ReleasedShare),
+		Status:    CeremonyCollecting,
+		CreatedAt: now,
+	}
+
+	m.mu.Lock()
+	m.ceremonies[c.ID] = c
+	m.appendLog("ceremony_started", escrow.ID, m.config.NodeID, c.ID)
+	m.mu.Unlock()
+	return c, m.save()
+}
+
+// SubmitShare 向恢复仪式提交受托节点发回的份额，凑齐门限后还原私钥
+func (m *Manager) SubmitShare(ceremonyID string, rs *ReleasedShare) (*Ceremony, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.ceremonies[ceremonyID]
+	if !ok {
+		return nil, ErrCeremonyNotFound
+	}
+	if c.Status != CeremonyCollecting {
+		return nil, ErrCeremonyClosed
+	}
+	if rs == nil || rs.EscrowID != c.Escrow.ID || rs.Requester != c.Requester || !contains(c.Escrow.Guardians, rs.Guardian) {
+		return nil, ErrInvalidRelease
+	}
+	if ok, err := m.config.VerifyFunc(rs.Guardian, rs.signingPayload(), rs.Signature); err != nil || !ok {
+		m.appendLog("share_rejected", c.Escrow.ID, rs.Guardian, "bad signature")
+		m.saveLocked()
+		return nil, fmt.Errorf("%w: bad signature from %s", ErrInvalidRelease, rs.Guardian)
+	}
+	c.Approvals[rs.Guardian] = rs
+	m.appendLog("share_received", c.Escrow.ID, rs.Guardian, fmt.Sprintf("%d/%d", len(c.Approvals), c.Escrow.Threshold))
+
+	if len(c.Approvals) >= c.Escrow.Threshold {
+		m.reassemble(c)
+	}
+	copied := *c
+	return &copied, m.saveLocked()
+}
+
+// reassemble 还原内容密钥并解密备份，调用方持有锁
+func (m *Manager) reassemble(c *Ceremony) {
+	fail := func(err error) {
+		c.Status = CeremonyFailed
+		c.Error = err.Error()
+		c.CompletedAt = m.now()
+		m.appendLog("ceremony_failed", c.Escrow.ID, c.Requester, err.Error())
+	}
+
+	shares := make([]Share, 0, len(c.Approvals))
+	for _, rs := range c.Approvals {
+		data, err := m.config.UnwrapFunc(rs.Wrapped)
+		if err != nil {
+			fail(fmt.Errorf("unwrap share from %s: %w", rs.Guardian, err))
+			return
+		}
+		shares = append(shares, Share{Index: rs.Index, Data: data})
+	}
+	contentKey, err := CombineShares(shares)
+	if err != nil {
+		fail(err)
+		return
+	}
+	key, err := open(contentKey, c.Escrow.Ciphertext)
+	if err != nil {
+		fail(ErrRecoveryMismatch)
+		return
+	}
+	if sum := sha256.Sum256(key); hex.EncodeToString(sum[:]) != c.Escrow.KeyHash {
+		fail(ErrRecoveryMismatch)
+		return
+	}
+	c.recovered = key
+	c.Status = CeremonyCompleted
+	c.CompletedAt = m.now()
+	m.appendLog("ceremony_completed", c.Escrow.ID, c.Requester, c.ID)
+}
+
+// GetCeremony 查询恢复仪式
+func (m *Manager) GetCeremony(ceremonyID string) (*Ceremony, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.ceremonies[ceremonyID]
+	if !ok {
+		return nil, ErrCeremonyNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// RecoveredKey 返回恢复仪式还原的私钥（只保存在内存中，节点重启后需要重新提交份额）
+func (m *Manager) RecoveredKey(ceremonyID string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.ceremonies[ceremonyID]
+	if !ok {
+		return nil, ErrCeremonyNotFound
+	}
+	if c.recovered == nil {
+		return nil, ErrRecoveryIncomplete
+	}
+	return append([]byte(nil), c.recovered...), nil
+}
+
+// Log 返回恢复日志，escrowID 为空时返回全部（最新的在后）
+func (m *Manager) Log(escrowID string) []*LogEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*LogEntry, 0)
+	for _, e := range m.log {
+		if escrowID == "" || e.EscrowID == escrowID {
+			copied := *e
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+// appendLog 追加日志，调用方持有锁
+func (m *Manager) appendLog(event, escrowID, actor, detail string) {
+	m.log = append(m.log, &LogEntry{Time: m.now(), Event: event, EscrowID: escrowID, Actor: actor, Detail: detail})
+	if max := m.config.MaxLogEntries; max > 0 && len(m.log) > max {
+		m.log = m.log[len(m.log)-max:]
+	}
+}
+
+// persistState 持久化状态（还原的私钥不落盘）
+type persistState struct {
+	Escrows    map[string]*Escrow        `json:"escrows"`
+	Envelopes  map[string]*ShareEnvelope `json:"envelopes"`
+	Requests   map[string]*Request       `json:"requests"`
+	Ceremonies map[string]*Ceremony      `json:"ceremonies"`
+	Log        []*LogEntry               `json:"log"`
+}
+
+func (m *Manager) save() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.saveLocked()
+}
+
+// saveLocked 保存状态，调用方持有锁
+func (m *Manager) saveLocked() error {
+	if m.config.DataDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(&persistState{
+		Escrows:    m.escrows,
+		Envelopes:  m.envelopes,
+		Requests:   m.requests,
+		Ceremonies: m.ceremonies,
+		Log:        m.log,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.config.DataDir, "recovery.json"), data, 0600)
+}
+
+func (m *Manager) load() error {
+	if m.config.DataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "recovery.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var state persistState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Escrows != nil {
+		m.escrows = state.Escrows
+	}
+	if state.Envelopes != nil {
+		m.envelopes = state.Envelopes
+	}
+	if state.Requests != nil {
+		m.requests = state.Requests
+	}
+	if state.Ceremonies != nil {
+		m.ceremonies = state.Ceremonies
+	}
+	m.log = state.Log
+	return nil
+}
+
+func newID(seed string, now time.Time) string {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%x", seed, now.UnixNano(), nonce)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
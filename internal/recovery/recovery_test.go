@@ -0,0 +1,213 @@
+package recovery
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/crypto"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("SplitSecret failed: %v", err)
+	}
+	for _, pick := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		subset := make([]Share, 0, len(pick))
+		for _, i := range pick {
+			subset = append(subset, shares[i])
+		}
+		got, err := CombineShares(subset)
+		if err != nil || !bytes.Equal(got, secret) {
+			t.Errorf("shares %v: got %x, %v", pick, got, err)
+		}
+	}
+	if got, _ := CombineShares(shares[:2]); bytes.Equal(got, secret) {
+		t.Error("fewer than threshold shares must not reveal the secret")
+	}
+	if _, err := SplitSecret(secret, 2, 3); err != ErrInvalidShareParams {
+		t.Errorf("expected ErrInvalidShareParams, got %v", err)
+	}
+	if _, err := CombineShares([]Share{shares[0], shares[0]}); err != ErrInvalidShares {
+		t.Errorf("expected ErrInvalidShares for duplicate index, got %v", err)
+	}
+}
+
+// testNetwork 按节点名登记 ed25519 密钥的测试网络
+type testNetwork struct {
+	keys map[string]ed25519.PrivateKey
+}
+
+func (tn *testNetwork) newManager(t *testing.T, name string) *Manager {
+	t.Helper()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	tn.keys[name] = priv
+
+	config := DefaultConfig(name)
+	config.DataDir = t.TempDir()
+	config.WrapFunc = func(recipient string, data []byte) ([]byte, error) {
+		key, ok := tn.keys[recipient]
+		if !ok {
+			return nil, errors.New("unknown recipient")
+		}
+		return crypto.SealToEd25519(key.Public().(ed25519.PublicKey), data)
+	}
+	config.UnwrapFunc = func(wrapped []byte) ([]byte, error) {
+		return crypto.OpenWithEd25519(priv, wrapped)
+	}
+	config.SignFunc = func(data []byte) ([]byte, error) {
+		return ed25519.Sign(priv, data), nil
+	}
+	config.VerifyFunc = func(signerID string, data, sig []byte) (bool, error) {
+		key, ok := tn.keys[signerID]
+		return ok && ed25519.Verify(key.Public().(ed25519.PublicKey), data, sig), nil
+	}
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return m
+}
+
+func TestSocialRecovery(t *testing.T) {
+	tn := &testNetwork{keys: make(map[string]ed25519.PrivateKey)}
+	owner := tn.newManager(t, "owner")
+	guardians := map[string]*Manager{}
+	for _, g := range []string{"g1", "g2", "g3"} {
+		guardians[g] = tn.newManager(t, g)
+	}
+	nodeKey := []byte("node private key material")
+
+	if _, _, err := owner.CreateEscrow(nodeKey, []string{"g1", "owner"}, 2); !errors.Is(err, ErrInvalidGuardians) {
+		t.Errorf("self as guardian: expected ErrInvalidGuardians, got %v", err)
+	}
+	escrow, envelopes, err := owner.CreateEscrow(nodeKey, []string{"g1", "g2", "g3"}, 2)
+	if err != nil {
+		t.Fatalf("CreateEscrow failed: %v", err)
+	}
+	if bytes.Contains(escrow.Ciphertext, nodeKey) {
+		t.Fatal("escrow must not contain the plaintext key")
+	}
+	for _, env := range envelopes {
+		if err := guardians[env.Guardian].HoldShare(escrow, env); err != nil {
+			t.Fatalf("HoldShare failed: %v", err)
+		}
+	}
+	if err := guardians["g1"].HoldShare(escrow, envelopes[1]); err != ErrNotGuardian {
+		t.Errorf("holding another guardian's share: expected ErrNotGuardian, got %v", err)
+	}
+
+	// 私钥丢失后以新身份发起恢复
+	requester := tn.newManager(t, "owner-new")
+	held, _ := guardians["g2"].GetEscrow(escrow.ID)
+	ceremony, err := requester.StartCeremony(held)
+	if err != nil {
+		t.Fatalf("StartCeremony failed: %v", err)
+	}
+
+	approve := func(g string) *ReleasedShare {
+		t.Helper()
+		req, err := guardians[g].SubmitRequest(escrow.ID, "owner-new", "lost disk")
+		if err != nil {
+			t.Fatalf("SubmitRequest to %s failed: %v", g, err)
+		}
+		rs, err := guardians[g].Approve(req.ID)
+		if err != nil {
+			t.Fatalf("Approve by %s failed: %v", g, err)
+		}
+		return rs
+	}
+
+	rs1 := approve("g1")
+	forged := *rs1
+	forged.Guardian = "g3"
+	if _, err := requester.SubmitShare(ceremony.ID, &forged); !errors.Is(err, ErrInvalidRelease) {
+		t.Errorf("forged release: expected ErrInvalidRelease, got %v", err)
+	}
+	c, err := requester.SubmitShare(ceremony.ID, rs1)
+	if err != nil || c.Status != CeremonyCollecting {
+		t.Fatalf("first share: %+v, %v", c, err)
+	}
+	if _, err := requester.RecoveredKey(ceremony.ID); err != ErrRecoveryIncomplete {
+		t.Errorf("expected ErrRecoveryIncomplete, got %v", err)
+	}
+
+	c, err = requester.SubmitShare(ceremony.ID, approve("g3"))
+	if err != nil || c.Status != CeremonyCompleted {
+		t.Fatalf("second share should complete the ceremony: %+v, %v", c, err)
+	}
+	recovered, err := requester.RecoveredKey(ceremony.ID)
+	if err != nil || !bytes.Equal(recovered, nodeKey) {
+		t.Fatalf("recovered %q, %v", recovered, err)
+	}
+
+	if len(requester.Log(escrow.ID)) == 0 || len(guardians["g1"].Log(escrow.ID)) == 0 {
+		t.Error("recovery steps should be logged")
+	}
+}
+
+func TestRecoveryRequestLimits(t *testing.T) {
+	tn := &testNetwork{keys: make(map[string]ed25519.PrivateKey)}
+	owner := tn.newManager(t, "owner")
+	g1 := tn.newManager(t, "g1")
+	tn.newManager(t, "g2")
+	now := time.Now()
+	g1.now = func() time.Time { return now }
+
+	escrow, envelopes, err := owner.CreateEscrow([]byte("key"), []string{"g1", "g2"}, 2)
+	if err != nil {
+		t.Fatalf("CreateEscrow failed: %v", err)
+	}
+	g1.HoldShare(escrow, envelopes[0])
+
+	if _, err := g1.SubmitRequest("unknown", "attacker", ""); err != ErrNotGuardian {
+		t.Errorf("expected ErrNotGuardian, got %v", err)
+	}
+	first, err := g1.SubmitRequest(escrow.ID, "attacker1", "")
+	if err != nil {
+		t.Fatalf("SubmitRequest failed: %v", err)
+	}
+	if _, err := g1.SubmitRequest(escrow.ID, "attacker1", ""); err != ErrDuplicateRequest {
+		t.Errorf("expected ErrDuplicateRequest, got %v", err)
+	}
+	g1.SubmitRequest(escrow.ID, "attacker2", "")
+	g1.SubmitRequest(escrow.ID, "attacker3", "")
+	if _, err := g1.SubmitRequest(escrow.ID, "attacker4", ""); err != ErrRateLimited {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+
+	if err := g1.Reject(first.ID, "unknown requester"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if _, err := g1.Approve(first.ID); err != ErrRequestDecided {
+		t.Errorf("expected ErrRequestDecided, got %v", err)
+	}
+
+	// 窗口过后可以再次请求；过期的请求不能批准
+	now = now.Add(25 * time.Hour)
+	if _, err := g1.SubmitRequest(escrow.ID, "attacker4", ""); err != nil {
+		t.Errorf("request after window should be accepted: %v", err)
+	}
+	now = now.Add(73 * time.Hour)
+	for _, r := range g1.Requests() {
+		if r.Status == RequestPending {
+			if _, err := g1.Approve(r.ID); err != ErrRequestExpired {
+				t.Errorf("expected ErrRequestExpired, got %v", err)
+			}
+		}
+	}
+
+	// 状态落盘后可以恢复
+	reloaded, err := NewManager(g1.config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if len(reloaded.Requests()) != 4 || len(reloaded.HeldEscrows()) != 1 {
+		t.Errorf("unexpected reloaded state: %d requests, %d escrows", len(reloaded.Requests()), len(reloaded.HeldEscrows()))
+	}
+}
@@ -0,0 +1,125 @@
+package recovery
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+// Shamir 秘密分享（GF(2^8)，逐字节独立分享）
+// 秘密的每个字节作为 threshold-1 次随机多项式的常数项，份额 i 为多项式在 x=i 处的取值；
+// 任意 threshold 份通过拉格朗日插值还原常数项，少于 threshold 份得不到秘密的任何信息。
+
+var (
+	ErrInvalidShareParams = errors.New("invalid secret sharing parameters")
+	ErrInvalidShares      = errors.New("invalid or inconsistent shares")
+)
+
+// Share 秘密份额，Index 为求值点（1..255）
+type Share struct {
+	Index byte   `json:"index"`
+	Data  []byte `json:"data"`
+}
+
+var gfExp [512]byte
+var gfLog [256]byte
+
+func init() {
+	// 以 3 为生成元、AES 既约多项式 x^8+x^4+x^3+x+1 构造对数表
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		x ^= gfMulSlow(x, 2)
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+// gfMulSlow 不查表的乘法，仅用于构造对数表
+func gfMulSlow(a, b byte) byte {
+	var p byte
+	for b > 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// SplitSecret 把秘密分成 n 份，任意 threshold 份可以还原
+func SplitSecret(secret []byte, n, threshold int) ([]Share, error) {
+	if len(secret) == 0 || threshold < 2 || threshold > n || n > 255 {
+		return nil, ErrInvalidShareParams
+	}
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{Index: byte(i + 1), Data: make([]byte, len(secret))}
+	}
+	coeffs := make([]byte, threshold)
+	for pos, b := range secret {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			// 霍纳法求值
+			x, y := shares[i].Index, byte(0)
+			for k := threshold - 1; k >= 0; k-- {
+				y = gfMul(y, x) ^ coeffs[k]
+			}
+			shares[i].Data[pos] = y
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares 由份额还原秘密，份额数不足门限时得到的是错误结果而不是错误，调用方需自行校验
+func CombineShares(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrInvalidShares
+	}
+	size := len(shares[0].Data)
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.Index == 0 || seen[s.Index] || len(s.Data) != size || size == 0 {
+			return nil, ErrInvalidShares
+		}
+		seen[s.Index] = true
+	}
+
+	secret := make([]byte, size)
+	for i, si := range shares {
+		// 拉格朗日基多项式在 x=0 处的值
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = gfMul(basis, gfDiv(sj.Index, sj.Index^si.Index))
+			}
+		}
+		for pos := range secret {
+			secret[pos] ^= gfMul(si.Data[pos], basis)
+		}
+	}
+	return secret, nil
+}
@@ -0,0 +1,313 @@
+// Package release 实现发布包的打包、校验和与签名
+// 构建工具 cmd/release 用它生成各平台归档，节点的 update 命令用它校验下载的归档。
+package release
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	BinaryName    = "agentnetwork"
+	ChecksumsFile = "SHA256SUMS"
+	SignatureFile = "SHA256SUMS.sig"
+)
+
+var (
+	ErrInvalidTarget    = errors.New("invalid release target")
+	ErrInvalidChecksums = errors.New("invalid checksums file")
+	ErrChecksumMissing  = errors.New("file not listed in checksums")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrInvalidSignature = errors.New("invalid checksums signature")
+	ErrInvalidKey       = errors.New("invalid release key")
+	ErrBinaryNotFound   = errors.New("binary not found in archive")
+)
+
+// Target 目标平台
+type Target struct {
+	GOOS   string
+	GOARCH string
+}
+
+// DefaultTargets 默认发布的平台
+var DefaultTargets = []Target{
+	{"linux", "amd64"},
+	{"linux", "arm64"},
+	{"darwin", "amd64"},
+	{"darwin", "arm64"},
+	{"windows", "amd64"},
+}
+
+// String 返回 os-arch 形式的平台名
+func (t Target) String() string {
+	return t.GOOS + "-" + t.GOARCH
+}
+
+// BinaryName 目标平台的可执行文件名
+func (t Target) BinaryName() string {
+	if t.GOOS == "windows" {
+		return BinaryName + ".exe"
+	}
+	return BinaryName
+}
+
+// ArchiveName 目标平台的归档文件名（Windows 用 zip，其余用 tar.gz）
+func (t Target) ArchiveName(version string) string {
+	base := fmt.Sprintf("%s-%s-%s", BinaryName, version, t)
+	if t.GOOS == "windows" {
+		return base + ".zip"
+	}
+	return base + ".tar.gz"
+}
+
+// ParseTargets 解析逗号分隔的平台列表，如 "linux/amd64,darwin/arm64"
+func ParseTargets(s string) ([]Target, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultTargets, nil
+	}
+	var targets []Target
+	for _, item := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(item), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTarget, item)
+		}
+		targets = append(targets, Target{GOOS: parts[0], GOARCH: parts[1]})
+	}
+	return targets, nil
+}
+
+// LDFlags 生成注入版本信息的链接参数
+// pubKeyHex 非空时同时嵌入发布公钥，供 update 命令默认校验签名
+func LDFlags(version, buildTime, pubKeyHex string) string {
+	flags := fmt.Sprintf("-s -w -X main.version=%s -X main.buildTime=%s", version, buildTime)
+	if pubKeyHex != "" {
+		flags += " -X main.releasePubKey=" + pubKeyHex
+	}
+	return flags
+}
+
+// WriteArchive 将可执行文件打包为目标平台的归档
+func WriteArchive(archivePath, binaryPath string, t Target) error {
+	data, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if t.GOOS == "windows" {
+		zw := zip.NewWriter(f)
+		hdr := &zip.FileHeader{Name: t.BinaryName(), Method: zip.Deflate}
+		hdr.SetMode(0755)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	hdr := &tar.Header{
+		Name: t.BinaryName(),
+		Mode: 0755,
+		Size: int64(len(data)),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ExtractBinary 从归档中取出可执行文件内容
+func ExtractBinary(archivePath string) ([]byte, error) {
+	if strings.HasSuffix(archivePath, ".zip") {
+		zr, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if isBinaryEntry(f.Name) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(rc)
+			}
+		}
+		return nil, ErrBinaryNotFound
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, ErrBinaryNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && isBinaryEntry(hdr.Name) {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func isBinaryEntry(name string) bool {
+	base := path.Base(name)
+	return base == BinaryName || base == BinaryName+".exe"
+}
+
+// Checksums 文件名 -> SHA-256（hex）
+type Checksums map[string]string
+
+// FileChecksum 计算文件的 SHA-256
+func FileChecksum(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Marshal 按 sha256sum 格式输出（按文件名排序）
+func (c Checksums) Marshal() []byte {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", c[name], name)
+	}
+	return buf.Bytes()
+}
+
+// ParseChecksums 解析 sha256sum 格式的校验和文件
+func ParseChecksums(data []byte) (Checksums, error) {
+	sums := make(Checksums)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len(fields[0]) != sha256.Size*2 {
+			return nil, ErrInvalidChecksums
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil {
+			return nil, ErrInvalidChecksums
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if len(sums) == 0 {
+		return nil, ErrInvalidChecksums
+	}
+	return sums, nil
+}
+
+// VerifyFile 校验文件与校验和列表中同名条目一致
+func (c Checksums) VerifyFile(filePath string) error {
+	expected, ok := c[filepath.Base(filePath)]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChecksumMissing, filepath.Base(filePath))
+	}
+	actual, err := FileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, filepath.Base(filePath))
+	}
+	return nil
+}
+
+// GenerateKey 生成发布签名密钥对（hex 编码的公钥和私钥种子）
+func GenerateKey() (pubHex, seedHex string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(pub), hex.EncodeToString(priv.Seed()), nil
+}
+
+// ParsePrivateKey 解析 hex 编码的私钥种子
+func ParsePrivateKey(seedHex string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(strings.TrimSpace(seedHex))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKey 解析 hex 编码的公钥
+func ParsePublicKey(pubHex string) (ed25519.PublicKey, error) {
+	pub, err := hex.DecodeString(strings.TrimSpace(pubHex))
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(pub), nil
+}
+
+// Sign 对校验和文件签名，返回 hex 编码的签名
+func Sign(priv ed25519.PrivateKey, checksums []byte) []byte {
+	return []byte(hex.EncodeToString(ed25519.Sign(priv, checksums)) + "\n")
+}
+
+// VerifySignature 校验校验和文件的签名
+func VerifySignature(pub ed25519.PublicKey, checksums, signature []byte) error {
+	sig, err := hex.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(pub, checksums, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
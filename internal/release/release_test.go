@@ -0,0 +1,130 @@
+package release
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets("")
+	if err != nil || len(targets) != len(DefaultTargets) {
+		t.Fatalf("空列表应返回默认平台, 得到 %v, %v", targets, err)
+	}
+
+	targets, err = ParseTargets("linux/amd64, windows/arm64")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if len(targets) != 2 || targets[1] != (Target{"windows", "arm64"}) {
+		t.Errorf("解析结果错误: %v", targets)
+	}
+
+	if _, err := ParseTargets("linux"); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("期望 ErrInvalidTarget, 得到 %v", err)
+	}
+}
+
+func TestTargetNames(t *testing.T) {
+	win := Target{"windows", "amd64"}
+	if win.BinaryName() != "agentnetwork.exe" || win.ArchiveName("1.2.0") != "agentnetwork-1.2.0-windows-amd64.zip" {
+		t.Errorf("Windows 名称错误: %s, %s", win.BinaryName(), win.ArchiveName("1.2.0"))
+	}
+	linux := Target{"linux", "arm64"}
+	if linux.BinaryName() != "agentnetwork" || linux.ArchiveName("1.2.0") != "agentnetwork-1.2.0-linux-arm64.tar.gz" {
+		t.Errorf("Linux 名称错误: %s, %s", linux.BinaryName(), linux.ArchiveName("1.2.0"))
+	}
+}
+
+func TestLDFlags(t *testing.T) {
+	flags := LDFlags("1.2.0", "2026-01-02T03:04:05Z", "")
+	if !strings.Contains(flags, "-X main.version=1.2.0") || !strings.Contains(flags, "-X main.buildTime=2026-01-02T03:04:05Z") {
+		t.Errorf("链接参数错误: %s", flags)
+	}
+	if strings.Contains(flags, "releasePubKey") {
+		t.Error("未提供公钥时不应嵌入")
+	}
+	if !strings.Contains(LDFlags("1.2.0", "now", "abcd"), "-X main.releasePubKey=abcd") {
+		t.Error("应嵌入发布公钥")
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "bin")
+	content := []byte("fake binary content")
+	os.WriteFile(binary, content, 0755)
+
+	for _, target := range []Target{{"linux", "amd64"}, {"windows", "amd64"}} {
+		archive := filepath.Join(dir, target.ArchiveName("1.0.0"))
+		if err := WriteArchive(archive, binary, target); err != nil {
+			t.Fatalf("%s 打包失败: %v", target, err)
+		}
+		got, err := ExtractBinary(archive)
+		if err != nil {
+			t.Fatalf("%s 解包失败: %v", target, err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("%s 解包内容不一致", target)
+		}
+	}
+}
+
+func TestChecksumsAndSignature(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "agentnetwork-1.0.0-linux-amd64.tar.gz")
+	os.WriteFile(file, []byte("archive"), 0644)
+
+	sum, err := FileChecksum(file)
+	if err != nil {
+		t.Fatalf("计算校验和失败: %v", err)
+	}
+	data := Checksums{filepath.Base(file): sum}.Marshal()
+
+	sums, err := ParseChecksums(data)
+	if err != nil {
+		t.Fatalf("解析校验和失败: %v", err)
+	}
+	if err := sums.VerifyFile(file); err != nil {
+		t.Errorf("校验应通过: %v", err)
+	}
+
+	// 篡改文件
+	os.WriteFile(file, []byte("tampered"), 0644)
+	if err := sums.VerifyFile(file); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("期望 ErrChecksumMismatch, 得到 %v", err)
+	}
+	if err := sums.VerifyFile(filepath.Join(dir, "other.zip")); !errors.Is(err, ErrChecksumMissing) {
+		t.Errorf("期望 ErrChecksumMissing, 得到 %v", err)
+	}
+	if _, err := ParseChecksums([]byte("not a checksum\n")); err != ErrInvalidChecksums {
+		t.Errorf("期望 ErrInvalidChecksums, 得到 %v", err)
+	}
+
+	pubHex, seedHex, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	priv, err := ParsePrivateKey(seedHex)
+	if err != nil {
+		t.Fatalf("解析私钥失败: %v", err)
+	}
+	pub, err := ParsePublicKey(pubHex)
+	if err != nil {
+		t.Fatalf("解析公钥失败: %v", err)
+	}
+
+	sig := Sign(priv, data)
+	if err := VerifySignature(pub, data, sig); err != nil {
+		t.Errorf("签名校验应通过: %v", err)
+	}
+	if err := VerifySignature(pub, append(data, 'x'), sig); err != ErrInvalidSignature {
+		t.Errorf("期望 ErrInvalidSignature, 得到 %v", err)
+	}
+	if _, err := ParsePublicKey("zz"); err != ErrInvalidKey {
+		t.Errorf("期望 ErrInvalidKey, 得到 %v", err)
+	}
+}
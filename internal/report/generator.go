@@ -0,0 +1,113 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CollectFunc 收集与本节点有关的原始记录
+type CollectFunc func() *Input
+
+// DeliverFunc 定期报告生成后的投递（如发到运营者邮箱）
+type DeliverFunc func(r *Report) error
+
+// ErrInvalidPeriod 周期结束时间不晚于开始时间
+var ErrInvalidPeriod = errors.New("period end must be after start")
+
+// Generator 生成并保存报告；Run 在每周开始后补上一周的报告
+type Generator struct {
+	nodeID  string
+	store   *Store
+	collect CollectFunc
+
+	mu      sync.Mutex
+	deliver DeliverFunc
+	now     func() time.Time
+}
+
+// NewGenerator 创建报告生成器
+func NewGenerator(nodeID string, store *Store, collect CollectFunc) *Generator {
+	return &Generator{nodeID: nodeID, store: store, collect: collect, now: time.Now}
+}
+
+// Store 报告存储
+func (g *Generator) Store() *Store {
+	return g.store
+}
+
+// SetDeliverFunc 设置定期报告的投递函数，为 nil 时只保存
+func (g *Generator) SetDeliverFunc(fn DeliverFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.deliver = fn
+}
+
+// Generate 生成并保存周期 [start, end) 的报告
+func (g *Generator) Generate(start, end time.Time) (*Report, error) {
+	if !end.After(start) {
+		return nil, ErrInvalidPeriod
+	}
+	in := g.collect()
+	if in == nil {
+		in = &Input{}
+	}
+	r := Build(g.nodeID, start, end, in)
+	r.GeneratedAt = g.now().UTC()
+	if err := g.store.Save(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GenerateRecent 生成截至当前（按分钟取整）最近若干天的报告
+func (g *Generator) GenerateRecent(days int) (*Report, error) {
+	if days <= 0 {
+		days = 7
+	}
+	end := g.now().UTC().Truncate(time.Minute)
+	return g.Generate(end.AddDate(0, 0, -days), end)
+}
+
+// GenerateDue 上一个完整周的报告不存在时生成并投递；已存在时返回 nil
+func (g *Generator) GenerateDue() (*Report, error) {
+	start, end := LastCompleteWeek(g.now())
+	if g.store.Has(PeriodID(start, end)) {
+		return nil, nil
+	}
+	r, err := g.Generate(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	deliver := g.deliver
+	g.mu.Unlock()
+	if deliver != nil {
+		if err := deliver(r); err != nil {
+			return r, fmt.Errorf("failed to deliver report %s: %w", r.ID, err)
+		}
+	}
+	return r, nil
+}
+
+// Run 按 checkInterval 检查是否需要生成周报，直到 ctx 取消
+func (g *Generator) Run(ctx context.Context, checkInterval time.Duration) {
+	if checkInterval <= 0 {
+		checkInterval = time.Hour
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := g.GenerateDue(); err != nil {
+			fmt.Printf("Warning: activity report: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
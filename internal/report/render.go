@@ -0,0 +1,186 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// 报告格式
+const (
+	FormatJSON = "json"
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// ContentType 格式对应的 MIME 类型
+func ContentType(format string) string {
+	switch format {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/json"
+}
+
+// line 文本与 PDF 渲染共用的一行
+type line struct {
+	text string
+	bold bool
+}
+
+const dateLayout = "2006-01-02"
+
+func signed(v float64) string {
+	return fmt.Sprintf("%+.2f", v)
+}
+
+// lines 报告的逐行文本，每节最多列出 maxItems 条明细（<= 0 时不限）
+func (r *Report) lines(maxItems int) []line {
+	var out []line
+	add := func(bold bool, format string, args ...interface{}) {
+		out = append(out, line{text: fmt.Sprintf(format, args...), bold: bold})
+	}
+	more := func(shown, total int) {
+		if shown < total {
+			add(false, "    ... and %d more", total-shown)
+		}
+	}
+	limit := func(n int) int {
+		if maxItems > 0 && n > maxItems {
+			return maxItems
+		}
+		return n
+	}
+
+	add(true, "DAAN Node Activity Report")
+	add(false, "Node:      %s", r.NodeID)
+	add(false, "Period:    %s - %s (UTC)", r.PeriodStart.Format("2006-01-02 15:04"), r.PeriodEnd.Format("2006-01-02 15:04"))
+	add(false, "Generated: %s", r.GeneratedAt.Format(time.RFC3339))
+	add(false, "")
+
+	add(true, "Tasks")
+	add(false, "  Completed %d, reputation reward %.2f", r.Tasks.Completed, r.Tasks.RewardTotal)
+	n := limit(len(r.Tasks.Items))
+	for _, t := range r.Tasks.Items[:n] {
+		add(false, "  - %s  %s  [%s]  %s", t.Time.Format(dateLayout), t.ID, t.Status, t.Title)
+	}
+	more(n, len(r.Tasks.Items))
+	add(false, "")
+
+	add(true, "Tokens ($DAAN)")
+	add(false, "  Earned %.2f, spent %.2f, net %s, balance %.2f", r.Tokens.Earned, r.Tokens.Spent, signed(r.Tokens.Net), r.Tokens.Balance)
+	add(false, "  Minted %.2f, received %.2f, sent %.2f, slashed %.2f, locked %.2f, unlocked %.2f",
+		r.Tokens.Minted, r.Tokens.Received, r.Tokens.Sent, r.Tokens.Slashed, r.Tokens.Locked, r.Tokens.Unlocked)
+	n = limit(len(r.Tokens.Items))
+	for _, e := range r.Tokens.Items[:n] {
+		add(false, "  - %s  %-8s %s  %s %s", e.Time.Format(dateLayout), e.Type, signed(e.Amount), e.Counterparty, e.Reference)
+	}
+	more(n, len(r.Tokens.Items))
+	add(false, "")
+
+	add(true, "Reputation")
+	add(false, "  %.2f -> %.2f (%s), range %.2f - %.2f, %d changes",
+		r.Reputation.Start, r.Reputation.End, signed(r.Reputation.Change), r.Reputation.Min, r.Reputation.Max, len(r.Reputation.Points))
+	add(false, "")
+
+	add(true, "Audits")
+	add(false, "  Performed %d (passed %d, failed %d)", r.Audits.Performed, r.Audits.Passed, r.Audits.Failed)
+	n = limit(len(r.Audits.Items))
+	for _, a := range r.Audits.Items[:n] {
+		verdict := "passed"
+		if !a.Passed {
+			verdict = "failed"
+		}
+		add(false, "  - %s  %s  %s  %s", a.Time.Format(dateLayout), a.TaskID, verdict, a.Reason)
+	}
+	more(n, len(r.Audits.Items))
+	add(false, "")
+
+	add(true, "Disputes")
+	add(false, "  Involved %d (filed %d, defended %d, arbitrated %d), resolved this period %d",
+		r.Disputes.Involved, r.Disputes.Filed, r.Disputes.Defended, r.Disputes.Arbitrated, r.Disputes.Resolved)
+	n = limit(len(r.Disputes.Items))
+	for _, d := range r.Disputes.Items[:n] {
+		add(false, "  - %s  %s  %s  [%s]  amount %.2f", d.CreatedAt.Format(dateLayout), d.ID, d.Role, d.Status, d.Amount)
+	}
+	more(n, len(r.Disputes.Items))
+
+	for i := range out {
+		out[i].text = strings.TrimRight(out[i].text, " ")
+	}
+	return out
+}
+
+// RenderText 纯文本摘要（用于邮件），每节最多列出 10 条明细
+func RenderText(r *Report) string {
+	var b strings.Builder
+	for _, l := range r.lines(10) {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":   func(t time.Time) string { return t.Format(dateLayout) },
+	"time":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"num":    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"signed": signed,
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Activity report {{.ID}}</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:32px;color:#222}
+h1{font-size:22px}h2{font-size:17px;margin-top:28px;border-bottom:1px solid #ddd;padding-bottom:4px}
+table{border-collapse:collapse;margin-top:8px}td,th{border:1px solid #ddd;padding:4px 10px;font-size:13px;text-align:left}
+.meta{color:#666;font-size:13px}.pos{color:#18794e}.neg{color:#c0392b}
+</style></head><body>
+<h1>DAAN Node Activity Report</h1>
+<p class="meta">Node {{.NodeID}}<br>Period {{time .PeriodStart}} – {{time .PeriodEnd}} (UTC)<br>Generated {{time .GeneratedAt}} UTC</p>
+
+<h2>Tasks</h2>
+<p>Completed <b>{{.Tasks.Completed}}</b>, reputation reward {{num .Tasks.RewardTotal}}</p>
+{{if .Tasks.Items}}<table><tr><th>Date</th><th>Task</th><th>Title</th><th>Status</th><th>Reward</th></tr>
+{{range .Tasks.Items}}<tr><td>{{date .Time}}</td><td>{{.ID}}</td><td>{{.Title}}</td><td>{{.Status}}</td><td>{{num .Reward}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Tokens ($DAAN)</h2>
+<table>
+<tr><th>Earned</th><td>{{num .Tokens.Earned}}</td><th>Spent</th><td>{{num .Tokens.Spent}}</td><th>Net</th><td class="{{if lt .Tokens.Net 0.0}}neg{{else}}pos{{end}}">{{signed .Tokens.Net}}</td><th>Balance</th><td>{{num .Tokens.Balance}}</td></tr>
+<tr><th>Minted</th><td>{{num .Tokens.Minted}}</td><th>Received</th><td>{{num .Tokens.Received}}</td><th>Sent</th><td>{{num .Tokens.Sent}}</td><th>Slashed</th><td>{{num .Tokens.Slashed}}</td></tr>
+</table>
+{{if .Tokens.Items}}<table><tr><th>Date</th><th>Type</th><th>Amount</th><th>Counterparty</th><th>Reference</th></tr>
+{{range .Tokens.Items}}<tr><td>{{date .Time}}</td><td>{{.Type}}</td><td>{{signed .Amount}}</td><td>{{.Counterparty}}</td><td>{{.Reference}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Reputation</h2>
+<p>{{num .Reputation.Start}} → <b>{{num .Reputation.End}}</b> (<span class="{{if lt .Reputation.Change 0.0}}neg{{else}}pos{{end}}">{{signed .Reputation.Change}}</span>), range {{num .Reputation.Min}} – {{num .Reputation.Max}}</p>
+{{if .Reputation.Points}}<table><tr><th>Time</th><th>Value</th><th>Change</th><th>Reason</th></tr>
+{{range .Reputation.Points}}<tr><td>{{time .Time}}</td><td>{{num .Value}}</td><td>{{signed .Delta}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Audits</h2>
+<p>Performed <b>{{.Audits.Performed}}</b> (passed {{.Audits.Passed}}, failed {{.Audits.Failed}})</p>
+{{if .Audits.Items}}<table><tr><th>Date</th><th>Task</th><th>Verdict</th><th>Reason</th></tr>
+{{range .Audits.Items}}<tr><td>{{date .Time}}</td><td>{{.TaskID}}</td><td>{{if .Passed}}passed{{else}}failed{{end}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>{{end}}
+
+<h2>Disputes</h2>
+<p>Involved <b>{{.Disputes.Involved}}</b> (filed {{.Disputes.Filed}}, defended {{.Disputes.Defended}}, arbitrated {{.Disputes.Arbitrated}}), resolved this period {{.Disputes.Resolved}}</p>
+{{if .Disputes.Items}}<table><tr><th>Opened</th><th>Dispute</th><th>Task</th><th>Role</th><th>Status</th><th>Amount</th></tr>
+{{range .Disputes.Items}}<tr><td>{{date .CreatedAt}}</td><td>{{.ID}}</td><td>{{.TaskID}}</td><td>{{.Role}}</td><td>{{.Status}}</td><td>{{num .Amount}}</td></tr>
+{{end}}</table>{{end}}
+</body></html>
+`))
+
+// RenderHTML 渲染为独立的 HTML 页面
+func RenderHTML(r *Report) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,320 @@
+package report
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 节点活动报告
+//
+// 按周期（默认每周，从周一 00:00 UTC 起算）汇总本节点完成的任务、$DAAN 收支、声誉走势、
+// 执行的审计和参与的争议。报告以 JSON 保存在数据目录中，下载时渲染为 HTML 或 PDF，
+// 也可以把文本摘要发到运营者的邮箱。
+
+// TaskRecord 本节点作为执行方交付的任务
+type TaskRecord struct {
+	ID     string    `json:"id"`
+	Title  string    `json:"title"`
+	Status string    `json:"status"`
+	Reward float64   `json:"reward"`
+	Time   time.Time `json:"time"` // 交付或验收时间
+}
+
+// TokenRecord 一条与本节点有关的代币流水
+type TokenRecord struct {
+	Type         string    `json:"type"`   // mint, transfer, lock, unlock, slash
+	Amount       float64   `json:"amount"` // 转入、铸造为正，转出、罚没为负；锁定和解锁取正值
+	Counterparty string    `json:"counterparty,omitempty"`
+	Reference    string    `json:"reference,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// ReputationPoint 一次声誉变化后的值
+type ReputationPoint struct {
+	Time   time.Time `json:"time"`
+	Value  float64   `json:"value"`
+	Delta  float64   `json:"delta"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// AuditRecord 本节点作为审计方给出的结论
+type AuditRecord struct {
+	TaskID string    `json:"task_id"`
+	Passed bool      `json:"passed"`
+	Reason string    `json:"reason,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// 本节点在争议中的角色
+const (
+	DisputeComplainant = "complainant"
+	DisputeDefendant   = "defendant"
+	DisputeArbitrator  = "arbitrator"
+)
+
+// DisputeRecord 本节点参与的争议
+type DisputeRecord struct {
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id,omitempty"`
+	Role       string    `json:"role"`
+	Status     string    `json:"status"`
+	Amount     float64   `json:"amount"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+}
+
+// Input 生成报告的原始记录，只需包含与本节点有关的，不要求按周期过滤或排序
+type Input struct {
+	Tasks             []TaskRecord
+	Tokens            []TokenRecord
+	TokenBalance      float64
+	Reputation        []ReputationPoint
+	CurrentReputation float64
+	Audits            []AuditRecord
+	Disputes          []DisputeRecord
+}
+
+// TaskSection 任务
+type TaskSection struct {
+	Completed   int          `json:"completed"`
+	RewardTotal float64      `json:"reward_total"`
+	Items       []TaskRecord `json:"items"`
+}
+
+// TokenSection 代币收支
+type TokenSection struct {
+	Earned   float64       `json:"earned"` // 铸造 + 转入
+	Spent    float64       `json:"spent"`  // 转出 + 罚没
+	Net      float64       `json:"net"`
+	Minted   float64       `json:"minted"`
+	Received float64       `json:"received"`
+	Sent     float64       `json:"sent"`
+	Slashed  float64       `json:"slashed"`
+	Locked   float64       `json:"locked"`
+	Unlocked float64       `json:"unlocked"`
+	Balance  float64       `json:"balance"` // 生成报告时的余额
+	Items    []TokenRecord `json:"items"`
+}
+
+// ReputationSection 声誉走势
+type ReputationSection struct {
+	Start  float64           `json:"start"`
+	End    float64           `json:"end"`
+	Change float64           `json:"change"`
+	Min    float64           `json:"min"`
+	Max    float64           `json:"max"`
+	Points []ReputationPoint `json:"points"`
+}
+
+// AuditSection 审计
+type AuditSection struct {
+	Performed int           `json:"performed"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Items     []AuditRecord `json:"items"`
+}
+
+// DisputeSection 争议：周期内创建或周期开始时尚未了结的
+type DisputeSection struct {
+	Involved   int             `json:"involved"`
+	Filed      int             `json:"filed"`
+	Defended   int             `json:"defended"`
+	Arbitrated int             `json:"arbitrated"`
+	Resolved   int             `json:"resolved"` // 周期内了结的
+	Items      []DisputeRecord `json:"items"`
+}
+
+// Report 一个周期的活动报告
+type Report struct {
+	ID          string            `json:"id"`
+	NodeID      string            `json:"node_id"`
+	PeriodStart time.Time         `json:"period_start"`
+	PeriodEnd   time.Time         `json:"period_end"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Tasks       TaskSection       `json:"tasks"`
+	Tokens      TokenSection      `json:"tokens"`
+	Reputation  ReputationSection `json:"reputation"`
+	Audits      AuditSection      `json:"audits"`
+	Disputes    DisputeSection    `json:"disputes"`
+}
+
+// Summary 报告列表中的摘要
+type Summary struct {
+	ID               string    `json:"id"`
+	NodeID           string    `json:"node_id"`
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"`
+	GeneratedAt      time.Time `json:"generated_at"`
+	TasksCompleted   int       `json:"tasks_completed"`
+	TokensNet        float64   `json:"tokens_net"`
+	ReputationChange float64   `json:"reputation_change"`
+	Disputes         int       `json:"disputes"`
+}
+
+// Summary 报告的摘要
+func (r *Report) Summary() *Summary {
+	return &Summary{
+		ID:               r.ID,
+		NodeID:           r.NodeID,
+		PeriodStart:      r.PeriodStart,
+		PeriodEnd:        r.PeriodEnd,
+		GeneratedAt:      r.GeneratedAt,
+		TasksCompleted:   r.Tasks.Completed,
+		TokensNet:        r.Tokens.Net,
+		ReputationChange: r.Reputation.Change,
+		Disputes:         r.Disputes.Involved,
+	}
+}
+
+// periodIDLayout 报告 ID 中的时间格式，ID 为 起始-结束
+const periodIDLayout = "20060102T1504Z"
+
+// PeriodID 周期 [start, end) 的报告 ID
+func PeriodID(start, end time.Time) string {
+	return start.UTC().Format(periodIDLayout) + "-" + end.UTC().Format(periodIDLayout)
+}
+
+// WeekStart t 所在周的周一 00:00 UTC
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// LastCompleteWeek now 之前最近一个完整的周
+func LastCompleteWeek(now time.Time) (start, end time.Time) {
+	end = WeekStart(now)
+	return end.AddDate(0, 0, -7), end
+}
+
+func inPeriod(t, start, end time.Time) bool {
+	return !t.Before(start) && t.Before(end)
+}
+
+// Build 汇总周期 [start, end) 内的记录
+func Build(nodeID string, start, end time.Time, in *Input) *Report {
+	r := &Report{
+		ID:          PeriodID(start, end),
+		NodeID:      nodeID,
+		PeriodStart: start.UTC(),
+		PeriodEnd:   end.UTC(),
+		GeneratedAt: time.Now().UTC(),
+		Tasks:       TaskSection{Items: []TaskRecord{}},
+		Tokens:      TokenSection{Items: []TokenRecord{}, Balance: in.TokenBalance},
+		Audits:      AuditSection{Items: []AuditRecord{}},
+		Disputes:    DisputeSection{Items: []DisputeRecord{}},
+	}
+
+	for _, t := range in.Tasks {
+		if inPeriod(t.Time, start, end) {
+			r.Tasks.Items = append(r.Tasks.Items, t)
+			r.Tasks.RewardTotal += t.Reward
+		}
+	}
+	r.Tasks.Completed = len(r.Tasks.Items)
+	sort.Slice(r.Tasks.Items, func(i, j int) bool { return r.Tasks.Items[i].Time.Before(r.Tasks.Items[j].Time) })
+
+	for _, e := range in.Tokens {
+		if !inPeriod(e.Time, start, end) {
+			continue
+		}
+		r.Tokens.Items = append(r.Tokens.Items, e)
+		switch e.Type {
+		case "mint":
+			r.Tokens.Minted += e.Amount
+		case "transfer":
+			if e.Amount >= 0 {
+				r.Tokens.Received += e.Amount
+			} else {
+				r.Tokens.Sent -= e.Amount
+			}
+		case "slash":
+			r.Tokens.Slashed += math.Abs(e.Amount)
+		case "lock":
+			r.Tokens.Locked += math.Abs(e.Amount)
+		case "unlock":
+			r.Tokens.Unlocked += math.Abs(e.Amount)
+		}
+	}
+	r.Tokens.Earned = r.Tokens.Minted + r.Tokens.Received
+	r.Tokens.Spent = r.Tokens.Sent + r.Tokens.Slashed
+	r.Tokens.Net = r.Tokens.Earned - r.Tokens.Spent
+	sort.Slice(r.Tokens.Items, func(i, j int) bool { return r.Tokens.Items[i].Time.Before(r.Tokens.Items[j].Time) })
+
+	r.Reputation = buildReputation(in.Reputation, in.CurrentReputation, start, end)
+
+	for _, a := range in.Audits {
+		if !inPeriod(a.Time, start, end) {
+			continue
+		}
+		r.Audits.Items = append(r.Audits.Items, a)
+		if a.Passed {
+			r.Audits.Passed++
+		} else {
+			r.Audits.Failed++
+		}
+	}
+	r.Audits.Performed = len(r.Audits.Items)
+	sort.Slice(r.Audits.Items, func(i, j int) bool { return r.Audits.Items[i].Time.Before(r.Audits.Items[j].Time) })
+
+	for _, d := range in.Disputes {
+		if !d.CreatedAt.Before(end) || (!d.ResolvedAt.IsZero() && d.ResolvedAt.Before(start)) {
+			continue
+		}
+		r.Disputes.Items = append(r.Disputes.Items, d)
+		switch d.Role {
+		case DisputeComplainant:
+			r.Disputes.Filed++
+		case DisputeDefendant:
+			r.Disputes.Defended++
+		case DisputeArbitrator:
+			r.Disputes.Arbitrated++
+		}
+		if !d.ResolvedAt.IsZero() && inPeriod(d.ResolvedAt, start, end) {
+			r.Disputes.Resolved++
+		}
+	}
+	r.Disputes.Involved = len(r.Disputes.Items)
+	sort.Slice(r.Disputes.Items, func(i, j int) bool { return r.Disputes.Items[i].CreatedAt.Before(r.Disputes.Items[j].CreatedAt) })
+
+	return r
+}
+
+// buildReputation 周期内的声誉走势
+// 起点取周期前最后一次变化后的值，周期前没有记录时由周期内第一次变化倒推；都没有时为当前值。
+func buildReputation(points []ReputationPoint, current float64, start, end time.Time) ReputationSection {
+	sorted := append([]ReputationPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	sec := ReputationSection{Points: []ReputationPoint{}}
+	haveStart := false
+	for _, p := range sorted {
+		switch {
+		case p.Time.Before(start):
+			sec.Start = p.Value
+			haveStart = true
+		case p.Time.Before(end):
+			if !haveStart {
+				sec.Start = p.Value - p.Delta
+				haveStart = true
+			}
+			sec.Points = append(sec.Points, p)
+		}
+	}
+	if !haveStart {
+		sec.Start = current
+	}
+	sec.End = sec.Start
+	if n := len(sec.Points); n > 0 {
+		sec.End = sec.Points[n-1].Value
+	}
+	sec.Change = sec.End - sec.Start
+	sec.Min, sec.Max = sec.Start, sec.Start
+	for _, p := range sec.Points {
+		sec.Min = math.Min(sec.Min, p.Value)
+		sec.Max = math.Max(sec.Max, p.Value)
+	}
+	return sec
+}
@@ -0,0 +1,198 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	weekStart = time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC) // 周一
+	weekEnd   = weekStart.AddDate(0, 0, 7)
+)
+
+func day(n int) time.Time {
+	return weekStart.AddDate(0, 0, n).Add(10 * time.Hour)
+}
+
+func testInput() *Input {
+	return &Input{
+		Tasks: []TaskRecord{
+			{ID: "task-2", Title: "index", Status: "completed", Reward: 3, Time: day(2)},
+			{ID: "task-1", Title: "crawl", Status: "verified", Reward: 2, Time: day(0)},
+			{ID: "task-0", Status: "completed", Reward: 9, Time: day(-1)},
+		},
+		Tokens: []TokenRecord{
+			{Type: "mint", Amount: 10, Time: day(1)},
+			{Type: "transfer", Amount: 4, Counterparty: "node-b", Time: day(1)},
+			{Type: "transfer", Amount: -3, Counterparty: "node-c", Time: day(2)},
+			{Type: "slash", Amount: -1, Time: day(3)},
+			{Type: "lock", Amount: 5, Time: day(3)},
+			{Type: "mint", Amount: 100, Time: day(8)},
+		},
+		TokenBalance: 42,
+		Reputation: []ReputationPoint{
+			{Time: day(-2), Value: 50, Delta: 1},
+			{Time: day(1), Value: 53, Delta: 3},
+			{Time: day(4), Value: 48, Delta: -5},
+		},
+		CurrentReputation: 48,
+		Audits: []AuditRecord{
+			{TaskID: "task-9", Passed: true, Time: day(2)},
+			{TaskID: "task-8", Passed: false, Reason: "hash mismatch", Time: day(5)},
+		},
+		Disputes: []DisputeRecord{
+			{ID: "d-old", Role: DisputeDefendant, Status: "resolved", CreatedAt: day(-10), ResolvedAt: day(-5)},
+			{ID: "d-open", Role: DisputeDefendant, Status: "arbitration", CreatedAt: day(-3)},
+			{ID: "d-new", Role: DisputeComplainant, Status: "resolved", CreatedAt: day(1), ResolvedAt: day(3)},
+			{ID: "d-arb", Role: DisputeArbitrator, Status: "pending", CreatedAt: day(6)},
+		},
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	if got := WeekStart(time.Date(2026, 10, 11, 23, 0, 0, 0, time.UTC)); !got.Equal(weekStart) {
+		t.Errorf("WeekStart(Sunday) = %v", got)
+	}
+	if got := WeekStart(weekStart); !got.Equal(weekStart) {
+		t.Errorf("WeekStart(Monday 00:00) = %v", got)
+	}
+	start, end := LastCompleteWeek(weekEnd.Add(time.Hour))
+	if !start.Equal(weekStart) || !end.Equal(weekEnd) {
+		t.Errorf("LastCompleteWeek() = %v, %v", start, end)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r := Build("node-a", weekStart, weekEnd, testInput())
+
+	if r.ID != "20261005T0000Z-20261012T0000Z" || r.NodeID != "node-a" {
+		t.Errorf("id = %q", r.ID)
+	}
+	if r.Tasks.Completed != 2 || r.Tasks.RewardTotal != 5 || r.Tasks.Items[0].ID != "task-1" {
+		t.Errorf("tasks = %+v", r.Tasks)
+	}
+	tk := r.Tokens
+	if tk.Earned != 14 || tk.Spent != 4 || tk.Net != 10 || tk.Locked != 5 || tk.Balance != 42 || len(tk.Items) != 5 {
+		t.Errorf("tokens = %+v", tk)
+	}
+	rep := r.Reputation
+	if rep.Start != 50 || rep.End != 48 || rep.Change != -2 || rep.Min != 48 || rep.Max != 53 || len(rep.Points) != 2 {
+		t.Errorf("reputation = %+v", rep)
+	}
+	if r.Audits.Performed != 2 || r.Audits.Passed != 1 || r.Audits.Failed != 1 {
+		t.Errorf("audits = %+v", r.Audits)
+	}
+	d := r.Disputes
+	if d.Involved != 3 || d.Filed != 1 || d.Defended != 1 || d.Arbitrated != 1 || d.Resolved != 1 || d.Items[0].ID != "d-open" {
+		t.Errorf("disputes = %+v", d)
+	}
+
+	// 周期前没有声誉记录时由第一次变化倒推起点
+	in := testInput()
+	in.Reputation = in.Reputation[1:]
+	if rep := Build("node-a", weekStart, weekEnd, in).Reputation; rep.Start != 50 {
+		t.Errorf("derived start = %v", rep.Start)
+	}
+	// 没有任何变化时为当前值
+	if rep := Build("node-a", weekStart, weekEnd, &Input{CurrentReputation: 7}).Reputation; rep.Start != 7 || rep.End != 7 || rep.Change != 0 {
+		t.Errorf("empty reputation = %+v", rep)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := Build("node-a", weekStart, weekEnd, testInput())
+	r.Tasks.Items[0].Title = "爬取 (part 1)"
+
+	text := RenderText(r)
+	for _, want := range []string{"node-a", "Completed 2", "net +10.00", "50.00 -> 48.00 (-2.00)", "failed 1", "arbitrated 1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+
+	html, err := RenderHTML(r)
+	if err != nil {
+		t.Fatalf("RenderHTML() error = %v", err)
+	}
+	if !bytes.Contains(html, []byte("<td>d-new</td>")) || !bytes.Contains(html, []byte("爬取")) {
+		t.Errorf("html = %s", html)
+	}
+
+	pdf := RenderPDF(r)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("pdf header/trailer missing")
+	}
+	if !bytes.Contains(pdf, []byte(`(  - 2026-10-05  task-1  [verified]  ?? \(part 1\)) Tj`)) {
+		t.Errorf("pdf text not escaped:\n%s", pdf)
+	}
+
+	// 明细多时按页分割
+	for i := 0; i < 120; i++ {
+		r.Tokens.Items = append(r.Tokens.Items, TokenRecord{Type: "mint", Amount: 1, Time: day(1)})
+	}
+	if pdf := RenderPDF(r); !bytes.Contains(pdf, []byte("/Count 3")) {
+		t.Error("expected 3 pages")
+	}
+	if text := RenderText(r); !strings.Contains(text, "... and 115 more") {
+		t.Errorf("text items not limited:\n%s", text)
+	}
+}
+
+func TestStoreAndGenerator(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	g := NewGenerator("node-a", store, testInput)
+	g.now = func() time.Time { return weekEnd.Add(2 * time.Hour) }
+
+	var delivered []string
+	g.SetDeliverFunc(func(r *Report) error {
+		delivered = append(delivered, r.ID)
+		return nil
+	})
+	r, err := g.GenerateDue()
+	if err != nil || r == nil || r.ID != PeriodID(weekStart, weekEnd) {
+		t.Fatalf("GenerateDue() = %v, %v", r, err)
+	}
+	if r, err := g.GenerateDue(); r != nil || err != nil {
+		t.Errorf("second GenerateDue() = %v, %v", r, err)
+	}
+	if len(delivered) != 1 {
+		t.Errorf("delivered = %v", delivered)
+	}
+
+	recent, err := g.GenerateRecent(3)
+	if err != nil {
+		t.Fatalf("GenerateRecent() error = %v", err)
+	}
+	if recent.ID != "20261009T0200Z-20261012T0200Z" || recent.Audits.Performed != 1 {
+		t.Errorf("recent = %s, audits %d", recent.ID, recent.Audits.Performed)
+	}
+	if len(delivered) != 1 {
+		t.Error("on-demand reports should not be delivered")
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].ID != recent.ID || list[1].TasksCompleted != 2 {
+		t.Errorf("List() = %+v, %v", list, err)
+	}
+	if data, err := store.Render(r.ID, FormatPDF); err != nil || !bytes.HasPrefix(data, []byte("%PDF")) {
+		t.Errorf("Render(pdf) error = %v", err)
+	}
+	if _, err := store.Render(r.ID, "docx"); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("Render(docx) error = %v", err)
+	}
+	if _, err := store.Get("../../etc/passwd"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("Get(traversal) error = %v", err)
+	}
+	if _, err := store.Get("20200101T0000Z-20200108T0000Z"); !errors.Is(err, ErrReportNotFound) {
+		t.Errorf("Get(missing) error = %v", err)
+	}
+	if _, err := g.Generate(weekEnd, weekStart); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("Generate(reversed) error = %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrReportNotFound = errors.New("report not found")
+	ErrInvalidID      = errors.New("invalid report id")
+	ErrInvalidFormat  = errors.New("format must be json, html or pdf")
+)
+
+var idPattern = regexp.MustCompile(`^\d{8}T\d{4}Z-\d{8}T\d{4}Z$`)
+
+// Store 报告存储，每份报告一个 <id>.json 文件
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore 创建报告存储
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Save 保存报告，同一周期的报告被覆盖
+func (s *Store) Save(r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := filepath.Join(s.dir, r.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Has 是否已有该 ID 的报告
+func (s *Store) Has(id string) bool {
+	if !idPattern.MatchString(id) {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(s.dir, id+".json"))
+	return err == nil
+}
+
+// Get 读取报告
+func (s *Store) Get(id string) (*Report, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// List 全部报告的摘要，周期最近的在前
+func (s *Store) List() ([]*Summary, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Summary, 0, len(entries))
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), ".json")
+		if e.IsDir() || id == e.Name() || !idPattern.MatchString(id) {
+			continue
+		}
+		r, err := s.Get(id)
+		if err != nil {
+			continue
+		}
+		result = append(result, r.Summary())
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].PeriodEnd.Equal(result[j].PeriodEnd) {
+			return result[i].PeriodEnd.After(result[j].PeriodEnd)
+		}
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+// Render 按格式输出报告
+func (s *Store) Render(id, format string) ([]byte, error) {
+	r, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return Render(r, format)
+}
+
+// Render 按格式输出报告
+func Render(r *Report, format string) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return json.MarshalIndent(r, "", "  ")
+	case FormatHTML:
+		return RenderHTML(r)
+	case FormatPDF:
+		return RenderPDF(r), nil
+	}
+	return nil, ErrInvalidFormat
+}
@@ -0,0 +1,71 @@
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// 声誉证明查询
+//
+// 查询方向一个邻居打开流并发送要查询的节点ID，邻居用本地声誉表中的分数签发证明后回复。
+// 证明只陈述证明人自己的记录，查询方按证明人的资格和签名决定是否采信。
+
+// AttestationProtocol 声誉证明查询的流协议
+const AttestationProtocol = "/daan/reputation-attest/1.0.0"
+
+// maxAttestationMessage 单条证明请求或回复的大小上限
+const maxAttestationMessage = 64 << 10
+
+// attestationRequest 声誉证明请求
+type attestationRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// attestationReply 声誉证明回复，证明人拒绝签发时只有 Error
+type attestationReply struct {
+	Attestation *Attestation `json:"attestation,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// SignData 证明的签名数据
+func (a *Attestation) SignData() []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s|%d", a.NodeID,
+		strconv.FormatFloat(a.Score, 'g', -1, 64), a.Attester, a.IssuedAt.UnixNano()))
+}
+
+// RequestAttestation 在流上查询 nodeID 的声誉证明
+func RequestAttestation(rw io.ReadWriter, nodeID string) (*Attestation, error) {
+	if err := json.NewEncoder(rw).Encode(&attestationRequest{NodeID: nodeID}); err != nil {
+		return nil, err
+	}
+	var reply attestationReply
+	if err := json.NewDecoder(io.LimitReader(rw, maxAttestationMessage)).Decode(&reply); err != nil {
+		return nil, err
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	if reply.Attestation == nil || reply.Attestation.NodeID != nodeID {
+		return nil, ErrInvalidAttestation
+	}
+	return reply.Attestation, nil
+}
+
+// ServeAttestation 读取一条声誉证明请求，用 attest 签发证明并回复
+func ServeAttestation(rw io.ReadWriter, attest func(nodeID string) (*Attestation, error)) error {
+	var req attestationRequest
+	if err := json.NewDecoder(io.LimitReader(rw, maxAttestationMessage)).Decode(&req); err != nil {
+		return err
+	}
+	var reply attestationReply
+	a, err := attest(req.NodeID)
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.Attestation = a
+	}
+	return json.NewEncoder(rw).Encode(&reply)
+}
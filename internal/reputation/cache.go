@@ -0,0 +1,285 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 远端声誉查询缓存（stale-while-revalidate）
+//
+// 市场撮合时需要查询对端节点的声誉证明，远端查询慢且不稳定。缓存在新鲜期内
+// 直接返回；过了新鲜期但仍在陈旧期内时，立即返回上次的证明并在后台刷新；
+// 超出陈旧期或没有缓存时同步查询，查询失败而有旧证明时仍返回旧证明
+// （stale-if-error）。同一节点同时只有一个查询在进行，并发请求共享结果。
+
+var (
+	ErrNoFetcher          = errors.New("reputation fetcher not configured")
+	ErrInvalidAttestation = errors.New("invalid reputation attestation")
+)
+
+// Attestation 声誉证明：Attester 在 IssuedAt 时刻对 NodeID 声誉的签名陈述
+type Attestation struct {
+	NodeID    string    `json:"node_id"`
+	Score     float64   `json:"score"`
+	Attester  string    `json:"attester"`
+	IssuedAt  time.Time `json:"issued_at"`
+	Signature []byte    `json:"signature,omitempty"`
+}
+
+// AttestationFetcher 向远端查询节点的声誉证明
+type AttestationFetcher func(ctx context.Context, nodeID string) (*Attestation, error)
+
+// AttestationVerifier 校验声誉证明（签名、证明人资格等），未通过的证明不进入缓存
+type AttestationVerifier func(a *Attestation) error
+
+// CacheConfig 缓存配置
+type CacheConfig struct {
+	FreshFor     time.Duration // 新鲜期：直接返回缓存
+	StaleFor     time.Duration // 新鲜期之后的陈旧期：返回缓存并后台刷新
+	FetchTimeout time.Duration // 后台刷新的超时
+	MaxEntries   int           // 最多缓存的节点数，超出时淘汰最久未刷新的
+}
+
+// DefaultCacheConfig 返回默认缓存配置
+func DefaultCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		FreshFor:     time.Minute,
+		StaleFor:     30 * time.Minute,
+		FetchTimeout: 5 * time.Second,
+		MaxEntries:   4096,
+	}
+}
+
+// CachedScore 一次查询的结果
+type CachedScore struct {
+	Attestation
+	CacheAgeMs int64  `json:"cache_age_ms"`         // 证明取回至今的时长
+	Stale      bool   `json:"stale"`                // 已过新鲜期
+	Refreshing bool   `json:"refreshing"`           // 后台刷新进行中
+	LastError  string `json:"last_error,omitempty"` // 最近一次刷新失败的原因
+}
+
+// CacheStats 缓存统计
+type CacheStats struct {
+	Entries       int   `json:"entries"`
+	Hits          int64 `json:"hits"`
+	StaleHits     int64 `json:"stale_hits"`
+	Misses        int64 `json:"misses"`
+	Refreshes     int64 `json:"refreshes"`
+	RefreshErrors int64 `json:"refresh_errors"`
+}
+
+type cacheEntry struct {
+	att       *Attestation
+	fetchedAt time.Time
+	lastError string
+}
+
+// fetchCall 进行中的查询，并发请求等待同一个结果
+type fetchCall struct {
+	done chan struct{}
+	att  *Attestation
+	err  error
+}
+
+// Cache 远端声誉证明缓存
+type Cache struct {
+	mu       sync.Mutex
+	config   CacheConfig
+	fetch    AttestationFetcher
+	verify   AttestationVerifier
+	entries  map[string]*cacheEntry
+	inflight map[string]*fetchCall
+	stats    CacheStats
+	now      func() time.Time
+}
+
+// NewCache 创建缓存，config 为 nil 时使用默认配置
+func NewCache(config *CacheConfig, fetch AttestationFetcher) *Cache {
+	if config == nil {
+		config = DefaultCacheConfig()
+	}
+	return &Cache{
+		config:   *config,
+		fetch:    fetch,
+		entries:  make(map[string]*cacheEntry),
+		inflight: make(map[string]*fetchCall),
+		now:      time.Now,
+	}
+}
+
+// SetVerifier 设置证明校验函数
+func (c *Cache) SetVerifier(fn AttestationVerifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.verify = fn
+}
+
+// Get 查询节点声誉
+// 新鲜期内直接返回；陈旧期内返回旧证明并触发后台刷新；否则同步查询。
+func (c *Cache) Get(ctx context.Context, nodeID string) (*CachedScore, error) {
+	c.mu.Lock()
+	entry := c.entries[nodeID]
+	if entry != nil {
+		age := c.now().Sub(entry.fetchedAt)
+		if age < c.config.FreshFor {
+			c.stats.Hits++
+			result := c.resultLocked(nodeID, entry)
+			c.mu.Unlock()
+			return result, nil
+		}
+		if age < c.config.FreshFor+c.config.StaleFor {
+			c.stats.StaleHits++
+			c.startFetchLocked(nodeID)
+			result := c.resultLocked(nodeID, entry)
+			c.mu.Unlock()
+			return result, nil
+		}
+	}
+	c.stats.Misses++
+	call := c.startFetchLocked(nodeID)
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return c.fallback(nodeID, ctx.Err())
+	}
+	if call.err != nil {
+		return c.fallback(nodeID, call.err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.entries[nodeID]; entry != nil {
+		return c.resultLocked(nodeID, entry), nil
+	}
+	// 查询完成后条目已被 Invalidate
+	return &CachedScore{Attestation: *call.att}, nil
+}
+
+// Peek 只读缓存，不触发查询
+func (c *Cache) Peek(nodeID string) (*CachedScore, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entries[nodeID]
+	if entry == nil {
+		return nil, false
+	}
+	return c.resultLocked(nodeID, entry), true
+}
+
+// Invalidate 删除节点的缓存（例如收到该节点的声誉变更事件后）
+func (c *Cache) Invalidate(nodeID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, nodeID)
+}
+
+// Stats 返回缓存统计
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// fallback 同步查询失败时返回旧证明（不论多旧），没有旧证明则返回错误
+func (c *Cache) fallback(nodeID string, err error) (*CachedScore, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entries[nodeID]
+	if entry == nil {
+		return nil, err
+	}
+	result := c.resultLocked(nodeID, entry)
+	result.LastError = err.Error()
+	return result, nil
+}
+
+// startFetchLocked 启动后台查询，已有查询进行中时复用（调用方持有锁）
+func (c *Cache) startFetchLocked(nodeID string) *fetchCall {
+	if call, ok := c.inflight[nodeID]; ok {
+		return call
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	c.inflight[nodeID] = call
+	c.stats.Refreshes++
+	fetch, verify := c.fetch, c.verify
+	go func() {
+		att, err := c.runFetch(fetch, verify, nodeID)
+		c.mu.Lock()
+		delete(c.inflight, nodeID)
+		if err != nil {
+			c.stats.RefreshErrors++
+			if entry := c.entries[nodeID]; entry != nil {
+				entry.lastError = err.Error()
+			}
+		} else {
+			c.storeLocked(nodeID, att)
+		}
+		call.att, call.err = att, err
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+func (c *Cache) runFetch(fetch AttestationFetcher, verify AttestationVerifier, nodeID string) (*Attestation, error) {
+	if fetch == nil {
+		return nil, ErrNoFetcher
+	}
+	ctx := context.Background()
+	if c.config.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.FetchTimeout)
+		defer cancel()
+	}
+	att, err := fetch(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if att == nil || att.NodeID != nodeID {
+		return nil, ErrInvalidAttestation
+	}
+	if verify != nil {
+		if err := verify(att); err != nil {
+			return nil, err
+		}
+	}
+	return att, nil
+}
+
+// storeLocked 写入缓存并按容量淘汰（调用方持有锁）
+func (c *Cache) storeLocked(nodeID string, att *Attestation) {
+	c.entries[nodeID] = &cacheEntry{att: att, fetchedAt: c.now()}
+	if c.config.MaxEntries <= 0 || len(c.entries) <= c.config.MaxEntries {
+		return
+	}
+	oldestID := ""
+	var oldest time.Time
+	for id, entry := range c.entries {
+		if id == nodeID {
+			continue
+		}
+		if oldestID == "" || entry.fetchedAt.Before(oldest) {
+			oldestID, oldest = id, entry.fetchedAt
+		}
+	}
+	delete(c.entries, oldestID)
+}
+
+func (c *Cache) resultLocked(nodeID string, entry *cacheEntry) *CachedScore {
+	age := c.now().Sub(entry.fetchedAt)
+	_, refreshing := c.inflight[nodeID]
+	return &CachedScore{
+		Attestation: *entry.att,
+		CacheAgeMs:  age.Milliseconds(),
+		Stale:       age >= c.config.FreshFor,
+		Refreshing:  refreshing,
+		LastError:   entry.lastError,
+	}
+}
@@ -0,0 +1,215 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(fetch AttestationFetcher) (*Cache, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	cache := NewCache(&CacheConfig{FreshFor: time.Minute, StaleFor: 10 * time.Minute, FetchTimeout: time.Second, MaxEntries: 2}, fetch)
+	cache.now = clock.Now
+	return cache, clock
+}
+
+// waitRefresh 等待后台刷新结束
+func waitRefresh(t *testing.T, c *Cache, nodeID string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		_, busy := c.inflight[nodeID]
+		c.mu.Unlock()
+		if !busy {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("background refresh did not finish")
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var score atomic.Int64
+	score.Store(60)
+	var fail atomic.Bool
+	cache, clock := newTestCache(func(ctx context.Context, nodeID string) (*Attestation, error) {
+		if fail.Load() {
+			return nil, errors.New("peer unreachable")
+		}
+		return &Attestation{NodeID: nodeID, Score: float64(score.Load()), Attester: "witness"}, nil
+	})
+	ctx := context.Background()
+
+	got, err := cache.Get(ctx, "node-a")
+	if err != nil || got.Score != 60 || got.Stale || got.CacheAgeMs != 0 {
+		t.Fatalf("first Get() = %+v, %v", got, err)
+	}
+
+	// 新鲜期内不重新查询
+	score.Store(70)
+	clock.Advance(30 * time.Second)
+	got, _ = cache.Get(ctx, "node-a")
+	if got.Score != 60 || got.Stale || got.CacheAgeMs != 30000 {
+		t.Errorf("fresh Get() = %+v", got)
+	}
+
+	// 陈旧期内立即返回旧值，后台刷新
+	clock.Advance(time.Minute)
+	got, _ = cache.Get(ctx, "node-a")
+	if got.Score != 60 || !got.Stale {
+		t.Errorf("stale Get() = %+v, want old score marked stale", got)
+	}
+	waitRefresh(t, cache, "node-a")
+	got, _ = cache.Get(ctx, "node-a")
+	if got.Score != 70 || got.Stale {
+		t.Errorf("Get() after refresh = %+v, want refreshed score", got)
+	}
+
+	// 后台刷新失败时保留旧值并记录原因
+	fail.Store(true)
+	clock.Advance(2 * time.Minute)
+	cache.Get(ctx, "node-a")
+	waitRefresh(t, cache, "node-a")
+	got, _ = cache.Get(ctx, "node-a")
+	if got.Score != 70 || got.LastError == "" {
+		t.Errorf("Get() after failed refresh = %+v", got)
+	}
+	waitRefresh(t, cache, "node-a")
+
+	// 超出陈旧期同步查询，失败时仍返回旧证明
+	clock.Advance(time.Hour)
+	got, err = cache.Get(ctx, "node-a")
+	if err != nil || got.Score != 70 || !got.Stale || got.LastError == "" {
+		t.Errorf("expired Get() = %+v, %v, want stale-if-error", got, err)
+	}
+	if _, err := cache.Get(ctx, "node-b"); err == nil {
+		t.Error("expected error for uncached node when fetch fails")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.StaleHits != 3 || stats.Misses != 3 || stats.RefreshErrors != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCacheSharesInflightFetch(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	cache, _ := newTestCache(func(ctx context.Context, nodeID string) (*Attestation, error) {
+		calls.Add(1)
+		<-release
+		return &Attestation{NodeID: nodeID, Score: 42}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := cache.Get(context.Background(), "node-a"); err != nil || got.Score != 42 {
+				t.Errorf("Get() = %+v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("fetch called %d times, want 1", calls.Load())
+	}
+}
+
+func TestCacheVerifierAndEviction(t *testing.T) {
+	cache, clock := newTestCache(func(ctx context.Context, nodeID string) (*Attestation, error) {
+		return &Attestation{NodeID: nodeID, Score: 10, Signature: []byte(nodeID)}, nil
+	})
+	cache.SetVerifier(func(a *Attestation) error {
+		if a.NodeID == "forged" {
+			return ErrInvalidAttestation
+		}
+		return nil
+	})
+	ctx := context.Background()
+
+	if _, err := cache.Get(ctx, "forged"); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("expected ErrInvalidAttestation, got %v", err)
+	}
+	if _, ok := cache.Peek("forged"); ok {
+		t.Error("rejected attestation should not be cached")
+	}
+
+	// 容量为 2，写入第三个时淘汰最久未刷新的
+	for _, id := range []string{"a", "b", "c"} {
+		cache.Get(ctx, id)
+		clock.Advance(time.Second)
+	}
+	if _, ok := cache.Peek("a"); ok {
+		t.Error("oldest entry should be evicted")
+	}
+	if _, ok := cache.Peek("c"); !ok {
+		t.Error("newest entry should be cached")
+	}
+
+	cache.Invalidate("c")
+	if _, ok := cache.Peek("c"); ok {
+		t.Error("invalidated entry should be removed")
+	}
+}
+
+func TestAttestationExchange(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	serve := func(attest func(nodeID string) (*Attestation, error)) io.ReadWriter {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			ServeAttestation(server, attest)
+		}()
+		return client
+	}
+
+	a, err := RequestAttestation(serve(func(nodeID string) (*Attestation, error) {
+		return &Attestation{NodeID: nodeID, Score: 42.5, Attester: "bob", IssuedAt: issued}, nil
+	}), "carol")
+	if err != nil || a.Score != 42.5 || a.Attester != "bob" || !a.IssuedAt.Equal(issued) {
+		t.Fatalf("attestation = %+v, %v", a, err)
+	}
+	if want := "carol|42.5|bob|1700000000000000000"; string(a.SignData()) != want {
+		t.Errorf("sign data = %q, want %q", a.SignData(), want)
+	}
+
+	// 证明人拒绝签发时返回其错误，答非所问的证明被拒绝
+	if _, err := RequestAttestation(serve(func(string) (*Attestation, error) {
+		return nil, errors.New("unknown node")
+	}), "carol"); err == nil || err.Error() != "unknown node" {
+		t.Errorf("refusal = %v", err)
+	}
+	if _, err := RequestAttestation(serve(func(string) (*Attestation, error) {
+		return &Attestation{NodeID: "mallory", Attester: "bob"}, nil
+	}), "carol"); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("mismatched attestation = %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package reputation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 声誉防振荡
+//
+// 一阵集中的奖励或惩罚会让声誉大起大落。启用阻尼后，Manager 把增量累加到原始分数（Raw），
+// 生效分数（Score）不再直接等于原始分数：先向原始分数做指数移动平均（EMA），再限制每个
+// 统计周期内的累计变化，最后裁剪到 Min/Max。各模块读取的都是生效分数，原始分数和当前
+// 统计周期可通过 Detail 核对。Set 是管理操作，同时设置两者，不经阻尼。
+
+var ErrInvalidDamping = errors.New("invalid damping config")
+
+// DampingConfig 阻尼配置
+type DampingConfig struct {
+	Smoothing float64       // EMA 系数 (0, 1]，越小越平滑，1 表示不平滑
+	MaxChange float64       // 每个统计周期内生效分数的最大累计变化，0 表示不限
+	Interval  time.Duration // 变化上限的统计周期
+}
+
+// DefaultDampingConfig 返回默认阻尼配置
+func DefaultDampingConfig() *DampingConfig {
+	return &DampingConfig{
+		Smoothing: 0.3,
+		MaxChange: 100,
+		Interval:  time.Hour,
+	}
+}
+
+func (c *DampingConfig) validate() error {
+	switch {
+	case c.Smoothing <= 0 || c.Smoothing > 1:
+		return fmt.Errorf("%w: smoothing must be in (0, 1]", ErrInvalidDamping)
+	case c.MaxChange < 0:
+		return fmt.Errorf("%w: max change must not be negative", ErrInvalidDamping)
+	case c.MaxChange > 0 && c.Interval <= 0:
+		return fmt.Errorf("%w: max change needs an interval", ErrInvalidDamping)
+	}
+	return nil
+}
+
+// ScoreDetail 原始分数与生效分数
+type ScoreDetail struct {
+	NodeID       string    `json:"node_id"`
+	Raw          float64   `json:"raw"`    // 增量直接累加的分数
+	Damped       float64   `json:"damped"` // 阻尼后生效的分数，未启用阻尼时等于 Raw
+	Min          float64   `json:"min"`
+	Max          float64   `json:"max"`
+	Damping      bool      `json:"damping"`
+	WindowStart  time.Time `json:"window_start,omitempty"` // 当前统计周期的起点
+	WindowChange float64   `json:"window_change"`          // 本周期内生效分数已发生的变化
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SetDamping 设置阻尼，nil 表示关闭；已有节点的生效分数从当前原始分数重新开始
+func (m *Manager) SetDamping(config *DampingConfig) error {
+	if config != nil {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		copied := *config
+		config = &copied
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.Damping = config
+	for _, s := range m.scores {
+		if s.Score != s.Raw {
+			s.Score = s.Raw
+			m.dirty = true
+		}
+		s.windowStart = time.Time{}
+	}
+	return nil
+}
+
+// RawReputation 获取未经阻尼的原始分数，未记录的节点返回初始值
+func (m *Manager) RawReputation(nodeID string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.scores[nodeID]; ok {
+		return s.Raw
+	}
+	return m.config.Initial
+}
+
+// Detail 获取节点的原始分数、生效分数及当前统计周期
+func (m *Manager) Detail(nodeID string) (*ScoreDetail, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.scores[nodeID]
+	if !ok {
+		return nil, false
+	}
+	detail := &ScoreDetail{
+		NodeID:      nodeID,
+		Raw:         s.Raw,
+		Damped:      s.Score,
+		Min:         m.config.Min,
+		Max:         m.config.Max,
+		Damping:     m.config.Damping != nil,
+		WindowStart: s.windowStart,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if !s.windowStart.IsZero() {
+		detail.WindowChange = s.Score - s.windowBase
+	}
+	return detail, true
+}
+
+// dampLocked 由新的原始分数推进生效分数并返回：EMA → 周期变化上限 → 上下限（调用方持有写锁）
+func (m *Manager) dampLocked(s *Score, now time.Time) float64 {
+	d := m.config.Damping
+	if d == nil {
+		return s.Raw
+	}
+
+	if s.windowStart.IsZero() || (d.Interval > 0 && now.Sub(s.windowStart) >= d.Interval) {
+		s.windowStart = now
+		s.windowBase = s.Score
+	}
+	damped := s.Score + d.Smoothing*(s.Raw-s.Score)
+	if d.MaxChange > 0 {
+		damped = math.Max(s.windowBase-d.MaxChange, math.Min(s.windowBase+d.MaxChange, damped))
+	}
+	return m.clip(damped)
+}
@@ -0,0 +1,318 @@
+package reputation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 声誉快照增量同步
+//
+// 邻居之间传播声誉表时，每次发送完整快照在稳定网络中大部分是重复数据。
+// 发送端给每次有效变化编版本号，记录每个节点确认过的版本，之后只发送该版本
+// 之后变化的条目；条目携带的是当前分数而非差值，因此基准版本不高于接收端
+// 当前版本的增量都可以安全应用，重复或乱序的增量不会破坏状态。
+// 以下情况改发完整快照：对端从未确认、对端确认的是发送端重启前的代次、
+// 对端落后太多（删除记录已被清理），或距上次给该节点发完整快照超过 FullEvery。
+
+var (
+	// ErrDeltaBaseMismatch 增量的基准与本地状态不符，需要对端重发完整快照
+	ErrDeltaBaseMismatch = errors.New("reputation delta base does not match local snapshot")
+)
+
+// ScoreSyncMessage 一次声誉同步消息（完整快照或增量）
+type ScoreSyncMessage struct {
+	Generation  string             `json:"generation"`             // 发送端代次，重启后变化
+	Full        bool               `json:"full"`                   // 完整快照
+	BaseVersion uint64             `json:"base_version,omitempty"` // 增量的基准版本
+	Version     uint64             `json:"version"`                // 应用后到达的版本
+	Scores      map[string]float64 `json:"scores,omitempty"`       // 新增或变化的分数（完整快照为全部）
+	Removed     []string           `json:"removed,omitempty"`      // 基准版本之后移除的节点
+}
+
+// DeltaConfig 增量同步配置
+type DeltaConfig struct {
+	Epsilon    float64       // 分数变化小于该值不视为变化，避免衰减带来的细碎更新
+	FullEvery  time.Duration // 对同一节点至少每隔该时长发送一次完整快照，用于纠正漂移
+	MaxHistory uint64        // 删除记录保留的版本数，对端落后更多时只能发完整快照
+}
+
+// DefaultDeltaConfig 返回默认配置
+func DefaultDeltaConfig() *DeltaConfig {
+	return &DeltaConfig{
+		Epsilon:    0.001,
+		FullEvery:  30 * time.Minute,
+		MaxHistory: 1024,
+	}
+}
+
+// DeltaStats 发送统计
+type DeltaStats struct {
+	Version      uint64 `json:"version"`
+	Entries      int    `json:"entries"`
+	Peers        int    `json:"peers"`
+	FullSent     int64  `json:"full_sent"`
+	DeltaSent    int64  `json:"delta_sent"`
+	EntriesSent  int64  `json:"entries_sent"`  // 实际发送的条目数（含删除）
+	EntriesSaved int64  `json:"entries_saved"` // 与每次都发完整快照相比少发的条目数
+}
+
+type peerSyncState struct {
+	acked    uint64
+	hasAck   bool
+	lastFull time.Time
+}
+
+// DeltaPublisher 声誉快照发送端
+type DeltaPublisher struct {
+	mu         sync.Mutex
+	config     *DeltaConfig
+	generation string
+	version    uint64
+
+	scores  map[string]float64
+	changed map[string]uint64 // 节点分数最近一次变化的版本
+	removed map[string]uint64 // 删除记录：节点 -> 删除时的版本
+	pruned  uint64            // 早于该版本的删除记录已清理，更早的基准无法生成增量
+
+	peers map[string]*peerSyncState
+	stats DeltaStats
+
+	now func() time.Time
+}
+
+// NewDeltaPublisher 创建发送端，每个实例使用新的代次
+func NewDeltaPublisher(config *DeltaConfig) *DeltaPublisher {
+	if config == nil {
+		config = DefaultDeltaConfig()
+	}
+	gen := make([]byte, 8)
+	rand.Read(gen)
+	return &DeltaPublisher{
+		config:     config,
+		generation: hex.EncodeToString(gen),
+		scores:     make(map[string]float64),
+		changed:    make(map[string]uint64),
+		removed:    make(map[string]uint64),
+		peers:      make(map[string]*peerSyncState),
+		now:        time.Now,
+	}
+}
+
+// Update 用当前完整的声誉表（如 System.GetAllScores）更新快照
+// 有效变化时版本号加一，返回当前版本。
+func (p *DeltaPublisher) Update(scores map[string]float64) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := p.version + 1
+	dirty := false
+	for id, score := range scores {
+		old, ok := p.scores[id]
+		if ok && math.Abs(score-old) < p.config.Epsilon {
+			continue
+		}
+		p.scores[id] = score
+		p.changed[id] = next
+		delete(p.removed, id)
+		dirty = true
+	}
+	for id := range p.scores {
+		if _, ok := scores[id]; !ok {
+			delete(p.scores, id)
+			delete(p.changed, id)
+			p.removed[id] = next
+			dirty = true
+		}
+	}
+	if !dirty {
+		return p.version
+	}
+	p.version = next
+
+	if p.config.MaxHistory > 0 && p.version > p.config.MaxHistory {
+		floor := p.version - p.config.MaxHistory
+		for id, v := range p.removed {
+			if v <= floor {
+				delete(p.removed, id)
+			}
+		}
+		if floor > p.pruned {
+			p.pruned = floor
+		}
+	}
+	return p.version
+}
+
+// Prepare 生成发给 peerID 的同步消息，对端已是最新且不需要完整快照时返回 nil
+// 消息发出后等待对端 Ack；未确认前再次调用会从同一基准重新生成。
+func (p *DeltaPublisher) Prepare(peerID string) *ScoreSyncMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	st, ok := p.peers[peerID]
+	if !ok {
+		st = &peerSyncState{}
+		p.peers[peerID] = st
+	}
+
+	needFull := !st.hasAck || st.acked < p.pruned ||
+		(p.config.FullEvery > 0 && now.Sub(st.lastFull) >= p.config.FullEvery)
+	if needFull {
+		msg := &ScoreSyncMessage{
+			Generation: p.generation,
+			Full:       true,
+			Version:    p.version,
+			Scores:     make(map[string]float64, len(p.scores)),
+		}
+		for id, score := range p.scores {
+			msg.Scores[id] = score
+		}
+		st.lastFull = now
+		p.stats.FullSent++
+		p.stats.EntriesSent += int64(len(msg.Scores))
+		return msg
+	}
+	if st.acked == p.version {
+		p.stats.EntriesSaved += int64(len(p.scores))
+		return nil
+	}
+
+	msg := &ScoreSyncMessage{
+		Generation:  p.generation,
+		BaseVersion: st.acked,
+		Version:     p.version,
+		Scores:      make(map[string]float64),
+	}
+	for id, v := range p.changed {
+		if v > st.acked {
+			msg.Scores[id] = p.scores[id]
+		}
+	}
+	for id, v := range p.removed {
+		if v > st.acked {
+			msg.Removed = append(msg.Removed, id)
+		}
+	}
+	sort.Strings(msg.Removed)
+	sent := len(msg.Scores) + len(msg.Removed)
+	p.stats.DeltaSent++
+	p.stats.EntriesSent += int64(sent)
+	if saved := len(p.scores) - sent; saved > 0 {
+		p.stats.EntriesSaved += int64(saved)
+	}
+	return msg
+}
+
+// Ack 记录对端确认的代次和版本（接收端 DeltaReplica.State 的返回值）
+// 代次不符或版本超前时下次发送完整快照。
+func (p *DeltaPublisher) Ack(peerID, generation string, version uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st, ok := p.peers[peerID]
+	if !ok {
+		st = &peerSyncState{}
+		p.peers[peerID] = st
+	}
+	if generation != p.generation || version > p.version {
+		st.hasAck = false
+		return
+	}
+	if !st.hasAck || version > st.acked {
+		st.acked = version
+	}
+	st.hasAck = true
+}
+
+// ForgetPeer 移除对端的同步状态（断开连接后调用），重连后从完整快照开始
+func (p *DeltaPublisher) ForgetPeer(peerID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.peers, peerID)
+}
+
+// Stats 返回发送统计
+func (p *DeltaPublisher) Stats() DeltaStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Version = p.version
+	stats.Entries = len(p.scores)
+	stats.Peers = len(p.peers)
+	return stats
+}
+
+// DeltaReplica 声誉快照接收端，保存某个邻居的声誉表
+type DeltaReplica struct {
+	mu         sync.RWMutex
+	generation string
+	version    uint64
+	scores     map[string]float64
+}
+
+// NewDeltaReplica 创建空的接收端
+func NewDeltaReplica() *DeltaReplica {
+	return &DeltaReplica{scores: make(map[string]float64)}
+}
+
+// Apply 应用同步消息
+// 增量的代次与本地不同或基准版本高于本地版本时返回 ErrDeltaBaseMismatch，
+// 调用方把 State 回给发送端即可触发完整快照。旧版本的消息被忽略。
+func (r *DeltaReplica) Apply(msg *ScoreSyncMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if msg.Full {
+		if msg.Generation == r.generation && msg.Version < r.version {
+			return nil
+		}
+		r.generation = msg.Generation
+		r.version = msg.Version
+		r.scores = make(map[string]float64, len(msg.Scores))
+		for id, score := range msg.Scores {
+			r.scores[id] = score
+		}
+		return nil
+	}
+
+	if msg.Generation != r.generation || msg.BaseVersion > r.version {
+		return ErrDeltaBaseMismatch
+	}
+	if msg.Version <= r.version {
+		return nil
+	}
+	for id, score := range msg.Scores {
+		r.scores[id] = score
+	}
+	for _, id := range msg.Removed {
+		delete(r.scores, id)
+	}
+	r.version = msg.Version
+	return nil
+}
+
+// State 返回本地快照的代次和版本，作为对发送端的确认
+func (r *DeltaReplica) State() (generation string, version uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.generation, r.version
+}
+
+// Scores 返回声誉表副本
+func (r *DeltaReplica) Scores() map[string]float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scores := make(map[string]float64, len(r.scores))
+	for id, score := range r.scores {
+		scores[id] = score
+	}
+	return scores
+}
@@ -0,0 +1,155 @@
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+// 邻居间的声誉推送
+//
+// 发起方定期用本地声誉表更新快照，对每个邻居取 Prepare 生成的完整快照或增量，
+// 对端已是最新时不打开流。对端把消息应用到以连接对端为键的副本后回复副本的代次和
+// 版本，发起方据此 Ack；增量基准不符时对端照常回复，发起方下次改发完整快照。
+// 每个邻居的副本只记录该邻居自己的声誉表，不写入本地声誉。
+
+// DeltaSyncProtocol 声誉推送的流协议
+const DeltaSyncProtocol = "/daan/reputation-sync/1.0.0"
+
+// maxSyncMessage 单条同步消息的大小上限
+const maxSyncMessage = 4 << 20
+
+// syncAck 接收端对同步消息的确认
+type syncAck struct {
+	Generation string `json:"generation"`
+	Version    uint64 `json:"version"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DeltaSync 声誉推送：本节点的发送端和各邻居推送来的副本
+type DeltaSync struct {
+	publisher *DeltaPublisher
+
+	mu       sync.RWMutex
+	replicas map[string]*DeltaReplica
+}
+
+// NewDeltaSync 创建声誉推送，config 为 nil 时使用默认配置
+func NewDeltaSync(config *DeltaConfig) *DeltaSync {
+	return &DeltaSync{
+		publisher: NewDeltaPublisher(config),
+		replicas:  make(map[string]*DeltaReplica),
+	}
+}
+
+// Update 用本地完整的声誉表更新待推送的快照
+func (s *DeltaSync) Update(scores map[string]float64) uint64 {
+	return s.publisher.Update(scores)
+}
+
+// Push 把快照推送给 peerID，对端已是最新时不调用 open
+func (s *DeltaSync) Push(peerID string, open func() (io.ReadWriteCloser, error)) error {
+	msg := s.publisher.Prepare(peerID)
+	if msg == nil {
+		return nil
+	}
+	rw, err := open()
+	if err != nil {
+		return err
+	}
+	defer rw.Close()
+
+	if err := json.NewEncoder(rw).Encode(msg); err != nil {
+		return err
+	}
+	var ack syncAck
+	if err := json.NewDecoder(io.LimitReader(rw, maxSyncMessage)).Decode(&ack); err != nil {
+		return err
+	}
+	s.publisher.Ack(peerID, ack.Generation, ack.Version)
+	if ack.Error != "" {
+		return errors.New(ack.Error)
+	}
+	return nil
+}
+
+// HandlePush 处理 from 推送来的同步消息并回复副本状态，from 由连接决定
+func (s *DeltaSync) HandlePush(rw io.ReadWriter, from string) error {
+	var msg ScoreSyncMessage
+	if err := json.NewDecoder(io.LimitReader(rw, maxSyncMessage)).Decode(&msg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	replica, ok := s.replicas[from]
+	if !ok {
+		replica = NewDeltaReplica()
+		s.replicas[from] = replica
+	}
+	s.mu.Unlock()
+
+	applyErr := replica.Apply(&msg)
+	ack := syncAck{}
+	ack.Generation, ack.Version = replica.State()
+	if applyErr != nil {
+		ack.Error = applyErr.Error()
+	}
+	if err := json.NewEncoder(rw).Encode(&ack); err != nil {
+		return err
+	}
+	return applyErr
+}
+
+// ForgetPeer 断开连接后清除与对端的同步状态和对端的副本，重连后从完整快照开始
+func (s *DeltaSync) ForgetPeer(peerID string) {
+	s.publisher.ForgetPeer(peerID)
+	s.mu.Lock()
+	delete(s.replicas, peerID)
+	s.mu.Unlock()
+}
+
+// Views 各邻居推送的声誉表中 nodeID 的分数：邻居ID -> 分数
+func (s *DeltaSync) Views(nodeID string) map[string]float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := make(map[string]float64)
+	for peerID, replica := range s.replicas {
+		replica.mu.RLock()
+		if score, ok := replica.scores[nodeID]; ok {
+			views[peerID] = score
+		}
+		replica.mu.RUnlock()
+	}
+	return views
+}
+
+// Peers 已收到声誉表的邻居，按节点ID排序
+func (s *DeltaSync) Peers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peers := make([]string, 0, len(s.replicas))
+	for peerID := range s.replicas {
+		peers = append(peers, peerID)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// Stats 实现 stats.Provider
+func (s *DeltaSync) Stats() []stats.Metric {
+	st := s.publisher.Stats()
+	return []stats.Metric{
+		stats.Gauge("version", float64(st.Version), "本地快照版本"),
+		stats.Gauge("peers", float64(len(s.Peers())), "已收到声誉表的邻居数"),
+		stats.Counter("full_sent", float64(st.FullSent), "发送的完整快照数"),
+		stats.Counter("delta_sent", float64(st.DeltaSent), "发送的增量数"),
+		stats.Counter("entries_sent", float64(st.EntriesSent), "发送的条目数"),
+		stats.Counter("entries_saved", float64(st.EntriesSaved), "与每次都发完整快照相比少发的条目数"),
+	}
+}
@@ -0,0 +1,208 @@
+package reputation
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// syncOnce 模拟一轮同步：发送端生成消息，接收端应用后回确认
+func syncOnce(t *testing.T, p *DeltaPublisher, r *DeltaReplica, peerID string) *ScoreSyncMessage {
+	t.Helper()
+	msg := p.Prepare(peerID)
+	if msg != nil {
+		if err := r.Apply(msg); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	gen, version := r.State()
+	p.Ack(peerID, gen, version)
+	return msg
+}
+
+func assertScoresEqual(t *testing.T, want, got map[string]float64) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("期望 %d 条, 得到 %d 条", len(want), len(got))
+	}
+	for id, score := range want {
+		if got[id] != score {
+			t.Fatalf("%s: 期望 %v, 得到 %v", id, score, got[id])
+		}
+	}
+}
+
+func TestDeltaSyncSendsOnlyChanges(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	p := NewDeltaPublisher(nil)
+	p.now = clock.Now
+	r := NewDeltaReplica()
+
+	scores := make(map[string]float64)
+	for i := 0; i < 100; i++ {
+		scores[fmt.Sprintf("node-%d", i)] = float64(i) / 100
+	}
+	p.Update(scores)
+
+	if msg := syncOnce(t, p, r, "peer"); msg == nil || !msg.Full || len(msg.Scores) != 100 {
+		t.Fatalf("首次同步应为完整快照: %+v", msg)
+	}
+
+	// 低于 Epsilon 的漂移不算变化
+	scores["node-1"] += 0.0001
+	p.Update(scores)
+	if msg := syncOnce(t, p, r, "peer"); msg != nil {
+		t.Fatalf("无有效变化时不应发送: %+v", msg)
+	}
+
+	scores["node-2"] = 0.9
+	scores["new"] = 0.5
+	delete(scores, "node-3")
+	p.Update(scores)
+	msg := syncOnce(t, p, r, "peer")
+	if msg == nil || msg.Full || len(msg.Scores) != 2 || len(msg.Removed) != 1 || msg.Removed[0] != "node-3" {
+		t.Fatalf("应只发送变化的条目: %+v", msg)
+	}
+	want := r.Scores()
+	if want["node-2"] != 0.9 || want["new"] != 0.5 {
+		t.Fatalf("增量未生效: %v", want)
+	}
+	if _, ok := want["node-3"]; ok {
+		t.Fatal("删除未生效")
+	}
+
+	stats := p.Stats()
+	if stats.FullSent != 1 || stats.DeltaSent != 1 || stats.EntriesSent != 103 {
+		t.Errorf("统计不符: %+v", stats)
+	}
+	if stats.EntriesSaved < 190 {
+		t.Errorf("增量同步应显著少发条目: %+v", stats)
+	}
+
+	// 超过 FullEvery 后重发完整快照
+	clock.Advance(DefaultDeltaConfig().FullEvery)
+	if msg := syncOnce(t, p, r, "peer"); msg == nil || !msg.Full {
+		t.Fatalf("应定期发送完整快照: %+v", msg)
+	}
+}
+
+func TestDeltaSyncUnackedAndOutOfOrder(t *testing.T) {
+	p := NewDeltaPublisher(nil)
+	r := NewDeltaReplica()
+	scores := map[string]float64{"a": 0.1, "b": 0.2}
+	p.Update(scores)
+	syncOnce(t, p, r, "peer")
+
+	// 两次更新之间未确认：第二个增量从同一基准生成，包含两次的变化
+	scores["a"] = 0.3
+	p.Update(scores)
+	first := p.Prepare("peer")
+	scores["b"] = 0.4
+	p.Update(scores)
+	second := p.Prepare("peer")
+	if len(second.Scores) != 2 || second.BaseVersion != first.BaseVersion {
+		t.Fatalf("未确认时应从同一基准生成: %+v", second)
+	}
+
+	// 乱序到达：新的先到，旧的被忽略
+	if err := r.Apply(second); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if err := r.Apply(first); err != nil {
+		t.Fatalf("旧增量应被忽略: %v", err)
+	}
+	assertScoresEqual(t, scores, r.Scores())
+}
+
+func TestDeltaSyncResync(t *testing.T) {
+	config := DefaultDeltaConfig()
+	config.MaxHistory = 2
+	p := NewDeltaPublisher(config)
+	r := NewDeltaReplica()
+	scores := map[string]float64{"a": 0.1, "b": 0.2, "c": 0.3}
+	p.Update(scores)
+	syncOnce(t, p, r, "peer")
+
+	// 新接收端（例如对端重启）收到增量时要求完整快照
+	scores["a"] = 0.5
+	p.Update(scores)
+	fresh := NewDeltaReplica()
+	if err := fresh.Apply(p.Prepare("peer")); err != ErrDeltaBaseMismatch {
+		t.Fatalf("期望 ErrDeltaBaseMismatch, 得到 %v", err)
+	}
+	gen, version := fresh.State()
+	p.Ack("peer", gen, version)
+	if msg := p.Prepare("peer"); msg == nil || !msg.Full {
+		t.Fatalf("代次不符后应发送完整快照: %+v", msg)
+	}
+
+	// 对端落后超过 MaxHistory，删除记录已清理，只能发完整快照
+	p.ForgetPeer("peer")
+	syncOnce(t, p, r, "peer")
+	for i := 0; i < 3; i++ {
+		delete(scores, "c")
+		scores[fmt.Sprintf("x-%d", i)] = 0.1
+		p.Update(scores)
+	}
+	if msg := syncOnce(t, p, r, "peer"); msg == nil || !msg.Full {
+		t.Fatalf("落后太多时应发送完整快照: %+v", msg)
+	}
+	assertScoresEqual(t, scores, r.Scores())
+
+	// 发送端重启后代次变化，旧接收端收到完整快照后重新对齐
+	restarted := NewDeltaPublisher(config)
+	restarted.Update(map[string]float64{"a": 1})
+	syncOnce(t, restarted, r, "peer")
+	assertScoresEqual(t, map[string]float64{"a": 1}, r.Scores())
+}
+
+func TestDeltaSyncPush(t *testing.T) {
+	sender := NewDeltaSync(nil)
+	receiver := NewDeltaSync(nil)
+	opened := 0
+	open := func() (io.ReadWriteCloser, error) {
+		opened++
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			receiver.HandlePush(server, "alice")
+		}()
+		return client, nil
+	}
+
+	sender.Update(map[string]float64{"n1": 10, "n2": 20})
+	if err := sender.Push("bob", open); err != nil {
+		t.Fatalf("full push: %v", err)
+	}
+	assertScoresEqual(t, map[string]float64{"alice": 20}, receiver.Views("n2"))
+
+	// 对端已是最新时不打开流
+	if err := sender.Push("bob", open); err != nil || opened != 1 {
+		t.Fatalf("up-to-date push: opened %d, err %v", opened, err)
+	}
+
+	sender.Update(map[string]float64{"n1": 15})
+	if err := sender.Push("bob", open); err != nil {
+		t.Fatalf("delta push: %v", err)
+	}
+	assertScoresEqual(t, map[string]float64{"alice": 15}, receiver.Views("n1"))
+	if views := receiver.Views("n2"); len(views) != 0 {
+		t.Errorf("removed node still visible: %v", views)
+	}
+	if st := sender.publisher.Stats(); st.FullSent != 1 || st.DeltaSent != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// 对端丢失副本后增量基准不符，下次改发完整快照
+	receiver.ForgetPeer("alice")
+	sender.Update(map[string]float64{"n1": 30})
+	if err := sender.Push("bob", open); err == nil {
+		t.Fatal("expected base mismatch")
+	}
+	if err := sender.Push("bob", open); err != nil {
+		t.Fatalf("resync push: %v", err)
+	}
+	assertScoresEqual(t, map[string]float64{"alice": 30}, receiver.Views("n1"))
+}
@@ -0,0 +1,496 @@
+package reputation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
+)
+
+var (
+	ErrNilManagerConfig = errors.New("reputation manager config cannot be nil")
+	ErrEmptyNodeID      = errors.New("node ID cannot be empty")
+	ErrInvalidDelta     = errors.New("reputation delta must be a finite number")
+	ErrInvalidBounds    = errors.New("reputation bounds are invalid")
+)
+
+// Store 声誉读写接口，由 Manager 实现，激励和指责等模块通过它访问声誉
+type Store interface {
+	GetReputation(nodeID string) float64
+	UpdateReputation(nodeID string, delta float64) error
+}
+
+// StoreFuncs 用函数实现 Store，字段为 nil 时读取返回 0、更新为空操作
+type StoreFuncs struct {
+	Get    func(nodeID string) float64
+	Update func(nodeID string, delta float64) error
+}
+
+// GetReputation 实现 Store
+func (f StoreFuncs) GetReputation(nodeID string) float64 {
+	if f.Get == nil {
+		return 0
+	}
+	return f.Get(nodeID)
+}
+
+// UpdateReputation 实现 Store
+func (f StoreFuncs) UpdateReputation(nodeID string, delta float64) error {
+	if f.Update == nil {
+		return nil
+	}
+	return f.Update(nodeID, delta)
+}
+
+// ManagerConfig 声誉管理器配置
+type ManagerConfig struct {
+	DataDir string // 为空时不持久化
+
+	Initial float64 // 未记录过的节点的声誉
+	Min     float64
+	Max     float64
+
+	// 超过宽限期未活跃的节点按 CalculateNaturalDecay 衰减，DecayGrace 为 0 时不衰减
+	DecayGrace time.Duration
+
+	HistoryLimit  int           // 每个节点保留的历史条数
+	FlushInterval time.Duration // 后台落盘和衰减检查周期
+
+	Damping *DampingConfig // 阻尼，nil 表示增量直接生效
+}
+
+// DefaultManagerConfig 返回默认配置
+func DefaultManagerConfig() *ManagerConfig {
+	return &ManagerConfig{
+		Initial:       ReputationInitial,
+		Min:           ReputationMin,
+		Max:           ReputationMax,
+		DecayGrace:    DecayGraceDays * 24 * time.Hour,
+		HistoryLimit:  100,
+		FlushInterval: time.Minute,
+	}
+}
+
+// Score 节点声誉
+type Score struct {
+	NodeID     string    `json:"node_id"`
+	Score      float64   `json:"score"` // 生效分数，启用阻尼时见 damping.go
+	Raw        float64   `json:"raw"`   // 原始分数，未启用阻尼时等于 Score
+	Highest    float64   `json:"highest"`
+	Lowest     float64   `json:"lowest"`
+	Rank       int       `json:"rank,omitempty"`
+	LastActive time.Time `json:"last_active"` // 最近一次非衰减的变化
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	activeScore float64   // 最近一次活跃时的原始分数，衰减以此为基准，重复检查不会叠加
+	windowStart time.Time // 阻尼变化上限的统计周期起点
+	windowBase  float64   // 周期起点的生效分数
+}
+
+// HistoryEntry 声誉变化记录
+type HistoryEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Delta     float64   `json:"delta"`
+	Value     float64   `json:"value"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// 历史记录中衰减的原因
+const ReasonDecay = "decay"
+
+// Manager 节点声誉的唯一存储
+// 激励、指责、HTTP API 和管理后台都通过它读写声誉，负责上下限、时间衰减、历史和排行。
+type Manager struct {
+	mu      sync.RWMutex
+	config  *ManagerConfig
+	scores  map[string]*Score
+	history map[string][]*HistoryEntry
+	dirty   bool
+	running bool
+	stopCh  chan struct{}
+	now     func() time.Time
+
+	onChange func(nodeID string, delta, value float64, reason string)
+}
+
+// NewManager 创建声誉管理器
+func NewManager(config *ManagerConfig) (*Manager, error) {
+	if config == nil {
+		return nil, ErrNilManagerConfig
+	}
+	if config.Min >= config.Max || config.Initial < config.Min || config.Initial > config.Max {
+		return nil, ErrInvalidBounds
+	}
+	if config.Damping != nil {
+		if err := config.Damping.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	m := &Manager{
+		config:  config,
+		scores:  make(map[string]*Score),
+		history: make(map[string][]*HistoryEntry),
+		stopCh:  make(chan struct{}),
+		now:     time.Now,
+	}
+	m.load()
+	return m, nil
+}
+
+// SetOnChange 设置声誉变化回调（在锁外调用）
+func (m *Manager) SetOnChange(fn func(nodeID string, delta, value float64, reason string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = fn
+}
+
+// Start 启动后台落盘和衰减
+func (m *Manager) Start() {
+	m.mu.Lock()
+	if m.running || m.config.FlushInterval <= 0 {
+		m.mu.Unlock()
+		return
+	}
+	m.running = true
+	m.stopCh = make(chan struct{})
+	m.mu.Unlock()
+
+	go m.loop()
+}
+
+// Stop 停止后台任务并落盘
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if m.running {
+		m.running = false
+		close(m.stopCh)
+	}
+	m.mu.Unlock()
+
+	m.Flush()
+}
+
+func (m *Manager) loop() {
+	ticker := time.NewTicker(m.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Decay()
+			m.Flush()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// GetReputation 获取节点声誉，未记录的节点返回初始值
+func (m *Manager) GetReputation(nodeID string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.scores[nodeID]; ok {
+		return s.Score
+	}
+	return m.config.Initial
+}
+
+// UpdateReputation 按增量调整声誉，签名与各模块的声誉回调一致
+func (m *Manager) UpdateReputation(nodeID string, delta float64) error {
+	_, err := m.Adjust(nodeID, delta, "")
+	return err
+}
+
+// Adjust 按增量调整原始分数（限制在上下限内），返回调整后的生效分数
+func (m *Manager) Adjust(nodeID string, delta float64, reason string) (float64, error) {
+	if math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return 0, ErrInvalidDelta
+	}
+	return m.apply(nodeID, reason, true, func(current float64) float64 { return current + delta })
+}
+
+// Set 直接设置声誉（限制在上下限内），原始分数和生效分数相同，不经阻尼；返回设置后的值
+func (m *Manager) Set(nodeID string, value float64, reason string) (float64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrInvalidDelta
+	}
+	return m.apply(nodeID, reason, false, func(float64) float64 { return value })
+}
+
+func (m *Manager) apply(nodeID, reason string, damp bool, next func(current float64) float64) (float64, error) {
+	if nodeID == "" {
+		return 0, ErrEmptyNodeID
+	}
+	now := m.now()
+
+	m.mu.Lock()
+	s := m.scoreLocked(nodeID, now)
+	s.Raw = m.clip(next(s.Raw))
+	s.activeScore = s.Raw
+	s.LastActive = now
+	value := s.Raw
+	if damp {
+		value = m.dampLocked(s, now)
+	} else {
+		s.windowStart = time.Time{}
+	}
+	delta := value - s.Score
+	m.recordLocked(s, delta, reason, now)
+	onChange := m.onChange
+	m.mu.Unlock()
+
+	if onChange != nil && delta != 0 {
+		onChange(nodeID, delta, value, reason)
+	}
+	return value, nil
+}
+
+// Get 获取节点声誉详情
+func (m *Manager) Get(nodeID string) (*Score, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.scores[nodeID]
+	if !ok {
+		return nil, false
+	}
+	c := *s
+	return &c, true
+}
+
+// History 获取节点的声誉变化，最新的在前
+func (m *Manager) History(nodeID string, limit int) []*HistoryEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := m.history[nodeID]
+	result := make([]*HistoryEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		e := *entries[i]
+		result = append(result, &e)
+	}
+	return result
+}
+
+// Ranking 按声誉降序排列（同分按节点ID），limit <= 0 返回全部
+func (m *Manager) Ranking(limit int) []*Score {
+	m.mu.RLock()
+	ranked := make([]*Score, 0, len(m.scores))
+	for _, s := range m.scores {
+		c := *s
+		ranked = append(ranked, &c)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].NodeID < ranked[j].NodeID
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	for i, s := range ranked {
+		s.Rank = i + 1
+	}
+	return ranked
+}
+
+// Decay 对超过宽限期未活跃的节点应用自然衰减，返回声誉发生变化的节点数
+// 衰减以最近一次活跃时的声誉为基准计算，多次检查结果相同。
+func (m *Manager) Decay() int {
+	if m.config.DecayGrace <= 0 {
+		return 0
+	}
+	now := m.now()
+	type change struct {
+		nodeID       string
+		delta, value float64
+	}
+	var changes []change
+
+	m.mu.Lock()
+	for _, s := range m.scores {
+		inactive := now.Sub(s.LastActive)
+		if inactive <= m.config.DecayGrace {
+			continue
+		}
+		// CalculateNaturalDecay 按天计算并自带宽限期，这里把配置的宽限期折算进去
+		days := DecayGraceDays + int((inactive-m.config.DecayGrace)/(24*time.Hour))
+		raw := m.clip(CalculateNaturalDecay(s.activeScore, days))
+		if raw >= s.Raw {
+			continue
+		}
+		s.Raw = raw
+		value := m.dampLocked(s, now)
+		delta := value - s.Score
+		if delta == 0 {
+			continue
+		}
+		m.recordLocked(s, delta, ReasonDecay, now)
+		changes = append(changes, change{s.NodeID, delta, value})
+	}
+	onChange := m.onChange
+	m.mu.Unlock()
+
+	if onChange != nil {
+		for _, c := range changes {
+			onChange(c.nodeID, c.delta, c.value, ReasonDecay)
+		}
+	}
+	return len(changes)
+}
+
+// Stats 实现 stats.Provider
+func (m *Manager) Stats() []stats.Metric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total float64
+	tiers := make(map[ReputationTier]int)
+	for _, s := range m.scores {
+		total += s.Score
+		tiers[GetTier(s.Score)]++
+	}
+	average := 0.0
+	if len(m.scores) > 0 {
+		average = total / float64(len(m.scores))
+	}
+	return []stats.Metric{
+		stats.Gauge("nodes", float64(len(m.scores)), "有声誉记录的节点数"),
+		stats.Gauge("average_reputation", average, "平均声誉"),
+		stats.Gauge("blacklisted_nodes", float64(tiers[TierLevelBlacklist]), "黑名单等级的节点数"),
+		stats.Gauge("trusted_nodes", float64(tiers[TierLevelTrusted]+tiers[TierLevelElder]), "信任及以上等级的节点数"),
+	}
+}
+
+// scoreLocked 获取或创建节点声誉（调用方持有写锁）
+func (m *Manager) scoreLocked(nodeID string, now time.Time) *Score {
+	s, ok := m.scores[nodeID]
+	if !ok {
+		s = &Score{
+			NodeID:      nodeID,
+			Score:       m.config.Initial,
+			Raw:         m.config.Initial,
+			Highest:     m.config.Initial,
+			Lowest:      m.config.Initial,
+			LastActive:  now,
+			activeScore: m.config.Initial,
+		}
+		m.scores[nodeID] = s
+	}
+	return s
+}
+
+// recordLocked 更新声誉值并追加历史（调用方持有写锁）
+func (m *Manager) recordLocked(s *Score, delta float64, reason string, now time.Time) {
+	s.Score += delta
+	s.Highest = math.Max(s.Highest, s.Score)
+	s.Lowest = math.Min(s.Lowest, s.Score)
+	s.UpdatedAt = now
+
+	entries := append(m.history[s.NodeID], &HistoryEntry{
+		Timestamp: now,
+		Delta:     delta,
+		Value:     s.Score,
+		Reason:    reason,
+	})
+	if limit := m.config.HistoryLimit; limit > 0 && len(entries) > limit {
+		entries = append([]*HistoryEntry(nil), entries[len(entries)-limit:]...)
+	}
+	m.history[s.NodeID] = entries
+	m.dirty = true
+}
+
+func (m *Manager) clip(value float64) float64 {
+	return math.Max(m.config.Min, math.Min(m.config.Max, value))
+}
+
+// managerState 持久化状态
+type managerState struct {
+	Scores  []*persistedScore          `json:"scores"`
+	History map[string][]*HistoryEntry `json:"history"`
+}
+
+type persistedScore struct {
+	*Score
+	ActiveScore float64  `json:"active_score"`
+	Raw         *float64 `json:"raw,omitempty"` // 旧版本没有原始分数，加载时取生效分数
+}
+
+// Flush 有未落盘的变化时写入 reputation.json
+func (m *Manager) Flush() error {
+	if m.config.DataDir == "" {
+		return nil
+	}
+	m.mu.Lock()
+	if !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	state := managerState{History: make(map[string][]*HistoryEntry, len(m.history))}
+	for _, s := range m.scores {
+		c := *s
+		raw := s.Raw
+		state.Scores = append(state.Scores, &persistedScore{Score: &c, ActiveScore: s.activeScore, Raw: &raw})
+	}
+	for id, entries := range m.history {
+		state.History[id] = append([]*HistoryEntry(nil), entries...)
+	}
+	m.dirty = false
+	m.mu.Unlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.config.DataDir, "reputation.json"), data, 0644)
+}
+
+func (m *Manager) load() {
+	if m.config.DataDir == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(m.config.DataDir, "reputation.json"))
+	if err != nil {
+		return
+	}
+	var state managerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+	for _, p := range state.Scores {
+		if p == nil || p.Score == nil || p.NodeID == "" {
+			continue
+		}
+		s := p.Score
+		s.activeScore = p.ActiveScore
+		s.Raw = s.Score
+		if p.Raw != nil {
+			s.Raw = *p.Raw
+		}
+		s.Rank = 0
+		m.scores[s.NodeID] = s
+	}
+	for id, entries := range state.History {
+		if _, ok := m.scores[id]; ok {
+			m.history[id] = entries
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	config := DefaultManagerConfig()
+	config.DataDir = t.TempDir()
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return m
+}
+
+func TestManagerAdjustBoundsAndHistory(t *testing.T) {
+	m := newTestManager(t)
+
+	if got := m.GetReputation("node-a"); got != ReputationInitial {
+		t.Errorf("unknown node reputation = %v, want %v", got, ReputationInitial)
+	}
+	if _, err := m.Adjust("", 1, "x"); err != ErrEmptyNodeID {
+		t.Errorf("empty node ID error = %v", err)
+	}
+
+	var changes []string
+	m.SetOnChange(func(nodeID string, delta, value float64, reason string) {
+		changes = append(changes, reason)
+	})
+
+	if v, _ := m.Adjust("node-a", 5, "task"); v != ReputationInitial+5 {
+		t.Errorf("after +5 = %v", v)
+	}
+	if v, _ := m.Adjust("node-a", -100, "accusation"); v != ReputationMin {
+		t.Errorf("reputation should be clipped to min, got %v", v)
+	}
+	if v, _ := m.Set("node-a", 5000, "admin"); v != ReputationMax {
+		t.Errorf("reputation should be clipped to max, got %v", v)
+	}
+	if len(changes) != 3 {
+		t.Errorf("OnChange called %d times, want 3", len(changes))
+	}
+
+	history := m.History("node-a", 2)
+	if len(history) != 2 || history[0].Reason != "admin" || history[1].Reason != "accusation" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	s, ok := m.Get("node-a")
+	if !ok || s.Highest != ReputationMax || s.Lowest != ReputationMin {
+		t.Errorf("unexpected score %+v", s)
+	}
+}
+
+func TestManagerRankingAndPersistence(t *testing.T) {
+	m := newTestManager(t)
+	m.Adjust("node-a", 5, "task")
+	m.Adjust("node-b", 50, "task")
+	m.Adjust("node-c", 5, "task")
+
+	ranking := m.Ranking(2)
+	if len(ranking) != 2 || ranking[0].NodeID != "node-b" || ranking[1].NodeID != "node-a" || ranking[1].Rank != 2 {
+		t.Errorf("unexpected ranking %+v", ranking)
+	}
+
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	reloaded, err := NewManager(m.config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := reloaded.GetReputation("node-b"); got != ReputationInitial+50 {
+		t.Errorf("reloaded reputation = %v", got)
+	}
+	if len(reloaded.History("node-b", 0)) != 1 {
+		t.Error("history should be persisted")
+	}
+}
+
+func TestManagerDecay(t *testing.T) {
+	m := newTestManager(t)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	m.Adjust("node-a", 90, "task")
+
+	if n := m.Decay(); n != 0 {
+		t.Errorf("active node decayed: %d", n)
+	}
+
+	now = now.Add(m.config.DecayGrace + 14*24*time.Hour)
+	if n := m.Decay(); n != 1 {
+		t.Fatalf("Decay changed %d nodes, want 1", n)
+	}
+	decayed := m.GetReputation("node-a")
+	if decayed >= 100 {
+		t.Errorf("reputation should decay, got %v", decayed)
+	}
+	if n := m.Decay(); n != 0 || m.GetReputation("node-a") != decayed {
+		t.Error("repeated decay checks should be idempotent")
+	}
+	if history := m.History("node-a", 1); len(history) != 1 || history[0].Reason != ReasonDecay {
+		t.Errorf("decay should be recorded, got %+v", history)
+	}
+
+	// 重新活跃后以当前声誉为基准
+	m.Adjust("node-a", 1, "task")
+	if n := m.Decay(); n != 0 {
+		t.Errorf("reactivated node decayed: %d", n)
+	}
+}
+
+func TestManagerDamping(t *testing.T) {
+	config := DefaultManagerConfig()
+	config.DataDir = t.TempDir()
+	config.Damping = &DampingConfig{Smoothing: 0.5, MaxChange: 30, Interval: time.Hour}
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	// EMA 得到 60，受本周期变化上限限制为 40
+	if v, _ := m.Adjust("node-a", 100, "task"); v != ReputationInitial+30 {
+		t.Errorf("damped reputation = %v, want %v", v, ReputationInitial+30)
+	}
+	if got := m.RawReputation("node-a"); got != ReputationInitial+100 {
+		t.Errorf("raw reputation = %v, want %v", got, ReputationInitial+100)
+	}
+	if v, _ := m.Adjust("node-a", 0, "task"); v != ReputationInitial+30 {
+		t.Errorf("change within the window should stay capped, got %v", v)
+	}
+
+	now = now.Add(time.Hour)
+	if v, _ := m.Adjust("node-a", 0, "task"); v != ReputationInitial+60 {
+		t.Errorf("next window damped reputation = %v, want %v", v, ReputationInitial+60)
+	}
+	detail, ok := m.Detail("node-a")
+	if !ok || detail.Raw != ReputationInitial+100 || detail.Damped != ReputationInitial+60 || detail.WindowChange != 30 || !detail.Damping {
+		t.Errorf("unexpected detail %+v", detail)
+	}
+
+	if err := m.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	reloaded, err := NewManager(m.config)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if reloaded.GetReputation("node-a") != ReputationInitial+60 || reloaded.RawReputation("node-a") != ReputationInitial+100 {
+		t.Error("raw and damped reputation should be persisted")
+	}
+
+	// 管理操作不经阻尼
+	if v, _ := m.Set("node-a", 500, "admin"); v != 500 || m.RawReputation("node-a") != 500 {
+		t.Errorf("Set should bypass damping, got %v", v)
+	}
+	if err := m.SetDamping(&DampingConfig{Smoothing: 0}); err == nil {
+		t.Error("invalid damping config should be rejected")
+	}
+}
@@ -45,11 +45,15 @@ type Agent struct {
 	LastUpdated  time.Time          // 最后更新时间
 }
 
+// ChangeHook 声誉变化钩子（用于对外推送事件）
+type ChangeHook func(agentID, source string, delta float64)
+
 // System 信誉系统
 type System struct {
 	agents       map[string]*Agent
 	mu           sync.RWMutex
 	halfLifeDays int // 半衰期（天）
+	changeHook   ChangeHook
 }
 
 // NewSystem 创建信誉系统
@@ -71,6 +75,13 @@ func NewSystemWithHalfLife(halfLifeDays int) *System {
 	}
 }
 
+// SetChangeHook 设置声誉变化钩子
+func (s *System) SetChangeHook(fn ChangeHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changeHook = fn
+}
+
 // RegisterAgent 注册 Agent
 func (s *System) RegisterAgent(id string, ownerTrust float64) {
 	s.mu.Lock()
@@ -107,10 +118,9 @@ func (s *System) AddRating(rating Rating) {
 // AddReputationRecord 添加声誉记录（带时间戳，用于时间衰减）
 func (s *System) AddReputationRecord(agentID string, score float64, source, sourceNode string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	agent, exists := s.agents[agentID]
 	if !exists {
+		s.mu.Unlock()
 		return
 	}
 
@@ -121,19 +131,30 @@ func (s *System) AddReputationRecord(agentID string, score float64, source, sour
 		SourceNode: sourceNode,
 	}
 	agent.Records = append(agent.Records, record)
+	hook := s.changeHook
+	s.mu.Unlock()
+
+	if hook != nil {
+		hook(agentID, source, score)
+	}
 }
 
 // AddPenalty 添加惩罚
 func (s *System) AddPenalty(agentID string, penalty float64) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	agent, exists := s.agents[agentID]
 	if !exists {
+		s.mu.Unlock()
 		return
 	}
 
 	agent.Penalty += penalty
+	hook := s.changeHook
+	s.mu.Unlock()
+
+	if hook != nil {
+		hook(agentID, "penalty", -penalty)
+	}
 }
 
 // UpdateScore 更新信誉值
@@ -0,0 +1,373 @@
+package reputation
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook 请求头
+const (
+	WebhookSignatureHeader = "X-Daan-Signature"
+	WebhookTimestampHeader = "X-Daan-Timestamp"
+	WebhookNonceHeader     = "X-Daan-Nonce"
+	WebhookEventHeader     = "X-Daan-Event"
+)
+
+// 声誉事件类型
+const (
+	EventReputationChanged = "reputation.changed"
+)
+
+// Webhook 错误
+var (
+	ErrNilWebhookConfig    = errors.New("webhook config cannot be nil")
+	ErrEmptyWebhookNodeID  = errors.New("webhook node ID cannot be empty")
+	ErrCallbackDisabled    = errors.New("webhook callback is not configured")
+	ErrMissingSignature    = errors.New("missing webhook signature headers")
+	ErrInvalidSignature    = errors.New("invalid webhook signature")
+	ErrStaleTimestamp      = errors.New("webhook timestamp outside allowed window")
+	ErrReplayedNonce       = errors.New("webhook nonce already used")
+	ErrInvalidAdjustment   = errors.New("invalid reputation adjustment")
+	ErrAdjustmentTooLarge  = errors.New("reputation adjustment exceeds limit")
+	ErrWebhookDeliveryFail = errors.New("webhook delivery failed")
+)
+
+// ChangeEvent 声誉变化事件
+type ChangeEvent struct {
+	EventID      string   `json:"event_id"`
+	Type         string   `json:"type"`
+	Emitter      string   `json:"emitter"` // 发出事件的节点
+	NodeID       string   `json:"node_id"` // 声誉变化的节点
+	Source       string   `json:"source"`  // 变化来源（task/accusation/penalty/...）
+	Delta        float64  `json:"delta"`   // 变化量
+	EvidenceRefs []string `json:"evidence_refs,omitempty"`
+	Timestamp    int64    `json:"timestamp"`
+}
+
+// ExternalAdjustment 外部评分系统回调的声誉调整
+type ExternalAdjustment struct {
+	NodeID       string   `json:"node_id"`
+	Delta        float64  `json:"delta"`
+	Reason       string   `json:"reason"`
+	Source       string   `json:"source"`
+	EvidenceRefs []string `json:"evidence_refs,omitempty"`
+}
+
+// WebhookEndpoint 事件推送目标
+type WebhookEndpoint struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"` // HMAC-SHA256 共享密钥（可选，为空时只用签名密钥签名）
+}
+
+// WebhookConfig Webhook 配置
+type WebhookConfig struct {
+	NodeID         string
+	Endpoints      []WebhookEndpoint
+	CallbackSecret string          // 入站回调的共享密钥（为空则拒绝所有回调）
+	MaxClockSkew   time.Duration   // 允许的时间偏差
+	MaxDelta       float64         // 单次外部调整的绝对值上限
+	Timeout        time.Duration   // 推送超时
+	MaxRetries     int             // 推送失败重试次数
+	Keyring        *WebhookKeyring // 推送签名密钥（与节点身份无关），为 nil 时只用 HMAC
+
+	// 应用外部调整（由声誉模块注入）
+	ApplyAdjustmentFunc func(adj *ExternalAdjustment) error
+}
+
+// DefaultWebhookConfig 返回默认配置
+func DefaultWebhookConfig(nodeID string) *WebhookConfig {
+	return &WebhookConfig{
+		NodeID:       nodeID,
+		MaxClockSkew: 5 * time.Minute,
+		MaxDelta:     50,
+		Timeout:      10 * time.Second,
+		MaxRetries:   2,
+	}
+}
+
+// WebhookManager 声誉事件 Webhook 管理器
+type WebhookManager struct {
+	mu     sync.RWMutex
+	config *WebhookConfig
+	client *http.Client
+
+	seenNonces map[string]time.Time
+
+	delivered int64
+	failed    int64
+	accepted  int64
+	rejected  int64
+
+	// 回调
+	OnDeliveryFailed func(endpoint string, event *ChangeEvent, err error)
+}
+
+// NewWebhookManager 创建 Webhook 管理器
+func NewWebhookManager(config *WebhookConfig) (*WebhookManager, error) {
+	if config == nil {
+		return nil, ErrNilWebhookConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyWebhookNodeID
+	}
+	if config.MaxClockSkew <= 0 {
+		config.MaxClockSkew = 5 * time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &WebhookManager{
+		config:     config,
+		client:     &http.Client{Timeout: config.Timeout},
+		seenNonces: make(map[string]time.Time),
+	}, nil
+}
+
+// SignPayload 计算 Webhook 签名：HMAC-SHA256(secret, timestamp "." nonce "." body)
+func SignPayload(secret string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewChangeEvent 构造声誉变化事件
+func (wm *WebhookManager) NewChangeEvent(nodeID, source string, delta float64, evidenceRefs []string) *ChangeEvent {
+	now := time.Now()
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%f:%d", wm.config.NodeID, nodeID, source, delta, now.UnixNano())))
+	return &ChangeEvent{
+		EventID:      hex.EncodeToString(hash[:16]),
+		Type:         EventReputationChanged,
+		Emitter:      wm.config.NodeID,
+		NodeID:       nodeID,
+		Source:       source,
+		Delta:        delta,
+		EvidenceRefs: evidenceRefs,
+		Timestamp:    now.Unix(),
+	}
+}
+
+// Emit 异步推送事件到所有配置的端点
+func (wm *WebhookManager) Emit(event *ChangeEvent) {
+	wm.mu.RLock()
+	endpoints := append([]WebhookEndpoint(nil), wm.config.Endpoints...)
+	wm.mu.RUnlock()
+
+	for _, ep := range endpoints {
+		go func(ep WebhookEndpoint) {
+			if err := wm.Deliver(ep, event); err != nil && wm.OnDeliveryFailed != nil {
+				wm.OnDeliveryFailed(ep.URL, event, err)
+			}
+		}(ep)
+	}
+}
+
+// EmitChange 构造并推送声誉变化事件
+func (wm *WebhookManager) EmitChange(nodeID, source string, delta float64, evidenceRefs []string) *ChangeEvent {
+	event := wm.NewChangeEvent(nodeID, source, delta, evidenceRefs)
+	wm.Emit(event)
+	return event
+}
+
+// Deliver 同步推送事件到指定端点，失败时按配置重试
+func (wm *WebhookManager) Deliver(ep WebhookEndpoint, event *ChangeEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= wm.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+		}
+		if lastErr = wm.post(ep, event.Type, body); lastErr == nil {
+			wm.mu.Lock()
+			wm.delivered++
+			wm.mu.Unlock()
+			return nil
+		}
+	}
+
+	wm.mu.Lock()
+	wm.failed++
+	wm.mu.Unlock()
+	return fmt.Errorf("%w: %s: %v", ErrWebhookDeliveryFail, ep.URL, lastErr)
+}
+
+func (wm *WebhookManager) post(ep WebhookEndpoint, eventType string, body []byte) error {
+	nonce, err := newWebhookNonce()
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(WebhookNonceHeader, nonce)
+	if ep.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignPayload(ep.Secret, ts, nonce, body))
+	}
+	if wm.config.Keyring != nil {
+		keyID, sig := wm.config.Keyring.Sign(ts, nonce, body)
+		req.Header.Set(WebhookKeyIDHeader, keyID)
+		req.Header.Set(WebhookKeySignatureHeader, base64.StdEncoding.EncodeToString(sig))
+	}
+
+	resp, err := wm.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// HandleCallback 校验并应用外部评分系统的回调
+// 校验顺序：签名头完整 -> 时间窗口 -> HMAC 签名 -> nonce 防重放 -> 调整内容
+func (wm *WebhookManager) HandleCallback(header http.Header, body []byte) (*ExternalAdjustment, error) {
+	adj, err := wm.verifyCallback(header, body)
+
+	wm.mu.Lock()
+	if err != nil {
+		wm.rejected++
+	} else {
+		wm.accepted++
+	}
+	apply := wm.config.ApplyAdjustmentFunc
+	wm.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if apply != nil {
+		if err := apply(adj); err != nil {
+			return nil, err
+		}
+	}
+	return adj, nil
+}
+
+func (wm *WebhookManager) verifyCallback(header http.Header, body []byte) (*ExternalAdjustment, error) {
+	if wm.config.CallbackSecret == "" {
+		return nil, ErrCallbackDisabled
+	}
+
+	sig := header.Get(WebhookSignatureHeader)
+	tsStr := header.Get(WebhookTimestampHeader)
+	nonce := header.Get(WebhookNonceHeader)
+	if sig == "" || tsStr == "" || nonce == "" {
+		return nil, ErrMissingSignature
+	}
+
+	ts, err := strconv.ParseInt(tsStr, 10, 64)
+	if err != nil {
+		return nil, ErrStaleTimestamp
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(ts, 0))
+	if skew > wm.config.MaxClockSkew || skew < -wm.config.MaxClockSkew {
+		return nil, ErrStaleTimestamp
+	}
+
+	expected := SignPayload(wm.config.CallbackSecret, ts, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return nil, ErrInvalidSignature
+	}
+
+	wm.mu.Lock()
+	wm.pruneNoncesLocked(now)
+	if _, seen := wm.seenNonces[nonce]; seen {
+		wm.mu.Unlock()
+		return nil, ErrReplayedNonce
+	}
+	wm.seenNonces[nonce] = time.Unix(ts, 0)
+	wm.mu.Unlock()
+
+	var adj ExternalAdjustment
+	if err := json.Unmarshal(body, &adj); err != nil {
+		return nil, ErrInvalidAdjustment
+	}
+	if adj.NodeID == "" || adj.Delta == 0 || math.IsNaN(adj.Delta) || math.IsInf(adj.Delta, 0) {
+		return nil, ErrInvalidAdjustment
+	}
+	if wm.config.MaxDelta > 0 && math.Abs(adj.Delta) > wm.config.MaxDelta {
+		return nil, ErrAdjustmentTooLarge
+	}
+	if adj.Source == "" {
+		adj.Source = "external"
+	}
+	return &adj, nil
+}
+
+// pruneNoncesLocked 清理超出时间窗口的 nonce（窗口外的请求已被时间校验拒绝）
+func (wm *WebhookManager) pruneNoncesLocked(now time.Time) {
+	cutoff := now.Add(-2 * wm.config.MaxClockSkew)
+	for nonce, ts := range wm.seenNonces {
+		if ts.Before(cutoff) {
+			delete(wm.seenNonces, nonce)
+		}
+	}
+}
+
+// SetEndpoints 更新推送端点
+func (wm *WebhookManager) SetEndpoints(endpoints []WebhookEndpoint) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.config.Endpoints = endpoints
+}
+
+// SetApplyAdjustmentFunc 设置外部调整的应用函数（声誉模块晚于 Webhook 初始化时使用）
+func (wm *WebhookManager) SetApplyAdjustmentFunc(fn func(adj *ExternalAdjustment) error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.config.ApplyAdjustmentFunc = fn
+}
+
+// GetStats 获取统计信息
+func (wm *WebhookManager) GetStats() map[string]interface{} {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	return map[string]interface{}{
+		"endpoints":          len(wm.config.Endpoints),
+		"delivered":          wm.delivered,
+		"failed":             wm.failed,
+		"callbacks_accepted": wm.accepted,
+		"callbacks_rejected": wm.rejected,
+		"callback_enabled":   wm.config.CallbackSecret != "",
+		"signing_key":        wm.config.Keyring != nil,
+	}
+}
+
+func newWebhookNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,214 @@
+package reputation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook 专用签名密钥
+// 推送的事件除可选的 HMAC 共享密钥外，还用节点独立的 Ed25519 密钥签名。该密钥与节点
+// p2p 身份无关，接收方只需从 /.well-known/daan-webhook-keys 取得公钥即可验签；
+// 轮换后旧密钥在宽限期内仍然公布，便于接收方处理轮换前发出、重试中的推送。
+
+// Webhook 签名密钥请求头
+const (
+	WebhookKeyIDHeader        = "X-Daan-Key-Id"
+	WebhookKeySignatureHeader = "X-Daan-Key-Signature" // base64(Ed25519(timestamp "." nonce "." body))
+)
+
+// WebhookKeyAlgorithm 签名算法
+const WebhookKeyAlgorithm = "ed25519"
+
+var (
+	ErrWebhookKeyNotFound = errors.New("webhook signing key not found")
+	ErrWebhookKeyCorrupt  = errors.New("webhook signing key file is corrupt")
+)
+
+// WebhookKey 签名密钥（含私钥，仅保存在本地）
+type WebhookKey struct {
+	KeyID      string    `json:"key_id"`
+	PublicKey  []byte    `json:"public_key"`
+	PrivateKey []byte    `json:"private_key"`
+	CreatedAt  time.Time `json:"created_at"`
+	RetiredAt  time.Time `json:"retired_at,omitempty"` // 轮换后停止签名的时间，零值表示当前密钥
+}
+
+// WebhookPublicKey 对外公布的公钥
+type WebhookPublicKey struct {
+	KeyID     string    `json:"key_id"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey string    `json:"public_key"` // base64
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 已轮换的密钥停止公布的时间
+}
+
+// WebhookKeyring Webhook 签名密钥环，最后一把为当前签名密钥
+type WebhookKeyring struct {
+	mu    sync.RWMutex
+	path  string
+	grace time.Duration
+	keys  []*WebhookKey
+	now   func() time.Time
+}
+
+// LoadOrCreateWebhookKeyring 加载密钥环，文件不存在时生成第一把密钥
+// grace 为轮换后旧密钥继续公布的时长。
+func LoadOrCreateWebhookKeyring(path string, grace time.Duration) (*WebhookKeyring, error) {
+	if grace <= 0 {
+		grace = 7 * 24 * time.Hour
+	}
+	k := &WebhookKeyring{path: path, grace: grace, now: time.Now}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &k.keys); err != nil {
+			return nil, ErrWebhookKeyCorrupt
+		}
+		for _, key := range k.keys {
+			if len(key.PrivateKey) != ed25519.PrivateKeySize || len(key.PublicKey) != ed25519.PublicKeySize {
+				return nil, ErrWebhookKeyCorrupt
+			}
+		}
+		if len(k.keys) > 0 {
+			return k, nil
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	if _, err := k.Rotate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Rotate 生成新的签名密钥，原密钥转为已轮换并在宽限期后删除
+func (k *WebhookKeyring) Rotate() (*WebhookPublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pub)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.now()
+	kept := make([]*WebhookKey, 0, len(k.keys)+1)
+	for _, key := range k.keys {
+		if key.RetiredAt.IsZero() {
+			key.RetiredAt = now
+		}
+		if now.Sub(key.RetiredAt) < k.grace {
+			kept = append(kept, key)
+		}
+	}
+	key := &WebhookKey{
+		KeyID:      hex.EncodeToString(sum[:8]),
+		PublicKey:  pub,
+		PrivateKey: priv,
+		CreatedAt:  now,
+	}
+	k.keys = append(kept, key)
+	if err := k.saveLocked(); err != nil {
+		return nil, err
+	}
+	return k.publicKeyLocked(key), nil
+}
+
+// Sign 用当前密钥签名推送内容，返回密钥ID和签名
+func (k *WebhookKeyring) Sign(timestamp int64, nonce string, body []byte) (string, []byte) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key := k.keys[len(k.keys)-1]
+	return key.KeyID, ed25519.Sign(ed25519.PrivateKey(key.PrivateKey), webhookSigningPayload(timestamp, nonce, body))
+}
+
+// PublicKeys 当前公布的公钥（当前密钥和宽限期内的已轮换密钥）
+func (k *WebhookKeyring) PublicKeys() []*WebhookPublicKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	now := k.now()
+	result := make([]*WebhookPublicKey, 0, len(k.keys))
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		key := k.keys[i]
+		if !key.RetiredAt.IsZero() && now.Sub(key.RetiredAt) >= k.grace {
+			continue
+		}
+		result = append(result, k.publicKeyLocked(key))
+	}
+	return result
+}
+
+// PublicKey 按密钥ID查找公钥
+func (k *WebhookKeyring) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	for _, key := range k.PublicKeys() {
+		if key.KeyID == keyID {
+			pub, err := base64.StdEncoding.DecodeString(key.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			return ed25519.PublicKey(pub), nil
+		}
+	}
+	return nil, ErrWebhookKeyNotFound
+}
+
+func (k *WebhookKeyring) publicKeyLocked(key *WebhookKey) *WebhookPublicKey {
+	pk := &WebhookPublicKey{
+		KeyID:     key.KeyID,
+		Algorithm: WebhookKeyAlgorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(key.PublicKey),
+		Active:    key.RetiredAt.IsZero(),
+		CreatedAt: key.CreatedAt,
+	}
+	if !pk.Active {
+		pk.ExpiresAt = key.RetiredAt.Add(k.grace)
+	}
+	return pk
+}
+
+func (k *WebhookKeyring) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(k.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := k.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, k.path)
+}
+
+// VerifyWebhookKeySignature 接收方验证推送签名（signature 为请求头中的 base64 值）
+func VerifyWebhookKeySignature(publicKey ed25519.PublicKey, timestamp int64, nonce string, body []byte, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(publicKey, webhookSigningPayload(timestamp, nonce, body), sig)
+}
+
+// webhookSigningPayload 与 HMAC 签名相同的规范化内容：timestamp "." nonce "." body
+func webhookSigningPayload(timestamp int64, nonce string, body []byte) []byte {
+	payload := make([]byte, 0, len(body)+len(nonce)+24)
+	payload = strconv.AppendInt(payload, timestamp, 10)
+	payload = append(payload, '.')
+	payload = append(payload, nonce...)
+	payload = append(payload, '.')
+	return append(payload, body...)
+}
@@ -0,0 +1,228 @@
+package reputation
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeader(secret string, ts int64, nonce string, body []byte) http.Header {
+	h := http.Header{}
+	h.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+	h.Set(WebhookNonceHeader, nonce)
+	h.Set(WebhookSignatureHeader, SignPayload(secret, ts, nonce, body))
+	return h
+}
+
+func TestNewWebhookManager(t *testing.T) {
+	if _, err := NewWebhookManager(nil); err != ErrNilWebhookConfig {
+		t.Errorf("期望 ErrNilWebhookConfig, 得到 %v", err)
+	}
+	if _, err := NewWebhookManager(&WebhookConfig{}); err != ErrEmptyWebhookNodeID {
+		t.Errorf("期望 ErrEmptyWebhookNodeID, 得到 %v", err)
+	}
+}
+
+func TestWebhookDeliverSigned(t *testing.T) {
+	const secret = "endpoint-secret"
+	received := make(chan *ChangeEvent, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		expected := SignPayload(secret, ts, r.Header.Get(WebhookNonceHeader), body)
+		if r.Header.Get(WebhookSignatureHeader) != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var ev ChangeEvent
+		json.Unmarshal(body, &ev)
+		received <- &ev
+	}))
+	defer srv.Close()
+
+	wm, _ := NewWebhookManager(DefaultWebhookConfig("node-1"))
+	event := wm.NewChangeEvent("node-2", "task", 5, []string{"task:abc"})
+	if err := wm.Deliver(WebhookEndpoint{URL: srv.URL, Secret: secret}, event); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+
+	ev := <-received
+	if ev.NodeID != "node-2" || ev.Source != "task" || ev.Delta != 5 || ev.Emitter != "node-1" {
+		t.Errorf("事件内容错误: %+v", ev)
+	}
+	if len(ev.EvidenceRefs) != 1 || ev.EvidenceRefs[0] != "task:abc" {
+		t.Errorf("证据引用错误: %v", ev.EvidenceRefs)
+	}
+
+	// 错误的密钥应导致推送失败
+	cfg := DefaultWebhookConfig("node-1")
+	cfg.MaxRetries = 0
+	wm2, _ := NewWebhookManager(cfg)
+	if err := wm2.Deliver(WebhookEndpoint{URL: srv.URL, Secret: "wrong"}, event); !errors.Is(err, ErrWebhookDeliveryFail) {
+		t.Errorf("期望推送失败, 得到 %v", err)
+	}
+}
+
+func TestWebhookSigningKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "webhook_signing.json")
+	keyring, err := LoadOrCreateWebhookKeyring(path, time.Hour)
+	if err != nil {
+		t.Fatalf("创建密钥环失败: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("密钥文件权限错误: %v %v", info, err)
+	}
+	first := keyring.PublicKeys()[0]
+
+	// 接收方只凭公布的公钥验签，不需要共享密钥
+	received := make(chan bool, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		pub, err := keyring.PublicKey(r.Header.Get(WebhookKeyIDHeader))
+		ok := err == nil && r.Header.Get(WebhookSignatureHeader) == "" &&
+			VerifyWebhookKeySignature(pub, ts, r.Header.Get(WebhookNonceHeader), body, r.Header.Get(WebhookKeySignatureHeader))
+		received <- ok
+	}))
+	defer srv.Close()
+
+	cfg := DefaultWebhookConfig("node-1")
+	cfg.Keyring = keyring
+	wm, _ := NewWebhookManager(cfg)
+	if err := wm.Deliver(WebhookEndpoint{URL: srv.URL}, wm.NewChangeEvent("node-2", "task", 1, nil)); err != nil {
+		t.Fatalf("推送失败: %v", err)
+	}
+	if !<-received {
+		t.Error("签名密钥验签失败")
+	}
+	if VerifyWebhookKeySignature(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)), 1, "n", []byte("{}"), "AAAA") {
+		t.Error("错误的签名不应通过验证")
+	}
+
+	// 轮换：新密钥签名，旧密钥在宽限期内继续公布
+	now := time.Now()
+	keyring.now = func() time.Time { return now }
+	second, err := keyring.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	if keyID, _ := keyring.Sign(1, "n", nil); keyID != second.KeyID || keyID == first.KeyID {
+		t.Errorf("轮换后应使用新密钥签名, 得到 %s", keyID)
+	}
+	keys := keyring.PublicKeys()
+	if len(keys) != 2 || !keys[0].Active || keys[1].Active || keys[1].ExpiresAt.IsZero() {
+		t.Errorf("公布的公钥错误: %+v", keys)
+	}
+
+	// 重新加载后密钥不变；宽限期过后旧密钥不再公布
+	reloaded, err := LoadOrCreateWebhookKeyring(path, time.Hour)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	reloaded.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if keys := reloaded.PublicKeys(); len(keys) != 1 || keys[0].KeyID != second.KeyID {
+		t.Errorf("宽限期后应只公布新密钥: %+v", keys)
+	}
+	if _, err := reloaded.PublicKey(first.KeyID); err != ErrWebhookKeyNotFound {
+		t.Errorf("期望 ErrWebhookKeyNotFound, 得到 %v", err)
+	}
+}
+
+func TestWebhookCallback(t *testing.T) {
+	const secret = "callback-secret"
+	var applied *ExternalAdjustment
+
+	cfg := DefaultWebhookConfig("node-1")
+	cfg.CallbackSecret = secret
+	cfg.MaxDelta = 10
+	cfg.ApplyAdjustmentFunc = func(adj *ExternalAdjustment) error {
+		applied = adj
+		return nil
+	}
+	wm, _ := NewWebhookManager(cfg)
+
+	body := []byte(`{"node_id":"node-9","delta":-3.5,"reason":"spam score"}`)
+	now := time.Now().Unix()
+
+	adj, err := wm.HandleCallback(signedHeader(secret, now, "n1", body), body)
+	if err != nil {
+		t.Fatalf("回调应被接受: %v", err)
+	}
+	if adj.Source != "external" || applied == nil || applied.Delta != -3.5 {
+		t.Errorf("调整未正确应用: %+v", applied)
+	}
+
+	// 重放
+	if _, err := wm.HandleCallback(signedHeader(secret, now, "n1", body), body); err != ErrReplayedNonce {
+		t.Errorf("期望 ErrReplayedNonce, 得到 %v", err)
+	}
+
+	// 签名错误
+	if _, err := wm.HandleCallback(signedHeader("wrong", now, "n2", body), body); err != ErrInvalidSignature {
+		t.Errorf("期望 ErrInvalidSignature, 得到 %v", err)
+	}
+
+	// 过期时间戳
+	old := now - int64((10 * time.Minute).Seconds())
+	if _, err := wm.HandleCallback(signedHeader(secret, old, "n3", body), body); err != ErrStaleTimestamp {
+		t.Errorf("期望 ErrStaleTimestamp, 得到 %v", err)
+	}
+
+	// 缺少签名头
+	if _, err := wm.HandleCallback(http.Header{}, body); err != ErrMissingSignature {
+		t.Errorf("期望 ErrMissingSignature, 得到 %v", err)
+	}
+
+	// 超出上限
+	big := []byte(`{"node_id":"node-9","delta":100}`)
+	if _, err := wm.HandleCallback(signedHeader(secret, now, "n4", big), big); err != ErrAdjustmentTooLarge {
+		t.Errorf("期望 ErrAdjustmentTooLarge, 得到 %v", err)
+	}
+
+	stats := wm.GetStats()
+	if stats["callbacks_accepted"].(int64) != 1 || stats["callbacks_rejected"].(int64) != 5 {
+		t.Errorf("统计错误: %v", stats)
+	}
+}
+
+func TestWebhookCallbackDisabled(t *testing.T) {
+	wm, _ := NewWebhookManager(DefaultWebhookConfig("node-1"))
+	body := []byte(`{"node_id":"x","delta":1}`)
+	if _, err := wm.HandleCallback(signedHeader("", time.Now().Unix(), "n", body), body); err != ErrCallbackDisabled {
+		t.Errorf("期望 ErrCallbackDisabled, 得到 %v", err)
+	}
+}
+
+func TestSystemChangeHook(t *testing.T) {
+	sys := NewSystem()
+	sys.RegisterAgent("agent-1", 0.5)
+
+	type change struct {
+		source string
+		delta  float64
+	}
+	var changes []change
+	sys.SetChangeHook(func(agentID, source string, delta float64) {
+		changes = append(changes, change{source, delta})
+	})
+
+	sys.AddReputationRecord("agent-1", 0.2, "task", "node-x")
+	sys.AddPenalty("agent-1", 0.1)
+	sys.AddPenalty("unknown", 0.1)
+
+	if len(changes) != 2 {
+		t.Fatalf("期望 2 次变化, 得到 %d", len(changes))
+	}
+	if changes[0] != (change{"task", 0.2}) || changes[1] != (change{"penalty", -0.1}) {
+		t.Errorf("变化记录错误: %+v", changes)
+	}
+}
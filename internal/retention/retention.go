@@ -0,0 +1,397 @@
+// Package retention 实现合规保全（legal hold）
+// 对邮箱会话、争议或账本区间设置保全后，过期清理、GC 与归档都会跳过这些记录，
+// 直到保全被解除。每次设置和解除都写入带哈希链的追加式审计日志，记录操作者身份。
+package retention
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHoldNotFound   = errors.New("retention hold not found")
+	ErrHoldReleased   = errors.New("retention hold already released")
+	ErrActorRequired  = errors.New("actor identity is required")
+	ErrReasonRequired = errors.New("hold reason is required")
+	ErrInvalidKind    = errors.New("invalid hold kind")
+	ErrInvalidTarget  = errors.New("invalid hold target")
+	ErrAuditTampered  = errors.New("retention audit log has been tampered with")
+)
+
+// Kind 保全对象类型
+type Kind string
+
+const (
+	KindMailboxThread Kind = "mailbox_thread" // 邮箱会话（mailbox.ThreadID）
+	KindDispute       Kind = "dispute"        // 争议
+	KindLedgerRange   Kind = "ledger_range"   // 账本序号区间 [RangeStart, RangeEnd]
+)
+
+// 审计动作
+const (
+	ActionHold    = "hold"
+	ActionRelease = "release"
+)
+
+// Hold 保全记录（解除后仍保留）
+type Hold struct {
+	ID         string `json:"id"`
+	Kind       Kind   `json:"kind"`
+	Target     string `json:"target,omitempty"`
+	RangeStart uint64 `json:"range_start,omitempty"`
+	RangeEnd   uint64 `json:"range_end,omitempty"`
+	Reason     string `json:"reason"`
+
+	PlacedBy string    `json:"placed_by"`
+	PlacedAt time.Time `json:"placed_at"`
+
+	ReleasedBy    string     `json:"released_by,omitempty"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `json:"release_reason,omitempty"`
+}
+
+// Active 保全是否仍然有效
+func (h *Hold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+// AuditEntry 审计日志条目
+type AuditEntry struct {
+	Seq       uint64    `json:"seq"`
+	Action    string    `json:"action"`
+	HoldID    string    `json:"hold_id"`
+	Kind      Kind      `json:"kind"`
+	Target    string    `json:"target"` // 区间保全为 "start-end"
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+func (e *AuditEntry) computeHash() string {
+	data := fmt.Sprintf("%d|%s|%s|%s|%s|%s|%s|%d|%s",
+		e.Seq, e.Action, e.HoldID, e.Kind, e.Target, e.Actor, e.Reason,
+		e.Timestamp.UnixNano(), e.PrevHash)
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// Config 保全配置
+type Config struct {
+	DataDir string
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		DataDir: "./data/retention",
+	}
+}
+
+// Manager 保全管理器
+type Manager struct {
+	mu     sync.RWMutex
+	config *Config
+	holds  map[string]*Hold
+	audit  []*AuditEntry
+}
+
+// NewManager 创建保全管理器
+func NewManager(config *Config) (*Manager, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	m := &Manager{
+		config: config,
+		holds:  make(map[string]*Hold),
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		if err := m.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// PlaceHold 对邮箱会话或争议设置保全
+func (m *Manager) PlaceHold(kind Kind, target, reason, actor string) (*Hold, error) {
+	if kind != KindMailboxThread && kind != KindDispute {
+		return nil, ErrInvalidKind
+	}
+	if target == "" {
+		return nil, ErrInvalidTarget
+	}
+	return m.place(&Hold{Kind: kind, Target: target, Reason: reason}, actor)
+}
+
+// PlaceLedgerHold 对账本序号区间 [start, end] 设置保全
+func (m *Manager) PlaceLedgerHold(start, end uint64, reason, actor string) (*Hold, error) {
+	if start == 0 || end < start {
+		return nil, ErrInvalidTarget
+	}
+	return m.place(&Hold{Kind: KindLedgerRange, RangeStart: start, RangeEnd: end, Reason: reason}, actor)
+}
+
+func (m *Manager) place(hold *Hold, actor string) (*Hold, error) {
+	if actor == "" {
+		return nil, ErrActorRequired
+	}
+	if hold.Reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold.ID = generateHoldID()
+	hold.PlacedBy = actor
+	hold.PlacedAt = time.Now()
+
+	if err := m.appendAudit(ActionHold, hold, actor, hold.Reason); err != nil {
+		return nil, err
+	}
+	m.holds[hold.ID] = hold
+	m.save()
+
+	c := *hold
+	return &c, nil
+}
+
+// Release 解除保全
+func (m *Manager) Release(holdID, actor, reason string) (*Hold, error) {
+	if actor == "" {
+		return nil, ErrActorRequired
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, ok := m.holds[holdID]
+	if !ok {
+		return nil, ErrHoldNotFound
+	}
+	if !hold.Active() {
+		return nil, ErrHoldReleased
+	}
+
+	if err := m.appendAudit(ActionRelease, hold, actor, reason); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	hold.ReleasedBy = actor
+	hold.ReleasedAt = &now
+	hold.ReleaseReason = reason
+	m.save()
+
+	c := *hold
+	return &c, nil
+}
+
+// IsHeld 邮箱会话或争议是否处于保全中
+func (m *Manager) IsHeld(kind Kind, target string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, h := range m.holds {
+		if h.Active() && h.Kind == kind && h.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// LedgerRangeHeld 账本区间 [start, end] 中是否有任何序号处于保全中
+func (m *Manager) LedgerRangeHeld(start, end uint64) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, h := range m.holds {
+		if h.Active() && h.Kind == KindLedgerRange && h.RangeStart <= end && start <= h.RangeEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// ListHolds 列出保全记录，includeReleased 为 false 时只返回有效保全
+func (m *Manager) ListHolds(includeReleased bool) []*Hold {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*Hold, 0, len(m.holds))
+	for _, h := range m.holds {
+		if !includeReleased && !h.Active() {
+			continue
+		}
+		c := *h
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].PlacedAt.After(result[j].PlacedAt)
+	})
+	return result
+}
+
+// GetHold 获取保全记录
+func (m *Manager) GetHold(holdID string) (*Hold, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hold, ok := m.holds[holdID]
+	if !ok {
+		return nil, ErrHoldNotFound
+	}
+	c := *hold
+	return &c, nil
+}
+
+// AuditLog 获取审计日志（按时间顺序）
+func (m *Manager) AuditLog() []*AuditEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]*AuditEntry, len(m.audit))
+	for i, e := range m.audit {
+		c := *e
+		result[i] = &c
+	}
+	return result
+}
+
+// VerifyAudit 校验审计日志哈希链
+func (m *Manager) VerifyAudit() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return verifyChain(m.audit)
+}
+
+// ============ 内部方法 ============
+
+func (m *Manager) appendAudit(action string, hold *Hold, actor, reason string) error {
+	entry := &AuditEntry{
+		Seq:       uint64(len(m.audit)) + 1,
+		Action:    action,
+		HoldID:    hold.ID,
+		Kind:      hold.Kind,
+		Target:    holdTarget(hold),
+		Actor:     actor,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+	if n := len(m.audit); n > 0 {
+		entry.PrevHash = m.audit[n-1].Hash
+	}
+	entry.Hash = entry.computeHash()
+
+	// 审计日志只追加，写入失败时不变更保全状态
+	if m.config.DataDir != "" {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(m.auditPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	m.audit = append(m.audit, entry)
+	return nil
+}
+
+func holdTarget(h *Hold) string {
+	if h.Kind == KindLedgerRange {
+		return fmt.Sprintf("%d-%d", h.RangeStart, h.RangeEnd)
+	}
+	return h.Target
+}
+
+func verifyChain(entries []*AuditEntry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != uint64(i)+1 || e.PrevHash != prev || e.computeHash() != e.Hash {
+			return fmt.Errorf("%w at seq %d", ErrAuditTampered, i+1)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+func generateHoldID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "hold_" + hex.EncodeToString(b)
+}
+
+func (m *Manager) auditPath() string {
+	return filepath.Join(m.config.DataDir, "audit.jsonl")
+}
+
+func (m *Manager) save() {
+	if m.config.DataDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.holds, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(m.config.DataDir, "holds.json"), data, 0644)
+}
+
+func (m *Manager) load() error {
+	if data, err := os.ReadFile(filepath.Join(m.config.DataDir, "holds.json")); err == nil {
+		var holds map[string]*Hold
+		if err := json.Unmarshal(data, &holds); err == nil && holds != nil {
+			m.holds = holds
+		}
+	}
+
+	f, err := os.Open(m.auditPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("%w: %v", ErrAuditTampered, err)
+		}
+		m.audit = append(m.audit, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return verifyChain(m.audit)
+}
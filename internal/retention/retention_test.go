@@ -0,0 +1,131 @@
+package retention
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func createTestManager(t *testing.T) (*Manager, *Config) {
+	config := &Config{DataDir: t.TempDir()}
+	m, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return m, config
+}
+
+func TestPlaceAndReleaseHold(t *testing.T) {
+	m, _ := createTestManager(t)
+
+	hold, err := m.PlaceHold(KindDispute, "dispute-1", "litigation", "alice")
+	if err != nil {
+		t.Fatalf("PlaceHold() error = %v", err)
+	}
+	if !m.IsHeld(KindDispute, "dispute-1") {
+		t.Error("争议应处于保全中")
+	}
+	if m.IsHeld(KindMailboxThread, "dispute-1") {
+		t.Error("保全只对指定类型生效")
+	}
+
+	released, err := m.Release(hold.ID, "bob", "case closed")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if released.ReleasedBy != "bob" || released.Active() {
+		t.Errorf("解除记录错误: %+v", released)
+	}
+	if m.IsHeld(KindDispute, "dispute-1") {
+		t.Error("解除后不应再处于保全中")
+	}
+	if _, err := m.Release(hold.ID, "bob", ""); err != ErrHoldReleased {
+		t.Errorf("重复解除应返回 ErrHoldReleased, got %v", err)
+	}
+
+	if len(m.ListHolds(false)) != 0 || len(m.ListHolds(true)) != 1 {
+		t.Error("已解除的保全应保留在历史中")
+	}
+}
+
+func TestHoldValidation(t *testing.T) {
+	m, _ := createTestManager(t)
+
+	tests := []struct {
+		name string
+		fn   func() error
+		want error
+	}{
+		{"缺少操作者", func() error { _, err := m.PlaceHold(KindDispute, "d1", "r", ""); return err }, ErrActorRequired},
+		{"缺少原因", func() error { _, err := m.PlaceHold(KindDispute, "d1", "", "alice"); return err }, ErrReasonRequired},
+		{"无效类型", func() error { _, err := m.PlaceHold(KindLedgerRange, "d1", "r", "alice"); return err }, ErrInvalidKind},
+		{"无效区间", func() error { _, err := m.PlaceLedgerHold(10, 5, "r", "alice"); return err }, ErrInvalidTarget},
+		{"未知保全", func() error { _, err := m.Release("hold_x", "alice", ""); return err }, ErrHoldNotFound},
+	}
+	for _, tt := range tests {
+		if err := tt.fn(); err != tt.want {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if len(m.AuditLog()) != 0 {
+		t.Error("失败的操作不应写入审计日志")
+	}
+}
+
+func TestLedgerRangeHold(t *testing.T) {
+	m, _ := createTestManager(t)
+	m.PlaceLedgerHold(100, 200, "audit", "alice")
+
+	tests := []struct {
+		start, end uint64
+		want       bool
+	}{
+		{1, 99, false},
+		{1, 100, true},
+		{150, 160, true},
+		{200, 300, true},
+		{201, 300, false},
+	}
+	for _, tt := range tests {
+		if got := m.LedgerRangeHeld(tt.start, tt.end); got != tt.want {
+			t.Errorf("LedgerRangeHeld(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestAuditLogPersistence(t *testing.T) {
+	m, config := createTestManager(t)
+
+	hold, _ := m.PlaceHold(KindMailboxThread, "thread_1", "regulator request", "alice")
+	m.PlaceLedgerHold(1, 10, "audit", "carol")
+	m.Release(hold.ID, "bob", "done")
+
+	log := m.AuditLog()
+	if len(log) != 3 {
+		t.Fatalf("审计日志条数 = %d, want 3", len(log))
+	}
+	if log[0].Action != ActionHold || log[0].Actor != "alice" || log[2].Action != ActionRelease || log[2].Actor != "bob" {
+		t.Errorf("审计记录错误: %+v %+v", log[0], log[2])
+	}
+	if log[1].Target != "1-10" {
+		t.Errorf("区间保全目标 = %s, want 1-10", log[1].Target)
+	}
+
+	m2, err := NewManager(config)
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if len(m2.AuditLog()) != 3 || m2.IsHeld(KindMailboxThread, "thread_1") || !m2.LedgerRangeHeld(5, 5) {
+		t.Error("保全状态或审计日志未持久化")
+	}
+
+	// 篡改审计日志后拒绝加载
+	path := filepath.Join(config.DataDir, "audit.jsonl")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), `"actor":"bob"`, `"actor":"mallory"`, 1)), 0644)
+	if _, err := NewManager(config); !errors.Is(err, ErrAuditTampered) {
+		t.Errorf("篡改后应返回 ErrAuditTampered, got %v", err)
+	}
+}
@@ -22,6 +22,9 @@ type SecurityManager struct {
 	// 声誉查询函数
 	getReputation func(nodeID string) float64
 
+	// 隔离时长缩放函数（如按信任网背书距离缩短自动拉黑时长）
+	quarantineScale func(nodeID string) float64
+
 	// 黑名单
 	blacklist map[string]time.Time // nodeID -> 解禁时间
 
@@ -71,6 +74,14 @@ func (sm *SecurityManager) SetReputationFunc(fn func(nodeID string) float64) {
 	sm.messageLimiter.SetReputationFunc(fn)
 }
 
+// SetQuarantineScaleFunc 设置自动隔离时长的缩放函数
+func (sm *SecurityManager) SetQuarantineScaleFunc(fn func(nodeID string) float64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.quarantineScale = fn
+}
+
 // CheckBulletinPublish 检查是否允许发布留言
 func (sm *SecurityManager) CheckBulletinPublish(nodeID string) error {
 	sm.mu.RLock()
@@ -222,7 +233,13 @@ func (sm *SecurityManager) handleSuspiciousBehavior(nodeID string, reason string
 		action = "blocked"
 		// 自动加入黑名单
 		sm.mu.Lock()
-		sm.blacklist[nodeID] = time.Now().Add(2 * time.Hour)
+		duration := 2 * time.Hour
+		if sm.quarantineScale != nil {
+			if scale := sm.quarantineScale(nodeID); scale > 0 {
+				duration = time.Duration(float64(duration) * scale)
+			}
+		}
+		sm.blacklist[nodeID] = time.Now().Add(duration)
 		sm.mu.Unlock()
 	} else if score >= 0.8 {
 		severity = "high"
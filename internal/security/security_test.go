@@ -218,3 +218,25 @@ func TestSecurityManager_Report(t *testing.T) {
 		t.Error("Report timestamp should be set")
 	}
 }
+
+func TestSecurityManager_QuarantineScale(t *testing.T) {
+	sm := NewSecurityManager()
+	sm.SetQuarantineScaleFunc(func(nodeID string) float64 {
+		if nodeID == "endorsed" {
+			return 0.25
+		}
+		return 1.0
+	})
+
+	sm.handleSuspiciousBehavior("endorsed", "burst", 0.95)
+	sm.handleSuspiciousBehavior("stranger", "burst", 0.95)
+
+	endorsed := time.Until(sm.blacklist["endorsed"])
+	stranger := time.Until(sm.blacklist["stranger"])
+	if endorsed > 31*time.Minute || endorsed < 29*time.Minute {
+		t.Errorf("Endorsed node quarantine should be ~30m, got %v", endorsed)
+	}
+	if stranger < 119*time.Minute {
+		t.Errorf("Unendorsed node quarantine should be ~2h, got %v", stranger)
+	}
+}
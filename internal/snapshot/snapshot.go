@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// 跨存储快照读
+//
+// 争议、托管、抵押等存储各自加锁，联合查询依次读取它们时可能夹进其他请求的写入，
+// 得到一半新一半旧的结果。Reader 用版本号做乐观读：读取前后比较各存储的版本号，
+// 期间有写入就整体重读。跨多个存储的写操作通过 Update 执行，快照读不会落在它的中间。
+// 读取函数必须把数据复制出来（如转换为 map），不能把存储内部的对象带出快照。
+
+// DefaultMaxAttempts 版本号一直变化时的最多读取次数
+const DefaultMaxAttempts = 5
+
+var ErrUnstable = errors.New("snapshot read kept conflicting with concurrent writes")
+
+// Source 参与快照读的存储，每次写入后 Version 递增
+type Source interface {
+	Version() uint64
+}
+
+// Snapshot 一次快照读的时间和各存储版本号
+type Snapshot struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Versions  map[string]uint64 `json:"versions"`
+	Attempts  int               `json:"attempts"`
+}
+
+// Reader 跨存储快照读
+type Reader struct {
+	// Update 持读锁，多个跨存储写操作可以并行；Read 持写锁，等待进行中的跨存储写完成
+	mu          sync.RWMutex
+	sources     map[string]Source
+	maxAttempts int
+	now         func() time.Time
+}
+
+// NewReader 创建快照读取器
+func NewReader() *Reader {
+	return &Reader{
+		sources:     make(map[string]Source),
+		maxAttempts: DefaultMaxAttempts,
+		now:         time.Now,
+	}
+}
+
+// AddSource 登记参与快照读的存储
+func (r *Reader) AddSource(name string, s Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources[name] = s
+}
+
+// SetMaxAttempts 设置最多读取次数
+func (r *Reader) SetMaxAttempts(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n > 0 {
+		r.maxAttempts = n
+	}
+}
+
+// Update 执行跨多个存储的写操作，快照读看到的要么是执行前、要么是执行后的状态
+// fn 内不能调用 Read。
+func (r *Reader) Update(fn func() error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return fn()
+}
+
+// Read 在一致的快照上执行 fn
+// fn 可能被执行多次，每次都应从头收集结果；fn 返回错误时不再重试。
+func (r *Reader) Read(fn func() error) (*Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		at := r.now()
+		before := r.versionsLocked()
+		if err := fn(); err != nil {
+			return nil, err
+		}
+		if after := r.versionsLocked(); sameVersions(before, after) {
+			return &Snapshot{Timestamp: at, Versions: after, Attempts: attempt}, nil
+		}
+	}
+	return nil, ErrUnstable
+}
+
+func (r *Reader) versionsLocked() map[string]uint64 {
+	versions := make(map[string]uint64, len(r.sources))
+	for name, s := range r.sources {
+		versions[name] = s.Version()
+	}
+	return versions
+}
+
+func sameVersions(a, b map[string]uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, v := range a {
+		if b[name] != v {
+			return false
+		}
+	}
+	return true
+}
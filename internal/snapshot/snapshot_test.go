@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type counter struct{ v atomic.Uint64 }
+
+func (c *counter) Version() uint64 { return c.v.Load() }
+
+func TestReadRetriesOnConcurrentWrite(t *testing.T) {
+	r := NewReader()
+	a, b := &counter{}, &counter{}
+	r.AddSource("a", a)
+	r.AddSource("b", b)
+
+	calls := 0
+	snap, err := r.Read(func() error {
+		calls++
+		if calls == 1 {
+			b.v.Add(1) // 第一次读取期间有写入
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if calls != 2 || snap.Attempts != 2 || snap.Versions["b"] != 1 || snap.Timestamp.IsZero() {
+		t.Errorf("calls = %d, snapshot = %+v", calls, snap)
+	}
+
+	r.SetMaxAttempts(3)
+	if _, err := r.Read(func() error { a.v.Add(1); return nil }); !errors.Is(err, ErrUnstable) {
+		t.Errorf("expected ErrUnstable, got %v", err)
+	}
+	failed := errors.New("boom")
+	if _, err := r.Read(func() error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+}
+
+func TestReadWaitsForUpdate(t *testing.T) {
+	r := NewReader()
+	src := &counter{}
+	r.AddSource("s", src)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go r.Update(func() error {
+		src.v.Add(1)
+		close(started)
+		<-release
+		src.v.Add(1)
+		return nil
+	})
+	<-started
+
+	done := make(chan *Snapshot)
+	go func() {
+		snap, _ := r.Read(func() error { return nil })
+		done <- snap
+	}()
+	select {
+	case <-done:
+		t.Fatal("Read returned while a cross-store update was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if snap := <-done; snap.Versions["s"] != 2 {
+		t.Errorf("snapshot = %+v, want version 2", snap)
+	}
+}
@@ -0,0 +1,303 @@
+// Package startup 按依赖关系并行启动节点子系统
+// 每个子系统声明依赖的阶段，依赖全部成功后立即在独立的 goroutine 中启动，
+// 互不依赖的子系统（如 P2P 主机、邮箱和留言板的磁盘加载）并发进行。
+// 每个阶段有独立的超时；失败或超时只影响依赖它的阶段（被跳过），
+// 其余子系统照常启动。启动结束后可以得到各阶段的耗时明细。
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicateStage    = errors.New("startup stage already added")
+	ErrUnknownDependency = errors.New("unknown startup stage dependency")
+	ErrUnknownStage      = errors.New("unknown startup stage")
+	ErrStageTimeout      = errors.New("startup stage timed out")
+	ErrDependencyFailed  = errors.New("startup stage dependency failed")
+)
+
+// State 阶段状态
+type State string
+
+const (
+	StatePending State = "pending"
+	StateRunning State = "running"
+	StateOK      State = "ok"
+	StateFailed  State = "failed"
+	StateTimeout State = "timeout"
+	StateSkipped State = "skipped" // 依赖失败，未启动
+)
+
+// Stage 启动阶段
+type Stage struct {
+	Name      string
+	DependsOn []string
+	Timeout   time.Duration // 0 表示使用默认超时
+	Run       func(ctx context.Context) error
+}
+
+// Config 调度配置
+type Config struct {
+	DefaultTimeout time.Duration // 单个阶段的默认超时
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		DefaultTimeout: time.Minute,
+	}
+}
+
+// StageResult 阶段的启动结果
+type StageResult struct {
+	Name      string        `json:"name"`
+	DependsOn []string      `json:"depends_on,omitempty"`
+	State     State         `json:"state"`
+	Error     string        `json:"error,omitempty"`
+	Offset    time.Duration `json:"offset"`   // 相对调度开始的启动时刻
+	Duration  time.Duration `json:"duration"` // 运行耗时（等待依赖的时间不计入）
+}
+
+// Report 启动耗时明细
+type Report struct {
+	Total        time.Duration `json:"total"`
+	Stages       []StageResult `json:"stages"`        // 按启动时刻排序
+	CriticalPath []string      `json:"critical_path"` // 决定总耗时的依赖链
+}
+
+type stage struct {
+	Stage
+	state    State
+	err      error
+	started  time.Time
+	finished time.Time
+	done     chan struct{}
+}
+
+// Scheduler 启动阶段调度器
+// 阶段只能依赖已添加的阶段，因此不会出现循环依赖；
+// 可以在部分阶段运行期间继续添加新阶段。
+type Scheduler struct {
+	mu     sync.Mutex
+	config *Config
+	ctx    context.Context
+	start  time.Time
+	stages map[string]*stage
+	order  []*stage // 添加顺序
+	wg     sync.WaitGroup
+
+	now func() time.Time
+}
+
+// New 创建调度器，ctx 取消时尚未完成的阶段按失败处理
+func New(ctx context.Context, config *Config) *Scheduler {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Scheduler{
+		config: config,
+		ctx:    ctx,
+		start:  time.Now(),
+		stages: make(map[string]*stage),
+		now:    time.Now,
+	}
+}
+
+// Add 添加阶段，依赖全部成功后立即启动
+func (s *Scheduler) Add(st Stage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.stages[st.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateStage, st.Name)
+	}
+	deps := make([]*stage, 0, len(st.DependsOn))
+	for _, name := range st.DependsOn {
+		dep, ok := s.stages[name]
+		if !ok {
+			return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, st.Name, name)
+		}
+		deps = append(deps, dep)
+	}
+	if st.Timeout <= 0 {
+		st.Timeout = s.config.DefaultTimeout
+	}
+	rs := &stage{Stage: st, state: StatePending, done: make(chan struct{})}
+	s.stages[st.Name] = rs
+	s.order = append(s.order, rs)
+
+	s.wg.Add(1)
+	go s.run(rs, deps)
+	return nil
+}
+
+// Go 添加使用默认超时的阶段
+func (s *Scheduler) Go(name string, run func(ctx context.Context) error, dependsOn ...string) error {
+	return s.Add(Stage{Name: name, DependsOn: dependsOn, Run: run})
+}
+
+// run 等待依赖完成后运行阶段
+func (s *Scheduler) run(rs *stage, deps []*stage) {
+	defer s.wg.Done()
+	defer close(rs.done)
+
+	for _, dep := range deps {
+		<-dep.done
+		s.mu.Lock()
+		state := dep.state
+		s.mu.Unlock()
+		if state != StateOK {
+			s.finish(rs, StateSkipped, fmt.Errorf("%w: %s", ErrDependencyFailed, dep.Name))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	rs.state = StateRunning
+	rs.started = s.now()
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, rs.Timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- rs.Run(ctx)
+	}()
+
+	// 超时后不再等待该阶段，它的依赖方被跳过，其余阶段不受影响
+	select {
+	case err := <-result:
+		if err != nil {
+			s.finish(rs, StateFailed, err)
+		} else {
+			s.finish(rs, StateOK, nil)
+		}
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.finish(rs, StateTimeout, fmt.Errorf("%w after %s", ErrStageTimeout, rs.Timeout))
+		} else {
+			s.finish(rs, StateFailed, ctx.Err())
+		}
+	}
+}
+
+func (s *Scheduler) finish(rs *stage, state State, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs.state = state
+	rs.err = err
+	rs.finished = s.now()
+	if rs.started.IsZero() {
+		rs.started = rs.finished
+	}
+}
+
+// Wait 等待指定阶段完成（不指定时等待全部已添加的阶段），返回其中第一个失败阶段的错误
+func (s *Scheduler) Wait(names ...string) error {
+	if len(names) == 0 {
+		s.wg.Wait()
+		s.mu.Lock()
+		for _, rs := range s.order {
+			names = append(names, rs.Name)
+		}
+		s.mu.Unlock()
+	}
+
+	var waiting []*stage
+	s.mu.Lock()
+	for _, name := range names {
+		rs, ok := s.stages[name]
+		if !ok {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %s", ErrUnknownStage, name)
+		}
+		waiting = append(waiting, rs)
+	}
+	s.mu.Unlock()
+
+	for _, rs := range waiting {
+		<-rs.done
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rs := range waiting {
+		if rs.err != nil {
+			return fmt.Errorf("%s: %w", rs.Name, rs.err)
+		}
+	}
+	return nil
+}
+
+// Report 返回当前的启动耗时明细
+func (s *Scheduler) Report() *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &Report{Stages: make([]StageResult, 0, len(s.order))}
+	var last *stage
+	for _, rs := range s.order {
+		result := StageResult{
+			Name:      rs.Name,
+			DependsOn: rs.DependsOn,
+			State:     rs.state,
+		}
+		if rs.err != nil {
+			result.Error = rs.err.Error()
+		}
+		if !rs.started.IsZero() {
+			result.Offset = rs.started.Sub(s.start)
+			if !rs.finished.IsZero() {
+				result.Duration = rs.finished.Sub(rs.started)
+			}
+		}
+		if !rs.finished.IsZero() {
+			if end := rs.finished.Sub(s.start); end > report.Total {
+				report.Total = end
+				last = rs
+			}
+		}
+		report.Stages = append(report.Stages, result)
+	}
+	sort.SliceStable(report.Stages, func(i, j int) bool {
+		return report.Stages[i].Offset < report.Stages[j].Offset
+	})
+
+	// 从最后完成的阶段沿最晚完成的依赖回溯
+	for rs := last; rs != nil; {
+		report.CriticalPath = append([]string{rs.Name}, report.CriticalPath...)
+		var next *stage
+		for _, name := range rs.DependsOn {
+			if dep := s.stages[name]; next == nil || dep.finished.After(next.finished) {
+				next = dep
+			}
+		}
+		rs = next
+	}
+	return report
+}
+
+// String 格式化为可读的耗时明细
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "启动耗时 %s", r.Total.Round(time.Millisecond))
+	if len(r.CriticalPath) > 0 {
+		fmt.Fprintf(&b, "（关键路径: %s）", strings.Join(r.CriticalPath, " → "))
+	}
+	b.WriteString("\n")
+	for _, st := range r.Stages {
+		fmt.Fprintf(&b, "  %-10s %-8s +%-8s %s", st.Name, st.State,
+			st.Offset.Round(time.Millisecond), st.Duration.Round(time.Millisecond))
+		if st.Error != "" {
+			fmt.Fprintf(&b, "  %s", st.Error)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sleepStage(d time.Duration, err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case <-time.After(d):
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestParallelStartup(t *testing.T) {
+	s := New(context.Background(), nil)
+
+	var running, peak int32
+	track := func(d time.Duration) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			defer atomic.AddInt32(&running, -1)
+			return sleepStage(d, nil)(ctx)
+		}
+	}
+
+	s.Go("p2p", track(60*time.Millisecond))
+	s.Go("mailbox", track(40*time.Millisecond))
+	s.Go("bulletin", track(40*time.Millisecond))
+	if err := s.Wait("p2p"); err != nil {
+		t.Fatalf("Wait(p2p) failed: %v", err)
+	}
+	// 依赖已完成的阶段可以随后添加
+	s.Go("http", track(10*time.Millisecond), "p2p")
+	s.Go("admin", track(10*time.Millisecond), "p2p", "mailbox")
+
+	if err := s.Go("http", track(0)); !errors.Is(err, ErrDuplicateStage) {
+		t.Errorf("expected ErrDuplicateStage, got %v", err)
+	}
+	if err := s.Go("grpc", track(0), "missing"); !errors.Is(err, ErrUnknownDependency) {
+		t.Errorf("expected ErrUnknownDependency, got %v", err)
+	}
+
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if peak < 3 {
+		t.Errorf("independent stages should run concurrently, peak concurrency %d", peak)
+	}
+
+	report := s.Report()
+	if len(report.Stages) != 5 || report.Stages[0].State != StateOK {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Total >= 150*time.Millisecond {
+		t.Errorf("parallel startup took %s, expected well below the sequential sum", report.Total)
+	}
+	if got := strings.Join(report.CriticalPath, ","); got != "p2p,http" && got != "p2p,admin" {
+		t.Errorf("unexpected critical path %q", got)
+	}
+	if !strings.Contains(report.String(), "mailbox") {
+		t.Errorf("report should list every stage:\n%s", report)
+	}
+}
+
+func TestStartupFailureIsolation(t *testing.T) {
+	s := New(context.Background(), &Config{DefaultTimeout: time.Second})
+
+	s.Go("mailbox", sleepStage(0, errors.New("disk corrupted")))
+	s.Add(Stage{Name: "bulletin", Timeout: 20 * time.Millisecond, Run: sleepStage(time.Second, nil)})
+	s.Go("p2p", sleepStage(0, nil))
+	s.Go("inbox-sync", sleepStage(0, nil), "mailbox", "p2p")
+	s.Go("templates", sleepStage(0, nil), "bulletin")
+	s.Go("http", sleepStage(0, nil), "p2p")
+
+	if err := s.Wait("http"); err != nil {
+		t.Fatalf("stages unrelated to the failures should start: %v", err)
+	}
+	if err := s.Wait(); err == nil {
+		t.Error("Wait should report the first failed stage")
+	}
+
+	states := make(map[string]StageResult)
+	for _, st := range s.Report().Stages {
+		states[st.Name] = st
+	}
+	if states["mailbox"].State != StateFailed || states["mailbox"].Error != "disk corrupted" {
+		t.Errorf("unexpected mailbox result: %+v", states["mailbox"])
+	}
+	if states["bulletin"].State != StateTimeout {
+		t.Errorf("bulletin should time out: %+v", states["bulletin"])
+	}
+	if states["inbox-sync"].State != StateSkipped || states["templates"].State != StateSkipped {
+		t.Errorf("dependents of failed stages should be skipped: %+v %+v", states["inbox-sync"], states["templates"])
+	}
+	if states["http"].State != StateOK || states["p2p"].State != StateOK {
+		t.Errorf("independent stages should succeed: %+v %+v", states["http"], states["p2p"])
+	}
+}
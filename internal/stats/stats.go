@@ -0,0 +1,165 @@
+// Package stats 模块统计的统一接口与注册表
+// 各管理器实现 Provider 返回带类型的计数器/仪表值，注册到 Registry 后由
+// /api/v1/stats 统一输出快照，并支持按模块查看。
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidModule   = errors.New("invalid stats module")
+	ErrDuplicateModule = errors.New("stats module already registered")
+	ErrUnknownModule   = errors.New("unknown stats module")
+)
+
+// Kind 指标类型
+type Kind string
+
+const (
+	KindCounter Kind = "counter" // 单调递增的累计值
+	KindGauge   Kind = "gauge"   // 可增可减的当前值
+)
+
+// Metric 单个指标
+type Metric struct {
+	Name  string  `json:"name"`
+	Kind  Kind    `json:"kind"`
+	Value float64 `json:"value"`
+	Help  string  `json:"help,omitempty"`
+}
+
+// Counter 构造计数器指标
+func Counter(name string, value float64, help string) Metric {
+	return Metric{Name: name, Kind: KindCounter, Value: value, Help: help}
+}
+
+// Gauge 构造仪表指标
+func Gauge(name string, value float64, help string) Metric {
+	return Metric{Name: name, Kind: KindGauge, Value: value, Help: help}
+}
+
+// Provider 模块统计接口
+type Provider interface {
+	Stats() []Metric
+}
+
+// ProviderFunc 把函数适配为 Provider，用于没有实现接口的模块
+type ProviderFunc func() []Metric
+
+// Stats 实现 Provider
+func (f ProviderFunc) Stats() []Metric {
+	return f()
+}
+
+// ModuleStats 单个模块的统计快照
+type ModuleStats struct {
+	Module      string    `json:"module"`
+	Metrics     []Metric  `json:"metrics"`
+	Error       string    `json:"error,omitempty"` // 采集时 panic 的信息
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+// Value 按名称取指标值
+func (ms *ModuleStats) Value(name string) (float64, bool) {
+	for _, m := range ms.Metrics {
+		if m.Name == name {
+			return m.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Snapshot 全部模块的统计快照
+type Snapshot struct {
+	CollectedAt time.Time      `json:"collected_at"`
+	Modules     []*ModuleStats `json:"modules"`
+}
+
+// Registry 统计注册表
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	now       func() time.Time
+}
+
+// NewRegistry 创建统计注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]Provider),
+		now:       time.Now,
+	}
+}
+
+// Register 注册模块，模块名在注册表内唯一
+func (r *Registry) Register(module string, p Provider) error {
+	if module == "" || p == nil {
+		return ErrInvalidModule
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[module]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateModule, module)
+	}
+	r.providers[module] = p
+	return nil
+}
+
+// Unregister 注销模块
+func (r *Registry) Unregister(module string) {
+	r.mu.Lock()
+	delete(r.providers, module)
+	r.mu.Unlock()
+}
+
+// Modules 返回已注册的模块名（按名称排序）
+func (r *Registry) Modules() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	modules := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		modules = append(modules, name)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// Collect 采集单个模块的统计
+func (r *Registry) Collect(module string) (*ModuleStats, error) {
+	r.mu.RLock()
+	p, ok := r.providers[module]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownModule, module)
+	}
+	return r.collect(module, p), nil
+}
+
+// Snapshot 采集全部模块的统计，单个模块出错不影响其他模块
+func (r *Registry) Snapshot() *Snapshot {
+	snap := &Snapshot{CollectedAt: r.now(), Modules: []*ModuleStats{}}
+	for _, module := range r.Modules() {
+		if ms, err := r.Collect(module); err == nil {
+			snap.Modules = append(snap.Modules, ms)
+		}
+	}
+	return snap
+}
+
+func (r *Registry) collect(module string, p Provider) (ms *ModuleStats) {
+	ms = &ModuleStats{Module: module, Metrics: []Metric{}, CollectedAt: r.now()}
+	defer func() {
+		if v := recover(); v != nil {
+			ms.Metrics = []Metric{}
+			ms.Error = fmt.Sprint(v)
+		}
+	}()
+	if metrics := p.Stats(); metrics != nil {
+		ms.Metrics = metrics
+	}
+	return ms
+}
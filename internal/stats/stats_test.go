@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeModule struct {
+	count int
+}
+
+func (f *fakeModule) Stats() []Metric {
+	f.count++
+	return []Metric{
+		Counter("calls_total", float64(f.count), "采集次数"),
+		Gauge("queue", 3, ""),
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	mod := &fakeModule{}
+	if err := r.Register("mailbox", mod); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register("mailbox", mod); !errors.Is(err, ErrDuplicateModule) {
+		t.Errorf("expected ErrDuplicateModule, got %v", err)
+	}
+	if err := r.Register("", mod); err != ErrInvalidModule {
+		t.Errorf("expected ErrInvalidModule, got %v", err)
+	}
+	r.Register("broken", ProviderFunc(func() []Metric { panic("nil manager") }))
+	r.Register("bulletin", ProviderFunc(func() []Metric { return nil }))
+
+	ms, err := r.Collect("mailbox")
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if v, ok := ms.Value("queue"); !ok || v != 3 {
+		t.Errorf("unexpected queue gauge: %v %v", v, ok)
+	}
+	if ms.Metrics[0].Kind != KindCounter || ms.Metrics[1].Kind != KindGauge {
+		t.Errorf("unexpected metric kinds: %+v", ms.Metrics)
+	}
+	if _, err := r.Collect("unknown"); !errors.Is(err, ErrUnknownModule) {
+		t.Errorf("expected ErrUnknownModule, got %v", err)
+	}
+
+	snap := r.Snapshot()
+	if len(snap.Modules) != 3 {
+		t.Fatalf("expected 3 modules, got %d", len(snap.Modules))
+	}
+	if snap.Modules[0].Module != "broken" || snap.Modules[0].Error != "nil manager" {
+		t.Errorf("panicking provider should be reported, got %+v", snap.Modules[0])
+	}
+	if snap.Modules[1].Metrics == nil {
+		t.Error("nil metrics should be normalized to an empty list")
+	}
+	if v, _ := snap.Modules[2].Value("calls_total"); v != 2 {
+		t.Errorf("counter should advance across collections, got %v", v)
+	}
+
+	r.Unregister("broken")
+	if got := r.Modules(); len(got) != 2 || got[0] != "bulletin" {
+		t.Errorf("unexpected modules after unregister: %v", got)
+	}
+}
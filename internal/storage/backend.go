@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 键值存储后端
+//
+// 各模块原先在每次 save() 时把整个内存表序列化成一个 JSON 文件，记录一多就写不动。
+// Backend 按 bucket 分组保存记录，每条记录单独编码；模块用 Tracker 记下变化的键，
+// 保存时只编码和写入这些记录（见 state.go）。后端不在内存中保留记录值，模块自己的
+// 内存表是唯一的一份。
+//
+// 后端按驱动名打开，内置驱动 "log" 是追加写日志加内存键索引的实现（LogDB），
+// 其他实现（如 BoltDB、SQLite）通过 RegisterDriver 接入。
+
+var (
+	ErrUnknownDriver = errors.New("unknown storage driver")
+	ErrClosed        = errors.New("storage backend closed")
+	ErrCorrupt       = errors.New("storage data corrupt")
+)
+
+// Backend 键值存储后端
+type Backend interface {
+	// Get 读取记录，不存在时返回 ErrKeyNotFound
+	Get(bucket, key string) ([]byte, error)
+	// Put 写入记录
+	Put(bucket, key string, value []byte) error
+	// Delete 删除记录，不存在时不报错
+	Delete(bucket, key string) error
+	// Write 在 bucket 中写入 puts 并删除 deletes 的键，一次落盘；与已存值相同的记录不重复写入，
+	// 返回实际写入和删除的条数
+	Write(bucket string, puts map[string][]byte, deletes []string) (int, error)
+	// ForEach 按键的字典序遍历 bucket
+	ForEach(bucket string, fn func(key string, value []byte) error) error
+	// Keys bucket 中的键，按字典序
+	Keys(bucket string) []string
+	// Len bucket 中的记录数
+	Len(bucket string) int
+	// Compact 整理存储，回收被覆盖和删除的记录（加密后端同时用当前密钥重新加密）
+	Compact() error
+	// Close 关闭后端
+	Close() error
+}
+
+// Sealer 记录加密，crypto.StorageKeyring 满足此接口
+type Sealer interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(data []byte) ([]byte, error)
+}
+
+// Options 打开后端的选项
+type Options struct {
+	Sealer       Sealer  // 非空时每条记录加密落盘
+	SyncWrites   bool    // 每次写入后 fsync
+	CompactRatio float64 // 失效记录数超过有效记录数的该倍数时自动整理，0 表示默认 1
+	CompactMin   int     // 失效记录数低于该值时不自动整理，0 表示默认 1000
+}
+
+// OpenFunc 驱动的打开函数
+type OpenFunc func(path string, opts *Options) (Backend, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]OpenFunc{
+		"log": func(path string, opts *Options) (Backend, error) { return OpenLogDB(path, opts) },
+	}
+)
+
+// RegisterDriver 注册存储驱动，同名驱动会被替换
+func RegisterDriver(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[name] = open
+}
+
+// Drivers 已注册的驱动名
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open 用指定驱动打开存储后端
+func Open(driver, path string, opts *Options) (Backend, error) {
+	driversMu.RLock()
+	open, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+	return open(path, opts)
+}
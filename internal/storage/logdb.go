@@ -0,0 +1,431 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// LogDB 追加写日志存储
+//
+// 每次写入在日志文件末尾追加一行 JSON 记录（put 或 del），打开时按顺序重放，内存中只保留
+// 每个键最新记录在日志中的位置和值的摘要，读取时从文件取回；记录值只在模块的内存表中有一份。
+// 被覆盖和删除的记录积累到一定数量后整理（重写为只含有效记录的新文件后原子替换）。
+// 进程在写入中途退出时，日志末尾不完整的一行在下次打开时被截掉。
+
+// ErrSealed 记录已加密且无法解密
+var ErrSealed = errors.New("storage data is encrypted and cannot be opened")
+
+const (
+	opPut    = "put"
+	opDelete = "del"
+)
+
+// logRecord 日志中的一行
+type logRecord struct {
+	Op     string `json:"op"`
+	Bucket string `json:"b"`
+	Key    string `json:"k"`
+	Value  []byte `json:"v,omitempty"`
+}
+
+// logEntry 键的最新记录在日志中的位置
+type logEntry struct {
+	offset int64
+	size   int               // 行长度（含换行）
+	sum    [sha256.Size]byte // 明文值的摘要，写入相同的值时跳过
+}
+
+// LogDB 追加写日志存储后端
+type LogDB struct {
+	mu      sync.RWMutex
+	path    string
+	opts    Options
+	file    *os.File
+	end     int64 // 日志末尾的位置
+	buckets map[string]map[string]logEntry
+	live    int // 有效记录数
+	dead    int // 日志中已失效的记录数
+	closed  bool
+}
+
+// OpenLogDB 打开或创建日志存储
+func OpenLogDB(path string, opts *Options) (*LogDB, error) {
+	db := &LogDB{
+		path:    path,
+		buckets: make(map[string]map[string]logEntry),
+	}
+	if opts != nil {
+		db.opts = *opts
+	}
+	if db.opts.CompactRatio <= 0 {
+		db.opts.CompactRatio = 1
+	}
+	if db.opts.CompactMin <= 0 {
+		db.opts.CompactMin = 1000
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.replay(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	db.file = file
+	return db, nil
+}
+
+// replay 重放日志，截掉末尾不完整的记录
+func (db *LogDB) replay(file *os.File) error {
+	reader := bufio.NewReader(file)
+	var pos, good int64 // 已读取的字节数、最后一条有效记录的结束位置
+	var corrupt error
+	for {
+		line, err := reader.ReadBytes('\n')
+		start := pos
+		pos += int64(len(line))
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			// 只有最后一行允许损坏（写入中途退出），之后还有记录说明日志本身已损坏
+			if corrupt != nil {
+				return corrupt
+			}
+			var rec logRecord
+			if jerr := json.Unmarshal(line, &rec); jerr != nil {
+				corrupt = fmt.Errorf("%w: %s at offset %d", ErrCorrupt, db.path, good)
+				continue
+			}
+			if aerr := db.replayRecord(&rec, start, len(line)); aerr != nil {
+				return aerr
+			}
+			good = pos
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := file.Truncate(good); err != nil {
+		return err
+	}
+	db.end = good
+	_, err := file.Seek(good, io.SeekStart)
+	return err
+}
+
+func (db *LogDB) replayRecord(rec *logRecord, offset int64, size int) error {
+	switch rec.Op {
+	case opPut:
+		value, err := db.open(rec.Value)
+		if err != nil {
+			return err
+		}
+		db.setLocked(rec.Bucket, rec.Key, logEntry{offset: offset, size: size, sum: sha256.Sum256(value)})
+	case opDelete:
+		db.dead++ // 删除记录本身
+		db.deleteLocked(rec.Bucket, rec.Key)
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrCorrupt, rec.Op)
+	}
+	return nil
+}
+
+// open 解密落盘的记录值
+func (db *LogDB) open(value []byte) ([]byte, error) {
+	if db.opts.Sealer == nil {
+		return value, nil
+	}
+	opened, err := db.opts.Sealer.Open(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSealed, err)
+	}
+	return opened, nil
+}
+
+// setLocked 更新键的位置
+func (db *LogDB) setLocked(bucket, key string, e logEntry) {
+	b := db.buckets[bucket]
+	if b == nil {
+		b = make(map[string]logEntry)
+		db.buckets[bucket] = b
+	}
+	if _, exists := b[key]; exists {
+		db.dead++
+	} else {
+		db.live++
+	}
+	b[key] = e
+}
+
+// deleteLocked 删除键
+func (db *LogDB) deleteLocked(bucket, key string) {
+	b := db.buckets[bucket]
+	if _, exists := b[key]; !exists {
+		return
+	}
+	delete(b, key)
+	if len(b) == 0 {
+		delete(db.buckets, bucket)
+	}
+	db.live--
+	db.dead++
+}
+
+// readLocked 从日志读取键的最新值
+func (db *LogDB) readLocked(e logEntry) ([]byte, error) {
+	line := make([]byte, e.size)
+	if _, err := db.file.ReadAt(line, e.offset); err != nil {
+		return nil, err
+	}
+	var rec logRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return nil, fmt.Errorf("%w: %s at offset %d", ErrCorrupt, db.path, e.offset)
+	}
+	return db.open(rec.Value)
+}
+
+// appendLocked 把一批记录写入日志末尾，返回每条记录的位置
+func (db *LogDB) appendLocked(recs []logRecord) ([]logEntry, error) {
+	var buf bytes.Buffer
+	entries := make([]logEntry, len(recs))
+	for i, rec := range recs {
+		if rec.Op == opPut {
+			entries[i].sum = sha256.Sum256(rec.Value)
+			if db.opts.Sealer != nil {
+				sealed, err := db.opts.Sealer.Seal(rec.Value)
+				if err != nil {
+					return nil, err
+				}
+				rec.Value = sealed
+			}
+		}
+		line, err := json.Marshal(&rec)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].offset = db.end + int64(buf.Len())
+		entries[i].size = len(line) + 1
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if _, err := db.file.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	db.end += int64(buf.Len())
+	if db.opts.SyncWrites {
+		return entries, db.file.Sync()
+	}
+	return entries, nil
+}
+
+// writeLocked 写入日志并更新键的位置，必要时整理
+func (db *LogDB) writeLocked(recs []logRecord) error {
+	if db.closed {
+		return ErrClosed
+	}
+	if len(recs) == 0 {
+		return nil
+	}
+	entries, err := db.appendLocked(recs)
+	if err != nil {
+		return err
+	}
+	for i, rec := range recs {
+		if rec.Op == opPut {
+			db.setLocked(rec.Bucket, rec.Key, entries[i])
+		} else {
+			db.dead++
+			db.deleteLocked(rec.Bucket, rec.Key)
+		}
+	}
+	if db.dead >= db.opts.CompactMin && float64(db.dead) > float64(db.live)*db.opts.CompactRatio {
+		return db.compactLocked()
+	}
+	return nil
+}
+
+// Get 读取记录
+func (db *LogDB) Get(bucket, key string) ([]byte, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return nil, ErrClosed
+	}
+	e, ok := db.buckets[bucket][key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return db.readLocked(e)
+}
+
+// Put 写入记录
+func (db *LogDB) Put(bucket, key string, value []byte) error {
+	_, err := db.Write(bucket, map[string][]byte{key: value}, nil)
+	return err
+}
+
+// Delete 删除记录
+func (db *LogDB) Delete(bucket, key string) error {
+	_, err := db.Write(bucket, nil, []string{key})
+	return err
+}
+
+// Write 追加新增、变化和删除的记录，与已存值相同的记录跳过
+func (db *LogDB) Write(bucket string, puts map[string][]byte, deletes []string) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return 0, ErrClosed
+	}
+	existing := db.buckets[bucket]
+	var recs []logRecord
+	for key, value := range puts {
+		if e, ok := existing[key]; ok && e.sum == sha256.Sum256(value) {
+			continue
+		}
+		recs = append(recs, logRecord{Op: opPut, Bucket: bucket, Key: key, Value: append([]byte(nil), value...)})
+	}
+	for _, key := range deletes {
+		if _, ok := existing[key]; ok {
+			recs = append(recs, logRecord{Op: opDelete, Bucket: bucket, Key: key})
+		}
+	}
+	// 固定写入顺序，便于比对日志
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].Key < recs[j].Key })
+	if err := db.writeLocked(recs); err != nil {
+		return 0, err
+	}
+	return len(recs), nil
+}
+
+// ForEach 按键的字典序遍历 bucket，回调中不能写入同一个后端
+func (db *LogDB) ForEach(bucket string, fn func(key string, value []byte) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return ErrClosed
+	}
+	b := db.buckets[bucket]
+	for _, key := range sortedKeys(b) {
+		value, err := db.readLocked(b[key])
+		if err != nil {
+			return err
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keys bucket 中的键
+func (db *LogDB) Keys(bucket string) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return sortedKeys(db.buckets[bucket])
+}
+
+// Len bucket 中的记录数
+func (db *LogDB) Len(bucket string) int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.buckets[bucket])
+}
+
+func sortedKeys(b map[string]logEntry) []string {
+	keys := make([]string, 0, len(b))
+	for key := range b {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Compact 重写日志，只保留有效记录
+func (db *LogDB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	return db.compactLocked()
+}
+
+func (db *LogDB) compactLocked() error {
+	tmpPath := db.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	// 逐条读回有效记录，用当前密钥重新加密后写入新文件
+	bucketNames := make([]string, 0, len(db.buckets))
+	for name := range db.buckets {
+		bucketNames = append(bucketNames, name)
+	}
+	sort.Strings(bucketNames)
+	var recs []logRecord
+	for _, name := range bucketNames {
+		for _, key := range sortedKeys(db.buckets[name]) {
+			value, err := db.readLocked(db.buckets[name][key])
+			if err != nil {
+				tmp.Close()
+				os.Remove(tmpPath)
+				return err
+			}
+			recs = append(recs, logRecord{Op: opPut, Bucket: name, Key: key, Value: value})
+		}
+	}
+
+	file, end := db.file, db.end
+	db.file, db.end = tmp, 0
+	entries, err := db.appendLocked(recs)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, db.path)
+	}
+	if err != nil {
+		db.file, db.end = file, end
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	file.Close()
+	for i, rec := range recs {
+		db.buckets[rec.Bucket][rec.Key] = entries[i]
+	}
+	db.dead = 0
+	return nil
+}
+
+// Close 关闭日志文件
+func (db *LogDB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+	return db.file.Close()
+}
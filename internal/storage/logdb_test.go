@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// xorSealer 测试用的可逆"加密"
+type xorSealer struct{ key byte }
+
+func (s xorSealer) Seal(p []byte) ([]byte, error) {
+	out := make([]byte, len(p))
+	for i, b := range p {
+		out[i] = b ^ s.key
+	}
+	return out, nil
+}
+
+func (s xorSealer) Open(p []byte) ([]byte, error) {
+	if len(p) > 0 && p[0] == '{' {
+		return nil, errors.New("not sealed with this key")
+	}
+	return s.Seal(p)
+}
+
+func TestLogDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	db, err := Open("log", path, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	db.Put("mail", "m1", []byte(`{"to":"a"}`))
+	db.Put("mail", "m2", []byte(`{"to":"b"}`))
+	db.Put("mail", "m1", []byte(`{"to":"c"}`))
+	db.Delete("mail", "m2")
+	if v, err := db.Get("mail", "m1"); err != nil || string(v) != `{"to":"c"}` {
+		t.Errorf("Get m1 = %s, %v", v, err)
+	}
+	if _, err := db.Get("mail", "m2"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("deleted key: expected ErrKeyNotFound, got %v", err)
+	}
+
+	// 与已存值相同的记录不重复写入，不存在的键不写删除记录
+	n, err := db.Write("mail", map[string][]byte{"m1": []byte(`{"to":"c"}`), "m3": []byte(`{"to":"d"}`)}, []string{"m2"})
+	if err != nil || n != 1 {
+		t.Errorf("Write wrote %d records (err %v), want 1", n, err)
+	}
+	if n, _ := db.Write("mail", nil, []string{"m1"}); n != 1 || db.Len("mail") != 1 {
+		t.Errorf("Write should delete m1: wrote %d, len %d", n, db.Len("mail"))
+	}
+	if keys := db.Keys("mail"); len(keys) != 1 || keys[0] != "m3" {
+		t.Errorf("Keys = %v, want [m3]", keys)
+	}
+	db.Close()
+	if err := db.Put("mail", "x", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	// 重放日志，截掉写入中途退出留下的半行
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"op":"put","b":"mail","k":"m9"`)
+	f.Close()
+	db, err = Open("log", path, nil)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if db.Len("mail") != 1 {
+		t.Errorf("replayed %d records, want 1", db.Len("mail"))
+	}
+	db.Put("mail", "m4", []byte(`{"to":"e"}`))
+	db.Close()
+	db, err = Open("log", path, nil)
+	if err != nil || db.Len("mail") != 2 {
+		t.Fatalf("reopen after truncated tail: len %d, err %v", db.Len("mail"), err)
+	}
+	db.Close()
+
+	// 中间的损坏记录不能静默跳过
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append([]byte("garbage\n"), data...), 0600)
+	if _, err := Open("log", path, nil); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+
+	if _, err := Open("nope", path, nil); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+}
+
+func TestLogDBCompactAndSeal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.db")
+	opts := &Options{Sealer: xorSealer{key: 0x5a}, CompactMin: 10}
+	db, err := OpenLogDB(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		db.Put("counter", "n", []byte{'{', byte('0' + i%10), '}'})
+	}
+	db.Close()
+
+	// 自动整理后日志只剩少量记录，且落盘内容已加密
+	data, _ := os.ReadFile(path)
+	if lines := bytes.Count(data, []byte("\n")); lines > 11 {
+		t.Errorf("log has %d lines after compaction", lines)
+	}
+	if bytes.Contains(data, []byte("ezl9")) { // base64("{9}")
+		t.Error("values should be sealed on disk")
+	}
+
+	db, err = OpenLogDB(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("counter", "n"); string(v) != "{9}" {
+		t.Errorf("value after reopen = %q, want {9}", v)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	db.Close()
+	if data, _ := os.ReadFile(path); bytes.Count(data, []byte("\n")) != 1 {
+		t.Errorf("compacted log should hold exactly one record:\n%s", data)
+	}
+
+	if _, err := OpenLogDB(path, nil); err != nil {
+		t.Fatalf("open without sealer: %v", err)
+	}
+	os.WriteFile(path, []byte(`{"op":"put","b":"x","k":"y","v":"e30="}`+"\n"), 0600)
+	if _, err := OpenLogDB(path, opts); !errors.Is(err, ErrSealed) {
+		t.Errorf("expected ErrSealed for an unsealed record, got %v", err)
+	}
+}
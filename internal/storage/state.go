@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 模块状态与后端之间的映射
+//
+// 模块的持久化结构（原先整体写成一个 JSON 文件）按字段拆开保存：
+// map[string]T 字段各占一个 bucket "<prefix>/<json 字段名>"，每个值一条记录；
+// 其余字段合在 bucket "<prefix>" 的 "state" 记录里。模块修改记录时在 Tracker 中
+// 记下字段和键，保存时只编码这些记录，保存的开销与变化的记录数成正比而不是与总数成正比。
+
+const stateKey = "state"
+
+// Tracker 记录模块自上次保存以来变化的记录，模块在修改内存表的同时调用 Mark
+type Tracker struct {
+	mu   sync.Mutex
+	full bool
+	keys map[string]map[string]struct{} // 字段 JSON 名 -> 键
+}
+
+// NewTracker 创建变化记录
+func NewTracker() *Tracker {
+	return &Tracker{keys: make(map[string]map[string]struct{})}
+}
+
+// Mark 记下字段中变化的键（新增、修改或删除），nil Tracker 上调用不做任何事
+func (t *Tracker) Mark(field string, keys ...string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.full {
+		return
+	}
+	set := t.keys[field]
+	if set == nil {
+		set = make(map[string]struct{}, len(keys))
+		t.keys[field] = set
+	}
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+}
+
+// MarkAll 下次保存时写入全部记录并删除后端中多余的记录（导入旧数据或整体替换内存表后调用）
+func (t *Tracker) MarkAll() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.full = true
+	t.keys = make(map[string]map[string]struct{})
+}
+
+// take 取出并清空已记下的变化
+func (t *Tracker) take() (bool, map[string]map[string]struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	full, keys := t.full, t.keys
+	t.full, t.keys = false, make(map[string]map[string]struct{})
+	return full, keys
+}
+
+// restore 写入失败后放回取出的变化，下次保存时重试
+func (t *Tracker) restore(full bool, keys map[string]map[string]struct{}) {
+	if full {
+		t.MarkAll()
+		return
+	}
+	for field, set := range keys {
+		for key := range set {
+			t.Mark(field, key)
+		}
+	}
+}
+
+// Snapshot 编码后的模块状态，可在模块锁内编码、锁外写入
+type Snapshot struct {
+	prefix  string
+	full    bool // 全量快照：写入时删除后端中不在快照里的记录
+	buckets map[string]map[string][]byte
+	deletes map[string][]string
+
+	tracker *Tracker
+	marked  map[string]map[string]struct{}
+}
+
+// EncodeState 编码模块的持久化结构，state 为结构体或结构体指针。tracker 为 nil 或调用过 MarkAll 时
+// 编码全部记录，否则只编码其中记下的记录，记下但已不在内存表中的键在写入时删除。
+// 非 map 字段每次都编码，未变化时由后端跳过。
+func EncodeState(prefix string, state interface{}, tracker *Tracker) (*Snapshot, error) {
+	v := reflect.Indirect(reflect.ValueOf(state))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: state must be a struct, got %s", ErrInvalidData, v.Kind())
+	}
+
+	snap := &Snapshot{
+		prefix:  prefix,
+		full:    true,
+		buckets: make(map[string]map[string][]byte),
+		deletes: make(map[string][]string),
+		tracker: tracker,
+	}
+	if tracker != nil {
+		snap.full, snap.marked = tracker.take()
+	}
+	fail := func(err error) (*Snapshot, error) {
+		if tracker != nil {
+			tracker.restore(snap.full, snap.marked)
+		}
+		return nil, err
+	}
+
+	meta := make(map[string]json.RawMessage)
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := fieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if isRecordMap(field.Type()) {
+			bucket := prefix + "/" + name
+			if snap.full {
+				records := make(map[string][]byte, field.Len())
+				iter := field.MapRange()
+				for iter.Next() {
+					data, err := json.Marshal(iter.Value().Interface())
+					if err != nil {
+						return fail(fmt.Errorf("encode %s/%s: %w", name, iter.Key().String(), err))
+					}
+					records[iter.Key().String()] = data
+				}
+				snap.buckets[bucket] = records
+				continue
+			}
+			for key := range snap.marked[name] {
+				value := field.MapIndex(reflect.ValueOf(key).Convert(field.Type().Key()))
+				if !value.IsValid() {
+					snap.deletes[bucket] = append(snap.deletes[bucket], key)
+					continue
+				}
+				data, err := json.Marshal(value.Interface())
+				if err != nil {
+					return fail(fmt.Errorf("encode %s/%s: %w", name, key, err))
+				}
+				if snap.buckets[bucket] == nil {
+					snap.buckets[bucket] = make(map[string][]byte)
+				}
+				snap.buckets[bucket][key] = data
+			}
+			continue
+		}
+		data, err := json.Marshal(field.Interface())
+		if err != nil {
+			return fail(fmt.Errorf("encode %s: %w", name, err))
+		}
+		meta[name] = data
+	}
+	if len(meta) > 0 {
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return fail(err)
+		}
+		snap.buckets[prefix] = map[string][]byte{stateKey: data}
+	}
+	return snap, nil
+}
+
+// Write 把快照写入后端，返回实际写入和删除的记录数；失败时快照中的变化放回 Tracker
+func (s *Snapshot) Write(b Backend) (int, error) {
+	n, err := s.write(b)
+	if err != nil && s.tracker != nil {
+		s.tracker.restore(s.full, s.marked)
+	}
+	return n, err
+}
+
+func (s *Snapshot) write(b Backend) (int, error) {
+	names := make([]string, 0, len(s.buckets)+len(s.deletes))
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	for name := range s.deletes {
+		if _, ok := s.buckets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	total := 0
+	for _, name := range names {
+		puts, deletes := s.buckets[name], s.deletes[name]
+		if s.full {
+			for _, key := range b.Keys(name) {
+				if _, ok := puts[key]; !ok {
+					deletes = append(deletes, key)
+				}
+			}
+		}
+		n, err := b.Write(name, puts, deletes)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// SaveState 编码并写入模块的全部状态
+func SaveState(b Backend, prefix string, state interface{}) (int, error) {
+	snap, err := EncodeState(prefix, state, nil)
+	if err != nil {
+		return 0, err
+	}
+	return snap.Write(b)
+}
+
+// LoadState 从后端读取模块状态到 state（结构体指针），后端中没有该模块的数据时返回 false
+func LoadState(b Backend, prefix string, state interface{}) (bool, error) {
+	v := reflect.ValueOf(state)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return false, fmt.Errorf("%w: state must be a struct pointer", ErrInvalidData)
+	}
+	v = v.Elem()
+
+	found := false
+	var meta map[string]json.RawMessage
+	if data, err := b.Get(prefix, stateKey); err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return false, fmt.Errorf("%w: %s state: %v", ErrInvalidData, prefix, err)
+		}
+		found = true
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return false, err
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := fieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if isRecordMap(field.Type()) {
+			bucket := prefix + "/" + name
+			if b.Len(bucket) == 0 {
+				continue
+			}
+			found = true
+			m := reflect.MakeMap(field.Type())
+			err := b.ForEach(bucket, func(key string, value []byte) error {
+				elem := reflect.New(field.Type().Elem())
+				if err := json.Unmarshal(value, elem.Interface()); err != nil {
+					return fmt.Errorf("%w: %s/%s: %v", ErrInvalidData, bucket, key, err)
+				}
+				m.SetMapIndex(reflect.ValueOf(key).Convert(field.Type().Key()), elem.Elem())
+				return nil
+			})
+			if err != nil {
+				return false, err
+			}
+			field.Set(m)
+			continue
+		}
+		if raw, ok := meta[name]; ok {
+			if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+				return false, fmt.Errorf("%w: %s.%s: %v", ErrInvalidData, prefix, name, err)
+			}
+		}
+	}
+	return found, nil
+}
+
+// RetireLegacy 导入后备份旧的 JSON 文件（重命名为 .migrated），文件不存在时不报错
+func RetireLegacy(path string) error {
+	if err := os.Rename(path, path+".migrated"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// fieldName 字段的 JSON 名称，不参与序列化的字段返回 false
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// isRecordMap 以字符串为键的 map 按记录拆分保存
+func isRecordMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type testState struct {
+	Records  map[string]*testRecord    `json:"records"`
+	Groups   map[string]map[string]int `json:"groups"`
+	Seen     map[string][]time.Time    `json:"seen,omitempty"`
+	Pinned   []string                  `json:"pinned"`
+	LastScan time.Time                 `json:"last_scan"`
+	Ignored  string                    `json:"-"`
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	db, err := OpenLogDB(filepath.Join(t.TempDir(), "node.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var empty testState
+	if found, err := LoadState(db, "mod", &empty); found || err != nil {
+		t.Fatalf("empty backend: found %v, err %v", found, err)
+	}
+
+	scan := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	state := &testState{
+		Records:  map[string]*testRecord{"a": {Name: "a", Count: 1}, "b": {Name: "b", Count: 2}},
+		Groups:   map[string]map[string]int{"g": {"x": 1}},
+		Pinned:   []string{"a"},
+		LastScan: scan,
+		Ignored:  "not stored",
+	}
+	if n, err := SaveState(db, "mod", state); err != nil || n != 4 {
+		t.Fatalf("SaveState wrote %d records (err %v), want 4", n, err)
+	}
+
+	// 增量保存只编码记下的记录，记下但已删除的键从后端删除
+	tracker := NewTracker()
+	state.Records["b"].Count = 3
+	state.Records["c"] = &testRecord{Name: "c"}
+	delete(state.Records, "a")
+	tracker.Mark("records", "a", "b")
+	snap, err := EncodeState("mod", state, tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := snap.Write(db); n != 2 {
+		t.Errorf("incremental save wrote %d records, want 2", n)
+	}
+	if _, err := db.Get("mod/records", "c"); err == nil {
+		t.Error("unmarked record should not be written")
+	}
+
+	// 写入失败时变化放回 Tracker，MarkAll 后全量写入并清理多余的记录
+	db.Put("mod/records", "stale", []byte("{}"))
+	tracker.MarkAll()
+	snap, _ = EncodeState("mod", state, tracker)
+	db.Close()
+	if _, err := snap.Write(db); err == nil {
+		t.Fatal("write to a closed backend should fail")
+	}
+	db, err = OpenLogDB(db.path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	snap, _ = EncodeState("mod", state, tracker)
+	if n, err := snap.Write(db); err != nil || n != 2 {
+		t.Errorf("full save wrote %d records (err %v), want 2", n, err)
+	}
+
+	var loaded testState
+	found, err := LoadState(db, "mod", &loaded)
+	if err != nil || !found {
+		t.Fatalf("LoadState: found %v, err %v", found, err)
+	}
+	if len(loaded.Records) != 2 || loaded.Records["b"].Count != 3 || loaded.Records["c"] == nil {
+		t.Errorf("unexpected records: %+v", loaded.Records)
+	}
+	if loaded.Groups["g"]["x"] != 1 || len(loaded.Pinned) != 1 || !loaded.LastScan.Equal(scan) {
+		t.Errorf("unexpected state: %+v", loaded)
+	}
+	if loaded.Ignored != "" || loaded.Seen != nil {
+		t.Errorf("ignored and empty fields should stay zero: %+v", loaded)
+	}
+}
+
+func TestRetireLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	os.WriteFile(path, []byte("{}"), 0644)
+	if err := RetireLegacy(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".migrated"); err != nil {
+		t.Errorf("legacy file should be kept as .migrated: %v", err)
+	}
+	if err := RetireLegacy(path); err != nil {
+		t.Errorf("missing legacy file should not be an error: %v", err)
+	}
+}
@@ -149,6 +149,9 @@ func (ai *AuditIntegration) handleAuditorDeviation(deviation *AuditDeviation) {
 			fmt.Sprintf("actual:%s", deviation.ActualResult),
 			fmt.Sprintf("severity:%s", deviation.Severity),
 		}
+		if violationEvent != nil {
+			evidence = append(evidence, fmt.Sprintf("violation_event:%s", violationEvent.Hash))
+		}
 
 		slashEvent, err = ai.collateralMgr.SlashByNodePurpose(
 			deviation.AuditorID,
@@ -236,6 +239,9 @@ func (ai *AuditIntegration) ManualPenalty(deviation *AuditDeviation) (*ledger.Ev
 			fmt.Sprintf("audit_id:%s", deviation.AuditID),
 			fmt.Sprintf("severity:%s", deviation.Severity),
 		}
+		if violationEvent != nil {
+			evidence = append(evidence, fmt.Sprintf("violation_event:%s", violationEvent.Hash))
+		}
 
 		var err error
 		slashEvent, err = ai.collateralMgr.SlashByNodePurpose(
@@ -0,0 +1,304 @@
+// Package supernode - scoreboard.go
+// 超级节点履职记分板：按周期统计审计完成率、在线率和响应延迟，
+// 连续多个周期不达标的超级节点会被自动提交降级治理提案。
+
+package supernode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 每个节点保留的周期评估历史条数
+const maxEpochHistory = 10
+
+// ScoreboardConfig 记分板配置
+type ScoreboardConfig struct {
+	EpochDuration      time.Duration // 评估周期
+	MinAuditCompletion float64       // 最低审计完成率 (0-1)
+	MinUptime          float64       // 最低在线率 (0-1)
+	MaxAvgLatency      time.Duration // 最大平均响应延迟
+	DemotionEpochs     int           // 连续不达标多少个周期后提议降级
+}
+
+// DefaultScoreboardConfig 返回默认记分板配置
+func DefaultScoreboardConfig() *ScoreboardConfig {
+	return &ScoreboardConfig{
+		EpochDuration:      24 * time.Hour,
+		MinAuditCompletion: 0.8,
+		MinUptime:          0.9,
+		MaxAvgLatency:      2 * time.Second,
+		DemotionEpochs:     3,
+	}
+}
+
+// DemotionProposer 提交降级治理提案，返回提案ID
+type DemotionProposer func(nodeID, reason string) (string, error)
+
+// DutyMetrics 履职指标
+type DutyMetrics struct {
+	AuditsAssigned  int   `json:"audits_assigned"`
+	AuditsCompleted int   `json:"audits_completed"`
+	UptimeChecks    int   `json:"uptime_checks"`
+	UptimeOK        int   `json:"uptime_ok"`
+	LatencyTotalMs  int64 `json:"latency_total_ms"`
+	LatencySamples  int   `json:"latency_samples"`
+}
+
+// CompletionRate 审计完成率，未分配审计时视为 1
+func (m DutyMetrics) CompletionRate() float64 {
+	if m.AuditsAssigned == 0 {
+		return 1
+	}
+	return float64(m.AuditsCompleted) / float64(m.AuditsAssigned)
+}
+
+// Uptime 在线率，未探测时视为 1
+func (m DutyMetrics) Uptime() float64 {
+	if m.UptimeChecks == 0 {
+		return 1
+	}
+	return float64(m.UptimeOK) / float64(m.UptimeChecks)
+}
+
+// AvgLatency 平均响应延迟
+func (m DutyMetrics) AvgLatency() time.Duration {
+	if m.LatencySamples == 0 {
+		return 0
+	}
+	return time.Duration(m.LatencyTotalMs/int64(m.LatencySamples)) * time.Millisecond
+}
+
+func (m *DutyMetrics) add(o DutyMetrics) {
+	m.AuditsAssigned += o.AuditsAssigned
+	m.AuditsCompleted += o.AuditsCompleted
+	m.UptimeChecks += o.UptimeChecks
+	m.UptimeOK += o.UptimeOK
+	m.LatencyTotalMs += o.LatencyTotalMs
+	m.LatencySamples += o.LatencySamples
+}
+
+// EpochResult 单个周期的评估结果
+type EpochResult struct {
+	Epoch    int64       `json:"epoch"`
+	Metrics  DutyMetrics `json:"metrics"`
+	Passed   bool        `json:"passed"`
+	Reasons  []string    `json:"reasons,omitempty"` // 不达标原因
+	ClosedAt time.Time   `json:"closed_at"`
+}
+
+// DutyScore 超级节点履职记录
+type DutyScore struct {
+	NodeID             string        `json:"node_id"`
+	Current            DutyMetrics   `json:"current"`  // 当前周期
+	Lifetime           DutyMetrics   `json:"lifetime"` // 累计（不含当前周期）
+	History            []EpochResult `json:"history"`
+	FailedEpochs       int           `json:"failed_epochs"` // 连续不达标周期数
+	DemotionProposalID string        `json:"demotion_proposal_id,omitempty"`
+	DemotionProposedAt time.Time     `json:"demotion_proposed_at,omitempty"`
+}
+
+// scoreOf 获取或创建节点的履职记录（调用方需持有写锁）
+func (s *SuperNodeManager) scoreOf(nodeID string) *DutyScore {
+	score, ok := s.scores[nodeID]
+	if !ok {
+		score = &DutyScore{NodeID: nodeID}
+		s.scores[nodeID] = score
+	}
+	return score
+}
+
+// SetDemotionProposer 设置降级提案函数（通常对接 voting.CreateProposal(VoteDemote, ...)）
+func (s *SuperNodeManager) SetDemotionProposer(fn DemotionProposer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.demotionProposer = fn
+}
+
+// RecordUptimeProbe 记录一次在线探测结果
+func (s *SuperNodeManager) RecordUptimeProbe(nodeID string, online bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.superNodes[nodeID]; !ok {
+		return
+	}
+	score := s.scoreOf(nodeID)
+	score.Current.UptimeChecks++
+	if online {
+		score.Current.UptimeOK++
+	}
+}
+
+// RecordResponseLatency 记录一次请求响应延迟
+func (s *SuperNodeManager) RecordResponseLatency(nodeID string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.superNodes[nodeID]; !ok {
+		return
+	}
+	score := s.scoreOf(nodeID)
+	score.Current.LatencyTotalMs += latency.Milliseconds()
+	score.Current.LatencySamples++
+}
+
+// evaluateEpoch 按阈值评估一个周期的指标
+func (s *SuperNodeManager) evaluateEpoch(m DutyMetrics) []string {
+	cfg := s.config.Scoreboard
+	var reasons []string
+	if rate := m.CompletionRate(); rate < cfg.MinAuditCompletion {
+		reasons = append(reasons, fmt.Sprintf("audit completion %.0f%% < %.0f%%", rate*100, cfg.MinAuditCompletion*100))
+	}
+	if up := m.Uptime(); up < cfg.MinUptime {
+		reasons = append(reasons, fmt.Sprintf("uptime %.0f%% < %.0f%%", up*100, cfg.MinUptime*100))
+	}
+	if cfg.MaxAvgLatency > 0 {
+		if lat := m.AvgLatency(); lat > cfg.MaxAvgLatency {
+			reasons = append(reasons, fmt.Sprintf("avg latency %s > %s", lat, cfg.MaxAvgLatency))
+		}
+	}
+	return reasons
+}
+
+// CloseEpoch 结束当前评估周期
+// 评估每个活跃超级节点，对连续不达标达到阈值的节点提交降级提案，返回本次提议降级的节点
+func (s *SuperNodeManager) CloseEpoch() []string {
+	s.mu.Lock()
+	now := time.Now()
+	epoch := s.epoch
+	s.epoch++
+
+	type pending struct {
+		nodeID string
+		reason string
+	}
+	var toPropose []pending
+
+	for nodeID, sn := range s.superNodes {
+		if !sn.IsActive {
+			continue
+		}
+		score := s.scoreOf(nodeID)
+		reasons := s.evaluateEpoch(score.Current)
+		result := EpochResult{
+			Epoch:    epoch,
+			Metrics:  score.Current,
+			Passed:   len(reasons) == 0,
+			Reasons:  reasons,
+			ClosedAt: now,
+		}
+		score.History = append(score.History, result)
+		if len(score.History) > maxEpochHistory {
+			score.History = score.History[len(score.History)-maxEpochHistory:]
+		}
+		score.Lifetime.add(score.Current)
+		score.Current = DutyMetrics{}
+
+		if result.Passed {
+			score.FailedEpochs = 0
+			continue
+		}
+		score.FailedEpochs++
+		if score.FailedEpochs >= s.config.Scoreboard.DemotionEpochs && score.DemotionProposalID == "" {
+			toPropose = append(toPropose, pending{
+				nodeID: nodeID,
+				reason: fmt.Sprintf("supernode below duty thresholds for %d epochs: %s",
+					score.FailedEpochs, strings.Join(reasons, "; ")),
+			})
+		}
+	}
+	proposer := s.demotionProposer
+	s.mu.Unlock()
+
+	if proposer == nil {
+		return nil
+	}
+
+	// 提案函数可能涉及网络广播，不持锁调用
+	var proposed []string
+	for _, p := range toPropose {
+		proposalID, err := proposer(p.nodeID, p.reason)
+		if err != nil {
+			fmt.Printf("Warning: failed to propose demotion of %s: %v\n", p.nodeID, err)
+			continue
+		}
+		s.mu.Lock()
+		score := s.scoreOf(p.nodeID)
+		score.DemotionProposalID = proposalID
+		score.DemotionProposedAt = time.Now()
+		s.mu.Unlock()
+		proposed = append(proposed, p.nodeID)
+	}
+	sort.Strings(proposed)
+	return proposed
+}
+
+// ResolveDemotion 处理降级提案结果
+// 通过则移除超级节点；否则清除提案标记并重新开始计数
+func (s *SuperNodeManager) ResolveDemotion(nodeID string, demoted bool) error {
+	s.mu.Lock()
+	score, ok := s.scores[nodeID]
+	if !ok || score.DemotionProposalID == "" {
+		s.mu.Unlock()
+		return errors.New("no pending demotion proposal")
+	}
+	score.DemotionProposalID = ""
+	score.DemotionProposedAt = time.Time{}
+	if !demoted {
+		score.FailedEpochs = 0
+	}
+	s.mu.Unlock()
+
+	if demoted {
+		return s.RemoveSuperNode(nodeID, "demoted by governance proposal")
+	}
+	return nil
+}
+
+// GetDutyScore 获取单个超级节点的履职记录
+func (s *SuperNodeManager) GetDutyScore(nodeID string) (*DutyScore, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	score, ok := s.scores[nodeID]
+	if !ok {
+		return nil, errors.New("duty score not found")
+	}
+	return copyDutyScore(score), nil
+}
+
+// GetScoreboard 获取所有超级节点的履职记录（按连续不达标周期数降序）
+func (s *SuperNodeManager) GetScoreboard() []*DutyScore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*DutyScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		result = append(result, copyDutyScore(score))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].FailedEpochs != result[j].FailedEpochs {
+			return result[i].FailedEpochs > result[j].FailedEpochs
+		}
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
+
+// CurrentEpoch 当前评估周期序号
+func (s *SuperNodeManager) CurrentEpoch() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.epoch
+}
+
+func copyDutyScore(score *DutyScore) *DutyScore {
+	c := *score
+	c.History = make([]EpochResult, len(score.History))
+	copy(c.History, score.History)
+	return &c
+}
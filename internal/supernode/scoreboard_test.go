@@ -0,0 +1,184 @@
+package supernode
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func addTestSuperNodes(sm *SuperNodeManager, ids ...string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, id := range ids {
+		sm.superNodes[id] = &SuperNode{NodeID: id, IsActive: true}
+	}
+}
+
+func TestScoreboardAuditTracking(t *testing.T) {
+	sm := createTestManager(t)
+	addTestSuperNodes(sm, "super-001", "super-002", "super-003")
+
+	audit, _ := sm.CreateAudit(AuditTask, "target-001")
+	sm.SubmitAuditResult(audit.ID, audit.Auditors[0], ResultPass, "ok")
+
+	done, err := sm.GetDutyScore(audit.Auditors[0])
+	if err != nil {
+		t.Fatalf("GetDutyScore() error = %v", err)
+	}
+	if done.Current.AuditsAssigned != 1 || done.Current.AuditsCompleted != 1 {
+		t.Errorf("已完成审计者指标错误: %+v", done.Current)
+	}
+
+	idle, _ := sm.GetDutyScore(audit.Auditors[1])
+	if idle.Current.CompletionRate() != 0 {
+		t.Errorf("未提交审计者完成率 = %v, want 0", idle.Current.CompletionRate())
+	}
+
+	if _, err := sm.GetDutyScore("unknown"); err == nil {
+		t.Error("未知节点应返回错误")
+	}
+}
+
+func TestScoreboardMetrics(t *testing.T) {
+	sm := createTestManager(t)
+	addTestSuperNodes(sm, "super-001")
+
+	sm.RecordUptimeProbe("super-001", true)
+	sm.RecordUptimeProbe("super-001", false)
+	sm.RecordResponseLatency("super-001", 100*time.Millisecond)
+	sm.RecordResponseLatency("super-001", 300*time.Millisecond)
+	// 非超级节点的指标不记录
+	sm.RecordUptimeProbe("normal-node", true)
+
+	score, _ := sm.GetDutyScore("super-001")
+	if score.Current.Uptime() != 0.5 {
+		t.Errorf("Uptime() = %v, want 0.5", score.Current.Uptime())
+	}
+	if score.Current.AvgLatency() != 200*time.Millisecond {
+		t.Errorf("AvgLatency() = %v, want 200ms", score.Current.AvgLatency())
+	}
+	if _, err := sm.GetDutyScore("normal-node"); err == nil {
+		t.Error("非超级节点不应有履职记录")
+	}
+}
+
+func TestScoreboardDemotion(t *testing.T) {
+	sm := createTestManager(t)
+	sm.config.Scoreboard.DemotionEpochs = 2
+	addTestSuperNodes(sm, "super-good", "super-bad")
+
+	var proposals []string
+	sm.SetDemotionProposer(func(nodeID, reason string) (string, error) {
+		proposals = append(proposals, nodeID)
+		if reason == "" {
+			t.Error("降级提案应包含原因")
+		}
+		return "proposal-" + nodeID, nil
+	})
+
+	runEpoch := func() []string {
+		sm.RecordUptimeProbe("super-good", true)
+		sm.RecordUptimeProbe("super-bad", false)
+		return sm.CloseEpoch()
+	}
+
+	if got := runEpoch(); len(got) != 0 {
+		t.Errorf("第一个周期不应提议降级, got %v", got)
+	}
+	got := runEpoch()
+	if len(got) != 1 || got[0] != "super-bad" {
+		t.Fatalf("第二个周期应提议降级 super-bad, got %v", got)
+	}
+	// 已有进行中的提案时不重复提议
+	if got := runEpoch(); len(got) != 0 {
+		t.Errorf("不应重复提议, got %v", got)
+	}
+	if len(proposals) != 1 {
+		t.Errorf("提案次数 = %d, want 1", len(proposals))
+	}
+
+	score, _ := sm.GetDutyScore("super-bad")
+	if score.FailedEpochs != 3 || score.DemotionProposalID != "proposal-super-bad" {
+		t.Errorf("履职记录错误: failed=%d proposal=%s", score.FailedEpochs, score.DemotionProposalID)
+	}
+	if len(score.History) != 3 || score.History[0].Passed || len(score.History[0].Reasons) == 0 {
+		t.Errorf("周期历史错误: %+v", score.History)
+	}
+	if score.Lifetime.UptimeChecks != 3 {
+		t.Errorf("累计探测次数 = %d, want 3", score.Lifetime.UptimeChecks)
+	}
+
+	board := sm.GetScoreboard()
+	if len(board) != 2 || board[0].NodeID != "super-bad" {
+		t.Errorf("记分板应按不达标周期排序: %+v", board)
+	}
+
+	if err := sm.ResolveDemotion("super-bad", true); err != nil {
+		t.Fatalf("ResolveDemotion() error = %v", err)
+	}
+	if sm.IsSuperNode("super-bad") {
+		t.Error("降级后不应再是超级节点")
+	}
+	if err := sm.ResolveDemotion("super-good", false); err == nil {
+		t.Error("无进行中提案时应返回错误")
+	}
+}
+
+func TestScoreboardDemotionRejected(t *testing.T) {
+	sm := createTestManager(t)
+	sm.config.Scoreboard.DemotionEpochs = 1
+	addTestSuperNodes(sm, "super-001")
+
+	calls := 0
+	sm.SetDemotionProposer(func(nodeID, reason string) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("network unavailable")
+		}
+		return "p1", nil
+	})
+
+	sm.RecordUptimeProbe("super-001", false)
+	if got := sm.CloseEpoch(); len(got) != 0 {
+		t.Errorf("提案失败时不应返回节点, got %v", got)
+	}
+	// 提案失败后下个周期重试
+	sm.RecordUptimeProbe("super-001", false)
+	if got := sm.CloseEpoch(); len(got) != 1 {
+		t.Fatalf("应重试提议降级, got %v", got)
+	}
+
+	if err := sm.ResolveDemotion("super-001", false); err != nil {
+		t.Fatalf("ResolveDemotion() error = %v", err)
+	}
+	score, _ := sm.GetDutyScore("super-001")
+	if score.FailedEpochs != 0 || score.DemotionProposalID != "" {
+		t.Errorf("提案被否决后应重置计数: %+v", score)
+	}
+	if !sm.IsSuperNode("super-001") {
+		t.Error("提案被否决后应保留超级节点")
+	}
+}
+
+func TestScoreboardPersistence(t *testing.T) {
+	config := createTestConfig(t)
+	sm, _ := NewSuperNodeManager(config)
+	addTestSuperNodes(sm, "super-001")
+	sm.RecordUptimeProbe("super-001", true)
+	sm.CloseEpoch()
+	if err := sm.saveToDisk(); err != nil {
+		t.Fatalf("saveToDisk() error = %v", err)
+	}
+
+	sm2, _ := NewSuperNodeManager(config)
+	if err := sm2.loadFromDisk(); err != nil {
+		t.Fatalf("loadFromDisk() error = %v", err)
+	}
+	if sm2.CurrentEpoch() != 1 {
+		t.Errorf("CurrentEpoch() = %d, want 1", sm2.CurrentEpoch())
+	}
+	score, err := sm2.GetDutyScore("super-001")
+	if err != nil || len(score.History) != 1 {
+		t.Errorf("履职记录未持久化: %+v, %v", score, err)
+	}
+}
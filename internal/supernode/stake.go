@@ -0,0 +1,59 @@
+package supernode
+
+import (
+	"fmt"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+)
+
+// 候选抵押
+//
+// 设置 StakeLocker 后，申请候选时按申报的抵押值实际锁定抵押，锁定失败则拒绝申请；
+// 撤回候选、被移除或任期结束时释放。当选期间抵押保持锁定，审计偏离的罚没从中扣除。
+
+// StakeLocker 锁定和释放候选抵押
+type StakeLocker interface {
+	LockStake(nodeID string, amount float64) error
+	ReleaseStake(nodeID string, amount float64) error
+}
+
+// SetStakeLocker 设置候选抵押的锁定器
+func (s *SuperNodeManager) SetStakeLocker(locker StakeLocker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stakeLocker = locker
+}
+
+// releaseStakeLocked 释放节点的候选抵押（调用方持有锁）
+func (s *SuperNodeManager) releaseStakeLocked(nodeID string, amount float64) {
+	if s.stakeLocker == nil {
+		return
+	}
+	if err := s.stakeLocker.ReleaseStake(nodeID, amount); err != nil {
+		fmt.Printf("Warning: failed to release stake of %s: %v\n", nodeID, err)
+	}
+}
+
+// CollateralStake 用抵押账户支撑候选抵押
+// 抵押锁定在审计者抵押用途下，与 AuditIntegration 罚没的是同一个账户。
+type CollateralStake struct {
+	cm      *collateral.CollateralManager
+	purpose string
+}
+
+// NewCollateralStake 创建候选抵押锁定器，purpose 通常为 AuditPenaltyConfig.AuditorCollateralPurpose
+func NewCollateralStake(cm *collateral.CollateralManager, purpose string) *CollateralStake {
+	return &CollateralStake{cm: cm, purpose: purpose}
+}
+
+// LockStake 锁定候选抵押
+func (c *CollateralStake) LockStake(nodeID string, amount float64) error {
+	_, err := c.cm.Lock(nodeID, c.purpose, amount, "supernode candidacy")
+	return err
+}
+
+// ReleaseStake 释放候选抵押，已被罚没的部分不再释放
+func (c *CollateralStake) ReleaseStake(nodeID string, amount float64) error {
+	_, err := c.cm.Unlock(nodeID, c.purpose, amount)
+	return err
+}
@@ -0,0 +1,52 @@
+package supernode
+
+import (
+	"testing"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+)
+
+func TestCandidateStakeLocksCollateral(t *testing.T) {
+	config := DefaultConfig("self")
+	config.DataDir = ""
+	sm, err := NewSuperNodeManager(config)
+	if err != nil {
+		t.Fatalf("NewSuperNodeManager failed: %v", err)
+	}
+	cm := collateral.NewCollateralManager()
+	purpose := DefaultAuditPenaltyConfig().AuditorCollateralPurpose
+	sm.SetStakeLocker(NewCollateralStake(cm, purpose))
+
+	// 没有抵押账户或余额不足时不能申请
+	if err := sm.ApplyCandidate("node1", 80, 40); err == nil {
+		t.Error("expected candidacy without collateral to fail")
+	}
+	cm.Deposit("node1", purpose, 50)
+	if err := sm.ApplyCandidate("node1", 80, 60); err == nil {
+		t.Error("expected candidacy above collateral balance to fail")
+	}
+
+	if err := sm.ApplyCandidate("node1", 80, 40); err != nil {
+		t.Fatalf("ApplyCandidate failed: %v", err)
+	}
+	if acc, _ := cm.GetAccount("node1", purpose); acc.Locked != 40 {
+		t.Errorf("locked = %.2f, want 40", acc.Locked)
+	}
+	if _, err := cm.Withdraw("node1", purpose, 20); err == nil {
+		t.Error("expected locked stake to block withdrawal")
+	}
+
+	// 审计偏离罚没落在锁定的候选抵押上
+	ai := NewAuditIntegration(nil, nil, cm, sm, "system")
+	if _, slash, err := ai.ManualPenalty(&AuditDeviation{AuditID: "a1", AuditorID: "node1", Severity: "severe"}); err != nil || slash == nil || slash.Amount != 15 {
+		t.Fatalf("ManualPenalty slash = %+v, err = %v", slash, err)
+	}
+
+	if err := sm.WithdrawCandidate("node1"); err != nil {
+		t.Fatalf("WithdrawCandidate failed: %v", err)
+	}
+	acc, _ := cm.GetAccount("node1", purpose)
+	if acc.Locked != 0 || acc.Balance != 35 {
+		t.Errorf("after withdraw candidacy: %+v", acc)
+	}
+}
@@ -139,6 +139,7 @@ type SuperNodeConfig struct {
 	AuditThreshold      float64       // 审计通过阈值 (0-1)
 	AuditorsPerTask     int           // 每个任务的审计者数量
 	CleanupInterval     time.Duration // 清理间隔
+	Scoreboard          *ScoreboardConfig // 履职记分板配置（为空使用默认值）
 }
 
 // DefaultConfig 返回默认配置
@@ -154,6 +155,7 @@ func DefaultConfig(nodeID string) *SuperNodeConfig {
 		AuditThreshold:   0.6, // 60%审计者通过才算通过
 		AuditorsPerTask:  3,
 		CleanupInterval:  1 * time.Hour,
+		Scoreboard:       DefaultScoreboardConfig(),
 	}
 }
 
@@ -165,11 +167,16 @@ type SuperNodeManager struct {
 	audits      map[string]*MultiAudit // auditID -> MultiAudit
 	elections   map[string]*Election   // electionID -> Election
 	currentElection *Election
+	scores      map[string]*DutyScore  // nodeID -> 履职记录
+	epoch       int64                  // 当前评估周期
 	mu          sync.RWMutex
 
 	signFunc   SignFunc
 	verifyFunc VerifyFunc
 
+	demotionProposer DemotionProposer
+	stakeLocker      StakeLocker
+
 	// 回调
 	onSuperNodeElected   func(*SuperNode)
 	onSuperNodeRemoved   func(nodeID string)
@@ -195,6 +202,10 @@ func NewSuperNodeManager(config *SuperNodeConfig) (*SuperNodeManager, error) {
 		return nil, errors.New("max super nodes must be positive")
 	}
 
+	if config.Scoreboard == nil {
+		config.Scoreboard = DefaultScoreboardConfig()
+	}
+
 	if config.DataDir != "" {
 		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create data dir: %w", err)
@@ -207,6 +218,7 @@ func NewSuperNodeManager(config *SuperNodeConfig) (*SuperNodeManager, error) {
 		candidates: make(map[string]*Candidate),
 		audits:     make(map[string]*MultiAudit),
 		elections:  make(map[string]*Election),
+		scores:     make(map[string]*DutyScore),
 		stopCh:     make(chan struct{}),
 		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
@@ -317,6 +329,11 @@ func (s *SuperNodeManager) ApplyCandidate(nodeID string, reputation, stake float
 	if stake < s.config.MinStake {
 		return fmt.Errorf("stake too low: %.2f < %.2f", stake, s.config.MinStake)
 	}
+	if s.stakeLocker != nil {
+		if err := s.stakeLocker.LockStake(nodeID, stake); err != nil {
+			return fmt.Errorf("failed to lock stake: %w", err)
+		}
+	}
 
 	s.candidates[nodeID] = &Candidate{
 		NodeID:     nodeID,
@@ -334,11 +351,16 @@ func (s *SuperNodeManager) WithdrawCandidate(nodeID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.candidates[nodeID]; !exists {
+	c, exists := s.candidates[nodeID]
+	if !exists {
 		return errors.New("node is not a candidate")
 	}
 
 	delete(s.candidates, nodeID)
+	if s.currentElection != nil {
+		delete(s.currentElection.Candidates, nodeID)
+	}
+	s.releaseStakeLocked(nodeID, c.Stake)
 	return nil
 }
 
@@ -532,6 +554,9 @@ func (s *SuperNodeManager) RemoveSuperNode(nodeID string, reason string) error {
 		return errors.New("super node not found")
 	}
 
+	if sn.IsActive {
+		s.releaseStakeLocked(nodeID, sn.Stake)
+	}
 	sn.IsActive = false
 
 	if s.onSuperNodeRemoved != nil {
@@ -603,6 +628,10 @@ func (s *SuperNodeManager) CreateAudit(auditType AuditType, targetID string) (*M
 		FinalResult: ResultPending,
 	}
 
+	for _, auditorID := range auditors {
+		s.scoreOf(auditorID).Current.AuditsAssigned++
+	}
+
 	s.audits[audit.ID] = audit
 	return audit, nil
 }
@@ -665,6 +694,7 @@ func (s *SuperNodeManager) SubmitAuditResult(auditID, auditorID string, result A
 	if sn, exists := s.superNodes[auditorID]; exists {
 		sn.AuditCount++
 	}
+	s.scoreOf(auditorID).Current.AuditsCompleted++
 
 	// 检查是否可以结束
 	s.tryFinalizeAudit(audit)
@@ -791,6 +821,7 @@ func (s *SuperNodeManager) checkTermExpiry() {
 	for nodeID, sn := range s.superNodes {
 		if sn.IsActive && now.After(sn.TermEndsAt) {
 			sn.IsActive = false
+			s.releaseStakeLocked(nodeID, sn.Stake)
 			if s.onSuperNodeRemoved != nil {
 				go s.onSuperNodeRemoved(nodeID)
 			}
@@ -837,8 +868,10 @@ func (s *SuperNodeManager) mainLoop() {
 
 	ticker := time.NewTicker(s.config.CleanupInterval)
 	termTicker := time.NewTicker(1 * time.Hour) // 每小时检查任期
+	epochTicker := time.NewTicker(s.config.Scoreboard.EpochDuration)
 	defer ticker.Stop()
 	defer termTicker.Stop()
+	defer epochTicker.Stop()
 
 	for {
 		select {
@@ -846,6 +879,8 @@ func (s *SuperNodeManager) mainLoop() {
 			s.cleanup()
 		case <-termTicker.C:
 			s.checkTermExpiry()
+		case <-epochTicker.C:
+			s.CloseEpoch()
 		case <-s.stopCh:
 			return
 		}
@@ -880,6 +915,8 @@ type supernodeData struct {
 	Candidates map[string]*Candidate  `json:"candidates"`
 	Audits     map[string]*MultiAudit `json:"audits"`
 	Elections  map[string]*Election   `json:"elections"`
+	Scores     map[string]*DutyScore  `json:"scores"`
+	Epoch      int64                  `json:"epoch"`
 }
 
 func (s *SuperNodeManager) saveToDisk() error {
@@ -893,6 +930,8 @@ func (s *SuperNodeManager) saveToDisk() error {
 		Candidates: s.candidates,
 		Audits:     s.audits,
 		Elections:  s.elections,
+		Scores:     s.scores,
+		Epoch:      s.epoch,
 	}
 	s.mu.RUnlock()
 
@@ -940,6 +979,10 @@ func (s *SuperNodeManager) loadFromDisk() error {
 	if data.Elections != nil {
 		s.elections = data.Elections
 	}
+	if data.Scores != nil {
+		s.scores = data.Scores
+	}
+	s.epoch = data.Epoch
 
 	return nil
 }
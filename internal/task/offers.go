@@ -0,0 +1,391 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 任务邀约队列：执行方收到的任务邀约按优先级策略排序。
+// 优先级由单位报酬、委托方声誉、截止时间紧迫度和能力匹配度加权得出，
+// 每个邀约的评分明细可通过 API 提供给 Agent，便于解释和调整策略。
+
+var (
+	ErrOfferExists    = errors.New("offer already queued")
+	ErrOfferNotFound  = errors.New("offer not found")
+	ErrOfferQueueFull = errors.New("offer queue full and offer ranks below all queued offers")
+	ErrInvalidPolicy  = errors.New("invalid prioritization policy")
+)
+
+// 评分因素
+const (
+	FactorBudgetPerUnit = "budget_per_unit"
+	FactorReputation    = "creator_reputation"
+	FactorDeadline      = "deadline_tightness"
+	FactorCapability    = "capability_match"
+)
+
+// PriorityPolicy 邀约优先级策略
+type PriorityPolicy struct {
+	BudgetWeight     float64 `json:"budget_weight"`
+	ReputationWeight float64 `json:"reputation_weight"`
+	DeadlineWeight   float64 `json:"deadline_weight"`
+	CapabilityWeight float64 `json:"capability_weight"`
+
+	ReferenceBudgetPerUnit float64       `json:"reference_budget_per_unit"` // 单位报酬达到该值时该项满分
+	ReferenceReputation    float64       `json:"reference_reputation"`      // 委托方声誉达到该值时该项满分
+	DeadlineHorizon        time.Duration `json:"deadline_horizon"`          // 剩余时间超过该值时紧迫度为 0
+	MinLeadTime            time.Duration `json:"min_lead_time"`             // 剩余时间不足该值视为来不及完成，紧迫度为 0
+}
+
+// DefaultPriorityPolicy 返回默认优先级策略
+func DefaultPriorityPolicy() *PriorityPolicy {
+	return &PriorityPolicy{
+		BudgetWeight:     0.35,
+		ReputationWeight: 0.25,
+		DeadlineWeight:   0.2,
+		CapabilityWeight: 0.2,
+
+		ReferenceBudgetPerUnit: 10,
+		ReferenceReputation:    100,
+		DeadlineHorizon:        24 * time.Hour,
+		MinLeadTime:            5 * time.Minute,
+	}
+}
+
+// Validate 检查策略参数
+func (p *PriorityPolicy) Validate() error {
+	weights := []float64{p.BudgetWeight, p.ReputationWeight, p.DeadlineWeight, p.CapabilityWeight}
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return fmt.Errorf("%w: weights must not be negative", ErrInvalidPolicy)
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: at least one weight must be positive", ErrInvalidPolicy)
+	}
+	if p.ReferenceBudgetPerUnit <= 0 || p.ReferenceReputation <= 0 {
+		return fmt.Errorf("%w: reference values must be positive", ErrInvalidPolicy)
+	}
+	if p.DeadlineHorizon <= p.MinLeadTime || p.MinLeadTime < 0 {
+		return fmt.Errorf("%w: deadline_horizon must exceed min_lead_time", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// FactorScore 单项评分
+type FactorScore struct {
+	Value        float64 `json:"value"`        // 原始值（单位报酬、声誉、剩余秒数、匹配比例）
+	Score        float64 `json:"score"`        // 归一化得分 0-1
+	Weight       float64 `json:"weight"`       // 策略权重
+	Contribution float64 `json:"contribution"` // 对总分的贡献
+}
+
+// OfferScore 邀约评分明细
+type OfferScore struct {
+	TaskID     string                 `json:"task_id"`
+	Total      float64                `json:"total"` // 0-1
+	Factors    map[string]FactorScore `json:"factors"`
+	ScoredAt   int64                  `json:"scored_at"`
+	ReceivedAt int64                  `json:"received_at"`
+}
+
+// RankedOffer 排序后的邀约
+type RankedOffer struct {
+	Rank  int         `json:"rank"`
+	Task  *Task       `json:"task"`
+	Score *OfferScore `json:"score"`
+
+	receivedAt time.Time
+}
+
+// OfferQueueConfig 邀约队列配置
+type OfferQueueConfig struct {
+	MaxOffers      int                         // 队列上限，满时淘汰最低优先级的邀约
+	Policy         *PriorityPolicy             // 优先级策略
+	Capabilities   []string                    // 本执行方的能力
+	ReputationFunc func(nodeID string) float64 // 查询委托方声誉
+}
+
+// DefaultOfferQueueConfig 返回默认配置
+func DefaultOfferQueueConfig() *OfferQueueConfig {
+	return &OfferQueueConfig{
+		MaxOffers: 500,
+		Policy:    DefaultPriorityPolicy(),
+	}
+}
+
+type queuedOffer struct {
+	task       *Task
+	receivedAt time.Time
+}
+
+// OfferQueue 执行方的任务邀约队列
+type OfferQueue struct {
+	mu     sync.RWMutex
+	config *OfferQueueConfig
+	offers map[string]*queuedOffer // taskID -> offer
+	now    func() time.Time
+}
+
+// NewOfferQueue 创建邀约队列
+func NewOfferQueue(config *OfferQueueConfig) (*OfferQueue, error) {
+	if config == nil {
+		config = DefaultOfferQueueConfig()
+	}
+	if config.Policy == nil {
+		config.Policy = DefaultPriorityPolicy()
+	}
+	if err := config.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &OfferQueue{
+		config: config,
+		offers: make(map[string]*queuedOffer),
+		now:    time.Now,
+	}, nil
+}
+
+// SetPolicy 替换优先级策略
+func (q *OfferQueue) SetPolicy(policy *PriorityPolicy) error {
+	if policy == nil {
+		return ErrInvalidPolicy
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	copied := *policy
+	q.config.Policy = &copied
+	return nil
+}
+
+// GetPolicy 返回当前优先级策略
+func (q *OfferQueue) GetPolicy() *PriorityPolicy {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	copied := *q.config.Policy
+	return &copied
+}
+
+// SetCapabilities 更新本执行方的能力
+func (q *OfferQueue) SetCapabilities(capabilities []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.config.Capabilities = append([]string(nil), capabilities...)
+}
+
+// SetReputationFunc 设置委托方声誉查询函数
+func (q *OfferQueue) SetReputationFunc(fn func(nodeID string) float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.config.ReputationFunc = fn
+}
+
+// Add 接收一个任务邀约
+// 队列已满时，新邀约须高于当前最低优先级的邀约，后者被淘汰。
+func (q *OfferQueue) Add(task *Task) (*OfferScore, error) {
+	if task == nil || task.ID == "" {
+		return nil, ErrTaskNotFound
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if offerExpired(task, q.now()) {
+		return nil, ErrTaskExpired
+	}
+
+	if _, exists := q.offers[task.ID]; exists {
+		return nil, ErrOfferExists
+	}
+	q.pruneExpiredLocked()
+
+	offer := &queuedOffer{task: task, receivedAt: q.now()}
+	score := q.scoreLocked(offer)
+	if q.config.MaxOffers > 0 && len(q.offers) >= q.config.MaxOffers {
+		ranked := q.rankLocked()
+		lowest := ranked[len(ranked)-1]
+		if score.Total <= lowest.Score.Total {
+			return nil, ErrOfferQueueFull
+		}
+		delete(q.offers, lowest.Task.ID)
+	}
+	q.offers[task.ID] = offer
+	return score, nil
+}
+
+// Remove 移出邀约（已接单、拒绝或任务取消）
+func (q *OfferQueue) Remove(taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, exists := q.offers[taskID]; !exists {
+		return ErrOfferNotFound
+	}
+	delete(q.offers, taskID)
+	return nil
+}
+
+// List 按优先级从高到低返回邀约，limit<=0 表示全部
+// 紧迫度随时间变化，每次调用都会重新评分。
+func (q *OfferQueue) List(limit int) []*RankedOffer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pruneExpiredLocked()
+	ranked := q.rankLocked()
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// Explain 返回单个邀约的评分明细
+func (q *OfferQueue) Explain(taskID string) (*OfferScore, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	offer, exists := q.offers[taskID]
+	if !exists {
+		return nil, ErrOfferNotFound
+	}
+	return q.scoreLocked(offer), nil
+}
+
+// Len 队列中的邀约数
+func (q *OfferQueue) Len() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return len(q.offers)
+}
+
+// rankLocked 对全部邀约评分并排序（同分时先到先排）
+func (q *OfferQueue) rankLocked() []*RankedOffer {
+	ranked := make([]*RankedOffer, 0, len(q.offers))
+	for _, offer := range q.offers {
+		ranked = append(ranked, &RankedOffer{Task: offer.task, Score: q.scoreLocked(offer), receivedAt: offer.receivedAt})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score.Total != b.Score.Total {
+			return a.Score.Total > b.Score.Total
+		}
+		if !a.receivedAt.Equal(b.receivedAt) {
+			return a.receivedAt.Before(b.receivedAt)
+		}
+		return a.Task.ID < b.Task.ID
+	})
+	for i, r := range ranked {
+		r.Rank = i + 1
+	}
+	return ranked
+}
+
+func (q *OfferQueue) pruneExpiredLocked() {
+	now := q.now()
+	for id, offer := range q.offers {
+		if offerExpired(offer.task, now) {
+			delete(q.offers, id)
+		}
+	}
+}
+
+// offerExpired 与 Task.IsExpired 相同的判断，时间由队列提供
+func offerExpired(task *Task, now time.Time) bool {
+	if task.ExpiresAt > 0 {
+		return now.Unix() > task.ExpiresAt
+	}
+	if task.Deadline > 0 {
+		return now.Unix() > task.Deadline
+	}
+	return false
+}
+
+// scoreLocked 按当前策略计算邀约评分
+func (q *OfferQueue) scoreLocked(offer *queuedOffer) *OfferScore {
+	p := q.config.Policy
+	task := offer.task
+	now := q.now()
+
+	// 单位报酬：报酬 / 工作量（未声明工作量按 1 个单位计）
+	units := task.Units
+	if units <= 0 {
+		units = 1
+	}
+	budget := task.Reward / units
+
+	reputation := 0.0
+	if q.config.ReputationFunc != nil {
+		reputation = q.config.ReputationFunc(task.RequesterID)
+	}
+
+	// 紧迫度：剩余时间越短越高，来不及完成或没有截止时间为 0
+	remaining := -1.0
+	deadlineScore := 0.0
+	if task.Deadline > 0 {
+		left := time.Unix(task.Deadline, 0).Sub(now)
+		remaining = left.Seconds()
+		if left >= p.MinLeadTime && left < p.DeadlineHorizon {
+			deadlineScore = 1 - float64(left-p.MinLeadTime)/float64(p.DeadlineHorizon-p.MinLeadTime)
+		}
+	}
+
+	capRatio := capabilityMatch(task.RequiredCaps, q.config.Capabilities)
+
+	factors := map[string]FactorScore{
+		FactorBudgetPerUnit: {Value: budget, Score: clampUnit(budget / p.ReferenceBudgetPerUnit), Weight: p.BudgetWeight},
+		FactorReputation:    {Value: reputation, Score: clampUnit(reputation / p.ReferenceReputation), Weight: p.ReputationWeight},
+		FactorDeadline:      {Value: remaining, Score: deadlineScore, Weight: p.DeadlineWeight},
+		FactorCapability:    {Value: capRatio, Score: capRatio, Weight: p.CapabilityWeight},
+	}
+
+	totalWeight := p.BudgetWeight + p.ReputationWeight + p.DeadlineWeight + p.CapabilityWeight
+	// 按固定顺序累加，保证同一输入的总分逐位一致
+	total := 0.0
+	for _, name := range []string{FactorBudgetPerUnit, FactorReputation, FactorDeadline, FactorCapability} {
+		f := factors[name]
+		f.Contribution = f.Score * f.Weight / totalWeight
+		total += f.Contribution
+		factors[name] = f
+	}
+
+	return &OfferScore{
+		TaskID:     task.ID,
+		Total:      total,
+		Factors:    factors,
+		ScoredAt:   now.Unix(),
+		ReceivedAt: offer.receivedAt.Unix(),
+	}
+}
+
+// capabilityMatch 任务要求的能力中本执行方具备的比例，无要求时为 1
+func capabilityMatch(required, have []string) float64 {
+	if len(required) == 0 {
+		return 1
+	}
+	owned := make(map[string]bool, len(have))
+	for _, c := range have {
+		owned[c] = true
+	}
+	matched := 0
+	for _, c := range required {
+		if owned[c] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(required))
+}
+
+func clampUnit(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
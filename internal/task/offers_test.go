@@ -0,0 +1,141 @@
+package task
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestOfferQueue(t *testing.T, maxOffers int) (*OfferQueue, *time.Time) {
+	t.Helper()
+	config := DefaultOfferQueueConfig()
+	config.MaxOffers = maxOffers
+	config.Capabilities = []string{"translation", "search"}
+	config.ReputationFunc = func(nodeID string) float64 {
+		return map[string]float64{"trusted": 100, "newcomer": 10}[nodeID]
+	}
+	q, err := NewOfferQueue(config)
+	if err != nil {
+		t.Fatalf("NewOfferQueue failed: %v", err)
+	}
+	now := time.Now()
+	q.now = func() time.Time { return now }
+	return q, &now
+}
+
+func TestOfferScoreBreakdown(t *testing.T) {
+	q, now := newTestOfferQueue(t, 0)
+
+	task := &Task{
+		ID:           "t1",
+		RequesterID:  "trusted",
+		Reward:       50,
+		Units:        10,
+		Deadline:     now.Add(12*time.Hour + 150*time.Second).Unix(),
+		RequiredCaps: []string{"translation", "ocr"},
+	}
+	score, err := q.Add(task)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	f := score.Factors
+	if f[FactorBudgetPerUnit].Value != 5 || f[FactorBudgetPerUnit].Score != 0.5 {
+		t.Errorf("budget per unit: %+v", f[FactorBudgetPerUnit])
+	}
+	if f[FactorReputation].Score != 1 {
+		t.Errorf("creator reputation: %+v", f[FactorReputation])
+	}
+	if math.Abs(f[FactorDeadline].Score-0.5) > 0.01 {
+		t.Errorf("deadline tightness: %+v", f[FactorDeadline])
+	}
+	if f[FactorCapability].Score != 0.5 {
+		t.Errorf("capability match: %+v", f[FactorCapability])
+	}
+
+	sum := 0.0
+	for _, factor := range f {
+		sum += factor.Contribution
+	}
+	if math.Abs(sum-score.Total) > 1e-9 || score.Total <= 0 || score.Total > 1 {
+		t.Errorf("total %.4f should equal the sum of contributions %.4f", score.Total, sum)
+	}
+
+	if explained, err := q.Explain("t1"); err != nil || explained.Total != score.Total {
+		t.Errorf("Explain mismatch: %+v, %v", explained, err)
+	}
+	if _, err := q.Explain("missing"); err != ErrOfferNotFound {
+		t.Errorf("expected ErrOfferNotFound, got %v", err)
+	}
+}
+
+func TestOfferRanking(t *testing.T) {
+	q, now := newTestOfferQueue(t, 0)
+
+	q.Add(&Task{ID: "cheap", RequesterID: "newcomer", Reward: 1})
+	q.Add(&Task{ID: "rich", RequesterID: "trusted", Reward: 100, RequiredCaps: []string{"search"}})
+	q.Add(&Task{ID: "urgent", RequesterID: "newcomer", Reward: 1, Deadline: now.Add(10 * time.Minute).Unix()})
+	// 来不及完成的邀约不获得紧迫度加分
+	q.Add(&Task{ID: "hopeless", RequesterID: "newcomer", Reward: 1, Deadline: now.Add(time.Minute).Unix()})
+
+	ranked := q.List(0)
+	order := []string{}
+	for _, r := range ranked {
+		order = append(order, r.Task.ID)
+	}
+	if len(order) != 4 || order[0] != "rich" || order[1] != "urgent" || ranked[0].Rank != 1 {
+		t.Fatalf("unexpected ranking: %v", order)
+	}
+	// 同分时先到的排前面
+	if order[2] != "cheap" || order[3] != "hopeless" {
+		t.Errorf("ties should keep arrival order: %v", order)
+	}
+	if top := q.List(1); len(top) != 1 || top[0].Task.ID != "rich" {
+		t.Errorf("limit not applied: %v", top)
+	}
+
+	// 调整策略只看紧迫度
+	policy := q.GetPolicy()
+	policy.BudgetWeight, policy.ReputationWeight, policy.CapabilityWeight = 0, 0, 0
+	if err := q.SetPolicy(policy); err != nil {
+		t.Fatalf("SetPolicy failed: %v", err)
+	}
+	if top := q.List(1); top[0].Task.ID != "urgent" {
+		t.Errorf("deadline-only policy should rank urgent first, got %s", top[0].Task.ID)
+	}
+
+	policy.DeadlineWeight = 0
+	if err := q.SetPolicy(policy); !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("expected ErrInvalidPolicy, got %v", err)
+	}
+
+	// 过期邀约在列出时被移除
+	*now = now.Add(2 * time.Minute)
+	if err := q.Remove("urgent"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if ranked := q.List(0); len(ranked) != 2 || q.Len() != 2 {
+		t.Errorf("expired offer should be pruned, got %d offers", len(ranked))
+	}
+}
+
+func TestOfferQueueFull(t *testing.T) {
+	q, _ := newTestOfferQueue(t, 2)
+
+	q.Add(&Task{ID: "low", RequesterID: "newcomer", Reward: 1})
+	q.Add(&Task{ID: "mid", RequesterID: "newcomer", Reward: 5})
+
+	if _, err := q.Add(&Task{ID: "lower", RequesterID: "newcomer", Reward: 0}); err != ErrOfferQueueFull {
+		t.Errorf("expected ErrOfferQueueFull, got %v", err)
+	}
+	if _, err := q.Add(&Task{ID: "high", RequesterID: "trusted", Reward: 50}); err != nil {
+		t.Fatalf("higher priority offer should evict the lowest: %v", err)
+	}
+	if _, err := q.Explain("low"); err != ErrOfferNotFound {
+		t.Error("lowest priority offer should have been evicted")
+	}
+	if _, err := q.Add(&Task{ID: "high", RequesterID: "trusted"}); err != ErrOfferExists {
+		t.Errorf("expected ErrOfferExists, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// PayloadFormat 任务载荷格式（MIME 类型）
+type PayloadFormat string
+
+const (
+	PayloadJSON     PayloadFormat = "application/json"       // JSON 参数
+	PayloadProtobuf PayloadFormat = "application/x-protobuf" // Protocol Buffers 编码
+	PayloadWASM     PayloadFormat = "application/wasm"       // WebAssembly 模块
+)
+
+var (
+	ErrUnknownPayloadFormat  = errors.New("unknown payload format")
+	ErrPayloadFormatMismatch = errors.New("payload format mismatch")
+)
+
+// payloadAliases 常见别名到标准格式的映射
+var payloadAliases = map[string]PayloadFormat{
+	"application/json":                PayloadJSON,
+	"json":                            PayloadJSON,
+	"application/x-protobuf":          PayloadProtobuf,
+	"application/protobuf":            PayloadProtobuf,
+	"application/vnd.google.protobuf": PayloadProtobuf,
+	"protobuf":                        PayloadProtobuf,
+	"application/wasm":                PayloadWASM,
+	"wasm":                            PayloadWASM,
+}
+
+// ParsePayloadFormat 解析载荷格式，忽略大小写和 MIME 参数（如 charset）
+func ParsePayloadFormat(s string) (PayloadFormat, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	if mediaType, _, err := mime.ParseMediaType(value); err == nil {
+		value = mediaType
+	}
+	if f, ok := payloadAliases[value]; ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q (supported: %s, %s, %s)", ErrUnknownPayloadFormat, s, PayloadJSON, PayloadProtobuf, PayloadWASM)
+}
+
+// ParsePayloadFormats 解析并去重一组载荷格式，保持原有顺序
+func ParsePayloadFormats(values []string) ([]PayloadFormat, error) {
+	formats := make([]PayloadFormat, 0, len(values))
+	for _, v := range values {
+		f, err := ParsePayloadFormat(v)
+		if err != nil {
+			return nil, err
+		}
+		formats = appendFormat(formats, f)
+	}
+	return formats, nil
+}
+
+// NegotiatePayloadFormat 按委托方给出的优先顺序选出执行方支持的第一个格式
+// 执行方未声明格式时视为只支持 JSON。
+func NegotiatePayloadFormat(offered, supported []PayloadFormat) (PayloadFormat, error) {
+	if len(supported) == 0 {
+		supported = []PayloadFormat{PayloadJSON}
+	}
+	for _, f := range offered {
+		for _, s := range supported {
+			if f == s {
+				return f, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: task offers %s, agent supports %s",
+		ErrPayloadFormatMismatch, formatList(offered), formatList(supported))
+}
+
+// ReachabilityFunc 查询节点的公共可达性分数 [0,1]，没有证明时 ok 为 false
+type ReachabilityFunc func(nodeID string) (score float64, ok bool)
+
+// SetReachabilityFunc 设置可达性查询，MatchAgents 据此把更可能连得上的执行方排在前面
+func (tm *TaskManager) SetReachabilityFunc(fn ReachabilityFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.reachabilityFunc = fn
+}
+
+// MatchAgents 查找既具备任务所需能力、又支持任务载荷格式的已注册 Agent
+// 设置了可达性查询时按可达性分数降序排列，没有证明的 Agent 按 0.5 计。
+func (tm *TaskManager) MatchAgents(task *Task) []string {
+	matched := tm.matchAgents(task)
+
+	tm.mu.RLock()
+	reachability := tm.reachabilityFunc
+	tm.mu.RUnlock()
+	if reachability == nil || len(matched) < 2 {
+		return matched
+	}
+	scores := make(map[string]float64, len(matched))
+	for _, agentID := range matched {
+		score, ok := reachability(agentID)
+		if !ok {
+			score = 0.5
+		}
+		scores[agentID] = score
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if scores[matched[i]] != scores[matched[j]] {
+			return scores[matched[i]] > scores[matched[j]]
+		}
+		return matched[i] < matched[j]
+	})
+	return matched
+}
+
+func (tm *TaskManager) matchAgents(task *Task) []string {
+	var candidates []string
+	if len(task.RequiredCaps) > 0 {
+		candidates = tm.FindAgentsByCapability(task.RequiredCaps)
+	} else {
+		tm.mu.RLock()
+		for agentID := range tm.capabilities {
+			candidates = append(candidates, agentID)
+		}
+		tm.mu.RUnlock()
+	}
+	if len(task.PayloadFormats) == 0 {
+		return candidates
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var matched []string
+	for _, agentID := range candidates {
+		if _, err := NegotiatePayloadFormat(task.PayloadFormats, tm.agentFormats(agentID, nil)); err == nil {
+			matched = append(matched, agentID)
+		}
+	}
+	return matched
+}
+
+// normalizePayloadFormats 发布前规范化任务声明的载荷格式
+func normalizePayloadFormats(task *Task) error {
+	if len(task.PayloadFormats) == 0 {
+		return nil
+	}
+	values := make([]string, len(task.PayloadFormats))
+	for i, f := range task.PayloadFormats {
+		values[i] = string(f)
+	}
+	formats, err := ParsePayloadFormats(values)
+	if err != nil {
+		return err
+	}
+	task.PayloadFormats = formats
+	return nil
+}
+
+// negotiateFor 为接单方协商载荷格式，任务未声明格式时不做限制
+// declared 为接单时自行声明的格式，为空则使用能力注册表中的记录。
+func (tm *TaskManager) negotiateFor(task *Task, agentID string, declared []PayloadFormat) (PayloadFormat, error) {
+	if len(task.PayloadFormats) == 0 {
+		return "", nil
+	}
+	f, err := NegotiatePayloadFormat(task.PayloadFormats, tm.agentFormats(agentID, declared))
+	if err != nil {
+		return "", fmt.Errorf("task %s, agent %s: %w", task.ID, agentID, err)
+	}
+	return f, nil
+}
+
+// agentFormats 获取 Agent 支持的载荷格式（调用方需持有锁）
+func (tm *TaskManager) agentFormats(agentID string, declared []PayloadFormat) []PayloadFormat {
+	if len(declared) > 0 {
+		return canonicalFormats(declared)
+	}
+	if cap, ok := tm.capabilities[agentID]; ok {
+		return cap.PayloadFormats
+	}
+	return nil
+}
+
+func appendFormat(formats []PayloadFormat, f PayloadFormat) []PayloadFormat {
+	for _, existing := range formats {
+		if existing == f {
+			return formats
+		}
+	}
+	return append(formats, f)
+}
+
+func formatList(formats []PayloadFormat) string {
+	parts := make([]string, len(formats))
+	for i, f := range formats {
+		parts[i] = string(f)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// canonicalFormats 把能力记录中的格式别名转换为标准格式，无法识别的保留原值（不会匹配任何任务）
+func canonicalFormats(formats []PayloadFormat) []PayloadFormat {
+	result := make([]PayloadFormat, 0, len(formats))
+	for _, f := range formats {
+		if parsed, err := ParsePayloadFormat(string(f)); err == nil {
+			f = parsed
+		}
+		result = appendFormat(result, f)
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package task
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestParsePayloadFormat(t *testing.T) {
+	cases := map[string]PayloadFormat{
+		"application/json":                PayloadJSON,
+		"Application/JSON; charset=utf-8": PayloadJSON,
+		"protobuf":                        PayloadProtobuf,
+		"application/vnd.google.protobuf": PayloadProtobuf,
+		"wasm":                            PayloadWASM,
+	}
+	for in, want := range cases {
+		got, err := ParsePayloadFormat(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePayloadFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePayloadFormat("text/yaml"); !errors.Is(err, ErrUnknownPayloadFormat) {
+		t.Errorf("Expected ErrUnknownPayloadFormat, got %v", err)
+	}
+}
+
+func TestNegotiatePayloadFormat(t *testing.T) {
+	offered := []PayloadFormat{PayloadWASM, PayloadJSON}
+
+	f, err := NegotiatePayloadFormat(offered, []PayloadFormat{PayloadJSON, PayloadWASM})
+	if err != nil || f != PayloadWASM {
+		t.Errorf("Requester preference should win, got %q %v", f, err)
+	}
+
+	// 未声明格式的执行方只支持 JSON
+	f, err = NegotiatePayloadFormat(offered, nil)
+	if err != nil || f != PayloadJSON {
+		t.Errorf("Expected JSON fallback, got %q %v", f, err)
+	}
+
+	_, err = NegotiatePayloadFormat([]PayloadFormat{PayloadWASM}, []PayloadFormat{PayloadProtobuf})
+	if !errors.Is(err, ErrPayloadFormatMismatch) {
+		t.Errorf("Expected ErrPayloadFormatMismatch, got %v", err)
+	}
+}
+
+func TestPayloadFormatNegotiation(t *testing.T) {
+	tm := NewTaskManager(&TaskManagerConfig{
+		DataDir:           t.TempDir(),
+		MaxTasksPerHour:   10,
+		MinRepToPublish:   10,
+		DepositMultiplier: 1.0,
+	})
+	tm.RegisterCapability(&AgentCapability{AgentID: "json-worker", Capabilities: []string{"compute"}})
+	tm.RegisterCapability(&AgentCapability{
+		AgentID:        "wasm-worker",
+		Capabilities:   []string{"compute"},
+		PayloadFormats: []PayloadFormat{"wasm", PayloadJSON},
+	})
+
+	t.Run("unknown format rejected at publish", func(t *testing.T) {
+		task := &Task{Type: TaskTypeCompute, Title: "bad", RequesterID: "req", Reward: 5,
+			PayloadFormats: []PayloadFormat{"application/x-python"}}
+		if err := tm.PublishTask(task, 50); !errors.Is(err, ErrUnknownPayloadFormat) {
+			t.Errorf("Expected ErrUnknownPayloadFormat, got %v", err)
+		}
+	})
+
+	t.Run("direct task to incompatible worker", func(t *testing.T) {
+		task := &Task{Type: TaskTypeCompute, Title: "direct", RequesterID: "req", Reward: 5,
+			PublishMode: ModeDirect, TargetExecutorID: "json-worker",
+			PayloadFormats: []PayloadFormat{PayloadWASM}}
+		if err := tm.PublishTask(task, 50); !errors.Is(err, ErrPayloadFormatMismatch) {
+			t.Errorf("Expected ErrPayloadFormatMismatch, got %v", err)
+		}
+	})
+
+	task := &Task{Type: TaskTypeCompute, Title: "wasm job", RequesterID: "req", Reward: 5,
+		RequiredCaps: []string{"compute"}, PayloadFormats: []PayloadFormat{"WASM"}}
+	if err := tm.PublishTask(task, 50); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	if task.PayloadFormats[0] != PayloadWASM {
+		t.Errorf("Formats should be normalized, got %v", task.PayloadFormats)
+	}
+
+	t.Run("scheduler matches formats", func(t *testing.T) {
+		agents := tm.MatchAgents(task)
+		sort.Strings(agents)
+		if len(agents) != 1 || agents[0] != "wasm-worker" {
+			t.Errorf("Expected only wasm-worker, got %v", agents)
+		}
+	})
+
+	t.Run("scheduler prefers reachable agents", func(t *testing.T) {
+		jsonTask := &Task{RequiredCaps: []string{"compute"}}
+		tm.SetReachabilityFunc(func(nodeID string) (float64, bool) {
+			if nodeID == "json-worker" {
+				return 0.9, true
+			}
+			return 0, false
+		})
+		defer tm.SetReachabilityFunc(nil)
+		agents := tm.MatchAgents(jsonTask)
+		if len(agents) != 2 || agents[0] != "json-worker" {
+			t.Errorf("Expected json-worker first, got %v", agents)
+		}
+	})
+
+	t.Run("claim with mismatch", func(t *testing.T) {
+		err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "json-worker"}, 50)
+		if !errors.Is(err, ErrPayloadFormatMismatch) {
+			t.Fatalf("Expected ErrPayloadFormatMismatch, got %v", err)
+		}
+		if got, _ := tm.GetTask(task.ID); got.Status != StatusPublished {
+			t.Errorf("Rejected claim must not change status, got %s", got.Status)
+		}
+	})
+
+	t.Run("claim negotiates format", func(t *testing.T) {
+		if err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "wasm-worker"}, 50); err != nil {
+			t.Fatalf("ClaimTask failed: %v", err)
+		}
+		if got, _ := tm.GetTask(task.ID); got.PayloadFormat != PayloadWASM {
+			t.Errorf("Expected negotiated wasm, got %q", got.PayloadFormat)
+		}
+	})
+}
+
+func TestPayloadFormatBidding(t *testing.T) {
+	tm := NewTaskManager(&TaskManagerConfig{
+		DataDir:           t.TempDir(),
+		MaxTasksPerHour:   10,
+		MinRepToPublish:   10,
+		DepositMultiplier: 1.0,
+	})
+	task := &Task{Type: TaskTypeCompute, Title: "proto job", RequesterID: "req", Reward: 5,
+		BiddingPeriod: 600, PayloadFormats: []PayloadFormat{PayloadProtobuf, PayloadJSON}}
+	if err := tm.PublishTask(task, 50); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+
+	err := tm.SubmitBid(&TaskBid{TaskID: task.ID, BidderID: "wasm-only",
+		PayloadFormats: []PayloadFormat{PayloadWASM}})
+	if !errors.Is(err, ErrPayloadFormatMismatch) {
+		t.Errorf("Expected ErrPayloadFormatMismatch, got %v", err)
+	}
+
+	if err := tm.SubmitBid(&TaskBid{TaskID: task.ID, BidderID: "proto",
+		PayloadFormats: []PayloadFormat{"application/protobuf"}}); err != nil {
+		t.Fatalf("SubmitBid failed: %v", err)
+	}
+	if err := tm.AssignTask(&TaskAssignment{TaskID: task.ID, AssignedTo: "proto"}); err != nil {
+		t.Fatalf("AssignTask failed: %v", err)
+	}
+	if got, _ := tm.GetTask(task.ID); got.PayloadFormat != PayloadProtobuf {
+		t.Errorf("Expected negotiated protobuf, got %q", got.PayloadFormat)
+	}
+}
@@ -0,0 +1,259 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ProgressProtocolID 任务进度流协议ID
+// 执行方节点向委托方推送进度，一个流可承载多条更新，每条为一行 JSON。
+const ProgressProtocolID = "/daan/task-progress/1.0.0"
+
+const (
+	MaxProgressLogSize = 4096 // 单条更新携带的日志片段上限
+	MaxProgressHistory = 200  // 每个执行者保留的更新和检查点条数
+	maxProgressFrame   = 16 * 1024
+)
+
+var (
+	ErrInvalidProgress = errors.New("invalid progress update")
+	ErrStaleProgress   = errors.New("stale progress update")
+)
+
+// ProgressUpdate 执行方的一次进度更新
+type ProgressUpdate struct {
+	TaskID     string  `json:"task_id"`
+	ExecutorID string  `json:"executor_id"`
+	Seq        uint64  `json:"seq"`                  // 同一执行者按任务递增
+	Percent    float64 `json:"percent"`              // 0-100，不能回退
+	Checkpoint string  `json:"checkpoint,omitempty"` // 检查点哈希（已完成部分的摘要）
+	Log        string  `json:"log,omitempty"`        // 本次新增的部分日志
+	Message    string  `json:"message,omitempty"`    // 简短状态说明
+	Timestamp  int64   `json:"timestamp"`
+}
+
+// ProgressCheckpoint 执行过程中的检查点
+type ProgressCheckpoint struct {
+	Seq       uint64  `json:"seq"`
+	Hash      string  `json:"hash"`
+	Percent   float64 `json:"percent"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// TaskProgress 一名执行者在任务上的进度
+type TaskProgress struct {
+	TaskID      string                `json:"task_id"`
+	ExecutorID  string                `json:"executor_id"`
+	Percent     float64               `json:"percent"`
+	Message     string                `json:"message,omitempty"`
+	LastSeq     uint64                `json:"last_seq"`
+	UpdatedAt   int64                 `json:"updated_at"`
+	Checkpoints []*ProgressCheckpoint `json:"checkpoints,omitempty"`
+	Updates     []*ProgressUpdate     `json:"updates,omitempty"` // 最近的更新，按 Seq 升序
+}
+
+// ProgressSendFunc 把进度更新推送给委托方（通常经 ProgressProtocolID 流发送）
+type ProgressSendFunc func(requesterID string, update *ProgressUpdate) error
+
+// ProgressListener 收到或产生进度更新时回调（如推送到管理后台）
+type ProgressListener func(update *ProgressUpdate)
+
+// SetProgressSendFunc 设置进度推送回调
+func (tm *TaskManager) SetProgressSendFunc(fn ProgressSendFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.progressSendFunc = fn
+}
+
+// SetProgressListener 设置进度监听回调
+func (tm *TaskManager) SetProgressListener(fn ProgressListener) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.progressListener = fn
+}
+
+// ReportProgress 执行方报告进度，记录后推送给委托方
+// 已接受的任务收到首次进度时进入执行中状态。推送失败不影响本地记录，错误会返回给调用方。
+func (tm *TaskManager) ReportProgress(update *ProgressUpdate) (*ProgressUpdate, error) {
+	tm.mu.Lock()
+	task, exists := tm.tasks[update.TaskID]
+	if !exists {
+		tm.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+
+	current := tm.progress[update.TaskID][update.ExecutorID]
+	recorded := *update
+	recorded.Seq = 1
+	if current != nil {
+		recorded.Seq = current.LastSeq + 1
+	}
+	recorded.Timestamp = time.Now().Unix()
+	if err := tm.recordProgressLocked(task, &recorded); err != nil {
+		tm.mu.Unlock()
+		return nil, err
+	}
+	if task.Status == StatusAccepted && recorded.ExecutorID == task.ExecutorID {
+		task.Status = StatusInProgress
+		tm.save()
+	}
+	send, listener, requesterID := tm.progressSendFunc, tm.progressListener, task.RequesterID
+	tm.mu.Unlock()
+
+	if listener != nil {
+		listener(&recorded)
+	}
+	if send != nil {
+		if err := send(requesterID, &recorded); err != nil {
+			return &recorded, fmt.Errorf("progress recorded but not delivered: %w", err)
+		}
+	}
+	return &recorded, nil
+}
+
+// ReceiveProgress 委托方收到执行方推送的进度
+// from 为推送方节点（由传输层认证），必须与更新中的执行者一致；重复或乱序的更新返回 ErrStaleProgress。
+func (tm *TaskManager) ReceiveProgress(from string, update *ProgressUpdate) error {
+	if from != update.ExecutorID {
+		return fmt.Errorf("%w: sent by %s for executor %s", ErrNotAssignedToMe, from, update.ExecutorID)
+	}
+
+	tm.mu.Lock()
+	task, exists := tm.tasks[update.TaskID]
+	if !exists {
+		tm.mu.Unlock()
+		return ErrTaskNotFound
+	}
+	recorded := *update
+	if err := tm.recordProgressLocked(task, &recorded); err != nil {
+		tm.mu.Unlock()
+		return err
+	}
+	listener := tm.progressListener
+	tm.mu.Unlock()
+
+	if listener != nil {
+		listener(&recorded)
+	}
+	return nil
+}
+
+// GetProgress 返回任务各执行者的进度，主执行者在前
+func (tm *TaskManager) GetProgress(taskID string) ([]*TaskProgress, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	result := make([]*TaskProgress, 0, len(tm.progress[taskID]))
+	for _, p := range tm.progress[taskID] {
+		copied := *p
+		copied.Checkpoints = append([]*ProgressCheckpoint(nil), p.Checkpoints...)
+		copied.Updates = append([]*ProgressUpdate(nil), p.Updates...)
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].ExecutorID == task.ExecutorID) != (result[j].ExecutorID == task.ExecutorID) {
+			return result[i].ExecutorID == task.ExecutorID
+		}
+		return result[i].ExecutorID < result[j].ExecutorID
+	})
+	return result, nil
+}
+
+// recordProgressLocked 校验并记录进度更新（调用方持有写锁）
+func (tm *TaskManager) recordProgressLocked(task *Task, update *ProgressUpdate) error {
+	if update.ExecutorID != task.ExecutorID && !containsString(task.ReplicaExecutors, update.ExecutorID) {
+		return ErrNotAssignedToMe
+	}
+	if task.Status != StatusAccepted && task.Status != StatusInProgress {
+		return fmt.Errorf("%w: task is %s", ErrInvalidTransition, task.Status)
+	}
+	if update.Percent < 0 || update.Percent > 100 {
+		return fmt.Errorf("%w: percent %.1f out of range", ErrInvalidProgress, update.Percent)
+	}
+	if len(update.Log) > MaxProgressLogSize {
+		return fmt.Errorf("%w: log chunk larger than %d bytes", ErrInvalidProgress, MaxProgressLogSize)
+	}
+
+	byExecutor := tm.progress[task.ID]
+	if byExecutor == nil {
+		byExecutor = make(map[string]*TaskProgress)
+		tm.progress[task.ID] = byExecutor
+	}
+	p := byExecutor[update.ExecutorID]
+	if p == nil {
+		p = &TaskProgress{TaskID: task.ID, ExecutorID: update.ExecutorID}
+	}
+	if update.Seq <= p.LastSeq {
+		return fmt.Errorf("%w: seq %d, last %d", ErrStaleProgress, update.Seq, p.LastSeq)
+	}
+	if update.Percent < p.Percent {
+		return fmt.Errorf("%w: percent went back from %.1f to %.1f", ErrInvalidProgress, p.Percent, update.Percent)
+	}
+	byExecutor[update.ExecutorID] = p
+
+	p.Percent = update.Percent
+	p.LastSeq = update.Seq
+	p.UpdatedAt = update.Timestamp
+	if update.Message != "" {
+		p.Message = update.Message
+	}
+	if update.Checkpoint != "" {
+		p.Checkpoints = append(p.Checkpoints, &ProgressCheckpoint{
+			Seq:       update.Seq,
+			Hash:      update.Checkpoint,
+			Percent:   update.Percent,
+			Timestamp: update.Timestamp,
+		})
+		if len(p.Checkpoints) > MaxProgressHistory {
+			p.Checkpoints = p.Checkpoints[len(p.Checkpoints)-MaxProgressHistory:]
+		}
+	}
+	p.Updates = append(p.Updates, update)
+	if len(p.Updates) > MaxProgressHistory {
+		p.Updates = p.Updates[len(p.Updates)-MaxProgressHistory:]
+	}
+	return nil
+}
+
+// WriteProgress 向进度流写入一条更新
+func WriteProgress(w io.Writer, update *ProgressUpdate) error {
+	data, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// HandleProgressStream 读取执行方推送的进度流直到结束
+// 单条更新出错不会中断流，返回遇到的第一个错误。
+func (tm *TaskManager) HandleProgressStream(r io.Reader, from string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxProgressFrame)
+
+	var firstErr error
+	for scanner.Scan() {
+		var update ProgressUpdate
+		err := json.Unmarshal(scanner.Bytes(), &update)
+		if err == nil {
+			err = tm.ReceiveProgress(from, &update)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return firstErr
+}
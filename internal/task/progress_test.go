@@ -0,0 +1,104 @@
+package task
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReportProgress(t *testing.T) {
+	tm, root := createSubcontractManager(t)
+
+	var sent []*ProgressUpdate
+	tm.SetProgressSendFunc(func(requesterID string, update *ProgressUpdate) error {
+		if requesterID != "alice" {
+			t.Errorf("progress sent to %s, want alice", requesterID)
+		}
+		sent = append(sent, update)
+		return nil
+	})
+
+	if _, err := tm.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "mallory", Percent: 10}); err != ErrNotAssignedToMe {
+		t.Errorf("expected ErrNotAssignedToMe, got %v", err)
+	}
+
+	first, err := tm.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Percent: 25, Checkpoint: "sha256:aa", Log: "epoch 1 done\n"})
+	if err != nil {
+		t.Fatalf("ReportProgress failed: %v", err)
+	}
+	if first.Seq != 1 || first.Timestamp == 0 {
+		t.Errorf("seq/timestamp not assigned: %+v", first)
+	}
+	if task, _ := tm.GetTask(root.ID); task.Status != StatusInProgress {
+		t.Errorf("expected in_progress after first progress, got %s", task.Status)
+	}
+
+	if _, err := tm.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Percent: 20}); !errors.Is(err, ErrInvalidProgress) {
+		t.Errorf("expected ErrInvalidProgress for going back, got %v", err)
+	}
+	if _, err := tm.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Percent: 30, Log: strings.Repeat("x", MaxProgressLogSize+1)}); !errors.Is(err, ErrInvalidProgress) {
+		t.Errorf("expected ErrInvalidProgress for large log, got %v", err)
+	}
+	if _, err := tm.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Percent: 60, Message: "epoch 2"}); err != nil {
+		t.Fatalf("ReportProgress failed: %v", err)
+	}
+
+	if len(sent) != 2 || sent[1].Seq != 2 {
+		t.Fatalf("expected 2 updates streamed, got %d", len(sent))
+	}
+
+	progress, err := tm.GetProgress(root.ID)
+	if err != nil || len(progress) != 1 {
+		t.Fatalf("GetProgress = %v, %v", progress, err)
+	}
+	p := progress[0]
+	if p.Percent != 60 || p.LastSeq != 2 || p.Message != "epoch 2" || len(p.Checkpoints) != 1 || len(p.Updates) != 2 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+}
+
+func TestReceiveProgressStream(t *testing.T) {
+	executor, root := createSubcontractManager(t)
+	requester, _ := createSubcontractManager(t)
+	requester.tasks[root.ID] = executor.tasks[root.ID]
+
+	// 执行方经流推送，委托方读取
+	var stream bytes.Buffer
+	executor.SetProgressSendFunc(func(requesterID string, update *ProgressUpdate) error {
+		return WriteProgress(&stream, update)
+	})
+	var received []*ProgressUpdate
+	requester.SetProgressListener(func(update *ProgressUpdate) {
+		received = append(received, update)
+	})
+
+	for _, percent := range []float64{10, 50, 90} {
+		if _, err := executor.ReportProgress(&ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Percent: percent}); err != nil {
+			t.Fatalf("ReportProgress failed: %v", err)
+		}
+	}
+	replay := append([]byte(nil), stream.Bytes()...)
+
+	if err := requester.HandleProgressStream(&stream, "bob"); err != nil {
+		t.Fatalf("HandleProgressStream failed: %v", err)
+	}
+	if len(received) != 3 || received[2].Percent != 90 {
+		t.Fatalf("expected 3 updates, got %d", len(received))
+	}
+
+	// 重放的更新被拒绝
+	if err := requester.HandleProgressStream(bytes.NewReader(replay), "bob"); !errors.Is(err, ErrStaleProgress) {
+		t.Errorf("expected ErrStaleProgress on replay, got %v", err)
+	}
+	// 其他节点不能冒充执行者
+	forged := &ProgressUpdate{TaskID: root.ID, ExecutorID: "bob", Seq: 10, Percent: 100}
+	if err := requester.ReceiveProgress("mallory", forged); !errors.Is(err, ErrNotAssignedToMe) {
+		t.Errorf("expected ErrNotAssignedToMe, got %v", err)
+	}
+
+	progress, _ := requester.GetProgress(root.ID)
+	if len(progress) != 1 || progress[0].Percent != 90 || progress[0].LastSeq != 3 {
+		t.Errorf("unexpected requester progress: %+v", progress)
+	}
+}
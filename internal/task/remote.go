@@ -0,0 +1,60 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 跨节点的任务：委托方节点保存任务的权威记录，每次状态变化后广播带版本号的副本；
+// 其他节点保存副本供查询和接单，接单和交付作为请求交给委托方节点推进。
+
+// Snapshot 返回用于广播的任务副本，并递增本地记录的版本号
+// 版本号取当前时间（纳秒）且严格递增，重启后仍大于已广播过的版本。
+func (tm *TaskManager) Snapshot(taskID string) (*Task, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	revision := time.Now().UnixNano()
+	if revision <= task.Revision {
+		revision = task.Revision + 1
+	}
+	task.Revision = revision
+	tm.save()
+
+	snapshot := *task
+	return &snapshot, nil
+}
+
+// ReceiveTask 保存委托方节点广播的任务副本
+// 本地没有该任务时新增；已有时只接受同一委托方、版本号更大的副本，并整体替换本地记录。
+func (tm *TaskManager) ReceiveTask(task *Task) error {
+	if task.ID == "" || task.RequesterID == "" || task.Type == "" {
+		return errors.New("invalid task")
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	existing, exists := tm.tasks[task.ID]
+	if exists {
+		if existing.RequesterID != task.RequesterID || existing.Type != task.Type {
+			return fmt.Errorf("task %s does not match the local copy", task.ID)
+		}
+		if task.Revision <= existing.Revision {
+			return ErrStaleTask
+		}
+		tm.removeExecutorIndex(existing)
+	} else {
+		tm.addToIndex(task)
+	}
+
+	tm.tasks[task.ID] = task
+	tm.addExecutorIndex(task)
+	tm.save()
+	return nil
+}
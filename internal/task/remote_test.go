@@ -0,0 +1,60 @@
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTaskReplication(t *testing.T) {
+	newManager := func() *TaskManager {
+		config := DefaultConfig()
+		config.DataDir = t.TempDir()
+		config.DefaultBidding = 0
+		return NewTaskManager(config)
+	}
+	owner, replica := newManager(), newManager()
+
+	task := &Task{Type: TaskTypeSearch, Title: "find", RequesterID: "requester1", Reward: 10}
+	if err := owner.PublishTask(task, 50.0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	offer, err := owner.Snapshot(task.ID)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := replica.ReceiveTask(offer); err != nil {
+		t.Fatalf("ReceiveTask failed: %v", err)
+	}
+	if got := replica.GetTasksByRequester("requester1"); len(got) != 1 || got[0].Status != StatusPublished {
+		t.Fatalf("replica should list the offer: %+v", got)
+	}
+
+	// 委托方节点处理接单后广播新副本，替换其他节点的记录
+	if err := owner.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "executor1"}, 50.0); err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+	claimed, _ := owner.Snapshot(task.ID)
+	if claimed.Revision <= offer.Revision {
+		t.Fatalf("revision must increase: %d -> %d", offer.Revision, claimed.Revision)
+	}
+	if err := replica.ReceiveTask(claimed); err != nil {
+		t.Fatalf("ReceiveTask failed: %v", err)
+	}
+	if got := replica.GetTasksByExecutor("executor1"); len(got) != 1 || got[0].Status != StatusAccepted {
+		t.Fatalf("replica should index the executor: %+v", got)
+	}
+
+	// 乱序到达的旧副本被忽略
+	if err := replica.ReceiveTask(offer); !errors.Is(err, ErrStaleTask) {
+		t.Errorf("expected ErrStaleTask, got %v", err)
+	}
+	forged := *claimed
+	forged.RequesterID = "someone"
+	forged.Revision++
+	if err := replica.ReceiveTask(&forged); err == nil {
+		t.Error("a copy from another requester must not replace the task")
+	}
+	if got, _ := replica.GetTask(task.ID); got.Status != StatusAccepted || got.ExecutorID != "executor1" {
+		t.Errorf("unexpected replica state: %+v", got)
+	}
+}
@@ -0,0 +1,420 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 审计评审人分配
+//
+// 审计其他 Agent 交付物的任务需要 K 名评审人。候选人取自能力注册表中具备任务所需能力的 Agent，
+// 排除任务参与方、与参与方同一运营者的节点，以及最近与参与方合作过的节点；其余按声誉降序、
+// 同分按 sha256(任务ID|节点ID) 排序后取前 K 名，同一任务在任何节点上算出的结果相同。
+// 评审人需在 ReviewAcceptTimeout 内接受，拒绝或超时后从剩余候选人中补选。
+
+var (
+	ErrReviewersAssigned = errors.New("reviewers already assigned")
+	ErrNoReviewers       = errors.New("no eligible reviewers")
+	ErrNotReviewer       = errors.New("not an assigned reviewer")
+	ErrReviewResponded   = errors.New("reviewer already responded")
+)
+
+// ReviewerStatus 评审人状态
+type ReviewerStatus string
+
+const (
+	ReviewerPending   ReviewerStatus = "pending"   // 等待接受
+	ReviewerAccepted  ReviewerStatus = "accepted"  // 已接受
+	ReviewerDeclined  ReviewerStatus = "declined"  // 已拒绝
+	ReviewerTimedOut  ReviewerStatus = "timed_out" // 超时未响应
+	ReviewerCompleted ReviewerStatus = "completed" // 已提交审计结论
+)
+
+// active 仍占用评审名额
+func (s ReviewerStatus) active() bool {
+	return s == ReviewerPending || s == ReviewerAccepted || s == ReviewerCompleted
+}
+
+// Reviewer 一名评审人的分配记录
+type Reviewer struct {
+	NodeID      string         `json:"node_id"`
+	Status      ReviewerStatus `json:"status"`
+	Reputation  float64        `json:"reputation"` // 分配时的声誉
+	AssignedAt  int64          `json:"assigned_at"`
+	RespondedAt int64          `json:"responded_at,omitempty"`
+}
+
+// ReviewAssignment 任务的评审人分配
+type ReviewAssignment struct {
+	TaskID    string      `json:"task_id"`
+	Required  int         `json:"required"` // K
+	Reviewers []*Reviewer `json:"reviewers"`
+	CreatedAt int64       `json:"created_at"`
+}
+
+// Active 仍占用名额的评审人
+func (a *ReviewAssignment) Active() []string {
+	var ids []string
+	for _, r := range a.Reviewers {
+		if r.Status.active() {
+			ids = append(ids, r.NodeID)
+		}
+	}
+	return ids
+}
+
+// OperatorFunc 查询控制节点的运营者身份（如已验证的外部账号），有共同身份的节点视为同一运营者
+type OperatorFunc func(nodeID string) []string
+
+// SetReviewerReputationFunc 设置评审人声誉查询，未设置时候选人只按哈希排序
+func (tm *TaskManager) SetReviewerReputationFunc(fn func(nodeID string) float64) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.reviewerRepFunc = fn
+}
+
+// SetOperatorFunc 设置运营者查询，用于排除与参与方同一运营者的评审人
+func (tm *TaskManager) SetOperatorFunc(fn OperatorFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.operatorFunc = fn
+}
+
+// SetReviewHandler 设置评审人分配变化的回调：超时补选后对每个变化的任务调用，委托方节点据此广播新的分配
+func (tm *TaskManager) SetReviewHandler(fn func(taskID string)) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.reviewHandler = fn
+}
+
+// AssignReviewers 为任务选出 count 名评审人
+// 符合条件的候选人不足 count 时只分配现有的，一名都没有时返回 ErrNoReviewers。
+func (tm *TaskManager) AssignReviewers(taskID string, count int) (*ReviewAssignment, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("%w: reviewer count must be positive", ErrInvalidVerification)
+	}
+	candidates := tm.reviewCandidates(taskID)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	switch task.Status {
+	case StatusAccepted, StatusInProgress, StatusDelivered:
+	default:
+		return nil, ErrInvalidTransition
+	}
+	if _, exists := tm.reviews[taskID]; exists {
+		return nil, ErrReviewersAssigned
+	}
+
+	a := &ReviewAssignment{TaskID: taskID, Required: count, CreatedAt: time.Now().Unix()}
+	if tm.fillReviewersLocked(task, a, candidates, time.Now()) == 0 {
+		return nil, ErrNoReviewers
+	}
+	tm.reviews[taskID] = a
+	tm.save()
+	return copyAssignment(a), nil
+}
+
+// RespondReview 评审人接受或拒绝分配，拒绝后立即补选
+func (tm *TaskManager) RespondReview(taskID, reviewerID string, accept bool) (*ReviewAssignment, error) {
+	var candidates []*Reviewer
+	if !accept {
+		candidates = tm.reviewCandidates(taskID)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	a, exists := tm.reviews[taskID]
+	if !exists {
+		return nil, ErrNotReviewer
+	}
+	r := findReviewer(a, reviewerID)
+	if r == nil {
+		return nil, ErrNotReviewer
+	}
+	if r.Status != ReviewerPending {
+		return nil, ErrReviewResponded
+	}
+
+	now := time.Now()
+	r.RespondedAt = now.Unix()
+	if accept {
+		r.Status = ReviewerAccepted
+	} else {
+		r.Status = ReviewerDeclined
+		if task, ok := tm.tasks[taskID]; ok {
+			tm.fillReviewersLocked(task, a, candidates, now)
+		}
+	}
+	tm.save()
+	return copyAssignment(a), nil
+}
+
+// CheckReviewTimeouts 把超时未接受的评审人标记为超时并补选，返回超时的评审人数
+func (tm *TaskManager) CheckReviewTimeouts(now time.Time) int {
+	timeout := tm.config.ReviewAcceptTimeout
+	if timeout <= 0 {
+		return 0
+	}
+
+	tm.mu.RLock()
+	var expired []string
+	for taskID, a := range tm.reviews {
+		for _, r := range a.Reviewers {
+			if r.Status == ReviewerPending && now.Sub(time.Unix(r.AssignedAt, 0)) > timeout {
+				expired = append(expired, taskID)
+				break
+			}
+		}
+	}
+	tm.mu.RUnlock()
+
+	timedOut := 0
+	var changed []string
+	for _, taskID := range expired {
+		candidates := tm.reviewCandidates(taskID)
+
+		tm.mu.Lock()
+		a, exists := tm.reviews[taskID]
+		task, ok := tm.tasks[taskID]
+		if exists && ok {
+			for _, r := range a.Reviewers {
+				if r.Status == ReviewerPending && now.Sub(time.Unix(r.AssignedAt, 0)) > timeout {
+					r.Status = ReviewerTimedOut
+					r.RespondedAt = now.Unix()
+					timedOut++
+				}
+			}
+			changed = append(changed, taskID)
+			// 已结束的任务不再补选
+			switch task.Status {
+			case StatusAccepted, StatusInProgress, StatusDelivered:
+				tm.fillReviewersLocked(task, a, candidates, now)
+			}
+		}
+		tm.mu.Unlock()
+	}
+
+	if timedOut > 0 {
+		tm.mu.Lock()
+		tm.save()
+		handler := tm.reviewHandler
+		tm.mu.Unlock()
+		if handler != nil {
+			for _, taskID := range changed {
+				handler(taskID)
+			}
+		}
+	}
+	return timedOut
+}
+
+// ReceiveReviewAssignment 保存委托方节点广播的评审人分配副本，from 须为本地任务副本的委托方
+// 其他节点上的评审人据此得知自己被分配，响应仍交给委托方节点记录。
+func (tm *TaskManager) ReceiveReviewAssignment(from string, a *ReviewAssignment) error {
+	if a == nil || a.TaskID == "" {
+		return errors.New("invalid review assignment")
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[a.TaskID]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	if task.RequesterID != from {
+		return fmt.Errorf("review assignment for %s not sent by the requester", a.TaskID)
+	}
+	tm.reviews[a.TaskID] = copyAssignment(a)
+	tm.save()
+	return nil
+}
+
+// PendingReview 检查评审人在本地记录（或分配副本）中是否仍待响应
+func (tm *TaskManager) PendingReview(taskID, reviewerID string) error {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	a, exists := tm.reviews[taskID]
+	if !exists {
+		return ErrNotReviewer
+	}
+	r := findReviewer(a, reviewerID)
+	if r == nil {
+		return ErrNotReviewer
+	}
+	if r.Status != ReviewerPending {
+		return ErrReviewResponded
+	}
+	return nil
+}
+
+// GetReviewAssignment 获取任务的评审人分配
+func (tm *TaskManager) GetReviewAssignment(taskID string) (*ReviewAssignment, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	a, exists := tm.reviews[taskID]
+	if !exists {
+		if _, ok := tm.tasks[taskID]; !ok {
+			return nil, ErrTaskNotFound
+		}
+		return &ReviewAssignment{TaskID: taskID, Reviewers: []*Reviewer{}}, nil
+	}
+	return copyAssignment(a), nil
+}
+
+// reviewCandidates 按能力匹配并排序的候选评审人，已排除参与方和同一运营者的节点
+// 声誉和运营者查询可能访问其他模块，因此在锁外完成；最近合作关系由调用方持锁时过滤。
+func (tm *TaskManager) reviewCandidates(taskID string) []*Reviewer {
+	tm.mu.RLock()
+	task, exists := tm.tasks[taskID]
+	var snapshot Task
+	if exists {
+		snapshot = *task
+	}
+	reputation := tm.reviewerRepFunc
+	operators := tm.operatorFunc
+	supernode := tm.supernodeFunc
+	tm.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	participants := taskParticipants(&snapshot)
+	participantOps := make(map[string]bool)
+	if operators != nil {
+		for _, p := range participants {
+			for _, op := range operators(p) {
+				participantOps[op] = true
+			}
+		}
+	}
+
+	var candidates []*Reviewer
+	for _, agentID := range tm.matchAgents(&snapshot) {
+		if containsString(participants, agentID) {
+			continue
+		}
+		// 超级节点审计只能由超级节点提交结论
+		if snapshot.Verification == VerifySupernodeAudit && supernode != nil && !supernode(agentID) {
+			continue
+		}
+		if operators != nil && sharesOperator(operators(agentID), participantOps) {
+			continue
+		}
+		r := &Reviewer{NodeID: agentID}
+		if reputation != nil {
+			r.Reputation = reputation(agentID)
+		}
+		candidates = append(candidates, r)
+	}
+
+	keys := make(map[string]string, len(candidates))
+	for _, c := range candidates {
+		sum := sha256.Sum256([]byte(taskID + "|" + c.NodeID))
+		keys[c.NodeID] = hex.EncodeToString(sum[:])
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Reputation != candidates[j].Reputation {
+			return candidates[i].Reputation > candidates[j].Reputation
+		}
+		return keys[candidates[i].NodeID] < keys[candidates[j].NodeID]
+	})
+	return candidates
+}
+
+// fillReviewersLocked 从候选人中补足评审名额，跳过已分配过的和最近合作过的节点，返回新增人数
+func (tm *TaskManager) fillReviewersLocked(task *Task, a *ReviewAssignment, candidates []*Reviewer, now time.Time) int {
+	need := a.Required - len(a.Active())
+	added := 0
+	for _, c := range candidates {
+		if added >= need {
+			break
+		}
+		if findReviewer(a, c.NodeID) != nil || tm.recentlyCollaboratedLocked(c.NodeID, task, now) {
+			continue
+		}
+		a.Reviewers = append(a.Reviewers, &Reviewer{
+			NodeID:     c.NodeID,
+			Status:     ReviewerPending,
+			Reputation: c.Reputation,
+			AssignedAt: now.Unix(),
+		})
+		added++
+	}
+	return added
+}
+
+// recentlyCollaboratedLocked 候选人在合作窗口内是否与任务参与方同在一个任务中
+func (tm *TaskManager) recentlyCollaboratedLocked(candidate string, task *Task, now time.Time) bool {
+	window := tm.config.CollaborationWindow
+	if window <= 0 {
+		return false
+	}
+	since := now.Add(-window).Unix()
+	participants := taskParticipants(task)
+	for _, other := range tm.tasks {
+		if other.ID == task.ID || other.CreatedAt < since {
+			continue
+		}
+		members := taskParticipants(other)
+		if !containsString(members, candidate) {
+			continue
+		}
+		for _, p := range participants {
+			if containsString(members, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// taskParticipants 任务的委托方和全部执行者
+func taskParticipants(task *Task) []string {
+	participants := []string{task.RequesterID}
+	if task.ExecutorID != "" {
+		participants = append(participants, task.ExecutorID)
+	}
+	return append(participants, task.ReplicaExecutors...)
+}
+
+func sharesOperator(ops []string, participantOps map[string]bool) bool {
+	for _, op := range ops {
+		if participantOps[op] {
+			return true
+		}
+	}
+	return false
+}
+
+func findReviewer(a *ReviewAssignment, nodeID string) *Reviewer {
+	for _, r := range a.Reviewers {
+		if r.NodeID == nodeID {
+			return r
+		}
+	}
+	return nil
+}
+
+func copyAssignment(a *ReviewAssignment) *ReviewAssignment {
+	c := *a
+	c.Reviewers = make([]*Reviewer, 0, len(a.Reviewers))
+	for _, r := range a.Reviewers {
+		rc := *r
+		c.Reviewers = append(c.Reviewers, &rc)
+	}
+	return &c
+}
@@ -0,0 +1,193 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupReviewTask alice 发布、bob 执行的超级节点审计任务，r1..r6 为候选评审人
+// r5 与 bob 同一运营者，r6 最近为 alice 执行过任务。
+func setupReviewTask(t *testing.T) (*TaskManager, *Task) {
+	tm := createVerificationManager(t)
+	for _, id := range []string{"alice", "bob", "r1", "r2", "r3", "r4", "r5", "r6"} {
+		tm.RegisterCapability(&AgentCapability{AgentID: id, Capabilities: []string{"compute"}})
+	}
+
+	reputation := map[string]float64{"r1": 90, "r2": 80, "r3": 80, "r4": 70, "r5": 95, "r6": 95}
+	tm.SetReviewerReputationFunc(func(nodeID string) float64 { return reputation[nodeID] })
+	tm.SetOperatorFunc(func(nodeID string) []string {
+		if nodeID == "bob" || nodeID == "r5" {
+			return []string{"github:bob-corp"}
+		}
+		return []string{"github:" + nodeID}
+	})
+	tm.SetSupernodeCheckFunc(func(nodeID string) bool { return strings.HasPrefix(nodeID, "r") })
+
+	earlier := &Task{Type: TaskTypeCompute, Title: "earlier", RequesterID: "alice", Reward: 1}
+	if err := tm.PublishTask(earlier, 50.0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	if err := tm.ClaimTask(&TaskClaim{TaskID: earlier.ID, ClaimerID: "r6"}, 50.0); err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+
+	return tm, publishAndClaim(t, tm, &Task{Verification: VerifySupernodeAudit})
+}
+
+func reviewerIDs(a *ReviewAssignment) []string {
+	ids := make([]string, 0, len(a.Reviewers))
+	for _, r := range a.Reviewers {
+		ids = append(ids, r.NodeID)
+	}
+	return ids
+}
+
+func TestAssignReviewers(t *testing.T) {
+	tm, task := setupReviewTask(t)
+
+	a, err := tm.AssignReviewers(task.ID, 3)
+	if err != nil {
+		t.Fatalf("AssignReviewers failed: %v", err)
+	}
+
+	// 声誉降序，r2 与 r3 同分时按 sha256(任务ID|节点ID) 排序
+	second, third := "r2", "r3"
+	h2 := sha256.Sum256([]byte(task.ID + "|r2"))
+	h3 := sha256.Sum256([]byte(task.ID + "|r3"))
+	if hex.EncodeToString(h3[:]) < hex.EncodeToString(h2[:]) {
+		second, third = third, second
+	}
+	want := []string{"r1", second, third}
+	got := reviewerIDs(a)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("reviewers = %v, want %v", got, want)
+	}
+	for _, r := range a.Reviewers {
+		if r.Status != ReviewerPending {
+			t.Errorf("%s status = %s, want pending", r.NodeID, r.Status)
+		}
+	}
+
+	if _, err := tm.AssignReviewers(task.ID, 3); !errors.Is(err, ErrReviewersAssigned) {
+		t.Errorf("expected ErrReviewersAssigned, got %v", err)
+	}
+	if _, err := tm.AssignReviewers("missing", 3); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+
+	// 重新加载后分配记录保留
+	reloaded := NewTaskManager(tm.config)
+	stored, err := reloaded.GetReviewAssignment(task.ID)
+	if err != nil || strings.Join(reviewerIDs(stored), ",") != strings.Join(want, ",") {
+		t.Errorf("reloaded assignment = %+v, %v", stored, err)
+	}
+}
+
+func TestReviewResponsesAndTimeouts(t *testing.T) {
+	tm, task := setupReviewTask(t)
+
+	a, err := tm.AssignReviewers(task.ID, 2)
+	if err != nil {
+		t.Fatalf("AssignReviewers failed: %v", err)
+	}
+	first, second := a.Reviewers[0].NodeID, a.Reviewers[1].NodeID
+
+	if _, err := tm.RespondReview(task.ID, "r4", true); !errors.Is(err, ErrNotReviewer) {
+		t.Errorf("expected ErrNotReviewer, got %v", err)
+	}
+	if _, err := tm.RespondReview(task.ID, first, true); err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	if _, err := tm.RespondReview(task.ID, first, false); !errors.Is(err, ErrReviewResponded) {
+		t.Errorf("expected ErrReviewResponded, got %v", err)
+	}
+
+	// 拒绝后立即补选下一名候选人
+	a, err = tm.RespondReview(task.ID, second, false)
+	if err != nil {
+		t.Fatalf("decline failed: %v", err)
+	}
+	if len(a.Reviewers) != 3 || len(a.Active()) != 2 {
+		t.Fatalf("expected a replacement after decline, got %v", reviewerIDs(a))
+	}
+	replacement := a.Reviewers[2].NodeID
+
+	// 超时后补选最后一名候选人；r5、r6 因利益冲突始终不会入选
+	var changed []string
+	tm.SetReviewHandler(func(taskID string) { changed = append(changed, taskID) })
+	if n := tm.CheckReviewTimeouts(time.Now().Add(30 * time.Minute)); n != 0 {
+		t.Errorf("timed out %d reviewers before the deadline", n)
+	}
+	if n := tm.CheckReviewTimeouts(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("timed out %d reviewers, want 1", n)
+	}
+	if len(changed) != 1 || changed[0] != task.ID {
+		t.Errorf("review handler calls = %v", changed)
+	}
+	a, _ = tm.GetReviewAssignment(task.ID)
+	for _, r := range a.Reviewers {
+		if r.NodeID == "r5" || r.NodeID == "r6" {
+			t.Errorf("conflicted reviewer %s was assigned", r.NodeID)
+		}
+		if r.NodeID == replacement && r.Status != ReviewerTimedOut {
+			t.Errorf("replacement status = %s, want timed_out", r.Status)
+		}
+	}
+	if len(a.Reviewers) != 4 {
+		t.Errorf("reviewers = %v, want the last candidate added", reviewerIDs(a))
+	}
+
+	// 只有评审人可以提交审计结论
+	tm.SubmitDelivery(task.ID, "bob", "h1", "sig")
+	if _, err := tm.RecordAudit(task.ID, replacement, true, ""); !errors.Is(err, ErrNotReviewer) {
+		t.Errorf("expected ErrNotReviewer for timed-out reviewer, got %v", err)
+	}
+	if _, err := tm.RecordAudit(task.ID, first, true, ""); err != nil {
+		t.Fatalf("RecordAudit failed: %v", err)
+	}
+	a, _ = tm.GetReviewAssignment(task.ID)
+	if a.Reviewers[0].Status != ReviewerCompleted {
+		t.Errorf("auditor status = %s, want completed", a.Reviewers[0].Status)
+	}
+}
+
+func TestReceiveReviewAssignment(t *testing.T) {
+	owner, task := setupReviewTask(t)
+	a, err := owner.AssignReviewers(task.ID, 2)
+	if err != nil {
+		t.Fatalf("AssignReviewers failed: %v", err)
+	}
+	reviewer := a.Reviewers[0].NodeID
+
+	replica := createVerificationManager(t)
+	if err := replica.ReceiveReviewAssignment("alice", a); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound without a task copy, got %v", err)
+	}
+	snapshot, _ := owner.Snapshot(task.ID)
+	if err := replica.ReceiveTask(snapshot); err != nil {
+		t.Fatalf("ReceiveTask failed: %v", err)
+	}
+	if err := replica.ReceiveReviewAssignment("bob", a); err == nil {
+		t.Error("assignment from a non-requester should be rejected")
+	}
+	if err := replica.ReceiveReviewAssignment("alice", a); err != nil {
+		t.Fatalf("ReceiveReviewAssignment failed: %v", err)
+	}
+	if err := replica.PendingReview(task.ID, reviewer); err != nil {
+		t.Errorf("assigned reviewer should be pending, got %v", err)
+	}
+	if err := replica.PendingReview(task.ID, "r4"); !errors.Is(err, ErrNotReviewer) {
+		t.Errorf("expected ErrNotReviewer, got %v", err)
+	}
+
+	// 委托方记录响应后广播新的分配
+	a, _ = owner.RespondReview(task.ID, reviewer, true)
+	replica.ReceiveReviewAssignment("alice", a)
+	if err := replica.PendingReview(task.ID, reviewer); !errors.Is(err, ErrReviewResponded) {
+		t.Errorf("expected ErrReviewResponded, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 任务调度：生命周期阶段与截止时间跟踪
+//
+// HTTP 接口和管理后台按 created → accepted → executing → submitted → verified → rewarded
+// 六个阶段展示任务，内部状态机比这更细（已结算、已完成都属于 rewarded）。
+// 调度器定期扫描截止时间：无人接单的任务超过过期时间或截止时间后置为已过期；
+// 已接单但超过截止时间仍未交付的任务只标记一次逾期，由上层决定发起争议或取消。
+
+// ErrUnknownStatus 无法识别的任务状态
+var ErrUnknownStatus = errors.New("unknown task status")
+
+// Stage 对外展示的生命周期阶段
+type Stage string
+
+const (
+	StageCreated   Stage = "created"
+	StageAccepted  Stage = "accepted"
+	StageExecuting Stage = "executing"
+	StageSubmitted Stage = "submitted"
+	StageVerified  Stage = "verified"
+	StageRewarded  Stage = "rewarded"
+	StageDisputed  Stage = "disputed"
+	StageClosed    Stage = "closed" // 已取消或已过期
+)
+
+// StageOf 任务状态对应的生命周期阶段
+func StageOf(status TaskStatus) Stage {
+	switch status {
+	case StatusDraft, StatusPublished:
+		return StageCreated
+	case StatusAccepted:
+		return StageAccepted
+	case StatusInProgress:
+		return StageExecuting
+	case StatusDelivered:
+		return StageSubmitted
+	case StatusVerified:
+		return StageVerified
+	case StatusSettled, StatusCompleted:
+		return StageRewarded
+	case StatusDisputed:
+		return StageDisputed
+	default:
+		return StageClosed
+	}
+}
+
+// ParseStatus 解析任务状态，空字符串表示不限
+func ParseStatus(s string) (TaskStatus, error) {
+	status := TaskStatus(s)
+	switch status {
+	case "", StatusDraft, StatusPublished, StatusAccepted, StatusInProgress, StatusDelivered,
+		StatusVerified, StatusSettled, StatusCompleted, StatusDisputed, StatusCancelled, StatusExpired:
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownStatus, s)
+}
+
+// HashResult 执行结果的交付物摘要（sha256 十六进制）
+func HashResult(result []byte) string {
+	sum := sha256.Sum256(result)
+	return hex.EncodeToString(sum[:])
+}
+
+// SubmitResult 执行方提交结果：已接单尚未开始的任务先进入执行中，再按摘要交付
+func (tm *TaskManager) SubmitResult(taskID, executorID, deliverableHash, signature string) error {
+	tm.mu.RLock()
+	task, exists := tm.tasks[taskID]
+	start := exists && task.Status == StatusAccepted && task.ExecutorID == executorID
+	tm.mu.RUnlock()
+
+	if start {
+		if err := tm.StartExecution(taskID, executorID); err != nil {
+			return err
+		}
+	}
+	return tm.SubmitDelivery(taskID, executorID, deliverableHash, signature)
+}
+
+// DeadlineKind 截止时间事件类型
+type DeadlineKind string
+
+const (
+	DeadlineExpired DeadlineKind = "expired" // 无人接单，任务已过期
+	DeadlineOverdue DeadlineKind = "overdue" // 已接单但超过截止时间仍未交付
+)
+
+// DeadlineEvent 截止时间事件
+type DeadlineEvent struct {
+	TaskID      string       `json:"task_id"`
+	Kind        DeadlineKind `json:"kind"`
+	RequesterID string       `json:"requester_id"`
+	ExecutorID  string       `json:"executor_id,omitempty"`
+	Deadline    int64        `json:"deadline"`
+	DetectedAt  int64        `json:"detected_at"`
+}
+
+// DeadlineHandler 截止时间事件回调
+type DeadlineHandler func(ev DeadlineEvent)
+
+// SetDeadlineHandler 设置截止时间事件回调
+func (tm *TaskManager) SetDeadlineHandler(h DeadlineHandler) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.deadlineHandler = h
+}
+
+// CheckDeadlines 检查截止时间，过期未接单的任务置为已过期，逾期未交付的任务标记逾期
+func (tm *TaskManager) CheckDeadlines(now time.Time) []DeadlineEvent {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	ts := now.Unix()
+	var events []DeadlineEvent
+	for _, task := range tm.tasks {
+		switch task.Status {
+		case StatusPublished:
+			deadline := task.ExpiresAt
+			if deadline == 0 || (task.Deadline > 0 && task.Deadline < deadline) {
+				deadline = task.Deadline
+			}
+			if deadline == 0 || ts <= deadline {
+				continue
+			}
+			task.Status = StatusExpired
+			events = append(events, DeadlineEvent{
+				TaskID:      task.ID,
+				Kind:        DeadlineExpired,
+				RequesterID: task.RequesterID,
+				Deadline:    deadline,
+				DetectedAt:  ts,
+			})
+		case StatusAccepted, StatusInProgress:
+			if task.Deadline == 0 || ts <= task.Deadline || task.OverdueAt > 0 {
+				continue
+			}
+			task.OverdueAt = ts
+			events = append(events, DeadlineEvent{
+				TaskID:      task.ID,
+				Kind:        DeadlineOverdue,
+				RequesterID: task.RequesterID,
+				ExecutorID:  task.ExecutorID,
+				Deadline:    task.Deadline,
+				DetectedAt:  ts,
+			})
+		}
+	}
+
+	if len(events) > 0 {
+		sort.Slice(events, func(i, j int) bool { return events[i].TaskID < events[j].TaskID })
+		tm.save()
+	}
+	return events
+}
+
+// StartScheduler 启动截止时间扫描（间隔为 DeadlineCheckInterval），同时处理评审人接受超时
+func (tm *TaskManager) StartScheduler() {
+	interval := tm.config.DeadlineCheckInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	tm.mu.Lock()
+	if tm.stopCh != nil {
+		tm.mu.Unlock()
+		return
+	}
+	tm.stopCh = make(chan struct{})
+	stopCh := tm.stopCh
+	tm.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				events := tm.CheckDeadlines(time.Now())
+				tm.CheckReviewTimeouts(time.Now())
+				tm.mu.RLock()
+				handler := tm.deadlineHandler
+				tm.mu.RUnlock()
+				if handler != nil {
+					for _, ev := range events {
+						handler(ev)
+					}
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// StopScheduler 停止截止时间扫描
+func (tm *TaskManager) StopScheduler() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if tm.stopCh != nil {
+		close(tm.stopCh)
+		tm.stopCh = nil
+	}
+}
+
+// ListTasks 按状态列出任务副本（空状态表示全部），按创建时间从新到旧排列
+func (tm *TaskManager) ListTasks(status TaskStatus, limit int) []*Task {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	tasks := make([]*Task, 0)
+	for _, task := range tm.tasks {
+		if status == "" || task.Status == status {
+			copied := *task
+			tasks = append(tasks, &copied)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt != tasks[j].CreatedAt {
+			return tasks[i].CreatedAt > tasks[j].CreatedAt
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
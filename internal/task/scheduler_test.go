@@ -0,0 +1,110 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskDeadlines(t *testing.T) {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.DefaultBidding = 0
+	tm := NewTaskManager(config)
+
+	now := time.Now()
+	publish := func(title string, deadline time.Time) *Task {
+		t.Helper()
+		task := &Task{
+			Type:        TaskTypeSearch,
+			Title:       title,
+			RequesterID: "requester1",
+			Deadline:    deadline.Unix(),
+		}
+		if err := tm.PublishTask(task, 50.0); err != nil {
+			t.Fatalf("PublishTask failed: %v", err)
+		}
+		return task
+	}
+
+	open := publish("open", now.Add(time.Hour))
+	claimed := publish("claimed", now.Add(time.Hour))
+	later := publish("later", now.Add(3*time.Hour))
+	for _, task := range []*Task{claimed, later} {
+		if err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "executor1"}, 50.0); err != nil {
+			t.Fatalf("ClaimTask failed: %v", err)
+		}
+	}
+
+	if events := tm.CheckDeadlines(now); len(events) != 0 {
+		t.Fatalf("no deadline has passed yet, got %+v", events)
+	}
+
+	events := tm.CheckDeadlines(now.Add(2 * time.Hour))
+	if len(events) != 2 {
+		t.Fatalf("expected 2 deadline events, got %+v", events)
+	}
+	kinds := map[string]DeadlineKind{}
+	for _, ev := range events {
+		kinds[ev.TaskID] = ev.Kind
+	}
+	if kinds[open.ID] != DeadlineExpired || kinds[claimed.ID] != DeadlineOverdue {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	if got, _ := tm.GetTask(open.ID); got.Status != StatusExpired || StageOf(got.Status) != StageClosed {
+		t.Errorf("unclaimed task should expire, got %s", got.Status)
+	}
+	if got, _ := tm.GetTask(claimed.ID); got.Status != StatusAccepted || got.OverdueAt == 0 {
+		t.Errorf("claimed task should stay accepted and be flagged overdue: %+v", got)
+	}
+
+	// 逾期只报告一次
+	if events := tm.CheckDeadlines(now.Add(2 * time.Hour)); len(events) != 0 {
+		t.Errorf("overdue task reported twice: %+v", events)
+	}
+
+	if open := tm.GetOpenTasks(); len(open) != 0 {
+		t.Errorf("expired task should not be open, got %d", len(open))
+	}
+	if accepted := tm.ListTasks(StatusAccepted, 1); len(accepted) != 1 {
+		t.Errorf("limit not applied, got %d", len(accepted))
+	}
+
+	// 已接单的任务提交结果时自动进入执行中再交付
+	hash := HashResult([]byte("result"))
+	if err := tm.SubmitResult(later.ID, "executor2", hash, ""); err != ErrNotAssignedToMe {
+		t.Errorf("expected ErrNotAssignedToMe, got %v", err)
+	}
+	if err := tm.SubmitResult(later.ID, "executor1", hash, "sig"); err != nil {
+		t.Fatalf("SubmitResult failed: %v", err)
+	}
+	if got, _ := tm.GetTask(later.ID); got.Status != StatusDelivered || got.DeliverableHash != hash {
+		t.Errorf("unexpected task after submit: %s %s", got.Status, got.DeliverableHash)
+	}
+	if _, err := ParseStatus("bogus"); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("expected ErrUnknownStatus, got %v", err)
+	}
+
+	reloaded := NewTaskManager(config)
+	if got, _ := reloaded.GetTask(claimed.ID); got.OverdueAt == 0 {
+		t.Error("overdue flag should be persisted")
+	}
+}
+
+func TestStageOf(t *testing.T) {
+	cases := map[TaskStatus]Stage{
+		StatusPublished:  StageCreated,
+		StatusAccepted:   StageAccepted,
+		StatusInProgress: StageExecuting,
+		StatusDelivered:  StageSubmitted,
+		StatusVerified:   StageVerified,
+		StatusSettled:    StageRewarded,
+		StatusCompleted:  StageRewarded,
+		StatusCancelled:  StageClosed,
+	}
+	for status, want := range cases {
+		if got := StageOf(status); got != want {
+			t.Errorf("StageOf(%s) = %s, want %s", status, got, want)
+		}
+	}
+}
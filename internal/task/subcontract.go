@@ -0,0 +1,252 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SubcontractEscrowFunc 为转包子任务创建嵌套托管，预算从上级任务的托管中划拨
+// 返回错误时转包失败，子任务不会被创建。
+type SubcontractEscrowFunc func(parentTaskID, subtaskID, payerID string, budget float64) error
+
+// PenaltyFunc 执行转包失败的处罚
+type PenaltyFunc func(nodeID string, amount float64, reason string)
+
+// ChainPenalty 转包失败时沿转包链向上传递的处罚
+type ChainPenalty struct {
+	TaskID string  `json:"task_id"`
+	NodeID string  `json:"node_id"`
+	Amount float64 `json:"amount"`
+	Level  int     `json:"level"` // 0 为直接失败方，逐级向上递增
+}
+
+// SetSubcontractEscrowFunc 设置嵌套托管回调
+func (tm *TaskManager) SetSubcontractEscrowFunc(fn SubcontractEscrowFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.subcontractEscrowFunc = fn
+}
+
+// SetPenaltyFunc 设置处罚回调
+func (tm *TaskManager) SetPenaltyFunc(fn PenaltyFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.penaltyFunc = fn
+}
+
+// SubcontractTask 执行者把已接受任务的一部分转包给其他节点
+// 子任务由执行者作为委托方发布，报酬从上级任务报酬中拆分：
+// 所有未取消子任务的报酬之和不能超过上级报酬的 MaxSubcontractShare，
+// 子任务截止时间不能晚于上级任务，转包层级不能超过 MaxSubcontractDepth。
+func (tm *TaskManager) SubcontractTask(parentID, executorID string, sub *Task) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	parent, exists := tm.tasks[parentID]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	if parent.ExecutorID != executorID {
+		return ErrNotAssignedToMe
+	}
+	if parent.Status != StatusAccepted && parent.Status != StatusInProgress {
+		return ErrInvalidTransition
+	}
+	if parent.SubcontractDepth+1 > tm.maxSubcontractDepth() {
+		return fmt.Errorf("%w: max %d", ErrSubcontractDepth, tm.maxSubcontractDepth())
+	}
+
+	if sub.Reward <= 0 {
+		return errors.New("subtask reward must be positive")
+	}
+	budget := parent.Reward * tm.maxSubcontractShare()
+	allocated := tm.allocatedBudget(parent)
+	if allocated+sub.Reward > budget+1e-9 {
+		return fmt.Errorf("%w: requested %.2f, available %.2f", ErrSubcontractBudget, sub.Reward, budget-allocated)
+	}
+
+	if parent.Deadline > 0 && (sub.Deadline == 0 || sub.Deadline > parent.Deadline) {
+		sub.Deadline = parent.Deadline
+	}
+	if sub.Type == "" {
+		sub.Type = parent.Type
+	}
+	sub.RequesterID = executorID
+	if !sub.IsValid() {
+		return errors.New("invalid task")
+	}
+
+	if sub.ID == "" {
+		sub.ID = tm.generateID()
+	}
+	sub.ParentTaskID = parent.ID
+	sub.SubcontractDepth = parent.SubcontractDepth + 1
+	sub.CreatedAt = time.Now().Unix()
+	sub.Status = StatusPublished
+	if sub.TargetExecutorID != "" && sub.PublishMode == "" {
+		sub.PublishMode = ModeDirect
+	}
+	if sub.RequesterDeposit == 0 {
+		sub.RequesterDeposit = sub.Reward * tm.config.DepositMultiplier
+	}
+
+	if tm.subcontractEscrowFunc != nil {
+		if err := tm.subcontractEscrowFunc(parent.ID, sub.ID, executorID, sub.Reward); err != nil {
+			return fmt.Errorf("create nested escrow: %w", err)
+		}
+	}
+
+	parent.Subtasks = append(parent.Subtasks, sub.ID)
+	tm.tasks[sub.ID] = sub
+	tm.addToIndex(sub)
+
+	tm.save()
+	return nil
+}
+
+// ReportSubcontractFailure 转包方（子任务委托方）报告子任务失败
+// 子任务进入争议状态；承包方承担全额处罚，处罚按 PenaltyPropagation 逐级衰减
+// 传递给链上每一个转包方——转包不免除对上级委托方的责任。
+func (tm *TaskManager) ReportSubcontractFailure(subtaskID, reporterID, reason string) ([]*ChainPenalty, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	sub, exists := tm.tasks[subtaskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	if sub.ParentTaskID == "" {
+		return nil, errors.New("task is not a subcontract")
+	}
+	if sub.RequesterID != reporterID {
+		return nil, errors.New("not the task requester")
+	}
+	if sub.ExecutorID == "" {
+		return nil, errors.New("subtask has no executor")
+	}
+	if !sub.CanTransition(StatusDisputed) {
+		return nil, ErrInvalidTransition
+	}
+
+	sub.Status = StatusDisputed
+	sub.FailureReason = reason
+
+	base := sub.ExecutorDeposit
+	if base == 0 {
+		base = sub.Reward
+	}
+	factor := tm.penaltyPropagation()
+
+	penalties := []*ChainPenalty{{TaskID: sub.ID, NodeID: sub.ExecutorID, Amount: base}}
+	level := 1
+	for t := sub; t.ParentTaskID != ""; level++ {
+		parent, ok := tm.tasks[t.ParentTaskID]
+		if !ok {
+			break
+		}
+		penalties = append(penalties, &ChainPenalty{
+			TaskID: parent.ID,
+			NodeID: parent.ExecutorID,
+			Amount: base * math.Pow(factor, float64(level)),
+			Level:  level,
+		})
+		t = parent
+	}
+
+	if tm.penaltyFunc != nil {
+		for _, p := range penalties {
+			tm.penaltyFunc(p.NodeID, p.Amount, fmt.Sprintf("subcontract %s failed: %s", sub.ID, reason))
+		}
+	}
+
+	tm.save()
+	return penalties, nil
+}
+
+// GetSubcontractChain 获取从原始任务到指定任务的转包链
+func (tm *TaskManager) GetSubcontractChain(taskID string) ([]*Task, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	var chain []*Task
+	for id := taskID; id != ""; {
+		task, exists := tm.tasks[id]
+		if !exists {
+			return nil, ErrTaskNotFound
+		}
+		chain = append([]*Task{task}, chain...)
+		id = task.ParentTaskID
+	}
+	return chain, nil
+}
+
+// GetSubtasks 获取任务转包出去的子任务
+func (tm *TaskManager) GetSubtasks(taskID string) ([]*Task, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	subtasks := make([]*Task, 0, len(task.Subtasks))
+	for _, id := range task.Subtasks {
+		if sub, ok := tm.tasks[id]; ok {
+			subtasks = append(subtasks, sub)
+		}
+	}
+	return subtasks, nil
+}
+
+// allocatedBudget 已转包出去的报酬（取消和过期的子任务不计入）
+func (tm *TaskManager) allocatedBudget(task *Task) float64 {
+	var total float64
+	for _, id := range task.Subtasks {
+		sub, ok := tm.tasks[id]
+		if !ok || sub.Status == StatusCancelled || sub.Status == StatusExpired {
+			continue
+		}
+		total += sub.Reward
+	}
+	return total
+}
+
+// hasPendingSubtasks 是否有尚未结束的子任务
+func (tm *TaskManager) hasPendingSubtasks(task *Task) bool {
+	for _, id := range task.Subtasks {
+		sub, ok := tm.tasks[id]
+		if !ok {
+			continue
+		}
+		switch sub.Status {
+		case StatusSettled, StatusCompleted, StatusCancelled, StatusExpired:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func (tm *TaskManager) maxSubcontractDepth() int {
+	if tm.config.MaxSubcontractDepth > 0 {
+		return tm.config.MaxSubcontractDepth
+	}
+	return DefaultConfig().MaxSubcontractDepth
+}
+
+func (tm *TaskManager) maxSubcontractShare() float64 {
+	if tm.config.MaxSubcontractShare > 0 {
+		return tm.config.MaxSubcontractShare
+	}
+	return DefaultConfig().MaxSubcontractShare
+}
+
+func (tm *TaskManager) penaltyPropagation() float64 {
+	if tm.config.PenaltyPropagation > 0 {
+		return tm.config.PenaltyPropagation
+	}
+	return DefaultConfig().PenaltyPropagation
+}
@@ -0,0 +1,152 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func createSubcontractManager(t *testing.T) (*TaskManager, *Task) {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.MaxTasksPerHour = 10
+	tm := NewTaskManager(config)
+
+	root := &Task{
+		Type:        TaskTypeCompute,
+		Title:       "Train model",
+		RequesterID: "alice",
+		Reward:      10.0,
+		Deadline:    time.Now().Add(24 * time.Hour).Unix(),
+	}
+	if err := tm.PublishTask(root, 50.0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	if err := tm.ClaimTask(&TaskClaim{TaskID: root.ID, ClaimerID: "bob"}, 50.0); err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+	return tm, root
+}
+
+func TestSubcontractTask(t *testing.T) {
+	tm, root := createSubcontractManager(t)
+
+	var escrowCalls int
+	tm.SetSubcontractEscrowFunc(func(parentTaskID, subtaskID, payerID string, budget float64) error {
+		escrowCalls++
+		if parentTaskID != root.ID || payerID != "bob" {
+			t.Errorf("unexpected escrow call: %s %s", parentTaskID, payerID)
+		}
+		return nil
+	})
+
+	if err := tm.SubcontractTask(root.ID, "mallory", &Task{Title: "part", Reward: 1}); err != ErrNotAssignedToMe {
+		t.Errorf("expected ErrNotAssignedToMe, got %v", err)
+	}
+
+	sub := &Task{Title: "Preprocess data", Reward: 5.0, TargetExecutorID: "carol"}
+	if err := tm.SubcontractTask(root.ID, "bob", sub); err != nil {
+		t.Fatalf("SubcontractTask failed: %v", err)
+	}
+	if sub.RequesterID != "bob" || sub.ParentTaskID != root.ID || sub.SubcontractDepth != 1 {
+		t.Errorf("subtask chain not recorded: %+v", sub)
+	}
+	if sub.Deadline != root.Deadline || sub.PublishMode != ModeDirect {
+		t.Errorf("subtask deadline/mode not inherited: %d %s", sub.Deadline, sub.PublishMode)
+	}
+
+	// 默认最多转包 80% 报酬
+	if err := tm.SubcontractTask(root.ID, "bob", &Task{Title: "more", Reward: 4.0}); !errors.Is(err, ErrSubcontractBudget) {
+		t.Errorf("expected ErrSubcontractBudget, got %v", err)
+	}
+	if escrowCalls != 1 {
+		t.Errorf("escrow func called %d times, want 1", escrowCalls)
+	}
+
+	chain, _ := tm.GetSubcontractChain(sub.ID)
+	if len(chain) != 2 || chain[0].ID != root.ID {
+		t.Errorf("unexpected chain length %d", len(chain))
+	}
+}
+
+func TestSubcontractEscrowFailure(t *testing.T) {
+	tm, root := createSubcontractManager(t)
+	tm.SetSubcontractEscrowFunc(func(string, string, string, float64) error {
+		return errors.New("insufficient funds")
+	})
+
+	if err := tm.SubcontractTask(root.ID, "bob", &Task{Title: "part", Reward: 2.0}); err == nil {
+		t.Fatal("subcontract should fail when escrow cannot be created")
+	}
+	if subs, _ := tm.GetSubtasks(root.ID); len(subs) != 0 {
+		t.Errorf("no subtask should be recorded, got %d", len(subs))
+	}
+}
+
+func TestSubcontractDepthAndSettlement(t *testing.T) {
+	tm, root := createSubcontractManager(t)
+	tm.config.MaxSubcontractDepth = 1
+
+	sub := &Task{Title: "part", Reward: 4.0}
+	tm.SubcontractTask(root.ID, "bob", sub)
+	tm.ClaimTask(&TaskClaim{TaskID: sub.ID, ClaimerID: "carol"}, 50.0)
+
+	if err := tm.SubcontractTask(sub.ID, "carol", &Task{Title: "nested", Reward: 1.0}); !errors.Is(err, ErrSubcontractDepth) {
+		t.Errorf("expected ErrSubcontractDepth, got %v", err)
+	}
+
+	// 子任务未结清时上级任务不能结算
+	tm.StartExecution(root.ID, "bob")
+	tm.SubmitDelivery(root.ID, "bob", "hash", "sig")
+	tm.ConfirmDelivery(root.ID, "alice", "sig")
+	if _, err := tm.SettleTask(root.ID); err != ErrSubtasksPending {
+		t.Errorf("expected ErrSubtasksPending, got %v", err)
+	}
+
+	tm.StartExecution(sub.ID, "carol")
+	tm.SubmitDelivery(sub.ID, "carol", "hash", "sig")
+	tm.ConfirmDelivery(sub.ID, "bob", "sig")
+	if _, err := tm.SettleTask(sub.ID); err != nil {
+		t.Fatalf("settle subtask failed: %v", err)
+	}
+	if _, err := tm.SettleTask(root.ID); err != nil {
+		t.Errorf("settle root failed: %v", err)
+	}
+}
+
+func TestReportSubcontractFailure(t *testing.T) {
+	tm, root := createSubcontractManager(t)
+
+	sub := &Task{Title: "part", Reward: 6.0}
+	tm.SubcontractTask(root.ID, "bob", sub)
+	tm.ClaimTask(&TaskClaim{TaskID: sub.ID, ClaimerID: "carol"}, 50.0)
+
+	nested := &Task{Title: "nested", Reward: 4.0}
+	if err := tm.SubcontractTask(sub.ID, "carol", nested); err != nil {
+		t.Fatalf("nested subcontract failed: %v", err)
+	}
+	tm.ClaimTask(&TaskClaim{TaskID: nested.ID, ClaimerID: "dave"}, 50.0)
+
+	penalized := make(map[string]float64)
+	tm.SetPenaltyFunc(func(nodeID string, amount float64, reason string) {
+		penalized[nodeID] += amount
+	})
+
+	if _, err := tm.ReportSubcontractFailure(nested.ID, "bob", "timeout"); err == nil {
+		t.Error("only the subtask requester can report failure")
+	}
+
+	penalties, err := tm.ReportSubcontractFailure(nested.ID, "carol", "timeout")
+	if err != nil {
+		t.Fatalf("ReportSubcontractFailure failed: %v", err)
+	}
+	if len(penalties) != 3 {
+		t.Fatalf("expected 3 penalties along the chain, got %d", len(penalties))
+	}
+	if penalized["dave"] != 4.0 || penalized["carol"] != 2.0 || penalized["bob"] != 1.0 {
+		t.Errorf("unexpected penalties: %v", penalized)
+	}
+	if nested.Status != StatusDisputed || nested.FailureReason != "timeout" {
+		t.Errorf("subtask should be disputed, got %s", nested.Status)
+	}
+}
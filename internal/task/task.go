@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/stats"
 )
 
 var (
@@ -23,6 +26,15 @@ var (
 	ErrNotAssignedToMe    = errors.New("task not assigned to me")
 	ErrInvalidProof       = errors.New("invalid delivery proof")
 	ErrQuotaExceeded      = errors.New("task quota exceeded")
+	ErrSubcontractDepth   = errors.New("subcontract depth exceeded")
+	ErrSubcontractBudget  = errors.New("subcontract budget exceeded")
+	ErrSubtasksPending    = errors.New("subtasks are not finished")
+	ErrInvalidVerification = errors.New("invalid verification policy")
+	ErrVerificationMethod  = errors.New("not allowed by task verification method")
+	ErrVerificationPending = errors.New("task verification not satisfied")
+	ErrNotSupernode        = errors.New("auditor is not a supernode")
+	ErrReplicasFull        = errors.New("redundant executors already full")
+	ErrStaleTask           = errors.New("task copy is not newer than the local one")
 )
 
 // TaskManagerConfig 任务管理器配置
@@ -34,6 +46,17 @@ type TaskManagerConfig struct {
 	MinRepToPublish   float64       // 发布任务最低声誉
 	DepositMultiplier float64       // 押金倍数（相对于奖励）
 	ResponseTimeout   time.Duration // 响应超时
+
+	// 转包（0 表示使用默认值）
+	MaxSubcontractDepth int     // 最大转包层级
+	MaxSubcontractShare float64 // 可转包出去的报酬比例上限（相对于本任务报酬）
+	PenaltyPropagation  float64 // 转包失败时处罚逐级向上传递的系数
+
+	DeadlineCheckInterval time.Duration // 截止时间扫描间隔
+
+	// 审计评审人分配
+	ReviewAcceptTimeout time.Duration // 评审人接受分配的时限，超时后补选
+	CollaborationWindow time.Duration // 在此期间与参与方同做过任务的节点不得担任评审人
 }
 
 // DefaultConfig 返回默认配置
@@ -46,6 +69,15 @@ func DefaultConfig() *TaskManagerConfig {
 		MinRepToPublish:   30.0,
 		DepositMultiplier: 1.2, // 押金 = 奖励 * 1.2
 		ResponseTimeout:   24 * time.Hour,
+
+		MaxSubcontractDepth: 3,
+		MaxSubcontractShare: 0.8,
+		PenaltyPropagation:  0.5,
+
+		DeadlineCheckInterval: time.Minute,
+
+		ReviewAcceptTimeout: time.Hour,
+		CollaborationWindow: 30 * 24 * time.Hour,
 	}
 }
 
@@ -60,7 +92,6 @@ type TaskManager struct {
 	// 索引
 	tasksByRequester map[string][]string // requesterID -> []taskID
 	tasksByExecutor  map[string][]string // executorID -> []taskID
-	tasksByStatus    map[TaskStatus][]string
 	tasksByType      map[TaskType][]string
 
 	// 能力注册表
@@ -75,6 +106,41 @@ type TaskManager struct {
 
 	// 承诺-揭示
 	commitReveals map[string]*CommitReveal // taskID -> commit-reveal
+
+	// 转包回调
+	subcontractEscrowFunc SubcontractEscrowFunc
+	penaltyFunc           PenaltyFunc
+
+	// 验收
+	verifications map[string]*VerificationRecord // taskID -> record
+	supernodeFunc SupernodeCheckFunc
+
+	// 评审人分配
+	reviews         map[string]*ReviewAssignment // taskID -> assignment
+	reviewerRepFunc func(nodeID string) float64
+	operatorFunc    OperatorFunc
+	reviewHandler   func(taskID string)
+
+	// 执行进度（仅在内存中，最终结果以交付为准）
+	progress         map[string]map[string]*TaskProgress // taskID -> executorID -> progress
+	progressSendFunc ProgressSendFunc
+	progressListener ProgressListener
+
+	// 公共可达性分数（邻居回拨证明汇总），用于候选执行方排序
+	reachabilityFunc ReachabilityFunc
+
+	// 截止时间扫描
+	deadlineHandler DeadlineHandler
+	stopCh          chan struct{}
+
+	// 结算和取消回调（如支付或退回代币账本上的预付报酬）
+	settlementHandler SettlementHandler
+	cancelHandler     CancelHandler
+
+	// 吞吐计数（本次启动以来）
+	published uint64
+	delivered uint64
+	settled   uint64
 }
 
 type rateLimitRecord struct {
@@ -93,13 +159,15 @@ func NewTaskManager(config *TaskManagerConfig) *TaskManager {
 		tasks:            make(map[string]*Task),
 		tasksByRequester: make(map[string][]string),
 		tasksByExecutor:  make(map[string][]string),
-		tasksByStatus:    make(map[TaskStatus][]string),
 		tasksByType:      make(map[TaskType][]string),
 		capabilities:     make(map[string]*AgentCapability),
 		capIndex:         make(map[string][]string),
 		publishCount:     make(map[string]*rateLimitRecord),
 		deliveryProofs:   make(map[string]*DeliveryProof),
 		commitReveals:    make(map[string]*CommitReveal),
+		verifications:    make(map[string]*VerificationRecord),
+		reviews:          make(map[string]*ReviewAssignment),
+		progress:         make(map[string]map[string]*TaskProgress),
 	}
 
 	// 尝试加载持久化数据
@@ -127,6 +195,21 @@ func (tm *TaskManager) PublishTask(task *Task, requesterRep float64) error {
 	if !task.IsValid() {
 		return errors.New("invalid task")
 	}
+	if err := normalizePayloadFormats(task); err != nil {
+		return err
+	}
+	if err := validateVerification(task); err != nil {
+		return err
+	}
+
+	// 定向委托：目标已登记能力时提前检查载荷格式
+	if task.PublishMode == ModeDirect && task.TargetExecutorID != "" {
+		if _, registered := tm.capabilities[task.TargetExecutorID]; registered {
+			if _, err := tm.negotiateFor(task, task.TargetExecutorID, nil); err != nil {
+				return err
+			}
+		}
+	}
 
 	// 生成ID
 	if task.ID == "" {
@@ -136,6 +219,7 @@ func (tm *TaskManager) PublishTask(task *Task, requesterRep float64) error {
 	// 设置默认值
 	task.CreatedAt = time.Now().Unix()
 	task.Status = StatusPublished
+	tm.published++
 
 	// 设置竞标截止时间
 	if task.BiddingPeriod > 0 {
@@ -180,6 +264,11 @@ func (tm *TaskManager) SubmitBid(bid *TaskBid) error {
 		return fmt.Errorf("%w: need %.1f, have %.1f", ErrInsufficientRep, task.MinReputation, bid.Reputation)
 	}
 
+	// 检查载荷格式
+	if _, err := tm.negotiateFor(task, bid.BidderID, bid.PayloadFormats); err != nil {
+		return err
+	}
+
 	// 设置时间
 	bid.BidTime = time.Now().Unix()
 
@@ -201,6 +290,10 @@ func (tm *TaskManager) ClaimTask(claim *TaskClaim, claimerRep float64) error {
 	}
 
 	if task.Status != StatusPublished {
+		// 冗余执行的任务有人接单后，其他节点继续接单成为冗余执行者，直到凑满 M 份
+		if task.Verification == VerifyRedundant && task.ExecutorID != "" {
+			return tm.addReplicaLocked(task, claim.ClaimerID, claimerRep)
+		}
 		return ErrTaskAlreadyAssigned
 	}
 
@@ -219,7 +312,14 @@ func (tm *TaskManager) ClaimTask(claim *TaskClaim, claimerRep float64) error {
 		return fmt.Errorf("%w: need %.1f, have %.1f", ErrInsufficientRep, task.MinReputation, claimerRep)
 	}
 
+	// 协商载荷格式
+	format, err := tm.negotiateFor(task, claim.ClaimerID, claim.PayloadFormats)
+	if err != nil {
+		return err
+	}
+
 	// 分配任务
+	task.PayloadFormat = format
 	task.ExecutorID = claim.ClaimerID
 	task.Status = StatusAccepted
 
@@ -247,7 +347,20 @@ func (tm *TaskManager) AssignTask(assignment *TaskAssignment) error {
 		return ErrTaskAlreadyAssigned
 	}
 
+	// 协商载荷格式：优先使用该执行方竞标时声明的格式
+	var declared []PayloadFormat
+	for _, bid := range task.Bids {
+		if bid.BidderID == assignment.AssignedTo {
+			declared = bid.PayloadFormats
+		}
+	}
+	format, err := tm.negotiateFor(task, assignment.AssignedTo, declared)
+	if err != nil {
+		return err
+	}
+
 	// 分配
+	task.PayloadFormat = format
 	task.ExecutorID = assignment.AssignedTo
 	task.Status = StatusAccepted
 
@@ -291,6 +404,11 @@ func (tm *TaskManager) SubmitDelivery(taskID, executorID, deliverableHash, signa
 		return ErrTaskNotFound
 	}
 
+	// 冗余执行的其他执行者只登记结果
+	if task.Verification == VerifyRedundant && task.ExecutorID != executorID {
+		return tm.submitReplicaResult(task, executorID, deliverableHash)
+	}
+
 	if task.ExecutorID != executorID {
 		return ErrNotAssignedToMe
 	}
@@ -310,6 +428,10 @@ func (tm *TaskManager) SubmitDelivery(taskID, executorID, deliverableHash, signa
 	tm.deliveryProofs[taskID] = proof
 	task.DeliverableHash = deliverableHash
 	task.Status = StatusDelivered
+	tm.delivered++
+
+	// 自动验收的策略在交付时判定
+	tm.applyDeliveryVerification(task, executorID, deliverableHash)
 
 	tm.save()
 	return nil
@@ -329,6 +451,11 @@ func (tm *TaskManager) ConfirmDelivery(taskID, requesterID, signature string) er
 		return errors.New("not the task requester")
 	}
 
+	// 只有人工验收类的任务由委托方确认
+	if !task.Verification.requesterConfirms() {
+		return fmt.Errorf("%w: task uses %s verification", ErrVerificationMethod, task.Verification)
+	}
+
 	if !task.CanTransition(StatusVerified) {
 		return ErrInvalidTransition
 	}
@@ -349,11 +476,40 @@ func (tm *TaskManager) ConfirmDelivery(taskID, requesterID, signature string) er
 	return nil
 }
 
+// SettlementHandler 任务结算后的回调，在任务管理器锁外调用
+type SettlementHandler func(result *SettlementResult)
+
+// CancelHandler 任务取消后的回调，在任务管理器锁外调用
+type CancelHandler func(task *Task)
+
+// SetSettlementHandler 设置任务结算后的回调
+func (tm *TaskManager) SetSettlementHandler(h SettlementHandler) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.settlementHandler = h
+}
+
+// SetCancelHandler 设置任务取消后的回调
+func (tm *TaskManager) SetCancelHandler(h CancelHandler) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.cancelHandler = h
+}
+
 // SettleTask 结算任务
 func (tm *TaskManager) SettleTask(taskID string) (*SettlementResult, error) {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	result, err := tm.settleLocked(taskID)
+	handler := tm.settlementHandler
+	tm.mu.Unlock()
+
+	if err == nil && handler != nil {
+		handler(result)
+	}
+	return result, err
+}
 
+func (tm *TaskManager) settleLocked(taskID string) (*SettlementResult, error) {
 	task, exists := tm.tasks[taskID]
 	if !exists {
 		return nil, ErrTaskNotFound
@@ -363,6 +519,17 @@ func (tm *TaskManager) SettleTask(taskID string) (*SettlementResult, error) {
 		return nil, ErrInvalidTransition
 	}
 
+	// 转包出去的子任务结清前不能结算
+	if tm.hasPendingSubtasks(task) {
+		return nil, ErrSubtasksPending
+	}
+
+	// 自动验收策略未通过时不释放报酬
+	record, err := tm.settlementVerification(task)
+	if err != nil {
+		return nil, err
+	}
+
 	result := &SettlementResult{
 		TaskID:        taskID,
 		RequesterID:   task.RequesterID,
@@ -370,9 +537,11 @@ func (tm *TaskManager) SettleTask(taskID string) (*SettlementResult, error) {
 		RewardAmount:  task.Reward,
 		DepositReturn: task.RequesterDeposit,
 		SettledAt:     time.Now().Unix(),
+		RewardShares:  rewardShares(task, record),
 	}
 
 	task.Status = StatusSettled
+	tm.settled++
 	tm.save()
 
 	return result, nil
@@ -426,24 +595,31 @@ func (tm *TaskManager) DisputeTask(taskID, disputerID, reason string) error {
 // CancelTask 取消任务
 func (tm *TaskManager) CancelTask(taskID, requesterID string) error {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
-
 	task, exists := tm.tasks[taskID]
 	if !exists {
+		tm.mu.Unlock()
 		return ErrTaskNotFound
 	}
 
 	if task.RequesterID != requesterID {
+		tm.mu.Unlock()
 		return errors.New("only requester can cancel")
 	}
 
 	if !task.CanTransition(StatusCancelled) {
+		tm.mu.Unlock()
 		return ErrInvalidTransition
 	}
 
 	task.Status = StatusCancelled
 	tm.save()
+	cancelled := *task
+	handler := tm.cancelHandler
+	tm.mu.Unlock()
 
+	if handler != nil {
+		handler(&cancelled)
+	}
 	return nil
 }
 
@@ -491,17 +667,8 @@ func (tm *TaskManager) GetTasksByExecutor(executorID string) []*Task {
 
 // GetTasksByStatus 获取指定状态的任务
 func (tm *TaskManager) GetTasksByStatus(status TaskStatus) []*Task {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-
-	ids := tm.tasksByStatus[status]
-	tasks := make([]*Task, 0, len(ids))
-	for _, id := range ids {
-		if task, exists := tm.tasks[id]; exists {
-			tasks = append(tasks, task)
-		}
-	}
-	return tasks
+	// 状态随生命周期变化，按当前状态筛选而不维护索引
+	return tm.ListTasks(status, 0)
 }
 
 // GetOpenTasks 获取开放的任务（可接单）
@@ -527,6 +694,15 @@ func (tm *TaskManager) RegisterCapability(cap *AgentCapability) {
 	defer tm.mu.Unlock()
 
 	cap.UpdatedAt = time.Now().Unix()
+	cap.PayloadFormats = canonicalFormats(cap.PayloadFormats)
+	if old, exists := tm.capabilities[cap.AgentID]; exists {
+		// 重复的通告（如每次心跳）只刷新时间，不改索引也不落盘
+		if sameCapability(old, cap) {
+			old.UpdatedAt = cap.UpdatedAt
+			return
+		}
+		tm.removeCapIndex(old)
+	}
 	tm.capabilities[cap.AgentID] = cap
 
 	// 更新能力索引
@@ -537,6 +713,30 @@ func (tm *TaskManager) RegisterCapability(cap *AgentCapability) {
 	tm.save()
 }
 
+// removeCapIndex 从能力索引中移除 Agent 旧的能力
+func (tm *TaskManager) removeCapIndex(cap *AgentCapability) {
+	for _, c := range cap.Capabilities {
+		agents := tm.capIndex[c][:0]
+		for _, id := range tm.capIndex[c] {
+			if id != cap.AgentID {
+				agents = append(agents, id)
+			}
+		}
+		if len(agents) == 0 {
+			delete(tm.capIndex, c)
+		} else {
+			tm.capIndex[c] = agents
+		}
+	}
+}
+
+// sameCapability 两次通告的能力是否相同（忽略更新时间）
+func sameCapability(a, b *AgentCapability) bool {
+	x, y := *a, *b
+	x.UpdatedAt, y.UpdatedAt = 0, 0
+	return reflect.DeepEqual(x, y)
+}
+
 // FindAgentsByCapability 根据能力查找Agent
 func (tm *TaskManager) FindAgentsByCapability(capabilities []string) []string {
 	tm.mu.RLock()
@@ -602,6 +802,26 @@ func (tm *TaskManager) GetStatistics() *TaskStatistics {
 	return stats
 }
 
+// Stats 实现 stats.Provider
+func (tm *TaskManager) Stats() []stats.Metric {
+	s := tm.GetStatistics()
+	tm.mu.RLock()
+	metrics := []stats.Metric{
+		stats.Counter("published_tasks", float64(tm.published), "本次启动以来发布的任务数"),
+		stats.Counter("delivered_tasks", float64(tm.delivered), "本次启动以来交付的任务数"),
+		stats.Counter("settled_tasks", float64(tm.settled), "本次启动以来结算的任务数"),
+		stats.Gauge("tasks", float64(s.TotalTasks), "任务总数"),
+	}
+	tm.mu.RUnlock()
+	for _, status := range []TaskStatus{
+		StatusPublished, StatusAccepted, StatusInProgress, StatusDelivered, StatusVerified,
+		StatusSettled, StatusCompleted, StatusDisputed, StatusCancelled, StatusExpired,
+	} {
+		metrics = append(metrics, stats.Gauge("tasks_"+string(status), float64(s.ByStatus[status]), "处于该状态的任务数"))
+	}
+	return metrics
+}
+
 // SettlementResult 结算结果
 type SettlementResult struct {
 	TaskID        string
@@ -610,6 +830,7 @@ type SettlementResult struct {
 	RewardAmount  float64
 	DepositReturn float64
 	SettledAt     int64
+	RewardShares  map[string]float64 // 执行者 -> 报酬份额（冗余执行时由结果一致的执行者平分）
 }
 
 // TaskStatistics 任务统计
@@ -655,7 +876,6 @@ func (tm *TaskManager) checkAndUpdateQuota(nodeID string, reputation float64) bo
 
 func (tm *TaskManager) addToIndex(task *Task) {
 	tm.tasksByRequester[task.RequesterID] = append(tm.tasksByRequester[task.RequesterID], task.ID)
-	tm.tasksByStatus[task.Status] = append(tm.tasksByStatus[task.Status], task.ID)
 	tm.tasksByType[task.Type] = append(tm.tasksByType[task.Type], task.ID)
 }
 
@@ -665,6 +885,16 @@ func (tm *TaskManager) addExecutorIndex(task *Task) {
 	}
 }
 
+func (tm *TaskManager) removeExecutorIndex(task *Task) {
+	ids := tm.tasksByExecutor[task.ExecutorID]
+	for i, id := range ids {
+		if id == task.ID {
+			tm.tasksByExecutor[task.ExecutorID] = append(ids[:i:i], ids[i+1:]...)
+			return
+		}
+	}
+}
+
 func (tm *TaskManager) load() {
 	filePath := filepath.Join(tm.config.DataDir, "tasks.json")
 	data, err := os.ReadFile(filePath)
@@ -673,9 +903,11 @@ func (tm *TaskManager) load() {
 	}
 
 	var stored struct {
-		Tasks        map[string]*Task            `json:"tasks"`
-		Capabilities map[string]*AgentCapability `json:"capabilities"`
-		Proofs       map[string]*DeliveryProof   `json:"proofs"`
+		Tasks         map[string]*Task               `json:"tasks"`
+		Capabilities  map[string]*AgentCapability    `json:"capabilities"`
+		Proofs        map[string]*DeliveryProof      `json:"proofs"`
+		Verifications map[string]*VerificationRecord `json:"verifications,omitempty"`
+		Reviews       map[string]*ReviewAssignment   `json:"reviews,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &stored); err != nil {
@@ -703,6 +935,14 @@ func (tm *TaskManager) load() {
 	if stored.Proofs != nil {
 		tm.deliveryProofs = stored.Proofs
 	}
+
+	if stored.Verifications != nil {
+		tm.verifications = stored.Verifications
+	}
+
+	if stored.Reviews != nil {
+		tm.reviews = stored.Reviews
+	}
 }
 
 func (tm *TaskManager) save() {
@@ -711,13 +951,17 @@ func (tm *TaskManager) save() {
 	}
 
 	stored := struct {
-		Tasks        map[string]*Task            `json:"tasks"`
-		Capabilities map[string]*AgentCapability `json:"capabilities"`
-		Proofs       map[string]*DeliveryProof   `json:"proofs"`
+		Tasks         map[string]*Task               `json:"tasks"`
+		Capabilities  map[string]*AgentCapability    `json:"capabilities"`
+		Proofs        map[string]*DeliveryProof      `json:"proofs"`
+		Verifications map[string]*VerificationRecord `json:"verifications,omitempty"`
+		Reviews       map[string]*ReviewAssignment   `json:"reviews,omitempty"`
 	}{
-		Tasks:        tm.tasks,
-		Capabilities: tm.capabilities,
-		Proofs:       tm.deliveryProofs,
+		Tasks:         tm.tasks,
+		Capabilities:  tm.capabilities,
+		Proofs:        tm.deliveryProofs,
+		Verifications: tm.verifications,
+		Reviews:       tm.reviews,
 	}
 
 	data, err := json.MarshalIndent(stored, "", "  ")
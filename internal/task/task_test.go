@@ -242,6 +242,8 @@ func TestTaskLifecycle(t *testing.T) {
 	}
 
 	// 6. Settle task
+	var settled *SettlementResult
+	tm.SetSettlementHandler(func(r *SettlementResult) { settled = r })
 	result, err := tm.SettleTask(task.ID)
 	if err != nil {
 		t.Fatalf("SettleTask failed: %v", err)
@@ -250,6 +252,9 @@ func TestTaskLifecycle(t *testing.T) {
 	if result.RewardAmount != 10.0 {
 		t.Errorf("Reward should be 10.0, got %.1f", result.RewardAmount)
 	}
+	if settled != result || settled.RewardShares["executor1"] != 10.0 {
+		t.Errorf("settlement handler got %+v", settled)
+	}
 
 	// 7. Complete task
 	err = tm.CompleteTask(task.ID)
@@ -317,6 +322,9 @@ func TestTaskCancel(t *testing.T) {
 
 	tm.PublishTask(task, 50.0)
 
+	var cancelled []string
+	tm.SetCancelHandler(func(t *Task) { cancelled = append(cancelled, t.ID) })
+
 	// Only requester can cancel
 	err := tm.CancelTask(task.ID, "other_node")
 	if err == nil {
@@ -327,6 +335,9 @@ func TestTaskCancel(t *testing.T) {
 	if err != nil {
 		t.Errorf("CancelTask failed: %v", err)
 	}
+	if len(cancelled) != 1 || cancelled[0] != task.ID {
+		t.Errorf("cancel handler calls = %v", cancelled)
+	}
 
 	updatedTask, _ := tm.GetTask(task.ID)
 	if updatedTask.Status != StatusCancelled {
@@ -370,6 +381,14 @@ func TestTaskQueries(t *testing.T) {
 	if stats.TotalTasks != 3 {
 		t.Errorf("Expected 3 total tasks, got %d", stats.TotalTasks)
 	}
+
+	metrics := map[string]float64{}
+	for _, m := range tm.Stats() {
+		metrics[m.Name] = m.Value
+	}
+	if metrics["published_tasks"] != 3 || metrics["tasks_published"] != 3 || metrics["settled_tasks"] != 0 {
+		t.Errorf("Unexpected task metrics: %v", metrics)
+	}
 }
 
 func TestCapabilityRegistry(t *testing.T) {
@@ -399,6 +418,13 @@ func TestCapabilityRegistry(t *testing.T) {
 	if len(agents) != 1 || agents[0] != "agent1" {
 		t.Errorf("Expected only agent1, got %v", agents)
 	}
+
+	// Re-announcing replaces the previous capabilities without duplicating the index
+	tm.RegisterCapability(&AgentCapability{AgentID: "agent2", Capabilities: []string{"translation", "search"}})
+	tm.RegisterCapability(&AgentCapability{AgentID: "agent1", Capabilities: []string{"coding"}})
+	if agents = tm.FindAgentsByCapability([]string{"search"}); len(agents) != 1 || agents[0] != "agent2" {
+		t.Errorf("Expected only agent2 after re-registration, got %v", agents)
+	}
 }
 
 func TestTaskFilter(t *testing.T) {
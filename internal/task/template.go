@@ -0,0 +1,458 @@
+package task
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TemplateBulletinTopic 共享任务模板使用的留言板话题
+const TemplateBulletinTopic = "task-templates"
+
+var (
+	ErrTemplateNotFound      = errors.New("task template not found")
+	ErrInvalidTemplate       = errors.New("invalid task template")
+	ErrNotTemplateOwner      = errors.New("only the owner can modify a template")
+	ErrMissingParam          = errors.New("missing required parameter")
+	ErrUnknownParam          = errors.New("unknown parameter")
+	ErrInvalidParam          = errors.New("invalid parameter value")
+	ErrInvalidSharedTemplate = errors.New("invalid shared template")
+)
+
+// VerificationMethod 验收方式
+type VerificationMethod string
+
+const (
+	VerifyManual         VerificationMethod = "manual"          // 委托方人工验收
+	VerifyHash           VerificationMethod = "hash"            // 交付物哈希匹配期望摘要
+	VerifyCommitReveal   VerificationMethod = "commit_reveal"   // 承诺-揭示协议
+	VerifyWitness        VerificationMethod = "witness"         // 第三方见证
+	VerifyRedundant      VerificationMethod = "redundant"       // N-of-M 冗余执行结果比对
+	VerifySupernodeAudit VerificationMethod = "supernode_audit" // 超级节点审计
+)
+
+// ParamType 模板参数类型
+type ParamType string
+
+const (
+	ParamString  ParamType = "string"
+	ParamNumber  ParamType = "number"
+	ParamInteger ParamType = "integer"
+	ParamBoolean ParamType = "boolean"
+)
+
+// ParamSpec 模板参数定义（载荷 schema 中的一项）
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        ParamType   `json:"type"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Enum        []string    `json:"enum,omitempty"` // 仅 string 类型
+	Min         *float64    `json:"min,omitempty"`  // 数值下限 / 字符串最短长度
+	Max         *float64    `json:"max,omitempty"`  // 数值上限 / 字符串最长长度
+	Description string      `json:"description,omitempty"`
+}
+
+// TaskTemplate 可复用的任务定义
+// 标题和描述中的 {{name}} 占位符会在创建任务时替换为参数值
+type TaskTemplate struct {
+	ID                 string             `json:"id"`
+	Name               string             `json:"name"`
+	OwnerID            string             `json:"owner_id"`
+	Type               TaskType           `json:"type"`
+	TitlePattern       string             `json:"title_pattern"`
+	DescriptionPattern string             `json:"description_pattern"`
+	Params             []ParamSpec        `json:"params"`
+	DefaultReward      float64            `json:"default_reward"`   // 默认预算
+	DefaultDeadline    int64              `json:"default_deadline"` // 默认期限（秒），0 表示不限
+	Verification       VerificationMethod `json:"verification"`
+	AcceptanceCriteria string             `json:"acceptance_criteria,omitempty"`
+	RequiredCaps       []string           `json:"required_caps,omitempty"`
+	Version            int                `json:"version"`
+	Shared             bool               `json:"shared"` // 是否来自其他节点的共享
+	CreatedAt          int64              `json:"created_at"`
+	UpdatedAt          int64              `json:"updated_at"`
+}
+
+// Validate 校验模板定义
+func (t *TaskTemplate) Validate() error {
+	if t.Name == "" || t.Type == "" || t.TitlePattern == "" {
+		return fmt.Errorf("%w: name, type and title_pattern are required", ErrInvalidTemplate)
+	}
+	if t.DefaultReward < 0 || t.DefaultDeadline < 0 {
+		return fmt.Errorf("%w: negative reward or deadline", ErrInvalidTemplate)
+	}
+	switch t.Verification {
+	case VerifyManual, VerifyHash, VerifyCommitReveal, VerifyWitness, VerifyRedundant, VerifySupernodeAudit:
+	default:
+		return fmt.Errorf("%w: unknown verification method %q", ErrInvalidTemplate, t.Verification)
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range t.Params {
+		if p.Name == "" || seen[p.Name] {
+			return fmt.Errorf("%w: empty or duplicate parameter name %q", ErrInvalidTemplate, p.Name)
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case ParamString, ParamNumber, ParamInteger, ParamBoolean:
+		default:
+			return fmt.Errorf("%w: parameter %q has unknown type %q", ErrInvalidTemplate, p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if _, err := p.coerce(p.Default); err != nil {
+				return fmt.Errorf("%w: default of %q: %v", ErrInvalidTemplate, p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateParams 按 schema 校验参数，返回补全默认值后的参数
+func (t *TaskTemplate) ValidateParams(params map[string]interface{}) (map[string]interface{}, error) {
+	specs := make(map[string]ParamSpec, len(t.Params))
+	for _, p := range t.Params {
+		specs[p.Name] = p
+	}
+	for name := range params {
+		if _, ok := specs[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownParam, name)
+		}
+	}
+
+	result := make(map[string]interface{}, len(t.Params))
+	for _, p := range t.Params {
+		v, ok := params[p.Name]
+		if !ok || v == nil {
+			if p.Default != nil {
+				v = p.Default
+			} else if p.Required {
+				return nil, fmt.Errorf("%w: %s", ErrMissingParam, p.Name)
+			} else {
+				continue
+			}
+		}
+		coerced, err := p.coerce(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidParam, p.Name, err)
+		}
+		result[p.Name] = coerced
+	}
+	return result, nil
+}
+
+// coerce 将参数值转换为声明的类型并检查约束
+func (p *ParamSpec) coerce(v interface{}) (interface{}, error) {
+	switch p.Type {
+	case ParamString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("expected string")
+		}
+		if len(p.Enum) > 0 && !containsString(p.Enum, s) {
+			return nil, fmt.Errorf("must be one of %v", p.Enum)
+		}
+		n := float64(len([]rune(s)))
+		if p.Min != nil && n < *p.Min {
+			return nil, fmt.Errorf("shorter than %v", *p.Min)
+		}
+		if p.Max != nil && n > *p.Max {
+			return nil, fmt.Errorf("longer than %v", *p.Max)
+		}
+		return s, nil
+
+	case ParamNumber, ParamInteger:
+		var f float64
+		switch n := v.(type) {
+		case float64:
+			f = n
+		case float32:
+			f = float64(n)
+		case int:
+			f = float64(n)
+		case int64:
+			f = float64(n)
+		case json.Number:
+			parsed, err := n.Float64()
+			if err != nil {
+				return nil, errors.New("expected number")
+			}
+			f = parsed
+		default:
+			return nil, errors.New("expected number")
+		}
+		if p.Type == ParamInteger && f != math.Trunc(f) {
+			return nil, errors.New("expected integer")
+		}
+		if p.Min != nil && f < *p.Min {
+			return nil, fmt.Errorf("less than %v", *p.Min)
+		}
+		if p.Max != nil && f > *p.Max {
+			return nil, fmt.Errorf("greater than %v", *p.Max)
+		}
+		if p.Type == ParamInteger {
+			return int64(f), nil
+		}
+		return f, nil
+
+	case ParamBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, errors.New("expected boolean")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", p.Type)
+}
+
+// render 替换模式中的 {{name}} 占位符
+func render(pattern string, params map[string]interface{}) string {
+	if len(params) == 0 {
+		return pattern
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, v := range params {
+		pairs = append(pairs, "{{"+name+"}}", fmt.Sprint(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(pattern)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateStore 本节点的任务模板库
+type TemplateStore struct {
+	mu        sync.RWMutex
+	nodeID    string
+	dataDir   string
+	templates map[string]*TaskTemplate // templateID -> template
+}
+
+// NewTemplateStore 创建模板库
+func NewTemplateStore(nodeID, dataDir string) *TemplateStore {
+	ts := &TemplateStore{
+		nodeID:    nodeID,
+		dataDir:   dataDir,
+		templates: make(map[string]*TaskTemplate),
+	}
+	ts.load()
+	return ts
+}
+
+// Save 新建或更新本节点的模板
+func (ts *TemplateStore) Save(t *TaskTemplate) (*TaskTemplate, error) {
+	if t == nil {
+		return nil, ErrInvalidTemplate
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := time.Now().Unix()
+	c := *t
+	c.OwnerID = ts.nodeID
+	c.Shared = false
+	c.UpdatedAt = now
+
+	if existing, ok := ts.templates[c.ID]; ok && c.ID != "" {
+		if existing.OwnerID != ts.nodeID {
+			return nil, ErrNotTemplateOwner
+		}
+		c.CreatedAt = existing.CreatedAt
+		c.Version = existing.Version + 1
+	} else {
+		if c.ID == "" {
+			c.ID = "tpl_" + generateTemplateID()
+		}
+		c.CreatedAt = now
+		c.Version = 1
+	}
+
+	ts.templates[c.ID] = &c
+	ts.save()
+
+	result := c
+	return &result, nil
+}
+
+// Import 导入其他节点共享的模板（仅保留较新版本）
+func (ts *TemplateStore) Import(t *TaskTemplate) error {
+	if t == nil || t.ID == "" || t.OwnerID == "" {
+		return ErrInvalidSharedTemplate
+	}
+	if t.OwnerID == ts.nodeID {
+		return nil
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if existing, ok := ts.templates[t.ID]; ok {
+		if existing.OwnerID != t.OwnerID || existing.Version >= t.Version {
+			return nil
+		}
+	}
+
+	c := *t
+	c.Shared = true
+	ts.templates[c.ID] = &c
+	ts.save()
+	return nil
+}
+
+// Get 获取模板
+func (ts *TemplateStore) Get(id string) (*TaskTemplate, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	t, ok := ts.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+// List 列出所有模板（按名称排序）
+func (ts *TemplateStore) List() []*TaskTemplate {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	result := make([]*TaskTemplate, 0, len(ts.templates))
+	for _, t := range ts.templates {
+		c := *t
+		result = append(result, &c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Delete 删除模板（共享模板可直接删除本地副本）
+func (ts *TemplateStore) Delete(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, ok := ts.templates[id]; !ok {
+		return ErrTemplateNotFound
+	}
+	delete(ts.templates, id)
+	ts.save()
+	return nil
+}
+
+// Instantiate 根据模板和参数创建草稿任务，reward 为 0 时使用模板默认预算
+func (ts *TemplateStore) Instantiate(templateID, requesterID string, params map[string]interface{}, reward float64) (*Task, error) {
+	tpl, err := ts.Get(templateID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := tpl.ValidateParams(params)
+	if err != nil {
+		return nil, err
+	}
+	if reward <= 0 {
+		reward = tpl.DefaultReward
+	}
+
+	task := &Task{
+		Type:               tpl.Type,
+		Title:              render(tpl.TitlePattern, values),
+		Description:        render(tpl.DescriptionPattern, values),
+		RequesterID:        requesterID,
+		Reward:             reward,
+		AcceptanceCriteria: tpl.AcceptanceCriteria,
+		Verification:       tpl.Verification,
+		RequiredCaps:       append([]string(nil), tpl.RequiredCaps...),
+		TemplateID:         tpl.ID,
+		Payload:            values,
+		Status:             StatusDraft,
+	}
+	if tpl.DefaultDeadline > 0 {
+		task.Deadline = time.Now().Unix() + tpl.DefaultDeadline
+	}
+	return task, nil
+}
+
+// MarshalShared 序列化模板用于留言板共享
+func MarshalShared(t *TaskTemplate) (string, error) {
+	c := *t
+	c.Shared = false
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseShared 解析留言板中共享的模板，author 必须与模板所有者一致
+func ParseShared(content, author string) (*TaskTemplate, error) {
+	var t TaskTemplate
+	if err := json.Unmarshal([]byte(content), &t); err != nil {
+		return nil, ErrInvalidSharedTemplate
+	}
+	if t.OwnerID == "" || t.OwnerID != author {
+		return nil, ErrInvalidSharedTemplate
+	}
+	return &t, nil
+}
+
+func generateTemplateID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (ts *TemplateStore) load() {
+	if ts.dataDir == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(ts.dataDir, "templates.json"))
+	if err != nil {
+		return
+	}
+	var stored map[string]*TaskTemplate
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return
+	}
+	ts.templates = stored
+}
+
+func (ts *TemplateStore) save() {
+	if ts.dataDir == "" {
+		return
+	}
+	if err := os.MkdirAll(ts.dataDir, 0755); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(ts.templates, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(ts.dataDir, "templates.json"), data, 0644)
+}
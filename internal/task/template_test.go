@@ -0,0 +1,192 @@
+package task
+
+import (
+	"errors"
+	"testing"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func newSearchTemplate() *TaskTemplate {
+	return &TaskTemplate{
+		Name:               "Paper search",
+		Type:               TaskTypeSearch,
+		TitlePattern:       "Search: {{query}}",
+		DescriptionPattern: "Find {{limit}} papers about {{query}} in {{lang}}",
+		Params: []ParamSpec{
+			{Name: "query", Type: ParamString, Required: true, Min: floatPtr(3)},
+			{Name: "limit", Type: ParamInteger, Default: float64(10), Min: floatPtr(1), Max: floatPtr(50)},
+			{Name: "lang", Type: ParamString, Default: "en", Enum: []string{"en", "zh"}},
+		},
+		DefaultReward:   5.0,
+		DefaultDeadline: 3600,
+		Verification:    VerifyHash,
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tpl := newSearchTemplate()
+	if err := tpl.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	bad := newSearchTemplate()
+	bad.Verification = "magic"
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate for unknown verification, got %v", err)
+	}
+
+	bad = newSearchTemplate()
+	bad.Params = append(bad.Params, ParamSpec{Name: "query", Type: ParamString})
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate for duplicate param, got %v", err)
+	}
+
+	bad = newSearchTemplate()
+	bad.Params[1].Default = "ten"
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate for bad default, got %v", err)
+	}
+}
+
+func TestTemplateValidateParams(t *testing.T) {
+	tpl := newSearchTemplate()
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr error
+	}{
+		{"valid with defaults", map[string]interface{}{"query": "LLM agents"}, nil},
+		{"missing required", map[string]interface{}{}, ErrMissingParam},
+		{"unknown param", map[string]interface{}{"query": "LLM", "extra": 1}, ErrUnknownParam},
+		{"wrong type", map[string]interface{}{"query": 42}, ErrInvalidParam},
+		{"too short", map[string]interface{}{"query": "ab"}, ErrInvalidParam},
+		{"not integer", map[string]interface{}{"query": "LLM", "limit": 2.5}, ErrInvalidParam},
+		{"out of range", map[string]interface{}{"query": "LLM", "limit": float64(100)}, ErrInvalidParam},
+		{"not in enum", map[string]interface{}{"query": "LLM", "lang": "fr"}, ErrInvalidParam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tpl.ValidateParams(tt.params)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateParams() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateStoreInstantiate(t *testing.T) {
+	ts := NewTemplateStore("node1", t.TempDir())
+
+	saved, err := ts.Save(newSearchTemplate())
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if saved.ID == "" || saved.OwnerID != "node1" || saved.Version != 1 {
+		t.Errorf("Unexpected saved template: %+v", saved)
+	}
+
+	task, err := ts.Instantiate(saved.ID, "node1", map[string]interface{}{"query": "LLM agents", "lang": "zh"}, 0)
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+	if task.Title != "Search: LLM agents" {
+		t.Errorf("Title = %q", task.Title)
+	}
+	if task.Description != "Find 10 papers about LLM agents in zh" {
+		t.Errorf("Description = %q", task.Description)
+	}
+	if task.Reward != 5.0 || task.Verification != VerifyHash || task.Status != StatusDraft {
+		t.Errorf("Unexpected task defaults: %+v", task)
+	}
+	if task.TemplateID != saved.ID || task.Payload["limit"] != int64(10) {
+		t.Errorf("Unexpected template payload: %v", task.Payload)
+	}
+	if task.Deadline == 0 {
+		t.Error("Deadline should be set from template")
+	}
+
+	// 显式预算覆盖默认值
+	task, _ = ts.Instantiate(saved.ID, "node1", map[string]interface{}{"query": "LLM"}, 8.0)
+	if task.Reward != 8.0 {
+		t.Errorf("Reward = %f, want 8.0", task.Reward)
+	}
+
+	if _, err := ts.Instantiate("missing", "node1", nil, 0); err != ErrTemplateNotFound {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+
+	// 更新版本号递增
+	saved.DefaultReward = 6.0
+	updated, _ := ts.Save(saved)
+	if updated.Version != 2 || updated.CreatedAt != saved.CreatedAt {
+		t.Errorf("Unexpected update: %+v", updated)
+	}
+}
+
+func TestTemplateSharing(t *testing.T) {
+	owner := NewTemplateStore("node1", "")
+	tpl, _ := owner.Save(newSearchTemplate())
+
+	content, err := MarshalShared(tpl)
+	if err != nil {
+		t.Fatalf("MarshalShared failed: %v", err)
+	}
+
+	// 作者与模板所有者不一致时拒绝
+	if _, err := ParseShared(content, "node3"); err != ErrInvalidSharedTemplate {
+		t.Errorf("Expected ErrInvalidSharedTemplate, got %v", err)
+	}
+
+	shared, err := ParseShared(content, "node1")
+	if err != nil {
+		t.Fatalf("ParseShared failed: %v", err)
+	}
+
+	receiver := NewTemplateStore("node2", "")
+	if err := receiver.Import(shared); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	got, err := receiver.Get(tpl.ID)
+	if err != nil || !got.Shared || got.OwnerID != "node1" {
+		t.Fatalf("Shared template not imported: %+v, %v", got, err)
+	}
+
+	// 旧版本不会覆盖新版本
+	older := *shared
+	older.Version = 0
+	older.DefaultReward = 1
+	receiver.Import(&older)
+	if got, _ := receiver.Get(tpl.ID); got.DefaultReward != 5.0 {
+		t.Error("Older version should not overwrite")
+	}
+
+	// 不能修改他人的模板
+	if _, err := receiver.Save(got); err != ErrNotTemplateOwner {
+		t.Errorf("Expected ErrNotTemplateOwner, got %v", err)
+	}
+
+	// 共享模板可直接用于创建任务
+	if _, err := receiver.Instantiate(tpl.ID, "node2", map[string]interface{}{"query": "graphs"}, 0); err != nil {
+		t.Errorf("Instantiate shared template failed: %v", err)
+	}
+}
+
+func TestTemplateStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	ts := NewTemplateStore("node1", dir)
+	tpl, _ := ts.Save(newSearchTemplate())
+
+	ts2 := NewTemplateStore("node1", dir)
+	if _, err := ts2.Get(tpl.ID); err != nil {
+		t.Errorf("Template should be restored: %v", err)
+	}
+	if err := ts2.Delete(tpl.ID); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if len(ts2.List()) != 0 {
+		t.Error("Template should be deleted")
+	}
+}
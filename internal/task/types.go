@@ -67,17 +67,32 @@ type Task struct {
 
 	// 奖励与押金
 	Reward           float64 `json:"reward"`            // 声誉奖励
+	Units            float64 `json:"units,omitempty"`   // 工作量（委托方声明的计量单位数，如页数、GB），用于计算单位报酬
 	RequesterDeposit float64 `json:"requester_deposit"` // 委托方押金
 	ExecutorDeposit  float64 `json:"executor_deposit"`  // 执行方押金
 
 	// 时间约束
 	CreatedAt int64 `json:"created_at"`
-	Deadline  int64 `json:"deadline"`   // 截止时间
-	ExpiresAt int64 `json:"expires_at"` // 任务过期时间
+	Deadline  int64 `json:"deadline"`             // 截止时间
+	ExpiresAt int64 `json:"expires_at"`           // 任务过期时间
+	OverdueAt int64 `json:"overdue_at,omitempty"` // 超过截止时间仍未交付时标记
 
 	// 验收条件
-	AcceptanceCriteria string `json:"acceptance_criteria"` // 验收标准
-	DeliverableHash    string `json:"deliverable_hash"`    // 交付物哈希（可选）
+	AcceptanceCriteria string             `json:"acceptance_criteria"`    // 验收标准
+	DeliverableHash    string             `json:"deliverable_hash"`       // 交付物哈希（可选）
+	Verification       VerificationMethod `json:"verification,omitempty"` // 验收方式
+	ExpectedHash       string             `json:"expected_hash,omitempty"`       // hash：期望的交付物摘要
+	RedundancyRequired int                `json:"redundancy_required,omitempty"` // redundant：需一致的结果数 N
+	RedundancyTotal    int                `json:"redundancy_total,omitempty"`    // redundant：执行份数 M
+	ReplicaExecutors   []string           `json:"replica_executors,omitempty"`   // redundant：主执行者之外的执行者
+
+	// 模板来源
+	TemplateID string                 `json:"template_id,omitempty"` // 创建所用模板
+	Payload    map[string]interface{} `json:"payload,omitempty"`     // 模板参数（已校验）
+
+	// 载荷格式协商
+	PayloadFormats []PayloadFormat `json:"payload_formats,omitempty"` // 委托方可提供的格式（按优先顺序），空表示不限
+	PayloadFormat  PayloadFormat   `json:"payload_format,omitempty"`  // 接单时协商出的格式
 
 	// 发布选项
 	PublishMode       PublishMode `json:"publish_mode"`
@@ -89,7 +104,8 @@ type Task struct {
 	BiddingEndsAt     int64       `json:"bidding_ends_at"`     // 竞标截止时间
 
 	// 状态
-	Status TaskStatus `json:"status"`
+	Status   TaskStatus `json:"status"`
+	Revision int64      `json:"revision,omitempty"` // 委托方节点广播副本时的版本号，其他节点只接受更大的
 
 	// 隐私保护
 	IsEncrypted   bool   `json:"is_encrypted"`    // 描述是否加密
@@ -102,6 +118,12 @@ type Task struct {
 
 	// 竞标信息
 	Bids []TaskBid `json:"bids,omitempty"`
+
+	// 转包链
+	ParentTaskID     string   `json:"parent_task_id,omitempty"`    // 上级任务（由上级执行者转包）
+	SubcontractDepth int      `json:"subcontract_depth,omitempty"` // 转包层级，原始任务为 0
+	Subtasks         []string `json:"subtasks,omitempty"`          // 转包出去的子任务
+	FailureReason    string   `json:"failure_reason,omitempty"`    // 转包失败原因
 }
 
 // TaskBid 任务竞标
@@ -115,6 +137,8 @@ type TaskBid struct {
 	Message       string   `json:"message"`        // 竞标理由
 	Signature     string   `json:"signature"`
 	BidTime       int64    `json:"bid_time"`
+
+	PayloadFormats []PayloadFormat `json:"payload_formats,omitempty"` // 支持的载荷格式，空表示使用能力注册记录
 }
 
 // TaskClaim 任务抢单
@@ -123,6 +147,8 @@ type TaskClaim struct {
 	ClaimerID string `json:"claimer_id"`
 	ClaimTime int64  `json:"claim_time"`
 	Signature string `json:"signature"`
+
+	PayloadFormats []PayloadFormat `json:"payload_formats,omitempty"` // 支持的载荷格式，空表示使用能力注册记录
 }
 
 // TaskAssignment 任务分配
@@ -181,6 +207,8 @@ type AgentCapability struct {
 	AvailableFrom int      `json:"available_from"` // 可用开始时间（小时，0-23）
 	AvailableTo   int      `json:"available_to"`   // 可用结束时间（小时，0-23）
 	UpdatedAt     int64    `json:"updated_at"`
+
+	PayloadFormats []PayloadFormat `json:"payload_formats,omitempty"` // 支持的任务载荷格式，空表示仅 JSON
 }
 
 // IsValid 检查任务是否有效（用于发布前验证）
@@ -0,0 +1,306 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SupernodeCheckFunc 判断节点当前是否为超级节点（超级节点审计策略使用）
+type SupernodeCheckFunc func(nodeID string) bool
+
+// VerificationRecord 任务验收记录
+//
+// 发布时选定的验收策略决定由谁、在什么时候判定交付是否合格：
+//   - manual（默认）/ commit_reveal / witness：委托方调用 ConfirmDelivery 确认；
+//   - hash：交付时自动比对交付物哈希与发布时给出的期望摘要；
+//   - redundant：M 个执行者各自提交结果，N 个哈希一致即通过；
+//   - supernode_audit：由非参与方的超级节点审计后给出结论。
+//
+// 自动判定的策略不接受委托方确认，判定失败时任务进入争议状态，且结算前必须判定通过。
+type VerificationRecord struct {
+	TaskID    string             `json:"task_id"`
+	Method    VerificationMethod `json:"method"`
+	Results   map[string]string  `json:"results,omitempty"` // 冗余执行：执行者 -> 结果哈希
+	Agreed    []string           `json:"agreed,omitempty"`  // 结果一致的执行者
+	Auditor   string             `json:"auditor,omitempty"`
+	Decided   bool               `json:"decided"`
+	Passed    bool               `json:"passed"`
+	Reason    string             `json:"reason,omitempty"`
+	DecidedAt int64              `json:"decided_at,omitempty"`
+}
+
+// requesterConfirms 该验收方式是否由委托方确认交付
+func (m VerificationMethod) requesterConfirms() bool {
+	switch m {
+	case VerifyHash, VerifyRedundant, VerifySupernodeAudit:
+		return false
+	}
+	return true
+}
+
+// validateVerification 发布时检查验收策略的参数
+func validateVerification(task *Task) error {
+	switch task.Verification {
+	case "", VerifyManual, VerifyCommitReveal, VerifyWitness, VerifySupernodeAudit:
+	case VerifyHash:
+		if task.ExpectedHash == "" {
+			return fmt.Errorf("%w: hash verification requires expected_hash", ErrInvalidVerification)
+		}
+	case VerifyRedundant:
+		n, m := task.RedundancyRequired, task.RedundancyTotal
+		if m < 2 || n < 1 || n > m {
+			return fmt.Errorf("%w: redundant verification requires 1 <= N <= M and M >= 2, got %d of %d", ErrInvalidVerification, n, m)
+		}
+		// N 必须过半，否则可能出现两组互相矛盾的"一致"结果
+		if n*2 <= m {
+			return fmt.Errorf("%w: redundant verification requires a majority, got %d of %d", ErrInvalidVerification, n, m)
+		}
+	default:
+		return fmt.Errorf("%w: unknown method %q", ErrInvalidVerification, task.Verification)
+	}
+	return nil
+}
+
+// SetSupernodeCheckFunc 设置超级节点判定回调，未设置时拒绝所有审计
+func (tm *TaskManager) SetSupernodeCheckFunc(fn SupernodeCheckFunc) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.supernodeFunc = fn
+}
+
+// AddReplicaExecutor 为冗余执行任务增加一名执行者
+// 主执行者接单后，其余 M-1 名执行者独立执行同一任务并提交结果哈希。
+func (tm *TaskManager) AddReplicaExecutor(taskID, executorID string, executorRep float64) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	return tm.addReplicaLocked(task, executorID, executorRep)
+}
+
+// addReplicaLocked 登记冗余执行者（调用方持有锁）
+func (tm *TaskManager) addReplicaLocked(task *Task, executorID string, executorRep float64) error {
+	if task.Verification != VerifyRedundant {
+		return fmt.Errorf("%w: task uses %s verification", ErrVerificationMethod, task.Verification)
+	}
+	if task.Status != StatusAccepted && task.Status != StatusInProgress {
+		return ErrInvalidTransition
+	}
+	if executorID == task.RequesterID || executorID == task.ExecutorID || containsString(task.ReplicaExecutors, executorID) {
+		return fmt.Errorf("%w: %s is already a participant", ErrVerificationMethod, executorID)
+	}
+	if len(task.ReplicaExecutors)+1 >= task.RedundancyTotal {
+		return fmt.Errorf("%w: %d of %d", ErrReplicasFull, len(task.ReplicaExecutors)+1, task.RedundancyTotal)
+	}
+	if task.MinReputation > 0 && executorRep < task.MinReputation {
+		return fmt.Errorf("%w: need %.1f, have %.1f", ErrInsufficientRep, task.MinReputation, executorRep)
+	}
+
+	task.ReplicaExecutors = append(task.ReplicaExecutors, executorID)
+	tm.tasksByExecutor[executorID] = append(tm.tasksByExecutor[executorID], task.ID)
+	tm.save()
+	return nil
+}
+
+// RecordAudit 超级节点对已交付任务给出审计结论
+// 通过则任务进入已验收状态，否则进入争议状态。
+func (tm *TaskManager) RecordAudit(taskID, auditorID string, approved bool, note string) (*VerificationRecord, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	if task.Verification != VerifySupernodeAudit {
+		return nil, fmt.Errorf("%w: task uses %s verification", ErrVerificationMethod, task.Verification)
+	}
+	if task.Status != StatusDelivered {
+		return nil, ErrInvalidTransition
+	}
+	if auditorID == task.RequesterID || auditorID == task.ExecutorID {
+		return nil, fmt.Errorf("%w: participants cannot audit their own task", ErrVerificationMethod)
+	}
+	if tm.supernodeFunc == nil || !tm.supernodeFunc(auditorID) {
+		return nil, ErrNotSupernode
+	}
+	// 已分配评审人的任务只接受评审人的结论
+	var reviewer *Reviewer
+	if a, ok := tm.reviews[taskID]; ok {
+		if reviewer = findReviewer(a, auditorID); reviewer == nil || !reviewer.Status.active() {
+			return nil, ErrNotReviewer
+		}
+	}
+
+	record := tm.verificationRecord(task)
+	record.Auditor = auditorID
+	reason := "audit approved"
+	if !approved {
+		reason = "audit rejected"
+	}
+	if note != "" {
+		reason += ": " + note
+	}
+	tm.decide(task, record, approved, reason)
+	if reviewer != nil {
+		reviewer.Status = ReviewerCompleted
+		reviewer.RespondedAt = time.Now().Unix()
+	}
+
+	tm.save()
+	copied := *record
+	return &copied, nil
+}
+
+// GetVerification 获取任务的验收记录
+func (tm *TaskManager) GetVerification(taskID string) (*VerificationRecord, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	task, exists := tm.tasks[taskID]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	record, exists := tm.verifications[taskID]
+	if !exists {
+		return &VerificationRecord{TaskID: taskID, Method: task.Verification}, nil
+	}
+	copied := *record
+	copied.Results = make(map[string]string, len(record.Results))
+	for k, v := range record.Results {
+		copied.Results[k] = v
+	}
+	copied.Agreed = append([]string(nil), record.Agreed...)
+	return &copied, nil
+}
+
+// submitReplicaResult 登记冗余执行者的结果（调用方持有锁）
+func (tm *TaskManager) submitReplicaResult(task *Task, executorID, deliverableHash string) error {
+	if !containsString(task.ReplicaExecutors, executorID) {
+		return ErrNotAssignedToMe
+	}
+	switch task.Status {
+	case StatusAccepted, StatusInProgress, StatusDelivered:
+	default:
+		return ErrInvalidTransition
+	}
+
+	record := tm.verificationRecord(task)
+	if _, submitted := record.Results[executorID]; submitted {
+		return fmt.Errorf("%w: %s already submitted a result", ErrVerificationMethod, executorID)
+	}
+	record.Results[executorID] = deliverableHash
+
+	// 主执行者交付后才判定
+	if task.Status == StatusDelivered {
+		tm.evaluateRedundant(task, record)
+	}
+
+	tm.save()
+	return nil
+}
+
+// applyDeliveryVerification 主执行者交付时按策略自动判定（调用方持有锁）
+func (tm *TaskManager) applyDeliveryVerification(task *Task, executorID, deliverableHash string) {
+	switch task.Verification {
+	case VerifyHash:
+		record := tm.verificationRecord(task)
+		if strings.EqualFold(deliverableHash, task.ExpectedHash) {
+			tm.decide(task, record, true, "deliverable hash matches expected digest")
+		} else {
+			tm.decide(task, record, false, fmt.Sprintf("deliverable hash %s does not match expected digest", deliverableHash))
+		}
+	case VerifyRedundant:
+		record := tm.verificationRecord(task)
+		record.Results[executorID] = deliverableHash
+		tm.evaluateRedundant(task, record)
+	}
+}
+
+// evaluateRedundant N 个结果一致即通过；M 个结果都已提交仍未达成一致则失败
+func (tm *TaskManager) evaluateRedundant(task *Task, record *VerificationRecord) {
+	if record.Decided {
+		return
+	}
+
+	groups := make(map[string][]string)
+	for executor, hash := range record.Results {
+		key := strings.ToLower(hash)
+		groups[key] = append(groups[key], executor)
+	}
+	for hash, executors := range groups {
+		if len(executors) >= task.RedundancyRequired {
+			sort.Strings(executors)
+			record.Agreed = executors
+			task.DeliverableHash = hash
+			tm.decide(task, record, true, fmt.Sprintf("%d of %d results agree", len(executors), task.RedundancyTotal))
+			return
+		}
+	}
+	if len(record.Results) >= task.RedundancyTotal {
+		tm.decide(task, record, false, fmt.Sprintf("no %d of %d results agree", task.RedundancyRequired, task.RedundancyTotal))
+	}
+}
+
+// decide 记录判定结果并推进任务状态
+func (tm *TaskManager) decide(task *Task, record *VerificationRecord, passed bool, reason string) {
+	record.Decided = true
+	record.Passed = passed
+	record.Reason = reason
+	record.DecidedAt = time.Now().Unix()
+
+	if passed {
+		task.Status = StatusVerified
+	} else {
+		task.Status = StatusDisputed
+		task.FailureReason = reason
+	}
+}
+
+func (tm *TaskManager) verificationRecord(task *Task) *VerificationRecord {
+	record, exists := tm.verifications[task.ID]
+	if !exists {
+		record = &VerificationRecord{
+			TaskID:  task.ID,
+			Method:  task.Verification,
+			Results: make(map[string]string),
+		}
+		tm.verifications[task.ID] = record
+	}
+	if record.Results == nil {
+		record.Results = make(map[string]string)
+	}
+	return record
+}
+
+// settlementVerification 结算前检查自动验收策略是否已判定通过
+func (tm *TaskManager) settlementVerification(task *Task) (*VerificationRecord, error) {
+	if task.Verification.requesterConfirms() {
+		return nil, nil
+	}
+	record, exists := tm.verifications[task.ID]
+	if !exists || !record.Decided {
+		return nil, fmt.Errorf("%w: %s verification has not decided", ErrVerificationPending, task.Verification)
+	}
+	if !record.Passed {
+		return nil, fmt.Errorf("%w: %s", ErrVerificationPending, record.Reason)
+	}
+	return record, nil
+}
+
+// rewardShares 冗余执行时报酬由结果一致的执行者平分，其余情况全部归主执行者
+func rewardShares(task *Task, record *VerificationRecord) map[string]float64 {
+	if record == nil || len(record.Agreed) == 0 {
+		return map[string]float64{task.ExecutorID: task.Reward}
+	}
+	shares := make(map[string]float64, len(record.Agreed))
+	for _, executor := range record.Agreed {
+		shares[executor] = task.Reward / float64(len(record.Agreed))
+	}
+	return shares
+}
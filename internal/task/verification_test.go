@@ -0,0 +1,184 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func createVerificationManager(t *testing.T) *TaskManager {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.MaxTasksPerHour = 20
+	return NewTaskManager(config)
+}
+
+// publishAndClaim 发布任务，由 bob 抢单并开始执行
+func publishAndClaim(t *testing.T, tm *TaskManager, task *Task) *Task {
+	task.Type = TaskTypeCompute
+	task.Title = "Compute digest"
+	task.RequesterID = "alice"
+	task.Reward = 12.0
+	task.Deadline = time.Now().Add(time.Hour).Unix()
+	if err := tm.PublishTask(task, 50.0); err != nil {
+		t.Fatalf("PublishTask failed: %v", err)
+	}
+	if err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "bob"}, 50.0); err != nil {
+		t.Fatalf("ClaimTask failed: %v", err)
+	}
+	if err := tm.StartExecution(task.ID, "bob"); err != nil {
+		t.Fatalf("StartExecution failed: %v", err)
+	}
+	return task
+}
+
+func TestValidateVerification(t *testing.T) {
+	tm := createVerificationManager(t)
+
+	cases := []*Task{
+		{Verification: "vote"},
+		{Verification: VerifyHash},
+		{Verification: VerifyRedundant, RedundancyRequired: 1, RedundancyTotal: 1},
+		{Verification: VerifyRedundant, RedundancyRequired: 2, RedundancyTotal: 4},
+	}
+	for _, task := range cases {
+		task.Type = TaskTypeCompute
+		task.Title = "t"
+		task.RequesterID = "alice"
+		if err := tm.PublishTask(task, 50.0); !errors.Is(err, ErrInvalidVerification) {
+			t.Errorf("%s %d/%d: expected ErrInvalidVerification, got %v",
+				task.Verification, task.RedundancyRequired, task.RedundancyTotal, err)
+		}
+	}
+}
+
+func TestHashVerification(t *testing.T) {
+	tm := createVerificationManager(t)
+
+	task := publishAndClaim(t, tm, &Task{Verification: VerifyHash, ExpectedHash: "ABCD"})
+	if err := tm.ConfirmDelivery(task.ID, "alice", "sig"); !errors.Is(err, ErrVerificationMethod) {
+		t.Errorf("expected ErrVerificationMethod for manual confirm, got %v", err)
+	}
+	if _, err := tm.SettleTask(task.ID); err == nil {
+		t.Error("expected settlement to fail before delivery")
+	}
+
+	if err := tm.SubmitDelivery(task.ID, "bob", "abcd", "sig"); err != nil {
+		t.Fatalf("SubmitDelivery failed: %v", err)
+	}
+	if task.Status != StatusVerified {
+		t.Fatalf("expected verified on digest match, got %s", task.Status)
+	}
+	result, err := tm.SettleTask(task.ID)
+	if err != nil {
+		t.Fatalf("SettleTask failed: %v", err)
+	}
+	if result.RewardShares["bob"] != 12.0 {
+		t.Errorf("expected full reward to bob, got %v", result.RewardShares)
+	}
+
+	t.Run("mismatch disputes and blocks settlement", func(t *testing.T) {
+		task := publishAndClaim(t, tm, &Task{Verification: VerifyHash, ExpectedHash: "abcd"})
+		tm.SubmitDelivery(task.ID, "bob", "ffff", "sig")
+		if task.Status != StatusDisputed {
+			t.Fatalf("expected disputed on mismatch, got %s", task.Status)
+		}
+		if _, err := tm.SettleTask(task.ID); !errors.Is(err, ErrVerificationPending) {
+			t.Errorf("expected ErrVerificationPending, got %v", err)
+		}
+	})
+}
+
+func TestRedundantVerification(t *testing.T) {
+	tm := createVerificationManager(t)
+
+	task := publishAndClaim(t, tm, &Task{Verification: VerifyRedundant, RedundancyRequired: 2, RedundancyTotal: 3})
+	if err := tm.AddReplicaExecutor(task.ID, "carol", 50.0); err != nil {
+		t.Fatalf("AddReplicaExecutor failed: %v", err)
+	}
+	// 主执行者之后接单的节点成为冗余执行者
+	if err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "dave"}, 50.0); err != nil {
+		t.Fatalf("ClaimTask as replica failed: %v", err)
+	}
+	if err := tm.AddReplicaExecutor(task.ID, "erin", 50.0); !errors.Is(err, ErrReplicasFull) {
+		t.Errorf("expected ErrReplicasFull, got %v", err)
+	}
+	if err := tm.ClaimTask(&TaskClaim{TaskID: task.ID, ClaimerID: "erin"}, 50.0); !errors.Is(err, ErrReplicasFull) {
+		t.Errorf("expected ErrReplicasFull for a late claim, got %v", err)
+	}
+	if err := tm.SubmitDelivery(task.ID, "erin", "h1", "sig"); !errors.Is(err, ErrNotAssignedToMe) {
+		t.Errorf("expected ErrNotAssignedToMe for outsider, got %v", err)
+	}
+
+	// 冗余结果先于主执行者交付，不提前判定
+	tm.SubmitDelivery(task.ID, "carol", "h1", "sig")
+	if task.Status != StatusInProgress {
+		t.Fatalf("expected no decision before primary delivery, got %s", task.Status)
+	}
+
+	tm.SubmitDelivery(task.ID, "bob", "h2", "sig")
+	if task.Status != StatusDelivered {
+		t.Fatalf("expected pending with 1-1 split, got %s", task.Status)
+	}
+
+	tm.SubmitDelivery(task.ID, "dave", "H1", "sig")
+	if task.Status != StatusVerified || task.DeliverableHash != "h1" {
+		t.Fatalf("expected verified on 2-of-3 agreement, got %s %s", task.Status, task.DeliverableHash)
+	}
+
+	result, err := tm.SettleTask(task.ID)
+	if err != nil {
+		t.Fatalf("SettleTask failed: %v", err)
+	}
+	if len(result.RewardShares) != 2 || result.RewardShares["carol"] != 6.0 || result.RewardShares["dave"] != 6.0 {
+		t.Errorf("expected reward split between agreeing executors, got %v", result.RewardShares)
+	}
+
+	t.Run("no agreement", func(t *testing.T) {
+		task := publishAndClaim(t, tm, &Task{Verification: VerifyRedundant, RedundancyRequired: 2, RedundancyTotal: 2})
+		tm.AddReplicaExecutor(task.ID, "carol", 50.0)
+		tm.SubmitDelivery(task.ID, "bob", "h1", "sig")
+		tm.SubmitDelivery(task.ID, "carol", "h2", "sig")
+		if task.Status != StatusDisputed {
+			t.Errorf("expected disputed without agreement, got %s", task.Status)
+		}
+	})
+}
+
+func TestSupernodeAuditVerification(t *testing.T) {
+	tm := createVerificationManager(t)
+
+	task := publishAndClaim(t, tm, &Task{Verification: VerifySupernodeAudit})
+	tm.SubmitDelivery(task.ID, "bob", "h1", "sig")
+
+	if _, err := tm.RecordAudit(task.ID, "super1", true, ""); !errors.Is(err, ErrNotSupernode) {
+		t.Errorf("expected ErrNotSupernode without check func, got %v", err)
+	}
+
+	tm.SetSupernodeCheckFunc(func(nodeID string) bool { return nodeID == "super1" || nodeID == "alice" })
+	if _, err := tm.RecordAudit(task.ID, "alice", true, ""); !errors.Is(err, ErrVerificationMethod) {
+		t.Errorf("expected requester audit to be rejected, got %v", err)
+	}
+	if _, err := tm.RecordAudit(task.ID, "mallory", true, ""); !errors.Is(err, ErrNotSupernode) {
+		t.Errorf("expected ErrNotSupernode, got %v", err)
+	}
+
+	record, err := tm.RecordAudit(task.ID, "super1", true, "output reproduced")
+	if err != nil {
+		t.Fatalf("RecordAudit failed: %v", err)
+	}
+	if !record.Passed || record.Auditor != "super1" || task.Status != StatusVerified {
+		t.Errorf("expected passed audit, got %+v (%s)", record, task.Status)
+	}
+	if _, err := tm.SettleTask(task.ID); err != nil {
+		t.Errorf("SettleTask failed: %v", err)
+	}
+
+	t.Run("persisted", func(t *testing.T) {
+		reloaded := NewTaskManager(tm.config)
+		record, err := reloaded.GetVerification(task.ID)
+		if err != nil || !record.Passed || record.Auditor != "super1" {
+			t.Errorf("expected persisted audit record, got %+v %v", record, err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+)
+
+// 激励奖励铸造
+//
+// 代币只在激励系统确认任务奖励后铸造，数量为奖励最终得分乘以 PerScore，
+// 单笔不超过 MaxPerReward，每个节点每天（UTC）累计不超过 DailyCap。
+// 同一奖励只铸造一次；超过当日上限的部分不再补发。
+// 铸造流水由确认奖励的节点签名后广播，其他节点验签后按同样的上限入账。
+
+var (
+	ErrDuplicateMint      = errors.New("reward already minted")
+	ErrRewardNotConfirmed = errors.New("reward is not confirmed")
+	ErrDailyCapReached    = errors.New("daily mint cap reached")
+)
+
+// MintRule 铸造规则，上限为 0 时不限
+type MintRule struct {
+	PerScore     float64 `json:"per_score"`      // 每单位奖励得分铸造的代币
+	MaxPerReward float64 `json:"max_per_reward"` // 单笔奖励最多铸造
+	DailyCap     float64 `json:"daily_cap"`      // 每个节点每天最多铸造
+}
+
+// DefaultMintRule 默认铸造规则
+func DefaultMintRule() MintRule {
+	return MintRule{
+		PerScore:     1,
+		MaxPerReward: 100,
+		DailyCap:     500,
+	}
+}
+
+// MintPayload 铸造流水的签名载荷
+func MintPayload(minter, to string, amount float64, rewardID string) []byte {
+	return []byte(fmt.Sprintf("token:mint:%s:%s:%.8f:%s", minter, to, amount, rewardID))
+}
+
+// MintReward 为激励奖励铸造代币，返回铸造流水
+// 配置了本节点签名函数时流水由本节点签名，可广播给其他节点入账。
+func (l *Ledger) MintReward(nodeID, rewardID string, score float64) (*Entry, error) {
+	if score <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	l.mu.Lock()
+	entry, err := l.mintLocked(nodeID, rewardID, score*l.config.Mint.PerScore, func(e *Entry) error {
+		if l.config.SignFunc == nil || l.config.NodeID == "" {
+			return nil
+		}
+		sig, err := l.config.SignFunc(MintPayload(l.config.NodeID, e.To, e.Amount, e.Reference))
+		if err != nil {
+			return fmt.Errorf("failed to sign mint: %w", err)
+		}
+		e.Minter, e.Signature = l.config.NodeID, sig
+		return nil
+	})
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if entry.Signature != "" {
+		l.notifySigned(entry)
+	}
+	return entry, nil
+}
+
+// receiveMint 记入其他节点签名的铸造流水，数量仍受本地单笔和每日上限约束
+func (l *Ledger) receiveMint(e *Entry) error {
+	if e.Minter == "" || e.Signature == "" || e.Amount <= 0 {
+		return ErrInvalidSignature
+	}
+	if verify := l.config.VerifyFunc; verify != nil && !verify(e.Minter, MintPayload(e.Minter, e.To, e.Amount, e.Reference), e.Signature) {
+		return ErrInvalidSignature
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.mintedRewards[e.Reference] {
+		return nil
+	}
+	_, err := l.mintLocked(e.To, e.Reference, e.Amount, func(recorded *Entry) error {
+		recorded.Minter, recorded.Signature = e.Minter, e.Signature
+		return nil
+	})
+	return err
+}
+
+// mintLocked 按单笔和每日上限铸造，sign 在入账前为流水补上签名（调用方持有锁）
+func (l *Ledger) mintLocked(nodeID, rewardID string, amount float64, sign func(*Entry) error) (*Entry, error) {
+	if l.mintedRewards[rewardID] {
+		return nil, ErrDuplicateMint
+	}
+
+	rule := l.config.Mint
+	if rule.MaxPerReward > 0 {
+		amount = math.Min(amount, rule.MaxPerReward)
+	}
+
+	now := l.now()
+	if day := now.UTC().Format("2006-01-02"); day != l.mintDay {
+		l.mintDay = day
+		l.dailyMinted = make(map[string]float64)
+	}
+	if rule.DailyCap > 0 {
+		remaining := rule.DailyCap - l.dailyMinted[nodeID]
+		if remaining <= 0 {
+			return nil, fmt.Errorf("%w: %s minted %.2f today", ErrDailyCapReached, nodeID, l.dailyMinted[nodeID])
+		}
+		amount = math.Min(amount, remaining)
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	e := &Entry{Type: EntryMint, To: nodeID, Amount: amount, Reference: rewardID}
+	if err := sign(e); err != nil {
+		return nil, err
+	}
+	a := l.accountLocked(nodeID)
+	a.Balance += amount
+	a.Minted += amount
+	a.UpdatedAt = now
+	l.dailyMinted[nodeID] += amount
+	l.mintedRewards[rewardID] = true
+	entry := l.appendLocked(e)
+	l.saveLocked()
+	return entry, nil
+}
+
+// MintForReward 为已确认的激励奖励铸造代币
+func (l *Ledger) MintForReward(r *incentive.TaskReward) (*Entry, error) {
+	if r.Status != incentive.RewardStatusConfirmed && r.Status != incentive.RewardStatusPropagated {
+		return nil, fmt.Errorf("%w: %s is %s", ErrRewardNotConfirmed, r.RewardID, r.Status)
+	}
+	return l.MintReward(r.NodeID, r.RewardID, r.FinalScore)
+}
+
+// RewardHook 返回可设置为 IncentiveManager.OnRewardCreated 的回调，铸造失败时只打印警告
+func (l *Ledger) RewardHook() func(*incentive.TaskReward) {
+	return func(r *incentive.TaskReward) {
+		if _, err := l.MintForReward(r); err != nil {
+			fmt.Printf("Warning: token mint for reward %s: %v\n", r.RewardID, err)
+		}
+	}
+}
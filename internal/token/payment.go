@@ -0,0 +1,193 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 任务预付报酬
+//
+// 委托方雇佣其他 Agent 时先为任务预付报酬：代币从委托方的可用余额中锁定，
+// 任务结算时按份额支付给执行者，未支付的部分解锁退回；任务取消时全额退回。
+
+var (
+	ErrPaymentNotFound = errors.New("task payment not found")
+	ErrPaymentExists   = errors.New("task payment already exists")
+	ErrPaymentSettled  = errors.New("task payment already settled")
+	ErrPaymentExceeded = errors.New("payouts exceed held amount")
+)
+
+// PaymentStatus 预付报酬状态
+type PaymentStatus string
+
+const (
+	PaymentHeld     PaymentStatus = "held"     // 已锁定
+	PaymentReleased PaymentStatus = "released" // 已支付
+	PaymentRefunded PaymentStatus = "refunded" // 已退回
+)
+
+// TaskPayment 任务预付报酬
+type TaskPayment struct {
+	TaskID    string             `json:"task_id"`
+	PayerID   string             `json:"payer_id"`
+	Amount    float64            `json:"amount"`
+	Status    PaymentStatus      `json:"status"`
+	Payouts   map[string]float64 `json:"payouts,omitempty"`
+	Refunded  float64            `json:"refunded,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+	SettledAt time.Time          `json:"settled_at,omitempty"`
+}
+
+// HoldTaskPayment 为任务预付报酬，锁定委托方的代币
+func (l *Ledger) HoldTaskPayment(taskID, payerID string, amount float64) (*TaskPayment, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.payments[taskID]; ok {
+		return nil, ErrPaymentExists
+	}
+	a := l.accountLocked(payerID)
+	if a.Available() < amount {
+		return nil, fmt.Errorf("%w: %s has %.2f available, needs %.2f", ErrInsufficientBalance, payerID, a.Available(), amount)
+	}
+
+	now := l.now()
+	a.Locked += amount
+	a.UpdatedAt = now
+	p := &TaskPayment{TaskID: taskID, PayerID: payerID, Amount: amount, Status: PaymentHeld, CreatedAt: now}
+	l.payments[taskID] = p
+	l.appendLocked(&Entry{Type: EntryLock, From: payerID, Amount: amount, Reference: taskID})
+	l.saveLocked()
+	return copyPayment(p), nil
+}
+
+// ReleaseTaskPayment 按份额把预付报酬支付给执行者，剩余部分退回委托方
+// 委托方是本节点且配置了签名函数时，每笔支付都是本节点签名的转账，可广播给其他节点入账。
+func (l *Ledger) ReleaseTaskPayment(taskID string, payouts map[string]float64) (*TaskPayment, error) {
+	l.mu.Lock()
+	p, entries, err := l.releaseLocked(taskID, payouts)
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	l.notifySigned(entries...)
+	return p, nil
+}
+
+func (l *Ledger) releaseLocked(taskID string, payouts map[string]float64) (*TaskPayment, []*Entry, error) {
+	p, err := l.heldPaymentLocked(taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var total float64
+	payees := make([]string, 0, len(payouts))
+	for to, amount := range payouts {
+		if amount <= 0 || to == p.PayerID {
+			return nil, nil, fmt.Errorf("%w: invalid payout to %s", ErrInvalidAmount, to)
+		}
+		total += amount
+		payees = append(payees, to)
+	}
+	if total > p.Amount+1e-9 {
+		return nil, nil, fmt.Errorf("%w: %.2f > %.2f", ErrPaymentExceeded, total, p.Amount)
+	}
+
+	// 先按收款方顺序分配序号并签名，签名失败时不改动账本
+	sort.Strings(payees)
+	src := l.accountLocked(p.PayerID)
+	signatures := make(map[string]string, len(payees))
+	if sign := l.config.SignFunc; sign != nil && p.PayerID == l.config.NodeID {
+		for i, to := range payees {
+			sig, err := sign(TransferPayload(p.PayerID, to, payouts[to], src.Nonce+uint64(i)+1, taskID))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to sign payout: %w", err)
+			}
+			signatures[to] = sig
+		}
+	}
+
+	l.unlockPaymentLocked(p)
+	var signed []*Entry
+	for _, to := range payees {
+		var nonce uint64
+		sig := signatures[to]
+		if sig != "" {
+			nonce = src.Nonce + 1
+		}
+		// 锁定部分已解锁，可用余额足够支付
+		entry, err := l.transferLocked(p.PayerID, to, payouts[to], taskID, nonce, sig)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sig != "" {
+			src.Nonce = nonce
+			signed = append(signed, entry)
+		}
+	}
+	p.Status = PaymentReleased
+	p.Payouts = payouts
+	p.Refunded = p.Amount - total
+	p.SettledAt = l.now()
+	l.saveLocked()
+	return copyPayment(p), signed, nil
+}
+
+// RefundTaskPayment 任务取消时退回全部预付报酬
+func (l *Ledger) RefundTaskPayment(taskID string) (*TaskPayment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, err := l.heldPaymentLocked(taskID)
+	if err != nil {
+		return nil, err
+	}
+	l.unlockPaymentLocked(p)
+	p.Status = PaymentRefunded
+	p.Refunded = p.Amount
+	p.SettledAt = l.now()
+	l.saveLocked()
+	return copyPayment(p), nil
+}
+
+// GetTaskPayment 查询任务预付报酬
+func (l *Ledger) GetTaskPayment(taskID string) (*TaskPayment, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	p, ok := l.payments[taskID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	return copyPayment(p), nil
+}
+
+func (l *Ledger) heldPaymentLocked(taskID string) (*TaskPayment, error) {
+	p, ok := l.payments[taskID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	if p.Status != PaymentHeld {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentSettled, p.Status)
+	}
+	return p, nil
+}
+
+func (l *Ledger) unlockPaymentLocked(p *TaskPayment) {
+	a := l.accountLocked(p.PayerID)
+	a.Locked -= p.Amount
+	a.UpdatedAt = l.now()
+	l.appendLocked(&Entry{Type: EntryUnlock, To: p.PayerID, Amount: p.Amount, Reference: p.TaskID})
+}
+
+func copyPayment(p *TaskPayment) *TaskPayment {
+	copied := *p
+	if p.Payouts != nil {
+		copied.Payouts = make(map[string]float64, len(p.Payouts))
+		for k, v := range p.Payouts {
+			copied.Payouts[k] = v
+		}
+	}
+	return &copied
+}
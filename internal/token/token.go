@@ -0,0 +1,357 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// $DAAN 代币账本
+//
+// 代币是节点贡献的内部记账单位，不可兑换、不具有经济价值。余额只能通过激励奖励铸造产生，
+// 之后在节点间签名转账、为雇佣其他 Agent 的任务预付报酬，或被押金托管和抵押锁定。
+// 每个账户的余额包含锁定部分，锁定部分不能转出；所有变动都记入流水。
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInsufficientLocked  = errors.New("insufficient locked tokens")
+	ErrSelfTransfer        = errors.New("cannot transfer to self")
+	ErrInvalidSignature    = errors.New("invalid transfer signature")
+	ErrInvalidNonce        = errors.New("invalid transfer nonce")
+)
+
+// EntryType 流水类型
+type EntryType string
+
+const (
+	EntryMint     EntryType = "mint"     // 激励奖励铸造
+	EntryTransfer EntryType = "transfer" // 转账
+	EntryLock     EntryType = "lock"     // 锁定（押金、抵押）
+	EntryUnlock   EntryType = "unlock"   // 解锁
+	EntrySlash    EntryType = "slash"    // 从锁定部分销毁
+)
+
+// Account 代币账户
+type Account struct {
+	NodeID    string    `json:"node_id"`
+	Balance   float64   `json:"balance"` // 余额（含锁定部分）
+	Locked    float64   `json:"locked"`
+	Nonce     uint64    `json:"nonce"` // 已使用的最大签名转账序号
+	Minted    float64   `json:"minted"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Available 可转出的余额
+func (a *Account) Available() float64 {
+	return a.Balance - a.Locked
+}
+
+// Entry 一条流水
+type Entry struct {
+	ID        string    `json:"id"`
+	Type      EntryType `json:"type"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Amount    float64   `json:"amount"`
+	Reference string    `json:"reference,omitempty"` // 奖励ID、任务ID、转账备注等
+	Nonce     uint64    `json:"nonce,omitempty"`
+	Minter    string    `json:"minter,omitempty"` // 铸造流水的签名节点
+	Signature string    `json:"signature,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Config 账本配置
+type Config struct {
+	DataDir string   // 为空时不持久化
+	Mint    MintRule // 激励奖励铸造规则
+
+	// VerifyFunc 按签名者节点ID验证签名转账和铸造
+	VerifyFunc func(signerID string, data []byte, signature string) bool
+
+	// 本节点身份：设置 SignFunc 时，本节点铸造的奖励和支付的任务报酬带本节点签名，
+	// 与签名转账一样可以广播给其他节点入账
+	NodeID   string
+	SignFunc func(data []byte) (string, error)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		DataDir: "data/token",
+		Mint:    DefaultMintRule(),
+	}
+}
+
+// Ledger 代币账本
+type Ledger struct {
+	mu       sync.RWMutex
+	config   *Config
+	accounts map[string]*Account
+	entries  []*Entry
+	seq      uint64
+
+	// 铸造记录：已铸造的奖励ID，以及节点当天已铸造的数量
+	mintedRewards map[string]bool
+	dailyMinted   map[string]float64
+	mintDay       string
+
+	payments map[string]*TaskPayment // taskID -> 任务预付报酬
+
+	onSigned func(*Entry) // 本节点新记入签名流水后的回调（用于广播）
+
+	version uint64
+	now     func() time.Time
+}
+
+// NewLedger 创建代币账本并加载已保存的数据
+func NewLedger(config *Config) (*Ledger, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	l := &Ledger{
+		config:        config,
+		accounts:      make(map[string]*Account),
+		mintedRewards: make(map[string]bool),
+		dailyMinted:   make(map[string]float64),
+		payments:      make(map[string]*TaskPayment),
+		now:           time.Now,
+	}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetAccount 查询账户，不存在的账户余额为 0
+func (l *Ledger) GetAccount(nodeID string) *Account {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if a, ok := l.accounts[nodeID]; ok {
+		copied := *a
+		return &copied
+	}
+	return &Account{NodeID: nodeID}
+}
+
+// History 查询节点相关的流水，最新的在前；nodeID 为空时返回全部，limit <= 0 时不限
+func (l *Ledger) History(nodeID string, limit int) []*Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var result []*Entry
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		e := l.entries[i]
+		if nodeID != "" && e.From != nodeID && e.To != nodeID {
+			continue
+		}
+		copied := *e
+		result = append(result, &copied)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// TotalSupply 已铸造且未销毁的代币总量
+func (l *Ledger) TotalSupply() float64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var total float64
+	for _, a := range l.accounts {
+		total += a.Balance
+	}
+	return total
+}
+
+// Version 返回账本的版本号，每次写入后递增
+func (l *Ledger) Version() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version
+}
+
+// Transfer 不经签名的转账，供押金托管按已验签的结算方案支付
+// 只能转出可用余额。
+func (l *Ledger) Transfer(fromNodeID, toNodeID string, amount float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.transferLocked(fromNodeID, toNodeID, amount, "", 0, ""); err != nil {
+		return err
+	}
+	l.saveLocked()
+	return nil
+}
+
+// LockTokens 锁定节点的可用余额
+func (l *Ledger) LockTokens(nodeID string, amount float64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.accountLocked(nodeID)
+	if a.Available() < amount {
+		return fmt.Errorf("%w: %s has %.2f available, needs %.2f", ErrInsufficientBalance, nodeID, a.Available(), amount)
+	}
+	a.Locked += amount
+	a.UpdatedAt = l.now()
+	l.appendLocked(&Entry{Type: EntryLock, From: nodeID, Amount: amount})
+	l.saveLocked()
+	return nil
+}
+
+// UnlockTokens 解锁节点的锁定余额
+func (l *Ledger) UnlockTokens(nodeID string, amount float64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.accountLocked(nodeID)
+	if a.Locked < amount {
+		return fmt.Errorf("%w: %s has %.2f locked, needs %.2f", ErrInsufficientLocked, nodeID, a.Locked, amount)
+	}
+	a.Locked -= amount
+	a.UpdatedAt = l.now()
+	l.appendLocked(&Entry{Type: EntryUnlock, To: nodeID, Amount: amount})
+	l.saveLocked()
+	return nil
+}
+
+// SlashTokens 从锁定余额中销毁，供抵押罚没使用
+func (l *Ledger) SlashTokens(nodeID string, amount float64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a := l.accountLocked(nodeID)
+	if a.Locked < amount {
+		return fmt.Errorf("%w: %s has %.2f locked, needs %.2f", ErrInsufficientLocked, nodeID, a.Locked, amount)
+	}
+	a.Locked -= amount
+	a.Balance -= amount
+	a.UpdatedAt = l.now()
+	l.appendLocked(&Entry{Type: EntrySlash, From: nodeID, Amount: amount})
+	l.saveLocked()
+	return nil
+}
+
+// transferLocked 从可用余额转账并记流水（调用方持有锁）
+func (l *Ledger) transferLocked(from, to string, amount float64, reference string, nonce uint64, signature string) (*Entry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if from == to {
+		return nil, ErrSelfTransfer
+	}
+	src := l.accountLocked(from)
+	if src.Available() < amount {
+		return nil, fmt.Errorf("%w: %s has %.2f available, needs %.2f", ErrInsufficientBalance, from, src.Available(), amount)
+	}
+	dst := l.accountLocked(to)
+	now := l.now()
+	src.Balance -= amount
+	src.UpdatedAt = now
+	dst.Balance += amount
+	dst.UpdatedAt = now
+	return l.appendLocked(&Entry{
+		Type:      EntryTransfer,
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Reference: reference,
+		Nonce:     nonce,
+		Signature: signature,
+	}), nil
+}
+
+func (l *Ledger) accountLocked(nodeID string) *Account {
+	a, ok := l.accounts[nodeID]
+	if !ok {
+		a = &Account{NodeID: nodeID, UpdatedAt: l.now()}
+		l.accounts[nodeID] = a
+	}
+	return a
+}
+
+func (l *Ledger) appendLocked(e *Entry) *Entry {
+	l.seq++
+	e.ID = fmt.Sprintf("tx_%d", l.seq)
+	e.Timestamp = l.now()
+	l.entries = append(l.entries, e)
+	copied := *e
+	return &copied
+}
+
+type ledgerData struct {
+	Seq           uint64                  `json:"seq"`
+	Accounts      map[string]*Account     `json:"accounts"`
+	Entries       []*Entry                `json:"entries"`
+	MintedRewards map[string]bool         `json:"minted_rewards"`
+	DailyMinted   map[string]float64      `json:"daily_minted"`
+	MintDay       string                  `json:"mint_day"`
+	Payments      map[string]*TaskPayment `json:"payments"`
+}
+
+// saveLocked 保存账本并递增版本号（调用方持有锁）
+func (l *Ledger) saveLocked() {
+	l.version++
+	if l.config.DataDir == "" {
+		return
+	}
+	if err := os.MkdirAll(l.config.DataDir, 0755); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(&ledgerData{
+		Seq:           l.seq,
+		Accounts:      l.accounts,
+		Entries:       l.entries,
+		MintedRewards: l.mintedRewards,
+		DailyMinted:   l.dailyMinted,
+		MintDay:       l.mintDay,
+		Payments:      l.payments,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(l.config.DataDir, "token.json"), data, 0644)
+}
+
+func (l *Ledger) load() error {
+	if l.config.DataDir == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(filepath.Join(l.config.DataDir, "token.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var data ledgerData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("failed to parse token ledger: %w", err)
+	}
+	l.seq = data.Seq
+	l.entries = data.Entries
+	l.mintDay = data.MintDay
+	if data.Accounts != nil {
+		l.accounts = data.Accounts
+	}
+	if data.MintedRewards != nil {
+		l.mintedRewards = data.MintedRewards
+	}
+	if data.DailyMinted != nil {
+		l.dailyMinted = data.DailyMinted
+	}
+	if data.Payments != nil {
+		l.payments = data.Payments
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package token
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/incentive"
+)
+
+var (
+	_ escrow.Funds     = (*Ledger)(nil)
+	_ collateral.Funds = (*Ledger)(nil)
+)
+
+func newTestLedger(t *testing.T) *Ledger {
+	config := DefaultConfig()
+	config.DataDir = t.TempDir()
+	config.VerifyFunc = func(signer string, data []byte, signature string) bool {
+		return signature == "sig:"+signer+":"+string(data)
+	}
+	l, err := NewLedger(config)
+	if err != nil {
+		t.Fatalf("NewLedger() error = %v", err)
+	}
+	return l
+}
+
+func sign(signer string, data []byte) string {
+	return "sig:" + signer + ":" + string(data)
+}
+
+func TestMintReward(t *testing.T) {
+	l := newTestLedger(t)
+	day := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return day }
+
+	e, err := l.MintReward("nodeA", "r1", 150)
+	if err != nil || e.Amount != 100 {
+		t.Fatalf("MintReward() = %+v, %v; want capped at 100", e, err)
+	}
+	if _, err := l.MintReward("nodeA", "r1", 10); !errors.Is(err, ErrDuplicateMint) {
+		t.Errorf("expected ErrDuplicateMint, got %v", err)
+	}
+	for i, id := range []string{"r2", "r3", "r4", "r5"} {
+		if _, err := l.MintReward("nodeA", id, 100); err != nil {
+			t.Fatalf("mint %d: %v", i, err)
+		}
+	}
+	if _, err := l.MintReward("nodeA", "r6", 10); !errors.Is(err, ErrDailyCapReached) {
+		t.Errorf("expected ErrDailyCapReached, got %v", err)
+	}
+	day = day.Add(24 * time.Hour)
+	if _, err := l.MintReward("nodeA", "r6", 10); err != nil {
+		t.Errorf("mint on the next day: %v", err)
+	}
+
+	pending := &incentive.TaskReward{RewardID: "r7", NodeID: "nodeA", FinalScore: 5, Status: incentive.RewardStatusPending}
+	if _, err := l.MintForReward(pending); !errors.Is(err, ErrRewardNotConfirmed) {
+		t.Errorf("expected ErrRewardNotConfirmed, got %v", err)
+	}
+	if a := l.GetAccount("nodeA"); a.Balance != 510 || a.Minted != 510 || l.TotalSupply() != 510 {
+		t.Errorf("account = %+v", a)
+	}
+}
+
+func TestSignedTransfer(t *testing.T) {
+	l := newTestLedger(t)
+	l.MintReward("nodeA", "r1", 50)
+
+	payload := TransferPayload("nodeA", "nodeB", 20, 1, "hire")
+	if _, err := l.SignedTransfer("nodeA", "nodeB", 20, 1, "hire", sign("nodeB", payload)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+	if _, err := l.SignedTransfer("nodeA", "nodeB", 20, 2, "hire", sign("nodeA", TransferPayload("nodeA", "nodeB", 20, 2, "hire"))); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("expected ErrInvalidNonce, got %v", err)
+	}
+	e, err := l.SignedTransfer("nodeA", "nodeB", 20, 1, "hire", sign("nodeA", payload))
+	if err != nil || e.Type != EntryTransfer || e.Reference != "hire" {
+		t.Fatalf("SignedTransfer() = %+v, %v", e, err)
+	}
+	if _, err := l.SignedTransfer("nodeA", "nodeB", 20, 1, "hire", sign("nodeA", payload)); !errors.Is(err, ErrInvalidNonce) {
+		t.Errorf("replay: expected ErrInvalidNonce, got %v", err)
+	}
+	if l.NextNonce("nodeA") != 2 {
+		t.Errorf("NextNonce() = %d, want 2", l.NextNonce("nodeA"))
+	}
+
+	// 锁定部分不能转出
+	if err := l.LockTokens("nodeA", 25); err != nil {
+		t.Fatalf("LockTokens() error = %v", err)
+	}
+	big := TransferPayload("nodeA", "nodeB", 10, 2, "")
+	if _, err := l.SignedTransfer("nodeA", "nodeB", 10, 2, "", sign("nodeA", big)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("expected ErrInsufficientBalance, got %v", err)
+	}
+	if err := l.SlashTokens("nodeA", 5); err != nil {
+		t.Fatalf("SlashTokens() error = %v", err)
+	}
+	if a := l.GetAccount("nodeA"); a.Balance != 25 || a.Locked != 20 {
+		t.Errorf("nodeA = %+v", a)
+	}
+	if got := l.History("nodeB", 0); len(got) != 1 || got[0].From != "nodeA" {
+		t.Errorf("History(nodeB) = %+v", got)
+	}
+	if got := l.History("nodeA", 2); len(got) != 2 || got[0].Type != EntrySlash {
+		t.Errorf("History(nodeA, 2) = %+v", got)
+	}
+
+	e, err = l.SignAndTransfer("nodeA", "nodeB", 5, "", func(data []byte) (string, error) { return sign("nodeA", data), nil })
+	if err != nil || e.Nonce != 2 {
+		t.Fatalf("SignAndTransfer() = %+v, %v", e, err)
+	}
+}
+
+func TestTaskPayment(t *testing.T) {
+	l := newTestLedger(t)
+	l.MintReward("client", "r1", 100)
+
+	if _, err := l.HoldTaskPayment("task1", "client", 200); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("expected ErrInsufficientBalance, got %v", err)
+	}
+	if _, err := l.HoldTaskPayment("task1", "client", 60); err != nil {
+		t.Fatalf("HoldTaskPayment() error = %v", err)
+	}
+	if _, err := l.HoldTaskPayment("task1", "client", 10); !errors.Is(err, ErrPaymentExists) {
+		t.Errorf("expected ErrPaymentExists, got %v", err)
+	}
+	if _, err := l.ReleaseTaskPayment("task1", map[string]float64{"worker": 70}); !errors.Is(err, ErrPaymentExceeded) {
+		t.Errorf("expected ErrPaymentExceeded, got %v", err)
+	}
+	p, err := l.ReleaseTaskPayment("task1", map[string]float64{"worker": 30, "reviewer": 20})
+	if err != nil || p.Status != PaymentReleased || p.Refunded != 10 {
+		t.Fatalf("ReleaseTaskPayment() = %+v, %v", p, err)
+	}
+	if _, err := l.RefundTaskPayment("task1"); !errors.Is(err, ErrPaymentSettled) {
+		t.Errorf("expected ErrPaymentSettled, got %v", err)
+	}
+	if c, w := l.GetAccount("client"), l.GetAccount("worker"); c.Balance != 50 || c.Locked != 0 || w.Balance != 30 {
+		t.Errorf("client = %+v, worker = %+v", c, w)
+	}
+
+	l.HoldTaskPayment("task2", "client", 40)
+	if p, err := l.RefundTaskPayment("task2"); err != nil || p.Refunded != 40 {
+		t.Errorf("RefundTaskPayment() = %+v, %v", p, err)
+	}
+	if _, err := l.GetTaskPayment("missing"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("expected ErrPaymentNotFound, got %v", err)
+	}
+}
+
+func TestSignedEntryPropagation(t *testing.T) {
+	client := newTestLedger(t)
+	client.config.NodeID = "client"
+	client.config.SignFunc = func(data []byte) (string, error) { return sign("client", data), nil }
+	var published []*Entry
+	client.SetOnSigned(func(e *Entry) { published = append(published, e) })
+	worker := newTestLedger(t)
+
+	client.MintReward("client", "r1", 50)
+	client.HoldTaskPayment("task1", "client", 40)
+	if _, err := client.ReleaseTaskPayment("task1", map[string]float64{"worker": 25, "reviewer": 5}); err != nil {
+		t.Fatalf("ReleaseTaskPayment() error = %v", err)
+	}
+	if len(published) != 3 || published[0].Minter != "client" || published[1].To != "reviewer" || published[2].Nonce != 2 {
+		t.Fatalf("published = %+v", published)
+	}
+
+	// 另一个节点按相同顺序入账，重复收到的流水忽略
+	for _, e := range append(published, published...) {
+		if err := worker.ReceiveEntry(e); err != nil {
+			t.Fatalf("ReceiveEntry(%s) error = %v", e.ID, err)
+		}
+	}
+	if a := worker.GetAccount("worker"); a.Balance != 25 {
+		t.Errorf("worker balance = %v", a.Balance)
+	}
+	if a := worker.GetAccount("client"); a.Balance != 20 || a.Nonce != 2 {
+		t.Errorf("client account on worker = %+v", a)
+	}
+	if report := worker.Verify(); !report.OK() {
+		t.Errorf("Verify() issues = %+v", report.Issues)
+	}
+
+	forged := *published[0]
+	forged.Reference, forged.Amount = "r2", 100
+	if err := worker.ReceiveEntry(&forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged mint error = %v", err)
+	}
+	unsigned := &Entry{Type: EntryMint, To: "worker", Amount: 10, Reference: "r3"}
+	if err := worker.ReceiveEntry(unsigned); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("unsigned mint error = %v", err)
+	}
+}
+
+func TestLedgerPersistence(t *testing.T) {
+	l := newTestLedger(t)
+	l.MintReward("nodeA", "r1", 50)
+	l.HoldTaskPayment("task1", "nodeA", 10)
+
+	reloaded, err := NewLedger(l.config)
+	if err != nil {
+		t.Fatalf("NewLedger() error = %v", err)
+	}
+	if a := reloaded.GetAccount("nodeA"); a.Balance != 50 || a.Locked != 10 {
+		t.Errorf("reloaded account = %+v", a)
+	}
+	if _, err := reloaded.MintReward("nodeA", "r1", 50); !errors.Is(err, ErrDuplicateMint) {
+		t.Errorf("expected ErrDuplicateMint after reload, got %v", err)
+	}
+	if e, _ := reloaded.MintReward("nodeA", "r2", 1); e.ID != "tx_3" {
+		t.Errorf("entry ID after reload = %s, want tx_3", e.ID)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	l := newTestLedger(t)
+	l.MintReward("client", "r1", 100)
+	l.SignAndTransfer("client", "worker", 10, "tip", func(data []byte) (string, error) { return sign("client", data), nil })
+	l.HoldTaskPayment("task-1", "client", 40)
+	l.ReleaseTaskPayment("task-1", map[string]float64{"worker": 30})
+	l.LockTokens("worker", 20)
+	l.SlashTokens("worker", 5)
+
+	r := l.Verify()
+	if !r.OK() || r.Entries != 7 || r.SignedTransfers != 1 || r.Supply != 95 || !r.SignaturesChecked {
+		t.Fatalf("Verify() = %+v", r)
+	}
+
+	// 账户表被改写、签名转账被篡改
+	l.mu.Lock()
+	l.accounts["worker"].Balance = 1000
+	l.entries[1].Amount = 15
+	l.mu.Unlock()
+	r = l.Verify()
+	kinds := map[string]int{}
+	for _, issue := range r.Issues {
+		kinds[issue.Kind]++
+	}
+	if kinds[IssueSignature] != 1 || kinds[IssueAccount] != 2 || r.OK() {
+		t.Errorf("issues = %+v", r.Issues)
+	}
+}
@@ -0,0 +1,113 @@
+package token
+
+import "fmt"
+
+// 签名转账
+//
+// 节点间转账必须由转出方对载荷签名，载荷包含收款方、金额和序号。
+// 序号必须恰好是转出方上一次签名转账的序号加一，同一签名不能重放。
+
+// TransferPayload 签名转账的载荷
+func TransferPayload(from, to string, amount float64, nonce uint64, memo string) []byte {
+	return []byte(fmt.Sprintf("token:transfer:%s:%s:%.8f:%d:%s", from, to, amount, nonce, memo))
+}
+
+// NextNonce 节点下一次签名转账应使用的序号
+func (l *Ledger) NextNonce(nodeID string) uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if a, ok := l.accounts[nodeID]; ok {
+		return a.Nonce + 1
+	}
+	return 1
+}
+
+// SignedTransfer 执行转出方签名的转账
+// 未配置 VerifyFunc 时只检查签名非空。
+func (l *Ledger) SignedTransfer(from, to string, amount float64, nonce uint64, memo, signature string) (*Entry, error) {
+	entry, err := l.signedTransfer(from, to, amount, nonce, memo, signature)
+	if err != nil {
+		return nil, err
+	}
+	l.notifySigned(entry)
+	return entry, nil
+}
+
+func (l *Ledger) signedTransfer(from, to string, amount float64, nonce uint64, memo, signature string) (*Entry, error) {
+	if signature == "" {
+		return nil, ErrInvalidSignature
+	}
+	if verify := l.config.VerifyFunc; verify != nil && !verify(from, TransferPayload(from, to, amount, nonce, memo), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	src := l.accountLocked(from)
+	if nonce != src.Nonce+1 {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrInvalidNonce, src.Nonce+1, nonce)
+	}
+	entry, err := l.transferLocked(from, to, amount, memo, nonce, signature)
+	if err != nil {
+		return nil, err
+	}
+	src.Nonce = nonce
+	l.saveLocked()
+	return entry, nil
+}
+
+// SignAndTransfer 用本节点的签名函数对下一个序号签名并转账
+// 并发调用可能因序号冲突返回 ErrInvalidNonce，调用方可重试。
+func (l *Ledger) SignAndTransfer(from, to string, amount float64, memo string, sign func([]byte) (string, error)) (*Entry, error) {
+	nonce := l.NextNonce(from)
+	signature, err := sign(TransferPayload(from, to, amount, nonce, memo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transfer: %w", err)
+	}
+	return l.SignedTransfer(from, to, amount, nonce, memo, signature)
+}
+
+// SetOnSigned 设置本节点新记入签名流水（签名转账、本节点签名的铸造和任务报酬支付）后的回调，
+// 在账本锁外按记账顺序同步调用。收到的其他节点的流水不触发回调。
+func (l *Ledger) SetOnSigned(fn func(*Entry)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onSigned = fn
+}
+
+func (l *Ledger) notifySigned(entries ...*Entry) {
+	l.mu.RLock()
+	fn := l.onSigned
+	l.mu.RUnlock()
+	if fn == nil {
+		return
+	}
+	for _, e := range entries {
+		fn(e)
+	}
+}
+
+// ReceiveEntry 记入其他节点广播的签名流水：转账按转出方签名和序号入账，
+// 铸造按铸造方签名和本地铸造规则入账。已入账的流水（已用的序号、已铸造的奖励）忽略。
+func (l *Ledger) ReceiveEntry(e *Entry) error {
+	if e == nil {
+		return ErrInvalidAmount
+	}
+	switch e.Type {
+	case EntryTransfer:
+		l.mu.RLock()
+		var used bool
+		if a, ok := l.accounts[e.From]; ok {
+			used = e.Nonce <= a.Nonce
+		}
+		l.mu.RUnlock()
+		if used {
+			return nil
+		}
+		_, err := l.signedTransfer(e.From, e.To, e.Amount, e.Nonce, e.Reference, e.Signature)
+		return err
+	case EntryMint:
+		return l.receiveMint(e)
+	}
+	return fmt.Errorf("unsupported entry type %q", e.Type)
+}
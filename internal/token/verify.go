@@ -0,0 +1,179 @@
+package token
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 流水重放校验
+//
+// 从第一条流水开始重放铸造、转账、锁定、解锁和销毁，重新验证签名转账的签名和序号以及签名铸造的签名，
+// 再把重放得到的余额、锁定、铸造总量和序号与账户表逐一比较。
+// 账户表被直接改写或流水丢失时，两者就会出现偏差。
+
+// 校验发现的问题类型
+const (
+	IssueSequence  = "sequence"  // 流水编号不连续
+	IssueEntry     = "entry"     // 流水本身无效：金额非正、类型未知或转给自己
+	IssueSignature = "signature" // 签名转账或签名铸造验签失败
+	IssueNonce     = "nonce"     // 签名转账序号不是上一次加一
+	IssueOverdraft = "overdraft" // 重放到该条流水时可用或锁定余额不足
+	IssueAccount   = "account"   // 重放得到的账户与账户表不一致
+)
+
+// verifyTolerance 比较金额时允许的浮点误差
+const verifyTolerance = 1e-6
+
+// Issue 校验发现的一个问题
+type Issue struct {
+	Kind    string `json:"kind"`
+	EntryID string `json:"entry_id,omitempty"`
+	NodeID  string `json:"node_id,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+// VerifyReport 流水重放校验结果
+type VerifyReport struct {
+	Entries           int     `json:"entries"`
+	Accounts          int     `json:"accounts"`
+	SignedTransfers   int     `json:"signed_transfers"`
+	SignaturesChecked bool    `json:"signatures_checked"` // 未配置 VerifyFunc 时不验签
+	Supply            float64 `json:"supply"`             // 重放得到的流通总量
+	Issues            []Issue `json:"issues"`
+}
+
+// OK 是否未发现问题
+func (r *VerifyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Verify 重放全部流水并与账户表比较
+func (l *Ledger) Verify() *VerifyReport {
+	l.mu.RLock()
+	entries := make([]Entry, len(l.entries))
+	for i, e := range l.entries {
+		entries[i] = *e
+	}
+	stored := make(map[string]Account, len(l.accounts))
+	for id, a := range l.accounts {
+		stored[id] = *a
+	}
+	l.mu.RUnlock()
+
+	verify := l.config.VerifyFunc
+	report := &VerifyReport{
+		Entries:           len(entries),
+		Accounts:          len(stored),
+		SignaturesChecked: verify != nil,
+		Issues:            []Issue{},
+	}
+	replayed := make(map[string]*Account)
+	account := func(nodeID string) *Account {
+		a, ok := replayed[nodeID]
+		if !ok {
+			a = &Account{NodeID: nodeID}
+			replayed[nodeID] = a
+		}
+		return a
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		issue := func(kind, nodeID, format string, args ...interface{}) {
+			report.Issues = append(report.Issues, Issue{Kind: kind, EntryID: e.ID, NodeID: nodeID, Detail: fmt.Sprintf(format, args...)})
+		}
+		if want := fmt.Sprintf("tx_%d", i+1); e.ID != want {
+			issue(IssueSequence, "", "expected %s", want)
+		}
+		if e.Amount <= 0 {
+			issue(IssueEntry, "", "non-positive amount %.8f", e.Amount)
+			continue
+		}
+
+		// 余额不足时仍按流水记账，后续账户比较基于流水本身
+		switch e.Type {
+		case EntryMint:
+			if e.Signature != "" && verify != nil && !verify(e.Minter, MintPayload(e.Minter, e.To, e.Amount, e.Reference), e.Signature) {
+				issue(IssueSignature, e.Minter, "invalid mint signature")
+			}
+			a := account(e.To)
+			a.Balance += e.Amount
+			a.Minted += e.Amount
+		case EntryTransfer:
+			if e.From == e.To {
+				issue(IssueEntry, e.From, "transfer to self")
+				continue
+			}
+			src, dst := account(e.From), account(e.To)
+			if src.Available() < e.Amount-verifyTolerance {
+				issue(IssueOverdraft, e.From, "transfers %.8f with %.8f available", e.Amount, src.Available())
+			}
+			if e.Signature != "" {
+				report.SignedTransfers++
+				if e.Nonce != src.Nonce+1 {
+					issue(IssueNonce, e.From, "expected nonce %d, got %d", src.Nonce+1, e.Nonce)
+				}
+				if verify != nil && !verify(e.From, TransferPayload(e.From, e.To, e.Amount, e.Nonce, e.Reference), e.Signature) {
+					issue(IssueSignature, e.From, "invalid transfer signature")
+				}
+				src.Nonce = e.Nonce
+			}
+			src.Balance -= e.Amount
+			dst.Balance += e.Amount
+		case EntryLock:
+			a := account(e.From)
+			if a.Available() < e.Amount-verifyTolerance {
+				issue(IssueOverdraft, e.From, "locks %.8f with %.8f available", e.Amount, a.Available())
+			}
+			a.Locked += e.Amount
+		case EntryUnlock:
+			a := account(e.To)
+			if a.Locked < e.Amount-verifyTolerance {
+				issue(IssueOverdraft, e.To, "unlocks %.8f with %.8f locked", e.Amount, a.Locked)
+			}
+			a.Locked -= e.Amount
+		case EntrySlash:
+			a := account(e.From)
+			if a.Locked < e.Amount-verifyTolerance {
+				issue(IssueOverdraft, e.From, "slashes %.8f with %.8f locked", e.Amount, a.Locked)
+			}
+			a.Locked -= e.Amount
+			a.Balance -= e.Amount
+		default:
+			issue(IssueEntry, "", "unknown entry type %q", e.Type)
+		}
+	}
+
+	// 账户表和重放结果中出现过的账户都比较，缺失的一方按零计
+	ids := make([]string, 0, len(replayed)+len(stored))
+	for id := range replayed {
+		ids = append(ids, id)
+	}
+	for id := range stored {
+		if _, ok := replayed[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		want := account(id)
+		got := stored[id]
+		report.Supply += want.Balance
+		for _, field := range []struct {
+			name      string
+			want, got float64
+		}{
+			{"balance", want.Balance, got.Balance},
+			{"locked", want.Locked, got.Locked},
+			{"minted", want.Minted, got.Minted},
+			{"nonce", float64(want.Nonce), float64(got.Nonce)},
+		} {
+			if math.Abs(field.want-field.got) > verifyTolerance {
+				report.Issues = append(report.Issues, Issue{Kind: IssueAccount, NodeID: id,
+					Detail: fmt.Sprintf("%s replays to %.8f, account has %.8f", field.name, field.want, field.got)})
+			}
+		}
+	}
+	return report
+}
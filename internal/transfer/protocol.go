@@ -0,0 +1,935 @@
+// Package transfer - protocol.go
+// 节点间文件传输协议：分片发送、逐片哈希校验、整文件校验与断点续传。
+// 与邮箱附件无关，流由调用方提供（通常为 libp2p stream），便于测试。
+//
+// 帧格式: 长度(4字节大端) | JSON
+//
+//	发送方                      接收方
+//	offer(元数据)           ->
+//	                        <-  accept(缺失分片列表)
+//	chunk(index,data,hash)  ->
+//	                        <-  ack(index)
+//	...
+//
+// 接收方恢复传输时先发送 resume，发送方随后按上述流程重新发 offer。
+//
+// 接收方只自动接受 AcceptFunc 放行的节点（如受信联系人）发来的文件，其余的 offer 记为
+// pending 并回复可重试的错误，由本地调用 Accept 后接收方主动以 resume 拉取。
+// 每个对端未完成的入站传输受 PeerQuota 和 MaxPendingOffers 限制。
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolID 文件传输协议ID
+const ProtocolID = "/daan/transfer/1.0.0"
+
+const (
+	maxChunkSize = 1024 * 1024              // 接收方接受的最大分片
+	maxFrameSize = 2*maxChunkSize + 64*1024 // 分片经 base64 编码后的帧上限
+	frameOffer   = "offer"
+	frameResume  = "resume"
+	frameAccept  = "accept"
+	frameChunk   = "chunk"
+	frameAck     = "ack"
+	frameError   = "error"
+)
+
+var (
+	ErrNoTransport   = errors.New("transfer transport not configured")
+	ErrEmptyFile     = errors.New("cannot transfer empty file")
+	ErrFileTooLarge  = errors.New("file too large")
+	ErrHashMismatch  = errors.New("hash mismatch")
+	ErrProtocol      = errors.New("unexpected transfer frame")
+	ErrFrameTooLarge = errors.New("transfer frame too large")
+	// ErrAwaitingAccept 接收方尚未接受该传输
+	ErrAwaitingAccept = errors.New("transfer awaiting receiver acceptance")
+	// ErrPeerQuota 对端未完成的入站传输超出配额
+	ErrPeerQuota = errors.New("peer transfer quota exceeded")
+	// ErrOutsideOutbox 发送路径不在发件目录内
+	ErrOutsideOutbox = errors.New("path is outside the transfer outbox")
+)
+
+var transferIDPattern = regexp.MustCompile(`^transfer_[0-9a-f]{32}$`)
+
+// StreamOpener 打开到目标节点的传输流
+type StreamOpener func(ctx context.Context, peerID string) (io.ReadWriteCloser, error)
+
+// OutboundGate 发送分片前申请出站带宽，阻塞到放行；返回错误时中断本次连接
+type OutboundGate func(ctx context.Context, peerID string, size int) error
+
+// ServiceConfig 传输服务配置
+type ServiceConfig struct {
+	IncomingDir      string // 接收文件保存目录
+	OutboxDir        string // 只能发送该目录内的文件，为空时不限制
+	MaxFileSize      int64  // 接受的最大文件大小
+	PeerQuota        int64  // 每个对端未完成（待接受、传输中、暂停）的入站传输总字节数上限，0 表示不限
+	MaxPendingOffers int    // 每个对端待接受的入站传输数上限，0 表示不限
+}
+
+// DefaultServiceConfig 返回默认服务配置
+func DefaultServiceConfig() *ServiceConfig {
+	return &ServiceConfig{
+		IncomingDir:      "data/transfer/incoming",
+		OutboxDir:        "data/transfer/outbox",
+		MaxFileSize:      1 << 30, // 1GB
+		PeerQuota:        2 << 30, // 2GB
+		MaxPendingOffers: 5,
+	}
+}
+
+// TransferProgress 传输进度
+type TransferProgress struct {
+	TransferRequest
+	Direction  string `json:"direction"` // outgoing / incoming
+	ChunksDone int    `json:"chunks_done"`
+	BytesDone  int64  `json:"bytes_done"`
+	Active     bool   `json:"active"` // 当前是否有连接在传输
+}
+
+// frame 协议帧
+type frame struct {
+	Type       string           `json:"type"`
+	TransferID string           `json:"transfer_id,omitempty"`
+	Offer      *TransferRequest `json:"offer,omitempty"`
+	Missing    []int            `json:"missing,omitempty"`
+	Index      int              `json:"index,omitempty"`
+	Data       []byte           `json:"data,omitempty"`
+	Hash       string           `json:"hash,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Retry      bool             `json:"retry,omitempty"` // 错误可重试（不终止传输）
+}
+
+// remoteError 对端返回的错误
+type remoteError struct {
+	msg   string
+	retry bool
+}
+
+func (e *remoteError) Error() string {
+	return "remote: " + e.msg
+}
+
+// Service 文件传输服务
+type Service struct {
+	mu       sync.Mutex
+	manager  *TransferManager
+	localID  string
+	config   *ServiceConfig
+	opener   StreamOpener
+	gate     OutboundGate
+	accept   func(peerID string) bool      // 是否自动接受该节点的文件
+	sessions map[string]context.CancelFunc // transferID -> 当前连接
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService 创建传输服务
+func NewService(manager *TransferManager, localID string, config *ServiceConfig) *Service {
+	if config == nil {
+		config = DefaultServiceConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		manager:  manager,
+		localID:  localID,
+		config:   config,
+		sessions: make(map[string]context.CancelFunc),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// SetStreamOpener 设置打开传输流的函数
+func (s *Service) SetStreamOpener(fn StreamOpener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opener = fn
+}
+
+// SetOutboundGate 设置出站带宽调度，未设置时分片直接发送
+func (s *Service) SetOutboundGate(fn OutboundGate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gate = fn
+}
+
+// SetAcceptFunc 设置自动接受哪些节点发来的文件，未设置时全部等待本地接受
+func (s *Service) SetAcceptFunc(fn func(peerID string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accept = fn
+}
+
+// Close 中断所有进行中的传输（标记为暂停，可稍后恢复）
+func (s *Service) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Send 向节点发送文件，传输在后台进行；配置了 OutboxDir 时 path 相对于发件目录
+func (s *Service) Send(peerID, path string) (*TransferProgress, error) {
+	if s.getOpener() == nil {
+		return nil, ErrNoTransport
+	}
+	absPath, err := s.outboxPath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() == 0 {
+		return nil, ErrEmptyFile
+	}
+	fileHash, err := hashFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &TransferRequest{
+		SenderID:   s.localID,
+		ReceiverID: peerID,
+		FileHash:   fileHash,
+		FileName:   filepath.Base(absPath),
+		FileSize:   info.Size(),
+		LocalPath:  absPath,
+	}
+	if err := s.manager.CreateTransfer(req); err != nil {
+		return nil, err
+	}
+
+	id := req.ID
+	if err := s.startSession(id, func(ctx context.Context) error {
+		return s.dialSender(ctx, id, peerID)
+	}); err != nil {
+		return nil, err
+	}
+	return s.Progress(id)
+}
+
+// outboxPath 把发送路径限制在发件目录内：解析符号链接后仍须位于 OutboxDir 之下
+func (s *Service) outboxPath(path string) (string, error) {
+	if s.config.OutboxDir == "" {
+		return filepath.Abs(path)
+	}
+	root, err := filepath.Abs(s.config.OutboxDir)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideOutbox, path)
+	}
+	return resolved, nil
+}
+
+// Accept 接受等待中的入站传输，向发送方拉取文件
+func (s *Service) Accept(transferID string) error {
+	t, err := s.snapshot(transferID)
+	if err != nil {
+		return err
+	}
+	if t.ReceiverID != s.localID {
+		return ErrUnauthorized
+	}
+	if s.getOpener() == nil {
+		return ErrNoTransport
+	}
+	switch t.Status {
+	case TransferPending:
+		if t.ExpiresAt > 0 && time.Now().Unix() > t.ExpiresAt {
+			return ErrTransferExpired
+		}
+		if err := s.manager.AcceptTransfer(transferID, s.localID, ""); err != nil {
+			return err
+		}
+	case TransferAccepted:
+		// 上次拉取时连接失败，重新拉取
+	default:
+		return fmt.Errorf("cannot accept: transfer status is %s", t.Status)
+	}
+	return s.startSession(transferID, func(ctx context.Context) error {
+		return s.pull(ctx, transferID, t.SenderID)
+	})
+}
+
+// Reject 拒绝等待中的入站传输
+func (s *Service) Reject(transferID string) error {
+	t, err := s.snapshot(transferID)
+	if err != nil {
+		return err
+	}
+	if t.ReceiverID != s.localID || t.Status != TransferPending {
+		return fmt.Errorf("cannot reject: transfer status is %s", t.Status)
+	}
+	return s.manager.CancelTransfer(transferID, s.localID, "rejected")
+}
+
+// ResumeInterrupted 恢复发往 peerID 的、因连接中断或节点重启而暂停的传输，返回恢复的数量
+func (s *Service) ResumeInterrupted(peerID string) int {
+	resumed := 0
+	for _, t := range s.manager.ListTransfers(TransferPaused) {
+		if t.Interrupted && t.SenderID == s.localID && t.ReceiverID == peerID {
+			if s.Resume(t.ID) == nil {
+				resumed++
+			}
+		}
+	}
+	return resumed
+}
+
+// Pause 暂停传输并断开当前连接
+func (s *Service) Pause(transferID string) error {
+	if err := s.manager.PauseTransfer(transferID, s.localID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	cancel := s.sessions[transferID]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// Resume 恢复暂停的传输，只重传缺失的分片
+func (s *Service) Resume(transferID string) error {
+	t, err := s.snapshot(transferID)
+	if err != nil {
+		return err
+	}
+	if s.getOpener() == nil {
+		return ErrNoTransport
+	}
+	if err := s.manager.ResumeTransfer(transferID, s.localID); err != nil {
+		return err
+	}
+
+	if t.SenderID == s.localID {
+		return s.startSession(transferID, func(ctx context.Context) error {
+			return s.dialSender(ctx, transferID, t.ReceiverID)
+		})
+	}
+	return s.startSession(transferID, func(ctx context.Context) error {
+		return s.pull(ctx, transferID, t.SenderID)
+	})
+}
+
+// pull 接收方主动连接发送方，发送 resume 后按发送方重发的 offer 接收缺失的分片
+func (s *Service) pull(ctx context.Context, transferID, senderID string) error {
+	rw, err := s.getOpener()(ctx, senderID)
+	if err != nil {
+		return err
+	}
+	defer rw.Close()
+	defer closeOnCancel(ctx, rw)()
+	if err := writeFrame(rw, &frame{Type: frameResume, TransferID: transferID}); err != nil {
+		return err
+	}
+	fr, err := readReply(rw, frameOffer)
+	if err != nil {
+		return err
+	}
+	if fr.Offer == nil || fr.Offer.ID != transferID {
+		return ErrProtocol
+	}
+	return s.runReceiver(rw, fr.Offer, senderID)
+}
+
+// Progress 获取传输进度
+func (s *Service) Progress(transferID string) (*TransferProgress, error) {
+	t, err := s.snapshot(transferID)
+	if err != nil {
+		return nil, err
+	}
+	return s.progressOf(t), nil
+}
+
+// List 按状态列出传输进度（status 为空返回全部）
+func (s *Service) List(status TransferStatus) []*TransferProgress {
+	transfers := s.manager.ListTransfers(status)
+	result := make([]*TransferProgress, 0, len(transfers))
+	for _, t := range transfers {
+		result = append(result, s.progressOf(t))
+	}
+	return result
+}
+
+// HandleStream 处理对端打开的传输流
+func (s *Service) HandleStream(rw io.ReadWriteCloser, remotePeer string) {
+	defer rw.Close()
+
+	fr, err := readFrame(rw)
+	if err != nil {
+		return
+	}
+
+	switch fr.Type {
+	case frameOffer:
+		if fr.Offer == nil {
+			return
+		}
+		id := fr.Offer.ID
+		s.runSession(id, rw, func(ctx context.Context) error {
+			return s.runReceiver(rw, fr.Offer, remotePeer)
+		})
+	case frameResume:
+		id := fr.TransferID
+		t, err := s.snapshot(id)
+		if err != nil || t.SenderID != s.localID || t.ReceiverID != remotePeer {
+			writeFrame(rw, &frame{Type: frameError, Error: ErrTransferNotFound.Error()})
+			return
+		}
+		if t.Status == TransferPaused {
+			if err := s.manager.ResumeTransfer(id, s.localID); err != nil {
+				writeFrame(rw, &frame{Type: frameError, Error: err.Error()})
+				return
+			}
+		}
+		s.runSession(id, rw, func(ctx context.Context) error {
+			return s.runSender(ctx, rw, id)
+		})
+	}
+}
+
+// ============ 会话管理 ============
+
+func (s *Service) getOpener() StreamOpener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opener
+}
+
+func (s *Service) getGate() OutboundGate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gate
+}
+
+// register 登记传输的连接，同一传输同时只允许一个连接
+func (s *Service) register(transferID string) (context.Context, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[transferID]; ok {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.sessions[transferID] = cancel
+	return ctx, true
+}
+
+func (s *Service) unregister(transferID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.sessions[transferID]; ok {
+		cancel()
+		delete(s.sessions, transferID)
+	}
+}
+
+// startSession 在后台运行主动发起的连接
+func (s *Service) startSession(transferID string, run func(ctx context.Context) error) error {
+	ctx, ok := s.register(transferID)
+	if !ok {
+		return ErrTransferInProgress
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := run(ctx)
+		s.unregister(transferID)
+		s.finish(transferID, err)
+	}()
+	return nil
+}
+
+// runSession 在当前协程运行对端发起的连接
+func (s *Service) runSession(transferID string, rw io.ReadWriteCloser, run func(ctx context.Context) error) {
+	ctx, ok := s.register(transferID)
+	if !ok {
+		writeFrame(rw, &frame{Type: frameError, Error: ErrTransferInProgress.Error(), Retry: true})
+		return
+	}
+	s.wg.Add(1)
+	defer s.wg.Done()
+	defer closeOnCancel(ctx, rw)()
+	err := run(ctx)
+	s.unregister(transferID)
+	s.finish(transferID, err)
+}
+
+// finish 处理连接结束：对端拒绝则失败，连接中断则暂停以便续传，等待接收方接受时保持 pending
+func (s *Service) finish(transferID string, err error) {
+	if err == nil || errors.Is(err, ErrAwaitingAccept) {
+		return
+	}
+	var re *remoteError
+	if errors.As(err, &re) {
+		if re.msg == ErrAwaitingAccept.Error() {
+			return
+		}
+		if !re.retry {
+			s.manager.FailTransfer(transferID, re.msg)
+			return
+		}
+	}
+	t, snapErr := s.snapshot(transferID)
+	if snapErr != nil {
+		return
+	}
+	switch t.Status {
+	case TransferInProgress:
+		s.manager.InterruptTransfer(transferID)
+	case TransferPending:
+		s.manager.FailTransfer(transferID, err.Error())
+	}
+}
+
+// closeOnCancel 在 ctx 取消时关闭流，使阻塞的读写返回
+func closeOnCancel(ctx context.Context, rw io.Closer) func() {
+	stop := context.AfterFunc(ctx, func() { rw.Close() })
+	return func() { stop() }
+}
+
+// ============ 发送方 ============
+
+func (s *Service) dialSender(ctx context.Context, transferID, peerID string) error {
+	rw, err := s.getOpener()(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer rw.Close()
+	defer closeOnCancel(ctx, rw)()
+	return s.runSender(ctx, rw, transferID)
+}
+
+func (s *Service) runSender(ctx context.Context, rw io.ReadWriter, transferID string) error {
+	t, err := s.snapshot(transferID)
+	if err != nil {
+		return err
+	}
+	offer := *t
+	offer.LocalPath = ""
+	offer.Error = ""
+	if err := writeFrame(rw, &frame{Type: frameOffer, TransferID: transferID, Offer: &offer}); err != nil {
+		return err
+	}
+
+	fr, err := readReply(rw, frameAccept)
+	if err != nil {
+		return err
+	}
+	if t.Status == TransferPending {
+		if err := s.manager.AcceptTransfer(transferID, t.ReceiverID, ""); err != nil {
+			return err
+		}
+		if err := s.manager.StartTransfer(transferID, s.localID); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(t.LocalPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gate := s.getGate()
+	for _, index := range fr.Missing {
+		if index < 0 || index >= t.TotalChunks {
+			return ErrProtocol
+		}
+		data, err := readChunk(f, t, index)
+		if err != nil {
+			return err
+		}
+		if gate != nil {
+			if err := gate(ctx, t.ReceiverID, len(data)); err != nil {
+				return err
+			}
+		}
+		if err := writeFrame(rw, &frame{
+			Type:       frameChunk,
+			TransferID: transferID,
+			Index:      index,
+			Data:       data,
+			Hash:       hashBytes(data),
+		}); err != nil {
+			return err
+		}
+		ack, err := readReply(rw, frameAck)
+		if err != nil {
+			return err
+		}
+		if ack.Index != index {
+			return ErrProtocol
+		}
+		s.markChunk(transferID, index, len(data))
+	}
+
+	// 接收方未列出的分片已在之前的连接中收到
+	missing, _ := s.manager.GetMissingChunks(transferID)
+	for _, index := range missing {
+		s.markChunk(transferID, index, 0)
+	}
+	return nil
+}
+
+func (s *Service) markChunk(transferID string, index, size int) {
+	s.manager.ReceiveChunk(&TransferChunk{TransferID: transferID, Index: index, Size: size})
+}
+
+// ============ 接收方 ============
+
+// checkPeerQuota 检查对端未完成的入站传输加上 size 是否超出配额，过期未接受的不计
+func (s *Service) checkPeerQuota(peerID string, size int64) error {
+	now := time.Now().Unix()
+	var bytes int64
+	pending := 0
+	for _, t := range s.manager.ListTransfers("") {
+		if t.SenderID != peerID || t.ReceiverID != s.localID {
+			continue
+		}
+		switch t.Status {
+		case TransferPending:
+			if t.ExpiresAt > 0 && now > t.ExpiresAt {
+				continue
+			}
+			pending++
+			bytes += t.FileSize
+		case TransferAccepted, TransferInProgress, TransferPaused:
+			bytes += t.FileSize
+		}
+	}
+	if s.config.MaxPendingOffers > 0 && pending >= s.config.MaxPendingOffers {
+		return fmt.Errorf("%w: %d transfers awaiting acceptance", ErrPeerQuota, pending)
+	}
+	if s.config.PeerQuota > 0 && bytes+size > s.config.PeerQuota {
+		return fmt.Errorf("%w: %d bytes unfinished", ErrPeerQuota, bytes)
+	}
+	return nil
+}
+
+func (s *Service) autoAccept(peerID string) bool {
+	s.mu.Lock()
+	accept := s.accept
+	s.mu.Unlock()
+	return accept != nil && accept(peerID)
+}
+
+// acceptOffer 创建或恢复本地传输记录，返回缺失的分片；
+// 未自动接受的新传输记为 pending 并返回 ErrAwaitingAccept
+func (s *Service) acceptOffer(offer *TransferRequest, remotePeer string) ([]int, error) {
+	switch {
+	case !transferIDPattern.MatchString(offer.ID):
+		return nil, errors.New("invalid transfer id")
+	case offer.SenderID != remotePeer || offer.ReceiverID != s.localID:
+		return nil, ErrUnauthorized
+	case offer.FileSize <= 0 || offer.ChunkSize <= 0 || offer.ChunkSize > maxChunkSize:
+		return nil, ErrInvalidChunk
+	case s.config.MaxFileSize > 0 && offer.FileSize > s.config.MaxFileSize:
+		return nil, ErrFileTooLarge
+	}
+
+	existing, err := s.snapshot(offer.ID)
+	if err == nil {
+		if existing.SenderID != offer.SenderID || existing.FileHash != offer.FileHash {
+			return nil, ErrUnauthorized
+		}
+		switch existing.Status {
+		case TransferCompleted:
+			return nil, nil
+		case TransferPending:
+			return nil, ErrAwaitingAccept
+		case TransferAccepted:
+			if err := s.manager.StartTransfer(offer.ID, existing.SenderID); err != nil {
+				return nil, err
+			}
+		case TransferPaused:
+			if err := s.manager.ResumeTransfer(offer.ID, s.localID); err != nil {
+				return nil, err
+			}
+		case TransferInProgress:
+		default:
+			return nil, fmt.Errorf("transfer is %s", existing.Status)
+		}
+		return s.manager.GetMissingChunks(offer.ID)
+	}
+
+	if err := s.checkPeerQuota(remotePeer, offer.FileSize); err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(filepath.Clean("/" + offer.FileName))
+	if name == "/" || name == "." {
+		name = "file"
+	}
+	record := *offer
+	record.Status = ""
+	record.Progress = 0
+	record.ExpiresAt = 0
+	record.LocalPath = filepath.Join(s.config.IncomingDir, offer.ID, name)
+	if err := s.manager.CreateTransfer(&record); err != nil {
+		return nil, err
+	}
+	if !s.autoAccept(remotePeer) {
+		return nil, ErrAwaitingAccept
+	}
+	if err := s.manager.AcceptTransfer(record.ID, s.localID, ""); err != nil {
+		return nil, err
+	}
+	if err := s.manager.StartTransfer(record.ID, record.SenderID); err != nil {
+		return nil, err
+	}
+	return s.manager.GetMissingChunks(record.ID)
+}
+
+func (s *Service) runReceiver(rw io.ReadWriter, offer *TransferRequest, remotePeer string) error {
+	missing, err := s.acceptOffer(offer, remotePeer)
+	if err != nil {
+		writeFrame(rw, &frame{Type: frameError, Error: err.Error(), Retry: errors.Is(err, ErrAwaitingAccept)})
+		return err
+	}
+	if err := writeFrame(rw, &frame{Type: frameAccept, TransferID: offer.ID, Missing: missing}); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	t, err := s.snapshot(offer.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.config.IncomingDir, 0755); err != nil {
+		return err
+	}
+	partPath := filepath.Join(s.config.IncomingDir, t.ID+".part")
+	f, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for {
+		fr, err := readFrame(rw)
+		if err != nil {
+			return err
+		}
+		if fr.Type != frameChunk {
+			return ErrProtocol
+		}
+		if err := s.writeChunk(f, t, fr); err != nil {
+			s.manager.FailTransfer(t.ID, err.Error())
+			writeFrame(rw, &frame{Type: frameError, Error: err.Error()})
+			return err
+		}
+
+		// 最后一个分片写入后校验整个文件，通过后才确认
+		remaining, _ := s.manager.GetMissingChunks(t.ID)
+		if len(remaining) == 1 && remaining[0] == fr.Index {
+			if err := finalizeFile(f, partPath, t); err != nil {
+				s.manager.FailTransfer(t.ID, err.Error())
+				writeFrame(rw, &frame{Type: frameError, Error: err.Error()})
+				return err
+			}
+		}
+
+		if err := s.manager.ReceiveChunk(&TransferChunk{
+			TransferID: t.ID,
+			Index:      fr.Index,
+			Hash:       fr.Hash,
+			Size:       len(fr.Data),
+		}); err != nil {
+			return err
+		}
+		if err := writeFrame(rw, &frame{Type: frameAck, TransferID: t.ID, Index: fr.Index}); err != nil {
+			return err
+		}
+		if done, _ := s.snapshot(t.ID); done != nil && done.Status == TransferCompleted {
+			return nil
+		}
+	}
+}
+
+// writeChunk 校验分片并写入临时文件
+func (s *Service) writeChunk(f *os.File, t *TransferRequest, fr *frame) error {
+	if fr.Index < 0 || fr.Index >= t.TotalChunks {
+		return fmt.Errorf("%w: index %d", ErrInvalidChunk, fr.Index)
+	}
+	if int64(len(fr.Data)) != chunkLen(t, fr.Index) {
+		return fmt.Errorf("%w: chunk %d size %d", ErrInvalidChunk, fr.Index, len(fr.Data))
+	}
+	if hashBytes(fr.Data) != fr.Hash {
+		return fmt.Errorf("%w: chunk %d", ErrHashMismatch, fr.Index)
+	}
+	_, err := f.WriteAt(fr.Data, int64(fr.Index)*t.ChunkSize)
+	return err
+}
+
+// finalizeFile 校验整个文件哈希并移动到最终位置
+func finalizeFile(f *os.File, partPath string, t *TransferRequest) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	fileHash, err := hashFile(partPath)
+	if err != nil {
+		return err
+	}
+	if fileHash != t.FileHash {
+		return fmt.Errorf("%w: file %s", ErrHashMismatch, t.FileName)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.LocalPath), 0755); err != nil {
+		return err
+	}
+	return os.Rename(partPath, t.LocalPath)
+}
+
+// ============ 内部工具 ============
+
+func (s *Service) snapshot(transferID string) (*TransferRequest, error) {
+	s.manager.mu.RLock()
+	defer s.manager.mu.RUnlock()
+	t, ok := s.manager.transfers[transferID]
+	if !ok {
+		return nil, ErrTransferNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (s *Service) progressOf(t *TransferRequest) *TransferProgress {
+	p := &TransferProgress{TransferRequest: *t, Direction: "incoming"}
+	if t.SenderID == s.localID {
+		p.Direction = "outgoing"
+	}
+
+	s.manager.mu.RLock()
+	for i, done := range s.manager.chunkStatus[t.ID] {
+		if done {
+			p.ChunksDone++
+			p.BytesDone += chunkLen(t, i)
+		}
+	}
+	s.manager.mu.RUnlock()
+
+	s.mu.Lock()
+	_, p.Active = s.sessions[t.ID]
+	s.mu.Unlock()
+	return p
+}
+
+func chunkLen(t *TransferRequest, index int) int64 {
+	start := int64(index) * t.ChunkSize
+	if remain := t.FileSize - start; remain < t.ChunkSize {
+		return remain
+	}
+	return t.ChunkSize
+}
+
+func readChunk(f *os.File, t *TransferRequest, index int) ([]byte, error) {
+	data := make([]byte, chunkLen(t, index))
+	if _, err := f.ReadAt(data, int64(index)*t.ChunkSize); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readReply 读取期望类型的应答帧，对端错误转换为 remoteError
+func readReply(r io.Reader, want string) (*frame, error) {
+	fr, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	if fr.Type == frameError {
+		return nil, &remoteError{msg: fr.Error, retry: fr.Retry}
+	}
+	if fr.Type != want {
+		return nil, ErrProtocol
+	}
+	return fr, nil
+}
+
+func writeFrame(w io.Writer, fr *frame) error {
+	data, err := json.Marshal(fr)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxFrameSize {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err = w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	var fr frame
+	if err := json.Unmarshal(data, &fr); err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
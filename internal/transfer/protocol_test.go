@@ -0,0 +1,517 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cutConn fails every write after the first n, simulating a dropped connection
+type cutConn struct {
+	net.Conn
+	mu     sync.Mutex
+	writes int
+	limit  int
+}
+
+func (c *cutConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	c.writes++
+	cut := c.limit > 0 && c.writes > c.limit
+	c.mu.Unlock()
+	if cut {
+		c.Conn.Close()
+		return 0, io.ErrClosedPipe
+	}
+	return c.Conn.Write(p)
+}
+
+func newTestService(t *testing.T, nodeID string) *Service {
+	dir := t.TempDir()
+	tm := NewTransferManager(&TransferConfig{
+		DataDir:                filepath.Join(dir, "transfer"),
+		DefaultChunkSize:       1024,
+		MaxConcurrentTransfers: 5,
+		TransferTimeout:        time.Minute,
+	})
+	s := NewService(tm, nodeID, &ServiceConfig{
+		IncomingDir: filepath.Join(dir, "incoming"),
+		MaxFileSize: 1 << 20,
+	})
+	s.SetAcceptFunc(func(string) bool { return true })
+	t.Cleanup(s.Close)
+	return s
+}
+
+// link lets from open streams to to; writeLimit > 0 drops the connection
+func link(from, to *Service, writeLimit *int) {
+	from.SetStreamOpener(func(ctx context.Context, peerID string) (io.ReadWriteCloser, error) {
+		if peerID != to.localID {
+			return nil, errors.New("unknown peer")
+		}
+		a, b := net.Pipe()
+		go to.HandleStream(b, from.localID)
+		limit := 0
+		if writeLimit != nil {
+			limit = *writeLimit
+		}
+		return &cutConn{Conn: a, limit: limit}, nil
+	})
+}
+
+func writeTestFile(t *testing.T, size int) (string, []byte) {
+	data := make([]byte, size)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func waitStatus(t *testing.T, s *Service, id string, want TransferStatus) *TransferProgress {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, err := s.Progress(id)
+		if err == nil && p.Status == want && !p.Active {
+			return p
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: transfer %s did not reach %s (progress %+v, err %v)", s.localID, id, want, p, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServiceSendFile(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+	link(sender, receiver, nil)
+
+	path, data := writeTestFile(t, 10*1024+100)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if p.Direction != "outgoing" || p.TotalChunks != 11 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+
+	sent := waitStatus(t, sender, p.ID, TransferCompleted)
+	if sent.BytesDone != int64(len(data)) || sent.Progress != 1 {
+		t.Errorf("sender progress = %d bytes (%.2f)", sent.BytesDone, sent.Progress)
+	}
+
+	received := waitStatus(t, receiver, p.ID, TransferCompleted)
+	if received.Direction != "incoming" || received.SenderID != "sender" {
+		t.Errorf("unexpected incoming record: %+v", received)
+	}
+	got, err := os.ReadFile(received.LocalPath)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("received file differs (err %v)", err)
+	}
+	if filepath.Base(received.LocalPath) != "payload.bin" {
+		t.Errorf("unexpected file name: %s", received.LocalPath)
+	}
+
+	if list := receiver.List(TransferCompleted); len(list) != 1 || list[0].ID != p.ID {
+		t.Errorf("List(completed) = %+v", list)
+	}
+	if list := receiver.List(TransferInProgress); len(list) != 0 {
+		t.Errorf("List(in_progress) = %+v", list)
+	}
+}
+
+func TestServiceResumeAfterDisconnect(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+
+	// offer + 3 chunks get through, then the connection drops
+	limit := 4
+	link(sender, receiver, &limit)
+	link(receiver, sender, nil)
+
+	path, data := writeTestFile(t, 10*1024)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	waitStatus(t, sender, p.ID, TransferPaused)
+	partial := waitStatus(t, receiver, p.ID, TransferPaused)
+	if partial.ChunksDone != 3 || partial.BytesDone != 3*1024 {
+		t.Errorf("expected 3 chunks before disconnect, got %d (%d bytes)", partial.ChunksDone, partial.BytesDone)
+	}
+
+	// The receiver asks the sender to resume; only missing chunks are sent
+	if err := receiver.Resume(p.ID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	received := waitStatus(t, receiver, p.ID, TransferCompleted)
+	waitStatus(t, sender, p.ID, TransferCompleted)
+
+	got, err := os.ReadFile(received.LocalPath)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("resumed file differs (err %v)", err)
+	}
+	if err := receiver.Resume(p.ID); err == nil {
+		t.Error("resuming a completed transfer should fail")
+	}
+}
+
+func TestServicePause(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+
+	// Hold the connection open without reading so the transfer stays active
+	sender.SetStreamOpener(func(ctx context.Context, peerID string) (io.ReadWriteCloser, error) {
+		a, b := net.Pipe()
+		go func() {
+			fr, err := readFrame(b)
+			if err != nil {
+				return
+			}
+			writeFrame(b, &frame{Type: frameAccept, TransferID: fr.TransferID, Missing: []int{0, 1}})
+		}()
+		return a, nil
+	})
+
+	path, _ := writeTestFile(t, 2048)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cur, _ := sender.Progress(p.ID)
+		if cur.Status == TransferInProgress {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("transfer never started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := sender.Pause(p.ID); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	waitStatus(t, sender, p.ID, TransferPaused)
+
+	link(sender, receiver, nil)
+	if err := sender.Resume(p.ID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	waitStatus(t, receiver, p.ID, TransferCompleted)
+	waitStatus(t, sender, p.ID, TransferCompleted)
+}
+
+func TestServiceRejectsBadData(t *testing.T) {
+	receiver := newTestService(t, "receiver")
+
+	offerFor := func(id string, hash string) *TransferRequest {
+		return &TransferRequest{
+			ID:         id,
+			SenderID:   "sender",
+			ReceiverID: "receiver",
+			FileHash:   hash,
+			FileName:   "../../etc/passwd",
+			FileSize:   4,
+			ChunkSize:  1024,
+		}
+	}
+	exchange := func(offer *TransferRequest, chunk []byte) (*frame, *frame) {
+		a, b := net.Pipe()
+		defer a.Close()
+		go receiver.HandleStream(b, "sender")
+		writeFrame(a, &frame{Type: frameOffer, TransferID: offer.ID, Offer: offer})
+		accept, err := readFrame(a)
+		if err != nil || accept.Type != frameAccept {
+			return accept, nil
+		}
+		writeFrame(a, &frame{Type: frameChunk, TransferID: offer.ID, Index: 0, Data: chunk, Hash: hashBytes(chunk)})
+		reply, _ := readFrame(a)
+		return accept, reply
+	}
+
+	t.Run("file hash mismatch", func(t *testing.T) {
+		id := "transfer_" + "00112233445566778899aabbccddeeff"
+		_, reply := exchange(offerFor(id, hashBytes([]byte("good"))), []byte("evil"))
+		if reply == nil || reply.Type != frameError {
+			t.Fatalf("expected error frame, got %+v", reply)
+		}
+		failed := waitStatus(t, receiver, id, TransferFailed)
+		if failed.Error == "" {
+			t.Error("failure reason should be recorded")
+		}
+		if _, err := os.Stat(failed.LocalPath); !os.IsNotExist(err) {
+			t.Error("unverified file should not be moved into place")
+		}
+		if filepath.Dir(filepath.Dir(failed.LocalPath)) != receiver.config.IncomingDir {
+			t.Errorf("file name escaped incoming dir: %s", failed.LocalPath)
+		}
+	})
+
+	t.Run("invalid offers", func(t *testing.T) {
+		bad := offerFor("../escape", hashBytes([]byte("good")))
+		if accept, _ := exchange(bad, nil); accept == nil || accept.Type != frameError {
+			t.Errorf("invalid transfer id should be rejected, got %+v", accept)
+		}
+
+		other := offerFor("transfer_"+"ffeeddccbbaa99887766554433221100", hashBytes([]byte("good")))
+		other.ReceiverID = "someone-else"
+		if accept, _ := exchange(other, nil); accept == nil || accept.Type != frameError {
+			t.Errorf("offer for another node should be rejected, got %+v", accept)
+		}
+
+		huge := offerFor("transfer_"+"0123456789abcdef0123456789abcdef", hashBytes([]byte("good")))
+		huge.FileSize = 2 << 20
+		if accept, _ := exchange(huge, nil); accept == nil || accept.Error != ErrFileTooLarge.Error() {
+			t.Errorf("oversized file should be rejected, got %+v", accept)
+		}
+	})
+}
+
+func TestServiceSendErrors(t *testing.T) {
+	s := newTestService(t, "sender")
+	path, _ := writeTestFile(t, 10)
+	if _, err := s.Send("receiver", path); err != ErrNoTransport {
+		t.Errorf("expected ErrNoTransport, got %v", err)
+	}
+
+	link(s, s, nil)
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, nil, 0644)
+	if _, err := s.Send("receiver", empty); err != ErrEmptyFile {
+		t.Errorf("expected ErrEmptyFile, got %v", err)
+	}
+
+	// Unreachable peer: the transfer never starts and is marked failed
+	p, err := s.Send("unknown", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	waitStatus(t, s, p.ID, TransferFailed)
+}
+
+func TestServiceOutboundGate(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+	link(sender, receiver, nil)
+
+	var (
+		mu    sync.Mutex
+		bytes int
+		calls int
+		deny  = true
+	)
+	sender.SetOutboundGate(func(ctx context.Context, peerID string, size int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if peerID != "receiver" {
+			t.Errorf("gate called for %s", peerID)
+		}
+		if deny {
+			return errors.New("outbound queue full")
+		}
+		calls++
+		bytes += size
+		return nil
+	})
+
+	path, data := writeTestFile(t, 3*1024+10)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	// 带宽申请失败时中断连接，传输暂停以便续传
+	waitStatus(t, sender, p.ID, TransferPaused)
+
+	mu.Lock()
+	deny = false
+	mu.Unlock()
+	if err := sender.Resume(p.ID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	waitStatus(t, sender, p.ID, TransferCompleted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 4 || bytes != len(data) {
+		t.Errorf("expected 4 gated chunks totalling %d bytes, got %d calls, %d bytes", len(data), calls, bytes)
+	}
+}
+
+func TestServiceAwaitAccept(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+	receiver.SetAcceptFunc(nil)
+	link(sender, receiver, nil)
+	link(receiver, sender, nil)
+
+	path, data := writeTestFile(t, 2*1024+1)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	// 未被自动接受：双方都停在 pending，接收方不写入任何数据
+	pending := waitStatus(t, receiver, p.ID, TransferPending)
+	waitStatus(t, sender, p.ID, TransferPending)
+	if pending.ChunksDone != 0 {
+		t.Errorf("pending transfer received %d chunks", pending.ChunksDone)
+	}
+
+	if err := sender.Accept(p.ID); err != ErrUnauthorized {
+		t.Errorf("sender Accept: expected ErrUnauthorized, got %v", err)
+	}
+	if err := receiver.Accept(p.ID); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	received := waitStatus(t, receiver, p.ID, TransferCompleted)
+	waitStatus(t, sender, p.ID, TransferCompleted)
+	got, err := os.ReadFile(received.LocalPath)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("accepted file differs (err %v)", err)
+	}
+
+	// 拒绝后发送方重发的 offer 也不再被接受
+	p2, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	waitStatus(t, receiver, p2.ID, TransferPending)
+	if err := receiver.Reject(p2.ID); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	waitStatus(t, receiver, p2.ID, TransferCancelled)
+	if err := receiver.Accept(p2.ID); err == nil {
+		t.Error("accepting a rejected transfer should fail")
+	}
+}
+
+func TestServicePeerQuota(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+	receiver.SetAcceptFunc(nil)
+	receiver.config.PeerQuota = 3 * 1024
+	receiver.config.MaxPendingOffers = 2
+	link(sender, receiver, nil)
+
+	path, _ := writeTestFile(t, 1024)
+	for i := 0; i < 2; i++ {
+		p, err := sender.Send("receiver", path)
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		waitStatus(t, receiver, p.ID, TransferPending)
+	}
+	// 第三个待接受的 offer 超出数量限制，被拒绝后发送方标记失败
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	failed := waitStatus(t, sender, p.ID, TransferFailed)
+	if !strings.Contains(failed.Error, ErrPeerQuota.Error()) {
+		t.Errorf("expected quota error, got %q", failed.Error)
+	}
+
+	if err := receiver.checkPeerQuota("sender", 1024); err == nil {
+		t.Error("expected pending offer limit to apply")
+	}
+	receiver.config.MaxPendingOffers = 0
+	if err := receiver.checkPeerQuota("sender", 1024); err != nil {
+		t.Errorf("1KB more should fit in the quota: %v", err)
+	}
+	if err := receiver.checkPeerQuota("sender", 1025); err == nil {
+		t.Error("expected byte quota to apply")
+	}
+	if err := receiver.checkPeerQuota("other", 3*1024); err != nil {
+		t.Errorf("quota should be per peer: %v", err)
+	}
+}
+
+func TestServiceOutbox(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+	link(sender, receiver, nil)
+
+	outbox := filepath.Join(t.TempDir(), "outbox")
+	os.MkdirAll(outbox, 0755)
+	sender.config.OutboxDir = outbox
+	os.WriteFile(filepath.Join(outbox, "report.txt"), []byte("report"), 0644)
+
+	secret, _ := writeTestFile(t, 10)
+	os.Symlink(secret, filepath.Join(outbox, "link.bin"))
+
+	for _, path := range []string{secret, "../outbox/../secret", "link.bin", outbox, "."} {
+		if _, err := sender.Send("receiver", path); !errors.Is(err, ErrOutsideOutbox) && !os.IsNotExist(err) {
+			t.Errorf("Send(%q): expected ErrOutsideOutbox, got %v", path, err)
+		}
+	}
+
+	p, err := sender.Send("receiver", "report.txt")
+	if err != nil {
+		t.Fatalf("Send from outbox failed: %v", err)
+	}
+	waitStatus(t, receiver, p.ID, TransferCompleted)
+	if _, err := sender.Send("receiver", filepath.Join(outbox, "report.txt")); err != nil {
+		t.Errorf("absolute path inside outbox rejected: %v", err)
+	}
+}
+
+func TestServiceRecoverAfterRestart(t *testing.T) {
+	sender := newTestService(t, "sender")
+	receiver := newTestService(t, "receiver")
+
+	// 在传输中途模拟发送方崩溃：记录仍为 in_progress
+	limit := 3
+	link(sender, receiver, &limit)
+	path, data := writeTestFile(t, 5*1024)
+	p, err := sender.Send("receiver", path)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	waitStatus(t, receiver, p.ID, TransferPaused)
+	waitStatus(t, sender, p.ID, TransferPaused)
+	sender.manager.mu.Lock()
+	sender.manager.transfers[p.ID].Status = TransferInProgress
+	sender.manager.transfers[p.ID].Interrupted = false
+	sender.manager.save()
+	sender.manager.mu.Unlock()
+
+	restarted := NewService(NewTransferManager(sender.manager.config), "sender", sender.config)
+	t.Cleanup(restarted.Close)
+	link(restarted, receiver, nil)
+
+	recovered, err := restarted.manager.GetTransfer(p.ID)
+	if err != nil {
+		t.Fatalf("transfer lost after restart: %v", err)
+	}
+	if recovered.Status != TransferPaused || !recovered.Interrupted {
+		t.Fatalf("expected interrupted paused transfer, got %s (interrupted %v)", recovered.Status, recovered.Interrupted)
+	}
+
+	// 对端重新连上后自动续传
+	if n := restarted.ResumeInterrupted("other"); n != 0 {
+		t.Errorf("resumed %d transfers for an unrelated peer", n)
+	}
+	if n := restarted.ResumeInterrupted("receiver"); n != 1 {
+		t.Fatalf("ResumeInterrupted = %d, want 1", n)
+	}
+	received := waitStatus(t, receiver, p.ID, TransferCompleted)
+	waitStatus(t, restarted, p.ID, TransferCompleted)
+	got, err := os.ReadFile(received.LocalPath)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("recovered file differs (err %v)", err)
+	}
+}
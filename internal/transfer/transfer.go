@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
@@ -73,6 +74,11 @@ type TransferRequest struct {
 	// 签名
 	SenderSig   string `json:"sender_sig"`
 	ReceiverSig string `json:"receiver_sig"`
+
+	// 本地信息（不随传输协议发送）
+	LocalPath   string `json:"local_path,omitempty"`  // 发送方为源文件，接收方为保存位置
+	Error       string `json:"error,omitempty"`       // 失败原因
+	Interrupted bool   `json:"interrupted,omitempty"` // 因连接中断或节点重启暂停（而非手动暂停），可自动恢复
 }
 
 // TransferChunk 传输分片
@@ -381,6 +387,7 @@ func (tm *TransferManager) PauseTransfer(transferID, nodeID string) error {
 
 	oldStatus := transfer.Status
 	transfer.Status = TransferPaused
+	transfer.Interrupted = false
 
 	// 保存检查点
 	tm.saveCheckpoint(transferID)
@@ -411,6 +418,7 @@ func (tm *TransferManager) ResumeTransfer(transferID, nodeID string) error {
 
 	oldStatus := transfer.Status
 	transfer.Status = TransferInProgress
+	transfer.Interrupted = false
 
 	tm.updateStatusIndex(transferID, oldStatus, TransferInProgress)
 	tm.save()
@@ -418,6 +426,27 @@ func (tm *TransferManager) ResumeTransfer(transferID, nodeID string) error {
 	return nil
 }
 
+// InterruptTransfer 连接中断时暂停传输，标记为可自动恢复
+func (tm *TransferManager) InterruptTransfer(transferID string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	transfer, exists := tm.transfers[transferID]
+	if !exists {
+		return ErrTransferNotFound
+	}
+	if transfer.Status != TransferInProgress {
+		return fmt.Errorf("cannot interrupt: transfer status is %s", transfer.Status)
+	}
+
+	transfer.Status = TransferPaused
+	transfer.Interrupted = true
+	tm.saveCheckpoint(transferID)
+	tm.updateStatusIndex(transferID, TransferInProgress, TransferPaused)
+	tm.save()
+	return nil
+}
+
 // CancelTransfer 取消传输
 func (tm *TransferManager) CancelTransfer(transferID, nodeID, reason string) error {
 	tm.mu.Lock()
@@ -451,6 +480,54 @@ func (tm *TransferManager) CancelTransfer(transferID, nodeID, reason string) err
 	return nil
 }
 
+// FailTransfer 将传输标记为失败
+func (tm *TransferManager) FailTransfer(transferID, reason string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	transfer, exists := tm.transfers[transferID]
+	if !exists {
+		return ErrTransferNotFound
+	}
+
+	if transfer.Status == TransferCompleted || transfer.Status == TransferCancelled || transfer.Status == TransferFailed {
+		return fmt.Errorf("cannot fail: transfer status is %s", transfer.Status)
+	}
+
+	oldStatus := transfer.Status
+	transfer.Status = TransferFailed
+	transfer.Error = reason
+
+	if oldStatus == TransferInProgress {
+		tm.updateBandwidth(transfer.SenderID, false)
+		tm.updateBandwidth(transfer.ReceiverID, false)
+	}
+
+	tm.updateStatusIndex(transferID, oldStatus, TransferFailed)
+	tm.save()
+
+	return nil
+}
+
+// ListTransfers 按状态列出传输（status 为空返回全部），返回副本
+func (tm *TransferManager) ListTransfers(status TransferStatus) []*TransferRequest {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	var transfers []*TransferRequest
+	for _, t := range tm.transfers {
+		if status != "" && t.Status != status {
+			continue
+		}
+		c := *t
+		transfers = append(transfers, &c)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		return transfers[i].CreatedAt > transfers[j].CreatedAt
+	})
+	return transfers
+}
+
 // GetTransfer 获取传输信息
 func (tm *TransferManager) GetTransfer(transferID string) (*TransferRequest, error) {
 	tm.mu.RLock()
@@ -660,9 +737,16 @@ func (tm *TransferManager) load() {
 		return
 	}
 
+	// 节点退出时仍在传输的记录改为中断暂停，重启后可续传
+	interrupted := false
 	if stored.Transfers != nil {
 		tm.transfers = stored.Transfers
 		for _, t := range tm.transfers {
+			if t.Status == TransferInProgress {
+				t.Status = TransferPaused
+				t.Interrupted = true
+				interrupted = true
+			}
 			tm.addToIndex(t)
 		}
 	}
@@ -674,6 +758,10 @@ func (tm *TransferManager) load() {
 	if stored.Checkpoints != nil {
 		tm.checkpoints = stored.Checkpoints
 	}
+
+	if interrupted {
+		tm.save()
+	}
 }
 
 func (tm *TransferManager) save() {
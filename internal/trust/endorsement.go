@@ -0,0 +1,385 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 背书相关常量
+const (
+	EndorsementMaxDistance = 3                    // 背书距离计算的最大深度
+	EndorsementDefaultTTL  = 365 * 24 * time.Hour // 背书默认有效期
+)
+
+// 背书错误
+var (
+	ErrNilEndorsementConfig = errors.New("endorsement config cannot be nil")
+	ErrEmptyEndorserID      = errors.New("endorser node ID cannot be empty")
+	ErrSelfEndorsement      = errors.New("cannot endorse own key")
+	ErrEmptySubjectKey      = errors.New("subject public key cannot be empty")
+	ErrNoSignFunc           = errors.New("endorsement sign function not set")
+	ErrInvalidEndorsement   = errors.New("invalid endorsement signature")
+	ErrEndorsementExpired   = errors.New("endorsement expired")
+	ErrEndorsementNotFound  = errors.New("endorsement not found")
+	ErrNotEndorser          = errors.New("only the endorser can revoke an endorsement")
+	ErrEndorsementRevoked   = errors.New("endorsement has been revoked")
+)
+
+// Endorsement 密钥背书：背书人声明已通过带外方式核实被背书节点的运营者与公钥
+type Endorsement struct {
+	ID         string    `json:"id"`
+	Endorser   string    `json:"endorser"`    // 背书人节点ID
+	Subject    string    `json:"subject"`     // 被背书节点ID
+	SubjectKey string    `json:"subject_key"` // 被背书节点公钥(hex)
+	Statement  string    `json:"statement"`   // 背书说明，如 "verified operator off-band"
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Signature  []byte    `json:"signature"`
+	Revoked    bool      `json:"revoked"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+}
+
+// SignData 背书签名的原始数据
+func (e *Endorsement) SignData() []byte {
+	return []byte(fmt.Sprintf("endorse|%s|%s|%s|%s|%d|%d",
+		e.Endorser, e.Subject, e.SubjectKey, e.Statement, e.CreatedAt.Unix(), e.ExpiresAt.Unix()))
+}
+
+// Valid 背书在指定时间是否有效
+func (e *Endorsement) Valid(now time.Time) bool {
+	return !e.Revoked && now.Before(e.ExpiresAt)
+}
+
+// EndorsementConfig 背书配置
+type EndorsementConfig struct {
+	NodeID      string
+	DataDir     string
+	MaxDistance int
+	DefaultTTL  time.Duration
+
+	// 签名函数（本节点私钥）
+	SignFunc func(data []byte) ([]byte, error)
+	// 验签函数（根据背书人节点ID校验）
+	VerifyFunc func(endorserID string, data, signature []byte) (bool, error)
+}
+
+// DefaultEndorsementConfig 返回默认配置
+func DefaultEndorsementConfig(nodeID string) *EndorsementConfig {
+	return &EndorsementConfig{
+		NodeID:      nodeID,
+		DataDir:     "./data/trust",
+		MaxDistance: EndorsementMaxDistance,
+		DefaultTTL:  EndorsementDefaultTTL,
+	}
+}
+
+// EndorsementManager 背书管理器（信任网）
+type EndorsementManager struct {
+	mu           sync.RWMutex
+	config       *EndorsementConfig
+	endorsements map[string]*Endorsement // ID -> Endorsement
+
+	// 回调
+	OnEndorsementAdded func(e *Endorsement)
+}
+
+// NewEndorsementManager 创建背书管理器
+func NewEndorsementManager(config *EndorsementConfig) (*EndorsementManager, error) {
+	if config == nil {
+		return nil, ErrNilEndorsementConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyEndorserID
+	}
+	if config.MaxDistance <= 0 {
+		config.MaxDistance = EndorsementMaxDistance
+	}
+	if config.DefaultTTL <= 0 {
+		config.DefaultTTL = EndorsementDefaultTTL
+	}
+
+	em := &EndorsementManager{
+		config:       config,
+		endorsements: make(map[string]*Endorsement),
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		em.load()
+	}
+
+	return em, nil
+}
+
+// Endorse 为其他节点的密钥签发背书
+func (em *EndorsementManager) Endorse(subject, subjectKey, statement string, ttl time.Duration) (*Endorsement, error) {
+	if subject == em.config.NodeID {
+		return nil, ErrSelfEndorsement
+	}
+	if subjectKey == "" {
+		return nil, ErrEmptySubjectKey
+	}
+	if em.config.SignFunc == nil {
+		return nil, ErrNoSignFunc
+	}
+	if ttl <= 0 {
+		ttl = em.config.DefaultTTL
+	}
+
+	now := time.Now()
+	e := &Endorsement{
+		Endorser:   em.config.NodeID,
+		Subject:    subject,
+		SubjectKey: subjectKey,
+		Statement:  statement,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	e.ID = endorsementID(e)
+
+	sig, err := em.config.SignFunc(e.SignData())
+	if err != nil {
+		return nil, fmt.Errorf("签名背书失败: %w", err)
+	}
+	e.Signature = sig
+
+	em.mu.Lock()
+	em.endorsements[e.ID] = e
+	em.save()
+	em.mu.Unlock()
+
+	if em.OnEndorsementAdded != nil {
+		em.OnEndorsementAdded(e)
+	}
+	return e, nil
+}
+
+// AddEndorsement 添加来自其他节点的背书（校验签名）
+// 撤销标记不在签名范围内，收到的副本一律按未撤销保存；已保存的背书不会被覆盖，本地撤销的背书也不会恢复。
+func (em *EndorsementManager) AddEndorsement(e *Endorsement) error {
+	if e == nil || e.Endorser == "" || e.Subject == "" {
+		return ErrInvalidEndorsement
+	}
+	if e.Endorser == e.Subject {
+		return ErrSelfEndorsement
+	}
+	if !time.Now().Before(e.ExpiresAt) {
+		return ErrEndorsementExpired
+	}
+	if em.config.VerifyFunc != nil {
+		ok, err := em.config.VerifyFunc(e.Endorser, e.SignData(), e.Signature)
+		if err != nil || !ok {
+			return ErrInvalidEndorsement
+		}
+	}
+
+	c := *e
+	c.ID = endorsementID(&c)
+	c.Revoked, c.RevokedAt = false, time.Time{}
+
+	em.mu.Lock()
+	if existing, ok := em.endorsements[c.ID]; ok {
+		em.mu.Unlock()
+		if existing.Revoked {
+			return ErrEndorsementRevoked
+		}
+		return nil
+	}
+	em.endorsements[c.ID] = &c
+	em.save()
+	em.mu.Unlock()
+
+	if em.OnEndorsementAdded != nil {
+		em.OnEndorsementAdded(&c)
+	}
+	return nil
+}
+
+// Revoke 撤销本节点签发的背书
+func (em *EndorsementManager) Revoke(id string) error {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	e, ok := em.endorsements[id]
+	if !ok {
+		return ErrEndorsementNotFound
+	}
+	if e.Endorser != em.config.NodeID {
+		return ErrNotEndorser
+	}
+	e.Revoked = true
+	e.RevokedAt = time.Now()
+	em.save()
+	return nil
+}
+
+// GetEndorsementsFor 获取某节点收到的有效背书
+func (em *EndorsementManager) GetEndorsementsFor(subject string) []*Endorsement {
+	return em.filter(func(e *Endorsement) bool { return e.Subject == subject })
+}
+
+// GetEndorsementsBy 获取某节点签发的有效背书
+func (em *EndorsementManager) GetEndorsementsBy(endorser string) []*Endorsement {
+	return em.filter(func(e *Endorsement) bool { return e.Endorser == endorser })
+}
+
+func (em *EndorsementManager) filter(match func(e *Endorsement) bool) []*Endorsement {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]*Endorsement, 0)
+	for _, e := range em.endorsements {
+		if e.Valid(now) && match(e) {
+			c := *e
+			result = append(result, &c)
+		}
+	}
+	return result
+}
+
+// Distance 计算背书图中 from 到 to 的最短背书距离，超过最大深度返回 -1
+func (em *EndorsementManager) Distance(from, to string) int {
+	if from == to {
+		return 0
+	}
+
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	graph := em.graphLocked(time.Now())
+	visited := map[string]bool{from: true}
+	frontier := []string{from}
+	for depth := 1; depth <= em.config.MaxDistance && len(frontier) > 0; depth++ {
+		var next []string
+		for _, node := range frontier {
+			for subject := range graph[node] {
+				if subject == to {
+					return depth
+				}
+				if !visited[subject] {
+					visited[subject] = true
+					next = append(next, subject)
+				}
+			}
+		}
+		frontier = next
+	}
+	return -1
+}
+
+// TrustDistance 本节点到目标节点的背书距离
+func (em *EndorsementManager) TrustDistance(nodeID string) int {
+	return em.Distance(em.config.NodeID, nodeID)
+}
+
+// ToleranceMultiplier 根据背书距离放宽耐受值：距离越近倍数越高
+func (em *EndorsementManager) ToleranceMultiplier(nodeID string) float64 {
+	switch em.TrustDistance(nodeID) {
+	case 0, 1:
+		return 2.0
+	case 2:
+		return 1.5
+	case 3:
+		return 1.2
+	default:
+		return 1.0
+	}
+}
+
+// QuarantineMultiplier 根据背书距离缩短隔离时长：距离越近倍数越低
+func (em *EndorsementManager) QuarantineMultiplier(nodeID string) float64 {
+	switch em.TrustDistance(nodeID) {
+	case 0, 1:
+		return 0.25
+	case 2:
+		return 0.5
+	case 3:
+		return 0.75
+	default:
+		return 1.0
+	}
+}
+
+// graphLocked 构建有效背书的邻接表: endorser -> subject set
+func (em *EndorsementManager) graphLocked(now time.Time) map[string]map[string]bool {
+	graph := make(map[string]map[string]bool)
+	for _, e := range em.endorsements {
+		if !e.Valid(now) {
+			continue
+		}
+		if graph[e.Endorser] == nil {
+			graph[e.Endorser] = make(map[string]bool)
+		}
+		graph[e.Endorser][e.Subject] = true
+	}
+	return graph
+}
+
+// GetStats 获取统计信息
+func (em *EndorsementManager) GetStats() map[string]interface{} {
+	em.mu.RLock()
+	defer em.mu.RUnlock()
+
+	now := time.Now()
+	valid, revoked, issued := 0, 0, 0
+	for _, e := range em.endorsements {
+		if e.Revoked {
+			revoked++
+		} else if e.Valid(now) {
+			valid++
+		}
+		if e.Endorser == em.config.NodeID {
+			issued++
+		}
+	}
+	return map[string]interface{}{
+		"total":   len(em.endorsements),
+		"valid":   valid,
+		"revoked": revoked,
+		"issued":  issued,
+	}
+}
+
+func endorsementID(e *Endorsement) string {
+	hash := sha256.Sum256(e.SignData())
+	return hex.EncodeToString(hash[:16])
+}
+
+func (em *EndorsementManager) save() {
+	if em.config.DataDir == "" {
+		return
+	}
+	list := make([]*Endorsement, 0, len(em.endorsements))
+	for _, e := range em.endorsements {
+		list = append(list, e)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(em.config.DataDir, "endorsements.json"), data, 0644)
+}
+
+func (em *EndorsementManager) load() {
+	data, err := os.ReadFile(filepath.Join(em.config.DataDir, "endorsements.json"))
+	if err != nil {
+		return
+	}
+	var list []*Endorsement
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, e := range list {
+		em.endorsements[e.ID] = e
+	}
+}
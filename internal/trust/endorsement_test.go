@@ -0,0 +1,199 @@
+package trust
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakeSigner 测试用签名：签名 = 节点ID + 数据
+func fakeSigner(nodeID string) func([]byte) ([]byte, error) {
+	return func(data []byte) ([]byte, error) {
+		return append([]byte(nodeID+":"), data...), nil
+	}
+}
+
+func fakeVerify(endorserID string, data, sig []byte) (bool, error) {
+	return bytes.Equal(sig, append([]byte(endorserID+":"), data...)), nil
+}
+
+func newTestEndorsementManager(t *testing.T, nodeID string) *EndorsementManager {
+	cfg := DefaultEndorsementConfig(nodeID)
+	cfg.DataDir = t.TempDir()
+	cfg.SignFunc = fakeSigner(nodeID)
+	cfg.VerifyFunc = fakeVerify
+	em, err := NewEndorsementManager(cfg)
+	if err != nil {
+		t.Fatalf("failed to create endorsement manager: %v", err)
+	}
+	return em
+}
+
+// peerEndorsement 构造由其他节点签发的背书
+func peerEndorsement(endorser, subject string) *Endorsement {
+	now := time.Now()
+	e := &Endorsement{
+		Endorser:   endorser,
+		Subject:    subject,
+		SubjectKey: "key-" + subject,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	e.Signature, _ = fakeSigner(endorser)(e.SignData())
+	return e
+}
+
+func TestNewEndorsementManager(t *testing.T) {
+	if _, err := NewEndorsementManager(nil); err != ErrNilEndorsementConfig {
+		t.Errorf("expected ErrNilEndorsementConfig, got %v", err)
+	}
+	if _, err := NewEndorsementManager(&EndorsementConfig{}); err != ErrEmptyEndorserID {
+		t.Errorf("expected ErrEmptyEndorserID, got %v", err)
+	}
+}
+
+func TestEndorse(t *testing.T) {
+	em := newTestEndorsementManager(t, "A")
+
+	if _, err := em.Endorse("A", "key-A", "", 0); err != ErrSelfEndorsement {
+		t.Errorf("expected ErrSelfEndorsement, got %v", err)
+	}
+	if _, err := em.Endorse("B", "", "", 0); err != ErrEmptySubjectKey {
+		t.Errorf("expected ErrEmptySubjectKey, got %v", err)
+	}
+
+	e, err := em.Endorse("B", "key-B", "verified operator off-band", 0)
+	if err != nil {
+		t.Fatalf("Endorse failed: %v", err)
+	}
+	if ok, _ := fakeVerify("A", e.SignData(), e.Signature); !ok {
+		t.Error("endorsement signature should verify")
+	}
+	if got := len(em.GetEndorsementsFor("B")); got != 1 {
+		t.Errorf("expected 1 endorsement for B, got %d", got)
+	}
+	if em.TrustDistance("B") != 1 {
+		t.Errorf("expected distance 1, got %d", em.TrustDistance("B"))
+	}
+
+	if err := em.Revoke(e.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if em.TrustDistance("B") != -1 {
+		t.Error("revoked endorsement should not count")
+	}
+}
+
+func TestAddEndorsement(t *testing.T) {
+	em := newTestEndorsementManager(t, "A")
+
+	if err := em.AddEndorsement(peerEndorsement("B", "C")); err != nil {
+		t.Fatalf("AddEndorsement failed: %v", err)
+	}
+
+	forged := peerEndorsement("B", "D")
+	forged.Signature = []byte("forged")
+	if err := em.AddEndorsement(forged); err != ErrInvalidEndorsement {
+		t.Errorf("expected ErrInvalidEndorsement, got %v", err)
+	}
+
+	expired := peerEndorsement("B", "E")
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := em.AddEndorsement(expired); err != ErrEndorsementExpired {
+		t.Errorf("expected ErrEndorsementExpired, got %v", err)
+	}
+
+	// 不能撤销他人的背书
+	list := em.GetEndorsementsBy("B")
+	if len(list) != 1 {
+		t.Fatalf("expected 1 endorsement by B, got %d", len(list))
+	}
+	if err := em.Revoke(list[0].ID); err != ErrNotEndorser {
+		t.Errorf("expected ErrNotEndorser, got %v", err)
+	}
+}
+
+func TestAddEndorsementIgnoresRevokedFlag(t *testing.T) {
+	em := newTestEndorsementManager(t, "A")
+
+	// 撤销标记不在签名内，收到的副本按未撤销保存
+	flagged := peerEndorsement("B", "C")
+	flagged.Revoked = true
+	if err := em.AddEndorsement(flagged); err != nil {
+		t.Fatalf("AddEndorsement failed: %v", err)
+	}
+	if list := em.GetEndorsementsFor("C"); len(list) != 1 {
+		t.Errorf("incoming revoked flag should be ignored, got %d endorsements", len(list))
+	}
+
+	// 本地撤销的背书不会被重放的副本恢复
+	own, err := em.Endorse("D", "key-D", "", 0)
+	if err != nil {
+		t.Fatalf("Endorse failed: %v", err)
+	}
+	if err := em.Revoke(own.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := em.AddEndorsement(own); err != ErrEndorsementRevoked {
+		t.Errorf("expected ErrEndorsementRevoked, got %v", err)
+	}
+	if list := em.GetEndorsementsFor("D"); len(list) != 0 {
+		t.Errorf("revoked endorsement should stay revoked, got %d", len(list))
+	}
+}
+
+func TestEndorsementDistance(t *testing.T) {
+	em := newTestEndorsementManager(t, "A")
+
+	// A -> B -> C -> D -> E
+	em.Endorse("B", "key-B", "", 0)
+	em.AddEndorsement(peerEndorsement("B", "C"))
+	em.AddEndorsement(peerEndorsement("C", "D"))
+	em.AddEndorsement(peerEndorsement("D", "E"))
+
+	tests := []struct {
+		node       string
+		distance   int
+		tolerance  float64
+		quarantine float64
+	}{
+		{"B", 1, 2.0, 0.25},
+		{"C", 2, 1.5, 0.5},
+		{"D", 3, 1.2, 0.75},
+		{"E", -1, 1.0, 1.0}, // 超过最大深度
+		{"X", -1, 1.0, 1.0},
+	}
+	for _, tt := range tests {
+		if got := em.TrustDistance(tt.node); got != tt.distance {
+			t.Errorf("TrustDistance(%s) = %d, want %d", tt.node, got, tt.distance)
+		}
+		if got := em.ToleranceMultiplier(tt.node); got != tt.tolerance {
+			t.Errorf("ToleranceMultiplier(%s) = %f, want %f", tt.node, got, tt.tolerance)
+		}
+		if got := em.QuarantineMultiplier(tt.node); got != tt.quarantine {
+			t.Errorf("QuarantineMultiplier(%s) = %f, want %f", tt.node, got, tt.quarantine)
+		}
+	}
+
+	// 背书是有向的
+	if em.Distance("C", "B") != -1 {
+		t.Error("endorsement graph should be directed")
+	}
+}
+
+func TestEndorsementPersistence(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultEndorsementConfig("A")
+	cfg.DataDir = dir
+	cfg.SignFunc = fakeSigner("A")
+	em, _ := NewEndorsementManager(cfg)
+	em.Endorse("B", "key-B", "", 0)
+
+	em2, _ := NewEndorsementManager(cfg)
+	if em2.TrustDistance("B") != 1 {
+		t.Error("endorsements should be restored after restart")
+	}
+	if stats := em2.GetStats(); stats["issued"] != 1 {
+		t.Errorf("unexpected stats: %v", stats)
+	}
+}
@@ -0,0 +1,546 @@
+package trust
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 外部身份证明：新节点在自己控制的外部账号上发布由节点私钥签名的证明令牌
+// （GitHub gist、Moltbook 帖子或域名 TXT 记录），节点软件抓取并校验后
+// 给予少量起始声誉并缩短隔离时长。证明记录可被其他节点独立重新校验。
+
+// ProofKind 外部身份类型
+type ProofKind string
+
+const (
+	ProofGitHubGist ProofKind = "github_gist" // 公开 gist，URL 路径须包含 GitHub 用户名
+	ProofMoltbook   ProofKind = "moltbook"    // Moltbook 帖子，URL 路径须包含用户名
+	ProofDNSTXT     ProofKind = "dns_txt"     // 域名 _daan.<domain> 的 TXT 记录
+)
+
+// ProofStatus 证明校验状态
+type ProofStatus string
+
+const (
+	ProofPending  ProofStatus = "pending"  // 已签发，等待发布后校验
+	ProofVerified ProofStatus = "verified" // 最近一次校验通过
+	ProofFailed   ProofStatus = "failed"   // 最近一次校验失败
+)
+
+// ProofTokenPrefix 证明令牌前缀
+const ProofTokenPrefix = "daan-identity-proof:v1"
+
+var (
+	ErrNilProofConfig    = errors.New("identity proof config cannot be nil")
+	ErrUnknownProofKind  = errors.New("unknown identity proof kind")
+	ErrEmptyProofHandle  = errors.New("identity proof handle cannot be empty")
+	ErrInvalidProofURL   = errors.New("proof location does not belong to the claimed handle")
+	ErrProofNotFound     = errors.New("identity proof not found")
+	ErrProofTokenMissing = errors.New("proof token not found at location")
+	ErrInvalidProofSig   = errors.New("invalid identity proof signature")
+	ErrProofHandleTaken  = errors.New("identity proof handle already verified for another node")
+)
+
+// proofHosts 各类证明允许的 URL 主机
+var proofHosts = map[ProofKind][]string{
+	ProofGitHubGist: {"gist.github.com", "gist.githubusercontent.com"},
+	ProofMoltbook:   {"moltbook.com", "www.moltbook.com"},
+}
+
+// IdentityProof 外部身份证明记录
+type IdentityProof struct {
+	ID            string      `json:"id"`
+	NodeID        string      `json:"node_id"`
+	Kind          ProofKind   `json:"kind"`
+	Handle        string      `json:"handle"`   // GitHub/Moltbook 用户名或域名
+	Location      string      `json:"location"` // gist/帖子 URL；DNS 证明为空
+	CreatedAt     time.Time   `json:"created_at"`
+	Signature     []byte      `json:"signature"`
+	Status        ProofStatus `json:"status"`
+	VerifiedAt    time.Time   `json:"verified_at,omitempty"`
+	LastCheckedAt time.Time   `json:"last_checked_at,omitempty"`
+	LastError     string      `json:"last_error,omitempty"`
+
+	StarterGranted float64 `json:"starter_granted,omitempty"` // 首次验证时已计入声誉的起始声誉
+}
+
+// SignData 证明签名的原始数据
+func (p *IdentityProof) SignData() []byte {
+	return []byte(fmt.Sprintf("identity-proof|%s|%s|%s|%d",
+		p.NodeID, p.Kind, strings.ToLower(p.Handle), p.CreatedAt.Unix()))
+}
+
+// Token 需要发布到外部账号上的证明令牌
+func (p *IdentityProof) Token() string {
+	return fmt.Sprintf("%s;node=%s;kind=%s;handle=%s;ts=%d;sig=%s",
+		ProofTokenPrefix, p.NodeID, p.Kind, strings.ToLower(p.Handle), p.CreatedAt.Unix(), hex.EncodeToString(p.Signature))
+}
+
+// DNSName DNS 证明需要设置 TXT 记录的域名
+func (p *IdentityProof) DNSName() string {
+	if p.Kind != ProofDNSTXT {
+		return ""
+	}
+	return "_daan." + strings.TrimSuffix(strings.ToLower(p.Handle), ".")
+}
+
+// IdentityProofConfig 外部身份证明配置
+type IdentityProofConfig struct {
+	NodeID  string
+	DataDir string
+
+	StarterReputation    float64       // 每类已验证证明给予的起始声誉
+	MaxStarterReputation float64       // 起始声誉上限
+	QuarantineDiscount   float64       // 每类已验证证明缩短的隔离时长比例
+	MinQuarantineFactor  float64       // 隔离时长倍数下限
+	FetchTimeout         time.Duration // 抓取证明内容的超时
+	MaxFetchSize         int64         // 抓取内容的最大字节数
+
+	// 签名函数（本节点私钥）
+	SignFunc func(data []byte) ([]byte, error)
+	// 验签函数（按节点ID提取公钥）
+	VerifyFunc func(nodeID string, data, signature []byte) (bool, error)
+	// 抓取 URL 内容（默认 HTTP GET）
+	FetchFunc func(ctx context.Context, rawURL string) (string, error)
+	// 查询 TXT 记录（默认系统解析器）
+	LookupTXTFunc func(ctx context.Context, name string) ([]string, error)
+}
+
+// DefaultIdentityProofConfig 返回默认配置
+func DefaultIdentityProofConfig(nodeID string) *IdentityProofConfig {
+	return &IdentityProofConfig{
+		NodeID:               nodeID,
+		DataDir:              "./data/trust",
+		StarterReputation:    5,
+		MaxStarterReputation: 15,
+		QuarantineDiscount:   0.2,
+		MinQuarantineFactor:  0.5,
+		FetchTimeout:         10 * time.Second,
+		MaxFetchSize:         64 * 1024,
+	}
+}
+
+// IdentityProofManager 外部身份证明管理器
+type IdentityProofManager struct {
+	mu     sync.RWMutex
+	config *IdentityProofConfig
+	proofs map[string]*IdentityProof // ID -> Proof
+
+	// 回调
+	OnProofVerified func(p *IdentityProof)
+}
+
+// NewIdentityProofManager 创建外部身份证明管理器
+func NewIdentityProofManager(config *IdentityProofConfig) (*IdentityProofManager, error) {
+	if config == nil {
+		return nil, ErrNilProofConfig
+	}
+	if config.NodeID == "" {
+		return nil, ErrEmptyEndorserID
+	}
+	if config.FetchFunc == nil {
+		config.FetchFunc = httpFetcher(config.MaxFetchSize)
+	}
+	if config.LookupTXTFunc == nil {
+		config.LookupTXTFunc = net.DefaultResolver.LookupTXT
+	}
+
+	pm := &IdentityProofManager{
+		config: config,
+		proofs: make(map[string]*IdentityProof),
+	}
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			return nil, err
+		}
+		pm.load()
+	}
+	return pm, nil
+}
+
+// CreateProof 为本节点签发一份待发布的身份证明
+// 调用方把返回证明的 Token() 发布到 location（DNS 证明发布到 DNSName() 的 TXT 记录）后再调用 Verify。
+func (pm *IdentityProofManager) CreateProof(kind ProofKind, handle, location string) (*IdentityProof, error) {
+	if pm.config.SignFunc == nil {
+		return nil, ErrNoSignFunc
+	}
+	p := &IdentityProof{
+		NodeID:    pm.config.NodeID,
+		Kind:      kind,
+		Handle:    strings.TrimSpace(handle),
+		Location:  strings.TrimSpace(location),
+		CreatedAt: time.Now(),
+		Status:    ProofPending,
+	}
+	if err := checkProofShape(p); err != nil {
+		return nil, err
+	}
+	pm.mu.RLock()
+	owner := pm.handleOwnerLocked(p.Kind, p.Handle, p.NodeID)
+	pm.mu.RUnlock()
+	if owner != "" {
+		return nil, fmt.Errorf("%w: %s", ErrProofHandleTaken, owner)
+	}
+
+	sig, err := pm.config.SignFunc(p.SignData())
+	if err != nil {
+		return nil, fmt.Errorf("签名身份证明失败: %w", err)
+	}
+	p.Signature = sig
+	p.ID = proofID(p)
+
+	pm.mu.Lock()
+	pm.proofs[p.ID] = p
+	pm.save()
+	pm.mu.Unlock()
+
+	c := *p
+	return &c, nil
+}
+
+// ImportProof 导入其他节点公布的证明记录，校验通过才保存
+func (pm *IdentityProofManager) ImportProof(ctx context.Context, p *IdentityProof) (*IdentityProof, error) {
+	if p == nil {
+		return nil, ErrProofNotFound
+	}
+	c := *p
+	c.ID = proofID(&c)
+	c.Status = ProofPending
+	c.VerifiedAt = time.Time{}
+	if err := checkProofShape(&c); err != nil {
+		return nil, err
+	}
+	if err := pm.check(ctx, &c); err != nil {
+		return nil, err
+	}
+
+	pm.mu.Lock()
+	if owner := pm.handleOwnerLocked(c.Kind, c.Handle, c.NodeID); owner != "" {
+		pm.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrProofHandleTaken, owner)
+	}
+	if existing, ok := pm.proofs[c.ID]; ok {
+		c.StarterGranted = existing.StarterGranted
+	} else {
+		c.StarterGranted = 0
+	}
+	c.Status = ProofVerified
+	c.VerifiedAt = c.LastCheckedAt
+	pm.proofs[c.ID] = &c
+	pm.save()
+	pm.mu.Unlock()
+
+	if pm.OnProofVerified != nil {
+		pm.OnProofVerified(&c)
+	}
+	result := c
+	return &result, nil
+}
+
+// Verify 重新抓取并校验证明，更新其状态
+// 本节点签发的证明在发布后调用以完成验证；其他节点的证明可随时重新校验。
+func (pm *IdentityProofManager) Verify(ctx context.Context, id string) (*IdentityProof, error) {
+	pm.mu.RLock()
+	stored, ok := pm.proofs[id]
+	var c IdentityProof
+	if ok {
+		c = *stored
+	}
+	pm.mu.RUnlock()
+	if !ok {
+		return nil, ErrProofNotFound
+	}
+
+	checkErr := pm.check(ctx, &c)
+
+	pm.mu.Lock()
+	stored, ok = pm.proofs[id]
+	if !ok {
+		pm.mu.Unlock()
+		return nil, ErrProofNotFound
+	}
+	stored.LastCheckedAt = c.LastCheckedAt
+	wasVerified := stored.Status == ProofVerified
+	if checkErr == nil {
+		if owner := pm.handleOwnerLocked(stored.Kind, stored.Handle, stored.NodeID); owner != "" {
+			checkErr = fmt.Errorf("%w: %s", ErrProofHandleTaken, owner)
+		}
+	}
+	if checkErr != nil {
+		stored.Status = ProofFailed
+		stored.LastError = checkErr.Error()
+	} else {
+		stored.Status = ProofVerified
+		stored.VerifiedAt = c.LastCheckedAt
+		stored.LastError = ""
+	}
+	result := *stored
+	pm.save()
+	pm.mu.Unlock()
+
+	if checkErr == nil && !wasVerified && pm.OnProofVerified != nil {
+		pm.OnProofVerified(&result)
+	}
+	return &result, checkErr
+}
+
+// ReverifyAll 重新校验全部证明（用于定期巡检，撤下的证明会变为 failed）
+func (pm *IdentityProofManager) ReverifyAll(ctx context.Context) (verified, failed int) {
+	pm.mu.RLock()
+	ids := make([]string, 0, len(pm.proofs))
+	for id := range pm.proofs {
+		ids = append(ids, id)
+	}
+	pm.mu.RUnlock()
+
+	for _, id := range ids {
+		if _, err := pm.Verify(ctx, id); err != nil {
+			failed++
+		} else {
+			verified++
+		}
+	}
+	return verified, failed
+}
+
+// GetProofsFor 获取节点的全部证明记录，按创建时间排序
+func (pm *IdentityProofManager) GetProofsFor(nodeID string) []*IdentityProof {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]*IdentityProof, 0)
+	for _, p := range pm.proofs {
+		if p.NodeID == nodeID {
+			c := *p
+			result = append(result, &c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// handleOwnerLocked 已用同一外部身份（类型和 handle，不区分大小写）通过验证的其他节点，
+// 没有时返回空。一个外部身份只能为一个节点作证，先通过验证的节点占有（调用方持有锁）
+func (pm *IdentityProofManager) handleOwnerLocked(kind ProofKind, handle, except string) string {
+	key := strings.TrimSuffix(strings.ToLower(handle), ".")
+	for _, p := range pm.proofs {
+		if p.Status == ProofVerified && p.Kind == kind && p.NodeID != except &&
+			strings.TrimSuffix(strings.ToLower(p.Handle), ".") == key {
+			return p.NodeID
+		}
+	}
+	return ""
+}
+
+// ClaimStarterReputation 节点尚未计入声誉的起始声誉，返回后记为已计入
+// 每类证明首次通过验证时计入一次，证明失效后重新验证不再重复计入；总额不超过上限。
+func (pm *IdentityProofManager) ClaimStarterReputation(nodeID string) float64 {
+	target := pm.StarterReputation(nodeID)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	granted := 0.0
+	var holder *IdentityProof
+	for _, p := range pm.proofs {
+		if p.NodeID != nodeID {
+			continue
+		}
+		granted += p.StarterGranted
+		if p.Status == ProofVerified && (holder == nil || p.ID < holder.ID) {
+			holder = p
+		}
+	}
+	delta := target - granted
+	if delta <= 0 || holder == nil {
+		return 0
+	}
+	holder.StarterGranted += delta
+	pm.save()
+	return delta
+}
+
+// verifiedKinds 节点已验证的证明类型数（同类多份只计一次）
+func (pm *IdentityProofManager) verifiedKinds(nodeID string) int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	kinds := make(map[ProofKind]bool)
+	for _, p := range pm.proofs {
+		if p.NodeID == nodeID && p.Status == ProofVerified {
+			kinds[p.Kind] = true
+		}
+	}
+	return len(kinds)
+}
+
+// StarterReputation 按已验证的证明类型给予的起始声誉
+func (pm *IdentityProofManager) StarterReputation(nodeID string) float64 {
+	rep := float64(pm.verifiedKinds(nodeID)) * pm.config.StarterReputation
+	if pm.config.MaxStarterReputation > 0 && rep > pm.config.MaxStarterReputation {
+		rep = pm.config.MaxStarterReputation
+	}
+	return rep
+}
+
+// QuarantineMultiplier 按已验证的证明类型缩短隔离时长
+func (pm *IdentityProofManager) QuarantineMultiplier(nodeID string) float64 {
+	factor := 1.0 - float64(pm.verifiedKinds(nodeID))*pm.config.QuarantineDiscount
+	if factor < pm.config.MinQuarantineFactor {
+		factor = pm.config.MinQuarantineFactor
+	}
+	return factor
+}
+
+// check 校验签名、令牌和外部内容，成功或失败都会更新 LastCheckedAt
+func (pm *IdentityProofManager) check(ctx context.Context, p *IdentityProof) error {
+	p.LastCheckedAt = time.Now()
+
+	if pm.config.VerifyFunc != nil {
+		ok, err := pm.config.VerifyFunc(p.NodeID, p.SignData(), p.Signature)
+		if err != nil || !ok {
+			return ErrInvalidProofSig
+		}
+	}
+
+	if pm.config.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pm.config.FetchTimeout)
+		defer cancel()
+	}
+
+	token := p.Token()
+	switch p.Kind {
+	case ProofDNSTXT:
+		records, err := pm.config.LookupTXTFunc(ctx, p.DNSName())
+		if err != nil {
+			return fmt.Errorf("lookup %s: %w", p.DNSName(), err)
+		}
+		for _, r := range records {
+			if strings.TrimSpace(r) == token {
+				return nil
+			}
+		}
+	default:
+		content, err := pm.config.FetchFunc(ctx, p.Location)
+		if err != nil {
+			return fmt.Errorf("fetch %s: %w", p.Location, err)
+		}
+		if strings.Contains(content, token) {
+			return nil
+		}
+	}
+	return ErrProofTokenMissing
+}
+
+// checkProofShape 校验证明类型、账号与位置是否对应
+func checkProofShape(p *IdentityProof) error {
+	if p.Handle == "" {
+		return ErrEmptyProofHandle
+	}
+	switch p.Kind {
+	case ProofDNSTXT:
+		if strings.ContainsAny(p.Handle, "/: ") {
+			return fmt.Errorf("%w: %q is not a domain", ErrInvalidProofURL, p.Handle)
+		}
+		return nil
+	case ProofGitHubGist, ProofMoltbook:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownProofKind, p.Kind)
+	}
+
+	u, err := url.Parse(p.Location)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("%w: %s must be an https URL", ErrInvalidProofURL, p.Location)
+	}
+	hostOK := false
+	for _, h := range proofHosts[p.Kind] {
+		if strings.EqualFold(u.Hostname(), h) {
+			hostOK = true
+		}
+	}
+	if !hostOK {
+		return fmt.Errorf("%w: host %s not allowed for %s", ErrInvalidProofURL, u.Hostname(), p.Kind)
+	}
+	// 账号绑定：URL 路径中须有与用户名一致的一段（如 /alice/<gist-id>、/u/alice/...）
+	for _, seg := range strings.Split(u.Path, "/") {
+		if strings.EqualFold(seg, p.Handle) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not under %s", ErrInvalidProofURL, p.Location, p.Handle)
+}
+
+func proofID(p *IdentityProof) string {
+	hash := sha256.Sum256(append(p.SignData(), []byte("|"+p.Location)...))
+	return hex.EncodeToString(hash[:16])
+}
+
+// httpFetcher 默认的 HTTPS 抓取函数，只读取前 maxSize 字节
+func httpFetcher(maxSize int64) func(ctx context.Context, rawURL string) (string, error) {
+	return func(ctx context.Context, rawURL string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", errors.New("unexpected status " + strconv.Itoa(resp.StatusCode))
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+func (pm *IdentityProofManager) save() {
+	if pm.config.DataDir == "" {
+		return
+	}
+	list := make([]*IdentityProof, 0, len(pm.proofs))
+	for _, p := range pm.proofs {
+		list = append(list, p)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(pm.config.DataDir, "identity_proofs.json"), data, 0644)
+}
+
+func (pm *IdentityProofManager) load() {
+	data, err := os.ReadFile(filepath.Join(pm.config.DataDir, "identity_proofs.json"))
+	if err != nil {
+		return
+	}
+	var list []*IdentityProof
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, p := range list {
+		pm.proofs[p.ID] = p
+	}
+}
@@ -0,0 +1,173 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeWeb 测试用的外部内容：URL -> 页面内容，域名 -> TXT 记录
+type fakeWeb struct {
+	pages map[string]string
+	txt   map[string][]string
+}
+
+func (w *fakeWeb) fetch(ctx context.Context, rawURL string) (string, error) {
+	if page, ok := w.pages[rawURL]; ok {
+		return page, nil
+	}
+	return "", errors.New("404")
+}
+
+func (w *fakeWeb) lookupTXT(ctx context.Context, name string) ([]string, error) {
+	return w.txt[name], nil
+}
+
+func newTestProofManager(t *testing.T, nodeID string, web *fakeWeb) *IdentityProofManager {
+	cfg := DefaultIdentityProofConfig(nodeID)
+	cfg.DataDir = t.TempDir()
+	cfg.SignFunc = fakeSigner(nodeID)
+	cfg.VerifyFunc = fakeVerify
+	cfg.FetchFunc = web.fetch
+	cfg.LookupTXTFunc = web.lookupTXT
+	pm, err := NewIdentityProofManager(cfg)
+	if err != nil {
+		t.Fatalf("failed to create proof manager: %v", err)
+	}
+	return pm
+}
+
+func TestCreateProofShape(t *testing.T) {
+	pm := newTestProofManager(t, "A", &fakeWeb{})
+
+	cases := []struct {
+		kind     ProofKind
+		handle   string
+		location string
+		want     error
+	}{
+		{"twitter", "alice", "https://x.com/alice", ErrUnknownProofKind},
+		{ProofGitHubGist, "", "https://gist.github.com/alice/1", ErrEmptyProofHandle},
+		{ProofGitHubGist, "alice", "https://gist.github.com/mallory/1", ErrInvalidProofURL},
+		{ProofGitHubGist, "alice", "https://evil.example/alice/1", ErrInvalidProofURL},
+		{ProofMoltbook, "alice", "http://moltbook.com/u/alice/post/1", ErrInvalidProofURL},
+		{ProofDNSTXT, "https://example.org", "", ErrInvalidProofURL},
+	}
+	for _, c := range cases {
+		if _, err := pm.CreateProof(c.kind, c.handle, c.location); !errors.Is(err, c.want) {
+			t.Errorf("CreateProof(%s, %q, %q): expected %v, got %v", c.kind, c.handle, c.location, c.want, err)
+		}
+	}
+}
+
+func TestVerifyProofs(t *testing.T) {
+	web := &fakeWeb{pages: map[string]string{}, txt: map[string][]string{}}
+	pm := newTestProofManager(t, "A", web)
+
+	gist, err := pm.CreateProof(ProofGitHubGist, "Alice", "https://gist.github.com/alice/abc123")
+	if err != nil {
+		t.Fatalf("CreateProof failed: %v", err)
+	}
+	if gist.Status != ProofPending || pm.StarterReputation("A") != 0 {
+		t.Fatalf("unpublished proof must not grant reputation")
+	}
+
+	// 页面存在但尚未发布令牌
+	web.pages[gist.Location] = "hello world"
+	if _, err := pm.Verify(context.Background(), gist.ID); !errors.Is(err, ErrProofTokenMissing) {
+		t.Errorf("expected ErrProofTokenMissing, got %v", err)
+	}
+
+	web.pages[gist.Location] = "my node: " + gist.Token()
+	verified, err := pm.Verify(context.Background(), gist.ID)
+	if err != nil || verified.Status != ProofVerified {
+		t.Fatalf("expected verified proof, got %v %v", verified, err)
+	}
+
+	dns, _ := pm.CreateProof(ProofDNSTXT, "example.org", "")
+	web.txt[dns.DNSName()] = []string{"v=spf1 -all", dns.Token()}
+	if _, err := pm.Verify(context.Background(), dns.ID); err != nil {
+		t.Fatalf("DNS verify failed: %v", err)
+	}
+
+	if got := pm.StarterReputation("A"); got != 10 {
+		t.Errorf("expected starter reputation 10, got %v", got)
+	}
+	if got := pm.ClaimStarterReputation("A"); got != 10 {
+		t.Errorf("expected to claim 10, got %v", got)
+	}
+	if got := pm.ClaimStarterReputation("A"); got != 0 {
+		t.Errorf("starter reputation must be claimed once, got %v", got)
+	}
+	if got := pm.QuarantineMultiplier("A"); got < 0.59 || got > 0.61 {
+		t.Errorf("expected quarantine multiplier 0.6, got %v", got)
+	}
+
+	t.Run("withdrawn proof fails reverify", func(t *testing.T) {
+		delete(web.pages, gist.Location)
+		if verified, failed := pm.ReverifyAll(context.Background()); verified != 1 || failed != 1 {
+			t.Errorf("expected 1 verified and 1 failed, got %d/%d", verified, failed)
+		}
+		if got := pm.StarterReputation("A"); got != 5 {
+			t.Errorf("expected starter reputation 5 after withdrawal, got %v", got)
+		}
+
+		// 重新发布后再次通过验证，起始声誉不重复计入
+		web.pages[gist.Location] = gist.Token()
+		if _, err := pm.Verify(context.Background(), gist.ID); err != nil {
+			t.Fatalf("reverify failed: %v", err)
+		}
+		if got := pm.ClaimStarterReputation("A"); got != 0 {
+			t.Errorf("re-verified proof must not grant again, got %v", got)
+		}
+	})
+
+	t.Run("persisted", func(t *testing.T) {
+		reloaded, _ := NewIdentityProofManager(pm.config)
+		if got := len(reloaded.GetProofsFor("A")); got != 2 {
+			t.Errorf("expected 2 persisted proofs, got %d", got)
+		}
+	})
+}
+
+func TestImportProof(t *testing.T) {
+	web := &fakeWeb{pages: map[string]string{}, txt: map[string][]string{}}
+	author := newTestProofManager(t, "A", web)
+	peer := newTestProofManager(t, "B", web)
+
+	p, _ := author.CreateProof(ProofMoltbook, "alice", "https://moltbook.com/u/alice/post/7")
+	web.pages[p.Location] = p.Token()
+
+	t.Run("forged signature", func(t *testing.T) {
+		forged := *p
+		forged.NodeID = "C"
+		if _, err := peer.ImportProof(context.Background(), &forged); !errors.Is(err, ErrInvalidProofSig) {
+			t.Errorf("expected ErrInvalidProofSig, got %v", err)
+		}
+	})
+
+	imported, err := peer.ImportProof(context.Background(), p)
+	if err != nil {
+		t.Fatalf("ImportProof failed: %v", err)
+	}
+	if imported.Status != ProofVerified || peer.StarterReputation("A") != 5 {
+		t.Errorf("expected imported proof to count, got %s", imported.Status)
+	}
+
+	// 同一外部身份只能为一个节点作证
+	sybil := newTestProofManager(t, "C", web)
+	q, err := sybil.CreateProof(ProofMoltbook, "Alice", "https://moltbook.com/u/alice/post/8")
+	if err != nil {
+		t.Fatalf("CreateProof failed: %v", err)
+	}
+	web.pages[q.Location] = q.Token()
+	if _, err := peer.ImportProof(context.Background(), q); !errors.Is(err, ErrProofHandleTaken) {
+		t.Errorf("expected ErrProofHandleTaken, got %v", err)
+	}
+	if _, err := peer.CreateProof(ProofMoltbook, "alice", "https://moltbook.com/u/alice/post/9"); !errors.Is(err, ErrProofHandleTaken) {
+		t.Errorf("expected ErrProofHandleTaken for a local proof, got %v", err)
+	}
+	if peer.StarterReputation("C") != 0 {
+		t.Error("a rejected proof must not count")
+	}
+}
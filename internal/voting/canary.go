@@ -0,0 +1,509 @@
+// Package voting - canary.go
+// 参数变更的金丝雀发布：带试运行期的参数提案通过后，先只在自愿参与的金丝雀节点上生效。
+// 试运行结束时每个节点报告试运行期间的请求出错数和任务争议数（签名后广播），
+// 各节点汇总报告，比较金丝雀节点与其余节点（对照组）的出错率和争议率：
+// 金丝雀组不高于对照组超过容忍度时参数全网生效并记入变更日志，否则金丝雀节点恢复旧值。
+// 对比结果作为提案的一部分保存，并发布到提案所在的治理话题。
+
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrCanaryTrialRequired = errors.New("canary trial period must be positive")
+	ErrNoCanaryRollout     = errors.New("proposal has no canary rollout")
+	ErrCanaryNotInTrial    = errors.New("canary rollout is not in trial")
+)
+
+// 签名的域分隔标签
+const (
+	canaryReportLabel = "daan-canary-report"
+	canaryOptInLabel  = "daan-canary-opt-in"
+)
+
+// CanaryPhase 金丝雀发布阶段
+type CanaryPhase string
+
+const (
+	CanaryTrial      CanaryPhase = "trial"       // 只在金丝雀节点上生效
+	CanaryPromoted   CanaryPhase = "promoted"    // 对比通过，全网生效
+	CanaryRolledBack CanaryPhase = "rolled_back" // 对比未通过，金丝雀节点恢复旧值
+)
+
+// CanaryMetrics 节点的请求和任务计数
+type CanaryMetrics struct {
+	Requests int64 `json:"requests"`
+	Errors   int64 `json:"errors"`
+	Tasks    int64 `json:"tasks"`
+	Disputes int64 `json:"disputes"`
+}
+
+// ErrorRate 请求出错率，没有请求时为 0
+func (m CanaryMetrics) ErrorRate() float64 {
+	if m.Requests <= 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Requests)
+}
+
+// DisputeRate 任务争议率，没有任务时为 0
+func (m CanaryMetrics) DisputeRate() float64 {
+	if m.Tasks <= 0 {
+		return 0
+	}
+	return float64(m.Disputes) / float64(m.Tasks)
+}
+
+// since 相对 base 的增量；计数器回绕（如节点重启）时按 0 计
+func (m CanaryMetrics) since(base CanaryMetrics) CanaryMetrics {
+	delta := func(cur, prev int64) int64 {
+		if cur < prev {
+			return 0
+		}
+		return cur - prev
+	}
+	return CanaryMetrics{
+		Requests: delta(m.Requests, base.Requests),
+		Errors:   delta(m.Errors, base.Errors),
+		Tasks:    delta(m.Tasks, base.Tasks),
+		Disputes: delta(m.Disputes, base.Disputes),
+	}
+}
+
+func (m *CanaryMetrics) add(o CanaryMetrics) {
+	m.Requests += o.Requests
+	m.Errors += o.Errors
+	m.Tasks += o.Tasks
+	m.Disputes += o.Disputes
+}
+
+// MetricsFunc 返回本节点的累计计数
+// 在投票管理器持有锁时同步调用，不能回调 VotingManager。
+type MetricsFunc func() CanaryMetrics
+
+// CanaryReport 节点在试运行期间的指标增量，由报告节点签名
+type CanaryReport struct {
+	ProposalID string        `json:"proposal_id"`
+	NodeID     string        `json:"node_id"`
+	Metrics    CanaryMetrics `json:"metrics"`
+	ReportedAt time.Time     `json:"reported_at"`
+	Signature  []byte        `json:"signature"`
+}
+
+func (r *CanaryReport) signData() []byte {
+	m := r.Metrics
+	return []byte(fmt.Sprintf("%s|%s|%s|%d|%d|%d|%d|%d", canaryReportLabel, r.ProposalID, r.NodeID,
+		m.Requests, m.Errors, m.Tasks, m.Disputes, r.ReportedAt.UnixNano()))
+}
+
+// CanaryComparison 金丝雀组与对照组的指标对比
+type CanaryComparison struct {
+	CanaryNodes        int           `json:"canary_nodes"`  // 提交报告的金丝雀节点数
+	ControlNodes       int           `json:"control_nodes"` // 提交报告的对照组节点数
+	Canary             CanaryMetrics `json:"canary"`
+	Control            CanaryMetrics `json:"control"`
+	CanaryErrorRate    float64       `json:"canary_error_rate"`
+	ControlErrorRate   float64       `json:"control_error_rate"`
+	CanaryDisputeRate  float64       `json:"canary_dispute_rate"`
+	ControlDisputeRate float64       `json:"control_dispute_rate"`
+	Tolerance          float64       `json:"tolerance"`
+	Passed             bool          `json:"passed"`
+	Reason             string        `json:"reason,omitempty"`
+	EvaluatedAt        time.Time     `json:"evaluated_at"`
+}
+
+// CanaryRollout 参数提案的金丝雀发布状态
+type CanaryRollout struct {
+	Trial      time.Duration            `json:"trial"`
+	Phase      CanaryPhase              `json:"phase,omitempty"` // 提案通过后才开始
+	Nodes      []string                 `json:"nodes,omitempty"` // 提案通过时已自愿参与的节点
+	StartedAt  time.Time                `json:"started_at,omitempty"`
+	EndsAt     time.Time                `json:"ends_at,omitempty"`
+	Applied    bool                     `json:"applied"`              // 参数已在本节点生效
+	Baseline   *CanaryMetrics           `json:"baseline,omitempty"`   // 本节点试运行开始时的累计计数
+	Reports    map[string]*CanaryReport `json:"reports,omitempty"`    // nodeID -> 指标报告
+	Comparison *CanaryComparison        `json:"comparison,omitempty"` // 试运行结束后的对比结果
+}
+
+// IsCanary 节点是否是本次发布的金丝雀节点
+func (c *CanaryRollout) IsCanary(nodeID string) bool {
+	for _, id := range c.Nodes {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetMetricsFunc 设置获取本节点累计计数的函数，未设置时本节点不报告指标
+func (v *VotingManager) SetMetricsFunc(fn MetricsFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.canaryMetrics = fn
+}
+
+// SetOnCanaryReport 设置本节点生成指标报告后的回调（用于广播）
+func (v *VotingManager) SetOnCanaryReport(fn func(*CanaryReport)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onCanaryReport = fn
+}
+
+// SetOnCanaryEvaluated 设置金丝雀发布得出对比结果后的回调
+func (v *VotingManager) SetOnCanaryEvaluated(fn func(*Proposal)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onCanaryEvaluated = fn
+}
+
+// SetCanaryOptIn 设置节点是否自愿作为参数变更的金丝雀节点，只影响之后通过的提案
+func (v *VotingManager) SetCanaryOptIn(nodeID string, optIn bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	node, exists := v.nodes[nodeID]
+	if !exists {
+		return errors.New("node not found")
+	}
+	node.Canary = optIn
+	return nil
+}
+
+// CanaryOptIn 节点自愿参与（或退出）金丝雀发布的声明，由该节点签名后广播，
+// 各节点只采纳每个节点时间最新的一份
+type CanaryOptIn struct {
+	NodeID    string    `json:"node_id"`
+	OptIn     bool      `json:"opt_in"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Signature []byte    `json:"signature"`
+}
+
+func (o *CanaryOptIn) signData() []byte {
+	return []byte(fmt.Sprintf("%s|%s|%t|%d", canaryOptInLabel, o.NodeID, o.OptIn, o.UpdatedAt.UnixNano()))
+}
+
+// OptInCanary 签名并应用本节点的参与声明，返回的声明由调用方广播
+func (v *VotingManager) OptInCanary(optIn bool) (*CanaryOptIn, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	node, exists := v.nodes[v.config.NodeID]
+	if !exists {
+		return nil, errors.New("node not found")
+	}
+	o := &CanaryOptIn{NodeID: v.config.NodeID, OptIn: optIn, UpdatedAt: time.Now()}
+	if !o.UpdatedAt.After(node.CanaryUpdatedAt) {
+		o.UpdatedAt = node.CanaryUpdatedAt.Add(time.Nanosecond)
+	}
+	if v.signFunc != nil {
+		sig, err := v.signFunc(o.signData())
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign canary opt-in: %w", err)
+		}
+		o.Signature = sig
+	}
+	applyCanaryOptIn(node, o)
+	return o, nil
+}
+
+// ReceiveCanaryOptIn 接收其他节点的参与声明，早于已采纳声明的忽略
+func (v *VotingManager) ReceiveCanaryOptIn(o *CanaryOptIn) error {
+	if o == nil || o.NodeID == "" {
+		return errors.New("invalid canary opt-in")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	node, exists := v.nodes[o.NodeID]
+	if !exists {
+		return errors.New("unknown node")
+	}
+	if !o.UpdatedAt.After(node.CanaryUpdatedAt) {
+		return nil
+	}
+	if v.verifyFunc != nil {
+		valid, err := v.verifyFunc(o.NodeID, o.signData(), o.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to verify signature: %w", err)
+		}
+		if !valid {
+			return errors.New("invalid canary opt-in signature")
+		}
+	}
+	applyCanaryOptIn(node, o)
+	return nil
+}
+
+// CanaryOptInOf 节点最近一次签名的参与声明，未声明过时返回 nil
+func (v *VotingManager) CanaryOptInOf(nodeID string) *CanaryOptIn {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	node, exists := v.nodes[nodeID]
+	if !exists || node.CanaryUpdatedAt.IsZero() {
+		return nil
+	}
+	return &CanaryOptIn{
+		NodeID:    nodeID,
+		OptIn:     node.Canary,
+		UpdatedAt: node.CanaryUpdatedAt,
+		Signature: node.CanarySignature,
+	}
+}
+
+func applyCanaryOptIn(node *NodeTrust, o *CanaryOptIn) {
+	node.Canary = o.OptIn
+	node.CanaryUpdatedAt = o.UpdatedAt
+	node.CanarySignature = o.Signature
+}
+
+// CanaryNodes 当前自愿参与金丝雀发布的活跃节点
+func (v *VotingManager) CanaryNodes() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.canaryNodesLocked()
+}
+
+func (v *VotingManager) canaryNodesLocked() []string {
+	var ids []string
+	for id, node := range v.nodes {
+		if node.Canary && node.Status == StatusActive {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CreateCanaryParameterProposal 发起带试运行期的参数提案：通过后先在金丝雀节点上试运行 trial
+func (v *VotingManager) CreateCanaryParameterProposal(key, value, reason string, trial time.Duration) (*Proposal, error) {
+	if trial <= 0 {
+		return nil, ErrCanaryTrialRequired
+	}
+	return v.createParameterProposal(key, value, reason, &CanaryRollout{Trial: trial})
+}
+
+// GetCanary 获取提案的金丝雀发布状态
+func (v *VotingManager) GetCanary(proposalID string) (*CanaryRollout, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	proposal, exists := v.proposals[proposalID]
+	if !exists {
+		return nil, errors.New("proposal not found")
+	}
+	if proposal.Canary == nil {
+		return nil, ErrNoCanaryRollout
+	}
+	return proposal.Canary, nil
+}
+
+// startCanaryLocked 提案通过后开始试运行：本节点是金丝雀节点时先行生效。
+// 没有节点自愿参与时直接全网生效。（调用方持有锁）
+func (v *VotingManager) startCanaryLocked(proposal *Proposal, now time.Time) {
+	c := proposal.Canary
+	c.Nodes = v.canaryNodesLocked()
+	c.StartedAt = now
+	c.EndsAt = now.Add(c.Trial)
+	c.Reports = make(map[string]*CanaryReport)
+
+	if len(c.Nodes) == 0 {
+		c.Phase = CanaryPromoted
+		c.Comparison = &CanaryComparison{
+			Tolerance:   v.config.CanaryTolerance,
+			Passed:      true,
+			Reason:      "no canary nodes opted in, activated network-wide",
+			EvaluatedAt: now,
+		}
+		if v.applyParameterLocked(proposal.Parameter) {
+			c.Applied = true
+			v.recordChangeLocked(proposal, ActionParameter)
+		}
+		if v.onCanaryEvaluated != nil {
+			go v.onCanaryEvaluated(proposal)
+		}
+		return
+	}
+
+	c.Phase = CanaryTrial
+	if v.canaryMetrics != nil {
+		baseline := v.canaryMetrics()
+		c.Baseline = &baseline
+	}
+	if c.IsCanary(v.config.NodeID) {
+		c.Applied = v.applyParameterLocked(proposal.Parameter)
+	}
+}
+
+// ReceiveCanaryReport 接收其他节点的指标报告，每个节点只采纳第一份
+func (v *VotingManager) ReceiveCanaryReport(report *CanaryReport) error {
+	if report == nil || report.NodeID == "" {
+		return errors.New("invalid canary report")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	proposal, exists := v.proposals[report.ProposalID]
+	if !exists {
+		return errors.New("proposal not found")
+	}
+	if proposal.Canary == nil {
+		return ErrNoCanaryRollout
+	}
+	if proposal.Canary.Phase != CanaryTrial {
+		return ErrCanaryNotInTrial
+	}
+	if _, reported := proposal.Canary.Reports[report.NodeID]; reported {
+		return errors.New("report already recorded")
+	}
+	if _, known := v.nodes[report.NodeID]; !known {
+		return errors.New("unknown reporting node")
+	}
+
+	if v.verifyFunc != nil {
+		valid, err := v.verifyFunc(report.NodeID, report.signData(), report.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to verify signature: %w", err)
+		}
+		if !valid {
+			return errors.New("invalid report signature")
+		}
+	}
+
+	recorded := *report
+	proposal.Canary.Reports[report.NodeID] = &recorded
+	return nil
+}
+
+// checkCanaries 试运行结束时报告本节点指标，报告期结束后对比并决定全网生效或回滚
+func (v *VotingManager) checkCanaries(now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, proposal := range v.proposals {
+		c := proposal.Canary
+		if c == nil || c.Phase != CanaryTrial || now.Before(c.EndsAt) {
+			continue
+		}
+		if _, reported := c.Reports[v.config.NodeID]; !reported {
+			v.reportCanaryLocked(proposal, now)
+		}
+		if !now.Before(c.EndsAt.Add(v.config.CanaryReportWindow)) {
+			v.evaluateCanaryLocked(proposal, now)
+		}
+	}
+}
+
+// reportCanaryLocked 生成本节点试运行期间的指标报告（调用方持有锁）
+func (v *VotingManager) reportCanaryLocked(proposal *Proposal, now time.Time) {
+	c := proposal.Canary
+	if v.canaryMetrics == nil || c.Baseline == nil {
+		return
+	}
+	report := &CanaryReport{
+		ProposalID: proposal.ID,
+		NodeID:     v.config.NodeID,
+		Metrics:    v.canaryMetrics().since(*c.Baseline),
+		ReportedAt: now,
+	}
+	if v.signFunc != nil {
+		sig, err := v.signFunc(report.signData())
+		if err != nil {
+			fmt.Printf("Warning: failed to sign canary report: %v\n", err)
+			return
+		}
+		report.Signature = sig
+	}
+	c.Reports[v.config.NodeID] = report
+	if v.onCanaryReport != nil {
+		go v.onCanaryReport(report)
+	}
+}
+
+// compareCanary 汇总报告，比较金丝雀组与对照组
+func compareCanary(c *CanaryRollout, tolerance float64, now time.Time) *CanaryComparison {
+	cmp := &CanaryComparison{Tolerance: tolerance, EvaluatedAt: now}
+	for nodeID, report := range c.Reports {
+		if c.IsCanary(nodeID) {
+			cmp.CanaryNodes++
+			cmp.Canary.add(report.Metrics)
+		} else {
+			cmp.ControlNodes++
+			cmp.Control.add(report.Metrics)
+		}
+	}
+	cmp.CanaryErrorRate = cmp.Canary.ErrorRate()
+	cmp.ControlErrorRate = cmp.Control.ErrorRate()
+	cmp.CanaryDisputeRate = cmp.Canary.DisputeRate()
+	cmp.ControlDisputeRate = cmp.Control.DisputeRate()
+
+	switch {
+	case cmp.CanaryNodes == 0:
+		cmp.Reason = "no metrics reported by canary nodes"
+	case cmp.CanaryErrorRate-cmp.ControlErrorRate > tolerance:
+		cmp.Reason = fmt.Sprintf("canary error rate %.2f%% exceeds control %.2f%% by more than %.2f%%",
+			cmp.CanaryErrorRate*100, cmp.ControlErrorRate*100, tolerance*100)
+	case cmp.CanaryDisputeRate-cmp.ControlDisputeRate > tolerance:
+		cmp.Reason = fmt.Sprintf("canary dispute rate %.2f%% exceeds control %.2f%% by more than %.2f%%",
+			cmp.CanaryDisputeRate*100, cmp.ControlDisputeRate*100, tolerance*100)
+	default:
+		cmp.Passed = true
+	}
+	return cmp
+}
+
+// evaluateCanaryLocked 按对比结果全网生效或在金丝雀节点上回滚（调用方持有锁）
+func (v *VotingManager) evaluateCanaryLocked(proposal *Proposal, now time.Time) {
+	c := proposal.Canary
+	c.Comparison = compareCanary(c, v.config.CanaryTolerance, now)
+
+	param := proposal.Parameter
+	if c.Comparison.Passed {
+		c.Phase = CanaryPromoted
+		if !c.Applied {
+			c.Applied = v.applyParameterLocked(param)
+		}
+		if c.Applied {
+			v.recordChangeLocked(proposal, ActionParameter)
+		}
+	} else {
+		c.Phase = CanaryRolledBack
+		if c.Applied {
+			if _, err := v.applyParameter(param.Key, param.OldValue); err != nil {
+				param.ApplyError = "rollback failed: " + err.Error()
+			} else {
+				c.Applied = false
+			}
+		}
+	}
+
+	if v.onCanaryEvaluated != nil {
+		go v.onCanaryEvaluated(proposal)
+	}
+}
+
+// CanarySummary 金丝雀发布结果的单行可读摘要，用于在提案的治理话题上报告
+func CanarySummary(proposal *Proposal) string {
+	c := proposal.Canary
+	what := "parameter change"
+	if proposal.Parameter != nil {
+		what = fmt.Sprintf("set %s = %q", proposal.Parameter.Key, proposal.Parameter.Value)
+	}
+	summary := fmt.Sprintf("[governance] canary for proposal %s (%s): %s", proposal.ID, what, c.Phase)
+	if cmp := c.Comparison; cmp != nil {
+		summary += fmt.Sprintf("; canary %d nodes error %.2f%% dispute %.2f%%, control %d nodes error %.2f%% dispute %.2f%% (tolerance %.2f%%)",
+			cmp.CanaryNodes, cmp.CanaryErrorRate*100, cmp.CanaryDisputeRate*100,
+			cmp.ControlNodes, cmp.ControlErrorRate*100, cmp.ControlDisputeRate*100, cmp.Tolerance*100)
+		if cmp.Reason != "" {
+			summary += "; " + cmp.Reason
+		}
+	}
+	return summary
+}
@@ -0,0 +1,254 @@
+package voting
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createCanaryManager 在变更日志测试的节点上接入参数表和可调的本节点计数
+func createCanaryManager(t *testing.T) (*VotingManager, chan *ChangelogEntry, map[string]string, *CanaryMetrics) {
+	vm, changes := createChangelogManager(t)
+	vm.config.CanaryTolerance = 0.02
+	vm.config.CanaryReportWindow = 10 * time.Minute
+
+	params := map[string]string{"bulletin.max_per_hour": "60"}
+	vm.SetParameterApplier(func(key, value string) (string, error) {
+		old, ok := params[key]
+		if !ok {
+			return "", errors.New("unknown parameter")
+		}
+		params[key] = value
+		return old, nil
+	})
+	counters := &CanaryMetrics{}
+	vm.SetMetricsFunc(func() CanaryMetrics { return *counters })
+	return vm, changes, params, counters
+}
+
+// passCanaryProposal 发起并通过带试运行期的参数提案；最后一票由 node-002 投出，本节点即 node-002
+func passCanaryProposal(t *testing.T, vm *VotingManager) *Proposal {
+	t.Helper()
+	vm.config.NodeID = "node-001"
+	proposal, err := vm.CreateCanaryParameterProposal("bulletin.max_per_hour", "30", "reduce spam", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateCanaryParameterProposal() error = %v", err)
+	}
+	passProposal(vm, proposal.ID)
+	return proposal
+}
+
+func TestCanaryPromotion(t *testing.T) {
+	vm, changes, params, counters := createCanaryManager(t)
+	if _, err := vm.CreateCanaryParameterProposal("bulletin.max_per_hour", "30", "", 0); !errors.Is(err, ErrCanaryTrialRequired) {
+		t.Errorf("zero trial error = %v", err)
+	}
+	if err := vm.SetCanaryOptIn("node-002", true); err != nil {
+		t.Fatalf("SetCanaryOptIn() error = %v", err)
+	}
+	if got := vm.CanaryNodes(); len(got) != 1 || got[0] != "node-002" {
+		t.Fatalf("CanaryNodes() = %v", got)
+	}
+
+	*counters = CanaryMetrics{Requests: 1000, Errors: 10}
+	proposal := passCanaryProposal(t, vm)
+	c, err := vm.GetCanary(proposal.ID)
+	if err != nil {
+		t.Fatalf("GetCanary() error = %v", err)
+	}
+	if c.Phase != CanaryTrial || !c.Applied || params["bulletin.max_per_hour"] != "30" {
+		t.Fatalf("canary after pass = %+v, params = %v", c, params)
+	}
+	if got := vm.GetChangelog(0); len(got) != 0 {
+		t.Fatalf("changelog during trial = %+v", got)
+	}
+
+	// 试运行期间：本节点（金丝雀）出错率 1%，对照组 0.5%
+	*counters = CanaryMetrics{Requests: 2000, Errors: 20, Tasks: 10}
+	control := &CanaryReport{
+		ProposalID: proposal.ID,
+		NodeID:     "node-001",
+		Metrics:    CanaryMetrics{Requests: 400, Errors: 2, Tasks: 20, Disputes: 0},
+	}
+	if err := vm.ReceiveCanaryReport(control); err != nil {
+		t.Fatalf("ReceiveCanaryReport() error = %v", err)
+	}
+	if err := vm.ReceiveCanaryReport(control); err == nil {
+		t.Error("expected duplicate report to be rejected")
+	}
+	if err := vm.ReceiveCanaryReport(&CanaryReport{ProposalID: proposal.ID, NodeID: "stranger"}); err == nil {
+		t.Error("expected report from unknown node to be rejected")
+	}
+
+	// 试运行结束：生成本节点报告，等待报告期
+	vm.checkCanaries(c.EndsAt)
+	if report := c.Reports["node-002"]; report == nil || report.Metrics.Requests != 1000 || report.Metrics.Errors != 10 {
+		t.Fatalf("own report = %+v", report)
+	}
+	if c.Phase != CanaryTrial {
+		t.Fatalf("phase before report window ends = %s", c.Phase)
+	}
+
+	vm.checkCanaries(c.EndsAt.Add(vm.config.CanaryReportWindow))
+	cmp := c.Comparison
+	if c.Phase != CanaryPromoted || cmp == nil || !cmp.Passed || cmp.CanaryNodes != 1 || cmp.ControlNodes != 1 {
+		t.Fatalf("canary = %+v, comparison = %+v", c, cmp)
+	}
+	if cmp.CanaryErrorRate != 0.01 || cmp.ControlErrorRate != 0.005 {
+		t.Errorf("error rates = %v / %v", cmp.CanaryErrorRate, cmp.ControlErrorRate)
+	}
+
+	e := waitChange(t, changes)
+	if e.ProposalID != proposal.ID || e.Parameter.OldValue != "60" || !e.AppliedAt.Equal(cmp.EvaluatedAt) {
+		t.Errorf("entry = %+v, parameter = %+v", e, e.Parameter)
+	}
+	if err := vm.ReceiveCanaryReport(&CanaryReport{ProposalID: proposal.ID, NodeID: "target-node"}); !errors.Is(err, ErrCanaryNotInTrial) {
+		t.Errorf("late report error = %v", err)
+	}
+
+	summary := CanarySummary(proposal)
+	for _, want := range []string{proposal.ID, "promoted", "canary 1 nodes error 1.00%", "control 1 nodes error 0.50%"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("CanarySummary() = %q, missing %q", summary, want)
+		}
+	}
+}
+
+func TestCanaryRollback(t *testing.T) {
+	vm, changes, params, counters := createCanaryManager(t)
+	vm.SetCanaryOptIn("node-002", true)
+	evaluated := make(chan *Proposal, 1)
+	vm.SetOnCanaryEvaluated(func(p *Proposal) { evaluated <- p })
+
+	proposal := passCanaryProposal(t, vm)
+	c := proposal.Canary
+
+	// 金丝雀节点的争议率 20%，对照组 0%
+	*counters = CanaryMetrics{Requests: 100, Tasks: 10, Disputes: 2}
+	vm.ReceiveCanaryReport(&CanaryReport{
+		ProposalID: proposal.ID,
+		NodeID:     "node-001",
+		Metrics:    CanaryMetrics{Requests: 100, Tasks: 10},
+	})
+	vm.checkCanaries(c.EndsAt.Add(vm.config.CanaryReportWindow))
+
+	if c.Phase != CanaryRolledBack || c.Applied || params["bulletin.max_per_hour"] != "60" {
+		t.Fatalf("canary = %+v, params = %v", c, params)
+	}
+	if !strings.Contains(c.Comparison.Reason, "dispute rate") {
+		t.Errorf("reason = %q", c.Comparison.Reason)
+	}
+	select {
+	case p := <-evaluated:
+		if p.ID != proposal.ID {
+			t.Errorf("evaluated proposal = %s", p.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no canary evaluation callback")
+	}
+	select {
+	case e := <-changes:
+		t.Errorf("unexpected changelog entry %+v", e)
+	default:
+	}
+}
+
+func TestCanaryWithoutReports(t *testing.T) {
+	vm, _, params, _ := createCanaryManager(t)
+	vm.SetCanaryOptIn("node-001", true)
+
+	// 本节点（node-002）不是金丝雀节点，试运行期间保持旧值
+	proposal := passCanaryProposal(t, vm)
+	c := proposal.Canary
+	if c.Applied || params["bulletin.max_per_hour"] != "60" {
+		t.Fatalf("control node applied the change: %+v", c)
+	}
+
+	// 金丝雀节点没有报告：不全网生效
+	vm.checkCanaries(c.EndsAt.Add(vm.config.CanaryReportWindow))
+	if c.Phase != CanaryRolledBack || c.Comparison.CanaryNodes != 0 || params["bulletin.max_per_hour"] != "60" {
+		t.Errorf("canary = %+v, comparison = %+v", c, c.Comparison)
+	}
+}
+
+func TestCanaryWithoutOptIn(t *testing.T) {
+	vm, changes, params, _ := createCanaryManager(t)
+
+	proposal := passCanaryProposal(t, vm)
+	if c := proposal.Canary; c.Phase != CanaryPromoted || !c.Applied || params["bulletin.max_per_hour"] != "30" {
+		t.Fatalf("canary = %+v, params = %v", c, params)
+	}
+	if e := waitChange(t, changes); e.ProposalID != proposal.ID {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestCanaryOptInExchange(t *testing.T) {
+	alice := createEqualPowerManager(t, "alice", "bob")
+	alice.config.NodeID = "alice"
+	alice.SetSignFunc(signAs("alice"))
+	bob := createEqualPowerManager(t, "alice", "bob")
+	bob.config.NodeID = "bob"
+	bob.SetVerifyFunc(testVerify)
+
+	if alice.CanaryOptInOf("alice") != nil {
+		t.Fatal("opt-in reported before declaring")
+	}
+	in, err := alice.OptInCanary(true)
+	if err != nil {
+		t.Fatalf("OptInCanary() error = %v", err)
+	}
+	if got := alice.CanaryOptInOf("alice"); got == nil || !got.OptIn || string(got.Signature) != string(in.Signature) {
+		t.Fatalf("CanaryOptInOf() = %+v", got)
+	}
+
+	forged := *in
+	forged.NodeID = "bob"
+	if err := bob.ReceiveCanaryOptIn(&forged); err == nil {
+		t.Error("opt-in signed by another node accepted")
+	}
+	if err := bob.ReceiveCanaryOptIn(in); err != nil {
+		t.Fatalf("ReceiveCanaryOptIn() error = %v", err)
+	}
+	if got := bob.CanaryNodes(); len(got) != 1 || got[0] != "alice" {
+		t.Fatalf("CanaryNodes() = %v", got)
+	}
+
+	// 退出声明覆盖参与声明，重放旧声明不生效
+	out, err := alice.OptInCanary(false)
+	if err != nil {
+		t.Fatalf("OptInCanary(false) error = %v", err)
+	}
+	bob.ReceiveCanaryOptIn(out)
+	if err := bob.ReceiveCanaryOptIn(in); err != nil {
+		t.Fatalf("replayed opt-in error = %v", err)
+	}
+	if got := bob.CanaryNodes(); len(got) != 0 {
+		t.Errorf("CanaryNodes() after opt-out = %v", got)
+	}
+}
+
+func TestChangelogPublisherCanary(t *testing.T) {
+	var topic string
+	var tags []string
+	pub := NewChangelogPublisher(nil, func(content, tp string, tg []string) error {
+		topic, tags = tp, tg
+		return nil
+	}, "node-001")
+
+	proposal := &Proposal{
+		ID:        "prop-1",
+		Parameter: &ParameterChange{Key: "bulletin.max_per_hour", Value: "30"},
+		Canary: &CanaryRollout{
+			Phase:      CanaryRolledBack,
+			Comparison: &CanaryComparison{CanaryNodes: 2, Reason: "canary error rate too high"},
+		},
+	}
+	if err := pub.PublishCanary(proposal); err != nil {
+		t.Fatalf("PublishCanary() error = %v", err)
+	}
+	if topic != ChangelogBulletinTopic || len(tags) != 3 || tags[1] != "rolled_back" || tags[2] != "prop-1" {
+		t.Errorf("topic = %q, tags = %v", topic, tags)
+	}
+}
@@ -0,0 +1,338 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/ledger"
+)
+
+// 治理变更日志
+//
+// 提案通过后实际执行的变更（剔除、恢复节点，晋升、撤销超级节点，修改网络参数）各生成一条 ChangelogEntry，
+// 记录变更内容、发起者和计入结果的每一票。ChangelogPublisher 把它作为签名的
+// GOVERNANCE_CHANGE 事件写入账本，并在保留话题上发布一条可读摘要，
+// 让每个节点运营者都能看到改了什么、何时改的、由哪些票决定。
+
+// ChangelogBulletinTopic 治理变更摘要在留言板上使用的保留话题
+const ChangelogBulletinTopic = "governance-changelog"
+
+var (
+	ErrParameterKeyRequired = errors.New("parameter key is required")
+	ErrNoParameterApplier   = errors.New("no parameter applier configured")
+)
+
+// ChangeAction 治理变更动作
+type ChangeAction string
+
+const (
+	ActionBan       ChangeAction = "ban"       // 剔除节点
+	ActionUnban     ChangeAction = "unban"     // 恢复节点
+	ActionParameter ChangeAction = "parameter" // 修改网络参数
+	ActionPromote   ChangeAction = "promote"   // 晋升为超级节点
+	ActionDemote    ChangeAction = "demote"    // 撤销超级节点
+)
+
+// ParameterChange 参数提案要修改的参数
+type ParameterChange struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	OldValue   string `json:"old_value,omitempty"`   // 执行时由参数应用函数返回
+	ApplyError string `json:"apply_error,omitempty"` // 提案通过但执行失败的原因
+}
+
+// ParameterApplier 执行参数变更并返回旧值
+// 在投票管理器持有锁时同步调用，不能回调 VotingManager。
+type ParameterApplier func(key, value string) (oldValue string, err error)
+
+// ChangelogVote 计入结果的一票；DelegatorID 非空时为受托人代委托人投出的权重
+type ChangelogVote struct {
+	VoterID     string     `json:"voter_id"`
+	DelegatorID string     `json:"delegator_id,omitempty"`
+	Choice      VoteChoice `json:"choice"`
+	Weight      float64    `json:"weight"`
+}
+
+// ChangelogEntry 一次已执行的治理变更
+type ChangelogEntry struct {
+	ProposalID    string           `json:"proposal_id"`
+	ProposalType  VoteType         `json:"proposal_type"`
+	Action        ChangeAction     `json:"action"`
+	TargetNodeID  string           `json:"target_node_id,omitempty"`
+	Parameter     *ParameterChange `json:"parameter,omitempty"`
+	ProposerID    string           `json:"proposer_id"`
+	Reason        string           `json:"reason"`
+	Votes         []ChangelogVote  `json:"votes"`
+	YesWeight     float64          `json:"yes_weight"`
+	NoWeight      float64          `json:"no_weight"`
+	AbstainWeight float64          `json:"abstain_weight"`
+	YesRatio      float64          `json:"yes_ratio"`
+	AppliedAt     time.Time        `json:"applied_at"`
+}
+
+// SetParameterApplier 设置参数提案通过后执行变更的函数
+func (v *VotingManager) SetParameterApplier(fn ParameterApplier) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.applyParameter = fn
+}
+
+// SetOnChangeApplied 设置治理变更执行后的回调
+func (v *VotingManager) SetOnChangeApplied(fn func(*ChangelogEntry)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onChangeApplied = fn
+}
+
+// CreateParameterProposal 发起修改网络参数的提案
+func (v *VotingManager) CreateParameterProposal(key, value, reason string) (*Proposal, error) {
+	return v.createParameterProposal(key, value, reason, nil)
+}
+
+// createParameterProposal 发起参数提案，canary 非空时通过后先试运行（见 canary.go）
+func (v *VotingManager) createParameterProposal(key, value, reason string, canary *CanaryRollout) (*Proposal, error) {
+	if key == "" {
+		return nil, ErrParameterKeyRequired
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	proposerRep := v.getNodeReputation(v.config.NodeID)
+	if proposerRep < v.config.MinRepToPropose {
+		return nil, fmt.Errorf("reputation too low to propose: %.2f < %.2f", proposerRep, v.config.MinRepToPropose)
+	}
+	for _, p := range v.proposals {
+		if p.Status == ProposalPending && p.Parameter != nil && p.Parameter.Key == key {
+			return nil, errors.New("similar proposal already pending")
+		}
+	}
+
+	return v.createProposalLocked(VoteProposal, "", &ParameterChange{Key: key, Value: value}, canary, reason)
+}
+
+// GetChangelog 返回已执行的治理变更，最新的在前；limit <= 0 时返回全部
+func (v *VotingManager) GetChangelog(limit int) []*ChangelogEntry {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	n := len(v.changelog)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]*ChangelogEntry, 0, n)
+	for i := len(v.changelog) - 1; i >= 0 && len(result) < n; i-- {
+		result = append(result, v.changelog[i])
+	}
+	return result
+}
+
+// applyParameterLocked 执行参数变更，失败时记录原因（调用方持有锁）
+func (v *VotingManager) applyParameterLocked(param *ParameterChange) bool {
+	if v.applyParameter == nil {
+		param.ApplyError = ErrNoParameterApplier.Error()
+		return false
+	}
+	old, err := v.applyParameter(param.Key, param.Value)
+	if err != nil {
+		param.ApplyError = err.Error()
+		return false
+	}
+	param.OldValue = old
+	return true
+}
+
+// recordChangeLocked 为已执行的提案生成变更日志并触发回调（调用方持有锁）
+func (v *VotingManager) recordChangeLocked(proposal *Proposal, action ChangeAction) {
+	entry := newChangelogEntry(proposal, action)
+	v.changelog = append(v.changelog, entry)
+	if v.onChangeApplied != nil {
+		go v.onChangeApplied(entry)
+	}
+}
+
+func newChangelogEntry(proposal *Proposal, action ChangeAction) *ChangelogEntry {
+	entry := &ChangelogEntry{
+		ProposalID:   proposal.ID,
+		ProposalType: proposal.Type,
+		Action:       action,
+		TargetNodeID: proposal.TargetNodeID,
+		ProposerID:   proposal.ProposerID,
+		Reason:       proposal.Reason,
+		Votes:        make([]ChangelogVote, 0, len(proposal.Votes)),
+	}
+	if proposal.Parameter != nil {
+		param := *proposal.Parameter
+		entry.Parameter = &param
+	}
+
+	for _, vote := range proposal.Votes {
+		entry.Votes = append(entry.Votes, ChangelogVote{VoterID: vote.VoterID, Choice: vote.Choice, Weight: vote.Weight})
+	}
+	if r := proposal.Result; r != nil {
+		for _, dv := range r.Delegations {
+			if dv.Status == DelegationCounted {
+				entry.Votes = append(entry.Votes, ChangelogVote{VoterID: dv.VoterID, DelegatorID: dv.DelegatorID, Choice: dv.Choice, Weight: dv.Weight})
+			}
+		}
+		entry.YesWeight = r.YesWeight
+		entry.NoWeight = r.NoWeight
+		entry.AbstainWeight = r.AbstainWeight
+		entry.YesRatio = r.YesRatio
+		entry.AppliedAt = r.FinalizedAt
+	}
+	// 经金丝雀试运行的变更在对比通过时才全网生效
+	if c := proposal.Canary; c != nil && c.Comparison != nil {
+		entry.AppliedAt = c.Comparison.EvaluatedAt
+	}
+	if entry.AppliedAt.IsZero() {
+		entry.AppliedAt = time.Now()
+	}
+	sort.Slice(entry.Votes, func(i, j int) bool {
+		a, b := entry.Votes[i], entry.Votes[j]
+		if a.VoterID != b.VoterID {
+			return a.VoterID < b.VoterID
+		}
+		return a.DelegatorID < b.DelegatorID
+	})
+	return entry
+}
+
+// Summary 变更的单行可读摘要，用于留言板
+func (e *ChangelogEntry) Summary() string {
+	var what string
+	switch e.Action {
+	case ActionBan:
+		what = "banned node " + e.TargetNodeID
+	case ActionUnban:
+		what = "restored node " + e.TargetNodeID
+	case ActionPromote:
+		what = "promoted node " + e.TargetNodeID + " to supernode"
+	case ActionDemote:
+		what = "demoted supernode " + e.TargetNodeID
+	case ActionParameter:
+		what = fmt.Sprintf("set %s = %q", e.Parameter.Key, e.Parameter.Value)
+		if e.Parameter.OldValue != "" {
+			what += fmt.Sprintf(" (was %q)", e.Parameter.OldValue)
+		}
+	default:
+		what = string(e.Action)
+	}
+
+	votes := make([]string, 0, len(e.Votes))
+	for _, vote := range e.Votes {
+		s := fmt.Sprintf("%s %s %.2f", vote.VoterID, vote.Choice, vote.Weight)
+		if vote.DelegatorID != "" {
+			s += " for " + vote.DelegatorID
+		}
+		votes = append(votes, s)
+	}
+
+	summary := fmt.Sprintf("[governance] %s at %s by proposal %s from %s: yes %.2f / no %.2f / abstain %.2f (%.0f%% yes)",
+		what, e.AppliedAt.UTC().Format(time.RFC3339), e.ProposalID, e.ProposerID,
+		e.YesWeight, e.NoWeight, e.AbstainWeight, e.YesRatio*100)
+	if len(votes) > 0 {
+		summary += "; votes: " + strings.Join(votes, ", ")
+	}
+	if e.Reason != "" {
+		summary += "; reason: " + e.Reason
+	}
+	return summary
+}
+
+// LedgerData 转换为账本 GOVERNANCE_CHANGE 事件的数据
+func (e *ChangelogEntry) LedgerData() *ledger.GovernanceChangeData {
+	data := &ledger.GovernanceChangeData{
+		ProposalID:    e.ProposalID,
+		Action:        string(e.Action),
+		TargetNodeID:  e.TargetNodeID,
+		ProposerID:    e.ProposerID,
+		Reason:        e.Reason,
+		Votes:         make([]ledger.GovernanceVoteData, 0, len(e.Votes)),
+		YesWeight:     e.YesWeight,
+		NoWeight:      e.NoWeight,
+		AbstainWeight: e.AbstainWeight,
+		YesRatio:      e.YesRatio,
+		AppliedAt:     e.AppliedAt.Unix(),
+	}
+	if e.Parameter != nil {
+		data.ParameterKey = e.Parameter.Key
+		data.OldValue = e.Parameter.OldValue
+		data.NewValue = e.Parameter.Value
+	}
+	for _, vote := range e.Votes {
+		data.Votes = append(data.Votes, ledger.GovernanceVoteData{
+			VoterID:     vote.VoterID,
+			DelegatorID: vote.DelegatorID,
+			Choice:      string(vote.Choice),
+			Weight:      vote.Weight,
+		})
+	}
+	return data
+}
+
+// PostFunc 在留言板指定话题上发布内容
+type PostFunc func(content, topic string, tags []string) error
+
+// ChangelogPublisher 把治理变更写入账本并发布摘要
+type ChangelogPublisher struct {
+	ledger   *ledger.Ledger
+	post     PostFunc
+	signerID string
+}
+
+// NewChangelogPublisher 创建变更日志发布器
+// 事件由账本的签名函数以 signerID 身份签名；post 为 nil 时只写账本。
+func NewChangelogPublisher(l *ledger.Ledger, post PostFunc, signerID string) *ChangelogPublisher {
+	return &ChangelogPublisher{ledger: l, post: post, signerID: signerID}
+}
+
+// Publish 追加签名的 GOVERNANCE_CHANGE 事件，并在保留话题上发布带事件序号和哈希的摘要
+func (p *ChangelogPublisher) Publish(entry *ChangelogEntry) (*ledger.Event, error) {
+	nodeID := entry.TargetNodeID
+	if nodeID == "" {
+		nodeID = entry.ProposerID
+	}
+	event, err := p.ledger.AppendEvent(ledger.EventGovernanceChange, nodeID, entry.LedgerData(), p.signerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append governance change: %w", err)
+	}
+	if p.post == nil {
+		return event, nil
+	}
+
+	content := fmt.Sprintf("%s [ledger #%d %s]", entry.Summary(), event.Sequence, event.Hash)
+	if err := p.post(content, ChangelogBulletinTopic, []string{string(entry.Action), entry.ProposalID}); err != nil {
+		return event, fmt.Errorf("failed to post governance summary: %w", err)
+	}
+	return event, nil
+}
+
+// Handle 作为 SetOnChangeApplied 的回调使用，发布失败时只打印警告
+func (p *ChangelogPublisher) Handle(entry *ChangelogEntry) {
+	if _, err := p.Publish(entry); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
+
+// PublishCanary 在保留话题上发布金丝雀发布的对比结果，以提案ID为标签归入该提案的讨论
+func (p *ChangelogPublisher) PublishCanary(proposal *Proposal) error {
+	if p.post == nil || proposal.Canary == nil {
+		return nil
+	}
+	tags := []string{"canary", string(proposal.Canary.Phase), proposal.ID}
+	if err := p.post(CanarySummary(proposal), ChangelogBulletinTopic, tags); err != nil {
+		return fmt.Errorf("failed to post canary report: %w", err)
+	}
+	return nil
+}
+
+// HandleCanary 作为 SetOnCanaryEvaluated 的回调使用，发布失败时只打印警告
+func (p *ChangelogPublisher) HandleCanary(proposal *Proposal) {
+	if err := p.PublishCanary(proposal); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,168 @@
+package voting
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/ledger"
+)
+
+// createChangelogManager 投票权 node-001 44、node-002 54、target-node 27，
+// 法定人数 50% 需要 node-001 与 node-002 都投票
+func createChangelogManager(t *testing.T) (*VotingManager, chan *ChangelogEntry) {
+	config := createTestConfig(t)
+	config.QuorumThreshold = 0.5
+	config.PassThreshold = 0.5
+	vm, err := NewVotingManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create voting manager: %v", err)
+	}
+	vm.RegisterNode("node-001", 50, 30)
+	vm.RegisterNode("node-002", 60, 40)
+	vm.RegisterNode("target-node", 30, 20)
+
+	changes := make(chan *ChangelogEntry, 4)
+	vm.SetOnChangeApplied(func(e *ChangelogEntry) { changes <- e })
+	return vm, changes
+}
+
+func passProposal(vm *VotingManager, proposalID string) {
+	for _, id := range []string{"node-001", "node-002"} {
+		vm.config.NodeID = id
+		vm.CastVote(proposalID, ChoiceYes, "")
+	}
+}
+
+func waitChange(t *testing.T, changes chan *ChangelogEntry) *ChangelogEntry {
+	t.Helper()
+	select {
+	case e := <-changes:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no changelog entry emitted")
+		return nil
+	}
+}
+
+func TestChangelogOnKick(t *testing.T) {
+	vm, changes := createChangelogManager(t)
+
+	vm.config.NodeID = "node-001"
+	proposal, err := vm.CreateProposal(VoteKick, "target-node", "spam")
+	if err != nil {
+		t.Fatalf("CreateProposal() error = %v", err)
+	}
+	passProposal(vm, proposal.ID)
+
+	e := waitChange(t, changes)
+	if e.Action != ActionBan || e.TargetNodeID != "target-node" || e.ProposalID != proposal.ID || e.ProposerID != "node-001" {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.Votes) != 2 || e.Votes[0].VoterID != "node-001" || e.Votes[1].Choice != ChoiceYes {
+		t.Errorf("votes = %+v", e.Votes)
+	}
+	summary := e.Summary()
+	for _, want := range []string{"banned node target-node", proposal.ID, "node-002 yes", "reason: spam"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Summary() = %q, missing %q", summary, want)
+		}
+	}
+	if got := vm.GetChangelog(0); len(got) != 1 || got[0].ProposalID != proposal.ID {
+		t.Errorf("GetChangelog() = %+v", got)
+	}
+}
+
+func TestParameterProposal(t *testing.T) {
+	vm, changes := createChangelogManager(t)
+	params := map[string]string{"bulletin.max_per_hour": "60"}
+	vm.SetParameterApplier(func(key, value string) (string, error) {
+		old, ok := params[key]
+		if !ok {
+			return "", errors.New("unknown parameter")
+		}
+		params[key] = value
+		return old, nil
+	})
+
+	if _, err := vm.CreateParameterProposal("", "1", ""); !errors.Is(err, ErrParameterKeyRequired) {
+		t.Errorf("empty key error = %v", err)
+	}
+
+	vm.config.NodeID = "node-001"
+	proposal, err := vm.CreateParameterProposal("bulletin.max_per_hour", "30", "reduce spam")
+	if err != nil {
+		t.Fatalf("CreateParameterProposal() error = %v", err)
+	}
+	if _, err := vm.CreateParameterProposal("bulletin.max_per_hour", "20", ""); err == nil {
+		t.Error("expected duplicate pending parameter proposal to be rejected")
+	}
+	passProposal(vm, proposal.ID)
+
+	e := waitChange(t, changes)
+	if e.Action != ActionParameter || e.Parameter.OldValue != "60" || e.Parameter.Value != "30" || params["bulletin.max_per_hour"] != "30" {
+		t.Errorf("entry = %+v, parameter = %+v", e, e.Parameter)
+	}
+	if !strings.Contains(e.Summary(), `set bulletin.max_per_hour = "30" (was "60")`) {
+		t.Errorf("Summary() = %q", e.Summary())
+	}
+
+	// 执行失败的参数提案记录原因，不产生变更日志
+	vm.config.NodeID = "node-001"
+	failed, _ := vm.CreateParameterProposal("unknown.key", "1", "")
+	passProposal(vm, failed.ID)
+	p, _ := vm.GetProposal(failed.ID)
+	if p.Status != ProposalPassed || p.Parameter.ApplyError == "" {
+		t.Errorf("failed proposal = %+v, parameter = %+v", p, p.Parameter)
+	}
+	if got := vm.GetChangelog(0); len(got) != 1 {
+		t.Errorf("GetChangelog() len = %d, want 1", len(got))
+	}
+}
+
+func TestChangelogPublisher(t *testing.T) {
+	l, err := ledger.NewLedger(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLedger() error = %v", err)
+	}
+	l.SetSignFunc(func(data []byte) (string, error) { return "sig:" + string(data[:8]), nil })
+
+	var posted []string
+	var topic string
+	pub := NewChangelogPublisher(l, func(content, tp string, tags []string) error {
+		posted = append(posted, content)
+		topic = tp
+		return nil
+	}, "node-001")
+
+	entry := &ChangelogEntry{
+		ProposalID: "p1",
+		Action:     ActionParameter,
+		Parameter:  &ParameterChange{Key: "k", Value: "2", OldValue: "1"},
+		ProposerID: "node-001",
+		Votes:      []ChangelogVote{{VoterID: "node-002", DelegatorID: "node-003", Choice: ChoiceYes, Weight: 1.5}},
+		YesWeight:  1.5,
+		YesRatio:   1,
+		AppliedAt:  time.Unix(1700000000, 0),
+	}
+	event, err := pub.Publish(entry)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if event.Type != ledger.EventGovernanceChange || event.NodeID != "node-001" || !strings.HasPrefix(event.Signature, "sig:") {
+		t.Errorf("event = %+v", event)
+	}
+	var data ledger.GovernanceChangeData
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("unmarshal event data: %v", err)
+	}
+	if data.ParameterKey != "k" || data.OldValue != "1" || data.NewValue != "2" || len(data.Votes) != 1 || data.Votes[0].DelegatorID != "node-003" {
+		t.Errorf("event data = %+v", data)
+	}
+
+	if topic != ChangelogBulletinTopic || len(posted) != 1 || !strings.Contains(posted[0], event.Hash) || !strings.Contains(posted[0], "node-002 yes 1.50 for node-003") {
+		t.Errorf("posted %q on %q", posted, topic)
+	}
+}
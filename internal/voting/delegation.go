@@ -0,0 +1,321 @@
+// Package voting - delegation.go
+// 投票委托（流动民主）：不关注治理的节点可以把投票权委托给信任的节点。
+// 委托可按提案类型限定范围，随时撤销，并可传递（A→B→C）。
+// 计票时未直接投票的委托人沿委托链找到第一个直接投票的节点，
+// 其投票权按该节点的选择计入；链上出现循环或无人投票时不计入。
+// 直接投票总是优先于委托。每条委托的去向都随计票结果公开。
+
+package voting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrSelfDelegation     = errors.New("cannot delegate to self")
+	ErrDelegationCycle    = errors.New("delegation would create a cycle")
+	ErrDelegationNotFound = errors.New("delegation not found")
+	ErrInvalidScope       = errors.New("invalid delegation scope")
+)
+
+// voteTypes 所有提案类型（校验委托范围与检查循环时使用）
+var voteTypes = []VoteType{VoteKick, VoteRestore, VotePromote, VoteDemote, VoteProposal}
+
+// Delegation 投票委托记录
+type Delegation struct {
+	ID          string     `json:"id"`
+	DelegatorID string     `json:"delegator_id"`
+	DelegateID  string     `json:"delegate_id"`
+	Scope       VoteType   `json:"scope,omitempty"` // 限定的提案类型，空表示全部类型
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	Signature   []byte     `json:"signature"`
+}
+
+// Active 委托是否有效
+func (d *Delegation) Active() bool {
+	return d.RevokedAt == nil
+}
+
+// 委托在计票中的去向
+const (
+	DelegationCounted = "counted" // 计入链末直接投票者的选择
+	DelegationCycle   = "cycle"   // 委托链成环，不计入
+	DelegationNoVote  = "no_vote" // 链上无人直接投票，不计入
+)
+
+// DelegatedVote 计票中一条委托的去向
+type DelegatedVote struct {
+	DelegatorID string     `json:"delegator_id"`
+	Chain       []string   `json:"chain"`              // 委托链（不含委托人），依次为各级受托人
+	VoterID     string     `json:"voter_id,omitempty"` // 最终代为投票的节点
+	Choice      VoteChoice `json:"choice,omitempty"`
+	Weight      float64    `json:"weight"`
+	Status      string     `json:"status"`
+}
+
+// Delegate 把本节点的投票权委托给 delegateID
+// scope 为空时适用于所有提案类型；同一范围的新委托替换旧委托。
+func (v *VotingManager) Delegate(delegateID string, scope VoteType) (*Delegation, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	d := &Delegation{
+		DelegatorID: v.config.NodeID,
+		DelegateID:  delegateID,
+		Scope:       scope,
+		CreatedAt:   time.Now(),
+	}
+	if err := v.checkDelegation(d); err != nil {
+		return nil, err
+	}
+	d.ID = generateDelegationID(d)
+	if err := v.signDelegation(d); err != nil {
+		return nil, err
+	}
+
+	v.delegations[delegationKey(d.DelegatorID, d.Scope)] = d
+	copied := *d
+	return &copied, nil
+}
+
+// RevokeDelegation 撤销本节点在 scope 范围内的委托
+func (v *VotingManager) RevokeDelegation(scope VoteType) (*Delegation, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	d, exists := v.delegations[delegationKey(v.config.NodeID, scope)]
+	if !exists || !d.Active() {
+		return nil, ErrDelegationNotFound
+	}
+	revoked := *d
+	now := time.Now()
+	revoked.RevokedAt = &now
+	if err := v.signDelegation(&revoked); err != nil {
+		return nil, err
+	}
+
+	v.delegations[delegationKey(revoked.DelegatorID, revoked.Scope)] = &revoked
+	copied := revoked
+	return &copied, nil
+}
+
+// ReceiveDelegation 接收其他节点广播的委托或撤销记录
+// 同一委托人同一范围只保留最新的记录；签名无效的记录被拒绝。
+func (v *VotingManager) ReceiveDelegation(d *Delegation) error {
+	if d == nil {
+		return errors.New("delegation is nil")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if d.Active() {
+		// 远端记录只做静态检查，循环在计票时处理（委托可能在各节点间并发生效）
+		if d.DelegatorID == d.DelegateID {
+			return ErrSelfDelegation
+		}
+		if !validScope(d.Scope) {
+			return ErrInvalidScope
+		}
+	}
+	if v.verifyFunc != nil {
+		valid, err := v.verifyFunc(d.DelegatorID, delegationSignData(d), d.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to verify signature: %w", err)
+		}
+		if !valid {
+			return errors.New("invalid delegation signature")
+		}
+	}
+
+	key := delegationKey(d.DelegatorID, d.Scope)
+	if existing, ok := v.delegations[key]; ok && !delegationNewer(d, existing) {
+		return nil
+	}
+	recorded := *d
+	v.delegations[key] = &recorded
+	return nil
+}
+
+// ListDelegations 列出有效委托；nodeID 非空时只返回该节点委托出去或收到的
+func (v *VotingManager) ListDelegations(nodeID string) []*Delegation {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var result []*Delegation
+	for _, d := range v.delegations {
+		if !d.Active() {
+			continue
+		}
+		if nodeID != "" && d.DelegatorID != nodeID && d.DelegateID != nodeID {
+			continue
+		}
+		copied := *d
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// TallyProposal 计算提案当前的计票结果（含委托去向），不改变提案状态
+func (v *VotingManager) TallyProposal(proposalID string) (*ProposalResult, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	proposal, exists := v.proposals[proposalID]
+	if !exists {
+		return nil, errors.New("proposal not found")
+	}
+	if proposal.Result != nil {
+		copied := *proposal.Result
+		return &copied, nil
+	}
+	return v.calculateResult(proposal), nil
+}
+
+// === 内部方法 ===
+
+// checkDelegation 校验本节点发起的委托（调用方持有锁）
+func (v *VotingManager) checkDelegation(d *Delegation) error {
+	if d.DelegateID == "" {
+		return errors.New("delegate ID is required")
+	}
+	if d.DelegateID == d.DelegatorID {
+		return ErrSelfDelegation
+	}
+	if !validScope(d.Scope) {
+		return fmt.Errorf("%w: %s", ErrInvalidScope, d.Scope)
+	}
+
+	// 不限范围的委托需要对每种提案类型都不成环
+	scopes := []VoteType{d.Scope}
+	if d.Scope == "" {
+		scopes = voteTypes
+	}
+	for _, scope := range scopes {
+		visited := map[string]bool{d.DelegatorID: true}
+		for cur := d.DelegateID; cur != ""; cur = v.delegateFor(cur, scope) {
+			if visited[cur] {
+				return fmt.Errorf("%w: via %s for %s proposals", ErrDelegationCycle, cur, scope)
+			}
+			visited[cur] = true
+		}
+	}
+	return nil
+}
+
+// delegateFor 节点对某类提案的受托人：限定范围的委托优先于不限范围的委托
+func (v *VotingManager) delegateFor(nodeID string, voteType VoteType) string {
+	if d, ok := v.delegations[delegationKey(nodeID, voteType)]; ok && d.Active() {
+		return d.DelegateID
+	}
+	if d, ok := v.delegations[delegationKey(nodeID, "")]; ok && d.Active() {
+		return d.DelegateID
+	}
+	return ""
+}
+
+// resolveDelegations 解析提案中所有未直接投票的委托人（调用方持有锁）
+func (v *VotingManager) resolveDelegations(proposal *Proposal) []*DelegatedVote {
+	delegators := make(map[string]bool)
+	for _, d := range v.delegations {
+		if !d.Active() || (d.Scope != "" && d.Scope != proposal.Type) {
+			continue
+		}
+		if _, voted := proposal.Votes[d.DelegatorID]; voted {
+			continue
+		}
+		delegators[d.DelegatorID] = true
+	}
+
+	ids := make([]string, 0, len(delegators))
+	for id := range delegators {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var result []*DelegatedVote
+	for _, id := range ids {
+		weight, err := v.proposalPower(proposal, id)
+		if err != nil || weight <= 0 {
+			continue // 不在快照中的节点没有可委托的投票权
+		}
+		dv := &DelegatedVote{DelegatorID: id, Weight: weight, Status: DelegationNoVote}
+		visited := map[string]bool{id: true}
+		for cur := v.delegateFor(id, proposal.Type); cur != ""; cur = v.delegateFor(cur, proposal.Type) {
+			if visited[cur] {
+				dv.Status = DelegationCycle
+				break
+			}
+			visited[cur] = true
+			dv.Chain = append(dv.Chain, cur)
+			if vote, voted := proposal.Votes[cur]; voted {
+				dv.VoterID = cur
+				dv.Choice = vote.Choice
+				dv.Status = DelegationCounted
+				break
+			}
+		}
+		result = append(result, dv)
+	}
+	return result
+}
+
+func (v *VotingManager) signDelegation(d *Delegation) error {
+	if v.signFunc == nil {
+		return nil
+	}
+	sig, err := v.signFunc(delegationSignData(d))
+	if err != nil {
+		return fmt.Errorf("failed to sign delegation: %w", err)
+	}
+	d.Signature = sig
+	return nil
+}
+
+func validScope(scope VoteType) bool {
+	if scope == "" {
+		return true
+	}
+	for _, t := range voteTypes {
+		if t == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// delegationNewer 记录 a 是否比 b 新（撤销视为晚于同一委托的创建）
+func delegationNewer(a, b *Delegation) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.RevokedAt != nil && b.RevokedAt == nil
+}
+
+func delegationKey(delegatorID string, scope VoteType) string {
+	return delegatorID + "|" + string(scope)
+}
+
+func generateDelegationID(d *Delegation) string {
+	data := fmt.Sprintf("%s|%s|%s|%d", d.DelegatorID, d.DelegateID, d.Scope, d.CreatedAt.UnixNano())
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:16])
+}
+
+func delegationSignData(d *Delegation) []byte {
+	revoked := int64(0)
+	if d.RevokedAt != nil {
+		revoked = d.RevokedAt.UnixNano()
+	}
+	return []byte(fmt.Sprintf("delegation|%s|%s|%s|%d|%d",
+		d.DelegatorID, d.DelegateID, d.Scope, d.CreatedAt.UnixNano(), revoked))
+}
@@ -0,0 +1,187 @@
+package voting
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// createDelegationManager 本节点与 a-e 五个节点，投票权均为 0.7*50 + 0.3*30 = 44
+// 法定人数设为不可达，提案保持进行中以便观察计票
+func createDelegationManager(t *testing.T) *VotingManager {
+	config := createTestConfig(t)
+	config.QuorumThreshold = 1.1
+	vm, err := NewVotingManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create voting manager: %v", err)
+	}
+	for _, id := range []string{config.NodeID, "a", "b", "c", "d", "e"} {
+		vm.RegisterNode(id, 50, 30)
+	}
+	return vm
+}
+
+func receiveDelegation(t *testing.T, vm *VotingManager, from, to string, scope VoteType) {
+	t.Helper()
+	d := &Delegation{DelegatorID: from, DelegateID: to, Scope: scope, CreatedAt: time.Now()}
+	if err := vm.ReceiveDelegation(d); err != nil {
+		t.Fatalf("ReceiveDelegation(%s->%s) error = %v", from, to, err)
+	}
+}
+
+func delegatedByID(result *ProposalResult) map[string]*DelegatedVote {
+	m := make(map[string]*DelegatedVote)
+	for _, dv := range result.Delegations {
+		m[dv.DelegatorID] = dv
+	}
+	return m
+}
+
+func TestDelegationValidation(t *testing.T) {
+	vm := createDelegationManager(t)
+
+	if _, err := vm.Delegate(vm.config.NodeID, ""); !errors.Is(err, ErrSelfDelegation) {
+		t.Errorf("Delegate(self) error = %v, want ErrSelfDelegation", err)
+	}
+	if _, err := vm.Delegate("a", "budget"); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("Delegate(invalid scope) error = %v, want ErrInvalidScope", err)
+	}
+
+	// c -> a -> 本节点，本节点再委托给 c 会成环
+	receiveDelegation(t, vm, "a", vm.config.NodeID, "")
+	receiveDelegation(t, vm, "c", "a", VoteKick)
+	if _, err := vm.Delegate("c", VoteKick); !errors.Is(err, ErrDelegationCycle) {
+		t.Errorf("Delegate(cycle) error = %v, want ErrDelegationCycle", err)
+	}
+	// 不限范围的委托对任一类型成环都拒绝
+	if _, err := vm.Delegate("c", ""); !errors.Is(err, ErrDelegationCycle) {
+		t.Errorf("Delegate(unscoped cycle) error = %v, want ErrDelegationCycle", err)
+	}
+	// 只对 promote 提案委托给 c 不成环（c 的委托仅限 kick）
+	if _, err := vm.Delegate("c", VotePromote); err != nil {
+		t.Errorf("Delegate(promote) error = %v", err)
+	}
+
+	if _, err := vm.RevokeDelegation(VoteDemote); !errors.Is(err, ErrDelegationNotFound) {
+		t.Errorf("RevokeDelegation(missing) error = %v, want ErrDelegationNotFound", err)
+	}
+}
+
+func TestDelegatedTally(t *testing.T) {
+	vm := createDelegationManager(t)
+	self := vm.config.NodeID
+
+	if _, err := vm.Delegate("a", ""); err != nil {
+		t.Fatalf("Delegate() error = %v", err)
+	}
+	receiveDelegation(t, vm, "b", "c", VoteKick)
+	receiveDelegation(t, vm, "c", "a", "")
+	receiveDelegation(t, vm, "d", "e", "")
+	receiveDelegation(t, vm, "e", "d", "")
+
+	proposal, err := vm.CreateProposal(VoteKick, "bad-node", "spam")
+	if err != nil {
+		t.Fatalf("CreateProposal() error = %v", err)
+	}
+	vm.ReceiveVote(&Vote{ProposalID: proposal.ID, VoterID: "a", Choice: ChoiceYes, Timestamp: time.Now()})
+
+	result, err := vm.TallyProposal(proposal.ID)
+	if err != nil {
+		t.Fatalf("TallyProposal() error = %v", err)
+	}
+	// a 直接投票，本节点、b（经 c）、c 委托计入，d/e 成环
+	if !approxEqual(result.YesWeight, 4*44) || !approxEqual(result.DelegatedWeight, 3*44) {
+		t.Errorf("yes = %v, delegated = %v, want 176 and 132", result.YesWeight, result.DelegatedWeight)
+	}
+	dvs := delegatedByID(result)
+	if dv := dvs["b"]; dv == nil || dv.Status != DelegationCounted || dv.VoterID != "a" ||
+		len(dv.Chain) != 2 || dv.Chain[0] != "c" {
+		t.Errorf("b delegation = %+v, want counted via c to a", dv)
+	}
+	if dvs["d"].Status != DelegationCycle || dvs["e"].Status != DelegationCycle {
+		t.Errorf("expected cycle for d and e, got %+v %+v", dvs["d"], dvs["e"])
+	}
+
+	// 直接投票优先于委托；撤销后不再计入
+	vm.ReceiveVote(&Vote{ProposalID: proposal.ID, VoterID: "b", Choice: ChoiceNo, Timestamp: time.Now()})
+	if _, err := vm.RevokeDelegation(""); err != nil {
+		t.Fatalf("RevokeDelegation() error = %v", err)
+	}
+	result, _ = vm.TallyProposal(proposal.ID)
+	if !approxEqual(result.YesWeight, 2*44) || !approxEqual(result.NoWeight, 44) {
+		t.Errorf("yes = %v, no = %v, want 88 and 44", result.YesWeight, result.NoWeight)
+	}
+	dvs = delegatedByID(result)
+	if dvs["b"] != nil || dvs[self] != nil {
+		t.Errorf("direct voter and revoked delegator should not appear: %+v", result.Delegations)
+	}
+
+	// 限定 kick 的委托不影响其他类型的提案
+	promote, _ := vm.CreateProposal(VotePromote, "good-node", "uptime")
+	result, _ = vm.TallyProposal(promote.ID)
+	if dv := delegatedByID(result)["b"]; dv != nil {
+		t.Errorf("kick-scoped delegation applied to promote proposal: %+v", dv)
+	}
+	if dv := delegatedByID(result)["c"]; dv == nil || dv.Status != DelegationNoVote {
+		t.Errorf("c delegation = %+v, want no_vote", dv)
+	}
+}
+
+func TestDelegationFinalizesProposal(t *testing.T) {
+	vm := createDelegationManager(t)
+	vm.config.QuorumThreshold = 0.5
+	receiveDelegation(t, vm, "b", "a", "")
+	receiveDelegation(t, vm, "c", "a", "")
+
+	proposal, _ := vm.CreateProposal(VoteKick, "bad-node", "spam")
+	vm.ReceiveVote(&Vote{ProposalID: proposal.ID, VoterID: "a", Choice: ChoiceYes, Timestamp: time.Now()})
+
+	// a 加上两份委托达到 3/6 的法定人数
+	if proposal.Status != ProposalPassed {
+		t.Fatalf("status = %s, want passed", proposal.Status)
+	}
+	if len(proposal.Result.Delegations) != 2 || !approxEqual(proposal.Result.DelegatedWeight, 88) {
+		t.Errorf("finalized result should disclose delegations: %+v", proposal.Result)
+	}
+}
+
+func TestReceiveDelegationOrdering(t *testing.T) {
+	vm := createDelegationManager(t)
+	created := time.Now()
+
+	vm.ReceiveDelegation(&Delegation{DelegatorID: "b", DelegateID: "a", CreatedAt: created})
+	revokedAt := created.Add(time.Minute)
+	vm.ReceiveDelegation(&Delegation{DelegatorID: "b", DelegateID: "a", CreatedAt: created, RevokedAt: &revokedAt})
+	// 迟到的旧记录不会覆盖撤销
+	vm.ReceiveDelegation(&Delegation{DelegatorID: "b", DelegateID: "a", CreatedAt: created})
+	if got := vm.ListDelegations("b"); len(got) != 0 {
+		t.Errorf("expected revoked delegation, got %+v", got)
+	}
+
+	vm.SetVerifyFunc(func(pubKey string, data, signature []byte) (bool, error) {
+		return len(signature) > 0, nil
+	})
+	err := vm.ReceiveDelegation(&Delegation{DelegatorID: "c", DelegateID: "a", CreatedAt: created})
+	if err == nil {
+		t.Error("expected unsigned delegation to be rejected")
+	}
+}
+
+func TestDelegationPersistence(t *testing.T) {
+	config := createTestConfig(t)
+	vm, _ := NewVotingManager(config)
+	vm.SetSignFunc(mockSignFunc)
+	vm.Start()
+	if _, err := vm.Delegate("a", VoteKick); err != nil {
+		t.Fatalf("Delegate() error = %v", err)
+	}
+	vm.Stop()
+
+	reloaded, _ := NewVotingManager(config)
+	reloaded.Start()
+	defer reloaded.Stop()
+	got := reloaded.ListDelegations(config.NodeID)
+	if len(got) != 1 || got[0].DelegateID != "a" || got[0].Scope != VoteKick || len(got[0].Signature) == 0 {
+		t.Errorf("delegations after reload = %+v", got)
+	}
+}
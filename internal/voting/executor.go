@@ -0,0 +1,73 @@
+// Package voting - executor.go
+// 提案执行：投票管理器自身只维护节点状态（剔除、恢复），提案的实际效果由按提案类型
+// 注册的执行器完成，如断开被剔除节点的连接、撤销超级节点身份。参数提案由
+// ParameterApplier 执行（见 changelog.go）。执行结果记录在提案的 execution 字段。
+
+package voting
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrProposalNotReady = errors.New("proposal has not reached quorum before its deadline")
+
+// Executor 执行已通过提案的效果
+// 在投票管理器持有锁时同步调用，不能回调 VotingManager。
+type Executor func(proposal *Proposal) error
+
+// Execution 提案的执行结果
+type Execution struct {
+	ExecutedAt time.Time `json:"executed_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// RegisterExecutor 注册提案类型的执行器，同一类型的新执行器替换旧的；fn 为 nil 时取消注册
+func (v *VotingManager) RegisterExecutor(voteType VoteType, fn Executor) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if fn == nil {
+		delete(v.executors, voteType)
+		return
+	}
+	v.executors[voteType] = fn
+}
+
+// executeLocked 调用提案类型的执行器并记录结果，执行成功时返回 true（调用方持有锁）
+func (v *VotingManager) executeLocked(proposal *Proposal) bool {
+	fn := v.executors[proposal.Type]
+	if fn == nil {
+		return false
+	}
+	exec := &Execution{ExecutedAt: time.Now()}
+	if err := fn(proposal); err != nil {
+		exec.Error = err.Error()
+	}
+	proposal.Execution = exec
+	return exec.Error == ""
+}
+
+// FinalizeProposal 结束提案：达到法定人数时按当前计票结束，过了截止时间仍未达到时记为过期。
+// 截止前未达到法定人数时返回 ErrProposalNotReady。
+func (v *VotingManager) FinalizeProposal(proposalID string) (*Proposal, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	proposal, exists := v.proposals[proposalID]
+	if !exists {
+		return nil, errors.New("proposal not found")
+	}
+	if proposal.Status != ProposalPending {
+		return proposal, nil
+	}
+
+	v.tryFinalizeProposal(proposal)
+	if proposal.Status == ProposalPending {
+		now := time.Now()
+		if !now.After(proposal.ExpiresAt) {
+			return nil, ErrProposalNotReady
+		}
+		v.expireProposalLocked(proposal, now)
+	}
+	return proposal, nil
+}
@@ -0,0 +1,203 @@
+package voting
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 测试用签名：节点ID + 数据哈希，能区分签名者和篡改
+func signAs(nodeID string) SignFunc {
+	return func(data []byte) ([]byte, error) {
+		return testSignature(nodeID, data), nil
+	}
+}
+
+func testSignature(nodeID string, data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return append([]byte(nodeID+":"), sum[:]...)
+}
+
+func testVerify(signer string, data, signature []byte) (bool, error) {
+	return string(signature) == string(testSignature(signer, data)), nil
+}
+
+// createEqualPowerManager 每个活跃节点 1 票，所有节点都投票才达到法定人数
+func createEqualPowerManager(t *testing.T, nodeIDs ...string) *VotingManager {
+	config := createTestConfig(t)
+	config.PowerFormula = PowerEqual
+	config.QuorumThreshold = 1
+	config.SupermajorityThreshold = 2.0 / 3
+	vm, err := NewVotingManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create voting manager: %v", err)
+	}
+	for _, id := range nodeIDs {
+		vm.RegisterNode(id, 50, 0)
+	}
+	return vm
+}
+
+func castVotes(vm *VotingManager, proposalID string, choices map[string]VoteChoice) {
+	for id, choice := range choices {
+		vm.config.NodeID = id
+		vm.CastVote(proposalID, choice, "")
+	}
+}
+
+func TestSupermajority(t *testing.T) {
+	vm := createEqualPowerManager(t, "a", "b", "c", "d", "e")
+	choices := map[string]VoteChoice{"a": ChoiceYes, "b": ChoiceYes, "c": ChoiceYes, "d": ChoiceNo, "e": ChoiceNo}
+
+	// 60% 赞成：普通提案通过，剔除提案未达到 2/3
+	vm.config.NodeID = "a"
+	kick, _ := vm.CreateProposal(VoteKick, "e", "spam")
+	plain, _ := vm.CreateProposal(VoteProposal, "network", "adopt code of conduct")
+	castVotes(vm, kick.ID, choices)
+	castVotes(vm, plain.ID, choices)
+
+	if p, _ := vm.GetProposal(kick.ID); p.Status != ProposalRejected {
+		t.Errorf("kick with 60%% yes: status = %s, want rejected", p.Status)
+	}
+	if p, _ := vm.GetProposal(plain.ID); p.Status != ProposalPassed {
+		t.Errorf("plain proposal with 60%% yes: status = %s, want passed", p.Status)
+	}
+	if vm.GetNodeStatus("e") != StatusActive {
+		t.Error("node removed by rejected kick proposal")
+	}
+}
+
+func TestExecutors(t *testing.T) {
+	vm := createEqualPowerManager(t, "a", "b", "c")
+	all := map[string]VoteChoice{"a": ChoiceYes, "b": ChoiceYes, "c": ChoiceYes}
+
+	var kicked, demoted []string
+	vm.RegisterExecutor(VoteKick, func(p *Proposal) error {
+		kicked = append(kicked, p.TargetNodeID)
+		return nil
+	})
+	vm.RegisterExecutor(VoteDemote, func(p *Proposal) error {
+		if p.TargetNodeID == "c" {
+			return errors.New("not a supernode")
+		}
+		demoted = append(demoted, p.TargetNodeID)
+		return nil
+	})
+
+	vm.config.NodeID = "a"
+	kick, _ := vm.CreateProposal(VoteKick, "outsider", "spam")
+	castVotes(vm, kick.ID, all)
+	if len(kicked) != 1 || kicked[0] != "outsider" || kick.Execution == nil || kick.Execution.Error != "" {
+		t.Errorf("kicked = %v, execution = %+v", kicked, kick.Execution)
+	}
+
+	vm.config.NodeID = "a"
+	demote, _ := vm.CreateProposal(VoteDemote, "b", "missed duties")
+	failed, _ := vm.CreateProposal(VoteDemote, "c", "missed duties")
+	castVotes(vm, demote.ID, all)
+	castVotes(vm, failed.ID, all)
+	if len(demoted) != 1 || demoted[0] != "b" {
+		t.Errorf("demoted = %v", demoted)
+	}
+	if failed.Status != ProposalPassed || failed.Execution == nil || failed.Execution.Error != "not a supernode" {
+		t.Errorf("failed demotion = %+v, execution = %+v", failed, failed.Execution)
+	}
+
+	// 没有执行器的晋升提案通过后不执行，也不记入变更日志
+	vm.config.NodeID = "a"
+	promote, _ := vm.CreateProposal(VotePromote, "b", "")
+	castVotes(vm, promote.ID, all)
+	if promote.Execution != nil {
+		t.Errorf("promotion executed without executor: %+v", promote.Execution)
+	}
+
+	actions := map[ChangeAction]int{}
+	for _, e := range vm.GetChangelog(0) {
+		actions[e.Action]++
+	}
+	if actions[ActionBan] != 1 || actions[ActionDemote] != 1 || actions[ActionPromote] != 0 {
+		t.Errorf("changelog actions = %v", actions)
+	}
+}
+
+func TestFinalizeAtDeadline(t *testing.T) {
+	vm := createEqualPowerManager(t, "a", "b", "c", "d")
+	vm.config.QuorumThreshold = 0.5
+
+	vm.config.NodeID = "a"
+	quorate, _ := vm.CreateProposal(VoteProposal, "network", "quorum reached by delegation")
+	idle, _ := vm.CreateProposal(VoteProposal, "bulletin", "nobody votes")
+	if _, err := vm.FinalizeProposal(idle.ID); !errors.Is(err, ErrProposalNotReady) {
+		t.Errorf("FinalizeProposal before deadline error = %v", err)
+	}
+
+	// a 投票后 b 委托给 a：委托不触发结束，截止时计入
+	vm.CastVote(quorate.ID, ChoiceYes, "")
+	vm.config.NodeID = "b"
+	if _, err := vm.Delegate("a", ""); err != nil {
+		t.Fatalf("Delegate() error = %v", err)
+	}
+	if quorate.Status != ProposalPending {
+		t.Fatalf("status before deadline = %s", quorate.Status)
+	}
+
+	quorate.ExpiresAt = time.Now().Add(-time.Second)
+	idle.ExpiresAt = time.Now().Add(-time.Second)
+	vm.checkExpiredProposals()
+	if quorate.Status != ProposalPassed || quorate.Result.DelegatedWeight != 1 {
+		t.Errorf("quorate proposal = %s, result = %+v", quorate.Status, quorate.Result)
+	}
+	if idle.Status != ProposalExpired {
+		t.Errorf("idle proposal = %s, want expired", idle.Status)
+	}
+	if p, err := vm.FinalizeProposal(idle.ID); err != nil || p.Status != ProposalExpired {
+		t.Errorf("FinalizeProposal(finished) = %v, %v", p, err)
+	}
+}
+
+func TestReceiveProposal(t *testing.T) {
+	alice := createEqualPowerManager(t, "alice", "bob")
+	alice.config.NodeID = "alice"
+	alice.SetSignFunc(signAs("alice"))
+	bob := createEqualPowerManager(t, "alice", "bob")
+	bob.config.NodeID = "bob"
+	bob.SetVerifyFunc(testVerify)
+
+	proposal, err := alice.CreateCanaryParameterProposal("bulletin.max_per_hour", "30", "reduce spam", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateCanaryParameterProposal() error = %v", err)
+	}
+	if len(proposal.Signature) == 0 {
+		t.Fatal("proposal not signed")
+	}
+
+	// 篡改内容
+	tampered := *proposal
+	tampered.Parameter = &ParameterChange{Key: "bulletin.max_per_hour", Value: "3000"}
+	if err := bob.ReceiveProposal(&tampered); err == nil {
+		t.Error("tampered proposal accepted")
+	}
+	// 篡改ID对应的字段
+	forged := *proposal
+	forged.TargetNodeID = "bob"
+	if err := bob.ReceiveProposal(&forged); err == nil {
+		t.Error("proposal with mismatched ID accepted")
+	}
+
+	if err := bob.ReceiveProposal(proposal); err != nil {
+		t.Fatalf("ReceiveProposal() error = %v", err)
+	}
+	if err := bob.ReceiveProposal(proposal); err == nil {
+		t.Error("duplicate proposal accepted")
+	}
+	received, _ := bob.GetProposal(proposal.ID)
+	if received == proposal || received.Canary.Trial != time.Hour || received.Weighting.TotalPower != 2 {
+		t.Errorf("received = %+v", received)
+	}
+
+	// 收到的提案在本节点可以投票
+	if _, err := bob.CastVote(proposal.ID, ChoiceYes, ""); err != nil {
+		t.Errorf("CastVote() on received proposal error = %v", err)
+	}
+}
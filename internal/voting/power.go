@@ -0,0 +1,145 @@
+// Package voting - power.go
+// 投票权计算：支持一节点一票、信誉+抵押线性加权、按抵押锁定时长加权三种公式。
+// 投票权在提案创建时快照，之后的信誉或抵押变化不影响该提案。
+
+package voting
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PowerFormula 投票权公式
+type PowerFormula string
+
+const (
+	PowerEqual        PowerFormula = "equal"         // 一节点一票
+	PowerLinear       PowerFormula = "linear"        // α·信誉 + β·抵押
+	PowerTimeWeighted PowerFormula = "time_weighted" // α·信誉 + β·抵押·min(1, 锁定时长/成熟期)
+)
+
+// VotingWeighting 提案的投票权快照（随提案详情公开）
+type VotingWeighting struct {
+	Formula          PowerFormula       `json:"formula"`
+	Description      string             `json:"description"`
+	ReputationWeight float64            `json:"reputation_weight"`
+	StakeWeight      float64            `json:"stake_weight"`
+	StakeMaturity    string             `json:"stake_maturity,omitempty"` // time_weighted 的成熟期
+	SnapshotAt       time.Time          `json:"snapshot_at"`
+	TotalPower       float64            `json:"total_power"`
+	Powers           map[string]float64 `json:"powers"` // nodeID -> 投票权
+}
+
+// formula 返回生效的公式（未配置时为 linear，与旧版本一致）
+func (v *VotingManager) formula() PowerFormula {
+	if v.config.PowerFormula == "" {
+		return PowerLinear
+	}
+	return v.config.PowerFormula
+}
+
+// describeFormula 生成可读的公式说明
+func (v *VotingManager) describeFormula() string {
+	repWeight, stakeWeight := v.config.ReputationWeight, v.config.StakeWeight
+	switch v.formula() {
+	case PowerEqual:
+		return "power = 1 per active node"
+	case PowerTimeWeighted:
+		return fmt.Sprintf("power = %.2f*reputation + %.2f*stake*min(1, stake_age/%s)", repWeight, stakeWeight, v.config.StakeMaturity)
+	default:
+		return fmt.Sprintf("power = %.2f*reputation + %.2f*stake", repWeight, stakeWeight)
+	}
+}
+
+// nodePower 按配置公式计算节点在 at 时刻的投票权
+func (v *VotingManager) nodePower(node *NodeTrust, at time.Time) float64 {
+	switch v.formula() {
+	case PowerEqual:
+		return 1
+	case PowerTimeWeighted:
+		return v.config.ReputationWeight*node.Reputation +
+			v.config.StakeWeight*node.Stake*v.stakeAgeFactor(node, at)
+	default:
+		return v.config.ReputationWeight*node.Reputation +
+			v.config.StakeWeight*node.Stake
+	}
+}
+
+// stakeAgeFactor 抵押成熟度 [0, 1]，未配置成熟期时视为已成熟
+func (v *VotingManager) stakeAgeFactor(node *NodeTrust, at time.Time) float64 {
+	if v.config.StakeMaturity <= 0 {
+		return 1
+	}
+	since := node.StakeSince
+	if since.IsZero() {
+		since = node.JoinedAt
+	}
+	if since.IsZero() || !at.After(since) {
+		return 0
+	}
+	return math.Min(1, float64(at.Sub(since))/float64(v.config.StakeMaturity))
+}
+
+// adjustStakeSince 抵押增加时按币龄加权平滑起始时间，减少时保留原有时长
+// 旧版本保存的节点没有起始时间，按加入时间起算，与 stakeAgeFactor 一致，同步信任信息时不会清零已累积的时长。
+func adjustStakeSince(node *NodeTrust, newStake float64, now time.Time) {
+	if newStake <= 0 {
+		node.StakeSince = time.Time{}
+		return
+	}
+	if node.Stake <= 0 {
+		node.StakeSince = now
+		return
+	}
+	if node.StakeSince.IsZero() {
+		node.StakeSince = node.JoinedAt
+		if node.StakeSince.IsZero() {
+			node.StakeSince = now
+		}
+	}
+	if newStake > node.Stake {
+		age := now.Sub(node.StakeSince)
+		kept := time.Duration(float64(age) * node.Stake / newStake)
+		node.StakeSince = now.Add(-kept)
+	}
+}
+
+// snapshotPower 快照所有活跃节点的投票权（调用方需持有锁）
+func (v *VotingManager) snapshotPower(at time.Time) *VotingWeighting {
+	w := &VotingWeighting{
+		Formula:          v.formula(),
+		Description:      v.describeFormula(),
+		ReputationWeight: v.config.ReputationWeight,
+		StakeWeight:      v.config.StakeWeight,
+		SnapshotAt:       at,
+		Powers:           make(map[string]float64),
+	}
+	if w.Formula == PowerTimeWeighted {
+		w.StakeMaturity = v.config.StakeMaturity.String()
+	}
+	for nodeID, node := range v.nodes {
+		if node.Status != StatusActive {
+			continue
+		}
+		power := v.nodePower(node, at)
+		w.Powers[nodeID] = power
+		w.TotalPower += power
+	}
+	return w
+}
+
+// proposalPower 投票者在提案中的投票权；旧提案没有快照时按当前值计算
+// 不在快照中的投票者（未登记或快照后才登记的节点）与未登记节点一样只按信誉计权，快照后增加的抵押不计入。
+func (v *VotingManager) proposalPower(proposal *Proposal, voterID string) (float64, error) {
+	if proposal.Weighting == nil {
+		return v.calculateVoteWeight(voterID), nil
+	}
+	if power, ok := proposal.Weighting.Powers[voterID]; ok {
+		return power, nil
+	}
+	if v.formula() == PowerEqual {
+		return 1, nil
+	}
+	return v.config.ReputationWeight * v.getNodeReputation(voterID), nil
+}
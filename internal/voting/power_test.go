@@ -0,0 +1,145 @@
+package voting
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestPowerFormulas(t *testing.T) {
+	now := time.Now()
+	node := &NodeTrust{Reputation: 80, Stake: 40, StakeSince: now.Add(-15 * 24 * time.Hour)}
+
+	tests := []struct {
+		formula PowerFormula
+		want    float64
+	}{
+		{PowerEqual, 1},
+		{PowerLinear, 0.7*80 + 0.3*40},
+		{PowerTimeWeighted, 0.7*80 + 0.3*40*0.5}, // 锁定 15 天 / 成熟期 30 天
+		{"", 0.7*80 + 0.3*40},                    // 未配置时保持线性公式
+	}
+	for _, tt := range tests {
+		config := createTestConfig(t)
+		config.PowerFormula = tt.formula
+		config.StakeMaturity = 30 * 24 * time.Hour
+		vm, _ := NewVotingManager(config)
+
+		if got := vm.nodePower(node, now); !approxEqual(got, tt.want) {
+			t.Errorf("%q: power = %v, want %v", tt.formula, got, tt.want)
+		}
+	}
+}
+
+func TestStakeAgeAdjustment(t *testing.T) {
+	now := time.Now()
+	node := &NodeTrust{Stake: 10, StakeSince: now.Add(-10 * time.Hour)}
+
+	// 抵押翻倍：原有 10 小时币龄摊薄为 5 小时
+	adjustStakeSince(node, 20, now)
+	if got := now.Sub(node.StakeSince); got != 5*time.Hour {
+		t.Errorf("stake age after increase = %v, want 5h", got)
+	}
+
+	// 减少抵押不影响时长
+	node.Stake = 20
+	adjustStakeSince(node, 5, now)
+	if got := now.Sub(node.StakeSince); got != 5*time.Hour {
+		t.Errorf("stake age after decrease = %v, want 5h", got)
+	}
+
+	adjustStakeSince(node, 0, now)
+	if !node.StakeSince.IsZero() {
+		t.Error("StakeSince should reset when stake is withdrawn")
+	}
+}
+
+func TestProposalPowerSnapshot(t *testing.T) {
+	config := createTestConfig(t)
+	config.PowerFormula = PowerTimeWeighted
+	config.StakeMaturity = 10 * 24 * time.Hour
+	vm, _ := NewVotingManager(config)
+
+	vm.RegisterNode(config.NodeID, 50, 0)
+	vm.RegisterNode("veteran", 50, 100)
+	vm.RegisterNode("newcomer", 50, 100)
+	vm.RegisterNode("suspended", 90, 100)
+	vm.nodes["veteran"].StakeSince = time.Now().Add(-20 * 24 * time.Hour)
+	vm.nodes["suspended"].Status = StatusSuspended
+
+	proposal, err := vm.CreateProposal(VoteKick, "target", "Test")
+	if err != nil {
+		t.Fatalf("CreateProposal() error = %v", err)
+	}
+
+	w := proposal.Weighting
+	if w == nil {
+		t.Fatal("proposal should carry a power snapshot")
+	}
+	if w.Formula != PowerTimeWeighted || !strings.Contains(w.Description, "stake_age") || w.StakeMaturity != "240h0m0s" {
+		t.Errorf("weighting not disclosed: %+v", w)
+	}
+	if !approxEqual(w.Powers["veteran"], 0.7*50+0.3*100) {
+		t.Errorf("veteran power = %v", w.Powers["veteran"])
+	}
+	if w.Powers["newcomer"] >= 0.7*50+0.01 {
+		t.Errorf("fresh stake should carry almost no weight, got %v", w.Powers["newcomer"])
+	}
+	if _, ok := w.Powers["suspended"]; ok {
+		t.Error("suspended nodes should not be in the snapshot")
+	}
+
+	// 快照后的抵押变化不影响本提案
+	vm.UpdateNodeTrust("newcomer", 100, 1000)
+	vm.RegisterNode("late-joiner", 90, 100)
+
+	vm.SetVerifyFunc(mockVerifyFunc)
+	err = vm.ReceiveVote(&Vote{
+		ProposalID: proposal.ID,
+		VoterID:    "newcomer",
+		Choice:     ChoiceNo,
+		Weight:     9999, // 对端声明的权重不被采信
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("ReceiveVote() error = %v", err)
+	}
+	p, _ := vm.GetProposal(proposal.ID)
+	if got := p.Votes["newcomer"].Weight; !approxEqual(got, w.Powers["newcomer"]) {
+		t.Errorf("recorded weight = %v, want snapshot %v", got, w.Powers["newcomer"])
+	}
+
+	// 快照后才登记的节点仍可投票，但只按信誉计权
+	err = vm.ReceiveVote(&Vote{ProposalID: proposal.ID, VoterID: "late-joiner", Choice: ChoiceYes, Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("late joiner: ReceiveVote() error = %v", err)
+	}
+	p, _ = vm.GetProposal(proposal.ID)
+	if got := p.Votes["late-joiner"].Weight; !approxEqual(got, 0.7*90) {
+		t.Errorf("late joiner weight = %v, want reputation-only %v", got, 0.7*90)
+	}
+}
+
+func TestStakeSincePreservedOnSync(t *testing.T) {
+	config := createTestConfig(t)
+	vm, _ := NewVotingManager(config)
+	if f := DefaultConfig("n").PowerFormula; f != "" {
+		t.Errorf("default formula = %q, want linear (unset)", f)
+	}
+
+	// 旧版本保存的节点没有 StakeSince，同步信任信息时按加入时间起算
+	joined := time.Now().Add(-20 * 24 * time.Hour)
+	vm.RegisterNode("legacy", 50, 100)
+	vm.nodes["legacy"].JoinedAt = joined
+	vm.nodes["legacy"].StakeSince = time.Time{}
+
+	vm.UpdateNodeTrust("legacy", 60, 100)
+	if got := vm.nodes["legacy"].StakeSince; !got.Equal(joined) {
+		t.Errorf("StakeSince = %v, want joined_at %v", got, joined)
+	}
+}
@@ -69,6 +69,11 @@ type Proposal struct {
 	Votes        map[string]*Vote `json:"votes"`          // 投票记录: voterID -> Vote
 	Status       ProposalStatus   `json:"status"`         // 提案状态
 	Result       *ProposalResult  `json:"result,omitempty"` // 提案结果
+	Weighting    *VotingWeighting `json:"weighting,omitempty"` // 投票权快照
+	Parameter    *ParameterChange `json:"parameter,omitempty"` // 参数变更（仅参数提案）
+	Canary       *CanaryRollout   `json:"canary,omitempty"`    // 金丝雀发布（带试运行期的参数提案）
+	Execution    *Execution       `json:"execution,omitempty"` // 通过后执行器的执行结果
+	Signature    []byte           `json:"signature,omitempty"` // 发起者签名
 }
 
 // ProposalStatus 提案状态
@@ -90,17 +95,25 @@ type ProposalResult struct {
 	YesRatio      float64   `json:"yes_ratio"`
 	Passed        bool      `json:"passed"`
 	FinalizedAt   time.Time `json:"finalized_at"`
+
+	// 委托：计入各选项的委托投票权合计，以及每条委托的去向
+	DelegatedWeight float64          `json:"delegated_weight,omitempty"`
+	Delegations     []*DelegatedVote `json:"delegations,omitempty"`
 }
 
 // NodeTrust 节点信任信息
 type NodeTrust struct {
-	NodeID     string     `json:"node_id"`
-	Reputation float64    `json:"reputation"`  // 信誉分 [0, 100]
-	Stake      float64    `json:"stake"`       // 抵押分 [0, 100]
-	Status     NodeStatus `json:"status"`
-	JoinedAt   time.Time  `json:"joined_at"`
-	LastActive time.Time  `json:"last_active"`
-	VoteCount  int        `json:"vote_count"`  // 参与投票次数
+	NodeID          string     `json:"node_id"`
+	Reputation      float64    `json:"reputation"` // 信誉分 [0, 100]
+	Stake           float64    `json:"stake"`      // 抵押分 [0, 100]
+	Status          NodeStatus `json:"status"`
+	JoinedAt        time.Time  `json:"joined_at"`
+	LastActive      time.Time  `json:"last_active"`
+	VoteCount       int        `json:"vote_count"`                  // 参与投票次数
+	StakeSince      time.Time  `json:"stake_since,omitempty"`       // 抵押锁定起始（增加抵押时按币龄加权平移）
+	Canary          bool       `json:"canary,omitempty"`            // 自愿作为参数变更的金丝雀节点
+	CanaryUpdatedAt time.Time  `json:"canary_updated_at,omitempty"` // 最近一次签名参与声明的时间
+	CanarySignature []byte     `json:"canary_signature,omitempty"`  // 该声明的签名
 }
 
 // SignFunc 签名函数类型
@@ -117,14 +130,19 @@ type VotingConfig struct {
 	NodeID            string        // 当前节点ID
 	DataDir           string        // 数据目录
 	PassThreshold     float64       // 通过阈值 (0-1)
+	SupermajorityThreshold float64  // 剔除节点、撤销超级节点的通过阈值，不高于 PassThreshold 时不生效
 	QuorumThreshold   float64       // 法定人数阈值 (0-1)
 	ProposalDuration  time.Duration // 提案持续时间
 	BufferPeriod      time.Duration // 缓冲期（防止突发操纵）
 	ReputationWeight  float64       // 信誉权重系数 α
 	StakeWeight       float64       // 抵押权重系数 β
+	PowerFormula      PowerFormula  // 投票权公式（空为 linear）
+	StakeMaturity     time.Duration // 抵押锁定多久后获得全额权重（time_weighted）
 	MinRepToVote      float64       // 最低投票信誉要求
 	MinRepToPropose   float64       // 最低提案信誉要求
 	CleanupInterval   time.Duration // 清理间隔
+	CanaryTolerance   float64       // 金丝雀组出错率、争议率可高于对照组的最大差值
+	CanaryReportWindow time.Duration // 试运行结束后等待各节点报告指标的时间
 }
 
 // DefaultConfig 返回默认配置
@@ -133,14 +151,18 @@ func DefaultConfig(nodeID string) *VotingConfig {
 		NodeID:            nodeID,
 		DataDir:           "./data/voting",
 		PassThreshold:     0.6,         // 60%通过
+		SupermajorityThreshold: 2.0 / 3, // 剔除和降级需 2/3 多数
 		QuorumThreshold:   0.3,         // 30%参与
 		ProposalDuration:  30 * time.Minute,
 		BufferPeriod:      5 * time.Minute,
 		ReputationWeight:  0.7,         // α = 0.7
 		StakeWeight:       0.3,         // β = 0.3
+		StakeMaturity:     30 * 24 * time.Hour, // 仅 PowerFormula 为 time_weighted 时生效
 		MinRepToVote:      10,          // 最低10分可投票
 		MinRepToPropose:   30,          // 最低30分可发起提案
 		CleanupInterval:   1 * time.Hour,
+		CanaryTolerance:   0.02,        // 高出 2 个百分点即回滚
+		CanaryReportWindow: 10 * time.Minute,
 	}
 }
 
@@ -149,6 +171,7 @@ type VotingManager struct {
 	config    *VotingConfig
 	proposals map[string]*Proposal // proposalID -> Proposal
 	nodes     map[string]*NodeTrust // nodeID -> NodeTrust
+	delegations map[string]*Delegation // delegator|scope -> 最新的委托或撤销记录
 	mu        sync.RWMutex
 
 	signFunc      SignFunc
@@ -161,6 +184,16 @@ type VotingManager struct {
 	onProposalFinalized func(*Proposal)
 	onNodeKicked      func(nodeID string)
 	onNodeRestored    func(nodeID string)
+	onChangeApplied   func(*ChangelogEntry)
+
+	executors      map[VoteType]Executor
+	applyParameter ParameterApplier
+	changelog      []*ChangelogEntry
+
+	// 金丝雀发布
+	canaryMetrics     MetricsFunc
+	onCanaryReport    func(*CanaryReport)
+	onCanaryEvaluated func(*Proposal)
 
 	stopCh chan struct{}
 	wg     sync.WaitGroup
@@ -188,6 +221,8 @@ func NewVotingManager(config *VotingConfig) (*VotingManager, error) {
 		config:    config,
 		proposals: make(map[string]*Proposal),
 		nodes:     make(map[string]*NodeTrust),
+		delegations: make(map[string]*Delegation),
+		executors: make(map[VoteType]Executor),
 		stopCh:    make(chan struct{}),
 	}
 
@@ -282,14 +317,16 @@ func (v *VotingManager) RegisterNode(nodeID string, reputation, stake float64) e
 		return errors.New("node already registered")
 	}
 
-	v.nodes[nodeID] = &NodeTrust{
+	node := &NodeTrust{
 		NodeID:     nodeID,
 		Reputation: reputation,
-		Stake:      stake,
 		Status:     StatusActive,
 		JoinedAt:   time.Now(),
 		LastActive: time.Now(),
 	}
+	adjustStakeSince(node, stake, node.JoinedAt)
+	node.Stake = stake
+	v.nodes[nodeID] = node
 
 	return nil
 }
@@ -304,9 +341,11 @@ func (v *VotingManager) UpdateNodeTrust(nodeID string, reputation, stake float64
 		return errors.New("node not found")
 	}
 
+	now := time.Now()
+	adjustStakeSince(node, stake, now)
 	node.Reputation = reputation
 	node.Stake = stake
-	node.LastActive = time.Now()
+	node.LastActive = now
 
 	return nil
 }
@@ -362,6 +401,11 @@ func (v *VotingManager) CreateProposal(voteType VoteType, targetNodeID, reason s
 		}
 	}
 
+	return v.createProposalLocked(voteType, targetNodeID, nil, nil, reason)
+}
+
+// createProposalLocked 创建、签名并存储提案（调用方持有锁）
+func (v *VotingManager) createProposalLocked(voteType VoteType, targetNodeID string, param *ParameterChange, canary *CanaryRollout, reason string) (*Proposal, error) {
 	now := time.Now()
 	proposal := &Proposal{
 		Type:         voteType,
@@ -372,11 +416,23 @@ func (v *VotingManager) CreateProposal(voteType VoteType, targetNodeID, reason s
 		ExpiresAt:    now.Add(v.config.ProposalDuration),
 		Votes:        make(map[string]*Vote),
 		Status:       ProposalPending,
+		Weighting:    v.snapshotPower(now),
+		Parameter:    param,
+		Canary:       canary,
 	}
 
 	// 生成提案ID
 	proposal.ID = v.generateProposalID(proposal)
 
+	// 签名
+	if v.signFunc != nil {
+		sig, err := v.signFunc(v.getProposalSignData(proposal))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign proposal: %w", err)
+		}
+		proposal.Signature = sig
+	}
+
 	// 存储提案
 	v.proposals[proposal.ID] = proposal
 
@@ -388,6 +444,77 @@ func (v *VotingManager) CreateProposal(voteType VoteType, targetNodeID, reason s
 	return proposal, nil
 }
 
+// ReceiveProposal 接收其他节点发起的提案（用于提案传播）
+// 提案ID和发起者签名必须有效，投票期不能超过本节点的 ProposalDuration。
+// 投票权按本地快照计算，不采用对方的快照；提案中附带的投票和状态被忽略。
+func (v *VotingManager) ReceiveProposal(p *Proposal) error {
+	if p == nil {
+		return errors.New("proposal is nil")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, exists := v.proposals[p.ID]; exists {
+		return errors.New("proposal already known")
+	}
+	if p.ID != v.generateProposalID(p) {
+		return errors.New("proposal ID mismatch")
+	}
+	if p.ProposerID == v.config.NodeID {
+		return errors.New("proposal from self")
+	}
+	if p.ExpiresAt.Sub(p.CreatedAt) > v.config.ProposalDuration {
+		return errors.New("proposal voting period too long")
+	}
+	if time.Now().After(p.ExpiresAt) {
+		return errors.New("proposal has expired")
+	}
+	if p.Type != VoteProposal && p.TargetNodeID == "" {
+		return errors.New("target node ID is required")
+	}
+
+	// 验证发起者签名
+	if v.verifyFunc != nil {
+		valid, err := v.verifyFunc(p.ProposerID, v.getProposalSignData(p), p.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to verify signature: %w", err)
+		}
+		if !valid {
+			return errors.New("invalid proposal signature")
+		}
+	}
+
+	// 验证发起者信誉
+	proposerRep := v.getNodeReputation(p.ProposerID)
+	if proposerRep < v.config.MinRepToPropose {
+		return fmt.Errorf("proposer reputation too low: %.2f < %.2f", proposerRep, v.config.MinRepToPropose)
+	}
+
+	proposal := &Proposal{
+		ID:           p.ID,
+		Type:         p.Type,
+		TargetNodeID: p.TargetNodeID,
+		ProposerID:   p.ProposerID,
+		Reason:       p.Reason,
+		CreatedAt:    p.CreatedAt,
+		ExpiresAt:    p.ExpiresAt,
+		Votes:        make(map[string]*Vote),
+		Status:       ProposalPending,
+		Weighting:    v.snapshotPower(p.CreatedAt),
+		Signature:    p.Signature,
+	}
+	if p.Parameter != nil {
+		proposal.Parameter = &ParameterChange{Key: p.Parameter.Key, Value: p.Parameter.Value}
+	}
+	if p.Canary != nil {
+		proposal.Canary = &CanaryRollout{Trial: p.Canary.Trial}
+	}
+	v.proposals[proposal.ID] = proposal
+
+	return nil
+}
+
 // CastVote 投票
 func (v *VotingManager) CastVote(proposalID string, choice VoteChoice, reason string) (*Vote, error) {
 	v.mu.Lock()
@@ -427,8 +554,11 @@ func (v *VotingManager) CastVote(proposalID string, choice VoteChoice, reason st
 			v.config.BufferPeriod - time.Since(proposal.CreatedAt))
 	}
 
-	// 计算投票权重
-	weight := v.calculateVoteWeight(v.config.NodeID)
+	// 投票权取自提案创建时的快照
+	weight, err := v.proposalPower(proposal, v.config.NodeID)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建投票
 	vote := &Vote{
@@ -497,8 +627,8 @@ func (v *VotingManager) ReceiveVote(vote *Vote) error {
 		return errors.New("vote already recorded")
 	}
 
-	// 验证签名
-	if v.verifyFunc != nil && len(vote.Signature) > 0 {
+	// 验证签名：设置了验签函数时不接受未签名的投票
+	if v.verifyFunc != nil {
 		signData := v.getVoteSignData(vote)
 		valid, err := v.verifyFunc(vote.VoterID, signData, vote.Signature)
 		if err != nil {
@@ -515,8 +645,16 @@ func (v *VotingManager) ReceiveVote(vote *Vote) error {
 		return fmt.Errorf("voter reputation too low: %.2f < %.2f", voterRep, v.config.MinRepToVote)
 	}
 
+	// 按本地快照计票，不采信对端声明的权重
+	weight, err := v.proposalPower(proposal, vote.VoterID)
+	if err != nil {
+		return err
+	}
+	recorded := *vote
+	recorded.Weight = weight
+
 	// 记录投票
-	proposal.Votes[vote.VoterID] = vote
+	proposal.Votes[vote.VoterID] = &recorded
 
 	// 尝试结束
 	v.tryFinalizeProposal(proposal)
@@ -573,18 +711,19 @@ func (v *VotingManager) GetActiveProposals() []*Proposal {
 
 // === 内部方法 ===
 
-// calculateVoteWeight 计算投票权重
+// calculateVoteWeight 按当前信任信息计算投票权重（公式见 power.go）
 func (v *VotingManager) calculateVoteWeight(nodeID string) float64 {
-	// VoteWeight = α * Reputation + β * Stake
 	node, exists := v.nodes[nodeID]
 	if !exists {
+		if v.formula() == PowerEqual {
+			return 1
+		}
 		// 使用外部获取信誉函数
 		rep := v.getNodeReputation(nodeID)
 		return v.config.ReputationWeight * rep
 	}
 
-	return v.config.ReputationWeight * node.Reputation + 
-	       v.config.StakeWeight * node.Stake
+	return v.nodePower(node, time.Now())
 }
 
 // getNodeReputation 获取节点信誉
@@ -612,8 +751,11 @@ func (v *VotingManager) tryFinalizeProposal(proposal *Proposal) {
 	// 计算投票结果
 	result := v.calculateResult(proposal)
 
-	// 计算总权重（所有已注册节点）
+	// 计算总权重（快照中的所有活跃节点）
 	totalPossibleWeight := v.calculateTotalPossibleWeight()
+	if proposal.Weighting != nil {
+		totalPossibleWeight = proposal.Weighting.TotalPower
+	}
 	if totalPossibleWeight <= 0 {
 		return
 	}
@@ -631,7 +773,7 @@ func (v *VotingManager) tryFinalizeProposal(proposal *Proposal) {
 	}
 
 	// 检查是否通过
-	result.Passed = result.YesRatio >= v.config.PassThreshold
+	result.Passed = result.YesRatio >= v.passThreshold(proposal.Type)
 	result.FinalizedAt = time.Now()
 
 	// 更新提案状态
@@ -649,25 +791,45 @@ func (v *VotingManager) tryFinalizeProposal(proposal *Proposal) {
 	}
 }
 
-// calculateResult 计算投票结果
+// passThreshold 提案类型的通过阈值：剔除节点和撤销超级节点需要绝对多数
+func (v *VotingManager) passThreshold(voteType VoteType) float64 {
+	if (voteType == VoteKick || voteType == VoteDemote) && v.config.SupermajorityThreshold > v.config.PassThreshold {
+		return v.config.SupermajorityThreshold
+	}
+	return v.config.PassThreshold
+}
+
+// calculateResult 计算投票结果（直接投票加上解析后的委托）
 func (v *VotingManager) calculateResult(proposal *Proposal) *ProposalResult {
 	result := &ProposalResult{}
 
 	for _, vote := range proposal.Votes {
-		result.TotalWeight += vote.Weight
-		switch vote.Choice {
-		case ChoiceYes:
-			result.YesWeight += vote.Weight
-		case ChoiceNo:
-			result.NoWeight += vote.Weight
-		case ChoiceAbstain:
-			result.AbstainWeight += vote.Weight
+		result.add(vote.Choice, vote.Weight)
+	}
+
+	result.Delegations = v.resolveDelegations(proposal)
+	for _, dv := range result.Delegations {
+		if dv.Status == DelegationCounted {
+			result.add(dv.Choice, dv.Weight)
+			result.DelegatedWeight += dv.Weight
 		}
 	}
 
 	return result
 }
 
+func (r *ProposalResult) add(choice VoteChoice, weight float64) {
+	r.TotalWeight += weight
+	switch choice {
+	case ChoiceYes:
+		r.YesWeight += weight
+	case ChoiceNo:
+		r.NoWeight += weight
+	case ChoiceAbstain:
+		r.AbstainWeight += weight
+	}
+}
+
 // calculateTotalPossibleWeight 计算总可能权重
 func (v *VotingManager) calculateTotalPossibleWeight() float64 {
 	var total float64
@@ -689,6 +851,8 @@ func (v *VotingManager) applyProposalResult(proposal *Proposal) {
 		if v.onNodeKicked != nil {
 			go v.onNodeKicked(proposal.TargetNodeID)
 		}
+		v.executeLocked(proposal)
+		v.recordChangeLocked(proposal, ActionBan)
 
 	case VoteRestore:
 		if node, exists := v.nodes[proposal.TargetNodeID]; exists {
@@ -697,9 +861,30 @@ func (v *VotingManager) applyProposalResult(proposal *Proposal) {
 		if v.onNodeRestored != nil {
 			go v.onNodeRestored(proposal.TargetNodeID)
 		}
+		v.executeLocked(proposal)
+		v.recordChangeLocked(proposal, ActionUnban)
+
+	case VoteProposal:
+		if proposal.Parameter != nil && proposal.Canary != nil {
+			v.startCanaryLocked(proposal, proposal.Result.FinalizedAt)
+		} else if proposal.Parameter != nil {
+			if v.applyParameterLocked(proposal.Parameter) {
+				v.recordChangeLocked(proposal, ActionParameter)
+			}
+		} else {
+			// 不涉及参数的提案只调用执行器
+			v.executeLocked(proposal)
+		}
 
-	case VotePromote, VoteDemote, VoteProposal:
-		// 这些类型由外部处理
+	case VotePromote:
+		if v.executeLocked(proposal) {
+			v.recordChangeLocked(proposal, ActionPromote)
+		}
+
+	case VoteDemote:
+		if v.executeLocked(proposal) {
+			v.recordChangeLocked(proposal, ActionDemote)
+		}
 	}
 }
 
@@ -727,6 +912,26 @@ func (v *VotingManager) generateVoteID(vote *Vote) string {
 	return hex.EncodeToString(hash[:16])
 }
 
+// getProposalSignData 获取提案签名数据
+func (v *VotingManager) getProposalSignData(proposal *Proposal) []byte {
+	data := fmt.Sprintf("%s|%s|%s|%s|%s|%d|%d",
+		proposal.ID,
+		proposal.Type,
+		proposal.TargetNodeID,
+		proposal.ProposerID,
+		proposal.Reason,
+		proposal.CreatedAt.UnixNano(),
+		proposal.ExpiresAt.UnixNano(),
+	)
+	if proposal.Parameter != nil {
+		data += fmt.Sprintf("|%s=%s", proposal.Parameter.Key, proposal.Parameter.Value)
+	}
+	if proposal.Canary != nil {
+		data += fmt.Sprintf("|canary=%d", int64(proposal.Canary.Trial))
+	}
+	return []byte(data)
+}
+
 // getVoteSignData 获取投票签名数据
 func (v *VotingManager) getVoteSignData(vote *Vote) []byte {
 	data := fmt.Sprintf("%s|%s|%s|%.6f|%d",
@@ -754,13 +959,14 @@ func (v *VotingManager) mainLoop() {
 			v.cleanup()
 		case <-checkTicker.C:
 			v.checkExpiredProposals()
+			v.checkCanaries(time.Now())
 		case <-v.stopCh:
 			return
 		}
 	}
 }
 
-// checkExpiredProposals 检查过期提案
+// checkExpiredProposals 到期的提案按截止时的投票和委托结束，仍未达到法定人数的记为过期
 func (v *VotingManager) checkExpiredProposals() {
 	v.mu.Lock()
 	defer v.mu.Unlock()
@@ -768,17 +974,25 @@ func (v *VotingManager) checkExpiredProposals() {
 	now := time.Now()
 	for _, proposal := range v.proposals {
 		if proposal.Status == ProposalPending && now.After(proposal.ExpiresAt) {
-			proposal.Status = ProposalExpired
-			proposal.Result = v.calculateResult(proposal)
-			proposal.Result.FinalizedAt = now
-
-			if v.onProposalFinalized != nil {
-				go v.onProposalFinalized(proposal)
+			v.tryFinalizeProposal(proposal)
+			if proposal.Status == ProposalPending {
+				v.expireProposalLocked(proposal, now)
 			}
 		}
 	}
 }
 
+// expireProposalLocked 把未达到法定人数的提案记为过期（调用方持有锁）
+func (v *VotingManager) expireProposalLocked(proposal *Proposal, now time.Time) {
+	proposal.Status = ProposalExpired
+	proposal.Result = v.calculateResult(proposal)
+	proposal.Result.FinalizedAt = now
+
+	if v.onProposalFinalized != nil {
+		go v.onProposalFinalized(proposal)
+	}
+}
+
 // cleanup 清理旧数据
 func (v *VotingManager) cleanup() {
 	v.mu.Lock()
@@ -798,8 +1012,10 @@ func (v *VotingManager) cleanup() {
 // === 持久化 ===
 
 type votingData struct {
-	Proposals map[string]*Proposal  `json:"proposals"`
-	Nodes     map[string]*NodeTrust `json:"nodes"`
+	Proposals   map[string]*Proposal   `json:"proposals"`
+	Nodes       map[string]*NodeTrust  `json:"nodes"`
+	Delegations map[string]*Delegation `json:"delegations,omitempty"`
+	Changelog   []*ChangelogEntry      `json:"changelog,omitempty"`
 }
 
 func (v *VotingManager) saveToDisk() error {
@@ -809,12 +1025,13 @@ func (v *VotingManager) saveToDisk() error {
 
 	v.mu.RLock()
 	data := &votingData{
-		Proposals: v.proposals,
-		Nodes:     v.nodes,
+		Proposals:   v.proposals,
+		Nodes:       v.nodes,
+		Delegations: v.delegations,
+		Changelog:   v.changelog,
 	}
-	v.mu.RUnlock()
-
 	jsonData, err := json.MarshalIndent(data, "", "  ")
+	v.mu.RUnlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
@@ -852,6 +1069,10 @@ func (v *VotingManager) loadFromDisk() error {
 	if data.Nodes != nil {
 		v.nodes = data.Nodes
 	}
+	if data.Delegations != nil {
+		v.delegations = data.Delegations
+	}
+	v.changelog = data.Changelog
 
 	return nil
 }
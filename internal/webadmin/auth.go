@@ -1,10 +1,14 @@
 package webadmin
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"sync"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
 )
 
 // TokenCookieName is the name of the token cookie.
@@ -28,6 +32,8 @@ type Session struct {
 	ExpiresAt time.Time `json:"expires_at"`
 	IPAddress string    `json:"ip_address"`
 	UserAgent string    `json:"user_agent"`
+	Role      auth.Role `json:"role"`
+	KeyID     string    `json:"key_id,omitempty"` // API key used to log in; empty for the admin token
 }
 
 // IsExpired returns whether the session has expired.
@@ -39,6 +45,7 @@ func (s *Session) IsExpired() bool {
 type AuthManager struct {
 	adminToken      string
 	sessionDuration time.Duration
+	rbac            *auth.Authorizer
 
 	sessions map[string]*Session
 	mu       sync.RWMutex
@@ -60,15 +67,98 @@ func NewAuthManager(adminToken string, sessionDuration time.Duration) *AuthManag
 
 // ValidateToken validates the admin token.
 func (am *AuthManager) ValidateToken(token string) bool {
-	if am.adminToken == "" {
+	adminToken := am.GetToken()
+	if adminToken == "" {
 		return false
 	}
-	return token == am.adminToken
+	return token == adminToken
+}
+
+// SetAuthorizer enables API keys issued by `token create`.
+// Requests made with a key are limited to the routes its role is allowed to call.
+func (am *AuthManager) SetAuthorizer(a *auth.Authorizer) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.rbac = a
+}
+
+func (am *AuthManager) authorizer() *auth.Authorizer {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.rbac
+}
+
+// Authenticate resolves a token to a role.
+// The admin token has the admin role and an empty key ID; API keys return their own ID.
+func (am *AuthManager) Authenticate(token string) (role auth.Role, keyID string, ok bool) {
+	if am.ValidateToken(token) {
+		return auth.RoleAdmin, "", true
+	}
+	if rbac := am.authorizer(); rbac != nil {
+		if key, ok := rbac.Authenticate(token); ok {
+			return key.Role, key.ID, true
+		}
+	}
+	return "", "", false
+}
+
+// Identity returns the identity reconnect tokens are bound to for a key ID
+// (empty for the admin token).
+func (am *AuthManager) Identity(keyID string) Identity {
+	if keyID != "" {
+		return Identity{KeyID: keyID}
+	}
+	return Identity{Credential: am.adminFingerprint()}
+}
+
+// ResolveIdentity resolves the role of an identity from a reconnect token.
+// The admin identity is valid only while the admin token it was issued under
+// is current; an API key identity only while the key is active.
+func (am *AuthManager) ResolveIdentity(id Identity) (role auth.Role, ok bool) {
+	if id.KeyID == "" {
+		current := am.adminFingerprint()
+		if current == "" || !hmac.Equal([]byte(current), []byte(id.Credential)) {
+			return "", false
+		}
+		return auth.RoleAdmin, true
+	}
+	rbac := am.authorizer()
+	if rbac == nil {
+		return "", false
+	}
+	key, ok := rbac.Key(id.KeyID)
+	if !ok {
+		return "", false
+	}
+	return key.Role, true
+}
+
+// identityValid reports whether an identity still resolves to a role.
+func (am *AuthManager) identityValid(id Identity) bool {
+	_, ok := am.ResolveIdentity(id)
+	return ok
+}
+
+// adminFingerprint identifies the current admin token without revealing it.
+func (am *AuthManager) adminFingerprint() string {
+	token := am.GetToken()
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("daan-webadmin-reconnect|" + token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Allowed reports whether an API key role may call the route.
+func (am *AuthManager) Allowed(role auth.Role, method, path string) bool {
+	rbac := am.authorizer()
+	return rbac != nil && rbac.Allowed(role, method, path)
 }
 
 // CreateSession creates a new session for a valid token.
 func (am *AuthManager) CreateSession(token, ipAddress, userAgent string) (*Session, error) {
-	if !am.ValidateToken(token) {
+	role, keyID, ok := am.Authenticate(token)
+	if !ok {
 		return nil, ErrInvalidToken
 	}
 
@@ -84,6 +174,8 @@ func (am *AuthManager) CreateSession(token, ipAddress, userAgent string) (*Sessi
 		ExpiresAt: time.Now().Add(am.sessionDuration),
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
+		Role:      role,
+		KeyID:     keyID,
 	}
 
 	am.mu.Lock()
@@ -113,6 +205,26 @@ func (am *AuthManager) ValidateSession(sessionID string) bool {
 	return true
 }
 
+// SessionIdentity returns the role and API key ID of a valid session.
+// Sessions opened with an API key end as soon as the key is revoked.
+func (am *AuthManager) SessionIdentity(sessionID string) (role auth.Role, keyID string, ok bool) {
+	if !am.ValidateSession(sessionID) {
+		return "", "", false
+	}
+	session := am.GetSession(sessionID)
+	if session == nil {
+		return "", "", false
+	}
+	if session.KeyID != "" {
+		rbac := am.authorizer()
+		if rbac == nil || !rbac.KeyActive(session.KeyID) {
+			am.DeleteSession(sessionID)
+			return "", "", false
+		}
+	}
+	return session.Role, session.KeyID, true
+}
+
 // GetSession returns a session by ID.
 func (am *AuthManager) GetSession(sessionID string) *Session {
 	am.mu.RLock()
@@ -161,7 +273,8 @@ func (am *AuthManager) GetActiveSessions() []*Session {
 	return sessions
 }
 
-// UpdateToken updates the admin token and invalidates all existing sessions.
+// UpdateToken updates the admin token and invalidates all existing sessions
+// and the reconnect tokens issued under the old token.
 func (am *AuthManager) UpdateToken(newToken string) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
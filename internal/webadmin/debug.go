@@ -0,0 +1,46 @@
+package webadmin
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// profileDeadlineSlack is added on top of the requested sampling duration so that
+// long CPU profiles and traces are not cut off by the server's WriteTimeout.
+const profileDeadlineSlack = 10 * time.Second
+
+// setupDebugRoutes registers net/http/pprof and runtime/trace endpoints.
+// All routes require admin authentication. The block and mutex profiles stay
+// empty unless their sampling rates are configured.
+func (s *Server) setupDebugRoutes() {
+	if s.config.BlockProfileRate > 0 {
+		runtime.SetBlockProfileRate(s.config.BlockProfileRate)
+	}
+	if s.config.MutexProfileFraction > 0 {
+		runtime.SetMutexProfileFraction(s.config.MutexProfileFraction)
+	}
+
+	// pprof.Index also serves the named profiles: heap, goroutine, block, mutex, allocs, threadcreate
+	s.mux.HandleFunc("/debug/pprof/", s.wrapHandler(pprof.Index, true))
+	s.mux.HandleFunc("/debug/pprof/cmdline", s.wrapHandler(pprof.Cmdline, true))
+	s.mux.HandleFunc("/debug/pprof/symbol", s.wrapHandler(pprof.Symbol, true))
+	s.mux.HandleFunc("/debug/pprof/profile", s.wrapHandler(withProfileDeadline(pprof.Profile), true))
+	s.mux.HandleFunc("/debug/pprof/trace", s.wrapHandler(withProfileDeadline(pprof.Trace), true))
+}
+
+// withProfileDeadline extends the write deadline according to the "seconds" query parameter.
+func withProfileDeadline(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seconds, err := strconv.Atoi(r.URL.Query().Get("seconds"))
+		if err != nil || seconds <= 0 {
+			seconds = 30 // pprof 默认采样时长
+		}
+		deadline := time.Now().Add(time.Duration(seconds)*time.Second + profileDeadlineSlack)
+		// ResponseRecorder 等不支持 deadline 的实现会返回错误，忽略即可
+		_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+		handler(w, r)
+	}
+}
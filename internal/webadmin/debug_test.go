@@ -0,0 +1,84 @@
+package webadmin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDebugEndpointsRequireAuth tests that profiling endpoints are not publicly accessible.
+func TestDebugEndpointsRequireAuth(t *testing.T) {
+	server := newTestServer()
+
+	endpoints := []string{
+		"/debug/pprof/",
+		"/debug/pprof/heap",
+		"/debug/pprof/goroutine",
+		"/debug/pprof/profile",
+		"/debug/pprof/trace",
+	}
+
+	for _, endpoint := range endpoints {
+		t.Run(endpoint, func(t *testing.T) {
+			req := httptest.NewRequest("GET", endpoint, nil)
+			w := httptest.NewRecorder()
+
+			server.mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401 for %s without auth, got %d", endpoint, w.Code)
+			}
+		})
+	}
+}
+
+// TestDebugEndpointsWithAuth tests that profiles can be fetched with the admin token.
+func TestDebugEndpointsWithAuth(t *testing.T) {
+	server := newTestServer()
+
+	t.Run("index", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+		req.Header.Set("Authorization", "Bearer test-token-12345")
+		w := httptest.NewRecorder()
+
+		server.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "goroutine") {
+			t.Error("Expected profile index to list goroutine profile")
+		}
+	})
+
+	t.Run("goroutine debug", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil)
+		req.Header.Set("Authorization", "Bearer test-token-12345")
+		w := httptest.NewRecorder()
+
+		server.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "goroutine profile") {
+			t.Error("Expected goroutine profile output")
+		}
+	})
+
+	t.Run("cpu profile", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/debug/pprof/profile?seconds=1", nil)
+		req.Header.Set("Authorization", "Bearer test-token-12345")
+		w := httptest.NewRecorder()
+
+		server.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		if w.Body.Len() == 0 {
+			t.Error("Expected non-empty CPU profile")
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package webadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DemoConfig 演示模式配置
+// 相同的 Seed 总是生成相同的节点、邮件、留言、提案和统计序列，便于前端开发和 e2e 测试。
+type DemoConfig struct {
+	Seed          int64         // 随机种子
+	Peers         int           // 模拟节点数
+	Messages      int           // 模拟邮件数（收件箱与发件箱合计）
+	Bulletins     int           // 模拟留言数
+	Proposals     int           // 模拟提案数
+	Epoch         time.Time     // 生成数据的基准时间（固定值保证时间戳可复现）
+	StatsInterval time.Duration // 统计推送间隔
+}
+
+// DefaultDemoConfig 返回默认演示配置
+func DefaultDemoConfig() *DemoConfig {
+	return &DemoConfig{
+		Seed:          42,
+		Peers:         12,
+		Messages:      20,
+		Bulletins:     15,
+		Proposals:     6,
+		Epoch:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		StatsInterval: 2 * time.Second,
+	}
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	demoSubjects  = []string{"任务邀请", "结果确认", "仲裁通知", "带宽协商", "声誉复核", "模板共享"}
+	demoTopics    = []string{"general", "announcements", "tasks", "governance"}
+	demoModules   = []string{"p2p", "mailbox", "bulletin", "voting", "task"}
+	demoProposals = []struct{ title, kind string }{
+		{"移除长期离线节点", "kick"},
+		{"提高任务最低报酬", "parameter"},
+		{"选举新超级节点", "election"},
+		{"调整留言板限流", "parameter"},
+	}
+	demoStatuses = []string{"pending", "active", "passed", "rejected"}
+)
+
+// demoPeerID 生成形如 libp2p 节点ID的确定性字符串
+func demoPeerID(rng *rand.Rand) string {
+	b := make([]byte, 44)
+	for i := range b {
+		b[i] = base58Alphabet[rng.Intn(len(base58Alphabet))]
+	}
+	return "12D3KooW" + string(b)
+}
+
+// demoData 由种子生成的全部演示数据
+type demoData struct {
+	nodeID      string
+	peers       []string
+	neighbors   []*NeighborInfo
+	inbox       []*MailMessage
+	outbox      []*MailMessage
+	bulletins   []*BulletinMessage
+	reputations map[string]*ReputationInfo
+	proposals   []*ProposalDetail
+}
+
+func generateDemoData(config *DemoConfig) *demoData {
+	rng := rand.New(rand.NewSource(config.Seed))
+	at := func(offset time.Duration) string {
+		return config.Epoch.Add(offset).Format(time.RFC3339)
+	}
+
+	d := &demoData{
+		nodeID:      demoPeerID(rng),
+		reputations: make(map[string]*ReputationInfo),
+	}
+
+	for i := 0; i < config.Peers; i++ {
+		id := demoPeerID(rng)
+		d.peers = append(d.peers, id)
+
+		rep := int64(40 + rng.Intn(60))
+		neighbor := &NeighborInfo{
+			NodeID:       id,
+			Type:         "normal",
+			Reputation:   rep,
+			TrustScore:   float64(rep) / 100,
+			Status:       "online",
+			LastSeen:     at(-time.Duration(rng.Intn(600)) * time.Second),
+			Addresses:    []string{fmt.Sprintf("/ip4/10.0.%d.%d/tcp/4001", i/250, i%250+1)},
+			SuccessPings: 10 + rng.Intn(90),
+			FailedPings:  rng.Intn(10),
+		}
+		if i%5 == 0 {
+			neighbor.Type = "super"
+		}
+		if rng.Intn(4) == 0 {
+			neighbor.Status = "offline"
+			neighbor.LastSeen = at(-time.Duration(30+rng.Intn(600)) * time.Minute)
+		}
+		d.neighbors = append(d.neighbors, neighbor)
+		d.reputations[id] = &ReputationInfo{NodeID: id, Reputation: float64(rep)}
+	}
+
+	// 按声誉排名
+	ranked := make([]*ReputationInfo, 0, len(d.reputations))
+	for _, id := range d.peers {
+		ranked = append(ranked, d.reputations[id])
+	}
+	for i := range ranked {
+		rank := 1
+		for _, other := range ranked {
+			if other.Reputation > ranked[i].Reputation {
+				rank++
+			}
+		}
+		ranked[i].Rank = rank
+	}
+
+	pick := func() string {
+		if len(d.peers) == 0 {
+			return "self"
+		}
+		return d.peers[rng.Intn(len(d.peers))]
+	}
+
+	for i := 0; i < config.Messages; i++ {
+		subject := demoSubjects[rng.Intn(len(demoSubjects))]
+		msg := &MailMessage{
+			ID:        fmt.Sprintf("demo-mail-%03d", i+1),
+			Subject:   subject,
+			Content:   fmt.Sprintf("%s #%d：这是演示模式生成的邮件内容。", subject, i+1),
+			Timestamp: at(-time.Duration(i*37+rng.Intn(30)) * time.Minute),
+			Status:    "delivered",
+			Encrypted: rng.Intn(3) == 0,
+		}
+		if i%3 == 2 {
+			msg.From, msg.To = "self", pick()
+			d.outbox = append(d.outbox, msg)
+		} else {
+			msg.From, msg.To = pick(), "self"
+			if rng.Intn(2) == 0 {
+				msg.Status = "read"
+				msg.ReadAt = msg.Timestamp
+			}
+			d.inbox = append(d.inbox, msg)
+		}
+	}
+
+	for i := 0; i < config.Bulletins; i++ {
+		topic := demoTopics[rng.Intn(len(demoTopics))]
+		author := pick()
+		posted := -time.Duration(i*53+rng.Intn(50)) * time.Minute
+		b := &BulletinMessage{
+			MessageID:  fmt.Sprintf("demo-bulletin-%03d", i+1),
+			Author:     author,
+			Topic:      topic,
+			Content:    fmt.Sprintf("[%s] 演示留言 #%d", topic, i+1),
+			Timestamp:  at(posted),
+			ExpiresAt:  at(posted + 24*time.Hour),
+			Status:     "active",
+			Reputation: 50,
+		}
+		if r, ok := d.reputations[author]; ok {
+			b.Reputation = r.Reputation
+		}
+		if topic == "announcements" && rng.Intn(2) == 0 {
+			b.Status = "pinned"
+		}
+		d.bulletins = append(d.bulletins, b)
+	}
+
+	for i := 0; i < config.Proposals; i++ {
+		tpl := demoProposals[rng.Intn(len(demoProposals))]
+		created := -time.Duration(i*6+rng.Intn(6)) * time.Hour
+		p := &ProposalDetail{
+			ProposalInfo: ProposalInfo{
+				ID:        fmt.Sprintf("demo-prop-%03d", i+1),
+				Title:     tpl.title,
+				Type:      tpl.kind,
+				Status:    demoStatuses[rng.Intn(len(demoStatuses))],
+				Creator:   pick(),
+				CreatedAt: at(created),
+			},
+			Description: fmt.Sprintf("演示提案 #%d：%s", i+1, tpl.title),
+			Votes:       make(map[string]string),
+			ExpiresAt:   at(created + 72*time.Hour),
+		}
+		if tpl.kind == "kick" {
+			p.Target = pick()
+		}
+		for _, voter := range d.peers {
+			switch rng.Intn(3) {
+			case 0:
+				p.Votes[voter] = "yes"
+				p.VotesYes++
+			case 1:
+				p.Votes[voter] = "no"
+				p.VotesNo++
+			}
+		}
+		d.proposals = append(d.proposals, p)
+	}
+
+	return d
+}
+
+// NewDemoOperationsProvider 创建填充了确定性演示数据的操作提供者
+func NewDemoOperationsProvider(config *DemoConfig) *MockExtendedOperationsProvider {
+	if config == nil {
+		config = DefaultDemoConfig()
+	}
+	d := generateDemoData(config)
+
+	m := NewMockExtendedOperationsProvider()
+	m.neighbors = d.neighbors
+	m.inbox = d.inbox
+	m.outbox = d.outbox
+	m.bulletins = d.bulletins
+	m.reputations = d.reputations
+	m.subscriptions = append([]string(nil), demoTopics...)
+	m.proposals = d.proposals
+	return m
+}
+
+// DemoNodeInfoProvider 演示模式的节点信息提供者，按固定种子推进统计数据
+type DemoNodeInfoProvider struct {
+	*DefaultNodeInfoProvider
+
+	config  *DemoConfig
+	rng     *rand.Rand
+	current NetworkStats
+	ticks   int
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+}
+
+// NewDemoNodeInfoProvider 创建演示节点信息提供者
+func NewDemoNodeInfoProvider(config *DemoConfig, version string) *DemoNodeInfoProvider {
+	if config == nil {
+		config = DefaultDemoConfig()
+	}
+	d := generateDemoData(config)
+
+	p := &DemoNodeInfoProvider{
+		DefaultNodeInfoProvider: NewDefaultNodeInfoProvider(),
+		config:                  config,
+		// 统计序列使用独立的随机源，不受数据生成数量影响
+		rng: rand.New(rand.NewSource(config.Seed + 1)),
+	}
+	p.SetNodeInfo(d.nodeID, "", version)
+	p.SetReputation(72)
+	p.SetTokenCount(1000)
+	p.SetPeers(d.peers)
+
+	p.current = NetworkStats{
+		TotalPeers:  len(d.peers),
+		ActivePeers: len(d.peers),
+		AvgLatency:  40,
+	}
+	initial := p.current
+	p.UpdateStats(&initial)
+	p.AddLog("info", "demo", fmt.Sprintf("演示模式已启动（seed=%d，%d 个模拟节点）", config.Seed, len(d.peers)))
+	return p
+}
+
+// Tick 推进一步统计数据并返回快照，序列只由种子决定
+func (p *DemoNodeInfoProvider) Tick() *NetworkStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ticks++
+	sent := int64(1 + p.rng.Intn(20))
+	received := int64(1 + p.rng.Intn(25))
+	p.current.MessagesSent += sent
+	p.current.MessagesReceived += received
+	p.current.BytesSent += sent * int64(512+p.rng.Intn(2048))
+	p.current.BytesReceived += received * int64(512+p.rng.Intn(2048))
+	p.current.AvgLatency = 20 + float64(p.rng.Intn(800))/10
+
+	if p.ticks%5 == 0 {
+		module := demoModules[p.rng.Intn(len(demoModules))]
+		p.AddLog("info", module, fmt.Sprintf("演示事件 #%d：收到 %d 条消息", p.ticks, received))
+	}
+
+	stats := p.current
+	p.UpdateStats(&stats)
+	return &stats
+}
+
+// Start 定期推进统计数据并推送到 WebSocket stats 频道
+func (p *DemoNodeInfoProvider) Start(server *Server) {
+	p.mu.Lock()
+	if p.stopCh != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stopCh = make(chan struct{})
+	stopCh := p.stopCh
+	p.mu.Unlock()
+
+	interval := p.config.StatsInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				stats := p.Tick()
+				if server.wsHub.ClientCount("stats") > 0 {
+					if data, err := json.Marshal(stats); err == nil {
+						server.wsHub.Broadcast("stats", data)
+					}
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止统计推送
+func (p *DemoNodeInfoProvider) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopCh != nil {
+		close(p.stopCh)
+		p.stopCh = nil
+	}
+}
@@ -0,0 +1,76 @@
+package webadmin
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDemoDataDeterministic(t *testing.T) {
+	a := NewDemoOperationsProvider(nil)
+	b := NewDemoOperationsProvider(nil)
+
+	neighborsA, _ := a.GetNeighbors()
+	neighborsB, _ := b.GetNeighbors()
+	if len(neighborsA) != DefaultDemoConfig().Peers {
+		t.Fatalf("expected %d neighbors, got %d", DefaultDemoConfig().Peers, len(neighborsA))
+	}
+	if !reflect.DeepEqual(neighborsA, neighborsB) {
+		t.Error("same seed should generate identical neighbors")
+	}
+
+	propsA, _ := a.ListProposals("")
+	propsB, _ := b.ListProposals("")
+	if len(propsA) != DefaultDemoConfig().Proposals || !reflect.DeepEqual(propsA, propsB) {
+		t.Error("same seed should generate identical proposals")
+	}
+
+	config := DefaultDemoConfig()
+	config.Seed = 7
+	neighborsC, _ := NewDemoOperationsProvider(config).GetNeighbors()
+	if reflect.DeepEqual(neighborsA, neighborsC) {
+		t.Error("different seeds should generate different data")
+	}
+}
+
+func TestDemoProposalVote(t *testing.T) {
+	m := NewDemoOperationsProvider(nil)
+
+	before, err := m.GetProposal("demo-prop-001")
+	if err != nil {
+		t.Fatalf("GetProposal() error = %v", err)
+	}
+	if err := m.Vote("demo-prop-001", "yes"); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	after, _ := m.GetProposal("demo-prop-001")
+	if after.VotesYes != before.VotesYes+1 {
+		t.Errorf("expected %d yes votes, got %d", before.VotesYes+1, after.VotesYes)
+	}
+	if err := m.Vote("demo-prop-001", "no"); err == nil {
+		t.Error("second vote should be rejected")
+	}
+	if _, err := m.GetProposal("unknown"); err == nil {
+		t.Error("unknown proposal should return error")
+	}
+}
+
+func TestDemoNodeInfoStats(t *testing.T) {
+	a := NewDemoNodeInfoProvider(nil, "test")
+	b := NewDemoNodeInfoProvider(nil, "test")
+
+	if a.GetNodeID() != b.GetNodeID() || a.GetPeerCount() != DefaultDemoConfig().Peers {
+		t.Fatal("demo node identity should be deterministic")
+	}
+
+	for i := 0; i < 10; i++ {
+		sa, _ := json.Marshal(a.Tick())
+		sb, _ := json.Marshal(b.Tick())
+		if string(sa) != string(sb) {
+			t.Fatalf("tick %d: stats diverged: %s vs %s", i, sa, sb)
+		}
+	}
+	if stats := a.GetNetworkStats(); stats.MessagesSent == 0 || stats.TotalPeers != DefaultDemoConfig().Peers {
+		t.Errorf("stats not advanced: %+v", stats)
+	}
+}
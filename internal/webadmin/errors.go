@@ -14,4 +14,23 @@ var (
 
 	// ErrServerNotRunning indicates the server is not running.
 	ErrServerNotRunning = errors.New("server not running")
+
+	// ErrInvalidReconnectToken indicates a malformed or forged reconnect token.
+	ErrInvalidReconnectToken = errors.New("invalid reconnect token")
+
+	// ErrReconnectTokenExpired indicates that the reconnect token has expired.
+	ErrReconnectTokenExpired = errors.New("reconnect token expired")
+
+	// ErrReportNotFound indicates that no activity report has the requested ID.
+	ErrReportNotFound = errors.New("report not found")
+
+	// ErrInvalidReport indicates a malformed report ID or unsupported format.
+	ErrInvalidReport = errors.New("invalid report id or format")
+
+	// ErrContactNotFound indicates that the address book has no such contact.
+	ErrContactNotFound = errors.New("contact not found")
+
+	// ErrInvalidContact indicates a contact with a missing node ID, an
+	// over-long label or notes, or an unknown trust or encryption setting.
+	ErrInvalidContact = errors.New("invalid contact")
 )
@@ -2,6 +2,8 @@ package webadmin
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,15 +14,10 @@ type ExtendedOperationsProvider interface {
 	OperationsProvider // 嵌入基础接口
 
 	// 声誉扩展
-	UpdateReputation(nodeID string, delta int, reason string) (*ReputationUpdateResult, error)
-	GetReputationHistory(nodeID string, limit int) ([]*ReputationHistoryEntry, error)
+	ReputationOperationsProvider
 
 	// 任务管理
-	CreateTask(taskType, description string, deadline int64) (*TaskCreateResult, error)
-	GetTaskStatus(taskID string) (*TaskStatus, error)
-	AcceptTask(taskID string) error
-	SubmitTaskResult(taskID, result string) error
-	ListTasks(status string, limit int) ([]*TaskInfo, error)
+	TaskOperationsProvider
 
 	// 指责系统
 	CreateAccusation(accused, accusationType, reason string) (*AccusationCreateResult, error)
@@ -70,19 +67,65 @@ type ExtendedOperationsProvider interface {
 	ManualPenalty(nodeID, severity, reason string) (*ManualPenaltyResult, error)
 
 	// 抵押物管理 (Task44)
+	CollateralOperationsProvider
+
+	// 争议预审 (Task44)
+	DisputeOperationsProvider
+
+	// 托管多签 (Task44)
+	EscrowOperationsProvider
+
+	// $DAAN 代币账本
+	TokenOperationsProvider
+
+	// 节点活动报告
+	ReportOperationsProvider
+
+	// 邮箱通讯录
+	ContactOperationsProvider
+}
+
+// CollateralOperationsProvider 抵押账户接口，可单独提供给管理后台
+type CollateralOperationsProvider interface {
 	ListCollaterals(status string) ([]*CollateralInfo, error)
 	GetCollateralByNode(nodeID, purpose string) (*CollateralInfo, error)
 	SlashByNode(nodeID, purpose, reason, evidence string, ratio float64) (*SlashResult, error)
 	GetSlashHistory(nodeID string, limit int) ([]*SlashRecord, error)
+}
 
-	// 争议预审 (Task44)
+// DisputeOperationsProvider 争议预审接口，可单独提供给管理后台
+type DisputeOperationsProvider interface {
 	ListDisputes(status string) ([]*DisputeInfo, error)
 	GetDisputeSuggestion(id string) (*DisputeSuggestionInfo, error)
 	VerifyEvidence(disputeID, evidenceID, verifierID string) error
 	ApplyDisputeSuggestion(disputeID, approverID string) (*ApplySuggestionResult, error)
 	GetDisputeDetail(id string) (*DisputeDetail, error)
+}
 
-	// 托管多签 (Task44)
+// TokenOperationsProvider 代币账本接口，可单独提供给管理后台
+type TokenOperationsProvider interface {
+	GetTokenBalance(nodeID string) (*TokenAccountInfo, error)
+	GetTokenHistory(nodeID string, limit int) ([]*TokenEntryInfo, error)
+	TransferTokens(to string, amount float64, memo string) (*TokenEntryInfo, error)
+}
+
+// ReportOperationsProvider 活动报告接口，可单独提供给管理后台
+type ReportOperationsProvider interface {
+	ListReports() ([]*ReportInfo, error)
+	GetReport(id, format string) (data []byte, contentType string, err error)
+	GenerateReport(days int) (*ReportInfo, error)
+}
+
+// ContactOperationsProvider 邮箱通讯录接口，可单独提供给管理后台
+type ContactOperationsProvider interface {
+	ListContacts(query string, limit int) ([]*ContactInfo, error)
+	SaveContact(contact *ContactInfo) (*ContactInfo, error)
+	DeleteContact(nodeID string) error
+	CheckRecipient(nodeID string) (*RecipientCheckInfo, error)
+}
+
+// EscrowOperationsProvider 托管多签接口，可单独提供给管理后台
+type EscrowOperationsProvider interface {
 	ListEscrows(status string) ([]*EscrowInfo, error)
 	GetEscrowDetail(id string) (*EscrowDetail, error)
 	SubmitArbitratorSignature(escrowID, arbitratorID, signature string) (*SignatureSubmitResult, error)
@@ -90,6 +133,21 @@ type ExtendedOperationsProvider interface {
 	ResolveEscrow(escrowID, winner string, signatures map[string]string) (*EscrowResolveResult, error)
 }
 
+// ReputationOperationsProvider 声誉调整和历史接口，可单独提供给管理后台
+type ReputationOperationsProvider interface {
+	UpdateReputation(nodeID string, delta int, reason string) (*ReputationUpdateResult, error)
+	GetReputationHistory(nodeID string, limit int) ([]*ReputationHistoryEntry, error)
+}
+
+// TaskOperationsProvider 任务管理接口，可单独提供给管理后台（不需要完整的扩展操作）
+type TaskOperationsProvider interface {
+	CreateTask(taskType, description string, deadline int64) (*TaskCreateResult, error)
+	GetTaskStatus(taskID string) (*TaskStatus, error)
+	AcceptTask(taskID string) error
+	SubmitTaskResult(taskID, result string) error
+	ListTasks(status string, limit int) ([]*TaskInfo, error)
+}
+
 // ========== 数据结构定义 ==========
 
 // ReputationUpdateResult 声誉更新结果
@@ -114,8 +172,11 @@ type TaskCreateResult struct {
 type TaskStatus struct {
 	TaskID   string `json:"task_id"`
 	Status   string `json:"status"`
+	Stage    string `json:"stage,omitempty"` // created/accepted/executing/submitted/verified/rewarded
 	Progress int    `json:"progress"`
 	Worker   string `json:"worker,omitempty"`
+	Deadline int64  `json:"deadline,omitempty"`
+	Overdue  bool   `json:"overdue,omitempty"`
 }
 
 // TaskInfo 任务信息
@@ -337,6 +398,7 @@ type CollateralInfo struct {
 	NodeID       string  `json:"node_id"`
 	Purpose      string  `json:"purpose"`
 	Amount       float64 `json:"amount"`
+	Locked       float64 `json:"locked"`
 	Slashed      float64 `json:"slashed"`
 	Status       string  `json:"status"`
 	CreatedAt    string  `json:"created_at"`
@@ -344,6 +406,7 @@ type CollateralInfo struct {
 
 // SlashResult 罚没结果
 type SlashResult struct {
+	ID            string  `json:"id,omitempty"`
 	SlashedAmount float64 `json:"slashed_amount"`
 	Remaining     float64 `json:"remaining"`
 }
@@ -354,8 +417,9 @@ type SlashRecord struct {
 	NodeID    string  `json:"node_id"`
 	Purpose   string  `json:"purpose"`
 	Amount    float64 `json:"amount"`
-	Reason    string  `json:"reason"`
-	Timestamp string  `json:"timestamp"`
+	Reason    string   `json:"reason"`
+	Evidence  []string `json:"evidence,omitempty"`
+	Timestamp string   `json:"timestamp"`
 }
 
 // DisputeInfo 争议信息
@@ -446,12 +510,79 @@ type EscrowResolveResult struct {
 	Amount   float64 `json:"amount"`
 }
 
+// TokenAccountInfo 代币账户
+type TokenAccountInfo struct {
+	NodeID    string  `json:"node_id"`
+	Balance   float64 `json:"balance"`
+	Locked    float64 `json:"locked"`
+	Available float64 `json:"available"`
+	Minted    float64 `json:"minted"`
+	Nonce     uint64  `json:"nonce"`
+}
+
+// TokenEntryInfo 代币流水
+type TokenEntryInfo struct {
+	ID        string  `json:"id"`
+	Type      string  `json:"type"`
+	From      string  `json:"from,omitempty"`
+	To        string  `json:"to,omitempty"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference,omitempty"`
+	Timestamp string  `json:"timestamp"`
+}
+
+// ReportInfo 活动报告摘要
+type ReportInfo struct {
+	ID               string  `json:"id"`
+	PeriodStart      string  `json:"period_start"`
+	PeriodEnd        string  `json:"period_end"`
+	GeneratedAt      string  `json:"generated_at"`
+	TasksCompleted   int     `json:"tasks_completed"`
+	TokensNet        float64 `json:"tokens_net"`
+	ReputationChange float64 `json:"reputation_change"`
+	Disputes         int     `json:"disputes"`
+}
+
+// ContactInfo 通讯录联系人
+type ContactInfo struct {
+	NodeID     string `json:"node_id"`
+	Label      string `json:"label,omitempty"`
+	Notes      string `json:"notes,omitempty"`
+	Trust      string `json:"trust"`                // trusted, normal, low
+	Encryption string `json:"encryption,omitempty"` // always, never，为空时按发信请求
+	CreatedAt  string `json:"created_at,omitempty"`
+	UpdatedAt  string `json:"updated_at,omitempty"`
+}
+
+// RecipientWarningInfo 发信警告
+type RecipientWarningInfo struct {
+	Code    string `json:"code"` // low_trust, low_reputation, quarantined
+	Message string `json:"message"`
+}
+
+// RecipientCheckInfo 发信前的收件人检查
+type RecipientCheckInfo struct {
+	NodeID      string                 `json:"node_id"`
+	Contact     *ContactInfo           `json:"contact,omitempty"`
+	Reputation  *float64               `json:"reputation,omitempty"`
+	Quarantined bool                   `json:"quarantined"`
+	Warnings    []RecipientWarningInfo `json:"warnings"`
+}
+
 // ========== 扩展操作处理器 ==========
 
 // ExtendedOperationHandlers 扩展操作处理器
 type ExtendedOperationHandlers struct {
 	server   *Server
 	provider ExtendedOperationsProvider
+	tasks      TaskOperationsProvider       // 单独设置的任务管理，优先于 provider
+	reputation ReputationOperationsProvider // 单独设置的声誉管理，优先于 provider
+	escrow     EscrowOperationsProvider     // 单独设置的托管多签，优先于 provider
+	dispute    DisputeOperationsProvider    // 单独设置的争议预审，优先于 provider
+	collateral CollateralOperationsProvider // 单独设置的抵押账户，优先于 provider
+	token      TokenOperationsProvider      // 单独设置的代币账本，优先于 provider
+	report     ReportOperationsProvider     // 单独设置的活动报告，优先于 provider
+	contacts   ContactOperationsProvider    // 单独设置的邮箱通讯录，优先于 provider
 }
 
 // NewExtendedOperationHandlers 创建扩展操作处理器
@@ -467,11 +598,99 @@ func (h *ExtendedOperationHandlers) getProvider() ExtendedOperationsProvider {
 	return h.provider
 }
 
+// getTaskProvider 获取任务管理 provider
+func (h *ExtendedOperationHandlers) getTaskProvider() TaskOperationsProvider {
+	if h.tasks != nil {
+		return h.tasks
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getReputationProvider 获取声誉管理 provider
+func (h *ExtendedOperationHandlers) getReputationProvider() ReputationOperationsProvider {
+	if h.reputation != nil {
+		return h.reputation
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getDisputeProvider 获取争议预审 provider
+func (h *ExtendedOperationHandlers) getDisputeProvider() DisputeOperationsProvider {
+	if h.dispute != nil {
+		return h.dispute
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getCollateralProvider 获取抵押账户 provider
+func (h *ExtendedOperationHandlers) getCollateralProvider() CollateralOperationsProvider {
+	if h.collateral != nil {
+		return h.collateral
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getTokenProvider 获取代币账本 provider
+func (h *ExtendedOperationHandlers) getTokenProvider() TokenOperationsProvider {
+	if h.token != nil {
+		return h.token
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getReportProvider 获取活动报告 provider
+func (h *ExtendedOperationHandlers) getReportProvider() ReportOperationsProvider {
+	if h.report != nil {
+		return h.report
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getContactProvider 获取邮箱通讯录 provider
+func (h *ExtendedOperationHandlers) getContactProvider() ContactOperationsProvider {
+	if h.contacts != nil {
+		return h.contacts
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
+// getEscrowProvider 获取托管多签 provider
+func (h *ExtendedOperationHandlers) getEscrowProvider() EscrowOperationsProvider {
+	if h.escrow != nil {
+		return h.escrow
+	}
+	if h.provider != nil {
+		return h.provider
+	}
+	return nil
+}
+
 // ========== 声誉扩展处理器 ==========
 
 // HandleReputationUpdate 更新声誉
 func (h *ExtendedOperationHandlers) HandleReputationUpdate(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getReputationProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -499,7 +718,7 @@ func (h *ExtendedOperationHandlers) HandleReputationUpdate(w http.ResponseWriter
 
 // HandleReputationHistory 获取声誉历史
 func (h *ExtendedOperationHandlers) HandleReputationHistory(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getReputationProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -524,7 +743,7 @@ func (h *ExtendedOperationHandlers) HandleReputationHistory(w http.ResponseWrite
 
 // HandleTaskCreate 创建任务
 func (h *ExtendedOperationHandlers) HandleTaskCreate(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getTaskProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -552,7 +771,7 @@ func (h *ExtendedOperationHandlers) HandleTaskCreate(w http.ResponseWriter, r *h
 
 // HandleTaskStatus 获取任务状态
 func (h *ExtendedOperationHandlers) HandleTaskStatus(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getTaskProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -575,7 +794,7 @@ func (h *ExtendedOperationHandlers) HandleTaskStatus(w http.ResponseWriter, r *h
 
 // HandleTaskAccept 接受任务
 func (h *ExtendedOperationHandlers) HandleTaskAccept(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getTaskProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -600,7 +819,7 @@ func (h *ExtendedOperationHandlers) HandleTaskAccept(w http.ResponseWriter, r *h
 
 // HandleTaskSubmit 提交任务结果
 func (h *ExtendedOperationHandlers) HandleTaskSubmit(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getTaskProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -626,7 +845,7 @@ func (h *ExtendedOperationHandlers) HandleTaskSubmit(w http.ResponseWriter, r *h
 
 // HandleTaskList 获取任务列表
 func (h *ExtendedOperationHandlers) HandleTaskList(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getTaskProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1445,7 +1664,7 @@ func (h *ExtendedOperationHandlers) HandleManualPenalty(w http.ResponseWriter, r
 
 // HandleCollateralList 获取抵押物列表
 func (h *ExtendedOperationHandlers) HandleCollateralList(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getCollateralProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1467,7 +1686,7 @@ func (h *ExtendedOperationHandlers) HandleCollateralList(w http.ResponseWriter,
 
 // HandleCollateralByNode 按节点查询抵押物
 func (h *ExtendedOperationHandlers) HandleCollateralByNode(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getCollateralProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1492,7 +1711,7 @@ func (h *ExtendedOperationHandlers) HandleCollateralByNode(w http.ResponseWriter
 
 // HandleSlashByNode 按节点罚没
 func (h *ExtendedOperationHandlers) HandleSlashByNode(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getCollateralProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1511,6 +1730,11 @@ func (h *ExtendedOperationHandlers) HandleSlashByNode(w http.ResponseWriter, r *
 		return
 	}
 
+	if req.NodeID == "" || req.Purpose == "" || req.Evidence == "" {
+		WriteError(w, http.StatusBadRequest, "node_id, purpose and evidence are required")
+		return
+	}
+
 	result, err := provider.SlashByNode(req.NodeID, req.Purpose, req.Reason, req.Evidence, req.Ratio)
 	if err != nil {
 		WriteError(w, http.StatusInternalServerError, err.Error())
@@ -1522,7 +1746,7 @@ func (h *ExtendedOperationHandlers) HandleSlashByNode(w http.ResponseWriter, r *
 
 // HandleSlashHistory 获取罚没历史
 func (h *ExtendedOperationHandlers) HandleSlashHistory(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getCollateralProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1547,7 +1771,7 @@ func (h *ExtendedOperationHandlers) HandleSlashHistory(w http.ResponseWriter, r
 
 // HandleDisputeList 获取争议列表
 func (h *ExtendedOperationHandlers) HandleDisputeList(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getDisputeProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1569,7 +1793,7 @@ func (h *ExtendedOperationHandlers) HandleDisputeList(w http.ResponseWriter, r *
 
 // HandleDisputeSuggestion 获取争议建议
 func (h *ExtendedOperationHandlers) HandleDisputeSuggestion(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getDisputeProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1592,7 +1816,7 @@ func (h *ExtendedOperationHandlers) HandleDisputeSuggestion(w http.ResponseWrite
 
 // HandleVerifyEvidence 验证证据
 func (h *ExtendedOperationHandlers) HandleVerifyEvidence(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getDisputeProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1619,7 +1843,7 @@ func (h *ExtendedOperationHandlers) HandleVerifyEvidence(w http.ResponseWriter,
 
 // HandleApplySuggestion 应用建议
 func (h *ExtendedOperationHandlers) HandleApplySuggestion(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getDisputeProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1646,7 +1870,7 @@ func (h *ExtendedOperationHandlers) HandleApplySuggestion(w http.ResponseWriter,
 
 // HandleDisputeDetail 获取争议详情
 func (h *ExtendedOperationHandlers) HandleDisputeDetail(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getDisputeProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1667,11 +1891,317 @@ func (h *ExtendedOperationHandlers) HandleDisputeDetail(w http.ResponseWriter, r
 	WriteJSON(w, http.StatusOK, detail)
 }
 
+// ========== 代币账本处理器 ==========
+
+// HandleTokenBalance 查询代币余额，node_id 省略时为本节点
+func (h *ExtendedOperationHandlers) HandleTokenBalance(w http.ResponseWriter, r *http.Request) {
+	provider := h.getTokenProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	account, err := provider.GetTokenBalance(r.URL.Query().Get("node_id"))
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, account)
+}
+
+// HandleTokenHistory 获取代币流水
+func (h *ExtendedOperationHandlers) HandleTokenHistory(w http.ResponseWriter, r *http.Request) {
+	provider := h.getTokenProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	nodeID := r.URL.Query().Get("node_id")
+	limit := parseIntParam(r, "limit", 50)
+
+	history, err := provider.GetTokenHistory(nodeID, limit)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// HandleTokenTransfer 本节点签名转账
+func (h *ExtendedOperationHandlers) HandleTokenTransfer(w http.ResponseWriter, r *http.Request) {
+	provider := h.getTokenProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		To     string  `json:"to"`
+		Amount float64 `json:"amount"`
+		Memo   string  `json:"memo"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.To == "" || req.Amount <= 0 {
+		WriteError(w, http.StatusBadRequest, "to and a positive amount are required")
+		return
+	}
+
+	entry, err := provider.TransferTokens(req.To, req.Amount, req.Memo)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, entry)
+}
+
+// ========== 活动报告处理器 ==========
+
+// HandleReportList 列出已生成的活动报告，周期最近的在前
+func (h *ExtendedOperationHandlers) HandleReportList(w http.ResponseWriter, r *http.Request) {
+	provider := h.getReportProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	reports, err := provider.ListReports()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"reports": reports,
+		"count":   len(reports),
+	})
+}
+
+// HandleReportDownload 下载报告，format 为 json（默认）、html 或 pdf
+func (h *ExtendedOperationHandlers) HandleReportDownload(w http.ResponseWriter, r *http.Request) {
+	provider := h.getReportProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		WriteError(w, http.StatusBadRequest, "Report ID required")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	data, contentType, err := provider.GetReport(id, format)
+	switch {
+	case errors.Is(err, ErrReportNotFound):
+		WriteError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, ErrInvalidReport):
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	// inline=1 时在浏览器中直接打开，否则作为附件下载
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "1" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="activity-report-%s.%s"`, disposition, id, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// HandleReportGenerate 立即生成截至当前最近若干天（默认 7 天）的报告
+func (h *ExtendedOperationHandlers) HandleReportGenerate(w http.ResponseWriter, r *http.Request) {
+	provider := h.getReportProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		Days int `json:"days"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Days < 0 || req.Days > 366 {
+		WriteError(w, http.StatusBadRequest, "days must be between 1 and 366")
+		return
+	}
+
+	info, err := provider.GenerateReport(req.Days)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, info)
+}
+
+// ========== 邮箱通讯录处理器 ==========
+
+// HandleContactList 列出联系人，q 非空时用于写信时的收件人补全
+func (h *ExtendedOperationHandlers) HandleContactList(w http.ResponseWriter, r *http.Request) {
+	provider := h.getContactProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	contacts, err := provider.ListContacts(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"contacts": contacts,
+		"count":    len(contacts),
+	})
+}
+
+// HandleContactSave 添加或更新联系人
+func (h *ExtendedOperationHandlers) HandleContactSave(w http.ResponseWriter, r *http.Request) {
+	provider := h.getContactProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ContactInfo
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.NodeID == "" {
+		WriteError(w, http.StatusBadRequest, "Node ID required")
+		return
+	}
+
+	contact, err := provider.SaveContact(&req)
+	if errors.Is(err, ErrInvalidContact) {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, contact)
+}
+
+// HandleContactDelete 删除联系人
+func (h *ExtendedOperationHandlers) HandleContactDelete(w http.ResponseWriter, r *http.Request) {
+	provider := h.getContactProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	nodeID := r.URL.Query().Get("node_id")
+	if nodeID == "" {
+		WriteError(w, http.StatusBadRequest, "Node ID required")
+		return
+	}
+
+	err := provider.DeleteContact(nodeID)
+	if errors.Is(err, ErrContactNotFound) {
+		WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": nodeID,
+		"deleted": true,
+	})
+}
+
+// HandleContactCheck 发信前检查收件人，返回联系人信息和需要确认的警告
+func (h *ExtendedOperationHandlers) HandleContactCheck(w http.ResponseWriter, r *http.Request) {
+	provider := h.getContactProvider()
+	if provider == nil {
+		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
+		return
+	}
+
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		WriteError(w, http.StatusBadRequest, "Recipient required")
+		return
+	}
+
+	check, err := provider.CheckRecipient(to)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, check)
+}
+
 // ========== 托管多签处理器 (Task44) ==========
 
 // HandleEscrowList 获取托管列表
 func (h *ExtendedOperationHandlers) HandleEscrowList(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getEscrowProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1693,7 +2223,7 @@ func (h *ExtendedOperationHandlers) HandleEscrowList(w http.ResponseWriter, r *h
 
 // HandleEscrowDetail 获取托管详情
 func (h *ExtendedOperationHandlers) HandleEscrowDetail(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getEscrowProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1716,7 +2246,7 @@ func (h *ExtendedOperationHandlers) HandleEscrowDetail(w http.ResponseWriter, r
 
 // HandleArbitratorSignature 提交仲裁者签名
 func (h *ExtendedOperationHandlers) HandleArbitratorSignature(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getEscrowProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1744,7 +2274,7 @@ func (h *ExtendedOperationHandlers) HandleArbitratorSignature(w http.ResponseWri
 
 // HandleSignatureCount 获取签名数量
 func (h *ExtendedOperationHandlers) HandleSignatureCount(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getEscrowProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
@@ -1767,7 +2297,7 @@ func (h *ExtendedOperationHandlers) HandleSignatureCount(w http.ResponseWriter,
 
 // HandleEscrowResolve 解决托管
 func (h *ExtendedOperationHandlers) HandleEscrowResolve(w http.ResponseWriter, r *http.Request) {
-	provider := h.getProvider()
+	provider := h.getEscrowProvider()
 	if provider == nil {
 		WriteError(w, http.StatusServiceUnavailable, "Provider not available")
 		return
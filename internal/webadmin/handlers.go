@@ -27,6 +27,7 @@ type LoginResponse struct {
 	Success   bool   `json:"success"`
 	SessionID string `json:"session_id,omitempty"`
 	ExpiresAt string `json:"expires_at,omitempty"`
+	Role      string `json:"role,omitempty"`
 	Error     string `json:"error,omitempty"`
 }
 
@@ -65,6 +66,7 @@ func (h *Handlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		Success:   true,
 		SessionID: session.ID,
 		ExpiresAt: session.ExpiresAt.Format(time.RFC3339),
+		Role:      string(session.Role),
 	})
 }
 
@@ -257,59 +259,77 @@ func (h *Handlers) HandleIndex(w http.ResponseWriter, r *http.Request) {
 
 // HandleWSTopology handles WebSocket connections for topology updates.
 func (h *Handlers) HandleWSTopology(w http.ResponseWriter, r *http.Request) {
-	conn, err := wsUpgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
-
-	client := &WSClient{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		channel:  "topology",
-		hub:      h.server.wsHub,
-	}
-
-	h.server.wsHub.register <- client
-
-	go client.writePump()
-	go client.readPump()
+	h.serveWS(w, r, "topology")
 }
 
 // HandleWSLogs handles WebSocket connections for log streaming.
 func (h *Handlers) HandleWSLogs(w http.ResponseWriter, r *http.Request) {
-	conn, err := wsUpgrader.Upgrade(w, r, nil)
-	if err != nil {
-		return
-	}
+	h.serveWS(w, r, "logs")
+}
 
+// HandleWSStats handles WebSocket connections for stats updates.
+func (h *Handlers) HandleWSStats(w http.ResponseWriter, r *http.Request) {
+	h.serveWS(w, r, "stats")
+}
+
+// HandleWSTasks handles WebSocket connections for task progress updates.
+func (h *Handlers) HandleWSTasks(w http.ResponseWriter, r *http.Request) {
+	h.serveWS(w, r, "tasks")
+}
+
+// serveWS upgrades the connection and subscribes it to a hub channel.
+//
+// Clients opt into resumable mode with ?resume=1. They then receive a
+// "session" message with a reconnect token and seq-framed events, and can
+// reconnect with ?reconnect=<token>&epoch=<epoch>&last_seq=<n> to resume the
+// same session and have missed events replayed.
+func (h *Handlers) serveWS(w http.ResponseWriter, r *http.Request, channel string) {
+	hub := h.server.wsHub
+	id, _ := r.Context().Value(wsIdentityKey{}).(Identity)
 	client := &WSClient{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		channel:  "logs",
-		hub:      h.server.wsHub,
+		channel:       channel,
+		hub:           hub,
+		issuer:        h.server.reconnect,
+		identity:      id,
+		identityValid: h.server.auth.identityValid,
 	}
 
-	h.server.wsHub.register <- client
-
-	go client.writePump()
-	go client.readPump()
-}
+	q := r.URL.Query()
+	if token := q.Get("reconnect"); token != "" {
+		claims, err := h.server.reconnect.Verify(token)
+		if err != nil || claims.Channel != channel {
+			http.Error(w, "invalid reconnect token", http.StatusUnauthorized)
+			return
+		}
+		client.resumable = true
+		client.sessionID = claims.SessionID
+		client.resumeEpoch = q.Get("epoch")
+		client.lastSeq, _ = strconv.ParseUint(q.Get("last_seq"), 10, 64)
+	} else if q.Get("resume") == "1" {
+		sessionID, err := generateSessionID()
+		if err != nil {
+			http.Error(w, "failed to create session", http.StatusInternalServerError)
+			return
+		}
+		client.resumable = true
+		client.sessionID = sessionID
+	}
 
-// HandleWSStats handles WebSocket connections for stats updates.
-func (h *Handlers) HandleWSStats(w http.ResponseWriter, r *http.Request) {
 	conn, err := wsUpgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
-
-	client := &WSClient{
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		channel:  "stats",
-		hub:      h.server.wsHub,
+	client.conn = conn
+
+	if client.resumable {
+		// Room for the session message plus a full replay
+		client.send = make(chan []byte, 256+replayBufferSize)
+		client.send <- client.sessionMessage(hub.epoch)
+	} else {
+		client.send = make(chan []byte, 256)
 	}
 
-	h.server.wsHub.register <- client
+	hub.register <- client
 
 	go client.writePump()
 	go client.readPump()
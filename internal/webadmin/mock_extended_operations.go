@@ -8,6 +8,9 @@ import (
 // MockExtendedOperationsProvider Mock扩展操作提供者（用于测试）
 type MockExtendedOperationsProvider struct {
 	*MockOperationsProvider
+
+	// proposals 非空时提案接口返回这些数据（演示模式），否则返回固定样例
+	proposals []*ProposalDetail
 }
 
 // NewMockExtendedOperationsProvider 创建Mock扩展操作提供者
@@ -151,6 +154,18 @@ func (m *MockExtendedOperationsProvider) CreateProposal(title, proposalType, tar
 }
 
 func (m *MockExtendedOperationsProvider) ListProposals(status string) ([]*ProposalInfo, error) {
+	if m.proposals != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		result := make([]*ProposalInfo, 0, len(m.proposals))
+		for _, p := range m.proposals {
+			if status == "" || p.Status == status {
+				info := p.ProposalInfo
+				result = append(result, &info)
+			}
+		}
+		return result, nil
+	}
 	return []*ProposalInfo{
 		{
 			ID:        "prop-001",
@@ -167,6 +182,17 @@ func (m *MockExtendedOperationsProvider) ListProposals(status string) ([]*Propos
 }
 
 func (m *MockExtendedOperationsProvider) GetProposal(id string) (*ProposalDetail, error) {
+	if m.proposals != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		for _, p := range m.proposals {
+			if p.ID == id {
+				detail := *p
+				return &detail, nil
+			}
+		}
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
 	return &ProposalDetail{
 		ProposalInfo: ProposalInfo{
 			ID:        id,
@@ -186,7 +212,27 @@ func (m *MockExtendedOperationsProvider) GetProposal(id string) (*ProposalDetail
 }
 
 func (m *MockExtendedOperationsProvider) Vote(proposalID, vote string) error {
-	return nil
+	if m.proposals == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.proposals {
+		if p.ID != proposalID {
+			continue
+		}
+		if _, voted := p.Votes["self"]; voted {
+			return fmt.Errorf("already voted")
+		}
+		p.Votes["self"] = vote
+		if vote == "yes" {
+			p.VotesYes++
+		} else {
+			p.VotesNo++
+		}
+		return nil
+	}
+	return fmt.Errorf("proposal not found: %s", proposalID)
 }
 
 func (m *MockExtendedOperationsProvider) FinalizeProposal(proposalID string) (*ProposalResult, error) {
@@ -510,3 +556,119 @@ func (m *MockExtendedOperationsProvider) ResolveEscrow(escrowID, winner string,
 		Amount:   1000.0,
 	}, nil
 }
+
+// Token operations
+
+func (m *MockExtendedOperationsProvider) GetTokenBalance(nodeID string) (*TokenAccountInfo, error) {
+	if nodeID == "" {
+		nodeID = "self"
+	}
+	return &TokenAccountInfo{
+		NodeID:    nodeID,
+		Balance:   120.0,
+		Locked:    20.0,
+		Available: 100.0,
+		Minted:    120.0,
+		Nonce:     3,
+	}, nil
+}
+
+func (m *MockExtendedOperationsProvider) GetTokenHistory(nodeID string, limit int) ([]*TokenEntryInfo, error) {
+	return []*TokenEntryInfo{
+		{ID: "tx_2", Type: "transfer", From: "self", To: "node-2", Amount: 10.0, Timestamp: time.Now().Format(time.RFC3339)},
+		{ID: "tx_1", Type: "mint", To: "self", Amount: 120.0, Reference: "reward-1", Timestamp: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+	}, nil
+}
+
+func (m *MockExtendedOperationsProvider) TransferTokens(to string, amount float64, memo string) (*TokenEntryInfo, error) {
+	return &TokenEntryInfo{
+		ID:        "tx_3",
+		Type:      "transfer",
+		From:      "self",
+		To:        to,
+		Amount:    amount,
+		Reference: memo,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+// Report operations
+
+func (m *MockExtendedOperationsProvider) ListReports() ([]*ReportInfo, error) {
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	return []*ReportInfo{
+		{
+			ID:               end.AddDate(0, 0, -7).Format("20060102T1504Z") + "-" + end.Format("20060102T1504Z"),
+			PeriodStart:      end.AddDate(0, 0, -7).Format(time.RFC3339),
+			PeriodEnd:        end.Format(time.RFC3339),
+			GeneratedAt:      end.Format(time.RFC3339),
+			TasksCompleted:   4,
+			TokensNet:        35.0,
+			ReputationChange: 2.5,
+			Disputes:         1,
+		},
+	}, nil
+}
+
+func (m *MockExtendedOperationsProvider) GetReport(id, format string) ([]byte, string, error) {
+	switch format {
+	case "json":
+		return []byte(`{"id":"` + id + `"}`), "application/json", nil
+	case "html":
+		return []byte("<html><body>" + id + "</body></html>"), "text/html; charset=utf-8", nil
+	case "pdf":
+		return []byte("%PDF-1.4\n"), "application/pdf", nil
+	}
+	return nil, "", ErrInvalidReport
+}
+
+func (m *MockExtendedOperationsProvider) GenerateReport(days int) (*ReportInfo, error) {
+	if days <= 0 {
+		days = 7
+	}
+	end := time.Now().UTC().Truncate(time.Minute)
+	start := end.AddDate(0, 0, -days)
+	return &ReportInfo{
+		ID:          start.Format("20060102T1504Z") + "-" + end.Format("20060102T1504Z"),
+		PeriodStart: start.Format(time.RFC3339),
+		PeriodEnd:   end.Format(time.RFC3339),
+		GeneratedAt: end.Format(time.RFC3339),
+	}, nil
+}
+
+// Contact operations
+
+func (m *MockExtendedOperationsProvider) ListContacts(query string, limit int) ([]*ContactInfo, error) {
+	now := time.Now().Format(time.RFC3339)
+	return []*ContactInfo{
+		{NodeID: "12D3KooWPeer001", Label: "Alice", Notes: "Reliable search executor", Trust: "trusted", Encryption: "always", CreatedAt: now, UpdatedAt: now},
+		{NodeID: "12D3KooWPeer002", Label: "Bob", Trust: "low", CreatedAt: now, UpdatedAt: now},
+	}, nil
+}
+
+func (m *MockExtendedOperationsProvider) SaveContact(contact *ContactInfo) (*ContactInfo, error) {
+	if contact.NodeID == "" {
+		return nil, ErrInvalidContact
+	}
+	saved := *contact
+	if saved.Trust == "" {
+		saved.Trust = "normal"
+	}
+	saved.CreatedAt = time.Now().Format(time.RFC3339)
+	saved.UpdatedAt = saved.CreatedAt
+	return &saved, nil
+}
+
+func (m *MockExtendedOperationsProvider) DeleteContact(nodeID string) error {
+	return nil
+}
+
+func (m *MockExtendedOperationsProvider) CheckRecipient(nodeID string) (*RecipientCheckInfo, error) {
+	reputation := 50.0
+	check := &RecipientCheckInfo{NodeID: nodeID, Reputation: &reputation, Warnings: []RecipientWarningInfo{}}
+	if nodeID == "12D3KooWPeer002" {
+		check.Contact = &ContactInfo{NodeID: nodeID, Label: "Bob", Trust: "low"}
+		check.Warnings = append(check.Warnings, RecipientWarningInfo{Code: "low_trust", Message: "Bob is marked as a low-trust contact"})
+	}
+	return check, nil
+}
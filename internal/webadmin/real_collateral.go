@@ -0,0 +1,91 @@
+package webadmin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+)
+
+// 管理后台的抵押账户操作，由节点的抵押管理器支撑；每个节点每种用途一个账户
+
+// SetCollateralManager 设置抵押管理器
+func (p *RealOperationsProvider) SetCollateralManager(m *collateral.CollateralManager) {
+	p.collateral = m
+}
+
+// ListCollaterals 获取抵押账户列表（status 为空时返回全部）
+func (p *RealOperationsProvider) ListCollaterals(status string) ([]*CollateralInfo, error) {
+	if p.collateral == nil {
+		return nil, errors.New("collateral manager not available")
+	}
+	result := []*CollateralInfo{}
+	for _, a := range p.collateral.ListAccounts("") {
+		if status == "" || a.Status() == status {
+			result = append(result, collateralInfo(a))
+		}
+	}
+	return result, nil
+}
+
+// GetCollateralByNode 获取节点某一用途的抵押账户
+func (p *RealOperationsProvider) GetCollateralByNode(nodeID, purpose string) (*CollateralInfo, error) {
+	if p.collateral == nil {
+		return nil, errors.New("collateral manager not available")
+	}
+	a, err := p.collateral.GetAccount(nodeID, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return collateralInfo(a), nil
+}
+
+// SlashByNode 按比例罚没节点某一用途的抵押
+func (p *RealOperationsProvider) SlashByNode(nodeID, purpose, reason, evidence string, ratio float64) (*SlashResult, error) {
+	if p.collateral == nil {
+		return nil, errors.New("collateral manager not available")
+	}
+	event, err := p.collateral.SlashAccount(nodeID, purpose, reason, []string{evidence}, ratio)
+	if err != nil {
+		return nil, err
+	}
+	return &SlashResult{
+		ID:            event.ID,
+		SlashedAmount: event.Amount,
+		Remaining:     event.Remaining,
+	}, nil
+}
+
+// GetSlashHistory 获取罚没记录，最新的在前（nodeID 为空时返回全部节点）
+func (p *RealOperationsProvider) GetSlashHistory(nodeID string, limit int) ([]*SlashRecord, error) {
+	if p.collateral == nil {
+		return nil, errors.New("collateral manager not available")
+	}
+	events := p.collateral.QuerySlashHistory(nodeID, "", limit)
+	result := make([]*SlashRecord, 0, len(events))
+	for _, e := range events {
+		result = append(result, &SlashRecord{
+			ID:        e.ID,
+			NodeID:    e.Owner,
+			Purpose:   e.Purpose,
+			Amount:    e.Amount,
+			Reason:    e.Reason,
+			Evidence:  e.Evidence,
+			Timestamp: e.Timestamp.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
+
+func collateralInfo(a *collateral.Account) *CollateralInfo {
+	return &CollateralInfo{
+		CollateralID: a.NodeID + "/" + a.Purpose,
+		NodeID:       a.NodeID,
+		Purpose:      a.Purpose,
+		Amount:       a.Balance,
+		Locked:       a.Locked,
+		Slashed:      a.Slashed,
+		Status:       a.Status(),
+		CreatedAt:    a.CreatedAt.Format(time.RFC3339),
+	}
+}
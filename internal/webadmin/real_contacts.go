@@ -0,0 +1,91 @@
+package webadmin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+)
+
+// 管理后台的邮箱通讯录：联系人管理、写信补全和发信前的收件人检查
+
+func contactInfo(c *mailbox.Contact) *ContactInfo {
+	if c == nil {
+		return nil
+	}
+	return &ContactInfo{
+		NodeID:     c.NodeID,
+		Label:      c.Label,
+		Notes:      c.Notes,
+		Trust:      string(c.Trust),
+		Encryption: string(c.Encryption),
+		CreatedAt:  c.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  c.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ListContacts 列出联系人，query 非空时按标签、节点 ID 和备注补全
+func (p *RealOperationsProvider) ListContacts(query string, limit int) ([]*ContactInfo, error) {
+	if p.mailbox == nil {
+		return nil, errors.New("mailbox not configured")
+	}
+	contacts := p.mailbox.SearchContacts(query, limit)
+	result := make([]*ContactInfo, 0, len(contacts))
+	for _, c := range contacts {
+		result = append(result, contactInfo(c))
+	}
+	return result, nil
+}
+
+// SaveContact 添加或更新联系人
+func (p *RealOperationsProvider) SaveContact(contact *ContactInfo) (*ContactInfo, error) {
+	if p.mailbox == nil {
+		return nil, errors.New("mailbox not configured")
+	}
+	saved, err := p.mailbox.SaveContact(mailbox.Contact{
+		NodeID:     contact.NodeID,
+		Label:      contact.Label,
+		Notes:      contact.Notes,
+		Trust:      mailbox.TrustLevel(contact.Trust),
+		Encryption: mailbox.EncryptionPreference(contact.Encryption),
+	})
+	if errors.Is(err, mailbox.ErrInvalidContact) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidContact, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return contactInfo(saved), nil
+}
+
+// DeleteContact 删除联系人
+func (p *RealOperationsProvider) DeleteContact(nodeID string) error {
+	if p.mailbox == nil {
+		return errors.New("mailbox not configured")
+	}
+	err := p.mailbox.DeleteContact(nodeID)
+	if errors.Is(err, mailbox.ErrContactNotFound) {
+		return ErrContactNotFound
+	}
+	return err
+}
+
+// CheckRecipient 发信前检查收件人的信任等级、声誉和隔离状态
+func (p *RealOperationsProvider) CheckRecipient(nodeID string) (*RecipientCheckInfo, error) {
+	if p.mailbox == nil {
+		return nil, errors.New("mailbox not configured")
+	}
+	check := p.mailbox.CheckRecipient(nodeID)
+	result := &RecipientCheckInfo{
+		NodeID:      check.NodeID,
+		Contact:     contactInfo(check.Contact),
+		Reputation:  check.Reputation,
+		Quarantined: check.Quarantined,
+		Warnings:    make([]RecipientWarningInfo, 0, len(check.Warnings)),
+	}
+	for _, w := range check.Warnings {
+		result.Warnings = append(result.Warnings, RecipientWarningInfo{Code: w.Code, Message: w.Message})
+	}
+	return result, nil
+}
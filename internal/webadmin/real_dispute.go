@@ -0,0 +1,154 @@
+package webadmin
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+)
+
+// 管理后台的争议预审操作，由节点的争议管理器支撑；验证者和批准者为空时记为本节点
+
+// SetDisputeManager 设置争议管理器
+func (p *RealOperationsProvider) SetDisputeManager(m *dispute.DisputeManager) {
+	p.disputes = m
+}
+
+// ListDisputes 获取争议列表（status 为空时返回全部）
+func (p *RealOperationsProvider) ListDisputes(status string) ([]*DisputeInfo, error) {
+	if p.disputes == nil {
+		return nil, errors.New("dispute manager not available")
+	}
+	var list []*dispute.Dispute
+	if status != "" {
+		list = p.disputes.GetDisputesByStatus(dispute.DisputeStatus(status))
+	} else {
+		for _, s := range []dispute.DisputeStatus{
+			dispute.DisputePending, dispute.DisputeInReview, dispute.DisputeArbitration,
+			dispute.DisputeResolved, dispute.DisputeDismissed, dispute.DisputeExpired,
+		} {
+			list = append(list, p.disputes.GetDisputesByStatus(s)...)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+
+	result := make([]*DisputeInfo, 0, len(list))
+	for _, d := range list {
+		result = append(result, disputeInfo(d))
+	}
+	return result, nil
+}
+
+// GetDisputeSuggestion 由规则引擎生成裁决建议
+func (p *RealOperationsProvider) GetDisputeSuggestion(id string) (*DisputeSuggestionInfo, error) {
+	if p.disputes == nil {
+		return nil, errors.New("dispute manager not available")
+	}
+	suggestion, err := p.disputes.Suggest(id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := p.disputes.GetDispute(id)
+	if err != nil {
+		return nil, err
+	}
+	return &DisputeSuggestionInfo{
+		DisputeID:           id,
+		SuggestedResolution: resolutionLabel(d, suggestion.Suggestion),
+		Confidence:          suggestion.Confidence,
+		CanAutoExecute:      suggestion.CanAutoExecute,
+		MissingEvidence:     append([]string{}, suggestion.MissingEvidence...),
+		Warnings:            append([]string{}, suggestion.Warnings...),
+	}, nil
+}
+
+// VerifyEvidence 验证证据，本节点保存了原件时校验哈希
+func (p *RealOperationsProvider) VerifyEvidence(disputeID, evidenceID, verifierID string) error {
+	if p.disputes == nil {
+		return errors.New("dispute manager not available")
+	}
+	if verifierID == "" {
+		verifierID = p.nodeID
+	}
+	return p.disputes.VerifyEvidence(disputeID, evidenceID, verifierID)
+}
+
+// ApplyDisputeSuggestion 批准并执行最近一次裁决建议
+func (p *RealOperationsProvider) ApplyDisputeSuggestion(disputeID, approverID string) (*ApplySuggestionResult, error) {
+	if p.disputes == nil {
+		return nil, errors.New("dispute manager not available")
+	}
+	if approverID == "" {
+		approverID = p.nodeID
+	}
+	resolution, err := p.disputes.ApplySuggestion(disputeID, approverID)
+	if err != nil {
+		return nil, err
+	}
+	d, err := p.disputes.GetDispute(disputeID)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplySuggestionResult{
+		Applied:    true,
+		Resolution: resolutionLabel(d, resolution),
+	}, nil
+}
+
+// GetDisputeDetail 获取争议详情
+func (p *RealOperationsProvider) GetDisputeDetail(id string) (*DisputeDetail, error) {
+	if p.disputes == nil {
+		return nil, errors.New("dispute manager not available")
+	}
+	d, err := p.disputes.GetDispute(id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &DisputeDetail{
+		DisputeInfo: *disputeInfo(d),
+		Description: d.Description,
+		Evidence:    make([]EvidenceInfo, 0, len(d.Evidence)),
+	}
+	for _, e := range d.Evidence {
+		detail.Evidence = append(detail.Evidence, EvidenceInfo{
+			ID:          e.ID,
+			Type:        e.Type,
+			Content:     e.Content,
+			SubmittedBy: e.SubmitterID,
+			Verified:    e.Verified,
+			VerifiedBy:  e.VerifiedBy,
+			Timestamp:   time.Unix(e.SubmittedAt, 0).Format(time.RFC3339),
+		})
+	}
+	if d.Resolution != nil {
+		detail.Resolution = resolutionLabel(d, d.Resolution)
+	}
+	if d.ResolvedAt > 0 {
+		detail.ResolvedAt = time.Unix(d.ResolvedAt, 0).Format(time.RFC3339)
+	}
+	return detail, nil
+}
+
+func disputeInfo(d *dispute.Dispute) *DisputeInfo {
+	return &DisputeInfo{
+		ID:        d.ID,
+		Plaintiff: d.ComplainantID,
+		Defendant: d.DefendantID,
+		Status:    string(d.Status),
+		CreatedAt: time.Unix(d.CreatedAt, 0).Format(time.RFC3339),
+		EscrowID:  d.EscrowID,
+	}
+}
+
+// resolutionLabel 裁决对申诉方有利记为 favor_plaintiff，对被诉方有利记为 favor_defendant，驳回记为 dismissed
+func resolutionLabel(d *dispute.Dispute, r *dispute.Resolution) string {
+	switch {
+	case r == nil || r.Winner == "":
+		return "dismissed"
+	case r.Winner == d.ComplainantID:
+		return "favor_plaintiff"
+	}
+	return "favor_defendant"
+}
@@ -0,0 +1,167 @@
+package webadmin
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
+)
+
+// 管理后台的托管多签操作，由节点的托管管理器支撑；签名为空时以本节点身份对结算方案签名
+
+// SetEscrowManager 设置托管管理器和本节点签名函数
+func (p *RealOperationsProvider) SetEscrowManager(m *escrow.EscrowManager, sign func(data []byte) (string, error)) {
+	p.escrow = m
+	p.escrowSign = sign
+}
+
+// ListEscrows 获取托管列表（status 为空时返回全部）
+func (p *RealOperationsProvider) ListEscrows(status string) ([]*EscrowInfo, error) {
+	if p.escrow == nil {
+		return nil, errors.New("escrow manager not available")
+	}
+	var list []*escrow.Escrow
+	if status != "" {
+		list = p.escrow.GetEscrowsByStatus(escrow.EscrowStatus(status))
+	} else {
+		for _, s := range []escrow.EscrowStatus{
+			escrow.EscrowPending, escrow.EscrowLocked, escrow.EscrowDisputed,
+			escrow.EscrowReleased, escrow.EscrowRefunded, escrow.EscrowForfeited,
+		} {
+			list = append(list, p.escrow.GetEscrowsByStatus(s)...)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+
+	result := make([]*EscrowInfo, 0, len(list))
+	for _, e := range list {
+		result = append(result, escrowInfo(e))
+	}
+	return result, nil
+}
+
+// GetEscrowDetail 获取托管详情
+func (p *RealOperationsProvider) GetEscrowDetail(id string) (*EscrowDetail, error) {
+	if p.escrow == nil {
+		return nil, errors.New("escrow manager not available")
+	}
+	e, err := p.escrow.GetEscrow(id)
+	if err != nil {
+		return nil, err
+	}
+	signers, _ := p.escrow.GetArbitratorSigners(id)
+	_, required, _ := p.escrow.GetArbitratorSignatureCount(id)
+
+	detail := &EscrowDetail{
+		EscrowInfo:     *escrowInfo(e),
+		Description:    e.TaskID,
+		Arbitrators:    append([]string{}, e.Arbitrators...),
+		MinSignatures:  required,
+		CurrentSigners: append([]string{}, signers...),
+	}
+	if e.LockedUntil > 0 {
+		detail.ExpiresAt = time.Unix(e.LockedUntil, 0).Format(time.RFC3339)
+	}
+	return detail, nil
+}
+
+// SubmitArbitratorSignature 提交仲裁者签名；未给出签名时由本节点作为仲裁者签名
+func (p *RealOperationsProvider) SubmitArbitratorSignature(escrowID, arbitratorID, signature string) (*SignatureSubmitResult, error) {
+	if p.escrow == nil {
+		return nil, errors.New("escrow manager not available")
+	}
+	if signature == "" {
+		e, err := p.escrow.GetEscrow(escrowID)
+		if err != nil {
+			return nil, err
+		}
+		if e.Resolution == nil {
+			return nil, escrow.ErrNoResolution
+		}
+		if p.escrowSign == nil {
+			return nil, errors.New("signature is required")
+		}
+		arbitratorID = p.nodeID
+		signature, err = p.escrowSign(escrow.ResolutionPayload(escrowID, e.Resolution.Winner, e.Resolution.Amount))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.escrow.SubmitArbitratorSignature(escrowID, arbitratorID, signature); err != nil {
+		return nil, err
+	}
+	count, required, err := p.escrow.GetArbitratorSignatureCount(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureSubmitResult{
+		Submitted:    true,
+		CurrentCount: count,
+		Required:     required,
+	}, nil
+}
+
+// GetSignatureCount 获取仲裁签名数量
+func (p *RealOperationsProvider) GetSignatureCount(escrowID string) (*SignatureCountInfo, error) {
+	if p.escrow == nil {
+		return nil, errors.New("escrow manager not available")
+	}
+	count, required, err := p.escrow.GetArbitratorSignatureCount(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	signers, _ := p.escrow.GetArbitratorSigners(escrowID)
+	if signers == nil {
+		signers = []string{}
+	}
+	return &SignatureCountInfo{
+		EscrowID:     escrowID,
+		CurrentCount: count,
+		Required:     required,
+		Signers:      signers,
+	}, nil
+}
+
+// ResolveEscrow 以仲裁签名结算争议，释放全部可用余额给胜出方
+func (p *RealOperationsProvider) ResolveEscrow(escrowID, winner string, signatures map[string]string) (*EscrowResolveResult, error) {
+	if p.escrow == nil {
+		return nil, errors.New("escrow manager not available")
+	}
+	if err := p.escrow.ResolveDispute(escrowID, winner, 0, signatures); err != nil {
+		return nil, err
+	}
+	e, err := p.escrow.GetEscrow(escrowID)
+	if err != nil {
+		return nil, err
+	}
+	return &EscrowResolveResult{
+		Resolved: true,
+		Winner:   e.ReleasedTo,
+		Amount:   e.ReleasedAmount,
+	}, nil
+}
+
+// escrowInfo 押金最多的参与方作为存入方，释放对象（或待签名方案的胜出方）作为受益方
+func escrowInfo(e *escrow.Escrow) *EscrowInfo {
+	var depositor string
+	for _, nodeID := range e.Participants {
+		d := e.Deposits[nodeID]
+		if depositor == "" || d > e.Deposits[depositor] || (d == e.Deposits[depositor] && nodeID < depositor) {
+			depositor = nodeID
+		}
+	}
+	beneficiary := e.ReleasedTo
+	if beneficiary == "" && e.Resolution != nil {
+		beneficiary = e.Resolution.Winner
+	}
+	return &EscrowInfo{
+		ID:          e.ID,
+		Amount:      e.TotalAmount,
+		Depositor:   depositor,
+		Beneficiary: beneficiary,
+		Status:      string(e.Status),
+		CreatedAt:   time.Unix(e.CreatedAt, 0).Format(time.RFC3339),
+	}
+}
@@ -7,9 +7,16 @@ import (
 	"time"
 
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/bulletin"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/collateral"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/dispute"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/escrow"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/neighbor"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/report"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
 	"github.com/AgentNetworkPlan/AgentNetwork/internal/security"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/multiformats/go-multiaddr"
 )
@@ -20,6 +27,15 @@ type RealOperationsProvider struct {
 	neighborManager *neighbor.NeighborManager
 	mailbox         *mailbox.Mailbox
 	bulletinBoard   *bulletin.BulletinBoard
+	taskManager     *task.TaskManager
+	reputation      *reputation.Manager
+	escrow          *escrow.EscrowManager
+	escrowSign      func(data []byte) (string, error)
+	disputes        *dispute.DisputeManager
+	collateral      *collateral.CollateralManager
+	tokens          *token.Ledger
+	tokenSign       func(data []byte) (string, error)
+	reports         *report.Generator
 	
 	// 安全管理器
 	securityManager *security.SecurityManager
@@ -278,7 +294,9 @@ func (p *RealOperationsProvider) SendMail(to, subject, content string) (*SendMai
 		}
 	}
 	
-	msg, err := p.mailbox.SendMessage(to, subject, []byte(content), false)
+	// 通讯录中设置了加密偏好的联系人按偏好加密
+	contact, _ := p.mailbox.GetContact(to)
+	msg, err := p.mailbox.SendMessage(to, subject, []byte(content), contact.Encrypt(false))
 	if err != nil {
 		return nil, err
 	}
@@ -512,45 +530,6 @@ func (p *RealOperationsProvider) GetSubscriptions() ([]string, error) {
 	return topics, nil
 }
 
-// ============ 声誉查询 ============
-
-// GetReputation 获取声誉
-func (p *RealOperationsProvider) GetReputation(nodeID string) (*ReputationInfo, error) {
-	// 简单实现：从邻居管理器获取
-	if p.neighborManager != nil {
-		n, err := p.neighborManager.GetNeighbor(nodeID)
-		if err == nil && n != nil {
-			return &ReputationInfo{
-				NodeID:     nodeID,
-				Reputation: float64(n.Reputation),
-			}, nil
-		}
-	}
-	
-	return &ReputationInfo{
-		NodeID:     nodeID,
-		Reputation: 0,
-	}, nil
-}
-
-// GetReputationRanking 获取声誉排行
-func (p *RealOperationsProvider) GetReputationRanking(limit int) ([]*ReputationInfo, error) {
-	if p.neighborManager == nil {
-		return []*ReputationInfo{}, nil
-	}
-	
-	neighbors := p.neighborManager.GetBestNeighbors(limit)
-	result := make([]*ReputationInfo, 0, len(neighbors))
-	for i, n := range neighbors {
-		result = append(result, &ReputationInfo{
-			NodeID:     n.NodeID,
-			Reputation: float64(n.Reputation),
-			Rank:       i + 1,
-		})
-	}
-	return result, nil
-}
-
 // ============ 消息发送 ============
 
 // SendDirectMessage 发送直接消息
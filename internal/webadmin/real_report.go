@@ -0,0 +1,73 @@
+package webadmin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/report"
+)
+
+// 管理后台的节点活动报告：列出已保存的报告、按格式下载、立即生成
+
+// SetReportGenerator 设置活动报告生成器
+func (p *RealOperationsProvider) SetReportGenerator(g *report.Generator) {
+	p.reports = g
+}
+
+func reportInfo(s *report.Summary) *ReportInfo {
+	return &ReportInfo{
+		ID:               s.ID,
+		PeriodStart:      s.PeriodStart.Format(time.RFC3339),
+		PeriodEnd:        s.PeriodEnd.Format(time.RFC3339),
+		GeneratedAt:      s.GeneratedAt.Format(time.RFC3339),
+		TasksCompleted:   s.TasksCompleted,
+		TokensNet:        s.TokensNet,
+		ReputationChange: s.ReputationChange,
+		Disputes:         s.Disputes,
+	}
+}
+
+// ListReports 已生成的报告，周期最近的在前
+func (p *RealOperationsProvider) ListReports() ([]*ReportInfo, error) {
+	if p.reports == nil {
+		return nil, errors.New("activity reports not available")
+	}
+	summaries, err := p.reports.Store().List()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ReportInfo, 0, len(summaries))
+	for _, s := range summaries {
+		result = append(result, reportInfo(s))
+	}
+	return result, nil
+}
+
+// GetReport 按格式（json、html、pdf）输出报告
+func (p *RealOperationsProvider) GetReport(id, format string) ([]byte, string, error) {
+	if p.reports == nil {
+		return nil, "", errors.New("activity reports not available")
+	}
+	data, err := p.reports.Store().Render(id, format)
+	switch {
+	case errors.Is(err, report.ErrReportNotFound):
+		return nil, "", ErrReportNotFound
+	case errors.Is(err, report.ErrInvalidID), errors.Is(err, report.ErrInvalidFormat):
+		return nil, "", ErrInvalidReport
+	case err != nil:
+		return nil, "", err
+	}
+	return data, report.ContentType(format), nil
+}
+
+// GenerateReport 立即生成截至当前最近 days 天的报告（不投递到邮箱）
+func (p *RealOperationsProvider) GenerateReport(days int) (*ReportInfo, error) {
+	if p.reports == nil {
+		return nil, errors.New("activity reports not available")
+	}
+	r, err := p.reports.GenerateRecent(days)
+	if err != nil {
+		return nil, err
+	}
+	return reportInfo(r.Summary()), nil
+}
@@ -0,0 +1,102 @@
+package webadmin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/reputation"
+)
+
+// 管理后台的声誉操作，由节点的声誉管理器支撑；未设置时退回邻居表中的声誉
+
+// SetReputationManager 设置声誉管理器
+func (p *RealOperationsProvider) SetReputationManager(m *reputation.Manager) {
+	p.reputation = m
+}
+
+// GetReputation 获取声誉
+func (p *RealOperationsProvider) GetReputation(nodeID string) (*ReputationInfo, error) {
+	if p.reputation != nil {
+		return &ReputationInfo{
+			NodeID:     nodeID,
+			Reputation: p.reputation.GetReputation(nodeID),
+		}, nil
+	}
+	if p.neighborManager != nil {
+		n, err := p.neighborManager.GetNeighbor(nodeID)
+		if err == nil && n != nil {
+			return &ReputationInfo{
+				NodeID:     nodeID,
+				Reputation: float64(n.Reputation),
+			}, nil
+		}
+	}
+
+	return &ReputationInfo{
+		NodeID:     nodeID,
+		Reputation: 0,
+	}, nil
+}
+
+// GetReputationRanking 获取声誉排行
+func (p *RealOperationsProvider) GetReputationRanking(limit int) ([]*ReputationInfo, error) {
+	if p.reputation != nil {
+		scores := p.reputation.Ranking(limit)
+		result := make([]*ReputationInfo, 0, len(scores))
+		for _, s := range scores {
+			result = append(result, &ReputationInfo{
+				NodeID:     s.NodeID,
+				Reputation: s.Score,
+				Rank:       s.Rank,
+			})
+		}
+		return result, nil
+	}
+	if p.neighborManager == nil {
+		return []*ReputationInfo{}, nil
+	}
+
+	neighbors := p.neighborManager.GetBestNeighbors(limit)
+	result := make([]*ReputationInfo, 0, len(neighbors))
+	for i, n := range neighbors {
+		result = append(result, &ReputationInfo{
+			NodeID:     n.NodeID,
+			Reputation: float64(n.Reputation),
+			Rank:       i + 1,
+		})
+	}
+	return result, nil
+}
+
+// UpdateReputation 手动调整节点声誉
+func (p *RealOperationsProvider) UpdateReputation(nodeID string, delta int, reason string) (*ReputationUpdateResult, error) {
+	if p.reputation == nil {
+		return nil, errors.New("reputation manager not available")
+	}
+	if reason == "" {
+		reason = "admin"
+	}
+	value, err := p.reputation.Adjust(nodeID, float64(delta), reason)
+	if err != nil {
+		return nil, err
+	}
+	return &ReputationUpdateResult{NewReputation: value}, nil
+}
+
+// GetReputationHistory 获取节点声誉变化历史（最新在前）
+func (p *RealOperationsProvider) GetReputationHistory(nodeID string, limit int) ([]*ReputationHistoryEntry, error) {
+	if p.reputation == nil {
+		return nil, errors.New("reputation manager not available")
+	}
+	entries := p.reputation.History(nodeID, limit)
+	history := make([]*ReputationHistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		history = append(history, &ReputationHistoryEntry{
+			Timestamp: e.Timestamp.Format(time.RFC3339),
+			Delta:     int(e.Delta),
+			Reason:    e.Reason,
+			NewValue:  e.Value,
+		})
+	}
+	return history, nil
+}
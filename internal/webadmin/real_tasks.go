@@ -0,0 +1,110 @@
+package webadmin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/task"
+)
+
+// 管理后台的任务操作，由节点的任务管理器支撑（任务簿持久化在数据目录中）
+
+// SetTaskManager 设置任务管理器
+func (p *RealOperationsProvider) SetTaskManager(tm *task.TaskManager) {
+	p.taskManager = tm
+}
+
+// CreateTask 以本节点为委托方发布任务
+func (p *RealOperationsProvider) CreateTask(taskType, description string, deadline int64) (*TaskCreateResult, error) {
+	if p.taskManager == nil {
+		return nil, errors.New("task manager not available")
+	}
+	title := description
+	if title == "" {
+		title = taskType
+	}
+	t := &task.Task{
+		Type:        task.TaskType(taskType),
+		Title:       title,
+		Description: description,
+		RequesterID: p.nodeID,
+		Deadline:    deadline,
+	}
+	if err := p.taskManager.PublishTask(t, 0); err != nil {
+		return nil, err
+	}
+	return &TaskCreateResult{TaskID: t.ID}, nil
+}
+
+// GetTaskStatus 查询任务状态、生命周期阶段和主执行者进度
+func (p *RealOperationsProvider) GetTaskStatus(taskID string) (*TaskStatus, error) {
+	if p.taskManager == nil {
+		return nil, errors.New("task manager not available")
+	}
+	t, err := p.taskManager.GetTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TaskStatus{
+		TaskID:   t.ID,
+		Status:   string(t.Status),
+		Stage:    string(task.StageOf(t.Status)),
+		Worker:   t.ExecutorID,
+		Deadline: t.Deadline,
+		Overdue:  t.OverdueAt > 0,
+	}
+	if progress, err := p.taskManager.GetProgress(taskID); err == nil && len(progress) > 0 {
+		status.Progress = int(progress[0].Percent)
+	}
+	switch task.StageOf(t.Status) {
+	case task.StageSubmitted, task.StageVerified, task.StageRewarded:
+		status.Progress = 100
+	}
+	return status, nil
+}
+
+// AcceptTask 本节点作为执行方接单
+func (p *RealOperationsProvider) AcceptTask(taskID string) error {
+	if p.taskManager == nil {
+		return errors.New("task manager not available")
+	}
+	return p.taskManager.ClaimTask(&task.TaskClaim{
+		TaskID:    taskID,
+		ClaimerID: p.nodeID,
+		ClaimTime: time.Now().Unix(),
+	}, 0)
+}
+
+// SubmitTaskResult 提交执行结果，以结果摘要作为交付物哈希
+func (p *RealOperationsProvider) SubmitTaskResult(taskID, result string) error {
+	if p.taskManager == nil {
+		return errors.New("task manager not available")
+	}
+	return p.taskManager.SubmitResult(taskID, p.nodeID, task.HashResult([]byte(result)), "")
+}
+
+// ListTasks 按状态列出任务
+func (p *RealOperationsProvider) ListTasks(status string, limit int) ([]*TaskInfo, error) {
+	if p.taskManager == nil {
+		return nil, errors.New("task manager not available")
+	}
+	filter, err := task.ParseStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	tasks := p.taskManager.ListTasks(filter, limit)
+	result := make([]*TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		result = append(result, &TaskInfo{
+			TaskID:      t.ID,
+			Type:        string(t.Type),
+			Description: t.Description,
+			Status:      string(t.Status),
+			Creator:     t.RequesterID,
+			Worker:      t.ExecutorID,
+			Deadline:    t.Deadline,
+			CreatedAt:   time.Unix(t.CreatedAt, 0).Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,75 @@
+package webadmin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/token"
+)
+
+// 管理后台的 $DAAN 代币账本操作；转账总是由本节点转出并用节点私钥签名
+
+// SetTokenLedger 设置代币账本和本节点的签名函数
+func (p *RealOperationsProvider) SetTokenLedger(l *token.Ledger, sign func(data []byte) (string, error)) {
+	p.tokens = l
+	p.tokenSign = sign
+}
+
+// GetTokenBalance 查询代币余额（nodeID 为空时为本节点）
+func (p *RealOperationsProvider) GetTokenBalance(nodeID string) (*TokenAccountInfo, error) {
+	if p.tokens == nil {
+		return nil, errors.New("token ledger not available")
+	}
+	if nodeID == "" {
+		nodeID = p.nodeID
+	}
+	a := p.tokens.GetAccount(nodeID)
+	return &TokenAccountInfo{
+		NodeID:    a.NodeID,
+		Balance:   a.Balance,
+		Locked:    a.Locked,
+		Available: a.Available(),
+		Minted:    a.Minted,
+		Nonce:     a.Nonce,
+	}, nil
+}
+
+// GetTokenHistory 获取代币流水，最新的在前（nodeID 为空时为本节点）
+func (p *RealOperationsProvider) GetTokenHistory(nodeID string, limit int) ([]*TokenEntryInfo, error) {
+	if p.tokens == nil {
+		return nil, errors.New("token ledger not available")
+	}
+	if nodeID == "" {
+		nodeID = p.nodeID
+	}
+	entries := p.tokens.History(nodeID, limit)
+	result := make([]*TokenEntryInfo, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, tokenEntryInfo(e))
+	}
+	return result, nil
+}
+
+// TransferTokens 从本节点签名转账
+func (p *RealOperationsProvider) TransferTokens(to string, amount float64, memo string) (*TokenEntryInfo, error) {
+	if p.tokens == nil || p.tokenSign == nil {
+		return nil, errors.New("token ledger not available")
+	}
+	e, err := p.tokens.SignAndTransfer(p.nodeID, to, amount, memo, p.tokenSign)
+	if err != nil {
+		return nil, err
+	}
+	return tokenEntryInfo(e), nil
+}
+
+func tokenEntryInfo(e *token.Entry) *TokenEntryInfo {
+	return &TokenEntryInfo{
+		ID:        e.ID,
+		Type:      string(e.Type),
+		From:      e.From,
+		To:        e.To,
+		Amount:    e.Amount,
+		Reference: e.Reference,
+		Timestamp: e.Timestamp.Format(time.RFC3339),
+	}
+}
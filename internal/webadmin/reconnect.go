@@ -0,0 +1,142 @@
+package webadmin
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// reconnectTokenTTL is how long a WebSocket reconnect token stays valid.
+	reconnectTokenTTL = 5 * time.Minute
+
+	// replayWindow is how far back missed events are kept for resuming clients.
+	replayWindow = 2 * time.Minute
+
+	// replayBufferSize caps the number of events retained per channel.
+	replayBufferSize = 512
+)
+
+// ReconnectClaims is the payload carried by a reconnect token.
+type ReconnectClaims struct {
+	SessionID string `json:"sid"`
+	Channel   string `json:"ch"`
+	ExpiresAt int64  `json:"exp"`
+	Identity
+}
+
+// Identity is the credential a WebSocket client authenticated with. It is
+// carried in reconnect tokens and resolved again on every reconnect.
+type Identity struct {
+	// KeyID is the API key used; empty for the admin token.
+	KeyID string `json:"kid,omitempty"`
+	// Credential is a fingerprint of the admin token; empty for API keys.
+	Credential string `json:"cred,omitempty"`
+}
+
+// ReconnectIssuer issues and verifies HMAC-signed WebSocket reconnect tokens.
+//
+// Tokens are bound to the identity the client authenticated with: a token
+// issued under the admin token stops working once it is rotated, and one
+// issued for an API key stops working once the key is revoked. When the key
+// is stable (see Config.ReconnectKey) tokens also survive a server restart.
+type ReconnectIssuer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewReconnectIssuer creates an issuer. A random key is used if key is empty.
+func NewReconnectIssuer(key []byte, ttl time.Duration) *ReconnectIssuer {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	if ttl <= 0 {
+		ttl = reconnectTokenTTL
+	}
+	return &ReconnectIssuer{key: key, ttl: ttl}
+}
+
+// Issue creates a reconnect token bound to a WebSocket session, channel and identity.
+func (ri *ReconnectIssuer) Issue(sessionID, channel string, id Identity) (string, time.Time) {
+	expiresAt := time.Now().Add(ri.ttl)
+	payload, _ := json.Marshal(ReconnectClaims{
+		SessionID: sessionID,
+		Channel:   channel,
+		ExpiresAt: expiresAt.Unix(),
+		Identity:  id,
+	})
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	return enc + "." + ri.sign(enc), expiresAt
+}
+
+// Verify checks the token signature and expiry and returns its claims.
+func (ri *ReconnectIssuer) Verify(token string) (*ReconnectClaims, error) {
+	enc, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(ri.sign(enc))) {
+		return nil, ErrInvalidReconnectToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, ErrInvalidReconnectToken
+	}
+	var claims ReconnectClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionID == "" {
+		return nil, ErrInvalidReconnectToken
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrReconnectTokenExpired
+	}
+	return &claims, nil
+}
+
+func (ri *ReconnectIssuer) sign(data string) string {
+	mac := hmac.New(sha256.New, ri.key)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// wsSessionMessage tells a resumable client how to reconnect.
+type wsSessionMessage struct {
+	Type           string    `json:"type"` // "session"
+	SessionID      string    `json:"session_id"`
+	Epoch          string    `json:"epoch"`
+	ReconnectToken string    `json:"reconnect_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// wsEventFrame wraps a broadcast for resumable clients so they can track seq.
+type wsEventFrame struct {
+	Type string          `json:"type"` // "event"
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+// wsResyncMessage tells a resuming client that missed events could not be
+// replayed and it should reload the full state.
+type wsResyncMessage struct {
+	Type  string `json:"type"` // "resync"
+	Epoch string `json:"epoch"`
+	Seq   uint64 `json:"seq"`
+}
+
+// hubEvent is a broadcast retained for replay.
+type hubEvent struct {
+	seq   uint64
+	at    time.Time
+	frame []byte
+}
+
+// encodeEventFrame frames a broadcast payload; non-JSON payloads become strings.
+func encodeEventFrame(seq uint64, data []byte) []byte {
+	raw := json.RawMessage(data)
+	if !json.Valid(data) {
+		raw, _ = json.Marshal(string(data))
+	}
+	frame, _ := json.Marshal(wsEventFrame{Type: "event", Seq: seq, Data: raw})
+	return frame
+}
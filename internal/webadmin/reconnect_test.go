@@ -0,0 +1,271 @@
+package webadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectIssuer(t *testing.T) {
+	issuer := NewReconnectIssuer([]byte("test-key"), time.Minute)
+
+	token, expiresAt := issuer.Issue("session-1", "stats", Identity{KeyID: "key-1"})
+	if time.Until(expiresAt) <= 0 {
+		t.Fatal("token should expire in the future")
+	}
+
+	claims, err := issuer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if claims.SessionID != "session-1" || claims.Channel != "stats" || claims.KeyID != "key-1" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// Same key (e.g. after restart) accepts the token
+	if _, err := NewReconnectIssuer([]byte("test-key"), time.Minute).Verify(token); err != nil {
+		t.Errorf("token should verify with the same key: %v", err)
+	}
+	if _, err := NewReconnectIssuer([]byte("other-key"), time.Minute).Verify(token); err != ErrInvalidReconnectToken {
+		t.Errorf("expected ErrInvalidReconnectToken, got %v", err)
+	}
+	if _, err := issuer.Verify(strings.Replace(token, ".", "x.", 1)); err != ErrInvalidReconnectToken {
+		t.Errorf("expected ErrInvalidReconnectToken for tampered token, got %v", err)
+	}
+
+	expired := NewReconnectIssuer([]byte("test-key"), time.Minute)
+	expired.ttl = -time.Minute
+	old, _ := expired.Issue("session-1", "stats", Identity{})
+	if _, err := issuer.Verify(old); err != ErrReconnectTokenExpired {
+		t.Errorf("expected ErrReconnectTokenExpired, got %v", err)
+	}
+}
+
+func drain(ch chan []byte) []string {
+	var msgs []string
+	for {
+		select {
+		case msg := <-ch:
+			msgs = append(msgs, string(msg))
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestWebSocketHub_Replay(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	defer hub.Close()
+
+	for i := 1; i <= 3; i++ {
+		hub.Broadcast("stats", []byte(fmt.Sprintf(`{"n":%d}`, i)))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	issuer := NewReconnectIssuer(nil, time.Minute)
+
+	t.Run("replays missed events", func(t *testing.T) {
+		client := &WSClient{
+			send: make(chan []byte, 16), channel: "stats", hub: hub,
+			resumable: true, sessionID: "s1", issuer: issuer,
+			resumeEpoch: hub.epoch, lastSeq: 1,
+		}
+		hub.register <- client
+		time.Sleep(10 * time.Millisecond)
+
+		msgs := drain(client.send)
+		if len(msgs) != 2 {
+			t.Fatalf("expected 2 replayed events, got %v", msgs)
+		}
+		var frame wsEventFrame
+		json.Unmarshal([]byte(msgs[0]), &frame)
+		if frame.Type != "event" || frame.Seq != 2 || string(frame.Data) != `{"n":2}` {
+			t.Errorf("unexpected frame: %+v", frame)
+		}
+
+		// Live events are framed too
+		hub.Broadcast("stats", []byte("plain text"))
+		time.Sleep(10 * time.Millisecond)
+		msgs = drain(client.send)
+		if len(msgs) != 1 || !strings.Contains(msgs[0], `"seq":4`) || !strings.Contains(msgs[0], `"plain text"`) {
+			t.Errorf("unexpected live frame: %v", msgs)
+		}
+	})
+
+	t.Run("resync after restart", func(t *testing.T) {
+		client := &WSClient{
+			send: make(chan []byte, 16), channel: "stats", hub: hub,
+			resumable: true, sessionID: "s2", issuer: issuer,
+			resumeEpoch: "previous-epoch", lastSeq: 2,
+		}
+		hub.register <- client
+		time.Sleep(10 * time.Millisecond)
+
+		msgs := drain(client.send)
+		if len(msgs) != 1 || !strings.Contains(msgs[0], `"type":"resync"`) {
+			t.Errorf("expected resync message, got %v", msgs)
+		}
+	})
+
+	t.Run("plain clients unchanged", func(t *testing.T) {
+		client := &WSClient{send: make(chan []byte, 16), channel: "stats", hub: hub}
+		hub.register <- client
+		time.Sleep(10 * time.Millisecond)
+
+		hub.Broadcast("stats", []byte(`{"n":5}`))
+		time.Sleep(10 * time.Millisecond)
+		msgs := drain(client.send)
+		if len(msgs) != 1 || msgs[0] != `{"n":5}` {
+			t.Errorf("expected raw message, got %v", msgs)
+		}
+	})
+}
+
+func TestWebSocketHub_ReplayGap(t *testing.T) {
+	hub := NewWebSocketHub()
+	go hub.Run()
+	defer hub.Close()
+
+	for i := 0; i < replayBufferSize+5; i++ {
+		hub.Broadcast("logs", []byte(`{}`))
+		if i%100 == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	client := &WSClient{
+		send: make(chan []byte, 16), channel: "logs", hub: hub,
+		resumable: true, sessionID: "s1", issuer: NewReconnectIssuer(nil, time.Minute),
+		resumeEpoch: hub.epoch, lastSeq: 1,
+	}
+	hub.register <- client
+	time.Sleep(10 * time.Millisecond)
+
+	msgs := drain(client.send)
+	if len(msgs) != 1 || !strings.Contains(msgs[0], `"type":"resync"`) {
+		t.Errorf("expected resync when events were evicted, got %d messages", len(msgs))
+	}
+}
+
+func TestWebSocketReconnect(t *testing.T) {
+	config := DefaultConfig()
+	config.AdminToken = "admin-token"
+	s := New(config, nil)
+	go s.wsHub.Run()
+	defer s.wsHub.Close()
+
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/stats"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=admin-token&resume=1", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	var session wsSessionMessage
+	if err := conn.ReadJSON(&session); err != nil || session.Type != "session" || session.ReconnectToken == "" {
+		t.Fatalf("expected session message, got %+v (%v)", session, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	s.wsHub.Broadcast("stats", []byte(`{"n":1}`))
+	var frame wsEventFrame
+	if err := conn.ReadJSON(&frame); err != nil || frame.Seq != 1 {
+		t.Fatalf("expected first event, got %+v (%v)", frame, err)
+	}
+	conn.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	// Missed while disconnected
+	s.wsHub.Broadcast("stats", []byte(`{"n":2}`))
+	time.Sleep(10 * time.Millisecond)
+
+	if _, _, err := websocket.DefaultDialer.Dial(strings.Replace(wsURL, "stats", "logs", 1)+"?reconnect="+session.ReconnectToken, nil); err == nil {
+		t.Fatal("reconnect token should be bound to its channel")
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(fmt.Sprintf("%s?reconnect=%s&epoch=%s&last_seq=1",
+		wsURL, session.ReconnectToken, session.Epoch), nil)
+	if err != nil {
+		t.Fatalf("reconnect failed: %v", err)
+	}
+	defer conn.Close()
+
+	var resumed wsSessionMessage
+	if err := conn.ReadJSON(&resumed); err != nil || resumed.SessionID != session.SessionID {
+		t.Fatalf("expected same session, got %+v (%v)", resumed, err)
+	}
+	if err := conn.ReadJSON(&frame); err != nil || frame.Seq != 2 || string(frame.Data) != `{"n":2}` {
+		t.Errorf("expected replayed event, got %+v (%v)", frame, err)
+	}
+	conn.Close()
+
+	// Rotating the admin token invalidates the old token and its reconnect tokens
+	s.auth.UpdateToken("rotated-token")
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=admin-token", nil); err == nil {
+		t.Fatal("old admin token should be rejected")
+	}
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL+"?reconnect="+resumed.ReconnectToken, nil); err == nil {
+		t.Fatal("reconnect token issued under the old admin token should be rejected")
+	}
+}
+
+func TestWebSocketReconnectAPIKey(t *testing.T) {
+	config := DefaultConfig()
+	config.AdminToken = "admin-token"
+	s := New(config, nil)
+	keys, err := auth.NewKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+	if err != nil {
+		t.Fatalf("NewKeyStore() error = %v", err)
+	}
+	policy := auth.DefaultPolicy()
+	s.SetAuthorizer(auth.NewAuthorizer(keys, policy, policy.AdminRoutes))
+	reader, readerSecret, _ := keys.Create(auth.RoleReadOnly, "dashboard")
+	go s.wsHub.Run()
+	defer s.wsHub.Close()
+
+	ts := httptest.NewServer(s.mux)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/stats"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+readerSecret+"&resume=1", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	var session wsSessionMessage
+	if err := conn.ReadJSON(&session); err != nil || session.ReconnectToken == "" {
+		t.Fatalf("expected session message, got %+v (%v)", session, err)
+	}
+	conn.Close()
+
+	claims, err := s.reconnect.Verify(session.ReconnectToken)
+	if err != nil || claims.KeyID != reader.ID || claims.Credential != "" {
+		t.Fatalf("token should be bound to the API key, got %+v (%v)", claims, err)
+	}
+
+	// A token forged for the admin identity without the current admin token is useless
+	forged, _ := s.reconnect.Issue(session.SessionID, "stats", Identity{Credential: "guess"})
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL+"?reconnect="+forged, nil); err == nil {
+		t.Error("reconnect token with a stale admin credential should be rejected")
+	}
+
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL+"?reconnect="+session.ReconnectToken, nil)
+	if err != nil {
+		t.Fatalf("reconnect with an active key failed: %v", err)
+	}
+	conn.Close()
+
+	keys.Revoke(reader.ID)
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL+"?reconnect="+session.ReconnectToken, nil); err == nil {
+		t.Error("reconnect token of a revoked key should be rejected")
+	}
+}
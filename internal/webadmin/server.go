@@ -14,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
 	"github.com/gorilla/websocket"
 )
 
@@ -36,6 +37,16 @@ type Config struct {
 
 	// StaticPath is an optional path to serve static files from disk (for development)
 	StaticPath string `json:"static_path"`
+
+	// BlockProfileRate enables block profiling when > 0 (see runtime.SetBlockProfileRate)
+	BlockProfileRate int `json:"block_profile_rate"`
+
+	// MutexProfileFraction enables mutex profiling when > 0 (see runtime.SetMutexProfileFraction)
+	MutexProfileFraction int `json:"mutex_profile_fraction"`
+
+	// ReconnectKey signs WebSocket reconnect tokens. Keep it stable across
+	// restarts so clients can resume without logging in again (random if empty).
+	ReconnectKey []byte `json:"-"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -46,6 +57,10 @@ func DefaultConfig() *Config {
 		SessionDuration: 24 * time.Hour,
 		EnableCORS:      false,
 		StaticPath:      "",
+		// sample one blocking event per 10µs blocked and 1 in 100 mutex contentions,
+		// cheap enough to leave on so /debug/pprof/block and /mutex are never empty
+		BlockProfileRate:     10000,
+		MutexProfileFraction: 100,
 	}
 }
 
@@ -56,6 +71,7 @@ type Server struct {
 	mux        *http.ServeMux
 	auth       *AuthManager
 	wsHub      *WebSocketHub
+	reconnect  *ReconnectIssuer
 	topology   *TopologyManager
 	handlers   *Handlers
 	opHandlers *OperationHandlers
@@ -63,6 +79,14 @@ type Server struct {
 	nodeInfo   NodeInfoProvider
 	opsProvider OperationsProvider
 	extProvider ExtendedOperationsProvider
+	taskProvider TaskOperationsProvider
+	reputationProvider ReputationOperationsProvider
+	escrowProvider EscrowOperationsProvider
+	disputeProvider DisputeOperationsProvider
+	collateralProvider CollateralOperationsProvider
+	tokenProvider TokenOperationsProvider
+	reportProvider ReportOperationsProvider
+	contactProvider ContactOperationsProvider
 
 	mu      sync.RWMutex
 	running bool
@@ -181,6 +205,7 @@ func New(config *Config, nodeInfo NodeInfoProvider) *Server {
 
 	s.auth = NewAuthManager(config.AdminToken, config.SessionDuration)
 	s.wsHub = NewWebSocketHub()
+	s.reconnect = NewReconnectIssuer(config.ReconnectKey, reconnectTokenTTL)
 	s.topology = NewTopologyManager(nodeInfo)
 	s.handlers = NewHandlers(s)
 	s.opHandlers = NewOperationHandlers(s, nil) // 初始化时没有操作提供者
@@ -205,6 +230,116 @@ func (s *Server) SetExtendedOperationsProvider(provider ExtendedOperationsProvid
 	defer s.mu.Unlock()
 	s.extProvider = provider
 	s.extHandlers = NewExtendedOperationHandlers(s, provider)
+	s.extHandlers.tasks = s.taskProvider
+	s.extHandlers.reputation = s.reputationProvider
+	s.extHandlers.escrow = s.escrowProvider
+	s.extHandlers.dispute = s.disputeProvider
+	s.extHandlers.collateral = s.collateralProvider
+	s.extHandlers.token = s.tokenProvider
+	s.extHandlers.report = s.reportProvider
+	s.extHandlers.contacts = s.contactProvider
+}
+
+// SetTaskOperationsProvider sets the task provider used by the task routes,
+// so they work without a full extended operations provider.
+func (s *Server) SetTaskOperationsProvider(provider TaskOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.taskProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.tasks = provider
+}
+
+// SetReputationOperationsProvider sets the provider used by the reputation
+// update and history routes, independent of the extended operations provider.
+func (s *Server) SetReputationOperationsProvider(provider ReputationOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reputationProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.reputation = provider
+}
+
+// SetEscrowOperationsProvider sets the provider used by the escrow multisig
+// routes, independent of the extended operations provider.
+func (s *Server) SetEscrowOperationsProvider(provider EscrowOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.escrowProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.escrow = provider
+}
+
+// SetDisputeOperationsProvider sets the provider used by the dispute pre-trial
+// routes, independent of the extended operations provider.
+func (s *Server) SetDisputeOperationsProvider(provider DisputeOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disputeProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.dispute = provider
+}
+
+// SetCollateralOperationsProvider sets the provider used by the collateral
+// routes, independent of the extended operations provider.
+func (s *Server) SetCollateralOperationsProvider(provider CollateralOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collateralProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.collateral = provider
+}
+
+// SetTokenOperationsProvider sets the provider used by the token ledger
+// routes, independent of the extended operations provider.
+func (s *Server) SetTokenOperationsProvider(provider TokenOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.token = provider
+}
+
+// SetReportOperationsProvider sets the provider used by the activity report
+// routes, independent of the extended operations provider.
+func (s *Server) SetReportOperationsProvider(provider ReportOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.report = provider
+}
+
+// SetContactOperationsProvider sets the provider used by the mailbox address
+// book routes, independent of the extended operations provider.
+func (s *Server) SetContactOperationsProvider(provider ContactOperationsProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contactProvider = provider
+	if s.extHandlers == nil {
+		s.extHandlers = NewExtendedOperationHandlers(s, s.extProvider)
+	}
+	s.extHandlers.contacts = provider
+}
+
+// SetAuthorizer accepts API keys issued by `token create` in addition to the
+// admin token; each key is limited to the routes its role may call.
+func (s *Server) SetAuthorizer(a *auth.Authorizer) {
+	s.auth.SetAuthorizer(a)
 }
 
 // setupRoutes configures all HTTP routes.
@@ -266,6 +401,7 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/ws/topology", s.wsAuthMiddleware(s.handlers.HandleWSTopology))
 	s.mux.HandleFunc("/ws/logs", s.wsAuthMiddleware(s.handlers.HandleWSLogs))
 	s.mux.HandleFunc("/ws/stats", s.wsAuthMiddleware(s.handlers.HandleWSStats))
+	s.mux.HandleFunc("/ws/tasks", s.wsAuthMiddleware(s.handlers.HandleWSTasks))
 
 	// ========== 扩展 API (Task09 完整支持) ==========
 	// 声誉扩展
@@ -425,6 +561,42 @@ func (s *Server) setupRoutes() {
 		if s.extHandlers != nil { s.extHandlers.HandleSlashHistory(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
 	}, true))
 
+	// 代币账本
+	s.mux.HandleFunc("/api/token/balance", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleTokenBalance(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/token/history", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleTokenHistory(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/token/transfer", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleTokenTransfer(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+
+	// 活动报告
+	s.mux.HandleFunc("/api/reports/list", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleReportList(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/reports/download", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleReportDownload(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/reports/generate", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleReportGenerate(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+
+	// 邮箱通讯录
+	s.mux.HandleFunc("/api/mailbox/contacts", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleContactList(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/mailbox/contacts/save", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleContactSave(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/mailbox/contacts/delete", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleContactDelete(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+	s.mux.HandleFunc("/api/mailbox/contacts/check", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.extHandlers != nil { s.extHandlers.HandleContactCheck(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
+	}, true))
+
 	// 争议预审 (Task44)
 	s.mux.HandleFunc("/api/dispute/list", s.wrapExtendedHandler(func(w http.ResponseWriter, r *http.Request) {
 		if s.extHandlers != nil { s.extHandlers.HandleDisputeList(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
@@ -459,6 +631,9 @@ func (s *Server) setupRoutes() {
 		if s.extHandlers != nil { s.extHandlers.HandleEscrowResolve(w, r) } else { WriteError(w, http.StatusServiceUnavailable, "Extended operations not configured") }
 	}, true))
 
+	// Runtime profiling (pprof / trace)
+	s.setupDebugRoutes()
+
 	// Static files (Vue.js app)
 	s.setupStaticFiles()
 }
@@ -479,8 +654,7 @@ func (s *Server) wrapExtendedHandler(handler http.HandlerFunc, requireAuth bool)
 		}
 
 		// Auth check
-		if requireAuth && !s.checkAuth(r) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if requireAuth && !s.authorize(w, r) {
 			return
 		}
 
@@ -504,8 +678,7 @@ func (s *Server) wrapOperationHandler(handler http.HandlerFunc, requireAuth bool
 		}
 
 		// Auth check
-		if requireAuth && !s.checkAuth(r) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if requireAuth && !s.authorize(w, r) {
 			return
 		}
 
@@ -538,8 +711,7 @@ func (s *Server) wrapHandler(handler http.HandlerFunc, requireAuth bool) http.Ha
 		}
 
 		// Auth check
-		if requireAuth && !s.checkAuth(r) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if requireAuth && !s.authorize(w, r) {
 			return
 		}
 
@@ -547,37 +719,61 @@ func (s *Server) wrapHandler(handler http.HandlerFunc, requireAuth bool) http.Ha
 	}
 }
 
-// checkAuth checks if the request is authenticated.
-func (s *Server) checkAuth(r *http.Request) bool {
-	// Check URL token parameter (quick access with admin token)
+// credentials resolves the admin token, session or API key presented by the request.
+// keyID is empty for the admin token and sessions opened with it.
+func (s *Server) credentials(r *http.Request) (role auth.Role, keyID string, ok bool) {
+	// Check URL token parameter (quick access with admin token or API key)
 	token := r.URL.Query().Get("token")
-	if token != "" && s.auth.ValidateToken(token) {
-		return true
+	if token != "" {
+		if role, keyID, ok := s.auth.Authenticate(token); ok {
+			return role, keyID, true
+		}
 	}
 
 	// Check session cookie (session ID)
 	cookie, err := r.Cookie(TokenCookieName)
 	if err == nil {
 		// First try as session ID
-		if s.auth.ValidateSession(cookie.Value) {
-			return true
+		if role, keyID, ok := s.auth.SessionIdentity(cookie.Value); ok {
+			return role, keyID, true
 		}
 		// Fallback to direct token validation for backward compatibility
-		if s.auth.ValidateToken(cookie.Value) {
-			return true
+		if role, keyID, ok := s.auth.Authenticate(cookie.Value); ok {
+			return role, keyID, true
 		}
 	}
 
-	// Check Authorization header (admin token)
+	// Check Authorization header (admin token or API key)
 	authHeader := r.Header.Get("Authorization")
 	if strings.HasPrefix(authHeader, "Bearer ") {
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		if s.auth.ValidateToken(token) {
-			return true
+		if role, keyID, ok := s.auth.Authenticate(token); ok {
+			return role, keyID, true
 		}
 	}
 
-	return false
+	return "", "", false
+}
+
+// checkAuth checks if the request is authenticated.
+func (s *Server) checkAuth(r *http.Request) bool {
+	_, _, ok := s.credentials(r)
+	return ok
+}
+
+// authorize checks the request's credentials and, for API keys, whether the key's role
+// may call the route. It writes 401 or 403 and returns false when the request may not proceed.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	role, keyID, ok := s.credentials(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if keyID != "" && !s.auth.Allowed(role, r.Method, r.URL.Path) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
 }
 
 // setupStaticFiles configures static file serving with SPA fallback.
@@ -669,24 +865,37 @@ func mustReadIndex(fsys fs.FS) []byte {
 	return data
 }
 
+// wsIdentityKey is the request context key for the authenticated WebSocket identity.
+type wsIdentityKey struct{}
+
 // wsAuthMiddleware wraps a WebSocket handler with authentication.
+// Every upgrade resolves the caller's role, including upgrades that only carry
+// a reconnect token, and checks it against the route's RBAC rule.
 func (s *Server) wsAuthMiddleware(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check URL token parameter
-		token := r.URL.Query().Get("token")
-		if token != "" && s.auth.ValidateToken(token) {
-			handler(w, r)
+		// Check admin token, session or API key
+		role, keyID, ok := s.credentials(r)
+		id := s.auth.Identity(keyID)
+		if !ok {
+			// Reconnect token (channel binding is checked by the handler): the identity
+			// it was issued for must still be valid
+			if reconnect := r.URL.Query().Get("reconnect"); reconnect != "" {
+				if claims, err := s.reconnect.Verify(reconnect); err == nil {
+					id = claims.Identity
+					keyID = id.KeyID
+					role, ok = s.auth.ResolveIdentity(id)
+				}
+			}
+		}
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-
-		// Check token cookie
-		cookie, err := r.Cookie(TokenCookieName)
-		if err == nil && s.auth.ValidateToken(cookie.Value) {
-			handler(w, r)
+		if keyID != "" && !s.auth.Allowed(role, r.Method, r.URL.Path) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		handler(w, r.WithContext(context.WithValue(r.Context(), wsIdentityKey{}, id)))
 	}
 }
 
@@ -756,6 +965,18 @@ func (s *Server) IsRunning() bool {
 	return s.running
 }
 
+// PublishTaskProgress pushes a task progress update to /ws/tasks subscribers.
+func (s *Server) PublishTaskProgress(update interface{}) {
+	if s.wsHub.ClientCount("tasks") == 0 {
+		return
+	}
+	data, err := json.Marshal(update)
+	if err != nil {
+		return
+	}
+	s.wsHub.Broadcast("tasks", data)
+}
+
 // GetAdminURL returns the admin panel URL with token.
 func (s *Server) GetAdminURL() string {
 	addr := s.config.ListenAddr
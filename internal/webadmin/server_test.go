@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/auth"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/mailbox"
+	"github.com/AgentNetworkPlan/AgentNetwork/internal/report"
 )
 
 // mockNodeInfo implements NodeInfoProvider for testing.
@@ -514,6 +519,175 @@ func TestLogout(t *testing.T) {
 	}
 }
 
+// TestAPIKeyRoles tests that API keys are limited by their role.
+func TestAPIKeyRoles(t *testing.T) {
+	server := newTestServer()
+	keys, err := auth.NewKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+	if err != nil {
+		t.Fatalf("NewKeyStore() error = %v", err)
+	}
+	policy := auth.DefaultPolicy()
+	server.SetAuthorizer(auth.NewAuthorizer(keys, policy, policy.AdminRoutes))
+	reader, readerSecret, _ := keys.Create(auth.RoleReadOnly, "dashboard")
+	_, operatorSecret, _ := keys.Create(auth.RoleOperator, "bot")
+
+	send := func(method, path string, prepare func(*http.Request)) int {
+		req := httptest.NewRequest(method, path, nil)
+		prepare(req)
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	bearer := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	if code := send("GET", "/api/node/status", bearer(readerSecret)); code != http.StatusOK {
+		t.Errorf("read-only GET: expected 200, got %d", code)
+	}
+	if code := send("POST", "/api/auth/token/refresh", bearer(operatorSecret)); code != http.StatusForbidden {
+		t.Errorf("operator token refresh: expected 403, got %d", code)
+	}
+	if code := send("GET", "/api/node/status", bearer("dak_unknown")); code != http.StatusUnauthorized {
+		t.Errorf("unknown key: expected 401, got %d", code)
+	}
+
+	// Login with a key: the session carries the key's role and ends when the key is revoked
+	loginReq := httptest.NewRequest("POST", "/api/auth/login", strings.NewReader(`{"token": "`+readerSecret+`"}`))
+	loginW := httptest.NewRecorder()
+	server.mux.ServeHTTP(loginW, loginReq)
+	var login LoginResponse
+	json.NewDecoder(loginW.Body).Decode(&login)
+	if loginW.Code != http.StatusOK || login.Role != string(auth.RoleReadOnly) {
+		t.Fatalf("login with key: status %d, response %+v", loginW.Code, login)
+	}
+	session := func(req *http.Request) { req.AddCookie(&http.Cookie{Name: TokenCookieName, Value: login.SessionID}) }
+	if code := send("GET", "/api/node/status", session); code != http.StatusOK {
+		t.Errorf("key session GET: expected 200, got %d", code)
+	}
+	if code := send("POST", "/api/message/send", session); code != http.StatusForbidden {
+		t.Errorf("read-only session POST: expected 403, got %d", code)
+	}
+	keys.Revoke(reader.ID)
+	if code := send("GET", "/api/node/status", session); code != http.StatusUnauthorized {
+		t.Errorf("session of revoked key: expected 401, got %d", code)
+	}
+}
+
+// TestReportEndpoints tests listing, generating and downloading activity reports.
+func TestReportEndpoints(t *testing.T) {
+	server := newTestServer()
+	store, err := report.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	ops := NewRealOperationsProvider("12D3KooWTest123")
+	ops.SetReportGenerator(report.NewGenerator("12D3KooWTest123", store, func() *report.Input {
+		return &report.Input{TokenBalance: 42}
+	}))
+	server.SetReportOperationsProvider(ops)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token-12345")
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/api/reports/generate", `{"days": 14}`)
+	var generated ReportInfo
+	json.NewDecoder(w.Body).Decode(&generated)
+	if w.Code != http.StatusOK || generated.ID == "" {
+		t.Fatalf("generate: status %d, report %+v", w.Code, generated)
+	}
+
+	w = send("GET", "/api/reports/list", "")
+	var list struct {
+		Reports []*ReportInfo `json:"reports"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Reports) != 1 || list.Reports[0].ID != generated.ID {
+		t.Fatalf("list: status %d, reports %+v", w.Code, list.Reports)
+	}
+
+	w = send("GET", "/api/reports/download?format=pdf&id="+generated.ID, "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/pdf" || !strings.HasPrefix(w.Body.String(), "%PDF-") {
+		t.Errorf("pdf download: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") || !strings.Contains(disposition, generated.ID+".pdf") {
+		t.Errorf("Content-Disposition = %q", disposition)
+	}
+	if w = send("GET", "/api/reports/download?format=html&inline=1&id="+generated.ID, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "42.00") {
+		t.Errorf("html download: status %d", w.Code)
+	}
+	if w = send("GET", "/api/reports/download?format=doc&id="+generated.ID, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: expected 400, got %d", w.Code)
+	}
+	if w = send("GET", "/api/reports/download?id=20200106T0000Z-20200113T0000Z", ""); w.Code != http.StatusNotFound {
+		t.Errorf("missing report: expected 404, got %d", w.Code)
+	}
+}
+
+// TestContactEndpoints tests the mailbox address book and recipient check routes.
+func TestContactEndpoints(t *testing.T) {
+	server := newTestServer()
+	config := mailbox.DefaultConfig("12D3KooWTest123")
+	config.DataDir = t.TempDir()
+	mb, err := mailbox.NewMailbox(config)
+	if err != nil {
+		t.Fatalf("NewMailbox() error = %v", err)
+	}
+	mb.SetQuarantineFunc(func(nodeID string) bool { return nodeID == "peer-banned" })
+	ops := NewRealOperationsProvider("12D3KooWTest123")
+	ops.SetMailbox(mb)
+	server.SetContactOperationsProvider(ops)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token-12345")
+		w := httptest.NewRecorder()
+		server.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("POST", "/api/mailbox/contacts/save", `{"node_id":"peer-a","label":"Alice","trust":"low","encryption":"always"}`); w.Code != http.StatusOK {
+		t.Fatalf("save: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w := send("POST", "/api/mailbox/contacts/save", `{"node_id":"peer-b","trust":"unknown"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid trust: expected 400, got %d", w.Code)
+	}
+
+	w := send("GET", "/api/mailbox/contacts?q=ali", "")
+	var list struct {
+		Contacts []*ContactInfo `json:"contacts"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Contacts) != 1 || list.Contacts[0].NodeID != "peer-a" {
+		t.Fatalf("search: status %d, contacts %+v", w.Code, list.Contacts)
+	}
+
+	var check RecipientCheckInfo
+	w = send("GET", "/api/mailbox/contacts/check?to=peer-a", "")
+	json.NewDecoder(w.Body).Decode(&check)
+	if len(check.Warnings) != 1 || check.Warnings[0].Code != mailbox.WarnLowTrust || check.Contact == nil {
+		t.Errorf("check peer-a = %+v", check)
+	}
+	w = send("GET", "/api/mailbox/contacts/check?to=peer-banned", "")
+	check = RecipientCheckInfo{}
+	json.NewDecoder(w.Body).Decode(&check)
+	if !check.Quarantined || len(check.Warnings) != 1 || check.Warnings[0].Code != mailbox.WarnQuarantined {
+		t.Errorf("check peer-banned = %+v", check)
+	}
+
+	if w = send("POST", "/api/mailbox/contacts/delete?node_id=peer-a", ""); w.Code != http.StatusOK {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if w = send("POST", "/api/mailbox/contacts/delete?node_id=peer-a", ""); w.Code != http.StatusNotFound {
+		t.Errorf("delete missing: expected 404, got %d", w.Code)
+	}
+}
+
 // TestServerStartStop tests server lifecycle.
 func TestServerStartStop(t *testing.T) {
 	config := &Config{
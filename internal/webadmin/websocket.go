@@ -1,6 +1,9 @@
 package webadmin
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -27,6 +30,33 @@ type WSClient struct {
 	send    chan []byte
 	channel string
 	hub     *WebSocketHub
+
+	// Resumable clients receive seq-framed events and reconnect tokens.
+	resumable    bool
+	sessionID    string
+	issuer       *ReconnectIssuer
+	tokenExpires time.Time
+	identity     Identity
+	// Reports whether identity is still valid; checked before each token refresh.
+	identityValid func(Identity) bool
+
+	// Set when resuming a previous session: replay events after lastSeq.
+	resumeEpoch string
+	lastSeq     uint64
+}
+
+// sessionMessage issues a fresh reconnect token and encodes it for the client.
+func (c *WSClient) sessionMessage(epoch string) []byte {
+	token, expiresAt := c.issuer.Issue(c.sessionID, c.channel, c.identity)
+	c.tokenExpires = expiresAt
+	msg, _ := json.Marshal(wsSessionMessage{
+		Type:           "session",
+		SessionID:      c.sessionID,
+		Epoch:          epoch,
+		ReconnectToken: token,
+		ExpiresAt:      expiresAt,
+	})
+	return msg
 }
 
 // readPump pumps messages from the WebSocket connection to the hub.
@@ -87,6 +117,18 @@ func (c *WSClient) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+			// Refresh the reconnect token before it expires; a rotated admin
+			// token or revoked API key ends the connection instead
+			if c.resumable && time.Until(c.tokenExpires) < c.issuer.ttl/2 {
+				if c.identityValid != nil && !c.identityValid(c.identity) {
+					c.conn.WriteMessage(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "credentials no longer valid"))
+					return
+				}
+				if err := c.conn.WriteMessage(websocket.TextMessage, c.sessionMessage(c.hub.epoch)); err != nil {
+					return
+				}
+			}
 		}
 	}
 }
@@ -108,6 +150,12 @@ type WebSocketHub struct {
 	// Close signal
 	done chan struct{}
 
+	// Replay state for resumable clients. The epoch changes on every
+	// restart so clients can tell that their seq numbers no longer apply.
+	epoch   string
+	seq     map[string]uint64
+	history map[string][]hubEvent
+
 	mu sync.RWMutex
 }
 
@@ -125,9 +173,18 @@ func NewWebSocketHub() *WebSocketHub {
 		register:   make(chan *WSClient),
 		unregister: make(chan *WSClient),
 		done:       make(chan struct{}),
+		epoch:      newHubEpoch(),
+		seq:        make(map[string]uint64),
+		history:    make(map[string][]hubEvent),
 	}
 }
 
+func newHubEpoch() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // Run starts the hub event loop.
 func (h *WebSocketHub) Run() {
 	for {
@@ -138,6 +195,11 @@ func (h *WebSocketHub) Run() {
 				h.clients[client.channel] = make(map[*WSClient]bool)
 			}
 			h.clients[client.channel][client] = true
+			if client.resumable {
+				// Replay under the lock so no broadcast slips in between
+				// the missed events and live ones
+				h.resumeClient(client)
+			}
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
@@ -151,16 +213,21 @@ func (h *WebSocketHub) Run() {
 			h.mu.Unlock()
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
+			frame := h.record(message)
 			clients := h.clients[message.Channel]
 			for client := range clients {
+				data := message.Data
+				if client.resumable {
+					data = frame
+				}
 				select {
-				case client.send <- message.Data:
+				case client.send <- data:
 				default:
 					// Client is slow, skip this message
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 
 		case <-h.done:
 			return
@@ -168,6 +235,59 @@ func (h *WebSocketHub) Run() {
 	}
 }
 
+// record assigns the next seq to a broadcast and keeps it for replay.
+// Caller must hold h.mu.
+func (h *WebSocketHub) record(message *BroadcastMessage) []byte {
+	h.seq[message.Channel]++
+	seq := h.seq[message.Channel]
+	frame := encodeEventFrame(seq, message.Data)
+
+	now := time.Now()
+	events := append(h.history[message.Channel], hubEvent{seq: seq, at: now, frame: frame})
+	start := 0
+	if len(events) > replayBufferSize {
+		start = len(events) - replayBufferSize
+	}
+	for start < len(events) && now.Sub(events[start].at) > replayWindow {
+		start++
+	}
+	h.history[message.Channel] = events[start:]
+	return frame
+}
+
+// resumeClient queues the events a resuming client missed. If they are no
+// longer available a resync message is queued instead. Caller must hold h.mu.
+func (h *WebSocketHub) resumeClient(client *WSClient) {
+	var queue [][]byte
+	if client.resumeEpoch != "" {
+		events := h.history[client.channel]
+		current := h.seq[client.channel]
+		complete := client.resumeEpoch == h.epoch && client.lastSeq <= current
+		if complete && client.lastSeq < current {
+			// The first retained event must directly follow lastSeq
+			complete = len(events) > 0 && events[0].seq <= client.lastSeq+1
+		}
+		if complete {
+			for _, ev := range events {
+				if ev.seq > client.lastSeq {
+					queue = append(queue, ev.frame)
+				}
+			}
+		} else {
+			msg, _ := json.Marshal(wsResyncMessage{Type: "resync", Epoch: h.epoch, Seq: current})
+			queue = append(queue, msg)
+		}
+	}
+
+	for _, msg := range queue {
+		select {
+		case client.send <- msg:
+		default:
+			return
+		}
+	}
+}
+
 // Broadcast sends a message to all clients in a channel.
 func (h *WebSocketHub) Broadcast(channel string, data []byte) {
 	select {
@@ -26,7 +26,8 @@ $BUILD_DIR = "build"
 $MAIN_PATH = "./cmd/node/"
 
 # 构建参数
-$LDFLAGS = "-s -w -X main.Version=$VERSION"
+$BUILD_TIME = (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+$LDFLAGS = "-s -w -X main.version=$VERSION -X main.buildTime=$BUILD_TIME"
 
 # 平台配置
 $PLATFORMS = @(
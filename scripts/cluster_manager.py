@@ -138,10 +138,11 @@ class ClusterManager:
             commit = result.stdout.strip()
         
         # 构建
-        ldflags = f'-X main.Version={version} -X main.Commit={commit}'
+        build_time = datetime.now().strftime("%Y-%m-%dT%H:%M:%S")
+        ldflags = f'-X main.version={version}-{commit} -X main.buildTime={build_time}'
         output_path = BUILD_DIR / f"{output_name}.exe" if os.name == 'nt' else BUILD_DIR / output_name
         
-        cmd = f'go build -ldflags="{ldflags}" -o "{output_path}" ./cmd/node/main.go'
+        cmd = f'go build -ldflags="{ldflags}" -o "{output_path}" ./cmd/node'
         result = run_command(cmd)
         
         if result.returncode == 0:
@@ -176,6 +176,32 @@ export interface MailboxResponse {
   limit: number
 }
 
+export type ContactTrust = 'trusted' | 'normal' | 'low'
+export type ContactEncryption = '' | 'always' | 'never'
+
+export interface ContactInfo {
+  node_id: string
+  label?: string
+  notes?: string
+  trust: ContactTrust
+  encryption?: ContactEncryption
+  created_at?: string
+  updated_at?: string
+}
+
+export interface RecipientWarning {
+  code: 'low_trust' | 'low_reputation' | 'quarantined'
+  message: string
+}
+
+export interface RecipientCheck {
+  node_id: string
+  contact?: ContactInfo
+  reputation?: number
+  quarantined: boolean
+  warnings: RecipientWarning[]
+}
+
 // ========== 留言板类型 ==========
 export interface BulletinMessage {
   message_id: string
@@ -304,6 +330,40 @@ export interface EscrowInfo {
   released_at?: string
 }
 
+// ========== 代币类型 ==========
+export interface TokenAccountInfo {
+  node_id: string
+  balance: number
+  locked: number
+  available: number
+  minted: number
+  nonce: number
+}
+
+export interface TokenEntryInfo {
+  id: string
+  type: string
+  from?: string
+  to?: string
+  amount: number
+  reference?: string
+  timestamp: string
+}
+
+// ========== 活动报告类型 ==========
+export interface ReportInfo {
+  id: string
+  period_start: string
+  period_end: string
+  generated_at: string
+  tasks_completed: number
+  tokens_net: number
+  reputation_change: number
+  disputes: number
+}
+
+export type ReportFormat = 'json' | 'html' | 'pdf'
+
 const api = {
   // Auth
   login: (token: string): Promise<LoginResponse> => 
@@ -380,6 +440,19 @@ const api = {
   deleteMail: (messageId: string): Promise<{ status: string }> =>
     client.post('/mailbox/delete', { message_id: messageId }),
 
+  // 通讯录
+  getContacts: (query = '', limit = 0): Promise<{ contacts: ContactInfo[]; count: number }> =>
+    client.get(`/mailbox/contacts?q=${encodeURIComponent(query)}&limit=${limit}`),
+
+  saveContact: (contact: ContactInfo): Promise<ContactInfo> =>
+    client.post('/mailbox/contacts/save', contact),
+
+  deleteContact: (nodeId: string): Promise<{ node_id: string; deleted: boolean }> =>
+    client.post(`/mailbox/contacts/delete?node_id=${encodeURIComponent(nodeId)}`),
+
+  checkRecipient: (to: string): Promise<RecipientCheck> =>
+    client.get(`/mailbox/contacts/check?to=${encodeURIComponent(to)}`),
+
   // ========== 留言板 API ==========
   publishBulletin: (topic: string, content: string, ttl: number): Promise<{ message_id: string; topic: string; status: string }> =>
     client.post('/bulletin/publish', { topic, content, ttl }),
@@ -524,6 +597,26 @@ const api = {
   
   refundEscrow: (escrowId: string): Promise<{ status: string }> =>
     client.post('/escrow/refund', { escrow_id: escrowId }),
+
+  // ========== 代币 API ==========
+  getTokenBalance: (): Promise<TokenAccountInfo> =>
+    client.get('/token/balance'),
+  
+  getTokenHistory: (limit = 50): Promise<{ history: TokenEntryInfo[]; count: number }> =>
+    client.get(`/token/history?limit=${limit}`),
+  
+  transferTokens: (to: string, amount: number, memo: string): Promise<TokenEntryInfo> =>
+    client.post('/token/transfer', { to, amount, memo }),
+
+  // ========== 活动报告 API ==========
+  getReports: (): Promise<{ reports: ReportInfo[]; count: number }> =>
+    client.get('/reports/list'),
+
+  generateReport: (days = 7): Promise<ReportInfo> =>
+    client.post('/reports/generate', { days }),
+
+  downloadReport: (id: string, format: ReportFormat): Promise<Blob> =>
+    client.get(`/reports/download?id=${encodeURIComponent(id)}&format=${format}`, { responseType: 'blob' }),
 }
 
 export default api
@@ -85,6 +85,18 @@ const router = createRouter({
       component: () => import('@/views/DisputesView.vue'),
       meta: { title: '争议处理' }
     },
+    {
+      path: '/token',
+      name: 'token',
+      component: () => import('@/views/TokenView.vue'),
+      meta: { title: '代币账本' }
+    },
+    {
+      path: '/reports',
+      name: 'reports',
+      component: () => import('@/views/ReportsView.vue'),
+      meta: { title: '活动报告' }
+    },
     {
       path: '/about',
       name: 'about',